@@ -0,0 +1,27 @@
+package marketplace
+
+import "time"
+
+// BudgetAlertTopic is the Kafka topic metering publishes budget alerts to,
+// for the notification service to deliver
+const BudgetAlertTopic = "marketplace.budget.alerts"
+
+// BudgetAlertThresholds are the shares of a monthly budget, in percent, at
+// which the consumer is alerted
+var BudgetAlertThresholds = []int{50, 80, 100}
+
+// BudgetAlert reports that a consumer's spend in a month has reached a
+// threshold of its budget cap. Each threshold is alerted once per consumer
+// and month, and its ID is the same for every delivery. Alerts are keyed
+// by consumer ID.
+type BudgetAlert struct {
+	ID         string    `json:"id"`
+	OccurredAt time.Time `json:"occurred_at"`
+	ConsumerID string    `json:"consumer_id"`
+	Period     string    `json:"period"`    // The month, as YYYY-MM
+	Threshold  int       `json:"threshold"` // Percent of the cap
+	Spend      float64   `json:"spend"`
+	Cap        float64   `json:"cap"`
+	Currency   string    `json:"currency"`
+	HardStop   bool      `json:"hard_stop"` // Calls are refused once the cap is reached
+}
@@ -0,0 +1,144 @@
+package marketplace
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+// Kinds of service change recorded in a service's changelog
+const (
+	ChangePricing      = "pricing"
+	ChangeSLA          = "sla"
+	ChangeCapabilities = "capabilities"
+)
+
+// ServiceChange is an entry of a service's changelog: a change to its
+// pricing, SLA or capabilities, made at Revision and in effect from
+// EffectiveAt. Only the fields of its kind are set.
+type ServiceChange struct {
+	Kind        string       `json:"kind"`
+	Revision    int64        `json:"revision"`
+	Summary     string       `json:"summary"`
+	OldPricing  *PricingInfo `json:"old_pricing,omitempty"`
+	NewPricing  *PricingInfo `json:"new_pricing,omitempty"`
+	OldSLA      *SLAInfo     `json:"old_sla,omitempty"`
+	NewSLA      *SLAInfo     `json:"new_sla,omitempty"`
+	Added       []string     `json:"added,omitempty"`   // Capabilities added
+	Removed     []string     `json:"removed,omitempty"` // Capabilities removed
+	EffectiveAt time.Time    `json:"effective_at"`
+}
+
+// Changes returns the changelog entries for replacing old with new: one per
+// kind that differs, in the order pricing, SLA, capabilities. Revision and
+// EffectiveAt are left for the caller to set.
+func Changes(old, new *ServiceDescriptor) []ServiceChange {
+	var changes []ServiceChange
+	if !pricingEqual(old.Pricing, new.Pricing) {
+		changes = append(changes, ServiceChange{
+			Kind:       ChangePricing,
+			Summary:    fmt.Sprintf("Pricing changed from %s to %s", describePricing(old.Pricing), describePricing(new.Pricing)),
+			OldPricing: old.Pricing,
+			NewPricing: new.Pricing,
+		})
+	}
+	if !slaEqual(old.SLA, new.SLA) {
+		changes = append(changes, ServiceChange{
+			Kind:    ChangeSLA,
+			Summary: fmt.Sprintf("SLA changed from %s to %s", describeSLA(old.SLA), describeSLA(new.SLA)),
+			OldSLA:  old.SLA,
+			NewSLA:  new.SLA,
+		})
+	}
+	before, after := capabilityNames(old.Capabilities), capabilityNames(new.Capabilities)
+	change := ServiceChange{Kind: ChangeCapabilities}
+	for _, name := range after {
+		if !slices.Contains(before, name) {
+			change.Added = append(change.Added, name)
+		}
+	}
+	for _, name := range before {
+		if !slices.Contains(after, name) {
+			change.Removed = append(change.Removed, name)
+		}
+	}
+	switch {
+	case len(change.Added) > 0 && len(change.Removed) > 0:
+		change.Summary = fmt.Sprintf("Capabilities added: %v; removed: %v", change.Added, change.Removed)
+	case len(change.Added) > 0:
+		change.Summary = fmt.Sprintf("Capabilities added: %v", change.Added)
+	case len(change.Removed) > 0:
+		change.Summary = fmt.Sprintf("Capabilities removed: %v", change.Removed)
+	}
+	if change.Summary != "" {
+		changes = append(changes, change)
+	}
+	return changes
+}
+
+func pricingEqual(a, b *PricingInfo) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
+func slaEqual(a, b *SLAInfo) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func capabilityNames(caps []Capability) []string {
+	names := make([]string, len(caps))
+	for i, c := range caps {
+		names[i] = c.Name
+	}
+	return names
+}
+
+func describePricing(p *PricingInfo) string {
+	if p == nil {
+		return "none"
+	}
+	currency := p.Currency
+	if currency == "" {
+		currency = "USD"
+	}
+	s := fmt.Sprintf("%g %s per %s (%s)", p.Rate, currency, p.Unit, p.Model)
+	if len(p.Tiers) > 0 {
+		s += fmt.Sprintf(" with %d tiers", len(p.Tiers))
+	}
+	return s
+}
+
+func describeSLA(sla *SLAInfo) string {
+	if sla == nil {
+		return "none"
+	}
+	return fmt.Sprintf("%g%% availability, %d ms max latency", sla.Availability, sla.MaxLatencyMS)
+}
+
+// PriceChangeTopic is the Kafka topic the registry publishes price-change
+// notices to, for the notification service to deliver
+const PriceChangeTopic = "marketplace.price.changes"
+
+// PriceChangeNotice tells a consumer subscribed to a service that its price
+// will change at EffectiveAt. Each scheduled change sends one notice per
+// subscribed consumer when it is scheduled, and a cancelled change sends
+// notices withdrawing it. Notices are keyed by consumer ID.
+type PriceChangeNotice struct {
+	ID             string      `json:"id"`
+	OccurredAt     time.Time   `json:"occurred_at"`
+	PriceChangeID  string      `json:"price_change_id"`
+	ConsumerID     string      `json:"consumer_id"`
+	SubscriptionID string      `json:"subscription_id"`
+	ServiceID      string      `json:"service_id"`
+	ServiceName    string      `json:"service_name"`
+	ProviderID     string      `json:"provider_id"`
+	OldPricing     PricingInfo `json:"old_pricing"`
+	NewPricing     PricingInfo `json:"new_pricing"`
+	EffectiveAt    time.Time   `json:"effective_at"`
+	Cancelled      bool        `json:"cancelled,omitempty"` // The change was withdrawn and won't take effect
+}
@@ -0,0 +1,89 @@
+package marketplace
+
+import (
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// How the data of a dataset was obtained
+const (
+	ProvenanceCollected = "collected" // Gathered by the provider, e.g. from its own users with consent
+	ProvenanceLicensed  = "licensed"  // Licensed from a third party
+	ProvenanceSynthetic = "synthetic" // Generated, e.g. by a model
+	ProvenancePublic    = "public"    // Compiled from public sources
+	ProvenanceAnnotated = "annotated" // Labelled by annotators
+)
+
+// ProvenanceMethods lists the ways the data of a dataset can be obtained
+var ProvenanceMethods = []string{ProvenanceCollected, ProvenanceLicensed, ProvenanceSynthetic, ProvenancePublic, ProvenanceAnnotated}
+
+var (
+	// licenseID matches an SPDX license identifier or expression
+	// reference, e.g. CC-BY-4.0 or LicenseRef-Acme-Commercial
+	licenseID = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9.\-+]*$`)
+	// Dataset formats are lower case so search filters match them exactly,
+	// e.g. parquet, jsonl, csv
+	datasetFormat = regexp.MustCompile(`^[a-z0-9][a-z0-9.\-]*$`)
+)
+
+// DatasetInfo is what a dataset listing tells a consumer before licensing
+// it: the license the data is offered under, how big it is and where it came
+// from
+type DatasetInfo struct {
+	License string `json:"license"` // SPDX identifier, e.g. CC-BY-4.0
+	// Attribution is the credit consumers must give when the license
+	// requires it, e.g. "Acme Corp. Support Transcripts (2025)"
+	Attribution string             `json:"attribution,omitempty"`
+	Format      string             `json:"format,omitempty"`
+	SizeBytes   int64              `json:"size_bytes"`
+	Records     int64              `json:"records,omitempty"`
+	Provenance  *DatasetProvenance `json:"provenance,omitempty"`
+}
+
+// DatasetProvenance is where a dataset's data came from
+type DatasetProvenance struct {
+	Source string `json:"source"`           // Who or what the data came from
+	Method string `json:"method,omitempty"` // One of ProvenanceMethods
+	URL    string `json:"url,omitempty"`    // Documentation of the source, such as a datasheet
+}
+
+// Validate checks the dataset's license, size and provenance
+func (i *DatasetInfo) Validate() error {
+	e := newErrs()
+	i.validate(e)
+	return e.err()
+}
+
+func (i *DatasetInfo) validate(e errs) {
+	switch {
+	case i.License == "":
+		e.add("license", "is required")
+	case !licenseID.MatchString(i.License):
+		e.add("license", "must be an SPDX license identifier, e.g. CC-BY-4.0")
+	}
+	if i.Format != "" && !datasetFormat.MatchString(i.Format) {
+		e.add("format", "must be lower-case letters, digits, dots and hyphens")
+	}
+	if i.SizeBytes <= 0 {
+		e.add("size_bytes", "must be positive")
+	}
+	if i.Records < 0 {
+		e.add("records", "must not be negative")
+	}
+	if p := i.Provenance; p != nil {
+		pe := e.in("provenance")
+		if strings.TrimSpace(p.Source) == "" {
+			pe.add("source", "is required")
+		}
+		if p.Method != "" && !slices.Contains(ProvenanceMethods, p.Method) {
+			pe.add("method", "must be one of %s", strings.Join(ProvenanceMethods, ", "))
+		}
+		if p.URL != "" {
+			if u, err := url.Parse(p.URL); err != nil || u.Scheme == "" || u.Host == "" {
+				pe.add("url", "must be an absolute URL")
+			}
+		}
+	}
+}
@@ -0,0 +1,63 @@
+// Package egress guards the requests services make to URLs their users give
+// them, such as webhooks and health probes, so those URLs can't be used to
+// reach the marketplace's own network.
+package egress
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+// ErrPrivateAddress is returned when a connection would be made to an address
+// that is not publicly routable
+var ErrPrivateAddress = errors.New("address is not publicly routable")
+
+// Public reports whether ip is publicly routable: not loopback, private,
+// link-local, multicast or unspecified
+func Public(ip net.IP) bool {
+	return !(ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast())
+}
+
+// Control is a net.Dialer Control hook refusing connections to addresses that
+// are not Public. It runs on the address being dialled, after DNS resolution,
+// so a host name resolving, or later re-resolving, to an internal address is
+// refused too.
+func Control(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !Public(ip) {
+		return fmt.Errorf("%w: %s", ErrPrivateAddress, host)
+	}
+	return nil
+}
+
+// Client returns an HTTP client for user-given URLs. It connects only to
+// Public addresses, ignores proxy settings, which would hide the address
+// from Control, and returns redirects instead of following them, since they
+// could point anywhere.
+func Client(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control:   Control,
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
@@ -0,0 +1,57 @@
+package egress_test
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/org/llm-marketplace/pkg/marketplace/egress"
+)
+
+func TestPublic(t *testing.T) {
+	for addr, want := range map[string]bool{
+		"93.184.216.34":    true,
+		"2606:4700::1111":  true,
+		"127.0.0.1":        false,
+		"::1":              false,
+		"10.1.2.3":         false,
+		"172.16.0.1":       false,
+		"192.168.1.1":      false,
+		"fd00::1":          false,
+		"169.254.169.254":  false,
+		"fe80::1":          false,
+		"0.0.0.0":          false,
+		"::":               false,
+		"::ffff:127.0.0.1": false,
+	} {
+		if got := egress.Public(net.ParseIP(addr)); got != want {
+			t.Errorf("Public(%s) = %v, want %v", addr, got, want)
+		}
+	}
+}
+
+func TestClientRefusesLoopback(t *testing.T) {
+	called := false
+	server := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) { called = true }))
+	defer server.Close()
+
+	_, err := egress.Client(time.Second).Get(server.URL)
+	if !errors.Is(err, egress.ErrPrivateAddress) {
+		t.Errorf("err = %v, want ErrPrivateAddress", err)
+	}
+	if called {
+		t.Error("the loopback server was reached")
+	}
+}
+
+func TestClientDoesNotFollowRedirects(t *testing.T) {
+	client := egress.Client(time.Second)
+	req := httptest.NewRequest(http.MethodGet, "https://example.com/hook", nil)
+	via := []*http.Request{httptest.NewRequest(http.MethodGet, "https://example.com/", nil)}
+	if err := client.CheckRedirect(req, via); !errors.Is(err, http.ErrUseLastResponse) {
+		t.Errorf("CheckRedirect = %v, want ErrUseLastResponse", err)
+	}
+}
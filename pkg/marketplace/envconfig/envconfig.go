@@ -0,0 +1,92 @@
+// Package envconfig overrides a service's configuration from environment
+// variables. Each setting's variable is the service's prefix followed by its
+// YAML path upper-cased, with "_" between levels: with the prefix
+// "REGISTRY_", postgres.max_conns is overridden by REGISTRY_POSTGRES_MAX_CONNS.
+package envconfig
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Apply overrides every field of cfg, a pointer to a config struct, whose
+// variable is set and not empty. Strings are taken as is and string lists are
+// comma separated, e.g. REGISTRY_KAFKA_BROKERS=kafka-1:9092,kafka-2:9092;
+// anything else, including maps and lists of objects, is parsed as YAML.
+func Apply(cfg any, prefix string, getenv func(string) string) error {
+	return walk(reflect.ValueOf(cfg).Elem(), strings.TrimSuffix(prefix, "_"), func(name string, field reflect.Value) error {
+		value := getenv(name)
+		if value == "" {
+			return nil
+		}
+		if err := set(field, value); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		return nil
+	})
+}
+
+// Vars lists every environment variable that overrides a field of cfg, a
+// pointer to a config struct, in order
+func Vars(cfg any, prefix string) []string {
+	var names []string
+	walk(reflect.ValueOf(cfg).Elem(), strings.TrimSuffix(prefix, "_"), func(name string, _ reflect.Value) error {
+		names = append(names, name)
+		return nil
+	})
+	sort.Strings(names)
+	return names
+}
+
+// walk calls fn for each settable leaf of v, descending into nested config
+// structs
+func walk(v reflect.Value, prefix string, fn func(name string, field reflect.Value) error) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag, _, _ := strings.Cut(sf.Tag.Get("yaml"), ",")
+		if !sf.IsExported() || tag == "" || tag == "-" {
+			continue
+		}
+		name := prefix + "_" + strings.ToUpper(tag)
+		field := v.Field(i)
+		if field.Kind() == reflect.Struct {
+			if err := walk(field, name, fn); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := fn(name, field); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func set(field reflect.Value, value string) error {
+	switch {
+	case field.Kind() == reflect.String:
+		field.SetString(value)
+		return nil
+	case field.Kind() == reflect.Slice && field.Type().Elem().Kind() == reflect.String && !strings.HasPrefix(strings.TrimSpace(value), "["):
+		var items []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		field.Set(reflect.ValueOf(items).Convert(field.Type()))
+		return nil
+	}
+
+	parsed := reflect.New(field.Type())
+	if err := yaml.Unmarshal([]byte(value), parsed.Interface()); err != nil {
+		return err
+	}
+	field.Set(parsed.Elem())
+	return nil
+}
@@ -0,0 +1,66 @@
+package envconfig_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/org/llm-marketplace/pkg/marketplace/envconfig"
+)
+
+type testConfig struct {
+	Name     string   `yaml:"name"`
+	Brokers  []string `yaml:"brokers"`
+	Postgres struct {
+		MaxConns int           `yaml:"max_conns"`
+		Timeout  time.Duration `yaml:"timeout"`
+	} `yaml:"postgres"`
+	Weights  map[string]float64 `yaml:"weights,omitempty"`
+	Internal string             `yaml:"-"`
+	untagged string
+}
+
+func TestApply(t *testing.T) {
+	env := map[string]string{
+		"TEST_NAME":               "probe",
+		"TEST_BROKERS":            "kafka-1:9092, kafka-2:9092,",
+		"TEST_POSTGRES_MAX_CONNS": "20",
+		"TEST_POSTGRES_TIMEOUT":   "3s",
+		"TEST_WEIGHTS":            "{relevance: 0.5}",
+		"TEST_INTERNAL":           "ignored",
+	}
+	var cfg testConfig
+	cfg.Postgres.MaxConns = 5
+	if err := envconfig.Apply(&cfg, "TEST_", func(name string) string { return env[name] }); err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	if cfg.Name != "probe" || cfg.Postgres.MaxConns != 20 || cfg.Postgres.Timeout != 3*time.Second || cfg.Internal != "" {
+		t.Errorf("cfg = %+v", cfg)
+	}
+	if !reflect.DeepEqual(cfg.Brokers, []string{"kafka-1:9092", "kafka-2:9092"}) {
+		t.Errorf("brokers = %q", cfg.Brokers)
+	}
+	if cfg.Weights["relevance"] != 0.5 {
+		t.Errorf("weights = %v", cfg.Weights)
+	}
+
+	// Unset variables leave the setting alone; invalid ones are named
+	cfg.Postgres.MaxConns = 5
+	err := envconfig.Apply(&cfg, "TEST_", func(name string) string {
+		if name == "TEST_POSTGRES_MAX_CONNS" {
+			return "many"
+		}
+		return ""
+	})
+	if err == nil || !strings.Contains(err.Error(), "TEST_POSTGRES_MAX_CONNS") || cfg.Name != "probe" {
+		t.Errorf("Apply = %v, cfg %+v", err, cfg)
+	}
+}
+
+func TestVars(t *testing.T) {
+	want := []string{"TEST_BROKERS", "TEST_NAME", "TEST_POSTGRES_MAX_CONNS", "TEST_POSTGRES_TIMEOUT", "TEST_WEIGHTS"}
+	if vars := envconfig.Vars(&testConfig{}, "TEST_"); !reflect.DeepEqual(vars, want) {
+		t.Errorf("Vars = %q, want %q", vars, want)
+	}
+}
@@ -0,0 +1,74 @@
+package marketplace
+
+import "time"
+
+// CatalogTopic is the Kafka topic the registry publishes catalog events to
+const CatalogTopic = "marketplace.catalog.services"
+
+// Catalog event types
+const (
+	ServiceRegistered   = "service.registered"
+	ServiceUpdated      = "service.updated"
+	ServiceDeregistered = "service.deregistered"
+)
+
+// Service statuses, as stored by the registry and the discovery index
+const (
+	StatusActive     = "active"
+	StatusDeprecated = "deprecated"
+	StatusSuspended  = "suspended"
+	StatusRetired    = "retired"
+)
+
+// Statuses lists the statuses a registered service can have
+var Statuses = []string{StatusActive, StatusDeprecated, StatusSuspended, StatusRetired}
+
+// CatalogEvent records a change to a registered service. Each event carries
+// the whole descriptor as of Revision, so consumers can apply it without
+// reading the registry; an event with a lower revision than one already
+// applied is stale. Events are keyed by service ID, so a service's events are
+// delivered in order.
+type CatalogEvent struct {
+	ID         string            `json:"id"`
+	Type       string            `json:"type"`
+	OccurredAt time.Time         `json:"occurred_at"`
+	Revision   int64             `json:"revision"`
+	Status     string            `json:"status"`
+	Provider   ProviderInfo      `json:"provider"`
+	Service    ServiceDescriptor `json:"service"`
+	// UpheldReports counts the consumer reports about the service that were
+	// found at fault, by the policy engine or a marketplace operator
+	UpheldReports int `json:"upheld_reports,omitempty"`
+	// ComplianceExpiries are the service's certifications and
+	// data-processing agreement that are expiring or expired
+	ComplianceExpiries []ComplianceExpiry `json:"compliance_expiries,omitempty"`
+	// Changes are the changelog entries made at Revision
+	Changes []ServiceChange `json:"changes,omitempty"`
+}
+
+// ValidationTopic is the Kafka topic the registry publishes validation
+// events to
+const ValidationTopic = "marketplace.validation.events"
+
+// ValidationEvent records the outcome of checking a descriptor at
+// registration, update or a provider's dry run. ServiceID is empty for dry
+// runs and rejected registrations. Events are keyed by provider ID.
+type ValidationEvent struct {
+	ID            string            `json:"id"`
+	OccurredAt    time.Time         `json:"occurred_at"`
+	ProviderID    string            `json:"provider_id"`
+	ServiceID     string            `json:"service_id,omitempty"`
+	Name          string            `json:"name"`
+	Version       string            `json:"version"`
+	Valid         bool              `json:"valid"`
+	PolicyVersion string            `json:"policy_version,omitempty"`
+	Errors        []FieldError      `json:"errors,omitempty"`     // Descriptor fields that failed validation
+	Violations    []PolicyViolation `json:"violations,omitempty"` // Policies the descriptor broke
+}
+
+// PolicyViolation names a policy a descriptor broke
+type PolicyViolation struct {
+	PolicyID   string `json:"policy_id"`
+	PolicyName string `json:"policy_name"`
+	Severity   string `json:"severity"`
+}
@@ -0,0 +1,58 @@
+package marketplace
+
+import "time"
+
+// Kinds of compliance document whose expiry the registry tracks
+const (
+	ExpiryCertification           = "certification"
+	ExpiryDataProcessingAgreement = "data_processing_agreement"
+)
+
+// Expiry statuses of a compliance document
+const (
+	ExpiryExpiring = "expiring" // Lapses within the registry's warning period
+	ExpiryExpired  = "expired"
+)
+
+// ComplianceExpiry is a certification or data-processing agreement a service
+// declares that is about to lapse or has lapsed. A certification expires
+// with the last of its provider's artifacts for it; certifications without
+// artifacts aren't tracked.
+type ComplianceExpiry struct {
+	Kind      string    `json:"kind"`
+	Name      string    `json:"name"` // The certification, or the agreement's reference
+	Status    string    `json:"status"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// ExpiryStatus returns the status of a document expiring at expiresAt, as
+// of now: ExpiryExpired, ExpiryExpiring within warning of it, or "" while
+// it's current
+func ExpiryStatus(expiresAt, now time.Time, warning time.Duration) string {
+	switch {
+	case !expiresAt.After(now):
+		return ExpiryExpired
+	case !expiresAt.After(now.Add(warning)):
+		return ExpiryExpiring
+	}
+	return ""
+}
+
+// ComplianceExpiryTopic is the Kafka topic the registry publishes expiry
+// notices to, for the notification service to deliver
+const ComplianceExpiryTopic = "marketplace.compliance.expirations"
+
+// ComplianceExpiryNotice tells a provider, or the marketplace operators,
+// that a certification or data-processing agreement of a service started
+// expiring or expired. Each change of status sends one notice to the
+// provider and one to the operators' recipient. Notices are keyed by
+// recipient ID.
+type ComplianceExpiryNotice struct {
+	ID          string           `json:"id"`
+	OccurredAt  time.Time        `json:"occurred_at"`
+	RecipientID string           `json:"recipient_id"`
+	ProviderID  string           `json:"provider_id"`
+	ServiceID   string           `json:"service_id"`
+	ServiceName string           `json:"service_name"`
+	Expiry      ComplianceExpiry `json:"expiry"`
+}
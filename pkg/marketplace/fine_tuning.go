@@ -0,0 +1,75 @@
+package marketplace
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// FineTuningOffering is what a fine-tuning listing tells a consumer before
+// starting a job: the base models it tunes, the training data it accepts and
+// how soon a job finishes
+type FineTuningOffering struct {
+	// BaseModels are lower case so search filters match them exactly, e.g.
+	// llama-3.1-8b, mistral-7b
+	BaseModels       []string                    `json:"base_models"`
+	DataRequirements *FineTuningDataRequirements `json:"data_requirements,omitempty"`
+	// TurnaroundHours is the provider's SLA: the hours within which a job
+	// finishes once its training data is accepted
+	TurnaroundHours int32 `json:"turnaround_hours"`
+}
+
+// FineTuningDataRequirements is the training data a fine-tuning job accepts.
+// Zero bounds are unbounded.
+type FineTuningDataRequirements struct {
+	Formats      []string `json:"formats,omitempty"` // Lower case, e.g. jsonl, parquet
+	MinExamples  int64    `json:"min_examples,omitempty"`
+	MaxExamples  int64    `json:"max_examples,omitempty"`
+	MaxSizeBytes int64    `json:"max_size_bytes,omitempty"`
+}
+
+// Tunes reports whether the offering fine-tunes the base model
+func (o *FineTuningOffering) Tunes(model string) bool {
+	return slices.Contains(o.BaseModels, strings.ToLower(model))
+}
+
+// Validate checks the offering names its base models and turnaround, and that
+// its data requirements are consistent
+func (o *FineTuningOffering) Validate() error {
+	e := newErrs()
+	o.validate(e)
+	return e.err()
+}
+
+func (o *FineTuningOffering) validate(e errs) {
+	if len(o.BaseModels) == 0 {
+		e.add("base_models", "must list at least one base model")
+	}
+	for i, model := range o.BaseModels {
+		if !modelFamily.MatchString(model) {
+			e.add(fmt.Sprintf("base_models[%d]", i), "must be lower-case letters, digits, dots and hyphens")
+		}
+	}
+	if o.TurnaroundHours <= 0 {
+		e.add("turnaround_hours", "must be positive")
+	}
+	if r := o.DataRequirements; r != nil {
+		re := e.in("data_requirements")
+		for i, format := range r.Formats {
+			if !datasetFormat.MatchString(format) {
+				re.add(fmt.Sprintf("formats[%d]", i), "must be lower-case letters, digits, dots and hyphens")
+			}
+		}
+		if r.MinExamples < 0 {
+			re.add("min_examples", "must not be negative")
+		}
+		if r.MaxExamples < 0 {
+			re.add("max_examples", "must not be negative")
+		} else if r.MaxExamples > 0 && r.MaxExamples < r.MinExamples {
+			re.add("max_examples", "must be at least min_examples")
+		}
+		if r.MaxSizeBytes < 0 {
+			re.add("max_size_bytes", "must not be negative")
+		}
+	}
+}
@@ -0,0 +1,194 @@
+// Package flags evaluates the feature flags shared by the discovery service
+// and the policy engine. Flags are read from a Source on an interval, so they
+// can be flipped or rolled out without a redeploy.
+//
+// A flag is on for the tenants it turns on and off for those it turns off.
+// For anyone else it is on when enabled, or else for a stable percentage of
+// subjects: the same subject always lands in the same bucket, so raising the
+// percentage only adds subjects. A flag that isn't defined is on, so features
+// behind flags run as they did before the flag existed.
+package flags
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Flags gating discovery features
+const (
+	SemanticSearch   = "discovery.semantic_search"   // The semantic clause of search queries; rolled out by tenant, since results are cached per tenant
+	Personalization  = "discovery.personalization"   // Re-ranking search results and recommendations by stored user features
+	SessionReranking = "discovery.session_reranking" // Re-ranking search results by the session's clicks and dismissals
+	RegionRanking    = "discovery.region_ranking"    // Re-ranking search results by latency from the caller's region
+)
+
+// PolicyEngine returns the flag gating the evaluation of policies of
+// policyType, such as policy.engine.content_filtering for CONTENT_FILTERING
+func PolicyEngine(policyType string) string {
+	return "policy.engine." + strings.ToLower(policyType)
+}
+
+// Flag is one feature flag
+type Flag struct {
+	Enabled    bool            `json:"enabled" yaml:"enabled"`       // On for everyone the tenants don't decide
+	Percentage float64         `json:"percentage" yaml:"percentage"` // Share of other subjects, 0 to 100, the flag is on for
+	Tenants    map[string]bool `json:"tenants,omitempty" yaml:"tenants"`
+}
+
+// Set holds flags by name
+type Set map[string]Flag
+
+// Subject is who a flag is evaluated for. Percentage rollouts bucket by Key,
+// or by Tenant when Key is empty; a subject with neither is only covered by
+// an enabled flag.
+type Subject struct {
+	Tenant string
+	Key    string // A user, consumer or provider ID
+}
+
+// Validate reports the first flag with a percentage outside 0 to 100
+func (s Set) Validate() error {
+	for name, f := range s {
+		if f.Percentage < 0 || f.Percentage > 100 {
+			return fmt.Errorf("flag %s: percentage %v is not between 0 and 100", name, f.Percentage)
+		}
+	}
+	return nil
+}
+
+// Enabled reports whether the flag is on for subject
+func (s Set) Enabled(name string, subject Subject) bool {
+	f, ok := s[name]
+	if !ok {
+		return true
+	}
+	if on, ok := f.Tenants[subject.Tenant]; ok && subject.Tenant != "" {
+		return on
+	}
+	if f.Enabled {
+		return true
+	}
+	key := subject.Key
+	if key == "" {
+		key = subject.Tenant
+	}
+	return key != "" && bucket(name, key) < f.Percentage
+}
+
+// bucket places key in [0, 100) for the flag, independently per flag so a
+// subject early in one rollout isn't early in every rollout
+func bucket(name, key string) float64 {
+	h := fnv.New32a()
+	h.Write([]byte(name))
+	h.Write([]byte{0})
+	h.Write([]byte(key))
+	return float64(h.Sum32()%10000) / 100
+}
+
+// Source loads the current flags
+type Source interface {
+	Flags(ctx context.Context) (Set, error)
+}
+
+// Static is a Source of a fixed set, such as flags from a service's config
+type Static Set
+
+// Flags returns the set
+func (s Static) Flags(context.Context) (Set, error) {
+	return Set(s), nil
+}
+
+// File is a Source reading a JSON object of flags by name from a path,
+// re-read on every refresh
+type File string
+
+// Flags reads and decodes the file
+func (f File) Flags(context.Context) (Set, error) {
+	data, err := os.ReadFile(string(f))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read flags: %w", err)
+	}
+	var set Set
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("failed to decode flags from %s: %w", f, err)
+	}
+	return set, nil
+}
+
+// Evaluator answers flag checks from the last set loaded from its source.
+// A nil Evaluator has every flag on.
+type Evaluator struct {
+	source Source
+
+	mu  sync.RWMutex
+	set Set
+}
+
+// NewEvaluator creates an evaluator of source. Until the first refresh every
+// flag is on.
+func NewEvaluator(source Source) *Evaluator {
+	return &Evaluator{source: source}
+}
+
+// Enabled reports whether the flag is on for subject
+func (e *Evaluator) Enabled(name string, subject Subject) bool {
+	if e == nil {
+		return true
+	}
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.set.Enabled(name, subject)
+}
+
+// Flags returns the set in effect
+func (e *Evaluator) Flags() Set {
+	if e == nil {
+		return nil
+	}
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.set
+}
+
+// Refresh loads the flags from the source. A set that fails to load or
+// validate is returned as an error and the current one kept.
+func (e *Evaluator) Refresh(ctx context.Context) error {
+	set, err := e.source.Flags(ctx)
+	if err == nil {
+		err = set.Validate()
+	}
+	if err != nil {
+		return err
+	}
+	e.mu.Lock()
+	e.set = set
+	e.mu.Unlock()
+	return nil
+}
+
+// Run refreshes the flags every interval until ctx is cancelled, passing
+// each failure to onError
+func (e *Evaluator) Run(ctx context.Context, interval time.Duration, onError func(error)) {
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := e.Refresh(ctx); err != nil && onError != nil {
+				onError(err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package flags_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/org/llm-marketplace/pkg/marketplace/flags"
+)
+
+func TestFlagEvaluation(t *testing.T) {
+	set := flags.Set{
+		"off":     {},
+		"on":      {Enabled: true, Tenants: map[string]bool{"beta": false}},
+		"half":    {Percentage: 50, Tenants: map[string]bool{"acme": true}},
+		"nothing": {Percentage: 0},
+	}
+	for _, tt := range []struct {
+		name    string
+		subject flags.Subject
+		want    bool
+	}{
+		{"undefined", flags.Subject{}, true},
+		{"off", flags.Subject{Tenant: "acme", Key: "u1"}, false},
+		{"on", flags.Subject{}, true},
+		{"on", flags.Subject{Tenant: "beta"}, false},
+		{"half", flags.Subject{Tenant: "acme"}, true},
+		{"half", flags.Subject{}, false},
+		{"nothing", flags.Subject{Key: "u1"}, false},
+	} {
+		if got := set.Enabled(tt.name, tt.subject); got != tt.want {
+			t.Errorf("Enabled(%s, %+v) = %v, want %v", tt.name, tt.subject, got, tt.want)
+		}
+	}
+}
+
+func TestPercentageRolloutIsStable(t *testing.T) {
+	on := func(pct float64) map[string]bool {
+		set := flags.Set{"rollout": {Percentage: pct}}
+		subjects := map[string]bool{}
+		for i := 0; i < 2000; i++ {
+			key := fmt.Sprintf("user-%d", i)
+			if set.Enabled("rollout", flags.Subject{Key: key}) {
+				subjects[key] = true
+			}
+		}
+		return subjects
+	}
+
+	quarter, half := on(25), on(50)
+	if n := len(quarter); n < 400 || n > 600 {
+		t.Errorf("25%% rollout covers %d of 2000 subjects", n)
+	}
+	for key := range quarter {
+		if !half[key] {
+			t.Fatalf("%s is in the 25%% rollout but not the 50%% one", key)
+		}
+	}
+	if len(on(100)) != 2000 {
+		t.Error("100% rollout left subjects out")
+	}
+}
+
+func TestEvaluatorKeepsLastGoodFlags(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "flags.json")
+	write := func(data string) {
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	e := flags.NewEvaluator(flags.File(path))
+	ctx := context.Background()
+
+	if !e.Enabled(flags.SemanticSearch, flags.Subject{}) {
+		t.Error("flag off before the first refresh")
+	}
+	write(`{"discovery.semantic_search": {"enabled": false}}`)
+	if err := e.Refresh(ctx); err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+	if e.Enabled(flags.SemanticSearch, flags.Subject{}) {
+		t.Error("flag on after the file turned it off")
+	}
+
+	for _, bad := range []string{`{"x": {"percentage": 101}}`, `not json`} {
+		write(bad)
+		if err := e.Refresh(ctx); err == nil {
+			t.Errorf("Refresh accepted %s", bad)
+		}
+		if e.Enabled(flags.SemanticSearch, flags.Subject{}) {
+			t.Errorf("flags replaced by invalid %s", bad)
+		}
+	}
+
+	var nilEvaluator *flags.Evaluator
+	if !nilEvaluator.Enabled(flags.Personalization, flags.Subject{}) {
+		t.Error("nil evaluator has a flag off")
+	}
+}
+
+func TestPolicyEngineFlag(t *testing.T) {
+	if got := flags.PolicyEngine("CONTENT_FILTERING"); got != "policy.engine.content_filtering" {
+		t.Errorf("PolicyEngine = %s", got)
+	}
+}
@@ -0,0 +1,43 @@
+package marketplace
+
+import "encoding/json"
+
+// UnmarshalJSON also accepts the protobuf field names max_latency and
+// support_level, as written by services that encode the policy engine's
+// messages
+func (s *SLAInfo) UnmarshalJSON(data []byte) error {
+	type plain SLAInfo
+	var in struct {
+		plain
+		MaxLatency   *int    `json:"max_latency"`
+		SupportLevel *string `json:"support_level"`
+	}
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+	*s = SLAInfo(in.plain)
+	if in.MaxLatency != nil && s.MaxLatencyMS == 0 {
+		s.MaxLatencyMS = *in.MaxLatency
+	}
+	if in.SupportLevel != nil && s.SupportLevel == "" {
+		s.SupportLevel = *in.SupportLevel
+	}
+	return nil
+}
+
+// UnmarshalJSON also accepts tiers under the protobuf field name rates
+func (p *PricingInfo) UnmarshalJSON(data []byte) error {
+	type plain PricingInfo
+	var in struct {
+		plain
+		Rates []PricingTier `json:"rates"`
+	}
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+	*p = PricingInfo(in.plain)
+	if len(p.Tiers) == 0 {
+		p.Tiers = in.Rates
+	}
+	return nil
+}
@@ -0,0 +1,574 @@
+package marketplace_test
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/org/llm-marketplace/pkg/marketplace"
+)
+
+func TestValidateDescriptor(t *testing.T) {
+	valid := marketplace.ServiceDescriptor{
+		ServiceID:  "svc-1",
+		Name:       "Summarizer",
+		Endpoint:   &marketplace.EndpointInfo{URL: "https://api.example.com/v1", Authentication: "api_key"},
+		Compliance: &marketplace.ComplianceInfo{Level: "confidential", Certifications: []string{"SOC2"}, DataResidency: []string{"US", "EU"}},
+		SLA:        &marketplace.SLAInfo{Availability: 99.9, MaxLatencyMS: 500, SupportLevel: "enterprise"},
+		Pricing:    &marketplace.PricingInfo{Model: "per-token", Rate: 0.002, Unit: "1k tokens", Currency: "USD"},
+	}
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+
+	invalid := valid
+	invalid.Name = ""
+	invalid.Tags = []string{"nlp", ""}
+	invalid.Endpoint = &marketplace.EndpointInfo{URL: "api.example.com"}
+	invalid.Compliance = &marketplace.ComplianceInfo{Level: "secret", DataResidency: []string{" "}}
+	invalid.SLA = &marketplace.SLAInfo{Availability: 999}
+	invalid.Pricing = &marketplace.PricingInfo{Currency: "usd", Tiers: []marketplace.PricingTier{{Rate: -1}}}
+
+	var verr marketplace.ValidationError
+	if err := invalid.Validate(); !errors.As(err, &verr) {
+		t.Fatalf("Validate = %v, want a ValidationError", err)
+	}
+	var fields []string
+	for _, f := range verr {
+		fields = append(fields, f.Field)
+	}
+	want := []string{"name", "tags[1]", "endpoint.url", "compliance.level", "compliance.data_residency[0]", "sla.availability", "pricing.currency", "pricing.tiers[0].rate"}
+	if !reflect.DeepEqual(fields, want) {
+		t.Errorf("invalid fields = %v, want %v", fields, want)
+	}
+}
+
+func TestValidatePrivateClass(t *testing.T) {
+	private := marketplace.ServiceDescriptor{ServiceID: "svc-1", Name: "Internal LLM", Class: marketplace.ClassPrivate, TenantID: "acme"}
+	if err := private.Validate(); err != nil || !private.Private() {
+		t.Fatalf("Validate = %v, Private = %v", err, private.Private())
+	}
+
+	for _, tc := range []struct {
+		class, tenant, field string
+	}{
+		{marketplace.ClassPrivate, "", "tenant_id"},
+		{"", "acme", "tenant_id"},
+		{marketplace.ClassPublic, "acme", "tenant_id"},
+		{"internal", "", "class"},
+	} {
+		desc := marketplace.ServiceDescriptor{ServiceID: "svc-1", Name: "Internal LLM", Class: tc.class, TenantID: tc.tenant}
+		var verr marketplace.ValidationError
+		if err := desc.Validate(); !errors.As(err, &verr) || len(verr) != 1 || verr[0].Field != tc.field {
+			t.Errorf("class %q, tenant %q: Validate = %v, want an error on %s", tc.class, tc.tenant, err, tc.field)
+		}
+	}
+}
+
+func TestValidateDependencies(t *testing.T) {
+	desc := marketplace.ServiceDescriptor{ServiceID: "rag", Name: "RAG", Dependencies: []marketplace.Dependency{
+		{ServiceID: "embedder", Role: "embeddings"},
+		{ServiceID: "vectors", Role: "vector_store"},
+	}}
+	if err := desc.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+
+	desc.Dependencies = append(desc.Dependencies, marketplace.Dependency{}, marketplace.Dependency{ServiceID: "rag"}, marketplace.Dependency{ServiceID: "vectors"})
+	var verr marketplace.ValidationError
+	if err := desc.Validate(); !errors.As(err, &verr) {
+		t.Fatalf("Validate = %v, want a ValidationError", err)
+	}
+	var got []string
+	for _, f := range verr {
+		got = append(got, f.Field+": "+f.Message)
+	}
+	want := []string{
+		"dependencies[2].service_id: is required",
+		"dependencies[3].service_id: is the service itself",
+		"dependencies[4].service_id: is listed twice",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("errors = %v, want %v", got, want)
+	}
+}
+
+func TestValidatePromptTemplate(t *testing.T) {
+	desc := marketplace.ServiceDescriptor{
+		ServiceID: "tpl-1",
+		Name:      "Support reply",
+		Kind:      marketplace.KindPromptTemplate,
+		PromptTemplate: &marketplace.PromptTemplate{
+			Template: "Reply to {{ customer }} about {{issue}} in a {{tone}} tone. {{customer}} is waiting.",
+			Variables: []marketplace.TemplateVariable{
+				{Name: "customer", Required: true},
+				{Name: "issue", Required: true},
+				{Name: "tone", Default: "friendly"},
+			},
+			ModelFamilies: []string{"gpt-4", "claude-3"},
+		},
+	}
+	if err := desc.Validate(); err != nil || !desc.IsPromptTemplate() {
+		t.Fatalf("Validate = %v, IsPromptTemplate = %v", err, desc.IsPromptTemplate())
+	}
+	if got := desc.PromptTemplate.Placeholders(); !reflect.DeepEqual(got, []string{"customer", "issue", "tone"}) {
+		t.Errorf("Placeholders = %v", got)
+	}
+	if !desc.PromptTemplate.Supports("Claude-3") || desc.PromptTemplate.Supports("llama-3") {
+		t.Error("Supports doesn't match the template's model families")
+	}
+
+	desc.Endpoint = &marketplace.EndpointInfo{URL: "https://api.example.com"}
+	desc.PromptTemplate = &marketplace.PromptTemplate{
+		Template: "Summarise {{text}} in {{ language }}",
+		Variables: []marketplace.TemplateVariable{
+			{Name: "text", Required: true, Default: "-"},
+			{Name: "1st"},
+			{Name: "text"},
+			{Name: "unused"},
+		},
+		ModelFamilies: []string{"GPT-4", "llama-3", "llama-3"},
+	}
+	var verr marketplace.ValidationError
+	if err := desc.Validate(); !errors.As(err, &verr) {
+		t.Fatalf("Validate = %v, want a ValidationError", err)
+	}
+	var got []string
+	for _, f := range verr {
+		got = append(got, f.Field+": "+f.Message)
+	}
+	want := []string{
+		"prompt_template.variables[0].default: is not used by a required variable",
+		"prompt_template.variables[1].name: must be letters, digits and underscores, not starting with a digit",
+		"prompt_template.variables[2].name: is declared twice",
+		"prompt_template.template: uses undeclared variable {{language}}",
+		"prompt_template.variables[1].name: is not used by the template",
+		"prompt_template.variables[3].name: is not used by the template",
+		"prompt_template.model_families[0]: must be lower-case letters, digits, dots and hyphens",
+		"prompt_template.model_families[2]: is listed twice",
+		"endpoint: is not set on prompt templates",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("errors = %v, want %v", got, want)
+	}
+
+	for _, tc := range []struct {
+		desc  marketplace.ServiceDescriptor
+		field string
+	}{
+		{marketplace.ServiceDescriptor{Kind: marketplace.KindPromptTemplate}, "prompt_template"},
+		{marketplace.ServiceDescriptor{PromptTemplate: &marketplace.PromptTemplate{}}, "prompt_template"},
+		{marketplace.ServiceDescriptor{Kind: "notebook"}, "kind"},
+	} {
+		tc.desc.ServiceID, tc.desc.Name = "tpl-1", "Template"
+		if err := tc.desc.Validate(); !errors.As(err, &verr) || len(verr) != 1 || verr[0].Field != tc.field {
+			t.Errorf("kind %q: Validate = %v, want an error on %s", tc.desc.Kind, err, tc.field)
+		}
+	}
+}
+
+func TestValidateDataset(t *testing.T) {
+	desc := marketplace.ServiceDescriptor{
+		ServiceID: "ds-1",
+		Name:      "Support transcripts",
+		Kind:      marketplace.KindDataset,
+		Dataset: &marketplace.DatasetInfo{
+			License:     "CC-BY-4.0",
+			Attribution: "Acme Corp. Support Transcripts (2025)",
+			Format:      "jsonl",
+			SizeBytes:   2 << 30,
+			Records:     120000,
+			Provenance:  &marketplace.DatasetProvenance{Source: "Acme support desk", Method: marketplace.ProvenanceCollected},
+		},
+	}
+	if err := desc.Validate(); err != nil || !desc.IsDataset() || desc.ItemKind() != marketplace.KindDataset {
+		t.Fatalf("Validate = %v, IsDataset = %v", err, desc.IsDataset())
+	}
+
+	desc.Dataset = &marketplace.DatasetInfo{
+		License:    "CC BY 4.0",
+		Format:     "JSONL",
+		Records:    -1,
+		Provenance: &marketplace.DatasetProvenance{Method: "scraped", URL: "datasheet.pdf"},
+	}
+	desc.PromptTemplate = &marketplace.PromptTemplate{}
+	var verr marketplace.ValidationError
+	if err := desc.Validate(); !errors.As(err, &verr) {
+		t.Fatalf("Validate = %v, want a ValidationError", err)
+	}
+	var got []string
+	for _, f := range verr {
+		got = append(got, f.Field)
+	}
+	want := []string{
+		"prompt_template",
+		"dataset.license",
+		"dataset.format",
+		"dataset.size_bytes",
+		"dataset.records",
+		"dataset.provenance.source",
+		"dataset.provenance.method",
+		"dataset.provenance.url",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("invalid fields = %v, want %v", got, want)
+	}
+
+	for _, tc := range []struct {
+		desc  marketplace.ServiceDescriptor
+		field string
+	}{
+		{marketplace.ServiceDescriptor{Kind: marketplace.KindDataset}, "dataset"},
+		{marketplace.ServiceDescriptor{Dataset: &marketplace.DatasetInfo{License: "MIT", SizeBytes: 1}}, "dataset"},
+	} {
+		tc.desc.ServiceID, tc.desc.Name = "ds-1", "Dataset"
+		if err := tc.desc.Validate(); !errors.As(err, &verr) || len(verr) != 1 || verr[0].Field != tc.field {
+			t.Errorf("kind %q: Validate = %v, want an error on %s", tc.desc.Kind, err, tc.field)
+		}
+	}
+}
+
+func TestComplianceRank(t *testing.T) {
+	if marketplace.ComplianceRank("public") >= marketplace.ComplianceRank("restricted") {
+		t.Error("public ranks at or above restricted")
+	}
+	if marketplace.ComplianceRank("unknown") != -1 {
+		t.Error("unknown level has a rank")
+	}
+}
+
+func TestComplianceArtifacts(t *testing.T) {
+	now := time.Now()
+	artifact := marketplace.ComplianceArtifact{Certification: "SOC2", SHA256: "ab12", ExpiresAt: now.Add(time.Hour)}
+	if !artifact.Current(now) || artifact.Current(now.Add(2*time.Hour)) {
+		t.Error("artifact is current after it expires or not before")
+	}
+
+	// Artifacts are attached for validation only, never published
+	info := marketplace.ComplianceInfo{Level: "internal", Certifications: []string{"SOC2"}, Artifacts: []marketplace.ComplianceArtifact{artifact}}
+	data, err := json.Marshal(info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded map[string]interface{}
+	json.Unmarshal(data, &decoded)
+	if _, ok := decoded["artifacts"]; ok {
+		t.Errorf("compliance JSON = %s, want no artifacts", data)
+	}
+}
+
+func TestComplianceExpiry(t *testing.T) {
+	now := time.Now()
+	warning := 30 * 24 * time.Hour
+	for _, tt := range []struct {
+		expiresAt time.Time
+		want      string
+	}{
+		{now.Add(60 * 24 * time.Hour), ""},
+		{now.Add(10 * 24 * time.Hour), marketplace.ExpiryExpiring},
+		{now, marketplace.ExpiryExpired},
+		{now.Add(-time.Hour), marketplace.ExpiryExpired},
+	} {
+		if got := marketplace.ExpiryStatus(tt.expiresAt, now, warning); got != tt.want {
+			t.Errorf("status of expiry at %v = %q, want %q", tt.expiresAt.Sub(now), got, tt.want)
+		}
+	}
+
+	info := marketplace.ComplianceInfo{Level: "internal", DataProcessingAgreement: &marketplace.DataProcessingAgreement{Reference: "DPA-2026-01"}}
+	err := info.Validate()
+	if verr, ok := err.(marketplace.ValidationError); !ok || len(verr) != 1 || verr[0].Field != "data_processing_agreement.expires_at" {
+		t.Errorf("DPA without expiry = %v", err)
+	}
+	info.DataProcessingAgreement.ExpiresAt = now.AddDate(1, 0, 0)
+	if err := info.Validate(); err != nil {
+		t.Errorf("DPA with expiry = %v", err)
+	}
+}
+
+func TestJSONAcceptsProtobufFieldNames(t *testing.T) {
+	var sla marketplace.SLAInfo
+	if err := json.Unmarshal([]byte(`{"availability": 99.5, "max_latency": 300, "support_level": "premium"}`), &sla); err != nil {
+		t.Fatal(err)
+	}
+	if sla != (marketplace.SLAInfo{Availability: 99.5, MaxLatencyMS: 300, SupportLevel: "premium"}) {
+		t.Errorf("SLA = %+v", sla)
+	}
+
+	var pricing marketplace.PricingInfo
+	if err := json.Unmarshal([]byte(`{"model": "tiered", "rates": [{"tier": "free", "rate": 0}]}`), &pricing); err != nil {
+		t.Fatal(err)
+	}
+	if len(pricing.Tiers) != 1 || pricing.Tiers[0].Tier != "free" {
+		t.Errorf("pricing tiers = %+v, want the rates", pricing.Tiers)
+	}
+
+	// The stored names round-trip
+	data, _ := json.Marshal(marketplace.SLAInfo{Availability: 99, MaxLatencyMS: 100, SupportLevel: "basic"})
+	if string(data) != `{"availability":99,"max_latency_ms":100,"support":"basic"}` {
+		t.Errorf("SLA JSON = %s", data)
+	}
+}
+
+// Stand-ins for the policy engine's generated messages
+type tier struct{ name string }
+
+func (t *tier) GetTier() string        { return t.name }
+func (t *tier) GetRate() float64       { return 0.5 }
+func (t *tier) GetUnit() string        { return "request" }
+func (t *tier) GetDescription() string { return "" }
+
+type pricing struct{ tiers []*tier }
+
+func (p *pricing) GetModel() string    { return "tiered" }
+func (p *pricing) GetCurrency() string { return "EUR" }
+func (p *pricing) GetRates() []*tier   { return p.tiers }
+
+type sla struct{}
+
+func (sla) GetAvailability() float64 { return 99.9 }
+func (sla) GetMaxLatency() int32     { return 250 }
+func (sla) GetSupportLevel() string  { return "enterprise" }
+
+func TestFromProto(t *testing.T) {
+	p := marketplace.PricingFromProto[*tier](&pricing{tiers: []*tier{{"standard"}, {"bulk"}}})
+	if p.Model != "tiered" || p.Currency != "EUR" || len(p.Tiers) != 2 || p.Rate != 0.5 || p.Unit != "request" {
+		t.Errorf("pricing = %+v", p)
+	}
+	if s := marketplace.SLAFromProto(sla{}); *s != (marketplace.SLAInfo{Availability: 99.9, MaxLatencyMS: 250, SupportLevel: "enterprise"}) {
+		t.Errorf("SLA = %+v", s)
+	}
+}
+
+func TestTokensPerUnit(t *testing.T) {
+	for unit, want := range map[string]float64{
+		"token":       1,
+		"1k tokens":   1000,
+		"1K Tokens":   1000,
+		"1000 tokens": 1000,
+		"1m tokens":   1e6,
+		"0.5k tokens": 500,
+		"request":     0,
+		"":            0,
+		"1k requests": 0,
+		"many tokens": 0,
+	} {
+		got, ok := marketplace.TokensPerUnit(unit)
+		if ok != (want > 0) || got != want {
+			t.Errorf("TokensPerUnit(%q) = %v, %v; want %v", unit, got, ok, want)
+		}
+	}
+
+	for unit, want := range map[string]float64{"request": 1, "calls": 1, "1k requests": 1000, "1k tokens": 0} {
+		got, ok := marketplace.RequestsPerUnit(unit)
+		if ok != (want > 0) || got != want {
+			t.Errorf("RequestsPerUnit(%q) = %v, %v; want %v", unit, got, ok, want)
+		}
+	}
+
+	pricing := marketplace.PricingInfo{Model: "per-token", Rate: 0.002, Unit: "1k tokens"}
+	if price, ok := pricing.TokenPrice(); !ok || price != 0.000002 {
+		t.Errorf("TokenPrice() = %v, %v; want 0.000002", price, ok)
+	}
+}
+
+func TestValidateGraduatedTiers(t *testing.T) {
+	valid := marketplace.PricingInfo{Model: "tiered", Tiers: []marketplace.PricingTier{
+		{Tier: "free", Rate: 0, Unit: "1k tokens", UpTo: 100},
+		{Tier: "standard", Rate: 0.002, Unit: "1k tokens", UpTo: 10000},
+		{Tier: "volume", Rate: 0.001, Unit: "1k tokens"},
+	}}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("Validate = %v", err)
+	}
+
+	invalid := marketplace.PricingInfo{Model: "tiered", Tiers: []marketplace.PricingTier{
+		{Rate: 0, Unit: "request"},
+		{Rate: 0.01, Unit: "request", UpTo: 100},
+		{Rate: 0.01, Unit: "request", UpTo: 50},
+		{Rate: 0.005, Unit: "request"},
+	}}
+	var verr marketplace.ValidationError
+	if err := invalid.Validate(); !errors.As(err, &verr) {
+		t.Fatalf("Validate = %v, want a ValidationError", err)
+	}
+	var fields []string
+	for _, f := range verr {
+		fields = append(fields, f.Field)
+	}
+	if want := []string{"tiers[0].up_to", "tiers[2].up_to"}; !reflect.DeepEqual(fields, want) {
+		t.Errorf("invalid fields = %v, want %v", fields, want)
+	}
+}
+
+func TestSubscriptionStatus(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(1, 0, 0)
+	sub := marketplace.Subscription{ConsumerID: "org-1", ServiceID: "svc-1", StartsAt: start, EndsAt: &end}
+
+	for at, want := range map[time.Time]string{
+		start.Add(-time.Second): marketplace.SubscriptionPending,
+		start:                   marketplace.SubscriptionActive,
+		end.Add(-time.Second):   marketplace.SubscriptionActive,
+		end:                     marketplace.SubscriptionExpired,
+	} {
+		if got := sub.StatusAt(at); got != want {
+			t.Errorf("StatusAt(%s) = %s, want %s", at, got, want)
+		}
+	}
+
+	cancelled := start.AddDate(0, 6, 0)
+	sub.CancelledAt = &cancelled
+	if sub.ActiveAt(cancelled) || !sub.ActiveAt(cancelled.Add(-time.Second)) {
+		t.Error("a subscription must be in force until it is cancelled")
+	}
+
+	invalid := marketplace.Subscription{ServiceID: "svc-1", CommittedSpend: -1, StartsAt: end, EndsAt: &start, SLA: &marketplace.SLAInfo{Availability: 101}}
+	var verr marketplace.ValidationError
+	if err := invalid.Validate(); !errors.As(err, &verr) {
+		t.Fatalf("Validate = %v, want a ValidationError", err)
+	}
+	var fields []string
+	for _, f := range verr {
+		fields = append(fields, f.Field)
+	}
+	if want := []string{"consumer_id", "committed_spend", "ends_at", "sla.availability"}; !reflect.DeepEqual(fields, want) {
+		t.Errorf("invalid fields = %v, want %v", fields, want)
+	}
+}
+
+func TestChanges(t *testing.T) {
+	old := &marketplace.ServiceDescriptor{
+		Pricing:      &marketplace.PricingInfo{Model: "per-token", Rate: 0.002, Unit: "1k tokens"},
+		SLA:          &marketplace.SLAInfo{Availability: 99.9, MaxLatencyMS: 800},
+		Capabilities: []marketplace.Capability{{Name: "summarization"}, {Name: "translation"}},
+	}
+	same := *old
+	same.Description = "Only the description changed"
+	if changes := marketplace.Changes(old, &same); len(changes) != 0 {
+		t.Errorf("Changes = %+v, want none", changes)
+	}
+
+	changed := *old
+	changed.Pricing = &marketplace.PricingInfo{Model: "per-token", Rate: 0.003, Unit: "1k tokens", Currency: "EUR"}
+	changed.SLA = nil
+	changed.Capabilities = []marketplace.Capability{{Name: "summarization"}, {Name: "classification"}}
+	changes := marketplace.Changes(old, &changed)
+	var kinds []string
+	for _, c := range changes {
+		kinds = append(kinds, c.Kind)
+	}
+	if want := []string{marketplace.ChangePricing, marketplace.ChangeSLA, marketplace.ChangeCapabilities}; !reflect.DeepEqual(kinds, want) {
+		t.Fatalf("kinds = %v, want %v", kinds, want)
+	}
+	if want := "Pricing changed from 0.002 USD per 1k tokens (per-token) to 0.003 EUR per 1k tokens (per-token)"; changes[0].Summary != want {
+		t.Errorf("summary = %q, want %q", changes[0].Summary, want)
+	}
+	if changes[1].OldSLA == nil || changes[1].NewSLA != nil {
+		t.Errorf("SLA change = %+v, want the old SLA and no new one", changes[1])
+	}
+	if c := changes[2]; !reflect.DeepEqual(c.Added, []string{"classification"}) || !reflect.DeepEqual(c.Removed, []string{"translation"}) {
+		t.Errorf("capability change = %+v", c)
+	}
+}
+
+func TestValidateFineTuning(t *testing.T) {
+	desc := marketplace.ServiceDescriptor{
+		ServiceID: "ft-1",
+		Name:      "Llama fine-tuning",
+		Kind:      marketplace.KindFineTuning,
+		Endpoint:  &marketplace.EndpointInfo{URL: "https://tune.example.com/v1/jobs"},
+		FineTuning: &marketplace.FineTuningOffering{
+			BaseModels:       []string{"llama-3.1-8b", "mistral-7b"},
+			DataRequirements: &marketplace.FineTuningDataRequirements{Formats: []string{"jsonl"}, MinExamples: 100, MaxExamples: 100000},
+			TurnaroundHours:  24,
+		},
+	}
+	if err := desc.Validate(); err != nil || !desc.IsFineTuning() || !desc.FineTuning.Tunes("Llama-3.1-8B") {
+		t.Fatalf("Validate = %v, IsFineTuning = %v", err, desc.IsFineTuning())
+	}
+
+	desc.FineTuning = &marketplace.FineTuningOffering{
+		BaseModels:       []string{"Llama 3"},
+		DataRequirements: &marketplace.FineTuningDataRequirements{Formats: []string{"JSONL"}, MinExamples: 500, MaxExamples: 100, MaxSizeBytes: -1},
+	}
+	desc.Dataset = &marketplace.DatasetInfo{License: "MIT", SizeBytes: 1}
+	var verr marketplace.ValidationError
+	if err := desc.Validate(); !errors.As(err, &verr) {
+		t.Fatalf("Validate = %v, want a ValidationError", err)
+	}
+	var got []string
+	for _, f := range verr {
+		got = append(got, f.Field)
+	}
+	want := []string{
+		"dataset",
+		"fine_tuning.base_models[0]",
+		"fine_tuning.turnaround_hours",
+		"fine_tuning.data_requirements.formats[0]",
+		"fine_tuning.data_requirements.max_examples",
+		"fine_tuning.data_requirements.max_size_bytes",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("invalid fields = %v, want %v", got, want)
+	}
+
+	for _, tc := range []struct {
+		desc  marketplace.ServiceDescriptor
+		field string
+	}{
+		{marketplace.ServiceDescriptor{Kind: marketplace.KindFineTuning}, "fine_tuning"},
+		{marketplace.ServiceDescriptor{FineTuning: &marketplace.FineTuningOffering{BaseModels: []string{"mistral-7b"}, TurnaroundHours: 1}}, "fine_tuning"},
+		{marketplace.ServiceDescriptor{Kind: marketplace.KindFineTuning, FineTuning: &marketplace.FineTuningOffering{TurnaroundHours: 1}}, "fine_tuning.base_models"},
+	} {
+		tc.desc.ServiceID, tc.desc.Name = "ft-1", "Fine-tuning"
+		if err := tc.desc.Validate(); !errors.As(err, &verr) || len(verr) != 1 || verr[0].Field != tc.field {
+			t.Errorf("kind %q: Validate = %v, want an error on %s", tc.desc.Kind, err, tc.field)
+		}
+	}
+}
+
+func TestValidateServingRegions(t *testing.T) {
+	desc := marketplace.ServiceDescriptor{
+		ServiceID: "svc-1",
+		Name:      "Chat",
+		ServingRegions: []marketplace.ServingRegion{
+			{Region: "us-east-1", MedianLatencyMS: 40},
+			{Region: "eu-west-1", MedianLatencyMS: 65},
+		},
+	}
+	if err := desc.Validate(); err != nil {
+		t.Fatalf("Validate = %v", err)
+	}
+	if ms, ok := desc.RegionLatency("EU-WEST-1"); !ok || ms != 65 {
+		t.Errorf("RegionLatency(eu-west-1) = %d, %v; want 65, true", ms, ok)
+	}
+	if _, ok := desc.RegionLatency("ap-south-1"); ok {
+		t.Error("RegionLatency(ap-south-1) found a region the service isn't served from")
+	}
+
+	desc.ServingRegions = []marketplace.ServingRegion{
+		{Region: "US East", MedianLatencyMS: 40},
+		{Region: "eu-west-1"},
+		{Region: "eu-west-1", MedianLatencyMS: 70},
+		{MedianLatencyMS: 10},
+	}
+	var verr marketplace.ValidationError
+	if err := desc.Validate(); !errors.As(err, &verr) {
+		t.Fatalf("Validate = %v, want a ValidationError", err)
+	}
+	var got []string
+	for _, f := range verr {
+		got = append(got, f.Field)
+	}
+	want := []string{
+		"serving_regions[0].region",
+		"serving_regions[1].median_latency_ms",
+		"serving_regions[2].region",
+		"serving_regions[3].region",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("invalid fields = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,249 @@
+// Package migrate applies a service's database migrations. Migrations are SQL
+// files, usually embedded in the service's binary, applied in version order
+// and recorded in schema_migrations with a checksum of the file that was
+// applied.
+package migrate
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+// ErrDrift is returned when the database schema does not match the migrations
+// in this build
+var ErrDrift = errors.New("database schema drift")
+
+// Migration is one schema change, read from <dir>/<version>_<name>.sql
+type Migration struct {
+	Version  int
+	Name     string
+	SQL      string
+	Checksum string
+}
+
+// AppliedMigration is a row of schema_migrations
+type AppliedMigration struct {
+	Version   int
+	Name      string
+	Checksum  string
+	AppliedAt time.Time
+}
+
+// Status compares the database with the migrations in this build
+type Status struct {
+	Applied []AppliedMigration
+	Pending []Migration
+	// Drift describes applied migrations that differ from this build: edited
+	// after they were applied, or unknown because the database is ahead
+	Drift []string
+}
+
+// Current reports whether the schema is exactly what this build expects
+func (s *Status) Current() bool {
+	return len(s.Pending) == 0 && len(s.Drift) == 0
+}
+
+// Err returns ErrDrift describing every difference, or nil when current
+func (s *Status) Err() error {
+	if s.Current() {
+		return nil
+	}
+	problems := append([]string{}, s.Drift...)
+	for _, m := range s.Pending {
+		problems = append(problems, fmt.Sprintf("migration %d (%s) is not applied", m.Version, m.Name))
+	}
+	return fmt.Errorf("%w: %s", ErrDrift, strings.Join(problems, "; "))
+}
+
+// Load returns the migrations in dir of fsys in version order
+func Load(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	migrations := make([]Migration, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() || path.Ext(entry.Name()) != ".sql" {
+			continue
+		}
+		base := strings.TrimSuffix(entry.Name(), ".sql")
+		prefix, name, ok := strings.Cut(base, "_")
+		version, err := strconv.Atoi(prefix)
+		if !ok || err != nil || version <= 0 {
+			return nil, fmt.Errorf("migration %s is not named <version>_<name>.sql", entry.Name())
+		}
+
+		data, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		sum := sha256.Sum256(data)
+		migrations = append(migrations, Migration{
+			Version:  version,
+			Name:     name,
+			SQL:      string(data),
+			Checksum: hex.EncodeToString(sum[:]),
+		})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	for i := 1; i < len(migrations); i++ {
+		if migrations[i].Version == migrations[i-1].Version {
+			return nil, fmt.Errorf("duplicate migration version %d", migrations[i].Version)
+		}
+	}
+	return migrations, nil
+}
+
+// Migrator applies a service's migrations to its database
+type Migrator struct {
+	pool       *pgxpool.Pool
+	migrations []Migration
+	lockID     int64
+	logger     *zap.Logger
+}
+
+// New creates a migrator for the migrations in dir of fsys. lockID names the
+// advisory lock serializing replicas that migrate at the same time; each
+// service has its own.
+func New(pool *pgxpool.Pool, fsys fs.FS, dir string, lockID int64, logger *zap.Logger) (*Migrator, error) {
+	migrations, err := Load(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load migrations: %w", err)
+	}
+	return &Migrator{pool: pool, migrations: migrations, lockID: lockID, logger: logger}, nil
+}
+
+// Status reads schema_migrations without changing anything; a database that
+// was never migrated has every migration pending
+func (m *Migrator) Status(ctx context.Context) (*Status, error) {
+	var exists bool
+	if err := m.pool.QueryRow(ctx, `SELECT to_regclass('schema_migrations') IS NOT NULL`).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("failed to look up schema_migrations: %w", err)
+	}
+
+	var applied []AppliedMigration
+	if exists {
+		rows, err := m.pool.Query(ctx, `SELECT version, name, checksum, applied_at FROM schema_migrations ORDER BY version`)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var a AppliedMigration
+			if err := rows.Scan(&a.Version, &a.Name, &a.Checksum, &a.AppliedAt); err != nil {
+				return nil, fmt.Errorf("failed to scan schema_migrations: %w", err)
+			}
+			applied = append(applied, a)
+		}
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	return m.compare(applied), nil
+}
+
+func (m *Migrator) compare(applied []AppliedMigration) *Status {
+	status := &Status{Applied: applied}
+
+	known := make(map[int]Migration, len(m.migrations))
+	for _, mig := range m.migrations {
+		known[mig.Version] = mig
+	}
+	done := make(map[int]bool, len(applied))
+	for _, a := range applied {
+		done[a.Version] = true
+		mig, ok := known[a.Version]
+		switch {
+		case !ok:
+			status.Drift = append(status.Drift, fmt.Sprintf("migration %d (%s) is applied but unknown to this build", a.Version, a.Name))
+		case mig.Checksum != a.Checksum:
+			status.Drift = append(status.Drift, fmt.Sprintf("migration %d (%s) was changed after it was applied", a.Version, a.Name))
+		}
+	}
+	for _, mig := range m.migrations {
+		if !done[mig.Version] {
+			status.Pending = append(status.Pending, mig)
+		}
+	}
+	return status
+}
+
+// Up applies every pending migration, each in its own transaction, and
+// returns how many were applied. Replicas racing to migrate wait on an
+// advisory lock, then find nothing left to do. Up refuses to run over drift.
+func (m *Migrator) Up(ctx context.Context) (int, error) {
+	conn, err := m.pool.Acquire(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to acquire connection: %w", err)
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, `SELECT pg_advisory_lock($1)`, m.lockID); err != nil {
+		return 0, fmt.Errorf("failed to take migration lock: %w", err)
+	}
+	defer conn.Exec(context.Background(), `SELECT pg_advisory_unlock($1)`, m.lockID)
+
+	_, err = conn.Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INTEGER PRIMARY KEY,
+			name TEXT NOT NULL,
+			checksum TEXT NOT NULL,
+			applied_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+		)
+	`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+
+	status, err := m.Status(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if len(status.Drift) > 0 {
+		return 0, fmt.Errorf("%w: %s", ErrDrift, strings.Join(status.Drift, "; "))
+	}
+
+	for i, mig := range status.Pending {
+		start := time.Now()
+		tx, err := conn.Begin(ctx)
+		if err != nil {
+			return i, fmt.Errorf("failed to begin migration %d: %w", mig.Version, err)
+		}
+		if _, err := tx.Exec(ctx, mig.SQL); err != nil {
+			tx.Rollback(ctx)
+			return i, fmt.Errorf("migration %d (%s) failed: %w", mig.Version, mig.Name, err)
+		}
+		_, err = tx.Exec(ctx, `INSERT INTO schema_migrations (version, name, checksum) VALUES ($1, $2, $3)`,
+			mig.Version, mig.Name, mig.Checksum)
+		if err != nil {
+			tx.Rollback(ctx)
+			return i, fmt.Errorf("failed to record migration %d: %w", mig.Version, err)
+		}
+		if err := tx.Commit(ctx); err != nil {
+			return i, fmt.Errorf("failed to commit migration %d: %w", mig.Version, err)
+		}
+
+		m.logger.Info("Applied migration",
+			zap.Int("version", mig.Version),
+			zap.String("name", mig.Name),
+			zap.Duration("duration", time.Since(start)),
+		)
+	}
+
+	return len(status.Pending), nil
+}
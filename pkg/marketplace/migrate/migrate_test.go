@@ -0,0 +1,59 @@
+package migrate_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/org/llm-marketplace/pkg/marketplace/migrate"
+)
+
+func TestLoad(t *testing.T) {
+	fsys := fstest.MapFS{
+		"sql/2_add_index.sql":      {Data: []byte("CREATE INDEX i ON t(a);")},
+		"sql/1_initial_schema.sql": {Data: []byte("CREATE TABLE t (a INT);")},
+		"sql/README.md":            {Data: []byte("not a migration")},
+	}
+	loaded, err := migrate.Load(fsys, "sql")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(loaded) != 2 || loaded[0].Version != 1 || loaded[0].Name != "initial_schema" || loaded[1].Version != 2 {
+		t.Fatalf("loaded %+v, want both migrations in version order", loaded)
+	}
+	if len(loaded[0].Checksum) != 64 || loaded[0].Checksum == loaded[1].Checksum {
+		t.Errorf("checksums %q and %q, want distinct sha256s", loaded[0].Checksum, loaded[1].Checksum)
+	}
+
+	for name, fsys := range map[string]fstest.MapFS{
+		"unnumbered": {"sql/initial.sql": {}},
+		"zero":       {"sql/0_initial.sql": {}},
+		"duplicate":  {"sql/1_a.sql": {}, "sql/01_b.sql": {}},
+	} {
+		if _, err := migrate.Load(fsys, "sql"); err == nil {
+			t.Errorf("%s: Load accepted %v", name, fsys)
+		}
+	}
+}
+
+func TestStatusErr(t *testing.T) {
+	current := &migrate.Status{}
+	if err := current.Err(); err != nil {
+		t.Errorf("Err() = %v for a current schema", err)
+	}
+
+	behind := &migrate.Status{
+		Pending: []migrate.Migration{{Version: 2, Name: "saved_searches"}},
+		Drift:   []string{"migration 1 (initial_schema) was changed after it was applied"},
+	}
+	err := behind.Err()
+	if !errors.Is(err, migrate.ErrDrift) {
+		t.Fatalf("Err() = %v, want ErrDrift", err)
+	}
+	for _, want := range []string{"migration 1 (initial_schema) was changed", "migration 2 (saved_searches) is not applied"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Err() = %q, want it to mention %q", err, want)
+		}
+	}
+}
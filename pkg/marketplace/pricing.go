@@ -0,0 +1,57 @@
+package marketplace
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// TokensPerUnit returns how many tokens a pricing unit covers: 1000 for
+// "1k tokens", 1 for "token". It reports false for units not counted in
+// tokens, such as "request".
+func TokensPerUnit(unit string) (float64, bool) {
+	return unitSize(unit, "token", "tokens")
+}
+
+// RequestsPerUnit returns how many requests a pricing unit covers: 1 for
+// "request" or "call", 1000 for "1k requests". It reports false for units not
+// counted in requests.
+func RequestsPerUnit(unit string) (float64, bool) {
+	return unitSize(unit, "request", "requests", "call", "calls")
+}
+
+// unitSize parses a unit of the form "[<count>[k|m]] <noun>" where noun is
+// one of nouns
+func unitSize(unit string, nouns ...string) (float64, bool) {
+	fields := strings.Fields(strings.ToLower(unit))
+	if len(fields) == 0 || len(fields) > 2 || !slices.Contains(nouns, fields[len(fields)-1]) {
+		return 0, false
+	}
+	if len(fields) == 1 {
+		return 1, true
+	}
+
+	count := fields[0]
+	multiplier := 1.0
+	switch {
+	case strings.HasSuffix(count, "k"):
+		count, multiplier = strings.TrimSuffix(count, "k"), 1e3
+	case strings.HasSuffix(count, "m"):
+		count, multiplier = strings.TrimSuffix(count, "m"), 1e6
+	}
+	n, err := strconv.ParseFloat(count, 64)
+	if err != nil || n <= 0 {
+		return 0, false
+	}
+	return n * multiplier, true
+}
+
+// TokenPrice returns the headline price of one token. It reports false when
+// the service isn't charged by the token.
+func (p *PricingInfo) TokenPrice() (float64, bool) {
+	tokens, ok := TokensPerUnit(p.Unit)
+	if !ok {
+		return 0, false
+	}
+	return p.Rate / tokens, true
+}
@@ -0,0 +1,78 @@
+// Package problem writes the RFC 7807 problem details every marketplace
+// service answers errors with. Services add their own error types and
+// extension members by embedding Details.
+package problem
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// ContentType is the media type for RFC 7807 problem details
+const ContentType = "application/problem+json"
+
+// TypeBaseURL prefixes the code to form the problem type URI
+const TypeBaseURL = "https://docs.llm-marketplace.com/errors/"
+
+// Type describes a documented class of error
+type Type struct {
+	Code   string
+	Title  string
+	Status int
+}
+
+// Error types shared by the services. See each service's README "Error
+// Responses" for the ones it answers with.
+var (
+	InvalidRequest     = Type{Code: "invalid-request", Title: "Invalid request", Status: http.StatusBadRequest}
+	Unauthorized       = Type{Code: "unauthorized", Title: "Unauthorized", Status: http.StatusUnauthorized}
+	Forbidden          = Type{Code: "forbidden", Title: "Forbidden", Status: http.StatusForbidden}
+	NotFound           = Type{Code: "not-found", Title: "Resource not found", Status: http.StatusNotFound}
+	MethodNotAllowed   = Type{Code: "method-not-allowed", Title: "Method not allowed", Status: http.StatusMethodNotAllowed}
+	Conflict           = Type{Code: "conflict", Title: "Conflicting state", Status: http.StatusConflict}
+	PayloadTooLarge    = Type{Code: "payload-too-large", Title: "Request body too large", Status: http.StatusRequestEntityTooLarge}
+	RateLimited        = Type{Code: "rate-limited", Title: "Too many requests", Status: http.StatusTooManyRequests}
+	InternalError      = Type{Code: "internal-error", Title: "Internal server error", Status: http.StatusInternalServerError}
+	ServiceUnavailable = Type{Code: "service-unavailable", Title: "Service unavailable", Status: http.StatusServiceUnavailable}
+)
+
+// Details is an RFC 7807 problem details body
+type Details struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+	Code     string `json:"code"`
+}
+
+// New builds problem details of type t for the resource at instance
+func New(t Type, instance, detail string) Details {
+	return Details{
+		Type:     TypeBaseURL + t.Code,
+		Title:    t.Title,
+		Status:   t.Status,
+		Detail:   detail,
+		Instance: instance,
+		Code:     t.Code,
+	}
+}
+
+// Write answers with body, problem details or a struct embedding them, and
+// status
+func Write(w http.ResponseWriter, status int, body any) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", ContentType)
+	w.WriteHeader(status)
+	w.Write(data)
+}
+
+// Error answers r with problem details of type t
+func Error(w http.ResponseWriter, r *http.Request, t Type, detail string) {
+	p := New(t, r.URL.Path, detail)
+	Write(w, p.Status, &p)
+}
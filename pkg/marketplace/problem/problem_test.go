@@ -0,0 +1,54 @@
+package problem_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/org/llm-marketplace/pkg/marketplace/problem"
+)
+
+func TestError(t *testing.T) {
+	w := httptest.NewRecorder()
+	problem.Error(w, httptest.NewRequest(http.MethodGet, "/api/v1/services/missing?x=1", nil), problem.NotFound, "service missing not found")
+
+	if w.Code != http.StatusNotFound || w.Header().Get("Content-Type") != problem.ContentType {
+		t.Fatalf("status %d, content type %q", w.Code, w.Header().Get("Content-Type"))
+	}
+	var body map[string]any
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("invalid body %s: %v", w.Body, err)
+	}
+	want := map[string]any{
+		"type":     "https://docs.llm-marketplace.com/errors/not-found",
+		"title":    "Resource not found",
+		"status":   float64(404),
+		"detail":   "service missing not found",
+		"instance": "/api/v1/services/missing",
+		"code":     "not-found",
+	}
+	for k, v := range want {
+		if body[k] != v {
+			t.Errorf("%s = %v, want %v", k, body[k], v)
+		}
+	}
+}
+
+func TestWriteExtendedDetails(t *testing.T) {
+	// Services add extension members by embedding Details
+	type extended struct {
+		problem.Details
+		Violations []string `json:"violations,omitempty"`
+	}
+	p := &extended{Details: problem.New(problem.Conflict, "/x", ""), Violations: []string{"pii"}}
+
+	w := httptest.NewRecorder()
+	problem.Write(w, p.Status, p)
+	if w.Code != http.StatusConflict {
+		t.Errorf("status %d, want 409", w.Code)
+	}
+	if got := w.Body.String(); got != `{"type":"https://docs.llm-marketplace.com/errors/conflict","title":"Conflicting state","status":409,"instance":"/x","code":"conflict","violations":["pii"]}` {
+		t.Errorf("body %s", got)
+	}
+}
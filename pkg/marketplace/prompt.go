@@ -0,0 +1,112 @@
+package marketplace
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// MaxTemplateLength bounds the text of a prompt template, in bytes
+const MaxTemplateLength = 32 * 1024
+
+var (
+	// placeholderPattern matches a {{variable}} in a template's text
+	placeholderPattern = regexp.MustCompile(`\{\{\s*([^{}\s]*)\s*\}\}`)
+	variableName       = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	// Model families are lower case so search filters match them exactly,
+	// e.g. gpt-4, claude-3, llama-3.1
+	modelFamily = regexp.MustCompile(`^[a-z0-9][a-z0-9.\-]*$`)
+)
+
+// PromptTemplate is the text of a prompt template with the {{variables}}
+// consumers fill in, and the model families it was written and tested for
+type PromptTemplate struct {
+	Template      string             `json:"template"`
+	Variables     []TemplateVariable `json:"variables,omitempty"`
+	ModelFamilies []string           `json:"model_families"`
+}
+
+// TemplateVariable is a {{variable}} of a prompt template. A variable that
+// isn't required is replaced with its default, or with nothing.
+type TemplateVariable struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Required    bool   `json:"required,omitempty"`
+	Default     string `json:"default,omitempty"`
+}
+
+// Placeholders returns the names of the variables the template's text uses,
+// in order of first use
+func (t *PromptTemplate) Placeholders() []string {
+	var names []string
+	for _, m := range placeholderPattern.FindAllStringSubmatch(t.Template, -1) {
+		if !slices.Contains(names, m[1]) {
+			names = append(names, m[1])
+		}
+	}
+	return names
+}
+
+// Supports reports whether the template was written for the model family
+func (t *PromptTemplate) Supports(family string) bool {
+	return slices.Contains(t.ModelFamilies, strings.ToLower(family))
+}
+
+// Validate checks the template's text uses exactly the variables it
+// declares, and that it names the model families it is for
+func (t *PromptTemplate) Validate() error {
+	e := newErrs()
+	t.validate(e)
+	return e.err()
+}
+
+func (t *PromptTemplate) validate(e errs) {
+	switch {
+	case strings.TrimSpace(t.Template) == "":
+		e.add("template", "is required")
+	case len(t.Template) > MaxTemplateLength:
+		e.add("template", "must be at most %d bytes", MaxTemplateLength)
+	}
+
+	declared := make(map[string]bool, len(t.Variables))
+	for i, v := range t.Variables {
+		field := fmt.Sprintf("variables[%d].name", i)
+		switch {
+		case v.Name == "":
+			e.add(field, "is required")
+		case !variableName.MatchString(v.Name):
+			e.add(field, "must be letters, digits and underscores, not starting with a digit")
+		case declared[v.Name]:
+			e.add(field, "is declared twice")
+		}
+		if v.Required && v.Default != "" {
+			e.add(fmt.Sprintf("variables[%d].default", i), "is not used by a required variable")
+		}
+		declared[v.Name] = true
+	}
+	used := t.Placeholders()
+	for _, name := range used {
+		if !declared[name] {
+			e.add("template", "uses undeclared variable {{%s}}", name)
+		}
+	}
+	for i, v := range t.Variables {
+		if v.Name != "" && !slices.Contains(used, v.Name) {
+			e.add(fmt.Sprintf("variables[%d].name", i), "is not used by the template")
+		}
+	}
+
+	if len(t.ModelFamilies) == 0 {
+		e.add("model_families", "must name at least one model family")
+	}
+	for i, family := range t.ModelFamilies {
+		field := fmt.Sprintf("model_families[%d]", i)
+		switch {
+		case !modelFamily.MatchString(family):
+			e.add(field, "must be lower-case letters, digits, dots and hyphens")
+		case slices.Index(t.ModelFamilies, family) < i:
+			e.add(field, "is listed twice")
+		}
+	}
+}
@@ -0,0 +1,226 @@
+package marketplace
+
+// The policy engine's generated messages satisfy these interfaces through
+// their getters, so they convert without this module depending on the
+// generated code. Getters return zero values on a nil message.
+
+// EndpointMessage is a ServiceEndpoint message
+type EndpointMessage interface {
+	GetUrl() string
+	GetProtocol() string
+	GetAuthentication() string
+}
+
+// ComplianceMessage is a ServiceCompliance message
+type ComplianceMessage interface {
+	GetLevel() string
+	GetCertifications() []string
+	GetDataResidency() []string
+	GetGdprCompliant() bool
+	GetHipaaCompliant() bool
+}
+
+// SLAMessage is a ServiceSLA message
+type SLAMessage interface {
+	GetAvailability() float64
+	GetMaxLatency() int32
+	GetSupportLevel() string
+}
+
+// PricingTierMessage is a PricingTier message
+type PricingTierMessage interface {
+	GetTier() string
+	GetRate() float64
+	GetUnit() string
+	GetDescription() string
+}
+
+// PricingMessage is a ServicePricing message with tiers of type T
+type PricingMessage[T PricingTierMessage] interface {
+	GetModel() string
+	GetCurrency() string
+	GetRates() []T
+}
+
+// ServingRegionMessage is a ServingRegion message
+type ServingRegionMessage interface {
+	GetRegion() string
+	GetMedianLatencyMs() int32
+}
+
+// DependencyMessage is a ServiceDependency message
+type DependencyMessage interface {
+	GetServiceId() string
+	GetRole() string
+}
+
+// CapabilityMessage is a ServiceCapability message
+type CapabilityMessage interface {
+	GetName() string
+	GetDescription() string
+}
+
+// TemplateVariableMessage is a TemplateVariable message
+type TemplateVariableMessage interface {
+	GetName() string
+	GetDescription() string
+	GetRequired() bool
+	GetDefault() string
+}
+
+// PromptTemplateMessage is a PromptTemplate message with variables of type T
+type PromptTemplateMessage[T TemplateVariableMessage] interface {
+	GetTemplate() string
+	GetVariables() []T
+	GetModelFamilies() []string
+}
+
+// DatasetProvenanceMessage is a DatasetProvenance message
+type DatasetProvenanceMessage interface {
+	GetSource() string
+	GetMethod() string
+	GetUrl() string
+}
+
+// DatasetMessage is a Dataset message with provenance of type P
+type DatasetMessage[P DatasetProvenanceMessage] interface {
+	GetLicense() string
+	GetAttribution() string
+	GetFormat() string
+	GetSizeBytes() int64
+	GetRecords() int64
+	GetProvenance() P
+}
+
+// FineTuningDataMessage is a FineTuningDataRequirements message
+type FineTuningDataMessage interface {
+	GetFormats() []string
+	GetMinExamples() int64
+	GetMaxExamples() int64
+	GetMaxSizeBytes() int64
+}
+
+// FineTuningMessage is a FineTuningOffering message with data requirements
+// of type R
+type FineTuningMessage[R FineTuningDataMessage] interface {
+	GetBaseModels() []string
+	GetDataRequirements() R
+	GetTurnaroundHours() int32
+}
+
+// EndpointFromProto converts a ServiceEndpoint message
+func EndpointFromProto(m EndpointMessage) *EndpointInfo {
+	return &EndpointInfo{
+		URL:            m.GetUrl(),
+		Protocol:       m.GetProtocol(),
+		Authentication: m.GetAuthentication(),
+	}
+}
+
+// ComplianceFromProto converts a ServiceCompliance message
+func ComplianceFromProto(m ComplianceMessage) *ComplianceInfo {
+	return &ComplianceInfo{
+		Level:          m.GetLevel(),
+		Certifications: m.GetCertifications(),
+		DataResidency:  m.GetDataResidency(),
+		GDPRCompliant:  m.GetGdprCompliant(),
+		HIPAACompliant: m.GetHipaaCompliant(),
+	}
+}
+
+// SLAFromProto converts a ServiceSLA message
+func SLAFromProto(m SLAMessage) *SLAInfo {
+	return &SLAInfo{
+		Availability: m.GetAvailability(),
+		MaxLatencyMS: int(m.GetMaxLatency()),
+		SupportLevel: m.GetSupportLevel(),
+	}
+}
+
+// PricingFromProto converts a ServicePricing message. The message has no
+// headline rate, so Rate and Unit are taken from the first tier.
+func PricingFromProto[T PricingTierMessage](m PricingMessage[T]) *PricingInfo {
+	pricing := &PricingInfo{
+		Model:    m.GetModel(),
+		Currency: m.GetCurrency(),
+	}
+	for _, rate := range m.GetRates() {
+		pricing.Tiers = append(pricing.Tiers, PricingTier{
+			Tier:        rate.GetTier(),
+			Rate:        rate.GetRate(),
+			Unit:        rate.GetUnit(),
+			Description: rate.GetDescription(),
+		})
+	}
+	if len(pricing.Tiers) > 0 {
+		pricing.Rate = pricing.Tiers[0].Rate
+		pricing.Unit = pricing.Tiers[0].Unit
+	}
+	return pricing
+}
+
+// CapabilityFromProto converts a ServiceCapability message
+func CapabilityFromProto(m CapabilityMessage) Capability {
+	return Capability{Name: m.GetName(), Description: m.GetDescription()}
+}
+
+// ServingRegionFromProto converts a ServingRegion message
+func ServingRegionFromProto(m ServingRegionMessage) ServingRegion {
+	return ServingRegion{Region: m.GetRegion(), MedianLatencyMS: int(m.GetMedianLatencyMs())}
+}
+
+// DependencyFromProto converts a ServiceDependency message
+func DependencyFromProto(m DependencyMessage) Dependency {
+	return Dependency{ServiceID: m.GetServiceId(), Role: m.GetRole()}
+}
+
+// PromptTemplateFromProto converts a PromptTemplate message
+func PromptTemplateFromProto[T TemplateVariableMessage](m PromptTemplateMessage[T]) *PromptTemplate {
+	t := &PromptTemplate{
+		Template:      m.GetTemplate(),
+		ModelFamilies: m.GetModelFamilies(),
+	}
+	for _, v := range m.GetVariables() {
+		t.Variables = append(t.Variables, TemplateVariable{
+			Name:        v.GetName(),
+			Description: v.GetDescription(),
+			Required:    v.GetRequired(),
+			Default:     v.GetDefault(),
+		})
+	}
+	return t
+}
+
+// DatasetFromProto converts a Dataset message. Provenance is nil when the
+// message has none.
+func DatasetFromProto[P DatasetProvenanceMessage](m DatasetMessage[P]) *DatasetInfo {
+	d := &DatasetInfo{
+		License:     m.GetLicense(),
+		Attribution: m.GetAttribution(),
+		Format:      m.GetFormat(),
+		SizeBytes:   m.GetSizeBytes(),
+		Records:     m.GetRecords(),
+	}
+	if p := m.GetProvenance(); p.GetSource() != "" || p.GetMethod() != "" || p.GetUrl() != "" {
+		d.Provenance = &DatasetProvenance{Source: p.GetSource(), Method: p.GetMethod(), URL: p.GetUrl()}
+	}
+	return d
+}
+
+// FineTuningFromProto converts a FineTuningOffering message. Data
+// requirements are nil when the message has none.
+func FineTuningFromProto[R FineTuningDataMessage](m FineTuningMessage[R]) *FineTuningOffering {
+	o := &FineTuningOffering{
+		BaseModels:      m.GetBaseModels(),
+		TurnaroundHours: m.GetTurnaroundHours(),
+	}
+	if r := m.GetDataRequirements(); len(r.GetFormats()) > 0 || r.GetMinExamples() != 0 || r.GetMaxExamples() != 0 || r.GetMaxSizeBytes() != 0 {
+		o.DataRequirements = &FineTuningDataRequirements{
+			Formats:      r.GetFormats(),
+			MinExamples:  r.GetMinExamples(),
+			MaxExamples:  r.GetMaxExamples(),
+			MaxSizeBytes: r.GetMaxSizeBytes(),
+		}
+	}
+	return o
+}
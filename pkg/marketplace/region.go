@@ -0,0 +1,57 @@
+package marketplace
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// MaxServingRegions bounds the regions one service is served from
+const MaxServingRegions = 50
+
+// Region names are lower case so search filters match them exactly, e.g.
+// us-east-1, europe-west4
+var regionName = regexp.MustCompile(`^[a-z0-9][a-z0-9\-]*$`)
+
+// ServingRegion is a region a service is served from, with the median
+// latency the provider measured from clients in that region
+type ServingRegion struct {
+	Region          string `json:"region"`
+	MedianLatencyMS int    `json:"median_latency_ms"`
+}
+
+// RegionLatency returns the median latency measured in the region, and
+// whether the service is served from it at all
+func (d *ServiceDescriptor) RegionLatency(region string) (int, bool) {
+	region = strings.ToLower(region)
+	for _, r := range d.ServingRegions {
+		if r.Region == region {
+			return r.MedianLatencyMS, true
+		}
+	}
+	return 0, false
+}
+
+// validateServingRegions checks each region is named once, with a measured
+// latency
+func validateServingRegions(e errs, regions []ServingRegion) {
+	if len(regions) > MaxServingRegions {
+		e.add("serving_regions", "must list at most %d regions", MaxServingRegions)
+	}
+	seen := make(map[string]bool, len(regions))
+	for i, r := range regions {
+		field := fmt.Sprintf("serving_regions[%d]", i)
+		switch {
+		case r.Region == "":
+			e.add(field+".region", "is required")
+		case !regionName.MatchString(r.Region):
+			e.add(field+".region", "must be lower-case letters, digits and hyphens")
+		case seen[r.Region]:
+			e.add(field+".region", "is listed twice")
+		}
+		seen[r.Region] = true
+		if r.MedianLatencyMS <= 0 {
+			e.add(field+".median_latency_ms", "must be positive")
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+)
+
+// fileProvider reads a secret from a file, as mounted by Kubernetes or Docker
+type fileProvider struct{}
+
+func (fileProvider) Fetch(_ context.Context, path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// vaultProvider reads fields of KV secrets over Vault's HTTP API. References
+// are <path>#<field>, where path includes the mount and, for KV v2, "data/".
+type vaultProvider struct {
+	address   string
+	token     string
+	tokenFile string
+	namespace string
+	client    *http.Client
+}
+
+func newVaultProvider(cfg VaultConfig, timeout time.Duration) (*vaultProvider, error) {
+	address := cfg.Address
+	if address == "" {
+		address = os.Getenv("VAULT_ADDR")
+	}
+	token := cfg.Token
+	if token == "" {
+		token = os.Getenv("VAULT_TOKEN")
+	}
+	if address == "" {
+		return nil, errors.New("secrets.vault.address (or VAULT_ADDR) is required for vault: references")
+	}
+	if token == "" && cfg.TokenFile == "" {
+		return nil, errors.New("secrets.vault needs a token or token_file (or VAULT_TOKEN)")
+	}
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	return &vaultProvider{
+		address:   strings.TrimSuffix(address, "/"),
+		token:     token,
+		tokenFile: cfg.TokenFile,
+		namespace: cfg.Namespace,
+		client:    &http.Client{Timeout: timeout},
+	}, nil
+}
+
+func (p *vaultProvider) Fetch(ctx context.Context, ref string) (string, error) {
+	path, field, ok := strings.Cut(ref, "#")
+	if !ok || path == "" || field == "" {
+		return "", fmt.Errorf("vault reference %q is not <path>#<field>", ref)
+	}
+
+	// An agent may renew the token in the file, so it is read per request
+	token := p.token
+	if p.tokenFile != "" {
+		data, err := os.ReadFile(p.tokenFile)
+		if err != nil {
+			return "", fmt.Errorf("failed to read vault token: %w", err)
+		}
+		token = strings.TrimSpace(string(data))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.address+"/v1/"+strings.TrimPrefix(path, "/"), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-Vault-Token", token)
+	if p.namespace != "" {
+		req.Header.Set("X-Vault-Namespace", p.namespace)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("vault returned %s", resp.Status)
+	}
+
+	// KV v2 nests the fields under data.data; KV v1 has them under data
+	var secret struct {
+		Data map[string]json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(body, &secret); err != nil {
+		return "", fmt.Errorf("failed to decode vault response: %w", err)
+	}
+	fields := secret.Data
+	if nested, ok := fields["data"]; ok {
+		if _, isV2 := fields["metadata"]; isV2 {
+			fields = nil
+			if err := json.Unmarshal(nested, &fields); err != nil {
+				return "", fmt.Errorf("failed to decode vault response: %w", err)
+			}
+		}
+	}
+	return jsonField(fields, field)
+}
+
+// awsProvider reads Secrets Manager secrets with the SDK's default credential
+// chain. References are <secret-id>, or <secret-id>#<field> for a field of a
+// JSON secret.
+type awsProvider struct {
+	client *secretsmanager.Client
+}
+
+func newAWSProvider(ctx context.Context, cfg AWSConfig) (*awsProvider, error) {
+	var opts []func(*awsconfig.LoadOptions) error
+	if cfg.Region != "" {
+		opts = append(opts, awsconfig.WithRegion(cfg.Region))
+	}
+	awsCfg, err := awsconfig.LoadDefaultConfig(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS configuration: %w", err)
+	}
+	client := secretsmanager.NewFromConfig(awsCfg, func(o *secretsmanager.Options) {
+		if cfg.Endpoint != "" {
+			o.BaseEndpoint = aws.String(cfg.Endpoint)
+		}
+	})
+	return &awsProvider{client: client}, nil
+}
+
+func (p *awsProvider) Fetch(ctx context.Context, ref string) (string, error) {
+	id, field, _ := strings.Cut(ref, "#")
+	out, err := p.client.GetSecretValue(ctx, &secretsmanager.GetSecretValueInput{SecretId: aws.String(id)})
+	if err != nil {
+		return "", err
+	}
+	if out.SecretString == nil {
+		return "", fmt.Errorf("secret %s has no string value", id)
+	}
+	if field == "" {
+		return *out.SecretString, nil
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(*out.SecretString), &fields); err != nil {
+		return "", fmt.Errorf("secret %s is not a JSON object: %w", id, err)
+	}
+	return jsonField(fields, field)
+}
+
+// jsonField returns a field of a secret as a string
+func jsonField(fields map[string]json.RawMessage, field string) (string, error) {
+	raw, ok := fields[field]
+	if !ok {
+		return "", fmt.Errorf("secret has no field %q", field)
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s, nil
+	}
+	return string(raw), nil
+}
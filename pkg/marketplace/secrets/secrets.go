@@ -0,0 +1,272 @@
+// Package secrets resolves credentials kept outside the config file. A config
+// value of the form <provider>:<reference> names a secret instead of holding
+// it:
+//
+//	vault:secret/data/discovery#postgres_password   a field of a Vault KV secret
+//	aws:prod/discovery/postgres#password            a Secrets Manager secret, or a field of a JSON one
+//	file:/run/secrets/postgres_password             the contents of a file
+//
+// Values are cached and re-read on an interval; callers read the current value
+// per connection and can register callbacks for when a secret rotates.
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Config configures the providers behind secret references, under a
+// service's secrets setting. A password set to a reference is read from its
+// provider and re-read every refresh interval, so rotated credentials apply
+// to new connections without a restart.
+type Config struct {
+	RefreshInterval time.Duration `yaml:"refresh_interval"` // Referenced secrets are re-read this often
+	Timeout         time.Duration `yaml:"timeout"`          // Per fetch
+	Vault           VaultConfig   `yaml:"vault"`
+	AWS             AWSConfig     `yaml:"aws"`
+}
+
+// VaultConfig points at a Vault server; VAULT_ADDR and VAULT_TOKEN are used
+// when address and token are unset
+type VaultConfig struct {
+	Address   string `yaml:"address"`
+	Token     string `yaml:"token"`
+	TokenFile string `yaml:"token_file"` // Re-read per request, for tokens renewed by a Vault agent
+	Namespace string `yaml:"namespace"`
+}
+
+// AWSConfig configures Secrets Manager; credentials come from the SDK's
+// default chain (environment, IRSA, instance profile)
+type AWSConfig struct {
+	Region   string `yaml:"region"`   // The SDK's default when empty
+	Endpoint string `yaml:"endpoint"` // Override, e.g. a VPC endpoint or LocalStack
+}
+
+// Report is told about each secret a refresh re-read: with a nil error when
+// it rotated, or with the error that kept its last value
+type Report func(secret string, err error)
+
+// Provider fetches secrets from one backend
+type Provider interface {
+	Fetch(ctx context.Context, ref string) (string, error)
+}
+
+// IsReference reports whether value names a secret rather than holding one
+func IsReference(value string) bool {
+	_, _, ok := parseReference(value)
+	return ok
+}
+
+func parseReference(value string) (scheme, ref string, ok bool) {
+	scheme, ref, ok = strings.Cut(value, ":")
+	switch scheme {
+	case "vault", "aws", "file":
+		return scheme, ref, ok && ref != ""
+	default:
+		return "", "", false
+	}
+}
+
+// Secret is the cached value of a secret
+type Secret struct {
+	name string
+
+	mu       sync.RWMutex
+	value    string
+	onRotate []func(value string)
+}
+
+// Value returns the current value
+func (s *Secret) Value() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.value
+}
+
+// OnRotate registers fn to be called with the new value each time the secret
+// changes
+func (s *Secret) OnRotate(fn func(value string)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onRotate = append(s.onRotate, fn)
+}
+
+// set stores value, reporting whether it changed and the callbacks to run
+func (s *Secret) set(value string) (bool, []func(string)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if value == s.value {
+		return false, nil
+	}
+	s.value = value
+	return true, append([]func(string){}, s.onRotate...)
+}
+
+// Manager resolves secret references and keeps their values current
+type Manager struct {
+	config Config
+	report Report
+
+	mu        sync.Mutex
+	providers map[string]Provider
+	secrets   map[string]*Secret
+}
+
+// NewManager creates a manager with the vault, aws and file providers. Vault
+// and AWS clients are only created once a reference needs them. report, if
+// not nil, hears about rotations and failed refreshes.
+func NewManager(cfg Config, report Report) *Manager {
+	return &Manager{
+		config:    cfg,
+		report:    report,
+		providers: map[string]Provider{"file": fileProvider{}},
+		secrets:   make(map[string]*Secret),
+	}
+}
+
+// SetProvider replaces the provider for scheme
+func (m *Manager) SetProvider(scheme string, provider Provider) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.providers[scheme] = provider
+}
+
+func (m *Manager) provider(ctx context.Context, scheme string) (Provider, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if p, ok := m.providers[scheme]; ok {
+		return p, nil
+	}
+
+	var p Provider
+	var err error
+	switch scheme {
+	case "vault":
+		p, err = newVaultProvider(m.config.Vault, m.config.Timeout)
+	case "aws":
+		p, err = newAWSProvider(ctx, m.config.AWS)
+	default:
+		err = fmt.Errorf("unknown secrets provider %q", scheme)
+	}
+	if err != nil {
+		return nil, err
+	}
+	m.providers[scheme] = p
+	return p, nil
+}
+
+// Resolve returns the secret value names, fetching it the first time. A value
+// that is not a reference is returned as a secret that never rotates.
+func (m *Manager) Resolve(ctx context.Context, value string) (*Secret, error) {
+	if !IsReference(value) {
+		return &Secret{value: value}, nil
+	}
+
+	m.mu.Lock()
+	secret, ok := m.secrets[value]
+	m.mu.Unlock()
+	if ok {
+		return secret, nil
+	}
+
+	fetched, err := m.fetch(ctx, value)
+	if err != nil {
+		return nil, err
+	}
+	secret = &Secret{name: value, value: fetched}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if existing, ok := m.secrets[value]; ok {
+		return existing, nil
+	}
+	m.secrets[value] = secret
+	return secret, nil
+}
+
+// Password returns a function reading the current value of the secret value
+// names, for clients that take the password per connection. It returns nil
+// when value is a plain password.
+func (m *Manager) Password(ctx context.Context, value string) (func() string, error) {
+	if !IsReference(value) {
+		return nil, nil
+	}
+	secret, err := m.Resolve(ctx, value)
+	if err != nil {
+		return nil, err
+	}
+	return secret.Value, nil
+}
+
+func (m *Manager) fetch(ctx context.Context, value string) (string, error) {
+	scheme, ref, _ := parseReference(value)
+	provider, err := m.provider(ctx, scheme)
+	if err != nil {
+		return "", err
+	}
+	if m.config.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, m.config.Timeout)
+		defer cancel()
+	}
+	secret, err := provider.Fetch(ctx, ref)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch secret %s: %w", value, err)
+	}
+	return secret, nil
+}
+
+// Start re-reads resolved secrets every refresh interval until ctx is
+// cancelled
+func (m *Manager) Start(ctx context.Context) {
+	interval := m.config.RefreshInterval
+	if interval <= 0 {
+		interval = 5 * time.Minute
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			m.Refresh(ctx)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Refresh re-reads every resolved secret and runs the rotation callbacks of
+// those that changed. A secret that can't be read keeps its last value.
+func (m *Manager) Refresh(ctx context.Context) {
+	m.mu.Lock()
+	secrets := make([]*Secret, 0, len(m.secrets))
+	for _, s := range m.secrets {
+		secrets = append(secrets, s)
+	}
+	m.mu.Unlock()
+
+	for _, secret := range secrets {
+		value, err := m.fetch(ctx, secret.name)
+		if err != nil {
+			if m.report != nil {
+				m.report(secret.name, err)
+			}
+			continue
+		}
+		changed, callbacks := secret.set(value)
+		if !changed {
+			continue
+		}
+		if m.report != nil {
+			m.report(secret.name, nil)
+		}
+		for _, fn := range callbacks {
+			fn(value)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package secrets_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/org/llm-marketplace/pkg/marketplace/secrets"
+)
+
+func TestSecretReferences(t *testing.T) {
+	for value, want := range map[string]bool{
+		"vault:secret/data/discovery#postgres_password": true,
+		"aws:prod/discovery/postgres#password":          true,
+		"file:/run/secrets/postgres_password":           true,
+		"hunter2":                                       false,
+		"file:":                                         false,
+		"p@ss:word":                                     false,
+	} {
+		if got := secrets.IsReference(value); got != want {
+			t.Errorf("IsReference(%q) = %v, want %v", value, got, want)
+		}
+	}
+}
+
+func TestFileSecretRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "postgres_password")
+	if err := os.WriteFile(path, []byte("first\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	var reported []string
+	manager := secrets.NewManager(secrets.Config{}, func(name string, err error) {
+		reported = append(reported, name+": "+fmt.Sprint(err))
+	})
+
+	secret, err := manager.Resolve(context.Background(), "file:"+path)
+	if err != nil {
+		t.Fatalf("Resolve: %v", err)
+	}
+	if secret.Value() != "first" {
+		t.Errorf("Value() = %q, want the file without its trailing newline", secret.Value())
+	}
+	var rotated []string
+	secret.OnRotate(func(value string) { rotated = append(rotated, value) })
+
+	manager.Refresh(context.Background())
+	if len(rotated) != 0 {
+		t.Errorf("rotation callbacks ran for an unchanged secret: %v", rotated)
+	}
+
+	if err := os.WriteFile(path, []byte("second\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	manager.Refresh(context.Background())
+	if secret.Value() != "second" || len(rotated) != 1 || rotated[0] != "second" {
+		t.Errorf("after rotation Value() = %q and callbacks saw %v, want second", secret.Value(), rotated)
+	}
+
+	// A secret that can no longer be read keeps its last value
+	os.Remove(path)
+	manager.Refresh(context.Background())
+	if secret.Value() != "second" {
+		t.Errorf("Value() = %q after a failed refresh, want the last value", secret.Value())
+	}
+	// The rotation and the failure are reported, and nothing else
+	if len(reported) != 2 || reported[0] != "file:"+path+": <nil>" || reported[1] == reported[0] {
+		t.Errorf("reported %q, want the rotation then the failure", reported)
+	}
+}
+
+func TestPlainPasswordIsNotResolved(t *testing.T) {
+	password, err := secrets.NewManager(secrets.Config{}, nil).Password(context.Background(), "hunter2")
+	if err != nil || password != nil {
+		t.Errorf("Password() = non-nil %v, %v; want nil so clients use the configured password", password != nil, err)
+	}
+}
+
+func TestVaultSecrets(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "s.token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/secret/data/discovery": // KV v2
+			w.Write([]byte(`{"data": {"data": {"postgres_password": "from-v2", "port": 5432}, "metadata": {"version": 3}}}`))
+		case "/v1/kv/discovery": // KV v1
+			w.Write([]byte(`{"data": {"redis_password": "from-v1"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	manager := secrets.NewManager(secrets.Config{
+		Vault: secrets.VaultConfig{Address: server.URL, Token: "s.token"},
+	}, nil)
+
+	tests := []struct {
+		ref     string
+		want    string
+		wantErr bool
+	}{
+		{"vault:secret/data/discovery#postgres_password", "from-v2", false},
+		{"vault:secret/data/discovery#port", "5432", false},
+		{"vault:kv/discovery#redis_password", "from-v1", false},
+		{"vault:secret/data/discovery#missing", "", true},
+		{"vault:secret/data/other#password", "", true},
+		{"vault:secret/data/discovery", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			secret, err := manager.Resolve(context.Background(), tt.ref)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Resolve = %q, want an error", secret.Value())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Resolve: %v", err)
+			}
+			if secret.Value() != tt.want {
+				t.Errorf("Value() = %q, want %q", secret.Value(), tt.want)
+			}
+		})
+	}
+}
+
+func TestAWSSecrets(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIDEXAMPLE")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	t.Setenv("AWS_EC2_METADATA_DISABLED", "true")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Amz-Target") != "secretsmanager.GetSecretValue" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		var in struct{ SecretId string }
+		body, _ := io.ReadAll(r.Body)
+		json.Unmarshal(body, &in)
+		w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+		switch in.SecretId {
+		case "prod/discovery/postgres":
+			json.NewEncoder(w).Encode(map[string]string{"Name": in.SecretId, "SecretString": `{"username": "marketplace", "password": "from-aws"}`})
+		case "prod/discovery/redis":
+			json.NewEncoder(w).Encode(map[string]string{"Name": in.SecretId, "SecretString": "plain"})
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"__type": "ResourceNotFoundException", "message": "not found"}`))
+		}
+	}))
+	defer server.Close()
+
+	manager := secrets.NewManager(secrets.Config{
+		AWS: secrets.AWSConfig{Region: "us-east-1", Endpoint: server.URL},
+	}, nil)
+
+	for ref, want := range map[string]string{
+		"aws:prod/discovery/postgres#password": "from-aws",
+		"aws:prod/discovery/redis":             "plain",
+	} {
+		secret, err := manager.Resolve(context.Background(), ref)
+		if err != nil {
+			t.Fatalf("Resolve(%s): %v", ref, err)
+		}
+		if secret.Value() != want {
+			t.Errorf("Resolve(%s) = %q, want %q", ref, secret.Value(), want)
+		}
+	}
+	if _, err := manager.Resolve(context.Background(), "aws:prod/missing"); err == nil {
+		t.Error("Resolve of a missing secret succeeded")
+	}
+}
@@ -0,0 +1,59 @@
+package marketplace
+
+import "time"
+
+// SLABreachTopic is the Kafka topic discovery publishes changes in a
+// service's monitored health to
+const SLABreachTopic = "marketplace.sla.breaches"
+
+// Monitored health states, as discovery reports them
+const (
+	HealthHealthy  = "healthy"
+	HealthDegraded = "degraded"
+	HealthDown     = "down"
+)
+
+// SLABreach reports that a service's monitored health changed: it became
+// degraded or down, or recovered to healthy. Breaches are keyed by service
+// ID.
+type SLABreach struct {
+	ID             string    `json:"id"`
+	OccurredAt     time.Time `json:"occurred_at"`
+	ServiceID      string    `json:"service_id"`
+	ServiceName    string    `json:"service_name"`
+	ProviderID     string    `json:"provider_id"`
+	Status         string    `json:"status"`
+	PreviousStatus string    `json:"previous_status"`
+	Availability   float64   `json:"availability"` // Percent of checks that succeeded in the window
+	AvgLatencyMS   float64   `json:"avg_latency_ms"`
+	ErrorRate      float64   `json:"error_rate"`
+}
+
+// Recovered reports whether the service is healthy again
+func (b *SLABreach) Recovered() bool {
+	return b.Status == HealthHealthy
+}
+
+// SavedSearchTopic is the Kafka topic discovery publishes saved-search
+// matches to
+const SavedSearchTopic = "marketplace.saved-search.matches"
+
+// SavedSearchMatch reports services that newly match a search a user saved
+// with notifications on. Matches are keyed by user ID.
+type SavedSearchMatch struct {
+	ID            string              `json:"id"`
+	OccurredAt    time.Time           `json:"occurred_at"`
+	UserID        string              `json:"user_id"`
+	SavedSearchID string              `json:"saved_search_id"`
+	Name          string              `json:"name"`
+	Query         string              `json:"query"`
+	Services      []SavedSearchResult `json:"services"`
+}
+
+// SavedSearchResult is a service that matched a saved search
+type SavedSearchResult struct {
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	Version    string `json:"version"`
+	ProviderID string `json:"provider_id"`
+}
@@ -0,0 +1,77 @@
+package marketplace
+
+import "time"
+
+// Subscription statuses. A subscription's status follows from its dates and
+// whether it was cancelled; see StatusAt.
+const (
+	SubscriptionPending   = "pending" // Starts in the future
+	SubscriptionActive    = "active"
+	SubscriptionExpired   = "expired"
+	SubscriptionCancelled = "cancelled"
+)
+
+// SubscriptionStatuses lists the subscription statuses
+var SubscriptionStatuses = []string{SubscriptionPending, SubscriptionActive, SubscriptionExpired, SubscriptionCancelled}
+
+// Subscription is a contract between a consumer organisation and a service:
+// the pricing tier it buys, what it commits to spend, when the contract runs
+// and the SLA negotiated for it
+type Subscription struct {
+	ID             string     `json:"id"`
+	ConsumerID     string     `json:"consumer_id"`
+	ServiceID      string     `json:"service_id"`
+	ProviderID     string     `json:"provider_id"`
+	Tier           string     `json:"tier,omitempty"`            // One of the service's pricing tiers
+	CommittedSpend float64    `json:"committed_spend,omitempty"` // Per billing period
+	Currency       string     `json:"currency,omitempty"`        // ISO 4217; the service's pricing currency when empty
+	StartsAt       time.Time  `json:"starts_at"`
+	EndsAt         *time.Time `json:"ends_at,omitempty"` // Open-ended when nil
+	SLA            *SLAInfo   `json:"sla,omitempty"`     // Negotiated terms, in place of the service's SLA
+	Status         string     `json:"status"`
+	CancelledAt    *time.Time `json:"cancelled_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+// StatusAt returns the status of the subscription at t
+func (s *Subscription) StatusAt(t time.Time) string {
+	switch {
+	case s.CancelledAt != nil && !s.CancelledAt.After(t):
+		return SubscriptionCancelled
+	case t.Before(s.StartsAt):
+		return SubscriptionPending
+	case s.EndsAt != nil && !t.Before(*s.EndsAt):
+		return SubscriptionExpired
+	}
+	return SubscriptionActive
+}
+
+// ActiveAt reports whether the subscription is in force at t
+func (s *Subscription) ActiveAt(t time.Time) bool {
+	return s.StatusAt(t) == SubscriptionActive
+}
+
+// Validate checks the parties are named, the committed spend isn't negative,
+// the contract ends after it starts and the SLA terms are valid
+func (s *Subscription) Validate() error {
+	e := newErrs()
+	if s.ConsumerID == "" {
+		e.add("consumer_id", "is required")
+	}
+	if s.ServiceID == "" {
+		e.add("service_id", "is required")
+	}
+	if s.CommittedSpend < 0 {
+		e.add("committed_spend", "must not be negative")
+	}
+	if s.Currency != "" && !isCurrencyCode(s.Currency) {
+		e.add("currency", "must be a three-letter ISO 4217 code")
+	}
+	if s.EndsAt != nil && !s.EndsAt.After(s.StartsAt) {
+		e.add("ends_at", "must be after starts_at")
+	}
+	if s.SLA != nil {
+		s.SLA.validate(e.in("sla"))
+	}
+	return e.err()
+}
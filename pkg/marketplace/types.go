@@ -0,0 +1,223 @@
+// Package marketplace holds the service descriptor types shared by the
+// marketplace services: provider, endpoint, pricing, SLA and compliance
+// information, with their validation and conversions from the policy engine's
+// protobuf messages. JSON field names are the ones stored in the discovery
+// index.
+package marketplace
+
+import "time"
+
+// Compliance levels, from least to most restrictive
+const (
+	CompliancePublic       = "public"
+	ComplianceInternal     = "internal"
+	ComplianceConfidential = "confidential"
+	ComplianceRestricted   = "restricted"
+)
+
+// ComplianceLevels lists the compliance levels from least to most restrictive
+var ComplianceLevels = []string{CompliancePublic, ComplianceInternal, ComplianceConfidential, ComplianceRestricted}
+
+// ComplianceRank returns the position of level in ComplianceLevels, or -1 for
+// an unknown level
+func ComplianceRank(level string) int {
+	for i, l := range ComplianceLevels {
+		if l == level {
+			return i
+		}
+	}
+	return -1
+}
+
+// Service classes. Private services are an enterprise's own model
+// endpoints, discoverable and consumable only inside its tenant.
+const (
+	ClassPublic  = "public"
+	ClassPrivate = "private"
+)
+
+// Kinds of marketplace item. A service is called at its endpoint; a prompt
+// template is text consumers fill in and send to a model of one of the
+// families it was written for; a dataset is data consumers license, e.g. for
+// fine-tuning or evaluation; a fine-tuning offering is a service that tunes
+// base models on a consumer's data.
+const (
+	KindService        = "service"
+	KindPromptTemplate = "prompt_template"
+	KindDataset        = "dataset"
+	KindFineTuning     = "fine_tuning"
+)
+
+// Kinds lists the kinds of marketplace item
+var Kinds = []string{KindService, KindPromptTemplate, KindDataset, KindFineTuning}
+
+// ServiceDescriptor describes a service as submitted for validation or
+// publishing. Sections a caller doesn't know are nil.
+type ServiceDescriptor struct {
+	ServiceID    string          `json:"service_id"`
+	Name         string          `json:"name"`
+	Version      string          `json:"version,omitempty"`
+	Description  string          `json:"description,omitempty"`
+	ProviderID   string          `json:"provider_id,omitempty"`
+	Category     string          `json:"category,omitempty"`
+	Tags         []string        `json:"tags,omitempty"`
+	Endpoint     *EndpointInfo   `json:"endpoint,omitempty"`
+	Compliance   *ComplianceInfo `json:"compliance,omitempty"`
+	SLA          *SLAInfo        `json:"sla,omitempty"`
+	Pricing      *PricingInfo    `json:"pricing,omitempty"`
+	Capabilities []Capability    `json:"capabilities,omitempty"`
+	Dependencies []Dependency    `json:"dependencies,omitempty"`
+	// Class is ClassPublic when empty. TenantID is the tenant a private
+	// service belongs to.
+	Class    string `json:"class,omitempty"`
+	TenantID string `json:"tenant_id,omitempty"`
+	// Kind is KindService when empty. PromptTemplate is set on prompt
+	// templates only, Dataset on datasets and FineTuning on fine-tuning
+	// offerings.
+	Kind           string              `json:"kind,omitempty"`
+	PromptTemplate *PromptTemplate     `json:"prompt_template,omitempty"`
+	Dataset        *DatasetInfo        `json:"dataset,omitempty"`
+	FineTuning     *FineTuningOffering `json:"fine_tuning,omitempty"`
+	// ServingRegions are where the service is served from, with the median
+	// latency measured in each
+	ServingRegions []ServingRegion `json:"serving_regions,omitempty"`
+}
+
+// Private reports whether the service is private to its tenant
+func (d *ServiceDescriptor) Private() bool {
+	return d.Class == ClassPrivate
+}
+
+// ItemKind returns the kind of item, KindService when none is set
+func (d *ServiceDescriptor) ItemKind() string {
+	if d.Kind == "" {
+		return KindService
+	}
+	return d.Kind
+}
+
+// IsPromptTemplate reports whether the item is a prompt template
+func (d *ServiceDescriptor) IsPromptTemplate() bool {
+	return d.Kind == KindPromptTemplate
+}
+
+// IsDataset reports whether the item is a dataset
+func (d *ServiceDescriptor) IsDataset() bool {
+	return d.Kind == KindDataset
+}
+
+// IsFineTuning reports whether the item is a fine-tuning offering
+func (d *ServiceDescriptor) IsFineTuning() bool {
+	return d.Kind == KindFineTuning
+}
+
+// ProviderInfo identifies the provider of a service
+type ProviderInfo struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Verified bool   `json:"verified"`
+}
+
+// EndpointInfo describes how a service is called
+type EndpointInfo struct {
+	URL            string `json:"url"`
+	Protocol       string `json:"protocol,omitempty"`
+	Authentication string `json:"authentication,omitempty"` // e.g. api_key, oauth2
+}
+
+// PricingInfo is how a service is charged. Rate and Unit are the headline
+// rate; Tiers lists the rates of tiered pricing.
+type PricingInfo struct {
+	Model    string        `json:"model"`
+	Rate     float64       `json:"rate"`
+	Unit     string        `json:"unit"`
+	Currency string        `json:"currency,omitempty"` // ISO 4217; USD when empty
+	Tiers    []PricingTier `json:"tiers,omitempty"`
+}
+
+// Equal reports whether p and o charge the same
+func (p PricingInfo) Equal(o PricingInfo) bool {
+	if p.Model != o.Model || p.Rate != o.Rate || p.Unit != o.Unit || p.Currency != o.Currency || len(p.Tiers) != len(o.Tiers) {
+		return false
+	}
+	for i := range p.Tiers {
+		if p.Tiers[i] != o.Tiers[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// PricingTier is one rate of tiered pricing. Tiers with UpTo are graduated:
+// each covers a consumer's usage in a billing period up to UpTo of its unit,
+// counted from the start of the period, and the tier after the last UpTo
+// covers the rest.
+type PricingTier struct {
+	Tier        string  `json:"tier"`
+	Rate        float64 `json:"rate"`
+	Unit        string  `json:"unit"`
+	UpTo        float64 `json:"up_to,omitempty"`
+	Description string  `json:"description,omitempty"`
+}
+
+// SLAInfo is the service level a provider commits to
+type SLAInfo struct {
+	Availability float64 `json:"availability"` // Percent, e.g. 99.9
+	MaxLatencyMS int     `json:"max_latency_ms"`
+	SupportLevel string  `json:"support"`
+}
+
+// ComplianceInfo is a service's compliance level, certifications and where
+// it keeps data
+type ComplianceInfo struct {
+	Level          string   `json:"level"`
+	Certifications []string `json:"certifications"`
+	DataResidency  []string `json:"data_residency"` // Country or region codes, e.g. US, EU
+	GDPRCompliant  bool     `json:"gdpr_compliant,omitempty"`
+	HIPAACompliant bool     `json:"hipaa_compliant,omitempty"`
+	// DataProcessingAgreement is the agreement governing how the provider
+	// processes consumers' data, when it has one
+	DataProcessingAgreement *DataProcessingAgreement `json:"data_processing_agreement,omitempty"`
+
+	// Artifacts are the certification artifacts on file for the provider.
+	// The registry attaches them for the policy engine to verify the
+	// certifications against; they aren't part of the published descriptor.
+	Artifacts []ComplianceArtifact `json:"-"`
+}
+
+// ComplianceArtifact is evidence of a certification, such as a SOC 2 report
+// or an ISO 27001 certificate, held in object storage
+type ComplianceArtifact struct {
+	Certification string    `json:"certification"`
+	SHA256        string    `json:"sha256"` // Hex digest of the artifact
+	ExpiresAt     time.Time `json:"expires_at"`
+}
+
+// Current reports whether the artifact is unexpired at now
+func (a ComplianceArtifact) Current(now time.Time) bool {
+	return a.ExpiresAt.After(now)
+}
+
+// DataProcessingAgreement is a provider's data-processing agreement, such as
+// a GDPR Article 28 DPA, and when it lapses
+type DataProcessingAgreement struct {
+	Reference string     `json:"reference,omitempty"` // The agreement's identifier or URL
+	SignedAt  *time.Time `json:"signed_at,omitempty"`
+	ExpiresAt time.Time  `json:"expires_at"`
+}
+
+// Capability is something a service can do
+type Capability struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+// MaxDependencies bounds the services one service can depend on
+const MaxDependencies = 20
+
+// Dependency is another registered service a service is composed of, e.g.
+// the embedding service and vector store behind a RAG service
+type Dependency struct {
+	ServiceID string `json:"service_id"`
+	Role      string `json:"role,omitempty"` // What the service uses it for, e.g. embeddings
+}
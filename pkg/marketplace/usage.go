@@ -0,0 +1,29 @@
+package marketplace
+
+import "time"
+
+// UsageTopic is the Kafka topic the consumption gateway publishes usage
+// events to
+const UsageTopic = "marketplace.usage.events"
+
+// UsageEvent records one call a consumer made to a service through the
+// consumption gateway. Token counts are the ones the service reported, or
+// estimates when TokensEstimated is set. Events are keyed by consumer ID.
+type UsageEvent struct {
+	ID               string       `json:"id"`
+	RequestID        string       `json:"request_id,omitempty"`
+	OccurredAt       time.Time    `json:"occurred_at"`
+	ConsumerID       string       `json:"consumer_id"`
+	ServiceID        string       `json:"service_id"`
+	ProviderID       string       `json:"provider_id"`
+	Method           string       `json:"method"`
+	Path             string       `json:"path"` // Relative to the service endpoint
+	StatusCode       int          `json:"status_code"`
+	PromptTokens     int64        `json:"prompt_tokens"`
+	CompletionTokens int64        `json:"completion_tokens"`
+	TotalTokens      int64        `json:"total_tokens"`
+	TokensEstimated  bool         `json:"tokens_estimated,omitempty"`
+	LatencyMS        float64      `json:"latency_ms"`          // Including the policy checks
+	UpstreamMS       float64      `json:"upstream_latency_ms"` // The service alone
+	Pricing          *PricingInfo `json:"pricing,omitempty"`   // The service's pricing when it was called
+}
@@ -0,0 +1,257 @@
+package marketplace
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// FieldError is a field that failed validation. Field is the JSON path, e.g.
+// sla.availability.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationError lists every field that failed validation
+type ValidationError []FieldError
+
+func (e ValidationError) Error() string {
+	parts := make([]string, len(e))
+	for i, f := range e {
+		parts[i] = f.Field + ": " + f.Message
+	}
+	return "invalid service: " + strings.Join(parts, "; ")
+}
+
+// errs collects field errors, naming them under a path prefix
+type errs struct {
+	prefix string
+	list   *ValidationError
+}
+
+func newErrs() errs {
+	return errs{list: &ValidationError{}}
+}
+
+// in returns a collector for the fields of a section
+func (e errs) in(section string) errs {
+	return errs{prefix: e.prefix + section + ".", list: e.list}
+}
+
+func (e errs) add(field, format string, args ...interface{}) {
+	*e.list = append(*e.list, FieldError{Field: e.prefix + field, Message: fmt.Sprintf(format, args...)})
+}
+
+func (e errs) err() error {
+	if len(*e.list) == 0 {
+		return nil
+	}
+	return *e.list
+}
+
+// Validate checks the descriptor's required fields and each section it has.
+// It returns a ValidationError naming every invalid field.
+func (d *ServiceDescriptor) Validate() error {
+	e := newErrs()
+	if d.ServiceID == "" {
+		e.add("service_id", "is required")
+	}
+	if d.Name == "" {
+		e.add("name", "is required")
+	}
+	for i, tag := range d.Tags {
+		if strings.TrimSpace(tag) == "" {
+			e.add(fmt.Sprintf("tags[%d]", i), "is blank")
+		}
+	}
+	if d.Endpoint != nil {
+		d.Endpoint.validate(e.in("endpoint"))
+	}
+	if d.Compliance != nil {
+		d.Compliance.validate(e.in("compliance"))
+	}
+	if d.SLA != nil {
+		d.SLA.validate(e.in("sla"))
+	}
+	if d.Pricing != nil {
+		d.Pricing.validate(e.in("pricing"))
+	}
+	for i, c := range d.Capabilities {
+		if c.Name == "" {
+			e.add(fmt.Sprintf("capabilities[%d].name", i), "is required")
+		}
+	}
+	if len(d.Dependencies) > MaxDependencies {
+		e.add("dependencies", "must list at most %d services", MaxDependencies)
+	}
+	seen := make(map[string]bool, len(d.Dependencies))
+	for i, dep := range d.Dependencies {
+		field := fmt.Sprintf("dependencies[%d].service_id", i)
+		switch {
+		case dep.ServiceID == "":
+			e.add(field, "is required")
+		case dep.ServiceID == d.ServiceID:
+			e.add(field, "is the service itself")
+		case seen[dep.ServiceID]:
+			e.add(field, "is listed twice")
+		}
+		seen[dep.ServiceID] = true
+	}
+	validateServingRegions(e, d.ServingRegions)
+	switch d.Class {
+	case "", ClassPublic:
+		if d.TenantID != "" {
+			e.add("tenant_id", "is only set on private services")
+		}
+	case ClassPrivate:
+		if strings.TrimSpace(d.TenantID) == "" {
+			e.add("tenant_id", "is required for private services")
+		}
+	default:
+		e.add("class", "must be %s or %s", ClassPublic, ClassPrivate)
+	}
+	if !slices.Contains(Kinds, d.ItemKind()) {
+		e.add("kind", "must be one of %s", strings.Join(Kinds, ", "))
+	}
+	switch {
+	case d.IsPromptTemplate() && d.PromptTemplate == nil:
+		e.add("prompt_template", "is required for prompt templates")
+	case d.IsPromptTemplate():
+		d.PromptTemplate.validate(e.in("prompt_template"))
+	case d.PromptTemplate != nil:
+		e.add("prompt_template", "is only set on prompt templates")
+	}
+	if d.IsPromptTemplate() && d.Endpoint != nil {
+		e.add("endpoint", "is not set on prompt templates")
+	}
+	switch {
+	case d.IsDataset() && d.Dataset == nil:
+		e.add("dataset", "is required for datasets")
+	case d.IsDataset():
+		d.Dataset.validate(e.in("dataset"))
+	case d.Dataset != nil:
+		e.add("dataset", "is only set on datasets")
+	}
+	switch {
+	case d.IsFineTuning() && d.FineTuning == nil:
+		e.add("fine_tuning", "is required for fine-tuning offerings")
+	case d.IsFineTuning():
+		d.FineTuning.validate(e.in("fine_tuning"))
+	case d.FineTuning != nil:
+		e.add("fine_tuning", "is only set on fine-tuning offerings")
+	}
+	return e.err()
+}
+
+// Validate checks the endpoint URL is absolute
+func (i *EndpointInfo) Validate() error {
+	e := newErrs()
+	i.validate(e)
+	return e.err()
+}
+
+func (i *EndpointInfo) validate(e errs) {
+	if i.URL == "" {
+		e.add("url", "is required")
+	} else if u, err := url.Parse(i.URL); err != nil || u.Scheme == "" || u.Host == "" {
+		e.add("url", "must be an absolute URL")
+	}
+}
+
+// Validate checks the compliance level is known, no certification or
+// residency entry is blank and a data-processing agreement has an expiry
+func (i *ComplianceInfo) Validate() error {
+	e := newErrs()
+	i.validate(e)
+	return e.err()
+}
+
+func (i *ComplianceInfo) validate(e errs) {
+	if i.Level != "" && ComplianceRank(i.Level) < 0 {
+		e.add("level", "must be one of %s", strings.Join(ComplianceLevels, ", "))
+	}
+	for n, c := range i.Certifications {
+		if strings.TrimSpace(c) == "" {
+			e.add(fmt.Sprintf("certifications[%d]", n), "is blank")
+		}
+	}
+	for n, r := range i.DataResidency {
+		if strings.TrimSpace(r) == "" {
+			e.add(fmt.Sprintf("data_residency[%d]", n), "is blank")
+		}
+	}
+	if dpa := i.DataProcessingAgreement; dpa != nil {
+		switch {
+		case dpa.ExpiresAt.IsZero():
+			e.add("data_processing_agreement.expires_at", "is required")
+		case dpa.SignedAt != nil && !dpa.SignedAt.Before(dpa.ExpiresAt):
+			e.add("data_processing_agreement.signed_at", "must be before expires_at")
+		}
+	}
+}
+
+// Validate checks availability is a percentage and latency isn't negative
+func (i *SLAInfo) Validate() error {
+	e := newErrs()
+	i.validate(e)
+	return e.err()
+}
+
+func (i *SLAInfo) validate(e errs) {
+	if i.Availability < 0 || i.Availability > 100 {
+		e.add("availability", "must be a percentage between 0 and 100")
+	}
+	if i.MaxLatencyMS < 0 {
+		e.add("max_latency_ms", "must not be negative")
+	}
+}
+
+// Validate checks no rate is negative, graduated tiers are in order and the
+// currency is an ISO 4217 code
+func (i *PricingInfo) Validate() error {
+	e := newErrs()
+	i.validate(e)
+	return e.err()
+}
+
+func (i *PricingInfo) validate(e errs) {
+	if i.Rate < 0 {
+		e.add("rate", "must not be negative")
+	}
+	if i.Currency != "" && !isCurrencyCode(i.Currency) {
+		e.add("currency", "must be a three-letter ISO 4217 code")
+	}
+	graduated := false
+	for _, t := range i.Tiers {
+		graduated = graduated || t.UpTo > 0
+	}
+	var upTo float64
+	for n, t := range i.Tiers {
+		if t.Rate < 0 {
+			e.add(fmt.Sprintf("tiers[%d].rate", n), "must not be negative")
+		}
+		switch {
+		case t.UpTo < 0:
+			e.add(fmt.Sprintf("tiers[%d].up_to", n), "must not be negative")
+		case t.UpTo > 0 && t.UpTo <= upTo:
+			e.add(fmt.Sprintf("tiers[%d].up_to", n), "must be greater than the up_to of the tiers before it")
+		case t.UpTo == 0 && graduated && n < len(i.Tiers)-1:
+			e.add(fmt.Sprintf("tiers[%d].up_to", n), "is required on every graduated tier but the last")
+		}
+		upTo = max(upTo, t.UpTo)
+	}
+}
+
+func isCurrencyCode(s string) bool {
+	if len(s) != 3 {
+		return false
+	}
+	for _, r := range s {
+		if r < 'A' || r > 'Z' {
+			return false
+		}
+	}
+	return true
+}
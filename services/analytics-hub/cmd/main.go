@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/org/llm-marketplace/services/analytics-hub/internal/api"
+	"github.com/org/llm-marketplace/services/analytics-hub/internal/config"
+	"github.com/org/llm-marketplace/services/analytics-hub/internal/consumer"
+	"github.com/org/llm-marketplace/services/analytics-hub/internal/hub"
+	"github.com/org/llm-marketplace/services/analytics-hub/internal/migrations"
+	"github.com/org/llm-marketplace/services/analytics-hub/internal/store"
+)
+
+func main() {
+	// Load configuration; without a file, defaults and ANALYTICS_* variables apply
+	configPath := config.Path()
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		panic(fmt.Sprintf("Failed to load configuration: %v", err))
+	}
+
+	logger, err := newLogger(cfg.Logging)
+	if err != nil {
+		panic(fmt.Sprintf("Failed to initialize logger: %v", err))
+	}
+	defer logger.Sync()
+
+	logger.Info("Starting LLM-Marketplace Analytics Hub",
+		zap.String("version", "1.0.0"),
+		zap.String("environment", os.Getenv("ENVIRONMENT")),
+		zap.String("config", configPath),
+	)
+
+	ctx := context.Background()
+
+	pool, err := store.NewPool(ctx, cfg.Postgres)
+	if err != nil {
+		logger.Fatal("Failed to connect to PostgreSQL", zap.Error(err))
+	}
+	defer pool.Close()
+
+	if cfg.Postgres.AutoMigrate {
+		if _, err := migrations.Up(ctx, pool, logger); err != nil {
+			logger.Fatal("Failed to apply migrations", zap.Error(err))
+		}
+	}
+
+	hubService := hub.NewService(store.New(pool), logger)
+
+	// Events are landed, rolled up and pruned until shutdown
+	workCtx, stopWork := context.WithCancel(ctx)
+	var consumers sync.WaitGroup
+	topics := cfg.Kafka.Topics()
+	for _, source := range hub.Sources {
+		topic, ok := topics[source]
+		if !ok {
+			continue
+		}
+		c := consumer.NewConsumer(source, topic, cfg.Kafka, hubService, logger)
+		consumers.Add(1)
+		go func() {
+			defer consumers.Done()
+			c.Start(workCtx)
+		}()
+	}
+	go hubService.StartRollups(workCtx, cfg.Rollups.Interval, cfg.Rollups.Lookback)
+	go hubService.StartPruning(workCtx, cfg.Rollups.PruneInterval, cfg.Rollups.Retention)
+
+	// REST API
+	if cfg.Server.Mode == "production" {
+		gin.SetMode(gin.ReleaseMode)
+	}
+	router := gin.New()
+	router.HandleMethodNotAllowed = true
+	router.Use(gin.Recovery())
+
+	router.GET("/health", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{
+			"status":    "healthy",
+			"timestamp": time.Now().UTC(),
+		})
+	})
+	router.GET("/ready", api.Readiness(map[string]api.Check{
+		"postgres": pool.Ping,
+	}, 2*time.Second))
+	router.GET("/metrics", gin.WrapH(promhttp.Handler()))
+	api.RegisterRoutes(router, hubService, cfg.Server.ConsumerHeader, cfg.Server.ProviderHeader, logger)
+
+	addr := fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Server.Port)
+	server := &http.Server{
+		Addr:         addr,
+		Handler:      router,
+		ReadTimeout:  cfg.Server.ReadTimeout,
+		WriteTimeout: cfg.Server.WriteTimeout,
+		IdleTimeout:  cfg.Server.IdleTimeout,
+	}
+	go func() {
+		logger.Info("Starting HTTP server", zap.String("address", addr))
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			logger.Fatal("Failed to start HTTP server", zap.Error(err))
+		}
+	}()
+
+	// Graceful shutdown
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+
+	logger.Info("Shutting down server...")
+
+	shutdownCtx, cancel := context.WithTimeout(ctx, cfg.Server.ShutdownTimeout)
+	defer cancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		logger.Error("Server forced to shutdown", zap.Error(err))
+	}
+
+	// A batch being landed is rolled back and redelivered on the next start
+	stopWork()
+	consumers.Wait()
+
+	logger.Info("Server exited")
+}
+
+func newLogger(cfg config.LoggingConfig) (*zap.Logger, error) {
+	level, err := zapcore.ParseLevel(cfg.Level)
+	if err != nil {
+		return nil, err
+	}
+	zapConfig := zap.NewProductionConfig()
+	if cfg.Format == "console" {
+		zapConfig = zap.NewDevelopmentConfig()
+	}
+	zapConfig.Level = zap.NewAtomicLevelAt(level)
+	return zapConfig.Build()
+}
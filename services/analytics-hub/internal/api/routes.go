@@ -0,0 +1,196 @@
+// Package api serves the analytics hub query API
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/org/llm-marketplace/pkg/marketplace/problem"
+	"go.uber.org/zap"
+
+	"github.com/org/llm-marketplace/services/analytics-hub/internal/hub"
+)
+
+// dateLayout is the form of from and to
+const dateLayout = "2006-01-02"
+
+// RegisterRoutes registers the API routes. consumerHeader and providerHeader
+// name the headers carrying the caller the gateway authenticated: providers
+// only see the statistics of their own services and consumers see none.
+// Requests with neither are trusted as operators.
+func RegisterRoutes(router *gin.Engine, svc *hub.Service, consumerHeader, providerHeader string, logger *zap.Logger) {
+	h := &handlers{svc: svc, consumerHeader: consumerHeader, providerHeader: providerHeader, logger: logger}
+
+	api := router.Group("/api/v1")
+	{
+		api.GET("/services/daily", h.serviceStats)
+		api.GET("/services/:id/daily", h.serviceStats)
+		api.GET("/searches/daily", h.searchStats)
+		api.GET("/interactions", h.interactions)
+	}
+
+	router.NoRoute(func(c *gin.Context) {
+		abort(c, problem.NotFound, "No route matches "+c.Request.URL.Path)
+	})
+	router.NoMethod(func(c *gin.Context) {
+		abort(c, problem.MethodNotAllowed, c.Request.Method+" is not supported on "+c.Request.URL.Path)
+	})
+}
+
+type handlers struct {
+	svc            *hub.Service
+	consumerHeader string
+	providerHeader string
+	logger         *zap.Logger
+}
+
+// serviceStats handles GET /api/v1/services/daily and
+// GET /api/v1/services/:id/daily
+func (h *handlers) serviceStats(c *gin.Context) {
+	filter, ok := h.filter(c)
+	if !ok {
+		return
+	}
+	filter.ServiceID = c.Query("service_id")
+	if id := c.Param("id"); id != "" {
+		filter.ServiceID = id
+	}
+	filter.ProviderID = c.Query("provider_id")
+
+	if c.GetHeader(h.consumerHeader) != "" {
+		abortWithError(c, fmt.Errorf("%w: consumers can't read service statistics", hub.ErrForbidden), h.logger)
+		return
+	}
+	// Providers are held to their own services
+	if provider := c.GetHeader(h.providerHeader); provider != "" {
+		if filter.ProviderID != "" && filter.ProviderID != provider {
+			abortWithError(c, fmt.Errorf("%w: providers can only read the statistics of their own services", hub.ErrForbidden), h.logger)
+			return
+		}
+		filter.ProviderID = provider
+	}
+
+	days, err := h.svc.ServiceStats(c.Request.Context(), filter)
+	if err != nil {
+		abortWithError(c, err, h.logger)
+		return
+	}
+	if days == nil {
+		days = []hub.ServiceDay{}
+	}
+	c.JSON(http.StatusOK, gin.H{"days": days, "total": len(days)})
+}
+
+// searchStats handles GET /api/v1/searches/daily
+func (h *handlers) searchStats(c *gin.Context) {
+	if !h.operator(c, "read search statistics") {
+		return
+	}
+	filter, ok := h.filter(c)
+	if !ok {
+		return
+	}
+
+	days, err := h.svc.SearchStats(c.Request.Context(), filter)
+	if err != nil {
+		abortWithError(c, err, h.logger)
+		return
+	}
+	if days == nil {
+		days = []hub.SearchDay{}
+	}
+	c.JSON(http.StatusOK, gin.H{"days": days, "total": len(days)})
+}
+
+// interactions handles GET /api/v1/interactions
+func (h *handlers) interactions(c *gin.Context) {
+	if !h.operator(c, "read user interactions") {
+		return
+	}
+	filter, ok := h.filter(c)
+	if !ok {
+		return
+	}
+	filter.UserID = c.Query("user_id")
+	filter.ServiceID = c.Query("service_id")
+
+	interactions, err := h.svc.Interactions(c.Request.Context(), filter)
+	if err != nil {
+		abortWithError(c, err, h.logger)
+		return
+	}
+	if interactions == nil {
+		interactions = []hub.Interaction{}
+	}
+	c.JSON(http.StatusOK, gin.H{"interactions": interactions, "total": len(interactions)})
+}
+
+// filter reads the range and paging every query accepts
+func (h *handlers) filter(c *gin.Context) (hub.StatsFilter, bool) {
+	var filter hub.StatsFilter
+	var err error
+	for _, p := range []struct {
+		name string
+		day  *time.Time
+	}{{"from", &filter.From}, {"to", &filter.To}} {
+		if v := c.Query(p.name); v != "" {
+			if *p.day, err = time.Parse(dateLayout, v); err != nil {
+				abort(c, problem.InvalidRequest, fmt.Sprintf("%s: %q is not a YYYY-MM-DD date", p.name, v))
+				return filter, false
+			}
+		}
+	}
+	for _, p := range []struct {
+		name  string
+		value *int
+	}{{"limit", &filter.Limit}, {"offset", &filter.Offset}} {
+		if v := c.Query(p.name); v != "" {
+			if *p.value, err = strconv.Atoi(v); err != nil {
+				abort(c, problem.InvalidRequest, fmt.Sprintf("%s: %q is not a number", p.name, v))
+				return filter, false
+			}
+		}
+	}
+	return filter, true
+}
+
+// operator aborts with 403 unless the request carries neither caller header
+func (h *handlers) operator(c *gin.Context, action string) bool {
+	if c.GetHeader(h.consumerHeader) != "" || c.GetHeader(h.providerHeader) != "" {
+		abortWithError(c, fmt.Errorf("%w: only operators can %s", hub.ErrForbidden, action), h.logger)
+		return false
+	}
+	return true
+}
+
+// Check is a readiness check of one dependency
+type Check func(ctx context.Context) error
+
+// Readiness reports 200 when every check passes and 503 naming the failing
+// ones otherwise
+func Readiness(checks map[string]Check, timeout time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+
+		status := http.StatusOK
+		results := make(map[string]string, len(checks))
+		for name, check := range checks {
+			if err := check(ctx); err != nil {
+				status = http.StatusServiceUnavailable
+				results[name] = err.Error()
+				continue
+			}
+			results[name] = "ok"
+		}
+		ready := "ready"
+		if status != http.StatusOK {
+			ready = "not_ready"
+		}
+		c.JSON(status, gin.H{"status": ready, "checks": results, "timestamp": time.Now().UTC()})
+	}
+}
@@ -0,0 +1,174 @@
+// Package config loads the analytics hub configuration from built-in
+// defaults, an optional YAML file and ANALYTICS_* environment variables, in
+// that order.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/org/llm-marketplace/pkg/marketplace/envconfig"
+	"gopkg.in/yaml.v3"
+)
+
+type Config struct {
+	Server   ServerConfig   `yaml:"server"`
+	Postgres PostgresConfig `yaml:"postgres"`
+	Kafka    KafkaConfig    `yaml:"kafka"`
+	Rollups  RollupsConfig  `yaml:"rollups"`
+	Logging  LoggingConfig  `yaml:"logging"`
+}
+
+type ServerConfig struct {
+	Host         string        `yaml:"host"`
+	Port         int           `yaml:"port"`
+	Mode         string        `yaml:"mode"` // development, production
+	ReadTimeout  time.Duration `yaml:"read_timeout"`
+	WriteTimeout time.Duration `yaml:"write_timeout"`
+	IdleTimeout  time.Duration `yaml:"idle_timeout"`
+
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+
+	// ConsumerHeader and ProviderHeader carry the authenticated caller, set
+	// by the gateway. Providers only see the statistics of their services
+	// and consumers see nothing; requests with neither act as an operator.
+	ConsumerHeader string `yaml:"consumer_header"`
+	ProviderHeader string `yaml:"provider_header"`
+}
+
+type PostgresConfig struct {
+	Host        string        `yaml:"host"`
+	Port        int           `yaml:"port"`
+	Database    string        `yaml:"database"`
+	User        string        `yaml:"user"`
+	Password    string        `yaml:"password"`
+	SSLMode     string        `yaml:"ssl_mode"`
+	MaxConns    int           `yaml:"max_conns"`
+	MinConns    int           `yaml:"min_conns"`
+	ConnTimeout time.Duration `yaml:"conn_timeout"`
+	AutoMigrate bool          `yaml:"auto_migrate"` // Apply pending migrations at startup
+}
+
+// DSN returns the connection string for the pool
+func (c PostgresConfig) DSN() string {
+	return fmt.Sprintf("host=%s port=%d dbname=%s user=%s password='%s' sslmode=%s",
+		c.Host, c.Port, c.Database, c.User, dsnEscaper.Replace(c.Password), c.SSLMode)
+}
+
+var dsnEscaper = strings.NewReplacer(`\`, `\\`, `'`, `\'`)
+
+// KafkaConfig is where events are read from. An empty topic disables its
+// source.
+type KafkaConfig struct {
+	Brokers         []string      `yaml:"brokers"`
+	ConsumerGroup   string        `yaml:"consumer_group"`
+	SearchTopic     string        `yaml:"search_topic"`      // Searches, clicks and impressions published by discovery
+	UsageTopic      string        `yaml:"usage_topic"`       // Calls published by the consumption gateway
+	ValidationTopic string        `yaml:"validation_topic"`  // Descriptor checks published by the registry
+	BatchSize       int           `yaml:"batch_size"`        // Events landed per transaction
+	FlushInterval   time.Duration `yaml:"flush_interval"`    // Longest a partial batch waits
+	RetryBackoff    time.Duration `yaml:"retry_backoff"`     // Delay after the first failure to land a batch, doubled after each
+	MaxRetryBackoff time.Duration `yaml:"max_retry_backoff"` // Failed batches are retried until they succeed; later events wait
+}
+
+// Topics returns the topic of each enabled source
+func (c KafkaConfig) Topics() map[string]string {
+	topics := make(map[string]string)
+	for source, topic := range map[string]string{
+		"search":     c.SearchTopic,
+		"usage":      c.UsageTopic,
+		"validation": c.ValidationTopic,
+	} {
+		if topic != "" {
+			topics[source] = topic
+		}
+	}
+	return topics
+}
+
+// RollupsConfig controls the daily rollups and how long events are kept
+type RollupsConfig struct {
+	Interval      time.Duration `yaml:"interval"`       // How often recent days are rolled up again
+	Lookback      time.Duration `yaml:"lookback"`       // How far back late events are counted
+	Retention     time.Duration `yaml:"retention"`      // How long landed events are kept; rollups are kept
+	PruneInterval time.Duration `yaml:"prune_interval"` // How often expired events are removed
+}
+
+type LoggingConfig struct {
+	Level  string `yaml:"level"`  // debug, info, warn, error
+	Format string `yaml:"format"` // json, console
+}
+
+// DefaultPath is the config file used when CONFIG_PATH is not set
+const DefaultPath = "config.yaml"
+
+// Path returns the config file to load: CONFIG_PATH if set, otherwise
+// config.yaml when it exists in the working directory. It is empty when the
+// service is configured by defaults and environment variables alone.
+func Path() string {
+	if path := os.Getenv("CONFIG_PATH"); path != "" {
+		return path
+	}
+	if _, err := os.Stat(DefaultPath); err == nil {
+		return DefaultPath
+	}
+	return ""
+}
+
+// Load builds the configuration from the built-in defaults, overridden by the
+// file at path, if any, then by ANALYTICS_* environment variables
+func Load(path string) (*Config, error) {
+	var cfg Config
+	cfg.setDefaults()
+
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read config file: %w", err)
+		}
+		if err := yaml.Unmarshal([]byte(os.ExpandEnv(string(data))), &cfg); err != nil {
+			return nil, fmt.Errorf("failed to parse config: %w", err)
+		}
+	}
+
+	if err := envconfig.Apply(&cfg, "ANALYTICS_", os.Getenv); err != nil {
+		return nil, fmt.Errorf("invalid environment override: %w", err)
+	}
+
+	if err := validate(&cfg); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+	return &cfg, nil
+}
+
+func validate(cfg *Config) error {
+	var errs []error
+	if cfg.Server.Port <= 0 || cfg.Server.Port > 65535 {
+		errs = append(errs, fmt.Errorf("server.port %d is not a valid port", cfg.Server.Port))
+	}
+	if cfg.Postgres.Host == "" || cfg.Postgres.Database == "" {
+		errs = append(errs, errors.New("postgres.host and postgres.database are required"))
+	}
+	if len(cfg.Kafka.Brokers) == 0 || cfg.Kafka.ConsumerGroup == "" {
+		errs = append(errs, errors.New("kafka.brokers and kafka.consumer_group are required"))
+	}
+	if len(cfg.Kafka.Topics()) == 0 {
+		errs = append(errs, errors.New("at least one of kafka.search_topic, kafka.usage_topic and kafka.validation_topic is required"))
+	}
+	if cfg.Kafka.BatchSize <= 0 || cfg.Kafka.FlushInterval <= 0 {
+		errs = append(errs, errors.New("kafka.batch_size and kafka.flush_interval must be positive"))
+	}
+	if cfg.Kafka.RetryBackoff <= 0 || cfg.Kafka.MaxRetryBackoff < cfg.Kafka.RetryBackoff {
+		errs = append(errs, errors.New("kafka.retry_backoff must be positive and no longer than kafka.max_retry_backoff"))
+	}
+	if cfg.Rollups.Interval <= 0 || cfg.Rollups.Lookback < 0 {
+		errs = append(errs, errors.New("rollups.interval must be positive and rollups.lookback not negative"))
+	}
+	if cfg.Rollups.Retention <= cfg.Rollups.Lookback || cfg.Rollups.PruneInterval <= 0 {
+		errs = append(errs, errors.New("rollups.retention must exceed rollups.lookback and rollups.prune_interval must be positive"))
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,50 @@
+package config
+
+import (
+	"time"
+
+	"github.com/org/llm-marketplace/pkg/marketplace"
+)
+
+// setDefaults fills in the settings used when neither the config file nor the
+// environment sets them. They match config.yaml, with dependencies expected
+// on localhost.
+func (c *Config) setDefaults() {
+	c.Server.Host = "0.0.0.0"
+	c.Server.Port = 3040
+	c.Server.Mode = "development"
+	c.Server.ReadTimeout = 30 * time.Second
+	c.Server.WriteTimeout = 30 * time.Second
+	c.Server.IdleTimeout = 120 * time.Second
+	c.Server.ShutdownTimeout = 30 * time.Second
+	c.Server.ConsumerHeader = "X-Consumer-ID"
+	c.Server.ProviderHeader = "X-Provider-ID"
+
+	c.Postgres.Host = "localhost"
+	c.Postgres.Port = 5432
+	c.Postgres.Database = "analytics"
+	c.Postgres.User = "analytics"
+	c.Postgres.SSLMode = "prefer"
+	c.Postgres.MaxConns = 20
+	c.Postgres.MinConns = 2
+	c.Postgres.ConnTimeout = 5 * time.Second
+	c.Postgres.AutoMigrate = true
+
+	c.Kafka.Brokers = []string{"localhost:9092"}
+	c.Kafka.ConsumerGroup = "analytics-hub"
+	c.Kafka.SearchTopic = "marketplace.search.events"
+	c.Kafka.UsageTopic = marketplace.UsageTopic
+	c.Kafka.ValidationTopic = marketplace.ValidationTopic
+	c.Kafka.BatchSize = 500
+	c.Kafka.FlushInterval = time.Second
+	c.Kafka.RetryBackoff = time.Second
+	c.Kafka.MaxRetryBackoff = time.Minute
+
+	c.Rollups.Interval = 5 * time.Minute
+	c.Rollups.Lookback = 48 * time.Hour
+	c.Rollups.Retention = 90 * 24 * time.Hour
+	c.Rollups.PruneInterval = time.Hour
+
+	c.Logging.Level = "info"
+	c.Logging.Format = "json"
+}
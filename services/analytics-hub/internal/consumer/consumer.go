@@ -0,0 +1,160 @@
+// Package consumer reads the events the marketplace services publish and
+// lands them in batches
+package consumer
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"go.uber.org/zap"
+
+	"github.com/org/llm-marketplace/services/analytics-hub/internal/config"
+	"github.com/org/llm-marketplace/services/analytics-hub/internal/hub"
+)
+
+// Reader fetches events; *kafka.Reader satisfies it
+type Reader interface {
+	FetchMessage(ctx context.Context) (kafka.Message, error)
+	CommitMessages(ctx context.Context, msgs ...kafka.Message) error
+	Close() error
+}
+
+// Lander lands events; *hub.Service satisfies it
+type Lander interface {
+	Land(ctx context.Context, events []*hub.Event) error
+	Invalid(source string)
+}
+
+// Consumer lands the events of one source in batches. Offsets are committed
+// once a batch is landed, so each event is landed at least once; the store
+// drops redelivered events.
+type Consumer struct {
+	source string
+	topic  string
+	reader Reader
+	lander Lander
+	config config.KafkaConfig
+	logger *zap.Logger
+}
+
+// NewConsumer creates a consumer reading source's events from topic
+func NewConsumer(source, topic string, cfg config.KafkaConfig, lander Lander, logger *zap.Logger) *Consumer {
+	return &Consumer{
+		source: source,
+		topic:  topic,
+		reader: kafka.NewReader(kafka.ReaderConfig{
+			Brokers:  cfg.Brokers,
+			Topic:    topic,
+			GroupID:  cfg.ConsumerGroup,
+			MinBytes: 1,
+			MaxBytes: 10 << 20,
+		}),
+		lander: lander,
+		config: cfg,
+		logger: logger.With(zap.String("source", source)),
+	}
+}
+
+// SetReader replaces the Kafka reader
+func (c *Consumer) SetReader(reader Reader) {
+	c.reader = reader
+}
+
+// Start lands events until ctx is cancelled. A batch closes when it holds
+// BatchSize messages or FlushInterval after its first. A batch that fails
+// to land is retried with backoff; the events after it wait, so none are
+// lost while the database is unavailable.
+func (c *Consumer) Start(ctx context.Context) {
+	defer c.reader.Close()
+
+	c.logger.Info("Event consumer started", zap.String("topic", c.topic))
+
+	for {
+		msgs, err := c.fetch(ctx)
+		if len(msgs) == 0 {
+			if ctx.Err() != nil {
+				c.logger.Info("Event consumer stopped")
+				return
+			}
+			c.logger.Warn("Failed to fetch event", zap.Error(err))
+			if !sleep(ctx, time.Second) {
+				return
+			}
+			continue
+		}
+
+		events := c.decode(msgs)
+		backoff := c.config.RetryBackoff
+		for {
+			err := c.lander.Land(ctx, events)
+			if err == nil {
+				break
+			}
+			c.logger.Warn("Failed to land events, retrying",
+				zap.Int("count", len(events)),
+				zap.Int64("offset", msgs[0].Offset),
+				zap.Duration("retry_in", backoff),
+				zap.Error(err),
+			)
+			if !sleep(ctx, backoff) {
+				return
+			}
+			backoff = min(backoff*2, c.config.MaxRetryBackoff)
+		}
+
+		if err := c.reader.CommitMessages(ctx, msgs...); err != nil && ctx.Err() == nil {
+			// The events are landed; on redelivery they are skipped
+			c.logger.Warn("Failed to commit event offsets", zap.Error(err))
+		}
+	}
+}
+
+// fetch reads the next batch. It returns the messages read so far with the
+// error that ended the batch early.
+func (c *Consumer) fetch(ctx context.Context) ([]kafka.Message, error) {
+	msg, err := c.reader.FetchMessage(ctx)
+	if err != nil {
+		return nil, err
+	}
+	msgs := []kafka.Message{msg}
+
+	flushCtx, cancel := context.WithTimeout(ctx, c.config.FlushInterval)
+	defer cancel()
+	for len(msgs) < c.config.BatchSize {
+		msg, err := c.reader.FetchMessage(flushCtx)
+		if err != nil {
+			if errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil {
+				return msgs, nil
+			}
+			return msgs, err
+		}
+		msgs = append(msgs, msg)
+	}
+	return msgs, nil
+}
+
+// decode reads a batch of messages, skipping those that can never be landed
+func (c *Consumer) decode(msgs []kafka.Message) []*hub.Event {
+	events := make([]*hub.Event, 0, len(msgs))
+	for _, msg := range msgs {
+		event, err := hub.Decode(c.source, msg.Value)
+		if err != nil {
+			c.logger.Error("Skipping event", zap.Int64("offset", msg.Offset), zap.String("key", string(msg.Key)), zap.Error(err))
+			c.lander.Invalid(c.source)
+			continue
+		}
+		events = append(events, event)
+	}
+	return events
+}
+
+func sleep(ctx context.Context, d time.Duration) bool {
+	select {
+	case <-time.After(d):
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,253 @@
+// Package hub lands the search, usage and validation events the marketplace
+// services publish, rolls them up by day into service funnels, search
+// statistics and user interactions, and answers the queries dashboards and
+// recommendation training make of them.
+package hub
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/org/llm-marketplace/pkg/marketplace"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+)
+
+// Event sources, one per topic
+const (
+	SourceSearch     = "search"
+	SourceUsage      = "usage"
+	SourceValidation = "validation"
+)
+
+// Sources lists every event source
+var Sources = []string{SourceSearch, SourceUsage, SourceValidation}
+
+// Event types of the search source, as discovery publishes them
+const (
+	TypeSearch     = "search"
+	TypeClick      = "click"
+	TypeImpression = "recommendation_impression"
+)
+
+var (
+	// ErrInvalidEvent marks an event that can never be landed
+	ErrInvalidEvent = errors.New("invalid event")
+	// ErrInvalidQuery is returned for a query that can't be answered
+	ErrInvalidQuery = errors.New("invalid query")
+	// ErrForbidden is returned when a caller asks for data that isn't theirs
+	ErrForbidden = errors.New("forbidden")
+)
+
+var eventsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "analytics_events_total",
+	Help: "Events by source and result: landed, duplicate or invalid",
+}, []string{"source", "result"})
+
+// Event is a landed event with the fields rollups and training read. ActorID
+// is the user of search events, the consumer of usage events and the
+// provider of validation events.
+type Event struct {
+	Source     string
+	ID         string
+	Type       string
+	OccurredAt time.Time
+	ActorID    string
+	ServiceID  string   // The service clicked, called or validated
+	ProviderID string   // The provider of the service called or validated
+	QueryID    string   // The search a search or click event belongs to
+	Query      string   // The text of a search
+	ServiceIDs []string // The results of a search, or the services of an impression
+	Results    int      // The total results of a search
+	StatusCode int      // The status of a call
+	Tokens     int64    // The tokens of a call
+	LatencyMS  float64  // Of a search or a call
+	Valid      bool     // Whether a validation passed
+	Payload    json.RawMessage
+}
+
+// searchEnvelope is the event discovery publishes to the search topic
+type searchEnvelope struct {
+	ID         string    `json:"event_id"`
+	Type       string    `json:"event_type"`
+	OccurredAt time.Time `json:"occurred_at"`
+	UserID     string    `json:"user_id"`
+	Search     *struct {
+		QueryID   string   `json:"query_id"`
+		Query     string   `json:"query"`
+		Total     int      `json:"total"`
+		ResultIDs []string `json:"result_ids"`
+		LatencyMS float64  `json:"latency_ms"`
+	} `json:"search"`
+	Click *struct {
+		QueryID   string `json:"query_id"`
+		ServiceID string `json:"service_id"`
+	} `json:"click"`
+	Impression *struct {
+		ServiceIDs []string `json:"service_ids"`
+	} `json:"impression"`
+}
+
+// Decode reads an event published to source's topic. It returns an error
+// wrapping ErrInvalidEvent for a payload that can never be landed.
+func Decode(source string, payload []byte) (*Event, error) {
+	event := &Event{Source: source, Payload: payload}
+	var err error
+	switch source {
+	case SourceSearch:
+		err = decodeSearch(event, payload)
+	case SourceUsage:
+		err = decodeUsage(event, payload)
+	case SourceValidation:
+		err = decodeValidation(event, payload)
+	default:
+		return nil, fmt.Errorf("%w: unknown source %q", ErrInvalidEvent, source)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if event.ID == "" || event.OccurredAt.IsZero() {
+		return nil, fmt.Errorf("%w: no id or occurred_at", ErrInvalidEvent)
+	}
+	event.OccurredAt = event.OccurredAt.UTC()
+	return event, nil
+}
+
+func decodeSearch(event *Event, payload []byte) error {
+	var e searchEnvelope
+	if err := json.Unmarshal(payload, &e); err != nil {
+		return fmt.Errorf("%w: malformed search event: %v", ErrInvalidEvent, err)
+	}
+	event.ID, event.Type, event.OccurredAt, event.ActorID = e.ID, e.Type, e.OccurredAt, e.UserID
+	switch {
+	case e.Type == TypeSearch && e.Search != nil:
+		event.QueryID = e.Search.QueryID
+		event.Query = e.Search.Query
+		event.ServiceIDs = e.Search.ResultIDs
+		event.Results = e.Search.Total
+		event.LatencyMS = e.Search.LatencyMS
+	case e.Type == TypeClick && e.Click != nil && e.Click.ServiceID != "":
+		event.QueryID = e.Click.QueryID
+		event.ServiceID = e.Click.ServiceID
+	case e.Type == TypeImpression && e.Impression != nil:
+		event.ServiceIDs = e.Impression.ServiceIDs
+	default:
+		return fmt.Errorf("%w: %q search event without its payload", ErrInvalidEvent, e.Type)
+	}
+	return nil
+}
+
+func decodeUsage(event *Event, payload []byte) error {
+	var e marketplace.UsageEvent
+	if err := json.Unmarshal(payload, &e); err != nil {
+		return fmt.Errorf("%w: malformed usage event: %v", ErrInvalidEvent, err)
+	}
+	if e.ConsumerID == "" || e.ServiceID == "" {
+		return fmt.Errorf("%w: consumer_id and service_id are required", ErrInvalidEvent)
+	}
+	event.ID, event.Type, event.OccurredAt = e.ID, SourceUsage, e.OccurredAt
+	event.ActorID, event.ServiceID, event.ProviderID = e.ConsumerID, e.ServiceID, e.ProviderID
+	event.StatusCode = e.StatusCode
+	event.Tokens = e.TotalTokens
+	event.LatencyMS = e.LatencyMS
+	return nil
+}
+
+func decodeValidation(event *Event, payload []byte) error {
+	var e marketplace.ValidationEvent
+	if err := json.Unmarshal(payload, &e); err != nil {
+		return fmt.Errorf("%w: malformed validation event: %v", ErrInvalidEvent, err)
+	}
+	event.ID, event.Type, event.OccurredAt = e.ID, SourceValidation, e.OccurredAt
+	event.ActorID, event.ServiceID, event.ProviderID = e.ProviderID, e.ServiceID, e.ProviderID
+	event.Valid = e.Valid
+	return nil
+}
+
+// Store persists landed events and rollups
+type Store interface {
+	// Land stores events, skipping those already landed, and returns how
+	// many were new
+	Land(ctx context.Context, events []*Event) (int, error)
+	// ScanEvents calls fn with every event that occurred in [from, to),
+	// oldest first
+	ScanEvents(ctx context.Context, from, to time.Time, fn func(*Event) error) error
+	// ReplaceRollups replaces every rollup of day with rollups
+	ReplaceRollups(ctx context.Context, day time.Time, rollups *Rollups) error
+	// PruneEvents removes events that occurred before t
+	PruneEvents(ctx context.Context, before time.Time) (int64, error)
+
+	ServiceDaily(ctx context.Context, filter StatsFilter) ([]ServiceDay, error)
+	SearchDaily(ctx context.Context, filter StatsFilter) ([]SearchDay, error)
+	Interactions(ctx context.Context, filter StatsFilter) ([]Interaction, error)
+}
+
+// Service lands events and answers queries
+type Service struct {
+	store  Store
+	logger *zap.Logger
+	now    func() time.Time
+}
+
+// NewService creates a service over store
+func NewService(store Store, logger *zap.Logger) *Service {
+	return &Service{store: store, logger: logger, now: time.Now}
+}
+
+// SetClock replaces the clock that decides which days are rolled up and
+// the default query ranges
+func (s *Service) SetClock(now func() time.Time) {
+	s.now = now
+}
+
+// Land stores a batch of events of one source. Redelivered events are
+// dropped.
+func (s *Service) Land(ctx context.Context, events []*Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+	landed, err := s.store.Land(ctx, events)
+	if err != nil {
+		return fmt.Errorf("failed to land events: %w", err)
+	}
+	source := events[0].Source
+	eventsTotal.WithLabelValues(source, "landed").Add(float64(landed))
+	if duplicates := len(events) - landed; duplicates > 0 {
+		eventsTotal.WithLabelValues(source, "duplicate").Add(float64(duplicates))
+		s.logger.Debug("Skipped landed events", zap.String("source", source), zap.Int("count", duplicates))
+	}
+	return nil
+}
+
+// Invalid counts an event of source that was skipped as invalid
+func (s *Service) Invalid(source string) {
+	eventsTotal.WithLabelValues(source, "invalid").Inc()
+}
+
+// StartPruning removes events older than retention every interval until
+// ctx is cancelled. Rollups are kept.
+func (s *Service) StartPruning(ctx context.Context, interval, retention time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		n, err := s.store.PruneEvents(ctx, s.now().Add(-retention))
+		if err != nil {
+			if ctx.Err() == nil {
+				s.logger.Warn("Failed to prune events", zap.Error(err))
+			}
+			continue
+		}
+		if n > 0 {
+			s.logger.Info("Pruned events", zap.Int64("count", n))
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package hub
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// dateLayout is the form of days in queries and errors
+const dateLayout = "2006-01-02"
+
+// maxDays bounds the days a query may cover
+const maxDays = 366
+
+// Query limits
+const (
+	DefaultLimit = 100
+	MaxLimit     = 10000
+)
+
+// StatsFilter selects rollups of the days in [From, To]. Empty IDs match
+// everything.
+type StatsFilter struct {
+	From       time.Time // Inclusive day
+	To         time.Time // Inclusive day
+	ServiceID  string
+	ProviderID string
+	UserID     string
+	Limit      int
+	Offset     int
+}
+
+// normalize defaults the range to the last 30 days and the limit to
+// DefaultLimit, and checks both
+func (s *Service) normalize(filter *StatsFilter) error {
+	if filter.To.IsZero() {
+		filter.To = s.now()
+	}
+	filter.To = truncateDay(filter.To)
+	if filter.From.IsZero() {
+		filter.From = filter.To.AddDate(0, 0, -29)
+	}
+	filter.From = truncateDay(filter.From)
+	switch {
+	case filter.From.After(filter.To):
+		return fmt.Errorf("%w: from must not be after to", ErrInvalidQuery)
+	case filter.To.Sub(filter.From) >= maxDays*24*time.Hour:
+		return fmt.Errorf("%w: a query spans at most %d days", ErrInvalidQuery, maxDays)
+	case filter.Limit < 0 || filter.Limit > MaxLimit || filter.Offset < 0:
+		return fmt.Errorf("%w: limit must be between 1 and %d and offset not negative", ErrInvalidQuery, MaxLimit)
+	}
+	if filter.Limit == 0 {
+		filter.Limit = DefaultLimit
+	}
+	return nil
+}
+
+// ServiceStats returns the daily funnels filter selects, by day then
+// service
+func (s *Service) ServiceStats(ctx context.Context, filter StatsFilter) ([]ServiceDay, error) {
+	if err := s.normalize(&filter); err != nil {
+		return nil, err
+	}
+	days, err := s.store.ServiceDaily(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query service rollups: %w", err)
+	}
+	for i := range days {
+		if days[i].Calls > 0 {
+			days[i].AvgLatencyMS = days[i].LatencyMSSum / float64(days[i].Calls)
+		}
+	}
+	return days, nil
+}
+
+// SearchStats returns the most frequent queries of each day in the range,
+// by day then searches, most first
+func (s *Service) SearchStats(ctx context.Context, filter StatsFilter) ([]SearchDay, error) {
+	if err := s.normalize(&filter); err != nil {
+		return nil, err
+	}
+	days, err := s.store.SearchDaily(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query search rollups: %w", err)
+	}
+	for i := range days {
+		if days[i].Searches > 0 {
+			days[i].AvgLatencyMS = days[i].LatencyMSSum / float64(days[i].Searches)
+		}
+	}
+	return days, nil
+}
+
+// Interactions returns the user interactions filter selects, by day, user
+// and service, for recommendation training to page through
+func (s *Service) Interactions(ctx context.Context, filter StatsFilter) ([]Interaction, error) {
+	if err := s.normalize(&filter); err != nil {
+		return nil, err
+	}
+	interactions, err := s.store.Interactions(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query interactions: %w", err)
+	}
+	return interactions, nil
+}
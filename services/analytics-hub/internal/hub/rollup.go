@@ -0,0 +1,264 @@
+package hub
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// ServiceDay is the funnel of a service on a day: how often it was shown in
+// search results and recommendations, clicked, called and validated.
+// Failed calls are those the service answered with a 5xx status; their
+// tokens are not counted.
+type ServiceDay struct {
+	Day               time.Time `json:"day"`
+	ServiceID         string    `json:"service_id"`
+	ProviderID        string    `json:"provider_id,omitempty"`
+	Impressions       int64     `json:"impressions"`
+	Clicks            int64     `json:"clicks"`
+	Calls             int64     `json:"calls"`
+	Errors            int64     `json:"errors"`
+	Consumers         int64     `json:"consumers"` // Distinct consumers that called it
+	TotalTokens       int64     `json:"total_tokens"`
+	AvgLatencyMS      float64   `json:"avg_latency_ms"`
+	Validations       int64     `json:"validations"`
+	FailedValidations int64     `json:"failed_validations"`
+
+	LatencyMSSum float64 `json:"-"`
+}
+
+// SearchDay counts the searches for a query on a day. Queries are
+// normalized to lower case with single spaces.
+type SearchDay struct {
+	Day          time.Time `json:"day"`
+	Query        string    `json:"query"`
+	Searches     int64     `json:"searches"`
+	ZeroResults  int64     `json:"zero_results"`
+	Clicks       int64     `json:"clicks"` // On results of these searches
+	AvgLatencyMS float64   `json:"avg_latency_ms"`
+
+	LatencyMSSum float64 `json:"-"`
+}
+
+// Interaction is what a user did with a service on a day. Calls are
+// attributed to the consumer ID the gateway authenticated.
+type Interaction struct {
+	Day         time.Time `json:"day"`
+	UserID      string    `json:"user_id"`
+	ServiceID   string    `json:"service_id"`
+	Impressions int64     `json:"impressions"`
+	Clicks      int64     `json:"clicks"`
+	Calls       int64     `json:"calls"`
+}
+
+// Rollups are every rollup of one day
+type Rollups struct {
+	Services     []ServiceDay
+	Searches     []SearchDay
+	Interactions []Interaction
+}
+
+// NormalizeQuery folds a search query to the form it is counted under
+func NormalizeQuery(query string) string {
+	return strings.ToLower(strings.Join(strings.Fields(query), " "))
+}
+
+// Rollup recomputes the rollups of the UTC day containing t from its events
+func (s *Service) Rollup(ctx context.Context, t time.Time) error {
+	day := truncateDay(t)
+	r := newRollup(day)
+	if err := s.store.ScanEvents(ctx, day, day.AddDate(0, 0, 1), func(e *Event) error {
+		r.add(e)
+		return nil
+	}); err != nil {
+		return fmt.Errorf("failed to read events of %s: %w", day.Format(dateLayout), err)
+	}
+	if err := s.store.ReplaceRollups(ctx, day, r.result()); err != nil {
+		return fmt.Errorf("failed to store rollups of %s: %w", day.Format(dateLayout), err)
+	}
+	return nil
+}
+
+// RollupRecent recomputes the rollups of every day lookback reaches, up to
+// and including today
+func (s *Service) RollupRecent(ctx context.Context, lookback time.Duration) error {
+	now := s.now().UTC()
+	var errs []error
+	for day := truncateDay(now.Add(-lookback)); !day.After(now); day = day.AddDate(0, 0, 1) {
+		if err := s.Rollup(ctx, day); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// StartRollups recomputes recent rollups every interval until ctx is
+// cancelled
+func (s *Service) StartRollups(ctx context.Context, interval, lookback time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		start := time.Now()
+		if err := s.RollupRecent(ctx, lookback); err != nil {
+			if ctx.Err() == nil {
+				s.logger.Warn("Failed to roll up events", zap.Error(err))
+			}
+			continue
+		}
+		s.logger.Debug("Rolled up events", zap.Duration("took", time.Since(start)))
+	}
+}
+
+func truncateDay(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// rollup aggregates the events of one day
+type rollup struct {
+	day          time.Time
+	services     map[string]*ServiceDay
+	consumers    map[string]map[string]bool // By service
+	searches     map[string]*SearchDay
+	queries      map[string]string // Normalized query by query ID
+	interactions map[[2]string]*Interaction
+}
+
+func newRollup(day time.Time) *rollup {
+	return &rollup{
+		day:          day,
+		services:     map[string]*ServiceDay{},
+		consumers:    map[string]map[string]bool{},
+		searches:     map[string]*SearchDay{},
+		queries:      map[string]string{},
+		interactions: map[[2]string]*Interaction{},
+	}
+}
+
+func (r *rollup) service(id string) *ServiceDay {
+	s, ok := r.services[id]
+	if !ok {
+		s = &ServiceDay{Day: r.day, ServiceID: id}
+		r.services[id] = s
+	}
+	return s
+}
+
+// interaction returns the row of user and service, or nil for anonymous
+// users
+func (r *rollup) interaction(user, service string) *Interaction {
+	if user == "" {
+		return nil
+	}
+	key := [2]string{user, service}
+	i, ok := r.interactions[key]
+	if !ok {
+		i = &Interaction{Day: r.day, UserID: user, ServiceID: service}
+		r.interactions[key] = i
+	}
+	return i
+}
+
+func (r *rollup) add(e *Event) {
+	switch {
+	case e.Source == SourceSearch && e.Type == TypeSearch:
+		query := NormalizeQuery(e.Query)
+		if e.QueryID != "" {
+			r.queries[e.QueryID] = query
+		}
+		s, ok := r.searches[query]
+		if !ok {
+			s = &SearchDay{Day: r.day, Query: query}
+			r.searches[query] = s
+		}
+		s.Searches++
+		if e.Results == 0 {
+			s.ZeroResults++
+		}
+		s.LatencyMSSum += e.LatencyMS
+		r.impressions(e)
+
+	case e.Source == SourceSearch && e.Type == TypeImpression:
+		r.impressions(e)
+
+	case e.Source == SourceSearch && e.Type == TypeClick:
+		r.service(e.ServiceID).Clicks++
+		if i := r.interaction(e.ActorID, e.ServiceID); i != nil {
+			i.Clicks++
+		}
+		// Clicks on results of searches made earlier the same day
+		if query, ok := r.queries[e.QueryID]; ok {
+			r.searches[query].Clicks++
+		}
+
+	case e.Source == SourceUsage:
+		s := r.service(e.ServiceID)
+		s.ProviderID = e.ProviderID
+		s.Calls++
+		if e.StatusCode >= 500 {
+			s.Errors++
+		} else {
+			s.TotalTokens += e.Tokens
+		}
+		s.LatencyMSSum += e.LatencyMS
+		if r.consumers[e.ServiceID] == nil {
+			r.consumers[e.ServiceID] = map[string]bool{}
+		}
+		r.consumers[e.ServiceID][e.ActorID] = true
+		if i := r.interaction(e.ActorID, e.ServiceID); i != nil {
+			i.Calls++
+		}
+
+	case e.Source == SourceValidation && e.ServiceID != "":
+		s := r.service(e.ServiceID)
+		s.ProviderID = e.ProviderID
+		s.Validations++
+		if !e.Valid {
+			s.FailedValidations++
+		}
+	}
+}
+
+func (r *rollup) impressions(e *Event) {
+	for _, id := range e.ServiceIDs {
+		r.service(id).Impressions++
+		if i := r.interaction(e.ActorID, id); i != nil {
+			i.Impressions++
+		}
+	}
+}
+
+// result returns the rollups in key order
+func (r *rollup) result() *Rollups {
+	result := &Rollups{}
+	for id, s := range r.services {
+		s.Consumers = int64(len(r.consumers[id]))
+		result.Services = append(result.Services, *s)
+	}
+	for _, s := range r.searches {
+		result.Searches = append(result.Searches, *s)
+	}
+	for _, i := range r.interactions {
+		result.Interactions = append(result.Interactions, *i)
+	}
+	sort.Slice(result.Services, func(i, j int) bool { return result.Services[i].ServiceID < result.Services[j].ServiceID })
+	sort.Slice(result.Searches, func(i, j int) bool { return result.Searches[i].Query < result.Searches[j].Query })
+	sort.Slice(result.Interactions, func(i, j int) bool {
+		a, b := result.Interactions[i], result.Interactions[j]
+		if a.UserID != b.UserID {
+			return a.UserID < b.UserID
+		}
+		return a.ServiceID < b.ServiceID
+	})
+	return result
+}
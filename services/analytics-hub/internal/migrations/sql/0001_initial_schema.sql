@@ -0,0 +1,75 @@
+-- Initial analytics hub schema: the search, usage and validation events
+-- landed from Kafka, and the daily rollups computed from them.
+
+-- Events are kept for the configured retention. The columns rollups and
+-- training read are extracted; payload is the event as it was published.
+-- actor_id is the user of search events, the consumer of usage events and
+-- the provider of validation events. service_ids are the results of a
+-- search or the services of a recommendation impression.
+CREATE TABLE IF NOT EXISTS events (
+    source VARCHAR(20) NOT NULL,
+    event_id TEXT NOT NULL,
+    event_type VARCHAR(50) NOT NULL,
+    occurred_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    actor_id TEXT NOT NULL DEFAULT '',
+    service_id TEXT NOT NULL DEFAULT '',
+    provider_id TEXT NOT NULL DEFAULT '',
+    query_id TEXT NOT NULL DEFAULT '',
+    query TEXT NOT NULL DEFAULT '',
+    service_ids TEXT[] NOT NULL DEFAULT '{}',
+    results INTEGER NOT NULL DEFAULT 0,
+    status_code INTEGER NOT NULL DEFAULT 0,
+    total_tokens BIGINT NOT NULL DEFAULT 0,
+    latency_ms DOUBLE PRECISION NOT NULL DEFAULT 0,
+    valid BOOLEAN NOT NULL DEFAULT FALSE,
+    payload JSONB NOT NULL,
+    landed_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (source, event_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_events_occurred_at ON events(occurred_at);
+
+-- Rollups are per UTC day and recomputed whole from the day's events, so a
+-- late event is counted when its day is next rolled up
+
+-- The funnel of each service: how often it was shown, clicked, called and
+-- validated
+CREATE TABLE IF NOT EXISTS service_daily (
+    day DATE NOT NULL,
+    service_id TEXT NOT NULL,
+    provider_id TEXT NOT NULL DEFAULT '',
+    impressions BIGINT NOT NULL DEFAULT 0,
+    clicks BIGINT NOT NULL DEFAULT 0,
+    calls BIGINT NOT NULL DEFAULT 0,
+    errors BIGINT NOT NULL DEFAULT 0,
+    consumers BIGINT NOT NULL DEFAULT 0,
+    total_tokens BIGINT NOT NULL DEFAULT 0,
+    latency_ms_sum DOUBLE PRECISION NOT NULL DEFAULT 0,
+    validations BIGINT NOT NULL DEFAULT 0,
+    failed_validations BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (day, service_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_service_daily_provider ON service_daily(provider_id, day);
+
+-- Searches by normalized query text
+CREATE TABLE IF NOT EXISTS search_daily (
+    day DATE NOT NULL,
+    query TEXT NOT NULL,
+    searches BIGINT NOT NULL DEFAULT 0,
+    zero_results BIGINT NOT NULL DEFAULT 0,
+    clicks BIGINT NOT NULL DEFAULT 0,
+    latency_ms_sum DOUBLE PRECISION NOT NULL DEFAULT 0,
+    PRIMARY KEY (day, query)
+);
+
+-- What each user saw, clicked and called, for recommendation training
+CREATE TABLE IF NOT EXISTS interactions_daily (
+    day DATE NOT NULL,
+    user_id TEXT NOT NULL,
+    service_id TEXT NOT NULL,
+    impressions BIGINT NOT NULL DEFAULT 0,
+    clicks BIGINT NOT NULL DEFAULT 0,
+    calls BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (day, user_id, service_id)
+);
@@ -0,0 +1,242 @@
+// Package store keeps landed events and their daily rollups in PostgreSQL
+package store
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/org/llm-marketplace/services/analytics-hub/internal/config"
+	"github.com/org/llm-marketplace/services/analytics-hub/internal/hub"
+)
+
+// Store implements hub.Store
+type Store struct {
+	pool *pgxpool.Pool
+}
+
+// NewPool connects to PostgreSQL
+func NewPool(ctx context.Context, cfg config.PostgresConfig) (*pgxpool.Pool, error) {
+	poolConfig, err := pgxpool.ParseConfig(cfg.DSN())
+	if err != nil {
+		return nil, fmt.Errorf("invalid postgres config: %w", err)
+	}
+	poolConfig.MaxConns = int32(cfg.MaxConns)
+	poolConfig.MinConns = int32(cfg.MinConns)
+	poolConfig.ConnConfig.ConnectTimeout = cfg.ConnTimeout
+
+	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create pool: %w", err)
+	}
+	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("failed to ping postgres: %w", err)
+	}
+	return pool, nil
+}
+
+// New creates a store over pool
+func New(pool *pgxpool.Pool) *Store {
+	return &Store{pool: pool}
+}
+
+func (s *Store) Land(ctx context.Context, events []*hub.Event) (int, error) {
+	landed := 0
+	err := pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		batch := &pgx.Batch{}
+		for _, e := range events {
+			serviceIDs := e.ServiceIDs
+			if serviceIDs == nil {
+				serviceIDs = []string{}
+			}
+			batch.Queue(`
+				INSERT INTO events (source, event_id, event_type, occurred_at, actor_id, service_id, provider_id,
+					query_id, query, service_ids, results, status_code, total_tokens, latency_ms, valid, payload)
+				VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
+				ON CONFLICT (source, event_id) DO NOTHING
+			`, e.Source, e.ID, e.Type, e.OccurredAt, e.ActorID, e.ServiceID, e.ProviderID,
+				e.QueryID, e.Query, serviceIDs, e.Results, e.StatusCode, e.Tokens, e.LatencyMS, e.Valid, string(e.Payload))
+		}
+		results := tx.SendBatch(ctx, batch)
+		defer results.Close()
+		for range events {
+			tag, err := results.Exec()
+			if err != nil {
+				return err
+			}
+			landed += int(tag.RowsAffected())
+		}
+		return results.Close()
+	})
+	if err != nil {
+		return 0, err
+	}
+	return landed, nil
+}
+
+func (s *Store) ScanEvents(ctx context.Context, from, to time.Time, fn func(*hub.Event) error) error {
+	rows, err := s.pool.Query(ctx, `
+		SELECT source, event_id, event_type, occurred_at, actor_id, service_id, provider_id,
+			query_id, query, service_ids, results, status_code, total_tokens, latency_ms, valid
+		FROM events
+		WHERE occurred_at >= $1 AND occurred_at < $2
+		ORDER BY occurred_at, source, event_id
+	`, from, to)
+	if err != nil {
+		return fmt.Errorf("failed to query events: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var e hub.Event
+		if err := rows.Scan(&e.Source, &e.ID, &e.Type, &e.OccurredAt, &e.ActorID, &e.ServiceID, &e.ProviderID,
+			&e.QueryID, &e.Query, &e.ServiceIDs, &e.Results, &e.StatusCode, &e.Tokens, &e.LatencyMS, &e.Valid); err != nil {
+			return err
+		}
+		e.OccurredAt = e.OccurredAt.UTC()
+		if err := fn(&e); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func (s *Store) ReplaceRollups(ctx context.Context, day time.Time, rollups *hub.Rollups) error {
+	return pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		batch := &pgx.Batch{}
+		for _, table := range []string{"service_daily", "search_daily", "interactions_daily"} {
+			batch.Queue(`DELETE FROM `+table+` WHERE day = $1`, day)
+		}
+		for _, r := range rollups.Services {
+			batch.Queue(`
+				INSERT INTO service_daily (day, service_id, provider_id, impressions, clicks, calls, errors,
+					consumers, total_tokens, latency_ms_sum, validations, failed_validations)
+				VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+			`, day, r.ServiceID, r.ProviderID, r.Impressions, r.Clicks, r.Calls, r.Errors,
+				r.Consumers, r.TotalTokens, r.LatencyMSSum, r.Validations, r.FailedValidations)
+		}
+		for _, r := range rollups.Searches {
+			batch.Queue(`
+				INSERT INTO search_daily (day, query, searches, zero_results, clicks, latency_ms_sum)
+				VALUES ($1, $2, $3, $4, $5, $6)
+			`, day, r.Query, r.Searches, r.ZeroResults, r.Clicks, r.LatencyMSSum)
+		}
+		for _, r := range rollups.Interactions {
+			batch.Queue(`
+				INSERT INTO interactions_daily (day, user_id, service_id, impressions, clicks, calls)
+				VALUES ($1, $2, $3, $4, $5, $6)
+			`, day, r.UserID, r.ServiceID, r.Impressions, r.Clicks, r.Calls)
+		}
+		return tx.SendBatch(ctx, batch).Close()
+	})
+}
+
+func (s *Store) PruneEvents(ctx context.Context, before time.Time) (int64, error) {
+	tag, err := s.pool.Exec(ctx, `DELETE FROM events WHERE occurred_at < $1`, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune events: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+// where builds the conditions of filter on the columns given, after the day
+// range
+func where(filter hub.StatsFilter, columns map[string]string) (string, []interface{}) {
+	args := []interface{}{filter.From, filter.To}
+	conditions := []string{"day >= $1", "day <= $2"}
+	for column, value := range columns {
+		if value != "" {
+			args = append(args, value)
+			conditions = append(conditions, fmt.Sprintf("%s = $%d", column, len(args)))
+		}
+	}
+	return strings.Join(conditions, " AND "), args
+}
+
+func (s *Store) ServiceDaily(ctx context.Context, filter hub.StatsFilter) ([]hub.ServiceDay, error) {
+	conditions, args := where(filter, map[string]string{
+		"service_id":  filter.ServiceID,
+		"provider_id": filter.ProviderID,
+	})
+	rows, err := s.pool.Query(ctx, fmt.Sprintf(`
+		SELECT day, service_id, provider_id, impressions, clicks, calls, errors,
+			consumers, total_tokens, latency_ms_sum, validations, failed_validations
+		FROM service_daily WHERE %s
+		ORDER BY day, service_id
+		LIMIT %d OFFSET %d
+	`, conditions, filter.Limit, filter.Offset), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var days []hub.ServiceDay
+	for rows.Next() {
+		var d hub.ServiceDay
+		if err := rows.Scan(&d.Day, &d.ServiceID, &d.ProviderID, &d.Impressions, &d.Clicks, &d.Calls, &d.Errors,
+			&d.Consumers, &d.TotalTokens, &d.LatencyMSSum, &d.Validations, &d.FailedValidations); err != nil {
+			return nil, err
+		}
+		d.Day = d.Day.UTC()
+		days = append(days, d)
+	}
+	return days, rows.Err()
+}
+
+func (s *Store) SearchDaily(ctx context.Context, filter hub.StatsFilter) ([]hub.SearchDay, error) {
+	conditions, args := where(filter, nil)
+	rows, err := s.pool.Query(ctx, fmt.Sprintf(`
+		SELECT day, query, searches, zero_results, clicks, latency_ms_sum
+		FROM search_daily WHERE %s
+		ORDER BY day, searches DESC, query
+		LIMIT %d OFFSET %d
+	`, conditions, filter.Limit, filter.Offset), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var days []hub.SearchDay
+	for rows.Next() {
+		var d hub.SearchDay
+		if err := rows.Scan(&d.Day, &d.Query, &d.Searches, &d.ZeroResults, &d.Clicks, &d.LatencyMSSum); err != nil {
+			return nil, err
+		}
+		d.Day = d.Day.UTC()
+		days = append(days, d)
+	}
+	return days, rows.Err()
+}
+
+func (s *Store) Interactions(ctx context.Context, filter hub.StatsFilter) ([]hub.Interaction, error) {
+	conditions, args := where(filter, map[string]string{
+		"user_id":    filter.UserID,
+		"service_id": filter.ServiceID,
+	})
+	rows, err := s.pool.Query(ctx, fmt.Sprintf(`
+		SELECT day, user_id, service_id, impressions, clicks, calls
+		FROM interactions_daily WHERE %s
+		ORDER BY day, user_id, service_id
+		LIMIT %d OFFSET %d
+	`, conditions, filter.Limit, filter.Offset), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var interactions []hub.Interaction
+	for rows.Next() {
+		var i hub.Interaction
+		if err := rows.Scan(&i.Day, &i.UserID, &i.ServiceID, &i.Impressions, &i.Clicks, &i.Calls); err != nil {
+			return nil, err
+		}
+		i.Day = i.Day.UTC()
+		interactions = append(interactions, i)
+	}
+	return interactions, rows.Err()
+}
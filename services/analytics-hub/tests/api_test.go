@@ -0,0 +1,116 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/org/llm-marketplace/services/analytics-hub/internal/api"
+	"github.com/org/llm-marketplace/services/analytics-hub/internal/hub"
+)
+
+func newTestRouter(t *testing.T) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	svc, _ := newTestService()
+	at := day.Add(9 * time.Hour)
+	land(t, svc, hub.SourceSearch,
+		searchPayload("alice", "q-1", "chat", at, "gpt", "embed"),
+		searchPayload("bob", "q-2", "embeddings", at, "embed"),
+		clickPayload("alice", "q-1", "gpt", at.Add(time.Minute)),
+	)
+	land(t, svc, hub.SourceUsage,
+		usagePayload("alice", "gpt", at, 200, 100),
+		usagePayload("bob", "embed", at, 200, 100),
+	)
+	if err := svc.Rollup(context.Background(), day); err != nil {
+		t.Fatalf("Rollup() error = %v", err)
+	}
+
+	router := gin.New()
+	router.HandleMethodNotAllowed = true
+	api.RegisterRoutes(router, svc, "X-Consumer-ID", "X-Provider-ID", zap.NewNop())
+	return router
+}
+
+func get(router *gin.Engine, path string, headers map[string]string) (*httptest.ResponseRecorder, map[string]interface{}) {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var decoded map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &decoded)
+	return w, decoded
+}
+
+func TestStatsAPIScopesCallers(t *testing.T) {
+	router := newTestRouter(t)
+	const services = "/api/v1/services/daily?from=2026-03-10&to=2026-03-10"
+	provider := map[string]string{"X-Provider-ID": "prov-gpt"}
+	consumer := map[string]string{"X-Consumer-ID": "alice"}
+
+	tests := []struct {
+		name    string
+		query   string
+		headers map[string]string
+		status  int
+		total   float64
+	}{
+		{"operator", services, nil, http.StatusOK, 2},
+		{"service", "/api/v1/services/embed/daily?from=2026-03-10&to=2026-03-10", nil, http.StatusOK, 1},
+		{"provider", services, provider, http.StatusOK, 1},
+		{"provider's other service", "/api/v1/services/embed/daily?from=2026-03-10&to=2026-03-10", provider, http.StatusOK, 0},
+		{"other provider", services + "&provider_id=prov-embed", provider, http.StatusForbidden, 0},
+		{"consumer", services, consumer, http.StatusForbidden, 0},
+		{"searches", "/api/v1/searches/daily?from=2026-03-10&to=2026-03-10", nil, http.StatusOK, 2},
+		{"searches by provider", "/api/v1/searches/daily", provider, http.StatusForbidden, 0},
+		{"interactions", "/api/v1/interactions?from=2026-03-10&to=2026-03-10&user_id=alice", nil, http.StatusOK, 2},
+		{"interactions page", "/api/v1/interactions?from=2026-03-10&to=2026-03-10&limit=2&offset=2", nil, http.StatusOK, 1},
+		{"interactions by consumer", "/api/v1/interactions", consumer, http.StatusForbidden, 0},
+		{"bad day", "/api/v1/services/daily?from=yesterday", nil, http.StatusBadRequest, 0},
+		{"bad limit", "/api/v1/interactions?limit=all", nil, http.StatusBadRequest, 0},
+		{"reversed range", "/api/v1/services/daily?from=2026-03-11&to=2026-03-10", nil, http.StatusBadRequest, 0},
+	}
+	for _, tt := range tests {
+		w, body := get(router, tt.query, tt.headers)
+		if w.Code != tt.status {
+			t.Errorf("%s: status %d, want %d: %s", tt.name, w.Code, tt.status, w.Body)
+			continue
+		}
+		if tt.status != http.StatusOK {
+			if ct := w.Header().Get("Content-Type"); ct != "application/problem+json" {
+				t.Errorf("%s: content type %q", tt.name, ct)
+			}
+			continue
+		}
+		if body["total"] != tt.total {
+			t.Errorf("%s: total %v, want %v", tt.name, body["total"], tt.total)
+		}
+	}
+}
+
+func TestSearchStatsAPI(t *testing.T) {
+	router := newTestRouter(t)
+
+	w, body := get(router, "/api/v1/searches/daily?from=2026-03-10&to=2026-03-10", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	days := body["days"].([]interface{})
+	chat := days[0].(map[string]interface{})
+	if chat["query"] != "chat" || chat["clicks"] != 1.0 || chat["avg_latency_ms"] != 40.0 {
+		t.Errorf("first query = %v, want chat with 1 click", chat)
+	}
+	if _, ok := chat["LatencyMSSum"]; ok {
+		t.Errorf("latency sum leaked into %v", chat)
+	}
+}
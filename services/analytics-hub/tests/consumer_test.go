@@ -0,0 +1,111 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"go.uber.org/zap"
+
+	"github.com/org/llm-marketplace/services/analytics-hub/internal/config"
+	"github.com/org/llm-marketplace/services/analytics-hub/internal/consumer"
+	"github.com/org/llm-marketplace/services/analytics-hub/internal/hub"
+)
+
+// fakeReader serves queued messages, then blocks until cancelled
+type fakeReader struct {
+	mu        sync.Mutex
+	messages  []kafka.Message
+	committed []int64
+}
+
+func (r *fakeReader) FetchMessage(ctx context.Context) (kafka.Message, error) {
+	r.mu.Lock()
+	if len(r.messages) > 0 {
+		msg := r.messages[0]
+		r.messages = r.messages[1:]
+		r.mu.Unlock()
+		return msg, nil
+	}
+	r.mu.Unlock()
+	<-ctx.Done()
+	return kafka.Message{}, ctx.Err()
+}
+
+func (r *fakeReader) CommitMessages(_ context.Context, msgs ...kafka.Message) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, m := range msgs {
+		r.committed = append(r.committed, m.Offset)
+	}
+	return nil
+}
+
+func (r *fakeReader) Close() error { return nil }
+
+// flakyStore fails the first failures batches
+type flakyStore struct {
+	*memStore
+	failures int
+}
+
+func (s *flakyStore) Land(ctx context.Context, events []*hub.Event) (int, error) {
+	if s.failures > 0 {
+		s.failures--
+		return 0, errors.New("connection reset")
+	}
+	return s.memStore.Land(ctx, events)
+}
+
+func TestConsumerLandsBatchesRetriesAndSkipsInvalidEvents(t *testing.T) {
+	store := &flakyStore{memStore: newMemStore(), failures: 2}
+	svc := hub.NewService(store, zap.NewNop())
+
+	click := clickPayload("alice", "q-1", "gpt", day)
+	reader := &fakeReader{messages: []kafka.Message{
+		{Offset: 1, Value: searchPayload("alice", "q-1", "chat", day, "gpt")},
+		{Offset: 2, Value: []byte("not json")},
+		{Offset: 3, Value: click},
+		{Offset: 4, Value: click}, // Redelivered
+		{Offset: 5, Value: impressionPayload("bob", day, "gpt")},
+	}}
+
+	cfg := config.KafkaConfig{
+		Brokers:         []string{"localhost:9092"},
+		BatchSize:       2,
+		FlushInterval:   10 * time.Millisecond,
+		RetryBackoff:    time.Millisecond,
+		MaxRetryBackoff: time.Millisecond,
+	}
+	c := consumer.NewConsumer(hub.SourceSearch, "search", cfg, svc, zap.NewNop())
+	c.SetReader(reader)
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		c.Start(ctx)
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		reader.mu.Lock()
+		committed := len(reader.committed)
+		reader.mu.Unlock()
+		if committed == 5 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("committed %d offsets, want all 5", committed)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	<-stopped
+
+	if len(store.events) != 3 {
+		t.Errorf("landed %d events, want the search, the click once and the impression", len(store.events))
+	}
+}
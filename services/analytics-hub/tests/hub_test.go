@@ -0,0 +1,310 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/org/llm-marketplace/pkg/marketplace"
+	"go.uber.org/zap"
+
+	"github.com/org/llm-marketplace/services/analytics-hub/internal/hub"
+)
+
+// now is the clock of the tests: mid-March 2026
+var now = time.Date(2026, 3, 15, 12, 0, 0, 0, time.UTC)
+
+// day is the day the test events occur on
+var day = time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC)
+
+func newTestService() (*hub.Service, *memStore) {
+	store := newMemStore()
+	svc := hub.NewService(store, zap.NewNop())
+	svc.SetClock(func() time.Time { return now })
+	return svc, store
+}
+
+var seq int
+
+func nextID() string {
+	seq++
+	return fmt.Sprintf("evt-%d", seq)
+}
+
+func searchPayload(user, queryID, query string, at time.Time, results ...string) []byte {
+	payload, _ := json.Marshal(map[string]interface{}{
+		"event_id": nextID(), "event_type": hub.TypeSearch, "schema_version": 1, "occurred_at": at, "user_id": user,
+		"search": map[string]interface{}{"query_id": queryID, "query": query, "total": len(results), "result_ids": results, "latency_ms": 40},
+	})
+	return payload
+}
+
+func clickPayload(user, queryID, service string, at time.Time) []byte {
+	payload, _ := json.Marshal(map[string]interface{}{
+		"event_id": nextID(), "event_type": hub.TypeClick, "schema_version": 1, "occurred_at": at, "user_id": user,
+		"click": map[string]interface{}{"query_id": queryID, "service_id": service, "position": 1},
+	})
+	return payload
+}
+
+func impressionPayload(user string, at time.Time, services ...string) []byte {
+	payload, _ := json.Marshal(map[string]interface{}{
+		"event_id": nextID(), "event_type": hub.TypeImpression, "schema_version": 1, "occurred_at": at, "user_id": user,
+		"impression": map[string]interface{}{"source": "recommendations", "service_ids": services},
+	})
+	return payload
+}
+
+func usagePayload(consumer, service string, at time.Time, status int, tokens int64) []byte {
+	payload, _ := json.Marshal(&marketplace.UsageEvent{
+		ID: nextID(), OccurredAt: at, ConsumerID: consumer, ServiceID: service, ProviderID: "prov-" + service,
+		StatusCode: status, TotalTokens: tokens, LatencyMS: 200,
+	})
+	return payload
+}
+
+func validationPayload(service string, at time.Time, valid bool) []byte {
+	payload, _ := json.Marshal(&marketplace.ValidationEvent{
+		ID: nextID(), OccurredAt: at, ProviderID: "prov-" + service, ServiceID: service, Name: service, Version: "1.0.0", Valid: valid,
+	})
+	return payload
+}
+
+// land decodes payloads of source and lands them
+func land(t *testing.T, svc *hub.Service, source string, payloads ...[]byte) {
+	t.Helper()
+	events := make([]*hub.Event, 0, len(payloads))
+	for _, p := range payloads {
+		e, err := hub.Decode(source, p)
+		if err != nil {
+			t.Fatalf("Decode() error = %v", err)
+		}
+		events = append(events, e)
+	}
+	if err := svc.Land(context.Background(), events); err != nil {
+		t.Fatalf("Land() error = %v", err)
+	}
+}
+
+func TestDecodeRejectsInvalidEvents(t *testing.T) {
+	tests := []struct {
+		name    string
+		source  string
+		payload string
+	}{
+		{"malformed", hub.SourceSearch, "not json"},
+		{"unknown source", "billing", `{"id": "e-1"}`},
+		{"search without payload", hub.SourceSearch, `{"event_id": "e-1", "event_type": "search", "occurred_at": "2026-03-10T09:00:00Z"}`},
+		{"unknown search type", hub.SourceSearch, `{"event_id": "e-1", "event_type": "scroll", "occurred_at": "2026-03-10T09:00:00Z"}`},
+		{"click without service", hub.SourceSearch, `{"event_id": "e-1", "event_type": "click", "occurred_at": "2026-03-10T09:00:00Z", "click": {"query_id": "q-1"}}`},
+		{"usage without consumer", hub.SourceUsage, `{"id": "e-1", "occurred_at": "2026-03-10T09:00:00Z", "service_id": "gpt"}`},
+		{"no id", hub.SourceValidation, `{"occurred_at": "2026-03-10T09:00:00Z", "valid": true}`},
+		{"no time", hub.SourceValidation, `{"id": "e-1", "valid": true}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := hub.Decode(tt.source, []byte(tt.payload)); !errors.Is(err, hub.ErrInvalidEvent) {
+				t.Errorf("Decode() error = %v, want ErrInvalidEvent", err)
+			}
+		})
+	}
+}
+
+func TestDecodeKeepsPayload(t *testing.T) {
+	payload := searchPayload("alice", "q-1", "Chat  Models", day.Add(time.Hour), "gpt", "claude")
+	e, err := hub.Decode(hub.SourceSearch, payload)
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if e.QueryID != "q-1" || e.ActorID != "alice" || e.Results != 2 || len(e.ServiceIDs) != 2 || string(e.Payload) != string(payload) {
+		t.Errorf("Decode() = %+v", e)
+	}
+}
+
+func TestLandDropsRedeliveredEvents(t *testing.T) {
+	svc, store := newTestService()
+	payload := usagePayload("alice", "gpt", day, 200, 100)
+	land(t, svc, hub.SourceUsage, payload, payload)
+	land(t, svc, hub.SourceUsage, payload)
+
+	if len(store.events) != 1 {
+		t.Errorf("landed %d events, want 1", len(store.events))
+	}
+}
+
+func TestRollupBuildsFunnels(t *testing.T) {
+	svc, store := newTestService()
+	at := day.Add(9 * time.Hour)
+	land(t, svc, hub.SourceSearch,
+		searchPayload("alice", "q-1", "Chat  Models", at, "gpt", "claude"),
+		searchPayload("", "q-2", "chat models", at.Add(time.Minute), "gpt"),
+		searchPayload("bob", "q-3", "translation", at.Add(2*time.Minute)),
+		clickPayload("alice", "q-1", "gpt", at.Add(3*time.Minute)),
+		clickPayload("alice", "q-old", "claude", at.Add(4*time.Minute)), // Its search was another day
+		impressionPayload("bob", at.Add(5*time.Minute), "gpt"),
+		searchPayload("carol", "q-4", "chat models", day.AddDate(0, 0, 1), "gpt"), // The next day
+	)
+	land(t, svc, hub.SourceUsage,
+		usagePayload("alice", "gpt", at, 200, 100),
+		usagePayload("alice", "gpt", at, 200, 50),
+		usagePayload("bob", "gpt", at, 502, 70),
+	)
+	land(t, svc, hub.SourceValidation,
+		validationPayload("gpt", at, true),
+		validationPayload("gpt", at, false),
+		validationPayload("", at, false), // Checked before publishing
+	)
+
+	if err := svc.Rollup(context.Background(), at); err != nil {
+		t.Fatalf("Rollup() error = %v", err)
+	}
+
+	services := store.services[day]
+	if len(services) != 2 {
+		t.Fatalf("services = %+v, want gpt and claude", services)
+	}
+	gpt := services[1]
+	want := hub.ServiceDay{
+		Day: day, ServiceID: "gpt", ProviderID: "prov-gpt",
+		Impressions: 3, Clicks: 1, Calls: 3, Errors: 1, Consumers: 2, TotalTokens: 150, LatencyMSSum: 600,
+		Validations: 2, FailedValidations: 1,
+	}
+	if gpt != want {
+		t.Errorf("gpt = %+v, want %+v", gpt, want)
+	}
+	if claude := services[0]; claude.Impressions != 1 || claude.Clicks != 1 {
+		t.Errorf("claude = %+v, want 1 impression and 1 click", claude)
+	}
+
+	searches := store.searches[day]
+	if len(searches) != 2 || searches[0].Query != "chat models" || searches[1].Query != "translation" {
+		t.Fatalf("searches = %+v", searches)
+	}
+	if s := searches[0]; s.Searches != 2 || s.Clicks != 1 || s.ZeroResults != 0 {
+		t.Errorf("chat models = %+v, want 2 searches and 1 click", s)
+	}
+	if s := searches[1]; s.Searches != 1 || s.ZeroResults != 1 {
+		t.Errorf("translation = %+v, want 1 search without results", s)
+	}
+
+	interactions := map[string]hub.Interaction{}
+	for _, i := range store.interactions[day] {
+		interactions[i.UserID+"/"+i.ServiceID] = i
+	}
+	if len(interactions) != 3 {
+		t.Errorf("interactions = %+v, want alice/claude, alice/gpt and bob/gpt", store.interactions[day])
+	}
+	if i := interactions["alice/gpt"]; i.Impressions != 1 || i.Clicks != 1 || i.Calls != 2 {
+		t.Errorf("alice/gpt = %+v", i)
+	}
+	if i := interactions["bob/gpt"]; i.Impressions != 1 || i.Calls != 1 {
+		t.Errorf("bob/gpt = %+v", i)
+	}
+}
+
+func TestRollupRecountsLateEvents(t *testing.T) {
+	svc, store := newTestService()
+	at := now.Add(-time.Hour)
+	land(t, svc, hub.SourceUsage, usagePayload("alice", "gpt", at, 200, 100))
+	if err := svc.RollupRecent(context.Background(), 48*time.Hour); err != nil {
+		t.Fatalf("RollupRecent() error = %v", err)
+	}
+	land(t, svc, hub.SourceUsage, usagePayload("bob", "gpt", now.Add(-30*time.Hour), 200, 100))
+	land(t, svc, hub.SourceUsage, usagePayload("bob", "gpt", at, 200, 100))
+	if err := svc.RollupRecent(context.Background(), 48*time.Hour); err != nil {
+		t.Fatalf("RollupRecent() error = %v", err)
+	}
+
+	today := time.Date(2026, 3, 15, 0, 0, 0, 0, time.UTC)
+	if s := store.services[today]; len(s) != 1 || s[0].Calls != 2 || s[0].Consumers != 2 {
+		t.Errorf("today = %+v, want 2 calls by 2 consumers", s)
+	}
+	if s := store.services[today.AddDate(0, 0, -1)]; len(s) != 1 || s[0].Calls != 1 {
+		t.Errorf("yesterday = %+v, want the late call", s)
+	}
+}
+
+func TestServiceStats(t *testing.T) {
+	svc, _ := newTestService()
+	land(t, svc, hub.SourceUsage,
+		usagePayload("alice", "gpt", day, 200, 100),
+		usagePayload("bob", "gpt", day, 200, 100),
+		usagePayload("alice", "embed", day.AddDate(0, 0, 1), 200, 100),
+	)
+	for _, d := range []time.Time{day, day.AddDate(0, 0, 1)} {
+		if err := svc.Rollup(context.Background(), d); err != nil {
+			t.Fatalf("Rollup() error = %v", err)
+		}
+	}
+
+	stats, err := svc.ServiceStats(context.Background(), hub.StatsFilter{From: day, To: day.AddDate(0, 0, 1)})
+	if err != nil {
+		t.Fatalf("ServiceStats() error = %v", err)
+	}
+	if len(stats) != 2 || stats[0].ServiceID != "gpt" || stats[0].AvgLatencyMS != 200 || stats[1].ServiceID != "embed" {
+		t.Errorf("ServiceStats() = %+v", stats)
+	}
+
+	// The last 30 days by default
+	stats, err = svc.ServiceStats(context.Background(), hub.StatsFilter{ProviderID: "prov-embed"})
+	if err != nil {
+		t.Fatalf("ServiceStats() error = %v", err)
+	}
+	if len(stats) != 1 || stats[0].ServiceID != "embed" {
+		t.Errorf("ServiceStats(prov-embed) = %+v", stats)
+	}
+}
+
+func TestStatsRejectInvalidQueries(t *testing.T) {
+	svc, _ := newTestService()
+	tests := []struct {
+		name   string
+		filter hub.StatsFilter
+	}{
+		{"reversed range", hub.StatsFilter{From: day, To: day.AddDate(0, 0, -1)}},
+		{"too long", hub.StatsFilter{From: day.AddDate(-2, 0, 0), To: day}},
+		{"negative limit", hub.StatsFilter{Limit: -1}},
+		{"limit too high", hub.StatsFilter{Limit: hub.MaxLimit + 1}},
+		{"negative offset", hub.StatsFilter{Offset: -1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := svc.Interactions(context.Background(), tt.filter); !errors.Is(err, hub.ErrInvalidQuery) {
+				t.Errorf("Interactions() error = %v, want ErrInvalidQuery", err)
+			}
+		})
+	}
+}
+
+func TestStartPruningRemovesExpiredEvents(t *testing.T) {
+	svc, store := newTestService()
+	land(t, svc, hub.SourceUsage,
+		usagePayload("alice", "gpt", now.AddDate(0, 0, -100), 200, 100),
+		usagePayload("alice", "gpt", now.AddDate(0, 0, -1), 200, 100),
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		svc.StartPruning(ctx, time.Millisecond, 90*24*time.Hour)
+	}()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		store.mu.Lock()
+		n := len(store.events)
+		store.mu.Unlock()
+		if n == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d events left, want 1", n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	<-done
+}
@@ -0,0 +1,176 @@
+package tests
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/org/llm-marketplace/services/analytics-hub/internal/hub"
+)
+
+// memStore is an in-memory hub.Store with the semantics of the PostgreSQL
+// store
+type memStore struct {
+	mu           sync.Mutex
+	events       map[[2]string]*hub.Event
+	services     map[time.Time][]hub.ServiceDay
+	searches     map[time.Time][]hub.SearchDay
+	interactions map[time.Time][]hub.Interaction
+	err          error
+}
+
+func newMemStore() *memStore {
+	return &memStore{
+		events:       map[[2]string]*hub.Event{},
+		services:     map[time.Time][]hub.ServiceDay{},
+		searches:     map[time.Time][]hub.SearchDay{},
+		interactions: map[time.Time][]hub.Interaction{},
+	}
+}
+
+func (s *memStore) Land(_ context.Context, events []*hub.Event) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return 0, s.err
+	}
+	landed := 0
+	for _, e := range events {
+		key := [2]string{e.Source, e.ID}
+		if _, ok := s.events[key]; ok {
+			continue
+		}
+		s.events[key] = e
+		landed++
+	}
+	return landed, nil
+}
+
+func (s *memStore) ScanEvents(_ context.Context, from, to time.Time, fn func(*hub.Event) error) error {
+	s.mu.Lock()
+	var events []*hub.Event
+	for _, e := range s.events {
+		if !e.OccurredAt.Before(from) && e.OccurredAt.Before(to) {
+			events = append(events, e)
+		}
+	}
+	s.mu.Unlock()
+
+	sort.Slice(events, func(i, j int) bool {
+		a, b := events[i], events[j]
+		if !a.OccurredAt.Equal(b.OccurredAt) {
+			return a.OccurredAt.Before(b.OccurredAt)
+		}
+		if a.Source != b.Source {
+			return a.Source < b.Source
+		}
+		return a.ID < b.ID
+	})
+	for _, e := range events {
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *memStore) ReplaceRollups(_ context.Context, day time.Time, rollups *hub.Rollups) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return s.err
+	}
+	s.services[day] = rollups.Services
+	s.searches[day] = rollups.Searches
+	s.interactions[day] = rollups.Interactions
+	return nil
+}
+
+func (s *memStore) PruneEvents(_ context.Context, before time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var n int64
+	for key, e := range s.events {
+		if e.OccurredAt.Before(before) {
+			delete(s.events, key)
+			n++
+		}
+	}
+	return n, nil
+}
+
+// days returns the days of filter's range that have rollups, in order
+func days[T any](rollups map[time.Time][]T, filter hub.StatsFilter) []time.Time {
+	var days []time.Time
+	for day := range rollups {
+		if !day.Before(filter.From) && !day.After(filter.To) {
+			days = append(days, day)
+		}
+	}
+	sort.Slice(days, func(i, j int) bool { return days[i].Before(days[j]) })
+	return days
+}
+
+// page applies filter's offset and limit
+func page[T any](rows []T, filter hub.StatsFilter) []T {
+	if filter.Offset >= len(rows) {
+		return nil
+	}
+	rows = rows[filter.Offset:]
+	if len(rows) > filter.Limit {
+		rows = rows[:filter.Limit]
+	}
+	return rows
+}
+
+func (s *memStore) ServiceDaily(_ context.Context, filter hub.StatsFilter) ([]hub.ServiceDay, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return nil, s.err
+	}
+	var rows []hub.ServiceDay
+	for _, day := range days(s.services, filter) {
+		for _, r := range s.services[day] {
+			if (filter.ServiceID == "" || r.ServiceID == filter.ServiceID) &&
+				(filter.ProviderID == "" || r.ProviderID == filter.ProviderID) {
+				rows = append(rows, r)
+			}
+		}
+	}
+	return page(rows, filter), nil
+}
+
+func (s *memStore) SearchDaily(_ context.Context, filter hub.StatsFilter) ([]hub.SearchDay, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return nil, s.err
+	}
+	var rows []hub.SearchDay
+	for _, day := range days(s.searches, filter) {
+		searches := append([]hub.SearchDay(nil), s.searches[day]...)
+		sort.SliceStable(searches, func(i, j int) bool { return searches[i].Searches > searches[j].Searches })
+		rows = append(rows, searches...)
+	}
+	return page(rows, filter), nil
+}
+
+func (s *memStore) Interactions(_ context.Context, filter hub.StatsFilter) ([]hub.Interaction, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return nil, s.err
+	}
+	var rows []hub.Interaction
+	for _, day := range days(s.interactions, filter) {
+		for _, r := range s.interactions[day] {
+			if (filter.UserID == "" || r.UserID == filter.UserID) &&
+				(filter.ServiceID == "" || r.ServiceID == filter.ServiceID) {
+				rows = append(rows, r)
+			}
+		}
+	}
+	return page(rows, filter), nil
+}
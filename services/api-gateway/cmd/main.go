@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/org/llm-marketplace/services/api-gateway/internal/auth"
+	"github.com/org/llm-marketplace/services/api-gateway/internal/config"
+	"github.com/org/llm-marketplace/services/api-gateway/internal/gateway"
+	"github.com/org/llm-marketplace/services/api-gateway/internal/limits"
+	"github.com/org/llm-marketplace/services/api-gateway/internal/tracing"
+)
+
+func main() {
+	// Load configuration; without a file, defaults and API_GATEWAY_* variables apply
+	configPath := config.Path()
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		panic(fmt.Sprintf("Failed to load configuration: %v", err))
+	}
+
+	logger, err := newLogger(cfg.Logging)
+	if err != nil {
+		panic(fmt.Sprintf("Failed to initialize logger: %v", err))
+	}
+	defer logger.Sync()
+
+	logger.Info("Starting LLM-Marketplace API Gateway",
+		zap.String("version", "1.0.0"),
+		zap.String("environment", os.Getenv("ENVIRONMENT")),
+		zap.String("config", configPath),
+		zap.Int("routes", len(cfg.Routes)),
+	)
+
+	ctx := context.Background()
+
+	shutdownTracing, err := tracing.Init(cfg.Tracing, logger)
+	if err != nil {
+		logger.Fatal("Failed to initialize tracing", zap.Error(err))
+	}
+
+	verifier, err := auth.NewVerifier(cfg.Auth)
+	if err != nil {
+		logger.Fatal("Failed to load token keys", zap.Error(err))
+	}
+
+	redisClient := redis.NewClient(&redis.Options{
+		Addr:     cfg.Redis.Address,
+		Password: cfg.Redis.Password,
+		DB:       cfg.Redis.DB,
+	})
+	defer redisClient.Close()
+
+	gw, err := gateway.New(cfg, verifier, limits.NewLimiter(limits.NewRedisCounter(redisClient)), logger)
+	if err != nil {
+		logger.Fatal("Failed to create gateway", zap.Error(err))
+	}
+
+	if cfg.Server.Mode == "production" {
+		gin.SetMode(gin.ReleaseMode)
+	}
+	router := gin.New()
+	router.Use(gateway.Recovery(logger))
+	if err := router.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		logger.Fatal("Invalid trusted proxies", zap.Error(err))
+	}
+
+	router.GET("/health", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{
+			"status":    "healthy",
+			"timestamp": time.Now().UTC(),
+		})
+	})
+	router.GET("/ready", gateway.Readiness(map[string]gateway.Check{
+		"redis": func(ctx context.Context) error { return redisClient.Ping(ctx).Err() },
+	}, 2*time.Second))
+	router.GET("/metrics", gin.WrapH(promhttp.Handler()))
+	gw.RegisterRoutes(router)
+
+	// gRPC clients connect with HTTP/2 in cleartext (h2c)
+	protocols := new(http.Protocols)
+	protocols.SetHTTP1(true)
+	protocols.SetUnencryptedHTTP2(true)
+
+	addr := fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Server.Port)
+	server := &http.Server{
+		Addr:         addr,
+		Handler:      router,
+		Protocols:    protocols,
+		ReadTimeout:  cfg.Server.ReadTimeout,
+		WriteTimeout: cfg.Server.WriteTimeout,
+		IdleTimeout:  cfg.Server.IdleTimeout,
+	}
+	go func() {
+		logger.Info("Starting HTTP server", zap.String("address", addr))
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			logger.Fatal("Failed to start HTTP server", zap.Error(err))
+		}
+	}()
+
+	// Graceful shutdown
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+
+	logger.Info("Shutting down server...")
+
+	shutdownCtx, cancel := context.WithTimeout(ctx, cfg.Server.ShutdownTimeout)
+	defer cancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		logger.Error("Server forced to shutdown", zap.Error(err))
+	}
+	shutdownTracing(shutdownCtx)
+
+	logger.Info("Server exited")
+}
+
+func newLogger(cfg config.LoggingConfig) (*zap.Logger, error) {
+	level, err := zapcore.ParseLevel(cfg.Level)
+	if err != nil {
+		return nil, err
+	}
+	zapConfig := zap.NewProductionConfig()
+	if cfg.Format == "console" {
+		zapConfig = zap.NewDevelopmentConfig()
+	}
+	zapConfig.Level = zap.NewAtomicLevelAt(level)
+	return zapConfig.Build()
+}
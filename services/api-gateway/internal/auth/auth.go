@@ -0,0 +1,242 @@
+// Package auth verifies the bearer tokens callers present and reads who they
+// are from the token's claims
+package auth
+
+import (
+	"crypto"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/org/llm-marketplace/services/api-gateway/internal/config"
+)
+
+var (
+	// ErrInvalidToken is returned for a token that is malformed, badly
+	// signed, expired or meant for someone else
+	ErrInvalidToken = errors.New("invalid token")
+	// ErrTenantRequired is returned when a token grants several tenants and
+	// the caller didn't choose one
+	ErrTenantRequired = errors.New("tenant required")
+	// ErrTenantNotGranted is returned when the caller chose a tenant the
+	// token doesn't grant
+	ErrTenantNotGranted = errors.New("tenant not granted")
+)
+
+// Identity is the caller a token was issued to
+type Identity struct {
+	Subject    string
+	TenantID   string   // The tenant claim
+	Tenants    []string // The tenants claim
+	ConsumerID string
+	ProviderID string
+	Roles      []string
+	Operator   bool   // Holds one of the operator roles
+	APIKeyID   string // The API key the token was issued for, if any
+}
+
+// Is reports whether the caller is of kind: an operator, or a caller acting
+// for a provider or consumer organisation
+func (id *Identity) Is(kind string) bool {
+	switch kind {
+	case config.CallerOperator:
+		return id.Operator
+	case config.CallerProvider:
+		return !id.Operator && id.ProviderID != ""
+	case config.CallerConsumer:
+		return !id.Operator && id.ConsumerID != ""
+	}
+	return false
+}
+
+// Tenant returns the tenant the caller acts in. requested is the tenant the
+// caller chose, if any, which the token must grant. Without a choice, it is
+// the tenant claim, or the only tenant granted.
+func (id *Identity) Tenant(requested string) (string, error) {
+	if requested != "" {
+		if requested == id.TenantID || slices.Contains(id.Tenants, requested) {
+			return requested, nil
+		}
+		return "", fmt.Errorf("%w: the token does not grant tenant %s", ErrTenantNotGranted, requested)
+	}
+	switch {
+	case id.TenantID != "":
+		return id.TenantID, nil
+	case len(id.Tenants) == 1:
+		return id.Tenants[0], nil
+	case len(id.Tenants) > 1:
+		return "", fmt.Errorf("%w: the token grants %d tenants", ErrTenantRequired, len(id.Tenants))
+	}
+	return "", nil
+}
+
+// BearerToken returns the token of an Authorization header using the Bearer
+// scheme, and whether there was an Authorization header at all
+func BearerToken(h http.Header) (string, bool) {
+	value := h.Get("Authorization")
+	if value == "" {
+		return "", false
+	}
+	scheme, token, ok := strings.Cut(value, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", true
+	}
+	return strings.TrimSpace(token), true
+}
+
+// Verifier verifies tokens and reads the identity they carry
+type Verifier struct {
+	parser        *jwt.Parser
+	hmacSecret    []byte
+	keys          map[string]crypto.PublicKey
+	claims        config.ClaimsConfig
+	operatorRoles []string
+}
+
+// NewVerifier creates a verifier with the secret and public keys of cfg,
+// reading the keys' PEM files
+func NewVerifier(cfg config.AuthConfig) (*Verifier, error) {
+	v := &Verifier{
+		keys:          make(map[string]crypto.PublicKey, len(cfg.PublicKeys)),
+		claims:        cfg.Claims,
+		operatorRoles: cfg.OperatorRoles,
+	}
+
+	var methods []string
+	if cfg.HMACSecret != "" {
+		v.hmacSecret = []byte(cfg.HMACSecret)
+		methods = append(methods, "HS256", "HS384", "HS512")
+	}
+	for kid, path := range cfg.PublicKeys {
+		key, err := readPublicKey(path)
+		if err != nil {
+			return nil, fmt.Errorf("public key %s: %w", kid, err)
+		}
+		v.keys[kid] = key
+	}
+	if len(v.keys) > 0 {
+		methods = append(methods, "RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA")
+	}
+
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods(methods),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(cfg.Leeway),
+	}
+	if cfg.Issuer != "" {
+		opts = append(opts, jwt.WithIssuer(cfg.Issuer))
+	}
+	if len(cfg.Audiences) > 0 {
+		opts = append(opts, jwt.WithAudience(cfg.Audiences...))
+	}
+	v.parser = jwt.NewParser(opts...)
+	return v, nil
+}
+
+// readPublicKey reads a PEM encoded PKIX or PKCS #1 public key
+func readPublicKey(path string) (crypto.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM data")
+	}
+	switch block.Type {
+	case "PUBLIC KEY":
+		return x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	}
+	return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+}
+
+// key picks the key a token is verified with: the HMAC secret for HS*
+// tokens, otherwise the public key named by kid, or the only one when the
+// token names none
+func (v *Verifier) key(token *jwt.Token) (interface{}, error) {
+	if _, ok := token.Method.(*jwt.SigningMethodHMAC); ok {
+		return v.hmacSecret, nil
+	}
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" && len(v.keys) == 1 {
+		for _, key := range v.keys {
+			return key, nil
+		}
+	}
+	key, ok := v.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown key %q", kid)
+	}
+	return key, nil
+}
+
+// Verify checks token and returns the identity it carries
+func (v *Verifier) Verify(token string) (*Identity, error) {
+	claims := jwt.MapClaims{}
+	if _, err := v.parser.ParseWithClaims(token, claims, v.key); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidToken, err)
+	}
+
+	id := &Identity{
+		TenantID:   stringClaim(claims, v.claims.Tenant),
+		Tenants:    listClaim(claims, v.claims.Tenants),
+		ConsumerID: stringClaim(claims, v.claims.Consumer),
+		ProviderID: stringClaim(claims, v.claims.Provider),
+		Roles:      listClaim(claims, v.claims.Roles),
+		APIKeyID:   stringClaim(claims, v.claims.APIKey),
+	}
+	id.Subject, _ = claims.GetSubject()
+	if id.Subject == "" {
+		return nil, fmt.Errorf("%w: the token has no subject", ErrInvalidToken)
+	}
+	for _, role := range id.Roles {
+		if slices.Contains(v.operatorRoles, role) {
+			id.Operator = true
+		}
+	}
+	// The backends take a request naming no organisation to be an
+	// operator's, so every other caller acts at least as a consumer
+	if !id.Operator && id.ConsumerID == "" && id.ProviderID == "" {
+		id.ConsumerID = id.Subject
+	}
+	return id, nil
+}
+
+func stringClaim(claims jwt.MapClaims, name string) string {
+	if name == "" {
+		return ""
+	}
+	s, _ := claims[name].(string)
+	return s
+}
+
+// listClaim reads a list of strings, or a space separated string as OAuth
+// scopes are
+func listClaim(claims jwt.MapClaims, name string) []string {
+	if name == "" {
+		return nil
+	}
+	switch value := claims[name].(type) {
+	case string:
+		return strings.Fields(value)
+	case []interface{}:
+		list := make([]string, 0, len(value))
+		for _, item := range value {
+			if s, ok := item.(string); ok && s != "" {
+				list = append(list, s)
+			}
+		}
+		return list
+	}
+	return nil
+}
@@ -0,0 +1,369 @@
+// Package config loads the API gateway configuration from built-in defaults,
+// an optional YAML file and API_GATEWAY_* environment variables, in that
+// order.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/org/llm-marketplace/pkg/marketplace/envconfig"
+	"gopkg.in/yaml.v3"
+)
+
+type Config struct {
+	Server   ServerConfig   `yaml:"server"`
+	Auth     AuthConfig     `yaml:"auth"`
+	Identity IdentityConfig `yaml:"identity"`
+	Routes   []RouteConfig  `yaml:"routes"`
+	Redis    RedisConfig    `yaml:"redis"`
+	Upstream UpstreamConfig `yaml:"upstream"`
+	Tracing  TracingConfig  `yaml:"tracing"`
+	Logging  LoggingConfig  `yaml:"logging"`
+}
+
+type ServerConfig struct {
+	Host         string        `yaml:"host"`
+	Port         int           `yaml:"port"`
+	Mode         string        `yaml:"mode"` // development, production
+	ReadTimeout  time.Duration `yaml:"read_timeout"`
+	WriteTimeout time.Duration `yaml:"write_timeout"` // Must cover the slowest route's timeout
+	IdleTimeout  time.Duration `yaml:"idle_timeout"`
+
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+
+	MaxBodyBytes int64 `yaml:"max_body_bytes"` // Largest request body forwarded
+
+	// TrustedProxies are the addresses or CIDRs of load balancers whose
+	// X-Forwarded-For is believed. Anonymous callers are rate limited by
+	// client IP, so by default no proxy is trusted.
+	TrustedProxies []string `yaml:"trusted_proxies"`
+}
+
+// AuthConfig is how bearer tokens are verified. Tokens are JWTs signed with
+// the HMAC secret (HS256, HS384, HS512) or one of the public keys (RSA,
+// ECDSA or Ed25519), and must not have expired.
+type AuthConfig struct {
+	Issuer     string            `yaml:"issuer"`    // Required iss claim, if set
+	Audiences  []string          `yaml:"audiences"` // The aud claim must name one of them, if set
+	HMACSecret string            `yaml:"hmac_secret"`
+	PublicKeys map[string]string `yaml:"public_keys"` // Key ID (the kid header) to PEM file
+	Leeway     time.Duration     `yaml:"leeway"`      // Clock skew allowed on exp, nbf and iat
+
+	Claims ClaimsConfig `yaml:"claims"`
+
+	// OperatorRoles make a token a marketplace operator's
+	OperatorRoles []string `yaml:"operator_roles"`
+}
+
+// ClaimsConfig names the token claims the caller is read from. The subject
+// is always sub.
+type ClaimsConfig struct {
+	Tenant   string `yaml:"tenant"`   // The caller's tenant
+	Tenants  string `yaml:"tenants"`  // Every tenant the caller may act in, chosen with the tenant header
+	Consumer string `yaml:"consumer"` // The consumer organisation
+	Provider string `yaml:"provider"` // The provider organisation
+	Roles    string `yaml:"roles"`    // A list, or a space separated string
+	APIKey   string `yaml:"api_key"`  // The API key the token was issued for
+}
+
+// IdentityConfig names the headers the authenticated caller is passed to
+// the backends in. They are removed from every client request, whatever the
+// route, so a caller can't claim to be someone else.
+type IdentityConfig struct {
+	UserHeader     string `yaml:"user_header"`
+	TenantHeader   string `yaml:"tenant_header"` // Also where a caller with several tenants chooses one
+	ConsumerHeader string `yaml:"consumer_header"`
+	ProviderHeader string `yaml:"provider_header"`
+	// OperatorHeader carries an operator's subject, for audit only: the
+	// backends take requests without caller headers to be an operator's
+	OperatorHeader string `yaml:"operator_header"`
+	APIKeyHeader   string `yaml:"api_key_header"` // Discovery counts quotas by it
+}
+
+// Headers returns every identity header
+func (c IdentityConfig) Headers() []string {
+	return []string{c.UserHeader, c.TenantHeader, c.ConsumerHeader, c.ProviderHeader, c.OperatorHeader, c.APIKeyHeader}
+}
+
+// Route protocols
+const (
+	ProtocolHTTP = "http"
+	ProtocolGRPC = "grpc"
+)
+
+// Route authentication modes
+const (
+	AuthRequired = "required" // A valid token is required
+	AuthOptional = "optional" // Anonymous callers are let through; a token sent must be valid
+	AuthNone     = "none"     // The backend authenticates; Authorization is passed through
+)
+
+// Caller kinds a route can be restricted to
+const (
+	CallerOperator = "operator"
+	CallerProvider = "provider"
+	CallerConsumer = "consumer"
+)
+
+// RouteConfig sends requests whose path starts with one of Paths, on a
+// segment boundary, to Upstream. A path segment of * matches any one
+// segment. When several routes match, the longest path wins, then the route
+// listed first.
+type RouteConfig struct {
+	Name        string        `yaml:"name"` // Used in metrics, logs and rate limit keys
+	Paths       []string      `yaml:"paths"`
+	Methods     []string      `yaml:"methods"` // Empty matches every method
+	Upstream    string        `yaml:"upstream"`
+	StripPrefix string        `yaml:"strip_prefix"` // Removed from the path before forwarding
+	Protocol    string        `yaml:"protocol"`     // http (default) or grpc, proxied over h2c
+	Auth        string        `yaml:"auth"`         // required (default), optional or none
+	Callers     []string      `yaml:"callers"`      // Caller kinds let through; empty lets every authenticated caller through
+	Timeout     time.Duration `yaml:"timeout"`      // Defaults to upstream.timeout
+
+	RateLimit RateLimitConfig `yaml:"rate_limit"`
+}
+
+// AuthMode returns how the route authenticates callers
+func (r RouteConfig) AuthMode() string {
+	if r.Auth == "" {
+		return AuthRequired
+	}
+	return r.Auth
+}
+
+// GRPC reports whether the route proxies gRPC
+func (r RouteConfig) GRPC() bool {
+	return r.Protocol == ProtocolGRPC
+}
+
+// RateLimitConfig limits the requests each caller makes to a route in a
+// fixed window. Authenticated callers are counted by subject, anonymous ones
+// by client IP. 0 means unlimited.
+type RateLimitConfig struct {
+	Requests          int64         `yaml:"requests"`
+	AnonymousRequests int64         `yaml:"anonymous_requests"` // Defaults to requests
+	Period            time.Duration `yaml:"period"`
+}
+
+// RedisConfig holds the rate limit counters, shared by every gateway
+// replica
+type RedisConfig struct {
+	Address  string `yaml:"address"`
+	Password string `yaml:"password"`
+	DB       int    `yaml:"db"`
+}
+
+// UpstreamConfig controls calls to the backends
+type UpstreamConfig struct {
+	Timeout             time.Duration `yaml:"timeout"` // Per request, including the response body
+	MaxIdleConnsPerHost int           `yaml:"max_idle_conns_per_host"`
+}
+
+// TracingConfig exports the gateway's spans over OTLP. Trace context is
+// propagated to the backends whether or not tracing is enabled.
+type TracingConfig struct {
+	Enabled      bool    `yaml:"enabled"`
+	Endpoint     string  `yaml:"endpoint"` // host:port of the OTLP collector
+	Protocol     string  `yaml:"protocol"` // grpc or http
+	Insecure     bool    `yaml:"insecure"`
+	SamplingRate float64 `yaml:"sampling_rate"` // Of traces the caller didn't start; a caller's decision is kept
+}
+
+type LoggingConfig struct {
+	Level  string `yaml:"level"`  // debug, info, warn, error
+	Format string `yaml:"format"` // json, console
+}
+
+// DefaultPath is the config file used when CONFIG_PATH is not set
+const DefaultPath = "config.yaml"
+
+// Path returns the config file to load: CONFIG_PATH if set, otherwise
+// config.yaml when it exists in the working directory. It is empty when the
+// service is configured by defaults and environment variables alone.
+func Path() string {
+	if path := os.Getenv("CONFIG_PATH"); path != "" {
+		return path
+	}
+	if _, err := os.Stat(DefaultPath); err == nil {
+		return DefaultPath
+	}
+	return ""
+}
+
+// Load builds the configuration from the built-in defaults, overridden by the
+// file at path, if any, then by API_GATEWAY_* environment variables. Routes
+// in the file replace the default routes as a whole.
+func Load(path string) (*Config, error) {
+	var cfg Config
+	cfg.setDefaults()
+
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read config file: %w", err)
+		}
+		if err := yaml.Unmarshal([]byte(os.ExpandEnv(string(data))), &cfg); err != nil {
+			return nil, fmt.Errorf("failed to parse config: %w", err)
+		}
+	}
+
+	if err := envconfig.Apply(&cfg, "API_GATEWAY_", os.Getenv); err != nil {
+		return nil, fmt.Errorf("invalid environment override: %w", err)
+	}
+
+	if err := validate(&cfg); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+	return &cfg, nil
+}
+
+// minSecretBytes is the shortest HMAC secret accepted, the size of an
+// HS256 key
+const minSecretBytes = 32
+
+func validate(cfg *Config) error {
+	var errs []error
+	if cfg.Server.Port <= 0 || cfg.Server.Port > 65535 {
+		errs = append(errs, fmt.Errorf("server.port %d is not a valid port", cfg.Server.Port))
+	}
+	if cfg.Server.MaxBodyBytes <= 0 {
+		errs = append(errs, errors.New("server.max_body_bytes must be positive"))
+	}
+
+	if cfg.Auth.HMACSecret == "" && len(cfg.Auth.PublicKeys) == 0 {
+		errs = append(errs, errors.New("auth.hmac_secret or auth.public_keys is required to verify tokens"))
+	}
+	if cfg.Auth.HMACSecret != "" && len(cfg.Auth.HMACSecret) < minSecretBytes {
+		errs = append(errs, fmt.Errorf("auth.hmac_secret must be at least %d bytes", minSecretBytes))
+	}
+	if cfg.Auth.Leeway < 0 {
+		errs = append(errs, errors.New("auth.leeway must not be negative"))
+	}
+
+	seen := map[string]bool{}
+	for _, header := range cfg.Identity.Headers() {
+		if header == "" {
+			errs = append(errs, errors.New("every identity header must be named"))
+			break
+		}
+		if seen[http.CanonicalHeaderKey(header)] {
+			errs = append(errs, fmt.Errorf("identity header %s is used twice", header))
+		}
+		seen[http.CanonicalHeaderKey(header)] = true
+	}
+
+	if len(cfg.Routes) == 0 {
+		errs = append(errs, errors.New("at least one route is required"))
+	}
+	names := map[string]bool{}
+	for i, route := range cfg.Routes {
+		if route.Name == "" {
+			errs = append(errs, fmt.Errorf("routes[%d].name is required", i))
+		} else if names[route.Name] {
+			errs = append(errs, fmt.Errorf("route %s is defined twice", route.Name))
+		}
+		names[route.Name] = true
+		if err := validateRoute(route); err != nil {
+			errs = append(errs, fmt.Errorf("route %s: %w", route.Name, err))
+		}
+	}
+
+	if cfg.Redis.Address == "" {
+		errs = append(errs, errors.New("redis.address is required"))
+	}
+	if cfg.Upstream.Timeout <= 0 {
+		errs = append(errs, errors.New("upstream.timeout must be positive"))
+	}
+
+	if cfg.Tracing.Enabled {
+		if cfg.Tracing.Endpoint == "" {
+			errs = append(errs, errors.New("tracing.endpoint is required when tracing is enabled"))
+		}
+		if cfg.Tracing.Protocol != "grpc" && cfg.Tracing.Protocol != "http" {
+			errs = append(errs, fmt.Errorf("tracing.protocol %q is not grpc or http", cfg.Tracing.Protocol))
+		}
+	}
+	if cfg.Tracing.SamplingRate < 0 || cfg.Tracing.SamplingRate > 1 {
+		errs = append(errs, errors.New("tracing.sampling_rate must be between 0 and 1"))
+	}
+	return errors.Join(errs...)
+}
+
+func validateRoute(route RouteConfig) error {
+	var errs []error
+	if len(route.Paths) == 0 {
+		errs = append(errs, errors.New("paths is required"))
+	}
+	if strings.Contains(route.StripPrefix, "*") {
+		errs = append(errs, fmt.Errorf("strip_prefix %q can't have wildcards", route.StripPrefix))
+	}
+	for _, path := range route.Paths {
+		if !strings.HasPrefix(path, "/") {
+			errs = append(errs, fmt.Errorf("path %q must start with /", path))
+		}
+		for _, segment := range strings.Split(path, "/") {
+			if segment != "*" && strings.Contains(segment, "*") {
+				errs = append(errs, fmt.Errorf("path %q: * must be a whole segment", path))
+			}
+		}
+		if route.StripPrefix != "" && !strings.HasPrefix(path, route.StripPrefix) {
+			errs = append(errs, fmt.Errorf("strip_prefix %q is not a prefix of path %q", route.StripPrefix, path))
+		}
+	}
+
+	if u, err := url.Parse(route.Upstream); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		errs = append(errs, fmt.Errorf("upstream %q must be an http or https URL", route.Upstream))
+	}
+
+	switch route.Protocol {
+	case "", ProtocolHTTP:
+	case ProtocolGRPC:
+		for _, method := range route.Methods {
+			if method != http.MethodPost {
+				errs = append(errs, errors.New("gRPC routes only take POST"))
+				break
+			}
+		}
+	default:
+		errs = append(errs, fmt.Errorf("protocol %q is not http or grpc", route.Protocol))
+	}
+	for _, method := range route.Methods {
+		if method == "" || method != strings.ToUpper(method) {
+			errs = append(errs, fmt.Errorf("method %q must be upper case", method))
+		}
+	}
+
+	switch route.AuthMode() {
+	case AuthRequired, AuthOptional, AuthNone:
+	default:
+		errs = append(errs, fmt.Errorf("auth %q is not required, optional or none", route.Auth))
+	}
+	for _, caller := range route.Callers {
+		switch caller {
+		case CallerOperator, CallerProvider, CallerConsumer:
+		default:
+			errs = append(errs, fmt.Errorf("caller %q is not operator, provider or consumer", caller))
+		}
+	}
+	if len(route.Callers) > 0 && route.AuthMode() != AuthRequired {
+		errs = append(errs, errors.New("callers can only be restricted when auth is required"))
+	}
+	if route.Timeout < 0 {
+		errs = append(errs, errors.New("timeout must not be negative"))
+	}
+
+	limit := route.RateLimit
+	if limit.Requests < 0 || limit.AnonymousRequests < 0 {
+		errs = append(errs, errors.New("rate_limit requests must not be negative"))
+	}
+	if (limit.Requests > 0 || limit.AnonymousRequests > 0) && limit.Period <= 0 {
+		errs = append(errs, errors.New("rate_limit.period must be positive"))
+	}
+	return errors.Join(errs...)
+}
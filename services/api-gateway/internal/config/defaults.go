@@ -0,0 +1,215 @@
+package config
+
+import "time"
+
+// setDefaults fills in the settings used when neither the config file nor the
+// environment sets them. They match config.yaml, with the backends expected
+// on localhost. There is no default token key; auth.hmac_secret or
+// auth.public_keys must be set.
+func (c *Config) setDefaults() {
+	c.Server.Host = "0.0.0.0"
+	c.Server.Port = 8000
+	c.Server.Mode = "development"
+	c.Server.ReadTimeout = 30 * time.Second
+	c.Server.WriteTimeout = 2 * time.Minute
+	c.Server.IdleTimeout = 120 * time.Second
+	c.Server.ShutdownTimeout = 30 * time.Second
+	c.Server.MaxBodyBytes = 10 << 20
+
+	c.Auth.Leeway = 30 * time.Second
+	c.Auth.Claims = ClaimsConfig{
+		Tenant:   "tenant_id",
+		Tenants:  "tenants",
+		Consumer: "consumer_id",
+		Provider: "provider_id",
+		Roles:    "roles",
+		APIKey:   "api_key_id",
+	}
+	c.Auth.OperatorRoles = []string{"operator"}
+
+	c.Identity = IdentityConfig{
+		UserHeader:     "X-User-ID",
+		TenantHeader:   "X-Tenant-ID",
+		ConsumerHeader: "X-Consumer-ID",
+		ProviderHeader: "X-Provider-ID",
+		OperatorHeader: "X-Operator-ID",
+		APIKeyHeader:   "X-API-Key-ID",
+	}
+
+	c.Routes = DefaultRoutes("http://localhost:8080", "http://localhost:3010", "http://localhost:50051")
+
+	c.Redis.Address = "localhost:6379"
+
+	c.Upstream.Timeout = 60 * time.Second
+	c.Upstream.MaxIdleConnsPerHost = 32
+
+	c.Tracing.Protocol = "grpc"
+	c.Tracing.Insecure = true
+	c.Tracing.SamplingRate = 0.1
+
+	c.Logging.Level = "info"
+	c.Logging.Format = "json"
+}
+
+// DefaultRoutes are the routes to discovery, the registry and the policy
+// engine at the URLs given
+func DefaultRoutes(discovery, registry, policyEngine string) []RouteConfig {
+	const (
+		discoveryAPI = "/discovery/api/v1"
+		registryAPI  = "/registry/api/v1"
+		policyAPI    = "/policyengine.v1.PolicyEngineService"
+	)
+	perMinute := func(requests, anonymous int64) RateLimitConfig {
+		return RateLimitConfig{Requests: requests, AnonymousRequests: anonymous, Period: time.Minute}
+	}
+	return []RouteConfig{
+		// Discovery shows anonymous callers public services only
+		{
+			Name:        "discovery-search",
+			Paths:       []string{discoveryAPI + "/search", discoveryAPI + "/events/click", discoveryAPI + "/events/dismiss"},
+			Methods:     []string{"GET", "POST"},
+			Upstream:    discovery,
+			StripPrefix: "/discovery",
+			Auth:        AuthOptional,
+			RateLimit:   perMinute(120, 30),
+		},
+		// Answers call a model, so they are limited more tightly
+		{
+			Name:        "discovery-ask",
+			Paths:       []string{discoveryAPI + "/ask"},
+			Methods:     []string{"POST"},
+			Upstream:    discovery,
+			StripPrefix: "/discovery",
+			RateLimit:   perMinute(20, 0),
+		},
+		{
+			Name: "discovery-catalog",
+			Paths: []string{
+				discoveryAPI + "/services", discoveryAPI + "/categories", discoveryAPI + "/tags",
+				discoveryAPI + "/taxonomy", discoveryAPI + "/autocomplete", discoveryAPI + "/recommendations",
+			},
+			Methods:     []string{"GET"},
+			Upstream:    discovery,
+			StripPrefix: "/discovery",
+			Auth:        AuthOptional,
+			RateLimit:   perMinute(600, 120),
+		},
+		{
+			Name:        "discovery-operator",
+			Paths:       []string{discoveryAPI + "/admin", discoveryAPI + "/analytics"},
+			Upstream:    discovery,
+			StripPrefix: "/discovery",
+			Callers:     []string{CallerOperator},
+			RateLimit:   perMinute(60, 0),
+		},
+		{
+			Name:        "discovery-taxonomy",
+			Paths:       []string{discoveryAPI + "/taxonomy"},
+			Methods:     []string{"POST", "PATCH", "DELETE"},
+			Upstream:    discovery,
+			StripPrefix: "/discovery",
+			Callers:     []string{CallerOperator},
+			RateLimit:   perMinute(60, 0),
+		},
+		{
+			Name:        "discovery-status",
+			Paths:       []string{discoveryAPI + "/services"},
+			Methods:     []string{"PUT"},
+			Upstream:    discovery,
+			StripPrefix: "/discovery",
+			Callers:     []string{CallerOperator},
+			RateLimit:   perMinute(60, 0),
+		},
+		// Providers report the health of their services; discovery checks
+		// that each service is the caller's own
+		{
+			Name:        "discovery-health-reports",
+			Paths:       []string{discoveryAPI + "/services/*/health/reports"},
+			Methods:     []string{"POST"},
+			Upstream:    discovery,
+			StripPrefix: "/discovery",
+			Callers:     []string{CallerProvider},
+			RateLimit:   perMinute(60, 0),
+		},
+		{
+			Name:        "discovery",
+			Paths:       []string{discoveryAPI, "/discovery/graphql"},
+			Upstream:    discovery,
+			StripPrefix: "/discovery",
+			RateLimit:   perMinute(300, 0),
+		},
+
+		// The registry takes a request without X-Provider-ID to be an
+		// operator's, so only providers and operators change services
+		{
+			Name:        "registry-read",
+			Paths:       []string{registryAPI},
+			Methods:     []string{"GET"},
+			Upstream:    registry,
+			StripPrefix: "/registry",
+			RateLimit:   perMinute(300, 0),
+		},
+		{
+			Name:        "registry",
+			Paths:       []string{registryAPI},
+			Upstream:    registry,
+			StripPrefix: "/registry",
+			Callers:     []string{CallerProvider, CallerOperator},
+			RateLimit:   perMinute(120, 0),
+		},
+		{
+			Name:        "registry-subscriptions",
+			Paths:       []string{registryAPI + "/subscriptions"},
+			Upstream:    registry,
+			StripPrefix: "/registry",
+			RateLimit:   perMinute(120, 0),
+		},
+		// Consumers report listings to the moderation queue
+		{
+			Name:        "registry-reports",
+			Paths:       []string{registryAPI + "/services/*/report"},
+			Methods:     []string{"POST"},
+			Upstream:    registry,
+			StripPrefix: "/registry",
+			Callers:     []string{CallerConsumer},
+			RateLimit:   perMinute(10, 0),
+		},
+		{
+			Name:        "registry-admin",
+			Paths:       []string{registryAPI + "/admin"},
+			Upstream:    registry,
+			StripPrefix: "/registry",
+			Callers:     []string{CallerOperator},
+			RateLimit:   perMinute(60, 0),
+		},
+		// The provider portal authenticates providers by API key
+		{
+			Name:        "registry-portal",
+			Paths:       []string{registryAPI + "/portal"},
+			Upstream:    registry,
+			StripPrefix: "/registry",
+			Auth:        AuthNone,
+			RateLimit:   perMinute(120, 0),
+		},
+
+		{
+			Name: "policy-engine",
+			Paths: []string{
+				policyAPI + "/ValidateService", policyAPI + "/GetPolicy",
+				policyAPI + "/ListPolicies", policyAPI + "/HealthCheck",
+			},
+			Upstream:  policyEngine,
+			Protocol:  ProtocolGRPC,
+			RateLimit: perMinute(300, 0),
+		},
+		// Access checks name any consumer, and policies are the operators'
+		{
+			Name:      "policy-engine-operator",
+			Paths:     []string{policyAPI},
+			Upstream:  policyEngine,
+			Protocol:  ProtocolGRPC,
+			Callers:   []string{CallerOperator},
+			RateLimit: perMinute(300, 0),
+		},
+	}
+}
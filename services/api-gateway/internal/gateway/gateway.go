@@ -0,0 +1,369 @@
+// Package gateway routes requests to the marketplace backends. Each request
+// is matched to a route, its caller authenticated and their tenant resolved,
+// held to the route's rate limit, and forwarded with the caller in identity
+// headers, a request ID and the trace context.
+package gateway
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+
+	"github.com/org/llm-marketplace/services/api-gateway/internal/auth"
+	"github.com/org/llm-marketplace/services/api-gateway/internal/config"
+	"github.com/org/llm-marketplace/services/api-gateway/internal/limits"
+)
+
+// requestIDHeader is reused from the caller when valid, or generated, and
+// sent to the backend and echoed to the caller
+const requestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds IDs accepted from callers
+const maxRequestIDLength = 128
+
+// Gateway is the API gateway's HTTP handler
+type Gateway struct {
+	routes       routeTable
+	verifier     *auth.Verifier
+	limiter      *limits.Limiter
+	identity     config.IdentityConfig
+	timeout      time.Duration
+	maxBodyBytes int64
+	logger       *zap.Logger
+}
+
+// New creates a gateway over cfg.Routes
+func New(cfg *config.Config, verifier *auth.Verifier, limiter *limits.Limiter, logger *zap.Logger) (*Gateway, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConnsPerHost = cfg.Upstream.MaxIdleConnsPerHost
+
+	// gRPC backends speak HTTP/2, over TLS or in cleartext (h2c)
+	grpcTransport := http.DefaultTransport.(*http.Transport).Clone()
+	grpcTransport.MaxIdleConnsPerHost = cfg.Upstream.MaxIdleConnsPerHost
+	grpcTransport.Protocols = new(http.Protocols)
+	grpcTransport.Protocols.SetHTTP2(true)
+	grpcTransport.Protocols.SetUnencryptedHTTP2(true)
+
+	g := &Gateway{
+		verifier:     verifier,
+		limiter:      limiter,
+		identity:     cfg.Identity,
+		timeout:      cfg.Upstream.Timeout,
+		maxBodyBytes: cfg.Server.MaxBodyBytes,
+		logger:       logger,
+	}
+	routes, err := newRouteTable(cfg.Routes, transport, grpcTransport, g.proxyError)
+	if err != nil {
+		return nil, err
+	}
+	g.routes = routes
+	return g, nil
+}
+
+// RegisterRoutes sends every request no other handler of router serves
+// through the gateway
+func (g *Gateway) RegisterRoutes(router *gin.Engine) {
+	router.NoRoute(g.handle)
+}
+
+// requestState follows a request through the gateway and its proxy
+type requestState struct {
+	requestID string
+	clientIP  string
+	route     *route
+	outcome   string // Proxied, or why the request failed
+}
+
+type stateKey struct{}
+
+func stateFrom(ctx context.Context) *requestState {
+	state, _ := ctx.Value(stateKey{}).(*requestState)
+	return state
+}
+
+func (g *Gateway) handle(c *gin.Context) {
+	started := time.Now()
+	state := &requestState{requestID: c.GetHeader(requestIDHeader), clientIP: c.ClientIP()}
+	if !validRequestID(state.requestID) {
+		state.requestID = newRequestID()
+	}
+	c.Header(requestIDHeader, state.requestID)
+	c.Request.Header.Set(requestIDHeader, state.requestID)
+
+	// Continue a trace started by the caller
+	ctx := otel.GetTextMapPropagator().Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+	ctx, span := otel.Tracer("api-gateway").Start(ctx, c.Request.Method,
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			attribute.String("http.method", c.Request.Method),
+			attribute.String("http.target", c.Request.URL.Path),
+			attribute.String("http.request_id", state.requestID),
+		),
+	)
+	c.Request = c.Request.WithContext(context.WithValue(ctx, stateKey{}, state))
+
+	defer func() {
+		routeName := "unmatched"
+		if state.route != nil {
+			routeName = state.route.Name
+			span.SetName(c.Request.Method + " " + routeName)
+			span.SetAttributes(attribute.String("gateway.route", routeName))
+		}
+		status := c.Writer.Status()
+		span.SetAttributes(attribute.Int("http.status_code", status), attribute.String("gateway.outcome", state.outcome))
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, state.outcome)
+		}
+		span.End()
+		observeRequest(routeName, state.outcome, status, started)
+	}()
+
+	if !g.admit(c, state) {
+		return
+	}
+	g.forward(c, state)
+}
+
+// admit runs the checks a request must pass before it is forwarded and
+// prepares its headers. It reports whether to forward the request, having
+// written the response otherwise.
+func (g *Gateway) admit(c *gin.Context, state *requestState) bool {
+	req := c.Request
+	if !cleanPath(req.URL.Path) {
+		g.refuse(c, state, "invalid_request", invalidRequest, "The path must be absolute, without dot segments or repeated slashes")
+		return false
+	}
+
+	r, wrongMethod := g.routes.match(req.Method, req.URL.Path)
+	if r == nil {
+		if wrongMethod {
+			g.refuse(c, state, "method_not_allowed", methodNotAllowed, req.Method+" is not supported on "+req.URL.Path)
+			return false
+		}
+		g.refuse(c, state, "not_found", notFound, "No route matches "+req.URL.Path)
+		return false
+	}
+	state.route = r
+
+	var id *auth.Identity
+	if r.AuthMode() != config.AuthNone {
+		token, present := auth.BearerToken(req.Header)
+		switch {
+		case !present && r.AuthMode() == config.AuthRequired:
+			g.unauthenticated(c, state, "missing_token", "A bearer token is required")
+			return false
+		case present:
+			var err error
+			if id, err = g.verifier.Verify(token); err != nil {
+				g.unauthenticated(c, state, "invalid_token", "The bearer token is invalid: "+strings.TrimPrefix(err.Error(), auth.ErrInvalidToken.Error()+": "))
+				return false
+			}
+		}
+	}
+
+	if len(r.Callers) > 0 && (id == nil || !slices.ContainsFunc(r.Callers, id.Is)) {
+		authFailures.WithLabelValues(r.Name, "caller").Inc()
+		g.refuse(c, state, "forbidden", forbidden, fmt.Sprintf("Only %s callers may use this route", strings.Join(r.Callers, " or ")))
+		return false
+	}
+
+	var tenant string
+	if id != nil && !id.Operator {
+		requested := req.Header.Get(g.identity.TenantHeader)
+		var err error
+		if tenant, err = id.Tenant(requested); err != nil {
+			authFailures.WithLabelValues(r.Name, "tenant").Inc()
+			if errors.Is(err, auth.ErrTenantRequired) {
+				g.refuse(c, state, "invalid_request", invalidRequest, fmt.Sprintf("The token grants several tenants; choose one in %s", g.identity.TenantHeader))
+			} else {
+				g.refuse(c, state, "forbidden", forbidden, fmt.Sprintf("The token does not grant tenant %s", requested))
+			}
+			return false
+		}
+	}
+
+	if !g.allow(c, state, id) {
+		return false
+	}
+
+	if req.ContentLength > g.maxBodyBytes {
+		g.refuse(c, state, "payload_too_large", payloadTooLarge, fmt.Sprintf("Request bodies are limited to %d bytes", g.maxBodyBytes))
+		return false
+	}
+
+	g.setIdentity(req.Header, id, tenant)
+	if r.AuthMode() != config.AuthNone {
+		// The token is the gateway's to check; the backends trust the
+		// identity headers
+		req.Header.Del("Authorization")
+	}
+	return true
+}
+
+// allow holds the request to its route's rate limit, counting an
+// authenticated caller by subject and an anonymous one by client IP
+func (g *Gateway) allow(c *gin.Context, state *requestState, id *auth.Identity) bool {
+	limit := state.route.RateLimit
+	window := limits.Window{Period: limit.Period, Max: limit.Requests}
+	caller := "ip:" + state.clientIP
+	if id != nil {
+		caller = "sub:" + id.Subject
+	} else if limit.AnonymousRequests > 0 {
+		window.Max = limit.AnonymousRequests
+	}
+
+	result, err := g.limiter.Allow(c.Request.Context(), state.route.Name, caller, window)
+	if err != nil {
+		// Limits can't be enforced without the counters, so nothing goes through
+		g.logger.Error("Rate limit counters unavailable", zap.Error(err))
+		g.refuse(c, state, "limiter_unavailable", serviceUnavailable, "Rate limits can't be checked; try again later")
+		return false
+	}
+	if result.Limit == 0 {
+		return true
+	}
+
+	reset := strconv.Itoa(int(math.Ceil(result.Reset.Seconds())))
+	c.Header("X-RateLimit-Limit", strconv.FormatInt(result.Limit, 10))
+	c.Header("X-RateLimit-Remaining", strconv.FormatInt(result.Remaining, 10))
+	c.Header("X-RateLimit-Reset", reset)
+	if !result.Allowed {
+		c.Header("Retry-After", reset)
+		g.refuse(c, state, "rate_limited", rateLimited, fmt.Sprintf("At most %d requests per %s are allowed on this route", result.Limit, limit.Period))
+		return false
+	}
+	return true
+}
+
+// setIdentity replaces whatever identity headers the client sent with the
+// authenticated caller's. An operator is passed in none of the caller
+// headers, as the backends expect, and named in the operator header only.
+func (g *Gateway) setIdentity(h http.Header, id *auth.Identity, tenant string) {
+	for _, name := range g.identity.Headers() {
+		h.Del(name)
+	}
+	if id == nil {
+		return
+	}
+	if id.Operator {
+		h.Set(g.identity.OperatorHeader, id.Subject)
+		return
+	}
+	h.Set(g.identity.UserHeader, id.Subject)
+	for name, value := range map[string]string{
+		g.identity.TenantHeader:   tenant,
+		g.identity.ConsumerHeader: id.ConsumerID,
+		g.identity.ProviderHeader: id.ProviderID,
+		g.identity.APIKeyHeader:   id.APIKeyID,
+	} {
+		if value != "" {
+			h.Set(name, value)
+		}
+	}
+}
+
+// forward proxies the request to its route's upstream
+func (g *Gateway) forward(c *gin.Context, state *requestState) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), state.route.timeout(g.timeout))
+	defer cancel()
+
+	req := c.Request.WithContext(ctx)
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+	req.Body = http.MaxBytesReader(c.Writer, req.Body, g.maxBodyBytes)
+
+	state.outcome = "proxied"
+	state.route.proxy.ServeHTTP(c.Writer, req)
+	// An empty response still has its status line written, or the router
+	// would answer for it
+	c.Writer.WriteHeaderNow()
+}
+
+// proxyError answers a request the backend couldn't be reached for, or
+// didn't answer in time
+func (g *Gateway) proxyError(w http.ResponseWriter, r *http.Request, err error) {
+	state := stateFrom(r.Context())
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.As(err, &tooLarge):
+		state.outcome = "payload_too_large"
+		writeProblem(w, r, state.route.GRPC(), payloadTooLarge, fmt.Sprintf("Request bodies are limited to %d bytes", tooLarge.Limit))
+		return
+	case errors.Is(r.Context().Err(), context.DeadlineExceeded):
+		state.outcome = "upstream_timeout"
+		writeProblem(w, r, state.route.GRPC(), upstreamTimeout, fmt.Sprintf("The %s backend did not answer in time", state.route.Name))
+	default:
+		state.outcome = "upstream_error"
+		writeProblem(w, r, state.route.GRPC(), upstreamError, fmt.Sprintf("The %s backend could not be reached", state.route.Name))
+	}
+	g.logger.Warn("Backend call failed",
+		zap.String("route", state.route.Name),
+		zap.String("request_id", state.requestID),
+		zap.Error(err),
+	)
+}
+
+func (g *Gateway) unauthenticated(c *gin.Context, state *requestState, reason, detail string) {
+	authFailures.WithLabelValues(state.route.Name, reason).Inc()
+	challenge := `Bearer realm="llm-marketplace"`
+	if reason == "invalid_token" {
+		challenge += `, error="invalid_token"`
+	}
+	c.Header("WWW-Authenticate", challenge)
+	g.refuse(c, state, "unauthenticated", unauthenticated, detail)
+}
+
+// refuse answers a request that isn't forwarded and stops the handler chain
+func (g *Gateway) refuse(c *gin.Context, state *requestState, outcome string, t problemType, detail string) {
+	state.outcome = outcome
+	grpc := state.route != nil && state.route.GRPC()
+	writeProblem(c.Writer, c.Request, grpc, t, detail)
+	c.Abort()
+}
+
+// cleanPath reports whether p is absolute and canonical, so a prefix match
+// can't be steered elsewhere by the backend resolving dot segments
+func cleanPath(p string) bool {
+	clean := path.Clean(p)
+	if strings.HasSuffix(p, "/") && clean != "/" {
+		clean += "/"
+	}
+	return strings.HasPrefix(p, "/") && clean == p
+}
+
+// validRequestID reports whether an ID sent by a caller is safe to reuse:
+// non-empty, bounded and printable ASCII, so it can't forge log lines or
+// headers
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
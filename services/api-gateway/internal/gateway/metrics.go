@@ -0,0 +1,30 @@
+package gateway
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	requestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "api_gateway_requests_total",
+		Help: "Requests by route, outcome (proxied or why they were refused) and status code",
+	}, []string{"route", "outcome", "status"})
+	requestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "api_gateway_request_duration_seconds",
+		Help:    "Time from receiving a request to the end of its response",
+		Buckets: []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60},
+	}, []string{"route", "outcome"})
+	authFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "api_gateway_auth_failures_total",
+		Help: "Requests refused for their credentials by route and reason: missing_token, invalid_token, caller or tenant",
+	}, []string{"route", "reason"})
+)
+
+func observeRequest(route, outcome string, status int, started time.Time) {
+	requestsTotal.WithLabelValues(route, outcome, strconv.Itoa(status)).Inc()
+	requestDuration.WithLabelValues(route, outcome).Observe(time.Since(started).Seconds())
+}
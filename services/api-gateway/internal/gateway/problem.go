@@ -0,0 +1,80 @@
+package gateway
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/org/llm-marketplace/pkg/marketplace/problem"
+)
+
+// problemType is a documented class of error. GRPCCode is the gRPC status it
+// is reported as on gRPC routes.
+type problemType struct {
+	problem.Type
+	GRPCCode int
+}
+
+// gRPC status codes
+const (
+	grpcInvalidArgument   = 3
+	grpcDeadlineExceeded  = 4
+	grpcPermissionDenied  = 7
+	grpcResourceExhausted = 8
+	grpcUnimplemented     = 12
+	grpcUnavailable       = 14
+	grpcUnauthenticated   = 16
+)
+
+// Documented error types: the shared ones with their gRPC status, and the
+// gateway's own. See README "Error Responses".
+var (
+	invalidRequest     = problemType{problem.InvalidRequest, grpcInvalidArgument}
+	unauthenticated    = problemType{problem.Type{Code: "unauthenticated", Title: "Caller not authenticated", Status: http.StatusUnauthorized}, grpcUnauthenticated}
+	forbidden          = problemType{problem.Forbidden, grpcPermissionDenied}
+	notFound           = problemType{problem.NotFound, grpcUnimplemented}
+	methodNotAllowed   = problemType{problem.MethodNotAllowed, grpcUnimplemented}
+	payloadTooLarge    = problemType{problem.PayloadTooLarge, grpcResourceExhausted}
+	rateLimited        = problemType{problem.RateLimited, grpcResourceExhausted}
+	upstreamError      = problemType{problem.Type{Code: "upstream-error", Title: "Backend call failed", Status: http.StatusBadGateway}, grpcUnavailable}
+	serviceUnavailable = problemType{problem.ServiceUnavailable, grpcUnavailable}
+	upstreamTimeout    = problemType{problem.Type{Code: "upstream-timeout", Title: "Backend timed out", Status: http.StatusGatewayTimeout}, grpcDeadlineExceeded}
+)
+
+// problemDetails adds the request's ID to problem details
+type problemDetails struct {
+	problem.Details
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// writeProblem answers with problem details, or on a gRPC route with a
+// trailers-only gRPC response carrying the matching status
+func writeProblem(w http.ResponseWriter, r *http.Request, grpc bool, t problemType, detail string) {
+	if grpc {
+		h := w.Header()
+		h.Set("Content-Type", "application/grpc")
+		h.Set("Grpc-Status", strconv.Itoa(t.GRPCCode))
+		h.Set("Grpc-Message", grpcMessage(detail))
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	problem.Write(w, t.Status, &problemDetails{
+		Details:   problem.New(t.Type, r.URL.Path, detail),
+		RequestID: w.Header().Get(requestIDHeader),
+	})
+}
+
+// grpcMessage percent-encodes a grpc-message value as the gRPC HTTP/2
+// protocol requires
+func grpcMessage(msg string) string {
+	const hex = "0123456789ABCDEF"
+	encoded := make([]byte, 0, len(msg))
+	for i := 0; i < len(msg); i++ {
+		c := msg[i]
+		if c < 0x20 || c > 0x7e || c == '%' {
+			encoded = append(encoded, '%', hex[c>>4], hex[c&0xf])
+			continue
+		}
+		encoded = append(encoded, c)
+	}
+	return string(encoded)
+}
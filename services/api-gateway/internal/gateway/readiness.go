@@ -0,0 +1,37 @@
+package gateway
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Check is a readiness check of one dependency
+type Check func(ctx context.Context) error
+
+// Readiness reports 200 when every check passes and 503 naming the failing
+// ones otherwise
+func Readiness(checks map[string]Check, timeout time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+
+		status := http.StatusOK
+		results := make(map[string]string, len(checks))
+		for name, check := range checks {
+			if err := check(ctx); err != nil {
+				status = http.StatusServiceUnavailable
+				results[name] = err.Error()
+				continue
+			}
+			results[name] = "ok"
+		}
+		ready := "ready"
+		if status != http.StatusOK {
+			ready = "not_ready"
+		}
+		c.JSON(status, gin.H{"status": ready, "checks": results, "timestamp": time.Now().UTC()})
+	}
+}
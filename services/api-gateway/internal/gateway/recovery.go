@@ -0,0 +1,36 @@
+package gateway
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// Recovery answers a request whose handler panicked with a 500 and logs the
+// panic. http.ErrAbortHandler, which the proxy raises when a response breaks
+// off midway, is passed on so the server drops the connection and the
+// client sees the response is incomplete.
+func Recovery(logger *zap.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			err := recover()
+			if err == nil {
+				return
+			}
+			if err == http.ErrAbortHandler {
+				panic(err)
+			}
+			logger.Error("Panic recovered",
+				zap.Any("error", err),
+				zap.String("method", c.Request.Method),
+				zap.String("path", c.Request.URL.Path),
+				zap.String("request_id", c.Writer.Header().Get(requestIDHeader)),
+			)
+			if !c.Writer.Written() {
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+			}
+		}()
+		c.Next()
+	}
+}
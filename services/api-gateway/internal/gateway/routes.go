@@ -0,0 +1,141 @@
+package gateway
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/org/llm-marketplace/services/api-gateway/internal/config"
+)
+
+// route is a configured route with the proxy to its upstream
+type route struct {
+	config.RouteConfig
+	proxy *httputil.ReverseProxy
+}
+
+// matches returns the length of the route's longest path prefixing path on
+// a segment boundary, or -1
+func (r *route) matches(path string) int {
+	longest := -1
+	for _, prefix := range r.Paths {
+		if len(prefix) > longest && hasPathPrefix(path, prefix) {
+			longest = len(prefix)
+		}
+	}
+	return longest
+}
+
+func hasPathPrefix(path, prefix string) bool {
+	if strings.Contains(prefix, "*") {
+		return hasPatternPrefix(path, prefix)
+	}
+	if !strings.HasPrefix(path, prefix) {
+		return false
+	}
+	return len(path) == len(prefix) || strings.HasSuffix(prefix, "/") || path[len(prefix)] == '/'
+}
+
+// hasPatternPrefix is hasPathPrefix for a prefix with * segments, each
+// matching one non-empty segment of path
+func hasPatternPrefix(path, pattern string) bool {
+	want := strings.Split(strings.TrimSuffix(pattern, "/"), "/")
+	got := strings.Split(path, "/")
+	if len(got) < len(want) {
+		return false
+	}
+	for i, segment := range want {
+		if segment == "*" && got[i] == "" || segment != "*" && segment != got[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *route) allows(method string) bool {
+	return len(r.Methods) == 0 || slices.Contains(r.Methods, method)
+}
+
+// routeTable picks the route of each request
+type routeTable []*route
+
+// newRouteTable builds the routes and their proxies, calling upstreams with
+// transport, or grpcTransport on gRPC routes
+func newRouteTable(routes []config.RouteConfig, transport, grpcTransport http.RoundTripper, errorHandler func(http.ResponseWriter, *http.Request, error)) (routeTable, error) {
+	table := make(routeTable, 0, len(routes))
+	for _, cfg := range routes {
+		target, err := url.Parse(cfg.Upstream)
+		if err != nil {
+			return nil, fmt.Errorf("route %s: invalid upstream: %w", cfg.Name, err)
+		}
+		strip := cfg.StripPrefix
+		proxy := &httputil.ReverseProxy{
+			Rewrite: func(pr *httputil.ProxyRequest) {
+				pr.Out.URL.Path = stripPrefix(pr.Out.URL.Path, strip)
+				if pr.Out.URL.RawPath != "" {
+					pr.Out.URL.RawPath = stripPrefix(pr.Out.URL.RawPath, strip)
+				}
+				pr.SetURL(target)
+				pr.SetXForwarded()
+				// The client as the gateway sees it, past trusted proxies
+				if state := stateFrom(pr.In.Context()); state != nil {
+					pr.Out.Header.Set("X-Forwarded-For", state.clientIP)
+				}
+			},
+			Transport:    transport,
+			ErrorHandler: errorHandler,
+		}
+		if cfg.GRPC() {
+			proxy.Transport = grpcTransport
+			// gRPC streams each message as it is written
+			proxy.FlushInterval = -1
+		}
+		table = append(table, &route{RouteConfig: cfg, proxy: proxy})
+	}
+	return table, nil
+}
+
+func stripPrefix(path, prefix string) string {
+	path = strings.TrimPrefix(path, prefix)
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return path
+}
+
+// match returns the route of a request: among those with a path prefixing
+// path and allowing method, the one with the longest path, then the first
+// listed. methodNotAllowed reports whether some route has a matching path
+// but none allows method.
+func (t routeTable) match(method, path string) (matched *route, methodNotAllowed bool) {
+	longest := -1
+	for _, r := range t {
+		n := r.matches(path)
+		if n < 0 {
+			continue
+		}
+		if !r.allows(method) {
+			methodNotAllowed = true
+			continue
+		}
+		if n > longest {
+			matched, longest = r, n
+		}
+	}
+	if matched != nil {
+		return matched, false
+	}
+	return nil, methodNotAllowed
+}
+
+// timeout returns how long a request to r may take
+func (r *route) timeout(fallback time.Duration) time.Duration {
+	if r.Timeout > 0 {
+		return r.Timeout
+	}
+	return fallback
+}
@@ -0,0 +1,90 @@
+// Package limits enforces the per-route rate limits. Requests are counted
+// in fixed windows kept in Redis, so the limits hold across gateway
+// replicas.
+package limits
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Counter counts events in expiring windows
+type Counter interface {
+	// Incr adds one to the count at key and returns the new count. A new
+	// count expires after ttl.
+	Incr(ctx context.Context, key string, ttl time.Duration) (int64, error)
+}
+
+// RedisCounter is a Counter kept in Redis
+type RedisCounter struct {
+	client redis.UniversalClient
+}
+
+// NewRedisCounter creates a counter on client
+func NewRedisCounter(client redis.UniversalClient) *RedisCounter {
+	return &RedisCounter{client: client}
+}
+
+// Incr increments key and sets its expiry in one round trip
+func (c *RedisCounter) Incr(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	var incr *redis.IntCmd
+	_, err := c.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		incr = pipe.Incr(ctx, key)
+		pipe.Expire(ctx, key, ttl)
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to count request: %w", err)
+	}
+	return incr.Val(), nil
+}
+
+// Window is a limit on requests in a period of time
+type Window struct {
+	Period time.Duration
+	Max    int64 // 0 means unlimited
+}
+
+// Result is the outcome of a check
+type Result struct {
+	Allowed   bool
+	Limit     int64
+	Remaining int64         // Requests left in the window
+	Reset     time.Duration // Until the window resets
+}
+
+// Limiter checks requests against windows
+type Limiter struct {
+	counter Counter
+	now     func() time.Time
+}
+
+// NewLimiter creates a limiter counting with counter
+func NewLimiter(counter Counter) *Limiter {
+	return &Limiter{counter: counter, now: time.Now}
+}
+
+// Allow counts a request by caller to route and reports whether it is within
+// w. Windows are aligned to the clock, so a minute window resets on the
+// minute.
+func (l *Limiter) Allow(ctx context.Context, route, caller string, w Window) (Result, error) {
+	if w.Max <= 0 {
+		return Result{Allowed: true}, nil
+	}
+	now := l.now().UTC()
+	start := now.Truncate(w.Period)
+	key := fmt.Sprintf("api-gateway:requests:%s:%s:%d:%d", route, caller, int64(w.Period.Seconds()), start.Unix())
+	count, err := l.counter.Incr(ctx, key, w.Period)
+	if err != nil {
+		return Result{}, err
+	}
+	return Result{
+		Allowed:   count <= w.Max,
+		Limit:     w.Max,
+		Remaining: max(w.Max-count, 0),
+		Reset:     start.Add(w.Period).Sub(now),
+	}, nil
+}
@@ -0,0 +1,84 @@
+// Package tracing sets up OpenTelemetry tracing and W3C trace context
+// propagation
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.uber.org/zap"
+
+	"github.com/org/llm-marketplace/services/api-gateway/internal/config"
+)
+
+// Init installs the W3C trace context and baggage propagators and, when
+// tracing is enabled, a tracer provider exporting spans over OTLP. The
+// propagators are installed either way, so a caller's trace continues in
+// the backends. It returns a function flushing and stopping the exporter.
+func Init(cfg config.TracingConfig, logger *zap.Logger) (func(context.Context), error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+	if !cfg.Enabled {
+		logger.Info("Tracing is disabled")
+		return func(context.Context) {}, nil
+	}
+
+	ctx := context.Background()
+	var exp sdktrace.SpanExporter
+	var err error
+	if cfg.Protocol == "http" {
+		opts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(cfg.Endpoint)}
+		if cfg.Insecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		exp, err = otlptracehttp.New(ctx, opts...)
+	} else {
+		opts := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(cfg.Endpoint)}
+		if cfg.Insecure {
+			opts = append(opts, otlptracegrpc.WithInsecure())
+		}
+		exp, err = otlptracegrpc.New(ctx, opts...)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP trace exporter: %w", err)
+	}
+
+	res, err := resource.New(ctx,
+		resource.WithAttributes(
+			semconv.ServiceName("llm-marketplace-api-gateway"),
+			semconv.ServiceVersion("1.0.0"),
+		),
+		resource.WithFromEnv(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trace resource: %w", err)
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exp),
+		sdktrace.WithResource(res),
+		// Keep the caller's sampling decision; sample new traces at the rate
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SamplingRate))),
+	)
+	otel.SetTracerProvider(tp)
+
+	logger.Info("Tracing initialized",
+		zap.String("endpoint", cfg.Endpoint),
+		zap.String("protocol", cfg.Protocol),
+		zap.Float64("sampling_rate", cfg.SamplingRate),
+	)
+	return func(ctx context.Context) {
+		if err := tp.Shutdown(ctx); err != nil {
+			logger.Error("Failed to shut down tracer provider", zap.Error(err))
+		}
+	}, nil
+}
@@ -0,0 +1,177 @@
+package tests
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/org/llm-marketplace/services/api-gateway/internal/auth"
+	"github.com/org/llm-marketplace/services/api-gateway/internal/config"
+)
+
+func testAuthConfig() config.AuthConfig {
+	return config.AuthConfig{
+		Issuer:     "https://auth.llm-marketplace.test",
+		Audiences:  []string{"marketplace"},
+		HMACSecret: testSecret,
+		Claims: config.ClaimsConfig{
+			Tenant: "tenant_id", Tenants: "tenants", Consumer: "consumer_id", Provider: "provider_id", Roles: "roles",
+			APIKey: "api_key_id",
+		},
+		OperatorRoles: []string{"operator"},
+	}
+}
+
+// sign issues an HS256 token for the test issuer and audience, valid for an
+// hour, with extra claims
+func sign(t *testing.T, subject string, extra jwt.MapClaims) string {
+	t.Helper()
+	claims := jwt.MapClaims{
+		"sub": subject,
+		"iss": "https://auth.llm-marketplace.test",
+		"aud": "marketplace",
+		"iat": time.Now().Unix(),
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}
+	for k, v := range extra {
+		claims[k] = v
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(testSecret))
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+	return token
+}
+
+func TestVerifierReadsIdentity(t *testing.T) {
+	v, err := auth.NewVerifier(testAuthConfig())
+	if err != nil {
+		t.Fatalf("NewVerifier: %v", err)
+	}
+
+	id, err := v.Verify(sign(t, "alice", jwt.MapClaims{"tenant_id": "acme", "provider_id": "p-1", "roles": "reader writer"}))
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if id.Subject != "alice" || id.TenantID != "acme" || id.ProviderID != "p-1" || id.ConsumerID != "" || !slices.Equal(id.Roles, []string{"reader", "writer"}) {
+		t.Errorf("identity = %+v", id)
+	}
+	if !id.Is(config.CallerProvider) || id.Is(config.CallerConsumer) || id.Is(config.CallerOperator) {
+		t.Errorf("a provider's token is %+v", id)
+	}
+
+	// A caller naming no organisation acts as a consumer, never as an operator
+	id, _ = v.Verify(sign(t, "bob", nil))
+	if id.ConsumerID != "bob" || !id.Is(config.CallerConsumer) {
+		t.Errorf("identity = %+v, want the subject as consumer", id)
+	}
+
+	id, _ = v.Verify(sign(t, "root", jwt.MapClaims{"roles": []string{"operator"}, "consumer_id": "c-1"}))
+	if !id.Operator || !id.Is(config.CallerOperator) || id.Is(config.CallerConsumer) {
+		t.Errorf("identity = %+v, want an operator", id)
+	}
+}
+
+func TestVerifierRejectsTokens(t *testing.T) {
+	v, _ := auth.NewVerifier(testAuthConfig())
+	other, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub": "alice", "iss": "https://auth.llm-marketplace.test", "aud": "marketplace", "exp": time.Now().Add(time.Hour).Unix(),
+	}).SignedString([]byte("another-secret-of-at-least-32-bytes"))
+	unsigned, _ := jwt.NewWithClaims(jwt.SigningMethodNone, jwt.MapClaims{
+		"sub": "alice", "iss": "https://auth.llm-marketplace.test", "aud": "marketplace", "exp": time.Now().Add(time.Hour).Unix(),
+	}).SignedString(jwt.UnsafeAllowNoneSignatureType)
+
+	tests := map[string]string{
+		"expired":         sign(t, "alice", jwt.MapClaims{"exp": time.Now().Add(-time.Hour).Unix()}),
+		"no expiry":       sign(t, "alice", jwt.MapClaims{"exp": nil}),
+		"other issuer":    sign(t, "alice", jwt.MapClaims{"iss": "https://evil.test"}),
+		"other audience":  sign(t, "alice", jwt.MapClaims{"aud": "billing"}),
+		"no subject":      sign(t, "", nil),
+		"other secret":    other,
+		"unsigned":        unsigned,
+		"not a token":     "not-a-token",
+		"issued too late": sign(t, "alice", jwt.MapClaims{"iat": time.Now().Add(time.Hour).Unix()}),
+	}
+	for name, token := range tests {
+		if _, err := v.Verify(token); !errors.Is(err, auth.ErrInvalidToken) {
+			t.Errorf("%s: Verify = %v, want ErrInvalidToken", name, err)
+		}
+	}
+}
+
+func TestVerifierPublicKeys(t *testing.T) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	der, _ := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	path := filepath.Join(t.TempDir(), "key.pem")
+	os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0o600)
+
+	cfg := testAuthConfig()
+	cfg.HMACSecret = ""
+	cfg.PublicKeys = map[string]string{"2026-10": path}
+	v, err := auth.NewVerifier(cfg)
+	if err != nil {
+		t.Fatalf("NewVerifier: %v", err)
+	}
+
+	claims := jwt.MapClaims{"sub": "alice", "iss": cfg.Issuer, "aud": "marketplace", "exp": time.Now().Add(time.Hour).Unix()}
+	signWith := func(kid string) string {
+		token := jwt.NewWithClaims(jwt.SigningMethodES256, claims)
+		if kid != "" {
+			token.Header["kid"] = kid
+		}
+		s, _ := token.SignedString(key)
+		return s
+	}
+	for _, kid := range []string{"2026-10", ""} {
+		if _, err := v.Verify(signWith(kid)); err != nil {
+			t.Errorf("kid %q: Verify = %v", kid, err)
+		}
+	}
+	if _, err := v.Verify(signWith("2025-01")); !errors.Is(err, auth.ErrInvalidToken) {
+		t.Errorf("unknown kid: Verify = %v, want ErrInvalidToken", err)
+	}
+	// Without the secret configured, HMAC tokens aren't accepted
+	if _, err := v.Verify(sign(t, "alice", nil)); !errors.Is(err, auth.ErrInvalidToken) {
+		t.Errorf("HS256 token: Verify = %v, want ErrInvalidToken", err)
+	}
+
+	cfg.PublicKeys = map[string]string{"missing": filepath.Join(t.TempDir(), "missing.pem")}
+	if _, err := auth.NewVerifier(cfg); err == nil {
+		t.Error("NewVerifier with a missing key file succeeded")
+	}
+}
+
+func TestIdentityTenant(t *testing.T) {
+	tests := []struct {
+		name      string
+		id        auth.Identity
+		requested string
+		want      string
+		err       error
+	}{
+		{"claim", auth.Identity{TenantID: "acme"}, "", "acme", nil},
+		{"only tenant granted", auth.Identity{Tenants: []string{"acme"}}, "", "acme", nil},
+		{"chosen", auth.Identity{Tenants: []string{"acme", "globex"}}, "globex", "globex", nil},
+		{"claim chosen", auth.Identity{TenantID: "acme", Tenants: []string{"globex"}}, "acme", "acme", nil},
+		{"none", auth.Identity{}, "", "", nil},
+		{"choice required", auth.Identity{Tenants: []string{"acme", "globex"}}, "", "", auth.ErrTenantRequired},
+		{"not granted", auth.Identity{TenantID: "acme"}, "globex", "", auth.ErrTenantNotGranted},
+		{"no tenants", auth.Identity{}, "acme", "", auth.ErrTenantNotGranted},
+	}
+	for _, tt := range tests {
+		got, err := tt.id.Tenant(tt.requested)
+		if got != tt.want || !errors.Is(err, tt.err) {
+			t.Errorf("%s: Tenant(%q) = %q, %v; want %q, %v", tt.name, tt.requested, got, err, tt.want, tt.err)
+		}
+	}
+}
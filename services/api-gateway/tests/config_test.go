@@ -0,0 +1,105 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/org/llm-marketplace/services/api-gateway/internal/config"
+)
+
+const testSecret = "a-test-secret-of-at-least-32-bytes!"
+
+func TestLoadDefaults(t *testing.T) {
+	if _, err := config.Load(""); err == nil || !strings.Contains(err.Error(), "auth.hmac_secret or auth.public_keys") {
+		t.Fatalf("Load without a key = %v, want the missing key reported", err)
+	}
+
+	t.Setenv("API_GATEWAY_AUTH_HMAC_SECRET", testSecret)
+	cfg, err := config.Load("")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.Server.Port != 8000 || cfg.Identity.TenantHeader != "X-Tenant-ID" || len(cfg.Routes) == 0 {
+		t.Errorf("defaults = %+v", cfg)
+	}
+	for _, route := range cfg.Routes {
+		if route.Name == "registry-portal" && route.AuthMode() != config.AuthNone {
+			t.Errorf("the provider portal authenticates by API key, got auth %q", route.AuthMode())
+		}
+	}
+}
+
+func TestConfigFileRoutesMatchDefaults(t *testing.T) {
+	t.Setenv("JWT_SECRET", testSecret)
+	cfg, err := config.Load("../config.yaml")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	want := config.DefaultRoutes("http://discovery:8080", "http://registry:3010", "http://policy-engine:50051")
+	if !reflect.DeepEqual(cfg.Routes, want) {
+		t.Errorf("config.yaml routes differ from the defaults:\n%+v\n%+v", cfg.Routes, want)
+	}
+}
+
+func TestConfigFileRoutesReplaceDefaults(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	os.WriteFile(path, []byte(`
+auth:
+  hmac_secret: ${TEST_GATEWAY_SECRET}
+routes:
+  - name: echo
+    paths: [/echo]
+    upstream: http://echo:8080
+    rate_limit: {requests: 5, period: 1s}
+`), 0o600)
+	t.Setenv("TEST_GATEWAY_SECRET", testSecret)
+	t.Setenv("API_GATEWAY_UPSTREAM_TIMEOUT", "5s")
+
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(cfg.Routes) != 1 || cfg.Routes[0].Name != "echo" || cfg.Routes[0].AuthMode() != config.AuthRequired {
+		t.Errorf("routes = %+v, want the file's route with auth required", cfg.Routes)
+	}
+	if cfg.Routes[0].RateLimit.Period != time.Second || cfg.Upstream.Timeout != 5*time.Second {
+		t.Errorf("rate limit %+v, upstream timeout %s", cfg.Routes[0].RateLimit, cfg.Upstream.Timeout)
+	}
+}
+
+func TestConfigValidation(t *testing.T) {
+	tests := []struct {
+		name, yaml, want string
+	}{
+		{"short secret", "auth: {hmac_secret: short}", "at least 32 bytes"},
+		{"shared identity header", "identity: {operator_header: X-User-ID}", "used twice"},
+		{"partial wildcard", "routes: [{name: r, paths: ['/api/s*'], upstream: 'http://b'}]", "whole segment"},
+		{"relative path", "routes: [{name: r, paths: [api], upstream: 'http://b'}]", "must start with /"},
+		{"bad upstream", "routes: [{name: r, paths: [/api], upstream: 'b:80'}]", "http or https URL"},
+		{"strip prefix", "routes: [{name: r, paths: [/api], upstream: 'http://b', strip_prefix: /v1}]", "not a prefix"},
+		{"protocol", "routes: [{name: r, paths: [/api], upstream: 'http://b', protocol: ws}]", "not http or grpc"},
+		{"grpc method", "routes: [{name: r, paths: [/svc], upstream: 'http://b', protocol: grpc, methods: [GET]}]", "only take POST"},
+		{"callers without auth", "routes: [{name: r, paths: [/api], upstream: 'http://b', auth: optional, callers: [operator]}]", "auth is required"},
+		{"unknown caller", "routes: [{name: r, paths: [/api], upstream: 'http://b', callers: [admin]}]", "not operator, provider or consumer"},
+		{"duplicate route", "routes: [{name: r, paths: [/a], upstream: 'http://b'}, {name: r, paths: [/b], upstream: 'http://b'}]", "defined twice"},
+		{"rate limit period", "routes: [{name: r, paths: [/a], upstream: 'http://b', rate_limit: {requests: 5}}]", "period must be positive"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			os.WriteFile(path, []byte(tt.yaml), 0o600)
+			t.Setenv("API_GATEWAY_AUTH_HMAC_SECRET", "")
+			if !strings.Contains(tt.yaml, "hmac_secret") {
+				t.Setenv("API_GATEWAY_AUTH_HMAC_SECRET", testSecret)
+			}
+			_, err := config.Load(path)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Load = %v, want an error containing %q", err, tt.want)
+			}
+		})
+	}
+}
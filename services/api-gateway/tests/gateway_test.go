@@ -0,0 +1,493 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.uber.org/zap"
+
+	"github.com/org/llm-marketplace/services/api-gateway/internal/auth"
+	"github.com/org/llm-marketplace/services/api-gateway/internal/config"
+	"github.com/org/llm-marketplace/services/api-gateway/internal/gateway"
+	"github.com/org/llm-marketplace/services/api-gateway/internal/limits"
+)
+
+// memCounter counts in memory; windows never expire
+type memCounter struct {
+	mu     sync.Mutex
+	counts map[string]int64
+	err    error
+}
+
+func (m *memCounter) Incr(_ context.Context, key string, _ time.Duration) (int64, error) {
+	if m.err != nil {
+		return 0, m.err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.counts[key]++
+	return m.counts[key], nil
+}
+
+// backend records the requests it gets and answers them with respond
+type backend struct {
+	*httptest.Server
+	respond http.HandlerFunc
+
+	mu       sync.Mutex
+	requests []*http.Request
+}
+
+func newBackend(t *testing.T) *backend {
+	t.Helper()
+	b := &backend{respond: func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"path": "` + r.URL.Path + `"}`))
+	}}
+	b.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b.mu.Lock()
+		b.requests = append(b.requests, r.Clone(context.Background()))
+		b.mu.Unlock()
+		b.respond(w, r)
+	}))
+	t.Cleanup(b.Close)
+	return b
+}
+
+func (b *backend) last(t *testing.T) *http.Request {
+	t.Helper()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.requests) == 0 {
+		t.Fatal("the backend got no request")
+	}
+	return b.requests[len(b.requests)-1]
+}
+
+func (b *backend) count() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.requests)
+}
+
+type testGateway struct {
+	router  *gin.Engine
+	counter *memCounter
+}
+
+// newTestGateway routes the default routes to discovery, registry and
+// policyEngine
+func newTestGateway(t *testing.T, discovery, registry, policyEngine string, change func(*config.Config)) *testGateway {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	cfg := &config.Config{
+		Auth: testAuthConfig(),
+		Identity: config.IdentityConfig{
+			UserHeader: "X-User-ID", TenantHeader: "X-Tenant-ID", ConsumerHeader: "X-Consumer-ID",
+			ProviderHeader: "X-Provider-ID", OperatorHeader: "X-Operator-ID", APIKeyHeader: "X-API-Key-ID",
+		},
+		Routes: config.DefaultRoutes(discovery, registry, policyEngine),
+	}
+	cfg.Server.MaxBodyBytes = 1 << 10
+	cfg.Upstream.Timeout = 5 * time.Second
+	if change != nil {
+		change(cfg)
+	}
+
+	verifier, err := auth.NewVerifier(cfg.Auth)
+	if err != nil {
+		t.Fatalf("NewVerifier: %v", err)
+	}
+	g := &testGateway{counter: &memCounter{counts: map[string]int64{}}}
+	gw, err := gateway.New(cfg, verifier, limits.NewLimiter(g.counter), zap.NewNop())
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	g.router = gin.New()
+	g.router.GET("/health", func(c *gin.Context) { c.Status(http.StatusOK) })
+	gw.RegisterRoutes(g.router)
+	return g
+}
+
+func (g *testGateway) do(method, path, token string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	g.router.ServeHTTP(w, req)
+	return w
+}
+
+func problemCode(t *testing.T, w *httptest.ResponseRecorder) string {
+	t.Helper()
+	var p struct {
+		Code string `json:"code"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &p)
+	return p.Code
+}
+
+func TestGatewayRoutesToBackends(t *testing.T) {
+	discovery, registry := newBackend(t), newBackend(t)
+	g := newTestGateway(t, discovery.URL, registry.URL+"/base", "http://127.0.0.1:1", nil)
+	token := sign(t, "alice", jwt.MapClaims{"provider_id": "p-1"})
+
+	w := g.do(http.MethodGet, "/discovery/api/v1/search?q=chat", "", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("search: %d %s", w.Code, w.Body)
+	}
+	if r := discovery.last(t); r.URL.Path != "/api/v1/search" || r.URL.RawQuery != "q=chat" {
+		t.Errorf("discovery got %s?%s, want the path without /discovery", r.URL.Path, r.URL.RawQuery)
+	}
+
+	if w := g.do(http.MethodPatch, "/registry/api/v1/services/s-1/status", token, nil); w.Code != http.StatusOK {
+		t.Fatalf("registry: %d %s", w.Code, w.Body)
+	}
+	if r := registry.last(t); r.URL.Path != "/base/api/v1/services/s-1/status" || r.Method != http.MethodPatch {
+		t.Errorf("registry got %s %s", r.Method, r.URL.Path)
+	}
+
+	// Paths match on segment boundaries
+	if w := g.do(http.MethodGet, "/discovery/api/v1x", "", nil); w.Code != http.StatusNotFound || problemCode(t, w) != "not-found" {
+		t.Errorf("unknown path: %d %s, want 404 not-found", w.Code, problemCode(t, w))
+	}
+	if w := g.do(http.MethodPost, "/discovery/api/v1/categories", token, nil); w.Code != http.StatusOK {
+		t.Errorf("POST on a read-only path fell through to %d, want the authenticated discovery route", w.Code)
+	}
+	// Requests that could resolve to another route are refused
+	if w := g.do(http.MethodGet, "/discovery/api/v1/search/../admin/embeddings/backfill/1", "", nil); w.Code != http.StatusBadRequest {
+		t.Errorf("dot segments: %d, want 400", w.Code)
+	}
+	// The gateway's own endpoints are still served
+	if w := g.do(http.MethodGet, "/health", "", nil); w.Code != http.StatusOK {
+		t.Errorf("/health: %d", w.Code)
+	}
+}
+
+func TestGatewayAuthenticates(t *testing.T) {
+	discovery, registry := newBackend(t), newBackend(t)
+	g := newTestGateway(t, discovery.URL, registry.URL, "http://127.0.0.1:1", nil)
+
+	w := g.do(http.MethodGet, "/registry/api/v1/services", "", nil)
+	if w.Code != http.StatusUnauthorized || problemCode(t, w) != "unauthenticated" {
+		t.Fatalf("no token: %d %s, want 401 unauthenticated", w.Code, problemCode(t, w))
+	}
+	if challenge := w.Header().Get("WWW-Authenticate"); !strings.HasPrefix(challenge, "Bearer") {
+		t.Errorf("WWW-Authenticate = %q", challenge)
+	}
+
+	expired := sign(t, "alice", jwt.MapClaims{"exp": time.Now().Add(-time.Hour).Unix()})
+	w = g.do(http.MethodGet, "/registry/api/v1/services", expired, nil)
+	if w.Code != http.StatusUnauthorized || !strings.Contains(w.Header().Get("WWW-Authenticate"), "invalid_token") {
+		t.Errorf("expired token: %d %q", w.Code, w.Header().Get("WWW-Authenticate"))
+	}
+	// A token sent to an optional route must still be valid
+	if w := g.do(http.MethodGet, "/discovery/api/v1/search", expired, nil); w.Code != http.StatusUnauthorized {
+		t.Errorf("expired token on an optional route: %d, want 401", w.Code)
+	}
+
+	// Consumers may read the registry but not change it
+	consumer := sign(t, "bob", nil)
+	if w := g.do(http.MethodGet, "/registry/api/v1/services", consumer, nil); w.Code != http.StatusOK {
+		t.Errorf("consumer reading the registry: %d", w.Code)
+	}
+	w = g.do(http.MethodDelete, "/registry/api/v1/services/s-1", consumer, nil)
+	if w.Code != http.StatusForbidden || problemCode(t, w) != "forbidden" {
+		t.Errorf("consumer deregistering: %d %s, want 403 forbidden", w.Code, problemCode(t, w))
+	}
+	if w := g.do(http.MethodPost, "/registry/api/v1/subscriptions", consumer, nil); w.Code != http.StatusOK {
+		t.Errorf("consumer subscribing: %d", w.Code)
+	}
+	// Only consumers report listings, matched through the wildcard segment
+	if w := g.do(http.MethodPost, "/registry/api/v1/services/s-1/report", consumer, nil); w.Code != http.StatusOK {
+		t.Errorf("consumer reporting: %d", w.Code)
+	}
+	if r := registry.last(t); r.URL.Path != "/api/v1/services/s-1/report" {
+		t.Errorf("registry got %s", r.URL.Path)
+	}
+	provider := sign(t, "carol", jwt.MapClaims{"provider_id": "p-1"})
+	if w := g.do(http.MethodPost, "/registry/api/v1/services/s-1/report", provider, nil); w.Code != http.StatusForbidden {
+		t.Errorf("provider reporting: %d, want 403", w.Code)
+	}
+	if w := g.do(http.MethodPost, "/registry/api/v1/services/s-1/reporter", consumer, nil); w.Code != http.StatusForbidden {
+		t.Errorf("consumer posting past the wildcard route: %d, want 403", w.Code)
+	}
+	operator := sign(t, "root", jwt.MapClaims{"roles": []string{"operator"}})
+	if w := g.do(http.MethodDelete, "/registry/api/v1/services/s-1", operator, nil); w.Code != http.StatusOK {
+		t.Errorf("operator deregistering: %d", w.Code)
+	}
+	if w := g.do(http.MethodPost, "/discovery/api/v1/taxonomy/categories", consumer, nil); w.Code != http.StatusForbidden {
+		t.Errorf("consumer editing the taxonomy: %d, want 403", w.Code)
+	}
+	if w := g.do(http.MethodGet, "/discovery/api/v1/taxonomy/categories", "", nil); w.Code != http.StatusOK {
+		t.Errorf("anonymous taxonomy read: %d", w.Code)
+	}
+	// Only providers report service health
+	if w := g.do(http.MethodPost, "/discovery/api/v1/services/s-1/health/reports", provider, nil); w.Code != http.StatusOK {
+		t.Errorf("provider reporting health: %d", w.Code)
+	}
+	if r := discovery.last(t); r.URL.Path != "/api/v1/services/s-1/health/reports" || r.Header.Get("X-Provider-ID") != "p-1" {
+		t.Errorf("discovery got %s from provider %q", r.URL.Path, r.Header.Get("X-Provider-ID"))
+	}
+	for name, token := range map[string]string{"consumer": consumer, "operator": operator} {
+		if w := g.do(http.MethodPost, "/discovery/api/v1/services/s-1/health/reports", token, nil); w.Code != http.StatusForbidden {
+			t.Errorf("%s reporting health: %d, want 403", name, w.Code)
+		}
+	}
+	if w := g.do(http.MethodPost, "/discovery/api/v1/services/s-1/health/reports", "", nil); w.Code != http.StatusUnauthorized {
+		t.Errorf("anonymous health report: %d, want 401", w.Code)
+	}
+}
+
+func TestGatewayPassesIdentity(t *testing.T) {
+	discovery, registry := newBackend(t), newBackend(t)
+	g := newTestGateway(t, discovery.URL, registry.URL, "http://127.0.0.1:1", nil)
+	forged := map[string]string{
+		"X-User-ID": "mallory", "X-Consumer-ID": "mallory", "X-Provider-ID": "p-victim", "X-Operator-ID": "root",
+	}
+
+	// Anonymous callers reach the backend with no identity at all
+	g.do(http.MethodGet, "/discovery/api/v1/search", "", forged)
+	r := discovery.last(t)
+	for _, name := range []string{"X-User-ID", "X-Tenant-ID", "X-Consumer-ID", "X-Provider-ID", "X-Operator-ID"} {
+		if r.Header.Get(name) != "" {
+			t.Errorf("anonymous request forwarded %s: %q", name, r.Header.Get(name))
+		}
+	}
+
+	token := sign(t, "alice", jwt.MapClaims{"tenants": []string{"acme", "globex"}, "consumer_id": "c-1"})
+	headers := map[string]string{"X-Tenant-ID": "globex", "X-Provider-ID": "p-victim"}
+	if w := g.do(http.MethodGet, "/discovery/api/v1/services/s-1", token, headers); w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body)
+	}
+	r = discovery.last(t)
+	want := map[string]string{
+		"X-User-ID": "alice", "X-Tenant-ID": "globex", "X-Consumer-ID": "c-1", "X-Provider-ID": "", "X-Operator-ID": "", "Authorization": "",
+	}
+	for name, value := range want {
+		if got := r.Header.Get(name); got != value {
+			t.Errorf("%s = %q, want %q", name, got, value)
+		}
+	}
+
+	// The tenant must be chosen among those granted
+	w := g.do(http.MethodGet, "/discovery/api/v1/services/s-1", token, nil)
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "X-Tenant-ID") {
+		t.Errorf("no tenant chosen: %d %s", w.Code, w.Body)
+	}
+	if w := g.do(http.MethodGet, "/discovery/api/v1/services/s-1", token, map[string]string{"X-Tenant-ID": "initech"}); w.Code != http.StatusForbidden {
+		t.Errorf("tenant not granted: %d, want 403", w.Code)
+	}
+
+	// Operators are passed as the backends expect: without caller headers
+	operator := sign(t, "root", jwt.MapClaims{"roles": "operator", "tenant_id": "acme"})
+	g.do(http.MethodGet, "/registry/api/v1/services", operator, forged)
+	r = registry.last(t)
+	if r.Header.Get("X-Operator-ID") != "root" || r.Header.Get("X-User-ID") != "" || r.Header.Get("X-Tenant-ID") != "" || r.Header.Get("X-Provider-ID") != "" {
+		t.Errorf("operator request headers = %v", r.Header)
+	}
+
+	// The provider portal checks its own API keys
+	g.do(http.MethodGet, "/registry/api/v1/portal/services", "", map[string]string{"Authorization": "Bearer mk_live_123", "X-Provider-ID": "p-victim"})
+	r = registry.last(t)
+	if r.Header.Get("Authorization") != "Bearer mk_live_123" || r.Header.Get("X-Provider-ID") != "" {
+		t.Errorf("portal request: Authorization %q, X-Provider-ID %q", r.Header.Get("Authorization"), r.Header.Get("X-Provider-ID"))
+	}
+}
+
+// Discovery counts quotas by the API key ID, so a client choosing its own
+// would start a fresh count with every request
+func TestGatewayReplacesAPIKeyID(t *testing.T) {
+	discovery := newBackend(t)
+	g := newTestGateway(t, discovery.URL, "http://127.0.0.1:1", "http://127.0.0.1:1", nil)
+
+	keyed := sign(t, "alice", jwt.MapClaims{"tenant_id": "acme", "api_key_id": "key-1"})
+	unkeyed := sign(t, "bob", jwt.MapClaims{"tenant_id": "acme"})
+	for i, tc := range []struct {
+		token, want string
+	}{
+		{keyed, "key-1"},
+		{unkeyed, ""},
+		{"", ""},
+	} {
+		for _, spoofed := range []string{"key-2", "key-3"} {
+			g.do(http.MethodGet, "/discovery/api/v1/search", tc.token, map[string]string{"X-API-Key-ID": spoofed})
+			if got := discovery.last(t).Header.Get("X-API-Key-ID"); got != tc.want {
+				t.Errorf("case %d: spoofed key %s forwarded as %q, want %q", i, spoofed, got, tc.want)
+			}
+		}
+	}
+}
+
+func TestGatewayRateLimits(t *testing.T) {
+	discovery := newBackend(t)
+	g := newTestGateway(t, discovery.URL, discovery.URL, "http://127.0.0.1:1", func(cfg *config.Config) {
+		for i := range cfg.Routes {
+			cfg.Routes[i].RateLimit = config.RateLimitConfig{Requests: 3, AnonymousRequests: 1, Period: time.Minute}
+		}
+	})
+	alice, bob := sign(t, "alice", nil), sign(t, "bob", nil)
+
+	for i := 0; i < 3; i++ {
+		w := g.do(http.MethodGet, "/discovery/api/v1/search", alice, nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("request %d: %d", i+1, w.Code)
+		}
+		if remaining := w.Header().Get("X-RateLimit-Remaining"); remaining != strconv.Itoa(2-i) {
+			t.Errorf("request %d: X-RateLimit-Remaining = %q", i+1, remaining)
+		}
+	}
+	w := g.do(http.MethodGet, "/discovery/api/v1/search", alice, nil)
+	if w.Code != http.StatusTooManyRequests || problemCode(t, w) != "rate-limited" {
+		t.Fatalf("fourth request: %d %s, want 429 rate-limited", w.Code, problemCode(t, w))
+	}
+	if retry := w.Header().Get("Retry-After"); retry == "" || retry == "0" || w.Header().Get("X-RateLimit-Limit") != "3" {
+		t.Errorf("Retry-After = %q, X-RateLimit-Limit = %q", retry, w.Header().Get("X-RateLimit-Limit"))
+	}
+
+	// Limits are per caller and per route; anonymous callers share their IP's
+	if w := g.do(http.MethodGet, "/discovery/api/v1/search", bob, nil); w.Code != http.StatusOK {
+		t.Errorf("another caller: %d", w.Code)
+	}
+	if w := g.do(http.MethodGet, "/discovery/api/v1/services/s-1", alice, nil); w.Code != http.StatusOK {
+		t.Errorf("another route: %d", w.Code)
+	}
+	g.do(http.MethodGet, "/discovery/api/v1/search", "", nil)
+	if w := g.do(http.MethodGet, "/discovery/api/v1/search", "", nil); w.Code != http.StatusTooManyRequests {
+		t.Errorf("second anonymous request: %d, want 429", w.Code)
+	}
+	if got := discovery.count(); got != 6 {
+		t.Errorf("the backend got %d requests, want 6", got)
+	}
+
+	// Limits can't be enforced without the counters, so nothing goes through
+	g.counter.err = errors.New("redis: connection refused")
+	if w := g.do(http.MethodGet, "/discovery/api/v1/search", bob, nil); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("counters down: %d, want 503", w.Code)
+	}
+}
+
+func TestGatewayPropagatesRequestIDAndTrace(t *testing.T) {
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSampler(sdktrace.AlwaysSample()))
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() { tp.Shutdown(context.Background()) })
+
+	discovery := newBackend(t)
+	g := newTestGateway(t, discovery.URL, discovery.URL, "http://127.0.0.1:1", nil)
+
+	const traceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	w := g.do(http.MethodGet, "/discovery/api/v1/search", "", map[string]string{"X-Request-ID": "req-123", "Traceparent": traceparent})
+	r := discovery.last(t)
+	if w.Header().Get("X-Request-ID") != "req-123" || r.Header.Get("X-Request-ID") != "req-123" {
+		t.Errorf("request ID echoed %q, forwarded %q, want the caller's", w.Header().Get("X-Request-ID"), r.Header.Get("X-Request-ID"))
+	}
+	forwarded := r.Header.Get("Traceparent")
+	if !strings.HasPrefix(forwarded, "00-4bf92f3577b34da6a3ce929d0e0e4736-") || forwarded == traceparent {
+		t.Errorf("traceparent = %q, want the caller's trace with the gateway's span as parent", forwarded)
+	}
+
+	// Unsafe IDs are replaced
+	w = g.do(http.MethodGet, "/discovery/api/v1/search", "", map[string]string{"X-Request-ID": "bad id\x01"})
+	if id := discovery.last(t).Header.Get("X-Request-ID"); id == "" || id == "bad id\x01" || id != w.Header().Get("X-Request-ID") {
+		t.Errorf("request ID = %q, want a generated one", id)
+	}
+}
+
+func TestGatewayReportsBackendFailures(t *testing.T) {
+	slow := newBackend(t)
+	slow.respond = func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+	g := newTestGateway(t, slow.URL, down.URL, "http://127.0.0.1:1", func(cfg *config.Config) {
+		cfg.Upstream.Timeout = 50 * time.Millisecond
+	})
+	token := sign(t, "alice", nil)
+
+	if w := g.do(http.MethodGet, "/discovery/api/v1/search", "", nil); w.Code != http.StatusGatewayTimeout || problemCode(t, w) != "upstream-timeout" {
+		t.Errorf("slow backend: %d %s, want 504 upstream-timeout", w.Code, problemCode(t, w))
+	}
+	if w := g.do(http.MethodGet, "/registry/api/v1/services", token, nil); w.Code != http.StatusBadGateway || problemCode(t, w) != "upstream-error" {
+		t.Errorf("backend down: %d %s, want 502 upstream-error", w.Code, problemCode(t, w))
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/registry/api/v1/subscriptions", strings.NewReader(strings.Repeat("x", 2<<10)))
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	g.router.ServeHTTP(w, req)
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("large body: %d, want 413", w.Code)
+	}
+}
+
+func TestGatewayProxiesGRPC(t *testing.T) {
+	policyEngine := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ProtoMajor != 2 || r.Header.Get("Te") != "trailers" {
+			http.Error(w, "want gRPC over HTTP/2", http.StatusHTTPVersionNotSupported)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/grpc")
+		w.Header().Set("Trailer", "Grpc-Status")
+		w.Write(body)
+		w.Header().Set("Grpc-Status", "0")
+	}))
+	policyEngine.Config.Protocols = new(http.Protocols)
+	policyEngine.Config.Protocols.SetUnencryptedHTTP2(true)
+	policyEngine.Start()
+	t.Cleanup(policyEngine.Close)
+
+	g := newTestGateway(t, "http://127.0.0.1:1", "http://127.0.0.1:1", policyEngine.URL, nil)
+	call := func(method, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/policyengine.v1.PolicyEngineService/"+method, strings.NewReader("\x00\x00\x00\x00\x02hi"))
+		req.Header.Set("Content-Type", "application/grpc")
+		req.Header.Set("Te", "trailers")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		g.router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := call("ValidateService", sign(t, "alice", nil))
+	if w.Code != http.StatusOK || w.Body.String() != "\x00\x00\x00\x00\x02hi" || w.Result().Trailer.Get("Grpc-Status") != "0" {
+		t.Fatalf("ValidateService: %d %q, trailers %v", w.Code, w.Body, w.Result().Trailer)
+	}
+
+	// Refusals are gRPC statuses, not problem details
+	w = call("ValidateService", "")
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "application/grpc" || w.Header().Get("Grpc-Status") != "16" {
+		t.Errorf("no token: %d %s, grpc-status %q, want UNAUTHENTICATED", w.Code, w.Header().Get("Content-Type"), w.Header().Get("Grpc-Status"))
+	}
+	w = call("CreatePolicy", sign(t, "alice", nil))
+	if w.Header().Get("Grpc-Status") != "7" || !strings.Contains(w.Header().Get("Grpc-Message"), "operator") {
+		t.Errorf("consumer creating a policy: grpc-status %q %q, want PERMISSION_DENIED", w.Header().Get("Grpc-Status"), w.Header().Get("Grpc-Message"))
+	}
+	if w := call("CreatePolicy", sign(t, "root", jwt.MapClaims{"roles": []string{"operator"}})); w.Code != http.StatusOK || w.Result().Trailer.Get("Grpc-Status") != "0" {
+		t.Errorf("operator creating a policy: %d, trailers %v", w.Code, w.Result().Trailer)
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/org/llm-marketplace/services/consumption-gateway/internal/catalog"
+	"github.com/org/llm-marketplace/services/consumption-gateway/internal/config"
+	"github.com/org/llm-marketplace/services/consumption-gateway/internal/limits"
+	"github.com/org/llm-marketplace/services/consumption-gateway/internal/policy"
+	"github.com/org/llm-marketplace/services/consumption-gateway/internal/proxy"
+	"github.com/org/llm-marketplace/services/consumption-gateway/internal/usage"
+)
+
+func main() {
+	// Load configuration; without a file, defaults and GATEWAY_* variables apply
+	configPath := config.Path()
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		panic(fmt.Sprintf("Failed to load configuration: %v", err))
+	}
+
+	logger, err := newLogger(cfg.Logging)
+	if err != nil {
+		panic(fmt.Sprintf("Failed to initialize logger: %v", err))
+	}
+	defer logger.Sync()
+
+	logger.Info("Starting LLM-Marketplace Consumption Gateway",
+		zap.String("version", "1.0.0"),
+		zap.String("environment", os.Getenv("ENVIRONMENT")),
+		zap.String("config", configPath),
+	)
+
+	ctx := context.Background()
+
+	policyConn, err := policy.Dial(cfg.PolicyEngine)
+	if err != nil {
+		logger.Fatal("Failed to create policy engine client", zap.Error(err))
+	}
+	defer policyConn.Close()
+	policyClient := policy.NewClient(policyConn, cfg.PolicyEngine.Timeout)
+
+	redisClient := redis.NewClient(&redis.Options{
+		Addr:     cfg.Redis.Address,
+		Password: cfg.Redis.Password,
+		DB:       cfg.Redis.DB,
+	})
+	defer redisClient.Close()
+
+	publisher := usage.NewPublisher(usage.NewWriter(cfg.Usage), cfg.Usage, logger)
+	gateway := proxy.NewGateway(
+		catalog.NewRegistry(cfg.Registry),
+		policyClient,
+		limits.NewLimiter(limits.NewRedisCounter(redisClient)),
+		publisher,
+		cfg,
+		logger,
+	)
+
+	if cfg.Server.Mode == "production" {
+		gin.SetMode(gin.ReleaseMode)
+	}
+	router := gin.New()
+	router.Use(gin.Recovery())
+
+	router.GET("/health", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{
+			"status":    "healthy",
+			"timestamp": time.Now().UTC(),
+		})
+	})
+	router.GET("/ready", proxy.Readiness(map[string]proxy.Check{
+		"redis":         func(ctx context.Context) error { return redisClient.Ping(ctx).Err() },
+		"policy_engine": policyClient.Check,
+	}, 2*time.Second))
+	router.GET("/metrics", gin.WrapH(promhttp.Handler()))
+	gateway.RegisterRoutes(router)
+
+	addr := fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Server.Port)
+	server := &http.Server{
+		Addr:         addr,
+		Handler:      router,
+		ReadTimeout:  cfg.Server.ReadTimeout,
+		WriteTimeout: cfg.Server.WriteTimeout,
+		IdleTimeout:  cfg.Server.IdleTimeout,
+	}
+	go func() {
+		logger.Info("Starting HTTP server", zap.String("address", addr))
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			logger.Fatal("Failed to start HTTP server", zap.Error(err))
+		}
+	}()
+
+	// Graceful shutdown
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+
+	logger.Info("Shutting down server...")
+
+	shutdownCtx, cancel := context.WithTimeout(ctx, cfg.Server.ShutdownTimeout)
+	defer cancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		logger.Error("Server forced to shutdown", zap.Error(err))
+	}
+
+	// Usage of the calls that just finished is still billed
+	if err := publisher.Close(shutdownCtx); err != nil {
+		logger.Error("Failed to flush usage events", zap.Error(err))
+	}
+
+	logger.Info("Server exited")
+}
+
+func newLogger(cfg config.LoggingConfig) (*zap.Logger, error) {
+	level, err := zapcore.ParseLevel(cfg.Level)
+	if err != nil {
+		return nil, err
+	}
+	zapConfig := zap.NewProductionConfig()
+	if cfg.Format == "console" {
+		zapConfig = zap.NewDevelopmentConfig()
+	}
+	zapConfig.Level = zap.NewAtomicLevelAt(level)
+	return zapConfig.Build()
+}
@@ -0,0 +1,142 @@
+// Package catalog looks up the services the gateway proxies to in the
+// registry. Lookups are cached for a short TTL so a busy service costs the
+// registry one request per TTL rather than one per call.
+package catalog
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/org/llm-marketplace/pkg/marketplace"
+
+	"github.com/org/llm-marketplace/services/consumption-gateway/internal/config"
+)
+
+var (
+	// ErrNotFound is returned for a service the registry doesn't know
+	ErrNotFound = errors.New("service not found")
+	// ErrUnavailable is returned when the registry can't be reached
+	ErrUnavailable = errors.New("registry unavailable")
+)
+
+// Service is what the gateway needs to know about a registered service
+type Service struct {
+	ID         string
+	ProviderID string
+	Status     string
+	Endpoint   *marketplace.EndpointInfo
+	Pricing    *marketplace.PricingInfo
+	// TenantID is set on a private service, which only the tenant may call
+	TenantID string
+}
+
+// Consumable reports whether calls to the service may go through. Deprecated
+// services keep working until they are retired.
+func (s *Service) Consumable() bool {
+	return s.Status == marketplace.StatusActive || s.Status == marketplace.StatusDeprecated
+}
+
+// VisibleTo reports whether consumer may know of the service
+func (s *Service) VisibleTo(consumer string) bool {
+	return s.TenantID == "" || s.TenantID == consumer
+}
+
+// registration is the part of the registry's registration response read here
+type registration struct {
+	ID         string                        `json:"id"`
+	ProviderID string                        `json:"provider_id"`
+	Status     string                        `json:"status"`
+	Service    marketplace.ServiceDescriptor `json:"service"`
+}
+
+type entry struct {
+	service *Service
+	err     error // ErrNotFound is cached too
+	expires time.Time
+}
+
+// Registry looks up services with the registry REST API
+type Registry struct {
+	baseURL string
+	client  *http.Client
+	ttl     time.Duration
+
+	mu    sync.Mutex
+	cache map[string]entry
+}
+
+// NewRegistry creates a registry client for cfg
+func NewRegistry(cfg config.RegistryConfig) *Registry {
+	return &Registry{
+		baseURL: strings.TrimSuffix(cfg.URL, "/"),
+		client:  &http.Client{Timeout: cfg.Timeout},
+		ttl:     cfg.CacheTTL,
+		cache:   make(map[string]entry),
+	}
+}
+
+// Lookup returns the service with id, from the cache while it is fresh
+func (r *Registry) Lookup(ctx context.Context, id string) (*Service, error) {
+	now := time.Now()
+	r.mu.Lock()
+	e, ok := r.cache[id]
+	r.mu.Unlock()
+	if ok && now.Before(e.expires) {
+		return e.service, e.err
+	}
+
+	service, err := r.fetch(ctx, id)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return nil, err
+	}
+	if r.ttl > 0 {
+		r.mu.Lock()
+		r.cache[id] = entry{service: service, err: err, expires: now.Add(r.ttl)}
+		r.mu.Unlock()
+	}
+	return service, err
+}
+
+func (r *Registry) fetch(ctx context.Context, id string) (*Service, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.baseURL+"/api/v1/services/"+url.PathEscape(id), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, ErrNotFound
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("%w: lookup of %s returned %s", ErrUnavailable, id, resp.Status)
+	}
+
+	var reg registration
+	if err := json.NewDecoder(resp.Body).Decode(&reg); err != nil {
+		return nil, fmt.Errorf("%w: failed to decode service %s: %v", ErrUnavailable, id, err)
+	}
+	service := &Service{
+		ID:         reg.ID,
+		ProviderID: reg.ProviderID,
+		Status:     reg.Status,
+		Endpoint:   reg.Service.Endpoint,
+		Pricing:    reg.Service.Pricing,
+	}
+	if reg.Service.Private() {
+		service.TenantID = reg.Service.TenantID
+	}
+	return service, nil
+}
@@ -0,0 +1,151 @@
+// Package config loads the consumption gateway configuration from built-in defaults, an
+// optional YAML file and GATEWAY_* environment variables, in that order.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/org/llm-marketplace/pkg/marketplace/envconfig"
+	"gopkg.in/yaml.v3"
+)
+
+type Config struct {
+	Server       ServerConfig       `yaml:"server"`
+	Registry     RegistryConfig     `yaml:"registry"`
+	PolicyEngine PolicyEngineConfig `yaml:"policy_engine"`
+	Redis        RedisConfig        `yaml:"redis"`
+	Upstream     UpstreamConfig     `yaml:"upstream"`
+	Usage        UsageConfig        `yaml:"usage"`
+	Logging      LoggingConfig      `yaml:"logging"`
+}
+
+type ServerConfig struct {
+	Host         string        `yaml:"host"`
+	Port         int           `yaml:"port"`
+	Mode         string        `yaml:"mode"` // development, production
+	ReadTimeout  time.Duration `yaml:"read_timeout"`
+	WriteTimeout time.Duration `yaml:"write_timeout"` // Must cover the slowest upstream call, streaming included
+	IdleTimeout  time.Duration `yaml:"idle_timeout"`
+
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+
+	// ConsumerHeader carries the authenticated consumer, set by the API
+	// gateway in front of this one. Requests without it are rejected.
+	ConsumerHeader string `yaml:"consumer_header"`
+	MaxBodyBytes   int64  `yaml:"max_body_bytes"` // Largest request body accepted
+}
+
+// RegistryConfig is where services are looked up
+type RegistryConfig struct {
+	URL      string        `yaml:"url"`
+	Timeout  time.Duration `yaml:"timeout"`
+	CacheTTL time.Duration `yaml:"cache_ttl"` // How long a looked-up service is reused
+}
+
+// PolicyEngineConfig is the policy engine every request is checked with
+type PolicyEngineConfig struct {
+	GRPCEndpoint string        `yaml:"grpc_endpoint"`
+	Timeout      time.Duration `yaml:"timeout"`
+}
+
+// RedisConfig holds the request counters behind the per-minute and per-day
+// limits, shared by every gateway replica
+type RedisConfig struct {
+	Address  string `yaml:"address"`
+	Password string `yaml:"password"`
+	DB       int    `yaml:"db"`
+}
+
+// UpstreamConfig controls calls to the services
+type UpstreamConfig struct {
+	Timeout             time.Duration `yaml:"timeout"` // Per call, including streaming the response
+	MaxIdleConnsPerHost int           `yaml:"max_idle_conns_per_host"`
+}
+
+// UsageConfig controls the usage events published to Kafka
+type UsageConfig struct {
+	Brokers       []string      `yaml:"brokers"`
+	Topic         string        `yaml:"topic"`
+	BatchSize     int           `yaml:"batch_size"`
+	FlushInterval time.Duration `yaml:"flush_interval"`
+	BufferSize    int           `yaml:"buffer_size"` // Events held while Kafka is slow; more are dropped and counted
+}
+
+type LoggingConfig struct {
+	Level  string `yaml:"level"`  // debug, info, warn, error
+	Format string `yaml:"format"` // json, console
+}
+
+// DefaultPath is the config file used when CONFIG_PATH is not set
+const DefaultPath = "config.yaml"
+
+// Path returns the config file to load: CONFIG_PATH if set, otherwise
+// config.yaml when it exists in the working directory. It is empty when the
+// service is configured by defaults and environment variables alone.
+func Path() string {
+	if path := os.Getenv("CONFIG_PATH"); path != "" {
+		return path
+	}
+	if _, err := os.Stat(DefaultPath); err == nil {
+		return DefaultPath
+	}
+	return ""
+}
+
+// Load builds the configuration from the built-in defaults, overridden by the
+// file at path, if any, then by GATEWAY_* environment variables
+func Load(path string) (*Config, error) {
+	var cfg Config
+	cfg.setDefaults()
+
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read config file: %w", err)
+		}
+		if err := yaml.Unmarshal([]byte(os.ExpandEnv(string(data))), &cfg); err != nil {
+			return nil, fmt.Errorf("failed to parse config: %w", err)
+		}
+	}
+
+	if err := envconfig.Apply(&cfg, "GATEWAY_", os.Getenv); err != nil {
+		return nil, fmt.Errorf("invalid environment override: %w", err)
+	}
+
+	if err := validate(&cfg); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+	return &cfg, nil
+}
+
+func validate(cfg *Config) error {
+	var errs []error
+	if cfg.Server.Port <= 0 || cfg.Server.Port > 65535 {
+		errs = append(errs, fmt.Errorf("server.port %d is not a valid port", cfg.Server.Port))
+	}
+	if cfg.Server.ConsumerHeader == "" {
+		errs = append(errs, errors.New("server.consumer_header is required"))
+	}
+	if cfg.Server.MaxBodyBytes <= 0 {
+		errs = append(errs, errors.New("server.max_body_bytes must be positive"))
+	}
+	if cfg.Registry.URL == "" {
+		errs = append(errs, errors.New("registry.url is required"))
+	}
+	if cfg.PolicyEngine.GRPCEndpoint == "" {
+		errs = append(errs, errors.New("policy_engine.grpc_endpoint is required; every request is checked by the policy engine"))
+	}
+	if cfg.Redis.Address == "" {
+		errs = append(errs, errors.New("redis.address is required"))
+	}
+	if len(cfg.Usage.Brokers) == 0 || cfg.Usage.Topic == "" {
+		errs = append(errs, errors.New("usage.brokers and usage.topic are required"))
+	}
+	if cfg.Usage.BatchSize <= 0 || cfg.Usage.BufferSize < cfg.Usage.BatchSize {
+		errs = append(errs, errors.New("usage.batch_size must be positive and no larger than usage.buffer_size"))
+	}
+	return errors.Join(errs...)
+}
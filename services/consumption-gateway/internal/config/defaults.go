@@ -0,0 +1,43 @@
+package config
+
+import (
+	"time"
+
+	"github.com/org/llm-marketplace/pkg/marketplace"
+)
+
+// setDefaults fills in the settings used when neither the config file nor the
+// environment sets them. They match config.yaml, with dependencies expected
+// on localhost.
+func (c *Config) setDefaults() {
+	c.Server.Host = "0.0.0.0"
+	c.Server.Port = 3020
+	c.Server.Mode = "development"
+	c.Server.ReadTimeout = 30 * time.Second
+	c.Server.WriteTimeout = 5 * time.Minute
+	c.Server.IdleTimeout = 120 * time.Second
+	c.Server.ShutdownTimeout = 30 * time.Second
+	c.Server.ConsumerHeader = "X-Consumer-ID"
+	c.Server.MaxBodyBytes = 10 << 20
+
+	c.Registry.URL = "http://localhost:3010"
+	c.Registry.Timeout = 2 * time.Second
+	c.Registry.CacheTTL = 30 * time.Second
+
+	c.PolicyEngine.GRPCEndpoint = "localhost:50051"
+	c.PolicyEngine.Timeout = 2 * time.Second
+
+	c.Redis.Address = "localhost:6379"
+
+	c.Upstream.Timeout = 4 * time.Minute
+	c.Upstream.MaxIdleConnsPerHost = 32
+
+	c.Usage.Brokers = []string{"localhost:9092"}
+	c.Usage.Topic = marketplace.UsageTopic
+	c.Usage.BatchSize = 100
+	c.Usage.FlushInterval = time.Second
+	c.Usage.BufferSize = 10000
+
+	c.Logging.Level = "info"
+	c.Logging.Format = "json"
+}
@@ -0,0 +1,91 @@
+// Package limits enforces the per-minute and per-day request limits the
+// policy engine sets for a consumer. Requests are counted in fixed windows
+// kept in Redis, so the limits hold across gateway replicas.
+package limits
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Counter counts events in expiring windows
+type Counter interface {
+	// Incr adds one to the count at key and returns the new count. A new
+	// count expires after ttl.
+	Incr(ctx context.Context, key string, ttl time.Duration) (int64, error)
+}
+
+// RedisCounter is a Counter kept in Redis
+type RedisCounter struct {
+	client redis.UniversalClient
+}
+
+// NewRedisCounter creates a counter on client
+func NewRedisCounter(client redis.UniversalClient) *RedisCounter {
+	return &RedisCounter{client: client}
+}
+
+// Incr increments key and sets its expiry in one round trip
+func (c *RedisCounter) Incr(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	var incr *redis.IntCmd
+	_, err := c.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		incr = pipe.Incr(ctx, key)
+		pipe.Expire(ctx, key, ttl)
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to count request: %w", err)
+	}
+	return incr.Val(), nil
+}
+
+// Window is a limit on requests in a period of time
+type Window struct {
+	Name   string // minute or day, used in keys and messages
+	Period time.Duration
+	Max    int64 // 0 means unlimited
+}
+
+// Result is the outcome of a check
+type Result struct {
+	Allowed    bool
+	Window     Window        // The window that was exceeded
+	RetryAfter time.Duration // Until that window resets
+}
+
+// Limiter checks requests against windows
+type Limiter struct {
+	counter Counter
+	now     func() time.Time
+}
+
+// NewLimiter creates a limiter counting with counter
+func NewLimiter(counter Counter) *Limiter {
+	return &Limiter{counter: counter, now: time.Now}
+}
+
+// Allow counts a request by consumerID to serviceID in each window with a
+// limit, and reports the first window whose limit the request exceeds.
+// Windows are aligned to the clock: the minute window resets on the minute
+// and the day window at midnight UTC.
+func (l *Limiter) Allow(ctx context.Context, consumerID, serviceID string, windows ...Window) (Result, error) {
+	now := l.now().UTC()
+	for _, w := range windows {
+		if w.Max <= 0 {
+			continue
+		}
+		start := now.Truncate(w.Period)
+		key := fmt.Sprintf("gateway:requests:%s:%s:%s:%d", consumerID, serviceID, w.Name, start.Unix())
+		count, err := l.counter.Incr(ctx, key, w.Period)
+		if err != nil {
+			return Result{}, err
+		}
+		if count > w.Max {
+			return Result{Window: w, RetryAfter: start.Add(w.Period).Sub(now)}, nil
+		}
+	}
+	return Result{Allowed: true}, nil
+}
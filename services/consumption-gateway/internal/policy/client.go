@@ -0,0 +1,127 @@
+// Package policy asks the policy engine over gRPC whether consumers may call
+// services
+package policy
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
+	pb "github.com/org/llm-marketplace/services/consumption-gateway/api/proto/policyengine/v1"
+	"github.com/org/llm-marketplace/services/consumption-gateway/internal/config"
+	"github.com/org/llm-marketplace/services/consumption-gateway/internal/proxy"
+)
+
+// consumeAction is the CheckAccess action for calling a service
+const consumeAction = "consume"
+
+// Dial connects to the policy engine. The connection is made lazily, so this
+// succeeds while the policy engine is down.
+func Dial(cfg config.PolicyEngineConfig) (*grpc.ClientConn, error) {
+	conn, err := grpc.NewClient(cfg.GRPCEndpoint, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to policy engine: %w", err)
+	}
+	return conn, nil
+}
+
+// Client implements proxy.Policy
+type Client struct {
+	client  pb.PolicyEngineServiceClient
+	timeout time.Duration
+}
+
+// NewClient creates a client on conn; each call is limited to timeout
+func NewClient(conn grpc.ClientConnInterface, timeout time.Duration) *Client {
+	return &Client{client: pb.NewPolicyEngineServiceClient(conn), timeout: timeout}
+}
+
+// CheckAccess asks whether consumerID may consume serviceID. Any failure to
+// get an answer is reported as proxy.ErrPolicyUnavailable, so no call goes
+// through unchecked.
+func (c *Client) CheckAccess(ctx context.Context, consumerID, serviceID string, attrs map[string]string) (*proxy.AccessDecision, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	resp, err := c.client.CheckAccess(ctx, &pb.CheckAccessRequest{
+		UserId:    consumerID,
+		ServiceId: serviceID,
+		Action:    consumeAction,
+		Context:   attrs,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", proxy.ErrPolicyUnavailable, err)
+	}
+	return &proxy.AccessDecision{
+		Allowed:            resp.GetAllowed(),
+		Reason:             resp.GetReason(),
+		MissingPermissions: resp.GetMissingPermissions(),
+	}, nil
+}
+
+// ValidateConsumption asks whether the call in req may go through and
+// returns the limits it is held to
+func (c *Client) ValidateConsumption(ctx context.Context, req *proxy.ConsumptionRequest) (*proxy.ConsumptionDecision, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	resp, err := c.client.ValidateConsumption(ctx, &pb.ValidateConsumptionRequest{
+		ConsumerId:     req.ConsumerID,
+		ServiceId:      req.ServiceID,
+		RequestPayload: string(req.Payload),
+		Headers:        req.Headers,
+		Context: &pb.ConsumptionContext{
+			IpAddress: req.IPAddress,
+			UserAgent: req.UserAgent,
+			Metadata:  req.Metadata,
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", proxy.ErrPolicyUnavailable, err)
+	}
+
+	decision := &proxy.ConsumptionDecision{Allowed: resp.GetAllowed(), Reason: resp.GetReason()}
+	for _, v := range resp.GetViolations() {
+		decision.Violations = append(decision.Violations, proxy.Violation{
+			PolicyID:    v.GetPolicyId(),
+			PolicyName:  v.GetPolicyName(),
+			Severity:    v.GetSeverity(),
+			Message:     v.GetMessage(),
+			Remediation: v.GetRemediation(),
+		})
+	}
+	if l := resp.GetLimits(); l != nil {
+		decision.Limits = proxy.Limits{
+			MaxTokens:            l.GetMaxTokens(),
+			MaxRequestsPerMinute: l.GetMaxRequestsPerMinute(),
+			MaxRequestsPerDay:    l.GetMaxRequestsPerDay(),
+			MaxCostPerRequest:    l.GetMaxCostPerRequest(),
+		}
+	}
+	return decision, nil
+}
+
+// Check reports whether the policy engine is serving, for readiness
+func (c *Client) Check(ctx context.Context) error {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	resp, err := c.client.HealthCheck(ctx, &pb.HealthCheckRequest{Service: "consumption-gateway"})
+	if err != nil {
+		return err
+	}
+	if resp.GetStatus() != pb.HealthCheckResponse_SERVING {
+		return fmt.Errorf("policy engine is %s", resp.GetStatus())
+	}
+	return nil
+}
+
+func (c *Client) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if c.timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, c.timeout)
+}
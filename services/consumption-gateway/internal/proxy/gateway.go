@@ -0,0 +1,394 @@
+// Package proxy relays consumers' calls to the services listed in the
+// marketplace. Every call is checked with the policy engine, held to the
+// limits it returns, and recorded as a usage event once the service has
+// answered.
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/org/llm-marketplace/pkg/marketplace"
+	"github.com/org/llm-marketplace/pkg/marketplace/problem"
+	"go.uber.org/zap"
+
+	"github.com/org/llm-marketplace/services/consumption-gateway/internal/catalog"
+	"github.com/org/llm-marketplace/services/consumption-gateway/internal/config"
+	"github.com/org/llm-marketplace/services/consumption-gateway/internal/limits"
+	"github.com/org/llm-marketplace/services/consumption-gateway/internal/tokens"
+)
+
+// meterLimit is the largest response that is buffered to count its tokens.
+// Longer responses are still relayed but their tokens are estimated.
+const meterLimit = 1 << 20
+
+// requestIDHeader is passed to the service and echoed to the consumer
+const requestIDHeader = "X-Request-ID"
+
+// hopHeaders are not forwarded in either direction (RFC 9110 section 7.6.1)
+var hopHeaders = []string{
+	"Connection", "Keep-Alive", "Proxy-Authenticate", "Proxy-Authorization",
+	"Te", "Trailer", "Transfer-Encoding", "Upgrade",
+}
+
+// Catalog looks up services
+type Catalog interface {
+	Lookup(ctx context.Context, id string) (*catalog.Service, error)
+}
+
+// UsageSink receives a usage event for every call a service answered
+type UsageSink interface {
+	Publish(event *marketplace.UsageEvent)
+}
+
+// Gateway is the consumption gateway's HTTP handler
+type Gateway struct {
+	services Catalog
+	policy   Policy
+	limiter  *limits.Limiter
+	usage    UsageSink
+	client   *http.Client
+	logger   *zap.Logger
+
+	consumerHeader string
+	maxBodyBytes   int64
+}
+
+// NewGateway creates a gateway calling services with cfg.Upstream settings
+func NewGateway(services Catalog, policy Policy, limiter *limits.Limiter, usage UsageSink, cfg *config.Config, logger *zap.Logger) *Gateway {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConnsPerHost = cfg.Upstream.MaxIdleConnsPerHost
+	return &Gateway{
+		services: services,
+		policy:   policy,
+		limiter:  limiter,
+		usage:    usage,
+		client: &http.Client{
+			Transport: transport,
+			Timeout:   cfg.Upstream.Timeout,
+			// Redirects are the consumer's to follow
+			CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+		},
+		logger:         logger,
+		consumerHeader: cfg.Server.ConsumerHeader,
+		maxBodyBytes:   cfg.Server.MaxBodyBytes,
+	}
+}
+
+// RegisterRoutes adds the proxy route. A call to
+// /v1/services/:id/<path> goes to <path> under the service's endpoint URL.
+func (g *Gateway) RegisterRoutes(router gin.IRouter) {
+	router.Any("/v1/services/:id/*path", g.proxy)
+}
+
+// call is a consumer's call as it goes through the gateway
+type call struct {
+	started    time.Time
+	requestID  string
+	consumerID string
+	service    *catalog.Service
+	path       string
+	body       []byte
+	stream     bool
+	prompt     int64 // Estimated prompt tokens
+}
+
+func (g *Gateway) proxy(c *gin.Context) {
+	call := &call{started: time.Now(), requestID: c.GetHeader(requestIDHeader), path: c.Param("path")}
+	if call.requestID == "" {
+		call.requestID = uuid.NewString()
+	}
+	c.Header(requestIDHeader, call.requestID)
+
+	outcome := g.admit(c, call)
+	if outcome != "" {
+		// Unknown IDs are not labels, or anyone could add metric series
+		serviceID := "unknown"
+		if call.service != nil {
+			serviceID = call.service.ID
+		}
+		observeCall(serviceID, outcome, c.Writer.Status(), call.started)
+		return
+	}
+
+	resp, upstreamStarted, err := g.forward(c.Request, call)
+	if err != nil {
+		outcome = "upstream_error"
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			outcome = "upstream_timeout"
+			abort(c, upstreamTimeout, fmt.Sprintf("Service %s did not answer in time", call.service.ID))
+		} else {
+			abort(c, upstreamError, fmt.Sprintf("Service %s could not be reached", call.service.ID))
+		}
+		g.logger.Warn("Service call failed", zap.String("service_id", call.service.ID), zap.String("request_id", call.requestID), zap.Error(err))
+		observeCall(call.service.ID, outcome, c.Writer.Status(), call.started)
+		return
+	}
+	defer resp.Body.Close()
+
+	meter := tokens.NewMeter(resp.Header.Get("Content-Type"), meterLimit)
+	if err := relay(c, resp, meter); err != nil {
+		// The status is sent; all that's left is to record what was relayed
+		g.logger.Warn("Response relay interrupted", zap.String("service_id", call.service.ID), zap.String("request_id", call.requestID), zap.Error(err))
+	}
+	upstreamLatency := time.Since(upstreamStarted)
+
+	usage, estimated := meter.Usage(call.prompt)
+	g.usage.Publish(&marketplace.UsageEvent{
+		ID:               uuid.NewString(),
+		RequestID:        call.requestID,
+		OccurredAt:       call.started.UTC(),
+		ConsumerID:       call.consumerID,
+		ServiceID:        call.service.ID,
+		ProviderID:       call.service.ProviderID,
+		Method:           c.Request.Method,
+		Path:             call.path,
+		StatusCode:       resp.StatusCode,
+		PromptTokens:     usage.Prompt,
+		CompletionTokens: usage.Completion,
+		TotalTokens:      usage.Total(),
+		TokensEstimated:  estimated,
+		LatencyMS:        float64(time.Since(call.started).Microseconds()) / 1000,
+		UpstreamMS:       float64(upstreamLatency.Microseconds()) / 1000,
+		Pricing:          call.service.Pricing,
+	})
+	observeTokens(call.service.ID, usage, estimated)
+	observeCall(call.service.ID, "proxied", resp.StatusCode, call.started)
+}
+
+// admit runs the checks a call must pass before it is forwarded. It returns
+// the outcome label of a refused call, having written the response, or ""
+// for a call to forward.
+func (g *Gateway) admit(c *gin.Context, call *call) string {
+	ctx := c.Request.Context()
+
+	call.consumerID = c.GetHeader(g.consumerHeader)
+	if call.consumerID == "" {
+		c.Header("WWW-Authenticate", "Bearer")
+		abort(c, unauthenticated, fmt.Sprintf("The %s header is required", g.consumerHeader))
+		return "unauthenticated"
+	}
+
+	service, err := g.services.Lookup(ctx, c.Param("id"))
+	switch {
+	case errors.Is(err, catalog.ErrNotFound) || (err == nil && !service.VisibleTo(call.consumerID)):
+		// Other tenants don't know a private service exists
+		abort(c, problem.NotFound, catalog.ErrNotFound.Error())
+		return "not_found"
+	case err != nil:
+		g.logger.Warn("Registry unavailable", zap.Error(err))
+		abort(c, problem.ServiceUnavailable, "The registry is unavailable; try again later")
+		return "registry_unavailable"
+	case !service.Consumable():
+		abort(c, problem.NotFound, fmt.Sprintf("service %s is %s", service.ID, service.Status))
+		return "not_found"
+	case service.Endpoint == nil || service.Endpoint.URL == "":
+		abort(c, upstreamError, fmt.Sprintf("Service %s has no endpoint", service.ID))
+		return "upstream_error"
+	}
+	call.service = service
+
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, g.maxBodyBytes))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			abort(c, problem.PayloadTooLarge, fmt.Sprintf("Request bodies are limited to %d bytes", tooLarge.Limit))
+			return "payload_too_large"
+		}
+		abort(c, problem.InvalidRequest, "Failed to read the request body")
+		return "invalid_request"
+	}
+	call.body = body
+
+	access, err := g.policy.CheckAccess(ctx, call.consumerID, service.ID, map[string]string{
+		"method":     c.Request.Method,
+		"path":       call.path,
+		"ip_address": c.ClientIP(),
+	})
+	if err != nil {
+		return g.policyUnavailable(c, err)
+	}
+	if !access.Allowed {
+		abort(c, accessDenied, access.Reason)
+		return "access_denied"
+	}
+
+	decision, err := g.policy.ValidateConsumption(ctx, &ConsumptionRequest{
+		ConsumerID: call.consumerID,
+		ServiceID:  service.ID,
+		Payload:    body,
+		Headers:    policyHeaders(c.Request.Header, g.consumerHeader),
+		IPAddress:  c.ClientIP(),
+		UserAgent:  c.Request.UserAgent(),
+		Metadata:   map[string]string{"method": c.Request.Method, "path": call.path, "request_id": call.requestID},
+	})
+	if err != nil {
+		return g.policyUnavailable(c, err)
+	}
+	if !decision.Allowed {
+		p := newProblem(c, consumptionDenied, decision.Reason)
+		p.Violations = decision.Violations
+		writeProblem(c, p)
+		return "consumption_denied"
+	}
+
+	result, err := g.limiter.Allow(ctx, call.consumerID, service.ID,
+		limits.Window{Name: "minute", Period: time.Minute, Max: decision.Limits.MaxRequestsPerMinute},
+		limits.Window{Name: "day", Period: 24 * time.Hour, Max: decision.Limits.MaxRequestsPerDay},
+	)
+	if err != nil {
+		// Limits can't be enforced without the counters, so nothing goes through
+		g.logger.Error("Request counters unavailable", zap.Error(err))
+		abort(c, problem.ServiceUnavailable, "Request limits can't be checked; try again later")
+		return "limiter_unavailable"
+	}
+	if !result.Allowed {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(result.RetryAfter.Seconds()))))
+		abort(c, problem.RateLimited, fmt.Sprintf("At most %d requests per %s are allowed to service %s", result.Window.Max, result.Window.Name, service.ID))
+		return "rate_limited"
+	}
+
+	if detail := applyTokenLimits(call, decision.Limits); detail != "" {
+		abort(c, limitExceeded, detail)
+		return "limit_exceeded"
+	}
+	return ""
+}
+
+func (g *Gateway) policyUnavailable(c *gin.Context, err error) string {
+	g.logger.Warn("Policy engine unavailable", zap.Error(err))
+	abort(c, problem.ServiceUnavailable, "The policy engine is unavailable; try again later")
+	return "policy_unavailable"
+}
+
+// applyTokenLimits holds the call to the token and cost limits, lowering the
+// completion limit of the request so prompt and completion together stay
+// within them. It returns why the call can't be made within the limits, or
+// "" once the call fits.
+func applyTokenLimits(call *call, l Limits) string {
+	maxTokens := l.MaxTokens
+	if l.MaxCostPerRequest > 0 && call.service.Pricing != nil {
+		pricing := call.service.Pricing
+		if price, ok := pricing.TokenPrice(); ok && price > 0 {
+			if byCost := int64(l.MaxCostPerRequest / price); maxTokens <= 0 || byCost < maxTokens {
+				maxTokens = byCost
+			}
+		} else if strings.EqualFold(pricing.Unit, "request") && pricing.Rate > l.MaxCostPerRequest {
+			return fmt.Sprintf("A call to service %s costs %g %s, more than the %g allowed per request",
+				call.service.ID, pricing.Rate, pricing.Currency, l.MaxCostPerRequest)
+		}
+	}
+
+	req, ok := tokens.ParseRequest(call.body)
+	if ok {
+		call.stream = req.Stream()
+		call.prompt = req.PromptTokens()
+	} else {
+		call.prompt = tokens.Estimate(string(call.body))
+	}
+	if maxTokens <= 0 {
+		return ""
+	}
+
+	if call.prompt >= maxTokens {
+		return fmt.Sprintf("The prompt is about %d tokens; calls are limited to %d tokens", call.prompt, maxTokens)
+	}
+	if ok && req.CapMaxTokens(maxTokens-call.prompt) {
+		call.body = req.Body()
+	}
+	return ""
+}
+
+// forward sends the call to the service. It returns when the response
+// headers arrive, with the time the request was sent.
+func (g *Gateway) forward(in *http.Request, call *call) (*http.Response, time.Time, error) {
+	target, err := url.Parse(strings.TrimSuffix(call.service.Endpoint.URL, "/") + call.path)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("invalid endpoint URL: %w", err)
+	}
+	target.RawQuery = in.URL.RawQuery
+
+	out, err := http.NewRequestWithContext(in.Context(), in.Method, target.String(), bytes.NewReader(call.body))
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	out.Header = in.Header.Clone()
+	removeHopHeaders(out.Header)
+	out.Header.Del(g.consumerHeader)
+	out.Header.Set(requestIDHeader, call.requestID)
+	// The transport negotiates compression itself and hands back the body
+	// decompressed, so the response can be metered
+	out.Header.Del("Accept-Encoding")
+
+	started := time.Now()
+	resp, err := g.client.Do(out)
+	return resp, started, err
+}
+
+// relay copies the response to the consumer through meter, flushing each
+// read of a stream as it arrives
+func relay(c *gin.Context, resp *http.Response, meter *tokens.Meter) error {
+	header := c.Writer.Header()
+	for key, values := range resp.Header {
+		header[key] = values
+	}
+	removeHopHeaders(header)
+	c.Status(resp.StatusCode)
+	c.Writer.WriteHeaderNow()
+
+	buf := make([]byte, 32<<10)
+	for {
+		n, err := resp.Body.Read(buf)
+		if n > 0 {
+			meter.Write(buf[:n])
+			if _, werr := c.Writer.Write(buf[:n]); werr != nil {
+				return werr
+			}
+			c.Writer.Flush()
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func removeHopHeaders(h http.Header) {
+	for _, name := range h.Values("Connection") {
+		for _, field := range strings.Split(name, ",") {
+			h.Del(strings.TrimSpace(field))
+		}
+	}
+	for _, name := range hopHeaders {
+		h.Del(name)
+	}
+}
+
+// policyHeaders returns the request headers sent to the policy engine.
+// Credentials stay out of it.
+func policyHeaders(h http.Header, consumerHeader string) map[string]string {
+	headers := make(map[string]string, len(h))
+	for key := range h {
+		switch http.CanonicalHeaderKey(key) {
+		case "Authorization", "Cookie", "Proxy-Authorization", http.CanonicalHeaderKey(consumerHeader):
+			continue
+		}
+		headers[strings.ToLower(key)] = h.Get(key)
+	}
+	return headers
+}
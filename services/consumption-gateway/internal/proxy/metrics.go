@@ -0,0 +1,38 @@
+package proxy
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/org/llm-marketplace/services/consumption-gateway/internal/tokens"
+)
+
+var (
+	callsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "gateway_calls_total",
+		Help: "Calls by service, outcome (proxied or why they were refused) and status code",
+	}, []string{"service_id", "outcome", "status"})
+	callDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "gateway_call_duration_seconds",
+		Help:    "Time from receiving a call to the end of its response",
+		Buckets: []float64{.01, .05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60, 120},
+	}, []string{"service_id", "outcome"})
+	tokensTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "gateway_tokens_total",
+		Help: "Tokens of proxied calls by service, kind (prompt or completion) and whether they were estimated",
+	}, []string{"service_id", "kind", "estimated"})
+)
+
+func observeCall(serviceID, outcome string, status int, started time.Time) {
+	callsTotal.WithLabelValues(serviceID, outcome, strconv.Itoa(status)).Inc()
+	callDuration.WithLabelValues(serviceID, outcome).Observe(time.Since(started).Seconds())
+}
+
+func observeTokens(serviceID string, usage tokens.Usage, estimated bool) {
+	est := strconv.FormatBool(estimated)
+	tokensTotal.WithLabelValues(serviceID, "prompt", est).Add(float64(usage.Prompt))
+	tokensTotal.WithLabelValues(serviceID, "completion", est).Add(float64(usage.Completion))
+}
@@ -0,0 +1,62 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrPolicyUnavailable is returned when the policy engine can't be asked.
+// Calls are refused until it answers again.
+var ErrPolicyUnavailable = errors.New("policy engine unavailable")
+
+// Policy decides whether a consumer may call a service
+type Policy interface {
+	// CheckAccess asks whether the consumer may consume the service at all
+	CheckAccess(ctx context.Context, consumerID, serviceID string, attrs map[string]string) (*AccessDecision, error)
+	// ValidateConsumption asks whether this call may go through, and within
+	// which limits
+	ValidateConsumption(ctx context.Context, req *ConsumptionRequest) (*ConsumptionDecision, error)
+}
+
+// AccessDecision is the answer to an access check
+type AccessDecision struct {
+	Allowed            bool
+	Reason             string
+	MissingPermissions []string
+}
+
+// ConsumptionRequest describes a call for validation
+type ConsumptionRequest struct {
+	ConsumerID string
+	ServiceID  string
+	Payload    []byte
+	Headers    map[string]string
+	IPAddress  string
+	UserAgent  string
+	Metadata   map[string]string
+}
+
+// ConsumptionDecision is the answer to a consumption check
+type ConsumptionDecision struct {
+	Allowed    bool
+	Reason     string
+	Violations []Violation
+	Limits     Limits
+}
+
+// Violation is a policy the call breaks
+type Violation struct {
+	PolicyID    string `json:"policy_id"`
+	PolicyName  string `json:"policy_name"`
+	Severity    string `json:"severity"`
+	Message     string `json:"message"`
+	Remediation string `json:"remediation,omitempty"`
+}
+
+// Limits bound a call. A zero limit is not enforced.
+type Limits struct {
+	MaxTokens            int64 // Prompt and completion together
+	MaxRequestsPerMinute int64
+	MaxRequestsPerDay    int64
+	MaxCostPerRequest    float64 // In the service's pricing currency
+}
@@ -0,0 +1,39 @@
+package proxy
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/org/llm-marketplace/pkg/marketplace/problem"
+)
+
+// The gateway's own error types; the rest are shared. See README "Error
+// Responses".
+var (
+	unauthenticated   = problem.Type{Code: "unauthenticated", Title: "Consumer not identified", Status: http.StatusUnauthorized}
+	accessDenied      = problem.Type{Code: "access-denied", Title: "Access denied", Status: http.StatusForbidden}
+	consumptionDenied = problem.Type{Code: "consumption-denied", Title: "Call denied by marketplace policies", Status: http.StatusForbidden}
+	limitExceeded     = problem.Type{Code: "limit-exceeded", Title: "Call exceeds consumption limits", Status: http.StatusUnprocessableEntity}
+	upstreamError     = problem.Type{Code: "upstream-error", Title: "Service call failed", Status: http.StatusBadGateway}
+	upstreamTimeout   = problem.Type{Code: "upstream-timeout", Title: "Service timed out", Status: http.StatusGatewayTimeout}
+)
+
+// problemDetails adds the policies a denied call breaks to problem details
+type problemDetails struct {
+	problem.Details
+	Violations []Violation `json:"violations,omitempty"`
+}
+
+func newProblem(c *gin.Context, t problem.Type, detail string) *problemDetails {
+	return &problemDetails{Details: problem.New(t, c.Request.URL.Path, detail)}
+}
+
+func writeProblem(c *gin.Context, p *problemDetails) {
+	problem.Write(c.Writer, p.Status, p)
+	c.Abort()
+}
+
+// abort writes problem details and stops the handler chain
+func abort(c *gin.Context, t problem.Type, detail string) {
+	writeProblem(c, newProblem(c, t, detail))
+}
@@ -0,0 +1,270 @@
+// Package tokens counts the tokens of the LLM API calls the gateway proxies.
+// Counts come from the usage a service reports in its response, in the
+// OpenAI (prompt_tokens, completion_tokens) or Anthropic (input_tokens,
+// output_tokens) shape. When a service reports none, counts are estimated at
+// four characters a token.
+package tokens
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"unicode/utf8"
+)
+
+// charsPerToken is the rough size of a token in English text
+const charsPerToken = 4
+
+// maxTokensFields are the request fields that bound the completion, in the
+// order they are looked for
+var maxTokensFields = []string{"max_tokens", "max_completion_tokens", "max_output_tokens"}
+
+// Usage is the token count of a call
+type Usage struct {
+	Prompt     int64
+	Completion int64
+}
+
+// Total returns the prompt and completion tokens together
+func (u Usage) Total() int64 {
+	return u.Prompt + u.Completion
+}
+
+// Estimate returns the approximate token count of text
+func Estimate(text string) int64 {
+	n := int64(utf8.RuneCountInString(text))
+	return (n + charsPerToken - 1) / charsPerToken
+}
+
+// Request is a JSON request body to an LLM API
+type Request struct {
+	fields map[string]json.RawMessage
+}
+
+// ParseRequest parses body. It reports false for a body that is not a JSON
+// object, which is proxied as it is.
+func ParseRequest(body []byte) (*Request, bool) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil || fields == nil {
+		return nil, false
+	}
+	return &Request{fields: fields}, true
+}
+
+// Stream reports whether the request asks for a streamed response
+func (r *Request) Stream() bool {
+	var stream bool
+	json.Unmarshal(r.fields["stream"], &stream)
+	return stream
+}
+
+// PromptTokens estimates the tokens of the prompt: the messages, system
+// prompt, prompt or input of the request
+func (r *Request) PromptTokens() int64 {
+	var text strings.Builder
+	for _, field := range []string{"system", "messages", "prompt", "input"} {
+		if raw, ok := r.fields[field]; ok {
+			var v interface{}
+			json.Unmarshal(raw, &v)
+			appendText(&text, v)
+		}
+	}
+	return Estimate(text.String())
+}
+
+// MaxTokens returns the completion limit the request sets, if any
+func (r *Request) MaxTokens() (int64, bool) {
+	for _, field := range maxTokensFields {
+		if raw, ok := r.fields[field]; ok {
+			var n int64
+			if json.Unmarshal(raw, &n) == nil {
+				return n, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// CapMaxTokens lowers the completion limit of the request to limit, setting
+// max_tokens when the request has none. It reports whether the request
+// changed.
+func (r *Request) CapMaxTokens(limit int64) bool {
+	field := maxTokensFields[0]
+	for _, f := range maxTokensFields {
+		if _, ok := r.fields[f]; ok {
+			field = f
+			break
+		}
+	}
+	if n, ok := r.MaxTokens(); ok && n > 0 && n <= limit {
+		return false
+	}
+	r.fields[field], _ = json.Marshal(limit)
+	return true
+}
+
+// Body returns the request encoded as JSON
+func (r *Request) Body() []byte {
+	body, _ := json.Marshal(r.fields)
+	return body
+}
+
+// appendText adds the strings in v to text. Message content may be a string
+// or a list of parts; only the text of parts is counted.
+func appendText(text *strings.Builder, v interface{}) {
+	switch v := v.(type) {
+	case string:
+		if text.Len() > 0 {
+			text.WriteByte(' ')
+		}
+		text.WriteString(v)
+	case []interface{}:
+		for _, item := range v {
+			appendText(text, item)
+		}
+	case map[string]interface{}:
+		for _, key := range []string{"content", "text"} {
+			if item, ok := v[key]; ok {
+				appendText(text, item)
+			}
+		}
+	}
+}
+
+// Meter reads a response as it is relayed to the consumer and counts its
+// tokens. Streamed (server-sent events) responses are read event by event;
+// other responses are buffered up to a limit and read whole.
+type Meter struct {
+	stream bool
+	limit  int
+
+	body      bytes.Buffer
+	overflow  bool
+	line      []byte
+	usage     Usage
+	reported  bool
+	generated strings.Builder // Completion text of a stream
+}
+
+// NewMeter creates a meter for a response of contentType, buffering at most
+// limit bytes of a response that isn't streamed
+func NewMeter(contentType string, limit int) *Meter {
+	return &Meter{stream: strings.HasPrefix(contentType, "text/event-stream"), limit: limit}
+}
+
+// Write reads p, the next part of the response. It never fails.
+func (m *Meter) Write(p []byte) (int, error) {
+	if !m.stream {
+		if m.body.Len()+len(p) > m.limit {
+			m.overflow = true
+		} else {
+			m.body.Write(p)
+		}
+		return len(p), nil
+	}
+
+	data := append(m.line, p...)
+	for {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			break
+		}
+		m.readEvent(bytes.TrimSpace(data[:i]))
+		data = data[i+1:]
+	}
+	if len(data) > m.limit {
+		data = nil // Not an event line worth reading
+	}
+	m.line = append(m.line[:0], data...)
+	return len(p), nil
+}
+
+// readEvent reads a line of a server-sent event stream
+func (m *Meter) readEvent(line []byte) {
+	payload, ok := bytes.CutPrefix(line, []byte("data:"))
+	if !ok {
+		return
+	}
+	var event map[string]interface{}
+	if json.Unmarshal(bytes.TrimSpace(payload), &event) != nil {
+		return // e.g. [DONE]
+	}
+	m.read(event)
+	appendCompletion(&m.generated, event)
+}
+
+// read takes the usage reported in a response or event. A stream reports
+// usage across events (Anthropic sends input tokens first and output tokens
+// last), so the highest count of each kind is kept.
+func (m *Meter) read(obj map[string]interface{}) {
+	for _, usage := range []interface{}{obj["usage"], nested(obj, "message", "usage")} {
+		u, ok := usage.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if n, ok := count(u, "prompt_tokens", "input_tokens"); ok {
+			m.usage.Prompt = max(m.usage.Prompt, n)
+			m.reported = true
+		}
+		if n, ok := count(u, "completion_tokens", "output_tokens"); ok {
+			m.usage.Completion = max(m.usage.Completion, n)
+			m.reported = true
+		}
+	}
+}
+
+// Usage returns the tokens of the response, given the estimated tokens of
+// the prompt. It reports whether the counts are estimates.
+func (m *Meter) Usage(promptEstimate int64) (Usage, bool) {
+	var completion strings.Builder
+	if m.stream {
+		completion.WriteString(m.generated.String())
+	} else if !m.overflow {
+		var obj map[string]interface{}
+		if json.Unmarshal(m.body.Bytes(), &obj) == nil {
+			m.read(obj)
+			appendCompletion(&completion, obj)
+		}
+	}
+
+	if m.reported {
+		return m.usage, false
+	}
+	return Usage{Prompt: promptEstimate, Completion: Estimate(completion.String())}, true
+}
+
+// appendCompletion adds the generated text of a response or stream event:
+// OpenAI choices, or Anthropic content blocks and deltas
+func appendCompletion(text *strings.Builder, obj map[string]interface{}) {
+	if choices, ok := obj["choices"].([]interface{}); ok {
+		for _, c := range choices {
+			choice, _ := c.(map[string]interface{})
+			appendText(text, choice["text"])
+			appendText(text, nested(choice, "message", "content"))
+			appendText(text, nested(choice, "delta", "content"))
+		}
+	}
+	appendText(text, obj["content"])
+	appendText(text, nested(obj, "delta", "text"))
+}
+
+func nested(obj map[string]interface{}, keys ...string) interface{} {
+	var v interface{} = obj
+	for _, key := range keys {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		v = m[key]
+	}
+	return v
+}
+
+func count(obj map[string]interface{}, keys ...string) (int64, bool) {
+	for _, key := range keys {
+		if n, ok := obj[key].(float64); ok {
+			return int64(n), true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,184 @@
+// Package usage publishes a usage event for every call proxied through the
+// gateway, for billing and metrics. Events are buffered and written to Kafka
+// in batches so publishing never holds up a response.
+package usage
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/org/llm-marketplace/pkg/marketplace"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/segmentio/kafka-go"
+	"go.uber.org/zap"
+
+	"github.com/org/llm-marketplace/services/consumption-gateway/internal/config"
+)
+
+// writeTimeout bounds a single batch write to the brokers
+const writeTimeout = 10 * time.Second
+
+var usageEvents = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "gateway_usage_events_total",
+	Help: "Usage events by outcome: sent, failed or dropped",
+}, []string{"result"})
+
+// Writer writes messages to Kafka; *kafka.Writer satisfies it
+type Writer interface {
+	WriteMessages(ctx context.Context, msgs ...kafka.Message) error
+	Close() error
+}
+
+// NewWriter creates a Kafka writer for the configured topic. Events are what
+// consumers are billed from, so writes wait for every in-sync replica.
+func NewWriter(cfg config.UsageConfig) *kafka.Writer {
+	return &kafka.Writer{
+		Addr:      kafka.TCP(cfg.Brokers...),
+		Topic:     cfg.Topic,
+		Balancer:  &kafka.Hash{},
+		BatchSize: cfg.BatchSize,
+		// Batching happens in run; don't let the writer hold partial batches back
+		BatchTimeout: 10 * time.Millisecond,
+		RequiredAcks: kafka.RequireAll,
+	}
+}
+
+// Publisher buffers usage events and publishes them in batches keyed by
+// consumer ID. Events are dropped, and counted, when the buffer is full.
+type Publisher struct {
+	writer Writer
+	config config.UsageConfig
+	logger *zap.Logger
+
+	events    chan *marketplace.UsageEvent
+	done      chan struct{}
+	stopped   chan struct{}
+	closeOnce sync.Once
+	mu        sync.RWMutex
+	closed    bool
+}
+
+// NewPublisher starts publishing events with writer
+func NewPublisher(writer Writer, cfg config.UsageConfig, logger *zap.Logger) *Publisher {
+	p := &Publisher{
+		writer:  writer,
+		config:  cfg,
+		logger:  logger,
+		events:  make(chan *marketplace.UsageEvent, cfg.BufferSize),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	go p.run()
+	return p
+}
+
+// Publish queues event for publishing
+func (p *Publisher) Publish(event *marketplace.UsageEvent) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.closed {
+		usageEvents.WithLabelValues("dropped").Inc()
+		return
+	}
+
+	select {
+	case p.events <- event:
+	default:
+		usageEvents.WithLabelValues("dropped").Inc()
+		p.logger.Warn("Usage buffer full; dropping event",
+			zap.String("consumer_id", event.ConsumerID),
+			zap.String("service_id", event.ServiceID),
+		)
+	}
+}
+
+// Close stops accepting events, publishes the buffered ones and closes the
+// Kafka writer
+func (p *Publisher) Close(ctx context.Context) error {
+	p.closeOnce.Do(func() {
+		p.mu.Lock()
+		p.closed = true
+		p.mu.Unlock()
+		close(p.done)
+	})
+
+	select {
+	case <-p.stopped:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return p.writer.Close()
+}
+
+func (p *Publisher) run() {
+	defer close(p.stopped)
+
+	ticker := time.NewTicker(p.config.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]*marketplace.UsageEvent, 0, p.config.BatchSize)
+	add := func(event *marketplace.UsageEvent) {
+		batch = append(batch, event)
+		if len(batch) >= p.config.BatchSize {
+			batch = p.flush(batch)
+		}
+	}
+
+	for {
+		select {
+		case event := <-p.events:
+			add(event)
+		case <-ticker.C:
+			batch = p.flush(batch)
+		case <-p.done:
+			// Publish no longer sends once done is closed, so the buffer can be drained
+			for {
+				select {
+				case event := <-p.events:
+					add(event)
+				default:
+					p.flush(batch)
+					p.logger.Info("Usage publisher stopped")
+					return
+				}
+			}
+		}
+	}
+}
+
+// flush publishes batch and returns it emptied for reuse
+func (p *Publisher) flush(batch []*marketplace.UsageEvent) []*marketplace.UsageEvent {
+	if len(batch) == 0 {
+		return batch
+	}
+
+	messages := make([]kafka.Message, 0, len(batch))
+	for _, event := range batch {
+		value, err := json.Marshal(event)
+		if err != nil {
+			p.logger.Error("Failed to encode usage event", zap.String("id", event.ID), zap.Error(err))
+			continue
+		}
+		messages = append(messages, kafka.Message{
+			Key:     []byte(event.ConsumerID),
+			Value:   value,
+			Time:    event.OccurredAt,
+			Headers: []kafka.Header{{Key: "content-type", Value: []byte("application/json")}},
+		})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), writeTimeout)
+	defer cancel()
+
+	result := "sent"
+	if err := p.writer.WriteMessages(ctx, messages...); err != nil {
+		p.logger.Error("Failed to publish usage events", zap.Int("count", len(messages)), zap.Error(err))
+		result = "failed"
+	}
+	usageEvents.WithLabelValues(result).Add(float64(len(messages)))
+
+	return batch[:0]
+}
@@ -0,0 +1,80 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/org/llm-marketplace/services/consumption-gateway/internal/catalog"
+	"github.com/org/llm-marketplace/services/consumption-gateway/internal/config"
+)
+
+func TestRegistryLookupIsCached(t *testing.T) {
+	var requests atomic.Int32
+	registry := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		switch r.URL.Path {
+		case "/api/v1/services/chat":
+			w.Write([]byte(`{"id": "chat", "provider_id": "acme", "status": "deprecated", "revision": 3,
+				"service": {"service_id": "chat", "name": "Chat", "endpoint": {"url": "https://llm.acme.example"},
+				"pricing": {"model": "per-token", "rate": 0.01, "unit": "1k tokens", "currency": "USD"}}}`))
+		case "/api/v1/services/broken":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer registry.Close()
+
+	r := catalog.NewRegistry(config.RegistryConfig{URL: registry.URL + "/", Timeout: time.Second, CacheTTL: time.Minute})
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		s, err := r.Lookup(ctx, "chat")
+		if err != nil {
+			t.Fatalf("Lookup: %v", err)
+		}
+		if s.ProviderID != "acme" || s.Endpoint.URL != "https://llm.acme.example" || s.Pricing.Rate != 0.01 || !s.Consumable() {
+			t.Errorf("service = %+v", s)
+		}
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := r.Lookup(ctx, "missing"); !errors.Is(err, catalog.ErrNotFound) {
+			t.Errorf("Lookup(missing) = %v, want ErrNotFound", err)
+		}
+	}
+	if got := requests.Load(); got != 2 {
+		t.Errorf("registry got %d requests, want one per service", got)
+	}
+
+	// Failures are not cached
+	for i := 0; i < 2; i++ {
+		if _, err := r.Lookup(ctx, "broken"); !errors.Is(err, catalog.ErrUnavailable) {
+			t.Errorf("Lookup(broken) = %v, want ErrUnavailable", err)
+		}
+	}
+	if got := requests.Load(); got != 4 {
+		t.Errorf("registry got %d requests, want failed lookups retried", got)
+	}
+}
+
+func TestRegistryLookupOfPrivateService(t *testing.T) {
+	registry := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"id": "internal", "provider_id": "initech-ml", "status": "active",
+			"service": {"service_id": "internal", "name": "Internal LLM", "class": "private", "tenant_id": "initech"}}`))
+	}))
+	defer registry.Close()
+
+	r := catalog.NewRegistry(config.RegistryConfig{URL: registry.URL, Timeout: time.Second})
+	s, err := r.Lookup(context.Background(), "internal")
+	if err != nil {
+		t.Fatalf("Lookup: %v", err)
+	}
+	if s.TenantID != "initech" || !s.VisibleTo("initech") || s.VisibleTo("acme") {
+		t.Errorf("service = %+v, want visible to initech only", s)
+	}
+}
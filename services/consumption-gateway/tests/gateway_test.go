@@ -0,0 +1,407 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/org/llm-marketplace/pkg/marketplace"
+	"go.uber.org/zap"
+
+	"github.com/org/llm-marketplace/services/consumption-gateway/internal/catalog"
+	"github.com/org/llm-marketplace/services/consumption-gateway/internal/config"
+	"github.com/org/llm-marketplace/services/consumption-gateway/internal/limits"
+	"github.com/org/llm-marketplace/services/consumption-gateway/internal/proxy"
+)
+
+type fakeCatalog map[string]*catalog.Service
+
+func (f fakeCatalog) Lookup(_ context.Context, id string) (*catalog.Service, error) {
+	if s, ok := f[id]; ok {
+		return s, nil
+	}
+	return nil, catalog.ErrNotFound
+}
+
+// fakePolicy denies access to consumers in deny, denies consumption to
+// consumers in violate, and fails every call while err is set
+type fakePolicy struct {
+	deny    map[string]bool
+	violate map[string]bool
+	limits  proxy.Limits
+	err     error
+
+	mu       sync.Mutex
+	payloads []string
+}
+
+func (p *fakePolicy) CheckAccess(_ context.Context, consumerID, _ string, _ map[string]string) (*proxy.AccessDecision, error) {
+	if p.err != nil {
+		return nil, p.err
+	}
+	if p.deny[consumerID] {
+		return &proxy.AccessDecision{Reason: "consumer " + consumerID + " is not subscribed"}, nil
+	}
+	return &proxy.AccessDecision{Allowed: true}, nil
+}
+
+func (p *fakePolicy) ValidateConsumption(_ context.Context, req *proxy.ConsumptionRequest) (*proxy.ConsumptionDecision, error) {
+	if p.err != nil {
+		return nil, p.err
+	}
+	p.mu.Lock()
+	p.payloads = append(p.payloads, string(req.Payload))
+	p.mu.Unlock()
+	if p.violate[req.ConsumerID] {
+		return &proxy.ConsumptionDecision{Reason: "blocked", Violations: []proxy.Violation{{
+			PolicyID: "blocked-consumers", Severity: "critical", Message: "consumer is blocked",
+		}}}, nil
+	}
+	return &proxy.ConsumptionDecision{Allowed: true, Limits: p.limits}, nil
+}
+
+// memCounter counts in memory; windows never expire
+type memCounter struct {
+	mu     sync.Mutex
+	counts map[string]int64
+	err    error
+}
+
+func (m *memCounter) Incr(_ context.Context, key string, _ time.Duration) (int64, error) {
+	if m.err != nil {
+		return 0, m.err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.counts[key]++
+	return m.counts[key], nil
+}
+
+type usageRecorder struct {
+	mu     sync.Mutex
+	events []*marketplace.UsageEvent
+}
+
+func (u *usageRecorder) Publish(event *marketplace.UsageEvent) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.events = append(u.events, event)
+}
+
+// upstreamCall is a request as the service received it
+type upstreamCall struct {
+	Path     string
+	Query    string
+	Header   http.Header
+	Body     map[string]interface{}
+	RawBody  string
+	Consumer string
+}
+
+type testGateway struct {
+	policy   *fakePolicy
+	counter  *memCounter
+	usage    *usageRecorder
+	services fakeCatalog
+	router   *gin.Engine
+
+	mu    sync.Mutex
+	calls []upstreamCall
+}
+
+// newTestGateway proxies to an upstream that answers with respond. The
+// catalog holds chat, a per-token service on it, and suspended.
+func newTestGateway(t *testing.T, respond http.HandlerFunc) *testGateway {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	g := &testGateway{
+		policy:  &fakePolicy{deny: map[string]bool{"mallory": true}, violate: map[string]bool{"eve": true}},
+		counter: &memCounter{counts: map[string]int64{}},
+		usage:   &usageRecorder{},
+	}
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw, _ := io.ReadAll(r.Body)
+		call := upstreamCall{Path: r.URL.Path, Query: r.URL.RawQuery, Header: r.Header, RawBody: string(raw), Consumer: r.Header.Get("X-Consumer-ID")}
+		json.Unmarshal(raw, &call.Body)
+		g.mu.Lock()
+		g.calls = append(g.calls, call)
+		g.mu.Unlock()
+		respond(w, r)
+	}))
+	t.Cleanup(upstream.Close)
+
+	g.services = fakeCatalog{
+		"chat": {
+			ID: "chat", ProviderID: "acme", Status: marketplace.StatusActive,
+			Endpoint: &marketplace.EndpointInfo{URL: upstream.URL + "/api"},
+			Pricing:  &marketplace.PricingInfo{Model: "per-token", Rate: 0.01, Unit: "1k tokens", Currency: "USD"},
+		},
+		"suspended": {ID: "suspended", Status: marketplace.StatusSuspended, Endpoint: &marketplace.EndpointInfo{URL: upstream.URL}},
+		"internal":  {ID: "internal", Status: marketplace.StatusActive, TenantID: "initech", Endpoint: &marketplace.EndpointInfo{URL: upstream.URL}},
+	}
+
+	cfg := &config.Config{}
+	cfg.Server.ConsumerHeader = "X-Consumer-ID"
+	cfg.Server.MaxBodyBytes = 1 << 10
+	cfg.Upstream.Timeout = 5 * time.Second
+
+	g.router = gin.New()
+	proxy.NewGateway(g.services, g.policy, limits.NewLimiter(g.counter), g.usage, cfg, zap.NewNop()).RegisterRoutes(g.router)
+	return g
+}
+
+func (g *testGateway) do(t *testing.T, consumer, path, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer sk-provider")
+	if consumer != "" {
+		req.Header.Set("X-Consumer-ID", consumer)
+	}
+	w := httptest.NewRecorder()
+	g.router.ServeHTTP(w, req)
+	return w
+}
+
+func problemCode(t *testing.T, w *httptest.ResponseRecorder) string {
+	t.Helper()
+	var p struct {
+		Code string `json:"code"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &p)
+	return p.Code
+}
+
+func openAIResponse(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(`{"choices": [{"message": {"role": "assistant", "content": "Hello!"}}], "usage": {"prompt_tokens": 12, "completion_tokens": 3, "total_tokens": 15}}`))
+}
+
+const chatRequest = `{"model": "m", "messages": [{"role": "user", "content": "Say hello"}], "max_tokens": 500}`
+
+func TestGatewayProxiesCallsAndRecordsUsage(t *testing.T) {
+	g := newTestGateway(t, openAIResponse)
+
+	w := g.do(t, "alice", "/v1/services/chat/v1/chat/completions?beta=1", chatRequest)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body)
+	}
+	if !strings.Contains(w.Body.String(), "Hello!") {
+		t.Errorf("body = %s, want the service's response", w.Body)
+	}
+	if w.Header().Get("X-Request-ID") == "" {
+		t.Error("no X-Request-ID on the response")
+	}
+
+	if len(g.calls) != 1 {
+		t.Fatalf("service got %d calls, want 1", len(g.calls))
+	}
+	call := g.calls[0]
+	if call.Path != "/api/v1/chat/completions" || call.Query != "beta=1" {
+		t.Errorf("service called at %s?%s, want /api/v1/chat/completions?beta=1", call.Path, call.Query)
+	}
+	if call.Consumer != "" {
+		t.Error("the consumer header was passed to the service")
+	}
+	if call.Header.Get("Authorization") != "Bearer sk-provider" {
+		t.Errorf("Authorization = %q, want it passed through", call.Header.Get("Authorization"))
+	}
+	if len(g.policy.payloads) != 1 || g.policy.payloads[0] != chatRequest {
+		t.Errorf("policy engine saw payloads %q, want the request body", g.policy.payloads)
+	}
+
+	if len(g.usage.events) != 1 {
+		t.Fatalf("got %d usage events, want 1", len(g.usage.events))
+	}
+	e := g.usage.events[0]
+	if e.ConsumerID != "alice" || e.ServiceID != "chat" || e.ProviderID != "acme" || e.StatusCode != http.StatusOK {
+		t.Errorf("event = %+v", e)
+	}
+	if e.PromptTokens != 12 || e.CompletionTokens != 3 || e.TotalTokens != 15 || e.TokensEstimated {
+		t.Errorf("tokens = %d+%d=%d estimated=%v, want the reported 12+3=15", e.PromptTokens, e.CompletionTokens, e.TotalTokens, e.TokensEstimated)
+	}
+	if e.Path != "/v1/chat/completions" || e.RequestID != w.Header().Get("X-Request-ID") || e.Pricing == nil {
+		t.Errorf("event = %+v", e)
+	}
+}
+
+func TestGatewayMetersStreams(t *testing.T) {
+	g := newTestGateway(t, func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, event := range []string{
+			`{"type": "message_start", "message": {"usage": {"input_tokens": 20, "output_tokens": 1}}}`,
+			`{"type": "content_block_delta", "delta": {"type": "text_delta", "text": "Hi"}}`,
+			`{"type": "message_delta", "usage": {"output_tokens": 7}}`,
+		} {
+			fmt.Fprintf(w, "event: x\ndata: %s\n\n", event)
+			w.(http.Flusher).Flush()
+		}
+	})
+
+	w := g.do(t, "alice", "/v1/services/chat/messages", `{"messages": [{"role": "user", "content": [{"type": "text", "text": "Hi"}]}], "stream": true}`)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "text_delta") {
+		t.Fatalf("status = %d, body = %s", w.Code, w.Body)
+	}
+	e := g.usage.events[0]
+	if e.PromptTokens != 20 || e.CompletionTokens != 7 || e.TokensEstimated {
+		t.Errorf("tokens = %d+%d estimated=%v, want the reported 20+7", e.PromptTokens, e.CompletionTokens, e.TokensEstimated)
+	}
+}
+
+func TestGatewayEstimatesUnreportedTokens(t *testing.T) {
+	g := newTestGateway(t, func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"choices": [{"text": "twelve chars"}]}`))
+	})
+
+	g.do(t, "alice", "/v1/services/chat/completions", `{"prompt": "sixteen chars!!!"}`)
+	e := g.usage.events[0]
+	if !e.TokensEstimated || e.PromptTokens != 4 || e.CompletionTokens != 3 {
+		t.Errorf("tokens = %d+%d estimated=%v, want an estimated 4+3", e.PromptTokens, e.CompletionTokens, e.TokensEstimated)
+	}
+}
+
+func TestGatewayRefusesCalls(t *testing.T) {
+	g := newTestGateway(t, openAIResponse)
+
+	tests := []struct {
+		name     string
+		consumer string
+		path     string
+		body     string
+		status   int
+		code     string
+	}{
+		{"no consumer", "", "/v1/services/chat/x", chatRequest, http.StatusUnauthorized, "unauthenticated"},
+		{"unknown service", "alice", "/v1/services/missing/x", chatRequest, http.StatusNotFound, "not-found"},
+		{"suspended service", "alice", "/v1/services/suspended/x", chatRequest, http.StatusNotFound, "not-found"},
+		{"another tenant's private service", "alice", "/v1/services/internal/x", chatRequest, http.StatusNotFound, "not-found"},
+		{"access denied", "mallory", "/v1/services/chat/x", chatRequest, http.StatusForbidden, "access-denied"},
+		{"consumption denied", "eve", "/v1/services/chat/x", chatRequest, http.StatusForbidden, "consumption-denied"},
+		{"body too large", "alice", "/v1/services/chat/x", strings.Repeat("x", 2<<10), http.StatusRequestEntityTooLarge, "payload-too-large"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := g.do(t, tt.consumer, tt.path, tt.body)
+			if w.Code != tt.status || problemCode(t, w) != tt.code {
+				t.Errorf("got %d %s, want %d %s: %s", w.Code, problemCode(t, w), tt.status, tt.code, w.Body)
+			}
+		})
+	}
+
+	w := g.do(t, "eve", "/v1/services/chat/x", chatRequest)
+	if !strings.Contains(w.Body.String(), "blocked-consumers") {
+		t.Errorf("body = %s, want the violations", w.Body)
+	}
+	if len(g.calls) != 0 || len(g.usage.events) != 0 {
+		t.Errorf("refused calls reached the service (%d) or were recorded (%d)", len(g.calls), len(g.usage.events))
+	}
+}
+
+func TestGatewayFailsClosed(t *testing.T) {
+	g := newTestGateway(t, openAIResponse)
+
+	g.policy.err = fmt.Errorf("%w: connection refused", proxy.ErrPolicyUnavailable)
+	if w := g.do(t, "alice", "/v1/services/chat/x", chatRequest); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d with the policy engine down, want 503", w.Code)
+	}
+
+	g.policy.err = nil
+	g.policy.limits.MaxRequestsPerMinute = 10
+	g.counter.err = errors.New("redis: connection refused")
+	if w := g.do(t, "alice", "/v1/services/chat/x", chatRequest); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d with the counters down, want 503", w.Code)
+	}
+	if len(g.calls) != 0 {
+		t.Errorf("service got %d calls, want none", len(g.calls))
+	}
+}
+
+func TestGatewayRateLimits(t *testing.T) {
+	g := newTestGateway(t, openAIResponse)
+	g.policy.limits = proxy.Limits{MaxRequestsPerMinute: 2, MaxRequestsPerDay: 100}
+
+	for i := 0; i < 2; i++ {
+		if w := g.do(t, "alice", "/v1/services/chat/x", chatRequest); w.Code != http.StatusOK {
+			t.Fatalf("call %d: status = %d", i+1, w.Code)
+		}
+	}
+	w := g.do(t, "alice", "/v1/services/chat/x", chatRequest)
+	if w.Code != http.StatusTooManyRequests || problemCode(t, w) != "rate-limited" {
+		t.Fatalf("third call: %d %s, want 429 rate-limited", w.Code, problemCode(t, w))
+	}
+	if retry := w.Header().Get("Retry-After"); retry == "" || retry == "0" {
+		t.Errorf("Retry-After = %q, want the seconds to the next minute", retry)
+	}
+
+	// Limits are per consumer
+	if w := g.do(t, "bob", "/v1/services/chat/x", chatRequest); w.Code != http.StatusOK {
+		t.Errorf("another consumer got %d", w.Code)
+	}
+}
+
+func TestGatewayTokenLimits(t *testing.T) {
+	g := newTestGateway(t, openAIResponse)
+
+	// The completion is capped to what the prompt leaves of max_tokens
+	g.policy.limits = proxy.Limits{MaxTokens: 100}
+	if w := g.do(t, "alice", "/v1/services/chat/x", chatRequest); w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body)
+	}
+	if got := g.calls[0].Body["max_tokens"]; got != float64(97) {
+		t.Errorf("max_tokens = %v, want 97 (100 less the 3-token prompt)", got)
+	}
+
+	// A request without max_tokens gets one
+	g.do(t, "alice", "/v1/services/chat/x", `{"messages": [{"role": "user", "content": "Say hello"}]}`)
+	if got := g.calls[1].Body["max_tokens"]; got != float64(97) {
+		t.Errorf("max_tokens = %v, want 97", got)
+	}
+
+	// A lower requested limit is kept as is
+	g.do(t, "alice", "/v1/services/chat/x", `{"messages": [{"role": "user", "content": "Say hello"}], "max_tokens": 10}`)
+	if got := g.calls[2].RawBody; got != `{"messages": [{"role": "user", "content": "Say hello"}], "max_tokens": 10}` {
+		t.Errorf("body = %s, want it unchanged", got)
+	}
+
+	// The cost limit caps tokens at the service's price: 0.0005 USD at 0.01
+	// per 1k tokens is 50 tokens
+	g.policy.limits = proxy.Limits{MaxTokens: 100, MaxCostPerRequest: 0.0005}
+	g.do(t, "alice", "/v1/services/chat/x", chatRequest)
+	if got := g.calls[3].Body["max_tokens"]; got != float64(47) {
+		t.Errorf("max_tokens = %v, want 47 under the cost limit", got)
+	}
+
+	// A prompt that alone exceeds the limit is refused
+	g.policy.limits = proxy.Limits{MaxTokens: 5}
+	w := g.do(t, "alice", "/v1/services/chat/x", `{"messages": [{"role": "user", "content": "`+strings.Repeat("word ", 20)+`"}]}`)
+	if w.Code != http.StatusUnprocessableEntity || problemCode(t, w) != "limit-exceeded" {
+		t.Errorf("got %d %s, want 422 limit-exceeded", w.Code, problemCode(t, w))
+	}
+	if len(g.calls) != 4 {
+		t.Errorf("service got %d calls, want 4", len(g.calls))
+	}
+}
+
+func TestGatewayReportsUpstreamFailures(t *testing.T) {
+	g := newTestGateway(t, openAIResponse)
+	g.services["down"] = &catalog.Service{ID: "down", Status: marketplace.StatusActive, Endpoint: &marketplace.EndpointInfo{URL: "http://127.0.0.1:1"}}
+
+	w := g.do(t, "alice", "/v1/services/down/x", chatRequest)
+	if w.Code != http.StatusBadGateway || problemCode(t, w) != "upstream-error" {
+		t.Errorf("got %d %s, want 502 upstream-error", w.Code, problemCode(t, w))
+	}
+	if len(g.usage.events) != 0 {
+		t.Error("a call the service never answered was recorded")
+	}
+}
@@ -0,0 +1,60 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/org/llm-marketplace/pkg/marketplace"
+	"github.com/segmentio/kafka-go"
+	"go.uber.org/zap"
+
+	"github.com/org/llm-marketplace/services/consumption-gateway/internal/config"
+	"github.com/org/llm-marketplace/services/consumption-gateway/internal/usage"
+)
+
+type fakeWriter struct {
+	mu       sync.Mutex
+	messages []kafka.Message
+	closed   bool
+}
+
+func (w *fakeWriter) WriteMessages(_ context.Context, msgs ...kafka.Message) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.messages = append(w.messages, msgs...)
+	return nil
+}
+
+func (w *fakeWriter) Close() error {
+	w.closed = true
+	return nil
+}
+
+func TestUsagePublisherFlushesOnClose(t *testing.T) {
+	writer := &fakeWriter{}
+	publisher := usage.NewPublisher(writer, config.UsageConfig{BatchSize: 10, BufferSize: 100, FlushInterval: time.Hour}, zap.NewNop())
+
+	for _, consumer := range []string{"alice", "bob", "alice"} {
+		publisher.Publish(&marketplace.UsageEvent{ID: consumer, ConsumerID: consumer, ServiceID: "chat", TotalTokens: 15})
+	}
+	if err := publisher.Close(context.Background()); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	publisher.Publish(&marketplace.UsageEvent{ConsumerID: "late"})
+
+	if len(writer.messages) != 3 || !writer.closed {
+		t.Fatalf("wrote %d messages (closed %v), want the 3 published before Close", len(writer.messages), writer.closed)
+	}
+	for _, m := range writer.messages {
+		var event marketplace.UsageEvent
+		if err := json.Unmarshal(m.Value, &event); err != nil {
+			t.Fatalf("message is not a usage event: %v", err)
+		}
+		if string(m.Key) != event.ConsumerID || event.TotalTokens != 15 {
+			t.Errorf("message key %q for event %+v, want keyed by consumer", m.Key, event)
+		}
+	}
+}
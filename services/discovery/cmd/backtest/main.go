@@ -0,0 +1,106 @@
+// Command backtest replays historical interactions against the
+// recommendation algorithms and reports precision@k, recall@k and coverage
+// for each variant, to tune the recommendations section of config.yaml.
+//
+//	backtest [-config config.yaml] [-holdout 168h] [-history 2160h] [-k 5,10] [-weights "0.5,0.3,0.2;..."]
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/org/llm-marketplace/pkg/marketplace/secrets"
+	"github.com/org/llm-marketplace/services/discovery/internal/backtest"
+	"github.com/org/llm-marketplace/services/discovery/internal/config"
+	"github.com/org/llm-marketplace/services/discovery/internal/observability"
+	"github.com/org/llm-marketplace/services/discovery/internal/postgres"
+	"github.com/org/llm-marketplace/services/discovery/internal/privacy"
+	"github.com/org/llm-marketplace/services/discovery/internal/startup"
+)
+
+func main() {
+	configPath := flag.String("config", config.Path(), "Config file holding the Postgres connection and the recommendation settings under test")
+	holdout := flag.Duration("holdout", 7*24*time.Hour, "Most recent stretch of interactions held out for evaluation")
+	history := flag.Duration("history", 90*24*time.Hour, "Stretch of interactions before the holdout the algorithms train on")
+	kFlag := flag.String("k", "5,10", "Comma-separated cut-offs for precision, recall and coverage")
+	weightsFlag := flag.String("weights", "", "Hybrid weightings to compare, as collaborative,content,popularity separated by semicolons")
+	reportPath := flag.String("report", "backtest-report.json", "Where to write the JSON report; - for stdout")
+	flag.Parse()
+
+	ks, err := backtest.ParseK(*kFlag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid -k: %v\n", err)
+		os.Exit(2)
+	}
+	weights, err := backtest.ParseWeights(*weightsFlag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid -weights: %v\n", err)
+		os.Exit(2)
+	}
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load configuration: %v\n", err)
+		os.Exit(2)
+	}
+	logger, err := observability.NewLogger(cfg.Observability.Logging)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to initialize logger: %v\n", err)
+		os.Exit(2)
+	}
+	defer logger.Sync()
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	password, err := secrets.NewManager(cfg.Secrets, nil).Password(ctx, cfg.Postgres.Password)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to resolve the PostgreSQL password: %v\n", err)
+		os.Exit(2)
+	}
+
+	// Run as a job alongside a database that may still be starting
+	var pgPool *postgres.Pool
+	err = startup.NewWaiter(cfg.Server.Startup, logger).Wait(ctx, "postgres", func(context.Context) (err error) {
+		pgPool, err = postgres.NewPool(cfg.Postgres, password)
+		return err
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to connect to PostgreSQL: %v\n", err)
+		os.Exit(2)
+	}
+	defer pgPool.Close()
+
+	cutoff := time.Now().Add(-*holdout)
+	dataset, err := backtest.Load(ctx, pgPool, cutoff.Add(-*history), privacy.NewPseudonymizer(cfg.Privacy))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(2)
+	}
+
+	report := backtest.Run(dataset, backtest.Options{
+		Cutoff:  cutoff,
+		K:       ks,
+		Config:  cfg.Recommendations,
+		Weights: weights,
+	})
+
+	if *reportPath == "-" {
+		err = report.WriteJSON(os.Stdout)
+	} else {
+		err = report.WriteFile(*reportPath)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(2)
+	}
+
+	fmt.Fprintf(os.Stderr, "%d users, %d training and %d held-out interactions, %d active services\n",
+		report.Users, report.TrainSize, report.TestSize, report.CatalogSize)
+	report.WriteTable(os.Stderr)
+}
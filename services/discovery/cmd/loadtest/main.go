@@ -0,0 +1,77 @@
+// Command loadtest drives a running discovery service and checks its latency
+// against the performance targets in config.yaml.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/org/llm-marketplace/services/discovery/internal/config"
+	"github.com/org/llm-marketplace/services/discovery/internal/fixtures"
+	"github.com/org/llm-marketplace/services/discovery/internal/loadtest"
+)
+
+func main() {
+	baseURL := flag.String("url", "http://localhost:8080", "Base URL of the discovery service")
+	configPath := flag.String("config", "config.yaml", "Config file holding the performance targets")
+	concurrency := flag.Int("concurrency", 50, "Clients issuing requests in parallel")
+	total := flag.Int("requests", 5000, "Requests to send; 0 runs for -duration")
+	duration := flag.Duration("duration", 0, "Longest the run may take; 0 for no limit")
+	timeout := flag.Duration("timeout", 10*time.Second, "Per-request timeout")
+	reportPath := flag.String("report", "loadtest-report.json", "Where to write the JSON report; - for stdout")
+	queries := flag.Int("queries", 0, "Varied searches to add to the default mix, drawn from the fixture catalog's vocabulary")
+	seed := flag.Uint64("seed", 1, "Seed the -queries are generated from; match the seed command's to search a seeded catalog")
+	flag.Parse()
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load configuration: %v\n", err)
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	requests := loadtest.DefaultRequests()
+	if *queries > 0 {
+		requests = append(requests, loadtest.QueryRequests(fixtures.Queries(*seed, *queries))...)
+	}
+
+	report, err := loadtest.Run(ctx, loadtest.Options{
+		BaseURL:     *baseURL,
+		Requests:    requests,
+		Concurrency: *concurrency,
+		Total:       *total,
+		Duration:    *duration,
+		Timeout:     *timeout,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Load test failed: %v\n", err)
+		os.Exit(2)
+	}
+	passed := report.Check(cfg.Performance)
+
+	if *reportPath == "-" {
+		err = report.WriteJSON(os.Stdout)
+	} else {
+		err = report.WriteFile(*reportPath)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(2)
+	}
+
+	fmt.Fprintf(os.Stderr, "%d requests, %d errors, %.1f req/s, p95 %.1fms, p99 %.1fms\n",
+		report.Requests, report.Errors, report.Throughput, report.Latency.P95MS, report.Latency.P99MS)
+	if !passed {
+		for _, v := range report.Violations {
+			fmt.Fprintf(os.Stderr, "FAIL: %s\n", v)
+		}
+		os.Exit(1)
+	}
+}
@@ -2,29 +2,70 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"net/http"
 	"os"
 	"os/signal"
+	"sync/atomic"
 	"syscall"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	goredis "github.com/go-redis/redis/v8"
+	"github.com/prometheus/client_golang/prometheus"
 	"go.uber.org/zap"
 
+	"github.com/org/llm-marketplace/pkg/marketplace/flags"
+	"github.com/org/llm-marketplace/pkg/marketplace/secrets"
+	"github.com/org/llm-marketplace/services/discovery/internal/analytics"
 	"github.com/org/llm-marketplace/services/discovery/internal/api"
+	"github.com/org/llm-marketplace/services/discovery/internal/catalog"
 	"github.com/org/llm-marketplace/services/discovery/internal/config"
 	"github.com/org/llm-marketplace/services/discovery/internal/elasticsearch"
+	"github.com/org/llm-marketplace/services/discovery/internal/export"
+	"github.com/org/llm-marketplace/services/discovery/internal/features"
+	"github.com/org/llm-marketplace/services/discovery/internal/feed"
+	"github.com/org/llm-marketplace/services/discovery/internal/graphql"
+	"github.com/org/llm-marketplace/services/discovery/internal/health"
+	"github.com/org/llm-marketplace/services/discovery/internal/migrations"
 	"github.com/org/llm-marketplace/services/discovery/internal/observability"
+	"github.com/org/llm-marketplace/services/discovery/internal/policy"
 	"github.com/org/llm-marketplace/services/discovery/internal/postgres"
+	"github.com/org/llm-marketplace/services/discovery/internal/privacy"
+	"github.com/org/llm-marketplace/services/discovery/internal/problem"
+	"github.com/org/llm-marketplace/services/discovery/internal/quota"
 	"github.com/org/llm-marketplace/services/discovery/internal/redis"
+	"github.com/org/llm-marketplace/services/discovery/internal/sandbox"
 	"github.com/org/llm-marketplace/services/discovery/internal/search"
 	"github.com/org/llm-marketplace/services/discovery/internal/recommendation"
+	"github.com/org/llm-marketplace/services/discovery/internal/sla"
+	"github.com/org/llm-marketplace/services/discovery/internal/snapshot"
+	"github.com/org/llm-marketplace/services/discovery/internal/startup"
+	"github.com/org/llm-marketplace/services/discovery/internal/subscription"
+	"github.com/org/llm-marketplace/services/discovery/internal/taxonomy"
+	"github.com/org/llm-marketplace/services/discovery/internal/webhook"
+	"github.com/org/llm-marketplace/services/discovery/internal/worker"
 )
 
+// errIndicesNotReady fails the elasticsearch readiness check until index setup
+// has succeeded
+var errIndicesNotReady = errors.New("indices not created yet")
+
+// errCachesNotWarm fails the cache warm-up readiness check until the caches
+// are primed or search.warmup.timeout passes
+var errCachesNotWarm = errors.New("caches not warmed yet")
+
 func main() {
-	// Load configuration
-	cfg, err := config.Load("config.yaml")
+	sandboxMode := flag.Bool("sandbox", false, "Run against in-memory Elasticsearch, Redis and PostgreSQL seeded with a generated catalog")
+	sandboxSeed := flag.Uint64("sandbox-seed", 1, "Seed the sandbox catalog is generated from")
+	sandboxItems := flag.Int("sandbox-items", 500, "Items in the sandbox catalog")
+	flag.Parse()
+
+	// Load configuration; without a file, defaults and DISCOVERY_* variables apply
+	configPath := config.Path()
+	cfg, err := config.Load(configPath)
 	if err != nil {
 		panic(fmt.Sprintf("Failed to load configuration: %v", err))
 	}
@@ -39,42 +80,168 @@ func main() {
 	logger.Info("Starting LLM-Marketplace Discovery Service",
 		zap.String("version", "1.0.0"),
 		zap.String("environment", os.Getenv("ENVIRONMENT")),
+		zap.String("config", configPath),
+		zap.Bool("sandbox", *sandboxMode),
 	)
 
+	// The sandbox points the connections below at its own backends
+	if *sandboxMode {
+		sb, err := sandbox.Start(context.Background(), cfg, sandbox.Options{Seed: *sandboxSeed, Services: *sandboxItems, Users: *sandboxItems / 5}, logger)
+		if err != nil {
+			logger.Fatal("Failed to start sandbox", zap.Error(err))
+		}
+		defer sb.Close()
+	}
+
 	// Initialize observability
-	cleanup, err := observability.InitTracing(cfg.Observability.Tracing, logger)
+	res, err := observability.NewResource(cfg.Observability.ResourceAttributes)
+	if err != nil {
+		logger.Fatal("Failed to build telemetry resource", zap.Error(err))
+	}
+
+	cleanup, err := observability.InitTracing(cfg.Observability.Tracing, res, logger)
 	if err != nil {
 		logger.Fatal("Failed to initialize tracing", zap.Error(err))
 	}
 	defer cleanup()
 
 	metrics := observability.InitMetrics()
+	shutdownMetrics, err := observability.InitOTelMetrics(cfg.Observability.Metrics.OTel, res, prometheus.DefaultRegisterer, logger)
+	if err != nil {
+		logger.Fatal("Failed to initialize OTel metrics", zap.Error(err))
+	}
+	defer shutdownMetrics()
 
 	// Initialize database connections
 	logger.Info("Initializing database connections...")
 
-	pgPool, err := postgres.NewPool(cfg.Postgres)
+	// Dependencies may still be starting; wait for each rather than exiting
+	waiter := startup.NewWaiter(cfg.Server.Startup, logger)
+
+	// Passwords given as secret references are fetched here and re-read in
+	// the background; each new connection uses the current value
+	secretsManager := secrets.NewManager(cfg.Secrets, func(name string, err error) {
+		if err != nil {
+			logger.Warn("Failed to refresh secret; keeping the last value", zap.String("secret", name), zap.Error(err))
+			return
+		}
+		logger.Info("Secret rotated", zap.String("secret", name))
+	})
+	var pgPassword, redisPassword, esPassword func() string
+	err = waiter.Wait(context.Background(), "secrets", func(ctx context.Context) (err error) {
+		if pgPassword, err = secretsManager.Password(ctx, cfg.Postgres.Password); err != nil {
+			return err
+		}
+		if redisPassword, err = secretsManager.Password(ctx, cfg.Redis.Password); err != nil {
+			return err
+		}
+		esPassword, err = secretsManager.Password(ctx, cfg.Elasticsearch.Password)
+		return err
+	})
+	if err != nil {
+		logger.Fatal("Failed to resolve secrets", zap.Error(err))
+	}
+
+	var pgPool *postgres.Pool
+	err = waiter.Wait(context.Background(), "postgres", func(context.Context) (err error) {
+		pgPool, err = postgres.NewPool(cfg.Postgres, pgPassword)
+		return err
+	})
 	if err != nil {
 		logger.Fatal("Failed to connect to PostgreSQL", zap.Error(err))
 	}
 	defer pgPool.Close()
+	prometheus.MustRegister(pgPool.Collector())
+
+	// Apply or check schema migrations; the sandbox's database has no schema
+	if !*sandboxMode {
+		migrator, err := migrations.New(pgPool, logger)
+		if err != nil {
+			logger.Fatal("Failed to load migrations", zap.Error(err))
+		}
+		if cfg.Postgres.Migrations.AutoMigrate {
+			if _, err := migrator.Up(context.Background()); err != nil {
+				logger.Fatal("Failed to apply migrations", zap.Error(err))
+			}
+		}
+		schema, err := migrator.Status(context.Background())
+		if err != nil {
+			logger.Fatal("Failed to check migrations", zap.Error(err))
+		}
+		if err := schema.Err(); err != nil {
+			if cfg.Postgres.Migrations.FailOnDrift {
+				logger.Fatal("Database schema does not match this build; run migrate up", zap.Error(err))
+			}
+			logger.Warn("Database schema does not match this build; run migrate up", zap.Error(err))
+		}
+	}
 
-	redisClient, err := redis.NewClient(cfg.Redis)
+	var redisClient *goredis.Client
+	err = waiter.Wait(context.Background(), "redis", func(context.Context) (err error) {
+		redisClient, err = redis.NewClient(cfg.Redis, redisPassword)
+		return err
+	})
 	if err != nil {
 		logger.Fatal("Failed to connect to Redis", zap.Error(err))
 	}
 	defer redisClient.Close()
 
-	esClient, err := elasticsearch.NewClient(cfg.Elasticsearch)
+	var esClient *elasticsearch.Client
+	err = waiter.Wait(context.Background(), "elasticsearch", func(context.Context) (err error) {
+		esClient, err = elasticsearch.NewClient(cfg.Elasticsearch, esPassword)
+		return err
+	})
 	if err != nil {
 		logger.Fatal("Failed to connect to Elasticsearch", zap.Error(err))
 	}
+	esClient.SetMetrics(metrics)
+	if cfg.PolicyEngine.EnrichOnIndex {
+		policyConn, err := policy.Dial(cfg.PolicyEngine)
+		if err != nil {
+			logger.Fatal("Failed to connect to policy engine", zap.Error(err))
+		}
+		defer policyConn.Close()
+		esClient.SetPolicyValidator(policy.NewClient(policyConn, cfg.PolicyEngine.Timeout))
+	}
+	esHealth := elasticsearch.NewHealthProber(esClient, cfg.Elasticsearch.Health, logger)
 
-	// Initialize search index
-	logger.Info("Initializing Elasticsearch index...")
+	// Index setup is idempotent; see below for when it fails at startup
 	indexManager := elasticsearch.NewIndexManager(esClient, cfg.Elasticsearch, logger)
-	if err := indexManager.CreateIndex(context.Background()); err != nil {
-		logger.Fatal("Failed to create Elasticsearch index", zap.Error(err))
+	setupIndices := func(ctx context.Context) error {
+		if err := indexManager.CreateIndex(ctx); err != nil {
+			return fmt.Errorf("failed to create index: %w", err)
+		}
+		if err := indexManager.PutVectorFields(ctx, cfg.EmbeddingModels()); err != nil {
+			return fmt.Errorf("failed to map embedding vector fields: %w", err)
+		}
+		if err := indexManager.PutSuggestField(ctx); err != nil {
+			return fmt.Errorf("failed to map the suggest field: %w", err)
+		}
+		if err := indexManager.PutPolicyComplianceField(ctx); err != nil {
+			return fmt.Errorf("failed to map the policy compliance field: %w", err)
+		}
+		if err := indexManager.PutDependenciesField(ctx); err != nil {
+			return fmt.Errorf("failed to map the dependencies field: %w", err)
+		}
+		if err := indexManager.PutComplianceExpiriesField(ctx); err != nil {
+			return fmt.Errorf("failed to map the compliance expiries field: %w", err)
+		}
+		if err := indexManager.PutPromptTemplateFields(ctx); err != nil {
+			return fmt.Errorf("failed to map the prompt template fields: %w", err)
+		}
+		if err := indexManager.PutDatasetFields(ctx); err != nil {
+			return fmt.Errorf("failed to map the dataset fields: %w", err)
+		}
+		if err := indexManager.PutFineTuningFields(ctx); err != nil {
+			return fmt.Errorf("failed to map the fine-tuning fields: %w", err)
+		}
+		if err := indexManager.PutServingRegionsField(ctx); err != nil {
+			return fmt.Errorf("failed to map the serving regions field: %w", err)
+		}
+		if err := indexManager.CreateEntityIndices(ctx); err != nil {
+			return fmt.Errorf("failed to create entity indices: %w", err)
+		}
+		return nil
 	}
 
 	// Initialize services
@@ -87,6 +254,17 @@ func main() {
 		metrics,
 	)
 
+	taxonomyManager := taxonomy.NewManager(
+		pgPool,
+		redisClient,
+		cfg,
+		logger,
+	)
+	searchService.SetTaxonomy(taxonomyManager)
+	if cfg.Subscriptions.Enabled {
+		searchService.SetSubscriptions(subscription.NewClient(cfg.Subscriptions))
+	}
+
 	recommendationService := recommendation.NewService(
 		pgPool,
 		redisClient,
@@ -94,6 +272,228 @@ func main() {
 		logger,
 		metrics,
 	)
+	recommendationService.SetVisibility(searchService)
+	recommendationService.SetServiceSource(esClient)
+
+	// Ranking and recommendations read precomputed features, refreshed in the background
+	featureStore := features.NewStore(
+		pgPool,
+		redisClient,
+		cfg.Features,
+		privacy.NewPseudonymizer(cfg.Privacy),
+		logger,
+	)
+	searchService.SetFeatures(featureStore)
+	recommendationService.SetFeatures(featureStore)
+
+	// Feature flags gate semantic search, personalization and re-ranking
+	// stages, and change without a redeploy
+	var flagSource flags.Source = cfg
+	switch cfg.FeatureFlags.Source {
+	case config.FlagSourceFile:
+		flagSource = flags.File(cfg.FeatureFlags.File)
+	case config.FlagSourceRedis:
+		flagSource = redis.NewFlagSource(redisClient, cfg.FeatureFlags.RedisKey)
+	}
+	featureFlags := flags.NewEvaluator(flagSource)
+	if err := featureFlags.Refresh(context.Background()); err != nil {
+		logger.Warn("Failed to load feature flags; every flag is on until they load", zap.Error(err))
+	}
+	searchService.SetFlags(featureFlags)
+	recommendationService.SetFlags(featureFlags)
+
+	analyticsProducer := analytics.NewProducer(
+		cfg.AnalyticsHub,
+		logger,
+		metrics,
+	)
+	searchService.SetAnalytics(analyticsProducer)
+	recommendationService.SetAnalytics(analyticsProducer)
+
+	analyticsAggregator := analytics.NewAggregator(
+		pgPool,
+		redisClient,
+		cfg,
+		logger,
+	)
+	analyticsReporter := analytics.NewReporter(pgPool)
+	relatedSearches := analytics.NewRelated(pgPool, redisClient, cfg.AnalyticsHub.Related, logger)
+
+	slaMonitor := sla.NewMonitor(
+		esClient,
+		redisClient,
+		cfg.SLAMonitoring,
+		logger,
+		metrics,
+	)
+	var breachPublisher *sla.BreachPublisher
+	if cfg.SLAMonitoring.Enabled && cfg.SLAMonitoring.BreachTopic != "" {
+		breachPublisher = sla.NewBreachPublisher(cfg.SLAMonitoring.KafkaBrokers, cfg.SLAMonitoring.BreachTopic)
+		slaMonitor.SetBreaches(breachPublisher)
+	}
+
+	exporter := export.NewExporter(
+		searchService,
+		redisClient,
+		cfg.Export,
+		logger,
+	)
+
+	// Catalog snapshots go to S3 for partner syndication and disaster recovery
+	var snapshotter *snapshot.Snapshotter
+	if cfg.Snapshots.Enabled {
+		store, err := snapshot.NewS3Store(context.Background(), cfg.Snapshots)
+		if err != nil {
+			logger.Fatal("Failed to create snapshot store", zap.Error(err))
+		}
+		snapshotter = snapshot.NewSnapshotter(esClient, redisClient, store, cfg.Snapshots, cfg.Elasticsearch.IndexName, logger)
+	}
+
+	dispatcher := webhook.NewDispatcher(
+		pgPool,
+		cfg.Webhooks,
+		logger,
+		metrics,
+	)
+	var notifiers search.ChangeNotifiers
+	if cfg.Webhooks.Enabled {
+		notifiers = append(notifiers, dispatcher)
+	}
+
+	// Partners poll the feed for catalog changes, or have them pushed
+	var changeFeed *feed.Feed
+	var feedPusher *feed.Pusher
+	if cfg.Feed.Enabled {
+		changeFeed = feed.NewFeed(feed.NewStore(pgPool), esClient, cfg.Feed, logger)
+		notifiers = append(notifiers, changeFeed)
+		feedPusher, err = feed.NewPusher(changeFeed, redisClient, cfg.Feed, logger, metrics)
+		if err != nil {
+			logger.Fatal("Failed to create feed pusher", zap.Error(err))
+		}
+	}
+	searchService.SetChangeNotifier(notifiers)
+
+	// Services registered with the registry are indexed from its catalog topic
+	catalogConsumer := catalog.NewConsumer(
+		cfg.Catalog,
+		catalog.NewStore(pgPool),
+		esClient,
+		searchService,
+		logger,
+	)
+
+	gqlSchema, err := graphql.ParseSchema(searchService, recommendationService)
+	if err != nil {
+		logger.Fatal("Failed to parse GraphQL schema", zap.Error(err))
+	}
+	gqlHandler := graphql.NewHandler(gqlSchema, esClient, redisClient, cfg, logger)
+
+	// Background work is drained when the server shuts down
+	workers := worker.NewGroup(logger)
+	searchService.SetWorkers(workers)
+	exporter.SetWorkers(workers)
+	if snapshotter != nil {
+		snapshotter.SetWorkers(workers)
+	}
+
+	// A cluster can answer pings before it accepts index changes. Rather than
+	// exit, keep retrying in the background; elasticsearch stays unready until
+	// the indices exist.
+	logger.Info("Initializing Elasticsearch indices...")
+	var indicesReady, cachesWarm atomic.Bool
+	// Once the indices exist, prime the caches before reporting ready
+	warmUp := func(ctx context.Context) {
+		defer cachesWarm.Store(true)
+		warmup := cfg.Search.Warmup
+		if !warmup.Enabled {
+			return
+		}
+		ctx, cancel := context.WithTimeout(ctx, warmup.Timeout)
+		defer cancel()
+		queries, err := searchService.PopularQueries(ctx, warmup.TopQueries)
+		if err != nil {
+			logger.Warn("Failed to load popular queries to warm the cache", zap.Error(err))
+		}
+		searchService.Warm(ctx, queries)
+	}
+	if err := setupIndices(context.Background()); err != nil {
+		logger.Warn("Elasticsearch indices not ready, retrying in the background", zap.Error(err))
+		workers.Go("elasticsearch_indices", func(ctx context.Context) {
+			if waiter.Retry(ctx, "elasticsearch indices", setupIndices) == nil {
+				indicesReady.Store(true)
+				logger.Info("Elasticsearch indices ready")
+				warmUp(ctx)
+			}
+		})
+	} else {
+		indicesReady.Store(true)
+		workers.Go("cache_warmup", warmUp)
+	}
+	esCheck := func(ctx context.Context) (time.Duration, error) {
+		if !indicesReady.Load() {
+			return 0, errIndicesNotReady
+		}
+		return esHealth.Check(ctx)
+	}
+
+	// Readiness; per-dependency settings in server.readiness override these
+	dependencies := []health.Dependency{
+		{Name: "postgres", Check: health.Ping(pgPool.Ping), Critical: true, SlowThreshold: 100 * time.Millisecond},
+		{Name: "redis", Check: health.Ping(func(ctx context.Context) error { return redisClient.Ping(ctx).Err() }), SlowThreshold: 50 * time.Millisecond},
+		{Name: "elasticsearch", Check: esCheck, Critical: true, SlowThreshold: 500 * time.Millisecond},
+	}
+	// The semantic clause is dropped while the embedding service is down, so
+	// searches still work and it only degrades readiness
+	var embeddingHealth *search.EmbeddingHealth
+	if cfg.Search.SemanticEnabled && cfg.EmbeddingService.Backend != search.EmbeddingBackendLocal {
+		embeddingHealth = search.NewEmbeddingHealth(cfg.EmbeddingService, logger)
+		searchService.SetEmbeddingHealth(embeddingHealth)
+		dependencies = append(dependencies, health.Dependency{Name: "embedding_service", Check: embeddingHealth.Check, SlowThreshold: 200 * time.Millisecond})
+	}
+	if cfg.Search.Warmup.Enabled {
+		dependencies = append(dependencies, health.Dependency{Name: "cache_warmup", Critical: true, Check: health.Ping(func(context.Context) error {
+			if !cachesWarm.Load() {
+				return errCachesNotWarm
+			}
+			return nil
+		})})
+	}
+	readiness := health.NewMonitor(dependencies, cfg.Server.Readiness, logger, metrics)
+
+	if cfg.Server.HotReload {
+		if configPath == "" {
+			logger.Warn("server.hot_reload is set but there is no config file to watch")
+		} else {
+			workers.Go("config_watcher", config.NewWatcher(configPath, cfg, logger).Start)
+		}
+	}
+
+	workers.Go("secrets_refresh", secretsManager.Start)
+	workers.Go("elasticsearch_health", esHealth.Start)
+	if embeddingHealth != nil {
+		workers.Go("embedding_health", embeddingHealth.Start)
+	}
+	workers.Go("readiness", readiness.Start)
+	workers.Go("sla_monitor", slaMonitor.Start)
+	workers.Go("export_cleanup", exporter.Start)
+	if snapshotter != nil {
+		workers.Go("catalog_snapshots", snapshotter.Start)
+	}
+	workers.Go("webhook_dispatcher", dispatcher.Start)
+	if changeFeed != nil {
+		workers.Go("feed_pruning", changeFeed.Start)
+		workers.Go("feed_push", feedPusher.Start)
+	}
+	workers.Go("analytics_aggregator", analyticsAggregator.Start)
+	workers.Go("related_searches", relatedSearches.Start)
+	workers.Go("feature_materializer", featureStore.Start)
+	workers.Go("feature_flags", func(ctx context.Context) {
+		featureFlags.Run(ctx, cfg.FeatureFlags.RefreshInterval, func(err error) {
+			logger.Warn("Failed to refresh feature flags; keeping the current ones", zap.Error(err))
+		})
+	})
+	workers.Go("privacy", privacy.NewJob(pgPool, cfg.Privacy, logger).Start)
+	workers.Go("catalog_consumer", catalogConsumer.Start)
 
 	// Initialize API server
 	if cfg.Server.Mode == "production" {
@@ -101,12 +501,18 @@ func main() {
 	}
 
 	router := gin.New()
+	router.HandleMethodNotAllowed = true
 	router.Use(
-		observability.GinLogger(logger),
+		observability.GinRequestID(),
+		observability.GinLogger(logger, cfg.Observability.Logging.Access),
 		observability.GinRecovery(logger),
 		observability.GinTracing(),
 		observability.GinMetrics(metrics),
+		api.Entitlements(cfg.Entitlements),
 	)
+	if cfg.Performance.CompressionEnabled {
+		router.Use(api.Compression(cfg.Performance.CompressionMinSize))
+	}
 
 	// Health checks
 	router.GET("/health", func(c *gin.Context) {
@@ -116,48 +522,36 @@ func main() {
 		})
 	})
 
-	router.GET("/ready", func(c *gin.Context) {
-		// Check all dependencies
-		ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
-		defer cancel()
+	router.GET("/ready", readiness.Handler())
 
-		checks := map[string]bool{
-			"postgres": pgPool.Ping(ctx) == nil,
-			"redis": redisClient.Ping(ctx).Err() == nil,
-			"elasticsearch": esClient.Ping() == nil,
-		}
-
-		allHealthy := true
-		for _, healthy := range checks {
-			if !healthy {
-				allHealthy = false
-				break
-			}
-		}
-
-		status := http.StatusOK
-		if !allHealthy {
-			status = http.StatusServiceUnavailable
-		}
+	// API routes
+	quotas := quota.NewService(redisClient, cfg.Quotas, logger)
+	api.RegisterRoutes(router, searchService, recommendationService, slaMonitor, exporter, snapshotter, changeFeed, dispatcher, analyticsProducer, analyticsReporter, relatedSearches, taxonomyManager, quotas, logger, metrics)
 
-		c.JSON(status, gin.H{
-			"status": allHealthy,
-			"checks": checks,
-			"timestamp": time.Now().UTC(),
-		})
+	router.NoRoute(func(c *gin.Context) {
+		problem.Abort(c, problem.NotFound, "No route matches "+c.Request.URL.Path)
+	})
+	router.NoMethod(func(c *gin.Context) {
+		problem.Abort(c, problem.MethodNotAllowed, c.Request.Method+" is not supported on "+c.Request.URL.Path)
 	})
 
-	// API routes
-	api.RegisterRoutes(router, searchService, recommendationService, logger, metrics)
+	// GraphQL
+	api.RegisterGraphQL(router, gqlHandler.Handle, quotas, logger)
 
 	// Start metrics server
-	go func() {
-		metricsAddr := fmt.Sprintf(":%d", cfg.Observability.Metrics.Port)
-		logger.Info("Starting metrics server", zap.String("address", metricsAddr))
-		if err := observability.ServeMetrics(metricsAddr); err != nil {
-			logger.Error("Metrics server failed", zap.Error(err))
-		}
-	}()
+	var metricsServer *observability.MetricsServer
+	if cfg.Observability.Metrics.Enabled {
+		metricsServer = observability.NewMetricsServer(cfg.Observability.Metrics)
+		go func() {
+			logger.Info("Starting metrics server",
+				zap.String("address", metricsServer.Addr()),
+				zap.Bool("tls", cfg.Observability.Metrics.TLS.Enabled()),
+			)
+			if err := metricsServer.Serve(); err != nil {
+				logger.Error("Metrics server failed", zap.Error(err))
+			}
+		}()
+	}
 
 	// Start main HTTP server
 	addr := fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Server.Port)
@@ -184,12 +578,45 @@ func main() {
 
 	logger.Info("Shutting down server...")
 
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	shutdownTimeout := cfg.Server.ShutdownTimeout
+	if shutdownTimeout <= 0 {
+		shutdownTimeout = 30 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
 	defer cancel()
 
 	if err := server.Shutdown(ctx); err != nil {
 		logger.Error("Server forced to shutdown", zap.Error(err))
 	}
 
+	// Requests have finished; stop workers and let jobs they started complete
+	drainTimeout := cfg.Server.DrainTimeout
+	if drainTimeout <= 0 {
+		drainTimeout = 15 * time.Second
+	}
+	drainCtx, cancelDrain := context.WithTimeout(context.Background(), drainTimeout)
+	defer cancelDrain()
+
+	if err := workers.Shutdown(drainCtx); err != nil {
+		logger.Error("Background work did not drain", zap.Error(err))
+	}
+
+	// Flush events recorded by requests and background work
+	if err := analyticsProducer.Close(drainCtx); err != nil {
+		logger.Error("Failed to flush analytics events", zap.Error(err))
+	}
+	if breachPublisher != nil {
+		if err := breachPublisher.Close(); err != nil {
+			logger.Error("Failed to flush SLA breaches", zap.Error(err))
+		}
+	}
+
+	// Metrics stay scrapeable until everything else has stopped
+	if metricsServer != nil {
+		if err := metricsServer.Shutdown(drainCtx); err != nil {
+			logger.Error("Metrics server forced to shutdown", zap.Error(err))
+		}
+	}
+
 	logger.Info("Server exited")
 }
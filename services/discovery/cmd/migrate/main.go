@@ -0,0 +1,103 @@
+// Command migrate applies the discovery database migrations, or reports
+// whether the database is behind or has drifted from them.
+//
+//	migrate [-config config.yaml] up|status
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/org/llm-marketplace/pkg/marketplace/secrets"
+	"github.com/org/llm-marketplace/services/discovery/internal/config"
+	"github.com/org/llm-marketplace/services/discovery/internal/migrations"
+	"github.com/org/llm-marketplace/services/discovery/internal/observability"
+	"github.com/org/llm-marketplace/services/discovery/internal/postgres"
+	"github.com/org/llm-marketplace/services/discovery/internal/startup"
+)
+
+func main() {
+	configPath := flag.String("config", config.Path(), "Config file holding the Postgres connection; DISCOVERY_* variables override it")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: migrate [-config file] up|status\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	command := flag.Arg(0)
+	if flag.NArg() != 1 || (command != "up" && command != "status") {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load configuration: %v\n", err)
+		os.Exit(2)
+	}
+	logger, err := observability.NewLogger(cfg.Observability.Logging)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to initialize logger: %v\n", err)
+		os.Exit(2)
+	}
+	defer logger.Sync()
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	password, err := secrets.NewManager(cfg.Secrets, nil).Password(ctx, cfg.Postgres.Password)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to resolve the PostgreSQL password: %v\n", err)
+		os.Exit(2)
+	}
+
+	// Run as a job alongside a database that may still be starting
+	var pgPool *postgres.Pool
+	err = startup.NewWaiter(cfg.Server.Startup, logger).Wait(ctx, "postgres", func(context.Context) (err error) {
+		pgPool, err = postgres.NewPool(cfg.Postgres, password)
+		return err
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to connect to PostgreSQL: %v\n", err)
+		os.Exit(2)
+	}
+	defer pgPool.Close()
+
+	migrator, err := migrations.New(pgPool, logger)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(2)
+	}
+
+	if command == "up" {
+		applied, err := migrator.Up(ctx)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Migration failed after applying %d: %v\n", applied, err)
+			os.Exit(1)
+		}
+		fmt.Fprintf(os.Stderr, "Applied %d migrations\n", applied)
+		return
+	}
+
+	status, err := migrator.Status(ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(2)
+	}
+	for _, a := range status.Applied {
+		fmt.Printf("applied  %04d %-30s %s\n", a.Version, a.Name, a.AppliedAt.Format("2006-01-02 15:04:05"))
+	}
+	for _, m := range status.Pending {
+		fmt.Printf("pending  %04d %s\n", m.Version, m.Name)
+	}
+	for _, d := range status.Drift {
+		fmt.Printf("DRIFT    %s\n", d)
+	}
+	if !status.Current() {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,69 @@
+// Command mockembeddings serves the embedding service's HTTP API with vectors
+// hashed from each text's words, so semantic search works locally and in CI
+// without a model. Vectors are sized for the models in config.yaml.
+//
+//	mockembeddings [-config config.yaml] [-addr :8000] [-latency 20ms] [-jitter 10ms] [-fail-rate 0.1]
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/org/llm-marketplace/services/discovery/internal/config"
+	"github.com/org/llm-marketplace/services/discovery/internal/mockserver"
+)
+
+func main() {
+	configPath := flag.String("config", config.Path(), "Config file holding the embedding models and health path")
+	addr := flag.String("addr", ":8000", "Address to listen on")
+	dimensions := flag.Int("dimensions", 0, "Vector length for models not in the config; the query model's if 0")
+	latency := flag.Duration("latency", 0, "Delay added to every embedding request")
+	jitter := flag.Duration("jitter", 0, "Up to this much more delay, drawn per request")
+	failRate := flag.Float64("fail-rate", 0, "Fraction of embedding requests answered with 503")
+	flag.Parse()
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load configuration: %v\n", err)
+		os.Exit(2)
+	}
+
+	queryModel := cfg.QueryEmbeddingModel()
+	opts := mockserver.EmbeddingOptions{
+		Behavior:        mockserver.Behavior{Latency: *latency, Jitter: *jitter, FailureRate: *failRate},
+		Dimensions:      queryModel.Dimensions,
+		Model:           queryModel.Model,
+		HealthPath:      cfg.EmbeddingService.Health.Path,
+		ModelDimensions: make(map[string]int),
+	}
+	if *dimensions > 0 {
+		opts.Dimensions = *dimensions
+	}
+	for _, m := range cfg.EmbeddingModels() {
+		opts.ModelDimensions[m.Model] = m.Dimensions
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	server := &http.Server{Addr: *addr, Handler: mockserver.NewEmbeddings(opts)}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	fmt.Fprintf(os.Stderr, "Mock embedding service listening on %s\n", *addr)
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		fmt.Fprintf(os.Stderr, "Mock embedding service failed: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,67 @@
+// Command mockpolicy serves the policy engine's gRPC API with canned
+// validations, so services can be indexed with policy compliance locally and
+// in CI without the policy engine and its database. Without -responses every
+// service is compliant.
+//
+//	mockpolicy [-addr :50051] [-responses responses.json] [-latency 20ms] [-jitter 10ms] [-fail-rate 0.1]
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"google.golang.org/grpc"
+
+	pb "github.com/org/llm-marketplace/services/discovery/api/proto/policyengine/v1"
+	"github.com/org/llm-marketplace/services/discovery/internal/mockserver"
+)
+
+func main() {
+	addr := flag.String("addr", ":50051", "Address to listen on")
+	responsesPath := flag.String("responses", "", "JSON file of canned validations by service ID or name, with \"*\" for the rest")
+	version := flag.String("policy-version", "mock", "Policy version reported on validations")
+	latency := flag.Duration("latency", 0, "Delay added to every call but health checks")
+	jitter := flag.Duration("jitter", 0, "Up to this much more delay, drawn per call")
+	failRate := flag.Float64("fail-rate", 0, "Fraction of calls answered with UNAVAILABLE")
+	flag.Parse()
+
+	opts := mockserver.PolicyOptions{
+		Behavior:      mockserver.Behavior{Latency: *latency, Jitter: *jitter, FailureRate: *failRate},
+		PolicyVersion: *version,
+	}
+	if *responsesPath != "" {
+		responses, err := mockserver.LoadPolicyResponses(*responsesPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(2)
+		}
+		opts.Responses = responses
+	}
+
+	ln, err := net.Listen("tcp", *addr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to listen on %s: %v\n", *addr, err)
+		os.Exit(2)
+	}
+
+	server := grpc.NewServer()
+	pb.RegisterPolicyEngineServiceServer(server, mockserver.NewPolicy(opts))
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		server.GracefulStop()
+	}()
+
+	fmt.Fprintf(os.Stderr, "Mock policy engine listening on %s\n", ln.Addr())
+	if err := server.Serve(ln); err != nil {
+		fmt.Fprintf(os.Stderr, "Mock policy engine failed: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,167 @@
+// Command seed populates a development environment with a generated catalog:
+// it indexes the items into Elasticsearch and saves them, with their users'
+// interactions, to PostgreSQL, so search, recommendations and trending all
+// have data to work with. The same -seed always yields the same catalog, and
+// seeding again updates items in place.
+//
+//	seed [-config config.yaml] [-seed 1] [-services 500] [-users 100] [-out catalog.json]
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/org/llm-marketplace/pkg/marketplace/secrets"
+	"github.com/org/llm-marketplace/services/discovery/internal/config"
+	"github.com/org/llm-marketplace/services/discovery/internal/elasticsearch"
+	"github.com/org/llm-marketplace/services/discovery/internal/fixtures"
+	"github.com/org/llm-marketplace/services/discovery/internal/observability"
+	"github.com/org/llm-marketplace/services/discovery/internal/postgres"
+	"github.com/org/llm-marketplace/services/discovery/internal/startup"
+)
+
+func main() {
+	configPath := flag.String("config", config.Path(), "Config file holding the Postgres and Elasticsearch connections")
+	seed := flag.Uint64("seed", 1, "Seed the catalog is generated from")
+	services := flag.Int("services", 500, "Items to generate, of every kind")
+	users := flag.Int("users", 100, "Users to generate interactions for")
+	embeddings := flag.Bool("embeddings", true, "Generate embeddings sized for the query embedding model, so semantic search works without the embedding service")
+	outPath := flag.String("out", "", "Write the catalog as JSON to this path instead of loading it; - for stdout")
+	flag.Parse()
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load configuration: %v\n", err)
+		os.Exit(2)
+	}
+
+	opts := fixtures.Options{Seed: *seed, Services: *services, Users: *users}
+	if *embeddings {
+		model := cfg.QueryEmbeddingModel()
+		opts.Dimensions, opts.EmbeddingModel = model.Dimensions, model.Model
+	}
+	catalog := fixtures.Generate(opts)
+
+	if *outPath != "" {
+		if err := writeCatalog(*outPath, catalog); err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(2)
+		}
+		return
+	}
+
+	logger, err := observability.NewLogger(cfg.Observability.Logging)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to initialize logger: %v\n", err)
+		os.Exit(2)
+	}
+	defer logger.Sync()
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	secretsManager := secrets.NewManager(cfg.Secrets, nil)
+	pgPassword, err := secretsManager.Password(ctx, cfg.Postgres.Password)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to resolve the PostgreSQL password: %v\n", err)
+		os.Exit(2)
+	}
+	esPassword, err := secretsManager.Password(ctx, cfg.Elasticsearch.Password)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to resolve the Elasticsearch password: %v\n", err)
+		os.Exit(2)
+	}
+
+	// Run alongside a docker-compose stack that may still be starting
+	waiter := startup.NewWaiter(cfg.Server.Startup, logger)
+	var pgPool *postgres.Pool
+	err = waiter.Wait(ctx, "postgres", func(context.Context) (err error) {
+		pgPool, err = postgres.NewPool(cfg.Postgres, pgPassword)
+		return err
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to connect to PostgreSQL: %v\n", err)
+		os.Exit(2)
+	}
+	defer pgPool.Close()
+
+	var esClient *elasticsearch.Client
+	err = waiter.Wait(ctx, "elasticsearch", func(context.Context) (err error) {
+		esClient, err = elasticsearch.NewClient(cfg.Elasticsearch, esPassword)
+		return err
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to connect to Elasticsearch: %v\n", err)
+		os.Exit(2)
+	}
+
+	// Map the index before the first bulk request would create it with
+	// dynamic mappings; each step is a no-op on an index the service set up
+	if err := setupIndex(ctx, elasticsearch.NewIndexManager(esClient, cfg.Elasticsearch, logger), cfg); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(2)
+	}
+
+	// Postgres first: interactions reference the services table, and the
+	// service enriches indexed documents from it
+	if err := catalog.Save(ctx, pgPool); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(2)
+	}
+	result, err := catalog.Index(ctx, esClient)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to index catalog: %v\n", err)
+		os.Exit(2)
+	}
+
+	fmt.Fprintf(os.Stderr, "%d items indexed, %d failed; %d interactions from %d users saved\n",
+		result.Indexed, len(result.Failed), len(catalog.Interactions), *users)
+	if len(result.Failed) > 0 {
+		os.Exit(1)
+	}
+}
+
+func setupIndex(ctx context.Context, indexManager *elasticsearch.IndexManager, cfg *config.Config) error {
+	if err := indexManager.CreateIndex(ctx); err != nil {
+		return fmt.Errorf("failed to create index: %w", err)
+	}
+	if err := indexManager.PutVectorFields(ctx, cfg.EmbeddingModels()); err != nil {
+		return fmt.Errorf("failed to map embedding vector fields: %w", err)
+	}
+	if err := indexManager.PutPromptTemplateFields(ctx); err != nil {
+		return fmt.Errorf("failed to map the prompt template fields: %w", err)
+	}
+	if err := indexManager.PutDatasetFields(ctx); err != nil {
+		return fmt.Errorf("failed to map the dataset fields: %w", err)
+	}
+	if err := indexManager.PutFineTuningFields(ctx); err != nil {
+		return fmt.Errorf("failed to map the fine-tuning fields: %w", err)
+	}
+	if err := indexManager.PutServingRegionsField(ctx); err != nil {
+		return fmt.Errorf("failed to map the serving regions field: %w", err)
+	}
+	return nil
+}
+
+func writeCatalog(path string, catalog *fixtures.Catalog) error {
+	out := os.Stdout
+	if path != "-" {
+		f, err := os.Create(path)
+		if err != nil {
+			return fmt.Errorf("failed to create %s: %w", path, err)
+		}
+		defer f.Close()
+		out = f
+	}
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(catalog); err != nil {
+		return fmt.Errorf("failed to write catalog: %w", err)
+	}
+	return nil
+}
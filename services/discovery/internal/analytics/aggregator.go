@@ -0,0 +1,447 @@
+package analytics
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/jackc/pgx/v5"
+	"github.com/org/llm-marketplace/services/discovery/internal/config"
+	"github.com/org/llm-marketplace/services/discovery/internal/postgres"
+	"github.com/segmentio/kafka-go"
+	"go.uber.org/zap"
+)
+
+// latencyBoundsMS are the upper bounds of the latency histogram buckets.
+// Changing them makes older rollups incomparable with new ones.
+var latencyBoundsMS = []int{5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000}
+
+// overflowBoundMS is the bucket for latencies above the last bound
+const overflowBoundMS = math.MaxInt32
+
+// queryIDTTL bounds how long after a search its clicks can be attributed
+const queryIDTTL = 24 * time.Hour
+
+// Reader fetches analytics events; *kafka.Reader satisfies it
+type Reader interface {
+	FetchMessage(ctx context.Context) (kafka.Message, error)
+	CommitMessages(ctx context.Context, msgs ...kafka.Message) error
+	Close() error
+}
+
+// Aggregator consumes analytics events and maintains hourly rollups in PostgreSQL.
+// Offsets are committed only after a rollup flush, so events are counted at least once.
+type Aggregator struct {
+	reader      Reader
+	pgPool      *postgres.Pool
+	redisClient *redis.Client
+	config      config.AggregationConfig
+	related     config.RelatedConfig
+	halfLife    time.Duration
+	logger      *zap.Logger
+}
+
+func NewAggregator(
+	pgPool *postgres.Pool,
+	redisClient *redis.Client,
+	cfg *config.Config,
+	logger *zap.Logger,
+) *Aggregator {
+	hub := cfg.AnalyticsHub
+	a := &Aggregator{
+		pgPool:      pgPool,
+		redisClient: redisClient,
+		config:      hub.Aggregation,
+		related:     hub.Related,
+		halfLife:    cfg.Search.Autocomplete.QueryHalfLife,
+		logger:      logger,
+	}
+
+	if hub.Aggregation.Enabled && len(hub.KafkaBrokers) > 0 {
+		a.reader = kafka.NewReader(kafka.ReaderConfig{
+			Brokers:  hub.KafkaBrokers,
+			Topic:    hub.Topic,
+			GroupID:  hub.Aggregation.ConsumerGroup,
+			MinBytes: 1,
+			MaxBytes: 10 << 20,
+		})
+	}
+
+	return a
+}
+
+// SetReader replaces the Kafka reader
+func (a *Aggregator) SetReader(reader Reader) {
+	a.reader = reader
+}
+
+type queryKey struct {
+	bucket time.Time
+	query  string
+}
+
+type latencyKey struct {
+	bucket  time.Time
+	boundMS int
+}
+
+type pairKey struct {
+	bucket time.Time
+	pair   Pair
+}
+
+type queryCounts struct {
+	searches    int64
+	zeroResults int64
+	clicks      int64
+}
+
+// rollup accumulates counts between flushes
+type rollup struct {
+	queries       map[queryKey]*queryCounts
+	latency       map[latencyKey]int64
+	queryIDs      map[string]string
+	queryPairs    map[pairKey]int64
+	categoryPairs map[pairKey]int64
+	sessions      map[string]*Session // By session key
+	events        int
+}
+
+func newRollup() *rollup {
+	return &rollup{
+		queries:       make(map[queryKey]*queryCounts),
+		latency:       make(map[latencyKey]int64),
+		queryIDs:      make(map[string]string),
+		queryPairs:    make(map[pairKey]int64),
+		categoryPairs: make(map[pairKey]int64),
+		sessions:      make(map[string]*Session),
+	}
+}
+
+func (r *rollup) counts(bucket time.Time, query string) *queryCounts {
+	key := queryKey{bucket: bucket, query: query}
+	c, ok := r.queries[key]
+	if !ok {
+		c = &queryCounts{}
+		r.queries[key] = c
+	}
+	return c
+}
+
+// Start consumes events until ctx is cancelled, then flushes what it has
+func (a *Aggregator) Start(ctx context.Context) {
+	if a.reader == nil {
+		a.logger.Info("Analytics aggregation is disabled")
+		return
+	}
+	defer a.reader.Close()
+
+	a.logger.Info("Analytics aggregator started", zap.Duration("flush_interval", a.config.FlushInterval))
+
+	pending := newRollup()
+	var consumed []kafka.Message
+	flushAt := time.Now().Add(a.config.FlushInterval)
+
+	for {
+		fetchCtx, cancel := context.WithDeadline(ctx, flushAt)
+		msg, err := a.reader.FetchMessage(fetchCtx)
+		cancel()
+
+		switch {
+		case err == nil:
+			a.apply(ctx, pending, msg)
+			consumed = append(consumed, msg)
+		case ctx.Err() != nil:
+			flushCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			if a.flush(flushCtx, pending, consumed) {
+				a.logger.Info("Analytics aggregator stopped")
+			}
+			cancel()
+			return
+		case !errors.Is(err, context.DeadlineExceeded):
+			a.logger.Warn("Failed to fetch analytics event", zap.Error(err))
+			time.Sleep(time.Second)
+		}
+
+		if time.Now().After(flushAt) || pending.events >= a.config.MaxPending {
+			if a.flush(ctx, pending, consumed) {
+				pending = newRollup()
+				consumed = consumed[:0]
+			}
+			flushAt = time.Now().Add(a.config.FlushInterval)
+		}
+	}
+}
+
+// apply folds one event into the pending rollup
+func (a *Aggregator) apply(ctx context.Context, r *rollup, msg kafka.Message) {
+	var event Event
+	if err := json.Unmarshal(msg.Value, &event); err != nil {
+		a.logger.Warn("Skipping malformed analytics event", zap.Int64("offset", msg.Offset), zap.Error(err))
+		return
+	}
+	if event.SchemaVersion > SchemaVersion {
+		a.logger.Warn("Skipping analytics event with unsupported schema version",
+			zap.Int("schema_version", event.SchemaVersion),
+		)
+		return
+	}
+
+	bucket := event.OccurredAt.UTC().Truncate(time.Hour)
+
+	switch event.Type {
+	case EventSearch:
+		if event.Search == nil {
+			return
+		}
+		query := NormalizeQuery(event.Search.Query)
+		c := r.counts(bucket, query)
+		c.searches++
+		if event.Search.Total == 0 {
+			c.zeroResults++
+		}
+		r.latency[latencyKey{bucket: bucket, boundMS: latencyBound(event.Search.LatencyMS)}]++
+		if event.Search.QueryID != "" {
+			r.queryIDs[event.Search.QueryID] = query
+		}
+		if a.related.Enabled && event.UserID != "" {
+			a.relate(ctx, r, bucket, &event)
+		}
+	case EventClick:
+		if event.Click == nil || event.Click.QueryID == "" {
+			return
+		}
+		query, ok := a.lookupQuery(ctx, r, event.Click.QueryID)
+		if !ok {
+			return
+		}
+		r.counts(bucket, query).clicks++
+	default:
+		return
+	}
+
+	r.events++
+}
+
+// relate pairs a search with the user's earlier searches in the session.
+// Queries that found nothing aren't related to others.
+func (a *Aggregator) relate(ctx context.Context, r *rollup, bucket time.Time, event *Event) {
+	query := NormalizeQuery(event.Search.Query)
+	if event.Search.Total == 0 {
+		query = ""
+	}
+	var filters struct {
+		Categories []string `json:"categories"`
+	}
+	if len(event.Search.Filters) > 0 {
+		if err := json.Unmarshal(event.Search.Filters, &filters); err != nil {
+			a.logger.Debug("Skipping unreadable search filters", zap.Error(err))
+		}
+	}
+
+	session := a.session(ctx, r, event.UserID)
+	queries, categories := session.Observe(query, filters.Categories, event.OccurredAt, a.related.SessionWindow)
+	for _, pair := range queries {
+		r.queryPairs[pairKey{bucket: bucket, pair: pair}]++
+	}
+	for _, pair := range categories {
+		r.categoryPairs[pairKey{bucket: bucket, pair: pair}]++
+	}
+}
+
+// session returns the user's session, from this batch or an earlier one
+func (a *Aggregator) session(ctx context.Context, r *rollup, userID string) *Session {
+	key := sessionKey(userID)
+	if session, ok := r.sessions[key]; ok {
+		return session
+	}
+
+	session := &Session{}
+	data, err := a.redisClient.Get(ctx, key).Bytes()
+	switch {
+	case err == nil:
+		if err := json.Unmarshal(data, session); err != nil {
+			a.logger.Warn("Discarding unreadable search session", zap.Error(err))
+			session = &Session{}
+		}
+	case err != redis.Nil:
+		a.logger.Warn("Failed to load search session", zap.Error(err))
+	}
+	r.sessions[key] = session
+	return session
+}
+
+// lookupQuery resolves the query a click belongs to, from this batch or an earlier one
+func (a *Aggregator) lookupQuery(ctx context.Context, r *rollup, queryID string) (string, bool) {
+	if query, ok := r.queryIDs[queryID]; ok {
+		return query, true
+	}
+
+	query, err := a.redisClient.Get(ctx, queryIDKey(queryID)).Result()
+	if err != nil {
+		if err != redis.Nil {
+			a.logger.Warn("Failed to look up query for click", zap.Error(err))
+		}
+		return "", false
+	}
+	return query, true
+}
+
+// flush writes the rollup and commits the consumed offsets; it reports whether both succeeded
+func (a *Aggregator) flush(ctx context.Context, r *rollup, consumed []kafka.Message) bool {
+	if len(consumed) == 0 {
+		return true
+	}
+
+	if err := a.writeRollup(ctx, r); err != nil {
+		a.logger.Error("Failed to write analytics rollup", zap.Int("events", r.events), zap.Error(err))
+		return false
+	}
+
+	if err := a.reader.CommitMessages(ctx, consumed...); err != nil {
+		// The rollup is already written; these events will be counted again on redelivery
+		a.logger.Error("Failed to commit analytics offsets", zap.Error(err))
+	}
+
+	return true
+}
+
+func (a *Aggregator) writeRollup(ctx context.Context, r *rollup) error {
+	tx, err := a.pgPool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	for key, c := range r.queries {
+		_, err := tx.Exec(ctx, `
+			INSERT INTO search_query_rollups (bucket, query, searches, zero_results, clicks)
+			VALUES ($1, $2, $3, $4, $5)
+			ON CONFLICT (bucket, query) DO UPDATE SET
+				searches = search_query_rollups.searches + EXCLUDED.searches,
+				zero_results = search_query_rollups.zero_results + EXCLUDED.zero_results,
+				clicks = search_query_rollups.clicks + EXCLUDED.clicks
+		`, key.bucket, key.query, c.searches, c.zeroResults, c.clicks)
+		if err != nil {
+			return fmt.Errorf("failed to upsert query rollup: %w", err)
+		}
+
+		if err := a.recordSuggestion(ctx, tx, key, c); err != nil {
+			return err
+		}
+	}
+
+	for key, count := range r.latency {
+		_, err := tx.Exec(ctx, `
+			INSERT INTO search_latency_rollups (bucket, le_ms, count)
+			VALUES ($1, $2, $3)
+			ON CONFLICT (bucket, le_ms) DO UPDATE SET
+				count = search_latency_rollups.count + EXCLUDED.count
+		`, key.bucket, key.boundMS, count)
+		if err != nil {
+			return fmt.Errorf("failed to upsert latency rollup: %w", err)
+		}
+	}
+
+	for key, count := range r.queryPairs {
+		_, err := tx.Exec(ctx, `
+			INSERT INTO query_cooccurrence_rollups (bucket, query_a, query_b, count)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT (bucket, query_a, query_b) DO UPDATE SET
+				count = query_cooccurrence_rollups.count + EXCLUDED.count
+		`, key.bucket, key.pair.A, key.pair.B, count)
+		if err != nil {
+			return fmt.Errorf("failed to upsert query co-occurrence: %w", err)
+		}
+	}
+
+	for key, count := range r.categoryPairs {
+		_, err := tx.Exec(ctx, `
+			INSERT INTO category_cooccurrence_rollups (bucket, category_a, category_b, count)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT (bucket, category_a, category_b) DO UPDATE SET
+				count = category_cooccurrence_rollups.count + EXCLUDED.count
+		`, key.bucket, key.pair.A, key.pair.B, count)
+		if err != nil {
+			return fmt.Errorf("failed to upsert category co-occurrence: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit rollup: %w", err)
+	}
+
+	// Remember query IDs so clicks arriving in later batches can be attributed
+	if len(r.queryIDs) > 0 {
+		pipe := a.redisClient.Pipeline()
+		for id, query := range r.queryIDs {
+			pipe.Set(ctx, queryIDKey(id), query, queryIDTTL)
+		}
+		if _, err := pipe.Exec(ctx); err != nil {
+			a.logger.Warn("Failed to store query IDs", zap.Error(err))
+		}
+	}
+
+	// Sessions carry over to the next batch until they lapse
+	if len(r.sessions) > 0 {
+		pipe := a.redisClient.Pipeline()
+		for key, session := range r.sessions {
+			data, err := json.Marshal(session)
+			if err != nil {
+				continue
+			}
+			pipe.Set(ctx, key, data, a.related.SessionWindow)
+		}
+		if _, err := pipe.Exec(ctx); err != nil {
+			a.logger.Warn("Failed to store search sessions", zap.Error(err))
+		}
+	}
+
+	return nil
+}
+
+// recordSuggestion adds successful searches to the query's decayed popularity for autocomplete
+func (a *Aggregator) recordSuggestion(ctx context.Context, tx pgx.Tx, key queryKey, c *queryCounts) error {
+	successful := c.searches - c.zeroResults
+	if key.query == "" || successful <= 0 || a.halfLife <= 0 {
+		return nil
+	}
+
+	_, err := tx.Exec(ctx, `
+		INSERT INTO query_suggestions (query, score, searches, last_seen_at)
+		VALUES ($1, $2, $2, $3)
+		ON CONFLICT (query) DO UPDATE SET
+			score = query_suggestions.score * power(0.5,
+				GREATEST(EXTRACT(EPOCH FROM (EXCLUDED.last_seen_at - query_suggestions.last_seen_at)), 0) / $4
+			) + EXCLUDED.score,
+			searches = query_suggestions.searches + EXCLUDED.searches,
+			last_seen_at = GREATEST(query_suggestions.last_seen_at, EXCLUDED.last_seen_at)
+	`, key.query, successful, key.bucket, a.halfLife.Seconds())
+	if err != nil {
+		return fmt.Errorf("failed to upsert query suggestion: %w", err)
+	}
+	return nil
+}
+
+// NormalizeQuery folds case and whitespace so equivalent queries roll up together
+func NormalizeQuery(query string) string {
+	return strings.Join(strings.Fields(strings.ToLower(query)), " ")
+}
+
+func latencyBound(latencyMS float64) int {
+	for _, bound := range latencyBoundsMS {
+		if latencyMS <= float64(bound) {
+			return bound
+		}
+	}
+	return overflowBoundMS
+}
+
+func queryIDKey(id string) string {
+	return "analytics:query:" + id
+}
@@ -0,0 +1,98 @@
+package analytics
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+)
+
+// SchemaVersion is bumped whenever a field is removed or changes meaning.
+// Adding optional fields does not require a new version.
+const SchemaVersion = 1
+
+// Event types
+const (
+	EventSearch                   = "search"
+	EventClick                    = "click"
+	EventRecommendationImpression = "recommendation_impression"
+)
+
+// Event is the envelope published to the analytics topic; exactly one payload is set
+type Event struct {
+	ID            string    `json:"event_id"`
+	Type          string    `json:"event_type"`
+	SchemaVersion int       `json:"schema_version"`
+	OccurredAt    time.Time `json:"occurred_at"`
+	UserID        string    `json:"user_id,omitempty"`
+
+	Search     *SearchEvent     `json:"search,omitempty"`
+	Click      *ClickEvent      `json:"click,omitempty"`
+	Impression *ImpressionEvent `json:"impression,omitempty"`
+}
+
+// SearchEvent records an executed search
+type SearchEvent struct {
+	QueryID   string          `json:"query_id"`
+	Query     string          `json:"query"`
+	Filters   json.RawMessage `json:"filters,omitempty"`
+	Total     int             `json:"total"`
+	ResultIDs []string        `json:"result_ids"`
+	Page      int             `json:"page"`
+	PageSize  int             `json:"page_size"`
+	LatencyMS float64         `json:"latency_ms"`
+	CacheHit  bool            `json:"cache_hit"`
+}
+
+// ClickEvent records a click on a search result
+type ClickEvent struct {
+	QueryID   string `json:"query_id,omitempty"`
+	ServiceID string `json:"service_id"`
+	Position  int    `json:"position"`
+}
+
+// ImpressionEvent records recommendations shown to a user
+type ImpressionEvent struct {
+	Source     string   `json:"source"`              // recommendations, trending, similar or slate
+	Placement  string   `json:"placement,omitempty"` // The slate placement shown
+	Algorithm  string   `json:"algorithm,omitempty"`
+	ServiceIDs []string `json:"service_ids"`
+}
+
+// NewSearchEvent wraps a search payload in an envelope
+func NewSearchEvent(userID string, search *SearchEvent) *Event {
+	e := newEvent(EventSearch, userID)
+	e.Search = search
+	return e
+}
+
+// NewClickEvent wraps a click payload in an envelope
+func NewClickEvent(userID string, click *ClickEvent) *Event {
+	e := newEvent(EventClick, userID)
+	e.Click = click
+	return e
+}
+
+// NewImpressionEvent wraps a recommendation impression in an envelope
+func NewImpressionEvent(userID string, impression *ImpressionEvent) *Event {
+	e := newEvent(EventRecommendationImpression, userID)
+	e.Impression = impression
+	return e
+}
+
+func newEvent(eventType, userID string) *Event {
+	return &Event{
+		ID:            NewID(),
+		Type:          eventType,
+		SchemaVersion: SchemaVersion,
+		OccurredAt:    time.Now().UTC(),
+		UserID:        userID,
+	}
+}
+
+// NewID returns a random 128-bit hex identifier for events and queries
+func NewID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,217 @@
+package analytics
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/org/llm-marketplace/services/discovery/internal/config"
+	"github.com/org/llm-marketplace/services/discovery/internal/observability"
+	"github.com/segmentio/kafka-go"
+	"go.uber.org/zap"
+)
+
+// writeTimeout bounds a single batch write to the brokers
+const writeTimeout = 10 * time.Second
+
+// Writer publishes messages; *kafka.Writer satisfies it
+type Writer interface {
+	WriteMessages(ctx context.Context, msgs ...kafka.Message) error
+	Close() error
+}
+
+// Producer buffers analytics events and publishes them to Kafka in batches.
+// Tracking never blocks request handling: events are dropped when the buffer is full.
+// A nil or disabled Producer accepts and discards events.
+type Producer struct {
+	writer  Writer
+	config  config.AnalyticsHubConfig
+	logger  *zap.Logger
+	metrics *observability.Metrics
+
+	events    chan *Event
+	done      chan struct{}
+	stopped   chan struct{}
+	closeOnce sync.Once
+	mu        sync.RWMutex
+	closed    bool
+}
+
+func NewProducer(
+	cfg config.AnalyticsHubConfig,
+	logger *zap.Logger,
+	metrics *observability.Metrics,
+) *Producer {
+	if !cfg.Enabled || len(cfg.KafkaBrokers) == 0 {
+		return NewProducerWithWriter(cfg, nil, logger, metrics)
+	}
+
+	return NewProducerWithWriter(cfg, &kafka.Writer{
+		Addr:      kafka.TCP(cfg.KafkaBrokers...),
+		Topic:     cfg.Topic,
+		Balancer:  &kafka.Hash{},
+		BatchSize: cfg.BatchSize,
+		// Batching happens in run; don't let the writer hold partial batches back
+		BatchTimeout: 10 * time.Millisecond,
+		RequiredAcks: kafka.RequireOne,
+		Compression:  kafka.Snappy,
+	}, logger, metrics)
+}
+
+// NewProducerWithWriter creates a producer that publishes through writer;
+// a nil writer disables publishing
+func NewProducerWithWriter(
+	cfg config.AnalyticsHubConfig,
+	writer Writer,
+	logger *zap.Logger,
+	metrics *observability.Metrics,
+) *Producer {
+	p := &Producer{
+		config:  cfg,
+		logger:  logger,
+		metrics: metrics,
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+
+	if writer == nil {
+		logger.Info("Analytics event publishing is disabled")
+		close(p.stopped)
+		return p
+	}
+
+	p.writer = writer
+	p.events = make(chan *Event, cfg.BufferSize)
+
+	go p.run()
+
+	return p
+}
+
+// Track queues an event for publishing
+func (p *Producer) Track(event *Event) {
+	if p == nil || p.writer == nil {
+		return
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.closed {
+		return
+	}
+
+	select {
+	case p.events <- event:
+	default:
+		p.metrics.AnalyticsEvent(event.Type, "dropped")
+	}
+}
+
+// Close stops accepting events, flushes the buffer and closes the Kafka writer
+func (p *Producer) Close(ctx context.Context) error {
+	if p == nil {
+		return nil
+	}
+
+	p.closeOnce.Do(func() {
+		p.mu.Lock()
+		p.closed = true
+		p.mu.Unlock()
+		close(p.done)
+	})
+
+	select {
+	case <-p.stopped:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	if p.writer == nil {
+		return nil
+	}
+	return p.writer.Close()
+}
+
+func (p *Producer) run() {
+	defer close(p.stopped)
+
+	ticker := time.NewTicker(p.config.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]*Event, 0, p.config.BatchSize)
+
+	for {
+		select {
+		case event := <-p.events:
+			batch = append(batch, event)
+			if len(batch) >= p.config.BatchSize {
+				batch = p.flush(batch)
+			}
+		case <-ticker.C:
+			batch = p.flush(batch)
+		case <-p.done:
+			// Track no longer sends once done is closed, so the buffer can be drained
+			for {
+				select {
+				case event := <-p.events:
+					batch = append(batch, event)
+					if len(batch) >= p.config.BatchSize {
+						batch = p.flush(batch)
+					}
+				default:
+					p.flush(batch)
+					p.logger.Info("Analytics producer stopped")
+					return
+				}
+			}
+		}
+	}
+}
+
+// flush publishes batch and returns it emptied for reuse
+func (p *Producer) flush(batch []*Event) []*Event {
+	if len(batch) == 0 {
+		return batch
+	}
+
+	messages := make([]kafka.Message, 0, len(batch))
+	for _, event := range batch {
+		value, err := json.Marshal(event)
+		if err != nil {
+			p.logger.Error("Failed to encode analytics event", zap.String("type", event.Type), zap.Error(err))
+			continue
+		}
+
+		key := event.UserID
+		if key == "" {
+			key = event.ID
+		}
+
+		messages = append(messages, kafka.Message{
+			Key:   []byte(key),
+			Value: value,
+			Time:  event.OccurredAt,
+			Headers: []kafka.Header{
+				{Key: "content-type", Value: []byte("application/json")},
+				{Key: "event-type", Value: []byte(event.Type)},
+				{Key: "schema-version", Value: []byte(strconv.Itoa(event.SchemaVersion))},
+			},
+		})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), writeTimeout)
+	defer cancel()
+
+	result := "sent"
+	if err := p.writer.WriteMessages(ctx, messages...); err != nil {
+		p.logger.Error("Failed to publish analytics events", zap.Int("count", len(messages)), zap.Error(err))
+		result = "failed"
+	}
+	for _, event := range batch {
+		p.metrics.AnalyticsEvent(event.Type, result)
+	}
+
+	return batch[:0]
+}
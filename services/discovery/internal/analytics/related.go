@@ -0,0 +1,296 @@
+package analytics
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/org/llm-marketplace/services/discovery/internal/config"
+	"github.com/org/llm-marketplace/services/discovery/internal/postgres"
+	"go.uber.org/zap"
+)
+
+// maxSessionItems bounds the queries and categories a session remembers
+const maxSessionItems = 10
+
+// Kinds of related items, each with its rollup table and cache key
+const (
+	RelatedCategories = "categories"
+	RelatedQueries    = "queries"
+)
+
+var relatedSources = map[string]struct {
+	table, a, b string
+}{
+	RelatedCategories: {"category_cooccurrence_rollups", "category_a", "category_b"},
+	RelatedQueries:    {"query_cooccurrence_rollups", "query_a", "query_b"},
+}
+
+// Pair is two queries or categories searched in the same session, the lesser first
+type Pair struct {
+	A, B string
+}
+
+func newPair(a, b string) Pair {
+	if b < a {
+		a, b = b, a
+	}
+	return Pair{A: a, B: b}
+}
+
+// Session is what a user recently searched for, to relate their next search to
+type Session struct {
+	Queries    []string  `json:"queries,omitempty"`
+	Categories []string  `json:"categories,omitempty"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+}
+
+// Observe adds a search to the session and returns the query and category
+// pairs it makes: the query with each earlier query, and each category with
+// the others filtered on in this search or earlier ones. A search more than
+// window from the last one starts a new session. An empty query adds only
+// its categories.
+func (s *Session) Observe(query string, categories []string, at time.Time, window time.Duration) (queries, cats []Pair) {
+	if gap := at.Sub(s.LastSeenAt); gap > window || gap < -window {
+		*s = Session{}
+	}
+	if at.After(s.LastSeenAt) {
+		s.LastSeenAt = at
+	}
+
+	if query != "" && !containsString(s.Queries, query) {
+		for _, earlier := range s.Queries {
+			queries = append(queries, newPair(earlier, query))
+		}
+		s.Queries = appendBounded(s.Queries, query)
+	}
+
+	for _, category := range categories {
+		if category == "" || containsString(s.Categories, category) {
+			continue
+		}
+		for _, earlier := range s.Categories {
+			cats = append(cats, newPair(earlier, category))
+		}
+		s.Categories = appendBounded(s.Categories, category)
+	}
+	return queries, cats
+}
+
+// appendBounded appends v, dropping the oldest value past maxSessionItems
+func appendBounded(values []string, v string) []string {
+	values = append(values, v)
+	if len(values) > maxSessionItems {
+		values = values[len(values)-maxSessionItems:]
+	}
+	return values
+}
+
+func containsString(values []string, v string) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
+
+// sessionKey is where a user's session is kept between batches. The user ID
+// is hashed so it isn't stored in the clear.
+func sessionKey(userID string) string {
+	sum := sha256.Sum256([]byte(userID))
+	return "analytics:session:" + hex.EncodeToString(sum[:16])
+}
+
+// RelatedItem is a category or query searched together with another
+type RelatedItem struct {
+	Name  string `json:"name"`
+	Count int64  `json:"count"` // Sessions within the window that searched both
+}
+
+// Related refreshes the related categories and queries cached in Redis from
+// the co-occurrence rollups, and reads them back. Refreshes are idempotent,
+// so replicas running them at once only repeat each other's work.
+type Related struct {
+	pgPool      *postgres.Pool
+	redisClient *redis.Client
+	config      config.RelatedConfig
+	logger      *zap.Logger
+}
+
+func NewRelated(
+	pgPool *postgres.Pool,
+	redisClient *redis.Client,
+	cfg config.RelatedConfig,
+	logger *zap.Logger,
+) *Related {
+	return &Related{
+		pgPool:      pgPool,
+		redisClient: redisClient,
+		config:      cfg,
+		logger:      logger,
+	}
+}
+
+// Start refreshes the cache every refresh_interval until ctx is cancelled
+func (r *Related) Start(ctx context.Context) {
+	if !r.config.Enabled {
+		r.logger.Info("Related searches are disabled")
+		return
+	}
+
+	r.logger.Info("Starting related searches refresh", zap.Duration("interval", r.config.RefreshInterval))
+
+	ticker := time.NewTicker(r.config.RefreshInterval)
+	defer ticker.Stop()
+
+	r.refreshOnce(ctx)
+
+	for {
+		select {
+		case <-ticker.C:
+			r.refreshOnce(ctx)
+		case <-ctx.Done():
+			r.logger.Info("Related searches refresh stopped")
+			return
+		}
+	}
+}
+
+func (r *Related) refreshOnce(ctx context.Context) {
+	if err := r.Refresh(ctx); err != nil {
+		r.logger.Error("Failed to refresh related searches", zap.Error(err))
+	}
+}
+
+// Refresh recomputes every related list from the rollups within the window,
+// then deletes the rollups older than it
+func (r *Related) Refresh(ctx context.Context) error {
+	since := time.Now().Add(-r.config.Window).UTC().Truncate(time.Hour)
+
+	for _, kind := range []string{RelatedCategories, RelatedQueries} {
+		lists, err := r.compute(ctx, kind, since)
+		if err != nil {
+			return err
+		}
+		if err := r.store(ctx, kind, lists); err != nil {
+			return err
+		}
+		r.logger.Debug("Related searches refreshed", zap.String("kind", kind), zap.Int("items", len(lists)))
+	}
+
+	for _, source := range relatedSources {
+		if _, err := r.pgPool.Exec(ctx, "DELETE FROM "+source.table+" WHERE bucket < $1", since); err != nil {
+			return fmt.Errorf("failed to delete old %s: %w", source.table, err)
+		}
+	}
+	return nil
+}
+
+// compute ranks, for every item, the items most often searched with it
+func (r *Related) compute(ctx context.Context, kind string, since time.Time) (map[string][]RelatedItem, error) {
+	source := relatedSources[kind]
+	rows, err := r.pgPool.Query(ctx, fmt.Sprintf(`
+		WITH pairs AS (
+			SELECT %[2]s AS a, %[3]s AS b, SUM(count) AS count
+			FROM %[1]s
+			WHERE bucket >= $1
+			GROUP BY %[2]s, %[3]s
+			HAVING SUM(count) >= $2
+		), both_ways AS (
+			SELECT a AS item, b AS related, count FROM pairs
+			UNION ALL
+			SELECT b, a, count FROM pairs
+		)
+		SELECT item, related, count FROM (
+			SELECT item, related, count,
+				ROW_NUMBER() OVER (PARTITION BY item ORDER BY count DESC, related) AS rank
+			FROM both_ways
+		) ranked
+		WHERE rank <= $3
+		ORDER BY item, rank
+	`, source.table, source.a, source.b), since, r.config.MinCount, r.config.MaxRelated)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute related %s: %w", kind, err)
+	}
+	defer rows.Close()
+
+	lists := make(map[string][]RelatedItem)
+	for rows.Next() {
+		var item string
+		var related RelatedItem
+		if err := rows.Scan(&item, &related.Name, &related.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan related %s: %w", kind, err)
+		}
+		lists[item] = append(lists[item], related)
+	}
+	return lists, rows.Err()
+}
+
+// store replaces the cached lists of kind in one step. They expire if
+// refreshes stop, rather than going stale.
+func (r *Related) store(ctx context.Context, kind string, lists map[string][]RelatedItem) error {
+	key := relatedKey(kind)
+	if len(lists) == 0 {
+		return r.redisClient.Del(ctx, key).Err()
+	}
+
+	fields := make(map[string]interface{}, len(lists))
+	for item, related := range lists {
+		data, err := json.Marshal(related)
+		if err != nil {
+			return fmt.Errorf("failed to encode related %s: %w", kind, err)
+		}
+		fields[item] = data
+	}
+
+	staging := key + ":staging"
+	_, err := r.redisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, staging)
+		pipe.HSet(ctx, staging, fields)
+		pipe.Rename(ctx, staging, key)
+		pipe.Expire(ctx, key, 3*r.config.RefreshInterval)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to cache related %s: %w", kind, err)
+	}
+	return nil
+}
+
+// Categories returns up to limit categories most often searched with category
+func (r *Related) Categories(ctx context.Context, category string, limit int) ([]RelatedItem, error) {
+	return r.lookup(ctx, RelatedCategories, category, limit)
+}
+
+// Queries returns up to limit queries most often searched in the same session as query
+func (r *Related) Queries(ctx context.Context, query string, limit int) ([]RelatedItem, error) {
+	return r.lookup(ctx, RelatedQueries, NormalizeQuery(query), limit)
+}
+
+func (r *Related) lookup(ctx context.Context, kind, item string, limit int) ([]RelatedItem, error) {
+	related := []RelatedItem{}
+	data, err := r.redisClient.HGet(ctx, relatedKey(kind), item).Bytes()
+	if err == redis.Nil {
+		return related, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read related %s: %w", kind, err)
+	}
+
+	if err := json.Unmarshal(data, &related); err != nil {
+		return nil, fmt.Errorf("failed to decode related %s: %w", kind, err)
+	}
+	if limit > 0 && len(related) > limit {
+		related = related[:limit]
+	}
+	return related, nil
+}
+
+func relatedKey(kind string) string {
+	return "related:" + kind
+}
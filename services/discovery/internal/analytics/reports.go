@@ -0,0 +1,254 @@
+package analytics
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/org/llm-marketplace/services/discovery/internal/postgres"
+)
+
+// MaxWindow is the longest reporting window accepted
+const MaxWindow = 90 * 24 * time.Hour
+
+// Reporter answers operator queries from the rollup tables
+type Reporter struct {
+	pgPool *postgres.Pool
+}
+
+func NewReporter(pgPool *postgres.Pool) *Reporter {
+	return &Reporter{pgPool: pgPool}
+}
+
+// QueryStats summarizes one normalized query over a window
+type QueryStats struct {
+	Query          string  `json:"query"`
+	Searches       int64   `json:"searches"`
+	ZeroResults    int64   `json:"zero_results"`
+	Clicks         int64   `json:"clicks"`
+	CTR            float64 `json:"ctr"`
+	ZeroResultRate float64 `json:"zero_result_rate"`
+}
+
+// ZeroResultReport is the share of searches that returned nothing
+type ZeroResultReport struct {
+	Searches    int64        `json:"searches"`
+	ZeroResults int64        `json:"zero_results"`
+	Rate        float64      `json:"rate"`
+	TopQueries  []QueryStats `json:"top_queries"`
+}
+
+// CTRReport is the click-through rate overall and per query
+type CTRReport struct {
+	Searches int64        `json:"searches"`
+	Clicks   int64        `json:"clicks"`
+	CTR      float64      `json:"ctr"`
+	Queries  []QueryStats `json:"queries"`
+}
+
+// LatencyReport holds search latency percentiles in milliseconds, estimated from histogram buckets
+type LatencyReport struct {
+	Count int64   `json:"count"`
+	P50   float64 `json:"p50_ms"`
+	P90   float64 `json:"p90_ms"`
+	P95   float64 `json:"p95_ms"`
+	P99   float64 `json:"p99_ms"`
+}
+
+// TopQueries returns the most frequent non-empty queries
+func (r *Reporter) TopQueries(ctx context.Context, window time.Duration, limit int) ([]QueryStats, error) {
+	return r.queryStats(ctx, window, "", "searches", limit)
+}
+
+// ZeroResults returns the zero-result rate and the queries that most often return nothing
+func (r *Reporter) ZeroResults(ctx context.Context, window time.Duration, limit int) (*ZeroResultReport, error) {
+	searches, zeroResults, _, err := r.totals(ctx, window)
+	if err != nil {
+		return nil, err
+	}
+
+	queries, err := r.queryStats(ctx, window, "HAVING SUM(zero_results) > 0", "zero_results", limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ZeroResultReport{
+		Searches:    searches,
+		ZeroResults: zeroResults,
+		Rate:        ratio(zeroResults, searches),
+		TopQueries:  queries,
+	}, nil
+}
+
+// ClickThrough returns overall CTR and per-query CTR for the most frequent queries
+func (r *Reporter) ClickThrough(ctx context.Context, window time.Duration, limit int) (*CTRReport, error) {
+	searches, _, clicks, err := r.totals(ctx, window)
+	if err != nil {
+		return nil, err
+	}
+
+	queries, err := r.queryStats(ctx, window, "", "searches", limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CTRReport{
+		Searches: searches,
+		Clicks:   clicks,
+		CTR:      ratio(clicks, searches),
+		Queries:  queries,
+	}, nil
+}
+
+// Latency returns search latency percentiles
+func (r *Reporter) Latency(ctx context.Context, window time.Duration) (*LatencyReport, error) {
+	query := `
+		SELECT le_ms, SUM(count)
+		FROM search_latency_rollups
+		WHERE bucket >= date_trunc('hour', NOW() - make_interval(secs => $1))
+		GROUP BY le_ms
+		ORDER BY le_ms
+	`
+
+	rows, err := r.pgPool.Query(ctx, query, window.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("failed to query latency rollups: %w", err)
+	}
+	defer rows.Close()
+
+	var bounds []int
+	var counts []int64
+	var total int64
+	for rows.Next() {
+		var bound int
+		var count int64
+		if err := rows.Scan(&bound, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan latency rollup: %w", err)
+		}
+		bounds = append(bounds, bound)
+		counts = append(counts, count)
+		total += count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return &LatencyReport{
+		Count: total,
+		P50:   percentile(bounds, counts, total, 0.50),
+		P90:   percentile(bounds, counts, total, 0.90),
+		P95:   percentile(bounds, counts, total, 0.95),
+		P99:   percentile(bounds, counts, total, 0.99),
+	}, nil
+}
+
+func (r *Reporter) totals(ctx context.Context, window time.Duration) (searches, zeroResults, clicks int64, err error) {
+	query := `
+		SELECT COALESCE(SUM(searches), 0), COALESCE(SUM(zero_results), 0), COALESCE(SUM(clicks), 0)
+		FROM search_query_rollups
+		WHERE bucket >= date_trunc('hour', NOW() - make_interval(secs => $1))
+	`
+
+	err = r.pgPool.QueryRow(ctx, query, window.Seconds()).Scan(&searches, &zeroResults, &clicks)
+	if err != nil {
+		err = fmt.Errorf("failed to query rollup totals: %w", err)
+	}
+	return searches, zeroResults, clicks, err
+}
+
+// queryStats aggregates per-query rows; having and orderBy are fixed strings, never user input
+func (r *Reporter) queryStats(ctx context.Context, window time.Duration, having, orderBy string, limit int) ([]QueryStats, error) {
+	query := fmt.Sprintf(`
+		SELECT query, SUM(searches) AS searches, SUM(zero_results) AS zero_results, SUM(clicks) AS clicks
+		FROM search_query_rollups
+		WHERE bucket >= date_trunc('hour', NOW() - make_interval(secs => $1)) AND query <> ''
+		GROUP BY query
+		%s
+		ORDER BY %s DESC, query
+		LIMIT $2
+	`, having, orderBy)
+
+	rows, err := r.pgPool.Query(ctx, query, window.Seconds(), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query search rollups: %w", err)
+	}
+	defer rows.Close()
+
+	stats := []QueryStats{}
+	for rows.Next() {
+		var s QueryStats
+		if err := rows.Scan(&s.Query, &s.Searches, &s.ZeroResults, &s.Clicks); err != nil {
+			return nil, fmt.Errorf("failed to scan search rollup: %w", err)
+		}
+		s.CTR = ratio(s.Clicks, s.Searches)
+		s.ZeroResultRate = ratio(s.ZeroResults, s.Searches)
+		stats = append(stats, s)
+	}
+	return stats, rows.Err()
+}
+
+// ParseWindow parses a reporting window such as 1h, 24h, 7d or 30d; empty means 24h
+func ParseWindow(raw string) (time.Duration, error) {
+	if raw == "" {
+		return 24 * time.Hour, nil
+	}
+
+	var window time.Duration
+	if days, ok := strings.CutSuffix(raw, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, fmt.Errorf("invalid window: %s", raw)
+		}
+		window = time.Duration(n) * 24 * time.Hour
+	} else {
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return 0, fmt.Errorf("invalid window: %s", raw)
+		}
+		window = d
+	}
+
+	if window < time.Hour || window > MaxWindow {
+		return 0, fmt.Errorf("window must be between 1h and %dd", int(MaxWindow.Hours()/24))
+	}
+	return window, nil
+}
+
+// percentile interpolates linearly within the histogram bucket holding quantile q
+func percentile(bounds []int, counts []int64, total int64, q float64) float64 {
+	if total == 0 {
+		return 0
+	}
+
+	rank := q * float64(total)
+	var cumulative int64
+	lower := 0.0
+	for i, bound := range bounds {
+		prev := cumulative
+		cumulative += counts[i]
+		if float64(cumulative) < rank {
+			if bound != overflowBoundMS {
+				lower = float64(bound)
+			}
+			continue
+		}
+		if bound == overflowBoundMS {
+			// Nothing is known above the last bound
+			return lower
+		}
+		if counts[i] == 0 {
+			return float64(bound)
+		}
+		return lower + (float64(bound)-lower)*(rank-float64(prev))/float64(counts[i])
+	}
+	return lower
+}
+
+func ratio(part, whole int64) float64 {
+	if whole == 0 {
+		return 0
+	}
+	return float64(part) / float64(whole)
+}
@@ -0,0 +1,118 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/org/llm-marketplace/services/discovery/internal/analytics"
+	"github.com/org/llm-marketplace/services/discovery/internal/observability"
+	"github.com/org/llm-marketplace/services/discovery/internal/problem"
+	"go.uber.org/zap"
+)
+
+// parseReportParams reads ?window= and ?limit=, aborting the request when either is invalid
+func parseReportParams(c *gin.Context) (string, time.Duration, int, bool) {
+	raw := c.DefaultQuery("window", "24h")
+	window, err := analytics.ParseWindow(raw)
+	if err != nil {
+		problem.Abort(c, problem.InvalidRequest, err.Error())
+		return "", 0, 0, false
+	}
+
+	limit := parseIntQuery(c, "limit", 20)
+	if limit < 1 || limit > 500 {
+		problem.Abort(c, problem.InvalidRequest, "limit must be between 1 and 500")
+		return "", 0, 0, false
+	}
+
+	return raw, window, limit, true
+}
+
+// handleTopQueries handles GET /api/v1/analytics/top-queries
+func handleTopQueries(reporter *analytics.Reporter, logger *zap.Logger, metrics *observability.Metrics) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		raw, window, limit, ok := parseReportParams(c)
+		if !ok {
+			return
+		}
+
+		queries, err := reporter.TopQueries(c.Request.Context(), window, limit)
+		if err != nil {
+			logger.Error("Failed to get top queries", zap.Error(err))
+			problem.Abort(c, problem.Internal, "Failed to get top queries")
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"window":  raw,
+			"queries": queries,
+		})
+	}
+}
+
+// handleZeroResults handles GET /api/v1/analytics/zero-results
+func handleZeroResults(reporter *analytics.Reporter, logger *zap.Logger, metrics *observability.Metrics) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		raw, window, limit, ok := parseReportParams(c)
+		if !ok {
+			return
+		}
+
+		report, err := reporter.ZeroResults(c.Request.Context(), window, limit)
+		if err != nil {
+			logger.Error("Failed to get zero-result report", zap.Error(err))
+			problem.Abort(c, problem.Internal, "Failed to get zero-result report")
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"window": raw,
+			"report": report,
+		})
+	}
+}
+
+// handleClickThrough handles GET /api/v1/analytics/ctr
+func handleClickThrough(reporter *analytics.Reporter, logger *zap.Logger, metrics *observability.Metrics) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		raw, window, limit, ok := parseReportParams(c)
+		if !ok {
+			return
+		}
+
+		report, err := reporter.ClickThrough(c.Request.Context(), window, limit)
+		if err != nil {
+			logger.Error("Failed to get click-through report", zap.Error(err))
+			problem.Abort(c, problem.Internal, "Failed to get click-through report")
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"window": raw,
+			"report": report,
+		})
+	}
+}
+
+// handleLatency handles GET /api/v1/analytics/latency
+func handleLatency(reporter *analytics.Reporter, logger *zap.Logger, metrics *observability.Metrics) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		raw, window, _, ok := parseReportParams(c)
+		if !ok {
+			return
+		}
+
+		report, err := reporter.Latency(c.Request.Context(), window)
+		if err != nil {
+			logger.Error("Failed to get latency report", zap.Error(err))
+			problem.Abort(c, problem.Internal, "Failed to get latency report")
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"window": raw,
+			"report": report,
+		})
+	}
+}
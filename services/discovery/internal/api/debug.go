@@ -0,0 +1,30 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/org/llm-marketplace/services/discovery/internal/observability"
+	"github.com/org/llm-marketplace/services/discovery/internal/problem"
+	"github.com/org/llm-marketplace/services/discovery/internal/search"
+	"go.uber.org/zap"
+)
+
+// handleGetDebugCapture handles GET /api/v1/admin/search/debug/:request_id
+func handleGetDebugCapture(svc *search.Service, logger *zap.Logger, metrics *observability.Metrics) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		capture, err := svc.GetDebugCapture(c.Request.Context(), c.Param("request_id"))
+		if err != nil {
+			if errors.Is(err, search.ErrCaptureNotFound) {
+				problem.Abort(c, problem.NotFound, "No debug capture for this request")
+				return
+			}
+			logger.Error("Failed to get search debug capture", zap.Error(err))
+			problem.Abort(c, problem.Internal, "Failed to get search debug capture")
+			return
+		}
+
+		c.JSON(http.StatusOK, capture)
+	}
+}
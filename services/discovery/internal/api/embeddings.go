@@ -0,0 +1,49 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/org/llm-marketplace/services/discovery/internal/observability"
+	"github.com/org/llm-marketplace/services/discovery/internal/problem"
+	"github.com/org/llm-marketplace/services/discovery/internal/search"
+	"go.uber.org/zap"
+)
+
+// handleStartEmbeddingBackfill handles POST /api/v1/admin/embeddings/backfill
+func handleStartEmbeddingBackfill(svc *search.Service, logger *zap.Logger, metrics *observability.Metrics) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		job, err := svc.StartEmbeddingBackfill(c.Request.Context())
+		if err != nil {
+			if errors.Is(err, search.ErrBackfillRunning) {
+				problem.Abort(c, problem.Conflict, "An embedding backfill is already running")
+				return
+			}
+			logger.Error("Failed to start embedding backfill", zap.Error(err))
+			problem.Abort(c, problem.Internal, "Failed to start embedding backfill")
+			return
+		}
+
+		c.Header("Location", "/api/v1/admin/embeddings/backfill/"+job.ID)
+		c.JSON(http.StatusAccepted, job)
+	}
+}
+
+// handleGetEmbeddingBackfill handles GET /api/v1/admin/embeddings/backfill/:id
+func handleGetEmbeddingBackfill(svc *search.Service, logger *zap.Logger, metrics *observability.Metrics) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		job, err := svc.GetEmbeddingBackfill(c.Request.Context(), c.Param("id"))
+		if err != nil {
+			if errors.Is(err, search.ErrBackfillNotFound) {
+				problem.Abort(c, problem.NotFound, "Embedding backfill job not found")
+				return
+			}
+			logger.Error("Failed to get embedding backfill", zap.Error(err))
+			problem.Abort(c, problem.Internal, "Failed to get embedding backfill")
+			return
+		}
+
+		c.JSON(http.StatusOK, job)
+	}
+}
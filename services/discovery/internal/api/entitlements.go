@@ -0,0 +1,37 @@
+package api
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/org/llm-marketplace/services/discovery/internal/config"
+	"github.com/org/llm-marketplace/services/discovery/internal/entitlement"
+)
+
+// Entitlements attaches the caller identity forwarded by the gateway to the
+// request context. Services read it from there to filter restricted documents,
+// so it must run before any route that returns catalog data.
+func Entitlements(cfg config.EntitlementsConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		caller := entitlement.Caller{
+			TenantID: c.GetHeader(cfg.TenantHeader),
+			UserID:   c.GetString("user_id"),
+		}
+		if caller.UserID == "" {
+			caller.UserID = c.GetHeader(cfg.UserHeader)
+			if caller.UserID != "" {
+				c.Set("user_id", caller.UserID)
+			}
+		}
+		if caller.TenantID != "" {
+			c.Set("tenant_id", caller.TenantID)
+		}
+		if operator := c.GetHeader(cfg.OperatorHeader); cfg.OperatorHeader != "" && operator != "" {
+			c.Set("operator_id", operator)
+		}
+		if provider := c.GetHeader(cfg.ProviderHeader); cfg.ProviderHeader != "" && provider != "" {
+			c.Set("provider_id", provider)
+		}
+
+		c.Request = c.Request.WithContext(entitlement.WithCaller(c.Request.Context(), caller))
+		c.Next()
+	}
+}
@@ -0,0 +1,65 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/org/llm-marketplace/services/discovery/internal/analytics"
+	"github.com/org/llm-marketplace/services/discovery/internal/observability"
+	"github.com/org/llm-marketplace/services/discovery/internal/problem"
+	"github.com/org/llm-marketplace/services/discovery/internal/search"
+	"go.uber.org/zap"
+)
+
+// clickRequest reports a click on a search result
+type clickRequest struct {
+	QueryID   string `json:"query_id"`
+	ServiceID string `json:"service_id" binding:"required"`
+	Position  int    `json:"position" binding:"min=0"`
+	SessionID string `json:"session_id"`
+}
+
+// dismissRequest reports a search result the caller passed over
+type dismissRequest struct {
+	SessionID string `json:"session_id" binding:"required"`
+	ServiceID string `json:"service_id" binding:"required"`
+}
+
+// handleTrackClick handles POST /api/v1/events/click
+func handleTrackClick(svc *search.Service, producer *analytics.Producer, logger *zap.Logger, metrics *observability.Metrics) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req clickRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			problem.Abort(c, problem.InvalidRequest, err.Error())
+			return
+		}
+
+		producer.Track(analytics.NewClickEvent(c.GetString("user_id"), &analytics.ClickEvent{
+			QueryID:   req.QueryID,
+			ServiceID: req.ServiceID,
+			Position:  req.Position,
+		}))
+		if err := svc.RecordSessionClick(c.Request.Context(), req.SessionID, req.ServiceID); err != nil {
+			logger.Warn("Failed to record session click", zap.String("service_id", req.ServiceID), zap.Error(err))
+		}
+
+		c.Status(http.StatusAccepted)
+	}
+}
+
+// handleTrackDismissal handles POST /api/v1/events/dismiss
+func handleTrackDismissal(svc *search.Service, logger *zap.Logger, metrics *observability.Metrics) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req dismissRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			problem.Abort(c, problem.InvalidRequest, err.Error())
+			return
+		}
+
+		if err := svc.RecordSessionDismissal(c.Request.Context(), req.SessionID, req.ServiceID); err != nil {
+			logger.Warn("Failed to record session dismissal", zap.String("service_id", req.ServiceID), zap.Error(err))
+		}
+
+		c.Status(http.StatusAccepted)
+	}
+}
@@ -0,0 +1,36 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/org/llm-marketplace/services/discovery/internal/elasticsearch"
+	"github.com/org/llm-marketplace/services/discovery/internal/observability"
+	"github.com/org/llm-marketplace/services/discovery/internal/problem"
+	"github.com/org/llm-marketplace/services/discovery/internal/search"
+	"go.uber.org/zap"
+)
+
+// handleSearchExplain handles GET /api/v1/search/explain
+func handleSearchExplain(svc *search.Service, logger *zap.Logger, metrics *observability.Metrics) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		serviceID := c.Query("service_id")
+
+		explanation, err := svc.Explain(c.Request.Context(), c.Query("query"), serviceID)
+		if err != nil {
+			switch {
+			case errors.Is(err, search.ErrInvalidExplain):
+				problem.Abort(c, problem.InvalidRequest, err.Error())
+			case errors.Is(err, elasticsearch.ErrNotFound):
+				problem.Abort(c, problem.NotFound, "Service not found")
+			default:
+				logger.Error("Failed to explain search result", zap.String("service_id", serviceID), zap.Error(err))
+				problem.Abort(c, problem.Internal, "Failed to explain search result")
+			}
+			return
+		}
+
+		c.JSON(http.StatusOK, explanation)
+	}
+}
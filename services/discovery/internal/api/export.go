@@ -0,0 +1,133 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/org/llm-marketplace/services/discovery/internal/export"
+	"github.com/org/llm-marketplace/services/discovery/internal/observability"
+	"github.com/org/llm-marketplace/services/discovery/internal/problem"
+	"github.com/org/llm-marketplace/services/discovery/internal/taxonomy"
+	"go.uber.org/zap"
+)
+
+// bindExportRequest parses an export request; ?format= overrides the body
+func bindExportRequest(c *gin.Context, exporter *export.Exporter, async bool) (*export.Request, bool) {
+	var req export.Request
+	if err := c.ShouldBindJSON(&req); err != nil {
+		problem.Abort(c, problem.InvalidRequest, err.Error())
+		return nil, false
+	}
+
+	if format := c.Query("format"); format != "" {
+		req.Format = format
+	}
+
+	if err := exporter.Validate(&req, async); err != nil {
+		problem.Abort(c, problem.InvalidRequest, err.Error())
+		return nil, false
+	}
+
+	if userID, exists := c.Get("user_id"); exists {
+		req.UserID, _ = userID.(string)
+	}
+
+	return &req, true
+}
+
+// handleExport handles POST /api/v1/search/export
+func handleExport(exporter *export.Exporter, logger *zap.Logger, metrics *observability.Metrics) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		req, ok := bindExportRequest(c, exporter, false)
+		if !ok {
+			return
+		}
+
+		c.Header("Content-Type", export.ContentType(req.Format))
+		c.Header("Content-Disposition", `attachment; filename="`+export.FileName(req.Format)+`"`)
+		c.Status(http.StatusOK)
+
+		rows, err := exporter.Stream(c.Request.Context(), req, c.Writer)
+		if err != nil {
+			logger.Error("Export failed", zap.Int("rows", rows), zap.Error(err))
+			if !c.Writer.Written() {
+				c.Writer.Header().Del("Content-Disposition")
+				if errors.Is(err, taxonomy.ErrInvalidCategory) {
+					problem.Abort(c, problem.InvalidRequest, err.Error())
+					return
+				}
+				problem.Abort(c, problem.Internal, "Export failed")
+				return
+			}
+			// Headers are already sent; the truncated body is the only signal left
+			c.Abort()
+			return
+		}
+
+		logger.Info("Export streamed",
+			zap.String("format", req.Format),
+			zap.Int("rows", rows),
+		)
+	}
+}
+
+// handleCreateExportJob handles POST /api/v1/exports
+func handleCreateExportJob(exporter *export.Exporter, logger *zap.Logger, metrics *observability.Metrics) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		req, ok := bindExportRequest(c, exporter, true)
+		if !ok {
+			return
+		}
+
+		job, err := exporter.StartJob(c.Request.Context(), req)
+		if err != nil {
+			logger.Error("Failed to start export job", zap.Error(err))
+			problem.Abort(c, problem.Internal, "Failed to start export job")
+			return
+		}
+
+		c.Header("Location", "/api/v1/exports/"+job.ID)
+		c.JSON(http.StatusAccepted, job)
+	}
+}
+
+// handleGetExportJob handles GET /api/v1/exports/:id
+func handleGetExportJob(exporter *export.Exporter, logger *zap.Logger, metrics *observability.Metrics) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		job, err := exporter.GetJob(c.Request.Context(), c.Param("id"))
+		if err != nil {
+			if errors.Is(err, export.ErrJobNotFound) {
+				problem.Abort(c, problem.NotFound, "Export job not found")
+				return
+			}
+			logger.Error("Failed to get export job", zap.Error(err))
+			problem.Abort(c, problem.Internal, "Failed to get export job")
+			return
+		}
+
+		c.JSON(http.StatusOK, job)
+	}
+}
+
+// handleDownloadExport handles GET /api/v1/exports/:id/download
+func handleDownloadExport(exporter *export.Exporter, logger *zap.Logger, metrics *observability.Metrics) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		job, path, err := exporter.ResultPath(c.Request.Context(), c.Param("id"))
+		if err != nil {
+			switch {
+			case errors.Is(err, export.ErrJobNotFound):
+				problem.Abort(c, problem.NotFound, "Export job not found")
+			case errors.Is(err, export.ErrJobNotReady):
+				problem.Abort(c, problem.Conflict, "Export job is "+job.Status)
+			default:
+				logger.Error("Failed to get export job", zap.Error(err))
+				problem.Abort(c, problem.Internal, "Failed to get export job")
+			}
+			return
+		}
+
+		c.Header("Content-Type", export.ContentType(job.Format))
+		c.FileAttachment(path, export.FileName(job.Format))
+	}
+}
@@ -0,0 +1,50 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/org/llm-marketplace/services/discovery/internal/feed"
+	"github.com/org/llm-marketplace/services/discovery/internal/observability"
+	"github.com/org/llm-marketplace/services/discovery/internal/problem"
+	"github.com/org/llm-marketplace/services/discovery/internal/search"
+	"go.uber.org/zap"
+)
+
+// handleFeedChanges handles GET /api/v1/feed/changes
+func handleFeedChanges(changes *feed.Feed, logger *zap.Logger, metrics *observability.Metrics) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limit := 0 // The feed's page size
+		if raw := c.Query("limit"); raw != "" {
+			n, err := strconv.Atoi(raw)
+			if err != nil || n < 1 {
+				problem.Abort(c, problem.InvalidRequest, "limit must be a positive integer")
+				return
+			}
+			limit = n
+		}
+		fields, err := search.ParseFields(c.Query("fields"))
+		if err != nil {
+			problem.Abort(c, problem.InvalidRequest, err.Error())
+			return
+		}
+
+		page, err := changes.Changes(c.Request.Context(), c.Query("cursor"), limit, fields)
+		if err != nil {
+			switch {
+			case errors.Is(err, feed.ErrInvalidRequest):
+				problem.Abort(c, problem.InvalidRequest, err.Error())
+			case errors.Is(err, feed.ErrCursorExpired):
+				problem.Abort(c, problem.CursorExpired, "Changes after this cursor have been pruned; take a full copy of the catalog and poll from a new cursor")
+			default:
+				logger.Error("Failed to read feed changes", zap.Error(err))
+				problem.Abort(c, problem.Internal, "Failed to read feed changes")
+			}
+			return
+		}
+
+		c.JSON(http.StatusOK, page)
+	}
+}
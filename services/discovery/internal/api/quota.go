@@ -0,0 +1,180 @@
+package api
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/org/llm-marketplace/services/discovery/internal/observability"
+	"github.com/org/llm-marketplace/services/discovery/internal/problem"
+	"github.com/org/llm-marketplace/services/discovery/internal/quota"
+	"go.uber.org/zap"
+)
+
+// Quota counts each request against its caller's limits and rejects those
+// over them with 429. Requests are let through while Redis can't count
+// them. It must run after Entitlements, which identifies the tenant.
+func Quota(quotas *quota.Service, logger *zap.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !quotas.Enabled() {
+			c.Next()
+			return
+		}
+
+		caller := callerOf(c, quotas)
+		usage, err := quotas.Take(c.Request.Context(), caller)
+		if err != nil {
+			logger.Warn("Failed to check quota, allowing request", zap.String("caller", caller.String()), zap.Error(err))
+			c.Next()
+			return
+		}
+
+		setQuotaHeaders(c, usage)
+		if !usage.Allowed() {
+			window, detail := usage.Second, "Rate limit of "+strconv.Itoa(usage.Second.Limit)+" requests per second exceeded"
+			if usage.Day.Exceeded() {
+				window, detail = usage.Day, "Daily quota of "+strconv.Itoa(usage.Day.Limit)+" requests exceeded"
+			}
+			c.Header("Retry-After", strconv.Itoa(secondsUntil(window.Reset)))
+			problem.Abort(c, problem.RateLimited, detail)
+			return
+		}
+		c.Next()
+	}
+}
+
+// callerOf identifies who the request counts against
+func callerOf(c *gin.Context, quotas *quota.Service) quota.Caller {
+	return quota.Identify(c.GetHeader(quotas.APIKeyHeader()), c.GetString("tenant_id"), c.ClientIP())
+}
+
+// setQuotaHeaders reports the limited windows of usage: the rate limit in
+// X-RateLimit-* and the daily quota in X-Quota-*, each resetting in the
+// given number of seconds
+func setQuotaHeaders(c *gin.Context, usage *quota.Usage) {
+	for _, w := range []struct {
+		prefix string
+		window quota.Window
+	}{
+		{"X-RateLimit-", usage.Second},
+		{"X-Quota-", usage.Day},
+	} {
+		if w.window.Limit == 0 {
+			continue
+		}
+		c.Header(w.prefix+"Limit", strconv.Itoa(w.window.Limit))
+		c.Header(w.prefix+"Remaining", strconv.FormatInt(w.window.Remaining(), 10))
+		c.Header(w.prefix+"Reset", strconv.Itoa(secondsUntil(w.window.Reset)))
+	}
+}
+
+// secondsUntil rounds the time left until t up to whole seconds, at least 1
+func secondsUntil(t time.Time) int {
+	return int(math.Max(1, math.Ceil(time.Until(t).Seconds())))
+}
+
+// handleGetQuota handles GET /api/v1/quota
+func handleGetQuota(quotas *quota.Service, logger *zap.Logger, metrics *observability.Metrics) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		usage, err := quotas.Usage(c.Request.Context(), callerOf(c, quotas))
+		if err != nil {
+			logger.Error("Failed to get quota usage", zap.Error(err))
+			problem.Abort(c, problem.Internal, "Failed to get quota usage")
+			return
+		}
+
+		setQuotaHeaders(c, usage)
+		c.JSON(http.StatusOK, gin.H{
+			"enforced": quotas.Enabled(),
+			"usage":    usage,
+		})
+	}
+}
+
+// handleListQuotaOverrides handles GET /api/v1/admin/quotas
+func handleListQuotaOverrides(quotas *quota.Service, logger *zap.Logger, metrics *observability.Metrics) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		overrides, err := quotas.Overrides(c.Request.Context())
+		if err != nil {
+			logger.Error("Failed to list quota overrides", zap.Error(err))
+			problem.Abort(c, problem.Internal, "Failed to list quota overrides")
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"overrides": overrides,
+			"total":     len(overrides),
+		})
+	}
+}
+
+// handleSetQuotaOverride handles PUT /api/v1/admin/quotas/:kind/:id
+func handleSetQuotaOverride(quotas *quota.Service, logger *zap.Logger, metrics *observability.Metrics) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		caller, ok := overrideCaller(c)
+		if !ok {
+			return
+		}
+		var override quota.Override
+		if err := c.ShouldBindJSON(&override); err != nil {
+			problem.Abort(c, problem.InvalidRequest, err.Error())
+			return
+		}
+
+		if err := quotas.SetOverride(c.Request.Context(), caller, override); err != nil {
+			if errors.Is(err, quota.ErrInvalidOverride) || errors.Is(err, quota.ErrUnknownTier) {
+				problem.Abort(c, problem.InvalidRequest, err.Error())
+				return
+			}
+			logger.Error("Failed to set quota override", zap.Error(err))
+			problem.Abort(c, problem.Internal, "Failed to set quota override")
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"caller":   caller,
+			"override": override,
+		})
+	}
+}
+
+// handleDeleteQuotaOverride handles DELETE /api/v1/admin/quotas/:kind/:id
+func handleDeleteQuotaOverride(quotas *quota.Service, logger *zap.Logger, metrics *observability.Metrics) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		caller, ok := overrideCaller(c)
+		if !ok {
+			return
+		}
+
+		if err := quotas.DeleteOverride(c.Request.Context(), caller); err != nil {
+			if errors.Is(err, quota.ErrNotFound) {
+				problem.Abort(c, problem.NotFound, "No quota override for "+caller.String())
+				return
+			}
+			logger.Error("Failed to delete quota override", zap.Error(err))
+			problem.Abort(c, problem.Internal, "Failed to delete quota override")
+			return
+		}
+
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// overrideCaller reads the caller an admin route names, aborting on an
+// unknown kind
+func overrideCaller(c *gin.Context) (quota.Caller, bool) {
+	kinds := map[string]string{
+		"keys":    quota.KindAPIKey,
+		"tenants": quota.KindTenant,
+		"clients": quota.KindClient,
+	}
+	kind, ok := kinds[c.Param("kind")]
+	if !ok {
+		problem.Abort(c, problem.NotFound, "Quota overrides are set on keys, tenants or clients, not "+c.Param("kind"))
+		return quota.Caller{}, false
+	}
+	return quota.Caller{Kind: kind, ID: c.Param("id")}, true
+}
@@ -0,0 +1,55 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/org/llm-marketplace/services/discovery/internal/analytics"
+	"github.com/org/llm-marketplace/services/discovery/internal/observability"
+	"github.com/org/llm-marketplace/services/discovery/internal/problem"
+	"go.uber.org/zap"
+)
+
+// handleRelatedCategories handles GET /api/v1/categories/:name/related
+func handleRelatedCategories(related *analytics.Related, logger *zap.Logger, metrics *observability.Metrics) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		category := c.Param("name")
+
+		categories, err := related.Categories(c.Request.Context(), category, parseIntQuery(c, "limit", 10))
+		if err != nil {
+			logger.Error("Failed to get related categories", zap.String("category", category), zap.Error(err))
+			problem.Abort(c, problem.Internal, "Failed to get related categories")
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"category": category,
+			"related":  categories,
+			"total":    len(categories),
+		})
+	}
+}
+
+// handleRelatedQueries handles GET /api/v1/queries/related
+func handleRelatedQueries(related *analytics.Related, logger *zap.Logger, metrics *observability.Metrics) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		query := analytics.NormalizeQuery(c.Query("q"))
+		if query == "" {
+			problem.Abort(c, problem.InvalidRequest, "Query parameter 'q' is required")
+			return
+		}
+
+		queries, err := related.Queries(c.Request.Context(), query, parseIntQuery(c, "limit", 10))
+		if err != nil {
+			logger.Error("Failed to get related queries", zap.Error(err))
+			problem.Abort(c, problem.Internal, "Failed to get related queries")
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"query":   query,
+			"related": queries,
+			"total":   len(queries),
+		})
+	}
+}
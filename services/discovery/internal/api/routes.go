@@ -1,13 +1,26 @@
 package api
 
 import (
+	"context"
+	"errors"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/org/llm-marketplace/services/discovery/internal/analytics"
+	"github.com/org/llm-marketplace/services/discovery/internal/elasticsearch"
+	"github.com/org/llm-marketplace/services/discovery/internal/export"
+	"github.com/org/llm-marketplace/services/discovery/internal/feed"
 	"github.com/org/llm-marketplace/services/discovery/internal/observability"
+	"github.com/org/llm-marketplace/services/discovery/internal/problem"
+	"github.com/org/llm-marketplace/services/discovery/internal/quota"
 	"github.com/org/llm-marketplace/services/discovery/internal/recommendation"
 	"github.com/org/llm-marketplace/services/discovery/internal/search"
+	"github.com/org/llm-marketplace/services/discovery/internal/sla"
+	"github.com/org/llm-marketplace/services/discovery/internal/snapshot"
+	"github.com/org/llm-marketplace/services/discovery/internal/taxonomy"
+	"github.com/org/llm-marketplace/services/discovery/internal/webhook"
 	"go.uber.org/zap"
 )
 
@@ -16,29 +29,120 @@ func RegisterRoutes(
 	router *gin.Engine,
 	searchService *search.Service,
 	recService *recommendation.Service,
+	slaMonitor *sla.Monitor,
+	exporter *export.Exporter,
+	snapshots *snapshot.Snapshotter,
+	changes *feed.Feed,
+	dispatcher *webhook.Dispatcher,
+	producer *analytics.Producer,
+	reporter *analytics.Reporter,
+	related *analytics.Related,
+	taxonomyManager *taxonomy.Manager,
+	quotas *quota.Service,
 	logger *zap.Logger,
 	metrics *observability.Metrics,
 ) {
-	api := router.Group("/api/v1")
+	// Quota usage and overrides, outside the limits they report on
+	quotaAPI := router.Group("/api/v1")
+	{
+		quotaAPI.GET("/quota", handleGetQuota(quotas, logger, metrics))
+		quotaAPI.GET("/admin/quotas", handleListQuotaOverrides(quotas, logger, metrics))
+		quotaAPI.PUT("/admin/quotas/:kind/:id", handleSetQuotaOverride(quotas, logger, metrics))
+		quotaAPI.DELETE("/admin/quotas/:kind/:id", handleDeleteQuotaOverride(quotas, logger, metrics))
+	}
+
+	api := router.Group("/api/v1", Quota(quotas, logger))
 	{
 		// Search endpoints
 		api.POST("/search", handleSearch(searchService, logger, metrics))
 		api.GET("/search", handleSearchGET(searchService, logger, metrics))
+		api.GET("/search/explain", handleSearchExplain(searchService, logger, metrics))
+		api.POST("/search/export", handleExport(exporter, logger, metrics))
+		api.POST("/ask", handleAsk(searchService, logger, metrics))
+
+		// Export jobs
+		api.POST("/exports", handleCreateExportJob(exporter, logger, metrics))
+		api.GET("/exports/:id", handleGetExportJob(exporter, logger, metrics))
+		api.GET("/exports/:id/download", handleDownloadExport(exporter, logger, metrics))
 
 		// Service endpoints
-		api.GET("/services/:id", handleGetService(searchService, logger, metrics))
+		api.GET("/services/:id", ETag(), handleGetService(searchService, logger, metrics))
+		api.GET("/services/by-name/:name", handleGetServiceByName(searchService, logger, metrics))
+		api.GET("/services/:id/versions", handleServiceVersions(searchService, logger, metrics))
+		api.GET("/services/:id/dependencies", handleServiceDependencies(searchService, logger, metrics))
 		api.GET("/services/:id/similar", handleSimilarServices(searchService, recService, logger, metrics))
+		api.PUT("/services/:id/status", handleTransitionStatus(searchService, logger, metrics))
+		api.GET("/services/:id/health", handleServiceHealth(searchService, slaMonitor, logger, metrics))
+		api.POST("/services/:id/health/reports", handleProviderHealthReport(slaMonitor, logger, metrics))
+		api.PUT("/services/:id/benchmarks", handleSetBenchmark(searchService, logger, metrics))
 
 		// Recommendation endpoints
 		api.GET("/recommendations", handleRecommendations(recService, logger, metrics))
 		api.GET("/recommendations/trending", handleTrending(recService, logger, metrics))
+		api.GET("/recommendations/slate", handleSlate(recService, logger, metrics))
 
 		// Category and tag endpoints
-		api.GET("/categories", handleGetCategories(searchService, logger, metrics))
-		api.GET("/tags", handleGetTags(searchService, logger, metrics))
+		api.GET("/categories", ETag(), handleGetCategories(searchService, logger, metrics))
+		api.GET("/tags", ETag(), handleGetTags(searchService, logger, metrics))
+
+		// Related categories and "people also searched" queries
+		api.GET("/categories/:name/related", handleRelatedCategories(related, logger, metrics))
+		api.GET("/queries/related", handleRelatedQueries(related, logger, metrics))
+
+		// Category taxonomy administration
+		api.GET("/taxonomy/categories", handleListTaxonomy(taxonomyManager, logger, metrics))
+		api.POST("/taxonomy/categories", handleCreateTaxonomyCategory(taxonomyManager, logger, metrics))
+		api.GET("/taxonomy/categories/:name", handleGetTaxonomyCategory(taxonomyManager, logger, metrics))
+		api.PATCH("/taxonomy/categories/:name", handleUpdateTaxonomyCategory(taxonomyManager, logger, metrics))
+		api.DELETE("/taxonomy/categories/:name", handleDeleteTaxonomyCategory(taxonomyManager, logger, metrics))
+
+		// Embedding administration
+		api.POST("/admin/embeddings/backfill", handleStartEmbeddingBackfill(searchService, logger, metrics))
+		api.GET("/admin/embeddings/backfill/:id", handleGetEmbeddingBackfill(searchService, logger, metrics))
+
+		// Search debug captures
+		api.GET("/admin/search/debug/:request_id", handleGetDebugCapture(searchService, logger, metrics))
+
+		// Catalog snapshots, when enabled
+		if snapshots != nil {
+			api.POST("/admin/snapshots", handleStartSnapshot(snapshots, logger, metrics))
+			api.GET("/admin/snapshots/:id", handleGetSnapshot(snapshots, logger, metrics))
+		}
+
+		// Partner syndication feed, when enabled
+		if changes != nil {
+			api.GET("/feed/changes", handleFeedChanges(changes, logger, metrics))
+		}
 
 		// Autocomplete
 		api.GET("/autocomplete", handleAutocomplete(searchService, logger, metrics))
+
+		// Analytics events
+		api.POST("/events/click", handleTrackClick(searchService, producer, logger, metrics))
+		api.POST("/events/dismiss", handleTrackDismissal(searchService, logger, metrics))
+
+		// Search analytics for operators
+		api.GET("/analytics/top-queries", handleTopQueries(reporter, logger, metrics))
+		api.GET("/analytics/zero-results", handleZeroResults(reporter, logger, metrics))
+		api.GET("/analytics/ctr", handleClickThrough(reporter, logger, metrics))
+		api.GET("/analytics/latency", handleLatency(reporter, logger, metrics))
+
+		// Webhook subscriptions
+		api.POST("/webhooks", handleCreateWebhook(dispatcher, logger, metrics))
+		api.GET("/webhooks", handleListWebhooks(dispatcher, logger, metrics))
+		api.GET("/webhooks/:id", handleGetWebhook(dispatcher, logger, metrics))
+		api.DELETE("/webhooks/:id", handleDeleteWebhook(dispatcher, logger, metrics))
+		api.GET("/webhooks/:id/deliveries", handleListWebhookDeliveries(dispatcher, logger, metrics))
+	}
+}
+
+// RegisterGraphQL serves handle at /graphql, counted against the same quotas
+// as the REST API
+func RegisterGraphQL(router *gin.Engine, handle gin.HandlerFunc, quotas *quota.Service, logger *zap.Logger) {
+	graphQL := router.Group("/graphql", Quota(quotas, logger))
+	{
+		graphQL.GET("", handle)
+		graphQL.POST("", handle)
 	}
 }
 
@@ -48,10 +152,19 @@ func handleSearch(svc *search.Service, logger *zap.Logger, metrics *observabilit
 		var req search.SearchRequest
 		if err := c.ShouldBindJSON(&req); err != nil {
 			logger.Warn("Invalid search request", zap.Error(err))
-			c.JSON(http.StatusBadRequest, gin.H{
-				"error": "Invalid request body",
-				"details": err.Error(),
-			})
+			problem.Abort(c, problem.InvalidRequest, err.Error())
+			return
+		}
+
+		if raw := c.Query("fields"); raw != "" {
+			fields, err := search.ParseFields(raw)
+			if err != nil {
+				problem.Abort(c, problem.InvalidRequest, err.Error())
+				return
+			}
+			req.Fields = fields
+		} else if err := search.ValidateFields(req.Fields); err != nil {
+			problem.Abort(c, problem.InvalidRequest, err.Error())
 			return
 		}
 
@@ -60,6 +173,11 @@ func handleSearch(svc *search.Service, logger *zap.Logger, metrics *observabilit
 			req.UserID = userID.(string)
 		}
 
+		if req.RankingWeights != nil && !svc.AllowsRankingOverride(c.GetString("operator_id"), c.GetString("tenant_id")) {
+			problem.Abort(c, problem.Forbidden, "ranking_weights can only be sent by callers listed in search.ranking_overrides")
+			return
+		}
+
 		// Set defaults
 		if req.Pagination.PageSize == 0 {
 			req.Pagination.PageSize = 20
@@ -67,10 +185,15 @@ func handleSearch(svc *search.Service, logger *zap.Logger, metrics *observabilit
 
 		response, err := svc.Search(c.Request.Context(), &req)
 		if err != nil {
+			if abortGuardrailError(c, err) {
+				return
+			}
+			if errors.Is(err, taxonomy.ErrInvalidCategory) || errors.Is(err, search.ErrUnknownEntityType) || errors.Is(err, search.ErrUnknownFacet) || errors.Is(err, search.ErrInvalidPareto) || errors.Is(err, search.ErrInvalidKind) || errors.Is(err, search.ErrInvalidRankingWeights) {
+				problem.Abort(c, problem.InvalidRequest, err.Error())
+				return
+			}
 			logger.Error("Search failed", zap.Error(err))
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error": "Search failed",
-			})
+			problem.Abort(c, problem.Internal, "Search failed")
 			return
 		}
 
@@ -78,6 +201,24 @@ func handleSearch(svc *search.Service, logger *zap.Logger, metrics *observabilit
 	}
 }
 
+// abortGuardrailError answers a search the guardrails or its latency budget
+// stopped, reporting whether err was one
+func abortGuardrailError(c *gin.Context, err error) bool {
+	switch {
+	case errors.Is(err, search.ErrQueryTooExpensive):
+		problem.Abort(c, problem.QueryTooExpensive, err.Error())
+	case errors.Is(err, search.ErrSearchTimedOut):
+		problem.Abort(c, problem.ServiceUnavailable, "Search timed out")
+	case errors.Is(err, context.Canceled):
+		// The client went away, so there is no one to answer. Logged and
+		// counted with 499, nginx's status for a request the client closed.
+		c.AbortWithStatus(499)
+	default:
+		return false
+	}
+	return true
+}
+
 // handleSearchGET handles GET /api/v1/search
 func handleSearchGET(svc *search.Service, logger *zap.Logger, metrics *observability.Metrics) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -89,6 +230,13 @@ func handleSearchGET(svc *search.Service, logger *zap.Logger, metrics *observabi
 			},
 		}
 
+		fields, err := search.ParseFields(c.Query("fields"))
+		if err != nil {
+			problem.Abort(c, problem.InvalidRequest, err.Error())
+			return
+		}
+		req.Fields = fields
+
 		// Parse filters
 		if category := c.Query("category"); category != "" {
 			req.Filters.Categories = []string{category}
@@ -101,16 +249,122 @@ func handleSearchGET(svc *search.Service, logger *zap.Logger, metrics *observabi
 				req.Filters.MinRating = rating
 			}
 		}
+		if minPrice := c.Query("min_price"); minPrice != "" {
+			if price, err := strconv.ParseFloat(minPrice, 64); err == nil {
+				req.Filters.MinPrice = price
+			}
+		}
+		if maxPrice := c.Query("max_price"); maxPrice != "" {
+			if price, err := strconv.ParseFloat(maxPrice, 64); err == nil {
+				req.Filters.MaxPrice = price
+			}
+		}
 		if verifiedOnly := c.Query("verified_only"); verifiedOnly == "true" {
 			req.Filters.VerifiedOnly = true
 		}
+		if policyCompliant := c.Query("policy_compliant"); policyCompliant == "true" {
+			req.Filters.PolicyCompliant = true
+		}
+		req.Filters.Kind = c.Query("kind")
+		if families := c.Query("model_families"); families != "" {
+			req.Filters.ModelFamilies = strings.Split(families, ",")
+		}
+		if licenses := c.Query("licenses"); licenses != "" {
+			req.Filters.Licenses = strings.Split(licenses, ",")
+		}
+		if formats := c.Query("dataset_formats"); formats != "" {
+			req.Filters.DatasetFormats = strings.Split(formats, ",")
+		}
+		if maxBytes := c.Query("max_dataset_bytes"); maxBytes != "" {
+			if size, err := strconv.ParseInt(maxBytes, 10, 64); err == nil {
+				req.Filters.MaxDatasetBytes = size
+			}
+		}
+		if models := c.Query("base_models"); models != "" {
+			req.Filters.BaseModels = strings.Split(models, ",")
+		}
+		if maxHours := c.Query("max_turnaround_hours"); maxHours != "" {
+			if hours, err := strconv.Atoi(maxHours); err == nil {
+				req.Filters.MaxTurnaroundHours = hours
+			}
+		}
+		req.Filters.ServingRegion = c.Query("serving_region")
+		if types := c.Query("types"); types != "" {
+			req.Types = strings.Split(types, ",")
+		}
+		if allVersions := c.Query("all_versions"); allVersions == "true" {
+			req.AllVersions = true
+		}
+		if c.Query("literal") == "true" {
+			req.Literal = true
+		}
+		if c.Query("debug") == "true" {
+			req.Debug = true
+		}
+		req.SessionID = c.Query("session_id")
+		req.Region = c.Query("region")
+		// size=0 asks for facet counts only, as in the Elasticsearch API
+		if c.Query("aggregations_only") == "true" || c.Query("size") == "0" || c.Query("page_size") == "0" {
+			req.AggregationsOnly = true
+		}
+		if c.Query("count_only") == "true" {
+			req.CountOnly = true
+		}
+		if facets := c.Query("facets"); facets != "" {
+			req.Facets = strings.Split(facets, ",")
+		}
+		// The Pareto frontier of the category
+		if c.Query("mode") == "pareto" {
+			req.Pareto = &search.ParetoRequest{Category: c.Query("category")}
+			if minQuality := c.Query("min_quality"); minQuality != "" {
+				if quality, err := strconv.ParseFloat(minQuality, 64); err == nil {
+					req.Pareto.MinQuality = quality
+				}
+			}
+		}
 
 		response, err := svc.Search(c.Request.Context(), &req)
 		if err != nil {
+			if abortGuardrailError(c, err) {
+				return
+			}
+			if errors.Is(err, taxonomy.ErrInvalidCategory) || errors.Is(err, search.ErrUnknownEntityType) || errors.Is(err, search.ErrUnknownFacet) || errors.Is(err, search.ErrInvalidPareto) || errors.Is(err, search.ErrInvalidKind) {
+				problem.Abort(c, problem.InvalidRequest, err.Error())
+				return
+			}
 			logger.Error("Search failed", zap.Error(err))
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error": "Search failed",
-			})
+			problem.Abort(c, problem.Internal, "Search failed")
+			return
+		}
+
+		c.JSON(http.StatusOK, response)
+	}
+}
+
+// handleAsk handles POST /api/v1/ask
+func handleAsk(svc *search.Service, logger *zap.Logger, metrics *observability.Metrics) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req search.AskRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			problem.Abort(c, problem.InvalidRequest, err.Error())
+			return
+		}
+		if userID, exists := c.Get("user_id"); exists {
+			req.UserID = userID.(string)
+		}
+
+		response, err := svc.Ask(c.Request.Context(), &req)
+		if err != nil {
+			switch {
+			case errors.Is(err, search.ErrAssistantDisabled):
+				problem.Abort(c, problem.ServiceUnavailable, "The assistant is disabled")
+			case errors.Is(err, search.ErrInvalidQuestion) || errors.Is(err, taxonomy.ErrInvalidCategory):
+				problem.Abort(c, problem.InvalidRequest, err.Error())
+			case abortGuardrailError(c, err):
+			default:
+				logger.Error("Ask failed", zap.Error(err))
+				problem.Abort(c, problem.Internal, "Ask failed")
+			}
 			return
 		}
 
@@ -123,18 +377,35 @@ func handleGetService(svc *search.Service, logger *zap.Logger, metrics *observab
 	return func(c *gin.Context) {
 		serviceID := c.Param("id")
 		if serviceID == "" {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"error": "Service ID is required",
-			})
+			problem.Abort(c, problem.InvalidRequest, "Service ID is required")
+			return
+		}
+
+		fields, err := search.ParseFields(c.Query("fields"))
+		if err != nil {
+			problem.Abort(c, problem.InvalidRequest, err.Error())
 			return
 		}
 
 		service, err := svc.GetServiceByID(c.Request.Context(), serviceID)
 		if err != nil {
+			if errors.Is(err, elasticsearch.ErrNotFound) {
+				problem.Abort(c, problem.NotFound, "Service not found")
+				return
+			}
 			logger.Error("Failed to get service", zap.String("id", serviceID), zap.Error(err))
-			c.JSON(http.StatusNotFound, gin.H{
-				"error": "Service not found",
-			})
+			problem.Abort(c, problem.Internal, "Failed to get service")
+			return
+		}
+
+		if len(fields) > 0 {
+			sparse, err := search.ProjectDocument(service, fields)
+			if err != nil {
+				logger.Error("Failed to project service fields", zap.String("id", serviceID), zap.Error(err))
+				problem.Abort(c, problem.Internal, "Failed to get service")
+				return
+			}
+			c.JSON(http.StatusOK, sparse)
 			return
 		}
 
@@ -142,6 +413,185 @@ func handleGetService(svc *search.Service, logger *zap.Logger, metrics *observab
 	}
 }
 
+// handleGetServiceByName handles GET /api/v1/services/by-name/:name
+func handleGetServiceByName(svc *search.Service, logger *zap.Logger, metrics *observability.Metrics) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		name := c.Param("name")
+
+		match, err := svc.GetServiceByName(c.Request.Context(), name)
+		if err != nil {
+			switch {
+			case errors.Is(err, elasticsearch.ErrNotFound):
+				problem.Abort(c, problem.NotFound, "No service named "+name)
+			case errors.Is(err, search.ErrAmbiguousName):
+				problem.Abort(c, problem.Conflict, err.Error())
+			default:
+				logger.Error("Failed to get service by name", zap.String("name", name), zap.Error(err))
+				problem.Abort(c, problem.Internal, "Failed to get service")
+			}
+			return
+		}
+
+		c.JSON(http.StatusOK, match)
+	}
+}
+
+// handleServiceVersions handles GET /api/v1/services/:id/versions
+func handleServiceVersions(svc *search.Service, logger *zap.Logger, metrics *observability.Metrics) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		serviceID := c.Param("id")
+
+		history, err := svc.Versions(c.Request.Context(), serviceID)
+		if err != nil {
+			if errors.Is(err, elasticsearch.ErrNotFound) {
+				problem.Abort(c, problem.NotFound, "Service not found")
+				return
+			}
+			logger.Error("Failed to list service versions", zap.String("id", serviceID), zap.Error(err))
+			problem.Abort(c, problem.Internal, "Failed to list service versions")
+			return
+		}
+
+		c.JSON(http.StatusOK, history)
+	}
+}
+
+// handleServiceDependencies handles GET /api/v1/services/:id/dependencies
+func handleServiceDependencies(svc *search.Service, logger *zap.Logger, metrics *observability.Metrics) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		serviceID := c.Param("id")
+
+		graph, err := svc.Dependencies(c.Request.Context(), serviceID)
+		if err != nil {
+			if errors.Is(err, elasticsearch.ErrNotFound) {
+				problem.Abort(c, problem.NotFound, "Service not found")
+				return
+			}
+			logger.Error("Failed to load service dependencies", zap.String("id", serviceID), zap.Error(err))
+			problem.Abort(c, problem.Internal, "Failed to load service dependencies")
+			return
+		}
+
+		c.JSON(http.StatusOK, graph)
+	}
+}
+
+// handleTransitionStatus handles PUT /api/v1/services/:id/status
+func handleTransitionStatus(svc *search.Service, logger *zap.Logger, metrics *observability.Metrics) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		serviceID := c.Param("id")
+
+		var req search.StatusTransitionRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			problem.Abort(c, problem.InvalidRequest, err.Error())
+			return
+		}
+
+		service, err := svc.TransitionStatus(c.Request.Context(), serviceID, &req)
+		if err != nil {
+			switch {
+			case errors.Is(err, elasticsearch.ErrNotFound):
+				problem.Abort(c, problem.NotFound, "Service not found")
+			case errors.Is(err, search.ErrInvalidTransition):
+				problem.Abort(c, problem.Conflict, err.Error())
+			default:
+				logger.Error("Failed to change service status", zap.String("id", serviceID), zap.Error(err))
+				problem.Abort(c, problem.Internal, "Failed to change service status")
+			}
+			return
+		}
+
+		c.JSON(http.StatusOK, service)
+	}
+}
+
+// handleSetBenchmark handles PUT /api/v1/services/:id/benchmarks
+func handleSetBenchmark(svc *search.Service, logger *zap.Logger, metrics *observability.Metrics) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		serviceID := c.Param("id")
+
+		var req search.BenchmarkRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			problem.Abort(c, problem.InvalidRequest, err.Error())
+			return
+		}
+
+		service, err := svc.SetBenchmark(c.Request.Context(), serviceID, &req)
+		if err != nil {
+			switch {
+			case errors.Is(err, elasticsearch.ErrNotFound):
+				problem.Abort(c, problem.NotFound, "Service not found")
+			case errors.Is(err, search.ErrInvalidBenchmark):
+				problem.Abort(c, problem.InvalidRequest, err.Error())
+			default:
+				logger.Error("Failed to record benchmark", zap.String("id", serviceID), zap.Error(err))
+				problem.Abort(c, problem.Internal, "Failed to record benchmark")
+			}
+			return
+		}
+
+		c.JSON(http.StatusOK, service)
+	}
+}
+
+// handleServiceHealth handles GET /api/v1/services/:id/health
+func handleServiceHealth(svc *search.Service, monitor *sla.Monitor, logger *zap.Logger, metrics *observability.Metrics) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		serviceID := c.Param("id")
+
+		// Health is only reported for services the caller can see
+		if _, err := svc.GetServiceByID(c.Request.Context(), serviceID); err != nil {
+			if errors.Is(err, elasticsearch.ErrNotFound) {
+				problem.Abort(c, problem.NotFound, "Service not found")
+				return
+			}
+			logger.Error("Failed to get service", zap.String("id", serviceID), zap.Error(err))
+			problem.Abort(c, problem.Internal, "Failed to get service health")
+			return
+		}
+
+		health, err := monitor.GetHealth(c.Request.Context(), serviceID)
+		if err != nil {
+			logger.Error("Failed to get service health", zap.String("id", serviceID), zap.Error(err))
+			problem.Abort(c, problem.Internal, "Failed to get service health")
+			return
+		}
+
+		c.JSON(http.StatusOK, health)
+	}
+}
+
+// handleProviderHealthReport handles POST /api/v1/services/:id/health/reports
+func handleProviderHealthReport(monitor *sla.Monitor, logger *zap.Logger, metrics *observability.Metrics) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		serviceID := c.Param("id")
+
+		var report sla.ProviderReport
+		if err := c.ShouldBindJSON(&report); err != nil {
+			problem.Abort(c, problem.InvalidRequest, err.Error())
+			return
+		}
+
+		health, err := monitor.RecordProviderReport(c.Request.Context(), serviceID, c.GetString("provider_id"), &report)
+		if err != nil {
+			switch {
+			case errors.Is(err, elasticsearch.ErrNotFound):
+				problem.Abort(c, problem.NotFound, "Service not found")
+			case errors.Is(err, sla.ErrNotProvider):
+				problem.Abort(c, problem.Forbidden, err.Error())
+			case errors.Is(err, sla.ErrInvalidReport):
+				problem.Abort(c, problem.InvalidRequest, err.Error())
+			default:
+				logger.Error("Failed to record provider health report", zap.String("id", serviceID), zap.Error(err))
+				problem.Abort(c, problem.Internal, "Failed to record health report")
+			}
+			return
+		}
+
+		c.JSON(http.StatusAccepted, health)
+	}
+}
+
 // handleSimilarServices handles GET /api/v1/services/:id/similar
 func handleSimilarServices(
 	svc *search.Service,
@@ -161,9 +611,7 @@ func handleSimilarServices(
 		response, err := recSvc.GetRecommendations(c.Request.Context(), &req)
 		if err != nil {
 			logger.Error("Failed to get similar services", zap.Error(err))
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error": "Failed to get recommendations",
-			})
+			problem.Abort(c, problem.Internal, "Failed to get recommendations")
 			return
 		}
 
@@ -174,10 +622,8 @@ func handleSimilarServices(
 // handleRecommendations handles GET /api/v1/recommendations
 func handleRecommendations(svc *recommendation.Service, logger *zap.Logger, metrics *observability.Metrics) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		userID, _ := c.Get("user_id")
-
 		req := recommendation.RecommendationRequest{
-			UserID:          userID.(string),
+			UserID:          c.GetString("user_id"),
 			MaxResults:      parseIntQuery(c, "max_results", 10),
 			IncludeTrending: c.Query("include_trending") == "true",
 		}
@@ -189,9 +635,7 @@ func handleRecommendations(svc *recommendation.Service, logger *zap.Logger, metr
 		response, err := svc.GetRecommendations(c.Request.Context(), &req)
 		if err != nil {
 			logger.Error("Failed to get recommendations", zap.Error(err))
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error": "Failed to get recommendations",
-			})
+			problem.Abort(c, problem.Internal, "Failed to get recommendations")
 			return
 		}
 
@@ -204,17 +648,36 @@ func handleTrending(svc *recommendation.Service, logger *zap.Logger, metrics *ob
 	return func(c *gin.Context) {
 		maxResults := parseIntQuery(c, "max_results", 10)
 
-		req := recommendation.RecommendationRequest{
-			MaxResults:      maxResults,
-			IncludeTrending: true,
+		response, err := svc.GetTrending(c.Request.Context(), maxResults)
+		if err != nil {
+			logger.Error("Failed to get trending services", zap.Error(err))
+			problem.Abort(c, problem.Internal, "Failed to get trending services")
+			return
 		}
 
-		response, err := svc.GetRecommendations(c.Request.Context(), &req)
+		c.JSON(http.StatusOK, response)
+	}
+}
+
+// handleSlate handles GET /api/v1/recommendations/slate
+func handleSlate(svc *recommendation.Service, logger *zap.Logger, metrics *observability.Metrics) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		req := recommendation.SlateRequest{
+			UserID:   c.GetString("user_id"),
+			Category: c.Query("category"),
+		}
+		if placements := c.Query("placements"); placements != "" {
+			req.Placements = strings.Split(placements, ",")
+		}
+
+		response, err := svc.GetSlate(c.Request.Context(), &req)
+		if errors.Is(err, recommendation.ErrUnknownPlacement) {
+			problem.Abort(c, problem.InvalidRequest, err.Error())
+			return
+		}
 		if err != nil {
-			logger.Error("Failed to get trending services", zap.Error(err))
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error": "Failed to get trending services",
-			})
+			logger.Error("Failed to get recommendation slate", zap.Error(err))
+			problem.Abort(c, problem.Internal, "Failed to get recommendations")
 			return
 		}
 
@@ -228,9 +691,7 @@ func handleGetCategories(svc *search.Service, logger *zap.Logger, metrics *obser
 		categories, err := svc.GetCategories(c.Request.Context())
 		if err != nil {
 			logger.Error("Failed to get categories", zap.Error(err))
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error": "Failed to get categories",
-			})
+			problem.Abort(c, problem.Internal, "Failed to get categories")
 			return
 		}
 
@@ -246,9 +707,7 @@ func handleGetTags(svc *search.Service, logger *zap.Logger, metrics *observabili
 		tags, err := svc.GetTags(c.Request.Context())
 		if err != nil {
 			logger.Error("Failed to get tags", zap.Error(err))
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error": "Failed to get tags",
-			})
+			problem.Abort(c, problem.Internal, "Failed to get tags")
 			return
 		}
 
@@ -263,20 +722,20 @@ func handleAutocomplete(svc *search.Service, logger *zap.Logger, metrics *observ
 	return func(c *gin.Context) {
 		query := c.Query("q")
 		if query == "" {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"error": "Query parameter 'q' is required",
-			})
+			problem.Abort(c, problem.InvalidRequest, "Query parameter 'q' is required")
 			return
 		}
 
 		limit := parseIntQuery(c, "limit", 10)
 
-		suggestions, err := svc.Autocomplete(c.Request.Context(), query, limit)
+		suggestions, err := svc.Autocomplete(c.Request.Context(), query, c.Query("category"), limit)
 		if err != nil {
+			if errors.Is(err, taxonomy.ErrInvalidCategory) {
+				problem.Abort(c, problem.InvalidRequest, err.Error())
+				return
+			}
 			logger.Error("Autocomplete failed", zap.Error(err))
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error": "Autocomplete failed",
-			})
+			problem.Abort(c, problem.Internal, "Autocomplete failed")
 			return
 		}
 
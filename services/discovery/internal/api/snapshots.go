@@ -0,0 +1,49 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/org/llm-marketplace/services/discovery/internal/observability"
+	"github.com/org/llm-marketplace/services/discovery/internal/problem"
+	"github.com/org/llm-marketplace/services/discovery/internal/snapshot"
+	"go.uber.org/zap"
+)
+
+// handleStartSnapshot handles POST /api/v1/admin/snapshots
+func handleStartSnapshot(snapshots *snapshot.Snapshotter, logger *zap.Logger, metrics *observability.Metrics) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		job, err := snapshots.StartJob(c.Request.Context(), snapshot.TriggerAPI)
+		if err != nil {
+			if errors.Is(err, snapshot.ErrRunning) {
+				problem.Abort(c, problem.Conflict, "A catalog snapshot is already running")
+				return
+			}
+			logger.Error("Failed to start catalog snapshot", zap.Error(err))
+			problem.Abort(c, problem.Internal, "Failed to start catalog snapshot")
+			return
+		}
+
+		c.Header("Location", "/api/v1/admin/snapshots/"+job.ID)
+		c.JSON(http.StatusAccepted, job)
+	}
+}
+
+// handleGetSnapshot handles GET /api/v1/admin/snapshots/:id
+func handleGetSnapshot(snapshots *snapshot.Snapshotter, logger *zap.Logger, metrics *observability.Metrics) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		job, err := snapshots.GetJob(c.Request.Context(), c.Param("id"))
+		if err != nil {
+			if errors.Is(err, snapshot.ErrJobNotFound) {
+				problem.Abort(c, problem.NotFound, "Catalog snapshot job not found")
+				return
+			}
+			logger.Error("Failed to get catalog snapshot", zap.Error(err))
+			problem.Abort(c, problem.Internal, "Failed to get catalog snapshot")
+			return
+		}
+
+		c.JSON(http.StatusOK, job)
+	}
+}
@@ -0,0 +1,107 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/org/llm-marketplace/services/discovery/internal/observability"
+	"github.com/org/llm-marketplace/services/discovery/internal/problem"
+	"github.com/org/llm-marketplace/services/discovery/internal/taxonomy"
+	"go.uber.org/zap"
+)
+
+// handleListTaxonomy handles GET /api/v1/taxonomy/categories
+func handleListTaxonomy(manager *taxonomy.Manager, logger *zap.Logger, metrics *observability.Metrics) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		t, err := manager.Load(c.Request.Context())
+		if err != nil {
+			logger.Error("Failed to load taxonomy", zap.Error(err))
+			problem.Abort(c, problem.Internal, "Failed to load taxonomy")
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"categories": t.Tree(),
+		})
+	}
+}
+
+// handleGetTaxonomyCategory handles GET /api/v1/taxonomy/categories/:name
+func handleGetTaxonomyCategory(manager *taxonomy.Manager, logger *zap.Logger, metrics *observability.Metrics) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		category, err := manager.Get(c.Request.Context(), c.Param("name"))
+		if err != nil {
+			abortTaxonomyError(c, logger, err, "Failed to get category")
+			return
+		}
+
+		c.JSON(http.StatusOK, category)
+	}
+}
+
+// handleCreateTaxonomyCategory handles POST /api/v1/taxonomy/categories
+func handleCreateTaxonomyCategory(manager *taxonomy.Manager, logger *zap.Logger, metrics *observability.Metrics) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req taxonomy.CreateCategoryRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			problem.Abort(c, problem.InvalidRequest, err.Error())
+			return
+		}
+
+		category, err := manager.Create(c.Request.Context(), &req)
+		if err != nil {
+			abortTaxonomyError(c, logger, err, "Failed to create category")
+			return
+		}
+
+		c.Header("Location", "/api/v1/taxonomy/categories/"+category.Name)
+		c.JSON(http.StatusCreated, category)
+	}
+}
+
+// handleUpdateTaxonomyCategory handles PATCH /api/v1/taxonomy/categories/:name
+func handleUpdateTaxonomyCategory(manager *taxonomy.Manager, logger *zap.Logger, metrics *observability.Metrics) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req taxonomy.UpdateCategoryRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			problem.Abort(c, problem.InvalidRequest, err.Error())
+			return
+		}
+
+		category, err := manager.Update(c.Request.Context(), c.Param("name"), &req)
+		if err != nil {
+			abortTaxonomyError(c, logger, err, "Failed to update category")
+			return
+		}
+
+		c.JSON(http.StatusOK, category)
+	}
+}
+
+// handleDeleteTaxonomyCategory handles DELETE /api/v1/taxonomy/categories/:name
+func handleDeleteTaxonomyCategory(manager *taxonomy.Manager, logger *zap.Logger, metrics *observability.Metrics) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := manager.Delete(c.Request.Context(), c.Param("name")); err != nil {
+			abortTaxonomyError(c, logger, err, "Failed to delete category")
+			return
+		}
+
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// abortTaxonomyError maps taxonomy errors onto problem responses
+func abortTaxonomyError(c *gin.Context, logger *zap.Logger, err error, message string) {
+	switch {
+	case errors.Is(err, taxonomy.ErrCategoryNotFound):
+		problem.Abort(c, problem.NotFound, "Category not found")
+	case errors.Is(err, taxonomy.ErrCategoryExists), errors.Is(err, taxonomy.ErrCategoryInUse):
+		problem.Abort(c, problem.Conflict, err.Error())
+	case errors.Is(err, taxonomy.ErrInvalidCategory):
+		problem.Abort(c, problem.InvalidRequest, err.Error())
+	default:
+		logger.Error(message, zap.Error(err))
+		problem.Abort(c, problem.Internal, message)
+	}
+}
@@ -0,0 +1,114 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/org/llm-marketplace/services/discovery/internal/observability"
+	"github.com/org/llm-marketplace/services/discovery/internal/problem"
+	"github.com/org/llm-marketplace/services/discovery/internal/webhook"
+	"go.uber.org/zap"
+)
+
+// handleCreateWebhook handles POST /api/v1/webhooks
+func handleCreateWebhook(dispatcher *webhook.Dispatcher, logger *zap.Logger, metrics *observability.Metrics) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req webhook.CreateSubscriptionRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			problem.Abort(c, problem.InvalidRequest, err.Error())
+			return
+		}
+
+		ownerID := c.GetString("user_id")
+
+		sub, err := dispatcher.CreateSubscription(c.Request.Context(), ownerID, &req)
+		if err != nil {
+			if errors.Is(err, webhook.ErrInvalidSubscription) {
+				problem.Abort(c, problem.InvalidRequest, err.Error())
+				return
+			}
+			logger.Error("Failed to create webhook subscription", zap.Error(err))
+			problem.Abort(c, problem.Internal, "Failed to create webhook subscription")
+			return
+		}
+
+		c.Header("Location", "/api/v1/webhooks/"+sub.ID)
+		c.JSON(http.StatusCreated, sub)
+	}
+}
+
+// handleListWebhooks handles GET /api/v1/webhooks
+func handleListWebhooks(dispatcher *webhook.Dispatcher, logger *zap.Logger, metrics *observability.Metrics) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		subs, err := dispatcher.ListSubscriptions(c.Request.Context(), c.GetString("user_id"))
+		if err != nil {
+			logger.Error("Failed to list webhook subscriptions", zap.Error(err))
+			problem.Abort(c, problem.Internal, "Failed to list webhook subscriptions")
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"subscriptions": subs,
+			"total":         len(subs),
+		})
+	}
+}
+
+// handleGetWebhook handles GET /api/v1/webhooks/:id
+func handleGetWebhook(dispatcher *webhook.Dispatcher, logger *zap.Logger, metrics *observability.Metrics) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		sub, err := dispatcher.GetSubscription(c.Request.Context(), c.Param("id"))
+		if err != nil {
+			abortWebhookError(c, logger, err, "Failed to get webhook subscription")
+			return
+		}
+
+		c.JSON(http.StatusOK, sub)
+	}
+}
+
+// handleDeleteWebhook handles DELETE /api/v1/webhooks/:id
+func handleDeleteWebhook(dispatcher *webhook.Dispatcher, logger *zap.Logger, metrics *observability.Metrics) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := dispatcher.DeleteSubscription(c.Request.Context(), c.Param("id")); err != nil {
+			abortWebhookError(c, logger, err, "Failed to delete webhook subscription")
+			return
+		}
+
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// handleListWebhookDeliveries handles GET /api/v1/webhooks/:id/deliveries
+func handleListWebhookDeliveries(dispatcher *webhook.Dispatcher, logger *zap.Logger, metrics *observability.Metrics) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+		if err != nil || limit < 1 || limit > 500 {
+			problem.Abort(c, problem.InvalidRequest, "limit must be between 1 and 500")
+			return
+		}
+
+		deliveries, err := dispatcher.ListDeliveries(c.Request.Context(), c.Param("id"), limit)
+		if err != nil {
+			abortWebhookError(c, logger, err, "Failed to list webhook deliveries")
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"deliveries": deliveries,
+			"total":      len(deliveries),
+		})
+	}
+}
+
+// abortWebhookError maps dispatcher errors onto problem responses
+func abortWebhookError(c *gin.Context, logger *zap.Logger, err error, message string) {
+	if errors.Is(err, webhook.ErrSubscriptionNotFound) {
+		problem.Abort(c, problem.NotFound, "Webhook subscription not found")
+		return
+	}
+	logger.Error(message, zap.Error(err))
+	problem.Abort(c, problem.Internal, message)
+}
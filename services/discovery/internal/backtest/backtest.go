@@ -0,0 +1,268 @@
+// Package backtest replays historical interactions against the
+// recommendation algorithms offline. Interactions before a cutoff train
+// in-memory copies of the algorithms; each user's first interactions with
+// services after it are the ones a good algorithm would have recommended.
+package backtest
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/org/llm-marketplace/services/discovery/internal/config"
+	"github.com/org/llm-marketplace/services/discovery/internal/postgres"
+	"github.com/org/llm-marketplace/services/discovery/internal/privacy"
+)
+
+// Interaction is a row of user_interactions
+type Interaction struct {
+	UserID    string
+	ServiceID string
+	Type      string
+	Rating    float64
+	Rated     bool // Rating is NULL otherwise
+	Timestamp time.Time
+}
+
+// Service is the part of a catalog service the algorithms read
+type Service struct {
+	ID           string
+	Category     string
+	Tags         []string
+	PricingModel string
+	Status       string
+}
+
+// Dataset is the history a backtest replays
+type Dataset struct {
+	Interactions []Interaction
+	Services     []Service
+}
+
+// Load reads the interactions since since, and the whole catalog, from
+// Postgres. Users are known by their pseudonyms when pseudonyms is set, so
+// rows not yet pseudonymized join the rest of their user's.
+func Load(ctx context.Context, pool *postgres.Pool, since time.Time, pseudonyms *privacy.Pseudonymizer) (*Dataset, error) {
+	rows, err := pool.Query(ctx, `
+		SELECT user_id::text, user_pseudonymized, service_id::text, interaction_type, rating, timestamp
+		FROM user_interactions
+		WHERE timestamp >= $1
+		ORDER BY timestamp
+	`, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query interactions: %w", err)
+	}
+	defer rows.Close()
+
+	ds := &Dataset{}
+	for rows.Next() {
+		var in Interaction
+		var pseudonymized bool
+		var rating *float64
+		if err := rows.Scan(&in.UserID, &pseudonymized, &in.ServiceID, &in.Type, &rating, &in.Timestamp); err != nil {
+			return nil, fmt.Errorf("failed to read interaction: %w", err)
+		}
+		in.UserID = pseudonyms.Canonical(in.UserID, pseudonymized)
+		if rating != nil {
+			in.Rating, in.Rated = *rating, true
+		}
+		ds.Interactions = append(ds.Interactions, in)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read interactions: %w", err)
+	}
+
+	rows, err = pool.Query(ctx, `
+		SELECT id::text, category, COALESCE(tags, '{}'), COALESCE(pricing_model, ''), status
+		FROM services
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query services: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var svc Service
+		if err := rows.Scan(&svc.ID, &svc.Category, &svc.Tags, &svc.PricingModel, &svc.Status); err != nil {
+			return nil, fmt.Errorf("failed to read service: %w", err)
+		}
+		ds.Services = append(ds.Services, svc)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read services: %w", err)
+	}
+	return ds, nil
+}
+
+// Weights weigh the algorithms of the hybrid variant, as
+// recommendations.collaborative_weight, content_weight and popularity_weight do
+type Weights struct {
+	Collaborative float64 `json:"collaborative"`
+	Content       float64 `json:"content"`
+	Popularity    float64 `json:"popularity"`
+}
+
+func (w Weights) String() string {
+	return fmt.Sprintf("%g/%g/%g", w.Collaborative, w.Content, w.Popularity)
+}
+
+// ParseWeights reads hybrid weightings written as
+// "collaborative,content,popularity" and separated by semicolons. Like the
+// configured weights, each must sum to 1.
+func ParseWeights(s string) ([]Weights, error) {
+	var all []Weights
+	for _, set := range strings.Split(s, ";") {
+		if strings.TrimSpace(set) == "" {
+			continue
+		}
+		parts := strings.Split(set, ",")
+		if len(parts) != 3 {
+			return nil, fmt.Errorf("weights %q: want collaborative,content,popularity", set)
+		}
+		var values [3]float64
+		for i, p := range parts {
+			v, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+			if err != nil || v < 0 {
+				return nil, fmt.Errorf("weights %q: %q is not a non-negative number", set, p)
+			}
+			values[i] = v
+		}
+		w := Weights{Collaborative: values[0], Content: values[1], Popularity: values[2]}
+		if sum := values[0] + values[1] + values[2]; sum < 0.99 || sum > 1.01 {
+			return nil, fmt.Errorf("weights %q sum to %.2f, want 1.0", set, sum)
+		}
+		all = append(all, w)
+	}
+	return all, nil
+}
+
+// ParseK reads cut-offs written as "5,10,20"
+func ParseK(s string) ([]int, error) {
+	var ks []int
+	for _, p := range strings.Split(s, ",") {
+		k, err := strconv.Atoi(strings.TrimSpace(p))
+		if err != nil || k <= 0 {
+			return nil, fmt.Errorf("k %q is not a positive integer", p)
+		}
+		ks = append(ks, k)
+	}
+	sort.Ints(ks)
+	return ks, nil
+}
+
+// Options configure a backtest
+type Options struct {
+	Cutoff  time.Time                    // Interactions before it train the algorithms; later ones are held out
+	K       []int                        // Cut-offs of the metrics, ascending
+	Config  config.RecommendationsConfig // The weights and thresholds under test
+	Weights []Weights                    // Hybrid weightings to compare with the configured one
+}
+
+// Variants evaluated besides the hybrid weightings
+const (
+	VariantCollaborative = "collaborative"
+	VariantContent       = "content"
+	VariantTrending      = "trending"
+	VariantHybrid        = "hybrid"
+)
+
+// variant is an algorithm under evaluation, returning a user's top
+// recommendations best first
+type variant struct {
+	name      string
+	weights   *Weights
+	recommend func(userID string, limit int) []string
+}
+
+// Run evaluates every variant on ds. A user is evaluated when they have
+// interactions before the cutoff and use a service after it that they
+// hadn't before.
+func Run(ds *Dataset, opts Options) *Report {
+	m := newModel(ds, opts.Cutoff, opts.Config)
+	relevant := m.heldOut(ds.Interactions)
+	users := make([]string, 0, len(relevant))
+	for user := range relevant {
+		users = append(users, user)
+	}
+	sort.Strings(users)
+
+	report := &Report{
+		GeneratedAt: time.Now().UTC(),
+		Cutoff:      opts.Cutoff,
+		TrainSize:   len(m.train),
+		TestSize:    len(ds.Interactions) - len(m.train),
+		Users:       len(users),
+		CatalogSize: len(m.active),
+		K:           opts.K,
+	}
+
+	configured := Weights{opts.Config.CollaborativeWeight, opts.Config.ContentWeight, opts.Config.PopularityWeight}
+	variants := []variant{
+		{VariantCollaborative, nil, m.collaborative},
+		{VariantContent, nil, m.content},
+		{VariantTrending, nil, m.trendingFor},
+		{VariantHybrid, &configured, m.hybrid(configured)},
+	}
+	for _, w := range opts.Weights {
+		w := w
+		variants = append(variants, variant{VariantHybrid + ":" + w.String(), &w, m.hybrid(w)})
+	}
+
+	maxK := 0
+	if len(opts.K) > 0 {
+		maxK = opts.K[len(opts.K)-1]
+	}
+	for _, v := range variants {
+		report.Variants = append(report.Variants, evaluate(v, users, relevant, opts.K, maxK, m.active))
+	}
+	return report
+}
+
+// evaluate scores one variant's top maxK recommendations for every user.
+// Coverage is the share of active services recommended to anyone.
+func evaluate(v variant, users []string, relevant map[string]map[string]bool, ks []int, maxK int, active map[string]bool) VariantReport {
+	report := VariantReport{Name: v.name, Weights: v.weights, Metrics: make([]Metrics, len(ks))}
+	covered := make([]map[string]bool, len(ks))
+	for i, k := range ks {
+		report.Metrics[i].K = k
+		covered[i] = make(map[string]bool)
+	}
+
+	for _, user := range users {
+		recs := v.recommend(user, maxK)
+		if len(recs) > 0 {
+			report.UsersWithRecommendations++
+		}
+		for i, k := range ks {
+			top := recs
+			if len(top) > k {
+				top = top[:k]
+			}
+			hits := 0
+			for _, id := range top {
+				if active[id] {
+					covered[i][id] = true
+				}
+				if relevant[user][id] {
+					hits++
+				}
+			}
+			report.Metrics[i].Precision += float64(hits) / float64(k)
+			report.Metrics[i].Recall += float64(hits) / float64(len(relevant[user]))
+		}
+	}
+
+	for i := range ks {
+		if len(users) > 0 {
+			report.Metrics[i].Precision /= float64(len(users))
+			report.Metrics[i].Recall /= float64(len(users))
+		}
+		if len(active) > 0 {
+			report.Metrics[i].Coverage = float64(len(covered[i])) / float64(len(active))
+		}
+	}
+	return report
+}
@@ -0,0 +1,330 @@
+package backtest
+
+import (
+	"sort"
+	"time"
+
+	"github.com/org/llm-marketplace/services/discovery/internal/config"
+)
+
+// Limits the recommendation service's queries apply
+const (
+	historyLimit      = 100 // Interactions of the user's history read
+	similarUsersLimit = 50  // Similar users collaborative filtering draws on
+)
+
+// model holds the interactions before the cutoff and answers as the
+// recommendation service's queries would have at the cutoff
+type model struct {
+	cfg       config.RecommendationsConfig
+	cutoff    time.Time
+	train     []Interaction
+	byUser    map[string][]Interaction // Newest first
+	byService map[string][]Interaction
+	services  map[string]Service
+	active    map[string]bool
+	trending  []candidate
+}
+
+// candidate is a scored recommendation
+type candidate struct {
+	id    string
+	score float64
+}
+
+func newModel(ds *Dataset, cutoff time.Time, cfg config.RecommendationsConfig) *model {
+	m := &model{
+		cfg:       cfg,
+		cutoff:    cutoff,
+		byUser:    make(map[string][]Interaction),
+		byService: make(map[string][]Interaction),
+		services:  make(map[string]Service, len(ds.Services)),
+		active:    make(map[string]bool),
+	}
+	for _, svc := range ds.Services {
+		m.services[svc.ID] = svc
+		if svc.Status == "active" {
+			m.active[svc.ID] = true
+		}
+	}
+	for _, in := range ds.Interactions {
+		if in.Timestamp.Before(cutoff) {
+			m.train = append(m.train, in)
+		}
+	}
+	sort.SliceStable(m.train, func(i, j int) bool { return m.train[i].Timestamp.After(m.train[j].Timestamp) })
+	for _, in := range m.train {
+		m.byUser[in.UserID] = append(m.byUser[in.UserID], in)
+		m.byService[in.ServiceID] = append(m.byService[in.ServiceID], in)
+	}
+	m.trending = m.trendingAt(cutoff)
+	return m
+}
+
+// heldOut returns, by user, the services users with a history used for the
+// first time after the cutoff
+func (m *model) heldOut(all []Interaction) map[string]map[string]bool {
+	relevant := make(map[string]map[string]bool)
+	for _, in := range all {
+		if in.Timestamp.Before(m.cutoff) || len(m.byUser[in.UserID]) == 0 || m.used(in.UserID, in.ServiceID) {
+			continue
+		}
+		if relevant[in.UserID] == nil {
+			relevant[in.UserID] = make(map[string]bool)
+		}
+		relevant[in.UserID][in.ServiceID] = true
+	}
+	return relevant
+}
+
+// used reports whether the user used the service before the cutoff
+func (m *model) used(userID, serviceID string) bool {
+	for _, in := range m.byUser[userID] {
+		if in.ServiceID == serviceID {
+			return true
+		}
+	}
+	return false
+}
+
+// history is the user's newest interactions, as the service reads them
+func (m *model) history(userID string) []Interaction {
+	history := m.byUser[userID]
+	if len(history) > historyLimit {
+		history = history[:historyLimit]
+	}
+	return history
+}
+
+// collaborative ranks the services rated 4 or more by users who share the
+// user's services, by average rating then ratings, like
+// Service.collaborativeFiltering. Users with fewer than three interactions
+// get none, as from GetRecommendations.
+func (m *model) collaborative(userID string, limit int) []string {
+	return ids(m.collaborativeCandidates(userID, limit, m.cfg.CollaborativeWeight))
+}
+
+func (m *model) collaborativeCandidates(userID string, limit int, weight float64) []candidate {
+	history := m.history(userID)
+	if len(history) < 3 {
+		return nil
+	}
+	mine := make(map[string]bool, len(history))
+	for _, in := range history {
+		mine[in.ServiceID] = true
+	}
+
+	// Users sharing at least min_common_users interactions with the user's services
+	common := make(map[string]int)
+	for id := range mine {
+		for _, in := range m.byService[id] {
+			if in.UserID != userID {
+				common[in.UserID]++
+			}
+		}
+	}
+	var similar []candidate
+	for user, n := range common {
+		if n >= m.cfg.MinCommonUsers {
+			similar = append(similar, candidate{id: user, score: float64(n)})
+		}
+	}
+	rank(similar)
+	if len(similar) > similarUsersLimit {
+		similar = similar[:similarUsersLimit]
+	}
+
+	type rating struct {
+		sum   float64
+		count int
+	}
+	ratings := make(map[string]*rating)
+	for _, user := range similar {
+		for _, in := range m.byUser[user.id] {
+			if mine[in.ServiceID] || !in.Rated || in.Rating < 4 {
+				continue
+			}
+			r := ratings[in.ServiceID]
+			if r == nil {
+				r = &rating{}
+				ratings[in.ServiceID] = r
+			}
+			r.sum += in.Rating
+			r.count++
+		}
+	}
+	candidates := make([]candidate, 0, len(ratings))
+	for id, r := range ratings {
+		candidates = append(candidates, candidate{id: id, score: r.sum / float64(r.count)})
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.score != b.score {
+			return a.score > b.score
+		}
+		if ra, rb := ratings[a.id].count, ratings[b.id].count; ra != rb {
+			return ra > rb
+		}
+		return a.id < b.id
+	})
+	return scaled(truncate(candidates, limit), weight)
+}
+
+// content ranks active services by their similarity to the service the user
+// used last, like Service.contentBasedRecommendations: 0.5 for its
+// category, 0.3 for a shared tag and 0.2 for its pricing model
+func (m *model) content(userID string, limit int) []string {
+	return ids(m.contentCandidates(userID, limit, m.cfg.ContentWeight))
+}
+
+func (m *model) contentCandidates(userID string, limit int, weight float64) []candidate {
+	history := m.byUser[userID]
+	if len(history) == 0 {
+		return nil
+	}
+	anchor, ok := m.services[history[0].ServiceID]
+	if !ok {
+		return nil
+	}
+	tags := make(map[string]bool, len(anchor.Tags))
+	for _, tag := range anchor.Tags {
+		tags[tag] = true
+	}
+
+	var candidates []candidate
+	for id := range m.active {
+		if id == anchor.ID {
+			continue
+		}
+		svc := m.services[id]
+		score := 0.0
+		if svc.Category == anchor.Category {
+			score += 0.5
+		}
+		for _, tag := range svc.Tags {
+			if tags[tag] {
+				score += 0.3
+				break
+			}
+		}
+		if svc.PricingModel == anchor.PricingModel {
+			score += 0.2
+		}
+		candidates = append(candidates, candidate{id: id, score: score})
+	}
+	rank(candidates)
+	return scaled(truncate(candidates, limit), weight)
+}
+
+// trendingAt ranks the services used at least trending_min_interactions
+// times within trending_window before at, by uses then average rating, like
+// Service.getTrendingServices. Scores are uses over 100.
+func (m *model) trendingAt(at time.Time) []candidate {
+	since := at.Add(-m.cfg.TrendingWindow)
+	type usage struct {
+		count, rated int
+		sum          float64
+	}
+	usages := make(map[string]*usage)
+	for _, in := range m.train {
+		if !in.Timestamp.After(since) {
+			continue
+		}
+		u := usages[in.ServiceID]
+		if u == nil {
+			u = &usage{}
+			usages[in.ServiceID] = u
+		}
+		u.count++
+		if in.Rated {
+			u.rated++
+			u.sum += in.Rating
+		}
+	}
+
+	avg := func(u *usage) float64 {
+		if u.rated == 0 {
+			return 0
+		}
+		return u.sum / float64(u.rated)
+	}
+	var candidates []candidate
+	for id, u := range usages {
+		if u.count >= m.cfg.TrendingMinInteractions {
+			candidates = append(candidates, candidate{id: id, score: float64(u.count) / 100})
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.score != b.score {
+			return a.score > b.score
+		}
+		if ra, rb := avg(usages[a.id]), avg(usages[b.id]); ra != rb {
+			return ra > rb
+		}
+		return a.id < b.id
+	})
+	return candidates
+}
+
+// trendingFor is the same for every user
+func (m *model) trendingFor(_ string, limit int) []string {
+	return ids(truncate(m.trending, limit))
+}
+
+// hybrid merges the three algorithms under w as GetRecommendations does:
+// the first score of a service counts, trending fills at most half, and the
+// merged list is ranked by score
+func (m *model) hybrid(w Weights) func(userID string, limit int) []string {
+	return func(userID string, limit int) []string {
+		var merged []candidate
+		merged = append(merged, m.collaborativeCandidates(userID, limit, w.Collaborative)...)
+		merged = append(merged, m.contentCandidates(userID, limit, w.Content)...)
+		merged = append(merged, scaled(truncate(m.trending, limit/2), w.Popularity)...)
+
+		seen := make(map[string]bool, len(merged))
+		unique := merged[:0:0]
+		for _, c := range merged {
+			if !seen[c.id] {
+				seen[c.id] = true
+				unique = append(unique, c)
+			}
+		}
+		sort.SliceStable(unique, func(i, j int) bool { return unique[i].score > unique[j].score })
+		return ids(truncate(unique, limit))
+	}
+}
+
+// rank sorts candidates by score, then ID for a stable order
+func rank(candidates []candidate) {
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].score != candidates[j].score {
+			return candidates[i].score > candidates[j].score
+		}
+		return candidates[i].id < candidates[j].id
+	})
+}
+
+func truncate(candidates []candidate, limit int) []candidate {
+	if len(candidates) > limit {
+		return candidates[:limit]
+	}
+	return candidates
+}
+
+// scaled returns a copy of candidates with every score times weight
+func scaled(candidates []candidate, weight float64) []candidate {
+	out := make([]candidate, len(candidates))
+	for i, c := range candidates {
+		out[i] = candidate{id: c.id, score: c.score * weight}
+	}
+	return out
+}
+
+func ids(candidates []candidate) []string {
+	out := make([]string, len(candidates))
+	for i, c := range candidates {
+		out[i] = c.id
+	}
+	return out
+}
@@ -0,0 +1,71 @@
+package backtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+)
+
+// Report is the machine-readable result of a backtest
+type Report struct {
+	GeneratedAt time.Time       `json:"generated_at"`
+	Cutoff      time.Time       `json:"cutoff"`
+	TrainSize   int             `json:"train_interactions"`
+	TestSize    int             `json:"test_interactions"`
+	Users       int             `json:"users"`        // Users evaluated
+	CatalogSize int             `json:"catalog_size"` // Active services, the denominator of coverage
+	K           []int           `json:"k"`
+	Variants    []VariantReport `json:"variants"`
+}
+
+// VariantReport is how one algorithm variant did
+type VariantReport struct {
+	Name                     string    `json:"name"`
+	Weights                  *Weights  `json:"weights,omitempty"` // Hybrid variants only
+	UsersWithRecommendations int       `json:"users_with_recommendations"`
+	Metrics                  []Metrics `json:"metrics"` // One per k, ascending
+}
+
+// Metrics are a variant's scores over its top K recommendations, averaged
+// over the users evaluated
+type Metrics struct {
+	K         int     `json:"k"`
+	Precision float64 `json:"precision"` // Share of the K that the user went on to use
+	Recall    float64 `json:"recall"`    // Share of the services the user went on to use that were in the K
+	Coverage  float64 `json:"coverage"`  // Share of active services in anyone's K
+}
+
+// WriteJSON writes the report as indented JSON
+func (r *Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// WriteFile writes the report as JSON to path
+func (r *Report) WriteFile(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create report: %w", err)
+	}
+	if err := r.WriteJSON(f); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+	return f.Close()
+}
+
+// WriteTable writes one row per variant and k, for reading in a terminal
+func (r *Report) WriteTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "variant\tk\tprecision\trecall\tcoverage\tusers\n")
+	for _, v := range r.Variants {
+		for _, m := range v.Metrics {
+			fmt.Fprintf(tw, "%s\t%d\t%.4f\t%.4f\t%.4f\t%d/%d\n", v.Name, m.K, m.Precision, m.Recall, m.Coverage, v.UsersWithRecommendations, r.Users)
+		}
+	}
+	return tw.Flush()
+}
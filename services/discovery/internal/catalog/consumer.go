@@ -0,0 +1,198 @@
+// Package catalog indexes the services the registry publishes. Each catalog
+// event is published to the search index and recorded in PostgreSQL with
+// its registry revision; events at or below the recorded revision are
+// duplicates or out of date and are skipped.
+package catalog
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/org/llm-marketplace/pkg/marketplace"
+	"github.com/org/llm-marketplace/services/discovery/internal/config"
+	"github.com/org/llm-marketplace/services/discovery/internal/elasticsearch"
+	"github.com/segmentio/kafka-go"
+	"go.uber.org/zap"
+)
+
+// ErrPermanent marks an event that can never be applied. It is logged and
+// skipped rather than retried.
+var ErrPermanent = errors.New("catalog event can't be applied")
+
+// Reader fetches catalog events; *kafka.Reader satisfies it
+type Reader interface {
+	FetchMessage(ctx context.Context) (kafka.Message, error)
+	CommitMessages(ctx context.Context, msgs ...kafka.Message) error
+	Close() error
+}
+
+// Store records indexed services
+type Store interface {
+	// Revision returns the registry revision recorded for a service, or 0
+	Revision(ctx context.Context, serviceID string) (int64, error)
+	// Save records the event's service unless a later revision is recorded
+	Save(ctx context.Context, event *marketplace.CatalogEvent) error
+}
+
+// Index reads indexed documents; *elasticsearch.Client satisfies it
+type Index interface {
+	Get(ctx context.Context, id string) (*elasticsearch.ServiceDocument, error)
+}
+
+// Publisher publishes a service version to the search index, embedding it
+// and moving the latest-version flag; *search.Service satisfies it
+type Publisher interface {
+	PublishVersion(ctx context.Context, doc *elasticsearch.ServiceDocument) error
+}
+
+// Consumer applies catalog events in order. Offsets are committed once an
+// event is applied or skipped, so each event is applied at least once.
+type Consumer struct {
+	reader    Reader
+	store     Store
+	index     Index
+	publisher Publisher
+	config    config.CatalogConfig
+	logger    *zap.Logger
+}
+
+// NewConsumer creates a consumer; it reads from Kafka when the catalog is
+// enabled
+func NewConsumer(cfg config.CatalogConfig, store Store, index Index, publisher Publisher, logger *zap.Logger) *Consumer {
+	c := &Consumer{store: store, index: index, publisher: publisher, config: cfg, logger: logger}
+	if cfg.Enabled && len(cfg.KafkaBrokers) > 0 {
+		c.reader = kafka.NewReader(kafka.ReaderConfig{
+			Brokers:  cfg.KafkaBrokers,
+			Topic:    cfg.Topic,
+			GroupID:  cfg.ConsumerGroup,
+			MinBytes: 1,
+			MaxBytes: 10 << 20,
+		})
+	}
+	return c
+}
+
+// SetReader replaces the Kafka reader
+func (c *Consumer) SetReader(reader Reader) {
+	c.reader = reader
+}
+
+// Start applies events until ctx is cancelled. An event that fails for a
+// transient reason is retried with backoff; the events after it wait, so a
+// service's changes are never applied out of order.
+func (c *Consumer) Start(ctx context.Context) {
+	if c.reader == nil {
+		c.logger.Info("Catalog consumer is disabled")
+		return
+	}
+	defer c.reader.Close()
+
+	c.logger.Info("Catalog consumer started", zap.String("topic", c.config.Topic))
+
+	for {
+		msg, err := c.reader.FetchMessage(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				c.logger.Info("Catalog consumer stopped")
+				return
+			}
+			c.logger.Warn("Failed to fetch catalog event", zap.Error(err))
+			if !sleep(ctx, time.Second) {
+				return
+			}
+			continue
+		}
+
+		backoff := c.config.RetryBackoff
+		for {
+			err := c.Apply(ctx, msg.Value)
+			if err == nil {
+				break
+			}
+			if errors.Is(err, ErrPermanent) {
+				c.logger.Error("Skipping catalog event", zap.Int64("offset", msg.Offset), zap.String("key", string(msg.Key)), zap.Error(err))
+				break
+			}
+			c.logger.Warn("Failed to apply catalog event, retrying",
+				zap.Int64("offset", msg.Offset),
+				zap.String("key", string(msg.Key)),
+				zap.Duration("retry_in", backoff),
+				zap.Error(err),
+			)
+			if !sleep(ctx, backoff) {
+				return
+			}
+			backoff = min(backoff*2, c.config.MaxRetryBackoff)
+		}
+
+		if err := c.reader.CommitMessages(ctx, msg); err != nil && ctx.Err() == nil {
+			// The event is applied; on redelivery its revision is already recorded
+			c.logger.Warn("Failed to commit catalog offset", zap.Error(err))
+		}
+	}
+}
+
+// Apply indexes the service in one catalog event. It returns an error
+// wrapping ErrPermanent for an event that can never be applied.
+func (c *Consumer) Apply(ctx context.Context, payload []byte) error {
+	var event marketplace.CatalogEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return fmt.Errorf("%w: malformed event: %v", ErrPermanent, err)
+	}
+	if err := event.Service.Validate(); err != nil {
+		return fmt.Errorf("%w: %v", ErrPermanent, err)
+	}
+	if !elasticsearch.IsValidStatus(event.Status) {
+		return fmt.Errorf("%w: unknown status %q", ErrPermanent, event.Status)
+	}
+
+	revision, err := c.store.Revision(ctx, event.Service.ServiceID)
+	if err != nil {
+		return fmt.Errorf("failed to read revision: %w", err)
+	}
+	if event.Revision <= revision {
+		c.logger.Debug("Skipping out-of-date catalog event",
+			zap.String("service_id", event.Service.ServiceID),
+			zap.Int64("revision", event.Revision),
+			zap.Int64("indexed_revision", revision),
+		)
+		return nil
+	}
+
+	existing, err := c.index.Get(ctx, event.Service.ServiceID)
+	if errors.Is(err, elasticsearch.ErrNotFound) {
+		existing = nil
+	} else if err != nil {
+		return fmt.Errorf("failed to read indexed service: %w", err)
+	}
+
+	// Index first: if recording the revision then fails, the retry indexes
+	// the same document again
+	doc := Document(&event, existing)
+	if err := c.publisher.PublishVersion(ctx, doc); err != nil {
+		return fmt.Errorf("failed to index service: %w", err)
+	}
+	if err := c.store.Save(ctx, &event); err != nil {
+		return err
+	}
+
+	c.logger.Info("Indexed registry service",
+		zap.String("service_id", doc.ID),
+		zap.String("event", event.Type),
+		zap.String("status", doc.Status),
+		zap.Int64("revision", event.Revision),
+	)
+	return nil
+}
+
+func sleep(ctx context.Context, d time.Duration) bool {
+	select {
+	case <-time.After(d):
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,130 @@
+package catalog
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/org/llm-marketplace/pkg/marketplace"
+	"github.com/org/llm-marketplace/services/discovery/internal/elasticsearch"
+)
+
+// Document builds the search document for an event. Fields the registry
+// doesn't own, such as metrics, access rules and the creation time, are kept
+// from existing, the currently indexed document if there is one. Its vectors
+// are kept while the text they were computed from is unchanged. Private
+// services always belong to their tenant and are visible only inside it.
+func Document(event *marketplace.CatalogEvent, existing *elasticsearch.ServiceDocument) *elasticsearch.ServiceDocument {
+	svc := event.Service
+	doc := &elasticsearch.ServiceDocument{
+		ID:             svc.ServiceID,
+		Name:           svc.Name,
+		Description:    svc.Description,
+		Category:       svc.Category,
+		Tags:           svc.Tags,
+		Dependencies:   svc.Dependencies,
+		Kind:           svc.Kind,
+		PromptTemplate: svc.PromptTemplate,
+		Dataset:        svc.Dataset,
+		FineTuning:     svc.FineTuning,
+		ServingRegions: svc.ServingRegions,
+		Provider:       event.Provider,
+		Status:         event.Status,
+		// Upheld reports are counted by the registry
+		UpheldReports: event.UpheldReports,
+		Expiries:      event.ComplianceExpiries,
+		// Every version of a provider's service shares a key
+		ServiceKey: event.Provider.ID + ":" + strings.ToLower(svc.Name),
+		Version: &elasticsearch.VersionInfo{
+			Number:     svc.Version,
+			Stable:     !strings.Contains(svc.Version, "-"), // Semver pre-releases carry a suffix
+			ReleasedAt: event.OccurredAt,
+		},
+		CreatedAt: event.OccurredAt,
+		UpdatedAt: event.OccurredAt,
+	}
+	for _, c := range svc.Capabilities {
+		doc.Capabilities = append(doc.Capabilities, c.Name)
+	}
+	if svc.Endpoint != nil {
+		doc.Endpoint = svc.Endpoint.URL
+	}
+	if svc.Pricing != nil {
+		doc.Pricing = *svc.Pricing
+	}
+	if svc.SLA != nil {
+		doc.SLA = *svc.SLA
+	}
+	if svc.Compliance != nil {
+		doc.Compliance = *svc.Compliance
+	}
+	if doc.Status == elasticsearch.StatusDeprecated || doc.Status == elasticsearch.StatusRetired {
+		doc.Deprecation = &elasticsearch.DeprecationInfo{DeprecatedAt: event.OccurredAt}
+	}
+
+	if svc.Private() {
+		doc.TenantID = svc.TenantID
+		doc.Access = &elasticsearch.AccessInfo{Visibility: elasticsearch.VisibilityTenant, OwnerTenant: svc.TenantID}
+	}
+
+	if existing == nil {
+		doc.Changelog = appendChanges(nil, event.Changes)
+		return doc
+	}
+	doc.CreatedAt = existing.CreatedAt
+	doc.Metrics = existing.Metrics
+	if !svc.Private() {
+		doc.Access = existing.Access
+		doc.TenantID = existing.TenantID
+	}
+	doc.Metadata = existing.Metadata
+	doc.PolicyCompliance = existing.PolicyCompliance // Replaced on indexing unless the policy engine is down
+	doc.Changelog = appendChanges(existing.Changelog, event.Changes)
+	if existing.Version != nil {
+		doc.Version.ReleasedAt = existing.Version.ReleasedAt
+		doc.Version.Changelog = existing.Version.Changelog
+	}
+	if doc.Deprecation != nil && existing.Deprecation != nil {
+		doc.Deprecation = existing.Deprecation
+	}
+	if sameText(doc, existing) {
+		doc.Embedding = existing.Embedding
+		doc.Embeddings = existing.Embeddings
+		doc.EmbeddingModels = existing.EmbeddingModels
+	}
+	return doc
+}
+
+// maxChangelog bounds the changes kept on a document; the registry keeps
+// the full changelog
+const maxChangelog = 20
+
+// appendChanges puts the changes of an event, oldest first, ahead of a
+// changelog kept newest first. Changes of a revision already logged, as
+// when an event is redelivered, are skipped.
+func appendChanges(changelog, changes []marketplace.ServiceChange) []marketplace.ServiceChange {
+	if len(changes) == 0 {
+		return changelog
+	}
+	if len(changelog) > 0 && changes[0].Revision <= changelog[0].Revision {
+		return changelog
+	}
+	merged := make([]marketplace.ServiceChange, 0, len(changes)+len(changelog))
+	for i := len(changes) - 1; i >= 0; i-- {
+		merged = append(merged, changes[i])
+	}
+	merged = append(merged, changelog...)
+	if len(merged) > maxChangelog {
+		merged = merged[:maxChangelog]
+	}
+	return merged
+}
+
+// sameText reports whether the fields embeddings are computed from are equal
+func sameText(a, b *elasticsearch.ServiceDocument) bool {
+	return a.Name == b.Name &&
+		a.Description == b.Description &&
+		a.Category == b.Category &&
+		a.Provider.Name == b.Provider.Name &&
+		slices.Equal(a.Tags, b.Tags) &&
+		slices.Equal(a.Capabilities, b.Capabilities)
+}
@@ -0,0 +1,122 @@
+package catalog
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/org/llm-marketplace/pkg/marketplace"
+	"github.com/org/llm-marketplace/services/discovery/internal/postgres"
+)
+
+// PGStore records indexed services in the services table
+type PGStore struct {
+	pgPool *postgres.Pool
+}
+
+// NewStore creates a store over pgPool
+func NewStore(pgPool *postgres.Pool) *PGStore {
+	return &PGStore{pgPool: pgPool}
+}
+
+// Revision returns the registry revision recorded for a service, or 0 when
+// the service is unknown or was not indexed from the registry
+func (s *PGStore) Revision(ctx context.Context, serviceID string) (int64, error) {
+	var revision int64
+	err := s.pgPool.QueryRow(ctx, `SELECT COALESCE(registry_revision, 0) FROM services WHERE id = $1`, serviceID).Scan(&revision)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, nil
+	}
+	if isDataError(err) {
+		return 0, fmt.Errorf("%w: %v", ErrPermanent, err)
+	}
+	return revision, err
+}
+
+// Save upserts the event's service. A row already at a later revision is
+// left as it is.
+func (s *PGStore) Save(ctx context.Context, event *marketplace.CatalogEvent) error {
+	svc := event.Service
+	capabilities, err := json.Marshal(svc.Capabilities)
+	if err != nil {
+		return fmt.Errorf("%w: failed to encode capabilities: %v", ErrPermanent, err)
+	}
+	var pricing marketplace.PricingInfo
+	if svc.Pricing != nil {
+		pricing = *svc.Pricing
+	}
+	var sla marketplace.SLAInfo
+	if svc.SLA != nil {
+		sla = *svc.SLA
+	}
+	var compliance marketplace.ComplianceInfo
+	if svc.Compliance != nil {
+		compliance = *svc.Compliance
+	}
+	var fineTuning marketplace.FineTuningOffering
+	if svc.FineTuning != nil {
+		fineTuning = *svc.FineTuning
+	}
+
+	query := `
+		INSERT INTO services (
+			id, registry_id, name, version, description, provider_id, provider_name, provider_verified,
+			category, tags, capabilities, pricing_model, pricing_rate, pricing_unit,
+			sla_availability, sla_max_latency_ms, compliance_level, status, registry_revision,
+			kind, base_models, turnaround_hours, created_at, updated_at, published_at
+		)
+		VALUES ($1, $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, NULLIF($11, ''), $12, NULLIF($13, ''),
+			$14, $15, NULLIF($16, ''), $17, $18, $20, $21, NULLIF($22, 0), $19, $19, $19)
+		ON CONFLICT (id) DO UPDATE SET
+			name = EXCLUDED.name,
+			version = EXCLUDED.version,
+			description = EXCLUDED.description,
+			provider_id = EXCLUDED.provider_id,
+			provider_name = EXCLUDED.provider_name,
+			provider_verified = EXCLUDED.provider_verified,
+			category = EXCLUDED.category,
+			tags = EXCLUDED.tags,
+			capabilities = EXCLUDED.capabilities,
+			pricing_model = EXCLUDED.pricing_model,
+			pricing_rate = EXCLUDED.pricing_rate,
+			pricing_unit = EXCLUDED.pricing_unit,
+			sla_availability = EXCLUDED.sla_availability,
+			sla_max_latency_ms = EXCLUDED.sla_max_latency_ms,
+			compliance_level = EXCLUDED.compliance_level,
+			status = EXCLUDED.status,
+			registry_revision = EXCLUDED.registry_revision,
+			kind = EXCLUDED.kind,
+			base_models = EXCLUDED.base_models,
+			turnaround_hours = EXCLUDED.turnaround_hours,
+			updated_at = EXCLUDED.updated_at
+		WHERE services.registry_revision IS NULL OR services.registry_revision < EXCLUDED.registry_revision
+	`
+
+	_, err = s.pgPool.Exec(ctx, query,
+		svc.ServiceID, svc.Name, svc.Version, svc.Description, event.Provider.ID, event.Provider.Name, event.Provider.Verified,
+		svc.Category, svc.Tags, capabilities, pricing.Model, pricing.Rate, pricing.Unit,
+		sla.Availability, sla.MaxLatencyMS, compliance.Level, event.Status, event.Revision,
+		event.OccurredAt, svc.ItemKind(), fineTuning.BaseModels, fineTuning.TurnaroundHours,
+	)
+	if isDataError(err) {
+		return fmt.Errorf("%w: %v", ErrPermanent, err)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save service: %w", err)
+	}
+	return nil
+}
+
+// isDataError reports whether Postgres rejected the values themselves (SQL
+// state classes 22 and 23), which retrying can't fix
+func isDataError(err error) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+	return strings.HasPrefix(pgErr.Code, "22") || strings.HasPrefix(pgErr.Code, "23")
+}
@@ -2,25 +2,47 @@ package config
 
 import (
 	"fmt"
+	"net"
 	"os"
+	"regexp"
 	"strings"
 	"time"
 
+	"github.com/org/llm-marketplace/pkg/marketplace/envconfig"
+	"github.com/org/llm-marketplace/pkg/marketplace/flags"
+	"github.com/org/llm-marketplace/pkg/marketplace/secrets"
 	"gopkg.in/yaml.v3"
 )
 
 type Config struct {
-	Server            ServerConfig            `yaml:"server"`
-	Elasticsearch     ElasticsearchConfig     `yaml:"elasticsearch"`
-	Redis             RedisConfig             `yaml:"redis"`
-	Postgres          PostgresConfig          `yaml:"postgres"`
-	EmbeddingService  EmbeddingServiceConfig  `yaml:"embedding_service"`
-	Search            SearchConfig            `yaml:"search"`
-	Recommendations   RecommendationsConfig   `yaml:"recommendations"`
-	Performance       PerformanceConfig       `yaml:"performance"`
-	Observability     ObservabilityConfig     `yaml:"observability"`
-	PolicyEngine      PolicyEngineConfig      `yaml:"policy_engine"`
-	AnalyticsHub      AnalyticsHubConfig      `yaml:"analytics_hub"`
+	Server           ServerConfig           `yaml:"server"`
+	Elasticsearch    ElasticsearchConfig    `yaml:"elasticsearch"`
+	Redis            RedisConfig            `yaml:"redis"`
+	Postgres         PostgresConfig         `yaml:"postgres"`
+	EmbeddingService EmbeddingServiceConfig `yaml:"embedding_service"`
+	Search           SearchConfig           `yaml:"search"`
+	Recommendations  RecommendationsConfig  `yaml:"recommendations"`
+	Features         FeaturesConfig         `yaml:"features"`
+	Privacy          PrivacyConfig          `yaml:"privacy"`
+	Assistant        AssistantConfig        `yaml:"assistant"`
+	Performance      PerformanceConfig      `yaml:"performance"`
+	Observability    ObservabilityConfig    `yaml:"observability"`
+	PolicyEngine     PolicyEngineConfig     `yaml:"policy_engine"`
+	AnalyticsHub     AnalyticsHubConfig     `yaml:"analytics_hub"`
+	Catalog          CatalogConfig          `yaml:"catalog"`
+	SLAMonitoring    SLAMonitoringConfig    `yaml:"sla_monitoring"`
+	Export           ExportConfig           `yaml:"export"`
+	Snapshots        SnapshotConfig         `yaml:"snapshots"`
+	Feed             FeedConfig             `yaml:"feed"`
+	Webhooks         WebhookConfig          `yaml:"webhooks"`
+	Entitlements     EntitlementsConfig     `yaml:"entitlements"`
+	Subscriptions    SubscriptionsConfig    `yaml:"subscriptions"`
+	Quotas           QuotasConfig           `yaml:"quotas"`
+	Secrets          secrets.Config         `yaml:"secrets"`
+	FeatureFlags     FeatureFlagsConfig     `yaml:"feature_flags"`
+
+	// live holds the settings a Watcher can change while the service runs
+	live *reloadable
 }
 
 type ServerConfig struct {
@@ -30,21 +52,95 @@ type ServerConfig struct {
 	ReadTimeout  time.Duration `yaml:"read_timeout"`
 	WriteTimeout time.Duration `yaml:"write_timeout"`
 	IdleTimeout  time.Duration `yaml:"idle_timeout"`
+
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"` // How long in-flight requests get to finish
+	DrainTimeout    time.Duration `yaml:"drain_timeout"`    // How long background jobs get to finish afterwards
+
+	Startup   StartupConfig   `yaml:"startup"`
+	Readiness ReadinessConfig `yaml:"readiness"`
+
+	// HotReload watches the config file and applies changes to ranking
+	// weights, cache TTLs and feature flags without a restart
+	HotReload bool `yaml:"hot_reload"`
+}
+
+// StartupConfig controls how long startup waits for Postgres, Redis and
+// Elasticsearch before giving up
+type StartupConfig struct {
+	MaxWait        time.Duration `yaml:"max_wait"`        // Per dependency; unset waits 2m
+	InitialBackoff time.Duration `yaml:"initial_backoff"` // Delay after the first failed attempt, doubled after each
+	MaxBackoff     time.Duration `yaml:"max_backoff"`
+}
+
+// ReadinessConfig controls the dependency checks behind /ready
+type ReadinessConfig struct {
+	Interval time.Duration `yaml:"interval"` // How often dependencies are checked
+	Timeout  time.Duration `yaml:"timeout"`  // Per check; a check that times out fails
+
+	// Dependencies overrides the built-in settings of postgres, redis and
+	// elasticsearch
+	Dependencies map[string]DependencyCheckConfig `yaml:"dependencies"`
+}
+
+// DependencyCheckConfig sets how one dependency affects readiness
+type DependencyCheckConfig struct {
+	Critical      bool          `yaml:"critical"`       // Failing makes the service unhealthy rather than degraded
+	SlowThreshold time.Duration `yaml:"slow_threshold"` // Slower checks make the service degraded
 }
 
 type ElasticsearchConfig struct {
-	Addresses        []string      `yaml:"addresses"`
-	Username         string        `yaml:"username"`
-	Password         string        `yaml:"password"`
-	IndexName        string        `yaml:"index_name"`
-	MaxRetries       int           `yaml:"max_retries"`
-	RetryBackoff     time.Duration `yaml:"retry_backoff"`
-	EnableMetrics    bool          `yaml:"enable_metrics"`
-	Shards           int           `yaml:"shards"`
-	Replicas         int           `yaml:"replicas"`
-	RefreshInterval  string        `yaml:"refresh_interval"`
-	VectorDimensions int           `yaml:"vector_dimensions"`
-	Similarity       string        `yaml:"similarity"`
+	Addresses        []string          `yaml:"addresses"`
+	Username         string            `yaml:"username"`
+	Password         string            `yaml:"password"`
+	IndexName        string            `yaml:"index_name"`
+	MaxRetries       int               `yaml:"max_retries"`
+	RetryBackoff     time.Duration     `yaml:"retry_backoff"`     // First retry delay; doubled per retry with full jitter
+	MaxRetryBackoff  time.Duration     `yaml:"max_retry_backoff"` // Cap on the retry delay
+	Sniff            bool              `yaml:"sniff"`             // Discover cluster nodes beyond the configured addresses
+	SniffInterval    time.Duration     `yaml:"sniff_interval"`    // How often to rediscover nodes; 0 sniffs only at startup
+	Timeouts         ESTimeoutsConfig  `yaml:"timeouts"`
+	Health           ESHealthConfig    `yaml:"health"`
+	EnableMetrics    bool              `yaml:"enable_metrics"`
+	Shards           int               `yaml:"shards"`
+	Replicas         int               `yaml:"replicas"`
+	RefreshInterval  string            `yaml:"refresh_interval"`
+	VectorDimensions int               `yaml:"vector_dimensions"`
+	Similarity       string            `yaml:"similarity"`
+	EntityIndices    map[string]string `yaml:"entity_indices"` // Index per non-service entity type
+	Tenancy          TenancyConfig     `yaml:"tenancy"`
+	Bulk             BulkConfig        `yaml:"bulk"`
+}
+
+// TenancyConfig partitions the services index into per-tenant catalogs by a
+// tenant_id field. Tenant documents are routed by tenant so a tenant's
+// searches hit a single shard, unless the shared catalog is also visible.
+type TenancyConfig struct {
+	Enabled       bool `yaml:"enabled"`
+	SharedCatalog bool `yaml:"shared_catalog"` // Tenants also see services without a tenant_id
+}
+
+// ESTimeoutsConfig bounds each call to Elasticsearch, including the client's
+// retries. Zero leaves a call bounded only by its caller's context.
+type ESTimeoutsConfig struct {
+	Connect time.Duration `yaml:"connect"` // Dialing a node
+	Read    time.Duration `yaml:"read"`    // Searches and document reads
+	Write   time.Duration `yaml:"write"`   // Single document writes
+	Bulk    time.Duration `yaml:"bulk"`    // Each bulk request
+	Health  time.Duration `yaml:"health"`  // Each health probe
+}
+
+// ESHealthConfig controls the background probe behind /ready
+type ESHealthConfig struct {
+	Interval         time.Duration `yaml:"interval"`
+	FailureThreshold int           `yaml:"failure_threshold"` // Consecutive failed probes before reporting unhealthy
+}
+
+// BulkConfig controls how bulk indexing splits and retries requests
+type BulkConfig struct {
+	MaxBytes       int           `yaml:"max_bytes"`       // Largest request body; batches are split to fit
+	MaxAttempts    int           `yaml:"max_attempts"`    // Tries per document, including the first
+	InitialBackoff time.Duration `yaml:"initial_backoff"` // Doubled per retry with full jitter
+	MaxBackoff     time.Duration `yaml:"max_backoff"`
 }
 
 type RedisConfig struct {
@@ -64,9 +160,23 @@ type PostgresConfig struct {
 	User            string        `yaml:"user"`
 	Password        string        `yaml:"password"`
 	SSLMode         string        `yaml:"ssl_mode"`
-	MaxOpenConns    int           `yaml:"max_open_conns"`
-	MaxIdleConns    int           `yaml:"max_idle_conns"`
+	MaxConns        int           `yaml:"max_conns"`
+	MinConns        int           `yaml:"min_conns"` // Connections kept open while idle
 	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime"`
+	ConnMaxIdleTime time.Duration `yaml:"conn_max_idle_time"`
+
+	// StatementTimeout is applied server side to every statement; zero
+	// leaves the server's default
+	StatementTimeout time.Duration `yaml:"statement_timeout"`
+
+	Migrations MigrationsConfig `yaml:"migrations"`
+}
+
+// MigrationsConfig controls what happens at startup when the database schema
+// is behind or differs from the migrations built into the service
+type MigrationsConfig struct {
+	AutoMigrate bool `yaml:"auto_migrate"`  // Apply pending migrations at startup
+	FailOnDrift bool `yaml:"fail_on_drift"` // Refuse to start instead of logging a warning
 }
 
 type EmbeddingServiceConfig struct {
@@ -74,97 +184,704 @@ type EmbeddingServiceConfig struct {
 	Model     string        `yaml:"model"`
 	Timeout   time.Duration `yaml:"timeout"`
 	BatchSize int           `yaml:"batch_size"`
+
+	MaxAttempts    int           `yaml:"max_attempts"`    // Tries per batch, including the first
+	InitialBackoff time.Duration `yaml:"initial_backoff"` // Doubled per retry with full jitter
+	MaxBackoff     time.Duration `yaml:"max_backoff"`
+	Concurrency    int           `yaml:"concurrency"` // Batches in flight at once
+
+	QueryBudget time.Duration `yaml:"query_budget"` // Longest a search waits for a query embedding before skipping semantic matching
+
+	// Models lists every model documents are embedded with; more than one while
+	// migrating. Without it, model fills the embedding field at vector_dimensions.
+	Models     []EmbeddingModelConfig `yaml:"models"`
+	QueryModel string                 `yaml:"query_model"` // Name of the model searches use; the first model if empty
+
+	Backend  string               `yaml:"backend"`  // "remote" (default) or "local"
+	Fallback string               `yaml:"fallback"` // "local" to use the in-process model when the remote service fails
+	Local    LocalEmbeddingConfig `yaml:"local"`
+
+	Health EmbeddingHealthConfig `yaml:"health"`
+}
+
+// EmbeddingHealthConfig configures the background probe of the remote
+// embedding service. While it fails, readiness is degraded and searches skip
+// the semantic clause instead of waiting out the query budget.
+type EmbeddingHealthConfig struct {
+	Path             string        `yaml:"path"` // Probed with GET, relative to url
+	Interval         time.Duration `yaml:"interval"`
+	Timeout          time.Duration `yaml:"timeout"`
+	FailureThreshold int           `yaml:"failure_threshold"` // Consecutive failed probes before the service counts as down
+}
+
+// EmbeddingModelConfig is one embedding model and the vector field it fills
+type EmbeddingModelConfig struct {
+	Name       string `yaml:"name"`  // Vector field embeddings.<name>; never reuse a name for another model
+	Model      string `yaml:"model"` // Model requested from the embedding service
+	Dimensions int    `yaml:"dimensions"`
+}
+
+// LocalEmbeddingConfig configures the in-process ONNX embedding backend. The
+// ONNX file must be an export of Model, or its vectors won't be comparable with
+// the indexed ones.
+type LocalEmbeddingConfig struct {
+	Model          string `yaml:"model"`            // Model the export was made from; embedding_service.model if empty
+	ModelPath      string `yaml:"model_path"`       // ONNX sentence-transformer
+	VocabPath      string `yaml:"vocab_path"`       // WordPiece vocabulary (vocab.txt) for the model
+	RuntimePath    string `yaml:"runtime_path"`     // onnxruntime shared library; the system default if empty
+	MaxTokens      int    `yaml:"max_tokens"`       // Longer inputs are truncated
+	IntraOpThreads int    `yaml:"intra_op_threads"` // 0 lets onnxruntime decide
 }
 
 type SearchConfig struct {
-	MaxResults      int                    `yaml:"max_results"`
-	DefaultResults  int                    `yaml:"default_results"`
-	RankingWeights  RankingWeights         `yaml:"ranking_weights"`
-	FuzzyEnabled    bool                   `yaml:"fuzzy_enabled"`
-	FuzzyDistance   int                    `yaml:"fuzzy_distance"`
-	SemanticEnabled bool                   `yaml:"semantic_enabled"`
-	SemanticThreshold float64              `yaml:"semantic_threshold"`
-	HybridAlpha     float64                `yaml:"hybrid_alpha"`
+	MaxResults           int                        `yaml:"max_results"`
+	DefaultResults       int                        `yaml:"default_results"`
+	RankingWeights       RankingWeights             `yaml:"ranking_weights"`
+	FuzzyEnabled         bool                       `yaml:"fuzzy_enabled"`
+	FuzzyDistance        int                        `yaml:"fuzzy_distance"`
+	SemanticEnabled      bool                       `yaml:"semantic_enabled"`
+	SemanticThreshold    float64                    `yaml:"semantic_threshold"`
+	HybridAlpha          float64                    `yaml:"hybrid_alpha"`
+	Autocomplete         AutocompleteConfig         `yaml:"autocomplete"`
+	Facets               []FacetConfig              `yaml:"facets"` // Aggregations returned with search results; see FacetDefinitions
+	ReportDemotion       ReportDemotionConfig       `yaml:"report_demotion"`
+	QueryUnderstanding   QueryUnderstandingConfig   `yaml:"query_understanding"`
+	QueryPipeline        QueryPipelineConfig        `yaml:"query_pipeline"`
+	Session              SessionRerankConfig        `yaml:"session"`
+	RegionRanking        RegionRankingConfig        `yaml:"region_ranking"`
+	StaleWhileRevalidate StaleWhileRevalidateConfig `yaml:"stale_while_revalidate"`
+	Warmup               WarmupConfig               `yaml:"warmup"`
+	Guardrails           GuardrailsConfig           `yaml:"guardrails"`
+	LatencyBudget        LatencyBudgetConfig        `yaml:"latency_budget"`
+	DebugCapture         DebugCaptureConfig         `yaml:"debug_capture"`
+	RankingOverrides     RankingOverridesConfig     `yaml:"ranking_overrides"`
+}
+
+// GuardrailsConfig rejects searches too costly to run, and bounds how long
+// Elasticsearch spends on the rest. A limit of 0 is unlimited.
+type GuardrailsConfig struct {
+	Enabled        bool `yaml:"enabled"`
+	MaxFilters     int  `yaml:"max_filters"`      // Filter values across every filter
+	MaxPageSize    int  `yaml:"max_page_size"`    // Larger pages are rejected rather than cut to max_results
+	MaxOffset      int  `yaml:"max_offset"`       // Results skipped to reach the page
+	MaxQueryLength int  `yaml:"max_query_length"` // Characters in the query
+	MaxFuzzyTerms  int  `yaml:"max_fuzzy_terms"`  // Query words fuzzy matched, each expanded to every term within its edit distance
+
+	// Timeout is sent to Elasticsearch, whose shards stop collecting hits
+	// after it. With partial_results, what they found by then is returned
+	// marked partial; otherwise the search fails.
+	Timeout        time.Duration `yaml:"timeout"`
+	PartialResults bool          `yaml:"partial_results"`
+}
+
+// LatencyBudgetConfig bounds a search end to end. Each dependency it calls
+// gets a share of the time left when the call starts, so a slow stage leaves
+// less for those after it rather than pushing the search past its budget.
+type LatencyBudgetConfig struct {
+	Total              time.Duration `yaml:"total"`               // 0 leaves searches bounded only by the caller
+	EmbeddingShare     float64       `yaml:"embedding_share"`     // For the query embedding, up to embedding_service.query_budget
+	CacheShare         float64       `yaml:"cache_share"`         // For each search cache read
+	ElasticsearchShare float64       `yaml:"elasticsearch_share"` // Sent as the search timeout, when shorter than the guardrails timeout
+}
+
+// DebugCaptureConfig records searches that ask for it with debug: the
+// Elasticsearch query, its raw hits and the scores after each ranking stage,
+// keyed by request ID, so relevance bugs can be reproduced offline
+type DebugCaptureConfig struct {
+	Enabled bool          `yaml:"enabled"`
+	TTL     time.Duration `yaml:"ttl"` // How long a capture is kept in Redis
+}
+
+// RankingOverridesConfig lets privileged callers send their own
+// ranking_weights on a search, to try weightings out without changing the
+// configured ones. Operators are privileged, and so are callers of the
+// listed tenants; both are as the gateway authenticated them.
+type RankingOverridesConfig struct {
+	Enabled bool     `yaml:"enabled"`
+	Tenants []string `yaml:"tenants"`
+}
+
+// SessionRerankConfig re-ranks searches by what the same browsing session
+// clicked and dismissed, apart from long-term personalization
+type SessionRerankConfig struct {
+	Enabled       bool          `yaml:"enabled"`
+	TTL           time.Duration `yaml:"ttl"`            // Signals expire this long after the session's last one
+	CategoryBoost float64       `yaml:"category_boost"` // Score boost for a category that drew every click; shared pro rata otherwise
+	DismissFactor float64       `yaml:"dismiss_factor"` // Multiplies the score of services from a dismissed provider
+}
+
+// RegionRankingConfig ranks services served from the caller's region by the
+// median latency measured there
+type RegionRankingConfig struct {
+	Enabled          bool          `yaml:"enabled"`
+	LatencyBoost     float64       `yaml:"latency_boost"`     // Score boost for a service with no latency in the region; none at reference_latency and over
+	ReferenceLatency time.Duration `yaml:"reference_latency"` // Median latency from which a service served from the region gains nothing
+}
+
+// StaleWhileRevalidateConfig keeps cached search responses past their TTL in
+// redis.cache_ttl, the soft TTL. Until stale_ttl after it, a stale response
+// is served at once and refreshed in the background; only once that passes
+// too does a search wait for Elasticsearch.
+type StaleWhileRevalidateConfig struct {
+	Enabled        bool          `yaml:"enabled"`
+	StaleTTL       time.Duration `yaml:"stale_ttl"`       // How long past the soft TTL a response can still be served
+	RefreshTimeout time.Duration `yaml:"refresh_timeout"` // Bounds a background refresh, and how long replicas wait for one another's
+}
+
+// WarmupConfig primes the caches when an instance starts, before it reports
+// ready, so a rollout doesn't send its first requests to Elasticsearch:
+// categories, tags and the first page of the most popular queries.
+type WarmupConfig struct {
+	Enabled     bool          `yaml:"enabled"`
+	TopQueries  int           `yaml:"top_queries"` // Popular queries searched, ranked as autocomplete ranks them
+	Concurrency int           `yaml:"concurrency"` // Queries searched at once
+	Timeout     time.Duration `yaml:"timeout"`     // The instance reports ready after this even if priming hasn't finished
+}
+
+// QueryPipelineConfig rewrites queries before they are searched. The
+// enabled stages run in the order lowercase, stopwords, acronyms, synonyms,
+// LLM rewrite.
+type QueryPipelineConfig struct {
+	Lowercase         bool                `yaml:"lowercase"`
+	RemoveStopwords   bool                `yaml:"remove_stopwords"`
+	Stopwords         []string            `yaml:"stopwords"` // Kept when every word of a query is one
+	ExpandAcronyms    bool                `yaml:"expand_acronyms"`
+	Acronyms          map[string]string   `yaml:"acronyms"` // Expansion by acronym; the acronym is kept too
+	InjectSynonyms    bool                `yaml:"inject_synonyms"`
+	Synonyms          map[string][]string `yaml:"synonyms"`            // Added to queries with the word
+	LLMRewrite        bool                `yaml:"llm_rewrite"`         // Through the assistant model; needs the openai backend
+	LLMRewriteTimeout time.Duration       `yaml:"llm_rewrite_timeout"` // The query is searched as is when the model takes longer
+}
+
+// QueryUnderstandingConfig reads filters out of natural-language queries,
+// such as a price cap from "cheap" or a latency bound from "under 200ms"
+type QueryUnderstandingConfig struct {
+	Enabled            bool                `yaml:"enabled"`
+	CheapPrice         float64             `yaml:"cheap_price"`         // Price cap "cheap" and its synonyms stand for; 0 ignores them
+	Capabilities       map[string][]string `yaml:"capabilities"`        // Synonyms by capability name
+	EmbeddingThreshold float64             `yaml:"embedding_threshold"` // Similarity from which a word reads as the nearest capability; 0 matches synonyms only
+}
+
+// ReportDemotionConfig lowers the ranking of services with repeated upheld
+// consumer reports
+type ReportDemotionConfig struct {
+	Threshold int     `yaml:"threshold"` // Upheld reports from which a service is demoted; 0 disables demotion
+	Factor    float64 `yaml:"factor"`    // Multiplies the ranking score of a demoted service
+}
+
+// Facet aggregation types
+const (
+	FacetTerms         = "terms"
+	FacetAvg           = "avg"
+	FacetHistogram     = "histogram"
+	FacetAutoHistogram = "auto_histogram"
+	FacetRange         = "range"
+)
+
+// FacetConfig defines one aggregation returned with search results
+type FacetConfig struct {
+	Name     string             `yaml:"name"` // Key in the response aggregations
+	Field    string             `yaml:"field"`
+	Type     string             `yaml:"type"`     // terms, avg, histogram, auto_histogram or range
+	Size     int                `yaml:"size"`     // Buckets returned by terms and auto_histogram facets
+	Interval float64            `yaml:"interval"` // Bucket width of histogram facets
+	Ranges   []FacetRangeConfig `yaml:"ranges"`   // Buckets of range facets
+}
+
+// FacetRangeConfig is one bucket of a range facet; From is inclusive and To
+// exclusive, and either may be left open
+type FacetRangeConfig struct {
+	Key   string   `yaml:"key"`
+	Label string   `yaml:"label"` // Display text returned with the bucket
+	From  *float64 `yaml:"from"`
+	To    *float64 `yaml:"to"`
+}
+
+// defaultFacets apply when search.facets is not configured
+var defaultFacets = []FacetConfig{
+	{Name: "categories", Field: "category", Type: FacetTerms, Size: 50},
+	{Name: "tags", Field: "tags", Type: FacetTerms, Size: 100},
+	{Name: "pricing_models", Field: "pricing.model", Type: FacetTerms, Size: 10},
+	{Name: "compliance_levels", Field: "compliance.level", Type: FacetTerms, Size: 10},
+	{Name: "kinds", Field: "kind", Type: FacetTerms, Size: 5},
+	{Name: "licenses", Field: "dataset.license", Type: FacetTerms, Size: 50},
+	{Name: "dataset_formats", Field: "dataset.format", Type: FacetTerms, Size: 20},
+	{Name: "base_models", Field: "fine_tuning.base_models", Type: FacetTerms, Size: 50},
+	{Name: "avg_rating", Field: "metrics.rating", Type: FacetAvg},
+	{Name: "price_ranges", Field: "pricing.rate", Type: FacetRange, Ranges: defaultPriceRanges},
+}
+
+// defaultPriceRanges bucket pricing.rate, a price per 1K tokens, into bands a
+// buyer would pick from
+var defaultPriceRanges = []FacetRangeConfig{
+	{Key: "free", Label: "Free", To: ptr(0.000001)},
+	{Key: "under_0.001", Label: "Under $0.001 / 1K tokens", From: ptr(0.000001), To: ptr(0.001)},
+	{Key: "0.001_0.01", Label: "$0.001 - $0.01 / 1K tokens", From: ptr(0.001), To: ptr(0.01)},
+	{Key: "0.01_0.1", Label: "$0.01 - $0.10 / 1K tokens", From: ptr(0.01), To: ptr(0.1)},
+	{Key: "0.1_up", Label: "$0.10+ / 1K tokens", From: ptr(0.1)},
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+// AutocompleteConfig weights service-name suggestions against popular past queries
+type AutocompleteConfig struct {
+	Suggester     bool          `yaml:"suggester"` // Suggest names from the completion field rather than a prefix search
+	NameWeight    float64       `yaml:"name_weight"`
+	QueryWeight   float64       `yaml:"query_weight"`
+	QueryHalfLife time.Duration `yaml:"query_half_life"` // Time for a query's popularity to halve
+	MinQueryCount int           `yaml:"min_query_count"` // Successful searches before a query is suggested
 }
 
 type RankingWeights struct {
-	Relevance  float64 `yaml:"relevance"`
-	Popularity float64 `yaml:"popularity"`
-	Performance float64 `yaml:"performance"`
-	Compliance float64 `yaml:"compliance"`
+	Relevance   float64 `yaml:"relevance" json:"relevance"`
+	Popularity  float64 `yaml:"popularity" json:"popularity"`
+	Performance float64 `yaml:"performance" json:"performance"`
+	Compliance  float64 `yaml:"compliance" json:"compliance"`
+}
+
+// Sum returns the total of the weights, which must be 1.0
+func (w RankingWeights) Sum() float64 {
+	return w.Relevance + w.Popularity + w.Performance + w.Compliance
 }
 
 type RecommendationsConfig struct {
-	Enabled               bool          `yaml:"enabled"`
-	MaxRecommendations    int           `yaml:"max_recommendations"`
-	CollaborativeWeight   float64       `yaml:"collaborative_weight"`
-	ContentWeight         float64       `yaml:"content_weight"`
-	PopularityWeight      float64       `yaml:"popularity_weight"`
-	MinCommonUsers        int           `yaml:"min_common_users"`
-	SimilarityThreshold   float64       `yaml:"similarity_threshold"`
-	TrendingWindow        time.Duration `yaml:"trending_window"`
-	TrendingMinInteractions int         `yaml:"trending_min_interactions"`
+	Enabled                 bool                       `yaml:"enabled"`
+	MaxRecommendations      int                        `yaml:"max_recommendations"`
+	CollaborativeWeight     float64                    `yaml:"collaborative_weight"`
+	ContentWeight           float64                    `yaml:"content_weight"`
+	PopularityWeight        float64                    `yaml:"popularity_weight"`
+	MinCommonUsers          int                        `yaml:"min_common_users"`
+	SimilarityThreshold     float64                    `yaml:"similarity_threshold"`
+	TrendingWindow          time.Duration              `yaml:"trending_window"`
+	TrendingMinInteractions int                        `yaml:"trending_min_interactions"`
+	Slate                   map[string]PlacementConfig `yaml:"slate"` // Placements of GET /api/v1/recommendations/slate, by name
+	Hydration               HydrationConfig            `yaml:"hydration"`
+}
+
+// HydrationConfig bounds loading the services of recommended candidates.
+// They are looked up in Postgres in batches, several at once; candidates
+// a batch fails to load are looked up in Elasticsearch instead.
+type HydrationConfig struct {
+	BatchSize   int           `yaml:"batch_size"`  // Candidates per Postgres batch; 0 loads them in one
+	Concurrency int           `yaml:"concurrency"` // Lookups in flight at once
+	Timeout     time.Duration `yaml:"timeout"`     // Candidates not loaded by then are returned without their service; 0 waits
+}
+
+// Placements a recommendation slate can hold, in the order they are filled
+const (
+	PlacementHomepageHero       = "homepage_hero"
+	PlacementBecauseYouUsed     = "because_you_used"
+	PlacementTrendingInCategory = "trending_in_category"
+	PlacementNewArrivals        = "new_arrivals"
+	PlacementFineTuning         = "fine_tuning"
+)
+
+// Placements lists every placement in the order slates fill them
+var Placements = []string{PlacementHomepageHero, PlacementBecauseYouUsed, PlacementTrendingInCategory, PlacementNewArrivals, PlacementFineTuning}
+
+// Algorithms a placement can use
+const (
+	AlgorithmHybrid        = "hybrid"        // Collaborative filtering, the category's top rated and trending, merged
+	AlgorithmCollaborative = "collaborative" // Services liked by users with a similar history
+	AlgorithmContent       = "content"       // Services similar to the one the user used last
+	AlgorithmTrending      = "trending"      // Most used within trending_window, in the slate's category when it has one
+	AlgorithmNewest        = "newest"        // Most recently listed, in the slate's category when it has one
+	AlgorithmFineTuning    = "fine_tuning"   // Fine-tuning offerings, most used within trending_window and fastest first
+)
+
+// PlacementConfig is how one placement of a slate is filled
+type PlacementConfig struct {
+	Algorithm string `yaml:"algorithm"`
+	Budget    int    `yaml:"budget"` // Services the placement holds at most; 0 leaves it out of slates
+}
+
+// Assistant backends selectable in AssistantConfig
+const (
+	AssistantBackendOpenAI     = "openai"
+	AssistantBackendExtractive = "extractive"
+)
+
+// FeaturesConfig configures the feature store: user and service features
+// computed in the background, stored in Postgres and cached in Redis, that
+// search ranking and recommendations read instead of querying interactions
+type FeaturesConfig struct {
+	Enabled         bool          `yaml:"enabled"`
+	RefreshInterval time.Duration `yaml:"refresh_interval"` // How often every feature is recomputed
+	Window          time.Duration `yaml:"window"`           // Interactions user features are computed from
+	CacheTTL        time.Duration `yaml:"cache_ttl"`        // How long an entity's features stay in Redis
+
+	// Search ranking and recommendation scores are multiplied by
+	// 1 + affinity_boost x the user's share of interactions in the service's
+	// category, 1 - price_weight x the user's price sensitivity x how dear
+	// the service is in its category, and 1 + trust_weight x (2 x provider
+	// trust - 1)
+	AffinityBoost float64 `yaml:"affinity_boost"`
+	PriceWeight   float64 `yaml:"price_weight"`
+	TrustWeight   float64 `yaml:"trust_weight"`
+}
+
+// PrivacyConfig bounds how long interaction data is kept and pseudonymizes
+// the user IDs it is stored under. A background job purges expired rows and
+// pseudonymizes new ones every purge_interval.
+type PrivacyConfig struct {
+	Enabled       bool            `yaml:"enabled"`
+	PurgeInterval time.Duration   `yaml:"purge_interval"`
+	BatchSize     int             `yaml:"batch_size"` // Rows deleted, or users pseudonymized, per statement
+	Retention     RetentionConfig `yaml:"retention"`
+
+	// Pseudonymize replaces user IDs at rest with an HMAC of them under
+	// pseudonym_key. Changing the key unlinks users from their history.
+	Pseudonymize bool   `yaml:"pseudonymize"`
+	PseudonymKey string `yaml:"pseudonym_key"`
+}
+
+// RetentionConfig is how long raw rows of each table are kept; 0 keeps
+// them. Rollups hold no user IDs and are kept.
+type RetentionConfig struct {
+	UserInteractions time.Duration `yaml:"user_interactions"`
+	SearchAnalytics  time.Duration `yaml:"search_analytics"`
+}
+
+// minPseudonymKeyLength is the shortest pseudonym_key accepted
+const minPseudonymKeyLength = 32
+
+// AssistantConfig configures POST /api/v1/ask, which answers questions from
+// the services a search retrieves
+type AssistantConfig struct {
+	Enabled     bool          `yaml:"enabled"`
+	Backend     string        `yaml:"backend"` // "openai" for an OpenAI-compatible chat completions API, or "extractive" to list the matches without a model
+	URL         string        `yaml:"url"`     // Base URL; /chat/completions is appended
+	Model       string        `yaml:"model"`
+	APIKey      string        `yaml:"api_key"` // Sent as a bearer token when set
+	Timeout     time.Duration `yaml:"timeout"`
+	MaxServices int           `yaml:"max_services"` // Services retrieved and given to the model; requests may ask for fewer
+	MaxTokens   int           `yaml:"max_tokens"`   // Longest answer the model may write
+	Temperature float64       `yaml:"temperature"`
 }
 
 type PerformanceConfig struct {
-	TargetP95LatencyMS       int           `yaml:"target_p95_latency_ms"`
-	TargetP99LatencyMS       int           `yaml:"target_p99_latency_ms"`
-	MaxConcurrentRequests    int           `yaml:"max_concurrent_requests"`
-	CircuitBreakerThreshold  float64       `yaml:"circuit_breaker_threshold"`
-	CircuitBreakerTimeout    time.Duration `yaml:"circuit_breaker_timeout"`
+	TargetP95LatencyMS      int           `yaml:"target_p95_latency_ms"`
+	TargetP99LatencyMS      int           `yaml:"target_p99_latency_ms"`
+	MaxConcurrentRequests   int           `yaml:"max_concurrent_requests"`
+	CircuitBreakerThreshold float64       `yaml:"circuit_breaker_threshold"`
+	CircuitBreakerTimeout   time.Duration `yaml:"circuit_breaker_timeout"`
+	CompressionEnabled      bool          `yaml:"compression_enabled"`
+	CompressionMinSize      int           `yaml:"compression_min_size"` // bytes
 }
 
 type ObservabilityConfig struct {
 	Metrics MetricsConfig `yaml:"metrics"`
 	Tracing TracingConfig `yaml:"tracing"`
 	Logging LoggingConfig `yaml:"logging"`
+
+	// ResourceAttributes are added to exported traces and metrics, e.g.
+	// deployment.environment; service.name and service.version override the
+	// built-in values
+	ResourceAttributes map[string]string `yaml:"resource_attributes"`
 }
 
 type MetricsConfig struct {
-	Enabled         bool          `yaml:"enabled"`
-	Port            int           `yaml:"port"`
-	Path            string        `yaml:"path"`
-	CollectInterval time.Duration `yaml:"collect_interval"`
+	Enabled         bool              `yaml:"enabled"`
+	Port            int               `yaml:"port"`
+	Path            string            `yaml:"path"`
+	CollectInterval time.Duration     `yaml:"collect_interval"`
+	TLS             TLSConfig         `yaml:"tls"`
+	Debug           DebugConfig       `yaml:"debug"`
+	OTel            OTelMetricsConfig `yaml:"otel"`
+}
+
+// OTelMetricsConfig records key latencies and cache outcomes through
+// OpenTelemetry, with exemplars linking samples to the trace that produced
+// them. The instruments are served on the scrape endpoint and/or pushed to
+// an OTLP collector.
+type OTelMetricsConfig struct {
+	Enabled        bool          `yaml:"enabled"`
+	Prometheus     bool          `yaml:"prometheus"`      // Serve on the scrape endpoint
+	ExportInterval time.Duration `yaml:"export_interval"` // OTLP push interval
+	OTLP           OTLPConfig    `yaml:"otlp"`
+}
+
+// OTLPConfig points an exporter at an OTLP collector; it is off while the
+// endpoint is empty
+type OTLPConfig struct {
+	Endpoint string            `yaml:"endpoint"` // host:port
+	Protocol string            `yaml:"protocol"` // grpc or http
+	Insecure bool              `yaml:"insecure"`
+	Headers  map[string]string `yaml:"headers"`
+}
+
+// DebugConfig exposes pprof, expvar and goroutine dumps on the metrics port.
+// Callers must come from an allowed network and, when a username is set,
+// present the basic auth credentials.
+type DebugConfig struct {
+	Enabled      bool     `yaml:"enabled"`
+	Username     string   `yaml:"username"`
+	Password     string   `yaml:"password"`
+	AllowedCIDRs []string `yaml:"allowed_cidrs"` // Any address when empty
+}
+
+// TLSConfig serves over HTTPS when both files are set
+type TLSConfig struct {
+	CertFile string `yaml:"cert_file"`
+	KeyFile  string `yaml:"key_file"`
+}
+
+// Enabled reports whether a certificate and key are configured
+func (c TLSConfig) Enabled() bool {
+	return c.CertFile != "" && c.KeyFile != ""
 }
 
+// TracingConfig selects the span exporter (otlp, or the deprecated jaeger
+// collector exporter) and the sampler
 type TracingConfig struct {
-	Enabled        bool    `yaml:"enabled"`
-	Exporter       string  `yaml:"exporter"`
-	JaegerEndpoint string  `yaml:"jaeger_endpoint"`
-	SamplingRate   float64 `yaml:"sampling_rate"`
+	Enabled        bool       `yaml:"enabled"`
+	Exporter       string     `yaml:"exporter"` // otlp or jaeger
+	OTLP           OTLPConfig `yaml:"otlp"`
+	JaegerEndpoint string     `yaml:"jaeger_endpoint"`
+	Sampler        string     `yaml:"sampler"` // parent_based (default), always_on or ratio
+	SamplingRate   float64    `yaml:"sampling_rate"`
 }
 
 type LoggingConfig struct {
-	Level  string `yaml:"level"`
-	Format string `yaml:"format"`
-	Output string `yaml:"output"`
+	Level  string          `yaml:"level"`
+	Format string          `yaml:"format"`
+	Output string          `yaml:"output"`
+	Access AccessLogConfig `yaml:"access"`
+}
+
+// AccessLogConfig controls the per-request log line. Server errors, client
+// errors and slow requests are always logged; healthy requests are sampled.
+type AccessLogConfig struct {
+	SuccessSampleRate float64       `yaml:"success_sample_rate"` // Share of 2xx/3xx requests logged; unset logs all
+	SlowThreshold     time.Duration `yaml:"slow_threshold"`      // Slower requests log a warning; unset disables
 }
 
 type PolicyEngineConfig struct {
-	GRPCEndpoint string        `yaml:"grpc_endpoint"`
-	Timeout      time.Duration `yaml:"timeout"`
-	CacheTTL     time.Duration `yaml:"cache_ttl"`
+	GRPCEndpoint  string        `yaml:"grpc_endpoint"`
+	Timeout       time.Duration `yaml:"timeout"`
+	CacheTTL      time.Duration `yaml:"cache_ttl"`
+	EnrichOnIndex bool          `yaml:"enrich_on_index"` // Validate each service as it's indexed and store a compliance summary
 }
 
 type AnalyticsHubConfig struct {
-	KafkaBrokers  []string      `yaml:"kafka_brokers"`
-	Topic         string        `yaml:"topic"`
-	BatchSize     int           `yaml:"batch_size"`
+	Enabled       bool              `yaml:"enabled"`
+	KafkaBrokers  []string          `yaml:"kafka_brokers"`
+	Topic         string            `yaml:"topic"`
+	BatchSize     int               `yaml:"batch_size"`
+	FlushInterval time.Duration     `yaml:"flush_interval"`
+	BufferSize    int               `yaml:"buffer_size"` // Events held in memory before new ones are dropped
+	Aggregation   AggregationConfig `yaml:"aggregation"`
+	Related       RelatedConfig     `yaml:"related"`
+}
+
+// AggregationConfig controls the consumer that rolls analytics events up into PostgreSQL
+type AggregationConfig struct {
+	Enabled       bool          `yaml:"enabled"`
+	ConsumerGroup string        `yaml:"consumer_group"`
 	FlushInterval time.Duration `yaml:"flush_interval"`
+	MaxPending    int           `yaml:"max_pending"` // Events folded in before an early flush
+}
+
+// RelatedConfig controls the related categories and "people also searched"
+// lists built from what users search for within a session
+type RelatedConfig struct {
+	Enabled         bool          `yaml:"enabled"`
+	SessionWindow   time.Duration `yaml:"session_window"`   // Searches by a user this close together share a session
+	Window          time.Duration `yaml:"window"`           // Sessions counted; older rollups are deleted
+	RefreshInterval time.Duration `yaml:"refresh_interval"` // How often the cached lists are recomputed
+	MinCount        int           `yaml:"min_count"`        // Sessions two items must share to be related
+	MaxRelated      int           `yaml:"max_related"`      // Items cached for each category or query
+}
+
+// CatalogConfig controls the consumer indexing the services the registry
+// publishes
+type CatalogConfig struct {
+	Enabled         bool          `yaml:"enabled"`
+	KafkaBrokers    []string      `yaml:"kafka_brokers"`
+	Topic           string        `yaml:"topic"`
+	ConsumerGroup   string        `yaml:"consumer_group"`
+	RetryBackoff    time.Duration `yaml:"retry_backoff"`     // Delay after the first failure to index an event, doubled after each
+	MaxRetryBackoff time.Duration `yaml:"max_retry_backoff"` // Failed events are retried until they succeed; later events wait
+}
+
+type SLAMonitoringConfig struct {
+	Enabled           bool          `yaml:"enabled"`
+	ProbeInterval     time.Duration `yaml:"probe_interval"`
+	ProbeTimeout      time.Duration `yaml:"probe_timeout"`
+	Concurrency       int           `yaml:"concurrency"`
+	WindowSize        int           `yaml:"window_size"`
+	MaxServices       int           `yaml:"max_services"` // Services listed per page when collecting probe targets
+	DegradedLatencyMS float64       `yaml:"degraded_latency_ms"`
+	KafkaBrokers      []string      `yaml:"kafka_brokers"`
+	BreachTopic       string        `yaml:"breach_topic"`   // Health changes are published here for the notification service; empty disables
+	ReportMaxAge      time.Duration `yaml:"report_max_age"` // Provider reports older than this are rejected
+}
+
+type ExportConfig struct {
+	MaxRows      int           `yaml:"max_rows"`       // Limit for streamed exports
+	AsyncMaxRows int           `yaml:"async_max_rows"` // Limit for export jobs
+	Directory    string        `yaml:"directory"`
+	JobTimeout   time.Duration `yaml:"job_timeout"`
+	JobTTL       time.Duration `yaml:"job_ttl"`
+}
+
+// SnapshotConfig configures catalog snapshots: every service, as NDJSON, and
+// a manifest written to S3 on demand or every interval
+type SnapshotConfig struct {
+	Enabled           bool          `yaml:"enabled"`
+	Bucket            string        `yaml:"bucket"`
+	Prefix            string        `yaml:"prefix"`   // Each snapshot is kept under <prefix>/<id>/
+	Region            string        `yaml:"region"`   // The SDK's default when empty
+	Endpoint          string        `yaml:"endpoint"` // Override, e.g. MinIO or LocalStack
+	UsePathStyle      bool          `yaml:"use_path_style"`
+	Interval          time.Duration `yaml:"interval"` // 0 takes snapshots on demand only
+	BatchSize         int           `yaml:"batch_size"`
+	KeepAlive         time.Duration `yaml:"keep_alive"` // How long the point in time is held between pages
+	IncludeEmbeddings bool          `yaml:"include_embeddings"`
+	Directory         string        `yaml:"directory"` // Where snapshots are staged before upload
+	Timeout           time.Duration `yaml:"timeout"`
+}
+
+// FeedConfig configures the partner syndication feed: catalog changes since
+// a cursor, polled by partners or pushed to those listed
+type FeedConfig struct {
+	Enabled       bool          `yaml:"enabled"`
+	Retention     time.Duration `yaml:"retention"` // Cursors older than this have to start over
+	PageSize      int           `yaml:"page_size"`
+	MaxPageSize   int           `yaml:"max_page_size"`
+	PruneInterval time.Duration `yaml:"prune_interval"`
+	PushTimeout   time.Duration `yaml:"push_timeout"`
+	Partners      []FeedPartner `yaml:"partners"`
+}
+
+// FeedPartner is a partner marketplace the feed is pushed to. It gets what
+// its tenant and user may see.
+type FeedPartner struct {
+	Name     string        `yaml:"name"`
+	URL      string        `yaml:"url"`
+	Secret   string        `yaml:"secret"` // Signs each push as webhook deliveries are signed
+	TenantID string        `yaml:"tenant_id"`
+	UserID   string        `yaml:"user_id"`
+	Fields   []string      `yaml:"fields"` // Every field when empty
+	Interval time.Duration `yaml:"interval"`
+}
+
+type WebhookConfig struct {
+	Enabled        bool          `yaml:"enabled"`
+	PollInterval   time.Duration `yaml:"poll_interval"`
+	BatchSize      int           `yaml:"batch_size"`
+	Timeout        time.Duration `yaml:"timeout"`
+	MaxAttempts    int           `yaml:"max_attempts"`
+	InitialBackoff time.Duration `yaml:"initial_backoff"`
+	MaxBackoff     time.Duration `yaml:"max_backoff"`
+	AllowInsecure  bool          `yaml:"allow_insecure"` // Permit http:// endpoints, for local development
+}
+
+// Feature flag sources selectable in FeatureFlagsConfig
+const (
+	FlagSourceConfig = "config"
+	FlagSourceFile   = "file"
+	FlagSourceRedis  = "redis"
+)
+
+// FeatureFlagsConfig sets where the feature flags gating semantic search,
+// personalization and the re-ranking stages are read from: the flags here,
+// reloaded with the config file, or a JSON file or Redis key re-read every
+// refresh interval
+type FeatureFlagsConfig struct {
+	Source          string        `yaml:"source"` // "config" (default), "file" or "redis"
+	File            string        `yaml:"file"`
+	RedisKey        string        `yaml:"redis_key"`
+	RefreshInterval time.Duration `yaml:"refresh_interval"`
+	Flags           flags.Set     `yaml:"flags"`
 }
 
+// EntitlementsConfig names the headers the gateway uses to pass the caller's identity.
+// The gateway must strip these headers from client requests.
+type EntitlementsConfig struct {
+	TenantHeader   string `yaml:"tenant_header"`
+	UserHeader     string `yaml:"user_header"`
+	OperatorHeader string `yaml:"operator_header"` // Set for marketplace operators only
+	ProviderHeader string `yaml:"provider_header"` // Set for provider organisations only
+}
+
+// SubscriptionsConfig controls flagging search results the caller's tenant
+// is subscribed to, looked up in the registry
+type SubscriptionsConfig struct {
+	Enabled     bool          `yaml:"enabled"`
+	RegistryURL string        `yaml:"registry_url"`
+	Timeout     time.Duration `yaml:"timeout"`
+	CacheTTL    time.Duration `yaml:"cache_ttl"` // How long a tenant's subscriptions are reused
+}
+
+// QuotasConfig limits how many API requests each caller may make. Callers
+// are the API key of their token, which the gateway passes the ID of, else
+// the tenant, else the client IP; each is limited by its tier.
+type QuotasConfig struct {
+	Enabled       bool                   `yaml:"enabled"`
+	APIKeyHeader  string                 `yaml:"api_key_header"`
+	DefaultTier   string                 `yaml:"default_tier"`   // Tier of API keys and tenants not assigned one
+	AnonymousTier string                 `yaml:"anonymous_tier"` // Tier of callers with neither, per client IP
+	Tiers         map[string]QuotaLimits `yaml:"tiers"`
+	Tenants       map[string]string      `yaml:"tenants"`  // Tier by tenant ID
+	APIKeys       map[string]string      `yaml:"api_keys"` // Tier by API key ID
+}
+
+// QuotaLimits caps a caller's requests per second and per UTC day; 0 is unlimited
+type QuotaLimits struct {
+	RequestsPerSecond int `yaml:"requests_per_second" json:"requests_per_second"`
+	RequestsPerDay    int `yaml:"requests_per_day" json:"requests_per_day"`
+}
+
+// DefaultPath is the config file used when CONFIG_PATH is not set
+const DefaultPath = "config.yaml"
+
+// Path returns the config file to load: CONFIG_PATH if set, otherwise
+// config.yaml when it exists in the working directory. It is empty when the
+// service is configured by defaults and environment variables alone.
+func Path() string {
+	if path := os.Getenv("CONFIG_PATH"); path != "" {
+		return path
+	}
+	if _, err := os.Stat(DefaultPath); err == nil {
+		return DefaultPath
+	}
+	return ""
+}
+
+// Load builds the configuration from the built-in defaults, overridden by the
+// file at path, if any, then by DISCOVERY_* environment variables
 func Load(path string) (*Config, error) {
-	data, err := os.ReadFile(path)
+	cfg, err := load(path)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read config file: %w", err)
+		return nil, err
 	}
+	cfg.live = newReloadable(cfg)
+	return cfg, nil
+}
 
-	// Expand environment variables
-	content := os.ExpandEnv(string(data))
-
+func load(path string) (*Config, error) {
 	var cfg Config
-	if err := yaml.Unmarshal([]byte(content), &cfg); err != nil {
-		return nil, fmt.Errorf("failed to parse config: %w", err)
+	cfg.setDefaults()
+
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read config file: %w", err)
+		}
+
+		// Expand environment variables
+		content := os.ExpandEnv(string(data))
+
+		if err := yaml.Unmarshal([]byte(content), &cfg); err != nil {
+			return nil, fmt.Errorf("failed to parse config: %w", err)
+		}
+	}
+
+	if err := envconfig.Apply(&cfg, "DISCOVERY_", os.Getenv); err != nil {
+		return nil, fmt.Errorf("invalid environment override: %w", err)
 	}
 
 	// Validate configuration
@@ -175,24 +892,112 @@ func Load(path string) (*Config, error) {
 	return &cfg, nil
 }
 
+// embeddingModelName keeps model names usable as Elasticsearch field names
+var embeddingModelName = regexp.MustCompile(`^[a-z0-9_]+$`)
+
+func validateOTLP(cfg OTLPConfig) error {
+	switch cfg.Protocol {
+	case "", "grpc", "http":
+		return nil
+	default:
+		return fmt.Errorf("unknown protocol %q (want grpc or http)", cfg.Protocol)
+	}
+}
+
 func validate(cfg *Config) error {
 	// Validate server config
 	if cfg.Server.Port <= 0 || cfg.Server.Port > 65535 {
 		return fmt.Errorf("invalid server port: %d", cfg.Server.Port)
 	}
 
+	for name := range cfg.Server.Readiness.Dependencies {
+		switch name {
+		case "postgres", "redis", "elasticsearch", "embedding_service":
+		default:
+			return fmt.Errorf("unknown readiness dependency %q (want postgres, redis, elasticsearch or embedding_service)", name)
+		}
+	}
+
+	// Validate metrics TLS
+	if tlsCfg := cfg.Observability.Metrics.TLS; (tlsCfg.CertFile == "") != (tlsCfg.KeyFile == "") {
+		return fmt.Errorf("observability.metrics.tls needs both cert_file and key_file")
+	}
+
+	// Validate debug endpoints; they are never left open
+	if debug := cfg.Observability.Metrics.Debug; debug.Enabled {
+		if debug.Username == "" && len(debug.AllowedCIDRs) == 0 {
+			return fmt.Errorf("observability.metrics.debug needs credentials or allowed_cidrs")
+		}
+		if debug.Username != "" && debug.Password == "" {
+			return fmt.Errorf("observability.metrics.debug.password is required with a username")
+		}
+		for _, cidr := range debug.AllowedCIDRs {
+			if _, _, err := net.ParseCIDR(cidr); err != nil {
+				return fmt.Errorf("invalid debug allowed_cidrs entry %q: %w", cidr, err)
+			}
+		}
+	}
+
+	// Validate tracing
+	if tracing := cfg.Observability.Tracing; tracing.Enabled {
+		switch tracing.Exporter {
+		case "otlp":
+			if tracing.OTLP.Endpoint == "" {
+				return fmt.Errorf("observability.tracing.otlp.endpoint is required for the otlp exporter")
+			}
+			if err := validateOTLP(tracing.OTLP); err != nil {
+				return fmt.Errorf("observability.tracing.otlp: %w", err)
+			}
+		case "jaeger":
+			if tracing.JaegerEndpoint == "" {
+				return fmt.Errorf("observability.tracing.jaeger_endpoint is required for the jaeger exporter")
+			}
+		default:
+			return fmt.Errorf("unknown tracing exporter %q (want otlp or jaeger)", tracing.Exporter)
+		}
+		switch tracing.Sampler {
+		case "", "parent_based", "always_on", "ratio":
+		default:
+			return fmt.Errorf("unknown tracing sampler %q (want parent_based, always_on or ratio)", tracing.Sampler)
+		}
+		if tracing.SamplingRate < 0 || tracing.SamplingRate > 1 {
+			return fmt.Errorf("observability.tracing.sampling_rate must be between 0 and 1, got: %.2f", tracing.SamplingRate)
+		}
+	}
+
+	// Validate access log sampling
+	if rate := cfg.Observability.Logging.Access.SuccessSampleRate; rate < 0 || rate > 1 {
+		return fmt.Errorf("observability.logging.access.success_sample_rate must be between 0 and 1, got: %.2f", rate)
+	}
+
+	// Validate OTel metrics export
+	if otelCfg := cfg.Observability.Metrics.OTel; otelCfg.Enabled && otelCfg.OTLP.Endpoint != "" {
+		if err := validateOTLP(otelCfg.OTLP); err != nil {
+			return fmt.Errorf("observability.metrics.otel.otlp: %w", err)
+		}
+	}
+
 	// Validate Elasticsearch config
 	if len(cfg.Elasticsearch.Addresses) == 0 {
 		return fmt.Errorf("elasticsearch addresses cannot be empty")
 	}
 
 	// Validate ranking weights sum to 1.0
-	weights := cfg.Search.RankingWeights
-	sum := weights.Relevance + weights.Popularity + weights.Performance + weights.Compliance
+	sum := cfg.Search.RankingWeights.Sum()
 	if sum < 0.99 || sum > 1.01 {
 		return fmt.Errorf("ranking weights must sum to 1.0, got: %.2f", sum)
 	}
 
+	// Validate report demotion
+	if d := cfg.Search.ReportDemotion; d.Threshold < 0 || d.Factor < 0 || d.Factor > 1 {
+		return fmt.Errorf("search report_demotion needs a non-negative threshold and a factor between 0 and 1")
+	}
+
+	// Validate query understanding
+	if q := cfg.Search.QueryUnderstanding; q.CheapPrice < 0 || q.EmbeddingThreshold < 0 || q.EmbeddingThreshold > 1 {
+		return fmt.Errorf("search query_understanding needs a non-negative cheap_price and an embedding_threshold between 0 and 1")
+	}
+
 	// Validate recommendation weights
 	recWeights := cfg.Recommendations.CollaborativeWeight +
 		cfg.Recommendations.ContentWeight +
@@ -201,9 +1006,282 @@ func validate(cfg *Config) error {
 		return fmt.Errorf("recommendation weights must sum to 1.0, got: %.2f", recWeights)
 	}
 
+	// Validate slate placements
+	for name, placement := range cfg.Recommendations.Slate {
+		switch name {
+		case PlacementHomepageHero, PlacementBecauseYouUsed, PlacementTrendingInCategory, PlacementNewArrivals, PlacementFineTuning:
+		default:
+			return fmt.Errorf("unknown recommendations slate placement %q", name)
+		}
+		switch placement.Algorithm {
+		case AlgorithmHybrid, AlgorithmCollaborative, AlgorithmContent, AlgorithmTrending, AlgorithmNewest, AlgorithmFineTuning:
+		default:
+			return fmt.Errorf("unknown algorithm %q for slate placement %s (want hybrid, collaborative, content, trending, newest or fine_tuning)", placement.Algorithm, name)
+		}
+		if placement.Budget < 0 {
+			return fmt.Errorf("slate placement %s needs a non-negative budget", name)
+		}
+	}
+
+	// Validate recommendation hydration
+	if h := cfg.Recommendations.Hydration; h.BatchSize < 0 || h.Concurrency < 0 || h.Timeout < 0 {
+		return fmt.Errorf("recommendations hydration needs a non-negative batch_size, concurrency and timeout")
+	}
+
+	// Validate the feature store
+	if f := cfg.Features; f.Enabled && (f.RefreshInterval <= 0 || f.Window <= 0 || f.CacheTTL <= 0) {
+		return fmt.Errorf("features needs a positive refresh_interval, window and cache_ttl")
+	}
+	if f := cfg.Features; f.AffinityBoost < 0 || f.PriceWeight < 0 || f.PriceWeight > 1 || f.TrustWeight < 0 || f.TrustWeight > 1 {
+		return fmt.Errorf("features needs a non-negative affinity_boost, and a price_weight and trust_weight between 0 and 1")
+	}
+
+	// Validate retention and pseudonymization
+	if p := cfg.Privacy; p.Enabled && (p.PurgeInterval <= 0 || p.BatchSize <= 0) {
+		return fmt.Errorf("privacy needs a positive purge_interval and batch_size")
+	}
+	if r := cfg.Privacy.Retention; r.UserInteractions < 0 || r.SearchAnalytics < 0 {
+		return fmt.Errorf("privacy retention periods must not be negative")
+	}
+	if p := cfg.Privacy; p.Pseudonymize && len(p.PseudonymKey) < minPseudonymKeyLength {
+		return fmt.Errorf("privacy pseudonymize needs a pseudonym_key of at least %d characters", minPseudonymKeyLength)
+	}
+
+	// Validate search guardrails
+	if g := cfg.Search.Guardrails; g.MaxFilters < 0 || g.MaxPageSize < 0 || g.MaxOffset < 0 || g.MaxQueryLength < 0 || g.MaxFuzzyTerms < 0 || g.Timeout < 0 {
+		return fmt.Errorf("search guardrails limits and timeout must not be negative")
+	}
+
+	// Validate quotas
+	if err := validateQuotas(cfg.Quotas); err != nil {
+		return err
+	}
+
+	// Validate session re-ranking
+	if r := cfg.Search.Session; r.Enabled && (r.TTL <= 0 || r.CategoryBoost < 0 || r.DismissFactor < 0 || r.DismissFactor > 1) {
+		return fmt.Errorf("search session needs a positive ttl, a non-negative category_boost and a dismiss_factor between 0 and 1")
+	}
+
+	// Validate region ranking
+	if r := cfg.Search.RegionRanking; r.Enabled && (r.LatencyBoost < 0 || r.ReferenceLatency <= 0) {
+		return fmt.Errorf("search region_ranking needs a non-negative latency_boost and a positive reference_latency")
+	}
+
+	// Validate latency budget
+	if b := cfg.Search.LatencyBudget; b.Total < 0 || b.EmbeddingShare < 0 || b.EmbeddingShare > 1 || b.CacheShare < 0 || b.CacheShare > 1 || b.ElasticsearchShare < 0 || b.ElasticsearchShare > 1 {
+		return fmt.Errorf("search latency_budget needs a non-negative total and shares between 0 and 1")
+	}
+
+	// Validate search debug capture
+	if d := cfg.Search.DebugCapture; d.Enabled && d.TTL <= 0 {
+		return fmt.Errorf("search debug_capture needs a positive ttl")
+	}
+
+	// Validate stale-while-revalidate caching
+	if w := cfg.Search.StaleWhileRevalidate; w.Enabled && (w.StaleTTL <= 0 || w.RefreshTimeout <= 0) {
+		return fmt.Errorf("search stale_while_revalidate needs a positive stale_ttl and refresh_timeout")
+	}
+
+	// Validate cache warm-up
+	if w := cfg.Search.Warmup; w.Enabled {
+		if w.TopQueries < 0 || w.Concurrency <= 0 || w.Timeout <= 0 {
+			return fmt.Errorf("search warmup needs a non-negative top_queries and a positive concurrency and timeout")
+		}
+		if w.TopQueries > 0 && cfg.Search.Autocomplete.QueryHalfLife <= 0 {
+			return fmt.Errorf("search warmup ranks queries by autocomplete query_half_life, which must be positive")
+		}
+	}
+
+	// Validate query pipeline
+	if p := cfg.Search.QueryPipeline; p.LLMRewrite && (cfg.Assistant.Backend != AssistantBackendOpenAI || p.LLMRewriteTimeout <= 0) {
+		return fmt.Errorf("search query_pipeline llm_rewrite needs the %s assistant backend and a positive llm_rewrite_timeout", AssistantBackendOpenAI)
+	}
+
+	// Validate assistant
+	if a := cfg.Assistant; a.Enabled {
+		switch {
+		case a.Backend != AssistantBackendOpenAI && a.Backend != AssistantBackendExtractive:
+			return fmt.Errorf("assistant backend must be %q or %q, got: %q", AssistantBackendOpenAI, AssistantBackendExtractive, a.Backend)
+		case a.Backend == AssistantBackendOpenAI && (a.URL == "" || a.Model == ""):
+			return fmt.Errorf("assistant url and model are required for the %s backend", AssistantBackendOpenAI)
+		case a.MaxServices <= 0:
+			return fmt.Errorf("assistant max_services must be positive")
+		}
+	}
+
+	// Validate autocomplete weighting
+	if ac := cfg.Search.Autocomplete; ac.QueryWeight > 0 && ac.QueryHalfLife <= 0 {
+		return fmt.Errorf("search autocomplete query_half_life must be positive when query_weight is set")
+	}
+
+	// Validate analytics batching
+	if hub := cfg.AnalyticsHub; hub.Enabled {
+		if hub.BatchSize <= 0 || hub.FlushInterval <= 0 || hub.BufferSize <= 0 {
+			return fmt.Errorf("analytics_hub batch_size, flush_interval and buffer_size must be positive")
+		}
+	}
+	if agg := cfg.AnalyticsHub.Aggregation; agg.Enabled {
+		if agg.ConsumerGroup == "" || agg.FlushInterval <= 0 || agg.MaxPending <= 0 {
+			return fmt.Errorf("analytics_hub aggregation requires consumer_group, flush_interval and max_pending")
+		}
+	}
+	if rel := cfg.AnalyticsHub.Related; rel.Enabled {
+		if rel.SessionWindow <= 0 || rel.Window <= 0 || rel.RefreshInterval <= 0 || rel.MinCount <= 0 || rel.MaxRelated <= 0 {
+			return fmt.Errorf("analytics_hub related session_window, window, refresh_interval, min_count and max_related must be positive")
+		}
+	}
+
+	if sla := cfg.SLAMonitoring; sla.Enabled && sla.BreachTopic != "" && len(sla.KafkaBrokers) == 0 {
+		return fmt.Errorf("sla_monitoring breach_topic requires kafka_brokers")
+	}
+
+	if snap := cfg.Snapshots; snap.Enabled {
+		if snap.Bucket == "" {
+			return fmt.Errorf("snapshots requires bucket")
+		}
+		if snap.Interval < 0 || snap.BatchSize <= 0 || snap.KeepAlive <= 0 || snap.Timeout <= 0 {
+			return fmt.Errorf("snapshots batch_size, keep_alive and timeout must be positive and interval not negative")
+		}
+	}
+
+	if feed := cfg.Feed; feed.Enabled {
+		if feed.Retention <= 0 || feed.PageSize <= 0 || feed.MaxPageSize < feed.PageSize || feed.PruneInterval <= 0 || feed.PushTimeout <= 0 {
+			return fmt.Errorf("feed retention, page_size, prune_interval and push_timeout must be positive and max_page_size at least page_size")
+		}
+		partners := make(map[string]bool)
+		for _, p := range feed.Partners {
+			if p.Name == "" || partners[p.Name] {
+				return fmt.Errorf("feed partners require a unique name, got: %q", p.Name)
+			}
+			if p.URL == "" || p.Secret == "" || p.Interval <= 0 {
+				return fmt.Errorf("feed partner %s requires url, secret and a positive interval", p.Name)
+			}
+			partners[p.Name] = true
+		}
+	}
+
+	switch ff := cfg.FeatureFlags; ff.Source {
+	case FlagSourceConfig, "":
+	case FlagSourceFile:
+		if ff.File == "" {
+			return fmt.Errorf("feature_flags source file requires file")
+		}
+	case FlagSourceRedis:
+		if ff.RedisKey == "" {
+			return fmt.Errorf("feature_flags source redis requires redis_key")
+		}
+	default:
+		return fmt.Errorf("unknown feature_flags source %q (want config, file or redis)", ff.Source)
+	}
+	if err := cfg.FeatureFlags.Flags.Validate(); err != nil {
+		return fmt.Errorf("feature_flags: %w", err)
+	}
+
+	if cat := cfg.Catalog; cat.Enabled {
+		if len(cat.KafkaBrokers) == 0 || cat.Topic == "" || cat.ConsumerGroup == "" {
+			return fmt.Errorf("catalog requires kafka_brokers, topic and consumer_group")
+		}
+	}
+
+	// Validate embedding backends
+	emb := cfg.EmbeddingService
+	if emb.Backend != "" && emb.Backend != "remote" && emb.Backend != "local" {
+		return fmt.Errorf("invalid embedding_service backend: %s", emb.Backend)
+	}
+	if emb.Fallback != "" && emb.Fallback != "local" {
+		return fmt.Errorf("invalid embedding_service fallback: %s", emb.Fallback)
+	}
+	names := make(map[string]bool)
+	for _, m := range emb.Models {
+		if !embeddingModelName.MatchString(m.Name) || names[m.Name] {
+			return fmt.Errorf("embedding_service model names must be unique lowercase identifiers, got: %q", m.Name)
+		}
+		if m.Model == "" || m.Dimensions <= 0 {
+			return fmt.Errorf("embedding_service model %s requires model and dimensions", m.Name)
+		}
+		names[m.Name] = true
+	}
+	if emb.QueryModel != "" && !names[emb.QueryModel] {
+		return fmt.Errorf("embedding_service query_model %s is not in models", emb.QueryModel)
+	}
+	if (emb.Backend == "local" || emb.Fallback == "local") && (emb.Local.ModelPath == "" || emb.Local.VocabPath == "") {
+		return fmt.Errorf("embedding_service local model_path and vocab_path are required")
+	}
+
+	// Validate facets
+	facetNames := make(map[string]bool)
+	for _, f := range cfg.Search.Facets {
+		if f.Name == "" || f.Field == "" || facetNames[f.Name] {
+			return fmt.Errorf("search facets require a unique name and a field, got: %q", f.Name)
+		}
+		facetNames[f.Name] = true
+
+		switch f.Type {
+		case FacetTerms, FacetAutoHistogram:
+			if f.Size <= 0 {
+				return fmt.Errorf("search facet %s requires a positive size", f.Name)
+			}
+		case FacetAvg:
+		case FacetHistogram:
+			if f.Interval <= 0 {
+				return fmt.Errorf("search facet %s requires a positive interval", f.Name)
+			}
+		case FacetRange:
+			if len(f.Ranges) == 0 {
+				return fmt.Errorf("search facet %s requires ranges", f.Name)
+			}
+			for _, r := range f.Ranges {
+				if r.Key == "" || (r.From == nil && r.To == nil) {
+					return fmt.Errorf("search facet %s ranges require a key and a bound", f.Name)
+				}
+			}
+		default:
+			return fmt.Errorf("invalid search facet type for %s: %s", f.Name, f.Type)
+		}
+	}
+
+	if e := cfg.Entitlements; e.TenantHeader == "" || e.UserHeader == "" {
+		return fmt.Errorf("entitlements tenant_header and user_header are required")
+	}
+
+	if s := cfg.Subscriptions; s.Enabled && (s.RegistryURL == "" || s.Timeout <= 0 || s.CacheTTL <= 0) {
+		return fmt.Errorf("subscriptions registry_url, timeout and cache_ttl are required when enabled")
+	}
+
 	return nil
 }
 
+// FacetDefinitions returns the configured search facets, or the built-in set
+// when none are configured
+func (c *SearchConfig) FacetDefinitions() []FacetConfig {
+	if len(c.Facets) > 0 {
+		return c.Facets
+	}
+	return defaultFacets
+}
+
+// EmbeddingModels returns every model documents are embedded with
+func (c *Config) EmbeddingModels() []EmbeddingModelConfig {
+	if len(c.EmbeddingService.Models) > 0 {
+		return c.EmbeddingService.Models
+	}
+	return []EmbeddingModelConfig{{
+		Model:      c.EmbeddingService.Model,
+		Dimensions: c.Elasticsearch.VectorDimensions,
+	}}
+}
+
+// QueryEmbeddingModel returns the model searches embed queries with
+func (c *Config) QueryEmbeddingModel() EmbeddingModelConfig {
+	models := c.EmbeddingModels()
+	for _, m := range models {
+		if m.Name == c.EmbeddingService.QueryModel {
+			return m
+		}
+	}
+	return models[0]
+}
+
 // GetCacheTTL returns the cache TTL duration for a given key
 func (c *RedisConfig) GetCacheTTL(key string) time.Duration {
 	if ttl, ok := c.CacheTTL[key]; ok {
@@ -231,3 +1309,31 @@ func (c *ElasticsearchConfig) GetElasticsearchAddresses() []string {
 	}
 	return addresses
 }
+
+// validateQuotas checks every tier is known and no limit is negative
+func validateQuotas(q QuotasConfig) error {
+	for name, limits := range q.Tiers {
+		if limits.RequestsPerSecond < 0 || limits.RequestsPerDay < 0 {
+			return fmt.Errorf("quotas tier %s limits must not be negative", name)
+		}
+	}
+	if !q.Enabled {
+		return nil
+	}
+	if q.APIKeyHeader == "" {
+		return fmt.Errorf("quotas needs an api_key_header")
+	}
+	for _, tier := range []string{q.DefaultTier, q.AnonymousTier} {
+		if _, ok := q.Tiers[tier]; !ok {
+			return fmt.Errorf("quotas default_tier and anonymous_tier must name a tier, not %q", tier)
+		}
+	}
+	for _, assigned := range []map[string]string{q.Tenants, q.APIKeys} {
+		for id, tier := range assigned {
+			if _, ok := q.Tiers[tier]; !ok {
+				return fmt.Errorf("quotas assigns %s the unknown tier %q", id, tier)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,370 @@
+package config
+
+import (
+	"time"
+
+	"github.com/org/llm-marketplace/pkg/marketplace"
+)
+
+// setDefaults fills in the settings used when neither the config file nor the
+// environment sets them. They match config.yaml, except that dependencies are
+// expected on localhost and optional integrations are off.
+func (c *Config) setDefaults() {
+	// Server defaults
+	c.Server.Host = "0.0.0.0"
+	c.Server.Port = 8080
+	c.Server.Mode = "development"
+	c.Server.ReadTimeout = 30 * time.Second
+	c.Server.WriteTimeout = 30 * time.Second
+	c.Server.IdleTimeout = 120 * time.Second
+	c.Server.ShutdownTimeout = 30 * time.Second
+	c.Server.DrainTimeout = 15 * time.Second
+	c.Server.Startup.MaxWait = 2 * time.Minute
+	c.Server.Startup.InitialBackoff = 500 * time.Millisecond
+	c.Server.Startup.MaxBackoff = 10 * time.Second
+	c.Server.Readiness.Interval = 5 * time.Second
+	c.Server.Readiness.Timeout = 2 * time.Second
+
+	// Elasticsearch defaults
+	c.Elasticsearch.Addresses = []string{"http://localhost:9200"}
+	c.Elasticsearch.IndexName = "llm_services"
+	c.Elasticsearch.MaxRetries = 3
+	c.Elasticsearch.RetryBackoff = 100 * time.Millisecond
+	c.Elasticsearch.MaxRetryBackoff = 2 * time.Second
+	c.Elasticsearch.SniffInterval = 5 * time.Minute
+	c.Elasticsearch.Timeouts = ESTimeoutsConfig{
+		Connect: 2 * time.Second,
+		Read:    5 * time.Second,
+		Write:   10 * time.Second,
+		Bulk:    60 * time.Second,
+		Health:  2 * time.Second,
+	}
+	c.Elasticsearch.Health.Interval = 10 * time.Second
+	c.Elasticsearch.Health.FailureThreshold = 3
+	c.Elasticsearch.EnableMetrics = true
+	c.Elasticsearch.Shards = 3
+	c.Elasticsearch.Replicas = 1
+	c.Elasticsearch.RefreshInterval = "1s"
+	c.Elasticsearch.VectorDimensions = 768
+	c.Elasticsearch.Similarity = "cosine"
+	c.Elasticsearch.Bulk = BulkConfig{
+		MaxBytes:       5 << 20,
+		MaxAttempts:    4,
+		InitialBackoff: 200 * time.Millisecond,
+		MaxBackoff:     5 * time.Second,
+	}
+	c.Elasticsearch.EntityIndices = map[string]string{
+		"dataset":         "llm_datasets",
+		"prompt_template": "llm_prompt_templates",
+		"provider":        "llm_providers",
+	}
+	c.Elasticsearch.Tenancy.SharedCatalog = true
+
+	// Redis defaults
+	c.Redis.Address = "localhost:6379"
+	c.Redis.MaxRetries = 3
+	c.Redis.PoolSize = 100
+	c.Redis.MinIdleConns = 10
+	c.Redis.CacheTTL = map[string]string{
+		"search_results":      "30s",
+		"search_aggregations": "2m",
+		"search_counts":       "2m",
+		"service_details":     "5m",
+		"categories":          "1h",
+		"tags":                "1h",
+		"recommendations":     "2m",
+		"persisted_queries":   "24h",
+		"query_embeddings":    "24h",
+	}
+
+	// PostgreSQL defaults
+	c.Postgres.Host = "localhost"
+	c.Postgres.Port = 5432
+	c.Postgres.Database = "marketplace"
+	c.Postgres.User = "marketplace"
+	c.Postgres.SSLMode = "prefer"
+	c.Postgres.MaxConns = 100
+	c.Postgres.MinConns = 10
+	c.Postgres.ConnMaxLifetime = time.Hour
+	c.Postgres.ConnMaxIdleTime = 30 * time.Minute
+	c.Postgres.StatementTimeout = 5 * time.Second
+	c.Postgres.Migrations.FailOnDrift = true
+
+	// Embedding service defaults
+	c.EmbeddingService.URL = "http://localhost:8000"
+	c.EmbeddingService.Model = "sentence-transformers/all-mpnet-base-v2"
+	c.EmbeddingService.Timeout = 5 * time.Second
+	c.EmbeddingService.BatchSize = 32
+	c.EmbeddingService.MaxAttempts = 3
+	c.EmbeddingService.InitialBackoff = 50 * time.Millisecond
+	c.EmbeddingService.MaxBackoff = time.Second
+	c.EmbeddingService.Concurrency = 4
+	c.EmbeddingService.QueryBudget = 150 * time.Millisecond
+	c.EmbeddingService.Backend = "remote"
+	c.EmbeddingService.Local.MaxTokens = 384
+	c.EmbeddingService.Health.Path = "/health"
+	c.EmbeddingService.Health.Interval = 10 * time.Second
+	c.EmbeddingService.Health.Timeout = time.Second
+	c.EmbeddingService.Health.FailureThreshold = 2
+
+	// Search defaults
+	c.Search.MaxResults = 100
+	c.Search.DefaultResults = 20
+	c.Search.RankingWeights = RankingWeights{Relevance: 0.4, Popularity: 0.2, Performance: 0.2, Compliance: 0.2}
+	c.Search.FuzzyEnabled = true
+	c.Search.FuzzyDistance = 2
+	c.Search.SemanticEnabled = true
+	c.Search.SemanticThreshold = 0.7
+	c.Search.HybridAlpha = 0.5
+	c.Search.Autocomplete = AutocompleteConfig{
+		Suggester:     true,
+		NameWeight:    0.6,
+		QueryWeight:   0.4,
+		QueryHalfLife: 168 * time.Hour,
+		MinQueryCount: 3,
+	}
+	c.Search.ReportDemotion = ReportDemotionConfig{Threshold: 2, Factor: 0.5}
+	c.Search.QueryUnderstanding = QueryUnderstandingConfig{
+		Enabled:            true,
+		CheapPrice:         0.001,
+		EmbeddingThreshold: 0.8,
+		Capabilities: map[string][]string{
+			"summarization":      {"summarize", "summarise", "summary", "summaries", "summarisation"},
+			"translation":        {"translate", "translator", "translating"},
+			"classification":     {"classify", "classifier", "categorization"},
+			"embeddings":         {"embedding", "embed", "vectorization"},
+			"transcription":      {"transcribe", "speech-to-text", "speech to text"},
+			"code-generation":    {"code generation", "codegen", "coding"},
+			"sentiment-analysis": {"sentiment", "sentiment analysis"},
+			"question-answering": {"question answering", "qa"},
+			"chat":               {"chatbot", "conversational"},
+		},
+	}
+
+	c.Search.QueryPipeline = QueryPipelineConfig{
+		Lowercase:       true,
+		RemoveStopwords: true,
+		Stopwords:       []string{"a", "an", "the", "for", "of", "to", "with", "that", "which", "i", "me", "my", "need", "want", "looking", "find", "some", "please"},
+		ExpandAcronyms:  true,
+		Acronyms: map[string]string{
+			"llm": "large language model",
+			"asr": "automatic speech recognition",
+			"stt": "speech to text",
+			"tts": "text to speech",
+			"ocr": "optical character recognition",
+			"ner": "named entity recognition",
+			"nlp": "natural language processing",
+			"rag": "retrieval augmented generation",
+		},
+		InjectSynonyms: true,
+		Synonyms: map[string][]string{
+			"chatbot":   {"chat", "assistant"},
+			"speech":    {"audio", "voice"},
+			"image":     {"vision"},
+			"vector":    {"embedding"},
+			"summarize": {"summarization"},
+			"translate": {"translation"},
+		},
+		LLMRewriteTimeout: 300 * time.Millisecond,
+	}
+
+	c.Search.Session = SessionRerankConfig{
+		TTL:           30 * time.Minute,
+		CategoryBoost: 0.3,
+		DismissFactor: 0.5,
+	}
+
+	c.Search.RegionRanking = RegionRankingConfig{
+		Enabled:          true,
+		LatencyBoost:     0.3,
+		ReferenceLatency: 300 * time.Millisecond,
+	}
+
+	c.Search.StaleWhileRevalidate = StaleWhileRevalidateConfig{
+		Enabled:        true,
+		StaleTTL:       5 * time.Minute,
+		RefreshTimeout: 5 * time.Second,
+	}
+	c.Search.Warmup = WarmupConfig{
+		Enabled:     true,
+		TopQueries:  50,
+		Concurrency: 4,
+		Timeout:     30 * time.Second,
+	}
+
+	c.Search.Guardrails = GuardrailsConfig{
+		Enabled:        true,
+		MaxFilters:     50,
+		MaxPageSize:    100,
+		MaxOffset:      5000,
+		MaxQueryLength: 512,
+		MaxFuzzyTerms:  32,
+		Timeout:        2 * time.Second,
+		PartialResults: true,
+	}
+	c.Search.LatencyBudget = LatencyBudgetConfig{
+		Total:              3 * time.Second,
+		EmbeddingShare:     0.25,
+		CacheShare:         0.05,
+		ElasticsearchShare: 0.8,
+	}
+	c.Search.DebugCapture = DebugCaptureConfig{TTL: 15 * time.Minute}
+
+	// Assistant defaults
+	c.Assistant.Enabled = true
+	c.Assistant.Backend = AssistantBackendOpenAI
+	c.Assistant.URL = "http://localhost:8002/v1"
+	c.Assistant.Model = "gpt-4o-mini"
+	c.Assistant.Timeout = 20 * time.Second
+	c.Assistant.MaxServices = 5
+	c.Assistant.MaxTokens = 512
+	c.Assistant.Temperature = 0.2
+
+	// Recommendation defaults
+	c.Recommendations.Enabled = true
+	c.Recommendations.MaxRecommendations = 10
+	c.Recommendations.CollaborativeWeight = 0.4
+	c.Recommendations.ContentWeight = 0.3
+	c.Recommendations.PopularityWeight = 0.3
+	c.Recommendations.MinCommonUsers = 3
+	c.Recommendations.SimilarityThreshold = 0.6
+	c.Recommendations.TrendingWindow = 24 * time.Hour
+	c.Recommendations.TrendingMinInteractions = 10
+	c.Recommendations.Slate = map[string]PlacementConfig{
+		PlacementHomepageHero:       {Algorithm: AlgorithmHybrid, Budget: 3},
+		PlacementBecauseYouUsed:     {Algorithm: AlgorithmContent, Budget: 8},
+		PlacementTrendingInCategory: {Algorithm: AlgorithmTrending, Budget: 8},
+		PlacementNewArrivals:        {Algorithm: AlgorithmNewest, Budget: 8},
+		PlacementFineTuning:         {Algorithm: AlgorithmFineTuning, Budget: 4},
+	}
+	c.Recommendations.Hydration = HydrationConfig{
+		BatchSize:   10,
+		Concurrency: 4,
+		Timeout:     250 * time.Millisecond,
+	}
+
+	// Feature store defaults; ranking and recommendations ignore it while disabled
+	c.Features.RefreshInterval = time.Hour
+	c.Features.Window = 90 * 24 * time.Hour
+	c.Features.CacheTTL = time.Hour
+	c.Features.AffinityBoost = 0.2
+	c.Features.PriceWeight = 0.2
+	c.Features.TrustWeight = 0.1
+
+	// Privacy defaults; pseudonymization needs a key
+	c.Privacy.PurgeInterval = time.Hour
+	c.Privacy.BatchSize = 5000
+	c.Privacy.Retention.UserInteractions = 90 * 24 * time.Hour
+	c.Privacy.Retention.SearchAnalytics = 90 * 24 * time.Hour
+
+	// Performance defaults
+	c.Performance.TargetP95LatencyMS = 200
+	c.Performance.TargetP99LatencyMS = 500
+	c.Performance.MaxConcurrentRequests = 10000
+	c.Performance.CircuitBreakerThreshold = 0.5
+	c.Performance.CircuitBreakerTimeout = 30 * time.Second
+	c.Performance.CompressionEnabled = true
+	c.Performance.CompressionMinSize = 1024
+
+	// Observability defaults; tracing and OTLP export need an endpoint
+	c.Observability.Metrics.Enabled = true
+	c.Observability.Metrics.Port = 9090
+	c.Observability.Metrics.Path = "/metrics"
+	c.Observability.Metrics.CollectInterval = 15 * time.Second
+	c.Observability.Metrics.OTel.Enabled = true
+	c.Observability.Metrics.OTel.Prometheus = true
+	c.Observability.Metrics.OTel.ExportInterval = 30 * time.Second
+	c.Observability.Metrics.OTel.OTLP.Protocol = "grpc"
+	c.Observability.Tracing.Exporter = "otlp"
+	c.Observability.Tracing.OTLP.Protocol = "grpc"
+	c.Observability.Tracing.Sampler = "parent_based"
+	c.Observability.Tracing.SamplingRate = 0.1
+	c.Observability.Logging.Level = "info"
+	c.Observability.Logging.Format = "json"
+	c.Observability.Logging.Output = "stdout"
+	c.Observability.Logging.Access.SuccessSampleRate = 1.0
+	c.Observability.Logging.Access.SlowThreshold = time.Second
+
+	// Integration defaults; the analytics hub and catalog need Kafka brokers
+	c.PolicyEngine.GRPCEndpoint = "localhost:50051"
+	c.PolicyEngine.Timeout = 5 * time.Second
+	c.PolicyEngine.CacheTTL = 5 * time.Minute
+	c.AnalyticsHub.Topic = "marketplace.search.events"
+	c.AnalyticsHub.BatchSize = 100
+	c.AnalyticsHub.FlushInterval = 5 * time.Second
+	c.AnalyticsHub.BufferSize = 10000
+	c.AnalyticsHub.Aggregation.ConsumerGroup = "discovery-analytics"
+	c.AnalyticsHub.Aggregation.FlushInterval = 30 * time.Second
+	c.AnalyticsHub.Aggregation.MaxPending = 50000
+	c.AnalyticsHub.Related = RelatedConfig{
+		Enabled:         true,
+		SessionWindow:   30 * time.Minute,
+		Window:          30 * 24 * time.Hour,
+		RefreshInterval: time.Hour,
+		MinCount:        3,
+		MaxRelated:      10,
+	}
+	c.Catalog.Topic = marketplace.CatalogTopic
+	c.Catalog.ConsumerGroup = "discovery-catalog"
+	c.Catalog.RetryBackoff = time.Second
+	c.Catalog.MaxRetryBackoff = time.Minute
+
+	c.SLAMonitoring.Enabled = true
+	c.SLAMonitoring.ProbeInterval = time.Minute
+	c.SLAMonitoring.ProbeTimeout = 5 * time.Second
+	c.SLAMonitoring.Concurrency = 10
+	c.SLAMonitoring.WindowSize = 1440
+	c.SLAMonitoring.MaxServices = 1000
+	c.SLAMonitoring.DegradedLatencyMS = 1000
+	c.SLAMonitoring.ReportMaxAge = time.Hour
+
+	c.Export.MaxRows = 10000
+	c.Export.AsyncMaxRows = 1000000
+	c.Export.Directory = "/tmp/discovery-exports"
+	c.Export.JobTimeout = 30 * time.Minute
+	c.Export.JobTTL = 24 * time.Hour
+
+	c.Snapshots.Prefix = "catalog-snapshots"
+	c.Snapshots.BatchSize = 1000
+	c.Snapshots.KeepAlive = 5 * time.Minute
+	c.Snapshots.IncludeEmbeddings = true
+	c.Snapshots.Directory = "/tmp/discovery-snapshots"
+	c.Snapshots.Timeout = time.Hour
+
+	c.Feed.Retention = 30 * 24 * time.Hour
+	c.Feed.PageSize = 100
+	c.Feed.MaxPageSize = 1000
+	c.Feed.PruneInterval = time.Hour
+	c.Feed.PushTimeout = 10 * time.Second
+
+	c.Webhooks.Enabled = true
+	c.Webhooks.PollInterval = 5 * time.Second
+	c.Webhooks.BatchSize = 50
+	c.Webhooks.Timeout = 10 * time.Second
+	c.Webhooks.MaxAttempts = 8
+	c.Webhooks.InitialBackoff = 30 * time.Second
+	c.Webhooks.MaxBackoff = time.Hour
+
+	c.Entitlements.TenantHeader = "X-Tenant-ID"
+	c.Entitlements.UserHeader = "X-User-ID"
+	c.Entitlements.OperatorHeader = "X-Operator-ID"
+	c.Entitlements.ProviderHeader = "X-Provider-ID"
+
+	// Quota defaults; tiers come from config.yaml
+	c.Quotas.APIKeyHeader = "X-API-Key-ID"
+	c.Quotas.DefaultTier = "standard"
+	c.Quotas.AnonymousTier = "anonymous"
+
+	c.Subscriptions.RegistryURL = "http://localhost:3010"
+	c.Subscriptions.Timeout = 2 * time.Second
+	c.Subscriptions.CacheTTL = time.Minute
+
+	// Secrets defaults
+	c.Secrets.RefreshInterval = 5 * time.Minute
+	c.Secrets.Timeout = 10 * time.Second
+
+	// Feature flag defaults; flags left undefined are on
+	c.FeatureFlags.Source = FlagSourceConfig
+	c.FeatureFlags.RedisKey = "discovery:feature_flags"
+	c.FeatureFlags.RefreshInterval = 30 * time.Second
+}
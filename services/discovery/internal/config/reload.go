@@ -0,0 +1,203 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/org/llm-marketplace/pkg/marketplace/flags"
+	"go.uber.org/zap"
+)
+
+// reloadable holds the settings that can change while the service runs;
+// everything else is fixed at startup
+type reloadable struct {
+	mu       sync.RWMutex
+	weights  RankingWeights
+	cacheTTL map[string]string
+	flags    flags.Set
+}
+
+func newReloadable(cfg *Config) *reloadable {
+	return &reloadable{weights: cfg.Search.RankingWeights, cacheTTL: cfg.Redis.CacheTTL, flags: cfg.FeatureFlags.Flags}
+}
+
+// RankingWeights returns the search ranking weights in effect, which a
+// Watcher may have changed since startup
+func (c *Config) RankingWeights() RankingWeights {
+	if c.live == nil {
+		return c.Search.RankingWeights
+	}
+	c.live.mu.RLock()
+	defer c.live.mu.RUnlock()
+	return c.live.weights
+}
+
+// CacheTTL returns the cache TTL in effect for key, which a Watcher may have
+// changed since startup
+func (c *Config) CacheTTL(key string) time.Duration {
+	if c.live == nil {
+		return c.Redis.GetCacheTTL(key)
+	}
+	c.live.mu.RLock()
+	defer c.live.mu.RUnlock()
+	redis := RedisConfig{CacheTTL: c.live.cacheTTL}
+	return redis.GetCacheTTL(key)
+}
+
+// Flags returns the feature flags set in the config file, which a Watcher
+// may have changed since startup. It is the flags source "config".
+func (c *Config) Flags(context.Context) (flags.Set, error) {
+	if c.live == nil {
+		return c.FeatureFlags.Flags, nil
+	}
+	c.live.mu.RLock()
+	defer c.live.mu.RUnlock()
+	return c.live.flags, nil
+}
+
+// Reload applies the ranking weights, cache TTLs and feature flags of next, which must be
+// valid, and returns the settings that changed. Restart reports whether next
+// also differs in settings that only take effect on restart.
+func (c *Config) Reload(next *Config) (changed []string, restart bool) {
+	if c.live == nil {
+		c.live = newReloadable(c)
+	}
+
+	c.live.mu.Lock()
+	if c.live.weights != next.Search.RankingWeights {
+		changed = append(changed, "search.ranking_weights")
+	}
+	for key := range unionKeys(c.live.cacheTTL, next.Redis.CacheTTL) {
+		if c.live.cacheTTL[key] != next.Redis.CacheTTL[key] {
+			changed = append(changed, "redis.cache_ttl."+key)
+		}
+	}
+	if !reflect.DeepEqual(c.live.flags, next.FeatureFlags.Flags) {
+		changed = append(changed, "feature_flags.flags")
+	}
+	c.live.weights = next.Search.RankingWeights
+	c.live.cacheTTL = next.Redis.CacheTTL
+	c.live.flags = next.FeatureFlags.Flags
+	c.live.mu.Unlock()
+	sort.Strings(changed)
+
+	// Compare the rest with the reloadable settings taken from the original
+	fixed := *next
+	fixed.Search.RankingWeights = c.Search.RankingWeights
+	fixed.Redis.CacheTTL = c.Redis.CacheTTL
+	fixed.FeatureFlags.Flags = c.FeatureFlags.Flags
+	fixed.live = c.live
+	return changed, !reflect.DeepEqual(&fixed, c)
+}
+
+func unionKeys(a, b map[string]string) map[string]bool {
+	keys := make(map[string]bool, len(a)+len(b))
+	for k := range a {
+		keys[k] = true
+	}
+	for k := range b {
+		keys[k] = true
+	}
+	return keys
+}
+
+// Watcher reloads the config file when it changes. Changes that fail to
+// load or validate are logged and ignored.
+type Watcher struct {
+	path   string
+	cfg    *Config
+	logger *zap.Logger
+
+	mu   sync.Mutex
+	last []byte
+}
+
+// NewWatcher creates a watcher applying changes to path to cfg
+func NewWatcher(path string, cfg *Config, logger *zap.Logger) *Watcher {
+	last, _ := os.ReadFile(path)
+	return &Watcher{path: path, cfg: cfg, logger: logger, last: last}
+}
+
+// Start watches the file until ctx is cancelled. The directory is watched
+// rather than the file, so editors and Kubernetes ConfigMaps that replace
+// the file are followed.
+func (w *Watcher) Start(ctx context.Context) {
+	fsWatcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		w.logger.Error("Failed to start config watcher", zap.Error(err))
+		return
+	}
+	defer fsWatcher.Close()
+
+	dir := filepath.Dir(w.path)
+	if err := fsWatcher.Add(dir); err != nil {
+		w.logger.Error("Failed to watch config directory", zap.String("dir", dir), zap.Error(err))
+		return
+	}
+
+	w.logger.Info("Watching config file for changes", zap.String("path", w.path))
+
+	// Writes arrive as bursts of events; reload once they settle
+	debounce := time.NewTimer(time.Hour)
+	debounce.Stop()
+	defer debounce.Stop()
+
+	for {
+		select {
+		case event, ok := <-fsWatcher.Events:
+			if !ok {
+				return
+			}
+			if event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename) != 0 {
+				debounce.Reset(100 * time.Millisecond)
+			}
+		case err, ok := <-fsWatcher.Errors:
+			if !ok {
+				return
+			}
+			w.logger.Warn("Config watcher error", zap.Error(err))
+		case <-debounce.C:
+			w.Reload()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Reload loads the file and applies it if its contents changed
+func (w *Watcher) Reload() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	data, err := os.ReadFile(w.path)
+	if err != nil {
+		w.logger.Error("Failed to read config file; keeping current settings", zap.Error(err))
+		return err
+	}
+	if bytes.Equal(data, w.last) {
+		return nil
+	}
+
+	next, err := load(w.path)
+	if err != nil {
+		w.logger.Error("Config file is invalid; keeping current settings", zap.Error(err))
+		return err
+	}
+	w.last = data
+
+	changed, restart := w.cfg.Reload(next)
+	if len(changed) > 0 {
+		w.logger.Info("Configuration reloaded", zap.Strings("changed", changed))
+	}
+	if restart {
+		w.logger.Warn("Config file changes settings that only apply after a restart; only ranking weights, cache TTLs and feature flags are reloaded")
+	}
+	return nil
+}
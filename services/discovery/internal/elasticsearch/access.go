@@ -0,0 +1,27 @@
+package elasticsearch
+
+// Service visibility levels
+const (
+	VisibilityPublic  = "public"
+	VisibilityTenant  = "tenant"
+	VisibilityPrivate = "private"
+)
+
+// AccessInfo controls who may see a service. Tenant services are visible to
+// the owner tenant and the allow-lists; private services only to the
+// allow-lists. Documents without access info are public.
+type AccessInfo struct {
+	Visibility     string   `json:"visibility"`
+	OwnerTenant    string   `json:"owner_tenant,omitempty"`
+	AllowedTenants []string `json:"allowed_tenants,omitempty"`
+	AllowedUsers   []string `json:"allowed_users,omitempty"`
+}
+
+// IsValidVisibility reports whether visibility is a known level
+func IsValidVisibility(visibility string) bool {
+	switch visibility {
+	case VisibilityPublic, VisibilityTenant, VisibilityPrivate:
+		return true
+	}
+	return false
+}
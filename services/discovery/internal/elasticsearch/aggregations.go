@@ -0,0 +1,95 @@
+package elasticsearch
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// TermsAggregation is the result of a terms aggregation. Buckets is empty
+// when the aggregation matched nothing or Elasticsearch omitted them.
+type TermsAggregation struct {
+	Buckets []TermsBucket `json:"buckets"`
+}
+
+// TermsBucket is one term of a terms aggregation
+type TermsBucket struct {
+	Key      BucketKey `json:"key"`
+	DocCount int       `json:"doc_count"`
+}
+
+// RatedTermsBucket is a terms bucket with an avg_rating sub-aggregation
+type RatedTermsBucket struct {
+	TermsBucket
+	AvgRating MetricValue `json:"avg_rating"`
+}
+
+// BucketKey is the key of a terms bucket. Terms over numeric or boolean
+// fields key their buckets with the value itself rather than a string, so
+// those are kept in their JSON form.
+type BucketKey string
+
+// UnmarshalJSON accepts a string, number or boolean key
+func (k *BucketKey) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '"' {
+		var key string
+		if err := json.Unmarshal(data, &key); err != nil {
+			return err
+		}
+		*k = BucketKey(key)
+		return nil
+	}
+	switch {
+	case bytes.Equal(data, []byte("null")):
+		*k = ""
+	case bytes.Equal(data, []byte("true")), bytes.Equal(data, []byte("false")):
+		*k = BucketKey(data)
+	default:
+		var n json.Number
+		if err := json.Unmarshal(data, &n); err != nil {
+			return fmt.Errorf("unexpected bucket key %s", data)
+		}
+		*k = BucketKey(n)
+	}
+	return nil
+}
+
+// MetricValue is the result of a single-value metric aggregation such as
+// avg. Value is nil when no document had the field.
+type MetricValue struct {
+	Value *float64 `json:"value"`
+}
+
+// Aggregation decodes the aggregation named name into v, reporting whether
+// the response has it. Aggregations are kept encoded until asked for, so
+// each is decoded straight into the type its reader wants. A missing or null
+// aggregation leaves v untouched; one of an unexpected shape is an error
+// rather than a panic.
+func (r *SearchResponse) Aggregation(name string, v interface{}) (bool, error) {
+	raw, ok := r.Aggregations[name]
+	if !ok || bytes.Equal(bytes.TrimSpace(raw), []byte("null")) {
+		return false, nil
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		return true, fmt.Errorf("failed to decode %s aggregation: %w", name, err)
+	}
+	return true, nil
+}
+
+// DecodeAggregations decodes every aggregation untyped, for responses that
+// pass them on as Elasticsearch returned them
+func (r *SearchResponse) DecodeAggregations() (map[string]interface{}, error) {
+	if r.Aggregations == nil {
+		return nil, nil
+	}
+	aggs := make(map[string]interface{}, len(r.Aggregations))
+	for name, raw := range r.Aggregations {
+		var agg interface{}
+		if err := json.Unmarshal(raw, &agg); err != nil {
+			return nil, fmt.Errorf("failed to decode %s aggregation: %w", name, err)
+		}
+		aggs[name] = agg
+	}
+	return aggs, nil
+}
@@ -0,0 +1,266 @@
+package elasticsearch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/org/llm-marketplace/services/discovery/internal/observability"
+)
+
+// defaultBulkMaxBytes applies when elasticsearch.bulk.max_bytes is unset
+const defaultBulkMaxBytes = 5 << 20
+
+// ErrBulkItemsFailed is returned when some documents in a bulk request could not be indexed
+var ErrBulkItemsFailed = errors.New("bulk indexing failed for some documents")
+
+// BulkResult reports what a bulk request did with each document
+type BulkResult struct {
+	Indexed int           `json:"indexed"`
+	Failed  []BulkFailure `json:"failed,omitempty"`
+}
+
+// BulkFailure is a document Elasticsearch rejected
+type BulkFailure struct {
+	ID     string `json:"id"`
+	Status int    `json:"status"`
+	Type   string `json:"type,omitempty"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// bulkItem is one document's action and source lines
+type bulkItem struct {
+	id      string
+	lines   []byte
+	failure *BulkFailure // Last retryable failure, reported if attempts run out
+}
+
+// bulkResponse is the part of a bulk response needed to find failed items
+type bulkResponse struct {
+	Errors bool `json:"errors"`
+	Items  []map[string]struct {
+		ID     string `json:"_id"`
+		Status int    `json:"status"`
+		Error  *struct {
+			Type   string `json:"type"`
+			Reason string `json:"reason"`
+		} `json:"error"`
+	} `json:"items"`
+}
+
+// SetMetrics registers where bulk indexing outcomes are counted
+func (c *Client) SetMetrics(m *observability.Metrics) {
+	c.metrics = m
+}
+
+func (c *Client) countBulk(result string, n int) {
+	if c.metrics != nil && n > 0 {
+		c.metrics.BulkDocuments(result, n)
+	}
+}
+
+// BulkIndex indexes docs in requests of at most the configured size. Documents
+// that fail validation are reported without being sent. Documents rejected
+// with 429 or 5xx are retried with backoff; the rest of the batch is not held
+// up by them. The returned error wraps ErrBulkItemsFailed when any document
+// was not indexed, and the result lists which.
+func (c *Client) BulkIndex(ctx context.Context, docs []*ServiceDocument) (*BulkResult, error) {
+	result := &BulkResult{}
+	if len(docs) == 0 {
+		return result, nil
+	}
+
+	pending := make([]*bulkItem, 0, len(docs))
+	for _, doc := range docs {
+		if err := doc.Validate(); err != nil {
+			result.Failed = append(result.Failed, BulkFailure{
+				ID:     doc.ID,
+				Status: http.StatusBadRequest,
+				Type:   "validation_exception",
+				Reason: err.Error(),
+			})
+			c.countBulk("failed", 1)
+			continue
+		}
+		if err := c.assignTenant(ctx, doc); err != nil {
+			return nil, fmt.Errorf("%w: %s", err, doc.ID)
+		}
+		c.checkPolicies(ctx, doc)
+
+		action := map[string]interface{}{
+			"_index": c.config.IndexName,
+			"_id":    doc.ID,
+		}
+		if doc.TenantID != "" {
+			action["routing"] = doc.TenantID
+		}
+
+		var buf bytes.Buffer
+		if err := json.NewEncoder(&buf).Encode(map[string]interface{}{"index": action}); err != nil {
+			return nil, fmt.Errorf("failed to marshal bulk action: %w", err)
+		}
+		if err := json.NewEncoder(&buf).Encode(doc.indexed()); err != nil {
+			return nil, fmt.Errorf("failed to marshal document %s: %w", doc.ID, err)
+		}
+		pending = append(pending, &bulkItem{id: doc.ID, lines: buf.Bytes()})
+	}
+
+	maxAttempts := c.config.Bulk.MaxAttempts
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+
+	for attempt := 1; len(pending) > 0; attempt++ {
+		if attempt > 1 {
+			if err := sleepContext(ctx, retryBackoff(c.config.Bulk.InitialBackoff, c.config.Bulk.MaxBackoff)(attempt-1)); err != nil {
+				c.failBulk(result, pending)
+				return result, err
+			}
+		}
+
+		var retry []*bulkItem
+		for _, chunk := range c.chunkBulk(pending) {
+			retryable, err := c.sendBulk(ctx, chunk, result)
+			if err != nil {
+				c.failBulk(result, chunk)
+				return result, err
+			}
+			retry = append(retry, retryable...)
+		}
+
+		if len(retry) > 0 && attempt >= maxAttempts {
+			c.failBulk(result, retry)
+			break
+		}
+		c.countBulk("retried", len(retry))
+		pending = retry
+	}
+
+	if len(result.Failed) > 0 {
+		return result, fmt.Errorf("%w: %d of %d", ErrBulkItemsFailed, len(result.Failed), len(docs))
+	}
+	return result, nil
+}
+
+// chunkBulk splits items into request bodies no larger than MaxBytes; an item
+// larger than that is sent on its own
+func (c *Client) chunkBulk(items []*bulkItem) [][]*bulkItem {
+	maxBytes := c.config.Bulk.MaxBytes
+	if maxBytes <= 0 {
+		maxBytes = defaultBulkMaxBytes
+	}
+
+	var chunks [][]*bulkItem
+	var chunk []*bulkItem
+	size := 0
+	for _, item := range items {
+		if len(chunk) > 0 && size+len(item.lines) > maxBytes {
+			chunks = append(chunks, chunk)
+			chunk, size = nil, 0
+		}
+		chunk = append(chunk, item)
+		size += len(item.lines)
+	}
+	if len(chunk) > 0 {
+		chunks = append(chunks, chunk)
+	}
+	return chunks
+}
+
+// sendBulk sends one request, records indexed and permanently failed items in
+// result, and returns the items worth retrying
+func (c *Client) sendBulk(ctx context.Context, chunk []*bulkItem, result *BulkResult) ([]*bulkItem, error) {
+	var body bytes.Buffer
+	for _, item := range chunk {
+		body.Write(item.lines)
+	}
+
+	ctx, cancel := withTimeout(ctx, c.config.Timeouts.Bulk)
+	defer cancel()
+
+	res, err := c.es.Bulk(&body, c.es.Bulk.WithContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("bulk indexing failed: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		respBody, _ := io.ReadAll(res.Body)
+		// The whole request was rejected, e.g. a full write queue; retry every item
+		if retryableStatus(res.StatusCode) {
+			for _, item := range chunk {
+				item.failure = &BulkFailure{ID: item.id, Status: res.StatusCode, Reason: string(respBody)}
+			}
+			return chunk, nil
+		}
+		return nil, fmt.Errorf("bulk indexing error: %s - %s", res.Status(), string(respBody))
+	}
+
+	var parsed bulkResponse
+	if err := json.NewDecoder(res.Body).Decode(&parsed); err != nil {
+		return nil, fmt.Errorf("failed to decode bulk response: %w", err)
+	}
+	if len(parsed.Items) != len(chunk) {
+		return nil, fmt.Errorf("bulk response has %d items for %d documents", len(parsed.Items), len(chunk))
+	}
+
+	var retry []*bulkItem
+	indexed := 0
+	for i, entry := range parsed.Items {
+		for _, outcome := range entry {
+			if outcome.Status >= 200 && outcome.Status < 300 {
+				indexed++
+				continue
+			}
+
+			failure := BulkFailure{ID: chunk[i].id, Status: outcome.Status}
+			if outcome.Error != nil {
+				failure.Type = outcome.Error.Type
+				failure.Reason = outcome.Error.Reason
+			}
+			if retryableStatus(outcome.Status) {
+				chunk[i].failure = &failure
+				retry = append(retry, chunk[i])
+				continue
+			}
+			result.Failed = append(result.Failed, failure)
+			c.countBulk("failed", 1)
+		}
+	}
+	result.Indexed += indexed
+	c.countBulk("indexed", indexed)
+
+	return retry, nil
+}
+
+// failBulk records items that will not be retried again
+func (c *Client) failBulk(result *BulkResult, items []*bulkItem) {
+	for _, item := range items {
+		failure := BulkFailure{ID: item.id}
+		if item.failure != nil {
+			failure = *item.failure
+		}
+		result.Failed = append(result.Failed, failure)
+	}
+	c.countBulk("failed", len(items))
+}
+
+func retryableStatus(status int) bool {
+	return status == http.StatusTooManyRequests || status >= 500
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
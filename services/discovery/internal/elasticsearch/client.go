@@ -4,62 +4,121 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
 	"time"
 
 	"github.com/elastic/go-elasticsearch/v8"
 	"github.com/elastic/go-elasticsearch/v8/esapi"
+	"github.com/org/llm-marketplace/pkg/marketplace"
 	"github.com/org/llm-marketplace/services/discovery/internal/config"
+	"github.com/org/llm-marketplace/services/discovery/internal/observability"
+	"go.opentelemetry.io/otel"
 )
 
+// ErrNotFound is returned when a document does not exist in the index
+var ErrNotFound = errors.New("document not found")
+
+// DefaultSourceExcludes are fields left out of read paths that don't need them
+var DefaultSourceExcludes = []string{"embedding", "embeddings", suggestField}
+
 type Client struct {
-	es     *elasticsearch.Client
-	config config.ElasticsearchConfig
+	es              *elasticsearch.Client
+	config          config.ElasticsearchConfig
+	metrics         *observability.Metrics
+	policyValidator PolicyValidator
 }
 
 // ServiceDocument represents a service in Elasticsearch
 type ServiceDocument struct {
-	ID          string                 `json:"id"`
-	Name        string                 `json:"name"`
-	Description string                 `json:"description"`
-	Category    string                 `json:"category"`
-	Tags        []string               `json:"tags"`
-	Provider    ProviderInfo           `json:"provider"`
-	Capabilities []string              `json:"capabilities"`
-	Pricing     PricingInfo            `json:"pricing"`
-	SLA         SLAInfo                `json:"sla"`
-	Compliance  ComplianceInfo         `json:"compliance"`
-	Status      string                 `json:"status"`
-	Metrics     MetricsInfo            `json:"metrics"`
-	Embedding   []float32              `json:"embedding,omitempty"` // Vector embedding for semantic search
-	CreatedAt   time.Time              `json:"created_at"`
-	UpdatedAt   time.Time              `json:"updated_at"`
-	Metadata    map[string]interface{} `json:"metadata,omitempty"`
-}
-
-type ProviderInfo struct {
-	ID       string `json:"id"`
-	Name     string `json:"name"`
-	Verified bool   `json:"verified"`
+	ID               string                 `json:"id"`
+	Name             string                 `json:"name"`
+	Description      string                 `json:"description"`
+	Category         string                 `json:"category"`
+	Tags             []string               `json:"tags"`
+	Provider         ProviderInfo           `json:"provider"`
+	Capabilities     []string               `json:"capabilities"`
+	Dependencies     []Dependency           `json:"dependencies,omitempty"`    // Services this one is built on
+	Kind             string                 `json:"kind,omitempty"`            // One of marketplace.Kinds; a service when empty
+	PromptTemplate   *PromptTemplate        `json:"prompt_template,omitempty"` // Set on prompt templates only
+	Dataset          *DatasetInfo           `json:"dataset,omitempty"`         // Set on datasets only
+	FineTuning       *FineTuningOffering    `json:"fine_tuning,omitempty"`     // Set on fine-tuning offerings only
+	ServingRegions   []ServingRegion        `json:"serving_regions,omitempty"` // Where the service is served from, with the latency measured in each
+	Endpoint         string                 `json:"endpoint,omitempty"`        // Health-check URL probed by SLA monitoring
+	Pricing          PricingInfo            `json:"pricing"`
+	SLA              SLAInfo                `json:"sla"`
+	Compliance       ComplianceInfo         `json:"compliance"`
+	Status           string                 `json:"status"`
+	UpheldReports    int                    `json:"upheld_reports,omitempty"`      // Consumer reports upheld against the listing; repeat offenders are demoted
+	Expiries         []ComplianceExpiry     `json:"compliance_expiries,omitempty"` // Certifications and data-processing agreement expiring or expired, as flagged by the registry
+	Changelog        []ServiceChange        `json:"changelog,omitempty"`           // Pricing, SLA and capability changes, newest first
+	Deprecation      *DeprecationInfo       `json:"deprecation,omitempty"`
+	ServiceKey       string                 `json:"service_key,omitempty"` // Shared by every version of the service; defaults to ID
+	Version          *VersionInfo           `json:"version,omitempty"`
+	Access           *AccessInfo            `json:"access,omitempty"`            // Nil means public
+	TenantID         string                 `json:"tenant_id,omitempty"`         // Private marketplace the service belongs to; empty for the shared catalog
+	PolicyCompliance *PolicyCompliance      `json:"policy_compliance,omitempty"` // Result of the last policy engine check, set as the service is indexed
+	Metrics          MetricsInfo            `json:"metrics"`
+	Embedding        []float32              `json:"embedding,omitempty"`        // Vector embedding for semantic search
+	Embeddings       map[string][]float32   `json:"embeddings,omitempty"`       // Vectors from named models, keyed by model name
+	EmbeddingModels  []string               `json:"embedding_models,omitempty"` // Field, model and dimensions of every vector the document holds
+	CreatedAt        time.Time              `json:"created_at"`
+	UpdatedAt        time.Time              `json:"updated_at"`
+	Metadata         map[string]interface{} `json:"metadata,omitempty"`
 }
 
-type PricingInfo struct {
-	Model string  `json:"model"`
-	Rate  float64 `json:"rate"`
-	Unit  string  `json:"unit"`
-}
+// The provider, pricing, SLA and compliance sections, changelog entries,
+// dependencies, serving regions and compliance expiries are the shared
+// marketplace types
+type (
+	ProviderInfo     = marketplace.ProviderInfo
+	PricingInfo      = marketplace.PricingInfo
+	SLAInfo          = marketplace.SLAInfo
+	ComplianceInfo   = marketplace.ComplianceInfo
+	ServiceChange    = marketplace.ServiceChange
+	Dependency       = marketplace.Dependency
+	ServingRegion    = marketplace.ServingRegion
+	ComplianceExpiry = marketplace.ComplianceExpiry
+)
 
-type SLAInfo struct {
-	Availability float64 `json:"availability"`
-	MaxLatencyMS int     `json:"max_latency_ms"`
-	Support      string  `json:"support"`
+// Descriptor returns the document as a shared service descriptor, as sent
+// to the policy engine for validation
+func (d *ServiceDocument) Descriptor() *marketplace.ServiceDescriptor {
+	desc := &marketplace.ServiceDescriptor{
+		ServiceID:   d.ID,
+		Name:        d.Name,
+		Description: d.Description,
+		ProviderID:  d.Provider.ID,
+		Category:    d.Category,
+		Compliance:  &d.Compliance,
+		SLA:         &d.SLA,
+		Pricing:     &d.Pricing,
+	}
+	if d.Version != nil {
+		desc.Version = d.Version.Number
+	}
+	if d.Endpoint != "" {
+		desc.Endpoint = &marketplace.EndpointInfo{URL: d.Endpoint}
+	}
+	for _, name := range d.Capabilities {
+		desc.Capabilities = append(desc.Capabilities, marketplace.Capability{Name: name})
+	}
+	desc.Dependencies = d.Dependencies
+	desc.Kind = d.Kind
+	desc.PromptTemplate = d.PromptTemplate
+	desc.Dataset = d.Dataset
+	desc.FineTuning = d.FineTuning
+	desc.ServingRegions = d.ServingRegions
+	return desc
 }
 
-type ComplianceInfo struct {
-	Level           string   `json:"level"`
-	Certifications  []string `json:"certifications"`
-	DataResidency   []string `json:"data_residency"`
+// Validate checks the document's pricing, SLA and compliance sections
+func (d *ServiceDocument) Validate() error {
+	return d.Descriptor().Validate()
 }
 
 type MetricsInfo struct {
@@ -69,18 +128,43 @@ type MetricsInfo struct {
 	Rating          float64 `json:"rating"`
 	ReviewCount     int     `json:"review_count"`
 	PopularityScore float64 `json:"popularity_score"`
+
+	// Quality is benchmark quality by task category, from 0 to 1
+	Quality map[string]float64 `json:"quality,omitempty"`
 }
 
-// NewClient creates a new Elasticsearch client
-func NewClient(cfg config.ElasticsearchConfig) (*Client, error) {
+// retryStatuses are the responses the client retries, on another node where
+// there is one. 429 is included so a node shedding load doesn't fail the call.
+var retryStatuses = []int{http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout}
+
+// NewClient creates a new Elasticsearch client. Requests are spread across the
+// configured addresses, and across discovered nodes when sniffing is enabled;
+// a node that fails is taken out of rotation until it recovers. password,
+// when set, supplies the password for each request in place of cfg.Password.
+func NewClient(cfg config.ElasticsearchConfig, password func() string) (*Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if cfg.Timeouts.Connect > 0 {
+		transport.DialContext = (&net.Dialer{Timeout: cfg.Timeouts.Connect, KeepAlive: 30 * time.Second}).DialContext
+	}
+
 	esCfg := elasticsearch.Config{
-		Addresses: cfg.GetElasticsearchAddresses(),
-		Username:  cfg.Username,
-		Password:  cfg.Password,
-		MaxRetries: cfg.MaxRetries,
-		RetryBackoff: func(i int) time.Duration {
-			return time.Duration(i) * cfg.RetryBackoff
-		},
+		Addresses:            cfg.GetElasticsearchAddresses(),
+		Username:             cfg.Username,
+		Password:             cfg.Password,
+		MaxRetries:           cfg.MaxRetries,
+		RetryOnStatus:        retryStatuses,
+		RetryBackoff:         retryBackoff(cfg.RetryBackoff, cfg.MaxRetryBackoff),
+		DiscoverNodesOnStart: cfg.Sniff,
+		Transport:            transport,
+		// A client span per API call, named after the endpoint (search, mget, ...)
+		Instrumentation: elasticsearch.NewOpenTelemetryInstrumentation(otel.GetTracerProvider(), false),
+	}
+	if cfg.Sniff {
+		esCfg.DiscoverNodesInterval = cfg.SniffInterval
+	}
+	if password != nil && cfg.Username != "" {
+		esCfg.Username, esCfg.Password = "", ""
+		esCfg.Transport = &basicAuthTransport{base: transport, username: cfg.Username, password: password}
 	}
 
 	es, err := elasticsearch.NewClient(esCfg)
@@ -88,26 +172,72 @@ func NewClient(cfg config.ElasticsearchConfig) (*Client, error) {
 		return nil, fmt.Errorf("failed to create elasticsearch client: %w", err)
 	}
 
+	client := &Client{
+		es:     es,
+		config: cfg,
+	}
+
 	// Ping to verify connection
-	res, err := es.Ping()
-	if err != nil {
+	if err := client.Ping(); err != nil {
 		return nil, fmt.Errorf("failed to ping elasticsearch: %w", err)
 	}
-	defer res.Body.Close()
 
-	if res.IsError() {
-		return nil, fmt.Errorf("elasticsearch ping failed: %s", res.Status())
+	return client, nil
+}
+
+// basicAuthTransport authenticates each request with the current password,
+// so a rotated one applies without rebuilding the client
+type basicAuthTransport struct {
+	base     http.RoundTripper
+	username string
+	password func() string
+}
+
+func (t *basicAuthTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.SetBasicAuth(t.username, t.password())
+	return t.base.RoundTrip(req)
+}
+
+// retryBackoff doubles the delay from base per attempt up to maxDelay, with
+// full jitter so retries from many requests don't arrive together
+func retryBackoff(base, maxDelay time.Duration) func(int) time.Duration {
+	return func(attempt int) time.Duration {
+		if base <= 0 {
+			return 0
+		}
+		delay := base
+		for i := 1; i < attempt && (maxDelay <= 0 || delay < maxDelay); i++ {
+			delay *= 2
+		}
+		if maxDelay > 0 && delay > maxDelay {
+			delay = maxDelay
+		}
+		return rand.N(delay)
 	}
+}
 
-	return &Client{
-		es:     es,
-		config: cfg,
-	}, nil
+// observe records the latency and outcome of a call that started at start
+func (c *Client) observe(ctx context.Context, operation string, start time.Time, err *error) {
+	if c.metrics != nil {
+		c.metrics.DependencyCall(ctx, "elasticsearch", operation, time.Since(start), *err)
+	}
+}
+
+// withTimeout bounds a call by d, leaving ctx as is when d is unset
+func withTimeout(ctx context.Context, d time.Duration) (context.Context, context.CancelFunc) {
+	if d <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, d)
 }
 
 // Ping checks if Elasticsearch is reachable
 func (c *Client) Ping() error {
-	res, err := c.es.Ping()
+	ctx, cancel := withTimeout(context.Background(), c.config.Timeouts.Health)
+	defer cancel()
+
+	res, err := c.es.Ping(c.es.Ping.WithContext(ctx))
 	if err != nil {
 		return err
 	}
@@ -121,16 +251,28 @@ func (c *Client) Ping() error {
 
 // Index indexes a service document
 func (c *Client) Index(ctx context.Context, doc *ServiceDocument) error {
-	data, err := json.Marshal(doc)
+	if err := doc.Validate(); err != nil {
+		return err
+	}
+	if err := c.assignTenant(ctx, doc); err != nil {
+		return err
+	}
+	c.checkPolicies(ctx, doc)
+
+	data, err := json.Marshal(doc.indexed())
 	if err != nil {
 		return fmt.Errorf("failed to marshal document: %w", err)
 	}
 
+	ctx, cancel := withTimeout(ctx, c.config.Timeouts.Write)
+	defer cancel()
+
 	req := esapi.IndexRequest{
 		Index:      c.config.IndexName,
 		DocumentID: doc.ID,
 		Body:       bytes.NewReader(data),
 		Refresh:    "true",
+		Routing:    doc.TenantID,
 	}
 
 	res, err := req.Do(ctx, c.es)
@@ -147,57 +289,63 @@ func (c *Client) Index(ctx context.Context, doc *ServiceDocument) error {
 	return nil
 }
 
-// BulkIndex indexes multiple documents at once
-func (c *Client) BulkIndex(ctx context.Context, docs []*ServiceDocument) error {
-	if len(docs) == 0 {
-		return nil
+// UpdateFields applies a partial update to a document
+func (c *Client) UpdateFields(ctx context.Context, id string, fields map[string]interface{}) error {
+	data, err := json.Marshal(map[string]interface{}{"doc": fields})
+	if err != nil {
+		return fmt.Errorf("failed to marshal update: %w", err)
 	}
 
-	var buf bytes.Buffer
-	for _, doc := range docs {
-		meta := map[string]interface{}{
-			"index": map[string]interface{}{
-				"_index": c.config.IndexName,
-				"_id":    doc.ID,
-			},
-		}
-
-		metaJSON, _ := json.Marshal(meta)
-		buf.Write(metaJSON)
-		buf.WriteByte('\n')
+	ctx, cancel := withTimeout(ctx, c.config.Timeouts.Write)
+	defer cancel()
 
-		docJSON, _ := json.Marshal(doc)
-		buf.Write(docJSON)
-		buf.WriteByte('\n')
+	req := esapi.UpdateRequest{
+		Index:      c.config.IndexName,
+		DocumentID: id,
+		Body:       bytes.NewReader(data),
+		Routing:    c.docRouting(ctx),
 	}
 
-	res, err := c.es.Bulk(bytes.NewReader(buf.Bytes()))
+	res, err := req.Do(ctx, c.es)
 	if err != nil {
-		return fmt.Errorf("bulk indexing failed: %w", err)
+		return fmt.Errorf("failed to update document: %w", err)
 	}
 	defer res.Body.Close()
 
 	if res.IsError() {
+		if res.StatusCode == 404 {
+			return ErrNotFound
+		}
 		body, _ := io.ReadAll(res.Body)
-		return fmt.Errorf("bulk indexing error: %s - %s", res.Status(), string(body))
+		return fmt.Errorf("update failed: %s - %s", res.Status(), string(body))
 	}
 
 	return nil
 }
 
 // Search performs a search with the given query
-func (c *Client) Search(ctx context.Context, query map[string]interface{}) (*SearchResponse, error) {
+func (c *Client) Search(ctx context.Context, query map[string]interface{}) (_ *SearchResponse, err error) {
+	defer c.observe(ctx, "search", time.Now(), &err)
+
 	var buf bytes.Buffer
-	if err := json.NewEncoder(&buf).Encode(query); err != nil {
+	if err := json.NewEncoder(&buf).Encode(c.scopeQuery(ctx, query)); err != nil {
 		return nil, fmt.Errorf("failed to encode query: %w", err)
 	}
 
-	res, err := c.es.Search(
+	ctx, cancel := withTimeout(ctx, c.config.Timeouts.Read)
+	defer cancel()
+
+	opts := []func(*esapi.SearchRequest){
 		c.es.Search.WithContext(ctx),
 		c.es.Search.WithIndex(c.config.IndexName),
 		c.es.Search.WithBody(&buf),
 		c.es.Search.WithTrackTotalHits(true),
-	)
+	}
+	if routing := c.searchRouting(ctx); routing != "" {
+		opts = append(opts, c.es.Search.WithRouting(routing))
+	}
+
+	res, err := c.es.Search(opts...)
 	if err != nil {
 		return nil, fmt.Errorf("search failed: %w", err)
 	}
@@ -218,7 +366,45 @@ func (c *Client) Search(ctx context.Context, query map[string]interface{}) (*Sea
 
 // Get retrieves a document by ID
 func (c *Client) Get(ctx context.Context, id string) (*ServiceDocument, error) {
-	res, err := c.es.Get(c.config.IndexName, id)
+	return c.GetSource(ctx, id, nil, nil)
+}
+
+// GetSource retrieves a document by ID with _source filtering applied.
+// Documents outside the tenant catalog ctx is scoped to are reported as not found.
+func (c *Client) GetSource(ctx context.Context, id string, includes, excludes []string) (*ServiceDocument, error) {
+	if len(includes) > 0 && c.tenancyEnabled() {
+		includes = append(includes[:len(includes):len(includes)], "tenant_id")
+	}
+
+	doc, err := c.getSource(ctx, id, c.docRouting(ctx), includes, excludes)
+	if errors.Is(err, ErrNotFound) && c.docRouting(ctx) != "" && c.config.Tenancy.SharedCatalog {
+		// Shared catalog documents are stored without tenant routing
+		doc, err = c.getSource(ctx, id, "", includes, excludes)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if !c.InTenant(ctx, doc) {
+		return nil, ErrNotFound
+	}
+	return doc, nil
+}
+
+func (c *Client) getSource(ctx context.Context, id, routing string, includes, excludes []string) (*ServiceDocument, error) {
+	ctx, cancel := withTimeout(ctx, c.config.Timeouts.Read)
+	defer cancel()
+
+	opts := []func(*esapi.GetRequest){
+		c.es.Get.WithContext(ctx),
+		c.es.Get.WithSourceIncludes(includes...),
+		c.es.Get.WithSourceExcludes(excludes...),
+	}
+	if routing != "" {
+		opts = append(opts, c.es.Get.WithRouting(routing))
+	}
+
+	res, err := c.es.Get(c.config.IndexName, id, opts...)
 	if err != nil {
 		return nil, fmt.Errorf("get failed: %w", err)
 	}
@@ -226,7 +412,7 @@ func (c *Client) Get(ctx context.Context, id string) (*ServiceDocument, error) {
 
 	if res.IsError() {
 		if res.StatusCode == 404 {
-			return nil, fmt.Errorf("document not found")
+			return nil, ErrNotFound
 		}
 		body, _ := io.ReadAll(res.Body)
 		return nil, fmt.Errorf("get error: %s - %s", res.Status(), string(body))
@@ -243,12 +429,82 @@ func (c *Client) Get(ctx context.Context, id string) (*ServiceDocument, error) {
 	return &result.Source, nil
 }
 
+// MGet retrieves multiple documents by ID without embeddings, omitting IDs that
+// do not exist or are outside the tenant catalog ctx is scoped to
+func (c *Client) MGet(ctx context.Context, ids []string) (map[string]*ServiceDocument, error) {
+	docs := make(map[string]*ServiceDocument, len(ids))
+	if len(ids) == 0 {
+		return docs, nil
+	}
+
+	request := map[string]interface{}{"ids": ids}
+	if routing := c.docRouting(ctx); routing != "" {
+		refs := make([]map[string]interface{}, 0, 2*len(ids))
+		for _, id := range ids {
+			refs = append(refs, map[string]interface{}{"_id": id, "routing": routing})
+			if c.config.Tenancy.SharedCatalog {
+				// Shared catalog documents are stored without tenant routing
+				refs = append(refs, map[string]interface{}{"_id": id})
+			}
+		}
+		request = map[string]interface{}{"docs": refs}
+	}
+
+	body, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal ids: %w", err)
+	}
+
+	ctx, cancel := withTimeout(ctx, c.config.Timeouts.Read)
+	defer cancel()
+
+	res, err := c.es.Mget(
+		bytes.NewReader(body),
+		c.es.Mget.WithContext(ctx),
+		c.es.Mget.WithIndex(c.config.IndexName),
+		c.es.Mget.WithSourceExcludes(DefaultSourceExcludes...),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("mget failed: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		body, _ := io.ReadAll(res.Body)
+		return nil, fmt.Errorf("mget error: %s - %s", res.Status(), string(body))
+	}
+
+	var result struct {
+		Docs []struct {
+			ID     string          `json:"_id"`
+			Found  bool            `json:"found"`
+			Source ServiceDocument `json:"_source"`
+		} `json:"docs"`
+	}
+
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode documents: %w", err)
+	}
+
+	for i := range result.Docs {
+		if result.Docs[i].Found && c.InTenant(ctx, &result.Docs[i].Source) {
+			docs[result.Docs[i].ID] = &result.Docs[i].Source
+		}
+	}
+
+	return docs, nil
+}
+
 // Delete removes a document by ID
 func (c *Client) Delete(ctx context.Context, id string) error {
+	ctx, cancel := withTimeout(ctx, c.config.Timeouts.Write)
+	defer cancel()
+
 	req := esapi.DeleteRequest{
 		Index:      c.config.IndexName,
 		DocumentID: id,
 		Refresh:    "true",
+		Routing:    c.docRouting(ctx),
 	}
 
 	res, err := req.Do(ctx, c.es)
@@ -277,7 +533,8 @@ type SearchResponse struct {
 		MaxScore float64 `json:"max_score"`
 		Hits     []Hit   `json:"hits"`
 	} `json:"hits"`
-	Aggregations map[string]interface{} `json:"aggregations,omitempty"`
+	Aggregations map[string]json.RawMessage `json:"aggregations,omitempty"` // Decoded with Aggregation or DecodeAggregations
+	PitID        string                     `json:"pit_id,omitempty"`       // Point in time to continue from, for point in time searches
 }
 
 // Hit represents a search result hit
@@ -286,4 +543,5 @@ type Hit struct {
 	ID     string          `json:"_id"`
 	Score  float64         `json:"_score"`
 	Source ServiceDocument `json:"_source"`
+	Sort   []interface{}   `json:"sort,omitempty"` // Present when the query is sorted, used for search_after
 }
@@ -0,0 +1,50 @@
+package elasticsearch
+
+import (
+	"context"
+
+	"github.com/org/llm-marketplace/pkg/marketplace"
+)
+
+// Fields of datasets, listed alongside services
+const (
+	datasetField = "dataset"
+	// LicenseField holds the SPDX identifier of a dataset's license
+	LicenseField = datasetField + ".license"
+	// DatasetFormatField holds a dataset's format, e.g. parquet
+	DatasetFormatField = datasetField + ".format"
+	// DatasetSizeField holds a dataset's size in bytes
+	DatasetSizeField = datasetField + ".size_bytes"
+)
+
+// DatasetInfo is the license, size and provenance of a dataset
+type DatasetInfo = marketplace.DatasetInfo
+
+// PutDatasetFields adds the dataset fields to the services index. Documents
+// are given them as they are next written.
+func (im *IndexManager) PutDatasetFields(ctx context.Context) error {
+	return im.putFields(ctx, "dataset", map[string]interface{}{
+		datasetField: datasetMapping(),
+	})
+}
+
+// datasetMapping maps the dataset section of a listing. The license is
+// matched as written, so filters name SPDX identifiers exactly.
+func datasetMapping() map[string]interface{} {
+	return map[string]interface{}{
+		"properties": map[string]interface{}{
+			"license":     map[string]interface{}{"type": "keyword"},
+			"attribution": map[string]interface{}{"type": "text", "index": false},
+			"format":      map[string]interface{}{"type": "keyword"},
+			"size_bytes":  map[string]interface{}{"type": "long"},
+			"records":     map[string]interface{}{"type": "long"},
+			"provenance": map[string]interface{}{
+				"properties": map[string]interface{}{
+					"source": map[string]interface{}{"type": "keyword"},
+					"method": map[string]interface{}{"type": "keyword"},
+					"url":    map[string]interface{}{"type": "keyword", "index": false},
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,70 @@
+package elasticsearch
+
+import (
+	"context"
+	"slices"
+)
+
+// dependenciesField holds the services a service is built on
+const dependenciesField = "dependencies"
+
+// maxDependents bounds the dependents returned for one service
+const maxDependents = 200
+
+// Dependents returns the services in the catalog ctx is scoped to that
+// declare a dependency on the service id, without embeddings
+func (c *Client) Dependents(ctx context.Context, id string) ([]*ServiceDocument, error) {
+	query := map[string]interface{}{
+		"size": maxDependents,
+		"query": map[string]interface{}{
+			"term": map[string]interface{}{dependenciesField + ".service_id": id},
+		},
+		"sort": []interface{}{
+			map[string]interface{}{"name.keyword": map[string]interface{}{"order": "asc"}},
+		},
+		"_source": map[string]interface{}{
+			"excludes": DefaultSourceExcludes,
+		},
+	}
+
+	resp, err := c.Search(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	dependents := make([]*ServiceDocument, 0, len(resp.Hits.Hits))
+	for i := range resp.Hits.Hits {
+		doc := &resp.Hits.Hits[i].Source
+		if doc.ID == "" {
+			doc.ID = resp.Hits.Hits[i].ID
+		}
+		if !c.InTenant(ctx, doc) || !doc.DependsOn(id) {
+			continue
+		}
+		dependents = append(dependents, doc)
+	}
+	return dependents, nil
+}
+
+// DependsOn reports whether the service declares a dependency on id
+func (d *ServiceDocument) DependsOn(id string) bool {
+	return slices.ContainsFunc(d.Dependencies, func(dep Dependency) bool { return dep.ServiceID == id })
+}
+
+// PutDependenciesField adds the dependencies to the services index. Documents
+// are given them as they are next written.
+func (im *IndexManager) PutDependenciesField(ctx context.Context) error {
+	return im.putFields(ctx, "dependencies", map[string]interface{}{
+		dependenciesField: dependenciesMapping(),
+	})
+}
+
+// dependenciesMapping maps the dependencies of a service
+func dependenciesMapping() map[string]interface{} {
+	return map[string]interface{}{
+		"properties": map[string]interface{}{
+			"service_id": map[string]interface{}{"type": "keyword"},
+			"role":       map[string]interface{}{"type": "keyword"},
+		},
+	}
+}
@@ -0,0 +1,211 @@
+package elasticsearch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+)
+
+// Entity types that can be searched
+const (
+	EntityService        = "service"
+	EntityDataset        = "dataset"
+	EntityPromptTemplate = "prompt_template"
+	EntityProvider       = "provider"
+)
+
+// EntityTypes lists every searchable entity type
+var EntityTypes = []string{
+	EntityService,
+	EntityDataset,
+	EntityPromptTemplate,
+	EntityProvider,
+}
+
+// IsEntityType reports whether name is a known entity type
+func IsEntityType(name string) bool {
+	for _, t := range EntityTypes {
+		if t == name {
+			return true
+		}
+	}
+	return false
+}
+
+// DatasetDocument represents a dataset listed in the marketplace
+type DatasetDocument struct {
+	ID          string                 `json:"id"`
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	Tags        []string               `json:"tags"`
+	Provider    ProviderInfo           `json:"provider"`
+	License     string                 `json:"license"`
+	Modalities  []string               `json:"modalities"`
+	Formats     []string               `json:"formats"`
+	RecordCount int64                  `json:"record_count"`
+	SizeBytes   int64                  `json:"size_bytes"`
+	CreatedAt   time.Time              `json:"created_at"`
+	UpdatedAt   time.Time              `json:"updated_at"`
+	Metadata    map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// PromptTemplateDocument represents a reusable prompt template
+type PromptTemplateDocument struct {
+	ID          string                 `json:"id"`
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	Tags        []string               `json:"tags"`
+	Category    string                 `json:"category"`
+	Provider    ProviderInfo           `json:"provider"`
+	Template    string                 `json:"template"`
+	Variables   []string               `json:"variables"`
+	ServiceIDs  []string               `json:"service_ids"` // Services the template is written for
+	CreatedAt   time.Time              `json:"created_at"`
+	UpdatedAt   time.Time              `json:"updated_at"`
+	Metadata    map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// ProviderDocument represents a provider profile
+type ProviderDocument struct {
+	ID           string                 `json:"id"`
+	Name         string                 `json:"name"`
+	Description  string                 `json:"description"`
+	Tags         []string               `json:"tags"`
+	Verified     bool                   `json:"verified"`
+	Website      string                 `json:"website,omitempty"`
+	Regions      []string               `json:"regions"`
+	ServiceCount int                    `json:"service_count"`
+	CreatedAt    time.Time              `json:"created_at"`
+	UpdatedAt    time.Time              `json:"updated_at"`
+	Metadata     map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// EntityIndex returns the index holding entityType
+func (c *Client) EntityIndex(entityType string) (string, error) {
+	if entityType == EntityService {
+		return c.config.IndexName, nil
+	}
+	if index, ok := c.config.EntityIndices[entityType]; ok && index != "" {
+		return index, nil
+	}
+	return "", fmt.Errorf("no index configured for entity type %s", entityType)
+}
+
+// IndexEntity indexes a non-service entity document
+func (c *Client) IndexEntity(ctx context.Context, entityType, id string, doc interface{}) error {
+	index, err := c.EntityIndex(entityType)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return fmt.Errorf("failed to marshal document: %w", err)
+	}
+
+	ctx, cancel := withTimeout(ctx, c.config.Timeouts.Write)
+	defer cancel()
+
+	req := esapi.IndexRequest{
+		Index:      index,
+		DocumentID: id,
+		Body:       bytes.NewReader(data),
+		Refresh:    "true",
+	}
+
+	res, err := req.Do(ctx, c.es)
+	if err != nil {
+		return fmt.Errorf("failed to index document: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		body, _ := io.ReadAll(res.Body)
+		return fmt.Errorf("indexing failed: %s - %s", res.Status(), string(body))
+	}
+
+	return nil
+}
+
+// MultiSearchRequest is one search in a MultiSearch call
+type MultiSearchRequest struct {
+	Index string
+	Query map[string]interface{}
+}
+
+// RawSearchResponse is a search response whose hit sources are left undecoded
+type RawSearchResponse struct {
+	Took int `json:"took"`
+	Hits struct {
+		Total struct {
+			Value int `json:"value"`
+		} `json:"total"`
+		MaxScore float64  `json:"max_score"`
+		Hits     []RawHit `json:"hits"`
+	} `json:"hits"`
+	Error json.RawMessage `json:"error,omitempty"`
+}
+
+// RawHit is a search hit from any entity index
+type RawHit struct {
+	Index  string          `json:"_index"`
+	ID     string          `json:"_id"`
+	Score  float64         `json:"_score"`
+	Source json.RawMessage `json:"_source"`
+}
+
+// MultiSearch runs several searches in one round trip; responses are in request order
+func (c *Client) MultiSearch(ctx context.Context, requests []MultiSearchRequest) (_ []*RawSearchResponse, err error) {
+	if len(requests) == 0 {
+		return nil, nil
+	}
+	defer c.observe(ctx, "msearch", time.Now(), &err)
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, r := range requests {
+		if err := enc.Encode(map[string]interface{}{"index": r.Index}); err != nil {
+			return nil, fmt.Errorf("failed to encode header: %w", err)
+		}
+		if err := enc.Encode(r.Query); err != nil {
+			return nil, fmt.Errorf("failed to encode query: %w", err)
+		}
+	}
+
+	ctx, cancel := withTimeout(ctx, c.config.Timeouts.Read)
+	defer cancel()
+
+	res, err := c.es.Msearch(
+		&buf,
+		c.es.Msearch.WithContext(ctx),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("multi-search failed: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		body, _ := io.ReadAll(res.Body)
+		return nil, fmt.Errorf("multi-search error: %s - %s", res.Status(), string(body))
+	}
+
+	var body struct {
+		Responses []*RawSearchResponse `json:"responses"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	for i, r := range body.Responses {
+		if len(r.Error) > 0 {
+			return nil, fmt.Errorf("multi-search error in %s: %s", requests[i].Index, string(r.Error))
+		}
+	}
+
+	return body.Responses, nil
+}
@@ -0,0 +1,27 @@
+package elasticsearch
+
+import "context"
+
+// complianceExpiriesField holds the certifications and data-processing
+// agreement of a service that are expiring or expired
+const complianceExpiriesField = "compliance_expiries"
+
+// PutComplianceExpiriesField adds the compliance expiries to the services
+// index. Documents are given theirs as the registry next publishes them.
+func (im *IndexManager) PutComplianceExpiriesField(ctx context.Context) error {
+	return im.putFields(ctx, "compliance expiries", map[string]interface{}{
+		complianceExpiriesField: complianceExpiriesMapping(),
+	})
+}
+
+// complianceExpiriesMapping maps the compliance expiries of a service
+func complianceExpiriesMapping() map[string]interface{} {
+	return map[string]interface{}{
+		"properties": map[string]interface{}{
+			"kind":       map[string]interface{}{"type": "keyword"},
+			"name":       map[string]interface{}{"type": "keyword"},
+			"status":     map[string]interface{}{"type": "keyword"},
+			"expires_at": map[string]interface{}{"type": "date"},
+		},
+	}
+}
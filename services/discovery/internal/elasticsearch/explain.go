@@ -0,0 +1,75 @@
+package elasticsearch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+)
+
+// Explanation is Elasticsearch's account of how a score was computed: the
+// value, what it is, and the values it was computed from
+type Explanation struct {
+	Value       float64       `json:"value"`
+	Description string        `json:"description"`
+	Details     []Explanation `json:"details,omitempty"`
+}
+
+// Explain reports whether the document with id matches query, scoped as
+// Search scopes it, and how its score is computed
+func (c *Client) Explain(ctx context.Context, id string, query map[string]interface{}) (_ bool, _ *Explanation, err error) {
+	defer c.observe(ctx, "explain", time.Now(), &err)
+
+	data, err := json.Marshal(c.scopeQuery(ctx, map[string]interface{}{"query": query}))
+	if err != nil {
+		return false, nil, fmt.Errorf("failed to encode query: %w", err)
+	}
+
+	matched, explanation, err := c.explain(ctx, id, c.docRouting(ctx), data)
+	if errors.Is(err, ErrNotFound) && c.docRouting(ctx) != "" && c.config.Tenancy.SharedCatalog {
+		// Shared catalog documents are stored without tenant routing
+		matched, explanation, err = c.explain(ctx, id, "", data)
+	}
+	return matched, explanation, err
+}
+
+func (c *Client) explain(ctx context.Context, id, routing string, body []byte) (bool, *Explanation, error) {
+	ctx, cancel := withTimeout(ctx, c.config.Timeouts.Read)
+	defer cancel()
+
+	opts := []func(*esapi.ExplainRequest){
+		c.es.Explain.WithContext(ctx),
+		c.es.Explain.WithBody(bytes.NewReader(body)),
+	}
+	if routing != "" {
+		opts = append(opts, c.es.Explain.WithRouting(routing))
+	}
+
+	res, err := c.es.Explain(c.config.IndexName, id, opts...)
+	if err != nil {
+		return false, nil, fmt.Errorf("explain failed: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		if res.StatusCode == 404 {
+			return false, nil, ErrNotFound
+		}
+		body, _ := io.ReadAll(res.Body)
+		return false, nil, fmt.Errorf("explain error: %s - %s", res.Status(), string(body))
+	}
+
+	var resp struct {
+		Matched     bool         `json:"matched"`
+		Explanation *Explanation `json:"explanation"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&resp); err != nil {
+		return false, nil, fmt.Errorf("failed to decode explanation: %w", err)
+	}
+	return resp.Matched, resp.Explanation, nil
+}
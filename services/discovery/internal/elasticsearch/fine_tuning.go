@@ -0,0 +1,48 @@
+package elasticsearch
+
+import (
+	"context"
+
+	"github.com/org/llm-marketplace/pkg/marketplace"
+)
+
+// Fields of fine-tuning offerings, listed alongside services
+const (
+	fineTuningField = "fine_tuning"
+	// BaseModelsField holds the lower-case base models a fine-tuning
+	// offering tunes
+	BaseModelsField = fineTuningField + ".base_models"
+	// TurnaroundField holds the hours within which an offering's jobs
+	// finish
+	TurnaroundField = fineTuningField + ".turnaround_hours"
+)
+
+// FineTuningOffering is the base models, data requirements and turnaround
+// of a fine-tuning offering
+type FineTuningOffering = marketplace.FineTuningOffering
+
+// PutFineTuningFields adds the fine-tuning fields to the services index.
+// Documents are given them as they are next written.
+func (im *IndexManager) PutFineTuningFields(ctx context.Context) error {
+	return im.putFields(ctx, "fine-tuning", map[string]interface{}{
+		fineTuningField: fineTuningMapping(),
+	})
+}
+
+// fineTuningMapping maps the fine-tuning section of a listing
+func fineTuningMapping() map[string]interface{} {
+	return map[string]interface{}{
+		"properties": map[string]interface{}{
+			"base_models": map[string]interface{}{"type": "keyword"},
+			"data_requirements": map[string]interface{}{
+				"properties": map[string]interface{}{
+					"formats":        map[string]interface{}{"type": "keyword"},
+					"min_examples":   map[string]interface{}{"type": "long"},
+					"max_examples":   map[string]interface{}{"type": "long"},
+					"max_size_bytes": map[string]interface{}{"type": "long"},
+				},
+			},
+			"turnaround_hours": map[string]interface{}{"type": "integer"},
+		},
+	}
+}
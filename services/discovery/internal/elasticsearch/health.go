@@ -0,0 +1,155 @@
+package elasticsearch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/org/llm-marketplace/services/discovery/internal/config"
+	"go.uber.org/zap"
+)
+
+// Health is the latest result of probing the cluster
+type Health struct {
+	Healthy             bool          `json:"healthy"`
+	Status              string        `json:"status,omitempty"` // green, yellow or red
+	Nodes               int           `json:"nodes,omitempty"`
+	ConsecutiveFailures int           `json:"consecutive_failures"`
+	Error               string        `json:"error,omitempty"`
+	Latency             time.Duration `json:"-"` // Of the last probe
+	CheckedAt           time.Time     `json:"checked_at"`
+}
+
+// HealthProber polls cluster health in the background so readiness checks
+// answer from the last probe instead of calling Elasticsearch per request.
+// A red cluster or a failed probe counts as a failure; the cluster is reported
+// unhealthy once failures reach the configured threshold.
+type HealthProber struct {
+	client *Client
+	config config.ESHealthConfig
+	logger *zap.Logger
+
+	mu     sync.RWMutex
+	health Health
+}
+
+// NewHealthProber creates a prober. The cluster starts out healthy, since
+// NewClient has already reached it.
+func NewHealthProber(client *Client, cfg config.ESHealthConfig, logger *zap.Logger) *HealthProber {
+	return &HealthProber{
+		client: client,
+		config: cfg,
+		logger: logger,
+		health: Health{Healthy: true, CheckedAt: time.Now().UTC()},
+	}
+}
+
+// Health returns the latest probe result
+func (p *HealthProber) Health() Health {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.health
+}
+
+// Start runs the prober until ctx is cancelled
+func (p *HealthProber) Start(ctx context.Context) {
+	interval := p.config.Interval
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+
+	p.logger.Info("Starting Elasticsearch health prober", zap.Duration("interval", interval))
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	p.probe(ctx)
+
+	for {
+		select {
+		case <-ticker.C:
+			p.probe(ctx)
+		case <-ctx.Done():
+			p.logger.Info("Elasticsearch health prober stopped")
+			return
+		}
+	}
+}
+
+// Check reports the last probe for readiness: its latency, and an error once
+// the cluster is unhealthy
+func (p *HealthProber) Check(ctx context.Context) (time.Duration, error) {
+	h := p.Health()
+	if !h.Healthy {
+		return h.Latency, fmt.Errorf("%d consecutive failed probes: %s", h.ConsecutiveFailures, h.Error)
+	}
+	return h.Latency, nil
+}
+
+func (p *HealthProber) probe(ctx context.Context) {
+	start := time.Now()
+	status, nodes, err := p.client.clusterHealth(ctx)
+	latency := time.Since(start)
+	if err == nil && status == "red" {
+		err = fmt.Errorf("cluster status is red")
+	}
+
+	threshold := p.config.FailureThreshold
+	if threshold < 1 {
+		threshold = 1
+	}
+
+	p.mu.Lock()
+	prev := p.health
+	next := Health{Status: status, Nodes: nodes, Latency: latency, CheckedAt: time.Now().UTC()}
+	if err != nil {
+		next.ConsecutiveFailures = prev.ConsecutiveFailures + 1
+		next.Error = err.Error()
+	}
+	next.Healthy = next.ConsecutiveFailures < threshold
+	p.health = next
+	p.mu.Unlock()
+
+	if p.client.metrics != nil {
+		p.client.metrics.ElasticsearchHealthy(next.Healthy)
+	}
+
+	switch {
+	case prev.Healthy && !next.Healthy:
+		p.logger.Error("Elasticsearch is unhealthy",
+			zap.Int("consecutive_failures", next.ConsecutiveFailures),
+			zap.Error(err),
+		)
+	case !prev.Healthy && next.Healthy:
+		p.logger.Info("Elasticsearch is healthy again", zap.String("status", status))
+	case err != nil:
+		p.logger.Warn("Elasticsearch health probe failed", zap.Error(err))
+	}
+}
+
+// clusterHealth returns the cluster status and node count
+func (c *Client) clusterHealth(ctx context.Context) (string, int, error) {
+	ctx, cancel := withTimeout(ctx, c.config.Timeouts.Health)
+	defer cancel()
+
+	res, err := c.es.Cluster.Health(c.es.Cluster.Health.WithContext(ctx))
+	if err != nil {
+		return "", 0, fmt.Errorf("health request failed: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return "", 0, fmt.Errorf("health request failed: %s", res.Status())
+	}
+
+	var body struct {
+		Status        string `json:"status"`
+		NumberOfNodes int    `json:"number_of_nodes"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		return "", 0, fmt.Errorf("failed to decode cluster health: %w", err)
+	}
+	return body.Status, body.NumberOfNodes, nil
+}
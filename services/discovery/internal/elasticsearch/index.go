@@ -30,8 +30,31 @@ func NewIndexManager(client *Client, cfg config.ElasticsearchConfig, logger *zap
 
 // CreateIndex creates the services index with proper mappings
 func (im *IndexManager) CreateIndex(ctx context.Context) error {
+	return im.createIndex(ctx, im.config.IndexName, im.buildIndexMappings())
+}
+
+// CreateEntityIndices creates the index for every configured non-service entity type
+func (im *IndexManager) CreateEntityIndices(ctx context.Context) error {
+	for _, entityType := range EntityTypes {
+		if entityType == EntityService {
+			continue
+		}
+		index, err := im.client.EntityIndex(entityType)
+		if err != nil {
+			im.logger.Warn("Skipping entity type without an index", zap.String("type", entityType))
+			continue
+		}
+		if err := im.createIndex(ctx, index, im.buildEntityMappings(entityType)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// createIndex creates name with body unless it already exists
+func (im *IndexManager) createIndex(ctx context.Context, name string, body map[string]interface{}) error {
 	// Check if index exists
-	res, err := im.es.Indices.Exists([]string{im.config.IndexName})
+	res, err := im.es.Indices.Exists([]string{name}, im.es.Indices.Exists.WithContext(ctx))
 	if err != nil {
 		return fmt.Errorf("failed to check index existence: %w", err)
 	}
@@ -39,20 +62,17 @@ func (im *IndexManager) CreateIndex(ctx context.Context) error {
 
 	// Index already exists
 	if res.StatusCode == 200 {
-		im.logger.Info("Index already exists", zap.String("index", im.config.IndexName))
+		im.logger.Info("Index already exists", zap.String("index", name))
 		return nil
 	}
 
-	// Create index with mappings
-	mappings := im.buildIndexMappings()
-
 	var buf bytes.Buffer
-	if err := json.NewEncoder(&buf).Encode(mappings); err != nil {
+	if err := json.NewEncoder(&buf).Encode(body); err != nil {
 		return fmt.Errorf("failed to encode mappings: %w", err)
 	}
 
 	res, err = im.es.Indices.Create(
-		im.config.IndexName,
+		name,
 		im.es.Indices.Create.WithBody(&buf),
 		im.es.Indices.Create.WithContext(ctx),
 	)
@@ -66,67 +86,14 @@ func (im *IndexManager) CreateIndex(ctx context.Context) error {
 		return fmt.Errorf("index creation failed: %s - %s", res.Status(), string(body))
 	}
 
-	im.logger.Info("Index created successfully", zap.String("index", im.config.IndexName))
+	im.logger.Info("Index created successfully", zap.String("index", name))
 	return nil
 }
 
 // buildIndexMappings returns the Elasticsearch index mappings
 func (im *IndexManager) buildIndexMappings() map[string]interface{} {
 	return map[string]interface{}{
-		"settings": map[string]interface{}{
-			"number_of_shards":   im.config.Shards,
-			"number_of_replicas": im.config.Replicas,
-			"refresh_interval":   im.config.RefreshInterval,
-			"analysis": map[string]interface{}{
-				"analyzer": map[string]interface{}{
-					"service_analyzer": map[string]interface{}{
-						"type":      "custom",
-						"tokenizer": "standard",
-						"filter": []string{
-							"lowercase",
-							"asciifolding",
-							"service_synonym",
-							"english_stemmer",
-						},
-					},
-					"autocomplete": map[string]interface{}{
-						"type":      "custom",
-						"tokenizer": "autocomplete_tokenizer",
-						"filter": []string{
-							"lowercase",
-							"asciifolding",
-						},
-					},
-				},
-				"tokenizer": map[string]interface{}{
-					"autocomplete_tokenizer": map[string]interface{}{
-						"type":     "edge_ngram",
-						"min_gram": 2,
-						"max_gram": 20,
-						"token_chars": []string{
-							"letter",
-							"digit",
-						},
-					},
-				},
-				"filter": map[string]interface{}{
-					"english_stemmer": map[string]interface{}{
-						"type":     "stemmer",
-						"language": "english",
-					},
-					"service_synonym": map[string]interface{}{
-						"type": "synonym",
-						"synonyms": []string{
-							"llm, large language model, language model",
-							"ml, machine learning",
-							"ai, artificial intelligence",
-							"nlp, natural language processing",
-							"gpt, generative pretrained transformer",
-						},
-					},
-				},
-			},
-		},
+		"settings": im.indexSettings(),
 		"mappings": map[string]interface{}{
 			"properties": map[string]interface{}{
 				"id": map[string]interface{}{
@@ -177,6 +144,10 @@ func (im *IndexManager) buildIndexMappings() map[string]interface{} {
 				"capabilities": map[string]interface{}{
 					"type": "keyword",
 				},
+				"endpoint": map[string]interface{}{
+					"type":  "keyword",
+					"index": false,
+				},
 				"pricing": map[string]interface{}{
 					"properties": map[string]interface{}{
 						"model": map[string]interface{}{
@@ -188,6 +159,29 @@ func (im *IndexManager) buildIndexMappings() map[string]interface{} {
 						"unit": map[string]interface{}{
 							"type": "keyword",
 						},
+						"currency": map[string]interface{}{
+							"type": "keyword",
+						},
+						"tiers": map[string]interface{}{
+							"properties": map[string]interface{}{
+								"tier": map[string]interface{}{
+									"type": "keyword",
+								},
+								"rate": map[string]interface{}{
+									"type": "float",
+								},
+								"unit": map[string]interface{}{
+									"type": "keyword",
+								},
+								"up_to": map[string]interface{}{
+									"type": "float",
+								},
+								"description": map[string]interface{}{
+									"type":  "text",
+									"index": false,
+								},
+							},
+						},
 					},
 				},
 				"sla": map[string]interface{}{
@@ -214,11 +208,94 @@ func (im *IndexManager) buildIndexMappings() map[string]interface{} {
 						"data_residency": map[string]interface{}{
 							"type": "keyword",
 						},
+						"gdpr_compliant": map[string]interface{}{
+							"type": "boolean",
+						},
+						"hipaa_compliant": map[string]interface{}{
+							"type": "boolean",
+						},
 					},
 				},
 				"status": map[string]interface{}{
 					"type": "keyword",
 				},
+				"upheld_reports": map[string]interface{}{
+					"type": "integer",
+				},
+				"changelog": map[string]interface{}{
+					"type":    "object",
+					"enabled": false,
+				},
+				"deprecation": map[string]interface{}{
+					"properties": map[string]interface{}{
+						"message": map[string]interface{}{
+							"type":  "text",
+							"index": false,
+						},
+						"deprecated_at": map[string]interface{}{
+							"type": "date",
+						},
+						"sunset_at": map[string]interface{}{
+							"type": "date",
+						},
+						"replacement_service_id": map[string]interface{}{
+							"type": "keyword",
+						},
+					},
+				},
+				"access": map[string]interface{}{
+					"properties": map[string]interface{}{
+						"visibility": map[string]interface{}{
+							"type": "keyword",
+						},
+						"owner_tenant": map[string]interface{}{
+							"type": "keyword",
+						},
+						"allowed_tenants": map[string]interface{}{
+							"type": "keyword",
+						},
+						"allowed_users": map[string]interface{}{
+							"type": "keyword",
+						},
+					},
+				},
+				"tenant_id": map[string]interface{}{
+					"type": "keyword",
+				},
+				suggestField: suggestMapping(),
+				policyComplianceField: policyComplianceMapping(),
+				dependenciesField: dependenciesMapping(),
+				complianceExpiriesField: complianceExpiriesMapping(),
+				kindField: map[string]interface{}{
+					"type": "keyword",
+				},
+				promptTemplateField: promptTemplateMapping(),
+				datasetField: datasetMapping(),
+				fineTuningField: fineTuningMapping(),
+				servingRegionsField: servingRegionsMapping(),
+				"service_key": map[string]interface{}{
+					"type": "keyword",
+				},
+				"version": map[string]interface{}{
+					"properties": map[string]interface{}{
+						"number": map[string]interface{}{
+							"type": "keyword",
+						},
+						"stable": map[string]interface{}{
+							"type": "boolean",
+						},
+						"latest": map[string]interface{}{
+							"type": "boolean",
+						},
+						"changelog": map[string]interface{}{
+							"type":  "text",
+							"index": false,
+						},
+						"released_at": map[string]interface{}{
+							"type": "date",
+						},
+					},
+				},
 				"metrics": map[string]interface{}{
 					"properties": map[string]interface{}{
 						"total_requests": map[string]interface{}{
@@ -239,6 +316,10 @@ func (im *IndexManager) buildIndexMappings() map[string]interface{} {
 						"popularity_score": map[string]interface{}{
 							"type": "float",
 						},
+						"quality": map[string]interface{}{
+							"type":    "object",
+							"enabled": false,
+						},
 					},
 				},
 				"embedding": map[string]interface{}{
@@ -247,6 +328,9 @@ func (im *IndexManager) buildIndexMappings() map[string]interface{} {
 					"index": true,
 					"similarity": im.config.Similarity,
 				},
+				"embedding_models": map[string]interface{}{
+					"type": "keyword",
+				},
 				"created_at": map[string]interface{}{
 					"type": "date",
 				},
@@ -262,6 +346,216 @@ func (im *IndexManager) buildIndexMappings() map[string]interface{} {
 	}
 }
 
+// buildEntityMappings returns the mappings for a non-service entity index.
+// Every type shares name, description and tags so they can be searched together.
+func (im *IndexManager) buildEntityMappings(entityType string) map[string]interface{} {
+	keyword := map[string]interface{}{"type": "keyword"}
+	date := map[string]interface{}{"type": "date"}
+
+	properties := map[string]interface{}{
+		"id": keyword,
+		"name": map[string]interface{}{
+			"type":     "text",
+			"analyzer": "service_analyzer",
+			"fields": map[string]interface{}{
+				"keyword": keyword,
+				"autocomplete": map[string]interface{}{
+					"type":            "text",
+					"analyzer":        "autocomplete",
+					"search_analyzer": "standard",
+				},
+			},
+		},
+		"description": map[string]interface{}{
+			"type":     "text",
+			"analyzer": "service_analyzer",
+		},
+		"tags":       keyword,
+		"created_at": date,
+		"updated_at": date,
+		"metadata": map[string]interface{}{
+			"type":    "object",
+			"enabled": false,
+		},
+	}
+
+	provider := map[string]interface{}{
+		"properties": map[string]interface{}{
+			"id": keyword,
+			"name": map[string]interface{}{
+				"type":   "text",
+				"fields": map[string]interface{}{"keyword": keyword},
+			},
+			"verified": map[string]interface{}{"type": "boolean"},
+		},
+	}
+
+	switch entityType {
+	case EntityDataset:
+		properties["provider"] = provider
+		properties["license"] = keyword
+		properties["modalities"] = keyword
+		properties["formats"] = keyword
+		properties["record_count"] = map[string]interface{}{"type": "long"}
+		properties["size_bytes"] = map[string]interface{}{"type": "long"}
+	case EntityPromptTemplate:
+		properties["provider"] = provider
+		properties["category"] = keyword
+		properties["template"] = map[string]interface{}{
+			"type":     "text",
+			"analyzer": "service_analyzer",
+		}
+		properties["variables"] = keyword
+		properties["service_ids"] = keyword
+	case EntityProvider:
+		properties["verified"] = map[string]interface{}{"type": "boolean"}
+		properties["website"] = map[string]interface{}{"type": "keyword", "index": false}
+		properties["regions"] = keyword
+		properties["service_count"] = map[string]interface{}{"type": "integer"}
+	}
+
+	return map[string]interface{}{
+		"settings": im.indexSettings(),
+		"mappings": map[string]interface{}{
+			"properties": properties,
+		},
+	}
+}
+
+// indexSettings returns the shard and analysis settings shared by all indices
+func (im *IndexManager) indexSettings() map[string]interface{} {
+	return map[string]interface{}{
+		"number_of_shards":   im.config.Shards,
+		"number_of_replicas": im.config.Replicas,
+		"refresh_interval":   im.config.RefreshInterval,
+		"analysis": map[string]interface{}{
+			"analyzer": map[string]interface{}{
+				"service_analyzer": map[string]interface{}{
+					"type":      "custom",
+					"tokenizer": "standard",
+					"filter": []string{
+						"lowercase",
+						"asciifolding",
+						"service_synonym",
+						"english_stemmer",
+					},
+				},
+				"autocomplete": map[string]interface{}{
+					"type":      "custom",
+					"tokenizer": "autocomplete_tokenizer",
+					"filter": []string{
+						"lowercase",
+						"asciifolding",
+					},
+				},
+			},
+			"tokenizer": map[string]interface{}{
+				"autocomplete_tokenizer": map[string]interface{}{
+					"type":     "edge_ngram",
+					"min_gram": 2,
+					"max_gram": 20,
+					"token_chars": []string{
+						"letter",
+						"digit",
+					},
+				},
+			},
+			"filter": map[string]interface{}{
+				"english_stemmer": map[string]interface{}{
+					"type":     "stemmer",
+					"language": "english",
+				},
+				"service_synonym": map[string]interface{}{
+					"type": "synonym",
+					"synonyms": []string{
+						"llm, large language model, language model",
+						"ml, machine learning",
+						"ai, artificial intelligence",
+						"nlp, natural language processing",
+						"gpt, generative pretrained transformer",
+					},
+				},
+			},
+		},
+	}
+}
+
+// PutVectorFields adds a dense_vector field to the services index for every
+// named embedding model. Fields are only ever added, so a new model can be
+// introduced without reindexing; reusing a name with other dimensions fails.
+func (im *IndexManager) PutVectorFields(ctx context.Context, models []config.EmbeddingModelConfig) error {
+	vectors := make(map[string]interface{})
+	for _, m := range models {
+		if m.Name == "" {
+			continue
+		}
+		vectors[m.Name] = map[string]interface{}{
+			"type":       "dense_vector",
+			"dims":       m.Dimensions,
+			"index":      true,
+			"similarity": im.config.Similarity,
+		}
+	}
+
+	body := map[string]interface{}{
+		"properties": map[string]interface{}{
+			"embedding_models": map[string]interface{}{
+				"type": "keyword",
+			},
+			"embeddings": map[string]interface{}{
+				"properties": vectors,
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(body); err != nil {
+		return fmt.Errorf("failed to encode mappings: %w", err)
+	}
+
+	res, err := im.es.Indices.PutMapping(
+		[]string{im.config.IndexName},
+		&buf,
+		im.es.Indices.PutMapping.WithContext(ctx),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update mappings: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		body, _ := io.ReadAll(res.Body)
+		return fmt.Errorf("vector field mapping failed: %s - %s", res.Status(), string(body))
+	}
+
+	im.logger.Info("Vector fields mapped", zap.Int("models", len(vectors)))
+	return nil
+}
+
+// putFields adds properties to the services index mapping; what names them in errors
+func (im *IndexManager) putFields(ctx context.Context, what string, properties map[string]interface{}) error {
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(map[string]interface{}{"properties": properties}); err != nil {
+		return fmt.Errorf("failed to encode mappings: %w", err)
+	}
+
+	res, err := im.es.Indices.PutMapping(
+		[]string{im.config.IndexName},
+		&buf,
+		im.es.Indices.PutMapping.WithContext(ctx),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update mappings: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		body, _ := io.ReadAll(res.Body)
+		return fmt.Errorf("%s field mapping failed: %s - %s", what, res.Status(), string(body))
+	}
+	return nil
+}
+
 // DeleteIndex deletes the services index
 func (im *IndexManager) DeleteIndex(ctx context.Context) error {
 	res, err := im.es.Indices.Delete(
@@ -0,0 +1,61 @@
+package elasticsearch
+
+import (
+	"fmt"
+	"time"
+)
+
+// Service lifecycle states
+const (
+	StatusDraft      = "draft"
+	StatusActive     = "active"
+	StatusDeprecated = "deprecated"
+	StatusSuspended  = "suspended" // Withdrawn by the registry, e.g. pending review; may return
+	StatusRetired    = "retired"
+)
+
+// SearchableStatuses are the lifecycle states returned by search by default
+var SearchableStatuses = []string{StatusActive, StatusDeprecated}
+
+// statusTransitions lists the allowed target states for each lifecycle state
+var statusTransitions = map[string][]string{
+	StatusDraft:      {StatusActive, StatusRetired},
+	StatusActive:     {StatusDeprecated, StatusSuspended, StatusRetired},
+	StatusDeprecated: {StatusActive, StatusSuspended, StatusRetired},
+	StatusSuspended:  {StatusActive, StatusDeprecated, StatusRetired},
+	StatusRetired:    {},
+}
+
+// DeprecationInfo describes why a service is deprecated and what replaces it
+type DeprecationInfo struct {
+	Message              string     `json:"message,omitempty"`
+	DeprecatedAt         time.Time  `json:"deprecated_at"`
+	SunsetAt             *time.Time `json:"sunset_at,omitempty"`
+	ReplacementServiceID string     `json:"replacement_service_id,omitempty"`
+}
+
+// IsValidStatus reports whether status is a known lifecycle state
+func IsValidStatus(status string) bool {
+	_, ok := statusTransitions[status]
+	return ok
+}
+
+// ValidateTransition checks that a service may move from one lifecycle state to another
+func ValidateTransition(from, to string) error {
+	if !IsValidStatus(to) {
+		return fmt.Errorf("unknown status: %s", to)
+	}
+
+	// Documents indexed before lifecycle states existed have no status
+	if from == "" {
+		from = StatusActive
+	}
+
+	for _, allowed := range statusTransitions[from] {
+		if allowed == to {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("cannot move from %s to %s", from, to)
+}
@@ -0,0 +1,120 @@
+package elasticsearch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// OpenPointInTime freezes a view of the services index for keepAlive, so a
+// walk over it with search_after sees every document exactly once however
+// the index changes meanwhile
+func (c *Client) OpenPointInTime(ctx context.Context, keepAlive time.Duration) (_ string, err error) {
+	defer c.observe(ctx, "open_point_in_time", time.Now(), &err)
+
+	res, err := c.es.OpenPointInTime(
+		[]string{c.config.IndexName},
+		keepAliveParam(keepAlive),
+		c.es.OpenPointInTime.WithContext(ctx),
+	)
+	if err != nil {
+		return "", fmt.Errorf("failed to open point in time: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		body, _ := io.ReadAll(res.Body)
+		return "", fmt.Errorf("open point in time error: %s - %s", res.Status(), string(body))
+	}
+
+	var pit struct {
+		ID string `json:"id"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&pit); err != nil {
+		return "", fmt.Errorf("failed to decode point in time: %w", err)
+	}
+	return pit.ID, nil
+}
+
+// ClosePointInTime releases a point in time before its keep-alive runs out
+func (c *Client) ClosePointInTime(ctx context.Context, id string) error {
+	data, err := json.Marshal(map[string]string{"id": id})
+	if err != nil {
+		return fmt.Errorf("failed to encode point in time: %w", err)
+	}
+
+	res, err := c.es.ClosePointInTime(
+		c.es.ClosePointInTime.WithContext(ctx),
+		c.es.ClosePointInTime.WithBody(bytes.NewReader(data)),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to close point in time: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		body, _ := io.ReadAll(res.Body)
+		return fmt.Errorf("close point in time error: %s - %s", res.Status(), string(body))
+	}
+	return nil
+}
+
+// SearchPointInTime reads a page of every tenant's services from a point in
+// time in index order, after the sort values of the last hit of the page
+// before. The response's PitID is the one to pass for the next page.
+func (c *Client) SearchPointInTime(ctx context.Context, pitID string, keepAlive time.Duration, size int, after []interface{}, excludes []string) (_ *SearchResponse, err error) {
+	defer c.observe(ctx, "search_point_in_time", time.Now(), &err)
+
+	query := map[string]interface{}{
+		"pit":              map[string]interface{}{"id": pitID, "keep_alive": keepAliveParam(keepAlive)},
+		"sort":             []interface{}{map[string]interface{}{"_shard_doc": "asc"}},
+		"size":             size,
+		"track_total_hits": false,
+	}
+	if len(after) > 0 {
+		query["search_after"] = after
+	}
+	if len(excludes) > 0 {
+		query["_source"] = map[string]interface{}{"excludes": excludes}
+	}
+
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(query); err != nil {
+		return nil, fmt.Errorf("failed to encode query: %w", err)
+	}
+
+	ctx, cancel := withTimeout(ctx, c.config.Timeouts.Read)
+	defer cancel()
+
+	// A point in time names its index, so the request mustn't
+	res, err := c.es.Search(
+		c.es.Search.WithContext(ctx),
+		c.es.Search.WithBody(&buf),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("search failed: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		body, _ := io.ReadAll(res.Body)
+		return nil, fmt.Errorf("search error: %s - %s", res.Status(), string(body))
+	}
+
+	var searchResp SearchResponse
+	if err := json.NewDecoder(res.Body).Decode(&searchResp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	if searchResp.PitID == "" {
+		searchResp.PitID = pitID
+	}
+	return &searchResp, nil
+}
+
+// keepAliveParam formats a duration as an Elasticsearch time value
+func keepAliveParam(d time.Duration) string {
+	return fmt.Sprintf("%dms", d.Milliseconds())
+}
@@ -0,0 +1,77 @@
+package elasticsearch
+
+import (
+	"context"
+	"time"
+
+	"github.com/org/llm-marketplace/pkg/marketplace"
+)
+
+// policyComplianceField holds the compliance summary of each service
+const policyComplianceField = "policy_compliance"
+
+// PolicyCompliance summarises a service's last check with the policy engine,
+// so search can filter on it and show a badge without calling the engine
+type PolicyCompliance struct {
+	Compliant       bool      `json:"compliant"`
+	FailingPolicies []string  `json:"failing_policies,omitempty"` // IDs of the policies the service violates
+	PolicyVersion   string    `json:"policy_version,omitempty"`
+	ValidatedAt     time.Time `json:"validated_at"`
+}
+
+// PolicyValidator checks a service against the marketplace policies
+type PolicyValidator interface {
+	ValidateService(ctx context.Context, desc *marketplace.ServiceDescriptor) (*PolicyCompliance, error)
+}
+
+// SetPolicyValidator has documents checked with v as they're indexed
+func (c *Client) SetPolicyValidator(v PolicyValidator) {
+	c.policyValidator = v
+}
+
+// checkPolicies stores a fresh compliance summary on doc. While the policy
+// engine can't be reached, the document is indexed with the summary it
+// already carries, so a re-indexed service keeps its last known result.
+func (c *Client) checkPolicies(ctx context.Context, doc *ServiceDocument) {
+	if c.policyValidator == nil {
+		return
+	}
+
+	compliance, err := c.policyValidator.ValidateService(ctx, doc.Descriptor())
+	if err != nil {
+		c.countPolicyValidation("unavailable")
+		return
+	}
+	doc.PolicyCompliance = compliance
+	if compliance.Compliant {
+		c.countPolicyValidation("compliant")
+	} else {
+		c.countPolicyValidation("noncompliant")
+	}
+}
+
+func (c *Client) countPolicyValidation(result string) {
+	if c.metrics != nil {
+		c.metrics.PolicyValidation(result)
+	}
+}
+
+// PutPolicyComplianceField adds the compliance summary to the services index.
+// Documents are given a summary as they are next written.
+func (im *IndexManager) PutPolicyComplianceField(ctx context.Context) error {
+	return im.putFields(ctx, "policy compliance", map[string]interface{}{
+		policyComplianceField: policyComplianceMapping(),
+	})
+}
+
+// policyComplianceMapping maps the compliance summary
+func policyComplianceMapping() map[string]interface{} {
+	return map[string]interface{}{
+		"properties": map[string]interface{}{
+			"compliant":        map[string]interface{}{"type": "boolean"},
+			"failing_policies": map[string]interface{}{"type": "keyword"},
+			"policy_version":   map[string]interface{}{"type": "keyword"},
+			"validated_at":     map[string]interface{}{"type": "date"},
+		},
+	}
+}
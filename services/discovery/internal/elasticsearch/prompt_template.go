@@ -0,0 +1,48 @@
+package elasticsearch
+
+import (
+	"context"
+
+	"github.com/org/llm-marketplace/pkg/marketplace"
+)
+
+// Fields of prompt templates, listed alongside services
+const (
+	kindField           = "kind"
+	promptTemplateField = "prompt_template"
+	// ModelFamiliesField holds the model families a prompt template is for
+	ModelFamiliesField = promptTemplateField + ".model_families"
+)
+
+// PromptTemplate is the text, variables and model families of a prompt
+// template
+type PromptTemplate = marketplace.PromptTemplate
+
+// PutPromptTemplateFields adds the kind and prompt template fields to the
+// services index. Documents are given them as they are next written;
+// documents without a kind are services.
+func (im *IndexManager) PutPromptTemplateFields(ctx context.Context) error {
+	return im.putFields(ctx, "prompt template", map[string]interface{}{
+		kindField:           map[string]interface{}{"type": "keyword"},
+		promptTemplateField: promptTemplateMapping(),
+	})
+}
+
+// promptTemplateMapping maps the prompt template of a listing. The template
+// text is searchable; variables are matched by name.
+func promptTemplateMapping() map[string]interface{} {
+	return map[string]interface{}{
+		"properties": map[string]interface{}{
+			"template": map[string]interface{}{"type": "text", "analyzer": "standard"},
+			"variables": map[string]interface{}{
+				"properties": map[string]interface{}{
+					"name":        map[string]interface{}{"type": "keyword"},
+					"description": map[string]interface{}{"type": "text"},
+					"required":    map[string]interface{}{"type": "boolean"},
+					"default":     map[string]interface{}{"type": "keyword", "index": false},
+				},
+			},
+			"model_families": map[string]interface{}{"type": "keyword"},
+		},
+	}
+}
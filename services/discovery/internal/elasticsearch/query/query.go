@@ -0,0 +1,231 @@
+// Package query builds Elasticsearch query DSL from typed clauses. Clauses
+// render to the map form the client sends, so built queries can still be
+// adjusted by code that works on maps, such as tenant scoping.
+package query
+
+import "encoding/json"
+
+// Clause is a query DSL clause
+type Clause interface {
+	Source() map[string]interface{}
+}
+
+// sources renders clauses as a JSON array
+func sources(clauses []Clause) []interface{} {
+	out := make([]interface{}, len(clauses))
+	for i, c := range clauses {
+		out[i] = c.Source()
+	}
+	return out
+}
+
+// Raw is a clause written as a map, for DSL the builder doesn't cover
+type Raw map[string]interface{}
+
+func (r Raw) Source() map[string]interface{} {
+	return r
+}
+
+// MatchAll matches every document
+func MatchAll() Clause {
+	return Raw{"match_all": map[string]interface{}{}}
+}
+
+// Term matches documents whose field holds exactly value
+func Term(field string, value interface{}) Clause {
+	return Raw{"term": map[string]interface{}{field: value}}
+}
+
+// Terms matches documents whose field holds any of values
+func Terms[T any](field string, values []T) Clause {
+	return Raw{"terms": map[string]interface{}{field: values}}
+}
+
+// Exists matches documents with a value in field
+func Exists(field string) Clause {
+	return Raw{"exists": map[string]interface{}{"field": field}}
+}
+
+// RangeQuery matches documents whose field falls within the set bounds
+type RangeQuery struct {
+	field  string
+	bounds map[string]interface{}
+}
+
+// Range starts a range clause on field; set bounds with Gte, Gt, Lte and Lt
+func Range(field string) *RangeQuery {
+	return &RangeQuery{field: field, bounds: map[string]interface{}{}}
+}
+
+func (r *RangeQuery) Gte(v interface{}) *RangeQuery { r.bounds["gte"] = v; return r }
+func (r *RangeQuery) Gt(v interface{}) *RangeQuery  { r.bounds["gt"] = v; return r }
+func (r *RangeQuery) Lte(v interface{}) *RangeQuery { r.bounds["lte"] = v; return r }
+func (r *RangeQuery) Lt(v interface{}) *RangeQuery  { r.bounds["lt"] = v; return r }
+
+func (r *RangeQuery) Source() map[string]interface{} {
+	return map[string]interface{}{"range": map[string]interface{}{r.field: r.bounds}}
+}
+
+// MultiMatchQuery runs a full-text query across several fields
+type MultiMatchQuery struct {
+	query     string
+	fields    []string
+	matchType string
+	fuzziness string
+	operator  string
+}
+
+// MultiMatch matches text against fields, which may carry ^boosts
+func MultiMatch(text string, fields ...string) *MultiMatchQuery {
+	return &MultiMatchQuery{query: text, fields: fields}
+}
+
+// Type sets how fields are combined, e.g. best_fields
+func (m *MultiMatchQuery) Type(t string) *MultiMatchQuery { m.matchType = t; return m }
+
+// Fuzziness sets the allowed edit distance, e.g. AUTO
+func (m *MultiMatchQuery) Fuzziness(f string) *MultiMatchQuery { m.fuzziness = f; return m }
+
+// Operator sets whether all terms (and) or any term (or) must match
+func (m *MultiMatchQuery) Operator(op string) *MultiMatchQuery { m.operator = op; return m }
+
+func (m *MultiMatchQuery) Source() map[string]interface{} {
+	body := map[string]interface{}{
+		"query":  m.query,
+		"fields": m.fields,
+	}
+	if m.matchType != "" {
+		body["type"] = m.matchType
+	}
+	if m.fuzziness != "" {
+		body["fuzziness"] = m.fuzziness
+	}
+	if m.operator != "" {
+		body["operator"] = m.operator
+	}
+	return map[string]interface{}{"multi_match": body}
+}
+
+// Script is a Painless script with parameters
+type Script struct {
+	Source string
+	Params map[string]interface{}
+}
+
+func (s Script) source() map[string]interface{} {
+	body := map[string]interface{}{"source": s.Source}
+	if len(s.Params) > 0 {
+		body["params"] = s.Params
+	}
+	return body
+}
+
+// ScriptScore scores the documents matching inner with script
+func ScriptScore(inner Clause, script Script) Clause {
+	return Raw{"script_score": map[string]interface{}{
+		"query":  inner.Source(),
+		"script": script.source(),
+	}}
+}
+
+// BoolQuery combines clauses. Empty sections are left out of the rendered query.
+type BoolQuery struct {
+	must               []Clause
+	filter             []Clause
+	should             []Clause
+	mustNot            []Clause
+	minimumShouldMatch *int
+}
+
+// Bool starts an empty bool query
+func Bool() *BoolQuery {
+	return &BoolQuery{}
+}
+
+// Must adds scoring clauses that every match satisfies
+func (b *BoolQuery) Must(clauses ...Clause) *BoolQuery {
+	b.must = append(b.must, clauses...)
+	return b
+}
+
+// Filter adds non-scoring clauses that every match satisfies
+func (b *BoolQuery) Filter(clauses ...Clause) *BoolQuery {
+	b.filter = append(b.filter, clauses...)
+	return b
+}
+
+// Should adds clauses that raise the score of matches
+func (b *BoolQuery) Should(clauses ...Clause) *BoolQuery {
+	b.should = append(b.should, clauses...)
+	return b
+}
+
+// MustNot adds clauses that exclude matching documents
+func (b *BoolQuery) MustNot(clauses ...Clause) *BoolQuery {
+	b.mustNot = append(b.mustNot, clauses...)
+	return b
+}
+
+// MinimumShouldMatch sets how many should clauses a match needs
+func (b *BoolQuery) MinimumShouldMatch(n int) *BoolQuery {
+	b.minimumShouldMatch = &n
+	return b
+}
+
+func (b *BoolQuery) Source() map[string]interface{} {
+	body := map[string]interface{}{}
+	for name, clauses := range map[string][]Clause{
+		"must":     b.must,
+		"filter":   b.filter,
+		"should":   b.should,
+		"must_not": b.mustNot,
+	} {
+		if len(clauses) > 0 {
+			body[name] = sources(clauses)
+		}
+	}
+	if b.minimumShouldMatch != nil {
+		body["minimum_should_match"] = *b.minimumShouldMatch
+	}
+	return map[string]interface{}{"bool": body}
+}
+
+// Search is a search request body
+type Search struct {
+	Query          Clause
+	From           int
+	Size           int
+	Aggregations   map[string]interface{}
+	SourceFilter   interface{} // _source: false, a field list, or includes/excludes
+	Sort           []interface{}
+	TrackTotalHits bool
+}
+
+// Map renders the request as the body the client sends
+func (s *Search) Map() map[string]interface{} {
+	body := map[string]interface{}{
+		"from": s.From,
+		"size": s.Size,
+	}
+	if s.Query != nil {
+		body["query"] = s.Query.Source()
+	}
+	if len(s.Aggregations) > 0 {
+		body["aggs"] = s.Aggregations
+	}
+	if s.SourceFilter != nil {
+		body["_source"] = s.SourceFilter
+	}
+	if len(s.Sort) > 0 {
+		body["sort"] = s.Sort
+	}
+	if s.TrackTotalHits {
+		body["track_total_hits"] = true
+	}
+	return body
+}
+
+// MarshalJSON renders the request as JSON
+func (s *Search) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.Map())
+}
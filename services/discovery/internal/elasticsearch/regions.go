@@ -0,0 +1,38 @@
+package elasticsearch
+
+import (
+	"context"
+
+	"github.com/org/llm-marketplace/pkg/marketplace"
+)
+
+const (
+	servingRegionsField = "serving_regions"
+	// ServingRegionField holds the regions a service is served from
+	ServingRegionField = servingRegionsField + ".region"
+)
+
+// RegionLatency returns the median latency measured in the region, and
+// whether the service is served from it at all
+func (d *ServiceDocument) RegionLatency(region string) (int, bool) {
+	desc := marketplace.ServiceDescriptor{ServingRegions: d.ServingRegions}
+	return desc.RegionLatency(region)
+}
+
+// PutServingRegionsField adds the serving regions to the services index.
+// Documents are given them as they are next written.
+func (im *IndexManager) PutServingRegionsField(ctx context.Context) error {
+	return im.putFields(ctx, "serving regions", map[string]interface{}{
+		servingRegionsField: servingRegionsMapping(),
+	})
+}
+
+// servingRegionsMapping maps the regions a service is served from
+func servingRegionsMapping() map[string]interface{} {
+	return map[string]interface{}{
+		"properties": map[string]interface{}{
+			"region":            map[string]interface{}{"type": "keyword"},
+			"median_latency_ms": map[string]interface{}{"type": "integer"},
+		},
+	}
+}
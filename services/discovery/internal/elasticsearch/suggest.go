@@ -0,0 +1,273 @@
+package elasticsearch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+)
+
+// Suggestions are filtered by a single context whose values join a catalog,
+// an audience and a category. A completion query matches a suggestion indexed
+// with any one of the query's values, so the three must be combined into one
+// value for a suggestion to need all of them.
+const (
+	suggestField   = "suggest"
+	suggestContext = "scope"
+	anyCatalog     = "*"
+	sharedCatalog  = "shared"
+	anyCategory    = "*"
+)
+
+// AudiencePublic is the audience of services anyone may see
+const AudiencePublic = "public"
+
+// maxSuggestWords bounds the words of a name a suggestion can start from
+const maxSuggestWords = 8
+
+// TenantAudience is the audience of services a tenant may see
+func TenantAudience(tenantID string) string {
+	return "tenant:" + tenantID
+}
+
+// UserAudience is the audience of services a user may see
+func UserAudience(userID string) string {
+	return "user:" + userID
+}
+
+// tenantCatalog is the catalog of a tenant's private marketplace
+func tenantCatalog(tenantID string) string {
+	return "tenant:" + tenantID
+}
+
+// Audiences returns who may see a service with these access rules, as
+// CanView decides: the public, or the tenants and users it is shared with.
+// Unknown visibility levels have no audience.
+func (a *AccessInfo) Audiences() []string {
+	if a == nil {
+		return []string{AudiencePublic}
+	}
+
+	var audiences []string
+	switch a.Visibility {
+	case "", VisibilityPublic:
+		return []string{AudiencePublic}
+	case VisibilityTenant:
+		if a.OwnerTenant != "" {
+			audiences = append(audiences, TenantAudience(a.OwnerTenant))
+		}
+	case VisibilityPrivate:
+	default:
+		return nil
+	}
+
+	for _, tenant := range a.AllowedTenants {
+		audiences = append(audiences, TenantAudience(tenant))
+	}
+	for _, user := range a.AllowedUsers {
+		audiences = append(audiences, UserAudience(user))
+	}
+	return audiences
+}
+
+// Completion is the suggest field of a service document
+type Completion struct {
+	Input    []string            `json:"input"`
+	Weight   int                 `json:"weight"`
+	Contexts map[string][]string `json:"contexts"`
+}
+
+// Completion returns the autocomplete suggestion for the document, weighted
+// by popularity, or nil if search wouldn't return it: it isn't searchable,
+// is an older version or nobody may see it. The name is suggested from the
+// start of each of its words.
+func (d *ServiceDocument) Completion() *Completion {
+	if d.Name == "" || !isSearchable(d.Status) || (d.Version != nil && !d.Version.Latest) {
+		return nil
+	}
+	audiences := d.Access.Audiences()
+	if len(audiences) == 0 {
+		return nil
+	}
+
+	words := strings.Fields(d.Name)
+	if len(words) > maxSuggestWords {
+		words = words[:maxSuggestWords]
+	}
+	inputs := make([]string, 0, len(words))
+	for i := range words {
+		inputs = append(inputs, strings.Join(words[i:], " "))
+	}
+
+	own := sharedCatalog
+	if d.TenantID != "" {
+		own = tenantCatalog(d.TenantID)
+	}
+	categories := []string{anyCategory}
+	if d.Category != "" {
+		categories = append(categories, d.Category)
+	}
+
+	return &Completion{
+		Input:    inputs,
+		Weight:   suggestWeight(d.Metrics.PopularityScore),
+		Contexts: map[string][]string{suggestContext: scopes([]string{anyCatalog, own}, audiences, categories)},
+	}
+}
+
+// suggestWeight scales a popularity score to the integer weight ES ranks suggestions by
+func suggestWeight(popularity float64) int {
+	return 1 + int(math.Min(math.Max(popularity*1000, 0), math.MaxInt32-1))
+}
+
+func isSearchable(status string) bool {
+	for _, s := range SearchableStatuses {
+		if status == s {
+			return true
+		}
+	}
+	return false
+}
+
+// scopes joins every catalog, audience and category into context values
+func scopes(catalogs, audiences, categories []string) []string {
+	values := make([]string, 0, len(catalogs)*len(audiences)*len(categories))
+	for _, catalog := range catalogs {
+		for _, audience := range audiences {
+			for _, category := range categories {
+				values = append(values, catalog+"|"+audience+"|"+category)
+			}
+		}
+	}
+	return values
+}
+
+// indexedDocument is a service document as written, with its suggestion
+type indexedDocument struct {
+	*ServiceDocument
+	Suggest *Completion `json:"suggest,omitempty"`
+}
+
+// indexed returns the document to write, recomputing the suggestion from it
+func (d *ServiceDocument) indexed() indexedDocument {
+	return indexedDocument{ServiceDocument: d, Suggest: d.Completion()}
+}
+
+// suggestCatalogs returns the catalogs ctx may read suggestions from
+func (c *Client) suggestCatalogs(ctx context.Context) []string {
+	if !c.tenancyEnabled() || isAllTenants(ctx) {
+		return []string{anyCatalog}
+	}
+	tenant := TenantFromContext(ctx)
+	if tenant == "" {
+		return []string{sharedCatalog}
+	}
+	if c.config.Tenancy.SharedCatalog {
+		return []string{tenantCatalog(tenant), sharedCatalog}
+	}
+	return []string{tenantCatalog(tenant)}
+}
+
+// Suggest returns up to size services whose names have a word starting with
+// prefix, from the catalog ctx is scoped to, for the given audiences and, if
+// any are given, categories. Hits are scored by suggestion weight. Contexts
+// can be stale after a partial update, so hits search wouldn't return are
+// dropped; the caller must still check access, as for a search.
+func (c *Client) Suggest(ctx context.Context, prefix string, audiences, categories []string, size int) (_ []Hit, err error) {
+	defer c.observe(ctx, "suggest", time.Now(), &err)
+
+	if len(categories) == 0 {
+		categories = []string{anyCategory}
+	}
+	body := map[string]interface{}{
+		"_source": []string{"name", "access", "status", "version", "tenant_id"},
+		"suggest": map[string]interface{}{
+			"services": map[string]interface{}{
+				"prefix": prefix,
+				"completion": map[string]interface{}{
+					"field":           suggestField,
+					"size":            size,
+					"skip_duplicates": true,
+					"contexts": map[string]interface{}{
+						suggestContext: scopes(c.suggestCatalogs(ctx), audiences, categories),
+					},
+				},
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(body); err != nil {
+		return nil, fmt.Errorf("failed to encode suggest query: %w", err)
+	}
+
+	ctx, cancel := withTimeout(ctx, c.config.Timeouts.Read)
+	defer cancel()
+
+	opts := []func(*esapi.SearchRequest){
+		c.es.Search.WithContext(ctx),
+		c.es.Search.WithIndex(c.config.IndexName),
+		c.es.Search.WithBody(&buf),
+	}
+	if routing := c.searchRouting(ctx); routing != "" {
+		opts = append(opts, c.es.Search.WithRouting(routing))
+	}
+
+	res, err := c.es.Search(opts...)
+	if err != nil {
+		return nil, fmt.Errorf("suggest failed: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		body, _ := io.ReadAll(res.Body)
+		return nil, fmt.Errorf("suggest error: %s - %s", res.Status(), string(body))
+	}
+
+	var result struct {
+		Suggest map[string][]struct {
+			Options []Hit `json:"options"`
+		} `json:"suggest"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode suggest response: %w", err)
+	}
+
+	var hits []Hit
+	for _, entry := range result.Suggest["services"] {
+		for _, hit := range entry.Options {
+			doc := &hit.Source
+			if !c.InTenant(ctx, doc) || !isSearchable(doc.Status) || (doc.Version != nil && !doc.Version.Latest) {
+				continue
+			}
+			hits = append(hits, hit)
+		}
+	}
+	return hits, nil
+}
+
+// PutSuggestField adds the suggest completion field to the services index.
+// Documents are given suggestions as they are next written.
+func (im *IndexManager) PutSuggestField(ctx context.Context) error {
+	return im.putFields(ctx, "suggest", map[string]interface{}{suggestField: suggestMapping()})
+}
+
+// suggestMapping maps the suggest completion field and its context
+func suggestMapping() map[string]interface{} {
+	return map[string]interface{}{
+		"type":     "completion",
+		"analyzer": "simple",
+		"contexts": []interface{}{
+			map[string]interface{}{
+				"name": suggestContext,
+				"type": "category",
+			},
+		},
+	}
+}
@@ -0,0 +1,151 @@
+package elasticsearch
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrTenantMismatch is returned when a document is written for a different tenant than the caller's
+var ErrTenantMismatch = errors.New("document belongs to another tenant")
+
+type tenantKey struct{}
+
+// allTenants marks a context that may read across every tenant catalog
+const allTenants = "\x00all"
+
+// WithTenant scopes catalog reads and writes made with ctx to a tenant; empty means the shared catalog
+func WithTenant(ctx context.Context, tenantID string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenantID)
+}
+
+// WithAllTenants lets background jobs such as SLA probing read every tenant's catalog
+func WithAllTenants(ctx context.Context) context.Context {
+	return context.WithValue(ctx, tenantKey{}, allTenants)
+}
+
+// TenantFromContext returns the tenant ctx is scoped to
+func TenantFromContext(ctx context.Context) string {
+	tenant, _ := ctx.Value(tenantKey{}).(string)
+	if tenant == allTenants {
+		return ""
+	}
+	return tenant
+}
+
+func isAllTenants(ctx context.Context) bool {
+	tenant, _ := ctx.Value(tenantKey{}).(string)
+	return tenant == allTenants
+}
+
+// tenancyEnabled reports whether catalogs are partitioned by tenant
+func (c *Client) tenancyEnabled() bool {
+	return c.config.Tenancy.Enabled
+}
+
+// InTenant reports whether doc belongs to the catalog ctx is scoped to
+func (c *Client) InTenant(ctx context.Context, doc *ServiceDocument) bool {
+	if !c.tenancyEnabled() || isAllTenants(ctx) {
+		return true
+	}
+	tenant := TenantFromContext(ctx)
+	if doc.TenantID == tenant {
+		return true
+	}
+	return doc.TenantID == "" && c.config.Tenancy.SharedCatalog
+}
+
+// searchRouting returns the routing value for a search scoped by ctx, if it can be routed to one shard
+func (c *Client) searchRouting(ctx context.Context) string {
+	if !c.tenancyEnabled() || c.config.Tenancy.SharedCatalog {
+		// Shared catalog documents are not routed by tenant
+		return ""
+	}
+	return TenantFromContext(ctx)
+}
+
+// docRouting returns the routing value for single-document reads and writes scoped by ctx
+func (c *Client) docRouting(ctx context.Context) string {
+	if !c.tenancyEnabled() {
+		return ""
+	}
+	return TenantFromContext(ctx)
+}
+
+// tenantFilter returns the clause limiting a search to the catalog ctx is scoped to, or nil
+func (c *Client) tenantFilter(ctx context.Context) map[string]interface{} {
+	if !c.tenancyEnabled() || isAllTenants(ctx) {
+		return nil
+	}
+
+	shared := map[string]interface{}{
+		"bool": map[string]interface{}{
+			"must_not": map[string]interface{}{
+				"exists": map[string]interface{}{"field": "tenant_id"},
+			},
+		},
+	}
+
+	tenant := TenantFromContext(ctx)
+	if tenant == "" {
+		return shared
+	}
+
+	own := map[string]interface{}{
+		"term": map[string]interface{}{"tenant_id": tenant},
+	}
+	if !c.config.Tenancy.SharedCatalog {
+		return own
+	}
+
+	return map[string]interface{}{
+		"bool": map[string]interface{}{
+			"should":               []interface{}{own, shared},
+			"minimum_should_match": 1,
+		},
+	}
+}
+
+// ScopedQuery returns query as Search sends it for ctx
+func (c *Client) ScopedQuery(ctx context.Context, query map[string]interface{}) map[string]interface{} {
+	return c.scopeQuery(ctx, query)
+}
+
+// scopeQuery wraps query so it only matches the catalog ctx is scoped to
+func (c *Client) scopeQuery(ctx context.Context, query map[string]interface{}) map[string]interface{} {
+	filter := c.tenantFilter(ctx)
+	if filter == nil {
+		return query
+	}
+
+	inner, ok := query["query"]
+	if !ok {
+		inner = map[string]interface{}{"match_all": map[string]interface{}{}}
+	}
+
+	scoped := make(map[string]interface{}, len(query))
+	for k, v := range query {
+		scoped[k] = v
+	}
+	scoped["query"] = map[string]interface{}{
+		"bool": map[string]interface{}{
+			"must":   inner,
+			"filter": filter,
+		},
+	}
+	return scoped
+}
+
+// assignTenant stamps the caller's tenant on a new document and rejects writes into other tenants
+func (c *Client) assignTenant(ctx context.Context, doc *ServiceDocument) error {
+	if !c.tenancyEnabled() || isAllTenants(ctx) {
+		return nil
+	}
+	tenant := TenantFromContext(ctx)
+	if doc.TenantID == "" {
+		doc.TenantID = tenant
+	}
+	if doc.TenantID != tenant {
+		return ErrTenantMismatch
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package elasticsearch
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+// maxVersions bounds the version history returned for one service
+const maxVersions = 200
+
+// VersionInfo describes one published version of a service
+type VersionInfo struct {
+	Number     string    `json:"number"`
+	Stable     bool      `json:"stable"` // Pre-release versions are not stable
+	Latest     bool      `json:"latest"` // Set on the newest stable version only
+	Changelog  string    `json:"changelog,omitempty"`
+	ReleasedAt time.Time `json:"released_at"`
+}
+
+// Key returns the identifier shared by every version of the service
+func (d *ServiceDocument) Key() string {
+	if d.ServiceKey != "" {
+		return d.ServiceKey
+	}
+	return d.ID
+}
+
+// Versions returns every indexed version of a service, newest first, without embeddings
+func (c *Client) Versions(ctx context.Context, serviceKey string) ([]*ServiceDocument, error) {
+	query := map[string]interface{}{
+		"size": maxVersions,
+		"query": map[string]interface{}{
+			"bool": map[string]interface{}{
+				"should": []interface{}{
+					map[string]interface{}{"term": map[string]interface{}{"service_key": serviceKey}},
+					map[string]interface{}{"ids": map[string]interface{}{"values": []string{serviceKey}}},
+				},
+				"minimum_should_match": 1,
+			},
+		},
+		"sort": []interface{}{
+			map[string]interface{}{"version.released_at": map[string]interface{}{"order": "desc", "missing": "_last"}},
+		},
+		"_source": map[string]interface{}{
+			"excludes": DefaultSourceExcludes,
+		},
+	}
+
+	resp, err := c.Search(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	versions := make([]*ServiceDocument, 0, len(resp.Hits.Hits))
+	for i := range resp.Hits.Hits {
+		doc := &resp.Hits.Hits[i].Source
+		if doc.ID == "" {
+			doc.ID = resp.Hits.Hits[i].ID
+		}
+		// The ids clause can match a document that belongs to another service
+		if doc.Key() != serviceKey {
+			continue
+		}
+		versions = append(versions, doc)
+	}
+	return versions, nil
+}
+
+// LatestStable returns the newest stable, non-retired version, or nil if there is none
+func LatestStable(versions []*ServiceDocument) *ServiceDocument {
+	candidates := make([]*ServiceDocument, 0, len(versions))
+	for _, v := range versions {
+		if v.Version == nil || !v.Version.Stable || v.Status == StatusRetired {
+			continue
+		}
+		candidates = append(candidates, v)
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].Version.ReleasedAt.After(candidates[j].Version.ReleasedAt)
+	})
+	return candidates[0]
+}
@@ -0,0 +1,118 @@
+// Package entitlement decides which catalog documents a caller may see.
+package entitlement
+
+import (
+	"context"
+
+	"github.com/org/llm-marketplace/services/discovery/internal/elasticsearch"
+	"github.com/org/llm-marketplace/services/discovery/internal/elasticsearch/query"
+)
+
+type contextKey struct{}
+
+// Caller identifies who a request acts for; the zero value is anonymous and sees public services only
+type Caller struct {
+	TenantID string `json:"tenant_id,omitempty"`
+	UserID   string `json:"user_id,omitempty"`
+}
+
+// WithCaller returns a context carrying caller, scoped to the caller's tenant catalog
+func WithCaller(ctx context.Context, caller Caller) context.Context {
+	ctx = elasticsearch.WithTenant(ctx, caller.TenantID)
+	return context.WithValue(ctx, contextKey{}, caller)
+}
+
+// FromContext returns the caller attached to ctx, or the anonymous caller
+func FromContext(ctx context.Context) Caller {
+	caller, _ := ctx.Value(contextKey{}).(Caller)
+	return caller
+}
+
+// CanView reports whether the caller may see a service with the given access rules
+func (c Caller) CanView(access *elasticsearch.AccessInfo) bool {
+	if access == nil {
+		return true
+	}
+
+	switch access.Visibility {
+	case "", elasticsearch.VisibilityPublic:
+		return true
+	case elasticsearch.VisibilityTenant:
+		if c.TenantID != "" && c.TenantID == access.OwnerTenant {
+			return true
+		}
+	case elasticsearch.VisibilityPrivate:
+	default:
+		// Unknown levels fail closed
+		return false
+	}
+
+	return (c.TenantID != "" && contains(access.AllowedTenants, c.TenantID)) ||
+		(c.UserID != "" && contains(access.AllowedUsers, c.UserID))
+}
+
+// Filter returns the query clause that limits a search to services the caller may see.
+// It must be added to the filter context of every service query.
+func (c Caller) Filter() query.Clause {
+	filter := query.Bool().Should(
+		query.Bool().MustNot(query.Exists("access.visibility")),
+		query.Term("access.visibility", elasticsearch.VisibilityPublic),
+	).MinimumShouldMatch(1)
+
+	restricted := query.Terms("access.visibility", []string{elasticsearch.VisibilityTenant, elasticsearch.VisibilityPrivate})
+
+	if c.TenantID != "" {
+		filter.Should(
+			query.Bool().Filter(
+				query.Term("access.visibility", elasticsearch.VisibilityTenant),
+				query.Term("access.owner_tenant", c.TenantID),
+			),
+			query.Bool().Filter(restricted, query.Term("access.allowed_tenants", c.TenantID)),
+		)
+	}
+	if c.UserID != "" {
+		filter.Should(query.Bool().Filter(restricted, query.Term("access.allowed_users", c.UserID)))
+	}
+
+	return filter
+}
+
+// Audiences returns the suggestion audiences the caller belongs to
+func (c Caller) Audiences() []string {
+	audiences := []string{elasticsearch.AudiencePublic}
+	if c.TenantID != "" {
+		audiences = append(audiences, elasticsearch.TenantAudience(c.TenantID))
+	}
+	if c.UserID != "" {
+		audiences = append(audiences, elasticsearch.UserAudience(c.UserID))
+	}
+	return audiences
+}
+
+// CacheScope partitions cached results between callers who may see different services
+func (c Caller) CacheScope() string {
+	if c.TenantID == "" && c.UserID == "" {
+		return "public"
+	}
+	return "t=" + c.TenantID + ",u=" + c.UserID
+}
+
+// Visible returns the documents the caller may see, preserving order
+func (c Caller) Visible(docs []*elasticsearch.ServiceDocument) []*elasticsearch.ServiceDocument {
+	visible := docs[:0:0]
+	for _, doc := range docs {
+		if doc != nil && c.CanView(doc.Access) {
+			visible = append(visible, doc)
+		}
+	}
+	return visible
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,329 @@
+package export
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/org/llm-marketplace/services/discovery/internal/config"
+	"github.com/org/llm-marketplace/services/discovery/internal/entitlement"
+	"github.com/org/llm-marketplace/services/discovery/internal/search"
+	"github.com/org/llm-marketplace/services/discovery/internal/worker"
+	"go.uber.org/zap"
+)
+
+// Job states
+const (
+	JobPending   = "pending"
+	JobRunning   = "running"
+	JobCompleted = "completed"
+	JobFailed    = "failed"
+)
+
+var (
+	// ErrJobNotFound is returned for unknown or expired export jobs
+	ErrJobNotFound = errors.New("export job not found")
+	// ErrJobNotReady is returned when downloading a job that has not completed
+	ErrJobNotReady = errors.New("export job not completed")
+)
+
+// Request is a search to export; Limit defaults to the configured maximum
+type Request struct {
+	search.SearchRequest
+	Format string `json:"format"`
+	Limit  int    `json:"limit,omitempty"`
+}
+
+// Job tracks an asynchronous export
+type Job struct {
+	ID          string     `json:"id"`
+	Status      string     `json:"status"`
+	Format      string     `json:"format"`
+	Limit       int        `json:"limit"`
+	Rows        int        `json:"rows"`
+	Error       string     `json:"error,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	ExpiresAt   time.Time  `json:"expires_at"`
+
+	Caller entitlement.Caller `json:"caller"` // The export runs, and is readable, only as this caller
+}
+
+// Exporter streams search results as CSV/NDJSON and runs large exports as jobs
+type Exporter struct {
+	searchService *search.Service
+	redisClient   *redis.Client
+	config        config.ExportConfig
+	logger        *zap.Logger
+	workers       *worker.Group
+}
+
+func NewExporter(
+	searchService *search.Service,
+	redisClient *redis.Client,
+	cfg config.ExportConfig,
+	logger *zap.Logger,
+) *Exporter {
+	return &Exporter{
+		searchService: searchService,
+		redisClient:   redisClient,
+		config:        cfg,
+		logger:        logger,
+	}
+}
+
+// SetWorkers registers the group async jobs run in, so shutdown waits for them
+func (e *Exporter) SetWorkers(g *worker.Group) {
+	e.workers = g
+}
+
+// Validate checks the request and applies the row limit for streamed or async exports
+func (e *Exporter) Validate(req *Request, async bool) error {
+	if req.Format == "" {
+		req.Format = FormatCSV
+	}
+	if req.Format != FormatCSV && req.Format != FormatNDJSON {
+		return fmt.Errorf("unsupported export format: %s", req.Format)
+	}
+
+	if err := search.ValidateFields(req.Fields); err != nil {
+		return err
+	}
+
+	maxRows := e.config.MaxRows
+	if async {
+		maxRows = e.config.AsyncMaxRows
+	}
+	if req.Limit <= 0 {
+		req.Limit = maxRows
+	}
+	if req.Limit > maxRows {
+		return fmt.Errorf("limit %d exceeds maximum of %d rows", req.Limit, maxRows)
+	}
+
+	return nil
+}
+
+// Stream writes matching services to w and returns the number of rows written
+func (e *Exporter) Stream(ctx context.Context, req *Request, w io.Writer) (int, error) {
+	writer, err := NewWriter(req.Format, w, req.Fields)
+	if err != nil {
+		return 0, err
+	}
+
+	rows, err := e.searchService.Scan(ctx, &req.SearchRequest, req.Limit, writer.Write)
+	if err != nil {
+		return rows, err
+	}
+
+	return rows, writer.Close()
+}
+
+// StartJob records a pending export job and runs it in the background
+func (e *Exporter) StartJob(ctx context.Context, req *Request) (*Job, error) {
+	id, err := newJobID()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	job := &Job{
+		ID:        id,
+		Status:    JobPending,
+		Format:    req.Format,
+		Limit:     req.Limit,
+		CreatedAt: now,
+		ExpiresAt: now.Add(e.config.JobTTL),
+		Caller:    entitlement.FromContext(ctx),
+	}
+
+	if err := e.saveJob(ctx, job); err != nil {
+		return nil, err
+	}
+
+	// The run updates its own copy; job is returned to the caller
+	running := *job
+	e.workers.Task("export_job", func(ctx context.Context) {
+		e.runJob(ctx, &running, req)
+	})
+
+	return job, nil
+}
+
+// GetJob returns the current state of an export job
+func (e *Exporter) GetJob(ctx context.Context, id string) (*Job, error) {
+	data, err := e.redisClient.Get(ctx, jobKey(id)).Bytes()
+	if err == redis.Nil {
+		return nil, ErrJobNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load export job: %w", err)
+	}
+
+	var job Job
+	if err := json.Unmarshal(data, &job); err != nil {
+		return nil, fmt.Errorf("failed to decode export job: %w", err)
+	}
+	// Exports may hold restricted services, so only their creator can see them
+	if job.Caller != entitlement.FromContext(ctx) {
+		return nil, ErrJobNotFound
+	}
+	return &job, nil
+}
+
+// ResultPath returns the file holding a completed job's output
+func (e *Exporter) ResultPath(ctx context.Context, id string) (*Job, string, error) {
+	job, err := e.GetJob(ctx, id)
+	if err != nil {
+		return nil, "", err
+	}
+	if job.Status != JobCompleted {
+		return job, "", ErrJobNotReady
+	}
+	return job, e.resultPath(job), nil
+}
+
+// Start removes expired export files until ctx is cancelled
+func (e *Exporter) Start(ctx context.Context) {
+	if err := os.MkdirAll(e.config.Directory, 0o750); err != nil {
+		e.logger.Error("Failed to create export directory", zap.String("dir", e.config.Directory), zap.Error(err))
+	}
+
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			e.removeExpired()
+		}
+	}
+}
+
+// runJob writes the export; ctx is cancelled if shutdown outlasts the drain timeout
+func (e *Exporter) runJob(ctx context.Context, job *Job, req *Request) {
+	ctx, cancel := context.WithTimeout(entitlement.WithCaller(ctx, job.Caller), e.config.JobTimeout)
+	defer cancel()
+
+	job.Status = JobRunning
+	if err := e.saveJob(ctx, job); err != nil {
+		e.logger.Warn("Failed to update export job", zap.String("job_id", job.ID), zap.Error(err))
+	}
+
+	rows, err := e.writeResult(ctx, job, req)
+
+	completedAt := time.Now().UTC()
+	job.Rows = rows
+	job.CompletedAt = &completedAt
+	if err != nil {
+		job.Status = JobFailed
+		job.Error = err.Error()
+		e.logger.Error("Export job failed", zap.String("job_id", job.ID), zap.Error(err))
+	} else {
+		job.Status = JobCompleted
+		e.logger.Info("Export job completed",
+			zap.String("job_id", job.ID),
+			zap.Int("rows", rows),
+			zap.Duration("duration", completedAt.Sub(job.CreatedAt)),
+		)
+	}
+
+	// Record the outcome even when the job was cut off
+	if err := e.saveJob(context.WithoutCancel(ctx), job); err != nil {
+		e.logger.Error("Failed to save export job", zap.String("job_id", job.ID), zap.Error(err))
+	}
+}
+
+// writeResult streams into a temporary file that is renamed once complete
+func (e *Exporter) writeResult(ctx context.Context, job *Job, req *Request) (int, error) {
+	if err := os.MkdirAll(e.config.Directory, 0o750); err != nil {
+		return 0, fmt.Errorf("failed to create export directory: %w", err)
+	}
+
+	path := e.resultPath(job)
+	f, err := os.CreateTemp(e.config.Directory, job.ID+"-*.tmp")
+	if err != nil {
+		return 0, fmt.Errorf("failed to create export file: %w", err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	buf := bufio.NewWriter(f)
+	rows, err := e.Stream(ctx, req, buf)
+	if err != nil {
+		return rows, err
+	}
+	if err := buf.Flush(); err != nil {
+		return rows, fmt.Errorf("failed to write export file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return rows, fmt.Errorf("failed to write export file: %w", err)
+	}
+
+	if err := os.Rename(f.Name(), path); err != nil {
+		return rows, fmt.Errorf("failed to finalize export file: %w", err)
+	}
+	return rows, nil
+}
+
+func (e *Exporter) removeExpired() {
+	entries, err := os.ReadDir(e.config.Directory)
+	if err != nil {
+		e.logger.Warn("Failed to list export directory", zap.Error(err))
+		return
+	}
+
+	cutoff := time.Now().Add(-e.config.JobTTL)
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil || info.ModTime().After(cutoff) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(e.config.Directory, entry.Name())); err != nil {
+			e.logger.Warn("Failed to remove expired export", zap.String("file", entry.Name()), zap.Error(err))
+		}
+	}
+}
+
+func (e *Exporter) saveJob(ctx context.Context, job *Job) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("failed to encode export job: %w", err)
+	}
+	if err := e.redisClient.Set(ctx, jobKey(job.ID), data, e.config.JobTTL).Err(); err != nil {
+		return fmt.Errorf("failed to save export job: %w", err)
+	}
+	return nil
+}
+
+func (e *Exporter) resultPath(job *Job) string {
+	return filepath.Join(e.config.Directory, job.ID+"."+job.Format)
+}
+
+func jobKey(id string) string {
+	return "export:job:" + id
+}
+
+func newJobID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate job id: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// FileName is the suggested download name for an export
+func FileName(format string) string {
+	return "services." + format
+}
@@ -0,0 +1,160 @@
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/org/llm-marketplace/services/discovery/internal/elasticsearch"
+	"github.com/org/llm-marketplace/services/discovery/internal/search"
+)
+
+// Supported export formats
+const (
+	FormatCSV    = "csv"
+	FormatNDJSON = "ndjson"
+)
+
+// DefaultCSVColumns are used when a CSV export does not select fields
+var DefaultCSVColumns = []string{
+	"id",
+	"name",
+	"category",
+	"status",
+	"provider.name",
+	"provider.verified",
+	"pricing.model",
+	"pricing.rate",
+	"pricing.unit",
+	"sla.availability",
+	"metrics.rating",
+	"metrics.review_count",
+}
+
+// Writer encodes service documents in an export format
+type Writer interface {
+	Write(doc *elasticsearch.ServiceDocument) error
+	Close() error
+}
+
+// NewWriter returns a Writer for format; fields limit the exported fields
+func NewWriter(format string, w io.Writer, fields []string) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		if len(fields) == 0 {
+			fields = DefaultCSVColumns
+		}
+		return &csvWriter{w: csv.NewWriter(w), columns: fields}, nil
+	case FormatNDJSON:
+		return &ndjsonWriter{enc: json.NewEncoder(w), fields: fields}, nil
+	default:
+		return nil, fmt.Errorf("unsupported export format: %s", format)
+	}
+}
+
+// ContentType returns the media type for format
+func ContentType(format string) string {
+	if format == FormatCSV {
+		return "text/csv; charset=utf-8"
+	}
+	return "application/x-ndjson"
+}
+
+type csvWriter struct {
+	w             *csv.Writer
+	columns       []string
+	headerWritten bool
+}
+
+func (c *csvWriter) Write(doc *elasticsearch.ServiceDocument) error {
+	if err := c.writeHeader(); err != nil {
+		return err
+	}
+
+	sparse, err := search.ProjectDocument(doc, c.columns)
+	if err != nil {
+		return err
+	}
+
+	record := make([]string, len(c.columns))
+	for i, column := range c.columns {
+		record[i] = csvValue(lookup(sparse, strings.Split(column, ".")))
+	}
+	return c.w.Write(record)
+}
+
+// Close writes the header for empty exports and flushes buffered rows
+func (c *csvWriter) Close() error {
+	if err := c.writeHeader(); err != nil {
+		return err
+	}
+	c.w.Flush()
+	return c.w.Error()
+}
+
+func (c *csvWriter) writeHeader() error {
+	if c.headerWritten {
+		return nil
+	}
+	c.headerWritten = true
+	return c.w.Write(c.columns)
+}
+
+type ndjsonWriter struct {
+	enc    *json.Encoder
+	fields []string
+}
+
+func (n *ndjsonWriter) Write(doc *elasticsearch.ServiceDocument) error {
+	if len(n.fields) == 0 {
+		return n.enc.Encode(doc)
+	}
+
+	sparse, err := search.ProjectDocument(doc, n.fields)
+	if err != nil {
+		return err
+	}
+	return n.enc.Encode(sparse)
+}
+
+func (n *ndjsonWriter) Close() error {
+	return nil
+}
+
+func lookup(m map[string]interface{}, path []string) interface{} {
+	value, ok := m[path[0]]
+	if !ok || len(path) == 1 {
+		return value
+	}
+	child, ok := value.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	return lookup(child, path[1:])
+}
+
+// csvValue flattens a JSON value into a single cell; lists are joined with ";"
+func csvValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case []interface{}:
+		parts := make([]string, len(v))
+		for i, item := range v {
+			parts[i] = csvValue(item)
+		}
+		return strings.Join(parts, ";")
+	default:
+		data, _ := json.Marshal(v)
+		return string(data)
+	}
+}
@@ -0,0 +1,63 @@
+package features
+
+// CategoryUsage is a user's interactions within the window in one category
+type CategoryUsage struct {
+	Category     string
+	Interactions int
+	PriceRank    float64 // Mean price rank of the services interacted with
+	Priced       bool    // PriceRank is unknown otherwise, when none of them is active
+}
+
+// ComputeUser derives a user's features from their usage of each category.
+// Price sensitivity is 1 less the mean price rank of what they used,
+// weighted by interactions, and 0 when no price rank is known.
+func ComputeUser(usage []CategoryUsage) *UserFeatures {
+	f := &UserFeatures{CategoryAffinity: make(map[string]float64, len(usage))}
+
+	total, priced := 0, 0
+	rankSum := 0.0
+	for _, u := range usage {
+		total += u.Interactions
+		if u.Priced {
+			priced += u.Interactions
+			rankSum += u.PriceRank * float64(u.Interactions)
+		}
+	}
+	if total == 0 {
+		return f
+	}
+	for _, u := range usage {
+		if u.Interactions > 0 {
+			f.CategoryAffinity[u.Category] += float64(u.Interactions) / float64(total)
+		}
+	}
+	if priced > 0 {
+		f.PriceSensitivity = 1 - rankSum/float64(priced)
+	}
+	return f
+}
+
+// Provider is what provider trust is computed from, over the provider's
+// active services
+type Provider struct {
+	Verified  bool
+	Rating    float64 // Mean rating of the reviewed services, out of 5
+	Rated     bool    // None of the services is reviewed otherwise
+	ErrorRate float64 // Mean error rate
+}
+
+// Trust weighs verification at 0.4, rating at 0.4 and the error rate at
+// 0.2. An unrated provider counts as rated 2.5.
+func (p Provider) Trust() float64 {
+	trust := 0.0
+	if p.Verified {
+		trust += 0.4
+	}
+	rating := 2.5
+	if p.Rated {
+		rating = p.Rating
+	}
+	trust += 0.4 * rating / 5
+	trust += 0.2 * (1 - p.ErrorRate)
+	return max(0, min(trust, 1))
+}
@@ -0,0 +1,126 @@
+// Package features is a small feature store. Features of users and
+// services are computed in the background from interactions and the
+// catalog, stored in Postgres and cached in Redis, so search ranking and
+// recommendations read a few precomputed values instead of aggregating
+// interactions in the hot path.
+package features
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Entities features describe
+const (
+	EntityUser    = "user"
+	EntityService = "service"
+)
+
+// Definition names a feature and the version of how it is computed. A
+// change to the computation bumps the version; stored values of any other
+// version are ignored until they are recomputed, so readers never mix the
+// old meaning with the new.
+type Definition struct {
+	Name        string
+	Version     int
+	Entity      string
+	Description string
+}
+
+// Key identifies the definition's values in Postgres and Redis
+func (d Definition) Key() string {
+	return fmt.Sprintf("%s:%d", d.Name, d.Version)
+}
+
+// The features the store computes
+var (
+	CategoryAffinity = Definition{
+		Name:        "category_affinity",
+		Version:     1,
+		Entity:      EntityUser,
+		Description: "Share of the user's interactions within the window in each category",
+	}
+	PriceSensitivity = Definition{
+		Name:        "price_sensitivity",
+		Version:     1,
+		Entity:      EntityUser,
+		Description: "1 when the user only uses the cheapest services of their categories, 0 when only the dearest",
+	}
+	ProviderTrust = Definition{
+		Name:        "provider_trust",
+		Version:     1,
+		Entity:      EntityService,
+		Description: "Trust in the service's provider from its verification and its services' ratings and error rates, 0 to 1",
+	}
+	PriceRank = Definition{
+		Name:        "price_rank",
+		Version:     1,
+		Entity:      EntityService,
+		Description: "Percent rank of the service's price in its category, 0 for the cheapest",
+	}
+)
+
+// Definitions lists every feature the store computes
+var Definitions = []Definition{CategoryAffinity, PriceSensitivity, ProviderTrust, PriceRank}
+
+// UserFeatures are the features of a user. A user without interactions in
+// the window has none.
+type UserFeatures struct {
+	CategoryAffinity map[string]float64 // Shares sum to 1
+	PriceSensitivity float64
+}
+
+// TopCategories returns up to n of the categories the user has affinity
+// with, most first
+func (f *UserFeatures) TopCategories(n int) []string {
+	if f == nil {
+		return nil
+	}
+	categories := make([]string, 0, len(f.CategoryAffinity))
+	for category := range f.CategoryAffinity {
+		categories = append(categories, category)
+	}
+	sort.Slice(categories, func(i, j int) bool {
+		a, b := f.CategoryAffinity[categories[i]], f.CategoryAffinity[categories[j]]
+		if a != b {
+			return a > b
+		}
+		return categories[i] < categories[j]
+	})
+	if len(categories) > n {
+		categories = categories[:n]
+	}
+	return categories
+}
+
+// ServiceFeatures are the features of an active service
+type ServiceFeatures struct {
+	ProviderTrust float64
+	PriceRank     float64
+}
+
+// Weights turn features into score multipliers, as features.affinity_boost,
+// price_weight and trust_weight configure them
+type Weights struct {
+	AffinityBoost float64
+	PriceWeight   float64
+	TrustWeight   float64
+}
+
+// Factor is how much a service's score is multiplied by for the user:
+// raised by the user's affinity with its category, lowered by how dear it
+// is for a price sensitive user, and moved either way by its provider's
+// trust. Either side may be nil, leaving its features out.
+func (w Weights) Factor(user *UserFeatures, category string, svc *ServiceFeatures) float64 {
+	factor := 1.0
+	if user != nil {
+		factor *= 1 + w.AffinityBoost*user.CategoryAffinity[category]
+	}
+	if svc != nil {
+		if user != nil {
+			factor *= 1 - w.PriceWeight*user.PriceSensitivity*svc.PriceRank
+		}
+		factor *= 1 + w.TrustWeight*(2*svc.ProviderTrust-1)
+	}
+	return factor
+}
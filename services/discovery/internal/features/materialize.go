@@ -0,0 +1,321 @@
+package features
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+// materializeLockKey lets one replica at a time recompute features
+const materializeLockKey = "features:materialize:lock"
+
+// writeBatch is how many values go to Postgres, and entities to Redis, at once
+const writeBatch = 500
+
+// Start recomputes every feature each refresh_interval until ctx is cancelled
+func (s *Store) Start(ctx context.Context) {
+	if !s.config.Enabled {
+		s.logger.Info("Feature store is disabled")
+		return
+	}
+
+	s.logger.Info("Starting feature materializer", zap.Duration("interval", s.config.RefreshInterval))
+
+	ticker := time.NewTicker(s.config.RefreshInterval)
+	defer ticker.Stop()
+
+	s.materializeOnce(ctx)
+
+	for {
+		select {
+		case <-ticker.C:
+			s.materializeOnce(ctx)
+		case <-ctx.Done():
+			s.logger.Info("Feature materializer stopped")
+			return
+		}
+	}
+}
+
+// materializeOnce materializes unless another replica did within the
+// interval
+func (s *Store) materializeOnce(ctx context.Context) {
+	// The lock outlives the run and lapses just before the next tick
+	acquired, err := s.redisClient.SetNX(ctx, materializeLockKey, time.Now().UTC().Format(time.RFC3339), s.config.RefreshInterval*9/10).Result()
+	if err != nil {
+		s.logger.Error("Failed to acquire feature materializer lock", zap.Error(err))
+		return
+	}
+	if !acquired {
+		return
+	}
+
+	start := time.Now()
+	users, services, err := s.Materialize(ctx)
+	if err != nil {
+		s.logger.Error("Failed to materialize features", zap.Error(err))
+		return
+	}
+	s.logger.Info("Materialized features",
+		zap.Int("users", users),
+		zap.Int("services", services),
+		zap.Duration("duration", time.Since(start)),
+	)
+}
+
+// Materialize recomputes every feature, replaces the stored values and
+// refreshes the cache, returning how many users and services have
+// features. An entity that lost its features keeps the cached ones until
+// they expire.
+func (s *Store) Materialize(ctx context.Context) (int, int, error) {
+	if err := s.registerDefinitions(ctx); err != nil {
+		return 0, 0, err
+	}
+
+	services, err := s.computeServices(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+	users, err := s.computeUsers(ctx, time.Now().Add(-s.config.Window))
+	if err != nil {
+		return 0, 0, err
+	}
+
+	serviceValues, err := encodeValues(services, func(f *ServiceFeatures) map[Definition]interface{} {
+		return map[Definition]interface{}{ProviderTrust: f.ProviderTrust, PriceRank: f.PriceRank}
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+	userValues, err := encodeValues(users, func(f *UserFeatures) map[Definition]interface{} {
+		return map[Definition]interface{}{CategoryAffinity: f.CategoryAffinity, PriceSensitivity: f.PriceSensitivity}
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if err := s.store(ctx, map[string]map[string]map[string]json.RawMessage{
+		EntityService: serviceValues,
+		EntityUser:    userValues,
+	}); err != nil {
+		return 0, 0, err
+	}
+
+	s.refreshCache(ctx, EntityService, serviceValues)
+	s.refreshCache(ctx, EntityUser, userValues)
+	return len(users), len(services), nil
+}
+
+// registerDefinitions records every version of every feature computed
+func (s *Store) registerDefinitions(ctx context.Context) error {
+	for _, def := range Definitions {
+		_, err := s.pgPool.Exec(ctx, `
+			INSERT INTO feature_definitions (name, version, entity_type, description)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT (name, version) DO NOTHING
+		`, def.Name, def.Version, def.Entity, def.Description)
+		if err != nil {
+			return fmt.Errorf("failed to register feature %s: %w", def.Key(), err)
+		}
+	}
+	return nil
+}
+
+// computeServices computes the features of every active service. Prices
+// rank within categories, counting an unpriced service as free; providers'
+// ratings are averaged over their reviewed services.
+func (s *Store) computeServices(ctx context.Context) (map[string]*ServiceFeatures, error) {
+	rows, err := s.pgPool.Query(ctx, `
+		SELECT
+			id::text,
+			PERCENT_RANK() OVER (PARTITION BY category ORDER BY COALESCE(pricing_rate, 0)),
+			BOOL_OR(COALESCE(provider_verified, FALSE)) OVER (PARTITION BY provider_id),
+			AVG(avg_rating) FILTER (WHERE review_count > 0) OVER (PARTITION BY provider_id),
+			AVG(COALESCE(error_rate, 0)) OVER (PARTITION BY provider_id)
+		FROM services
+		WHERE status = 'active'
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query service features: %w", err)
+	}
+	defer rows.Close()
+
+	services := make(map[string]*ServiceFeatures)
+	for rows.Next() {
+		var id string
+		var p Provider
+		var rating *float64
+		f := &ServiceFeatures{}
+		if err := rows.Scan(&id, &f.PriceRank, &p.Verified, &rating, &p.ErrorRate); err != nil {
+			return nil, fmt.Errorf("failed to read service features: %w", err)
+		}
+		if rating != nil {
+			p.Rating, p.Rated = *rating, true
+		}
+		f.ProviderTrust = p.Trust()
+		services[id] = f
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read service features: %w", err)
+	}
+	return services, nil
+}
+
+// computeUsers computes the features of every user with interactions since
+// since, from their interactions by category. Users are keyed by pseudonym,
+// including those of rows not yet pseudonymized.
+func (s *Store) computeUsers(ctx context.Context, since time.Time) (map[string]*UserFeatures, error) {
+	rows, err := s.pgPool.Query(ctx, `
+		WITH ranked AS (
+			SELECT id, PERCENT_RANK() OVER (PARTITION BY category ORDER BY COALESCE(pricing_rate, 0)) AS price_rank
+			FROM services
+			WHERE status = 'active'
+		)
+		SELECT ui.user_id::text, ui.user_pseudonymized, s.category, COUNT(*), AVG(r.price_rank)
+		FROM user_interactions ui
+		JOIN services s ON s.id = ui.service_id
+		LEFT JOIN ranked r ON r.id = s.id
+		WHERE ui.timestamp >= $1
+		GROUP BY ui.user_id, ui.user_pseudonymized, s.category
+	`, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query user features: %w", err)
+	}
+	defer rows.Close()
+
+	usage := make(map[string][]CategoryUsage)
+	for rows.Next() {
+		var userID string
+		var pseudonymized bool
+		var u CategoryUsage
+		var priceRank *float64
+		if err := rows.Scan(&userID, &pseudonymized, &u.Category, &u.Interactions, &priceRank); err != nil {
+			return nil, fmt.Errorf("failed to read user features: %w", err)
+		}
+		if priceRank != nil {
+			u.PriceRank, u.Priced = *priceRank, true
+		}
+		userID = s.pseudonyms.Canonical(userID, pseudonymized)
+		usage[userID] = append(usage[userID], u)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read user features: %w", err)
+	}
+
+	users := make(map[string]*UserFeatures, len(usage))
+	for userID, u := range usage {
+		users[userID] = ComputeUser(u)
+	}
+	return users, nil
+}
+
+// encodeValues encodes each entity's features as JSON by definition key
+func encodeValues[F any](entities map[string]F, features func(F) map[Definition]interface{}) (map[string]map[string]json.RawMessage, error) {
+	encoded := make(map[string]map[string]json.RawMessage, len(entities))
+	for id, f := range entities {
+		raw := make(map[string]json.RawMessage)
+		for def, value := range features(f) {
+			data, err := json.Marshal(value)
+			if err != nil {
+				return nil, fmt.Errorf("failed to encode feature %s: %w", def.Key(), err)
+			}
+			raw[def.Key()] = data
+		}
+		encoded[id] = raw
+	}
+	return encoded, nil
+}
+
+// store replaces the stored feature values, by entity type, entity ID and
+// definition key, in one transaction. Values not recomputed, of entities
+// that lost their features or of other versions, are deleted.
+func (s *Store) store(ctx context.Context, values map[string]map[string]map[string]json.RawMessage) error {
+	tx, err := s.pgPool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var batch valueBatch
+	for entity, byID := range values {
+		for id, raw := range byID {
+			for key, value := range raw {
+				def, ok := definition(entity, key)
+				if !ok {
+					continue
+				}
+				batch.add(entity, id, def, value)
+				if len(batch.ids) == writeBatch {
+					if err := batch.write(ctx, tx); err != nil {
+						return err
+					}
+				}
+			}
+		}
+	}
+	if err := batch.write(ctx, tx); err != nil {
+		return err
+	}
+
+	// NOW() is the transaction's start, so every value written above is kept
+	if _, err := tx.Exec(ctx, `DELETE FROM feature_values WHERE computed_at < NOW()`); err != nil {
+		return fmt.Errorf("failed to delete stale features: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit features: %w", err)
+	}
+	return nil
+}
+
+// valueBatch collects feature values to upsert in one statement
+type valueBatch struct {
+	entities, ids, features, values []string
+	versions                        []int32
+}
+
+func (b *valueBatch) add(entity, id string, def Definition, value json.RawMessage) {
+	b.entities = append(b.entities, entity)
+	b.ids = append(b.ids, id)
+	b.features = append(b.features, def.Name)
+	b.versions = append(b.versions, int32(def.Version))
+	b.values = append(b.values, string(value))
+}
+
+// write upserts the batch, stamped with the transaction time, and empties it
+func (b *valueBatch) write(ctx context.Context, tx pgx.Tx) error {
+	if len(b.ids) == 0 {
+		return nil
+	}
+	_, err := tx.Exec(ctx, `
+		INSERT INTO feature_values (entity_type, entity_id, feature, version, value, computed_at)
+		SELECT e, i, f, v, x::jsonb, NOW()
+		FROM unnest($1::text[], $2::text[], $3::text[], $4::int[], $5::text[]) AS t(e, i, f, v, x)
+		ON CONFLICT (entity_type, entity_id, feature, version) DO UPDATE SET
+			value = EXCLUDED.value,
+			computed_at = EXCLUDED.computed_at
+	`, b.entities, b.ids, b.features, b.versions, b.values)
+	if err != nil {
+		return fmt.Errorf("failed to upsert features: %w", err)
+	}
+	*b = valueBatch{}
+	return nil
+}
+
+// refreshCache replaces the cached features of the entities, in batches
+func (s *Store) refreshCache(ctx context.Context, entity string, values map[string]map[string]json.RawMessage) {
+	ids := make([]string, 0, writeBatch)
+	for id := range values {
+		ids = append(ids, id)
+		if len(ids) == writeBatch {
+			s.cacheValues(ctx, entity, ids, values)
+			ids = ids[:0]
+		}
+	}
+	if len(ids) > 0 {
+		s.cacheValues(ctx, entity, ids, values)
+	}
+}
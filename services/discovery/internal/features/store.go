@@ -0,0 +1,241 @@
+package features
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/org/llm-marketplace/services/discovery/internal/config"
+	"github.com/org/llm-marketplace/services/discovery/internal/postgres"
+	"github.com/org/llm-marketplace/services/discovery/internal/privacy"
+	"go.uber.org/zap"
+)
+
+// emptyField marks an entity cached without features, so that it isn't
+// looked up in Postgres again until the cache expires
+const emptyField = "none"
+
+// Store serves precomputed features and, from Start, keeps them up to date
+type Store struct {
+	pgPool      *postgres.Pool
+	redisClient *redis.Client
+	config      config.FeaturesConfig
+	pseudonyms  *privacy.Pseudonymizer // Users' features are stored under their pseudonyms
+	logger      *zap.Logger
+}
+
+func NewStore(
+	pgPool *postgres.Pool,
+	redisClient *redis.Client,
+	cfg config.FeaturesConfig,
+	pseudonyms *privacy.Pseudonymizer,
+	logger *zap.Logger,
+) *Store {
+	return &Store{
+		pgPool:      pgPool,
+		redisClient: redisClient,
+		config:      cfg,
+		pseudonyms:  pseudonyms,
+		logger:      logger,
+	}
+}
+
+// Enabled reports whether features are computed and served
+func (s *Store) Enabled() bool {
+	return s != nil && s.config.Enabled
+}
+
+// Weights are the configured weights of the features in scores
+func (s *Store) Weights() Weights {
+	return Weights{
+		AffinityBoost: s.config.AffinityBoost,
+		PriceWeight:   s.config.PriceWeight,
+		TrustWeight:   s.config.TrustWeight,
+	}
+}
+
+// User returns the user's features, or nil when the user has none or the
+// store is disabled
+func (s *Store) User(ctx context.Context, userID string) (*UserFeatures, error) {
+	if !s.Enabled() || userID == "" {
+		return nil, nil
+	}
+	userID = s.pseudonyms.Pseudonym(userID)
+	values, err := s.load(ctx, EntityUser, []string{userID})
+	if err != nil {
+		return nil, err
+	}
+	raw := values[userID]
+	if len(raw) == 0 {
+		return nil, nil
+	}
+
+	f := &UserFeatures{}
+	if err := decode(raw, CategoryAffinity, &f.CategoryAffinity); err != nil {
+		return nil, err
+	}
+	if err := decode(raw, PriceSensitivity, &f.PriceSensitivity); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Services returns the features of the services by ID. Services without
+// features are left out, and all are while the store is disabled.
+func (s *Store) Services(ctx context.Context, ids []string) (map[string]*ServiceFeatures, error) {
+	if !s.Enabled() || len(ids) == 0 {
+		return nil, nil
+	}
+	values, err := s.load(ctx, EntityService, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	services := make(map[string]*ServiceFeatures, len(values))
+	for id, raw := range values {
+		if len(raw) == 0 {
+			continue
+		}
+		f := &ServiceFeatures{}
+		if err := decode(raw, ProviderTrust, &f.ProviderTrust); err != nil {
+			return nil, err
+		}
+		if err := decode(raw, PriceRank, &f.PriceRank); err != nil {
+			return nil, err
+		}
+		services[id] = f
+	}
+	return services, nil
+}
+
+// decode reads the value of def into v, leaving v as it is when there is none
+func decode(raw map[string]json.RawMessage, def Definition, v interface{}) error {
+	value, ok := raw[def.Key()]
+	if !ok {
+		return nil
+	}
+	if err := json.Unmarshal(value, v); err != nil {
+		return fmt.Errorf("failed to decode feature %s: %w", def.Key(), err)
+	}
+	return nil
+}
+
+func cacheKey(entity, id string) string {
+	return "features:" + entity + ":" + id
+}
+
+// definition returns the current definition of one of the entity's
+// features by key. Keys of other versions have none.
+func definition(entity, key string) (Definition, bool) {
+	for _, def := range Definitions {
+		if def.Entity == entity && def.Key() == key {
+			return def, true
+		}
+	}
+	return Definition{}, false
+}
+
+// load returns the entities' current feature values, by entity ID then
+// definition key. They are read from Redis, and from Postgres for the
+// entities not cached, which are cached on the way out. Postgres answers
+// for every entity when Redis can't be read.
+func (s *Store) load(ctx context.Context, entity string, ids []string) (map[string]map[string]json.RawMessage, error) {
+	values := make(map[string]map[string]json.RawMessage, len(ids))
+
+	pipe := s.redisClient.Pipeline()
+	cmds := make([]*redis.StringStringMapCmd, len(ids))
+	for i, id := range ids {
+		cmds[i] = pipe.HGetAll(ctx, cacheKey(entity, id))
+	}
+	missing := ids
+	if _, err := pipe.Exec(ctx); err != nil {
+		s.logger.Warn("Failed to read cached features", zap.String("entity", entity), zap.Error(err))
+	} else {
+		missing = nil
+		for i, id := range ids {
+			fields := cmds[i].Val()
+			if len(fields) == 0 {
+				missing = append(missing, id)
+				continue
+			}
+			raw := make(map[string]json.RawMessage, len(fields))
+			for key, value := range fields {
+				if _, ok := definition(entity, key); ok {
+					raw[key] = json.RawMessage(value)
+				}
+			}
+			values[id] = raw
+		}
+	}
+	if len(missing) == 0 {
+		return values, nil
+	}
+
+	stored, err := s.loadStored(ctx, entity, missing)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range missing {
+		values[id] = stored[id]
+	}
+	s.cacheValues(ctx, entity, missing, stored)
+	return values, nil
+}
+
+// loadStored reads the entities' current feature values from Postgres
+func (s *Store) loadStored(ctx context.Context, entity string, ids []string) (map[string]map[string]json.RawMessage, error) {
+	rows, err := s.pgPool.Query(ctx, `
+		SELECT entity_id, feature, version, value
+		FROM feature_values
+		WHERE entity_type = $1 AND entity_id = ANY($2)
+	`, entity, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query features: %w", err)
+	}
+	defer rows.Close()
+
+	stored := make(map[string]map[string]json.RawMessage)
+	for rows.Next() {
+		var id, feature string
+		var version int
+		var value []byte
+		if err := rows.Scan(&id, &feature, &version, &value); err != nil {
+			return nil, fmt.Errorf("failed to read feature: %w", err)
+		}
+		key := Definition{Name: feature, Version: version}.Key()
+		if _, ok := definition(entity, key); !ok {
+			continue
+		}
+		if stored[id] == nil {
+			stored[id] = make(map[string]json.RawMessage)
+		}
+		stored[id][key] = value
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read features: %w", err)
+	}
+	return stored, nil
+}
+
+// cacheValues replaces the cached features of the entities with values.
+// Entities without values are cached as such.
+func (s *Store) cacheValues(ctx context.Context, entity string, ids []string, values map[string]map[string]json.RawMessage) {
+	pipe := s.redisClient.Pipeline()
+	for _, id := range ids {
+		key := cacheKey(entity, id)
+		fields := map[string]interface{}{emptyField: "1"}
+		if raw := values[id]; len(raw) > 0 {
+			fields = make(map[string]interface{}, len(raw))
+			for k, v := range raw {
+				fields[k] = []byte(v)
+			}
+		}
+		pipe.Del(ctx, key)
+		pipe.HSet(ctx, key, fields)
+		pipe.Expire(ctx, key, s.config.CacheTTL)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		s.logger.Warn("Failed to cache features", zap.String("entity", entity), zap.Error(err))
+	}
+}
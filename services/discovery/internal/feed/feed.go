@@ -0,0 +1,222 @@
+// Package feed serves partner marketplaces the catalog changes made since a
+// cursor, as each partner is entitled to see them
+package feed
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/org/llm-marketplace/services/discovery/internal/config"
+	"github.com/org/llm-marketplace/services/discovery/internal/elasticsearch"
+	"github.com/org/llm-marketplace/services/discovery/internal/entitlement"
+	"github.com/org/llm-marketplace/services/discovery/internal/search"
+	"go.uber.org/zap"
+)
+
+// Change operations
+const (
+	OpUpsert = "upsert"
+	OpDelete = "delete"
+)
+
+var (
+	// ErrInvalidRequest is returned for malformed cursors and limits
+	ErrInvalidRequest = errors.New("invalid feed request")
+	// ErrCursorExpired is returned when changes after a cursor have been pruned
+	ErrCursorExpired = errors.New("feed cursor has expired")
+)
+
+// settleDelay holds back the newest writes. Sequence numbers are taken
+// before a write commits, so a later one can become visible first; a page
+// ending there would move the cursor past the earlier one.
+const settleDelay = 2 * time.Second
+
+// Catalog decides whether a service is in the caller's tenant catalog;
+// *elasticsearch.Client satisfies it
+type Catalog interface {
+	InTenant(ctx context.Context, doc *elasticsearch.ServiceDocument) bool
+}
+
+// Change is a service to copy or remove. Several writes to a service within
+// one page are sent as a single change.
+type Change struct {
+	Op        string      `json:"op"`
+	ServiceID string      `json:"service_id"`
+	ChangedAt time.Time   `json:"changed_at"`
+	Service   interface{} `json:"service,omitempty"` // Upserts only, limited to the requested fields
+}
+
+// Page is a run of changes and the cursor to poll from next
+type Page struct {
+	Changes []Change `json:"changes"`
+	Cursor  string   `json:"cursor"`
+	HasMore bool     `json:"has_more"`
+}
+
+// Feed records catalog writes and serves them as incremental changes, as
+// each caller is entitled to see them
+type Feed struct {
+	store   Store
+	catalog Catalog
+	config  config.FeedConfig
+	logger  *zap.Logger
+}
+
+// NewFeed creates a feed over store
+func NewFeed(store Store, catalog Catalog, cfg config.FeedConfig, logger *zap.Logger) *Feed {
+	return &Feed{store: store, catalog: catalog, config: cfg, logger: logger}
+}
+
+// ServiceChanged records a catalog write
+func (f *Feed) ServiceChanged(ctx context.Context, previous, current *elasticsearch.ServiceDocument) {
+	// The write has already happened; don't lose it to a cancelled request
+	ctx = context.WithoutCancel(ctx)
+
+	service := *current
+	service.Embedding = nil
+	service.Embeddings = nil
+
+	rec := &Record{Service: &service}
+	if previous != nil {
+		rec.Existed = true
+		rec.PreviousTenantID = previous.TenantID
+		rec.PreviousAccess = previous.Access
+	}
+
+	if err := f.store.Append(ctx, rec); err != nil {
+		f.logger.Error("Failed to record catalog change", zap.String("service_id", current.ID), zap.Error(err))
+	}
+}
+
+// Changes returns up to limit changes after cursor, 0 meaning the default
+// page size. Without a cursor it returns no changes and the cursor of the
+// latest write, to follow the catalog from.
+func (f *Feed) Changes(ctx context.Context, cursor string, limit int, fields []string) (*Page, error) {
+	if limit == 0 {
+		limit = f.config.PageSize
+	}
+	if limit < 1 || limit > f.config.MaxPageSize {
+		return nil, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidRequest, f.config.MaxPageSize)
+	}
+
+	prunedThrough, latest, err := f.store.Bounds(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if cursor == "" {
+		return &Page{Changes: []Change{}, Cursor: formatCursor(latest)}, nil
+	}
+
+	after, err := strconv.ParseInt(cursor, 10, 64)
+	if err != nil || after < 0 || after > latest {
+		return nil, fmt.Errorf("%w: unknown cursor", ErrInvalidRequest)
+	}
+	if after < prunedThrough {
+		return nil, ErrCursorExpired
+	}
+
+	records, err := f.store.After(ctx, after, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &Page{Changes: []Change{}, Cursor: cursor, HasMore: len(records) == limit}
+	settled := time.Now().Add(-settleDelay)
+	for i, rec := range records {
+		if rec.ChangedAt.After(settled) {
+			records, page.HasMore = records[:i], false
+			break
+		}
+	}
+	if len(records) == 0 {
+		return page, nil
+	}
+	page.Cursor = formatCursor(records[len(records)-1].Seq)
+
+	// The first write to a service in the page says what the caller saw
+	// before it, the last one what it sees now
+	first := make(map[string]*Record)
+	last := make(map[string]int)
+	for i, rec := range records {
+		if _, ok := first[rec.Service.ID]; !ok {
+			first[rec.Service.ID] = rec
+		}
+		last[rec.Service.ID] = i
+	}
+
+	for i, rec := range records {
+		if last[rec.Service.ID] != i {
+			continue
+		}
+
+		before := first[rec.Service.ID]
+		sawBefore := before.Existed && f.visible(ctx, &elasticsearch.ServiceDocument{
+			TenantID: before.PreviousTenantID,
+			Access:   before.PreviousAccess,
+		})
+
+		change := Change{ServiceID: rec.Service.ID, ChangedAt: rec.ChangedAt}
+		switch {
+		case f.visible(ctx, rec.Service):
+			change.Op = OpUpsert
+			if change.Service, err = render(rec.Service, fields); err != nil {
+				return nil, err
+			}
+		case sawBefore:
+			// Access was withdrawn
+			change.Op = OpDelete
+		default:
+			continue
+		}
+		page.Changes = append(page.Changes, change)
+	}
+
+	return page, nil
+}
+
+// Start prunes changes older than the retention period until ctx is cancelled
+func (f *Feed) Start(ctx context.Context) {
+	ticker := time.NewTicker(f.config.PruneInterval)
+	defer ticker.Stop()
+
+	f.logger.Info("Catalog change pruning started", zap.Duration("retention", f.config.Retention))
+
+	for {
+		select {
+		case <-ctx.Done():
+			f.logger.Info("Catalog change pruning stopped")
+			return
+		case <-ticker.C:
+			if err := f.store.Prune(ctx, time.Now().Add(-f.config.Retention)); err != nil {
+				f.logger.Error("Failed to prune catalog changes", zap.Error(err))
+			}
+		}
+	}
+}
+
+// visible reports whether the caller in ctx may see doc
+func (f *Feed) visible(ctx context.Context, doc *elasticsearch.ServiceDocument) bool {
+	return f.catalog.InTenant(ctx, doc) && entitlement.FromContext(ctx).CanView(doc.Access)
+}
+
+// render drops what partners must not see: who else may see the service,
+// the tenant it belongs to and internal metadata
+func render(doc *elasticsearch.ServiceDocument, fields []string) (interface{}, error) {
+	redacted := *doc
+	redacted.Access = nil
+	redacted.TenantID = ""
+	redacted.Metadata = nil
+	redacted.EmbeddingModels = nil
+
+	if len(fields) == 0 {
+		return &redacted, nil
+	}
+	return search.ProjectDocument(&redacted, fields)
+}
+
+func formatCursor(seq int64) string {
+	return strconv.FormatInt(seq, 10)
+}
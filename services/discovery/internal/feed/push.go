@@ -0,0 +1,196 @@
+package feed
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/org/llm-marketplace/services/discovery/internal/config"
+	"github.com/org/llm-marketplace/services/discovery/internal/entitlement"
+	"github.com/org/llm-marketplace/services/discovery/internal/observability"
+	"github.com/org/llm-marketplace/services/discovery/internal/search"
+	"github.com/org/llm-marketplace/services/discovery/internal/webhook"
+	"go.uber.org/zap"
+)
+
+// Push results
+const (
+	PushSucceeded = "succeeded"
+	PushFailed    = "failed"
+	PushExpired   = "expired"
+)
+
+// Pusher posts each configured partner the changes since its last push.
+// A partner's cursor is kept in Redis and only moves once the partner has
+// accepted a page, so failed pushes are sent again.
+type Pusher struct {
+	feed        *Feed
+	redisClient *redis.Client
+	partners    []config.FeedPartner
+	httpClient  *http.Client
+	logger      *zap.Logger
+	metrics     *observability.Metrics
+}
+
+// NewPusher creates a pusher for the partners in cfg
+func NewPusher(
+	feed *Feed,
+	redisClient *redis.Client,
+	cfg config.FeedConfig,
+	logger *zap.Logger,
+	metrics *observability.Metrics,
+) (*Pusher, error) {
+	for _, partner := range cfg.Partners {
+		if err := search.ValidateFields(partner.Fields); err != nil {
+			return nil, fmt.Errorf("feed partner %s: %w", partner.Name, err)
+		}
+	}
+
+	return &Pusher{
+		feed:        feed,
+		redisClient: redisClient,
+		partners:    cfg.Partners,
+		httpClient: &http.Client{
+			Timeout: cfg.PushTimeout,
+			// Partners must answer directly; redirects could point anywhere
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		logger:  logger,
+		metrics: metrics,
+	}, nil
+}
+
+// Start pushes to every partner on its interval until ctx is cancelled
+func (p *Pusher) Start(ctx context.Context) {
+	if len(p.partners) == 0 {
+		p.logger.Info("No feed partners to push to")
+		return
+	}
+
+	var wg sync.WaitGroup
+	for _, partner := range p.partners {
+		wg.Add(1)
+		go func(partner config.FeedPartner) {
+			defer wg.Done()
+			p.run(ctx, partner)
+		}(partner)
+	}
+	wg.Wait()
+}
+
+func (p *Pusher) run(ctx context.Context, partner config.FeedPartner) {
+	ticker := time.NewTicker(partner.Interval)
+	defer ticker.Stop()
+
+	p.logger.Info("Feed push started", zap.String("partner", partner.Name), zap.Duration("interval", partner.Interval))
+
+	for {
+		select {
+		case <-ctx.Done():
+			p.logger.Info("Feed push stopped", zap.String("partner", partner.Name))
+			return
+		case <-ticker.C:
+			p.Push(ctx, partner)
+		}
+	}
+}
+
+// Push sends the partner every page of changes since its cursor. The first
+// push only records the cursor of the latest write.
+func (p *Pusher) Push(ctx context.Context, partner config.FeedPartner) {
+	// One replica pushes to a partner at a time
+	lock := pushKey(partner.Name, "lock")
+	claimed, err := p.redisClient.SetNX(ctx, lock, 1, partner.Interval).Result()
+	if err != nil {
+		p.logger.Error("Failed to claim feed push", zap.String("partner", partner.Name), zap.Error(err))
+		return
+	}
+	if !claimed {
+		return
+	}
+	defer p.redisClient.Del(context.WithoutCancel(ctx), lock)
+
+	ctx = entitlement.WithCaller(ctx, entitlement.Caller{TenantID: partner.TenantID, UserID: partner.UserID})
+	cursorKey := pushKey(partner.Name, "cursor")
+	cursor, err := p.redisClient.Get(ctx, cursorKey).Result()
+	if err != nil && err != redis.Nil {
+		p.logger.Error("Failed to load feed cursor", zap.String("partner", partner.Name), zap.Error(err))
+		return
+	}
+
+	for {
+		page, err := p.feed.Changes(ctx, cursor, 0, partner.Fields)
+		if errors.Is(err, ErrCursorExpired) {
+			p.metrics.FeedPush(PushExpired)
+			p.logger.Error("Feed partner cursor has expired; the partner needs a full copy before its cursor is reset",
+				zap.String("partner", partner.Name),
+				zap.String("cursor", cursor),
+			)
+			return
+		}
+		if err != nil {
+			p.logger.Error("Failed to read feed changes", zap.String("partner", partner.Name), zap.Error(err))
+			return
+		}
+
+		if len(page.Changes) > 0 {
+			if err := p.send(ctx, partner, page); err != nil {
+				p.metrics.FeedPush(PushFailed)
+				p.logger.Warn("Feed push failed", zap.String("partner", partner.Name), zap.Error(err))
+				return
+			}
+			p.metrics.FeedPush(PushSucceeded)
+		}
+
+		if page.Cursor != cursor {
+			if err := p.redisClient.Set(ctx, cursorKey, page.Cursor, 0).Err(); err != nil {
+				p.logger.Error("Failed to save feed cursor", zap.String("partner", partner.Name), zap.Error(err))
+				return
+			}
+			cursor = page.Cursor
+		}
+		if !page.HasMore {
+			return
+		}
+	}
+}
+
+func (p *Pusher) send(ctx context.Context, partner config.FeedPartner, page *Page) error {
+	body, err := json.Marshal(page)
+	if err != nil {
+		return fmt.Errorf("failed to encode feed page: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, partner.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "llm-marketplace-feed/1.0")
+	req.Header.Set(webhook.HeaderSignature, webhook.Sign(partner.Secret, time.Now(), body))
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("partner returned %s", resp.Status)
+	}
+	return nil
+}
+
+func pushKey(partner, name string) string {
+	return "feed:push:" + partner + ":" + name
+}
@@ -0,0 +1,142 @@
+package feed
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/org/llm-marketplace/services/discovery/internal/elasticsearch"
+	"github.com/org/llm-marketplace/services/discovery/internal/postgres"
+)
+
+// Record is one catalog write as the feed stores it
+type Record struct {
+	Seq     int64
+	Service *elasticsearch.ServiceDocument // As written, without vectors
+	// Existed is false for new services. Otherwise the tenant and access
+	// rules the service had before the write decide who saw it until then.
+	Existed          bool
+	PreviousTenantID string
+	PreviousAccess   *elasticsearch.AccessInfo
+	ChangedAt        time.Time
+}
+
+// Store keeps the catalog writes the feed serves
+type Store interface {
+	// Append records a write, assigning its sequence number
+	Append(ctx context.Context, rec *Record) error
+	// After returns up to limit records with a later sequence number, oldest first
+	After(ctx context.Context, seq int64, limit int) ([]*Record, error)
+	// Bounds returns the last sequence number pruned and the latest one
+	Bounds(ctx context.Context) (prunedThrough, latest int64, err error)
+	// Prune removes records made before the given time
+	Prune(ctx context.Context, before time.Time) error
+}
+
+// PGStore keeps catalog writes in the catalog_changes table
+type PGStore struct {
+	pgPool *postgres.Pool
+}
+
+// NewStore creates a store over pgPool
+func NewStore(pgPool *postgres.Pool) *PGStore {
+	return &PGStore{pgPool: pgPool}
+}
+
+// Append inserts rec and sets its sequence number and time
+func (s *PGStore) Append(ctx context.Context, rec *Record) error {
+	document, err := json.Marshal(rec.Service)
+	if err != nil {
+		return fmt.Errorf("failed to encode service: %w", err)
+	}
+	var access []byte
+	if rec.PreviousAccess != nil {
+		if access, err = json.Marshal(rec.PreviousAccess); err != nil {
+			return fmt.Errorf("failed to encode access: %w", err)
+		}
+	}
+
+	query := `
+		INSERT INTO catalog_changes (service_id, document, existed, previous_tenant_id, previous_access)
+		VALUES ($1, $2, $3, NULLIF($4, ''), $5)
+		RETURNING seq, changed_at
+	`
+
+	err = s.pgPool.QueryRow(ctx, query, rec.Service.ID, document, rec.Existed, rec.PreviousTenantID, access).
+		Scan(&rec.Seq, &rec.ChangedAt)
+	if err != nil {
+		return fmt.Errorf("failed to record catalog change: %w", err)
+	}
+	return nil
+}
+
+// After returns the records following seq
+func (s *PGStore) After(ctx context.Context, seq int64, limit int) ([]*Record, error) {
+	query := `
+		SELECT seq, document, existed, COALESCE(previous_tenant_id, ''), previous_access, changed_at
+		FROM catalog_changes
+		WHERE seq > $1
+		ORDER BY seq
+		LIMIT $2
+	`
+
+	rows, err := s.pgPool.Query(ctx, query, seq, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read catalog changes: %w", err)
+	}
+	defer rows.Close()
+
+	var records []*Record
+	for rows.Next() {
+		var rec Record
+		var document, access []byte
+		if err := rows.Scan(&rec.Seq, &document, &rec.Existed, &rec.PreviousTenantID, &access, &rec.ChangedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan catalog change: %w", err)
+		}
+		if err := json.Unmarshal(document, &rec.Service); err != nil {
+			return nil, fmt.Errorf("failed to decode catalog change %d: %w", rec.Seq, err)
+		}
+		if access != nil {
+			if err := json.Unmarshal(access, &rec.PreviousAccess); err != nil {
+				return nil, fmt.Errorf("failed to decode catalog change %d: %w", rec.Seq, err)
+			}
+		}
+		records = append(records, &rec)
+	}
+	return records, rows.Err()
+}
+
+// Bounds reads the pruned watermark and the latest sequence number
+func (s *PGStore) Bounds(ctx context.Context) (int64, int64, error) {
+	query := `
+		SELECT
+			COALESCE((SELECT pruned_through FROM catalog_changes_pruned), 0),
+			COALESCE((SELECT MAX(seq) FROM catalog_changes), 0)
+	`
+
+	var prunedThrough, latest int64
+	if err := s.pgPool.QueryRow(ctx, query).Scan(&prunedThrough, &latest); err != nil {
+		return 0, 0, fmt.Errorf("failed to read catalog change bounds: %w", err)
+	}
+	// Everything may have been pruned
+	return prunedThrough, max(prunedThrough, latest), nil
+}
+
+// Prune deletes old records and moves the watermark past them
+func (s *PGStore) Prune(ctx context.Context, before time.Time) error {
+	query := `
+		WITH pruned AS (
+			DELETE FROM catalog_changes WHERE changed_at < $1 RETURNING seq
+		)
+		INSERT INTO catalog_changes_pruned (id, pruned_through)
+		SELECT TRUE, MAX(seq) FROM pruned HAVING COUNT(*) > 0
+		ON CONFLICT (id) DO UPDATE SET
+			pruned_through = GREATEST(catalog_changes_pruned.pruned_through, EXCLUDED.pruned_through)
+	`
+
+	if _, err := s.pgPool.Exec(ctx, query, before); err != nil {
+		return fmt.Errorf("failed to prune catalog changes: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,354 @@
+// Package fixtures generates a realistic catalog of services, prompt
+// templates, datasets and fine-tuning offerings, with the users who interact
+// with them, for integration, relevance and load tests and for seeding a
+// development environment. The same seed always yields the same catalog.
+package fixtures
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/org/llm-marketplace/pkg/marketplace"
+	"github.com/org/llm-marketplace/services/discovery/internal/elasticsearch"
+)
+
+// Options sets the size and shape of a generated catalog
+type Options struct {
+	Seed      uint64
+	Services  int       // Items in the catalog, of every kind
+	Providers int       // Providers the items are spread across; Services/5 when unset
+	Users     int       // Users with interactions; none when unset
+	Now       time.Time // Latest creation and interaction time; a fixed date when unset, so catalogs are identical across runs
+
+	// Dimensions sizes each item's embedding. Items of a category have
+	// vectors near its own direction, so semantic search groups them. No
+	// embeddings are generated when it is 0.
+	Dimensions int
+	// EmbeddingModel is recorded as the model behind the embeddings, as
+	// embedding:<model>@<dimensions>, so the backfill worker leaves them be.
+	// Without it, the worker replaces them with real embeddings.
+	EmbeddingModel string
+}
+
+// Catalog is a generated catalog
+type Catalog struct {
+	Services     []*elasticsearch.ServiceDocument
+	Interactions []Interaction
+}
+
+// Interaction is one thing a user did with an item, as recorded in
+// user_interactions
+type Interaction struct {
+	UserID    string    `json:"user_id"`
+	ServiceID string    `json:"service_id"`
+	Type      string    `json:"interaction_type"` // view, download, rate, consume or favorite
+	Rating    float64   `json:"rating,omitempty"` // 1 to 5 on rate interactions
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// epoch is the default Options.Now
+var epoch = time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// Generate builds the catalog opts describe
+func Generate(opts Options) *Catalog {
+	g := &generator{
+		rng:  rand.New(rand.NewPCG(opts.Seed, opts.Seed^0x9e3779b97f4a7c15)),
+		opts: opts,
+	}
+	if g.opts.Now.IsZero() {
+		g.opts.Now = epoch
+	}
+	if g.opts.Providers <= 0 {
+		g.opts.Providers = max(1, opts.Services/5)
+	}
+
+	providers := g.providers()
+	centroids := g.centroids()
+
+	catalog := &Catalog{Services: make([]*elasticsearch.ServiceDocument, opts.Services)}
+	for i := range catalog.Services {
+		catalog.Services[i] = g.item(i, providers[g.rng.IntN(len(providers))], centroids)
+	}
+	catalog.Interactions = g.interactions(catalog.Services)
+	return catalog
+}
+
+type generator struct {
+	rng  *rand.Rand
+	opts Options
+}
+
+// id returns a version 4 UUID drawn from the generator, as Postgres stores
+// service and user IDs as UUIDs
+func (g *generator) id() string {
+	hi, lo := g.rng.Uint64(), g.rng.Uint64()
+	hi = hi&^0xf000 | 0x4000
+	lo = lo&^(0xc<<60) | 0x8<<60
+	return fmt.Sprintf("%08x-%04x-%04x-%04x-%012x", hi>>32, hi>>16&0xffff, hi&0xffff, lo>>48, lo&0xffffffffffff)
+}
+
+func (g *generator) pick(values []string) string {
+	return values[g.rng.IntN(len(values))]
+}
+
+// some returns between lo and hi distinct values, in their listed order
+func (g *generator) some(values []string, lo, hi int) []string {
+	n := lo + g.rng.IntN(hi-lo+1)
+	picked := make([]string, 0, n)
+	for _, i := range g.rng.Perm(len(values))[:min(n, len(values))] {
+		picked = append(picked, values[i])
+	}
+	slices.SortFunc(picked, func(a, b string) int {
+		return slices.Index(values, a) - slices.Index(values, b)
+	})
+	return picked
+}
+
+// between returns a value uniformly in [lo, hi), rounded to places decimals
+func (g *generator) between(lo, hi float64, places int) float64 {
+	scale := math.Pow(10, float64(places))
+	return math.Round((lo+g.rng.Float64()*(hi-lo))*scale) / scale
+}
+
+func (g *generator) providers() []elasticsearch.ProviderInfo {
+	providers := make([]elasticsearch.ProviderInfo, g.opts.Providers)
+	for i := range providers {
+		name := fmt.Sprintf("%s %s", g.pick(providerPrefixes), g.pick(providerSuffixes))
+		providers[i] = elasticsearch.ProviderInfo{ID: g.id(), Name: name, Verified: g.rng.Float64() < 0.6}
+	}
+	return providers
+}
+
+// centroids gives each category a random unit direction its items'
+// embeddings cluster around
+func (g *generator) centroids() map[string][]float32 {
+	if g.opts.Dimensions <= 0 {
+		return nil
+	}
+	centroids := make(map[string][]float32, len(categories))
+	for _, c := range categories {
+		centroids[c.name] = g.unit(nil, 0)
+	}
+	return centroids
+}
+
+// unit returns a random unit vector, near center when one is given
+func (g *generator) unit(center []float32, spread float64) []float32 {
+	v := make([]float32, g.opts.Dimensions)
+	var norm float64
+	for i := range v {
+		x := g.rng.NormFloat64()
+		if center != nil {
+			x = float64(center[i]) + spread*x/math.Sqrt(float64(len(v)))
+		}
+		v[i] = float32(x)
+		norm += x * x
+	}
+	norm = math.Sqrt(norm)
+	for i := range v {
+		v[i] = float32(float64(v[i]) / norm)
+	}
+	return v
+}
+
+// item generates the i'th item. Of every ten, one is a prompt template, one
+// a dataset and one a fine-tuning offering; the rest are services.
+func (g *generator) item(i int, provider elasticsearch.ProviderInfo, centroids map[string][]float32) *elasticsearch.ServiceDocument {
+	cat := categories[g.rng.IntN(len(categories))]
+	created := g.opts.Now.Add(-time.Duration(g.rng.IntN(365*24)) * time.Hour)
+	updated := created.Add(time.Duration(g.rng.Int64N(int64(g.opts.Now.Sub(created)/time.Minute)+1)) * time.Minute)
+
+	doc := &elasticsearch.ServiceDocument{
+		ID:             g.id(),
+		Name:           fmt.Sprintf("%s %s", g.pick(namePrefixes), g.pick(cat.nouns)),
+		Description:    fmt.Sprintf("%s %s", g.pick(cat.descriptions), g.pick(descriptionTails)),
+		Category:       cat.name,
+		Tags:           g.some(cat.tags, 2, 4),
+		Provider:       provider,
+		Capabilities:   g.some(cat.capabilities, 1, 3),
+		Pricing:        g.pricing(),
+		SLA:            g.sla(),
+		Compliance:     g.compliance(),
+		Status:         g.status(),
+		ServingRegions: g.regions(),
+		Metrics:        g.metrics(),
+		CreatedAt:      created,
+		UpdatedAt:      updated,
+	}
+	doc.Version = &elasticsearch.VersionInfo{Number: fmt.Sprintf("%d.%d.0", 1+g.rng.IntN(3), g.rng.IntN(10)), Stable: true, Latest: true, ReleasedAt: updated}
+
+	switch i % 10 {
+	case 7:
+		doc.Kind = marketplace.KindPromptTemplate
+		doc.PromptTemplate = &marketplace.PromptTemplate{
+			Template:      fmt.Sprintf("You are an expert in %s. %s {{input}} in a {{tone}} tone.", cat.name, g.pick(cat.descriptions)),
+			Variables:     []marketplace.TemplateVariable{{Name: "input", Required: true}, {Name: "tone", Default: "neutral"}},
+			ModelFamilies: g.some(modelFamilies, 1, 3),
+		}
+		doc.Pricing = elasticsearch.PricingInfo{Model: "free", Unit: "request"}
+	case 8:
+		doc.Kind = marketplace.KindDataset
+		doc.Dataset = &marketplace.DatasetInfo{
+			License:   g.pick(licenses),
+			Format:    g.pick(datasetFormats),
+			SizeBytes: int64(1+g.rng.IntN(500)) << 20,
+			Records:   int64(1000 * (1 + g.rng.IntN(5000))),
+		}
+	case 9:
+		doc.Kind = marketplace.KindFineTuning
+		doc.FineTuning = &marketplace.FineTuningOffering{
+			BaseModels:      g.some(baseModels, 1, 3),
+			TurnaroundHours: int32(6 * (1 + g.rng.IntN(12))),
+		}
+	}
+
+	if centroid := centroids[cat.name]; centroid != nil {
+		doc.Embedding = g.unit(centroid, 0.5)
+		if g.opts.EmbeddingModel != "" {
+			doc.EmbeddingModels = []string{fmt.Sprintf("embedding:%s@%d", g.opts.EmbeddingModel, g.opts.Dimensions)}
+		}
+	}
+	return doc
+}
+
+// pricing picks one of the marketplace's pricing shapes: free, a flat rate
+// per 1K tokens or per request, volume tiers, or a subscription
+func (g *generator) pricing() elasticsearch.PricingInfo {
+	switch r := g.rng.Float64(); {
+	case r < 0.1:
+		return elasticsearch.PricingInfo{Model: "free", Unit: "1k tokens"}
+	case r < 0.55:
+		return elasticsearch.PricingInfo{Model: "per-token", Rate: g.between(0.0001, 0.06, 4), Unit: "1k tokens", Currency: "USD"}
+	case r < 0.75:
+		return elasticsearch.PricingInfo{Model: "per-request", Rate: g.between(0.001, 0.05, 3), Unit: "request", Currency: "USD"}
+	case r < 0.9:
+		base := g.between(0.001, 0.03, 4)
+		return elasticsearch.PricingInfo{
+			Model: "tiered", Rate: base, Unit: "1k tokens", Currency: "USD",
+			Tiers: []marketplace.PricingTier{
+				{Tier: "starter", Rate: base, Unit: "1k tokens", UpTo: 1_000_000},
+				{Tier: "growth", Rate: math.Round(base*0.8*10000) / 10000, Unit: "1k tokens", UpTo: 50_000_000},
+				{Tier: "scale", Rate: math.Round(base*0.6*10000) / 10000, Unit: "1k tokens"},
+			},
+		}
+	default:
+		return elasticsearch.PricingInfo{Model: "subscription", Rate: float64(10 * (1 + g.rng.IntN(50))), Unit: "month", Currency: "USD"}
+	}
+}
+
+func (g *generator) sla() elasticsearch.SLAInfo {
+	return elasticsearch.SLAInfo{
+		Availability: availabilities[g.rng.IntN(len(availabilities))],
+		MaxLatencyMS: 100 * (1 + g.rng.IntN(20)),
+		SupportLevel: g.pick(supportLevels),
+	}
+}
+
+// compliance picks one of the compliance profiles
+func (g *generator) compliance() elasticsearch.ComplianceInfo {
+	p := complianceProfiles[g.rng.IntN(len(complianceProfiles))]
+	return elasticsearch.ComplianceInfo{
+		Level:          p.level,
+		Certifications: slices.Clone(p.certifications),
+		DataResidency:  g.some(p.residency, 1, len(p.residency)),
+		GDPRCompliant:  p.gdpr,
+		HIPAACompliant: p.hipaa && g.rng.Float64() < 0.5,
+	}
+}
+
+// status is mostly active, with a few deprecated services
+func (g *generator) status() string {
+	if g.rng.Float64() < 0.9 {
+		return elasticsearch.StatusActive
+	}
+	return elasticsearch.StatusDeprecated
+}
+
+func (g *generator) regions() []elasticsearch.ServingRegion {
+	names := g.some(servingRegions, 1, 3)
+	regions := make([]elasticsearch.ServingRegion, len(names))
+	for i, name := range names {
+		regions[i] = elasticsearch.ServingRegion{Region: name, MedianLatencyMS: 20 + g.rng.IntN(400)}
+	}
+	return regions
+}
+
+func (g *generator) metrics() elasticsearch.MetricsInfo {
+	// Usage is long-tailed: a few services take most of the traffic
+	requests := int64(math.Exp(g.rng.Float64() * 16))
+	reviews := int(requests / int64(200+g.rng.IntN(800)))
+	return elasticsearch.MetricsInfo{
+		TotalRequests:   requests,
+		AvgLatencyMS:    g.between(40, 1200, 1),
+		ErrorRate:       g.between(0, 0.05, 4),
+		Rating:          g.between(2.5, 5, 1),
+		ReviewCount:     reviews,
+		PopularityScore: math.Round(math.Log1p(float64(requests))/16*1000) / 1000,
+	}
+}
+
+// interactions gives each user a few favourite categories and draws their
+// interactions mostly from those, so collaborative and content-based
+// recommendations have something to find
+func (g *generator) interactions(services []*elasticsearch.ServiceDocument) []Interaction {
+	if g.opts.Users <= 0 || len(services) == 0 {
+		return nil
+	}
+	byCategory := make(map[string][]*elasticsearch.ServiceDocument)
+	for _, s := range services {
+		byCategory[s.Category] = append(byCategory[s.Category], s)
+	}
+	names := make([]string, 0, len(categories))
+	for _, c := range categories {
+		if len(byCategory[c.name]) > 0 {
+			names = append(names, c.name)
+		}
+	}
+
+	var interactions []Interaction
+	for range g.opts.Users {
+		user := g.id()
+		favourites := g.some(names, 1, min(3, len(names)))
+		for range 3 + g.rng.IntN(15) {
+			pool := services
+			if g.rng.Float64() < 0.8 {
+				pool = byCategory[g.pick(favourites)]
+			}
+			svc := pool[g.rng.IntN(len(pool))]
+			in := Interaction{
+				UserID:    user,
+				ServiceID: svc.ID,
+				Type:      g.pick(interactionTypes),
+				Timestamp: g.opts.Now.Add(-time.Duration(g.rng.IntN(90*24*60)) * time.Minute),
+			}
+			if in.Type == "rate" {
+				in.Rating = math.Round(min(5, max(1, svc.Metrics.Rating+g.rng.NormFloat64()))*2) / 2
+			}
+			interactions = append(interactions, in)
+		}
+	}
+	return interactions
+}
+
+// Queries returns searches a user of the catalog would make: category
+// nouns, tags and capabilities, with a few multi-word phrases
+func Queries(seed uint64, n int) []string {
+	g := &generator{rng: rand.New(rand.NewPCG(seed, seed^0x9e3779b97f4a7c15))}
+	queries := make([]string, n)
+	for i := range queries {
+		cat := categories[g.rng.IntN(len(categories))]
+		switch g.rng.IntN(3) {
+		case 0:
+			queries[i] = strings.ToLower(g.pick(cat.nouns))
+		case 1:
+			queries[i] = g.pick(cat.tags)
+		default:
+			queries[i] = strings.ToLower(g.pick(namePrefixes) + " " + g.pick(cat.nouns))
+		}
+	}
+	return queries
+}
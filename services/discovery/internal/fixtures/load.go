@@ -0,0 +1,79 @@
+package fixtures
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/org/llm-marketplace/services/discovery/internal/elasticsearch"
+	"github.com/org/llm-marketplace/services/discovery/internal/postgres"
+)
+
+// Index bulk indexes the catalog's items
+func (c *Catalog) Index(ctx context.Context, es *elasticsearch.Client) (*elasticsearch.BulkResult, error) {
+	return es.BulkIndex(ctx, c.Services)
+}
+
+// Save upserts the catalog's items into the services table, with their
+// metrics so popularity and trending have something to rank, and records
+// its interactions. Loading the same catalog twice leaves one copy of each
+// item, but adds its interactions again.
+func (c *Catalog) Save(ctx context.Context, pgPool *postgres.Pool) error {
+	query := `
+		INSERT INTO services (
+			id, registry_id, name, version, description, provider_id, provider_name, provider_verified,
+			category, tags, capabilities, pricing_model, pricing_rate, pricing_unit,
+			sla_availability, sla_max_latency_ms, compliance_level, status, kind,
+			total_requests, avg_latency_ms, error_rate, avg_rating, review_count,
+			created_at, updated_at, published_at
+		)
+		VALUES ($1, $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13,
+			$14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24, $25, $24)
+		ON CONFLICT (id) DO UPDATE SET
+			name = EXCLUDED.name,
+			description = EXCLUDED.description,
+			category = EXCLUDED.category,
+			tags = EXCLUDED.tags,
+			capabilities = EXCLUDED.capabilities,
+			pricing_model = EXCLUDED.pricing_model,
+			pricing_rate = EXCLUDED.pricing_rate,
+			status = EXCLUDED.status,
+			total_requests = EXCLUDED.total_requests,
+			avg_latency_ms = EXCLUDED.avg_latency_ms,
+			error_rate = EXCLUDED.error_rate,
+			avg_rating = EXCLUDED.avg_rating,
+			review_count = EXCLUDED.review_count,
+			updated_at = EXCLUDED.updated_at
+	`
+
+	batch := &pgx.Batch{}
+	for _, s := range c.Services {
+		capabilities, err := json.Marshal(s.Capabilities)
+		if err != nil {
+			return fmt.Errorf("failed to encode capabilities of %s: %w", s.ID, err)
+		}
+		batch.Queue(query,
+			s.ID, s.Name, s.Version.Number, s.Description, s.Provider.ID, s.Provider.Name, s.Provider.Verified,
+			s.Category, s.Tags, capabilities, s.Pricing.Model, s.Pricing.Rate, s.Pricing.Unit,
+			s.SLA.Availability, s.SLA.MaxLatencyMS, s.Compliance.Level, s.Status, s.Descriptor().ItemKind(),
+			s.Metrics.TotalRequests, s.Metrics.AvgLatencyMS, s.Metrics.ErrorRate, s.Metrics.Rating, s.Metrics.ReviewCount,
+			s.CreatedAt, s.UpdatedAt,
+		)
+	}
+	for _, in := range c.Interactions {
+		var rating *float64
+		if in.Type == "rate" {
+			rating = &in.Rating
+		}
+		batch.Queue(`
+			INSERT INTO user_interactions (user_id, service_id, interaction_type, rating, timestamp)
+			VALUES ($1, $2, $3, $4, $5)
+		`, in.UserID, in.ServiceID, in.Type, rating, in.Timestamp)
+	}
+
+	if err := pgPool.SendBatch(ctx, batch).Close(); err != nil {
+		return fmt.Errorf("failed to save catalog: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package fixtures
+
+import "github.com/org/llm-marketplace/pkg/marketplace"
+
+// category is a catalog category with the words its items are made of
+type category struct {
+	name         string
+	nouns        []string // Completes an item's name
+	descriptions []string // Start an item's description
+	tags         []string
+	capabilities []string
+}
+
+var categories = []category{
+	{
+		name:         "text-generation",
+		nouns:        []string{"Writer", "Summarizer", "Copilot", "Drafting Assistant", "Language Model"},
+		descriptions: []string{"Generates long-form text and summaries", "Drafts articles, emails and reports", "Summarizes long documents into short abstracts"},
+		tags:         []string{"nlp", "summarization", "writing", "llm", "chat"},
+		capabilities: []string{"streaming", "function-calling", "json-mode", "long-context"},
+	},
+	{
+		name:         "chat",
+		nouns:        []string{"Chat", "Assistant", "Agent", "Support Bot", "Concierge"},
+		descriptions: []string{"Holds multi-turn conversations", "Answers customer questions from your knowledge base", "Runs agents that call your tools"},
+		tags:         []string{"chat", "conversational", "agents", "support", "llm"},
+		capabilities: []string{"streaming", "function-calling", "tool-use", "memory"},
+	},
+	{
+		name:         "translation",
+		nouns:        []string{"Translator", "Localizer", "Polyglot", "Interpreter"},
+		descriptions: []string{"Translates text between forty languages", "Localizes product copy for new markets", "Translates documents while keeping their formatting"},
+		tags:         []string{"translation", "multilingual", "nlp", "localization"},
+		capabilities: []string{"batch", "glossaries", "document-translation"},
+	},
+	{
+		name:         "code",
+		nouns:        []string{"Code Reviewer", "Coder", "Refactorer", "Test Writer", "Code Search"},
+		descriptions: []string{"Reviews pull requests and suggests fixes", "Writes and explains code in twenty languages", "Generates unit tests from source code"},
+		tags:         []string{"code", "developer-tools", "review", "testing"},
+		capabilities: []string{"streaming", "fill-in-the-middle", "repository-context", "function-calling"},
+	},
+	{
+		name:         "vision",
+		nouns:        []string{"Captioner", "Image Tagger", "OCR", "Vision Model", "Detector"},
+		descriptions: []string{"Describes images in natural language", "Extracts text from scanned documents", "Detects and labels objects in photos"},
+		tags:         []string{"vision", "images", "ocr", "captioning", "multimodal"},
+		capabilities: []string{"image-input", "batch", "bounding-boxes"},
+	},
+	{
+		name:         "speech",
+		nouns:        []string{"Transcriber", "Voice", "Speech Recognizer", "Narrator"},
+		descriptions: []string{"Transcribes meetings and calls", "Turns text into natural-sounding speech", "Recognizes speech in noisy environments"},
+		tags:         []string{"speech", "audio", "transcription", "tts"},
+		capabilities: []string{"streaming", "diarization", "timestamps", "voice-cloning"},
+	},
+	{
+		name:         "embeddings",
+		nouns:        []string{"Embedder", "Vectorizer", "Semantic Index", "Reranker"},
+		descriptions: []string{"Embeds text for semantic search and clustering", "Reranks search results by relevance", "Embeds documents in over a hundred languages"},
+		tags:         []string{"embeddings", "search", "retrieval", "rag"},
+		capabilities: []string{"batch", "multilingual", "matryoshka"},
+	},
+	{
+		name:         "moderation",
+		nouns:        []string{"Moderator", "Safety Filter", "Guard", "Classifier"},
+		descriptions: []string{"Flags harmful and unsafe content", "Detects personal data before it leaves your network", "Classifies prompts for jailbreak attempts"},
+		tags:         []string{"safety", "moderation", "pii", "classification"},
+		capabilities: []string{"batch", "low-latency", "custom-categories"},
+	},
+}
+
+var namePrefixes = []string{"Swift", "Atlas", "Nova", "Lumen", "Quill", "Echo", "Orbit", "Cobalt", "Aurora", "Summit", "Pioneer", "Vector"}
+
+var descriptionTails = []string{
+	"with enterprise-grade reliability.",
+	"at a fraction of the usual cost.",
+	"tuned for low latency.",
+	"with fine-grained access control.",
+	"for teams of every size.",
+	"backed by a 24/7 support team.",
+}
+
+var providerPrefixes = []string{"Acme", "Northwind", "Globex", "Initech", "Umbrella", "Stark", "Wayne", "Hooli", "Vandelay", "Tyrell"}
+
+var providerSuffixes = []string{"AI", "Labs", "Intelligence", "Systems", "Cloud"}
+
+var supportLevels = []string{"community", "standard", "premium", "enterprise"}
+
+var availabilities = []float64{99.0, 99.5, 99.9, 99.95, 99.99}
+
+var servingRegions = []string{"us-east-1", "us-west-2", "eu-west-1", "eu-central-1", "ap-southeast-1", "ap-northeast-1"}
+
+// complianceProfile is a combination of compliance attributes providers
+// commonly declare together, from a self-declared public one to a certified
+// restricted one
+type complianceProfile struct {
+	level          string
+	certifications []string
+	residency      []string // Locations the profile's data may be held in
+	gdpr, hipaa    bool
+}
+
+var complianceProfiles = []complianceProfile{
+	{level: marketplace.CompliancePublic, residency: []string{"US"}},
+	{level: marketplace.ComplianceInternal, certifications: []string{"SOC2"}, residency: []string{"US", "EU"}, gdpr: true},
+	{level: marketplace.ComplianceInternal, certifications: []string{"ISO27001"}, residency: []string{"EU", "UK"}, gdpr: true},
+	{level: marketplace.ComplianceConfidential, certifications: []string{"SOC2", "ISO27001"}, residency: []string{"US", "EU", "UK"}, gdpr: true, hipaa: true},
+	{level: marketplace.ComplianceRestricted, certifications: []string{"SOC2", "ISO27001", "FedRAMP"}, residency: []string{"US"}, hipaa: true},
+}
+
+var modelFamilies = []string{"gpt-4", "claude-3", "llama-3", "mistral", "gemini"}
+
+var licenses = []string{"CC-BY-4.0", "CC-BY-SA-4.0", "CC0-1.0", "MIT", "Apache-2.0", "ODbL-1.0"}
+
+var datasetFormats = []string{"jsonl", "parquet", "csv", "arrow"}
+
+var baseModels = []string{"llama-3-8b", "llama-3-70b", "mistral-7b", "gemma-7b", "qwen-2-7b"}
+
+var interactionTypes = []string{"view", "view", "view", "consume", "consume", "rate", "favorite", "download"}
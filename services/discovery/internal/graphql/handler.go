@@ -0,0 +1,175 @@
+// Package graphql serves the catalog over GraphQL for the web frontend.
+//
+// It is built on graph-gophers/graphql-go rather than gqlgen. The schema in
+// schema.graphql is bound to the resolvers when the service starts, and a
+// resolver that doesn't match it fails startup (and the tests), so there is no
+// generated code to keep in step with the schema and no generation step in
+// the build or the Docker image. Batching and persisted queries are this
+// package's own, in loader.go and handler.go, and don't depend on either
+// library.
+package graphql
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+	gql "github.com/graph-gophers/graphql-go"
+	"github.com/org/llm-marketplace/services/discovery/internal/config"
+	"github.com/org/llm-marketplace/services/discovery/internal/elasticsearch"
+	"github.com/org/llm-marketplace/services/discovery/internal/entitlement"
+	"github.com/org/llm-marketplace/services/discovery/internal/problem"
+	"go.uber.org/zap"
+)
+
+// Handler serves GraphQL over HTTP with automatic persisted query support
+type Handler struct {
+	schema      *gql.Schema
+	esClient    *elasticsearch.Client
+	redisClient *redis.Client
+	config      *config.Config
+	logger      *zap.Logger
+}
+
+func NewHandler(
+	schema *gql.Schema,
+	esClient *elasticsearch.Client,
+	redisClient *redis.Client,
+	cfg *config.Config,
+	logger *zap.Logger,
+) *Handler {
+	return &Handler{
+		schema:      schema,
+		esClient:    esClient,
+		redisClient: redisClient,
+		config:      cfg,
+		logger:      logger,
+	}
+}
+
+type request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+	Extensions    struct {
+		PersistedQuery *persistedQuery `json:"persistedQuery"`
+	} `json:"extensions"`
+}
+
+type persistedQuery struct {
+	Version    int    `json:"version"`
+	SHA256Hash string `json:"sha256Hash"`
+}
+
+var errPersistedQueryNotFound = errors.New("PersistedQueryNotFound")
+
+// Handle handles GET and POST /graphql
+func (h *Handler) Handle(c *gin.Context) {
+	req, err := parseRequest(c)
+	if err != nil {
+		problem.Abort(c, problem.InvalidRequest, err.Error())
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	query, err := h.resolveQuery(ctx, req)
+	if errors.Is(err, errPersistedQueryNotFound) {
+		// Tells the client to retry with the full query text
+		c.JSON(http.StatusOK, gin.H{
+			"errors": []gin.H{{
+				"message":    "PersistedQueryNotFound",
+				"extensions": gin.H{"code": "PERSISTED_QUERY_NOT_FOUND"},
+			}},
+		})
+		return
+	}
+	if err != nil {
+		problem.Abort(c, problem.InvalidRequest, err.Error())
+		return
+	}
+
+	ctx = withLoaders(ctx, newLoaders(h.esClient, entitlement.FromContext(ctx)))
+	if userID, exists := c.Get("user_id"); exists {
+		if id, ok := userID.(string); ok {
+			ctx = withUserID(ctx, id)
+		}
+	}
+
+	resp := h.schema.Exec(ctx, query, req.OperationName, req.Variables)
+	for _, gqlErr := range resp.Errors {
+		h.logger.Debug("GraphQL error", zap.String("error", gqlErr.Error()))
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// resolveQuery returns the query text, registering or looking up persisted queries by hash
+func (h *Handler) resolveQuery(ctx context.Context, req *request) (string, error) {
+	pq := req.Extensions.PersistedQuery
+	if pq == nil {
+		if req.Query == "" {
+			return "", fmt.Errorf("query is required")
+		}
+		return req.Query, nil
+	}
+
+	if pq.Version != 1 {
+		return "", fmt.Errorf("unsupported persisted query version: %d", pq.Version)
+	}
+
+	key := "graphql:pq:" + pq.SHA256Hash
+
+	if req.Query == "" {
+		query, err := h.redisClient.Get(ctx, key).Result()
+		if err == redis.Nil {
+			return "", errPersistedQueryNotFound
+		}
+		if err != nil {
+			return "", err
+		}
+		return query, nil
+	}
+
+	sum := sha256.Sum256([]byte(req.Query))
+	if hex.EncodeToString(sum[:]) != pq.SHA256Hash {
+		return "", fmt.Errorf("provided sha256Hash does not match query")
+	}
+
+	if err := h.redisClient.Set(ctx, key, req.Query, h.config.CacheTTL("persisted_queries")).Err(); err != nil {
+		h.logger.Warn("Failed to store persisted query", zap.Error(err))
+	}
+
+	return req.Query, nil
+}
+
+func parseRequest(c *gin.Context) (*request, error) {
+	var req request
+
+	if c.Request.Method == http.MethodGet {
+		req.Query = c.Query("query")
+		req.OperationName = c.Query("operationName")
+		if variables := c.Query("variables"); variables != "" {
+			if err := json.Unmarshal([]byte(variables), &req.Variables); err != nil {
+				return nil, fmt.Errorf("invalid variables: %w", err)
+			}
+		}
+		if extensions := c.Query("extensions"); extensions != "" {
+			if err := json.Unmarshal([]byte(extensions), &req.Extensions); err != nil {
+				return nil, fmt.Errorf("invalid extensions: %w", err)
+			}
+		}
+		return &req, nil
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		return nil, err
+	}
+	return &req, nil
+}
@@ -0,0 +1,81 @@
+package graphql
+
+import (
+	"context"
+	"time"
+
+	"github.com/graph-gophers/dataloader/v7"
+	"github.com/org/llm-marketplace/services/discovery/internal/elasticsearch"
+	"github.com/org/llm-marketplace/services/discovery/internal/entitlement"
+)
+
+type contextKey string
+
+const (
+	loadersKey contextKey = "graphql.loaders"
+	userIDKey  contextKey = "graphql.user_id"
+)
+
+// loaders holds request-scoped dataloaders so a single query batches its lookups
+type loaders struct {
+	services *dataloader.Loader[string, *elasticsearch.ServiceDocument]
+}
+
+// newLoaders builds the loaders for one request; documents the caller may not see resolve to null
+func newLoaders(esClient *elasticsearch.Client, caller entitlement.Caller) *loaders {
+	batchServices := func(ctx context.Context, ids []string) []*dataloader.Result[*elasticsearch.ServiceDocument] {
+		results := make([]*dataloader.Result[*elasticsearch.ServiceDocument], len(ids))
+
+		docs, err := esClient.MGet(ctx, ids)
+		for i, id := range ids {
+			if err != nil {
+				results[i] = &dataloader.Result[*elasticsearch.ServiceDocument]{Error: err}
+				continue
+			}
+			// Unknown IDs resolve to null rather than failing the whole batch
+			doc := docs[id]
+			if doc != nil && !caller.CanView(doc.Access) {
+				doc = nil
+			}
+			results[i] = &dataloader.Result[*elasticsearch.ServiceDocument]{Data: doc}
+		}
+		return results
+	}
+
+	return &loaders{
+		services: dataloader.NewBatchedLoader(
+			batchServices,
+			dataloader.WithWait[string, *elasticsearch.ServiceDocument](2*time.Millisecond),
+			dataloader.WithBatchCapacity[string, *elasticsearch.ServiceDocument](100),
+		),
+	}
+}
+
+func withLoaders(ctx context.Context, l *loaders) context.Context {
+	return context.WithValue(ctx, loadersKey, l)
+}
+
+func loadService(ctx context.Context, id string) (*elasticsearch.ServiceDocument, error) {
+	l := ctx.Value(loadersKey).(*loaders)
+	return l.services.Load(ctx, id)()
+}
+
+func loadServices(ctx context.Context, ids []string) ([]*elasticsearch.ServiceDocument, error) {
+	l := ctx.Value(loadersKey).(*loaders)
+	docs, errs := l.services.LoadMany(ctx, ids)()
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return docs, nil
+}
+
+func withUserID(ctx context.Context, userID string) context.Context {
+	return context.WithValue(ctx, userIDKey, userID)
+}
+
+func userIDFromContext(ctx context.Context) string {
+	userID, _ := ctx.Value(userIDKey).(string)
+	return userID
+}
@@ -0,0 +1,185 @@
+package graphql
+
+import (
+	"context"
+	_ "embed"
+
+	gql "github.com/graph-gophers/graphql-go"
+	"github.com/org/llm-marketplace/services/discovery/internal/recommendation"
+	"github.com/org/llm-marketplace/services/discovery/internal/search"
+)
+
+//go:embed schema.graphql
+var schemaSDL string
+
+// Resolver is the root GraphQL resolver
+type Resolver struct {
+	searchService *search.Service
+	recService    *recommendation.Service
+}
+
+// ParseSchema builds the executable schema backed by the search and recommendation services
+func ParseSchema(searchService *search.Service, recService *recommendation.Service) (*gql.Schema, error) {
+	resolver := &Resolver{
+		searchService: searchService,
+		recService:    recService,
+	}
+
+	return gql.ParseSchema(schemaSDL, resolver,
+		gql.MaxDepth(8),
+		gql.MaxParallelism(20),
+	)
+}
+
+// Service resolves a single service by ID
+func (r *Resolver) Service(ctx context.Context, args struct{ ID gql.ID }) (*serviceResolver, error) {
+	doc, err := loadService(ctx, string(args.ID))
+	if err != nil || doc == nil {
+		return nil, err
+	}
+	return &serviceResolver{root: r, doc: doc}, nil
+}
+
+// Services resolves services by ID in a single batch, with null for unknown IDs
+func (r *Resolver) Services(ctx context.Context, args struct{ IDs []gql.ID }) ([]*serviceResolver, error) {
+	ids := make([]string, len(args.IDs))
+	for i, id := range args.IDs {
+		ids[i] = string(id)
+	}
+
+	docs, err := loadServices(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	services := make([]*serviceResolver, len(docs))
+	for i, doc := range docs {
+		if doc != nil {
+			services[i] = &serviceResolver{root: r, doc: doc}
+		}
+	}
+	return services, nil
+}
+
+type searchInput struct {
+	Query           *string
+	Categories      *[]string
+	Tags            *[]string
+	MinRating       *float64
+	MinPrice        *float64
+	MaxPrice        *float64
+	PricingModels   *[]string
+	ComplianceLevel *string
+	Certifications  *[]string
+	DataResidency   *[]string
+	VerifiedOnly    *bool
+	Status          *string
+	MinAvailability *float64
+	Page            *int32
+	PageSize        *int32
+}
+
+// Search runs a faceted search
+func (r *Resolver) Search(ctx context.Context, args struct{ Input searchInput }) (*searchResultsResolver, error) {
+	in := args.Input
+	req := &search.SearchRequest{
+		Query: valueOr(in.Query, ""),
+		Filters: search.SearchFilters{
+			Categories:      valueOr(in.Categories, nil),
+			Tags:            valueOr(in.Tags, nil),
+			MinRating:       valueOr(in.MinRating, 0),
+			MinPrice:        valueOr(in.MinPrice, 0),
+			MaxPrice:        valueOr(in.MaxPrice, 0),
+			PricingModels:   valueOr(in.PricingModels, nil),
+			ComplianceLevel: valueOr(in.ComplianceLevel, ""),
+			Certifications:  valueOr(in.Certifications, nil),
+			DataResidency:   valueOr(in.DataResidency, nil),
+			VerifiedOnly:    valueOr(in.VerifiedOnly, false),
+			Status:          valueOr(in.Status, ""),
+			MinAvailability: valueOr(in.MinAvailability, 0),
+		},
+		Pagination: search.PaginationRequest{
+			Page:     int(valueOr(in.Page, 0)),
+			PageSize: int(valueOr(in.PageSize, 20)),
+		},
+		UserID: userIDFromContext(ctx),
+	}
+
+	resp, err := r.searchService.Search(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	return &searchResultsResolver{root: r, resp: resp}, nil
+}
+
+type recommendationInput struct {
+	ServiceID       *gql.ID
+	Categories      *[]string
+	MaxResults      *int32
+	IncludeTrending *bool
+}
+
+// Recommendations returns recommendations for the caller. They're always for
+// the authenticated user: another user's would reveal what they use.
+func (r *Resolver) Recommendations(ctx context.Context, args struct{ Input *recommendationInput }) (*recommendationsResolver, error) {
+	req := &recommendation.RecommendationRequest{
+		UserID:     userIDFromContext(ctx),
+		MaxResults: 10,
+	}
+
+	if in := args.Input; in != nil {
+		if in.ServiceID != nil {
+			req.ServiceID = string(*in.ServiceID)
+		}
+		req.Categories = valueOr(in.Categories, nil)
+		req.MaxResults = int(valueOr(in.MaxResults, 10))
+		req.IncludeTrending = valueOr(in.IncludeTrending, false)
+	}
+
+	resp, err := r.recService.GetRecommendations(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	return &recommendationsResolver{
+		algorithm: resp.Algorithm,
+		items:     r.newRecommendationResolvers(resp.Recommendations),
+	}, nil
+}
+
+// Categories lists all categories
+func (r *Resolver) Categories(ctx context.Context) ([]*categoryResolver, error) {
+	categories, err := r.searchService.GetCategories(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	resolvers := make([]*categoryResolver, len(categories))
+	for i, category := range categories {
+		resolvers[i] = &categoryResolver{info: category}
+	}
+	return resolvers, nil
+}
+
+// Tags lists all tags
+func (r *Resolver) Tags(ctx context.Context) ([]*tagResolver, error) {
+	tags, err := r.searchService.GetTags(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	resolvers := make([]*tagResolver, len(tags))
+	for i, tag := range tags {
+		resolvers[i] = &tagResolver{info: tag}
+	}
+	return resolvers, nil
+}
+
+func (r *Resolver) newRecommendationResolvers(recs []recommendation.Recommendation) []*recommendationResolver {
+	resolvers := make([]*recommendationResolver, len(recs))
+	for i, rec := range recs {
+		resolvers[i] = &recommendationResolver{root: r, rec: rec}
+	}
+	return resolvers
+}
@@ -0,0 +1,283 @@
+package graphql
+
+import (
+	"context"
+	"sort"
+	"strconv"
+
+	gql "github.com/graph-gophers/graphql-go"
+	"github.com/org/llm-marketplace/services/discovery/internal/elasticsearch"
+	"github.com/org/llm-marketplace/services/discovery/internal/recommendation"
+	"github.com/org/llm-marketplace/services/discovery/internal/search"
+)
+
+// serviceResolver exposes a ServiceDocument without its embedding vector
+type serviceResolver struct {
+	root *Resolver
+	doc  *elasticsearch.ServiceDocument
+}
+
+func (r *serviceResolver) ID() gql.ID             { return gql.ID(r.doc.ID) }
+func (r *serviceResolver) Name() string           { return r.doc.Name }
+func (r *serviceResolver) Description() string    { return r.doc.Description }
+func (r *serviceResolver) Category() string       { return r.doc.Category }
+func (r *serviceResolver) Tags() []string         { return nonNil(r.doc.Tags) }
+func (r *serviceResolver) Capabilities() []string { return nonNil(r.doc.Capabilities) }
+func (r *serviceResolver) Status() string         { return r.doc.Status }
+func (r *serviceResolver) CreatedAt() gql.Time    { return gql.Time{Time: r.doc.CreatedAt} }
+func (r *serviceResolver) UpdatedAt() gql.Time    { return gql.Time{Time: r.doc.UpdatedAt} }
+func (r *serviceResolver) Provider() *providerResolver {
+	return &providerResolver{info: r.doc.Provider}
+}
+func (r *serviceResolver) Pricing() *pricingResolver {
+	return &pricingResolver{info: r.doc.Pricing}
+}
+func (r *serviceResolver) SLA() *slaResolver {
+	return &slaResolver{info: r.doc.SLA}
+}
+func (r *serviceResolver) Compliance() *complianceResolver {
+	return &complianceResolver{info: r.doc.Compliance}
+}
+func (r *serviceResolver) Metrics() *metricsResolver {
+	return &metricsResolver{info: r.doc.Metrics}
+}
+
+// Similar resolves content-based recommendations for this service
+func (r *serviceResolver) Similar(ctx context.Context, args struct{ MaxResults *int32 }) ([]*recommendationResolver, error) {
+	req := &recommendation.RecommendationRequest{
+		ServiceID:  r.doc.ID,
+		MaxResults: int(valueOr(args.MaxResults, 10)),
+	}
+
+	resp, err := r.root.recService.GetRecommendations(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.root.newRecommendationResolvers(resp.Recommendations), nil
+}
+
+type providerResolver struct{ info elasticsearch.ProviderInfo }
+
+func (r *providerResolver) ID() gql.ID     { return gql.ID(r.info.ID) }
+func (r *providerResolver) Name() string   { return r.info.Name }
+func (r *providerResolver) Verified() bool { return r.info.Verified }
+
+type pricingResolver struct{ info elasticsearch.PricingInfo }
+
+func (r *pricingResolver) Model() string { return r.info.Model }
+func (r *pricingResolver) Rate() float64 { return r.info.Rate }
+func (r *pricingResolver) Unit() string  { return r.info.Unit }
+
+type slaResolver struct{ info elasticsearch.SLAInfo }
+
+func (r *slaResolver) Availability() float64 { return r.info.Availability }
+func (r *slaResolver) MaxLatencyMs() int32   { return int32(r.info.MaxLatencyMS) }
+func (r *slaResolver) Support() string       { return r.info.SupportLevel }
+
+type complianceResolver struct{ info elasticsearch.ComplianceInfo }
+
+func (r *complianceResolver) Level() string            { return r.info.Level }
+func (r *complianceResolver) Certifications() []string { return nonNil(r.info.Certifications) }
+func (r *complianceResolver) DataResidency() []string  { return nonNil(r.info.DataResidency) }
+
+type metricsResolver struct{ info elasticsearch.MetricsInfo }
+
+// TotalRequests is a Float because GraphQL Int is limited to 32 bits
+func (r *metricsResolver) TotalRequests() float64   { return float64(r.info.TotalRequests) }
+func (r *metricsResolver) AvgLatencyMs() float64    { return r.info.AvgLatencyMS }
+func (r *metricsResolver) ErrorRate() float64       { return r.info.ErrorRate }
+func (r *metricsResolver) Rating() float64          { return r.info.Rating }
+func (r *metricsResolver) ReviewCount() int32       { return int32(r.info.ReviewCount) }
+func (r *metricsResolver) PopularityScore() float64 { return r.info.PopularityScore }
+
+type searchResultsResolver struct {
+	root *Resolver
+	resp *search.SearchResponse
+}
+
+func (r *searchResultsResolver) Total() int32    { return int32(r.resp.Total) }
+func (r *searchResultsResolver) Page() int32     { return int32(r.resp.Page) }
+func (r *searchResultsResolver) PageSize() int32 { return int32(r.resp.PageSize) }
+func (r *searchResultsResolver) TookMs() int32   { return int32(r.resp.Took) }
+
+func (r *searchResultsResolver) Results() []*searchResultResolver {
+	results := make([]*searchResultResolver, len(r.resp.Results))
+	for i := range r.resp.Results {
+		results[i] = &searchResultResolver{root: r.root, result: &r.resp.Results[i]}
+	}
+	return results
+}
+
+func (r *searchResultsResolver) Facets() []*facetResolver {
+	return parseFacets(r.resp.Aggregations)
+}
+
+type searchResultResolver struct {
+	root   *Resolver
+	result *search.SearchResult
+}
+
+func (r *searchResultResolver) Score() float64 { return r.result.Score }
+func (r *searchResultResolver) Service() *serviceResolver {
+	return &serviceResolver{root: r.root, doc: r.result.Service}
+}
+func (r *searchResultResolver) MatchDetails() *matchDetailsResolver {
+	return &matchDetailsResolver{details: r.result.MatchDetails}
+}
+func (r *searchResultResolver) Deprecation() *deprecationResolver {
+	if r.result.Deprecation == nil {
+		return nil
+	}
+	return &deprecationResolver{banner: r.result.Deprecation}
+}
+func (r *searchResultResolver) Subscribed() bool { return r.result.Subscribed }
+
+type matchDetailsResolver struct{ details search.MatchDetails }
+
+func (r *matchDetailsResolver) RelevanceScore() float64   { return r.details.RelevanceScore }
+func (r *matchDetailsResolver) PopularityScore() float64  { return r.details.PopularityScore }
+func (r *matchDetailsResolver) PerformanceScore() float64 { return r.details.PerformanceScore }
+func (r *matchDetailsResolver) ComplianceScore() float64  { return r.details.ComplianceScore }
+func (r *matchDetailsResolver) SemanticMatch() bool       { return r.details.SemanticMatch }
+func (r *matchDetailsResolver) Demoted() bool             { return r.details.Demoted }
+
+type deprecationResolver struct{ banner *search.DeprecationBanner }
+
+func (r *deprecationResolver) Message() string { return r.banner.Message }
+func (r *deprecationResolver) SunsetAt() *gql.Time {
+	if r.banner.SunsetAt == nil {
+		return nil
+	}
+	return &gql.Time{Time: *r.banner.SunsetAt}
+}
+func (r *deprecationResolver) ReplacementServiceId() *gql.ID {
+	if r.banner.ReplacementService == "" {
+		return nil
+	}
+	id := gql.ID(r.banner.ReplacementService)
+	return &id
+}
+
+type facetResolver struct {
+	name    string
+	buckets []*facetBucketResolver
+}
+
+func (r *facetResolver) Name() string                    { return r.name }
+func (r *facetResolver) Buckets() []*facetBucketResolver { return r.buckets }
+
+type facetBucketResolver struct {
+	key   string
+	count int32
+}
+
+func (r *facetBucketResolver) Key() string  { return r.key }
+func (r *facetBucketResolver) Count() int32 { return r.count }
+
+type recommendationsResolver struct {
+	algorithm string
+	items     []*recommendationResolver
+}
+
+func (r *recommendationsResolver) Algorithm() string                { return r.algorithm }
+func (r *recommendationsResolver) Items() []*recommendationResolver { return r.items }
+
+type recommendationResolver struct {
+	root *Resolver
+	rec  recommendation.Recommendation
+}
+
+func (r *recommendationResolver) ServiceId() gql.ID   { return gql.ID(r.rec.ServiceID) }
+func (r *recommendationResolver) Score() float64      { return r.rec.Score }
+func (r *recommendationResolver) Reason() string      { return r.rec.Reason }
+func (r *recommendationResolver) Confidence() float64 { return r.rec.Confidence }
+
+// Service is resolved through the request-scoped loader so sibling recommendations share one mget
+func (r *recommendationResolver) Service(ctx context.Context) (*serviceResolver, error) {
+	doc := r.rec.Service
+	if doc == nil {
+		var err error
+		doc, err = loadService(ctx, r.rec.ServiceID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if doc == nil {
+		return nil, nil
+	}
+	return &serviceResolver{root: r.root, doc: doc}, nil
+}
+
+type categoryResolver struct{ info search.CategoryInfo }
+
+func (r *categoryResolver) Name() string       { return r.info.Name }
+func (r *categoryResolver) Count() int32       { return int32(r.info.Count) }
+func (r *categoryResolver) AvgRating() float64 { return r.info.AvgRating }
+
+type tagResolver struct{ info search.TagInfo }
+
+func (r *tagResolver) Name() string { return r.info.Name }
+func (r *tagResolver) Count() int32 { return int32(r.info.Count) }
+
+// parseFacets converts terms aggregations into facets, skipping metric aggregations
+func parseFacets(aggs map[string]interface{}) []*facetResolver {
+	facets := []*facetResolver{}
+	for name, raw := range aggs {
+		agg, ok := raw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		buckets, ok := agg["buckets"].([]interface{})
+		if !ok {
+			continue
+		}
+
+		facet := &facetResolver{name: name, buckets: []*facetBucketResolver{}}
+		for _, rawBucket := range buckets {
+			bucket, ok := rawBucket.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			count, _ := bucket["doc_count"].(float64)
+			facet.buckets = append(facet.buckets, &facetBucketResolver{
+				key:   bucketKey(bucket),
+				count: int32(count),
+			})
+		}
+		facets = append(facets, facet)
+	}
+
+	sort.Slice(facets, func(i, j int) bool {
+		return facets[i].name < facets[j].name
+	})
+	return facets
+}
+
+func bucketKey(bucket map[string]interface{}) string {
+	if key, ok := bucket["key_as_string"].(string); ok {
+		return key
+	}
+	switch key := bucket["key"].(type) {
+	case string:
+		return key
+	case float64:
+		return strconv.FormatFloat(key, 'f', -1, 64)
+	default:
+		return ""
+	}
+}
+
+func nonNil(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
+
+func valueOr[T any](value *T, fallback T) T {
+	if value == nil {
+		return fallback
+	}
+	return *value
+}
@@ -0,0 +1,253 @@
+// Package health reports whether the service can take traffic. Dependencies
+// are checked in the background and summarized as healthy, degraded or
+// unhealthy: degraded still accepts traffic, but shows orchestrators and
+// dashboards that a dependency is slow or a non-critical one is down.
+package health
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/org/llm-marketplace/services/discovery/internal/config"
+	"github.com/org/llm-marketplace/services/discovery/internal/observability"
+	"go.uber.org/zap"
+)
+
+// Status is the state of a dependency or of the service as a whole
+type Status string
+
+const (
+	Healthy   Status = "healthy"
+	Degraded  Status = "degraded"
+	Unhealthy Status = "unhealthy"
+)
+
+// Check probes a dependency and returns how long the probe took
+type Check func(ctx context.Context) (time.Duration, error)
+
+// Ping times a check that only reports an error
+func Ping(ping func(ctx context.Context) error) Check {
+	return func(ctx context.Context) (time.Duration, error) {
+		start := time.Now()
+		err := ping(ctx)
+		return time.Since(start), err
+	}
+}
+
+// Dependency is a service discovery relies on. A failing critical dependency
+// makes the service unhealthy; any other failure, or a check slower than
+// SlowThreshold, makes it degraded.
+type Dependency struct {
+	Name          string
+	Check         Check
+	Critical      bool
+	SlowThreshold time.Duration
+}
+
+// DependencyReport is the latest check of one dependency
+type DependencyReport struct {
+	Status        Status     `json:"status"`
+	Critical      bool       `json:"critical"`
+	LatencyMS     float64    `json:"latency_ms"`
+	LastSuccessAt *time.Time `json:"last_success_at,omitempty"`
+	CheckedAt     *time.Time `json:"checked_at,omitempty"`
+	Error         string     `json:"error,omitempty"`
+}
+
+// Report is the readiness of the service
+type Report struct {
+	Status       Status                      `json:"status"`
+	Dependencies map[string]DependencyReport `json:"dependencies"`
+	Timestamp    time.Time                   `json:"timestamp"`
+}
+
+var errNotChecked = errors.New("not checked yet")
+
+// Monitor checks dependencies on an interval so readiness probes answer from
+// the last round instead of calling every dependency per request
+type Monitor struct {
+	deps    []Dependency
+	config  config.ReadinessConfig
+	logger  *zap.Logger
+	metrics *observability.Metrics
+
+	mu      sync.RWMutex
+	reports map[string]DependencyReport
+	status  Status
+}
+
+// NewMonitor creates a monitor for deps. Criticality and slow thresholds set
+// in cfg.Dependencies override those passed in. Until the first round runs,
+// every dependency is unhealthy.
+func NewMonitor(deps []Dependency, cfg config.ReadinessConfig, logger *zap.Logger, metrics *observability.Metrics) *Monitor {
+	m := &Monitor{
+		config:  cfg,
+		logger:  logger,
+		metrics: metrics,
+		reports: make(map[string]DependencyReport, len(deps)),
+	}
+	for _, dep := range deps {
+		if override, ok := cfg.Dependencies[dep.Name]; ok {
+			dep.Critical = override.Critical
+			if override.SlowThreshold > 0 {
+				dep.SlowThreshold = override.SlowThreshold
+			}
+		}
+		m.deps = append(m.deps, dep)
+		m.reports[dep.Name] = DependencyReport{Status: Unhealthy, Critical: dep.Critical, Error: errNotChecked.Error()}
+	}
+	m.status = summarize(m.reports)
+	return m
+}
+
+// Start checks dependencies until ctx is cancelled
+func (m *Monitor) Start(ctx context.Context) {
+	interval := m.config.Interval
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+
+	m.logger.Info("Starting readiness monitor", zap.Duration("interval", interval))
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	m.Check(ctx)
+
+	for {
+		select {
+		case <-ticker.C:
+			m.Check(ctx)
+		case <-ctx.Done():
+			m.logger.Info("Readiness monitor stopped")
+			return
+		}
+	}
+}
+
+// Check runs one round of checks, concurrently, and records the results
+func (m *Monitor) Check(ctx context.Context) {
+	timeout := m.config.Timeout
+	if timeout <= 0 {
+		timeout = 2 * time.Second
+	}
+
+	results := make([]DependencyReport, len(m.deps))
+	latencies := make([]time.Duration, len(m.deps))
+	var wg sync.WaitGroup
+	for i, dep := range m.deps {
+		wg.Add(1)
+		go func(i int, dep Dependency) {
+			defer wg.Done()
+			checkCtx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+			var err error
+			latencies[i], err = dep.Check(checkCtx)
+			results[i] = evaluate(dep, latencies[i], err, time.Now().UTC())
+		}(i, dep)
+	}
+	wg.Wait()
+
+	m.mu.Lock()
+	prev := m.status
+	for i, dep := range m.deps {
+		report := results[i]
+		if report.LastSuccessAt == nil {
+			report.LastSuccessAt = m.reports[dep.Name].LastSuccessAt
+		}
+		m.reports[dep.Name] = report
+		if m.metrics != nil {
+			m.metrics.DependencyCheck(dep.Name, string(report.Status), latencies[i])
+		}
+	}
+	m.status = summarize(m.reports)
+	next := m.status
+	m.mu.Unlock()
+
+	if m.metrics != nil {
+		m.metrics.ReadinessStatus(string(next))
+	}
+	if next != prev {
+		fields := []zap.Field{zap.String("from", string(prev)), zap.String("to", string(next))}
+		for i, dep := range m.deps {
+			if results[i].Status != Healthy {
+				fields = append(fields, zap.String(dep.Name, results[i].describe()))
+			}
+		}
+		if next == Healthy {
+			m.logger.Info("Readiness changed", fields...)
+		} else {
+			m.logger.Warn("Readiness changed", fields...)
+		}
+	}
+}
+
+// Report returns the result of the last round
+func (m *Monitor) Report() Report {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	deps := make(map[string]DependencyReport, len(m.reports))
+	for name, report := range m.reports {
+		deps[name] = report
+	}
+	return Report{Status: m.status, Dependencies: deps, Timestamp: time.Now().UTC()}
+}
+
+func evaluate(dep Dependency, latency time.Duration, err error, now time.Time) DependencyReport {
+	report := DependencyReport{
+		Status:    Healthy,
+		Critical:  dep.Critical,
+		LatencyMS: float64(latency.Microseconds()) / 1000,
+		CheckedAt: &now,
+	}
+	switch {
+	case err != nil:
+		report.Status = Unhealthy
+		report.Error = err.Error()
+	case dep.SlowThreshold > 0 && latency > dep.SlowThreshold:
+		report.Status = Degraded
+		report.LastSuccessAt = &now
+	default:
+		report.LastSuccessAt = &now
+	}
+	return report
+}
+
+func (r DependencyReport) describe() string {
+	if r.Error != "" {
+		return string(r.Status) + ": " + r.Error
+	}
+	return string(r.Status)
+}
+
+// summarize derives the service status: unhealthy if a critical dependency
+// is down, degraded if anything else is wrong
+func summarize(reports map[string]DependencyReport) Status {
+	status := Healthy
+	for _, report := range reports {
+		switch {
+		case report.Status == Unhealthy && report.Critical:
+			return Unhealthy
+		case report.Status != Healthy:
+			status = Degraded
+		}
+	}
+	return status
+}
+
+// Handler serves the report: 200 when healthy or degraded, 503 when unhealthy
+func (m *Monitor) Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		report := m.Report()
+		status := http.StatusOK
+		if report.Status == Unhealthy {
+			status = http.StatusServiceUnavailable
+		}
+		c.JSON(status, report)
+	}
+}
@@ -0,0 +1,181 @@
+// Package loadtest drives the discovery HTTP API with concurrent clients and
+// reports latency against the targets in PerformanceConfig.
+package loadtest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Request is one kind of call the load generator makes
+type Request struct {
+	Name   string            `json:"name"` // Reported separately under this name
+	Method string            `json:"method"`
+	Path   string            `json:"path"` // Appended to Options.BaseURL, including any query string
+	Body   []byte            `json:"-"`
+	Header map[string]string `json:"-"`
+	Weight int               `json:"weight,omitempty"` // Relative share of traffic; 1 when unset
+}
+
+// DefaultRequests is a read-heavy mix of the search endpoints, weighted
+// roughly as production traffic is
+func DefaultRequests() []Request {
+	return []Request{
+		{Name: "search_get", Method: http.MethodGet, Path: "/api/v1/search?q=language+model&page_size=20", Weight: 5},
+		{Name: "search_post", Method: http.MethodPost, Path: "/api/v1/search", Weight: 3,
+			Body: []byte(`{"query":"text summarization","filters":{"categories":["text-generation"]},"pagination":{"page":0,"page_size":20}}`)},
+		{Name: "search_facets", Method: http.MethodGet, Path: "/api/v1/search?q=translation&aggregations_only=true", Weight: 1},
+		{Name: "autocomplete", Method: http.MethodGet, Path: "/api/v1/autocomplete?q=lang", Weight: 1},
+	}
+}
+
+// QueryRequests is a GET search for each of queries, reported together as
+// search_queries, for exercising the cache and index with varied searches
+// such as fixtures.Queries
+func QueryRequests(queries []string) []Request {
+	requests := make([]Request, len(queries))
+	for i, q := range queries {
+		requests[i] = Request{Name: "search_queries", Method: http.MethodGet, Path: "/api/v1/search?q=" + url.QueryEscape(q) + "&page_size=20"}
+	}
+	return requests
+}
+
+// Options configures a run. The run ends after Requests calls or once
+// Duration has elapsed, whichever comes first; at least one must be set.
+type Options struct {
+	BaseURL     string
+	Requests    []Request
+	Concurrency int           // Clients issuing requests in parallel
+	Total       int           // Calls to make across all clients
+	Duration    time.Duration // Longest the run may take
+	Timeout     time.Duration // Per call; 10s when unset
+	Client      *http.Client  // http.DefaultClient's transport with Timeout when nil
+}
+
+// Run generates load until the request count or duration is reached, or ctx
+// is cancelled, and returns what was observed. Failed calls are counted in
+// the report rather than returned as errors.
+func Run(ctx context.Context, opts Options) (*Report, error) {
+	if opts.BaseURL == "" {
+		return nil, errors.New("base URL is required")
+	}
+	if len(opts.Requests) == 0 {
+		return nil, errors.New("at least one request is required")
+	}
+	if opts.Total <= 0 && opts.Duration <= 0 {
+		return nil, errors.New("a request count or duration is required")
+	}
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = 1
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 10 * time.Second
+	}
+
+	client := opts.Client
+	if client == nil {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.MaxIdleConnsPerHost = opts.Concurrency
+		client = &http.Client{Transport: transport, Timeout: opts.Timeout}
+	}
+
+	schedule := weightedSchedule(opts.Requests)
+
+	if opts.Duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Duration)
+		defer cancel()
+	}
+
+	recorder := newRecorder(opts.Requests)
+	var issued atomic.Int64
+	var wg sync.WaitGroup
+
+	started := time.Now()
+	for i := 0; i < opts.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				n := issued.Add(1)
+				if opts.Total > 0 && n > int64(opts.Total) {
+					return
+				}
+				req := schedule[int(n-1)%len(schedule)]
+				status, latency, err := call(ctx, client, opts.BaseURL, req)
+				if err != nil && ctx.Err() != nil {
+					// Cut off by the end of the run, not a failure of the API
+					return
+				}
+				recorder.record(req.Name, status, latency, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	return recorder.report(started, time.Since(started), opts.Concurrency), nil
+}
+
+// weightedSchedule repeats each request by its weight so workers can cycle
+// through the slice and produce the configured mix
+func weightedSchedule(requests []Request) []Request {
+	var schedule []Request
+	for _, req := range requests {
+		weight := req.Weight
+		if weight <= 0 {
+			weight = 1
+		}
+		for i := 0; i < weight; i++ {
+			schedule = append(schedule, req)
+		}
+	}
+	return schedule
+}
+
+// call makes one request and returns its status and latency. The body is read
+// in full so latency covers the whole response.
+func call(ctx context.Context, client *http.Client, baseURL string, r Request) (int, time.Duration, error) {
+	method := r.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	var body io.Reader
+	if r.Body != nil {
+		body = bytes.NewReader(r.Body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, baseURL+r.Path, body)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to build request: %w", err)
+	}
+	if r.Body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for k, v := range r.Header {
+		req.Header.Set(k, v)
+	}
+
+	start := time.Now()
+	res, err := client.Do(req)
+	if err != nil {
+		return 0, time.Since(start), err
+	}
+	_, err = io.Copy(io.Discard, res.Body)
+	res.Body.Close()
+	latency := time.Since(start)
+	if err != nil {
+		return res.StatusCode, latency, fmt.Errorf("failed to read response: %w", err)
+	}
+	if res.StatusCode >= http.StatusBadRequest {
+		return res.StatusCode, latency, fmt.Errorf("unexpected status %d", res.StatusCode)
+	}
+	return res.StatusCode, latency, nil
+}
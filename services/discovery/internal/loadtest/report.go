@@ -0,0 +1,247 @@
+package loadtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/org/llm-marketplace/services/discovery/internal/config"
+)
+
+// bucketBoundsMS are the upper bounds of the latency histogram buckets; the
+// last bucket is unbounded
+var bucketBoundsMS = []float64{1, 2, 5, 10, 25, 50, 100, 200, 300, 500, 750, 1000, 2000, 5000}
+
+// maxErrorSamples caps how many distinct error messages a report keeps
+const maxErrorSamples = 10
+
+// Report is the machine-readable result of a run
+type Report struct {
+	StartedAt   time.Time                  `json:"started_at"`
+	DurationMS  float64                    `json:"duration_ms"`
+	Concurrency int                        `json:"concurrency"`
+	Requests    int                        `json:"requests"`
+	Errors      int                        `json:"errors"`
+	ErrorRate   float64                    `json:"error_rate"`
+	Throughput  float64                    `json:"throughput_rps"`
+	Latency     LatencySummary             `json:"latency"`
+	Histogram   []Bucket                   `json:"histogram"`
+	StatusCodes map[int]int                `json:"status_codes"` // 0 counts calls that got no response
+	Endpoints   map[string]*EndpointReport `json:"endpoints"`
+	ErrorSample []string                   `json:"error_sample,omitempty"`
+
+	// Set by Check
+	Targets    *Targets `json:"targets,omitempty"`
+	Passed     bool     `json:"passed"`
+	Violations []string `json:"violations,omitempty"`
+}
+
+// EndpointReport is the part of a run spent on one Request
+type EndpointReport struct {
+	Requests  int            `json:"requests"`
+	Errors    int            `json:"errors"`
+	Latency   LatencySummary `json:"latency"`
+	Histogram []Bucket       `json:"histogram"`
+}
+
+// LatencySummary holds latency statistics in milliseconds
+type LatencySummary struct {
+	MinMS  float64 `json:"min_ms"`
+	MeanMS float64 `json:"mean_ms"`
+	P50MS  float64 `json:"p50_ms"`
+	P90MS  float64 `json:"p90_ms"`
+	P95MS  float64 `json:"p95_ms"`
+	P99MS  float64 `json:"p99_ms"`
+	MaxMS  float64 `json:"max_ms"`
+}
+
+// Bucket counts calls that took at most UpperMS and more than the previous
+// bucket's bound. The last bucket has no upper bound and omits it.
+type Bucket struct {
+	UpperMS *float64 `json:"le_ms,omitempty"`
+	Count   int      `json:"count"`
+}
+
+// Targets are the latency objectives a report was checked against
+type Targets struct {
+	P95MS int `json:"p95_ms"`
+	P99MS int `json:"p99_ms"`
+}
+
+// Check compares the run with the P95 and P99 targets in cfg, records the
+// outcome in the report and reports whether every target was met. Targets
+// left at zero are not checked. A run in which every call failed never passes.
+func (r *Report) Check(cfg config.PerformanceConfig) bool {
+	r.Targets = &Targets{P95MS: cfg.TargetP95LatencyMS, P99MS: cfg.TargetP99LatencyMS}
+	r.Violations = nil
+
+	if r.Requests == r.Errors {
+		r.Violations = append(r.Violations, fmt.Sprintf("no successful requests out of %d", r.Requests))
+	}
+	if target := cfg.TargetP95LatencyMS; target > 0 && r.Latency.P95MS > float64(target) {
+		r.Violations = append(r.Violations, fmt.Sprintf("p95 latency %.1fms exceeds target %dms", r.Latency.P95MS, target))
+	}
+	if target := cfg.TargetP99LatencyMS; target > 0 && r.Latency.P99MS > float64(target) {
+		r.Violations = append(r.Violations, fmt.Sprintf("p99 latency %.1fms exceeds target %dms", r.Latency.P99MS, target))
+	}
+
+	r.Passed = len(r.Violations) == 0
+	return r.Passed
+}
+
+// WriteJSON writes the report as indented JSON
+func (r *Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// WriteFile writes the report as JSON to path
+func (r *Report) WriteFile(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create report: %w", err)
+	}
+	if err := r.WriteJSON(f); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+	return f.Close()
+}
+
+// recorder collects the outcome of every call in a run
+type recorder struct {
+	mu          sync.Mutex
+	all         []time.Duration
+	byName      map[string][]time.Duration
+	errors      map[string]int
+	statusCodes map[int]int
+	errorSample []string
+}
+
+func newRecorder(requests []Request) *recorder {
+	r := &recorder{
+		byName:      make(map[string][]time.Duration, len(requests)),
+		errors:      make(map[string]int, len(requests)),
+		statusCodes: make(map[int]int),
+	}
+	for _, req := range requests {
+		r.byName[req.Name] = nil
+	}
+	return r
+}
+
+func (r *recorder) record(name string, status int, latency time.Duration, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.all = append(r.all, latency)
+	r.byName[name] = append(r.byName[name], latency)
+	r.statusCodes[status]++
+	if err != nil {
+		r.errors[name]++
+		msg := fmt.Sprintf("%s: %v", name, err)
+		if len(r.errorSample) < maxErrorSamples && !contains(r.errorSample, msg) {
+			r.errorSample = append(r.errorSample, msg)
+		}
+	}
+}
+
+func (r *recorder) report(started time.Time, elapsed time.Duration, concurrency int) *Report {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	report := &Report{
+		StartedAt:   started.UTC(),
+		DurationMS:  ms(elapsed),
+		Concurrency: concurrency,
+		Requests:    len(r.all),
+		Latency:     summarize(r.all),
+		Histogram:   histogram(r.all),
+		StatusCodes: r.statusCodes,
+		Endpoints:   make(map[string]*EndpointReport, len(r.byName)),
+		ErrorSample: r.errorSample,
+	}
+	for name, latencies := range r.byName {
+		report.Endpoints[name] = &EndpointReport{
+			Requests:  len(latencies),
+			Errors:    r.errors[name],
+			Latency:   summarize(latencies),
+			Histogram: histogram(latencies),
+		}
+		report.Errors += r.errors[name]
+	}
+	if report.Requests > 0 {
+		report.ErrorRate = float64(report.Errors) / float64(report.Requests)
+	}
+	if elapsed > 0 {
+		report.Throughput = float64(report.Requests) / elapsed.Seconds()
+	}
+	return report
+}
+
+// summarize computes latency statistics; percentiles use the nearest-rank method
+func summarize(latencies []time.Duration) LatencySummary {
+	if len(latencies) == 0 {
+		return LatencySummary{}
+	}
+
+	sorted := make([]time.Duration, len(latencies))
+	copy(sorted, latencies)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	var total time.Duration
+	for _, l := range sorted {
+		total += l
+	}
+
+	return LatencySummary{
+		MinMS:  ms(sorted[0]),
+		MeanMS: ms(total / time.Duration(len(sorted))),
+		P50MS:  ms(percentile(sorted, 0.50)),
+		P90MS:  ms(percentile(sorted, 0.90)),
+		P95MS:  ms(percentile(sorted, 0.95)),
+		P99MS:  ms(percentile(sorted, 0.99)),
+		MaxMS:  ms(sorted[len(sorted)-1]),
+	}
+}
+
+// percentile returns the smallest latency at or above fraction p of sorted
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	rank := int(math.Ceil(p * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+func histogram(latencies []time.Duration) []Bucket {
+	buckets := make([]Bucket, len(bucketBoundsMS)+1)
+	for i := range bucketBoundsMS {
+		bound := bucketBoundsMS[i]
+		buckets[i].UpperMS = &bound
+	}
+	for _, l := range latencies {
+		i := sort.SearchFloat64s(bucketBoundsMS, ms(l))
+		buckets[i].Count++
+	}
+	return buckets
+}
+
+func ms(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+func contains(values []string, v string) bool {
+	for _, s := range values {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
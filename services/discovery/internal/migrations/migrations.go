@@ -0,0 +1,43 @@
+// Package migrations owns the discovery database schema: the SQL files in
+// sql/, embedded in the binary and applied by the shared migrate package.
+package migrations
+
+import (
+	"embed"
+
+	"github.com/org/llm-marketplace/pkg/marketplace/migrate"
+	"go.uber.org/zap"
+
+	"github.com/org/llm-marketplace/services/discovery/internal/postgres"
+)
+
+//go:embed sql/*.sql
+var files embed.FS
+
+// lockID serializes migrations across replicas starting at the same time
+const lockID = 7263541
+
+// ErrDrift is returned when the database schema does not match the migrations
+// in this build
+var ErrDrift = migrate.ErrDrift
+
+type (
+	// Migration is one schema change, read from sql/<version>_<name>.sql
+	Migration = migrate.Migration
+	// AppliedMigration is a row of schema_migrations
+	AppliedMigration = migrate.AppliedMigration
+	// Status compares the database with the migrations in this build
+	Status = migrate.Status
+	// Migrator applies the embedded migrations to a database
+	Migrator = migrate.Migrator
+)
+
+// Load returns the embedded migrations in version order
+func Load() ([]Migration, error) {
+	return migrate.Load(files, "sql")
+}
+
+// New creates a migrator for the embedded migrations
+func New(pgPool *postgres.Pool, logger *zap.Logger) (*Migrator, error) {
+	return migrate.New(pgPool.Pool, files, "sql", lockID, logger)
+}
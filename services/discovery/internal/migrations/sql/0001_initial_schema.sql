@@ -1,4 +1,5 @@
--- Discovery Service Database Schema
+-- Initial discovery schema. Every statement is idempotent, so databases
+-- created from the old scripts/init.sql adopt it without changes.
 
 -- Services table
 CREATE TABLE IF NOT EXISTS services (
@@ -38,12 +39,12 @@ CREATE TABLE IF NOT EXISTS services (
 );
 
 -- Indexes for services
-CREATE INDEX idx_services_status ON services(status);
-CREATE INDEX idx_services_category ON services(category);
-CREATE INDEX idx_services_provider ON services(provider_id);
-CREATE INDEX idx_services_created ON services(created_at DESC);
-CREATE INDEX idx_services_rating ON services(avg_rating DESC);
-CREATE INDEX idx_services_tags ON services USING GIN(tags);
+CREATE INDEX IF NOT EXISTS idx_services_status ON services(status);
+CREATE INDEX IF NOT EXISTS idx_services_category ON services(category);
+CREATE INDEX IF NOT EXISTS idx_services_provider ON services(provider_id);
+CREATE INDEX IF NOT EXISTS idx_services_created ON services(created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_services_rating ON services(avg_rating DESC);
+CREATE INDEX IF NOT EXISTS idx_services_tags ON services USING GIN(tags);
 
 -- User interactions table
 CREATE TABLE IF NOT EXISTS user_interactions (
@@ -60,10 +61,10 @@ CREATE TABLE IF NOT EXISTS user_interactions (
 );
 
 -- Indexes for user_interactions
-CREATE INDEX idx_interactions_user ON user_interactions(user_id);
-CREATE INDEX idx_interactions_service ON user_interactions(service_id);
-CREATE INDEX idx_interactions_timestamp ON user_interactions(timestamp DESC);
-CREATE INDEX idx_interactions_type ON user_interactions(interaction_type);
+CREATE INDEX IF NOT EXISTS idx_interactions_user ON user_interactions(user_id);
+CREATE INDEX IF NOT EXISTS idx_interactions_service ON user_interactions(service_id);
+CREATE INDEX IF NOT EXISTS idx_interactions_timestamp ON user_interactions(timestamp DESC);
+CREATE INDEX IF NOT EXISTS idx_interactions_type ON user_interactions(interaction_type);
 
 -- Service ratings table
 CREATE TABLE IF NOT EXISTS service_ratings (
@@ -79,9 +80,9 @@ CREATE TABLE IF NOT EXISTS service_ratings (
 );
 
 -- Indexes for ratings
-CREATE INDEX idx_ratings_service ON service_ratings(service_id);
-CREATE INDEX idx_ratings_user ON service_ratings(user_id);
-CREATE INDEX idx_ratings_created ON service_ratings(created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_ratings_service ON service_ratings(service_id);
+CREATE INDEX IF NOT EXISTS idx_ratings_user ON service_ratings(user_id);
+CREATE INDEX IF NOT EXISTS idx_ratings_created ON service_ratings(created_at DESC);
 
 -- Categories table
 CREATE TABLE IF NOT EXISTS categories (
@@ -114,9 +115,75 @@ CREATE TABLE IF NOT EXISTS search_analytics (
 );
 
 -- Indexes for search analytics
-CREATE INDEX idx_search_analytics_timestamp ON search_analytics(timestamp DESC);
-CREATE INDEX idx_search_analytics_user ON search_analytics(user_id);
-CREATE INDEX idx_search_analytics_query ON search_analytics USING GIN(to_tsvector('english', query));
+CREATE INDEX IF NOT EXISTS idx_search_analytics_timestamp ON search_analytics(timestamp DESC);
+CREATE INDEX IF NOT EXISTS idx_search_analytics_user ON search_analytics(user_id);
+CREATE INDEX IF NOT EXISTS idx_search_analytics_query ON search_analytics USING GIN(to_tsvector('english', query));
+
+-- Webhook subscriptions for catalog change events
+CREATE TABLE IF NOT EXISTS webhook_subscriptions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    owner_id VARCHAR(255),
+    tenant_id VARCHAR(255),
+    url TEXT NOT NULL,
+    events TEXT[] NOT NULL,
+    filter JSONB NOT NULL DEFAULT '{}',
+    secret VARCHAR(128) NOT NULL,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_subscriptions_owner ON webhook_subscriptions(owner_id);
+CREATE INDEX IF NOT EXISTS idx_webhook_subscriptions_events ON webhook_subscriptions USING GIN(events);
+
+-- Webhook delivery log and retry queue
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    subscription_id UUID NOT NULL REFERENCES webhook_subscriptions(id) ON DELETE CASCADE,
+    event_type VARCHAR(50) NOT NULL,
+    payload JSONB NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    last_status_code INTEGER,
+    last_error TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    delivered_at TIMESTAMP WITH TIME ZONE,
+
+    CONSTRAINT valid_delivery_status CHECK (status IN ('pending', 'succeeded', 'failed'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due ON webhook_deliveries(next_attempt_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_subscription ON webhook_deliveries(subscription_id, created_at DESC);
+
+-- Hourly search analytics rollups
+CREATE TABLE IF NOT EXISTS search_query_rollups (
+    bucket TIMESTAMP WITH TIME ZONE NOT NULL,
+    query TEXT NOT NULL,
+    searches BIGINT NOT NULL DEFAULT 0,
+    zero_results BIGINT NOT NULL DEFAULT 0,
+    clicks BIGINT NOT NULL DEFAULT 0,
+
+    PRIMARY KEY (bucket, query)
+);
+
+-- Search latency histogram; le_ms is the bucket's upper bound
+CREATE TABLE IF NOT EXISTS search_latency_rollups (
+    bucket TIMESTAMP WITH TIME ZONE NOT NULL,
+    le_ms INTEGER NOT NULL,
+    count BIGINT NOT NULL DEFAULT 0,
+
+    PRIMARY KEY (bucket, le_ms)
+);
+
+-- Popular successful queries for autocomplete; score decays with the configured half-life
+CREATE TABLE IF NOT EXISTS query_suggestions (
+    query TEXT PRIMARY KEY,
+    score DOUBLE PRECISION NOT NULL,
+    searches BIGINT NOT NULL DEFAULT 0,
+    last_seen_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_query_suggestions_prefix ON query_suggestions(query text_pattern_ops);
 
 -- Function to update service metrics
 CREATE OR REPLACE FUNCTION update_service_metrics()
@@ -133,6 +200,7 @@ END;
 $$ LANGUAGE plpgsql;
 
 -- Trigger for updating service metrics on interactions
+DROP TRIGGER IF EXISTS trigger_update_service_metrics ON user_interactions;
 CREATE TRIGGER trigger_update_service_metrics
 AFTER INSERT ON user_interactions
 FOR EACH ROW
@@ -162,6 +230,7 @@ END;
 $$ LANGUAGE plpgsql;
 
 -- Trigger for updating service ratings
+DROP TRIGGER IF EXISTS trigger_update_service_rating ON service_ratings;
 CREATE TRIGGER trigger_update_service_rating
 AFTER INSERT OR UPDATE OR DELETE ON service_ratings
 FOR EACH ROW
@@ -190,6 +259,7 @@ END;
 $$ LANGUAGE plpgsql;
 
 -- Trigger for updating tag usage
+DROP TRIGGER IF EXISTS trigger_update_tag_usage ON services;
 CREATE TRIGGER trigger_update_tag_usage
 AFTER INSERT OR UPDATE OR DELETE ON services
 FOR EACH ROW
@@ -256,8 +326,3 @@ GROUP BY s.id
 HAVING COUNT(*) >= 10
 ORDER BY recent_interactions DESC
 LIMIT 100;
-
--- Grant permissions
-GRANT ALL PRIVILEGES ON ALL TABLES IN SCHEMA public TO marketplace;
-GRANT ALL PRIVILEGES ON ALL SEQUENCES IN SCHEMA public TO marketplace;
-GRANT EXECUTE ON ALL FUNCTIONS IN SCHEMA public TO marketplace;
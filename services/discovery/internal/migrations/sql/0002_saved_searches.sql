@@ -0,0 +1,21 @@
+-- Searches a user saved to rerun later, optionally notifying them when new
+-- services match
+CREATE TABLE saved_searches (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL,
+    tenant_id VARCHAR(255),
+    name VARCHAR(255) NOT NULL,
+    query TEXT NOT NULL DEFAULT '',
+    filters JSONB NOT NULL DEFAULT '{}',
+    notify BOOLEAN NOT NULL DEFAULT FALSE,
+    last_run_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT unique_saved_search_name UNIQUE (user_id, name)
+);
+
+CREATE INDEX idx_saved_searches_notify ON saved_searches(updated_at) WHERE notify;
+
+-- A user's most recent interactions, read for every recommendation request
+CREATE INDEX idx_interactions_user_timestamp ON user_interactions(user_id, timestamp DESC);
@@ -0,0 +1,3 @@
+-- Services indexed from registry catalog events record the revision they
+-- were indexed at, so duplicate and out-of-date events are ignored
+ALTER TABLE services ADD COLUMN IF NOT EXISTS registry_revision BIGINT;
@@ -0,0 +1,26 @@
+-- Feature store: features of users and services computed in the background
+-- for search ranking and recommendations. Definitions record every version
+-- of every feature computed; values of versions no longer computed are
+-- deleted with the next refresh.
+CREATE TABLE IF NOT EXISTS feature_definitions (
+    name VARCHAR(100) NOT NULL,
+    version INTEGER NOT NULL,
+    entity_type VARCHAR(20) NOT NULL,
+    description TEXT,
+    registered_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+
+    PRIMARY KEY (name, version)
+);
+
+CREATE TABLE IF NOT EXISTS feature_values (
+    entity_type VARCHAR(20) NOT NULL,
+    entity_id TEXT NOT NULL,
+    feature VARCHAR(100) NOT NULL,
+    version INTEGER NOT NULL,
+    value JSONB NOT NULL,
+    computed_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+
+    PRIMARY KEY (entity_type, entity_id, feature, version)
+);
+
+CREATE INDEX IF NOT EXISTS idx_feature_values_computed ON feature_values(computed_at);
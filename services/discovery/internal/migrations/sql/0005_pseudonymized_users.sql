@@ -0,0 +1,7 @@
+-- Rows record whether their user ID has been replaced by its pseudonym, so
+-- the privacy job pseudonymizes each row once
+ALTER TABLE user_interactions ADD COLUMN IF NOT EXISTS user_pseudonymized BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE search_analytics ADD COLUMN IF NOT EXISTS user_pseudonymized BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE INDEX IF NOT EXISTS idx_interactions_unpseudonymized ON user_interactions(user_id) WHERE NOT user_pseudonymized;
+CREATE INDEX IF NOT EXISTS idx_search_analytics_unpseudonymized ON search_analytics(user_id) WHERE NOT user_pseudonymized;
@@ -0,0 +1,19 @@
+-- Hourly counts of queries and categories searched together in a session.
+-- Each pair is stored once, with the lesser value first.
+CREATE TABLE IF NOT EXISTS query_cooccurrence_rollups (
+    bucket TIMESTAMP WITH TIME ZONE NOT NULL,
+    query_a TEXT NOT NULL,
+    query_b TEXT NOT NULL,
+    count BIGINT NOT NULL DEFAULT 0,
+
+    PRIMARY KEY (bucket, query_a, query_b)
+);
+
+CREATE TABLE IF NOT EXISTS category_cooccurrence_rollups (
+    bucket TIMESTAMP WITH TIME ZONE NOT NULL,
+    category_a TEXT NOT NULL,
+    category_b TEXT NOT NULL,
+    count BIGINT NOT NULL DEFAULT 0,
+
+    PRIMARY KEY (bucket, category_a, category_b)
+);
@@ -0,0 +1,21 @@
+-- Catalog writes in the order they were made, read by partners polling the
+-- syndication feed. Each row keeps the document as written and the tenant
+-- and access rules the service had before, so a change that hides a service
+-- from a partner can be sent as a removal.
+CREATE TABLE IF NOT EXISTS catalog_changes (
+    seq BIGSERIAL PRIMARY KEY,
+    service_id TEXT NOT NULL,
+    document JSONB NOT NULL,
+    existed BOOLEAN NOT NULL,
+    previous_tenant_id TEXT,
+    previous_access JSONB,
+    changed_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_catalog_changes_changed_at ON catalog_changes(changed_at);
+
+-- The last change pruned. A cursor before it has missed changes.
+CREATE TABLE IF NOT EXISTS catalog_changes_pruned (
+    id BOOLEAN PRIMARY KEY DEFAULT TRUE CHECK (id),
+    pruned_through BIGINT NOT NULL
+);
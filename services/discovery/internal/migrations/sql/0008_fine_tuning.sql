@@ -0,0 +1,8 @@
+-- The kind of each listing, and the base models and turnaround of
+-- fine-tuning offerings, for the fine-tuning recommendation placement.
+-- Rows indexed before kinds were recorded are services.
+ALTER TABLE services ADD COLUMN IF NOT EXISTS kind TEXT NOT NULL DEFAULT 'service';
+ALTER TABLE services ADD COLUMN IF NOT EXISTS base_models TEXT[];
+ALTER TABLE services ADD COLUMN IF NOT EXISTS turnaround_hours INTEGER;
+
+CREATE INDEX IF NOT EXISTS idx_services_fine_tuning ON services(status) WHERE kind = 'fine_tuning';
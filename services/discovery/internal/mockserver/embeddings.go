@@ -0,0 +1,128 @@
+package mockserver
+
+import (
+	"encoding/json"
+	"hash/fnv"
+	"math"
+	"net/http"
+	"strings"
+	"unicode"
+
+	"github.com/org/llm-marketplace/services/discovery/internal/search"
+)
+
+// EmbeddingOptions configures the mock embedding service
+type EmbeddingOptions struct {
+	Behavior
+	Dimensions int    // Length of vectors for models not in ModelDimensions
+	Model      string // Reported when a request names no model
+	HealthPath string // Answered with 200 on GET; /health when empty
+
+	ModelDimensions map[string]int // Vector length by requested model
+}
+
+// Embeddings implements the embedding service's HTTP API. Vectors are hashed
+// from the words of each text, so the same text always gets the same vector
+// and texts sharing words are close, which is enough for semantic search to
+// rank plausibly.
+type Embeddings struct {
+	opts EmbeddingOptions
+	mux  *http.ServeMux
+}
+
+// NewEmbeddings creates the mock embedding service
+func NewEmbeddings(opts EmbeddingOptions) *Embeddings {
+	if opts.HealthPath == "" {
+		opts.HealthPath = "/health"
+	}
+	e := &Embeddings{opts: opts, mux: http.NewServeMux()}
+	e.mux.HandleFunc("POST /embeddings", e.embed)
+	e.mux.HandleFunc("GET "+opts.HealthPath, e.health)
+	return e
+}
+
+func (e *Embeddings) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	e.mux.ServeHTTP(w, r)
+}
+
+func (e *Embeddings) embed(w http.ResponseWriter, r *http.Request) {
+	var req search.EmbeddingRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(req.Texts) == 0 {
+		http.Error(w, "texts is required", http.StatusBadRequest)
+		return
+	}
+
+	if err := e.opts.delay(r.Context()); err != nil {
+		return
+	}
+	if e.opts.fail() {
+		w.Header().Set("Retry-After", "1")
+		http.Error(w, "injected failure", http.StatusServiceUnavailable)
+		return
+	}
+
+	resp := search.EmbeddingResponse{Model: req.Model, Embeddings: make([][]float32, len(req.Texts))}
+	if resp.Model == "" {
+		resp.Model = e.opts.Model
+	}
+	dimensions, ok := e.opts.ModelDimensions[resp.Model]
+	if !ok {
+		dimensions = e.opts.Dimensions
+	}
+	for i, text := range req.Texts {
+		resp.Embeddings[i] = HashEmbedding(text, dimensions)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// health answers at once, so probes see the service as up however it is
+// configured to degrade
+func (e *Embeddings) health(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(`{"status":"ok"}`))
+}
+
+// HashEmbedding returns a unit vector of the given length in which each
+// lower-cased word of text adds ±1 to the dimension it hashes to
+func HashEmbedding(text string, dimensions int) []float32 {
+	vector := make([]float32, dimensions)
+	if dimensions <= 0 {
+		return vector
+	}
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(words) == 0 {
+		// Zero vectors can't be compared by cosine similarity
+		words = []string{""}
+	}
+	for _, word := range words {
+		h := fnv.New64a()
+		h.Write([]byte(word))
+		sum := h.Sum64()
+		sign := float32(1)
+		if sum>>63 == 1 {
+			sign = -1
+		}
+		vector[sum%uint64(dimensions)] += sign
+	}
+
+	var norm float64
+	for _, v := range vector {
+		norm += float64(v) * float64(v)
+	}
+	if norm == 0 {
+		// Words cancelled each other out
+		vector[0], norm = 1, 1
+	}
+	scale := float32(1 / math.Sqrt(norm))
+	for i := range vector {
+		vector[i] *= scale
+	}
+	return vector
+}
@@ -0,0 +1,44 @@
+// Package mockserver stands in for the services discovery calls out to, the
+// embedding service and the policy engine, so the service can be run end to
+// end locally and in CI without models or policies. Each mock implements the
+// real contract with canned answers and can be made slow or flaky to exercise
+// timeouts, retries and fallbacks.
+package mockserver
+
+import (
+	"context"
+	"math/rand/v2"
+	"time"
+)
+
+// Behavior is how a mock degrades its answers
+type Behavior struct {
+	Latency     time.Duration // Added to every call
+	Jitter      time.Duration // Up to this much more, uniformly drawn
+	FailureRate float64       // Fraction of calls answered with a retryable error
+}
+
+// delay waits out the configured latency, returning early with ctx's error
+// when the caller gives up first
+func (b Behavior) delay(ctx context.Context) error {
+	d := b.Latency
+	if b.Jitter > 0 {
+		d += rand.N(b.Jitter)
+	}
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// fail reports whether this call should be failed
+func (b Behavior) fail() bool {
+	return b.FailureRate > 0 && rand.Float64() < b.FailureRate
+}
@@ -0,0 +1,146 @@
+package mockserver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "github.com/org/llm-marketplace/services/discovery/api/proto/policyengine/v1"
+)
+
+// DefaultResponseKey looks up the canned validation for services without
+// their own
+const DefaultResponseKey = "*"
+
+// PolicyOptions configures the mock policy engine
+type PolicyOptions struct {
+	Behavior
+	PolicyVersion string // Reported on validations that don't set their own
+
+	// Responses holds canned validations keyed by service ID or name, with
+	// DefaultResponseKey for the rest. Services matching none are compliant.
+	Responses map[string]*pb.ValidateServiceResponse
+}
+
+// Policy implements the policy engine's gRPC API. Validations return the
+// canned responses, and access and consumption checks are always allowed.
+// Policies can't be managed.
+type Policy struct {
+	pb.UnimplementedPolicyEngineServiceServer
+	opts PolicyOptions
+}
+
+// NewPolicy creates the mock policy engine
+func NewPolicy(opts PolicyOptions) *Policy {
+	if opts.PolicyVersion == "" {
+		opts.PolicyVersion = "mock"
+	}
+	return &Policy{opts: opts}
+}
+
+// LoadPolicyResponses reads canned validations from a JSON object mapping
+// service IDs or names to ValidateServiceResponse messages in their JSON
+// form, as in {"*": {"compliant": true}, "svc-1": {"compliant": false,
+// "violations": [{"policy_id": "p1", "severity": "high"}]}}
+func LoadPolicyResponses(path string) (map[string]*pb.ValidateServiceResponse, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policy responses: %w", err)
+	}
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse policy responses: %w", err)
+	}
+	responses := make(map[string]*pb.ValidateServiceResponse, len(raw))
+	for key, msg := range raw {
+		resp := &pb.ValidateServiceResponse{}
+		if err := protojson.Unmarshal(msg, resp); err != nil {
+			return nil, fmt.Errorf("failed to parse policy response for %q: %w", key, err)
+		}
+		responses[key] = resp
+	}
+	return responses, nil
+}
+
+// ValidateService answers with the canned validation for the service
+func (p *Policy) ValidateService(ctx context.Context, req *pb.ValidateServiceRequest) (*pb.ValidateServiceResponse, error) {
+	if err := p.call(ctx); err != nil {
+		return nil, err
+	}
+	start := time.Now()
+
+	resp := &pb.ValidateServiceResponse{Compliant: true}
+	for _, key := range []string{req.GetServiceId(), req.GetName(), DefaultResponseKey} {
+		if canned, ok := p.opts.Responses[key]; ok && key != "" {
+			resp = proto.Clone(canned).(*pb.ValidateServiceResponse)
+			break
+		}
+	}
+	if resp.PolicyVersion == "" {
+		resp.PolicyVersion = p.opts.PolicyVersion
+	}
+	if resp.ValidatedAt == nil {
+		resp.ValidatedAt = timestamppb.Now()
+	}
+	if resp.Metadata == nil {
+		failed := int32(len(resp.Violations))
+		resp.Metadata = &pb.ValidationMetadata{
+			PoliciesEvaluated:    failed,
+			PoliciesFailed:       failed,
+			ValidationDurationMs: time.Since(start).Milliseconds(),
+		}
+	}
+	return resp, nil
+}
+
+// CheckAccess allows every user
+func (p *Policy) CheckAccess(ctx context.Context, req *pb.CheckAccessRequest) (*pb.CheckAccessResponse, error) {
+	if err := p.call(ctx); err != nil {
+		return nil, err
+	}
+	return &pb.CheckAccessResponse{Allowed: true}, nil
+}
+
+// ValidateConsumption allows every request
+func (p *Policy) ValidateConsumption(ctx context.Context, req *pb.ValidateConsumptionRequest) (*pb.ValidateConsumptionResponse, error) {
+	if err := p.call(ctx); err != nil {
+		return nil, err
+	}
+	return &pb.ValidateConsumptionResponse{Allowed: true}, nil
+}
+
+// ListPolicies lists no policies
+func (p *Policy) ListPolicies(ctx context.Context, req *pb.ListPoliciesRequest) (*pb.ListPoliciesResponse, error) {
+	if err := p.call(ctx); err != nil {
+		return nil, err
+	}
+	return &pb.ListPoliciesResponse{}, nil
+}
+
+// HealthCheck always reports serving, without latency or failures
+func (p *Policy) HealthCheck(ctx context.Context, req *pb.HealthCheckRequest) (*pb.HealthCheckResponse, error) {
+	return &pb.HealthCheckResponse{
+		Status:    pb.HealthCheckResponse_SERVING,
+		Details:   map[string]string{"mock": "true"},
+		Timestamp: timestamppb.Now(),
+	}, nil
+}
+
+// call applies the configured latency and failures
+func (p *Policy) call(ctx context.Context) error {
+	if err := p.opts.delay(ctx); err != nil {
+		return status.FromContextError(err).Err()
+	}
+	if p.opts.fail() {
+		return status.Error(codes.Unavailable, "injected failure")
+	}
+	return nil
+}
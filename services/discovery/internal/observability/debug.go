@@ -0,0 +1,85 @@
+package observability
+
+import (
+	"crypto/subtle"
+	"expvar"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	runtimepprof "runtime/pprof"
+
+	"github.com/org/llm-marketplace/services/discovery/internal/config"
+)
+
+// registerDebug adds pprof, expvar and a goroutine dump to mux behind the
+// allowlist and basic auth in cfg. The handlers are registered explicitly
+// rather than through the net/http/pprof import side effect, which would
+// expose them on http.DefaultServeMux.
+func registerDebug(mux *http.ServeMux, cfg config.DebugConfig) {
+	guard := debugGuard(cfg)
+
+	mux.Handle("/debug/pprof/", guard(http.HandlerFunc(pprof.Index)))
+	mux.Handle("/debug/pprof/cmdline", guard(http.HandlerFunc(pprof.Cmdline)))
+	mux.Handle("/debug/pprof/profile", guard(http.HandlerFunc(pprof.Profile)))
+	mux.Handle("/debug/pprof/symbol", guard(http.HandlerFunc(pprof.Symbol)))
+	mux.Handle("/debug/pprof/trace", guard(http.HandlerFunc(pprof.Trace)))
+	mux.Handle("/debug/vars", guard(expvar.Handler()))
+	mux.Handle("/debug/goroutines", guard(http.HandlerFunc(goroutineDump)))
+}
+
+// goroutineDump writes every goroutine's full stack as text
+func goroutineDump(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	runtimepprof.Lookup("goroutine").WriteTo(w, 2)
+}
+
+// debugGuard rejects callers outside the allowed networks with 403 and, when
+// credentials are configured, callers without them with 401
+func debugGuard(cfg config.DebugConfig) func(http.Handler) http.Handler {
+	var networks []*net.IPNet
+	for _, cidr := range cfg.AllowedCIDRs {
+		// Validated with the rest of the config
+		if _, network, err := net.ParseCIDR(cidr); err == nil {
+			networks = append(networks, network)
+		}
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if len(networks) > 0 && !allowedAddr(r.RemoteAddr, networks) {
+				http.Error(w, "forbidden", http.StatusForbidden)
+				return
+			}
+
+			if cfg.Username != "" {
+				user, pass, ok := r.BasicAuth()
+				userOK := subtle.ConstantTimeCompare([]byte(user), []byte(cfg.Username)) == 1
+				passOK := subtle.ConstantTimeCompare([]byte(pass), []byte(cfg.Password)) == 1
+				if !ok || !userOK || !passOK {
+					w.Header().Set("WWW-Authenticate", `Basic realm="debug"`)
+					http.Error(w, "unauthorized", http.StatusUnauthorized)
+					return
+				}
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func allowedAddr(remoteAddr string, networks []*net.IPNet) bool {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	for _, network := range networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -1,11 +1,13 @@
 package observability
 
 import (
-	"net/http"
+	"context"
+	"strconv"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
 )
 
 // Metrics holds all Prometheus metrics
@@ -16,17 +18,65 @@ type Metrics struct {
 	searchResultsTotal    *prometheus.HistogramVec
 	searchErrors          prometheus.Counter
 
+	// Query rewrite metrics
+	queryRewritesTotal     *prometheus.CounterVec
+	queryRewriteDuration   *prometheus.HistogramVec
+	rewrittenSearchesTotal *prometheus.CounterVec
+
+	// Search guardrail metrics
+	searchGuardrailsTotal *prometheus.CounterVec
+	searchBudgetExceededTotal *prometheus.CounterVec
+
 	// Cache metrics
 	cacheHitsTotal        prometheus.Counter
 	cacheMissesTotal      prometheus.Counter
+	cacheStaleServesTotal prometheus.Counter
+	cacheRevalidationsTotal *prometheus.CounterVec
+	cacheWarmupQueriesTotal *prometheus.CounterVec
+	cacheWarmupDuration     prometheus.Gauge
 
 	// Recommendation metrics
 	recommendationRequestsTotal *prometheus.CounterVec
 	recommendationDuration      *prometheus.HistogramVec
+	recommendationFallbacksTotal *prometheus.CounterVec
 
 	// HTTP metrics
 	httpRequestsTotal     *prometheus.CounterVec
 	httpDuration          *prometheus.HistogramVec
+
+	// SLA monitoring metrics
+	slaProbesTotal        *prometheus.CounterVec
+	slaProbeDuration      prometheus.Histogram
+
+	// Webhook metrics
+	webhookDeliveriesTotal *prometheus.CounterVec
+	webhookDuration        prometheus.Histogram
+
+	// Feed metrics
+	feedPushesTotal *prometheus.CounterVec
+
+	// Analytics metrics
+	analyticsEventsTotal   *prometheus.CounterVec
+
+	// Embedding metrics
+	embeddingsGeneratedTotal *prometheus.CounterVec
+	queryEmbeddingsTotal     *prometheus.CounterVec
+	queryEmbeddingDuration   prometheus.Histogram
+	embeddingRequestsTotal   *prometheus.CounterVec
+	embeddingRequestDuration *prometheus.HistogramVec
+
+	// Elasticsearch metrics
+	bulkDocumentsTotal     *prometheus.CounterVec
+	elasticsearchHealthy   prometheus.Gauge
+	policyValidationsTotal *prometheus.CounterVec
+
+	// Readiness metrics
+	readinessStatus        *prometheus.GaugeVec
+	dependencyStatus       *prometheus.GaugeVec
+	dependencyCheckLatency *prometheus.GaugeVec
+
+	// OpenTelemetry instruments for the key latencies and cache outcomes
+	otel *otelInstruments
 }
 
 // InitMetrics initializes all Prometheus metrics
@@ -61,6 +111,42 @@ func InitMetrics() *Metrics {
 				Help: "Total number of search errors",
 			},
 		),
+		queryRewritesTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "discovery_query_rewrites_total",
+				Help: "Total number of query pipeline stage runs by stage and result (changed, unchanged, error)",
+			},
+			[]string{"stage", "result"},
+		),
+		queryRewriteDuration: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "discovery_query_rewrite_duration_seconds",
+				Help:    "Query pipeline stage duration in seconds",
+				Buckets: []float64{0.00001, 0.0001, 0.001, 0.01, 0.05, 0.1, 0.25, 0.5, 1.0},
+			},
+			[]string{"stage"},
+		),
+		rewrittenSearchesTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "discovery_rewritten_searches_total",
+				Help: "Total number of searches by whether the pipeline rewrote the query and whether anything was found (results, empty)",
+			},
+			[]string{"rewritten", "outcome"},
+		),
+		searchGuardrailsTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "discovery_search_guardrails_total",
+				Help: "Total number of searches stopped by a guardrail, by guard and action (rejected, partial, failed)",
+			},
+			[]string{"guard", "action"},
+		),
+		searchBudgetExceededTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "discovery_search_budget_exceeded_total",
+				Help: "Total number of search stages cut short, by stage (embedding, cache, search) and cause (deadline, canceled)",
+			},
+			[]string{"stage", "cause"},
+		),
 		cacheHitsTotal: prometheus.NewCounter(
 			prometheus.CounterOpts{
 				Name: "discovery_cache_hits_total",
@@ -73,6 +159,32 @@ func InitMetrics() *Metrics {
 				Help: "Total number of cache misses",
 			},
 		),
+		cacheStaleServesTotal: prometheus.NewCounter(
+			prometheus.CounterOpts{
+				Name: "discovery_cache_stale_serves_total",
+				Help: "Total number of cached search responses served past their soft TTL",
+			},
+		),
+		cacheRevalidationsTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "discovery_cache_revalidations_total",
+				Help: "Total number of background refreshes of stale search responses, by result (refreshed, failed, skipped)",
+			},
+			[]string{"result"},
+		),
+		cacheWarmupQueriesTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "discovery_cache_warmup_queries_total",
+				Help: "Total number of popular queries searched to prime the cache at startup, by result (primed, failed)",
+			},
+			[]string{"result"},
+		),
+		cacheWarmupDuration: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Name: "discovery_cache_warmup_seconds",
+				Help: "How long the last startup cache warm-up took",
+			},
+		),
 		recommendationRequestsTotal: prometheus.NewCounterVec(
 			prometheus.CounterOpts{
 				Name: "discovery_recommendation_requests_total",
@@ -88,6 +200,13 @@ func InitMetrics() *Metrics {
 			},
 			[]string{"algorithm"},
 		),
+		recommendationFallbacksTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "discovery_recommendation_hydration_fallbacks_total",
+				Help: "Total number of recommended services Postgres failed to load that were looked up in Elasticsearch, by result (loaded, failed)",
+			},
+			[]string{"result"},
+		),
 		httpRequestsTotal: prometheus.NewCounterVec(
 			prometheus.CounterOpts{
 				Name: "discovery_http_requests_total",
@@ -103,6 +222,126 @@ func InitMetrics() *Metrics {
 			},
 			[]string{"method", "path"},
 		),
+		slaProbesTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "discovery_sla_probes_total",
+				Help: "Total number of service endpoint probes",
+			},
+			[]string{"result"},
+		),
+		slaProbeDuration: prometheus.NewHistogram(
+			prometheus.HistogramOpts{
+				Name:    "discovery_sla_probe_duration_seconds",
+				Help:    "Service endpoint probe duration in seconds",
+				Buckets: prometheus.DefBuckets,
+			},
+		),
+		webhookDeliveriesTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "discovery_webhook_deliveries_total",
+				Help: "Total number of webhook delivery attempts",
+			},
+			[]string{"result"},
+		),
+		webhookDuration: prometheus.NewHistogram(
+			prometheus.HistogramOpts{
+				Name:    "discovery_webhook_delivery_duration_seconds",
+				Help:    "Webhook delivery attempt duration in seconds",
+				Buckets: prometheus.DefBuckets,
+			},
+		),
+		feedPushesTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "discovery_feed_pushes_total",
+				Help: "Total number of feed pages pushed to partners by result (succeeded, failed, expired)",
+			},
+			[]string{"result"},
+		),
+		analyticsEventsTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "discovery_analytics_events_total",
+				Help: "Total number of analytics events by type and publish result",
+			},
+			[]string{"type", "result"},
+		),
+		embeddingsGeneratedTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "discovery_embeddings_generated_total",
+				Help: "Total number of document embeddings generated by result",
+			},
+			[]string{"result"},
+		),
+		queryEmbeddingsTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "discovery_query_embeddings_total",
+				Help: "Total number of query embedding lookups by result (hit, miss, timeout, error)",
+			},
+			[]string{"result"},
+		),
+		queryEmbeddingDuration: prometheus.NewHistogram(
+			prometheus.HistogramOpts{
+				Name:    "discovery_query_embedding_duration_seconds",
+				Help:    "Time spent obtaining query embeddings, including cache lookups",
+				Buckets: []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1.0},
+			},
+		),
+		embeddingRequestsTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "discovery_embedding_requests_total",
+				Help: "Total number of embedding service attempts by result (success, retry, error)",
+			},
+			[]string{"result"},
+		),
+		embeddingRequestDuration: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "discovery_embedding_request_duration_seconds",
+				Help:    "Embedding service attempt duration in seconds",
+				Buckets: []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1.0, 2.5, 5.0},
+			},
+			[]string{"result"},
+		),
+		bulkDocumentsTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "discovery_bulk_documents_total",
+				Help: "Total number of documents sent in bulk requests by result (indexed, retried, failed)",
+			},
+			[]string{"result"},
+		),
+		policyValidationsTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "discovery_policy_validations_total",
+				Help: "Total number of documents checked with the policy engine while indexing by result (compliant, noncompliant, unavailable)",
+			},
+			[]string{"result"},
+		),
+		elasticsearchHealthy: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Name: "discovery_elasticsearch_healthy",
+				Help: "Whether the last Elasticsearch health probes passed (1) or not (0)",
+			},
+		),
+		readinessStatus: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "discovery_readiness_status",
+				Help: "Current readiness of the service; 1 for the active status (healthy, degraded, unhealthy)",
+			},
+			[]string{"status"},
+		),
+		dependencyStatus: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "discovery_dependency_status",
+				Help: "Result of the last readiness check per dependency; 1 for the active status",
+			},
+			[]string{"dependency", "status"},
+		),
+		dependencyCheckLatency: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "discovery_dependency_check_latency_seconds",
+				Help: "Latency of the last readiness check per dependency",
+			},
+			[]string{"dependency"},
+		),
+		otel: newOTelInstruments(),
 	}
 
 	// Register all metrics
@@ -111,21 +350,49 @@ func InitMetrics() *Metrics {
 		m.searchDuration,
 		m.searchResultsTotal,
 		m.searchErrors,
+		m.queryRewritesTotal,
+		m.queryRewriteDuration,
+		m.rewrittenSearchesTotal,
+		m.searchGuardrailsTotal,
+		m.searchBudgetExceededTotal,
 		m.cacheHitsTotal,
 		m.cacheMissesTotal,
+		m.cacheStaleServesTotal,
+		m.cacheRevalidationsTotal,
+		m.cacheWarmupQueriesTotal,
+		m.cacheWarmupDuration,
 		m.recommendationRequestsTotal,
 		m.recommendationDuration,
+		m.recommendationFallbacksTotal,
 		m.httpRequestsTotal,
 		m.httpDuration,
+		m.slaProbesTotal,
+		m.slaProbeDuration,
+		m.webhookDeliveriesTotal,
+		m.webhookDuration,
+		m.feedPushesTotal,
+		m.analyticsEventsTotal,
+		m.embeddingsGeneratedTotal,
+		m.queryEmbeddingsTotal,
+		m.queryEmbeddingDuration,
+		m.embeddingRequestsTotal,
+		m.embeddingRequestDuration,
+		m.bulkDocumentsTotal,
+		m.elasticsearchHealthy,
+		m.policyValidationsTotal,
+		m.readinessStatus,
+		m.dependencyStatus,
+		m.dependencyCheckLatency,
 	)
 
 	return m
 }
 
 // Search metrics methods
-func (m *Metrics) SearchDuration(duration time.Duration) {
+func (m *Metrics) SearchDuration(ctx context.Context, duration time.Duration) {
 	m.searchDuration.WithLabelValues("success").Observe(duration.Seconds())
 	m.searchRequestsTotal.WithLabelValues("success").Inc()
+	m.otel.searchLatency.Record(ctx, duration.Seconds())
 }
 
 func (m *Metrics) SearchResults(count int) {
@@ -138,12 +405,52 @@ func (m *Metrics) SearchError() {
 }
 
 // Cache metrics methods
-func (m *Metrics) CacheHit() {
+func (m *Metrics) CacheHit(ctx context.Context) {
 	m.cacheHitsTotal.Inc()
+	m.otel.cacheLookup(ctx, true)
 }
 
-func (m *Metrics) CacheMiss() {
+func (m *Metrics) CacheMiss(ctx context.Context) {
 	m.cacheMissesTotal.Inc()
+	m.otel.cacheLookup(ctx, false)
+}
+
+// CacheStaleServe counts a cached search response served past its soft TTL
+// while it is refreshed in the background
+func (m *Metrics) CacheStaleServe() {
+	m.cacheStaleServesTotal.Inc()
+}
+
+// CacheRevalidation counts a background refresh of a stale search response:
+// refreshed, failed, or skipped because another was already running
+func (m *Metrics) CacheRevalidation(result string) {
+	m.cacheRevalidationsTotal.WithLabelValues(result).Inc()
+}
+
+// CacheWarmupQuery counts a popular query searched at startup: primed or failed
+func (m *Metrics) CacheWarmupQuery(result string) {
+	m.cacheWarmupQueriesTotal.WithLabelValues(result).Inc()
+}
+
+// CacheWarmupFinished records how long the startup cache warm-up took
+func (m *Metrics) CacheWarmupFinished(duration time.Duration) {
+	m.cacheWarmupDuration.Set(duration.Seconds())
+}
+
+// Dependency metrics methods
+
+// DependencyCall records the latency of one call to dependency (elasticsearch,
+// redis, embedding_service) under operation, and whether it failed
+func (m *Metrics) DependencyCall(ctx context.Context, dependency, operation string, duration time.Duration, err error) {
+	outcome := "success"
+	if err != nil {
+		outcome = "error"
+	}
+	m.otel.dependencyLatency.Record(ctx, duration.Seconds(), metric.WithAttributes(
+		attribute.String("dependency", dependency),
+		attribute.String("operation", operation),
+		attribute.String("outcome", outcome),
+	))
 }
 
 // Recommendation metrics methods
@@ -152,16 +459,121 @@ func (m *Metrics) RecommendationRequest(algorithm string, duration time.Duration
 	m.recommendationDuration.WithLabelValues(algorithm).Observe(duration.Seconds())
 }
 
+// RecommendationHydrationFallback counts services looked up in Elasticsearch
+// after Postgres failed to load them: loaded, or failed
+func (m *Metrics) RecommendationHydrationFallback(result string, services int) {
+	m.recommendationFallbacksTotal.WithLabelValues(result).Add(float64(services))
+}
+
 // HTTP metrics methods
 func (m *Metrics) HTTPRequest(method, path, status string, duration time.Duration) {
 	m.httpRequestsTotal.WithLabelValues(method, path, status).Inc()
 	m.httpDuration.WithLabelValues(method, path).Observe(duration.Seconds())
 }
 
-// ServeMetrics starts the metrics HTTP server
-func ServeMetrics(addr string) error {
-	mux := http.NewServeMux()
-	mux.Handle("/metrics", promhttp.Handler())
+// SLA monitoring metrics methods
+func (m *Metrics) SLAProbe(result string, duration time.Duration) {
+	m.slaProbesTotal.WithLabelValues(result).Inc()
+	m.slaProbeDuration.Observe(duration.Seconds())
+}
+
+// Webhook metrics methods
+func (m *Metrics) WebhookDelivery(result string, duration time.Duration) {
+	m.webhookDeliveriesTotal.WithLabelValues(result).Inc()
+	m.webhookDuration.Observe(duration.Seconds())
+}
 
-	return http.ListenAndServe(addr, mux)
+// Feed metrics methods
+func (m *Metrics) FeedPush(result string) {
+	m.feedPushesTotal.WithLabelValues(result).Inc()
+}
+
+// Analytics metrics methods
+func (m *Metrics) AnalyticsEvent(eventType, result string) {
+	m.analyticsEventsTotal.WithLabelValues(eventType, result).Inc()
+}
+
+// Query rewrite metrics methods
+func (m *Metrics) QueryRewrite(stage, result string, duration time.Duration) {
+	m.queryRewritesTotal.WithLabelValues(stage, result).Inc()
+	m.queryRewriteDuration.WithLabelValues(stage).Observe(duration.Seconds())
+}
+
+// RewrittenSearch counts a search by whether its query was rewritten and
+// whether it found anything, for comparing zero-result rates
+func (m *Metrics) RewrittenSearch(rewritten bool, total int) {
+	outcome := "results"
+	if total == 0 {
+		outcome = "empty"
+	}
+	m.rewrittenSearchesTotal.WithLabelValues(strconv.FormatBool(rewritten), outcome).Inc()
+}
+
+// SearchGuardrail counts a search a guardrail rejected, or that timed out
+// and returned partial results or failed
+func (m *Metrics) SearchGuardrail(guard, action string) {
+	m.searchGuardrailsTotal.WithLabelValues(guard, action).Inc()
+}
+
+// SearchBudgetExceeded counts a stage of a search cut short because its
+// latency budget ran out (deadline) or the client went away (canceled)
+func (m *Metrics) SearchBudgetExceeded(stage, cause string) {
+	m.searchBudgetExceededTotal.WithLabelValues(stage, cause).Inc()
+}
+
+// Embedding metrics methods
+func (m *Metrics) EmbeddingsGenerated(result string, count int) {
+	m.embeddingsGeneratedTotal.WithLabelValues(result).Add(float64(count))
+}
+
+func (m *Metrics) QueryEmbedding(result string, duration time.Duration) {
+	m.queryEmbeddingsTotal.WithLabelValues(result).Inc()
+	m.queryEmbeddingDuration.Observe(duration.Seconds())
+}
+
+func (m *Metrics) EmbeddingRequest(result string, duration time.Duration) {
+	m.embeddingRequestsTotal.WithLabelValues(result).Inc()
+	m.embeddingRequestDuration.WithLabelValues(result).Observe(duration.Seconds())
+}
+
+// Elasticsearch metrics methods
+func (m *Metrics) BulkDocuments(result string, count int) {
+	m.bulkDocumentsTotal.WithLabelValues(result).Add(float64(count))
+}
+
+func (m *Metrics) PolicyValidation(result string) {
+	m.policyValidationsTotal.WithLabelValues(result).Inc()
+}
+
+func (m *Metrics) ElasticsearchHealthy(healthy bool) {
+	if healthy {
+		m.elasticsearchHealthy.Set(1)
+	} else {
+		m.elasticsearchHealthy.Set(0)
+	}
+}
+
+// Readiness metrics methods
+
+// readinessStatuses are the values of the status label, set one-hot
+var readinessStatuses = []string{"healthy", "degraded", "unhealthy"}
+
+func (m *Metrics) ReadinessStatus(status string) {
+	for _, s := range readinessStatuses {
+		m.readinessStatus.WithLabelValues(s).Set(boolGauge(s == status))
+	}
+}
+
+func (m *Metrics) DependencyCheck(dependency, status string, latency time.Duration) {
+	for _, s := range readinessStatuses {
+		m.dependencyStatus.WithLabelValues(dependency, s).Set(boolGauge(s == status))
+	}
+	m.dependencyCheckLatency.WithLabelValues(dependency).Set(latency.Seconds())
+}
+
+func boolGauge(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
 }
@@ -2,17 +2,43 @@ package observability
 
 import (
 	"fmt"
+	"math/rand/v2"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/org/llm-marketplace/services/discovery/internal/config"
+	"github.com/org/llm-marketplace/services/discovery/internal/problem"
+	"github.com/org/llm-marketplace/services/discovery/internal/requestid"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
 	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 )
 
-// GinLogger returns a Gin middleware for logging
-func GinLogger(logger *zap.Logger) gin.HandlerFunc {
+// GinRequestID reuses the caller's X-Request-ID or generates one, echoes it
+// on the response and attaches it to the request context for logs, problem
+// details and downstream calls
+func GinRequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(requestid.Header)
+		if !requestid.Valid(id) {
+			id = requestid.New()
+		}
+		c.Header(requestid.Header, id)
+		c.Request = c.Request.WithContext(requestid.WithID(c.Request.Context(), id))
+		c.Next()
+	}
+}
+
+// GinLogger returns a Gin middleware for access logging. Server errors are
+// logged at Error, requests slower than cfg.SlowThreshold at Warn, client
+// errors always, and other requests at cfg.SuccessSampleRate.
+func GinLogger(logger *zap.Logger, cfg config.AccessLogConfig) gin.HandlerFunc {
+	// The stack of the middleware says nothing about a failed request
+	logger = logger.WithOptions(zap.AddStacktrace(zapcore.FatalLevel))
+
 	return func(c *gin.Context) {
 		start := time.Now()
 		path := c.Request.URL.Path
@@ -22,19 +48,47 @@ func GinLogger(logger *zap.Logger) gin.HandlerFunc {
 
 		end := time.Now()
 		latency := end.Sub(start)
+		status := c.Writer.Status()
+		slow := cfg.SlowThreshold > 0 && latency >= cfg.SlowThreshold
 
-		logger.Info("HTTP request",
+		if status < 400 && !slow && !sampled(cfg.SuccessSampleRate) {
+			return
+		}
+
+		fields := []zap.Field{
 			zap.String("method", c.Request.Method),
 			zap.String("path", path),
 			zap.String("query", query),
-			zap.Int("status", c.Writer.Status()),
+			zap.Int("status", status),
 			zap.Duration("latency", latency),
 			zap.String("ip", c.ClientIP()),
 			zap.String("user_agent", c.Request.UserAgent()),
-		)
+			zap.String("request_id", requestid.FromContext(c.Request.Context())),
+		}
+		if sc := trace.SpanContextFromContext(c.Request.Context()); sc.HasTraceID() {
+			fields = append(fields,
+				zap.String("trace_id", sc.TraceID().String()),
+				zap.String("span_id", sc.SpanID().String()),
+			)
+		}
+
+		switch {
+		case status >= 500:
+			logger.Error("HTTP request", fields...)
+		case slow:
+			logger.Warn("Slow HTTP request", append(fields, zap.Duration("slow_threshold", cfg.SlowThreshold))...)
+		default:
+			logger.Info("HTTP request", fields...)
+		}
 	}
 }
 
+// sampled reports whether to log a healthy request; rates outside (0, 1) log
+// every request
+func sampled(rate float64) bool {
+	return rate <= 0 || rate >= 1 || rand.Float64() < rate
+}
+
 // GinRecovery returns a Gin middleware for panic recovery
 func GinRecovery(logger *zap.Logger) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -44,8 +98,9 @@ func GinRecovery(logger *zap.Logger) gin.HandlerFunc {
 					zap.Any("error", err),
 					zap.String("path", c.Request.URL.Path),
 					zap.String("method", c.Request.Method),
+					zap.String("request_id", requestid.FromContext(c.Request.Context())),
 				)
-				c.AbortWithStatus(500)
+				problem.Abort(c, problem.Internal, "")
 			}
 		}()
 		c.Next()
@@ -58,11 +113,14 @@ func GinTracing() gin.HandlerFunc {
 
 	return func(c *gin.Context) {
 		spanName := fmt.Sprintf("%s %s", c.Request.Method, c.FullPath())
-		ctx, span := tracer.Start(c.Request.Context(), spanName,
+		// Continue a trace started by the caller
+		ctx := otel.GetTextMapPropagator().Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+		ctx, span := tracer.Start(ctx, spanName,
 			trace.WithAttributes(
 				attribute.String("http.method", c.Request.Method),
 				attribute.String("http.url", c.Request.URL.String()),
 				attribute.String("http.route", c.FullPath()),
+				attribute.String("http.request_id", requestid.FromContext(ctx)),
 			),
 		)
 		defer span.End()
@@ -0,0 +1,158 @@
+package observability
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/org/llm-marketplace/services/discovery/internal/config"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	promexporter "go.opentelemetry.io/otel/exporters/prometheus"
+	"go.opentelemetry.io/otel/metric"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+	"go.uber.org/zap"
+)
+
+// meterName scopes the instruments recorded by the service
+const meterName = "github.com/org/llm-marketplace/services/discovery"
+
+// latencyBuckets are the histogram boundaries in seconds
+var latencyBuckets = []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1.0, 2.5, 5.0}
+
+// otelInstruments are created against the global meter provider, so they
+// record nothing until InitOTelMetrics installs one. Measurements taken with a
+// sampled span in the context carry it as an exemplar.
+type otelInstruments struct {
+	searchLatency     metric.Float64Histogram
+	dependencyLatency metric.Float64Histogram
+	cacheLookups      metric.Int64Counter
+
+	// Totals behind the hit ratio gauge
+	cacheHits   atomic.Int64
+	cacheMisses atomic.Int64
+}
+
+func newOTelInstruments() *otelInstruments {
+	meter := otel.Meter(meterName)
+	inst := &otelInstruments{}
+
+	// Instrument names are constant, so creation can only fail on a bug
+	inst.searchLatency, _ = meter.Float64Histogram("discovery.search.latency",
+		metric.WithDescription("End-to-end search latency"),
+		metric.WithUnit("s"),
+		metric.WithExplicitBucketBoundaries(latencyBuckets...),
+	)
+	inst.dependencyLatency, _ = meter.Float64Histogram("discovery.dependency.latency",
+		metric.WithDescription("Latency of calls to Elasticsearch, Redis and the embedding service"),
+		metric.WithUnit("s"),
+		metric.WithExplicitBucketBoundaries(latencyBuckets...),
+	)
+	inst.cacheLookups, _ = meter.Int64Counter("discovery.cache.lookups",
+		metric.WithDescription("Search cache lookups by result (hit, miss)"),
+	)
+	hitRatio, _ := meter.Float64ObservableGauge("discovery.cache.hit_ratio",
+		metric.WithDescription("Share of search cache lookups that hit since startup"),
+		metric.WithUnit("1"),
+	)
+	meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+		hits, misses := inst.cacheHits.Load(), inst.cacheMisses.Load()
+		if hits+misses > 0 {
+			o.ObserveFloat64(hitRatio, float64(hits)/float64(hits+misses))
+		}
+		return nil
+	}, hitRatio)
+
+	return inst
+}
+
+func (i *otelInstruments) cacheLookup(ctx context.Context, hit bool) {
+	result := "miss"
+	if hit {
+		i.cacheHits.Add(1)
+		result = "hit"
+	} else {
+		i.cacheMisses.Add(1)
+	}
+	i.cacheLookups.Add(ctx, 1, metric.WithAttributes(attribute.String("result", result)))
+}
+
+// InitOTelMetrics installs the global meter provider that backs the OTel
+// instruments. With cfg.Prometheus they are registered on reg and served by
+// the scrape endpoint; with an OTLP endpoint they are also pushed to it.
+func InitOTelMetrics(cfg config.OTelMetricsConfig, res *resource.Resource, reg prometheus.Registerer, logger *zap.Logger) (func(), error) {
+	if !cfg.Enabled {
+		logger.Info("OTel metrics are disabled")
+		return func() {}, nil
+	}
+
+	opts := []sdkmetric.Option{sdkmetric.WithResource(res)}
+
+	if cfg.Prometheus {
+		exp, err := promexporter.New(
+			promexporter.WithRegisterer(reg),
+			promexporter.WithoutScopeInfo(),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create Prometheus exporter: %w", err)
+		}
+		opts = append(opts, sdkmetric.WithReader(exp))
+	}
+
+	if cfg.OTLP.Endpoint != "" {
+		exp, err := newOTLPMetricExporter(cfg.OTLP)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create OTLP metric exporter: %w", err)
+		}
+		interval := cfg.ExportInterval
+		if interval <= 0 {
+			interval = 30 * time.Second
+		}
+		opts = append(opts, sdkmetric.WithReader(
+			sdkmetric.NewPeriodicReader(exp, sdkmetric.WithInterval(interval)),
+		))
+	}
+
+	mp := sdkmetric.NewMeterProvider(opts...)
+	otel.SetMeterProvider(mp)
+
+	logger.Info("OTel metrics initialized",
+		zap.Bool("prometheus", cfg.Prometheus),
+		zap.String("otlp_endpoint", cfg.OTLP.Endpoint),
+		zap.String("otlp_protocol", cfg.OTLP.Protocol),
+	)
+
+	return func() {
+		if err := mp.Shutdown(context.Background()); err != nil {
+			logger.Error("Failed to shutdown meter provider", zap.Error(err))
+		}
+	}, nil
+}
+
+func newOTLPMetricExporter(cfg config.OTLPConfig) (sdkmetric.Exporter, error) {
+	ctx := context.Background()
+	if cfg.Protocol == "http" {
+		opts := []otlpmetrichttp.Option{otlpmetrichttp.WithEndpoint(cfg.Endpoint)}
+		if cfg.Insecure {
+			opts = append(opts, otlpmetrichttp.WithInsecure())
+		}
+		if len(cfg.Headers) > 0 {
+			opts = append(opts, otlpmetrichttp.WithHeaders(cfg.Headers))
+		}
+		return otlpmetrichttp.New(ctx, opts...)
+	}
+
+	opts := []otlpmetricgrpc.Option{otlpmetricgrpc.WithEndpoint(cfg.Endpoint)}
+	if cfg.Insecure {
+		opts = append(opts, otlpmetricgrpc.WithInsecure())
+	}
+	if len(cfg.Headers) > 0 {
+		opts = append(opts, otlpmetricgrpc.WithHeaders(cfg.Headers))
+	}
+	return otlpmetricgrpc.New(ctx, opts...)
+}
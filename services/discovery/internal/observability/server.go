@@ -0,0 +1,84 @@
+package observability
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/org/llm-marketplace/services/discovery/internal/config"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// MetricsServer exposes the Prometheus registry on its own listener and mux,
+// so nothing registered on http.DefaultServeMux is served alongside it
+type MetricsServer struct {
+	server *http.Server
+	tls    config.TLSConfig
+}
+
+// NewMetricsServer creates the server for cfg; Serve starts it
+func NewMetricsServer(cfg config.MetricsConfig) *MetricsServer {
+	path := cfg.Path
+	if path == "" {
+		path = "/metrics"
+	}
+
+	mux := http.NewServeMux()
+	// OpenMetrics negotiation carries the exemplars on OTel histograms
+	mux.Handle(path, promhttp.InstrumentMetricHandler(
+		prometheus.DefaultRegisterer,
+		promhttp.HandlerFor(prometheus.DefaultGatherer, promhttp.HandlerOpts{EnableOpenMetrics: true}),
+	))
+	if cfg.Debug.Enabled {
+		registerDebug(mux, cfg.Debug)
+	}
+
+	server := &http.Server{
+		Addr:              fmt.Sprintf(":%d", cfg.Port),
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	if cfg.TLS.Enabled() {
+		server.TLSConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+
+	return &MetricsServer{server: server, tls: cfg.TLS}
+}
+
+// Addr is the address the server listens on
+func (s *MetricsServer) Addr() string {
+	return s.server.Addr
+}
+
+// Serve listens and serves until Shutdown; it returns nil once shut down
+func (s *MetricsServer) Serve() error {
+	ln, err := net.Listen("tcp", s.server.Addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", s.server.Addr, err)
+	}
+	return s.ServeListener(ln)
+}
+
+// ServeListener serves on ln, which the server takes ownership of
+func (s *MetricsServer) ServeListener(ln net.Listener) error {
+	var err error
+	if s.tls.Enabled() {
+		err = s.server.ServeTLS(ln, s.tls.CertFile, s.tls.KeyFile)
+	} else {
+		err = s.server.Serve(ln)
+	}
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+// Shutdown stops accepting scrapes and waits for in-flight ones to finish
+func (s *MetricsServer) Shutdown(ctx context.Context) error {
+	return s.server.Shutdown(ctx)
+}
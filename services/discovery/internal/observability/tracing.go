@@ -6,55 +6,50 @@ import (
 
 	"github.com/org/llm-marketplace/services/discovery/internal/config"
 	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/exporters/jaeger"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
 	"go.opentelemetry.io/otel/sdk/resource"
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 )
 
 // InitTracing initializes OpenTelemetry tracing
-func InitTracing(cfg config.TracingConfig, logger *zap.Logger) (func(), error) {
+func InitTracing(cfg config.TracingConfig, res *resource.Resource, logger *zap.Logger) (func(), error) {
 	if !cfg.Enabled {
 		logger.Info("Tracing is disabled")
 		return func() {}, nil
 	}
 
-	// Create Jaeger exporter
-	exp, err := jaeger.New(
-		jaeger.WithCollectorEndpoint(
-			jaeger.WithEndpoint(cfg.JaegerEndpoint),
-		),
-	)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create Jaeger exporter: %w", err)
-	}
-
-	// Create resource
-	res, err := resource.New(
-		context.Background(),
-		resource.WithAttributes(
-			semconv.ServiceName("llm-marketplace-discovery"),
-			semconv.ServiceVersion("1.0.0"),
-		),
-	)
+	exp, endpoint, err := newSpanExporter(cfg, logger)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create resource: %w", err)
+		return nil, err
 	}
 
 	// Create trace provider
 	tp := sdktrace.NewTracerProvider(
 		sdktrace.WithBatcher(exp),
 		sdktrace.WithResource(res),
-		sdktrace.WithSampler(sdktrace.TraceIDRatioBased(cfg.SamplingRate)),
+		sdktrace.WithSampler(newSampler(cfg)),
 	)
 
-	// Set global trace provider
+	// Set global trace provider and propagate W3C trace context to
+	// downstream calls
 	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
 
 	logger.Info("Tracing initialized",
 		zap.String("exporter", cfg.Exporter),
-		zap.String("endpoint", cfg.JaegerEndpoint),
+		zap.String("endpoint", endpoint),
+		zap.String("sampler", cfg.Sampler),
 		zap.Float64("sampling_rate", cfg.SamplingRate),
 	)
 
@@ -65,3 +60,91 @@ func InitTracing(cfg config.TracingConfig, logger *zap.Logger) (func(), error) {
 		}
 	}, nil
 }
+
+// newSpanExporter creates the configured exporter and reports where it sends
+// spans
+func newSpanExporter(cfg config.TracingConfig, logger *zap.Logger) (sdktrace.SpanExporter, string, error) {
+	if cfg.Exporter == "jaeger" {
+		logger.Warn("The jaeger trace exporter is deprecated; Jaeger accepts OTLP on port 4317")
+		exp, err := jaeger.New(
+			jaeger.WithCollectorEndpoint(
+				jaeger.WithEndpoint(cfg.JaegerEndpoint),
+			),
+		)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to create Jaeger exporter: %w", err)
+		}
+		return exp, cfg.JaegerEndpoint, nil
+	}
+
+	ctx := context.Background()
+	var exp sdktrace.SpanExporter
+	var err error
+	if cfg.OTLP.Protocol == "http" {
+		opts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(cfg.OTLP.Endpoint)}
+		if cfg.OTLP.Insecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		if len(cfg.OTLP.Headers) > 0 {
+			opts = append(opts, otlptracehttp.WithHeaders(cfg.OTLP.Headers))
+		}
+		exp, err = otlptracehttp.New(ctx, opts...)
+	} else {
+		opts := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(cfg.OTLP.Endpoint)}
+		if cfg.OTLP.Insecure {
+			opts = append(opts, otlptracegrpc.WithInsecure())
+		}
+		if len(cfg.OTLP.Headers) > 0 {
+			opts = append(opts, otlptracegrpc.WithHeaders(cfg.OTLP.Headers))
+		}
+		exp, err = otlptracegrpc.New(ctx, opts...)
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create OTLP trace exporter: %w", err)
+	}
+	return exp, cfg.OTLP.Endpoint, nil
+}
+
+// newSampler builds the configured sampler. parent_based keeps the sampling
+// decision of an incoming trace and samples new ones at the configured rate.
+func newSampler(cfg config.TracingConfig) sdktrace.Sampler {
+	switch cfg.Sampler {
+	case "always_on":
+		return sdktrace.AlwaysSample()
+	case "ratio":
+		return sdktrace.TraceIDRatioBased(cfg.SamplingRate)
+	default:
+		return sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SamplingRate))
+	}
+}
+
+// NewResource identifies the service on exported traces and metrics. attrs
+// are applied last, after OTEL_RESOURCE_ATTRIBUTES, so configuration wins;
+// empty values are skipped.
+func NewResource(attrs map[string]string) (*resource.Resource, error) {
+	configured := make([]attribute.KeyValue, 0, len(attrs))
+	for key, value := range attrs {
+		if value != "" {
+			configured = append(configured, attribute.String(key, value))
+		}
+	}
+
+	return resource.New(
+		context.Background(),
+		resource.WithAttributes(
+			semconv.ServiceName("llm-marketplace-discovery"),
+			semconv.ServiceVersion("1.0.0"),
+		),
+		resource.WithFromEnv(),
+		resource.WithAttributes(configured...),
+	)
+}
+
+// EndSpan marks span as failed when err is set and ends it
+func EndSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
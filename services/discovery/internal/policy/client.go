@@ -0,0 +1,196 @@
+// Package policy checks services with the policy engine over gRPC
+package policy
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/org/llm-marketplace/pkg/marketplace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
+	pb "github.com/org/llm-marketplace/services/discovery/api/proto/policyengine/v1"
+	"github.com/org/llm-marketplace/services/discovery/internal/config"
+	"github.com/org/llm-marketplace/services/discovery/internal/elasticsearch"
+	"github.com/org/llm-marketplace/services/discovery/internal/observability"
+)
+
+// Dial connects to the policy engine. The connection is made lazily, so this
+// succeeds while the policy engine is down.
+func Dial(cfg config.PolicyEngineConfig) (*grpc.ClientConn, error) {
+	opts := append([]grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}, observability.GRPCDialOptions()...)
+	conn, err := grpc.NewClient(cfg.GRPCEndpoint, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to policy engine: %w", err)
+	}
+	return conn, nil
+}
+
+// Client implements elasticsearch.PolicyValidator
+type Client struct {
+	client  pb.PolicyEngineServiceClient
+	timeout time.Duration
+}
+
+// NewClient creates a client on conn; each call is limited to timeout
+func NewClient(conn grpc.ClientConnInterface, timeout time.Duration) *Client {
+	return &Client{client: pb.NewPolicyEngineServiceClient(conn), timeout: timeout}
+}
+
+// ValidateService asks the policy engine whether desc complies with the
+// marketplace policies and summarises the answer
+func (c *Client) ValidateService(ctx context.Context, desc *marketplace.ServiceDescriptor) (*elasticsearch.PolicyCompliance, error) {
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	resp, err := c.client.ValidateService(ctx, ToProto(desc))
+	if err != nil {
+		return nil, fmt.Errorf("policy validation failed: %w", err)
+	}
+
+	compliance := &elasticsearch.PolicyCompliance{
+		Compliant:     resp.GetCompliant(),
+		PolicyVersion: resp.GetPolicyVersion(),
+		ValidatedAt:   time.Now().UTC(),
+	}
+	if resp.GetValidatedAt() != nil {
+		compliance.ValidatedAt = resp.GetValidatedAt().AsTime()
+	}
+	seen := make(map[string]bool)
+	for _, v := range resp.GetViolations() {
+		if id := v.GetPolicyId(); id != "" && !seen[id] {
+			seen[id] = true
+			compliance.FailingPolicies = append(compliance.FailingPolicies, id)
+		}
+	}
+	return compliance, nil
+}
+
+// ToProto converts a descriptor to a validation request
+func ToProto(desc *marketplace.ServiceDescriptor) *pb.ValidateServiceRequest {
+	req := &pb.ValidateServiceRequest{
+		ServiceId:      desc.ServiceID,
+		Name:           desc.Name,
+		Version:        desc.Version,
+		Description:    desc.Description,
+		ProviderId:     desc.ProviderID,
+		Category:       desc.Category,
+		Endpoint:       endpointToProto(desc.Endpoint),
+		Compliance:     complianceToProto(desc.Compliance),
+		Sla:            slaToProto(desc.SLA),
+		Pricing:        pricingToProto(desc.Pricing),
+		ServiceClass:   desc.Class,
+		TenantId:       desc.TenantID,
+		Kind:           desc.Kind,
+		PromptTemplate: promptTemplateToProto(desc.PromptTemplate),
+		Dataset:        datasetToProto(desc.Dataset),
+		FineTuning:     fineTuningToProto(desc.FineTuning),
+	}
+	for _, c := range desc.Capabilities {
+		req.Capabilities = append(req.Capabilities, &pb.ServiceCapability{Name: c.Name, Description: c.Description})
+	}
+	for _, d := range desc.Dependencies {
+		req.Dependencies = append(req.Dependencies, &pb.ServiceDependency{ServiceId: d.ServiceID, Role: d.Role})
+	}
+	for _, r := range desc.ServingRegions {
+		req.ServingRegions = append(req.ServingRegions, &pb.ServingRegion{Region: r.Region, MedianLatencyMs: int32(r.MedianLatencyMS)})
+	}
+	return req
+}
+
+func promptTemplateToProto(t *marketplace.PromptTemplate) *pb.PromptTemplate {
+	if t == nil {
+		return nil
+	}
+	template := &pb.PromptTemplate{Template: t.Template, ModelFamilies: t.ModelFamilies}
+	for _, v := range t.Variables {
+		template.Variables = append(template.Variables, &pb.TemplateVariable{
+			Name:        v.Name,
+			Description: v.Description,
+			Required:    v.Required,
+			Default:     v.Default,
+		})
+	}
+	return template
+}
+
+func datasetToProto(d *marketplace.DatasetInfo) *pb.Dataset {
+	if d == nil {
+		return nil
+	}
+	dataset := &pb.Dataset{
+		License:     d.License,
+		Attribution: d.Attribution,
+		Format:      d.Format,
+		SizeBytes:   d.SizeBytes,
+		Records:     d.Records,
+	}
+	if p := d.Provenance; p != nil {
+		dataset.Provenance = &pb.DatasetProvenance{Source: p.Source, Method: p.Method, Url: p.URL}
+	}
+	return dataset
+}
+
+func fineTuningToProto(o *marketplace.FineTuningOffering) *pb.FineTuning {
+	if o == nil {
+		return nil
+	}
+	fineTuning := &pb.FineTuning{BaseModels: o.BaseModels, TurnaroundHours: o.TurnaroundHours}
+	if r := o.DataRequirements; r != nil {
+		fineTuning.DataRequirements = &pb.FineTuningDataRequirements{
+			Formats:      r.Formats,
+			MinExamples:  r.MinExamples,
+			MaxExamples:  r.MaxExamples,
+			MaxSizeBytes: r.MaxSizeBytes,
+		}
+	}
+	return fineTuning
+}
+
+func endpointToProto(e *marketplace.EndpointInfo) *pb.ServiceEndpoint {
+	if e == nil {
+		return nil
+	}
+	return &pb.ServiceEndpoint{Url: e.URL, Protocol: e.Protocol, Authentication: e.Authentication}
+}
+
+func complianceToProto(c *marketplace.ComplianceInfo) *pb.ServiceCompliance {
+	if c == nil {
+		return nil
+	}
+	return &pb.ServiceCompliance{
+		Level:          c.Level,
+		Certifications: c.Certifications,
+		DataResidency:  c.DataResidency,
+		GdprCompliant:  c.GDPRCompliant,
+		HipaaCompliant: c.HIPAACompliant,
+	}
+}
+
+func slaToProto(s *marketplace.SLAInfo) *pb.ServiceSLA {
+	if s == nil {
+		return nil
+	}
+	return &pb.ServiceSLA{Availability: s.Availability, MaxLatency: int32(s.MaxLatencyMS), SupportLevel: s.SupportLevel}
+}
+
+// pricingToProto converts a pricing section. The message has no headline
+// rate, so pricing without tiers is sent as a single tier.
+func pricingToProto(p *marketplace.PricingInfo) *pb.ServicePricing {
+	if p == nil {
+		return nil
+	}
+	pricing := &pb.ServicePricing{Model: p.Model, Currency: p.Currency}
+	tiers := p.Tiers
+	if len(tiers) == 0 && (p.Rate != 0 || p.Unit != "") {
+		tiers = []marketplace.PricingTier{{Rate: p.Rate, Unit: p.Unit}}
+	}
+	for _, t := range tiers {
+		pricing.Rates = append(pricing.Rates, &pb.PricingTier{Tier: t.Tier, Rate: t.Rate, Unit: t.Unit, Description: t.Description})
+	}
+	return pricing
+}
@@ -0,0 +1,95 @@
+package postgres
+
+import (
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// statsCollector exports the pool's statistics, read at scrape time
+type statsCollector struct {
+	pool *pgxpool.Pool
+
+	connections       *prometheus.Desc
+	maxConnections    *prometheus.Desc
+	acquiresTotal     *prometheus.Desc
+	emptyAcquires     *prometheus.Desc
+	canceledAcquires  *prometheus.Desc
+	acquireWait       *prometheus.Desc
+	newConnections    *prometheus.Desc
+	closedConnections *prometheus.Desc
+}
+
+// Collector returns a Prometheus collector for the pool's connection counts
+// and acquire statistics
+func (p *Pool) Collector() prometheus.Collector {
+	return &statsCollector{
+		pool: p.Pool,
+		connections: prometheus.NewDesc(
+			"discovery_postgres_pool_connections",
+			"Current number of pool connections by state (acquired, idle, constructing)",
+			[]string{"state"}, nil,
+		),
+		maxConnections: prometheus.NewDesc(
+			"discovery_postgres_pool_max_connections",
+			"Maximum size of the pool",
+			nil, nil,
+		),
+		acquiresTotal: prometheus.NewDesc(
+			"discovery_postgres_pool_acquires_total",
+			"Total number of successful connection acquires",
+			nil, nil,
+		),
+		emptyAcquires: prometheus.NewDesc(
+			"discovery_postgres_pool_empty_acquires_total",
+			"Total number of acquires that waited because the pool had no idle connection",
+			nil, nil,
+		),
+		canceledAcquires: prometheus.NewDesc(
+			"discovery_postgres_pool_canceled_acquires_total",
+			"Total number of acquires canceled by their context",
+			nil, nil,
+		),
+		acquireWait: prometheus.NewDesc(
+			"discovery_postgres_pool_acquire_wait_seconds_total",
+			"Total time spent waiting for a connection",
+			nil, nil,
+		),
+		newConnections: prometheus.NewDesc(
+			"discovery_postgres_pool_new_connections_total",
+			"Total number of connections opened",
+			nil, nil,
+		),
+		closedConnections: prometheus.NewDesc(
+			"discovery_postgres_pool_closed_connections_total",
+			"Total number of connections closed for exceeding their lifetime or idle time",
+			nil, nil,
+		),
+	}
+}
+
+func (c *statsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.connections
+	ch <- c.maxConnections
+	ch <- c.acquiresTotal
+	ch <- c.emptyAcquires
+	ch <- c.canceledAcquires
+	ch <- c.acquireWait
+	ch <- c.newConnections
+	ch <- c.closedConnections
+}
+
+func (c *statsCollector) Collect(ch chan<- prometheus.Metric) {
+	stat := c.pool.Stat()
+
+	ch <- prometheus.MustNewConstMetric(c.connections, prometheus.GaugeValue, float64(stat.AcquiredConns()), "acquired")
+	ch <- prometheus.MustNewConstMetric(c.connections, prometheus.GaugeValue, float64(stat.IdleConns()), "idle")
+	ch <- prometheus.MustNewConstMetric(c.connections, prometheus.GaugeValue, float64(stat.ConstructingConns()), "constructing")
+	ch <- prometheus.MustNewConstMetric(c.maxConnections, prometheus.GaugeValue, float64(stat.MaxConns()))
+	ch <- prometheus.MustNewConstMetric(c.acquiresTotal, prometheus.CounterValue, float64(stat.AcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.emptyAcquires, prometheus.CounterValue, float64(stat.EmptyAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.canceledAcquires, prometheus.CounterValue, float64(stat.CanceledAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.acquireWait, prometheus.CounterValue, stat.AcquireDuration().Seconds())
+	ch <- prometheus.MustNewConstMetric(c.newConnections, prometheus.CounterValue, float64(stat.NewConnsCount()))
+	ch <- prometheus.MustNewConstMetric(c.closedConnections, prometheus.CounterValue,
+		float64(stat.MaxLifetimeDestroyCount()+stat.MaxIdleDestroyCount()))
+}
@@ -2,55 +2,81 @@ package postgres
 
 import (
 	"context"
-	"database/sql"
 	"fmt"
+	"strconv"
 
-	_ "github.com/lib/pq"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/org/llm-marketplace/services/discovery/internal/config"
 )
 
-// Pool wraps sql.DB for PostgreSQL connections
+// Pool wraps pgxpool.Pool for PostgreSQL connections. Query, QueryRow, Exec,
+// Begin and SendBatch come from the embedded pool and are traced.
 type Pool struct {
-	*sql.DB
+	*pgxpool.Pool
 }
 
-// NewPool creates a new PostgreSQL connection pool
-func NewPool(cfg config.PostgresConfig) (*Pool, error) {
-	db, err := sql.Open("postgres", cfg.GetDSN())
+// NewPool creates a new PostgreSQL connection pool. password, when set,
+// supplies the password for each new connection in place of cfg.Password.
+func NewPool(cfg config.PostgresConfig, password func() string) (*Pool, error) {
+	poolConfig, err := ParseConfig(cfg)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open database: %w", err)
+		return nil, err
 	}
 
-	// Configure connection pool
-	db.SetMaxOpenConns(cfg.MaxOpenConns)
-	db.SetMaxIdleConns(cfg.MaxIdleConns)
-	db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	// Each new connection authenticates with the current password, so a
+	// rotated one applies without recreating the pool
+	if password != nil {
+		poolConfig.BeforeConnect = func(_ context.Context, cc *pgx.ConnConfig) error {
+			cc.Password = password()
+			return nil
+		}
+	}
 
-	// Verify connection
 	ctx := context.Background()
-	if err := db.PingContext(ctx); err != nil {
+	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create pool: %w", err)
+	}
+
+	// Verify connection
+	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
-	return &Pool{DB: db}, nil
+	return &Pool{Pool: pool}, nil
 }
 
-// Ping checks if the database is reachable
-func (p *Pool) Ping(ctx context.Context) error {
-	return p.DB.PingContext(ctx)
-}
+// ParseConfig builds the pool configuration: connection limits, the
+// server-side statement timeout applied to every connection, and tracing
+func ParseConfig(cfg config.PostgresConfig) (*pgxpool.Config, error) {
+	poolConfig, err := pgxpool.ParseConfig(cfg.GetDSN())
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse database config: %w", err)
+	}
 
-// Query executes a query that returns rows
-func (p *Pool) Query(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
-	return p.DB.QueryContext(ctx, query, args...)
-}
+	// Configure connection pool; zero values keep the pgx defaults
+	if cfg.MaxConns > 0 {
+		poolConfig.MaxConns = int32(cfg.MaxConns)
+	}
+	if cfg.MinConns > 0 {
+		poolConfig.MinConns = int32(cfg.MinConns)
+	}
+	if cfg.ConnMaxLifetime > 0 {
+		poolConfig.MaxConnLifetime = cfg.ConnMaxLifetime
+	}
+	if cfg.ConnMaxIdleTime > 0 {
+		poolConfig.MaxConnIdleTime = cfg.ConnMaxIdleTime
+	}
 
-// QueryRow executes a query that returns at most one row
-func (p *Pool) QueryRow(ctx context.Context, query string, args ...interface{}) *sql.Row {
-	return p.DB.QueryRowContext(ctx, query, args...)
-}
+	// Postgres cancels any statement that runs longer, so a slow query fails
+	// instead of holding a connection
+	if cfg.StatementTimeout > 0 {
+		poolConfig.ConnConfig.RuntimeParams["statement_timeout"] = strconv.FormatInt(cfg.StatementTimeout.Milliseconds(), 10)
+	}
+
+	poolConfig.ConnConfig.Tracer = queryTracer{}
 
-// Exec executes a query without returning rows
-func (p *Pool) Exec(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
-	return p.DB.ExecContext(ctx, query, args...)
+	return poolConfig, nil
 }
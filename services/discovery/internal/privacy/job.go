@@ -0,0 +1,189 @@
+package privacy
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/org/llm-marketplace/services/discovery/internal/config"
+	"github.com/org/llm-marketplace/services/discovery/internal/postgres"
+	"go.uber.org/zap"
+)
+
+// Tables of interaction data the job keeps. Each has an id, a user_id, a
+// timestamp and a user_pseudonymized flag.
+const (
+	TableUserInteractions = "user_interactions"
+	TableSearchAnalytics  = "search_analytics"
+)
+
+// Job purges interaction data past its retention and pseudonymizes the
+// user IDs of the rest. Every statement is idempotent, so replicas running
+// it at once only repeat each other's work.
+type Job struct {
+	pgPool     *postgres.Pool
+	config     config.PrivacyConfig
+	pseudonyms *Pseudonymizer
+	logger     *zap.Logger
+}
+
+func NewJob(
+	pgPool *postgres.Pool,
+	cfg config.PrivacyConfig,
+	logger *zap.Logger,
+) *Job {
+	return &Job{
+		pgPool:     pgPool,
+		config:     cfg,
+		pseudonyms: NewPseudonymizer(cfg),
+		logger:     logger,
+	}
+}
+
+// Result counts the rows a run purged and pseudonymized, by table
+type Result struct {
+	Purged        map[string]int64
+	Pseudonymized map[string]int64
+}
+
+// Start runs the job every purge_interval until ctx is cancelled
+func (j *Job) Start(ctx context.Context) {
+	if !j.config.Enabled {
+		j.logger.Info("Privacy job is disabled")
+		return
+	}
+
+	j.logger.Info("Starting privacy job",
+		zap.Duration("interval", j.config.PurgeInterval),
+		zap.Bool("pseudonymize", j.pseudonyms != nil),
+	)
+
+	ticker := time.NewTicker(j.config.PurgeInterval)
+	defer ticker.Stop()
+
+	j.runOnce(ctx)
+
+	for {
+		select {
+		case <-ticker.C:
+			j.runOnce(ctx)
+		case <-ctx.Done():
+			j.logger.Info("Privacy job stopped")
+			return
+		}
+	}
+}
+
+func (j *Job) runOnce(ctx context.Context) {
+	result, err := j.Run(ctx)
+	if err != nil {
+		j.logger.Error("Privacy job failed", zap.Error(err))
+		return
+	}
+	for _, table := range []string{TableUserInteractions, TableSearchAnalytics} {
+		if purged, pseudonymized := result.Purged[table], result.Pseudonymized[table]; purged > 0 || pseudonymized > 0 {
+			j.logger.Info("Privacy job ran",
+				zap.String("table", table),
+				zap.Int64("purged", purged),
+				zap.Int64("pseudonymized", pseudonymized),
+			)
+		}
+	}
+}
+
+// Run purges every table past its retention, then pseudonymizes what is
+// left when pseudonymization is on
+func (j *Job) Run(ctx context.Context) (*Result, error) {
+	result := &Result{Purged: map[string]int64{}, Pseudonymized: map[string]int64{}}
+	for _, t := range []struct {
+		name      string
+		retention time.Duration
+	}{
+		{TableUserInteractions, j.config.Retention.UserInteractions},
+		{TableSearchAnalytics, j.config.Retention.SearchAnalytics},
+	} {
+		if t.retention > 0 {
+			n, err := j.purge(ctx, t.name, time.Now().Add(-t.retention))
+			result.Purged[t.name] = n
+			if err != nil {
+				return result, err
+			}
+		}
+		if j.pseudonyms != nil {
+			n, err := j.pseudonymize(ctx, t.name)
+			result.Pseudonymized[t.name] = n
+			if err != nil {
+				return result, err
+			}
+		}
+	}
+	return result, nil
+}
+
+// purge deletes the table's rows from before before, batch_size at a time
+// so no statement holds locks for long
+func (j *Job) purge(ctx context.Context, table string, before time.Time) (int64, error) {
+	var total int64
+	for {
+		tag, err := j.pgPool.Exec(ctx, fmt.Sprintf(`
+			DELETE FROM %[1]s WHERE id IN (
+				SELECT id FROM %[1]s WHERE timestamp < $1 LIMIT $2
+			)
+		`, table), before, j.config.BatchSize)
+		if err != nil {
+			return total, fmt.Errorf("failed to purge %s: %w", table, err)
+		}
+		total += tag.RowsAffected()
+		if tag.RowsAffected() < int64(j.config.BatchSize) {
+			return total, nil
+		}
+	}
+}
+
+// pseudonymize replaces the user IDs of the table's rows not yet
+// pseudonymized with their pseudonyms, batch_size users at a time
+func (j *Job) pseudonymize(ctx context.Context, table string) (int64, error) {
+	var total int64
+	for {
+		rows, err := j.pgPool.Query(ctx, fmt.Sprintf(`
+			SELECT DISTINCT user_id::text
+			FROM %s
+			WHERE NOT user_pseudonymized AND user_id IS NOT NULL
+			LIMIT $1
+		`, table), j.config.BatchSize)
+		if err != nil {
+			return total, fmt.Errorf("failed to query %s users: %w", table, err)
+		}
+		var ids, pseudonyms []string
+		for rows.Next() {
+			var id string
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				return total, fmt.Errorf("failed to read %s user: %w", table, err)
+			}
+			ids = append(ids, id)
+			pseudonyms = append(pseudonyms, j.pseudonyms.Pseudonym(id))
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return total, fmt.Errorf("failed to read %s users: %w", table, err)
+		}
+		if len(ids) == 0 {
+			return total, nil
+		}
+
+		tag, err := j.pgPool.Exec(ctx, fmt.Sprintf(`
+			UPDATE %s AS t
+			SET user_id = m.pseudonym::uuid, user_pseudonymized = TRUE
+			FROM unnest($1::text[], $2::text[]) AS m(id, pseudonym)
+			WHERE t.user_id = m.id::uuid AND NOT t.user_pseudonymized
+		`, table), ids, pseudonyms)
+		if err != nil {
+			return total, fmt.Errorf("failed to pseudonymize %s: %w", table, err)
+		}
+		total += tag.RowsAffected()
+		if len(ids) < j.config.BatchSize {
+			return total, nil
+		}
+	}
+}
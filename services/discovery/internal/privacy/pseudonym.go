@@ -0,0 +1,62 @@
+// Package privacy keeps interaction data within its retention periods and
+// replaces the user IDs it is stored under with pseudonyms.
+package privacy
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"fmt"
+
+	"github.com/org/llm-marketplace/services/discovery/internal/config"
+)
+
+// Pseudonymizer maps user IDs to pseudonyms: an HMAC-SHA256 of the ID,
+// formatted as a UUID so it fits the columns the ID did. The same ID
+// always has the same pseudonym, so a user's rows stay linked, but the ID
+// can't be recovered without the key.
+type Pseudonymizer struct {
+	key []byte
+}
+
+// NewPseudonymizer returns the pseudonymizer cfg configures, or nil when
+// user IDs are stored as they are
+func NewPseudonymizer(cfg config.PrivacyConfig) *Pseudonymizer {
+	if !cfg.Pseudonymize {
+		return nil
+	}
+	return &Pseudonymizer{key: []byte(cfg.PseudonymKey)}
+}
+
+// Pseudonym returns the pseudonym of userID, or userID itself without a
+// pseudonymizer
+func (p *Pseudonymizer) Pseudonym(userID string) string {
+	if p == nil || userID == "" {
+		return userID
+	}
+	mac := hmac.New(sha256.New, p.key)
+	mac.Write([]byte(userID))
+	b := mac.Sum(nil)[:16]
+	// A version 8 (custom) UUID of the RFC 9562 variant
+	b[6] = b[6]&0x0f | 0x80
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// StoredIDs returns the IDs a user's rows may be stored under: their
+// pseudonym, and their ID until the privacy job has pseudonymized rows
+// written since it last ran
+func (p *Pseudonymizer) StoredIDs(userID string) []string {
+	if p == nil || userID == "" {
+		return []string{userID}
+	}
+	return []string{userID, p.Pseudonym(userID)}
+}
+
+// Canonical returns the ID a stored row's user is known by: the stored ID
+// when it is already a pseudonym, or else its pseudonym
+func (p *Pseudonymizer) Canonical(storedID string, pseudonymized bool) string {
+	if pseudonymized {
+		return storedID
+	}
+	return p.Pseudonym(storedID)
+}
@@ -0,0 +1,57 @@
+// Package problem answers discovery's errors with the marketplace's shared
+// RFC 7807 problem details, adding the trace and request IDs
+package problem
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	shared "github.com/org/llm-marketplace/pkg/marketplace/problem"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/org/llm-marketplace/services/discovery/internal/requestid"
+)
+
+// Type describes a documented class of error
+type Type = shared.Type
+
+// Documented error types: the shared ones and discovery's own. See README
+// "Error Responses".
+var (
+	InvalidRequest     = shared.InvalidRequest
+	Forbidden          = shared.Forbidden
+	NotFound           = shared.NotFound
+	MethodNotAllowed   = shared.MethodNotAllowed
+	Conflict           = shared.Conflict
+	CursorExpired      = Type{Code: "cursor-expired", Title: "Cursor expired", Status: http.StatusGone}
+	QueryTooExpensive  = Type{Code: "query-too-expensive", Title: "Query too expensive", Status: http.StatusUnprocessableEntity}
+	RateLimited        = shared.RateLimited
+	Internal           = shared.InternalError
+	ServiceUnavailable = shared.ServiceUnavailable
+)
+
+// Details is an RFC 7807 problem details body with the IDs to look the
+// request up by
+type Details struct {
+	shared.Details
+	TraceID   string `json:"trace_id,omitempty"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// New builds problem details for the current request
+func New(c *gin.Context, t Type, detail string) *Details {
+	p := &Details{Details: shared.New(t, c.Request.URL.Path, detail)}
+
+	if sc := trace.SpanContextFromContext(c.Request.Context()); sc.HasTraceID() {
+		p.TraceID = sc.TraceID().String()
+	}
+	p.RequestID = requestid.FromContext(c.Request.Context())
+
+	return p
+}
+
+// Abort writes problem details and stops the handler chain
+func Abort(c *gin.Context, t Type, detail string) {
+	shared.Write(c.Writer, t.Status, New(c, t, detail))
+	c.Abort()
+}
@@ -0,0 +1,324 @@
+// Package quota limits how many API requests each API key, tenant or
+// anonymous client makes, per second and per UTC day, by the tier it is on.
+package quota
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/org/llm-marketplace/services/discovery/internal/config"
+	"go.uber.org/zap"
+)
+
+// Kinds of caller, from the most specific
+const (
+	KindAPIKey = "key"
+	KindTenant = "tenant"
+	KindClient = "client"
+)
+
+// overridesKey is the Redis hash of limits set through the admin API, by caller
+const overridesKey = "quota:overrides"
+
+var (
+	// ErrUnknownTier is returned for an override naming a tier not in config
+	ErrUnknownTier = errors.New("unknown quota tier")
+
+	// ErrInvalidOverride is returned for an override without a tier or
+	// limits, with both, or with negative limits
+	ErrInvalidOverride = errors.New("an override needs either a tier or non-negative limits")
+
+	// ErrNotFound is returned when removing an override that isn't set
+	ErrNotFound = errors.New("quota override not found")
+)
+
+// Caller is who a request counts against
+type Caller struct {
+	Kind string `json:"kind"`
+	ID   string `json:"id"`
+}
+
+// Identify returns the caller of a request: its API key, else its tenant,
+// else its client IP
+func Identify(apiKeyID, tenantID, clientIP string) Caller {
+	switch {
+	case apiKeyID != "":
+		return Caller{Kind: KindAPIKey, ID: apiKeyID}
+	case tenantID != "":
+		return Caller{Kind: KindTenant, ID: tenantID}
+	default:
+		return Caller{Kind: KindClient, ID: clientIP}
+	}
+}
+
+func (c Caller) String() string {
+	return c.Kind + ":" + c.ID
+}
+
+// Override replaces the limits a caller's tier gives it: either with those
+// of another tier, or with limits of its own
+type Override struct {
+	Tier string `json:"tier,omitempty"`
+	config.QuotaLimits
+}
+
+// Window is a caller's use of one limit. A limit of 0 is unlimited.
+type Window struct {
+	Limit int       `json:"limit"`
+	Used  int64     `json:"used"`
+	Reset time.Time `json:"reset"`
+}
+
+// Remaining is how many more requests the window allows, or -1 when unlimited
+func (w Window) Remaining() int64 {
+	if w.Limit == 0 {
+		return -1
+	}
+	if remaining := int64(w.Limit) - w.Used; remaining > 0 {
+		return remaining
+	}
+	return 0
+}
+
+// MarshalJSON adds the requests remaining to windows with a limit
+func (w Window) MarshalJSON() ([]byte, error) {
+	type window Window
+	out := struct {
+		window
+		Remaining *int64 `json:"remaining,omitempty"`
+	}{window: window(w)}
+	if w.Limit > 0 {
+		remaining := w.Remaining()
+		out.Remaining = &remaining
+	}
+	return json.Marshal(out)
+}
+
+// Exceeded reports whether the window's last request went over its limit
+func (w Window) Exceeded() bool {
+	return w.Limit > 0 && w.Used > int64(w.Limit)
+}
+
+// Usage is a caller's limits and how much of them it has used
+type Usage struct {
+	Caller     Caller `json:"caller"`
+	Tier       string `json:"tier,omitempty"` // Empty when an override sets the limits directly
+	Overridden bool   `json:"overridden"`
+	Second     Window `json:"second"`
+	Day        Window `json:"day"`
+}
+
+// Allowed reports whether the request that took this usage may proceed
+func (u *Usage) Allowed() bool {
+	return !u.Second.Exceeded() && !u.Day.Exceeded()
+}
+
+// Service counts requests in Redis, in fixed windows shared by every replica
+type Service struct {
+	redisClient *redis.Client
+	config      config.QuotasConfig
+	logger      *zap.Logger
+}
+
+func NewService(
+	redisClient *redis.Client,
+	cfg config.QuotasConfig,
+	logger *zap.Logger,
+) *Service {
+	return &Service{
+		redisClient: redisClient,
+		config:      cfg,
+		logger:      logger,
+	}
+}
+
+// Enabled reports whether requests are limited
+func (s *Service) Enabled() bool {
+	return s != nil && s.config.Enabled
+}
+
+// APIKeyHeader is the header the gateway passes the API key ID of the
+// caller's token in. The gateway removes it from client requests, so callers
+// can't pick the key they are counted against.
+func (s *Service) APIKeyHeader() string {
+	return s.config.APIKeyHeader
+}
+
+// Take counts a request against the caller and returns its usage. A
+// request that goes over a limit still counts towards the second, so
+// callers retrying at once stay limited, but not towards the day.
+func (s *Service) Take(ctx context.Context, caller Caller) (*Usage, error) {
+	now := time.Now().UTC()
+	secondKey, dayKey := windowKeys(caller, now)
+
+	var override *redis.StringCmd
+	var second, day *redis.IntCmd
+	_, err := s.redisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		second = pipe.Incr(ctx, secondKey)
+		pipe.Expire(ctx, secondKey, 2*time.Second)
+		day = pipe.Incr(ctx, dayKey)
+		pipe.Expire(ctx, dayKey, 25*time.Hour)
+		// Last, so that the error of any command before is not hidden by a
+		// missing override's
+		override = pipe.HGet(ctx, overridesKey, caller.String())
+		return nil
+	})
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return nil, fmt.Errorf("failed to count request: %w", err)
+	}
+
+	usage, err := s.usage(caller, override, now)
+	if err != nil {
+		return nil, err
+	}
+	usage.Second.Used, usage.Day.Used = second.Val(), day.Val()
+
+	// The usage returned still counts the request, so that it is rejected
+	if !usage.Allowed() {
+		if err := s.redisClient.Decr(ctx, dayKey).Err(); err != nil {
+			s.logger.Warn("Failed to uncount rejected request", zap.String("caller", caller.String()), zap.Error(err))
+		}
+	}
+	return usage, nil
+}
+
+// Usage returns the caller's usage without counting a request
+func (s *Service) Usage(ctx context.Context, caller Caller) (*Usage, error) {
+	now := time.Now().UTC()
+	secondKey, dayKey := windowKeys(caller, now)
+
+	var override, second, day *redis.StringCmd
+	_, err := s.redisClient.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		second = pipe.Get(ctx, secondKey)
+		day = pipe.Get(ctx, dayKey)
+		override = pipe.HGet(ctx, overridesKey, caller.String())
+		return nil
+	})
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return nil, fmt.Errorf("failed to read usage: %w", err)
+	}
+	// Counters are missing until the window's first request
+	for _, cmd := range []*redis.StringCmd{second, day} {
+		if err := cmd.Err(); err != nil && !errors.Is(err, redis.Nil) {
+			return nil, fmt.Errorf("failed to read usage: %w", err)
+		}
+	}
+
+	usage, err := s.usage(caller, override, now)
+	if err != nil {
+		return nil, err
+	}
+	usage.Second.Used, _ = strconv.ParseInt(second.Val(), 10, 64)
+	usage.Day.Used, _ = strconv.ParseInt(day.Val(), 10, 64)
+	return usage, nil
+}
+
+// usage returns the caller's limits, from its override when it has one,
+// with nothing used yet
+func (s *Service) usage(caller Caller, override *redis.StringCmd, now time.Time) (*Usage, error) {
+	usage := &Usage{Caller: caller, Tier: s.tier(caller)}
+	limits := s.config.Tiers[usage.Tier]
+
+	if raw, err := override.Result(); err == nil {
+		var o Override
+		if err := json.Unmarshal([]byte(raw), &o); err != nil {
+			return nil, fmt.Errorf("failed to decode quota override of %s: %w", caller, err)
+		}
+		usage.Overridden = true
+		usage.Tier = o.Tier
+		if o.Tier != "" {
+			limits = s.config.Tiers[o.Tier]
+		} else {
+			limits = o.QuotaLimits
+		}
+	} else if !errors.Is(err, redis.Nil) {
+		return nil, fmt.Errorf("failed to read quota override of %s: %w", caller, err)
+	}
+
+	second := now.Truncate(time.Second)
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	usage.Second = Window{Limit: limits.RequestsPerSecond, Reset: second.Add(time.Second)}
+	usage.Day = Window{Limit: limits.RequestsPerDay, Reset: day.AddDate(0, 0, 1)}
+	return usage, nil
+}
+
+// tier is the tier config puts the caller on
+func (s *Service) tier(caller Caller) string {
+	var assigned map[string]string
+	switch caller.Kind {
+	case KindAPIKey:
+		assigned = s.config.APIKeys
+	case KindTenant:
+		assigned = s.config.Tenants
+	default:
+		return s.config.AnonymousTier
+	}
+	if tier, ok := assigned[caller.ID]; ok {
+		return tier
+	}
+	return s.config.DefaultTier
+}
+
+// windowKeys are the counters of the second and day now falls in
+func windowKeys(caller Caller, now time.Time) (second, day string) {
+	prefix := "quota:" + caller.String()
+	return prefix + ":s:" + strconv.FormatInt(now.Unix(), 10), prefix + ":d:" + now.Format("20060102")
+}
+
+// Overrides returns every override set through the admin API, by caller
+func (s *Service) Overrides(ctx context.Context) (map[string]Override, error) {
+	fields, err := s.redisClient.HGetAll(ctx, overridesKey).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list quota overrides: %w", err)
+	}
+	overrides := make(map[string]Override, len(fields))
+	for caller, raw := range fields {
+		var o Override
+		if err := json.Unmarshal([]byte(raw), &o); err != nil {
+			s.logger.Warn("Skipping unreadable quota override", zap.String("caller", caller), zap.Error(err))
+			continue
+		}
+		overrides[caller] = o
+	}
+	return overrides, nil
+}
+
+// SetOverride replaces the limits of the caller's tier until the override
+// is removed
+func (s *Service) SetOverride(ctx context.Context, caller Caller, o Override) error {
+	hasLimits := o.RequestsPerSecond != 0 || o.RequestsPerDay != 0
+	if o.RequestsPerSecond < 0 || o.RequestsPerDay < 0 || (o.Tier != "") == hasLimits {
+		return ErrInvalidOverride
+	}
+	if _, ok := s.config.Tiers[o.Tier]; o.Tier != "" && !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownTier, o.Tier)
+	}
+
+	raw, err := json.Marshal(o)
+	if err != nil {
+		return err
+	}
+	if err := s.redisClient.HSet(ctx, overridesKey, caller.String(), raw).Err(); err != nil {
+		return fmt.Errorf("failed to set quota override: %w", err)
+	}
+	s.logger.Info("Quota override set", zap.String("caller", caller.String()), zap.String("tier", o.Tier))
+	return nil
+}
+
+// DeleteOverride returns the caller to the limits of its tier
+func (s *Service) DeleteOverride(ctx context.Context, caller Caller) error {
+	n, err := s.redisClient.HDel(ctx, overridesKey, caller.String()).Result()
+	if err != nil {
+		return fmt.Errorf("failed to delete quota override: %w", err)
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	s.logger.Info("Quota override removed", zap.String("caller", caller.String()))
+	return nil
+}
@@ -0,0 +1,91 @@
+package recommendation
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// AlgorithmAnonymousFallback is the algorithm of recommendations served to a
+// caller without a user ID, and of slate placements that need one
+const AlgorithmAnonymousFallback = "anonymous-fallback"
+
+// anonymousRecommendations serves a caller without a user ID: the top rated
+// services of the requested categories, what is trending, and when those
+// don't fill the page, the most used services of all. Nothing depends on the
+// caller, so every anonymous request for the same categories and page size
+// shares a cache entry.
+func (s *Service) anonymousRecommendations(ctx context.Context, req *RecommendationRequest, maxResults int) *RecommendationResponse {
+	cacheKey := fmt.Sprintf("recommendations:anonymous:%d:%s", maxResults, strings.Join(req.Categories, ","))
+	if cached := s.getCachedRecommendations(ctx, cacheKey); cached != nil {
+		cached = s.filterVisible(ctx, cached)
+		s.trackImpressions(req, cached)
+		return cached
+	}
+
+	var recommendations []Recommendation
+	if len(req.Categories) > 0 {
+		recommendations = append(recommendations, s.categoryBasedRecommendations(ctx, req.Categories, maxResults)...)
+	}
+	recommendations = append(recommendations, s.getTrendingServices(ctx, "", maxResults)...)
+	if len(recommendations) < maxResults {
+		recommendations = append(recommendations, s.popularServices(ctx, maxResults)...)
+	}
+
+	recommendations = s.deduplicateAndRank(recommendations, maxResults)
+	s.hydrateServices(ctx, recommendations)
+
+	response := &RecommendationResponse{
+		Recommendations: recommendations,
+		Algorithm:       AlgorithmAnonymousFallback,
+		Timestamp:       time.Now(),
+	}
+	s.cacheRecommendations(ctx, cacheKey, response)
+
+	response = s.filterVisible(ctx, response)
+	s.trackImpressions(req, response)
+	return response
+}
+
+// popularServices returns the active services with the most requests of all
+// time, scored by their rating at half the popularity weight
+func (s *Service) popularServices(ctx context.Context, maxResults int) []Recommendation {
+	query := `
+		SELECT id, avg_rating, total_requests
+		FROM services
+		WHERE status = 'active'
+		ORDER BY total_requests DESC, avg_rating DESC
+		LIMIT $1
+	`
+
+	rows, err := s.pgPool.Query(ctx, query, maxResults)
+	if err != nil {
+		s.logger.Error("Failed to get popular services", zap.Error(err))
+		return []Recommendation{}
+	}
+	defer rows.Close()
+
+	recommendations := []Recommendation{}
+	for rows.Next() {
+		var id string
+		var rating float64
+		var requests int64
+
+		if err := rows.Scan(&id, &rating, &requests); err != nil {
+			continue
+		}
+
+		recommendations = append(recommendations, Recommendation{
+			ServiceID:  id,
+			Service:    nil,
+			Score:      (rating / 5.0) * s.config.Recommendations.PopularityWeight / 2,
+			Reason:     "Popular in the catalog",
+			Confidence: rating / 5.0,
+		})
+	}
+
+	return recommendations
+}
@@ -0,0 +1,84 @@
+package recommendation
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+
+	"github.com/org/llm-marketplace/pkg/marketplace/flags"
+	"github.com/org/llm-marketplace/services/discovery/internal/entitlement"
+	"github.com/org/llm-marketplace/services/discovery/internal/features"
+)
+
+// affinityCategories is how many of the user's categories recommendations
+// draw on when the request names none
+const affinityCategories = 3
+
+// SetFeatures registers the feature store recommendations read the user's
+// and services' features from
+func (s *Service) SetFeatures(f *features.Store) {
+	s.features = f
+}
+
+// SetFlags registers the feature flags that gate personalization. Without
+// them recommendations are always personalized.
+func (s *Service) SetFlags(e *flags.Evaluator) {
+	s.flags = e
+}
+
+// personalized reports whether the personalization flag is on for the
+// user, in the caller's tenant
+func (s *Service) personalized(ctx context.Context, userID string) bool {
+	return s.flags.Enabled(flags.Personalization, flags.Subject{Tenant: entitlement.FromContext(ctx).TenantID, Key: userID})
+}
+
+// userFeatures returns the user's stored features, or nil when there are
+// none, they can't be read or personalization is off for the user
+func (s *Service) userFeatures(ctx context.Context, userID string) *features.UserFeatures {
+	if !s.features.Enabled() || !s.personalized(ctx, userID) {
+		return nil
+	}
+	user, err := s.features.User(ctx, userID)
+	if err != nil {
+		s.logger.Warn("Failed to load user features", zap.String("user_id", userID), zap.Error(err))
+		return nil
+	}
+	return user
+}
+
+// serviceFeatures returns the stored features of the recommended services,
+// or nil when the store is disabled or they can't be read
+func (s *Service) serviceFeatures(ctx context.Context, recommendations []Recommendation) map[string]*features.ServiceFeatures {
+	if !s.features.Enabled() || len(recommendations) == 0 {
+		return nil
+	}
+	ids := make([]string, len(recommendations))
+	for i, rec := range recommendations {
+		ids[i] = rec.ServiceID
+	}
+	services, err := s.features.Services(ctx, ids)
+	if err != nil {
+		s.logger.Warn("Failed to load service features", zap.Error(err))
+		return nil
+	}
+	return services
+}
+
+// personalize multiplies the scores of hydrated recommendations by their
+// feature factor for the user and sorts them again. Recommendations are
+// left as ranked when the store is disabled or no features could be read.
+func (s *Service) personalize(user *features.UserFeatures, services map[string]*features.ServiceFeatures, recommendations []Recommendation) {
+	if user == nil && len(services) == 0 {
+		return
+	}
+	weights := s.features.Weights()
+	for i := range recommendations {
+		rec := &recommendations[i]
+		category := ""
+		if rec.Service != nil {
+			category = rec.Service.Category
+		}
+		rec.Score *= weights.Factor(user, category, services[rec.ServiceID])
+	}
+	sortByScore(recommendations)
+}
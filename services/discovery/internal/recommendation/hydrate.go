@@ -0,0 +1,116 @@
+package recommendation
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/org/llm-marketplace/services/discovery/internal/elasticsearch"
+	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
+)
+
+// ServiceSource looks services up by ID, for candidates Postgres couldn't load
+type ServiceSource interface {
+	MGet(ctx context.Context, ids []string) (map[string]*elasticsearch.ServiceDocument, error)
+}
+
+// SetServiceSource registers where hydration falls back to when Postgres fails
+func (s *Service) SetServiceSource(source ServiceSource) {
+	s.services = source
+}
+
+// hydrateServices fills in the service of each recommendation. Candidates
+// are loaded in batches of recommendations.hydration.batch_size, each a
+// single round trip, with up to concurrency batches in flight. The
+// candidates of a batch that fails, and any row that can't be read, are
+// looked up in the service source instead. A service that no longer
+// exists, or isn't loaded within the hydration timeout, is left nil.
+func (s *Service) hydrateServices(ctx context.Context, recommendations []Recommendation) {
+	if len(recommendations) == 0 {
+		return
+	}
+
+	cfg := s.config.Recommendations.Hydration
+	if cfg.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.Timeout)
+		defer cancel()
+	}
+	size := cfg.BatchSize
+	if size <= 0 {
+		size = len(recommendations)
+	}
+
+	var group errgroup.Group
+	group.SetLimit(max(cfg.Concurrency, 1))
+	for start := 0; start < len(recommendations); start += size {
+		batch := recommendations[start:min(start+size, len(recommendations))]
+		group.Go(func() error {
+			if failed := s.loadServices(ctx, batch); len(failed) > 0 {
+				s.loadFallback(ctx, failed)
+			}
+			return nil
+		})
+	}
+	group.Wait()
+}
+
+// loadServices loads the services of recommendations from Postgres in one
+// batch, returning the recommendations it couldn't load for a reason other
+// than their service not existing
+func (s *Service) loadServices(ctx context.Context, recommendations []Recommendation) []*Recommendation {
+	resolved := make([]bool, len(recommendations))
+	batch := &pgx.Batch{}
+	for i := range recommendations {
+		rec := &recommendations[i]
+		batch.Queue(serviceDetailsQuery, rec.ServiceID).QueryRow(func(row pgx.Row) error {
+			svc, err := scanServiceDetails(row)
+			switch {
+			case errors.Is(err, pgx.ErrNoRows):
+				resolved[i] = true
+			case err != nil:
+				s.logger.Debug("Failed to read recommended service", zap.String("service_id", rec.ServiceID), zap.Error(err))
+			default:
+				rec.Service = svc
+				resolved[i] = true
+			}
+			// The rest of the batch is still read
+			return nil
+		})
+	}
+
+	if err := s.pgPool.SendBatch(ctx, batch).Close(); err != nil {
+		s.logger.Warn("Failed to load recommended services", zap.Int("services", len(recommendations)), zap.Error(err))
+	}
+
+	var failed []*Recommendation
+	for i := range recommendations {
+		if !resolved[i] {
+			failed = append(failed, &recommendations[i])
+		}
+	}
+	return failed
+}
+
+// loadFallback loads the services of recommendations from the service source
+func (s *Service) loadFallback(ctx context.Context, recommendations []*Recommendation) {
+	if s.services == nil {
+		return
+	}
+	ids := make([]string, len(recommendations))
+	for i, rec := range recommendations {
+		ids[i] = rec.ServiceID
+	}
+
+	docs, err := s.services.MGet(ctx, ids)
+	if err != nil {
+		s.logger.Warn("Failed to load recommended services from the fallback", zap.Int("services", len(ids)), zap.Error(err))
+		s.metrics.RecommendationHydrationFallback("failed", len(ids))
+		return
+	}
+	for _, rec := range recommendations {
+		rec.Service = docs[rec.ServiceID]
+	}
+	s.metrics.RecommendationHydrationFallback("loaded", len(ids))
+}
@@ -1,18 +1,24 @@
 package recommendation
 
 import (
+	"cmp"
 	"context"
-	"database/sql"
 	"encoding/json"
 	"fmt"
 	"math"
+	"slices"
 	"time"
 
 	"github.com/go-redis/redis/v8"
+	"github.com/jackc/pgx/v5"
+	"github.com/org/llm-marketplace/pkg/marketplace/flags"
+	"github.com/org/llm-marketplace/services/discovery/internal/analytics"
 	"github.com/org/llm-marketplace/services/discovery/internal/config"
 	"github.com/org/llm-marketplace/services/discovery/internal/elasticsearch"
+	"github.com/org/llm-marketplace/services/discovery/internal/features"
 	"github.com/org/llm-marketplace/services/discovery/internal/observability"
 	"github.com/org/llm-marketplace/services/discovery/internal/postgres"
+	"github.com/org/llm-marketplace/services/discovery/internal/privacy"
 	"go.uber.org/zap"
 )
 
@@ -22,6 +28,12 @@ type Service struct {
 	config      *config.Config
 	logger      *zap.Logger
 	metrics     *observability.Metrics
+	analytics   *analytics.Producer
+	visibility  VisibilityFilter
+	services    ServiceSource
+	features    *features.Store
+	flags       *flags.Evaluator
+	pseudonyms  *privacy.Pseudonymizer
 }
 
 func NewService(
@@ -37,6 +49,7 @@ func NewService(
 		config:      cfg,
 		logger:      logger,
 		metrics:     metrics,
+		pseudonyms:  privacy.NewPseudonymizer(cfg.Privacy),
 	}
 }
 
@@ -58,6 +71,7 @@ type RecommendationResponse struct {
 
 // Recommendation represents a single recommendation
 type Recommendation struct {
+	ServiceID   string                         `json:"service_id"`
 	Service     *elasticsearch.ServiceDocument `json:"service"`
 	Score       float64                        `json:"score"`
 	Reason      string                         `json:"reason"`
@@ -79,13 +93,22 @@ func (s *Service) GetRecommendations(ctx context.Context, req *RecommendationReq
 		maxResults = s.config.Recommendations.MaxRecommendations
 	}
 
+	// Without a user or a service there is nothing to personalize on
+	if req.UserID == "" && req.ServiceID == "" {
+		return s.anonymousRecommendations(ctx, req, maxResults), nil
+	}
+
 	// Check cache
 	cacheKey := fmt.Sprintf("recommendations:%s", req.UserID)
 	if cached := s.getCachedRecommendations(ctx, cacheKey); cached != nil {
 		s.logger.Debug("Cache hit for recommendations", zap.String("user_id", req.UserID))
+		cached = s.filterVisible(ctx, cached)
+		s.trackImpressions(req, cached)
 		return cached, nil
 	}
 
+	user := s.userFeatures(ctx, req.UserID)
+
 	// Get user interaction history
 	userHistory, err := s.getUserHistory(ctx, req.UserID)
 	if err != nil {
@@ -108,16 +131,23 @@ func (s *Service) GetRecommendations(ctx context.Context, req *RecommendationReq
 	} else if len(req.Categories) > 0 {
 		content := s.categoryBasedRecommendations(ctx, req.Categories, maxResults)
 		recommendations = append(recommendations, content...)
+	} else if categories := user.TopCategories(affinityCategories); len(categories) > 0 {
+		content := s.categoryBasedRecommendations(ctx, categories, maxResults)
+		recommendations = append(recommendations, content...)
 	}
 
 	// Trending services
 	if req.IncludeTrending {
-		trending := s.getTrendingServices(ctx, maxResults/2)
+		trending := s.getTrendingServices(ctx, "", maxResults/2)
 		recommendations = append(recommendations, trending...)
 	}
 
 	// Deduplicate and sort by score
 	recommendations = s.deduplicateAndRank(recommendations, maxResults)
+	s.hydrateServices(ctx, recommendations)
+	if s.personalized(ctx, req.UserID) {
+		s.personalize(user, s.serviceFeatures(ctx, recommendations), recommendations)
+	}
 
 	response := &RecommendationResponse{
 		Recommendations: recommendations,
@@ -125,12 +155,121 @@ func (s *Service) GetRecommendations(ctx context.Context, req *RecommendationReq
 		Timestamp:       time.Now(),
 	}
 
-	// Cache results
+	// Cache results; they are filtered per caller on the way out
+	s.cacheRecommendations(ctx, cacheKey, response)
+
+	response = s.filterVisible(ctx, response)
+	s.trackImpressions(req, response)
+
+	return response, nil
+}
+
+// GetTrending returns the services most used within trending_window. They
+// are the same for every caller, who only sees those it is entitled to.
+func (s *Service) GetTrending(ctx context.Context, maxResults int) (*RecommendationResponse, error) {
+	if !s.config.Recommendations.Enabled {
+		return &RecommendationResponse{
+			Recommendations: []Recommendation{},
+			Algorithm:       "disabled",
+			Timestamp:       time.Now(),
+		}, nil
+	}
+
+	if maxResults <= 0 || maxResults > s.config.Recommendations.MaxRecommendations {
+		maxResults = s.config.Recommendations.MaxRecommendations
+	}
+
+	req := &RecommendationRequest{MaxResults: maxResults, IncludeTrending: true}
+	cacheKey := fmt.Sprintf("recommendations:trending:%d", maxResults)
+	if cached := s.getCachedRecommendations(ctx, cacheKey); cached != nil {
+		cached = s.filterVisible(ctx, cached)
+		s.trackImpressions(req, cached)
+		return cached, nil
+	}
+
+	recommendations := s.getTrendingServices(ctx, "", maxResults)
+	s.hydrateServices(ctx, recommendations)
+
+	response := &RecommendationResponse{
+		Recommendations: recommendations,
+		Algorithm:       config.AlgorithmTrending,
+		Timestamp:       time.Now(),
+	}
 	s.cacheRecommendations(ctx, cacheKey, response)
 
+	response = s.filterVisible(ctx, response)
+	s.trackImpressions(req, response)
 	return response, nil
 }
 
+// SetAnalytics registers the producer that receives impression events
+func (s *Service) SetAnalytics(p *analytics.Producer) {
+	s.analytics = p
+}
+
+// VisibilityFilter reports which services the caller in ctx is entitled to see
+type VisibilityFilter interface {
+	VisibleServices(ctx context.Context, ids []string) (map[string]bool, error)
+}
+
+// SetVisibility registers the filter that drops restricted services from responses
+func (s *Service) SetVisibility(f VisibilityFilter) {
+	s.visibility = f
+}
+
+// filterVisible returns resp without services the caller may not see, dropping everything if the check fails
+func (s *Service) filterVisible(ctx context.Context, resp *RecommendationResponse) *RecommendationResponse {
+	if s.visibility == nil || len(resp.Recommendations) == 0 {
+		return resp
+	}
+
+	ids := make([]string, len(resp.Recommendations))
+	for i, rec := range resp.Recommendations {
+		ids[i] = rec.ServiceID
+	}
+
+	visible, err := s.visibility.VisibleServices(ctx, ids)
+	if err != nil {
+		s.logger.Warn("Failed to check service visibility", zap.Error(err))
+		visible = nil
+	}
+
+	filtered := *resp
+	filtered.Recommendations = make([]Recommendation, 0, len(resp.Recommendations))
+	for _, rec := range resp.Recommendations {
+		if visible[rec.ServiceID] {
+			filtered.Recommendations = append(filtered.Recommendations, rec)
+		}
+	}
+	return &filtered
+}
+
+// trackImpressions publishes the recommendations returned to the caller
+func (s *Service) trackImpressions(req *RecommendationRequest, resp *RecommendationResponse) {
+	if len(resp.Recommendations) == 0 {
+		return
+	}
+
+	source := "recommendations"
+	switch {
+	case req.ServiceID != "":
+		source = "similar"
+	case req.UserID == "" && req.IncludeTrending:
+		source = "trending"
+	}
+
+	serviceIDs := make([]string, len(resp.Recommendations))
+	for i, rec := range resp.Recommendations {
+		serviceIDs[i] = rec.ServiceID
+	}
+
+	s.analytics.Track(analytics.NewImpressionEvent(req.UserID, &analytics.ImpressionEvent{
+		Source:     source,
+		Algorithm:  resp.Algorithm,
+		ServiceIDs: serviceIDs,
+	}))
+}
+
 // UserInteraction represents a user's interaction with a service
 type UserInteraction struct {
 	ServiceID   string
@@ -145,12 +284,12 @@ func (s *Service) getUserHistory(ctx context.Context, userID string) ([]UserInte
 	query := `
 		SELECT service_id, interaction_type, rating, timestamp, duration_sec
 		FROM user_interactions
-		WHERE user_id = $1
+		WHERE user_id = ANY($1)
 		ORDER BY timestamp DESC
 		LIMIT 100
 	`
 
-	rows, err := s.pgPool.Query(ctx, query, userID)
+	rows, err := s.pgPool.Query(ctx, query, s.pseudonyms.StoredIDs(userID))
 	if err != nil {
 		return nil, err
 	}
@@ -159,8 +298,8 @@ func (s *Service) getUserHistory(ctx context.Context, userID string) ([]UserInte
 	var history []UserInteraction
 	for rows.Next() {
 		var interaction UserInteraction
-		var rating sql.NullFloat64
-		var duration sql.NullInt64
+		var rating *float64
+		var duration *int
 
 		err := rows.Scan(
 			&interaction.ServiceID,
@@ -173,11 +312,11 @@ func (s *Service) getUserHistory(ctx context.Context, userID string) ([]UserInte
 			continue
 		}
 
-		if rating.Valid {
-			interaction.Rating = rating.Float64
+		if rating != nil {
+			interaction.Rating = *rating
 		}
-		if duration.Valid {
-			interaction.DurationSec = int(duration.Int64)
+		if duration != nil {
+			interaction.DurationSec = *duration
 		}
 
 		history = append(history, interaction)
@@ -199,14 +338,14 @@ func (s *Service) collaborativeFiltering(ctx context.Context, userID string, his
 		SELECT DISTINCT u.user_id, COUNT(*) as common_services
 		FROM user_interactions u
 		WHERE u.service_id = ANY($1)
-		  AND u.user_id != $2
+		  AND u.user_id != ALL($2)
 		GROUP BY u.user_id
 		HAVING COUNT(*) >= $3
 		ORDER BY common_services DESC
 		LIMIT 50
 	`
 
-	rows, err := s.pgPool.Query(ctx, query, userServiceIDs, userID, s.config.Recommendations.MinCommonUsers)
+	rows, err := s.pgPool.Query(ctx, query, userServiceIDs, s.pseudonyms.StoredIDs(userID), s.config.Recommendations.MinCommonUsers)
 	if err != nil {
 		s.logger.Error("Failed to find similar users", zap.Error(err))
 		return []Recommendation{}
@@ -260,6 +399,7 @@ func (s *Service) collaborativeFiltering(ctx context.Context, userID string, his
 		confidence := math.Min(float64(count)/10.0, 1.0)
 
 		recommendations = append(recommendations, Recommendation{
+			ServiceID:  serviceID,
 			Service:    nil, // Will be populated later
 			Score:      avgRating * s.config.Recommendations.CollaborativeWeight,
 			Reason:     "Users similar to you liked this service",
@@ -274,7 +414,7 @@ func (s *Service) collaborativeFiltering(ctx context.Context, userID string, his
 func (s *Service) contentBasedRecommendations(ctx context.Context, serviceID string, maxResults int) []Recommendation {
 	// Get the reference service details
 	query := `
-		SELECT category, tags, pricing_model
+		SELECT category, tags, COALESCE(pricing_model, '')
 		FROM services
 		WHERE id = $1
 	`
@@ -327,6 +467,7 @@ func (s *Service) contentBasedRecommendations(ctx context.Context, serviceID str
 		}
 
 		recommendations = append(recommendations, Recommendation{
+			ServiceID:  id,
 			Service:    nil, // Will be populated later
 			Score:      score * s.config.Recommendations.ContentWeight,
 			Reason:     fmt.Sprintf("Similar to services in %s category", category),
@@ -368,6 +509,7 @@ func (s *Service) categoryBasedRecommendations(ctx context.Context, categories [
 
 		score := (rating / 5.0) * s.config.Recommendations.ContentWeight
 		recommendations = append(recommendations, Recommendation{
+			ServiceID:  id,
 			Service:    nil,
 			Score:      score,
 			Reason:     fmt.Sprintf("Top rated in %s", category),
@@ -378,22 +520,25 @@ func (s *Service) categoryBasedRecommendations(ctx context.Context, categories [
 	return recommendations
 }
 
-// getTrendingServices returns currently trending services
-func (s *Service) getTrendingServices(ctx context.Context, maxResults int) []Recommendation {
+// getTrendingServices returns currently trending services, in category
+// unless it is empty
+func (s *Service) getTrendingServices(ctx context.Context, category string, maxResults int) []Recommendation {
 	window := s.config.Recommendations.TrendingWindow
 	minInteractions := s.config.Recommendations.TrendingMinInteractions
 
 	query := `
-		SELECT service_id, COUNT(*) as interaction_count, AVG(rating) as avg_rating
-		FROM user_interactions
-		WHERE timestamp > NOW() - $1::interval
-		GROUP BY service_id
+		SELECT i.service_id, COUNT(*) as interaction_count, COALESCE(AVG(i.rating), 0) as avg_rating
+		FROM user_interactions i
+		JOIN services s ON s.id = i.service_id
+		WHERE i.timestamp > NOW() - make_interval(secs => $1)
+		  AND ($4::text = '' OR s.category = $4)
+		GROUP BY i.service_id
 		HAVING COUNT(*) >= $2
 		ORDER BY interaction_count DESC, avg_rating DESC
 		LIMIT $3
 	`
 
-	rows, err := s.pgPool.Query(ctx, query, window, minInteractions, maxResults)
+	rows, err := s.pgPool.Query(ctx, query, window.Seconds(), minInteractions, maxResults, category)
 	if err != nil {
 		s.logger.Error("Failed to get trending services", zap.Error(err))
 		return []Recommendation{}
@@ -410,11 +555,16 @@ func (s *Service) getTrendingServices(ctx context.Context, maxResults int) []Rec
 			continue
 		}
 
+		reason := "Trending now"
+		if category != "" {
+			reason = fmt.Sprintf("Trending in %s", category)
+		}
 		score := (float64(count) / 100.0) * s.config.Recommendations.PopularityWeight
 		recommendations = append(recommendations, Recommendation{
+			ServiceID:  serviceID,
 			Service:    nil,
 			Score:      score,
-			Reason:     "Trending now",
+			Reason:     reason,
 			Confidence: math.Min(float64(count)/100.0, 1.0),
 		})
 	}
@@ -422,26 +572,47 @@ func (s *Service) getTrendingServices(ctx context.Context, maxResults int) []Rec
 	return recommendations
 }
 
+// serviceDetailsQuery loads the service shown with a recommendation
+const serviceDetailsQuery = `
+	SELECT id, name, COALESCE(description, ''), category, COALESCE(tags, '{}'),
+	       provider_id, COALESCE(provider_name, ''), COALESCE(provider_verified, false), capabilities,
+	       COALESCE(pricing_model, ''), COALESCE(pricing_rate, 0), COALESCE(pricing_unit, ''),
+	       COALESCE(sla_availability, 0), COALESCE(sla_max_latency_ms, 0), COALESCE(compliance_level, ''),
+	       status, COALESCE(total_requests, 0), COALESCE(avg_latency_ms, 0), COALESCE(error_rate, 0),
+	       COALESCE(avg_rating, 0), COALESCE(review_count, 0), created_at, updated_at
+	FROM services
+	WHERE id = $1
+`
+
+func scanServiceDetails(row pgx.Row) (*elasticsearch.ServiceDocument, error) {
+	var svc elasticsearch.ServiceDocument
+	err := row.Scan(
+		&svc.ID, &svc.Name, &svc.Description, &svc.Category, &svc.Tags,
+		&svc.Provider.ID, &svc.Provider.Name, &svc.Provider.Verified, &svc.Capabilities,
+		&svc.Pricing.Model, &svc.Pricing.Rate, &svc.Pricing.Unit,
+		&svc.SLA.Availability, &svc.SLA.MaxLatencyMS, &svc.Compliance.Level,
+		&svc.Status, &svc.Metrics.TotalRequests, &svc.Metrics.AvgLatencyMS, &svc.Metrics.ErrorRate,
+		&svc.Metrics.Rating, &svc.Metrics.ReviewCount, &svc.CreatedAt, &svc.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &svc, nil
+}
+
 // deduplicateAndRank removes duplicates and ranks by score
 func (s *Service) deduplicateAndRank(recommendations []Recommendation, maxResults int) []Recommendation {
-	seen := make(map[string]bool)
-	unique := []Recommendation{}
+	seen := make(map[string]bool, len(recommendations))
+	unique := make([]Recommendation, 0, len(recommendations))
 
 	for _, rec := range recommendations {
-		if rec.Service != nil && !seen[rec.Service.ID] {
-			seen[rec.Service.ID] = true
+		if rec.ServiceID != "" && !seen[rec.ServiceID] {
+			seen[rec.ServiceID] = true
 			unique = append(unique, rec)
 		}
 	}
 
-	// Sort by score
-	for i := 0; i < len(unique)-1; i++ {
-		for j := i + 1; j < len(unique); j++ {
-			if unique[j].Score > unique[i].Score {
-				unique[i], unique[j] = unique[j], unique[i]
-			}
-		}
-	}
+	sortByScore(unique)
 
 	if len(unique) > maxResults {
 		unique = unique[:maxResults]
@@ -450,27 +621,40 @@ func (s *Service) deduplicateAndRank(recommendations []Recommendation, maxResult
 	return unique
 }
 
+// sortByScore orders recommendations by descending score, keeping the order of ties
+func sortByScore(recommendations []Recommendation) {
+	slices.SortStableFunc(recommendations, func(a, b Recommendation) int { return cmp.Compare(b.Score, a.Score) })
+}
+
 // Cache helpers
 func (s *Service) getCachedRecommendations(ctx context.Context, key string) *RecommendationResponse {
-	data, err := s.redisClient.Get(ctx, key).Bytes()
-	if err != nil {
-		return nil
-	}
-
 	var response RecommendationResponse
-	if err := json.Unmarshal(data, &response); err != nil {
+	if !s.getCached(ctx, key, &response) {
 		return nil
 	}
-
 	return &response
 }
 
 func (s *Service) cacheRecommendations(ctx context.Context, key string, response *RecommendationResponse) {
-	data, err := json.Marshal(response)
+	s.cache(ctx, key, response)
+}
+
+// getCached decodes the value cached under key into v, reporting whether there was one
+func (s *Service) getCached(ctx context.Context, key string, v interface{}) bool {
+	data, err := s.redisClient.Get(ctx, key).Bytes()
+	if err != nil {
+		return false
+	}
+	return json.Unmarshal(data, v) == nil
+}
+
+// cache stores v under key for the recommendations TTL
+func (s *Service) cache(ctx context.Context, key string, v interface{}) {
+	data, err := json.Marshal(v)
 	if err != nil {
 		return
 	}
 
-	ttl := s.config.Redis.GetCacheTTL("recommendations")
+	ttl := s.config.CacheTTL("recommendations")
 	s.redisClient.Set(ctx, key, data, ttl)
 }
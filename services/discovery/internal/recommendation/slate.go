@@ -0,0 +1,387 @@
+package recommendation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/org/llm-marketplace/services/discovery/internal/analytics"
+	"github.com/org/llm-marketplace/services/discovery/internal/config"
+)
+
+// ErrUnknownPlacement is returned for a slate request naming a placement
+// that isn't configured
+var ErrUnknownPlacement = errors.New("unknown placement")
+
+// SlateRequest asks for the placements of a page
+type SlateRequest struct {
+	UserID     string   `json:"user_id"`
+	Placements []string `json:"placements,omitempty"` // Every configured placement when empty
+	Category   string   `json:"category,omitempty"`   // For category placements; the user's top category, or that of the service they used last, when empty
+}
+
+// SlateResponse holds the placements of a page, in the order they were
+// filled. No service appears in more than one placement.
+type SlateResponse struct {
+	Placements []Placement `json:"placements"`
+	Timestamp  time.Time   `json:"timestamp"`
+}
+
+// Placement is one named set of recommendations in a slate
+type Placement struct {
+	Name            string           `json:"name"`
+	Algorithm       string           `json:"algorithm"`
+	Budget          int              `json:"budget"`
+	Category        string           `json:"category,omitempty"` // The category the placement was drawn from
+	Anchor          string           `json:"anchor,omitempty"`   // The service the placement's services are like
+	Recommendations []Recommendation `json:"recommendations"`
+}
+
+// slateInputs are what the placements of one slate are drawn from
+type slateInputs struct {
+	userID   string
+	history  []UserInteraction
+	anchor   string // The service the user used last
+	category string
+}
+
+// GetSlate fills the requested placements, each with its own algorithm and
+// budget. Placements are filled in the order of config.Placements, and a
+// service already placed is skipped by the placements after it.
+func (s *Service) GetSlate(ctx context.Context, req *SlateRequest) (*SlateResponse, error) {
+	names, err := s.slatePlacements(req.Placements)
+	if err != nil {
+		return nil, err
+	}
+	if !s.config.Recommendations.Enabled {
+		return &SlateResponse{Placements: []Placement{}, Timestamp: time.Now()}, nil
+	}
+
+	cacheKey := fmt.Sprintf("recommendations:slate:%s:%s:%s", req.UserID, req.Category, strings.Join(names, ","))
+	var cached SlateResponse
+	if s.getCached(ctx, cacheKey, &cached) {
+		s.logger.Debug("Cache hit for slate", zap.String("user_id", req.UserID))
+		slate := s.filterVisibleSlate(ctx, &cached)
+		s.trackSlateImpressions(req.UserID, slate)
+		return slate, nil
+	}
+
+	slate := s.buildSlate(ctx, req, names)
+
+	// Cache the slate; it is filtered per caller on the way out
+	s.cache(ctx, cacheKey, slate)
+
+	slate = s.filterVisibleSlate(ctx, slate)
+	s.trackSlateImpressions(req.UserID, slate)
+
+	return slate, nil
+}
+
+// slatePlacements returns the placements to fill in slate order: those
+// requested, or every configured one
+func (s *Service) slatePlacements(requested []string) ([]string, error) {
+	slate := s.config.Recommendations.Slate
+	for _, name := range requested {
+		if slate[name].Budget <= 0 {
+			return nil, fmt.Errorf("%w: %q", ErrUnknownPlacement, name)
+		}
+	}
+
+	names := []string{}
+	for _, name := range config.Placements {
+		if slate[name].Budget <= 0 {
+			continue
+		}
+		if len(requested) == 0 || contains(requested, name) {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// buildSlate fills each placement from the user's history and the slate's
+// category, then orders each by the user's features
+func (s *Service) buildSlate(ctx context.Context, req *SlateRequest, names []string) *SlateResponse {
+	in := slateInputs{userID: req.UserID, category: req.Category}
+	user := s.userFeatures(ctx, req.UserID)
+	if top := user.TopCategories(1); in.category == "" && len(top) > 0 {
+		in.category = top[0]
+	}
+	if req.UserID != "" {
+		history, err := s.getUserHistory(ctx, req.UserID)
+		if err != nil {
+			s.logger.Warn("Failed to get user history", zap.Error(err))
+		}
+		in.history = history
+	}
+	if len(in.history) > 0 {
+		in.anchor = in.history[0].ServiceID
+	}
+	if in.category == "" && in.anchor != "" {
+		in.category = s.serviceCategory(ctx, in.anchor)
+	}
+
+	slate := &SlateResponse{Placements: make([]Placement, 0, len(names)), Timestamp: time.Now()}
+	placed := make(map[string]bool)
+	for _, name := range names {
+		cfg := s.config.Recommendations.Slate[name]
+		placement := Placement{Name: name, Algorithm: cfg.Algorithm, Budget: cfg.Budget}
+
+		// Enough candidates to fill the budget past the services placed already
+		candidates := s.placementCandidates(ctx, &placement, in, cfg.Budget+len(placed))
+		placement.Recommendations = make([]Recommendation, 0, cfg.Budget)
+		for _, rec := range candidates {
+			if len(placement.Recommendations) == cfg.Budget {
+				break
+			}
+			if rec.ServiceID != "" && !placed[rec.ServiceID] {
+				placed[rec.ServiceID] = true
+				placement.Recommendations = append(placement.Recommendations, rec)
+			}
+		}
+		slate.Placements = append(slate.Placements, placement)
+	}
+
+	s.hydrateSlate(ctx, slate)
+	var all []Recommendation
+	for _, p := range slate.Placements {
+		all = append(all, p.Recommendations...)
+	}
+	if !s.personalized(ctx, req.UserID) {
+		return slate
+	}
+	services := s.serviceFeatures(ctx, all)
+	for i := range slate.Placements {
+		s.personalize(user, services, slate.Placements[i].Recommendations)
+	}
+	return slate
+}
+
+// placementCandidates runs the placement's algorithm, best first, and notes
+// on the placement the category or anchor it drew from. Placements whose
+// algorithm needs a user fall back to trending for an anonymous caller.
+func (s *Service) placementCandidates(ctx context.Context, p *Placement, in slateInputs, limit int) []Recommendation {
+	if in.userID == "" && (p.Algorithm == config.AlgorithmCollaborative || p.Algorithm == config.AlgorithmContent) {
+		// These need a user; an anonymous caller gets what is trending instead
+		p.Algorithm = AlgorithmAnonymousFallback
+		p.Category = in.category
+		return s.getTrendingServices(ctx, in.category, limit)
+	}
+	switch p.Algorithm {
+	case config.AlgorithmCollaborative:
+		if len(in.history) < 3 {
+			return nil
+		}
+		return s.deduplicateAndRank(s.collaborativeFiltering(ctx, in.userID, in.history, limit), limit)
+	case config.AlgorithmContent:
+		if in.anchor == "" {
+			return nil
+		}
+		p.Anchor = in.anchor
+		return s.deduplicateAndRank(s.contentBasedRecommendations(ctx, in.anchor, limit), limit)
+	case config.AlgorithmTrending:
+		p.Category = in.category
+		return s.getTrendingServices(ctx, in.category, limit)
+	case config.AlgorithmNewest:
+		p.Category = in.category
+		return s.newArrivals(ctx, in.category, limit)
+	case config.AlgorithmFineTuning:
+		return s.fineTuningOfferings(ctx, limit)
+	default:
+		var recommendations []Recommendation
+		if len(in.history) >= 3 {
+			recommendations = append(recommendations, s.collaborativeFiltering(ctx, in.userID, in.history, limit)...)
+		}
+		if in.category != "" {
+			p.Category = in.category
+			recommendations = append(recommendations, s.categoryBasedRecommendations(ctx, []string{in.category}, limit)...)
+		}
+		recommendations = append(recommendations, s.getTrendingServices(ctx, in.category, limit)...)
+		return s.deduplicateAndRank(recommendations, limit)
+	}
+}
+
+// serviceCategory returns the category of a service, or "" when it can't
+// be read
+func (s *Service) serviceCategory(ctx context.Context, serviceID string) string {
+	var category string
+	if err := s.pgPool.QueryRow(ctx, `SELECT category FROM services WHERE id = $1`, serviceID).Scan(&category); err != nil {
+		s.logger.Debug("Failed to get service category", zap.String("service_id", serviceID), zap.Error(err))
+		return ""
+	}
+	return category
+}
+
+// newArrivals returns the most recently listed active services, in category
+// unless it is empty. Scores halve every 30 days since listing.
+func (s *Service) newArrivals(ctx context.Context, category string, maxResults int) []Recommendation {
+	query := `
+		SELECT id, created_at
+		FROM services
+		WHERE status = 'active'
+		  AND ($1::text = '' OR category = $1)
+		ORDER BY created_at DESC
+		LIMIT $2
+	`
+
+	rows, err := s.pgPool.Query(ctx, query, category, maxResults)
+	if err != nil {
+		s.logger.Error("Failed to get new arrivals", zap.Error(err))
+		return []Recommendation{}
+	}
+	defer rows.Close()
+
+	recommendations := []Recommendation{}
+	for rows.Next() {
+		var id string
+		var createdAt time.Time
+
+		if err := rows.Scan(&id, &createdAt); err != nil {
+			continue
+		}
+
+		age := time.Since(createdAt).Hours() / 24
+		recommendations = append(recommendations, Recommendation{
+			ServiceID:  id,
+			Service:    nil,
+			Score:      math.Pow(0.5, math.Max(age, 0)/30),
+			Reason:     "New in the catalog",
+			Confidence: 1,
+		})
+	}
+
+	return recommendations
+}
+
+// fineTuningOfferings returns active fine-tuning offerings, most used within
+// trending_window first and then those with the shortest turnaround. Scores
+// grow with use and halve with every day of turnaround.
+func (s *Service) fineTuningOfferings(ctx context.Context, maxResults int) []Recommendation {
+	query := `
+		SELECT s.id, COALESCE(s.turnaround_hours, 0), COUNT(i.service_id) AS uses
+		FROM services s
+		LEFT JOIN user_interactions i
+		  ON i.service_id = s.id AND i.timestamp > NOW() - make_interval(secs => $1)
+		WHERE s.status = 'active'
+		  AND s.kind = 'fine_tuning'
+		GROUP BY s.id, s.turnaround_hours
+		ORDER BY uses DESC, s.turnaround_hours ASC NULLS LAST
+		LIMIT $2
+	`
+
+	rows, err := s.pgPool.Query(ctx, query, s.config.Recommendations.TrendingWindow.Seconds(), maxResults)
+	if err != nil {
+		s.logger.Error("Failed to get fine-tuning offerings", zap.Error(err))
+		return []Recommendation{}
+	}
+	defer rows.Close()
+
+	recommendations := []Recommendation{}
+	for rows.Next() {
+		var id string
+		var turnaround, uses int
+
+		if err := rows.Scan(&id, &turnaround, &uses); err != nil {
+			continue
+		}
+
+		reason := "Fine-tune a model"
+		if turnaround > 0 {
+			reason = fmt.Sprintf("Fine-tuning within %d hours", turnaround)
+		}
+		recommendations = append(recommendations, Recommendation{
+			ServiceID:  id,
+			Service:    nil,
+			Score:      (1 + float64(uses)/100) * math.Pow(0.5, float64(turnaround)/24),
+			Reason:     reason,
+			Confidence: math.Min(float64(uses)/100.0, 1.0),
+		})
+	}
+
+	return recommendations
+}
+
+// hydrateSlate fills in the services of every placement in one batch
+func (s *Service) hydrateSlate(ctx context.Context, slate *SlateResponse) {
+	var all []Recommendation
+	for _, p := range slate.Placements {
+		all = append(all, p.Recommendations...)
+	}
+	s.hydrateServices(ctx, all)
+
+	for i := range slate.Placements {
+		n := copy(slate.Placements[i].Recommendations, all)
+		all = all[n:]
+	}
+}
+
+// filterVisibleSlate returns slate without services the caller may not see,
+// dropping everything if the check fails
+func (s *Service) filterVisibleSlate(ctx context.Context, slate *SlateResponse) *SlateResponse {
+	if s.visibility == nil {
+		return slate
+	}
+
+	var ids []string
+	for _, p := range slate.Placements {
+		for _, rec := range p.Recommendations {
+			ids = append(ids, rec.ServiceID)
+		}
+	}
+	if len(ids) == 0 {
+		return slate
+	}
+
+	visible, err := s.visibility.VisibleServices(ctx, ids)
+	if err != nil {
+		s.logger.Warn("Failed to check service visibility", zap.Error(err))
+		visible = nil
+	}
+
+	filtered := *slate
+	filtered.Placements = make([]Placement, len(slate.Placements))
+	for i, p := range slate.Placements {
+		recommendations := make([]Recommendation, 0, len(p.Recommendations))
+		for _, rec := range p.Recommendations {
+			if visible[rec.ServiceID] {
+				recommendations = append(recommendations, rec)
+			}
+		}
+		p.Recommendations = recommendations
+		filtered.Placements[i] = p
+	}
+	return &filtered
+}
+
+// trackSlateImpressions publishes each placement returned to the caller
+func (s *Service) trackSlateImpressions(userID string, slate *SlateResponse) {
+	for _, p := range slate.Placements {
+		if len(p.Recommendations) == 0 {
+			continue
+		}
+		serviceIDs := make([]string, len(p.Recommendations))
+		for i, rec := range p.Recommendations {
+			serviceIDs[i] = rec.ServiceID
+		}
+		s.analytics.Track(analytics.NewImpressionEvent(userID, &analytics.ImpressionEvent{
+			Source:     "slate",
+			Placement:  p.Name,
+			Algorithm:  p.Algorithm,
+			ServiceIDs: serviceIDs,
+		}))
+	}
+}
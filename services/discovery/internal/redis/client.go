@@ -8,20 +8,41 @@ import (
 	"github.com/org/llm-marketplace/services/discovery/internal/config"
 )
 
-// NewClient creates a new Redis client
-func NewClient(cfg config.RedisConfig) (*redis.Client, error) {
-	client := redis.NewClient(&redis.Options{
+// NewClient creates a new Redis client. password, when set, supplies the
+// password for each new connection in place of cfg.Password.
+func NewClient(cfg config.RedisConfig, password func() string) (*redis.Client, error) {
+	opts := &redis.Options{
 		Addr:         cfg.Address,
 		Password:     cfg.Password,
 		DB:           cfg.DB,
 		MaxRetries:   cfg.MaxRetries,
 		PoolSize:     cfg.PoolSize,
 		MinIdleConns: cfg.MinIdleConns,
-	})
+	}
+	if password != nil {
+		// The client would send the fixed Password and SELECT before OnConnect,
+		// so both move into it
+		opts.Password = ""
+		opts.DB = 0
+		opts.OnConnect = func(ctx context.Context, cn *redis.Conn) error {
+			if pw := password(); pw != "" {
+				if err := cn.Auth(ctx, pw).Err(); err != nil {
+					return err
+				}
+			}
+			if cfg.DB > 0 {
+				return cn.Select(ctx, cfg.DB).Err()
+			}
+			return nil
+		}
+	}
+	client := redis.NewClient(opts)
+	client.AddHook(NewTracingHook(cfg.DB))
 
 	// Ping to verify connection
 	ctx := context.Background()
 	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
 		return nil, fmt.Errorf("failed to connect to Redis: %w", err)
 	}
 
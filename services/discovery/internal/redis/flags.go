@@ -0,0 +1,38 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/org/llm-marketplace/pkg/marketplace/flags"
+)
+
+// FlagSource reads feature flags from a Redis key holding the JSON object of
+// flags by name that a flags file holds. Without the key no flag is defined.
+type FlagSource struct {
+	client *redis.Client
+	key    string
+}
+
+// NewFlagSource creates a source reading key
+func NewFlagSource(client *redis.Client, key string) *FlagSource {
+	return &FlagSource{client: client, key: key}
+}
+
+// Flags reads and decodes the key
+func (s *FlagSource) Flags(ctx context.Context) (flags.Set, error) {
+	data, err := s.client.Get(ctx, s.key).Bytes()
+	if err == redis.Nil {
+		return flags.Set{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read flags: %w", err)
+	}
+	var set flags.Set
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("failed to decode flags from %s: %w", s.key, err)
+	}
+	return set, nil
+}
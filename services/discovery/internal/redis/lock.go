@@ -0,0 +1,34 @@
+package redis
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// ReleaseLockScript deletes KEYS[1] only while it holds ARGV[1], the token of
+// the caller that took the lock
+const ReleaseLockScript = `if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`
+
+var releaseLock = redis.NewScript(ReleaseLockScript)
+
+// LockToken returns a random value to take a lock with, identifying its holder
+func LockToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// ReleaseLock deletes the lock at key if it is still held with token. A
+// holder that outlived the lock's TTL leaves alone the lock another holder
+// has since taken.
+func ReleaseLock(ctx context.Context, client *redis.Client, key, token string) error {
+	return releaseLock.Run(ctx, client, []string{key}, token).Err()
+}
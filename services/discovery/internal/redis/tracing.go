@@ -0,0 +1,69 @@
+package redis
+
+import (
+	"context"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/org/llm-marketplace/services/discovery/internal/observability"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TracingHook starts a client span for every command and pipeline. A cache
+// miss (redis.Nil) is not recorded as an error.
+type TracingHook struct {
+	tracer trace.Tracer
+	db     int
+}
+
+// NewTracingHook creates a hook for a client using database db
+func NewTracingHook(db int) *TracingHook {
+	return &TracingHook{tracer: otel.Tracer("discovery-redis"), db: db}
+}
+
+func (h *TracingHook) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	ctx, _ = h.start(ctx, "redis "+cmd.FullName(), cmd.FullName())
+	return ctx, nil
+}
+
+func (h *TracingHook) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+	observability.EndSpan(trace.SpanFromContext(ctx), commandError(cmd))
+	return nil
+}
+
+func (h *TracingHook) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	ctx, span := h.start(ctx, "redis pipeline", "pipeline")
+	span.SetAttributes(attribute.Int("db.redis.pipeline_length", len(cmds)))
+	return ctx, nil
+}
+
+func (h *TracingHook) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	var err error
+	for _, cmd := range cmds {
+		if err = commandError(cmd); err != nil {
+			break
+		}
+	}
+	observability.EndSpan(trace.SpanFromContext(ctx), err)
+	return nil
+}
+
+func (h *TracingHook) start(ctx context.Context, name, operation string) (context.Context, trace.Span) {
+	return h.tracer.Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemRedis,
+			semconv.DBOperation(operation),
+			semconv.DBRedisDBIndex(h.db),
+		),
+	)
+}
+
+func commandError(cmd redis.Cmder) error {
+	if err := cmd.Err(); err != nil && err != redis.Nil {
+		return err
+	}
+	return nil
+}
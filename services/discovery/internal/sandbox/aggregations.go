@@ -0,0 +1,419 @@
+package sandbox
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// aggregate computes aggregations over the matched documents. Bucket
+// aggregations run their sub-aggregations over each bucket's documents;
+// types the sandbox doesn't know are left out of the response.
+func aggregate(specs map[string]interface{}, docs []*memoryDoc) map[string]interface{} {
+	out := make(map[string]interface{}, len(specs))
+	for name, raw := range specs {
+		spec, _ := raw.(map[string]interface{})
+		sub, _ := spec["aggs"].(map[string]interface{})
+		if sub == nil {
+			sub, _ = spec["aggregations"].(map[string]interface{})
+		}
+		for kind, body := range spec {
+			opts, _ := body.(map[string]interface{})
+			if result := aggregation(kind, opts, sub, docs); result != nil {
+				out[name] = result
+			}
+		}
+	}
+	return out
+}
+
+func aggregation(kind string, opts, sub map[string]interface{}, docs []*memoryDoc) map[string]interface{} {
+	field, _ := opts["field"].(string)
+	switch kind {
+	case "terms":
+		return termsAggregation(opts, sub, docs)
+	case "avg", "min", "max", "sum", "value_count", "cardinality", "stats":
+		return metric(kind, numbers(docs, field), docs, field)
+	case "range":
+		return rangeAggregation(opts, sub, docs)
+	case "histogram":
+		return histogram(opts, sub, docs)
+	case "variable_width_histogram":
+		return variableWidthHistogram(opts, docs)
+	case "filter":
+		return withSubAggregations(map[string]interface{}{}, sub, filterDocs(opts, docs))
+	case "filters":
+		filters, _ := opts["filters"].(map[string]interface{})
+		buckets := make(map[string]interface{}, len(filters))
+		for name, q := range filters {
+			query, _ := q.(map[string]interface{})
+			buckets[name] = withSubAggregations(map[string]interface{}{}, sub, filterDocs(query, docs))
+		}
+		return map[string]interface{}{"buckets": buckets}
+	case "missing":
+		var missing []*memoryDoc
+		for _, doc := range docs {
+			if len(values(doc, field)) == 0 {
+				missing = append(missing, doc)
+			}
+		}
+		return withSubAggregations(map[string]interface{}{}, sub, missing)
+	}
+	return nil
+}
+
+func withSubAggregations(bucket, sub map[string]interface{}, docs []*memoryDoc) map[string]interface{} {
+	bucket["doc_count"] = len(docs)
+	for name, result := range aggregate(sub, docs) {
+		bucket[name] = result
+	}
+	return bucket
+}
+
+func filterDocs(query map[string]interface{}, docs []*memoryDoc) []*memoryDoc {
+	var out []*memoryDoc
+	for _, doc := range docs {
+		if ok, _ := matches(query, doc); ok {
+			out = append(out, doc)
+		}
+	}
+	return out
+}
+
+// termsAggregation buckets documents by each distinct value of a field,
+// most documents first
+func termsAggregation(opts, sub map[string]interface{}, docs []*memoryDoc) map[string]interface{} {
+	field, _ := opts["field"].(string)
+	size := int(number(opts["size"], 10))
+	minCount := int(number(opts["min_doc_count"], 1))
+
+	type bucket struct {
+		key  interface{}
+		docs []*memoryDoc
+	}
+	byKey := make(map[string]*bucket)
+	for _, doc := range docs {
+		seen := make(map[string]bool)
+		for _, v := range values(doc, field) {
+			k := fmt.Sprint(v)
+			if seen[k] {
+				continue
+			}
+			seen[k] = true
+			if byKey[k] == nil {
+				byKey[k] = &bucket{key: v}
+			}
+			byKey[k].docs = append(byKey[k].docs, doc)
+		}
+	}
+
+	buckets := make([]*bucket, 0, len(byKey))
+	for _, b := range byKey {
+		if len(b.docs) >= minCount {
+			buckets = append(buckets, b)
+		}
+	}
+	sort.Slice(buckets, func(i, j int) bool {
+		if len(buckets[i].docs) != len(buckets[j].docs) {
+			return len(buckets[i].docs) > len(buckets[j].docs)
+		}
+		return compareSort(buckets[i].key, buckets[j].key) < 0
+	})
+
+	other := 0
+	out := []interface{}{}
+	for i, b := range buckets {
+		if i >= size {
+			other += len(b.docs)
+			continue
+		}
+		rendered := map[string]interface{}{"key": b.key}
+		if flag, ok := b.key.(bool); ok {
+			rendered["key"], rendered["key_as_string"] = 0, "false"
+			if flag {
+				rendered["key"], rendered["key_as_string"] = 1, "true"
+			}
+		}
+		out = append(out, withSubAggregations(rendered, sub, b.docs))
+	}
+	return map[string]interface{}{"doc_count_error_upper_bound": 0, "sum_other_doc_count": other, "buckets": out}
+}
+
+// numbers collects every numeric value of a field
+func numbers(docs []*memoryDoc, field string) []float64 {
+	var out []float64
+	for _, doc := range docs {
+		for _, v := range values(doc, field) {
+			if f, ok := v.(float64); ok {
+				out = append(out, f)
+			}
+		}
+	}
+	return out
+}
+
+func metric(kind string, nums []float64, docs []*memoryDoc, field string) map[string]interface{} {
+	sum, lo, hi := 0.0, math.Inf(1), math.Inf(-1)
+	for _, n := range nums {
+		sum += n
+		lo, hi = math.Min(lo, n), math.Max(hi, n)
+	}
+	// Metrics over no values are null, except the sum and counts
+	var avg, minimum, maximum interface{}
+	if len(nums) > 0 {
+		avg, minimum, maximum = sum/float64(len(nums)), lo, hi
+	}
+
+	switch kind {
+	case "avg":
+		return map[string]interface{}{"value": avg}
+	case "min":
+		return map[string]interface{}{"value": minimum}
+	case "max":
+		return map[string]interface{}{"value": maximum}
+	case "sum":
+		return map[string]interface{}{"value": sum}
+	case "value_count":
+		count := 0
+		for _, doc := range docs {
+			count += len(values(doc, field))
+		}
+		return map[string]interface{}{"value": count}
+	case "cardinality":
+		distinct := make(map[string]bool)
+		for _, doc := range docs {
+			for _, v := range values(doc, field) {
+				distinct[fmt.Sprint(v)] = true
+			}
+		}
+		return map[string]interface{}{"value": len(distinct)}
+	}
+	return map[string]interface{}{"count": len(nums), "min": minimum, "max": maximum, "avg": avg, "sum": sum}
+}
+
+// rangeAggregation counts documents with a value in each range, from
+// inclusive and to exclusive
+func rangeAggregation(opts, sub map[string]interface{}, docs []*memoryDoc) map[string]interface{} {
+	field, _ := opts["field"].(string)
+	ranges, _ := opts["ranges"].([]interface{})
+	out := make([]interface{}, 0, len(ranges))
+	for _, raw := range ranges {
+		r, _ := raw.(map[string]interface{})
+		from, hasFrom := toFloat(r["from"])
+		to, hasTo := toFloat(r["to"])
+
+		var inRange []*memoryDoc
+		for _, doc := range docs {
+			for _, v := range values(doc, field) {
+				n, ok := v.(float64)
+				if ok && (!hasFrom || n >= from) && (!hasTo || n < to) {
+					inRange = append(inRange, doc)
+					break
+				}
+			}
+		}
+
+		bucket := map[string]interface{}{}
+		key := rangeKey(r["from"], r["to"])
+		if k, ok := r["key"].(string); ok {
+			key = k
+		}
+		bucket["key"] = key
+		if hasFrom {
+			bucket["from"] = from
+		}
+		if hasTo {
+			bucket["to"] = to
+		}
+		out = append(out, withSubAggregations(bucket, sub, inRange))
+	}
+	return map[string]interface{}{"buckets": out}
+}
+
+func rangeKey(from, to interface{}) string {
+	bound := func(v interface{}) string {
+		if v == nil {
+			return "*"
+		}
+		return fmt.Sprint(v)
+	}
+	return bound(from) + "-" + bound(to)
+}
+
+// histogram buckets values by a fixed interval, including the empty
+// buckets between the lowest and highest
+func histogram(opts, sub map[string]interface{}, docs []*memoryDoc) map[string]interface{} {
+	field, _ := opts["field"].(string)
+	interval := number(opts["interval"], 1)
+	minCount := int(number(opts["min_doc_count"], 0))
+	if interval <= 0 {
+		return map[string]interface{}{"buckets": []interface{}{}}
+	}
+
+	byKey := make(map[float64][]*memoryDoc)
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for _, doc := range docs {
+		seen := make(map[float64]bool)
+		for _, v := range values(doc, field) {
+			n, ok := v.(float64)
+			if !ok {
+				continue
+			}
+			key := math.Floor(n/interval) * interval
+			lo, hi = math.Min(lo, key), math.Max(hi, key)
+			if !seen[key] {
+				seen[key] = true
+				byKey[key] = append(byKey[key], doc)
+			}
+		}
+	}
+
+	out := []interface{}{}
+	for key := lo; key <= hi; key += interval {
+		if len(byKey[key]) < minCount {
+			continue
+		}
+		out = append(out, withSubAggregations(map[string]interface{}{"key": key}, sub, byKey[key]))
+	}
+	return map[string]interface{}{"buckets": out}
+}
+
+// variableWidthHistogram splits the sorted values into up to the requested
+// number of buckets of roughly equal size; Elasticsearch clusters them
+// instead, but either keeps outliers from stretching every bucket
+func variableWidthHistogram(opts map[string]interface{}, docs []*memoryDoc) map[string]interface{} {
+	field, _ := opts["field"].(string)
+	nums := numbers(docs, field)
+	sort.Float64s(nums)
+	n := int(number(opts["buckets"], 10))
+
+	out := []interface{}{}
+	if len(nums) == 0 || n <= 0 {
+		return map[string]interface{}{"buckets": out}
+	}
+	n = min(n, len(nums))
+	for i := range n {
+		group := nums[i*len(nums)/n : (i+1)*len(nums)/n]
+		sum := 0.0
+		for _, v := range group {
+			sum += v
+		}
+		out = append(out, map[string]interface{}{
+			"key":       sum / float64(len(group)),
+			"min":       group[0],
+			"max":       group[len(group)-1],
+			"doc_count": len(group),
+		})
+	}
+	return map[string]interface{}{"buckets": out}
+}
+
+// suggest answers completion suggesters: documents with an input starting
+// with the prefix, ignoring case, and sharing a value with each requested
+// context, highest weight first
+func suggest(specs map[string]interface{}, docs []*memoryDoc, index string, includes, excludes []string) map[string]interface{} {
+	out := make(map[string]interface{}, len(specs))
+	for name, raw := range specs {
+		spec, _ := raw.(map[string]interface{})
+		prefix := fmt.Sprint(spec["prefix"])
+		completion, _ := spec["completion"].(map[string]interface{})
+		if completion == nil {
+			continue
+		}
+		field, _ := completion["field"].(string)
+		size := int(number(completion["size"], 5))
+		skipDuplicates, _ := completion["skip_duplicates"].(bool)
+		contexts, _ := completion["contexts"].(map[string]interface{})
+
+		type option struct {
+			text   string
+			weight float64
+			doc    *memoryDoc
+		}
+		var options []option
+		for _, doc := range docs {
+			for _, v := range values(doc, field) {
+				entry, _ := v.(map[string]interface{})
+				if entry == nil {
+					entry = map[string]interface{}{"input": v}
+				}
+				if !contextsMatch(contexts, entry["contexts"]) {
+					continue
+				}
+				inputs := entry["input"]
+				if s, ok := inputs.(string); ok {
+					inputs = []interface{}{s}
+				}
+				list, _ := inputs.([]interface{})
+				for _, in := range list {
+					text := fmt.Sprint(in)
+					if strings.HasPrefix(strings.ToLower(text), strings.ToLower(prefix)) {
+						options = append(options, option{text: text, weight: number(entry["weight"], 1), doc: doc})
+						break
+					}
+				}
+			}
+		}
+		sort.SliceStable(options, func(i, j int) bool {
+			if options[i].weight != options[j].weight {
+				return options[i].weight > options[j].weight
+			}
+			return options[i].text < options[j].text
+		})
+
+		rendered := []interface{}{}
+		seen := make(map[string]bool)
+		for _, o := range options {
+			if len(rendered) >= size {
+				break
+			}
+			if skipDuplicates && seen[o.text] {
+				continue
+			}
+			seen[o.text] = true
+			rendered = append(rendered, map[string]interface{}{
+				"text":    o.text,
+				"_index":  index,
+				"_id":     o.doc.id,
+				"_score":  o.weight,
+				"_source": filterSource(o.doc.source, includes, excludes),
+			})
+		}
+		out[name] = []interface{}{map[string]interface{}{
+			"text": prefix, "offset": 0, "length": len(prefix), "options": rendered,
+		}}
+	}
+	return out
+}
+
+// contextsMatch reports whether a suggestion has a value of every context
+// the request names; requested values may be strings or {"context": ...}
+func contextsMatch(requested map[string]interface{}, docContexts interface{}) bool {
+	have, _ := docContexts.(map[string]interface{})
+	for name, raw := range requested {
+		wanted, ok := raw.([]interface{})
+		if !ok {
+			wanted = []interface{}{raw}
+		}
+		docValues, ok := have[name].([]interface{})
+		if !ok && have[name] != nil {
+			docValues = []interface{}{have[name]}
+		}
+		found := false
+		for _, w := range wanted {
+			if m, ok := w.(map[string]interface{}); ok {
+				w = m["context"]
+			}
+			for _, d := range docValues {
+				if fmt.Sprint(d) == fmt.Sprint(w) {
+					found = true
+				}
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,622 @@
+package sandbox
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// memoryElasticsearch answers the part of the Elasticsearch REST API the
+// service uses from documents held in memory. Mappings are accepted and
+// ignored: keyword and text fields are told apart by the query, not the
+// mapping, and writes are visible to the next search.
+type memoryElasticsearch struct {
+	mu      sync.RWMutex
+	indices map[string]*memoryIndex
+	pits    map[string]*memoryIndex // Frozen copies of an index, by point in time ID
+	nextPIT int
+}
+
+// memoryIndex holds an index's documents by ID. Each document keeps the
+// sequence number it was first written with, which orders ties and
+// _shard_doc sorts as index order does in Elasticsearch.
+type memoryIndex struct {
+	name string
+	docs map[string]*memoryDoc
+	seq  int64
+}
+
+type memoryDoc struct {
+	id     string
+	seq    int64
+	source map[string]interface{}
+}
+
+func newMemoryElasticsearch() *memoryElasticsearch {
+	return &memoryElasticsearch{
+		indices: make(map[string]*memoryIndex),
+		pits:    make(map[string]*memoryIndex),
+	}
+}
+
+// index returns the named index, creating it when create is set as
+// Elasticsearch does on a document's first write
+func (m *memoryElasticsearch) index(name string, create bool) *memoryIndex {
+	idx := m.indices[name]
+	if idx == nil && create {
+		idx = &memoryIndex{name: name, docs: make(map[string]*memoryDoc)}
+		m.indices[name] = idx
+	}
+	return idx
+}
+
+func (idx *memoryIndex) put(id string, source map[string]interface{}) (created bool) {
+	if doc, ok := idx.docs[id]; ok {
+		doc.source = source
+		return false
+	}
+	idx.seq++
+	idx.docs[id] = &memoryDoc{id: id, seq: idx.seq, source: source}
+	return true
+}
+
+// snapshot copies the index for a point in time; sources are replaced
+// rather than modified on write, so they can be shared
+func (idx *memoryIndex) snapshot() *memoryIndex {
+	docs := make(map[string]*memoryDoc, len(idx.docs))
+	for id, doc := range idx.docs {
+		copied := *doc
+		docs[id] = &copied
+	}
+	return &memoryIndex{name: idx.name, docs: docs, seq: idx.seq}
+}
+
+func (m *memoryElasticsearch) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// The client refuses to talk to a server that doesn't claim to be Elasticsearch
+	w.Header().Set("X-Elastic-Product", "Elasticsearch")
+	w.Header().Set("Content-Type", "application/json")
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "parse_exception", err.Error())
+		return
+	}
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if parts[0] == "" {
+		parts = nil
+	}
+	switch {
+	case len(parts) == 0:
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"name":         "sandbox",
+			"cluster_name": "sandbox",
+			"version":      map[string]interface{}{"number": "8.12.1", "build_flavor": "default"},
+			"tagline":      "You Know, for Search",
+		})
+	case parts[0] == "_cluster":
+		writeJSON(w, http.StatusOK, map[string]interface{}{"cluster_name": "sandbox", "status": "green", "number_of_nodes": 1})
+	case parts[0] == "_bulk" || len(parts) == 2 && parts[1] == "_bulk":
+		m.bulk(w, defaultIndex(parts), body)
+	case parts[0] == "_msearch" || len(parts) == 2 && parts[1] == "_msearch":
+		m.msearch(w, defaultIndex(parts), body)
+	case parts[0] == "_search":
+		m.search(w, "", body)
+	case parts[0] == "_pit":
+		var req struct {
+			ID string `json:"id"`
+		}
+		json.Unmarshal(body, &req)
+		m.mu.Lock()
+		_, found := m.pits[req.ID]
+		delete(m.pits, req.ID)
+		m.mu.Unlock()
+		freed := 0
+		if found {
+			freed = 1
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"succeeded": true, "num_freed": freed})
+	case parts[0] == "_mget":
+		m.mget(w, r, "", body)
+	case len(parts) == 1:
+		m.indexAdmin(w, r.Method, parts[0])
+	default:
+		m.indexAPI(w, r, parts, body)
+	}
+}
+
+// defaultIndex is the index a bulk or multi-search request names in its
+// path, for items that don't name one
+func defaultIndex(parts []string) string {
+	if len(parts) == 2 {
+		return parts[0]
+	}
+	return ""
+}
+
+// indexAdmin creates, checks and deletes an index
+func (m *memoryElasticsearch) indexAdmin(w http.ResponseWriter, method, name string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	switch method {
+	case http.MethodHead, http.MethodGet:
+		if m.index(name, false) == nil {
+			writeError(w, http.StatusNotFound, "index_not_found_exception", "no such index ["+name+"]")
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{name: map[string]interface{}{}})
+	case http.MethodPut:
+		if m.index(name, false) != nil {
+			writeError(w, http.StatusBadRequest, "resource_already_exists_exception", "index ["+name+"] already exists")
+			return
+		}
+		m.index(name, true)
+		writeJSON(w, http.StatusOK, map[string]interface{}{"acknowledged": true, "index": name})
+	case http.MethodDelete:
+		if m.index(name, false) == nil {
+			writeError(w, http.StatusNotFound, "index_not_found_exception", "no such index ["+name+"]")
+			return
+		}
+		delete(m.indices, name)
+		writeJSON(w, http.StatusOK, map[string]interface{}{"acknowledged": true})
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", method+" is not supported on an index")
+	}
+}
+
+// indexAPI serves the endpoints under /<index>/
+func (m *memoryElasticsearch) indexAPI(w http.ResponseWriter, r *http.Request, parts []string, body []byte) {
+	name, endpoint := parts[0], parts[1]
+	id := ""
+	if len(parts) > 2 {
+		id = parts[2]
+	}
+
+	switch endpoint {
+	case "_search":
+		m.search(w, name, body)
+	case "_mget":
+		m.mget(w, r, name, body)
+	case "_count":
+		m.count(w, name, body)
+	case "_explain":
+		m.explain(w, name, id, body)
+	case "_mapping", "_settings", "_refresh", "_flush", "_forcemerge":
+		m.mu.Lock()
+		m.index(name, endpoint == "_mapping")
+		m.mu.Unlock()
+		writeJSON(w, http.StatusOK, map[string]interface{}{"acknowledged": true, "_shards": shards()})
+	case "_stats":
+		m.mu.RLock()
+		count := 0
+		if idx := m.index(name, false); idx != nil {
+			count = len(idx.docs)
+		}
+		m.mu.RUnlock()
+		stats := map[string]interface{}{"docs": map[string]interface{}{"count": count, "deleted": 0}}
+		all := map[string]interface{}{"primaries": stats, "total": stats}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"_all": all, "indices": map[string]interface{}{name: all}})
+	case "_pit":
+		m.mu.Lock()
+		idx := m.index(name, false)
+		if idx == nil {
+			m.mu.Unlock()
+			writeError(w, http.StatusNotFound, "index_not_found_exception", "no such index ["+name+"]")
+			return
+		}
+		m.nextPIT++
+		pitID := "sandbox-pit-" + strconv.Itoa(m.nextPIT)
+		m.pits[pitID] = idx.snapshot()
+		m.mu.Unlock()
+		writeJSON(w, http.StatusOK, map[string]interface{}{"id": pitID})
+	case "_doc", "_create":
+		m.document(w, r, name, id, body, endpoint == "_create")
+	case "_update":
+		m.update(w, name, id, body)
+	case "_delete_by_query":
+		m.deleteByQuery(w, name, body)
+	default:
+		writeError(w, http.StatusBadRequest, "illegal_argument_exception", "the sandbox does not support "+endpoint)
+	}
+}
+
+func (m *memoryElasticsearch) document(w http.ResponseWriter, r *http.Request, name, id string, body []byte, create bool) {
+	switch method := r.Method; method {
+	case http.MethodGet, http.MethodHead:
+		m.mu.RLock()
+		defer m.mu.RUnlock()
+		idx := m.index(name, false)
+		if idx == nil || idx.docs[id] == nil {
+			writeJSON(w, http.StatusNotFound, map[string]interface{}{"_index": name, "_id": id, "found": false})
+			return
+		}
+		source := filterSource(idx.docs[id].source, listParam(r, "_source_includes"), listParam(r, "_source_excludes"))
+		writeJSON(w, http.StatusOK, map[string]interface{}{"_index": name, "_id": id, "found": true, "_source": source})
+	case http.MethodPut, http.MethodPost:
+		var source map[string]interface{}
+		if err := json.Unmarshal(body, &source); err != nil {
+			writeError(w, http.StatusBadRequest, "mapper_parsing_exception", err.Error())
+			return
+		}
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		idx := m.index(name, true)
+		if id == "" {
+			id = fmt.Sprintf("sandbox-%d", idx.seq+1)
+		}
+		if create && idx.docs[id] != nil {
+			writeError(w, http.StatusConflict, "version_conflict_engine_exception", "["+id+"]: document already exists")
+			return
+		}
+		result, status := "updated", http.StatusOK
+		if idx.put(id, source) {
+			result, status = "created", http.StatusCreated
+		}
+		writeJSON(w, status, map[string]interface{}{"_index": name, "_id": id, "result": result, "_shards": shards()})
+	case http.MethodDelete:
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		idx := m.index(name, false)
+		if idx == nil || idx.docs[id] == nil {
+			writeJSON(w, http.StatusNotFound, map[string]interface{}{"_index": name, "_id": id, "result": "not_found"})
+			return
+		}
+		delete(idx.docs, id)
+		writeJSON(w, http.StatusOK, map[string]interface{}{"_index": name, "_id": id, "result": "deleted"})
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", method+" is not supported on a document")
+	}
+}
+
+// update merges a partial document into a stored one
+func (m *memoryElasticsearch) update(w http.ResponseWriter, name, id string, body []byte) {
+	var req struct {
+		Doc    map[string]interface{} `json:"doc"`
+		Upsert map[string]interface{} `json:"upsert"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "parse_exception", err.Error())
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	idx := m.index(name, false)
+	if idx == nil || idx.docs[id] == nil {
+		if req.Upsert == nil {
+			writeError(w, http.StatusNotFound, "document_missing_exception", "["+id+"]: document missing")
+			return
+		}
+		m.index(name, true).put(id, req.Upsert)
+		writeJSON(w, http.StatusCreated, map[string]interface{}{"_index": name, "_id": id, "result": "created"})
+		return
+	}
+	idx.put(id, merge(idx.docs[id].source, req.Doc))
+	writeJSON(w, http.StatusOK, map[string]interface{}{"_index": name, "_id": id, "result": "updated"})
+}
+
+// merge returns base with patch applied as Elasticsearch applies a partial
+// document: objects merge recursively, anything else is replaced
+func merge(base, patch map[string]interface{}) map[string]interface{} {
+	merged := make(map[string]interface{}, len(base)+len(patch))
+	for k, v := range base {
+		merged[k] = v
+	}
+	for k, v := range patch {
+		if sub, ok := v.(map[string]interface{}); ok {
+			if existing, ok := merged[k].(map[string]interface{}); ok {
+				merged[k] = merge(existing, sub)
+				continue
+			}
+		}
+		merged[k] = v
+	}
+	return merged
+}
+
+func (m *memoryElasticsearch) bulk(w http.ResponseWriter, defaultName string, body []byte) {
+	type action struct {
+		Index string `json:"_index"`
+		ID    string `json:"_id"`
+	}
+
+	items := []interface{}{}
+	failed := false
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	scanner.Buffer(make([]byte, 0, 64<<10), 64<<20)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var header map[string]action
+		if err := json.Unmarshal(line, &header); err != nil || len(header) != 1 {
+			writeError(w, http.StatusBadRequest, "illegal_argument_exception", "malformed bulk action line")
+			return
+		}
+		for op, a := range header {
+			if a.Index == "" {
+				a.Index = defaultName
+			}
+			result := map[string]interface{}{"_index": a.Index, "_id": a.ID}
+			items = append(items, map[string]interface{}{op: result})
+
+			if op == "delete" {
+				if idx := m.index(a.Index, false); idx != nil && idx.docs[a.ID] != nil {
+					delete(idx.docs, a.ID)
+					result["status"], result["result"] = http.StatusOK, "deleted"
+				} else {
+					result["status"], result["result"] = http.StatusNotFound, "not_found"
+				}
+				continue
+			}
+
+			if !scanner.Scan() {
+				writeError(w, http.StatusBadRequest, "illegal_argument_exception", "bulk action without a source line")
+				return
+			}
+			var source map[string]interface{}
+			if err := json.Unmarshal(scanner.Bytes(), &source); err != nil {
+				failed = true
+				result["status"] = http.StatusBadRequest
+				result["error"] = map[string]interface{}{"type": "mapper_parsing_exception", "reason": err.Error()}
+				continue
+			}
+
+			idx := m.index(a.Index, true)
+			switch op {
+			case "update":
+				if idx.docs[a.ID] == nil {
+					failed = true
+					result["status"] = http.StatusNotFound
+					result["error"] = map[string]interface{}{"type": "document_missing_exception", "reason": "[" + a.ID + "]: document missing"}
+					continue
+				}
+				patch, _ := source["doc"].(map[string]interface{})
+				idx.put(a.ID, merge(idx.docs[a.ID].source, patch))
+				result["status"], result["result"] = http.StatusOK, "updated"
+			case "create":
+				if idx.docs[a.ID] != nil {
+					failed = true
+					result["status"] = http.StatusConflict
+					result["error"] = map[string]interface{}{"type": "version_conflict_engine_exception", "reason": "[" + a.ID + "]: document already exists"}
+					continue
+				}
+				fallthrough
+			default:
+				if a.ID == "" {
+					a.ID = fmt.Sprintf("sandbox-%d", idx.seq+1)
+					result["_id"] = a.ID
+				}
+				if idx.put(a.ID, source) {
+					result["status"], result["result"] = http.StatusCreated, "created"
+				} else {
+					result["status"], result["result"] = http.StatusOK, "updated"
+				}
+			}
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"took": 1, "errors": failed, "items": items})
+}
+
+func (m *memoryElasticsearch) mget(w http.ResponseWriter, r *http.Request, name string, body []byte) {
+	var req struct {
+		IDs  []string `json:"ids"`
+		Docs []struct {
+			Index string `json:"_index"`
+			ID    string `json:"_id"`
+		} `json:"docs"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "parse_exception", err.Error())
+		return
+	}
+	for _, id := range req.IDs {
+		req.Docs = append(req.Docs, struct {
+			Index string `json:"_index"`
+			ID    string `json:"_id"`
+		}{name, id})
+	}
+
+	includes, excludes := listParam(r, "_source_includes"), listParam(r, "_source_excludes")
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	docs := make([]interface{}, 0, len(req.Docs))
+	for _, ref := range req.Docs {
+		if ref.Index == "" {
+			ref.Index = name
+		}
+		result := map[string]interface{}{"_index": ref.Index, "_id": ref.ID, "found": false}
+		if idx := m.index(ref.Index, false); idx != nil && idx.docs[ref.ID] != nil {
+			result["found"] = true
+			result["_source"] = filterSource(idx.docs[ref.ID].source, includes, excludes)
+		}
+		docs = append(docs, result)
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"docs": docs})
+}
+
+func (m *memoryElasticsearch) search(w http.ResponseWriter, name string, body []byte) {
+	var req searchRequest
+	if len(bytes.TrimSpace(body)) > 0 {
+		if err := json.Unmarshal(body, &req); err != nil {
+			writeError(w, http.StatusBadRequest, "parse_exception", err.Error())
+			return
+		}
+	}
+
+	resp, status, err := m.run(name, &req)
+	if err != nil {
+		writeError(w, status, "search_phase_execution_exception", err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// run executes a search against the named index, or the point in time the
+// request names
+func (m *memoryElasticsearch) run(name string, req *searchRequest) (map[string]interface{}, int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var idx *memoryIndex
+	if req.PIT != nil {
+		if idx = m.pits[req.PIT.ID]; idx == nil {
+			return nil, http.StatusNotFound, fmt.Errorf("no search context found for id [%s]", req.PIT.ID)
+		}
+	} else if idx = m.index(name, false); idx == nil {
+		return nil, http.StatusNotFound, fmt.Errorf("no such index [%s]", name)
+	}
+
+	resp := execute(idx, req)
+	if req.PIT != nil {
+		resp["pit_id"] = req.PIT.ID
+	}
+	return resp, http.StatusOK, nil
+}
+
+func (m *memoryElasticsearch) msearch(w http.ResponseWriter, defaultName string, body []byte) {
+	responses := []interface{}{}
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	scanner.Buffer(make([]byte, 0, 64<<10), 64<<20)
+	for scanner.Scan() {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var header struct {
+			Index string `json:"index"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &header); err != nil || !scanner.Scan() {
+			writeError(w, http.StatusBadRequest, "illegal_argument_exception", "malformed multi-search request")
+			return
+		}
+		if header.Index == "" {
+			header.Index = defaultName
+		}
+
+		var req searchRequest
+		if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+			responses = append(responses, map[string]interface{}{"status": http.StatusBadRequest, "error": errorBody("parse_exception", err.Error())})
+			continue
+		}
+		resp, status, err := m.run(header.Index, &req)
+		if err != nil {
+			responses = append(responses, map[string]interface{}{"status": status, "error": errorBody("index_not_found_exception", err.Error())})
+			continue
+		}
+		resp["status"] = http.StatusOK
+		responses = append(responses, resp)
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"took": 1, "responses": responses})
+}
+
+func (m *memoryElasticsearch) count(w http.ResponseWriter, name string, body []byte) {
+	var req searchRequest
+	if len(bytes.TrimSpace(body)) > 0 {
+		if err := json.Unmarshal(body, &req); err != nil {
+			writeError(w, http.StatusBadRequest, "parse_exception", err.Error())
+			return
+		}
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	n := 0
+	if idx := m.index(name, false); idx != nil {
+		for _, doc := range idx.docs {
+			if ok, _ := matches(req.Query, doc); ok {
+				n++
+			}
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"count": n, "_shards": shards()})
+}
+
+// explain reports whether the document matches the query and its score. The
+// sandbox's scoring is an approximation, so there is no breakdown.
+func (m *memoryElasticsearch) explain(w http.ResponseWriter, name, id string, body []byte) {
+	var req searchRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "parse_exception", err.Error())
+		return
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	idx := m.index(name, false)
+	if idx == nil || idx.docs[id] == nil {
+		writeJSON(w, http.StatusNotFound, map[string]interface{}{"_index": name, "_id": id, "matched": false})
+		return
+	}
+	matched, score := matches(req.Query, idx.docs[id])
+	description := "sandbox score"
+	if !matched {
+		score, description = 0, "no matching query"
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"_index":      name,
+		"_id":         id,
+		"matched":     matched,
+		"explanation": map[string]interface{}{"value": score, "description": description, "details": []interface{}{}},
+	})
+}
+
+func (m *memoryElasticsearch) deleteByQuery(w http.ResponseWriter, name string, body []byte) {
+	var req searchRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "parse_exception", err.Error())
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	deleted := 0
+	if idx := m.index(name, false); idx != nil {
+		for id, doc := range idx.docs {
+			if ok, _ := matches(req.Query, doc); ok {
+				delete(idx.docs, id)
+				deleted++
+			}
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"deleted": deleted, "total": deleted, "failures": []interface{}{}})
+}
+
+// listParam splits a comma-separated query parameter
+func listParam(r *http.Request, name string) []string {
+	if value := r.URL.Query().Get(name); value != "" {
+		return strings.Split(value, ",")
+	}
+	return nil
+}
+
+func shards() map[string]interface{} {
+	return map[string]interface{}{"total": 1, "successful": 1, "failed": 0}
+}
+
+func errorBody(errType, reason string) map[string]interface{} {
+	return map[string]interface{}{
+		"root_cause": []interface{}{map[string]interface{}{"type": errType, "reason": reason}},
+		"type":       errType,
+		"reason":     reason,
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, errType, reason string) {
+	writeJSON(w, status, map[string]interface{}{"error": errorBody(errType, reason), "status": status})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,169 @@
+package sandbox
+
+import (
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgproto3"
+)
+
+// nullPostgres speaks the PostgreSQL wire protocol without storing
+// anything: every statement succeeds, writes affect no rows and queries
+// return none. It stands in for the database so the service starts and its
+// Postgres-backed features behave as they do on an empty one.
+type nullPostgres struct{}
+
+// serve answers connections on ln until it is closed
+func (nullPostgres) serve(ln net.Listener) {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		go servePostgres(conn)
+	}
+}
+
+func servePostgres(conn net.Conn) {
+	defer conn.Close()
+	backend := pgproto3.NewBackend(conn, conn)
+
+	for {
+		startup, err := backend.ReceiveStartupMessage()
+		if err != nil {
+			return
+		}
+		if _, ok := startup.(*pgproto3.CancelRequest); ok {
+			return
+		}
+		if _, ok := startup.(*pgproto3.StartupMessage); ok {
+			break
+		}
+		// Decline encryption; the client goes on in plain text
+		if _, err := conn.Write([]byte("N")); err != nil {
+			return
+		}
+	}
+
+	backend.Send(&pgproto3.AuthenticationOk{})
+	for name, value := range map[string]string{
+		"server_version":              "16.0",
+		"server_encoding":             "UTF8",
+		"client_encoding":             "UTF8",
+		"DateStyle":                   "ISO, MDY",
+		"TimeZone":                    "UTC",
+		"integer_datetimes":           "on",
+		"standard_conforming_strings": "on",
+	} {
+		backend.Send(&pgproto3.ParameterStatus{Name: name, Value: value})
+	}
+	backend.Send(&pgproto3.BackendKeyData{ProcessID: 1, SecretKey: 1})
+	backend.Send(&pgproto3.ReadyForQuery{TxStatus: 'I'})
+	if backend.Flush() != nil {
+		return
+	}
+
+	// Statements by name, and portals by name to the statement they bind
+	statements := map[string]string{}
+	portals := map[string]string{}
+	// After an error the extended protocol skips to the next Sync
+	failed := false
+	for {
+		msg, err := backend.Receive()
+		if err != nil {
+			return
+		}
+		if failed {
+			if _, ok := msg.(*pgproto3.Sync); !ok {
+				continue
+			}
+		}
+
+		switch m := msg.(type) {
+		case *pgproto3.Query:
+			for _, sql := range strings.Split(m.String, ";") {
+				if strings.TrimSpace(sql) != "" {
+					backend.Send(&pgproto3.CommandComplete{CommandTag: commandTag(sql)})
+				}
+			}
+			if strings.TrimSpace(m.String) == "" {
+				backend.Send(&pgproto3.EmptyQueryResponse{})
+			}
+			backend.Send(&pgproto3.ReadyForQuery{TxStatus: 'I'})
+		case *pgproto3.Parse:
+			statements[m.Name] = m.Query
+			backend.Send(&pgproto3.ParseComplete{})
+		case *pgproto3.Describe:
+			if m.ObjectType == 'S' {
+				sql, ok := statements[m.Name]
+				if !ok {
+					backend.Send(&pgproto3.ErrorResponse{Severity: "ERROR", Code: "26000", Message: "prepared statement does not exist"})
+					failed = true
+					break
+				}
+				// Parameters of unknown type, which the client sends as text
+				backend.Send(&pgproto3.ParameterDescription{ParameterOIDs: make([]uint32, parameterCount(sql))})
+			}
+			backend.Send(&pgproto3.NoData{})
+		case *pgproto3.Bind:
+			portals[m.DestinationPortal] = statements[m.PreparedStatement]
+			backend.Send(&pgproto3.BindComplete{})
+		case *pgproto3.Execute:
+			backend.Send(&pgproto3.CommandComplete{CommandTag: commandTag(portals[m.Portal])})
+		case *pgproto3.Close:
+			if m.ObjectType == 'S' {
+				delete(statements, m.Name)
+			} else {
+				delete(portals, m.Name)
+			}
+			backend.Send(&pgproto3.CloseComplete{})
+		case *pgproto3.Sync:
+			failed = false
+			backend.Send(&pgproto3.ReadyForQuery{TxStatus: 'I'})
+		case *pgproto3.Flush:
+		case *pgproto3.Terminate:
+			return
+		default:
+			backend.Send(&pgproto3.ErrorResponse{Severity: "ERROR", Code: "0A000", Message: "not supported by the sandbox"})
+			failed = true
+		}
+		if backend.Flush() != nil {
+			return
+		}
+	}
+}
+
+var parameter = regexp.MustCompile(`\$(\d+)`)
+
+// parameterCount returns the highest $n placeholder in sql
+func parameterCount(sql string) int {
+	count := 0
+	for _, m := range parameter.FindAllStringSubmatch(sql, -1) {
+		if n, err := strconv.Atoi(m[1]); err == nil {
+			count = max(count, n)
+		}
+	}
+	return count
+}
+
+// commandTag reports sql as having affected no rows
+func commandTag(sql string) []byte {
+	fields := strings.Fields(sql)
+	if len(fields) == 0 {
+		return nil
+	}
+	verb := strings.ToUpper(fields[0])
+	if verb == "WITH" {
+		// The verb of a common table expression's main statement isn't worth parsing for
+		verb = "SELECT"
+	}
+	switch verb {
+	case "SELECT", "UPDATE", "DELETE", "MERGE", "FETCH", "MOVE", "COPY":
+		return []byte(verb + " 0")
+	case "INSERT":
+		return []byte("INSERT 0 0")
+	}
+	return []byte(verb)
+}
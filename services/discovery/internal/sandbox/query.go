@@ -0,0 +1,929 @@
+package sandbox
+
+import (
+	"fmt"
+	"math"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// searchRequest is the part of a search body the sandbox evaluates
+type searchRequest struct {
+	Query        map[string]interface{} `json:"query"`
+	From         *int                   `json:"from"`
+	Size         *int                   `json:"size"`
+	Sort         interface{}            `json:"sort"`
+	SearchAfter  []interface{}          `json:"search_after"`
+	Source       interface{}            `json:"_source"`
+	Aggs         map[string]interface{} `json:"aggs"`
+	Aggregations map[string]interface{} `json:"aggregations"`
+	Suggest      map[string]interface{} `json:"suggest"`
+	MinScore     *float64               `json:"min_score"`
+	PIT          *struct {
+		ID string `json:"id"`
+	} `json:"pit"`
+}
+
+// hit is a document that matched, with its score and sort values
+type hit struct {
+	doc   *memoryDoc
+	score float64
+	sort  []interface{}
+}
+
+// execute runs req against idx and renders the search response. Scoring
+// only approximates BM25: a matched term is worth more in a shorter field,
+// so names outrank descriptions, but there is no inverse document frequency.
+func execute(idx *memoryIndex, req *searchRequest) map[string]interface{} {
+	docs := make([]*memoryDoc, 0, len(idx.docs))
+	for _, doc := range idx.docs {
+		docs = append(docs, doc)
+	}
+	sort.Slice(docs, func(i, j int) bool { return docs[i].seq < docs[j].seq })
+
+	includes, excludes, withSource := sourceFilter(req.Source)
+	resp := map[string]interface{}{"took": 1, "timed_out": false, "_shards": shards()}
+	if req.Suggest != nil {
+		resp["suggest"] = suggest(req.Suggest, docs, idx.name, includes, excludes)
+	}
+
+	var hits []hit
+	var matched []*memoryDoc
+	maxScore := 0.0
+	for _, doc := range docs {
+		ok, score := matches(req.Query, doc)
+		if !ok || req.MinScore != nil && score < *req.MinScore {
+			continue
+		}
+		hits = append(hits, hit{doc: doc, score: score})
+		matched = append(matched, doc)
+		maxScore = math.Max(maxScore, score)
+	}
+
+	keys := sortKeys(req.Sort)
+	for i := range hits {
+		if keys != nil {
+			hits[i].sort = sortValues(keys, hits[i])
+		}
+	}
+	sort.SliceStable(hits, func(i, j int) bool { return compareHits(keys, hits[i], hits[j]) < 0 })
+	if len(req.SearchAfter) > 0 && keys != nil {
+		after := hit{sort: req.SearchAfter}
+		start := sort.Search(len(hits), func(i int) bool { return compareHits(keys, hits[i], after) > 0 })
+		hits = hits[start:]
+	}
+
+	from, size := 0, 10
+	if req.From != nil {
+		from = max(0, *req.From)
+	}
+	if req.Size != nil {
+		size = max(0, *req.Size)
+	}
+	page := []interface{}{}
+	for i := from; i < len(hits) && i < from+size; i++ {
+		h := map[string]interface{}{"_index": idx.name, "_id": hits[i].doc.id, "_score": hits[i].score}
+		if withSource {
+			h["_source"] = filterSource(hits[i].doc.source, includes, excludes)
+		}
+		if hits[i].sort != nil {
+			h["sort"] = hits[i].sort
+		}
+		page = append(page, h)
+	}
+
+	resp["hits"] = map[string]interface{}{
+		"total":     map[string]interface{}{"value": len(matched), "relation": "eq"},
+		"max_score": maxScore,
+		"hits":      page,
+	}
+	aggs := req.Aggs
+	if aggs == nil {
+		aggs = req.Aggregations
+	}
+	if aggs != nil {
+		resp["aggregations"] = aggregate(aggs, matched)
+	}
+	return resp
+}
+
+// matches reports whether doc matches query, and its score
+func matches(query map[string]interface{}, doc *memoryDoc) (bool, float64) {
+	if len(query) == 0 {
+		return true, 1
+	}
+	for kind, raw := range query {
+		body, _ := raw.(map[string]interface{})
+		boost := number(body["boost"], 1)
+		switch kind {
+		case "match_all":
+			return true, boost
+		case "match_none":
+			return false, 0
+		case "bool":
+			return matchBool(body, doc)
+		case "term":
+			field, value, opts := fieldQuery(body, "value")
+			insensitive, _ := opts["case_insensitive"].(bool)
+			for _, v := range values(doc, field) {
+				if equal(v, value, insensitive) {
+					return true, number(opts["boost"], 1)
+				}
+			}
+			return false, 0
+		case "terms":
+			for field, want := range body {
+				if field == "boost" {
+					continue
+				}
+				wanted, _ := want.([]interface{})
+				for _, v := range values(doc, field) {
+					for _, w := range wanted {
+						if equal(v, w, false) {
+							return true, boost
+						}
+					}
+				}
+			}
+			return false, 0
+		case "range":
+			for field, raw := range body {
+				bounds, _ := raw.(map[string]interface{})
+				for _, v := range values(doc, field) {
+					if inRange(v, bounds) {
+						return true, number(bounds["boost"], 1)
+					}
+				}
+			}
+			return false, 0
+		case "exists":
+			field, _ := body["field"].(string)
+			return len(values(doc, field)) > 0, boost
+		case "ids":
+			ids, _ := body["values"].([]interface{})
+			for _, id := range ids {
+				if id == doc.id {
+					return true, boost
+				}
+			}
+			return false, 0
+		case "match", "match_phrase", "match_phrase_prefix", "match_bool_prefix":
+			field, text, opts := fieldQuery(body, "query")
+			operator, _ := opts["operator"].(string)
+			if kind == "match_phrase" || kind == "match_phrase_prefix" {
+				operator = "and"
+			}
+			prefix := kind == "match_phrase_prefix" || kind == "match_bool_prefix"
+			ok, score := matchText(doc, field, fmt.Sprint(text), operator, opts["fuzziness"], prefix)
+			return ok, score * number(opts["boost"], 1)
+		case "multi_match":
+			return multiMatch(body, doc)
+		case "prefix":
+			field, value, opts := fieldQuery(body, "value")
+			want := strings.ToLower(fmt.Sprint(value))
+			for _, v := range values(doc, field) {
+				if s, ok := v.(string); ok && strings.HasPrefix(strings.ToLower(s), want) {
+					return true, number(opts["boost"], 1)
+				}
+			}
+			return false, 0
+		case "wildcard":
+			field, value, opts := fieldQuery(body, "value")
+			pattern := strings.ToLower(fmt.Sprint(value))
+			for _, v := range values(doc, field) {
+				if s, ok := v.(string); ok {
+					if ok, _ := path.Match(pattern, strings.ToLower(s)); ok {
+						return true, number(opts["boost"], 1)
+					}
+				}
+			}
+			return false, 0
+		case "constant_score":
+			filter, _ := body["filter"].(map[string]interface{})
+			ok, _ := matches(filter, doc)
+			return ok, boost
+		case "nested", "function_score":
+			inner, _ := body["query"].(map[string]interface{})
+			ok, score := matches(inner, doc)
+			return ok, score * boost
+		case "script_score":
+			inner, _ := body["query"].(map[string]interface{})
+			ok, score := matches(inner, doc)
+			if !ok {
+				return false, 0
+			}
+			script, _ := body["script"].(map[string]interface{})
+			return true, scriptScore(script, doc, score)
+		case "dis_max":
+			queries, _ := body["queries"].([]interface{})
+			best, found := 0.0, false
+			for _, q := range queries {
+				sub, _ := q.(map[string]interface{})
+				if ok, score := matches(sub, doc); ok {
+					best, found = math.Max(best, score), true
+				}
+			}
+			return found, best * boost
+		default:
+			// Anything the sandbox can't evaluate matches, so results are
+			// too broad rather than missing
+			return true, 0
+		}
+	}
+	return true, 1
+}
+
+func matchBool(body map[string]interface{}, doc *memoryDoc) (bool, float64) {
+	score := 0.0
+	must, filter, should := clauses(body["must"]), clauses(body["filter"]), clauses(body["should"])
+	for _, c := range must {
+		ok, s := matches(c, doc)
+		if !ok {
+			return false, 0
+		}
+		score += s
+	}
+	for _, c := range filter {
+		if ok, _ := matches(c, doc); !ok {
+			return false, 0
+		}
+	}
+	for _, c := range clauses(body["must_not"]) {
+		if ok, _ := matches(c, doc); ok {
+			return false, 0
+		}
+	}
+
+	minimum := 0
+	if len(should) > 0 && len(must) == 0 && len(filter) == 0 {
+		minimum = 1
+	}
+	if raw, ok := body["minimum_should_match"]; ok {
+		minimum = minimumShouldMatch(raw, len(should))
+	}
+	matched := 0
+	for _, c := range should {
+		if ok, s := matches(c, doc); ok {
+			matched++
+			score += s
+		}
+	}
+	if matched < minimum {
+		return false, 0
+	}
+	return true, score * number(body["boost"], 1)
+}
+
+// clauses accepts a bool clause given as one query or a list of them
+func clauses(raw interface{}) []map[string]interface{} {
+	switch v := raw.(type) {
+	case map[string]interface{}:
+		return []map[string]interface{}{v}
+	case []interface{}:
+		out := make([]map[string]interface{}, 0, len(v))
+		for _, c := range v {
+			if m, ok := c.(map[string]interface{}); ok {
+				out = append(out, m)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+// minimumShouldMatch reads a count, a negative count or a percentage of n
+func minimumShouldMatch(raw interface{}, n int) int {
+	s := strings.TrimSpace(fmt.Sprint(raw))
+	if strings.HasSuffix(s, "%") {
+		pct, _ := strconv.ParseFloat(strings.TrimSuffix(s, "%"), 64)
+		if pct < 0 {
+			return n + int(pct*float64(n)/100)
+		}
+		return int(pct * float64(n) / 100)
+	}
+	count, _ := strconv.Atoi(s)
+	if count < 0 {
+		return n + count
+	}
+	return count
+}
+
+// fieldQuery reads a query keyed by field, in its short form
+// {"field": value} or long form {"field": {key: value, ...}}
+func fieldQuery(body map[string]interface{}, key string) (string, interface{}, map[string]interface{}) {
+	for field, raw := range body {
+		if opts, ok := raw.(map[string]interface{}); ok {
+			return field, opts[key], opts
+		}
+		return field, raw, nil
+	}
+	return "", nil, nil
+}
+
+// multiFieldSuffixes name the sub-fields mappings index a field under,
+// which the sandbox reads from the field itself
+var multiFieldSuffixes = []string{".keyword", ".autocomplete", ".raw", ".text", ".ngram", ".exact", ".sort", ".lowercase"}
+
+// values returns every value at a dotted field path, flattening arrays
+func values(doc *memoryDoc, field string) []interface{} {
+	if field == "_id" {
+		return []interface{}{doc.id}
+	}
+	found := walk(doc.source, strings.Split(field, "."))
+	if len(found) == 0 {
+		for _, suffix := range multiFieldSuffixes {
+			if strings.HasSuffix(field, suffix) {
+				return walk(doc.source, strings.Split(strings.TrimSuffix(field, suffix), "."))
+			}
+		}
+	}
+	return found
+}
+
+func walk(node interface{}, segments []string) []interface{} {
+	switch v := node.(type) {
+	case nil:
+		return nil
+	case []interface{}:
+		var out []interface{}
+		for _, item := range v {
+			out = append(out, walk(item, segments)...)
+		}
+		return out
+	case map[string]interface{}:
+		if len(segments) == 0 {
+			return []interface{}{v}
+		}
+		// Keys may themselves contain dots, as embeddings.<model> fields do
+		for i := len(segments); i > 0; i-- {
+			if child, ok := v[strings.Join(segments[:i], ".")]; ok {
+				return walk(child, segments[i:])
+			}
+		}
+		return nil
+	default:
+		if len(segments) > 0 {
+			return nil
+		}
+		return []interface{}{v}
+	}
+}
+
+func equal(a, b interface{}, insensitive bool) bool {
+	switch av := a.(type) {
+	case float64:
+		bv, ok := toFloat(b)
+		return ok && av == bv
+	case bool:
+		switch bv := b.(type) {
+		case bool:
+			return av == bv
+		case string:
+			return strconv.FormatBool(av) == bv
+		}
+		return false
+	case string:
+		bs := fmt.Sprint(b)
+		if insensitive {
+			return strings.EqualFold(av, bs)
+		}
+		return av == bs
+	}
+	return false
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case string:
+		f, err := strconv.ParseFloat(n, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// number reads a numeric option, or def when it is unset
+func number(v interface{}, def float64) float64 {
+	if f, ok := toFloat(v); ok {
+		return f
+	}
+	return def
+}
+
+func inRange(v interface{}, bounds map[string]interface{}) bool {
+	for op, bound := range bounds {
+		var c int
+		var ok bool
+		switch op {
+		case "gte", "gt", "lte", "lt", "from", "to":
+			c, ok = compareValues(v, bound)
+		default:
+			continue
+		}
+		if !ok {
+			return false
+		}
+		switch op {
+		case "gte", "from":
+			ok = c >= 0
+		case "gt":
+			ok = c > 0
+		case "lte", "to":
+			ok = c <= 0
+		case "lt":
+			ok = c < 0
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// compareValues orders a field value against a bound: numerically, as
+// dates, which bounds may give in date math such as now-7d, or as strings
+func compareValues(v, bound interface{}) (int, bool) {
+	if bound == nil {
+		return 0, true
+	}
+	if a, ok := v.(float64); ok {
+		b, ok := toFloat(bound)
+		if !ok {
+			return 0, false
+		}
+		return cmpFloat(a, b), true
+	}
+	s, ok := v.(string)
+	if !ok {
+		return 0, false
+	}
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		if b, ok := parseDate(bound); ok {
+			return t.Compare(b), true
+		}
+	}
+	return strings.Compare(s, fmt.Sprint(bound)), true
+}
+
+var dateMathUnits = map[byte]time.Duration{
+	's': time.Second, 'm': time.Minute, 'h': time.Hour, 'H': time.Hour,
+	'd': 24 * time.Hour, 'w': 7 * 24 * time.Hour, 'M': 30 * 24 * time.Hour, 'y': 365 * 24 * time.Hour,
+}
+
+var dateMath = regexp.MustCompile(`([+-])(\d+)([smhHdwMy])`)
+
+func parseDate(v interface{}) (time.Time, bool) {
+	if ms, ok := v.(float64); ok {
+		return time.UnixMilli(int64(ms)), true
+	}
+	s, ok := v.(string)
+	if !ok {
+		return time.Time{}, false
+	}
+	if rest, ok := strings.CutPrefix(s, "now"); ok {
+		t := time.Now()
+		rest, _, _ = strings.Cut(rest, "/")
+		for _, m := range dateMath.FindAllStringSubmatch(rest, -1) {
+			n, _ := strconv.Atoi(m[2])
+			d := time.Duration(n) * dateMathUnits[m[3][0]]
+			if m[1] == "-" {
+				d = -d
+			}
+			t = t.Add(d)
+		}
+		return t, true
+	}
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+func cmpFloat(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// tokenize splits text into lowercase words, as the standard analyzer does
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// matchText scores the words of text against a field. Each query word found
+// in a value scores more the shorter the value; a fuzzy or prefix match
+// scores half. With the and operator every word must be found.
+func matchText(doc *memoryDoc, field, text, operator string, fuzziness interface{}, prefix bool) (bool, float64) {
+	words := tokenize(text)
+	if len(words) == 0 {
+		return false, 0
+	}
+	prefix = prefix || strings.HasSuffix(field, ".autocomplete")
+
+	best, bestMatched := 0.0, 0
+	for _, v := range values(doc, field) {
+		s, ok := v.(string)
+		if !ok {
+			continue
+		}
+		tokens := tokenize(s)
+		if len(tokens) == 0 {
+			continue
+		}
+		weight := 1 + 1/float64(len(tokens))
+		score, matched := 0.0, 0
+		for i, w := range words {
+			if credit := wordMatch(w, tokens, fuzziness, prefix && i == len(words)-1 || strings.HasSuffix(field, ".autocomplete")); credit > 0 {
+				score += credit * weight
+				matched++
+			}
+		}
+		if score > best {
+			best, bestMatched = score, matched
+		}
+	}
+	if bestMatched == 0 || strings.EqualFold(operator, "and") && bestMatched < len(words) {
+		return false, 0
+	}
+	return true, best
+}
+
+// wordMatch credits a query word found among tokens: 1 for the word itself,
+// half for one within the fuzziness or, when prefix is set, one it starts
+func wordMatch(word string, tokens []string, fuzziness interface{}, prefix bool) float64 {
+	allowed := editsAllowed(word, fuzziness)
+	credit := 0.0
+	for _, t := range tokens {
+		switch {
+		case t == word:
+			return 1
+		case prefix && strings.HasPrefix(t, word):
+			credit = 0.5
+		case allowed > 0 && withinEdits(word, t, allowed):
+			credit = 0.5
+		}
+	}
+	return credit
+}
+
+// editsAllowed applies a fuzziness setting to a word; AUTO allows one edit
+// from three letters and two from six
+func editsAllowed(word string, fuzziness interface{}) int {
+	switch f := fuzziness.(type) {
+	case float64:
+		return int(f)
+	case string:
+		if n, err := strconv.Atoi(f); err == nil {
+			return n
+		}
+		if strings.HasPrefix(strings.ToUpper(f), "AUTO") {
+			switch n := len([]rune(word)); {
+			case n >= 6:
+				return 2
+			case n >= 3:
+				return 1
+			}
+		}
+	}
+	return 0
+}
+
+// withinEdits reports whether a and b are at most max edits apart
+func withinEdits(a, b string, max int) bool {
+	ra, rb := []rune(a), []rune(b)
+	if d := len(ra) - len(rb); d > max || -d > max {
+		return false
+	}
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		rowMin := cur[0]
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			rowMin = min(rowMin, cur[j])
+		}
+		if rowMin > max {
+			return false
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)] <= max
+}
+
+func multiMatch(body map[string]interface{}, doc *memoryDoc) (bool, float64) {
+	text := fmt.Sprint(body["query"])
+	operator, _ := body["operator"].(string)
+	matchType, _ := body["type"].(string)
+	prefix := matchType == "phrase_prefix" || matchType == "bool_prefix"
+	if matchType == "phrase" || matchType == "phrase_prefix" {
+		operator = "and"
+	}
+
+	fields, _ := body["fields"].([]interface{})
+	best, sum, found := 0.0, 0.0, false
+	for _, f := range fields {
+		field, boost := fmt.Sprint(f), 1.0
+		if name, weight, ok := strings.Cut(field, "^"); ok {
+			field, boost = name, number(weight, 1)
+		}
+		// cross_fields treats the fields as one, so only the total needs every word
+		fieldOperator := operator
+		if matchType == "cross_fields" {
+			fieldOperator = "or"
+		}
+		if ok, score := matchText(doc, field, text, fieldOperator, body["fuzziness"], prefix); ok {
+			found = true
+			best = math.Max(best, score*boost)
+			sum += score * boost
+		}
+	}
+	if !found {
+		return false, 0
+	}
+	if matchType == "cross_fields" && strings.EqualFold(operator, "and") {
+		for _, w := range tokenize(text) {
+			if ok, _ := multiMatch(map[string]interface{}{"query": w, "fields": fields, "fuzziness": body["fuzziness"]}, doc); !ok {
+				return false, 0
+			}
+		}
+	}
+	boost := number(body["boost"], 1)
+	if matchType == "most_fields" || matchType == "cross_fields" {
+		return true, sum * boost
+	}
+	return true, best * boost
+}
+
+var scriptConstant = regexp.MustCompile(`\+\s*([0-9.]+)\s*$`)
+
+// scriptScore evaluates the vector similarity scripts semantic search
+// uses; any other script keeps the inner query's score
+func scriptScore(script map[string]interface{}, doc *memoryDoc, score float64) float64 {
+	source, _ := script["source"].(string)
+	params, _ := script["params"].(map[string]interface{})
+	queryVector, _ := params["query_vector"].([]interface{})
+	field, _ := params["field"].(string)
+	if len(queryVector) == 0 || field == "" {
+		return score
+	}
+	docVector := values(doc, field)
+	if len(docVector) != len(queryVector) {
+		return 0
+	}
+
+	var dot, qn, dn float64
+	for i := range queryVector {
+		q, _ := toFloat(queryVector[i])
+		d, _ := toFloat(docVector[i])
+		dot += q * d
+		qn += q * q
+		dn += d * d
+	}
+	result := dot
+	if strings.Contains(source, "cosineSimilarity") {
+		if qn == 0 || dn == 0 {
+			return 0
+		}
+		result = dot / math.Sqrt(qn*dn)
+	}
+	if m := scriptConstant.FindStringSubmatch(source); m != nil {
+		result += number(m[1], 0)
+	}
+	return math.Max(result, 0)
+}
+
+// sortKey is one level of a sort
+type sortKey struct {
+	field string
+	desc  bool
+}
+
+// sortKeys reads a sort given as a field, a {field: order} object, a
+// {field: {"order": ...}} object or a list of those. Script sorts are
+// skipped. Without a sort, hits are ordered by score.
+func sortKeys(raw interface{}) []sortKey {
+	var entries []interface{}
+	switch v := raw.(type) {
+	case nil:
+		return nil
+	case []interface{}:
+		entries = v
+	default:
+		entries = []interface{}{v}
+	}
+
+	keys := []sortKey{}
+	for _, entry := range entries {
+		switch e := entry.(type) {
+		case string:
+			keys = append(keys, sortKey{field: e, desc: e == "_score"})
+		case map[string]interface{}:
+			for field, spec := range e {
+				if field == "_script" {
+					continue
+				}
+				order := fmt.Sprint(spec)
+				if opts, ok := spec.(map[string]interface{}); ok {
+					order = fmt.Sprint(opts["order"])
+					if opts["order"] == nil && field == "_score" {
+						order = "desc"
+					}
+				}
+				keys = append(keys, sortKey{field: field, desc: order == "desc"})
+			}
+		}
+	}
+	return keys
+}
+
+// sortValues returns a hit's value for each key: its score, its index order
+// or the field's lowest value, or highest when sorting descending
+func sortValues(keys []sortKey, h hit) []interface{} {
+	out := make([]interface{}, len(keys))
+	for i, k := range keys {
+		switch k.field {
+		case "_score":
+			out[i] = h.score
+		case "_doc", "_shard_doc":
+			out[i] = float64(h.doc.seq)
+		case "_id":
+			out[i] = h.doc.id
+		default:
+			var pick interface{}
+			for _, v := range values(h.doc, k.field) {
+				if pick == nil || (compareSort(v, pick) < 0) != k.desc {
+					pick = v
+				}
+			}
+			out[i] = pick
+		}
+	}
+	return out
+}
+
+// compareHits orders hits by keys, then by index order; without keys, by
+// score, highest first
+func compareHits(keys []sortKey, a, b hit) int {
+	if keys == nil {
+		if c := cmpFloat(b.score, a.score); c != 0 {
+			return c
+		}
+	} else {
+		for i, k := range keys {
+			av, bv := a.sort[i], b.sort[i]
+			// Missing values sort last either way
+			switch {
+			case av == nil && bv == nil:
+				continue
+			case av == nil:
+				return 1
+			case bv == nil:
+				return -1
+			}
+			c := compareSort(av, bv)
+			if k.desc {
+				c = -c
+			}
+			if c != 0 {
+				return c
+			}
+		}
+	}
+	if a.doc == nil || b.doc == nil {
+		return 0
+	}
+	return int(a.doc.seq - b.doc.seq)
+}
+
+func compareSort(a, b interface{}) int {
+	af, aok := toFloat(a)
+	bf, bok := toFloat(b)
+	if _, isString := a.(string); aok && bok && !isString {
+		return cmpFloat(af, bf)
+	}
+	if ab, ok := a.(bool); ok {
+		if bb, ok := b.(bool); ok {
+			switch {
+			case ab == bb:
+				return 0
+			case bb:
+				return -1
+			}
+			return 1
+		}
+	}
+	return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+}
+
+// sourceFilter reads the _source option: false, a field or list of fields
+// to include, or an object of includes and excludes
+func sourceFilter(raw interface{}) (includes, excludes []string, enabled bool) {
+	switch v := raw.(type) {
+	case bool:
+		return nil, nil, v
+	case string:
+		return []string{v}, nil, true
+	case []interface{}:
+		return stringList(v), nil, true
+	case map[string]interface{}:
+		for _, key := range []string{"includes", "include"} {
+			if list, ok := v[key].([]interface{}); ok {
+				includes = stringList(list)
+			}
+		}
+		for _, key := range []string{"excludes", "exclude"} {
+			if list, ok := v[key].([]interface{}); ok {
+				excludes = stringList(list)
+			}
+		}
+	}
+	return includes, excludes, true
+}
+
+func stringList(list []interface{}) []string {
+	out := make([]string, len(list))
+	for i, v := range list {
+		out[i] = fmt.Sprint(v)
+	}
+	return out
+}
+
+// filterSource applies source includes and excludes, given as dotted paths
+// or wildcard patterns, to a document
+func filterSource(source map[string]interface{}, includes, excludes []string) map[string]interface{} {
+	if len(includes) == 0 && len(excludes) == 0 {
+		return source
+	}
+	return filterObject(source, "", includes, excludes)
+}
+
+func filterObject(obj map[string]interface{}, prefix string, includes, excludes []string) map[string]interface{} {
+	out := make(map[string]interface{}, len(obj))
+	for key, value := range obj {
+		p := prefix + key
+		if pathMatches(p, excludes) {
+			continue
+		}
+		child, isObject := value.(map[string]interface{})
+		switch {
+		case len(includes) == 0 || pathMatches(p, includes):
+			if isObject && patternsUnder(p, excludes) {
+				value = filterObject(child, p+".", nil, excludes)
+			}
+			out[key] = value
+		case isObject && patternsUnder(p, includes):
+			if sub := filterObject(child, p+".", includes, excludes); len(sub) > 0 {
+				out[key] = sub
+			}
+		}
+	}
+	return out
+}
+
+func pathMatches(p string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if pattern == p {
+			return true
+		}
+		if ok, _ := path.Match(pattern, p); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// patternsUnder reports whether a pattern names something inside the object at p
+func patternsUnder(p string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if strings.HasPrefix(pattern, p+".") || strings.HasPrefix(pattern, "*") {
+			return true
+		}
+	}
+	return false
+}
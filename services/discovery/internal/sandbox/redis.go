@@ -0,0 +1,656 @@
+package sandbox
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/org/llm-marketplace/services/discovery/internal/redis"
+)
+
+// memoryRedis speaks enough RESP for the go-redis client: strings, hashes
+// and lists with expiry, transactions, which run their queued commands
+// together, and the Lua scripts discovery runs, done in Go. Every connection
+// shares one database; SELECT is accepted and ignored. PUBLISH reaches no
+// subscribers.
+type memoryRedis struct {
+	mu      sync.Mutex
+	entries map[string]*redisEntry
+}
+
+type redisEntry struct {
+	str     string
+	hash    map[string]string
+	list    []string
+	expires time.Time // Zero for a key without a TTL
+}
+
+type redisError string
+
+// redisStatus is a simple string reply, as opposed to a bulk string
+type redisStatus string
+
+const (
+	wrongType = redisError("WRONGTYPE Operation against a key holding the wrong kind of value")
+	notInt    = redisError("ERR value is not an integer or out of range")
+)
+
+func newMemoryRedis() *memoryRedis {
+	return &memoryRedis{entries: make(map[string]*redisEntry)}
+}
+
+// serve answers commands on ln until it is closed
+func (m *memoryRedis) serve(ln net.Listener) {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		go m.handle(conn)
+	}
+}
+
+func (m *memoryRedis) handle(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	w := bufio.NewWriter(conn)
+
+	var queued [][]string
+	inMulti := false
+	for {
+		args, err := readCommand(r)
+		if err != nil {
+			return
+		}
+		if len(args) == 0 {
+			continue
+		}
+
+		var reply interface{}
+		switch name := strings.ToUpper(args[0]); {
+		case name == "QUIT":
+			writeReply(w, redisStatus("OK"))
+			w.Flush()
+			return
+		case name == "MULTI":
+			inMulti, queued = true, nil
+			reply = redisStatus("OK")
+		case name == "DISCARD":
+			inMulti, queued = false, nil
+			reply = redisStatus("OK")
+		case name == "EXEC":
+			if !inMulti {
+				reply = redisError("ERR EXEC without MULTI")
+				break
+			}
+			m.mu.Lock()
+			results := make([]interface{}, len(queued))
+			for i, cmd := range queued {
+				results[i] = m.exec(cmd)
+			}
+			m.mu.Unlock()
+			inMulti, queued = false, nil
+			reply = results
+		case inMulti:
+			queued = append(queued, args)
+			reply = redisStatus("QUEUED")
+		default:
+			m.mu.Lock()
+			reply = m.exec(args)
+			m.mu.Unlock()
+		}
+
+		writeReply(w, reply)
+		if r.Buffered() == 0 {
+			if err := w.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// readCommand reads one command, as an array of bulk strings or inline
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := readLine(r)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(line, "*") {
+		return strings.Fields(line), nil
+	}
+	n, err := strconv.Atoi(line[1:])
+	if err != nil {
+		return nil, err
+	}
+	args := make([]string, n)
+	for i := range args {
+		header, err := readLine(r)
+		if err != nil {
+			return nil, err
+		}
+		size, err := strconv.Atoi(strings.TrimPrefix(header, "$"))
+		if err != nil {
+			return nil, err
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		args[i] = string(buf[:size])
+	}
+	return args, nil
+}
+
+func readLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	return strings.TrimRight(line, "\r\n"), err
+}
+
+func writeReply(w *bufio.Writer, reply interface{}) {
+	switch v := reply.(type) {
+	case nil:
+		w.WriteString("$-1\r\n")
+	case redisStatus:
+		fmt.Fprintf(w, "+%s\r\n", v)
+	case redisError:
+		fmt.Fprintf(w, "-%s\r\n", v)
+	case int:
+		fmt.Fprintf(w, ":%d\r\n", v)
+	case int64:
+		fmt.Fprintf(w, ":%d\r\n", v)
+	case string:
+		fmt.Fprintf(w, "$%d\r\n%s\r\n", len(v), v)
+	case []string:
+		fmt.Fprintf(w, "*%d\r\n", len(v))
+		for _, s := range v {
+			writeReply(w, s)
+		}
+	case []interface{}:
+		fmt.Fprintf(w, "*%d\r\n", len(v))
+		for _, item := range v {
+			writeReply(w, item)
+		}
+	}
+}
+
+// get returns the live entry at key, dropping it once expired
+func (m *memoryRedis) get(key string) *redisEntry {
+	e := m.entries[key]
+	if e != nil && !e.expires.IsZero() && !time.Now().Before(e.expires) {
+		delete(m.entries, key)
+		return nil
+	}
+	return e
+}
+
+// exec runs one command; the caller holds mu
+func (m *memoryRedis) exec(args []string) interface{} {
+	name := strings.ToUpper(args[0])
+	args = args[1:]
+	if arity, ok := minArgs[name]; ok && len(args) < arity {
+		return redisError(fmt.Sprintf("ERR wrong number of arguments for '%s' command", strings.ToLower(name)))
+	}
+
+	switch name {
+	case "PING":
+		if len(args) > 0 {
+			return args[0]
+		}
+		return redisStatus("PONG")
+	case "SELECT", "AUTH", "CLIENT", "WATCH", "UNWATCH":
+		return redisStatus("OK")
+	case "FLUSHDB", "FLUSHALL":
+		m.entries = make(map[string]*redisEntry)
+		return redisStatus("OK")
+
+	case "GET":
+		e := m.get(args[0])
+		if e == nil {
+			return nil
+		}
+		if e.hash != nil || e.list != nil {
+			return wrongType
+		}
+		return e.str
+	case "SET":
+		return m.set(args)
+	case "SETNX":
+		if m.get(args[0]) != nil {
+			return 0
+		}
+		m.entries[args[0]] = &redisEntry{str: args[1]}
+		return 1
+	case "SETEX":
+		secs, err := strconv.Atoi(args[1])
+		if err != nil {
+			return notInt
+		}
+		m.entries[args[0]] = &redisEntry{str: args[2], expires: time.Now().Add(time.Duration(secs) * time.Second)}
+		return redisStatus("OK")
+	case "MGET":
+		out := make([]interface{}, len(args))
+		for i, key := range args {
+			if e := m.get(key); e != nil && e.hash == nil && e.list == nil {
+				out[i] = e.str
+			}
+		}
+		return out
+	case "MSET":
+		for i := 0; i+1 < len(args); i += 2 {
+			m.entries[args[i]] = &redisEntry{str: args[i+1]}
+		}
+		return redisStatus("OK")
+	case "INCR", "DECR", "INCRBY", "DECRBY":
+		delta := int64(1)
+		if len(args) > 1 {
+			n, err := strconv.ParseInt(args[1], 10, 64)
+			if err != nil {
+				return notInt
+			}
+			delta = n
+		}
+		if strings.HasPrefix(name, "DECR") {
+			delta = -delta
+		}
+		return m.incr(args[0], delta)
+
+	case "DEL", "UNLINK":
+		removed := 0
+		for _, key := range args {
+			if m.get(key) != nil {
+				delete(m.entries, key)
+				removed++
+			}
+		}
+		return removed
+	case "EXISTS":
+		found := 0
+		for _, key := range args {
+			if m.get(key) != nil {
+				found++
+			}
+		}
+		return found
+	case "EXPIRE", "PEXPIRE":
+		n, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil {
+			return notInt
+		}
+		e := m.get(args[0])
+		if e == nil {
+			return 0
+		}
+		unit := time.Second
+		if name == "PEXPIRE" {
+			unit = time.Millisecond
+		}
+		e.expires = time.Now().Add(time.Duration(n) * unit)
+		return 1
+	case "PERSIST":
+		e := m.get(args[0])
+		if e == nil || e.expires.IsZero() {
+			return 0
+		}
+		e.expires = time.Time{}
+		return 1
+	case "TTL", "PTTL":
+		e := m.get(args[0])
+		switch {
+		case e == nil:
+			return -2
+		case e.expires.IsZero():
+			return -1
+		case name == "PTTL":
+			return time.Until(e.expires).Milliseconds()
+		}
+		return int64(time.Until(e.expires).Round(time.Second) / time.Second)
+	case "RENAME":
+		e := m.get(args[0])
+		if e == nil {
+			return redisError("ERR no such key")
+		}
+		delete(m.entries, args[0])
+		m.entries[args[1]] = e
+		return redisStatus("OK")
+	case "KEYS":
+		keys := []string{}
+		for key := range m.entries {
+			if ok, _ := path.Match(args[0], key); ok && m.get(key) != nil {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+		return keys
+
+	case "HSET", "HMSET":
+		if len(args)%2 != 1 {
+			return redisError("ERR wrong number of arguments for 'hset' command")
+		}
+		e, err := m.hash(args[0], true)
+		if err != nil {
+			return err
+		}
+		added := 0
+		for i := 1; i+1 < len(args); i += 2 {
+			if _, ok := e.hash[args[i]]; !ok {
+				added++
+			}
+			e.hash[args[i]] = args[i+1]
+		}
+		if name == "HMSET" {
+			return redisStatus("OK")
+		}
+		return added
+	case "HGET":
+		e, err := m.hash(args[0], false)
+		if err != nil {
+			return err
+		}
+		if v, ok := e.hash[args[1]]; ok {
+			return v
+		}
+		return nil
+	case "HMGET":
+		e, err := m.hash(args[0], false)
+		if err != nil {
+			return err
+		}
+		out := make([]interface{}, len(args)-1)
+		for i, field := range args[1:] {
+			if v, ok := e.hash[field]; ok {
+				out[i] = v
+			}
+		}
+		return out
+	case "HGETALL":
+		e, err := m.hash(args[0], false)
+		if err != nil {
+			return err
+		}
+		fields := make([]string, 0, len(e.hash))
+		for field := range e.hash {
+			fields = append(fields, field)
+		}
+		sort.Strings(fields)
+		out := make([]string, 0, 2*len(fields))
+		for _, field := range fields {
+			out = append(out, field, e.hash[field])
+		}
+		return out
+	case "HDEL":
+		e, err := m.hash(args[0], false)
+		if err != nil {
+			return err
+		}
+		removed := 0
+		for _, field := range args[1:] {
+			if _, ok := e.hash[field]; ok {
+				delete(e.hash, field)
+				removed++
+			}
+		}
+		if len(e.hash) == 0 {
+			delete(m.entries, args[0])
+		}
+		return removed
+	case "HLEN":
+		e, err := m.hash(args[0], false)
+		if err != nil {
+			return err
+		}
+		return len(e.hash)
+	case "HINCRBY":
+		delta, err := strconv.ParseInt(args[2], 10, 64)
+		if err != nil {
+			return notInt
+		}
+		e, herr := m.hash(args[0], true)
+		if herr != nil {
+			return herr
+		}
+		n, err := strconv.ParseInt(orZero(e.hash[args[1]]), 10, 64)
+		if err != nil {
+			return redisError("ERR hash value is not an integer")
+		}
+		e.hash[args[1]] = strconv.FormatInt(n+delta, 10)
+		return n + delta
+
+	case "LPUSH", "RPUSH":
+		e, err := m.listEntry(args[0], true)
+		if err != nil {
+			return err
+		}
+		for _, v := range args[1:] {
+			if name == "LPUSH" {
+				e.list = append([]string{v}, e.list...)
+			} else {
+				e.list = append(e.list, v)
+			}
+		}
+		return len(e.list)
+	case "LRANGE", "LTRIM":
+		e, err := m.listEntry(args[0], false)
+		if err != nil {
+			return err
+		}
+		start, err1 := strconv.Atoi(args[1])
+		stop, err2 := strconv.Atoi(args[2])
+		if err1 != nil || err2 != nil {
+			return notInt
+		}
+		lo, hi := listRange(len(e.list), start, stop)
+		if name == "LTRIM" {
+			e.list = append([]string(nil), e.list[lo:hi]...)
+			if len(e.list) == 0 {
+				delete(m.entries, args[0])
+			}
+			return redisStatus("OK")
+		}
+		return append([]string{}, e.list[lo:hi]...)
+	case "LLEN":
+		e, err := m.listEntry(args[0], false)
+		if err != nil {
+			return err
+		}
+		return len(e.list)
+
+	case "PUBLISH":
+		return 0
+
+	case "EVAL", "EVALSHA":
+		return m.eval(name, args)
+	}
+	return redisError(fmt.Sprintf("ERR unknown command '%s'", strings.ToLower(name)))
+}
+
+// minArgs is the fewest arguments each command takes
+var minArgs = map[string]int{
+	"GET": 1, "SET": 2, "SETNX": 2, "SETEX": 3, "MGET": 1, "MSET": 2,
+	"INCR": 1, "DECR": 1, "INCRBY": 2, "DECRBY": 2,
+	"DEL": 1, "UNLINK": 1, "EXISTS": 1, "EXPIRE": 2, "PEXPIRE": 2, "PERSIST": 1, "TTL": 1, "PTTL": 1,
+	"RENAME": 2, "KEYS": 1,
+	"HSET": 3, "HMSET": 3, "HGET": 2, "HMGET": 2, "HGETALL": 1, "HDEL": 2, "HLEN": 1, "HINCRBY": 3,
+	"LPUSH": 2, "RPUSH": 2, "LRANGE": 3, "LTRIM": 3, "LLEN": 1,
+	"PUBLISH": 2, "EVAL": 2, "EVALSHA": 2,
+}
+
+// set handles SET with its EX, PX, NX, XX, KEEPTTL and GET options
+func (m *memoryRedis) set(args []string) interface{} {
+	key, value := args[0], args[1]
+	var expires time.Time
+	var nx, xx, keepTTL, get bool
+	for i := 2; i < len(args); i++ {
+		switch opt := strings.ToUpper(args[i]); opt {
+		case "NX":
+			nx = true
+		case "XX":
+			xx = true
+		case "KEEPTTL":
+			keepTTL = true
+		case "GET":
+			get = true
+		case "EX", "PX":
+			if i+1 >= len(args) {
+				return redisError("ERR syntax error")
+			}
+			n, err := strconv.ParseInt(args[i+1], 10, 64)
+			if err != nil || n <= 0 {
+				return redisError("ERR invalid expire time in 'set' command")
+			}
+			unit := time.Second
+			if opt == "PX" {
+				unit = time.Millisecond
+			}
+			expires = time.Now().Add(time.Duration(n) * unit)
+			i++
+		default:
+			return redisError("ERR syntax error")
+		}
+	}
+
+	existing := m.get(key)
+	var old interface{}
+	if existing != nil {
+		old = existing.str
+	}
+	if nx && existing != nil || xx && existing == nil {
+		if get {
+			return old
+		}
+		return nil
+	}
+	if keepTTL && existing != nil {
+		expires = existing.expires
+	}
+	m.entries[key] = &redisEntry{str: value, expires: expires}
+	if get {
+		return old
+	}
+	return redisStatus("OK")
+}
+
+func (m *memoryRedis) incr(key string, delta int64) interface{} {
+	e := m.get(key)
+	if e == nil {
+		e = &redisEntry{str: "0"}
+		m.entries[key] = e
+	}
+	if e.hash != nil || e.list != nil {
+		return wrongType
+	}
+	n, err := strconv.ParseInt(e.str, 10, 64)
+	if err != nil {
+		return notInt
+	}
+	e.str = strconv.FormatInt(n+delta, 10)
+	return n + delta
+}
+
+// hash returns the hash at key; a missing key is an empty hash, stored
+// only when create is set
+func (m *memoryRedis) hash(key string, create bool) (*redisEntry, interface{}) {
+	e := m.get(key)
+	if e == nil {
+		e = &redisEntry{hash: make(map[string]string)}
+		if create {
+			m.entries[key] = e
+		}
+		return e, nil
+	}
+	if e.hash == nil {
+		return nil, wrongType
+	}
+	return e, nil
+}
+
+// listEntry returns the list at key, as hash does for hashes
+func (m *memoryRedis) listEntry(key string, create bool) (*redisEntry, interface{}) {
+	e := m.get(key)
+	if e == nil {
+		e = &redisEntry{list: []string{}}
+		if create {
+			m.entries[key] = e
+		}
+		return e, nil
+	}
+	if e.list == nil {
+		return nil, wrongType
+	}
+	return e, nil
+}
+
+// listRange resolves inclusive, possibly negative LRANGE indexes into a
+// slice range of a list of length n
+func listRange(n, start, stop int) (int, int) {
+	if start < 0 {
+		start += n
+	}
+	if stop < 0 {
+		stop += n
+	}
+	start = max(start, 0)
+	stop = min(stop, n-1)
+	if start > stop {
+		return 0, 0
+	}
+	return start, stop + 1
+}
+
+func orZero(s string) string {
+	if s == "" {
+		return "0"
+	}
+	return s
+}
+
+// scripts are the Lua scripts discovery runs, by SHA1
+var scripts = map[string]func(m *memoryRedis, keys, args []string) interface{}{
+	scriptSHA(redis.ReleaseLockScript): (*memoryRedis).releaseLock,
+}
+
+func scriptSHA(script string) string {
+	sum := sha1.Sum([]byte(script))
+	return hex.EncodeToString(sum[:])
+}
+
+// eval runs a script given by its body for EVAL or its SHA1 for EVALSHA
+func (m *memoryRedis) eval(name string, args []string) interface{} {
+	sha := strings.ToLower(args[0])
+	if name == "EVAL" {
+		sha = scriptSHA(args[0])
+	}
+	script, ok := scripts[sha]
+	if !ok {
+		if name == "EVALSHA" {
+			return redisError("NOSCRIPT No matching script. Please use EVAL.")
+		}
+		return redisError("ERR the sandbox does not run this script")
+	}
+	numKeys, err := strconv.Atoi(args[1])
+	if err != nil || numKeys < 0 || numKeys > len(args)-2 {
+		return redisError("ERR Number of keys can't be greater than number of args")
+	}
+	return script(m, args[2:2+numKeys], args[2+numKeys:])
+}
+
+// releaseLock is redis.ReleaseLockScript
+func (m *memoryRedis) releaseLock(keys, args []string) interface{} {
+	if len(keys) != 1 || len(args) != 1 {
+		return redisError("ERR wrong number of keys or arguments for the lock release script")
+	}
+	e := m.get(keys[0])
+	if e == nil || e.hash != nil || e.list != nil || e.str != args[0] {
+		return 0
+	}
+	delete(m.entries, keys[0])
+	return 1
+}
@@ -0,0 +1,161 @@
+// Package sandbox runs the service without infrastructure: Elasticsearch,
+// Redis and PostgreSQL are replaced by in-memory servers on loopback ports,
+// and the catalog is seeded with generated items, so the full API can be
+// run locally against realistic data. The servers speak the real protocols,
+// so the service uses its ordinary clients against them.
+//
+// Elasticsearch and Redis hold what is written to them until the process
+// exits. PostgreSQL accepts every statement and returns no rows, so the
+// features it backs, such as recommendation history, analytics reports,
+// webhooks and saved searches, behave as on an empty database.
+package sandbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+
+	"go.uber.org/zap"
+
+	"github.com/org/llm-marketplace/services/discovery/internal/config"
+	"github.com/org/llm-marketplace/services/discovery/internal/elasticsearch"
+	"github.com/org/llm-marketplace/services/discovery/internal/fixtures"
+	"github.com/org/llm-marketplace/services/discovery/internal/search"
+)
+
+// Options sets the seeded catalog
+type Options struct {
+	Seed     uint64
+	Services int // Items to seed; none when 0
+	Users    int // Users the catalog's interactions are generated for
+}
+
+// Sandbox is a set of running in-memory backends
+type Sandbox struct {
+	es        *http.Server
+	listeners []net.Listener
+}
+
+// Start starts the in-memory backends and points cfg at them. Integrations
+// that need infrastructure the sandbox doesn't replace, such as Kafka, S3,
+// the policy engine and the registry, are turned off. The catalog is then
+// seeded through the service's own Elasticsearch client.
+func Start(ctx context.Context, cfg *config.Config, opts Options, logger *zap.Logger) (*Sandbox, error) {
+	sb := &Sandbox{}
+
+	esListener, err := sb.listen()
+	if err != nil {
+		return nil, err
+	}
+	sb.es = &http.Server{Handler: newMemoryElasticsearch()}
+	go sb.es.Serve(esListener)
+
+	redisListener, err := sb.listen()
+	if err != nil {
+		sb.Close()
+		return nil, err
+	}
+	go newMemoryRedis().serve(redisListener)
+
+	pgListener, err := sb.listen()
+	if err != nil {
+		sb.Close()
+		return nil, err
+	}
+	go nullPostgres{}.serve(pgListener)
+
+	cfg.Elasticsearch.Addresses = []string{"http://" + esListener.Addr().String()}
+	cfg.Elasticsearch.Username, cfg.Elasticsearch.Password = "", ""
+	cfg.Elasticsearch.Sniff = false
+
+	cfg.Redis.Address = redisListener.Addr().String()
+	cfg.Redis.Password, cfg.Redis.DB = "", 0
+
+	host, port, _ := net.SplitHostPort(pgListener.Addr().String())
+	cfg.Postgres.Host = host
+	cfg.Postgres.Port, _ = strconv.Atoi(port)
+	cfg.Postgres.Password, cfg.Postgres.SSLMode = "", "disable"
+	cfg.Postgres.Migrations.AutoMigrate, cfg.Postgres.Migrations.FailOnDrift = false, false
+
+	cfg.Catalog.Enabled = false
+	cfg.PolicyEngine.EnrichOnIndex = false
+	cfg.AnalyticsHub.Enabled = false
+	cfg.AnalyticsHub.Aggregation.Enabled = false
+	cfg.SLAMonitoring.Enabled = false
+	cfg.Snapshots.Enabled = false
+	cfg.Subscriptions.Enabled = false
+	cfg.Observability.Tracing.Enabled = false
+	// Without an embedding service, queries can't be embedded
+	if cfg.EmbeddingService.Backend != search.EmbeddingBackendLocal {
+		cfg.Search.SemanticEnabled = false
+	}
+
+	if err := sb.seed(ctx, cfg, opts, logger); err != nil {
+		sb.Close()
+		return nil, err
+	}
+	return sb, nil
+}
+
+func (sb *Sandbox) listen() (net.Listener, error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("failed to start sandbox listener: %w", err)
+	}
+	sb.listeners = append(sb.listeners, ln)
+	return ln, nil
+}
+
+// seed indexes a generated catalog, with embeddings sized for the query
+// embedding model
+func (sb *Sandbox) seed(ctx context.Context, cfg *config.Config, opts Options, logger *zap.Logger) error {
+	if opts.Services <= 0 {
+		return nil
+	}
+	model := cfg.QueryEmbeddingModel()
+	catalog := fixtures.Generate(fixtures.Options{
+		Seed:           opts.Seed,
+		Services:       opts.Services,
+		Users:          opts.Users,
+		Dimensions:     model.Dimensions,
+		EmbeddingModel: model.Model,
+	})
+
+	esClient, err := elasticsearch.NewClient(cfg.Elasticsearch, nil)
+	if err != nil {
+		return fmt.Errorf("failed to connect to the sandbox Elasticsearch: %w", err)
+	}
+	result, err := catalog.Index(ctx, esClient)
+	if err != nil {
+		return fmt.Errorf("failed to seed the sandbox catalog: %w", err)
+	}
+	if len(result.Failed) > 0 {
+		return fmt.Errorf("failed to seed %d of %d sandbox items", len(result.Failed), len(catalog.Services))
+	}
+
+	logger.Info("Sandbox catalog seeded",
+		zap.Int("items", result.Indexed),
+		zap.Uint64("seed", opts.Seed),
+		zap.String("elasticsearch", cfg.Elasticsearch.Addresses[0]),
+		zap.String("redis", cfg.Redis.Address),
+		zap.String("postgres", net.JoinHostPort(cfg.Postgres.Host, strconv.Itoa(cfg.Postgres.Port))),
+	)
+	return nil
+}
+
+// Close stops the backends; what they held is lost
+func (sb *Sandbox) Close() error {
+	var errs []error
+	if sb.es != nil {
+		errs = append(errs, sb.es.Close())
+	}
+	for _, ln := range sb.listeners {
+		if err := ln.Close(); err != nil && !errors.Is(err, net.ErrClosed) {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,321 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+
+	"github.com/org/llm-marketplace/services/discovery/internal/config"
+	"github.com/org/llm-marketplace/services/discovery/internal/elasticsearch"
+	"github.com/org/llm-marketplace/services/discovery/internal/observability"
+	"github.com/org/llm-marketplace/services/discovery/internal/requestid"
+)
+
+var assistantTracer = otel.Tracer("discovery-assistant")
+
+// ErrAssistantDisabled is returned by Ask when assistant.enabled is off
+var ErrAssistantDisabled = errors.New("assistant is disabled")
+
+// ErrInvalidQuestion is returned for an empty question or a negative
+// max_services
+var ErrInvalidQuestion = errors.New("invalid question")
+
+// AssistantBackendNone marks answers given without retrieving any service
+const AssistantBackendNone = "none"
+
+// maxContextDescription bounds each service description given to the model
+const maxContextDescription = 600
+
+// AskRequest is a question about the catalog
+type AskRequest struct {
+	Question    string        `json:"question"`
+	Filters     SearchFilters `json:"filters"`
+	MaxServices int           `json:"max_services,omitempty"` // Services to retrieve, up to assistant.max_services
+	UserID      string        `json:"user_id,omitempty"`
+}
+
+// AskResponse is an answer grounded in the services a search retrieved.
+// The answer refers to them as [1], [2] and so on, in the order of Services.
+type AskResponse struct {
+	Answer    string         `json:"answer"`
+	Citations []Citation     `json:"citations"` // The retrieved services the answer cites, in order of first citation
+	Services  []SearchResult `json:"services"`  // Every service retrieved, numbered from 1
+	Backend   string         `json:"backend"`   // openai, or extractive when no model wrote the answer
+	Model     string         `json:"model,omitempty"`
+	QueryID   string         `json:"query_id,omitempty"` // The retrieval search's, for click attribution
+	Intent    *QueryIntent   `json:"intent,omitempty"`
+}
+
+// Citation points from a marker in an answer to the service it cites
+type Citation struct {
+	Marker    int    `json:"marker"` // n in [n]
+	ServiceID string `json:"service_id"`
+	Name      string `json:"name"`
+}
+
+// Ask answers a question about the catalog. A search for the question, with
+// the caller's entitlements, retrieves up to assistant.max_services services,
+// and the configured backend composes an answer from them alone. When the
+// model can't be reached, the answer lists the matches instead.
+func (s *Service) Ask(ctx context.Context, req *AskRequest) (*AskResponse, error) {
+	cfg := s.config.Assistant
+	if !cfg.Enabled {
+		return nil, ErrAssistantDisabled
+	}
+	req.Question = strings.TrimSpace(req.Question)
+	switch {
+	case req.Question == "":
+		return nil, fmt.Errorf("%w: question is required", ErrInvalidQuestion)
+	case req.MaxServices < 0:
+		return nil, fmt.Errorf("%w: max_services must not be negative", ErrInvalidQuestion)
+	}
+	limit := cfg.MaxServices
+	if req.MaxServices > 0 && req.MaxServices < limit {
+		limit = req.MaxServices
+	}
+
+	found, err := s.Search(ctx, &SearchRequest{
+		Query:      req.Question,
+		Filters:    req.Filters,
+		Pagination: PaginationRequest{PageSize: limit},
+		UserID:     req.UserID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	response := &AskResponse{
+		Services:  found.Results,
+		Citations: []Citation{},
+		QueryID:   found.QueryID,
+		Intent:    found.Intent,
+	}
+	if len(found.Results) == 0 {
+		response.Answer = "No services in the catalog match this question."
+		response.Backend = AssistantBackendNone
+		return response, nil
+	}
+
+	if cfg.Backend == config.AssistantBackendOpenAI {
+		answer, err := s.assistant.complete(ctx, assistantMessages(req.Question, found.Results))
+		if err == nil {
+			response.Answer, response.Backend, response.Model = answer, config.AssistantBackendOpenAI, cfg.Model
+			response.Citations = citations(answer, found.Results)
+			return response, nil
+		}
+		s.logger.Warn("Assistant model failed; answering with the matches", zap.Error(err))
+	}
+	response.Answer, response.Citations = extractiveAnswer(found.Results)
+	response.Backend = config.AssistantBackendExtractive
+	return response, nil
+}
+
+const assistantInstructions = `You help developers choose AI services from a marketplace catalog.
+Answer the question using only the numbered services below. Cite every service you mention with its number in square brackets, like [1] or [2][3].
+If none of the services fit, say so. Do not invent services, prices or guarantees.`
+
+// assistantMessages puts the retrieved services, numbered from 1, before
+// the question
+func assistantMessages(question string, results []SearchResult) []chatMessage {
+	var b strings.Builder
+	b.WriteString("Services:\n")
+	for i, r := range results {
+		fmt.Fprintf(&b, "\n[%d] %s\n", i+1, describeService(r.Service))
+	}
+	fmt.Fprintf(&b, "\nQuestion: %s", question)
+	return []chatMessage{
+		{Role: "system", Content: assistantInstructions},
+		{Role: "user", Content: b.String()},
+	}
+}
+
+// describeService is the context the model gets about svc
+func describeService(svc *elasticsearch.ServiceDocument) string {
+	lines := []string{fmt.Sprintf("%s (id %s) by %s", svc.Name, svc.ID, svc.Provider.Name)}
+	if svc.Provider.Verified {
+		lines[0] += ", a verified provider"
+	}
+	add := func(format string, args ...interface{}) { lines = append(lines, fmt.Sprintf(format, args...)) }
+
+	description := svc.Description
+	if len(description) > maxContextDescription {
+		description = strings.TrimSpace(description[:maxContextDescription]) + "..."
+	}
+	if description != "" {
+		add("Description: %s", description)
+	}
+	add("Category: %s", svc.Category)
+	if len(svc.Capabilities) > 0 {
+		add("Capabilities: %s", strings.Join(svc.Capabilities, ", "))
+	}
+	currency := svc.Pricing.Currency
+	if currency == "" {
+		currency = "USD"
+	}
+	add("Pricing: %g %s per %s (%s)", svc.Pricing.Rate, currency, svc.Pricing.Unit, svc.Pricing.Model)
+	if svc.SLA.Availability > 0 || svc.SLA.MaxLatencyMS > 0 {
+		add("SLA: %g%% availability, %d ms max latency", svc.SLA.Availability, svc.SLA.MaxLatencyMS)
+	}
+	var compliance []string
+	if svc.Compliance.Level != "" {
+		compliance = append(compliance, svc.Compliance.Level)
+	}
+	if svc.Compliance.GDPRCompliant {
+		compliance = append(compliance, "GDPR compliant")
+	}
+	if svc.Compliance.HIPAACompliant {
+		compliance = append(compliance, "HIPAA compliant")
+	}
+	compliance = append(compliance, svc.Compliance.Certifications...)
+	if len(compliance) > 0 {
+		add("Compliance: %s", strings.Join(compliance, ", "))
+	}
+	if len(svc.Compliance.DataResidency) > 0 {
+		add("Data residency: %s", strings.Join(svc.Compliance.DataResidency, ", "))
+	}
+	if svc.Metrics.ReviewCount > 0 {
+		add("Rating: %.1f from %d reviews", svc.Metrics.Rating, svc.Metrics.ReviewCount)
+	}
+	return strings.Join(lines, "\n")
+}
+
+var citationMarker = regexp.MustCompile(`\[(\d+)\]`)
+
+// citations maps the [n] markers of answer to the retrieved services, in
+// order of first citation. Markers beyond the services are ignored.
+func citations(answer string, results []SearchResult) []Citation {
+	cited := []Citation{}
+	seen := map[int]bool{}
+	for _, m := range citationMarker.FindAllStringSubmatch(answer, -1) {
+		n, err := strconv.Atoi(m[1])
+		if err != nil || n < 1 || n > len(results) || seen[n] {
+			continue
+		}
+		seen[n] = true
+		svc := results[n-1].Service
+		cited = append(cited, Citation{Marker: n, ServiceID: svc.ID, Name: svc.Name})
+	}
+	return cited
+}
+
+// extractiveAnswer lists the retrieved services, citing each
+func extractiveAnswer(results []SearchResult) (string, []Citation) {
+	var b strings.Builder
+	b.WriteString("These services match your question:")
+	cited := make([]Citation, 0, len(results))
+	for i, r := range results {
+		svc := r.Service
+		fmt.Fprintf(&b, "\n- %s by %s [%d]", svc.Name, svc.Provider.Name, i+1)
+		if svc.Description != "" {
+			description := svc.Description
+			if len(description) > 160 {
+				description = strings.TrimSpace(description[:160]) + "..."
+			}
+			fmt.Fprintf(&b, ": %s", description)
+		}
+		cited = append(cited, Citation{Marker: i + 1, ServiceID: svc.ID, Name: svc.Name})
+	}
+	return b.String(), cited
+}
+
+// assistantClient calls an OpenAI-compatible chat completions API
+type assistantClient struct {
+	config     config.AssistantConfig
+	httpClient *http.Client
+}
+
+func newAssistantClient(cfg config.AssistantConfig) *assistantClient {
+	return &assistantClient{
+		config:     cfg,
+		httpClient: &http.Client{}, // Each call is bounded by cfg.Timeout through its context
+	}
+}
+
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type chatRequest struct {
+	Model       string        `json:"model"`
+	Messages    []chatMessage `json:"messages"`
+	MaxTokens   int           `json:"max_tokens,omitempty"`
+	Temperature float64       `json:"temperature"`
+}
+
+type chatResponse struct {
+	Choices []struct {
+		Message chatMessage `json:"message"`
+	} `json:"choices"`
+}
+
+// complete returns the model's reply to messages
+func (ac *assistantClient) complete(ctx context.Context, messages []chatMessage) (_ string, err error) {
+	ctx, span := assistantTracer.Start(ctx, "assistant.complete",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("assistant.model", ac.config.Model)),
+	)
+	defer func() { observability.EndSpan(span, err) }()
+
+	if ac.config.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, ac.config.Timeout)
+		defer cancel()
+	}
+
+	body, err := json.Marshal(chatRequest{
+		Model:       ac.config.Model,
+		Messages:    messages,
+		MaxTokens:   ac.config.MaxTokens,
+		Temperature: ac.config.Temperature,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", strings.TrimSuffix(ac.config.URL, "/")+"/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if ac.config.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+ac.config.APIKey)
+	}
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+	if id := requestid.FromContext(ctx); id != "" {
+		req.Header.Set(requestid.Header, id)
+	}
+
+	resp, err := ac.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to call assistant model: %w", err)
+	}
+	defer resp.Body.Close()
+
+	span.SetAttributes(attribute.Int("http.status_code", resp.StatusCode))
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return "", fmt.Errorf("assistant model returned status %d: %s", resp.StatusCode, respBody)
+	}
+
+	var chatResp chatResponse
+	if err := json.NewDecoder(resp.Body).Decode(&chatResp); err != nil {
+		return "", fmt.Errorf("failed to decode response: %w", err)
+	}
+	if len(chatResp.Choices) == 0 || strings.TrimSpace(chatResp.Choices[0].Message.Content) == "" {
+		return "", errors.New("assistant model returned no answer")
+	}
+	return strings.TrimSpace(chatResp.Choices[0].Message.Content), nil
+}
@@ -0,0 +1,242 @@
+package search
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/org/llm-marketplace/services/discovery/internal/elasticsearch"
+	"github.com/org/llm-marketplace/services/discovery/internal/worker"
+	"go.uber.org/zap"
+)
+
+// Backfill job states
+const (
+	BackfillRunning   = "running"
+	BackfillCompleted = "completed"
+	BackfillFailed    = "failed"
+)
+
+const (
+	backfillLockKey = "embedding_backfill:lock"
+	backfillLockTTL = 10 * time.Minute
+	backfillJobTTL  = 24 * time.Hour
+)
+
+var (
+	// ErrBackfillNotFound is returned for unknown or expired backfill jobs
+	ErrBackfillNotFound = errors.New("embedding backfill job not found")
+	// ErrBackfillRunning is returned when a backfill is already in progress
+	ErrBackfillRunning = errors.New("embedding backfill already running")
+)
+
+// BackfillJob tracks a run that embeds every service missing a vector from one
+// of the configured models
+type BackfillJob struct {
+	ID          string     `json:"id"`
+	Status      string     `json:"status"`
+	Models      []string   `json:"models"`
+	Embedded    int        `json:"embedded"`
+	Failed      int        `json:"failed"`
+	Error       string     `json:"error,omitempty"`
+	StartedAt   time.Time  `json:"started_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
+
+// StartEmbeddingBackfill starts a backfill job in the background. Only one
+// backfill runs at a time across replicas.
+func (s *Service) StartEmbeddingBackfill(ctx context.Context) (*BackfillJob, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return nil, fmt.Errorf("failed to generate job id: %w", err)
+	}
+
+	job := &BackfillJob{
+		ID:        hex.EncodeToString(b),
+		Status:    BackfillRunning,
+		StartedAt: time.Now().UTC(),
+	}
+	for _, m := range s.config.EmbeddingModels() {
+		job.Models = append(job.Models, embeddingModelID(m))
+	}
+
+	acquired, err := s.redisClient.SetNX(ctx, backfillLockKey, job.ID, backfillLockTTL).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire backfill lock: %w", err)
+	}
+	if !acquired {
+		return nil, ErrBackfillRunning
+	}
+
+	if err := s.saveBackfillJob(ctx, job); err != nil {
+		s.redisClient.Del(ctx, backfillLockKey)
+		return nil, err
+	}
+
+	s.workers.Task("embedding_backfill", func(ctx context.Context) {
+		s.runEmbeddingBackfill(ctx, job)
+	})
+
+	return job, nil
+}
+
+// SetWorkers registers the group backfills run in, so shutdown waits for them
+func (s *Service) SetWorkers(g *worker.Group) {
+	s.workers = g
+}
+
+// GetEmbeddingBackfill returns the current state of a backfill job
+func (s *Service) GetEmbeddingBackfill(ctx context.Context, id string) (*BackfillJob, error) {
+	data, err := s.redisClient.Get(ctx, backfillJobKey(id)).Bytes()
+	if err == redis.Nil {
+		return nil, ErrBackfillNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load backfill job: %w", err)
+	}
+
+	var job BackfillJob
+	if err := json.Unmarshal(data, &job); err != nil {
+		return nil, fmt.Errorf("failed to decode backfill job: %w", err)
+	}
+	return &job, nil
+}
+
+func (s *Service) runEmbeddingBackfill(ctx context.Context, job *BackfillJob) {
+	// The backfill walks every tenant's catalog and is not tied to the request
+	ctx = elasticsearch.WithAllTenants(ctx)
+	defer s.redisClient.Del(context.WithoutCancel(ctx), backfillLockKey)
+
+	err := s.backfillEmbeddings(ctx, job)
+
+	now := time.Now().UTC()
+	job.CompletedAt = &now
+	job.Status = BackfillCompleted
+	if err != nil {
+		job.Status = BackfillFailed
+		job.Error = err.Error()
+		s.logger.Error("Embedding backfill failed", zap.String("job_id", job.ID), zap.Error(err))
+	} else {
+		s.logger.Info("Embedding backfill completed",
+			zap.String("job_id", job.ID),
+			zap.Int("embedded", job.Embedded),
+			zap.Int("failed", job.Failed),
+		)
+	}
+
+	// Record the outcome even when the run was cut off by shutdown
+	if err := s.saveBackfillJob(context.WithoutCancel(ctx), job); err != nil {
+		s.logger.Error("Failed to save backfill job", zap.String("job_id", job.ID), zap.Error(err))
+	}
+}
+
+// backfillEmbeddings pages through services missing a vector from any job model
+// and writes the missing vectors. Documents the embedding service rejects are
+// counted as failed and skipped so one bad batch doesn't stall the run.
+func (s *Service) backfillEmbeddings(ctx context.Context, job *BackfillJob) error {
+	batchSize := s.config.EmbeddingService.BatchSize
+	if batchSize <= 0 {
+		batchSize = scanBatchSize
+	}
+
+	missing := make([]interface{}, len(job.Models))
+	for i, id := range job.Models {
+		missing[i] = map[string]interface{}{
+			"bool": map[string]interface{}{
+				"must_not": map[string]interface{}{"term": map[string]interface{}{"embedding_models": id}},
+			},
+		}
+	}
+
+	// Vectors stay in _source so models a document already has aren't recomputed
+	query := map[string]interface{}{
+		"query": map[string]interface{}{
+			"bool": map[string]interface{}{
+				"should":               missing,
+				"minimum_should_match": 1,
+			},
+		},
+		"sort": []interface{}{map[string]interface{}{"id": "asc"}},
+		"size": batchSize,
+	}
+
+	for {
+		resp, err := s.esClient.Search(ctx, query)
+		if err != nil {
+			return fmt.Errorf("failed to load services: %w", err)
+		}
+
+		hits := resp.Hits.Hits
+		if len(hits) == 0 {
+			return nil
+		}
+
+		docs := make([]*elasticsearch.ServiceDocument, len(hits))
+		for i := range hits {
+			docs[i] = &hits[i].Source
+		}
+
+		if err := s.EmbedDocuments(ctx, docs); err != nil {
+			if errors.Is(err, ErrDimensionMismatch) {
+				// Every later batch would fail the same way
+				return err
+			}
+			s.logger.Warn("Embedding backfill batch failed", zap.String("job_id", job.ID), zap.Error(err))
+		}
+
+		// Vectors that were generated are stored even if another model failed
+		for _, doc := range docs {
+			update := map[string]interface{}{
+				"embedding_models": doc.EmbeddingModels,
+			}
+			if doc.Embedding != nil {
+				update["embedding"] = doc.Embedding
+			}
+			if doc.Embeddings != nil {
+				update["embeddings"] = doc.Embeddings
+			}
+
+			complete := !s.NeedsEmbedding(doc)
+			if err := s.esClient.UpdateFields(elasticsearch.WithTenant(ctx, doc.TenantID), doc.ID, update); err != nil {
+				s.logger.Warn("Failed to store embedding", zap.String("id", doc.ID), zap.Error(err))
+				complete = false
+			}
+			if complete {
+				job.Embedded++
+			} else {
+				job.Failed++
+			}
+		}
+
+		// Progress is best effort; the lock is extended so long runs keep it
+		s.redisClient.Expire(ctx, backfillLockKey, backfillLockTTL)
+		if err := s.saveBackfillJob(ctx, job); err != nil {
+			s.logger.Warn("Failed to save backfill progress", zap.String("job_id", job.ID), zap.Error(err))
+		}
+
+		if len(hits) < batchSize {
+			return nil
+		}
+		query["search_after"] = hits[len(hits)-1].Sort
+	}
+}
+
+func (s *Service) saveBackfillJob(ctx context.Context, job *BackfillJob) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("failed to encode backfill job: %w", err)
+	}
+	if err := s.redisClient.Set(ctx, backfillJobKey(job.ID), data, backfillJobTTL).Err(); err != nil {
+		return fmt.Errorf("failed to save backfill job: %w", err)
+	}
+	return nil
+}
+
+func backfillJobKey(id string) string {
+	return "embedding_backfill:" + id
+}
@@ -0,0 +1,81 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Stages of a search that can be cut short by its latency budget, as counted in metrics
+const (
+	stageEmbedding = "embedding"
+	stageCache     = "cache"
+	stageSearch    = "search"
+)
+
+// withLatencyBudget bounds ctx to search.latency_budget.total, or to the
+// caller's deadline if that is sooner
+func (s *Service) withLatencyBudget(ctx context.Context) (context.Context, context.CancelFunc) {
+	if total := s.config.Search.LatencyBudget.Total; total > 0 {
+		return context.WithTimeout(ctx, total)
+	}
+	return context.WithCancel(ctx)
+}
+
+// withStageBudget bounds ctx for one stage of a search: share of the time
+// left before its deadline, and no more than limit. Without a deadline or
+// a share, limit alone applies; 0 is unlimited.
+func withStageBudget(ctx context.Context, share float64, limit time.Duration) (context.Context, context.CancelFunc) {
+	if deadline, ok := ctx.Deadline(); ok && share > 0 {
+		if left := time.Duration(share * float64(time.Until(deadline))); limit <= 0 || left < limit {
+			return context.WithTimeout(ctx, left)
+		}
+	}
+	if limit <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, limit)
+}
+
+// searchTimeout is the timeout Elasticsearch is sent: the guardrails
+// timeout, or elasticsearch_share of the time left before ctx's deadline if
+// that is sooner. 0 is unlimited.
+func (s *Service) searchTimeout(ctx context.Context) time.Duration {
+	var timeout time.Duration
+	if g := s.config.Search.Guardrails; g.Enabled {
+		timeout = g.Timeout
+	}
+	share := s.config.Search.LatencyBudget.ElasticsearchShare
+	if deadline, ok := ctx.Deadline(); ok && share > 0 {
+		// Elasticsearch takes timeouts in whole milliseconds
+		left := max(time.Duration(share*float64(time.Until(deadline))), time.Millisecond)
+		if timeout <= 0 || left < timeout {
+			timeout = left
+		}
+	}
+	return timeout
+}
+
+// budgetExceeded counts stage as cut short if ctx is done, reporting
+// whether it was: by its deadline passing, or by the client going away
+func (s *Service) budgetExceeded(ctx context.Context, stage string) bool {
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		s.metrics.SearchBudgetExceeded(stage, "deadline")
+	case errors.Is(ctx.Err(), context.Canceled):
+		s.metrics.SearchBudgetExceeded(stage, "canceled")
+	default:
+		return false
+	}
+	return true
+}
+
+// budgetError explains a search that failed once its context was done: it
+// timed out if its budget ran out, and was abandoned if the client went away
+func budgetError(ctx context.Context, err error) error {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("%w: latency budget exceeded: %v", ErrSearchTimedOut, err)
+	}
+	return fmt.Errorf("search abandoned: %w", ctx.Err())
+}
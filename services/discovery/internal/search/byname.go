@@ -0,0 +1,152 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/org/llm-marketplace/services/discovery/internal/elasticsearch"
+	"github.com/org/llm-marketplace/services/discovery/internal/elasticsearch/query"
+	"github.com/org/llm-marketplace/services/discovery/internal/entitlement"
+)
+
+// ErrAmbiguousName is returned when a name resolves equally well to more than one service
+var ErrAmbiguousName = errors.New("service name is ambiguous")
+
+// How a name resolved to a service
+const (
+	MatchExact = "exact"
+	MatchFuzzy = "fuzzy"
+)
+
+// nameCandidates is how many services a fuzzy name lookup compares
+const nameCandidates = 10
+
+// NameMatch is the service a name resolved to
+type NameMatch struct {
+	Service  *elasticsearch.ServiceDocument `json:"service"`
+	Match    string                         `json:"match"`
+	Distance int                            `json:"distance"` // Edits from the name asked for to the service's name
+}
+
+// GetServiceByName resolves a service name to the one searchable service the
+// caller may see with that name, ignoring case and spacing. Failing that, a
+// name within a typo or two is accepted: one edit for names of 3 to 5
+// characters, two for longer names. Older versions aren't matched.
+func (s *Service) GetServiceByName(ctx context.Context, name string) (*NameMatch, error) {
+	name = normalizeName(name)
+	if name == "" {
+		return nil, elasticsearch.ErrNotFound
+	}
+
+	boolQuery := query.Bool().
+		Should(
+			query.Raw{"term": map[string]interface{}{
+				"name.keyword": map[string]interface{}{"value": name, "case_insensitive": true, "boost": 10},
+			}},
+			query.Raw{"match": map[string]interface{}{
+				"name": map[string]interface{}{"query": name, "fuzziness": "AUTO", "operator": "and"},
+			}},
+		).
+		MinimumShouldMatch(1).
+		Filter(
+			query.Terms("status", elasticsearch.SearchableStatuses),
+			entitlement.FromContext(ctx).Filter(),
+		).
+		MustNot(query.Term("version.latest", false))
+
+	search := &query.Search{
+		Query:        boolQuery,
+		Size:         nameCandidates,
+		SourceFilter: map[string]interface{}{"excludes": elasticsearch.DefaultSourceExcludes},
+	}
+	resp, err := s.esClient.Search(ctx, search.Map())
+	if err != nil {
+		return nil, err
+	}
+
+	caller := entitlement.FromContext(ctx)
+	var best []*elasticsearch.ServiceDocument
+	bestDistance := maxNameEdits(name) + 1
+	for i := range resp.Hits.Hits {
+		doc := &resp.Hits.Hits[i].Source
+		if doc.ID == "" {
+			doc.ID = resp.Hits.Hits[i].ID
+		}
+		if !s.esClient.InTenant(ctx, doc) || !caller.CanView(doc.Access) {
+			continue
+		}
+
+		switch d := editDistance(name, normalizeName(doc.Name)); {
+		case d < bestDistance:
+			best, bestDistance = []*elasticsearch.ServiceDocument{doc}, d
+		case d == bestDistance:
+			best = append(best, doc)
+		}
+	}
+
+	switch len(best) {
+	case 0:
+		return nil, elasticsearch.ErrNotFound
+	case 1:
+	default:
+		ids := make([]string, len(best))
+		for i, doc := range best {
+			ids[i] = doc.ID
+		}
+		return nil, fmt.Errorf("%w: matches %s", ErrAmbiguousName, strings.Join(ids, ", "))
+	}
+
+	match := &NameMatch{Service: best[0], Match: MatchExact, Distance: bestDistance}
+	if bestDistance > 0 {
+		match.Match = MatchFuzzy
+	}
+	return match, nil
+}
+
+// normalizeName lowercases a name and collapses its whitespace
+func normalizeName(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), " "))
+}
+
+// maxNameEdits is the typo allowance for a name, as AUTO fuzziness allows for a word
+func maxNameEdits(name string) int {
+	switch n := utf8.RuneCountInString(name); {
+	case n < minFuzzyTermLength:
+		return 0
+	case n < 6:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// editDistance is the Levenshtein distance between a and b, in runes
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = prev[j-1] + cost
+			if prev[j]+1 < curr[j] {
+				curr[j] = prev[j] + 1
+			}
+			if curr[j-1]+1 < curr[j] {
+				curr[j] = curr[j-1] + 1
+			}
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
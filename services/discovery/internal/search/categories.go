@@ -0,0 +1,101 @@
+package search
+
+import (
+	"context"
+	"sort"
+
+	"github.com/org/llm-marketplace/services/discovery/internal/elasticsearch"
+	"github.com/org/llm-marketplace/services/discovery/internal/taxonomy"
+	"go.uber.org/zap"
+)
+
+// CategoryFacet is a category bucket with counts rolled up from its descendants
+type CategoryFacet struct {
+	Key         string `json:"key"`
+	Parent      string `json:"parent,omitempty"`
+	DocCount    int    `json:"doc_count"`
+	DirectCount int    `json:"direct_count"`
+}
+
+// SetTaxonomy registers the managed category hierarchy
+func (s *Service) SetTaxonomy(m *taxonomy.Manager) {
+	s.taxonomy = m
+}
+
+// loadTaxonomy returns the hierarchy, or an empty one when unmanaged or unavailable
+func (s *Service) loadTaxonomy(ctx context.Context) *taxonomy.Taxonomy {
+	if s.taxonomy == nil {
+		return taxonomy.New(nil)
+	}
+
+	t, err := s.taxonomy.Load(ctx)
+	if err != nil {
+		s.logger.Warn("Failed to load category taxonomy", zap.Error(err))
+		return taxonomy.New(nil)
+	}
+	return t
+}
+
+// categoryHierarchy rolls the flat categories aggregation up the taxonomy
+func categoryHierarchy(t *taxonomy.Taxonomy, buckets []elasticsearch.TermsBucket) []CategoryFacet {
+	direct := make(map[string]int, len(buckets))
+	for _, b := range buckets {
+		direct[string(b.Key)] = b.DocCount
+	}
+
+	facets := make([]CategoryFacet, 0, len(direct))
+	for key, count := range t.RollUp(direct) {
+		facets = append(facets, CategoryFacet{
+			Key:         key,
+			Parent:      t.Parent(key),
+			DocCount:    count,
+			DirectCount: direct[key],
+		})
+	}
+
+	sort.Slice(facets, func(i, j int) bool {
+		if facets[i].DocCount != facets[j].DocCount {
+			return facets[i].DocCount > facets[j].DocCount
+		}
+		return facets[i].Key < facets[j].Key
+	})
+	return facets
+}
+
+// rollUpCategories adds child counts to their ancestors; ratings are averaged by service count
+func rollUpCategories(t *taxonomy.Taxonomy, direct map[string]CategoryInfo) []CategoryInfo {
+	counts := make(map[string]int, len(direct))
+	for name, info := range direct {
+		counts[name] = info.Count
+	}
+
+	ratingSums := make(map[string]float64)
+	for name, info := range direct {
+		ratingSums[name] += info.AvgRating * float64(info.Count)
+		for _, ancestor := range t.Ancestors(name) {
+			ratingSums[ancestor] += info.AvgRating * float64(info.Count)
+		}
+	}
+
+	categories := []CategoryInfo{}
+	for name, total := range t.RollUp(counts) {
+		info := CategoryInfo{
+			Name:        name,
+			Parent:      t.Parent(name),
+			Count:       total,
+			DirectCount: counts[name],
+		}
+		if total > 0 {
+			info.AvgRating = ratingSums[name] / float64(total)
+		}
+		categories = append(categories, info)
+	}
+
+	sort.Slice(categories, func(i, j int) bool {
+		if categories[i].Count != categories[j].Count {
+			return categories[i].Count > categories[j].Count
+		}
+		return categories[i].Name < categories[j].Name
+	})
+	return categories
+}
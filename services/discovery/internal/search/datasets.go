@@ -0,0 +1,26 @@
+package search
+
+import (
+	"strings"
+
+	"github.com/org/llm-marketplace/services/discovery/internal/elasticsearch"
+	"github.com/org/llm-marketplace/services/discovery/internal/elasticsearch/query"
+)
+
+// filterDatasets restricts a search to the datasets with the licenses,
+// formats and size the filters ask for. Each filter only matches datasets.
+func filterDatasets(boolQuery *query.BoolQuery, f SearchFilters) {
+	if len(f.Licenses) > 0 {
+		boolQuery.Filter(query.Terms(elasticsearch.LicenseField, f.Licenses))
+	}
+	if len(f.DatasetFormats) > 0 {
+		formats := make([]string, len(f.DatasetFormats))
+		for i, format := range f.DatasetFormats {
+			formats[i] = strings.ToLower(strings.TrimSpace(format))
+		}
+		boolQuery.Filter(query.Terms(elasticsearch.DatasetFormatField, formats))
+	}
+	if f.MaxDatasetBytes > 0 {
+		boolQuery.Filter(query.Range(elasticsearch.DatasetSizeField).Gt(0).Lte(f.MaxDatasetBytes))
+	}
+}
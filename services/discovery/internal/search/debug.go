@@ -0,0 +1,150 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/org/llm-marketplace/services/discovery/internal/elasticsearch"
+	"github.com/org/llm-marketplace/services/discovery/internal/requestid"
+	"go.uber.org/zap"
+)
+
+// Ranking stages recorded in a debug capture
+const (
+	DebugStageElasticsearch = "elasticsearch" // Scores as Elasticsearch returned them
+	DebugStageRank          = "rank"          // After the weighted ranking
+	DebugStageRerank        = "rerank"        // After region, feature and session re-ranking
+)
+
+// ErrCaptureNotFound is returned for requests that weren't captured or whose
+// capture has expired
+var ErrCaptureNotFound = errors.New("search debug capture not found")
+
+// DebugCapture is everything needed to replay a search's ranking offline
+type DebugCapture struct {
+	RequestID  string    `json:"request_id"`
+	CapturedAt time.Time `json:"captured_at"`
+
+	Query   string        `json:"query"`           // As searched, after query understanding and the pipeline
+	Typed   string        `json:"typed,omitempty"` // As the caller wrote it, when that differs
+	Request SearchRequest `json:"request"`
+	Intent  *QueryIntent  `json:"intent,omitempty"`
+	Rewrite *QueryRewrite `json:"rewrite,omitempty"`
+
+	ElasticsearchQuery map[string]interface{} `json:"elasticsearch_query"` // As sent, scoped to the caller's tenant
+	Took               int                    `json:"took_ms"`
+	Total              int                    `json:"total"`
+	MaxScore           float64                `json:"max_score"`
+	Hits               []elasticsearch.Hit    `json:"hits"`
+
+	Stages []DebugStage `json:"stages"`
+}
+
+// DebugStage is the order and scores of the results after one ranking stage
+type DebugStage struct {
+	Name    string        `json:"name"`
+	Results []DebugResult `json:"results"`
+}
+
+// DebugResult is one result's scores after a stage
+type DebugResult struct {
+	ServiceID    string       `json:"service_id"`
+	Score        float64      `json:"score"`
+	MatchDetails MatchDetails `json:"match_details"`
+}
+
+// startCapture begins capturing req when it asks for debug and capture is
+// enabled, and returns nil otherwise. Only ranked searches of services are
+// captured, and never searches the service runs itself.
+func (s *Service) startCapture(ctx context.Context, req *SearchRequest) *DebugCapture {
+	if !req.Debug || req.background || !s.config.Search.DebugCapture.Enabled {
+		return nil
+	}
+	if !includesServices(req.Types) || req.AggregationsOnly || req.CountOnly || req.Pareto != nil {
+		return nil
+	}
+	id := requestid.FromContext(ctx)
+	if id == "" {
+		id = requestid.New()
+	}
+	capture := &DebugCapture{
+		RequestID:  id,
+		CapturedAt: time.Now().UTC(),
+		Query:      req.Query,
+		Request:    *req,
+		Intent:     req.intent,
+		Rewrite:    req.rewrite,
+	}
+	if req.typed != req.Query {
+		capture.Typed = req.typed
+	}
+	return capture
+}
+
+// recordElasticsearch records the query sent and the hits it returned
+func (c *DebugCapture) recordElasticsearch(query map[string]interface{}, resp *elasticsearch.SearchResponse) {
+	if c == nil {
+		return
+	}
+	c.ElasticsearchQuery = query
+	c.Took = resp.Took
+	c.Total = resp.Hits.Total.Value
+	c.MaxScore = resp.Hits.MaxScore
+	c.Hits = resp.Hits.Hits
+}
+
+// recordStage records the results' order and scores after the named stage
+func (c *DebugCapture) recordStage(name string, results []SearchResult) {
+	if c == nil {
+		return
+	}
+	stage := DebugStage{Name: name, Results: make([]DebugResult, 0, len(results))}
+	for _, r := range results {
+		result := DebugResult{Score: r.Score, MatchDetails: r.MatchDetails}
+		if r.Service != nil {
+			result.ServiceID = r.Service.ID
+		}
+		stage.Results = append(stage.Results, result)
+	}
+	c.Stages = append(c.Stages, stage)
+}
+
+// saveCapture stores capture for the configured TTL. A capture that can't be
+// stored is logged; the search is answered regardless.
+func (s *Service) saveCapture(ctx context.Context, capture *DebugCapture) {
+	if capture == nil {
+		return
+	}
+	data, err := json.Marshal(capture)
+	if err == nil {
+		err = s.redisClient.Set(ctx, debugCaptureKey(capture.RequestID), data, s.config.Search.DebugCapture.TTL).Err()
+	}
+	if err != nil {
+		s.logger.Warn("Failed to store search debug capture", zap.String("request_id", capture.RequestID), zap.Error(err))
+	}
+}
+
+// GetDebugCapture returns the capture of the search served as requestID
+func (s *Service) GetDebugCapture(ctx context.Context, requestID string) (*DebugCapture, error) {
+	data, err := s.redisClient.Get(ctx, debugCaptureKey(requestID)).Bytes()
+	if err == redis.Nil {
+		return nil, ErrCaptureNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load search debug capture: %w", err)
+	}
+
+	var capture DebugCapture
+	if err := json.Unmarshal(data, &capture); err != nil {
+		return nil, fmt.Errorf("failed to decode search debug capture: %w", err)
+	}
+	return &capture, nil
+}
+
+func debugCaptureKey(requestID string) string {
+	return "search_debug:" + requestID
+}
@@ -0,0 +1,95 @@
+package search
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/org/llm-marketplace/services/discovery/internal/elasticsearch"
+	"github.com/org/llm-marketplace/services/discovery/internal/entitlement"
+)
+
+// DependencyGraph lists the services a service is built on and the services
+// built on it
+type DependencyGraph struct {
+	ServiceID    string              `json:"service_id"`
+	Dependencies []DependencySummary `json:"dependencies"`
+	Dependents   []DependencySummary `json:"dependents"`
+}
+
+// DependencySummary is one service in a dependency graph
+type DependencySummary struct {
+	ID      string `json:"id"`
+	Name    string `json:"name,omitempty"`
+	Version string `json:"version,omitempty"`
+	Status  string `json:"status,omitempty"`
+	Role    string `json:"role,omitempty"` // The dependency's role in the dependent service
+	URL     string `json:"url,omitempty"`
+	// Missing is set on dependencies that aren't indexed or that the caller
+	// can't see; only their ID is given
+	Missing bool `json:"missing,omitempty"`
+}
+
+// Dependencies returns the dependency graph around the service id
+func (s *Service) Dependencies(ctx context.Context, id string) (*DependencyGraph, error) {
+	service, err := s.GetServiceByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(service.Dependencies))
+	for _, dep := range service.Dependencies {
+		ids = append(ids, dep.ServiceID)
+	}
+	docs, err := s.esClient.MGet(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load dependencies: %w", err)
+	}
+	all, err := s.esClient.Dependents(ctx, service.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load dependents: %w", err)
+	}
+
+	caller := entitlement.FromContext(ctx)
+	graph := &DependencyGraph{
+		ServiceID:    service.ID,
+		Dependencies: make([]DependencySummary, 0, len(service.Dependencies)),
+	}
+	for _, dep := range service.Dependencies {
+		doc := docs[dep.ServiceID]
+		if doc == nil || !caller.CanView(doc.Access) {
+			graph.Dependencies = append(graph.Dependencies, DependencySummary{ID: dep.ServiceID, Role: dep.Role, Missing: true})
+			continue
+		}
+		summary := dependencySummary(doc)
+		summary.Role = dep.Role
+		graph.Dependencies = append(graph.Dependencies, summary)
+	}
+
+	dependents := caller.Visible(all)
+	graph.Dependents = make([]DependencySummary, 0, len(dependents))
+	for _, doc := range dependents {
+		summary := dependencySummary(doc)
+		for _, dep := range doc.Dependencies {
+			if dep.ServiceID == service.ID {
+				summary.Role = dep.Role
+				break
+			}
+		}
+		graph.Dependents = append(graph.Dependents, summary)
+	}
+
+	return graph, nil
+}
+
+func dependencySummary(doc *elasticsearch.ServiceDocument) DependencySummary {
+	summary := DependencySummary{
+		ID:     doc.ID,
+		Name:   doc.Name,
+		Status: doc.Status,
+		URL:    "/api/v1/services/" + doc.ID,
+	}
+	if doc.Version != nil {
+		summary.Version = doc.Version.Number
+	}
+	return summary
+}
@@ -4,17 +4,45 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"math/rand/v2"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/org/llm-marketplace/services/discovery/internal/config"
+	"github.com/org/llm-marketplace/services/discovery/internal/elasticsearch"
+	"github.com/org/llm-marketplace/services/discovery/internal/observability"
+	"github.com/org/llm-marketplace/services/discovery/internal/requestid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/sync/errgroup"
+)
+
+var embeddingTracer = otel.Tracer("discovery-embedding")
+
+// Embedding backends selectable in EmbeddingServiceConfig
+const (
+	EmbeddingBackendRemote = "remote"
+	EmbeddingBackendLocal  = "local"
 )
 
 type EmbeddingClient struct {
 	config     config.EmbeddingServiceConfig
 	httpClient *http.Client
+	local      LocalEmbedder
+	metrics    *observability.Metrics
+}
+
+// LocalEmbedder computes embeddings in process; the ONNX backend satisfies it
+type LocalEmbedder interface {
+	Embed(texts []string) ([][]float32, error)
 }
 
 type EmbeddingRequest struct {
@@ -27,18 +55,36 @@ type EmbeddingResponse struct {
 	Model      string      `json:"model"`
 }
 
-func NewEmbeddingClient(cfg config.EmbeddingServiceConfig) *EmbeddingClient {
+func NewEmbeddingClient(cfg config.EmbeddingServiceConfig, metrics *observability.Metrics) *EmbeddingClient {
 	return &EmbeddingClient{
-		config: cfg,
-		httpClient: &http.Client{
-			Timeout: cfg.Timeout,
-		},
+		config:     cfg,
+		httpClient: &http.Client{}, // Each attempt is bounded by cfg.Timeout through its context
+		metrics:    metrics,
 	}
 }
 
-// GetEmbedding retrieves the embedding vector for a single text
-func (ec *EmbeddingClient) GetEmbedding(ctx context.Context, text string) ([]float32, error) {
-	embeddings, err := ec.GetEmbeddings(ctx, []string{text})
+// LoadLocalModel loads the in-process backend when it is configured as the
+// primary backend or the fallback. Until it succeeds, local embedding calls fail.
+func (ec *EmbeddingClient) LoadLocalModel() error {
+	if ec.config.Backend != EmbeddingBackendLocal && ec.config.Fallback != EmbeddingBackendLocal {
+		return nil
+	}
+	local, err := newLocalEmbedder(ec.config.Local)
+	if err != nil {
+		return fmt.Errorf("failed to load local embedding model: %w", err)
+	}
+	ec.local = local
+	return nil
+}
+
+// SetLocalModel replaces the in-process backend
+func (ec *EmbeddingClient) SetLocalModel(local LocalEmbedder) {
+	ec.local = local
+}
+
+// GetEmbedding retrieves the embedding vector for a single text from model
+func (ec *EmbeddingClient) GetEmbedding(ctx context.Context, model, text string) ([]float32, error) {
+	embeddings, err := ec.GetEmbeddings(ctx, model, []string{text})
 	if err != nil {
 		return nil, err
 	}
@@ -50,15 +96,62 @@ func (ec *EmbeddingClient) GetEmbedding(ctx context.Context, text string) ([]flo
 	return embeddings[0], nil
 }
 
-// GetEmbeddings retrieves embedding vectors for multiple texts
-func (ec *EmbeddingClient) GetEmbeddings(ctx context.Context, texts []string) ([][]float32, error) {
+// GetEmbeddings retrieves embedding vectors for multiple texts from model on the
+// configured backend, falling back to the local model if the remote call fails
+func (ec *EmbeddingClient) GetEmbeddings(ctx context.Context, model string, texts []string) ([][]float32, error) {
 	if len(texts) == 0 {
 		return nil, fmt.Errorf("no texts provided")
 	}
 
+	if ec.config.Backend == EmbeddingBackendLocal {
+		return ec.localEmbeddings(ctx, model, texts)
+	}
+
+	embeddings, err := ec.remoteEmbeddings(ctx, model, texts)
+	// A cancelled or expired context means the caller has stopped waiting
+	if err != nil && ec.config.Fallback == EmbeddingBackendLocal && ctx.Err() == nil {
+		local, localErr := ec.localEmbeddings(ctx, model, texts)
+		if localErr != nil {
+			return nil, fmt.Errorf("%w (local fallback: %v)", err, localErr)
+		}
+		return local, nil
+	}
+	return embeddings, err
+}
+
+func (ec *EmbeddingClient) localEmbeddings(ctx context.Context, model string, texts []string) ([][]float32, error) {
+	if ec.local == nil {
+		return nil, errors.New("local embedding model is not loaded")
+	}
+	if local := ec.localModel(); model != local {
+		return nil, fmt.Errorf("local embedding backend serves %s, not %s", local, model)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return ec.local.Embed(texts)
+}
+
+// localAvailable reports whether the in-process backend is loaded and
+// configured to serve embeddings
+func (ec *EmbeddingClient) localAvailable() bool {
+	return ec.local != nil && (ec.config.Backend == EmbeddingBackendLocal || ec.config.Fallback == EmbeddingBackendLocal)
+}
+
+// localModel is the model the in-process backend was exported from
+func (ec *EmbeddingClient) localModel() string {
+	if ec.config.Local.Model != "" {
+		return ec.config.Local.Model
+	}
+	return ec.config.Model
+}
+
+// remoteEmbeddings calls the embedding service, retrying transient failures with
+// jittered exponential backoff for as long as ctx allows
+func (ec *EmbeddingClient) remoteEmbeddings(ctx context.Context, model string, texts []string) ([][]float32, error) {
 	reqBody := EmbeddingRequest{
 		Texts: texts,
-		Model: ec.config.Model,
+		Model: model,
 	}
 
 	jsonData, err := json.Marshal(reqBody)
@@ -66,17 +159,88 @@ func (ec *EmbeddingClient) GetEmbeddings(ctx context.Context, texts []string) ([
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
+	maxAttempts := ec.config.MaxAttempts
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+
+	for attempt := 1; ; attempt++ {
+		start := time.Now()
+		embeddings, err := ec.callEmbeddingService(ctx, jsonData)
+		ec.metrics.DependencyCall(ctx, "embedding_service", "embed", time.Since(start), err)
+		if err == nil {
+			ec.metrics.EmbeddingRequest("success", time.Since(start))
+			return embeddings, nil
+		}
+
+		delay := ec.backoff(attempt)
+		var statusErr *embeddingStatusError
+		if errors.As(err, &statusErr) && statusErr.retryAfter > delay {
+			delay = statusErr.retryAfter
+		}
+		deadline, hasDeadline := ctx.Deadline()
+		if attempt >= maxAttempts || !retryableEmbeddingError(ctx, err) ||
+			(hasDeadline && time.Until(deadline) < delay) {
+			ec.metrics.EmbeddingRequest("error", time.Since(start))
+			return nil, err
+		}
+		ec.metrics.EmbeddingRequest("retry", time.Since(start))
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, err
+		case <-timer.C:
+		}
+	}
+}
+
+// embeddingStatusError is a non-200 response from the embedding service
+type embeddingStatusError struct {
+	status     int
+	body       string
+	retryAfter time.Duration
+}
+
+func (e *embeddingStatusError) Error() string {
+	return fmt.Sprintf("embedding service returned status %d: %s", e.status, e.body)
+}
+
+// callEmbeddingService makes one attempt, bounded by the configured timeout and
+// ctx's deadline. The remaining time is sent along so the service can drop
+// work the caller will no longer wait for.
+func (ec *EmbeddingClient) callEmbeddingService(ctx context.Context, body []byte) (_ [][]float32, err error) {
+	ctx, span := embeddingTracer.Start(ctx, "embedding_service.embed",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("http.method", "POST")),
+	)
+	defer func() { observability.EndSpan(span, err) }()
+
+	if ec.config.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, ec.config.Timeout)
+		defer cancel()
+	}
+
 	req, err := http.NewRequestWithContext(
 		ctx,
 		"POST",
 		ec.config.URL+"/embeddings",
-		bytes.NewReader(jsonData),
+		bytes.NewReader(body),
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+	if id := requestid.FromContext(ctx); id != "" {
+		req.Header.Set(requestid.Header, id)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		req.Header.Set("X-Request-Timeout-Ms", strconv.FormatInt(time.Until(deadline).Milliseconds(), 10))
+	}
 
 	resp, err := ec.httpClient.Do(req)
 	if err != nil {
@@ -84,9 +248,14 @@ func (ec *EmbeddingClient) GetEmbeddings(ctx context.Context, texts []string) ([
 	}
 	defer resp.Body.Close()
 
+	span.SetAttributes(attribute.Int("http.status_code", resp.StatusCode))
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("embedding service returned status %d: %s", resp.StatusCode, string(body))
+		respBody, _ := io.ReadAll(resp.Body)
+		statusErr := &embeddingStatusError{status: resp.StatusCode, body: string(respBody)}
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+			statusErr.retryAfter = time.Duration(seconds) * time.Second
+		}
+		return nil, statusErr
 	}
 
 	var embResp EmbeddingResponse
@@ -97,33 +266,218 @@ func (ec *EmbeddingClient) GetEmbeddings(ctx context.Context, texts []string) ([
 	return embResp.Embeddings, nil
 }
 
-// GetEmbeddingsBatch retrieves embeddings in batches
-func (ec *EmbeddingClient) GetEmbeddingsBatch(ctx context.Context, texts []string) ([][]float32, error) {
+// retryableEmbeddingError reports whether another attempt could succeed: the
+// caller is still waiting and the failure was a transport error, 429 or 5xx
+func retryableEmbeddingError(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	var statusErr *embeddingStatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.status == http.StatusTooManyRequests || statusErr.status >= 500
+	}
+	var urlErr *url.Error
+	return errors.As(err, &urlErr)
+}
+
+// backoff doubles from InitialBackoff per retry, capped at MaxBackoff, and
+// draws the delay uniformly below that so retries from many callers spread out
+func (ec *EmbeddingClient) backoff(attempt int) time.Duration {
+	delay := ec.config.InitialBackoff
+	if delay <= 0 {
+		return 0
+	}
+	for i := 1; i < attempt && (ec.config.MaxBackoff <= 0 || delay < ec.config.MaxBackoff); i++ {
+		delay *= 2
+	}
+	if ec.config.MaxBackoff > 0 && delay > ec.config.MaxBackoff {
+		delay = ec.config.MaxBackoff
+	}
+	return rand.N(delay)
+}
+
+// GetEmbeddingsBatch retrieves embeddings from model, sending up to Concurrency
+// batches at once. It fails if any batch fails.
+func (ec *EmbeddingClient) GetEmbeddingsBatch(ctx context.Context, model string, texts []string) ([][]float32, error) {
 	if len(texts) == 0 {
 		return nil, nil
 	}
 
-	var allEmbeddings [][]float32
+	size := ec.config.BatchSize
+	if size <= 0 {
+		size = len(texts)
+	}
+	batches := make([][][]float32, (len(texts)+size-1)/size)
 
-	for i := 0; i < len(texts); i += ec.config.BatchSize {
-		end := i + ec.config.BatchSize
+	group, groupCtx := errgroup.WithContext(ctx)
+	concurrency := ec.config.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	group.SetLimit(concurrency)
+	for i := range batches {
+		start := i * size
+		end := start + size
 		if end > len(texts) {
 			end = len(texts)
 		}
+		group.Go(func() error {
+			embeddings, err := ec.GetEmbeddings(groupCtx, model, texts[start:end])
+			if err != nil {
+				return fmt.Errorf("batch %d failed: %w", i, err)
+			}
+			if len(embeddings) != end-start {
+				return fmt.Errorf("batch %d returned %d embeddings for %d texts", i, len(embeddings), end-start)
+			}
+			batches[i] = embeddings
+			return nil
+		})
+	}
+	if err := group.Wait(); err != nil {
+		return nil, err
+	}
+
+	allEmbeddings := make([][]float32, 0, len(texts))
+	for _, embeddings := range batches {
+		allEmbeddings = append(allEmbeddings, embeddings...)
+	}
+	return allEmbeddings, nil
+}
+
+// ErrDimensionMismatch is returned when a model produces vectors that don't fit
+// its dense_vector mapping
+var ErrDimensionMismatch = errors.New("embedding dimensions do not match index mapping")
+
+// embeddingModelID identifies the field, model and vector size behind a
+// document's vector, so vectors from an older model or mapping can be found
+func embeddingModelID(m config.EmbeddingModelConfig) string {
+	return fmt.Sprintf("%s:%s@%d", vectorField(m), m.Model, m.Dimensions)
+}
+
+// vectorField is the document field holding m's vectors
+func vectorField(m config.EmbeddingModelConfig) string {
+	if m.Name == "" {
+		return "embedding"
+	}
+	return "embeddings." + m.Name
+}
+
+func documentVector(doc *elasticsearch.ServiceDocument, m config.EmbeddingModelConfig) []float32 {
+	if m.Name == "" {
+		return doc.Embedding
+	}
+	return doc.Embeddings[m.Name]
+}
+
+func setDocumentVector(doc *elasticsearch.ServiceDocument, m config.EmbeddingModelConfig, vector []float32) {
+	if m.Name == "" {
+		doc.Embedding = vector
+	} else {
+		if doc.Embeddings == nil {
+			doc.Embeddings = make(map[string][]float32)
+		}
+		doc.Embeddings[m.Name] = vector
+	}
 
-		batch := texts[i:end]
-		embeddings, err := ec.GetEmbeddings(ctx, batch)
-		if err != nil {
-			return nil, fmt.Errorf("batch %d failed: %w", i/ec.config.BatchSize, err)
+	id := embeddingModelID(m)
+	for _, existing := range doc.EmbeddingModels {
+		if existing == id {
+			return
 		}
+	}
+	doc.EmbeddingModels = append(doc.EmbeddingModels, id)
+}
 
-		allEmbeddings = append(allEmbeddings, embeddings...)
+// hasEmbedding reports whether doc holds a current vector from m
+func hasEmbedding(doc *elasticsearch.ServiceDocument, m config.EmbeddingModelConfig) bool {
+	if len(documentVector(doc, m)) == 0 {
+		return false
+	}
+	id := embeddingModelID(m)
+	for _, existing := range doc.EmbeddingModels {
+		if existing == id {
+			return true
+		}
+	}
+	return false
+}
 
-		// Small delay between batches to avoid overwhelming the service
-		if end < len(texts) {
-			time.Sleep(100 * time.Millisecond)
+// NeedsEmbedding reports whether doc is missing a vector from any configured model
+func (s *Service) NeedsEmbedding(doc *elasticsearch.ServiceDocument) bool {
+	for _, m := range s.config.EmbeddingModels() {
+		if !hasEmbedding(doc, m) {
+			return true
 		}
 	}
+	return false
+}
 
-	return allEmbeddings, nil
+// EmbedDocuments fills in the vectors docs are missing from each configured
+// model. While a migration lists two models, documents get both. A model that
+// fails leaves its vectors missing; the remaining models are still tried.
+func (s *Service) EmbedDocuments(ctx context.Context, docs []*elasticsearch.ServiceDocument) error {
+	var errs []error
+	for _, m := range s.config.EmbeddingModels() {
+		var pending []*elasticsearch.ServiceDocument
+		for _, doc := range docs {
+			if !hasEmbedding(doc, m) {
+				pending = append(pending, doc)
+			}
+		}
+		if len(pending) == 0 {
+			continue
+		}
+
+		if err := s.embedWith(ctx, m, pending); err != nil {
+			s.metrics.EmbeddingsGenerated("error", len(pending))
+			errs = append(errs, fmt.Errorf("%s: %w", embeddingModelID(m), err))
+			continue
+		}
+		s.metrics.EmbeddingsGenerated("success", len(pending))
+	}
+	return errors.Join(errs...)
+}
+
+// embedWith sets m's vector on every doc, or none of them if any batch fails
+func (s *Service) embedWith(ctx context.Context, m config.EmbeddingModelConfig, docs []*elasticsearch.ServiceDocument) error {
+	texts := make([]string, len(docs))
+	for i, doc := range docs {
+		texts[i] = embeddingText(doc)
+	}
+
+	vectors, err := s.embeddingClient.GetEmbeddingsBatch(ctx, m.Model, texts)
+	if err != nil {
+		return fmt.Errorf("failed to generate embeddings: %w", err)
+	}
+	if len(vectors) != len(docs) {
+		return fmt.Errorf("embedding service returned %d vectors for %d documents", len(vectors), len(docs))
+	}
+	for _, vector := range vectors {
+		if len(vector) != m.Dimensions {
+			return fmt.Errorf("%w: got %d, want %d", ErrDimensionMismatch, len(vector), m.Dimensions)
+		}
+	}
+
+	for i, doc := range docs {
+		setDocumentVector(doc, m, vectors[i])
+	}
+	return nil
+}
+
+// embeddingText is the text a service is embedded from
+func embeddingText(doc *elasticsearch.ServiceDocument) string {
+	parts := []string{doc.Name, doc.Description}
+	if doc.Category != "" {
+		parts = append(parts, "Category: "+doc.Category)
+	}
+	if len(doc.Tags) > 0 {
+		parts = append(parts, "Tags: "+strings.Join(doc.Tags, ", "))
+	}
+	if len(doc.Capabilities) > 0 {
+		parts = append(parts, "Capabilities: "+strings.Join(doc.Capabilities, ", "))
+	}
+	if doc.Provider.Name != "" {
+		parts = append(parts, "Provider: "+doc.Provider.Name)
+	}
+	return strings.Join(parts, "\n")
 }
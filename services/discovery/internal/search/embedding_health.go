@@ -0,0 +1,165 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/org/llm-marketplace/services/discovery/internal/config"
+	"go.uber.org/zap"
+)
+
+// EmbeddingHealth probes the remote embedding service in the background, so
+// readiness and searches answer from the last probe instead of calling the
+// service. The service counts as down once failures reach the configured
+// threshold.
+type EmbeddingHealth struct {
+	config     config.EmbeddingServiceConfig
+	httpClient *http.Client
+	logger     *zap.Logger
+
+	mu       sync.RWMutex
+	healthy  bool
+	failures int
+	latency  time.Duration
+	err      error
+}
+
+// NewEmbeddingHealth creates a prober. The service starts out healthy so
+// searches keep their semantic clause until a probe says otherwise.
+func NewEmbeddingHealth(cfg config.EmbeddingServiceConfig, logger *zap.Logger) *EmbeddingHealth {
+	return &EmbeddingHealth{
+		config:     cfg,
+		httpClient: &http.Client{},
+		logger:     logger,
+		healthy:    true,
+	}
+}
+
+// Healthy reports whether the last probes passed. Without a prober the
+// service is assumed healthy.
+func (h *EmbeddingHealth) Healthy() bool {
+	if h == nil {
+		return true
+	}
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.healthy
+}
+
+// Start runs the prober until ctx is cancelled
+func (h *EmbeddingHealth) Start(ctx context.Context) {
+	interval := h.config.Health.Interval
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+
+	h.logger.Info("Starting embedding service health prober", zap.Duration("interval", interval))
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	h.probe(ctx)
+
+	for {
+		select {
+		case <-ticker.C:
+			h.probe(ctx)
+		case <-ctx.Done():
+			h.logger.Info("Embedding service health prober stopped")
+			return
+		}
+	}
+}
+
+// Check reports the last probe for readiness: its latency, and an error once
+// the service is down
+func (h *EmbeddingHealth) Check(ctx context.Context) (time.Duration, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if !h.healthy {
+		return h.latency, fmt.Errorf("%d consecutive failed probes: %v", h.failures, h.err)
+	}
+	return h.latency, nil
+}
+
+func (h *EmbeddingHealth) probe(ctx context.Context) {
+	start := time.Now()
+	err := h.ping(ctx)
+	latency := time.Since(start)
+
+	threshold := h.config.Health.FailureThreshold
+	if threshold < 1 {
+		threshold = 1
+	}
+
+	h.mu.Lock()
+	wasHealthy := h.healthy
+	if err != nil {
+		h.failures++
+	} else {
+		h.failures = 0
+	}
+	h.healthy = h.failures < threshold
+	h.latency = latency
+	h.err = err
+	healthy, failures := h.healthy, h.failures
+	h.mu.Unlock()
+
+	switch {
+	case wasHealthy && !healthy:
+		h.logger.Error("Embedding service is down, skipping semantic search",
+			zap.Int("consecutive_failures", failures),
+			zap.Error(err),
+		)
+	case !wasHealthy && healthy:
+		h.logger.Info("Embedding service is healthy again")
+	case err != nil:
+		h.logger.Warn("Embedding service health probe failed", zap.Error(err))
+	}
+}
+
+// ping makes one GET of the health path, which passes on any 2xx
+func (h *EmbeddingHealth) ping(ctx context.Context) error {
+	timeout := h.config.Health.Timeout
+	if timeout <= 0 {
+		timeout = time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	path := h.config.Health.Path
+	if path == "" {
+		path = "/health"
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, h.config.URL+path, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := h.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("health request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("health request failed: %s", resp.Status)
+	}
+	return nil
+}
+
+// SetEmbeddingHealth registers the prober whose verdict turns the semantic
+// clause off while the embedding service is down
+func (s *Service) SetEmbeddingHealth(h *EmbeddingHealth) {
+	s.embeddingHealth = h
+}
+
+// semanticAvailable reports whether a query embedding can be had: the
+// embedding service is up, or the local model can stand in for it
+func (s *Service) semanticAvailable() bool {
+	return s.embeddingHealth.Healthy() || s.embeddingClient.localAvailable()
+}
@@ -0,0 +1,179 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/org/llm-marketplace/services/discovery/internal/analytics"
+	"github.com/org/llm-marketplace/services/discovery/internal/elasticsearch"
+	"github.com/org/llm-marketplace/services/discovery/internal/elasticsearch/query"
+	"go.uber.org/zap"
+)
+
+// ErrUnknownEntityType is returned when a search names an entity type that doesn't exist
+var ErrUnknownEntityType = errors.New("unknown entity type")
+
+// EntityGroup holds the results for one non-service entity type
+type EntityGroup struct {
+	Total   int            `json:"total"`
+	Results []EntityResult `json:"results"`
+}
+
+// EntityResult is a dataset, prompt template or provider matching a search
+type EntityResult struct {
+	ID       string          `json:"id"`
+	Type     string          `json:"type"`
+	Score    float64         `json:"score"`
+	Document json.RawMessage `json:"document"`
+}
+
+// ValidateTypes rejects unknown entity types
+func ValidateTypes(types []string) error {
+	for _, t := range types {
+		if !elasticsearch.IsEntityType(t) {
+			return fmt.Errorf("%w: %s", ErrUnknownEntityType, t)
+		}
+	}
+	return nil
+}
+
+// includesServices reports whether services are searched; they are when no types are given
+func includesServices(types []string) bool {
+	if len(types) == 0 {
+		return true
+	}
+	for _, t := range types {
+		if t == elasticsearch.EntityService {
+			return true
+		}
+	}
+	return false
+}
+
+// otherEntityTypes returns the requested types other than services, without duplicates
+func otherEntityTypes(types []string) []string {
+	seen := make(map[string]bool)
+	var others []string
+	for _, t := range types {
+		if t != elasticsearch.EntityService && !seen[t] {
+			seen[t] = true
+			others = append(others, t)
+		}
+	}
+	return others
+}
+
+// searchEntities runs one query per entity type in a single multi-search
+func (s *Service) searchEntities(ctx context.Context, req *SearchRequest, types []string) (map[string]*EntityGroup, error) {
+	size := req.Pagination.PageSize
+	if size <= 0 {
+		size = s.config.Search.DefaultResults
+	}
+	if size > s.config.Search.MaxResults {
+		size = s.config.Search.MaxResults
+	}
+
+	requests := make([]elasticsearch.MultiSearchRequest, 0, len(types))
+	for _, t := range types {
+		index, err := s.esClient.EntityIndex(t)
+		if err != nil {
+			return nil, err
+		}
+		requests = append(requests, elasticsearch.MultiSearchRequest{
+			Index: index,
+			Query: buildEntityQuery(t, req, size),
+		})
+	}
+
+	responses, err := s.esClient.MultiSearch(ctx, requests)
+	if err != nil {
+		return nil, err
+	}
+
+	groups := make(map[string]*EntityGroup, len(types))
+	for i, resp := range responses {
+		group := &EntityGroup{
+			Total:   resp.Hits.Total.Value,
+			Results: make([]EntityResult, 0, len(resp.Hits.Hits)),
+		}
+		for _, hit := range resp.Hits.Hits {
+			group.Results = append(group.Results, EntityResult{
+				ID:       hit.ID,
+				Type:     types[i],
+				Score:    hit.Score,
+				Document: hit.Source,
+			})
+		}
+		groups[types[i]] = group
+	}
+
+	return groups, nil
+}
+
+// buildEntityQuery matches the shared text fields; only filters that apply to every type are used
+func buildEntityQuery(entityType string, req *SearchRequest, size int) map[string]interface{} {
+	boolQuery := query.Bool()
+	if req.Query != "" {
+		fields := []string{"name^3", "name.autocomplete", "description", "tags^2"}
+		if entityType == elasticsearch.EntityPromptTemplate {
+			fields = append(fields, "template")
+		}
+		boolQuery.Must(query.MultiMatch(req.Query, fields...).Fuzziness("AUTO"))
+	} else {
+		boolQuery.Must(query.MatchAll())
+	}
+
+	if len(req.Filters.Tags) > 0 {
+		boolQuery.Filter(query.Terms("tags", req.Filters.Tags))
+	}
+	if req.Filters.VerifiedOnly {
+		field := "provider.verified"
+		if entityType == elasticsearch.EntityProvider {
+			field = "verified"
+		}
+		boolQuery.Filter(query.Term(field, true))
+	}
+
+	search := &query.Search{
+		Query: boolQuery,
+		From:  req.Pagination.Page * size,
+		Size:  size,
+		SourceFilter: map[string]interface{}{
+			"excludes": []string{"metadata"},
+		},
+		TrackTotalHits: true,
+	}
+	return search.Map()
+}
+
+// searchEntitiesOnly serves searches that exclude services
+func (s *Service) searchEntitiesOnly(ctx context.Context, req *SearchRequest, cacheKey string, startTime time.Time) (*SearchResponse, error) {
+	groups, err := s.searchEntities(ctx, req, otherEntityTypes(req.Types))
+	if err != nil {
+		s.logger.Error("Entity search failed", zap.Error(err))
+		s.metrics.SearchError()
+		return nil, fmt.Errorf("search failed: %w", err)
+	}
+
+	response := &SearchResponse{
+		Results:  []SearchResult{},
+		Page:     req.Pagination.Page,
+		PageSize: req.Pagination.PageSize,
+		Groups:   groups,
+	}
+
+	if err := s.cacheResults(ctx, cacheKey, response, "search_results"); err != nil {
+		s.logger.Warn("Failed to cache results", zap.Error(err))
+	}
+
+	duration := time.Since(startTime)
+	s.metrics.SearchDuration(ctx, duration)
+
+	response.QueryID = analytics.NewID()
+	s.trackSearchEvent(req, response, duration, false)
+
+	return response, nil
+}
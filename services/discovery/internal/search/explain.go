@@ -0,0 +1,132 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/org/llm-marketplace/services/discovery/internal/elasticsearch"
+)
+
+// ErrInvalidExplain is returned for explain requests without a query or service
+var ErrInvalidExplain = errors.New("explain needs a query and a service_id")
+
+// SearchExplanation accounts for a service's ranking score in a search, so
+// operators can tell why one service ranks above another
+type SearchExplanation struct {
+	Query     string        `json:"query"`
+	Searched  string        `json:"searched_query"` // After query understanding and the query pipeline
+	ServiceID string        `json:"service_id"`
+	Intent    *QueryIntent  `json:"intent,omitempty"`
+	Rewrite   *QueryRewrite `json:"rewrite,omitempty"`
+
+	Matched       bool                       `json:"matched"` // Whether the search returns the service at all
+	Elasticsearch *elasticsearch.Explanation `json:"elasticsearch,omitempty"`
+
+	Score          float64        `json:"score"` // Ranking score, before the caller's region, feature and session re-ranking
+	Components     RankComponents `json:"components"`
+	Demoted        bool           `json:"demoted"`
+	DemotionFactor float64        `json:"demotion_factor,omitempty"` // Multiplied into the score of a demoted service
+}
+
+// RankComponents are the weighted parts of a ranking score
+type RankComponents struct {
+	Relevance   RankComponent `json:"relevance"`
+	Popularity  RankComponent `json:"popularity"`
+	Performance RankComponent `json:"performance"`
+	Compliance  RankComponent `json:"compliance"`
+}
+
+// RankComponent is one part of a ranking score
+type RankComponent struct {
+	Score        float64 `json:"score"` // Between 0 and 1
+	Weight       float64 `json:"weight"`
+	Contribution float64 `json:"contribution"` // Score times weight
+}
+
+// Explain ranks one service for query as Search would: the query is read
+// and rewritten the same way, Elasticsearch explains the service's score
+// for the resulting query, and the score, relative to the query's best, is
+// weighed with the service's popularity, performance and compliance
+func (s *Service) Explain(ctx context.Context, query, serviceID string) (*SearchExplanation, error) {
+	if strings.TrimSpace(query) == "" || serviceID == "" {
+		return nil, ErrInvalidExplain
+	}
+
+	req := &SearchRequest{Query: query}
+	req.intent = s.understand(ctx, req)
+	req.rewrite = s.rewriteQuery(ctx, req)
+
+	svc, err := s.GetServiceByID(ctx, serviceID)
+	if err != nil {
+		return nil, err
+	}
+
+	esQuery, err := s.buildSearchQuery(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build query: %w", err)
+	}
+	clause, _ := esQuery["query"].(map[string]interface{})
+	matched, explanation, err := s.esClient.Explain(ctx, serviceID, clause)
+	if err != nil {
+		return nil, fmt.Errorf("explain failed: %w", err)
+	}
+
+	relevance := 0.0
+	if matched && explanation != nil {
+		maxScore, err := s.maxScore(ctx, esQuery)
+		if err != nil {
+			return nil, err
+		}
+		relevance = normalizeRelevance(explanation.Value, maxScore)
+	}
+	weights := s.config.RankingWeights()
+	score, details := s.rankingScore(svc, relevance, weights)
+
+	result := &SearchExplanation{
+		Query:         query,
+		Searched:      req.Query,
+		ServiceID:     serviceID,
+		Intent:        req.intent,
+		Rewrite:       req.rewrite,
+		Matched:       matched,
+		Elasticsearch: explanation,
+		Score:         score,
+		Components: RankComponents{
+			Relevance:   rankComponent(details.RelevanceScore, weights.Relevance),
+			Popularity:  rankComponent(details.PopularityScore, weights.Popularity),
+			Performance: rankComponent(details.PerformanceScore, weights.Performance),
+			Compliance:  rankComponent(details.ComplianceScore, weights.Compliance),
+		},
+		Demoted: details.Demoted,
+	}
+	if details.Demoted {
+		result.DemotionFactor = s.config.Search.ReportDemotion.Factor
+	}
+	return result, nil
+}
+
+// maxScore returns the best score of any match for esQuery, which Search
+// scales relevance by
+func (s *Service) maxScore(ctx context.Context, esQuery map[string]interface{}) (float64, error) {
+	top := make(map[string]interface{}, len(esQuery))
+	for k, v := range esQuery {
+		top[k] = v
+	}
+	delete(top, "aggs")
+	top["from"], top["size"], top["_source"] = 0, 1, false
+
+	resp, err := s.esClient.Search(ctx, top)
+	if err != nil {
+		return 0, fmt.Errorf("search failed: %w", err)
+	}
+	if len(resp.Hits.Hits) > 0 && resp.Hits.MaxScore <= 0 {
+		return resp.Hits.Hits[0].Score, nil
+	}
+	return resp.Hits.MaxScore, nil
+}
+
+func rankComponent(score, weight float64) RankComponent {
+	return RankComponent{Score: score, Weight: weight, Contribution: score * weight}
+}
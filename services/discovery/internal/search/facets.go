@@ -0,0 +1,145 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/org/llm-marketplace/services/discovery/internal/config"
+	"github.com/org/llm-marketplace/services/discovery/internal/elasticsearch"
+)
+
+// ErrUnknownFacet is returned when a search asks for a facet that isn't configured
+var ErrUnknownFacet = errors.New("unknown facet")
+
+// facetAggregations renders each configured facet as its Elasticsearch aggregation
+func facetAggregations(facets []config.FacetConfig) map[string]map[string]interface{} {
+	aggs := make(map[string]map[string]interface{}, len(facets))
+	for _, f := range facets {
+		switch f.Type {
+		case config.FacetTerms:
+			aggs[f.Name] = map[string]interface{}{
+				"terms": map[string]interface{}{"field": f.Field, "size": f.Size},
+			}
+		case config.FacetAvg:
+			aggs[f.Name] = map[string]interface{}{
+				"avg": map[string]interface{}{"field": f.Field},
+			}
+		case config.FacetHistogram:
+			aggs[f.Name] = map[string]interface{}{
+				"histogram": map[string]interface{}{"field": f.Field, "interval": f.Interval},
+			}
+		case config.FacetAutoHistogram:
+			// Bucket edges follow the data, so a few outliers don't stretch every bucket
+			aggs[f.Name] = map[string]interface{}{
+				"variable_width_histogram": map[string]interface{}{"field": f.Field, "buckets": f.Size},
+			}
+		case config.FacetRange:
+			ranges := make([]interface{}, len(f.Ranges))
+			for i, r := range f.Ranges {
+				bucket := map[string]interface{}{"key": r.Key}
+				if r.From != nil {
+					bucket["from"] = *r.From
+				}
+				if r.To != nil {
+					bucket["to"] = *r.To
+				}
+				ranges[i] = bucket
+			}
+			aggs[f.Name] = map[string]interface{}{
+				"range": map[string]interface{}{"field": f.Field, "ranges": ranges},
+			}
+		}
+	}
+	return aggs
+}
+
+// facetLabels collects the display label of each range bucket by facet and key
+func facetLabels(facets []config.FacetConfig) map[string]map[string]string {
+	labels := make(map[string]map[string]string)
+	for _, f := range facets {
+		for _, r := range f.Ranges {
+			if r.Label == "" {
+				continue
+			}
+			if labels[f.Name] == nil {
+				labels[f.Name] = make(map[string]string)
+			}
+			labels[f.Name][r.Key] = r.Label
+		}
+	}
+	return labels
+}
+
+// facetResults decodes the aggregations of esResponse as a search returns
+// them: range buckets labelled, and with a managed taxonomy, the categories
+// rolled up into category_hierarchy
+func (s *Service) facetResults(ctx context.Context, esResponse *elasticsearch.SearchResponse) (map[string]interface{}, error) {
+	aggs, err := esResponse.DecodeAggregations()
+	if err != nil {
+		return nil, err
+	}
+	s.labelFacetBuckets(aggs)
+
+	if _, ok := esResponse.Aggregations["categories"]; ok {
+		if tax := s.loadTaxonomy(ctx); !tax.Empty() {
+			var categories elasticsearch.TermsAggregation
+			if _, err := esResponse.Aggregation("categories", &categories); err != nil {
+				return nil, err
+			}
+			aggs["category_hierarchy"] = categoryHierarchy(tax, categories.Buckets)
+		}
+	}
+	return aggs, nil
+}
+
+// labelFacetBuckets adds the configured label to each range facet bucket in aggs
+func (s *Service) labelFacetBuckets(aggs map[string]interface{}) {
+	for name, labels := range s.facetLabels {
+		agg, ok := aggs[name].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		buckets, _ := agg["buckets"].([]interface{})
+		for _, bucket := range buckets {
+			b, ok := bucket.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			key, _ := b["key"].(string)
+			if label, ok := labels[key]; ok {
+				b["label"] = label
+			}
+		}
+	}
+}
+
+// ValidateFacets rejects facet names that aren't configured
+func (s *Service) ValidateFacets(names []string) error {
+	for _, name := range names {
+		if _, ok := s.facets[name]; !ok {
+			return fmt.Errorf("%w: %s", ErrUnknownFacet, name)
+		}
+	}
+	return nil
+}
+
+// buildAggregations builds the aggregations for the named facets, or for every
+// configured facet when names is empty
+func (s *Service) buildAggregations(names []string) (map[string]interface{}, error) {
+	if err := s.ValidateFacets(names); err != nil {
+		return nil, err
+	}
+
+	aggs := make(map[string]interface{}, len(s.facets))
+	if len(names) == 0 {
+		for name, agg := range s.facets {
+			aggs[name] = agg
+		}
+		return aggs, nil
+	}
+	for _, name := range names {
+		aggs[name] = s.facets[name]
+	}
+	return aggs, nil
+}
@@ -0,0 +1,114 @@
+package search
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/org/llm-marketplace/services/discovery/internal/elasticsearch"
+)
+
+// selectableFields are the top-level document fields clients may request
+var selectableFields = jsonFieldNames(reflect.TypeOf(elasticsearch.ServiceDocument{}))
+
+// rankingFields are always fetched so scoring, banners and visibility checks work under field selection
+var rankingFields = []string{"id", "status", "deprecation", "metrics", "sla", "compliance", "access"}
+
+// ParseFields parses a comma-separated fields parameter, e.g. "name,pricing.rate"
+func ParseFields(raw string) ([]string, error) {
+	var fields []string
+	for _, field := range strings.Split(raw, ",") {
+		if field = strings.TrimSpace(field); field != "" {
+			fields = append(fields, field)
+		}
+	}
+
+	if err := ValidateFields(fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+// ValidateFields checks that every field names a known document field
+func ValidateFields(fields []string) error {
+	for _, field := range fields {
+		top := strings.SplitN(field, ".", 2)[0]
+		if !selectableFields[top] {
+			return fmt.Errorf("unknown field: %s", field)
+		}
+	}
+	return nil
+}
+
+// sourceFilter builds the _source clause; without fields only the embedding is dropped
+func sourceFilter(fields []string) map[string]interface{} {
+	if len(fields) == 0 {
+		return map[string]interface{}{
+			"excludes": elasticsearch.DefaultSourceExcludes,
+		}
+	}
+
+	includes := make([]string, 0, len(fields)+len(rankingFields))
+	includes = append(includes, fields...)
+	includes = append(includes, rankingFields...)
+
+	return map[string]interface{}{
+		"includes": includes,
+	}
+}
+
+// ProjectDocument returns a sparse view of doc holding only the requested fields and its ID
+func ProjectDocument(doc *elasticsearch.ServiceDocument, fields []string) (map[string]interface{}, error) {
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	var full map[string]interface{}
+	if err := json.Unmarshal(data, &full); err != nil {
+		return nil, err
+	}
+
+	sparse := map[string]interface{}{"id": doc.ID}
+	for _, field := range fields {
+		copyPath(full, sparse, strings.Split(field, "."))
+	}
+
+	return sparse, nil
+}
+
+func copyPath(src, dst map[string]interface{}, path []string) {
+	value, ok := src[path[0]]
+	if !ok {
+		return
+	}
+
+	if len(path) == 1 {
+		dst[path[0]] = value
+		return
+	}
+
+	child, ok := value.(map[string]interface{})
+	if !ok {
+		return
+	}
+
+	next, ok := dst[path[0]].(map[string]interface{})
+	if !ok {
+		next = map[string]interface{}{}
+		dst[path[0]] = next
+	}
+	copyPath(child, next, path[1:])
+}
+
+func jsonFieldNames(t reflect.Type) map[string]bool {
+	names := make(map[string]bool, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		name := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+		if name != "" && name != "-" {
+			names[name] = true
+		}
+	}
+	return names
+}
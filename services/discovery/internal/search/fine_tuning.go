@@ -0,0 +1,24 @@
+package search
+
+import (
+	"strings"
+
+	"github.com/org/llm-marketplace/services/discovery/internal/elasticsearch"
+	"github.com/org/llm-marketplace/services/discovery/internal/elasticsearch/query"
+)
+
+// filterFineTuning restricts a search to the fine-tuning offerings for the
+// base models and within the turnaround the filters ask for. Each filter
+// only matches fine-tuning offerings.
+func filterFineTuning(boolQuery *query.BoolQuery, f SearchFilters) {
+	if len(f.BaseModels) > 0 {
+		models := make([]string, len(f.BaseModels))
+		for i, model := range f.BaseModels {
+			models[i] = strings.ToLower(strings.TrimSpace(model))
+		}
+		boolQuery.Filter(query.Terms(elasticsearch.BaseModelsField, models))
+	}
+	if f.MaxTurnaroundHours > 0 {
+		boolQuery.Filter(query.Range(elasticsearch.TurnaroundField).Gt(0).Lte(f.MaxTurnaroundHours))
+	}
+}
@@ -0,0 +1,32 @@
+package search
+
+import (
+	"context"
+
+	"github.com/org/llm-marketplace/pkg/marketplace/flags"
+	"github.com/org/llm-marketplace/services/discovery/internal/entitlement"
+)
+
+// SetFlags registers the feature flags that gate semantic search,
+// personalization and the re-ranking stages. Without them every stage runs.
+func (s *Service) SetFlags(e *flags.Evaluator) {
+	s.flags = e
+}
+
+// flagOn reports whether the flag is on for the caller's tenant and key
+func (s *Service) flagOn(ctx context.Context, name, key string) bool {
+	return s.flags.Enabled(name, flags.Subject{Tenant: entitlement.FromContext(ctx).TenantID, Key: key})
+}
+
+// rerank applies the re-ranking stages the flags leave on for the caller
+func (s *Service) rerank(ctx context.Context, req *SearchRequest, results []SearchResult) {
+	if s.flagOn(ctx, flags.RegionRanking, req.UserID) {
+		s.rankByRegion(req.Region, results)
+	}
+	if s.flagOn(ctx, flags.Personalization, req.UserID) {
+		s.personalize(ctx, req.UserID, results)
+	}
+	if s.flagOn(ctx, flags.SessionReranking, req.UserID) {
+		s.rerankForSession(ctx, req.SessionID, results)
+	}
+}
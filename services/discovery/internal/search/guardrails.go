@@ -0,0 +1,129 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/org/llm-marketplace/services/discovery/internal/elasticsearch"
+)
+
+var (
+	// ErrQueryTooExpensive is returned for a search over one of the guardrails
+	ErrQueryTooExpensive = errors.New("query too expensive")
+
+	// ErrSearchTimedOut is returned when Elasticsearch times out and partial
+	// results are not served
+	ErrSearchTimedOut = errors.New("search timed out")
+)
+
+// Guards a search can be stopped by, as counted in metrics
+const (
+	guardFilters     = "filters"
+	guardPageSize    = "page_size"
+	guardOffset      = "offset"
+	guardQueryLength = "query_length"
+	guardFuzzyTerms  = "fuzzy_terms"
+	guardTimeout     = "timeout"
+)
+
+// minFuzzyTermLength is the shortest word AUTO fuzziness allows an edit in
+const minFuzzyTermLength = 3
+
+// checkCost rejects a search over the guardrails, as the caller sent it.
+// Pareto searches fetch their own page of candidates, so pagination isn't
+// checked for them.
+func (s *Service) checkCost(req *SearchRequest) error {
+	g := s.config.Search.Guardrails
+	if !g.Enabled {
+		return nil
+	}
+
+	reject := func(guard, format string, args ...interface{}) error {
+		s.metrics.SearchGuardrail(guard, "rejected")
+		return fmt.Errorf("%w: %s", ErrQueryTooExpensive, fmt.Sprintf(format, args...))
+	}
+
+	if n := utf8.RuneCountInString(req.Query); g.MaxQueryLength > 0 && n > g.MaxQueryLength {
+		return reject(guardQueryLength, "query is %d characters, at most %d allowed", n, g.MaxQueryLength)
+	}
+	if n := fuzzyTerms(req.Query); g.MaxFuzzyTerms > 0 && n > g.MaxFuzzyTerms {
+		return reject(guardFuzzyTerms, "query has %d words of %d or more characters, at most %d allowed", n, minFuzzyTermLength, g.MaxFuzzyTerms)
+	}
+	if n := req.Filters.count(); g.MaxFilters > 0 && n > g.MaxFilters {
+		return reject(guardFilters, "%d filter values, at most %d allowed", n, g.MaxFilters)
+	}
+	if req.Pareto != nil {
+		return nil
+	}
+	if size := req.Pagination.PageSize; g.MaxPageSize > 0 && size > g.MaxPageSize {
+		return reject(guardPageSize, "page_size %d, at most %d allowed", size, g.MaxPageSize)
+	}
+	if offset := req.Pagination.Page * req.Pagination.PageSize; g.MaxOffset > 0 && offset > g.MaxOffset {
+		return reject(guardOffset, "page %d starts at result %d, at most %d allowed", req.Pagination.Page, offset, g.MaxOffset)
+	}
+	return nil
+}
+
+// fuzzyTerms counts the words of q long enough to be fuzzy matched
+func fuzzyTerms(q string) int {
+	n := 0
+	for _, word := range strings.Fields(q) {
+		if utf8.RuneCountInString(word) >= minFuzzyTermLength {
+			n++
+		}
+	}
+	return n
+}
+
+// count is the number of filter values set, each list item counting as one
+func (f SearchFilters) count() int {
+	n := len(f.Categories) + len(f.Tags) + len(f.PricingModels) + len(f.Certifications) + len(f.DataResidency) + len(f.Capabilities) + len(f.ModelFamilies) + len(f.Licenses) + len(f.DatasetFormats) + len(f.BaseModels)
+	for _, set := range []bool{
+		f.MinRating != 0,
+		f.MinPrice != 0,
+		f.MaxPrice != 0,
+		f.ComplianceLevel != "",
+		f.VerifiedOnly,
+		f.Status != "",
+		f.MinAvailability != 0,
+		f.MaxLatencyMS != 0,
+		f.GDPRCompliant,
+		f.HIPAACompliant,
+		f.PolicyCompliant,
+		f.Kind != "",
+		f.MaxDatasetBytes != 0,
+		f.MaxTurnaroundHours != 0,
+		f.ServingRegion != "",
+	} {
+		if set {
+			n++
+		}
+	}
+	return n
+}
+
+// boundQuery sets the timeout on an Elasticsearch search body: the
+// guardrails timeout, or what is left of the latency budget if that is sooner
+func (s *Service) boundQuery(ctx context.Context, esQuery map[string]interface{}) {
+	if timeout := s.searchTimeout(ctx); timeout > 0 {
+		esQuery["timeout"] = fmt.Sprintf("%dms", timeout.Milliseconds())
+	}
+}
+
+// partialResults reports whether Elasticsearch timed out and returned what
+// it found by then. It returns ErrSearchTimedOut when partial results are
+// not served.
+func (s *Service) partialResults(resp *elasticsearch.SearchResponse) (bool, error) {
+	if !resp.TimedOut {
+		return false, nil
+	}
+	if !s.config.Search.Guardrails.PartialResults {
+		s.metrics.SearchGuardrail(guardTimeout, "failed")
+		return false, ErrSearchTimedOut
+	}
+	s.metrics.SearchGuardrail(guardTimeout, "partial")
+	return true, nil
+}
@@ -6,19 +6,51 @@ import (
 	"fmt"
 
 	"github.com/org/llm-marketplace/services/discovery/internal/elasticsearch"
+	"github.com/org/llm-marketplace/services/discovery/internal/entitlement"
 	"go.uber.org/zap"
 )
 
 // GetServiceByID retrieves a service by its ID
 func (s *Service) GetServiceByID(ctx context.Context, id string) (*elasticsearch.ServiceDocument, error) {
+	service, err := s.loadService(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	// Restricted services are reported as missing so their existence isn't revealed.
+	// The cache is shared across tenants, so the tenant is checked here too.
+	if !s.esClient.InTenant(ctx, service) || !entitlement.FromContext(ctx).CanView(service.Access) {
+		return nil, elasticsearch.ErrNotFound
+	}
+
+	return service, nil
+}
+
+// VisibleServices reports which of ids exist and are visible to the caller in ctx
+func (s *Service) VisibleServices(ctx context.Context, ids []string) (map[string]bool, error) {
+	docs, err := s.esClient.MGet(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	caller := entitlement.FromContext(ctx)
+	visible := make(map[string]bool, len(docs))
+	for id, doc := range docs {
+		visible[id] = caller.CanView(doc.Access)
+	}
+	return visible, nil
+}
+
+// loadService reads a service through the cache without checking entitlements
+func (s *Service) loadService(ctx context.Context, id string) (*elasticsearch.ServiceDocument, error) {
 	// Check cache first
 	cacheKey := fmt.Sprintf("service:%s", id)
 	if cached, err := s.getCachedService(ctx, cacheKey); err == nil && cached != nil {
 		return cached, nil
 	}
 
-	// Get from Elasticsearch
-	service, err := s.esClient.Get(ctx, id)
+	// Get from Elasticsearch, without the embedding vector
+	service, err := s.esClient.GetSource(ctx, id, nil, elasticsearch.DefaultSourceExcludes)
 	if err != nil {
 		return nil, err
 	}
@@ -34,7 +66,7 @@ func (s *Service) GetServiceByID(ctx context.Context, id string) (*elasticsearch
 // GetCategories returns all available categories
 func (s *Service) GetCategories(ctx context.Context) ([]CategoryInfo, error) {
 	// Check cache
-	cacheKey := "categories:all"
+	cacheKey := s.tenantCacheKey(ctx, "categories:all")
 	if cached, err := s.getCachedCategories(ctx, cacheKey); err == nil && cached != nil {
 		return cached, nil
 	}
@@ -46,7 +78,7 @@ func (s *Service) GetCategories(ctx context.Context) ([]CategoryInfo, error) {
 			"categories": map[string]interface{}{
 				"terms": map[string]interface{}{
 					"field": "category",
-					"size":  100,
+					"size":  1000,
 				},
 				"aggs": map[string]interface{}{
 					"avg_rating": map[string]interface{}{
@@ -65,27 +97,23 @@ func (s *Service) GetCategories(ctx context.Context) ([]CategoryInfo, error) {
 	}
 
 	// Parse aggregation results
-	categories := []CategoryInfo{}
-	if aggs, ok := resp.Aggregations["categories"].(map[string]interface{}); ok {
-		if buckets, ok := aggs["buckets"].([]interface{}); ok {
-			for _, bucket := range buckets {
-				b := bucket.(map[string]interface{})
-				category := CategoryInfo{
-					Name:  b["key"].(string),
-					Count: int(b["doc_count"].(float64)),
-				}
-
-				if avgRating, ok := b["avg_rating"].(map[string]interface{}); ok {
-					if value, ok := avgRating["value"].(float64); ok {
-						category.AvgRating = value
-					}
-				}
-
-				categories = append(categories, category)
-			}
+	var agg struct {
+		Buckets []elasticsearch.RatedTermsBucket `json:"buckets"`
+	}
+	if _, err := resp.Aggregation("categories", &agg); err != nil {
+		return nil, err
+	}
+	direct := make(map[string]CategoryInfo, len(agg.Buckets))
+	for _, b := range agg.Buckets {
+		category := CategoryInfo{Name: string(b.Key), Count: b.DocCount}
+		if b.AvgRating.Value != nil {
+			category.AvgRating = *b.AvgRating.Value
 		}
+		direct[category.Name] = category
 	}
 
+	categories := rollUpCategories(s.loadTaxonomy(ctx), direct)
+
 	// Cache results
 	s.cacheCategories(ctx, cacheKey, categories)
 
@@ -95,7 +123,7 @@ func (s *Service) GetCategories(ctx context.Context) ([]CategoryInfo, error) {
 // GetTags returns all available tags
 func (s *Service) GetTags(ctx context.Context) ([]TagInfo, error) {
 	// Check cache
-	cacheKey := "tags:all"
+	cacheKey := s.tenantCacheKey(ctx, "tags:all")
 	if cached, err := s.getCachedTags(ctx, cacheKey); err == nil && cached != nil {
 		return cached, nil
 	}
@@ -119,18 +147,13 @@ func (s *Service) GetTags(ctx context.Context) ([]TagInfo, error) {
 	}
 
 	// Parse aggregation results
-	tags := []TagInfo{}
-	if aggs, ok := resp.Aggregations["tags"].(map[string]interface{}); ok {
-		if buckets, ok := aggs["buckets"].([]interface{}); ok {
-			for _, bucket := range buckets {
-				b := bucket.(map[string]interface{})
-				tag := TagInfo{
-					Name:  b["key"].(string),
-					Count: int(b["doc_count"].(float64)),
-				}
-				tags = append(tags, tag)
-			}
-		}
+	var agg elasticsearch.TermsAggregation
+	if _, err := resp.Aggregation("tags", &agg); err != nil {
+		return nil, err
+	}
+	tags := make([]TagInfo, 0, len(agg.Buckets))
+	for _, b := range agg.Buckets {
+		tags = append(tags, TagInfo{Name: string(b.Key), Count: b.DocCount})
 	}
 
 	// Cache results
@@ -139,52 +162,13 @@ func (s *Service) GetTags(ctx context.Context) ([]TagInfo, error) {
 	return tags, nil
 }
 
-// Autocomplete provides search suggestions
-func (s *Service) Autocomplete(ctx context.Context, query string, limit int) ([]string, error) {
-	if limit <= 0 || limit > 50 {
-		limit = 10
-	}
-
-	// Use the autocomplete analyzer
-	esQuery := map[string]interface{}{
-		"size": limit,
-		"query": map[string]interface{}{
-			"multi_match": map[string]interface{}{
-				"query": query,
-				"fields": []string{
-					"name.autocomplete^2",
-					"name^1",
-				},
-				"type": "bool_prefix",
-			},
-		},
-		"_source": []string{"name"},
-	}
-
-	resp, err := s.esClient.Search(ctx, esQuery)
-	if err != nil {
-		return nil, err
-	}
-
-	suggestions := []string{}
-	seen := make(map[string]bool)
-
-	for _, hit := range resp.Hits.Hits {
-		name := hit.Source.Name
-		if !seen[name] {
-			seen[name] = true
-			suggestions = append(suggestions, name)
-		}
-	}
-
-	return suggestions, nil
-}
-
-// CategoryInfo represents category metadata
+// CategoryInfo represents category metadata; Count includes services in child categories
 type CategoryInfo struct {
-	Name      string  `json:"name"`
-	Count     int     `json:"count"`
-	AvgRating float64 `json:"avg_rating"`
+	Name        string  `json:"name"`
+	Parent      string  `json:"parent,omitempty"`
+	Count       int     `json:"count"`
+	DirectCount int     `json:"direct_count"`
+	AvgRating   float64 `json:"avg_rating"`
 }
 
 // TagInfo represents tag metadata
@@ -214,7 +198,7 @@ func (s *Service) cacheService(ctx context.Context, key string, service *elastic
 		return err
 	}
 
-	ttl := s.config.Redis.GetCacheTTL("service_details")
+	ttl := s.config.CacheTTL("service_details")
 	return s.redisClient.Set(ctx, key, data, ttl).Err()
 }
 
@@ -238,7 +222,7 @@ func (s *Service) cacheCategories(ctx context.Context, key string, categories []
 		return err
 	}
 
-	ttl := s.config.Redis.GetCacheTTL("categories")
+	ttl := s.config.CacheTTL("categories")
 	return s.redisClient.Set(ctx, key, data, ttl).Err()
 }
 
@@ -262,6 +246,17 @@ func (s *Service) cacheTags(ctx context.Context, key string, tags []TagInfo) err
 		return err
 	}
 
-	ttl := s.config.Redis.GetCacheTTL("tags")
+	ttl := s.config.CacheTTL("tags")
 	return s.redisClient.Set(ctx, key, data, ttl).Err()
 }
+
+// tenantCacheKey suffixes key with the tenant whose catalog ctx is scoped to
+func (s *Service) tenantCacheKey(ctx context.Context, key string) string {
+	if !s.config.Elasticsearch.Tenancy.Enabled {
+		return key
+	}
+	if tenant := elasticsearch.TenantFromContext(ctx); tenant != "" {
+		return key + ":" + tenant
+	}
+	return key
+}
@@ -0,0 +1,348 @@
+package search
+
+import (
+	"context"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+
+	"github.com/org/llm-marketplace/services/discovery/internal/config"
+)
+
+// Sources of intent matches
+const (
+	IntentRule      = "rule"
+	IntentEmbedding = "embedding"
+)
+
+// QueryIntent is what query understanding read from a search query: the
+// filters its phrases stand for, and the text left to match
+type QueryIntent struct {
+	Original string        `json:"original"`
+	Query    string        `json:"query"` // The text searched for once the recognized phrases are removed
+	Matches  []IntentMatch `json:"matches"`
+}
+
+// IntentMatch is a phrase of a query read as a filter
+type IntentMatch struct {
+	Text       string      `json:"text"`
+	Filter     string      `json:"filter"` // The filter set, by its JSON name
+	Value      interface{} `json:"value"`
+	Source     string      `json:"source"`               // rule or embedding
+	Similarity float64     `json:"similarity,omitempty"` // How close an embedding match was, from 0 to 1
+	Applied    bool        `json:"applied"`              // False when the request set the filter itself
+}
+
+var (
+	latencyPhrase = regexp.MustCompile(`\b(?:under|below|less than|within|at most|max)\s*(\d+(?:\.\d+)?)\s*(ms|milliseconds?|s|secs?|seconds?)\b`)
+	pricePhrase   = regexp.MustCompile(`\b(?:under|below|less than|at most|max)\s*(?:\$\s*(\d+(?:\.\d+)?)|(\d*\.\d+))(?:\s*(?:per|/)\s*1k(?:\s*tokens)?)?`)
+	cheapPhrase   = regexp.MustCompile(`\b(?:cheap|cheapest|inexpensive|affordable|budget|low[- ]cost)\b`)
+	gdprPhrase    = regexp.MustCompile(`\bgdpr(?:[- ]compliant)?\b`)
+	hipaaPhrase   = regexp.MustCompile(`\bhipaa(?:[- ]compliant)?\b`)
+	verifiedWord  = regexp.MustCompile(`\bverified\b`)
+
+	// certificationPhrases map phrases to the certification names providers
+	// declare
+	certificationPhrases = map[string]*regexp.Regexp{
+		"SOC2":     regexp.MustCompile(`\bsoc[- ]?2(?:[- ]certified)?\b`),
+		"ISO27001": regexp.MustCompile(`\biso[- ]?27001(?:[- ]certified)?\b`),
+	}
+
+	// stopWords are dropped from what is left of a query that had phrases
+	// recognized, so that "with" and "for" don't match descriptions
+	stopWords = map[string]bool{
+		"a": true, "an": true, "the": true, "and": true, "with": true, "for": true, "that": true,
+		"which": true, "is": true, "are": true, "of": true, "to": true, "in": true, "on": true, "by": true,
+		"service": true, "services": true, "api": true, "apis": true,
+	}
+)
+
+// ParseQuery reads filters out of a query by rule: a price cap from "cheap"
+// or "under $0.002", a latency bound from "under 200ms", compliance from
+// "GDPR compliant", "HIPAA" or "SOC 2", verified providers from "verified",
+// and capabilities from their names and synonyms in cfg. It returns nil when
+// nothing is recognized.
+func ParseQuery(query string, cfg config.QueryUnderstandingConfig) *QueryIntent {
+	intent := &QueryIntent{Original: query}
+	text := strings.ToLower(query)
+
+	// take removes every match of re from text, recording each with the
+	// filter value its submatches give
+	take := func(re *regexp.Regexp, filter string, value func(groups []string) interface{}) {
+		for _, loc := range re.FindAllStringSubmatchIndex(text, -1) {
+			groups := make([]string, len(loc)/2)
+			for i := range groups {
+				if loc[2*i] >= 0 {
+					groups[i] = text[loc[2*i]:loc[2*i+1]]
+				}
+			}
+			v := value(groups)
+			if v == nil {
+				continue
+			}
+			intent.Matches = append(intent.Matches, IntentMatch{Text: strings.TrimSpace(groups[0]), Filter: filter, Value: v, Source: IntentRule})
+			text = text[:loc[0]] + strings.Repeat(" ", loc[1]-loc[0]) + text[loc[1]:]
+		}
+	}
+
+	// Latency first, so that "under 200ms" isn't read as a price
+	take(latencyPhrase, "max_latency_ms", func(g []string) interface{} {
+		n, err := strconv.ParseFloat(g[1], 64)
+		if err != nil {
+			return nil
+		}
+		if strings.HasPrefix(g[2], "s") {
+			n *= 1000
+		}
+		return int(math.Round(n))
+	})
+	take(pricePhrase, "max_price", func(g []string) interface{} {
+		amount := g[1]
+		if amount == "" {
+			amount = g[2]
+		}
+		n, err := strconv.ParseFloat(amount, 64)
+		if err != nil {
+			return nil
+		}
+		return n
+	})
+	if cfg.CheapPrice > 0 {
+		take(cheapPhrase, "max_price", func([]string) interface{} { return cfg.CheapPrice })
+	}
+	take(gdprPhrase, "gdpr_compliant", func([]string) interface{} { return true })
+	take(hipaaPhrase, "hipaa_compliant", func([]string) interface{} { return true })
+	for _, name := range sortedKeys(certificationPhrases) {
+		take(certificationPhrases[name], "certifications", func([]string) interface{} { return name })
+	}
+	take(verifiedWord, "verified_only", func([]string) interface{} { return true })
+	for _, name := range sortedKeys(cfg.Capabilities) {
+		words := append([]string{name}, cfg.Capabilities[name]...)
+		// Longest first, so that "sentiment analysis" wins over "sentiment"
+		sort.SliceStable(words, func(i, j int) bool { return len(words[i]) > len(words[j]) })
+		for i, w := range words {
+			words[i] = regexp.QuoteMeta(strings.ToLower(w))
+		}
+		take(regexp.MustCompile(`\b(?:`+strings.Join(words, "|")+`)\b`), "capabilities", func([]string) interface{} { return name })
+	}
+
+	if len(intent.Matches) == 0 {
+		return nil
+	}
+	intent.Query = strings.Join(remainingWords(text), " ")
+	return intent
+}
+
+// remainingWords are the words of text that aren't stop words
+func remainingWords(text string) []string {
+	var words []string
+	for _, w := range strings.Fields(text) {
+		if !stopWords[strings.Trim(w, ",.;:!?")] {
+			words = append(words, w)
+		}
+	}
+	return words
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// capabilityVectors caches the query model's embedding of each capability
+// name, computed on first use
+type capabilityVectors struct {
+	mu      sync.Mutex
+	names   []string
+	vectors [][]float32
+}
+
+// understand reads the intent of req's query and applies its filters,
+// leaving filters the request set alone. Words no rule recognized are
+// compared with the capability names through the embedding service, within
+// the query embedding budget; without it only rules apply.
+func (s *Service) understand(ctx context.Context, req *SearchRequest) *QueryIntent {
+	cfg := s.config.Search.QueryUnderstanding
+	if !cfg.Enabled || req.Literal || strings.TrimSpace(req.Query) == "" {
+		return nil
+	}
+
+	intent := ParseQuery(req.Query, cfg)
+	words := strings.Fields(strings.ToLower(req.Query))
+	if intent != nil {
+		words = strings.Fields(intent.Query)
+	}
+	if matches := s.matchCapabilities(ctx, words); len(matches) > 0 {
+		if intent == nil {
+			intent = &QueryIntent{Original: req.Query}
+		}
+		matched := map[string]bool{}
+		for _, m := range matches {
+			matched[m.Text] = true
+		}
+		var rest []string
+		for _, w := range remainingWords(strings.Join(words, " ")) {
+			if !matched[w] {
+				rest = append(rest, w)
+			}
+		}
+		intent.Matches = append(intent.Matches, matches...)
+		intent.Query = strings.Join(rest, " ")
+	}
+	if intent == nil {
+		return nil
+	}
+
+	explicit := req.Filters
+	for i := range intent.Matches {
+		intent.Matches[i].Applied = applyIntent(&req.Filters, explicit, intent.Matches[i])
+	}
+	req.Query = intent.Query
+	return intent
+}
+
+// applyIntent sets the filter m stands for, unless explicit, the filters the
+// request came with, set it
+func applyIntent(f *SearchFilters, explicit SearchFilters, m IntentMatch) bool {
+	switch m.Filter {
+	case "max_price":
+		if explicit.MaxPrice > 0 {
+			return false
+		}
+		// The tightest cap wins when a query names several
+		if price := m.Value.(float64); f.MaxPrice == 0 || price < f.MaxPrice {
+			f.MaxPrice = price
+		}
+	case "max_latency_ms":
+		if explicit.MaxLatencyMS > 0 {
+			return false
+		}
+		if latency := m.Value.(int); f.MaxLatencyMS == 0 || latency < f.MaxLatencyMS {
+			f.MaxLatencyMS = latency
+		}
+	case "gdpr_compliant":
+		f.GDPRCompliant = true
+	case "hipaa_compliant":
+		f.HIPAACompliant = true
+	case "verified_only":
+		f.VerifiedOnly = true
+	case "certifications":
+		if len(explicit.Certifications) > 0 {
+			return false
+		}
+		f.Certifications = appendUnique(f.Certifications, m.Value.(string))
+	case "capabilities":
+		if len(explicit.Capabilities) > 0 {
+			return false
+		}
+		f.Capabilities = appendUnique(f.Capabilities, m.Value.(string))
+	default:
+		return false
+	}
+	return true
+}
+
+func appendUnique(values []string, value string) []string {
+	for _, v := range values {
+		if v == value {
+			return values
+		}
+	}
+	return append(values, value)
+}
+
+// matchCapabilities reads words as the capability whose name embeds
+// closest, when the similarity reaches the configured threshold. Short words
+// are skipped.
+func (s *Service) matchCapabilities(ctx context.Context, words []string) []IntentMatch {
+	cfg := s.config.Search.QueryUnderstanding
+	var candidates []string
+	for _, w := range remainingWords(strings.Join(words, " ")) {
+		if len(w) >= 4 {
+			candidates = append(candidates, w)
+		}
+	}
+	if cfg.EmbeddingThreshold <= 0 || len(cfg.Capabilities) == 0 || len(candidates) == 0 {
+		return nil
+	}
+
+	budget := s.config.EmbeddingService.QueryBudget
+	if budget <= 0 {
+		budget = defaultQueryBudget
+	}
+	budgetCtx, cancel := withStageBudget(ctx, s.config.Search.LatencyBudget.EmbeddingShare, budget)
+	defer cancel()
+
+	model := s.config.QueryEmbeddingModel().Model
+	names, capabilities, err := s.capabilityEmbeddings(budgetCtx, model, sortedKeys(cfg.Capabilities))
+	if err != nil {
+		s.logger.Debug("Skipping capability matching", zap.Error(err))
+		return nil
+	}
+	vectors, err := s.embeddingClient.GetEmbeddingsBatch(budgetCtx, model, candidates)
+	if err != nil || len(vectors) != len(candidates) {
+		s.logger.Debug("Skipping capability matching", zap.Error(err))
+		return nil
+	}
+
+	var matches []IntentMatch
+	for i, v := range vectors {
+		best, bestSimilarity := -1, cfg.EmbeddingThreshold
+		for j, c := range capabilities {
+			if similarity := cosine(v, c); similarity >= bestSimilarity {
+				best, bestSimilarity = j, similarity
+			}
+		}
+		if best >= 0 {
+			matches = append(matches, IntentMatch{
+				Text:       candidates[i],
+				Filter:     "capabilities",
+				Value:      names[best],
+				Source:     IntentEmbedding,
+				Similarity: math.Round(bestSimilarity*1000) / 1000,
+			})
+		}
+	}
+	return matches
+}
+
+// capabilityEmbeddings returns the embeddings of names, computing them when
+// the configured capabilities have changed or were never embedded
+func (s *Service) capabilityEmbeddings(ctx context.Context, model string, names []string) ([]string, [][]float32, error) {
+	s.capabilities.mu.Lock()
+	defer s.capabilities.mu.Unlock()
+	if strings.Join(s.capabilities.names, "\x00") == strings.Join(names, "\x00") {
+		return s.capabilities.names, s.capabilities.vectors, nil
+	}
+	vectors, err := s.embeddingClient.GetEmbeddingsBatch(ctx, model, names)
+	if err != nil {
+		return nil, nil, err
+	}
+	s.capabilities.names, s.capabilities.vectors = names, vectors
+	return names, vectors, nil
+}
+
+func cosine(a, b []float32) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(na) * math.Sqrt(nb))
+}
@@ -0,0 +1,135 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/org/llm-marketplace/services/discovery/internal/elasticsearch"
+	"github.com/org/llm-marketplace/services/discovery/internal/entitlement"
+	"go.uber.org/zap"
+)
+
+// StatusTransitionRequest moves a service to a new lifecycle state
+type StatusTransitionRequest struct {
+	Status               string     `json:"status" binding:"required"`
+	Message              string     `json:"message,omitempty"`
+	SunsetAt             *time.Time `json:"sunset_at,omitempty"`
+	ReplacementServiceID string     `json:"replacement_service_id,omitempty"`
+}
+
+// ErrInvalidTransition is returned when a lifecycle transition is not allowed
+var ErrInvalidTransition = errors.New("invalid status transition")
+
+// TransitionStatus validates and applies a lifecycle transition to a service
+func (s *Service) TransitionStatus(ctx context.Context, id string, req *StatusTransitionRequest) (*elasticsearch.ServiceDocument, error) {
+	service, err := s.esClient.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	caller := entitlement.FromContext(ctx)
+	if !caller.CanView(service.Access) {
+		return nil, elasticsearch.ErrNotFound
+	}
+
+	if err := elasticsearch.ValidateTransition(service.Status, req.Status); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidTransition, err)
+	}
+
+	if req.ReplacementServiceID != "" {
+		if req.ReplacementServiceID == id {
+			return nil, fmt.Errorf("%w: service cannot replace itself", ErrInvalidTransition)
+		}
+		replacement, err := s.esClient.Get(ctx, req.ReplacementServiceID)
+		if err != nil || !caller.CanView(replacement.Access) {
+			return nil, fmt.Errorf("%w: replacement service not found: %s", ErrInvalidTransition, req.ReplacementServiceID)
+		}
+		if replacement.Status == elasticsearch.StatusRetired {
+			return nil, fmt.Errorf("%w: replacement service %s is retired", ErrInvalidTransition, req.ReplacementServiceID)
+		}
+	}
+
+	previous := *service
+	if service.Deprecation != nil {
+		deprecation := *service.Deprecation
+		previous.Deprecation = &deprecation
+	}
+
+	now := time.Now().UTC()
+
+	switch req.Status {
+	case elasticsearch.StatusDeprecated, elasticsearch.StatusRetired:
+		deprecation := service.Deprecation
+		if deprecation == nil {
+			deprecation = &elasticsearch.DeprecationInfo{DeprecatedAt: now}
+		}
+		if req.Message != "" {
+			deprecation.Message = req.Message
+		}
+		if req.SunsetAt != nil {
+			deprecation.SunsetAt = req.SunsetAt
+		}
+		if req.ReplacementServiceID != "" {
+			deprecation.ReplacementServiceID = req.ReplacementServiceID
+		}
+		service.Deprecation = deprecation
+	default:
+		service.Deprecation = nil
+	}
+
+	service.Status = req.Status
+	service.UpdatedAt = now
+
+	if err := s.esClient.Index(ctx, service); err != nil {
+		return nil, fmt.Errorf("failed to update service status: %w", err)
+	}
+
+	// Drop the cached copy and cached searches so readers see the new state
+	// immediately; a retired service must not linger in cached results
+	if err := s.redisClient.Del(ctx, fmt.Sprintf("service:%s", id)).Err(); err != nil {
+		s.logger.Warn("Failed to invalidate service cache", zap.String("id", id), zap.Error(err))
+	}
+	s.invalidateSearches(ctx)
+
+	s.logger.Info("Service status changed",
+		zap.String("id", id),
+		zap.String("from", previous.Status),
+		zap.String("to", service.Status),
+	)
+
+	// Retiring or restoring a version can change which one is latest
+	if service.Version != nil {
+		if err := s.refreshLatestVersion(ctx, service.Key()); err != nil {
+			s.logger.Warn("Failed to refresh latest version", zap.String("id", id), zap.Error(err))
+		}
+	}
+
+	s.notifyChange(ctx, &previous, service)
+
+	return service, nil
+}
+
+// buildDeprecationBanner returns the banner for a deprecated service, or nil
+func buildDeprecationBanner(svc *elasticsearch.ServiceDocument) *DeprecationBanner {
+	if svc.Status != elasticsearch.StatusDeprecated {
+		return nil
+	}
+
+	banner := &DeprecationBanner{
+		Message: "This service is deprecated",
+	}
+
+	if svc.Deprecation != nil {
+		if svc.Deprecation.Message != "" {
+			banner.Message = svc.Deprecation.Message
+		}
+		banner.SunsetAt = svc.Deprecation.SunsetAt
+		if svc.Deprecation.ReplacementServiceID != "" {
+			banner.ReplacementService = svc.Deprecation.ReplacementServiceID
+			banner.ReplacementURL = "/api/v1/services/" + svc.Deprecation.ReplacementServiceID
+		}
+	}
+
+	return banner
+}
@@ -0,0 +1,34 @@
+package search
+
+import (
+	"context"
+
+	"github.com/org/llm-marketplace/services/discovery/internal/elasticsearch"
+)
+
+// ChangeNotifier is told about catalog writes made through the search service
+type ChangeNotifier interface {
+	// ServiceChanged is called after a write; previous is nil for new services
+	ServiceChanged(ctx context.Context, previous, current *elasticsearch.ServiceDocument)
+}
+
+// ChangeNotifiers tells each of several notifiers about every write
+type ChangeNotifiers []ChangeNotifier
+
+// ServiceChanged passes the write on to every notifier in turn
+func (n ChangeNotifiers) ServiceChanged(ctx context.Context, previous, current *elasticsearch.ServiceDocument) {
+	for _, notifier := range n {
+		notifier.ServiceChanged(ctx, previous, current)
+	}
+}
+
+// SetChangeNotifier registers the receiver of catalog change notifications
+func (s *Service) SetChangeNotifier(n ChangeNotifier) {
+	s.notifier = n
+}
+
+func (s *Service) notifyChange(ctx context.Context, previous, current *elasticsearch.ServiceDocument) {
+	if s.notifier != nil {
+		s.notifier.ServiceChanged(ctx, previous, current)
+	}
+}
@@ -0,0 +1,188 @@
+//go:build onnx
+
+package search
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+
+	"github.com/org/llm-marketplace/services/discovery/internal/config"
+	ort "github.com/yalue/onnxruntime_go"
+)
+
+var (
+	ortOnce sync.Once
+	ortErr  error
+)
+
+// onnxEmbedder runs a sentence-transformer exported to ONNX. Token embeddings
+// are mean-pooled over the attention mask and L2-normalized, matching the
+// sentence-transformers pipeline; models exporting a pooled output are used as is.
+type onnxEmbedder struct {
+	session    *ort.DynamicAdvancedSession
+	tokenizer  *wordPieceTokenizer
+	tokenTypes bool // Whether the model takes token_type_ids
+}
+
+func newLocalEmbedder(cfg config.LocalEmbeddingConfig) (LocalEmbedder, error) {
+	tokenizer, err := loadWordPieceTokenizer(cfg.VocabPath, cfg.MaxTokens)
+	if err != nil {
+		return nil, err
+	}
+
+	ortOnce.Do(func() {
+		if cfg.RuntimePath != "" {
+			ort.SetSharedLibraryPath(cfg.RuntimePath)
+		}
+		ortErr = ort.InitializeEnvironment()
+	})
+	if ortErr != nil {
+		return nil, fmt.Errorf("failed to initialize onnxruntime: %w", ortErr)
+	}
+
+	inputs, outputs, err := ort.GetInputOutputInfo(cfg.ModelPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect model: %w", err)
+	}
+	if len(outputs) == 0 {
+		return nil, errors.New("model has no outputs")
+	}
+
+	inputNames := []string{"input_ids", "attention_mask"}
+	tokenTypes := false
+	for _, input := range inputs {
+		if input.Name == "token_type_ids" {
+			tokenTypes = true
+			inputNames = append(inputNames, input.Name)
+		}
+	}
+
+	options, err := ort.NewSessionOptions()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create session options: %w", err)
+	}
+	defer options.Destroy()
+	if cfg.IntraOpThreads > 0 {
+		if err := options.SetIntraOpNumThreads(cfg.IntraOpThreads); err != nil {
+			return nil, fmt.Errorf("failed to set intra-op threads: %w", err)
+		}
+	}
+
+	session, err := ort.NewDynamicAdvancedSession(cfg.ModelPath, inputNames, []string{outputs[0].Name}, options)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load model: %w", err)
+	}
+
+	return &onnxEmbedder{
+		session:    session,
+		tokenizer:  tokenizer,
+		tokenTypes: tokenTypes,
+	}, nil
+}
+
+// Embed runs texts through the model as one padded batch
+func (e *onnxEmbedder) Embed(texts []string) ([][]float32, error) {
+	encoded := make([][]int64, len(texts))
+	seqLen := 0
+	for i, text := range texts {
+		encoded[i] = e.tokenizer.Encode(text)
+		if len(encoded[i]) > seqLen {
+			seqLen = len(encoded[i])
+		}
+	}
+
+	batch := len(texts)
+	ids := make([]int64, batch*seqLen)
+	mask := make([]int64, batch*seqLen)
+	for i, tokens := range encoded {
+		row := ids[i*seqLen : (i+1)*seqLen]
+		for j := range row {
+			row[j] = e.tokenizer.pad
+		}
+		copy(row, tokens)
+		for j := range tokens {
+			mask[i*seqLen+j] = 1
+		}
+	}
+
+	shape := ort.NewShape(int64(batch), int64(seqLen))
+	idsTensor, err := ort.NewTensor(shape, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create input tensor: %w", err)
+	}
+	defer idsTensor.Destroy()
+	maskTensor, err := ort.NewTensor(shape, mask)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create mask tensor: %w", err)
+	}
+	defer maskTensor.Destroy()
+
+	inputs := []ort.Value{idsTensor, maskTensor}
+	if e.tokenTypes {
+		typesTensor, err := ort.NewTensor(shape, make([]int64, batch*seqLen))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create token type tensor: %w", err)
+		}
+		defer typesTensor.Destroy()
+		inputs = append(inputs, typesTensor)
+	}
+
+	// A nil output is allocated by onnxruntime with the shape the model produces
+	outputs := []ort.Value{nil}
+	if err := e.session.Run(inputs, outputs); err != nil {
+		return nil, fmt.Errorf("inference failed: %w", err)
+	}
+	defer outputs[0].Destroy()
+
+	output, ok := outputs[0].(*ort.Tensor[float32])
+	if !ok {
+		return nil, errors.New("model output is not a float32 tensor")
+	}
+	data := output.GetData()
+	dims := output.GetShape()
+
+	vectors := make([][]float32, batch)
+	switch len(dims) {
+	case 2: // Already pooled: [batch, hidden]
+		hidden := int(dims[1])
+		for i := range vectors {
+			vectors[i] = normalize(append([]float32(nil), data[i*hidden:(i+1)*hidden]...))
+		}
+	case 3: // Token embeddings: [batch, seq, hidden]
+		hidden := int(dims[2])
+		for i := range vectors {
+			pooled := make([]float32, hidden)
+			for j := 0; j < len(encoded[i]); j++ {
+				token := data[(i*seqLen+j)*hidden : (i*seqLen+j+1)*hidden]
+				for k, v := range token {
+					pooled[k] += v
+				}
+			}
+			for k := range pooled {
+				pooled[k] /= float32(len(encoded[i]))
+			}
+			vectors[i] = normalize(pooled)
+		}
+	default:
+		return nil, fmt.Errorf("unexpected model output shape %v", dims)
+	}
+
+	return vectors, nil
+}
+
+func normalize(v []float32) []float32 {
+	var sum float64
+	for _, x := range v {
+		sum += float64(x) * float64(x)
+	}
+	if sum == 0 {
+		return v
+	}
+	norm := float32(math.Sqrt(sum))
+	for i := range v {
+		v[i] /= norm
+	}
+	return v
+}
@@ -0,0 +1,14 @@
+//go:build !onnx
+
+package search
+
+import (
+	"errors"
+
+	"github.com/org/llm-marketplace/services/discovery/internal/config"
+)
+
+// newLocalEmbedder reports that local embeddings need a build with -tags onnx
+func newLocalEmbedder(cfg config.LocalEmbeddingConfig) (LocalEmbedder, error) {
+	return nil, errors.New("local embedding backend requires building with -tags onnx")
+}
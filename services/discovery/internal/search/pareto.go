@@ -0,0 +1,269 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/org/llm-marketplace/services/discovery/internal/analytics"
+	"github.com/org/llm-marketplace/services/discovery/internal/elasticsearch"
+	"github.com/org/llm-marketplace/services/discovery/internal/entitlement"
+)
+
+// ErrInvalidPareto is returned for a Pareto search without a task category
+// or with a quality threshold outside 0 to 1
+var ErrInvalidPareto = errors.New("invalid pareto search")
+
+// ErrInvalidBenchmark is returned for a benchmark quality outside 0 to 1
+var ErrInvalidBenchmark = errors.New("invalid benchmark")
+
+// ParetoRequest asks for the services of a task category that no other
+// service beats on price, benchmark quality and latency at once
+type ParetoRequest struct {
+	Category   string  `json:"category"`
+	MinQuality float64 `json:"min_quality,omitempty"` // Benchmark quality from 0 to 1; services below it are left out
+}
+
+// ParetoPoint is where a service sits on the three objectives
+type ParetoPoint struct {
+	Price     float64 `json:"price"`      // The headline pricing rate
+	Quality   float64 `json:"quality"`    // Benchmark quality in the category
+	LatencyMS float64 `json:"latency_ms"` // Measured average latency, or the SLA's maximum before any is measured
+}
+
+// ParetoSummary explains a Pareto search: how many candidates were
+// considered, and why each one off the frontier was left out
+type ParetoSummary struct {
+	Category   string             `json:"category"`
+	MinQuality float64            `json:"min_quality"`
+	Candidates int                `json:"candidates"`
+	Excluded   int                `json:"excluded"` // Without a benchmark in the category, or below min_quality
+	Dominated  []DominatedService `json:"dominated"`
+}
+
+// DominatedService is a candidate some frontier service beats: no worse on
+// every objective and better on at least one
+type DominatedService struct {
+	ServiceID   string      `json:"service_id"`
+	Name        string      `json:"name"`
+	Point       ParetoPoint `json:"point"`
+	DominatedBy []string    `json:"dominated_by"` // Frontier services that beat it, cheapest first
+	Reason      string      `json:"reason"`
+}
+
+func validatePareto(req *ParetoRequest) error {
+	switch {
+	case strings.TrimSpace(req.Category) == "":
+		return fmt.Errorf("%w: category is required", ErrInvalidPareto)
+	case req.MinQuality < 0 || req.MinQuality > 1:
+		return fmt.Errorf("%w: min_quality must be between 0 and 1", ErrInvalidPareto)
+	}
+	return nil
+}
+
+// searchPareto serves Pareto searches. Up to search.max_results candidates
+// in the category are fetched and ranked as usual, then reduced to their
+// frontier, cheapest first. Pagination doesn't apply.
+func (s *Service) searchPareto(ctx context.Context, req *SearchRequest, cacheKey string, startTime time.Time) (*SearchResponse, error) {
+	candidates := *req
+	candidates.Filters.Categories = []string{req.Pareto.Category}
+	candidates.Pagination = PaginationRequest{PageSize: s.config.Search.MaxResults}
+	if len(req.Fields) > 0 {
+		candidates.Fields = append(append([]string{}, req.Fields...), "pricing")
+	}
+
+	esQuery, err := s.buildSearchQuery(ctx, &candidates)
+	if err != nil {
+		s.logger.Error("Failed to build search query", zap.Error(err))
+		return nil, fmt.Errorf("failed to build query: %w", err)
+	}
+
+	s.boundQuery(ctx, esQuery)
+	esResponse, err := s.esClient.Search(ctx, esQuery)
+	if err != nil {
+		s.logger.Error("Search failed", zap.Error(err))
+		s.metrics.SearchError()
+		return nil, fmt.Errorf("search failed: %w", err)
+	}
+	partial, err := s.partialResults(esResponse)
+	if err != nil {
+		return nil, err
+	}
+
+	results := s.rankResults(s.processSearchResults(ctx, esResponse, req), esResponse.Hits.MaxScore, s.rankingWeights(req))
+	frontier, summary := paretoFrontier(results, req.Pareto)
+
+	response := &SearchResponse{
+		Results:  frontier,
+		Total:    len(frontier),
+		PageSize: len(frontier),
+		Took:     esResponse.Took,
+		Pareto:   summary,
+		Partial:  partial,
+	}
+
+	if !partial {
+		if err := s.cacheResults(ctx, cacheKey, response, "search_results"); err != nil {
+			s.logger.Warn("Failed to cache results", zap.Error(err))
+		}
+	}
+
+	s.markSubscribed(ctx, response.Results)
+
+	duration := time.Since(startTime)
+	s.metrics.SearchDuration(ctx, duration)
+	s.metrics.SearchResults(len(frontier))
+
+	response.QueryID = analytics.NewID()
+	s.trackSearchEvent(req, response, duration, false)
+
+	return response, nil
+}
+
+// paretoPoint places svc on the objectives; ok is false when it has no
+// benchmark in category
+func paretoPoint(svc *elasticsearch.ServiceDocument, category string) (ParetoPoint, bool) {
+	quality, ok := svc.Metrics.Quality[category]
+	if !ok {
+		quality, ok = svc.Metrics.Quality[svc.Category]
+	}
+	latency := svc.Metrics.AvgLatencyMS
+	if latency == 0 {
+		latency = float64(svc.SLA.MaxLatencyMS)
+	}
+	return ParetoPoint{Price: svc.Pricing.Rate, Quality: quality, LatencyMS: latency}, ok
+}
+
+// dominates reports whether a is no worse than b on every objective and
+// better on at least one
+func (a ParetoPoint) dominates(b ParetoPoint) bool {
+	if a.Price > b.Price || a.Quality < b.Quality || a.LatencyMS > b.LatencyMS {
+		return false
+	}
+	return a.Price < b.Price || a.Quality > b.Quality || a.LatencyMS < b.LatencyMS
+}
+
+// paretoFrontier keeps the ranked results no other candidate dominates,
+// cheapest first, and explains the rest
+func paretoFrontier(results []SearchResult, req *ParetoRequest) ([]SearchResult, *ParetoSummary) {
+	summary := &ParetoSummary{
+		Category:   req.Category,
+		MinQuality: req.MinQuality,
+		Candidates: len(results),
+		Dominated:  []DominatedService{},
+	}
+
+	var eligible []SearchResult
+	for _, r := range results {
+		point, ok := paretoPoint(r.Service, req.Category)
+		if !ok || point.Quality < req.MinQuality {
+			summary.Excluded++
+			continue
+		}
+		r.Pareto = &point
+		eligible = append(eligible, r)
+	}
+
+	frontier := []SearchResult{}
+	var dominated []SearchResult
+	for i, r := range eligible {
+		beaten := false
+		for j, other := range eligible {
+			if i != j && other.Pareto.dominates(*r.Pareto) {
+				beaten = true
+				break
+			}
+		}
+		if beaten {
+			dominated = append(dominated, r)
+		} else {
+			frontier = append(frontier, r)
+		}
+	}
+	sort.SliceStable(frontier, func(i, j int) bool {
+		a, b := frontier[i].Pareto, frontier[j].Pareto
+		if a.Price != b.Price {
+			return a.Price < b.Price
+		}
+		return a.Quality > b.Quality
+	})
+
+	// Every dominated candidate is beaten by a frontier service, since
+	// dominance is transitive
+	for _, r := range dominated {
+		entry := DominatedService{ServiceID: r.Service.ID, Name: r.Service.Name, Point: *r.Pareto}
+		var by *SearchResult
+		for k := range frontier {
+			if frontier[k].Pareto.dominates(*r.Pareto) {
+				entry.DominatedBy = append(entry.DominatedBy, frontier[k].Service.ID)
+				if by == nil {
+					by = &frontier[k]
+				}
+			}
+		}
+		entry.Reason = dominationReason(by, r.Pareto)
+		summary.Dominated = append(summary.Dominated, entry)
+	}
+	return frontier, summary
+}
+
+// dominationReason names the cheapest frontier service beating p and the
+// objectives it is better on, e.g. "Dominated by Acme Chat: cheaper (0.002
+// vs 0.003), faster (400 vs 900 ms)"
+func dominationReason(by *SearchResult, p *ParetoPoint) string {
+	if by == nil {
+		return ""
+	}
+	b := by.Pareto
+	var better []string
+	if b.Price < p.Price {
+		better = append(better, fmt.Sprintf("cheaper (%g vs %g)", b.Price, p.Price))
+	}
+	if b.Quality > p.Quality {
+		better = append(better, fmt.Sprintf("higher quality (%g vs %g)", b.Quality, p.Quality))
+	}
+	if b.LatencyMS < p.LatencyMS {
+		better = append(better, fmt.Sprintf("faster (%g vs %g ms)", b.LatencyMS, p.LatencyMS))
+	}
+	return fmt.Sprintf("Dominated by %s: %s", by.Service.Name, strings.Join(better, ", "))
+}
+
+// BenchmarkRequest records a service's benchmark quality in a task category
+type BenchmarkRequest struct {
+	Category string   `json:"category" binding:"required"`
+	Quality  *float64 `json:"quality" binding:"required"` // From 0 to 1
+}
+
+// SetBenchmark records a service's benchmark quality in a category, for
+// Pareto searches. Other categories' scores are kept.
+func (s *Service) SetBenchmark(ctx context.Context, id string, req *BenchmarkRequest) (*elasticsearch.ServiceDocument, error) {
+	if *req.Quality < 0 || *req.Quality > 1 {
+		return nil, fmt.Errorf("%w: quality must be between 0 and 1", ErrInvalidBenchmark)
+	}
+	service, err := s.esClient.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !entitlement.FromContext(ctx).CanView(service.Access) {
+		return nil, elasticsearch.ErrNotFound
+	}
+
+	err = s.esClient.UpdateFields(ctx, id, map[string]interface{}{
+		"metrics": map[string]interface{}{
+			"quality": map[string]interface{}{req.Category: *req.Quality},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to record benchmark: %w", err)
+	}
+	if service.Metrics.Quality == nil {
+		service.Metrics.Quality = map[string]float64{}
+	}
+	service.Metrics.Quality[req.Category] = *req.Quality
+	return service, nil
+}
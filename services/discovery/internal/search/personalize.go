@@ -0,0 +1,54 @@
+package search
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+
+	"github.com/org/llm-marketplace/services/discovery/internal/features"
+)
+
+// SetFeatures registers the feature store ranking reads the user's and the
+// results' features from
+func (s *Service) SetFeatures(f *features.Store) {
+	s.features = f
+}
+
+// personalize adjusts ranked results by stored features: the user's
+// affinity with each result's category and their price sensitivity, and
+// each result's provider trust. Results are left as ranked when the store
+// is disabled or the features can't be read.
+func (s *Service) personalize(ctx context.Context, userID string, results []SearchResult) {
+	if !s.features.Enabled() || len(results) == 0 {
+		return
+	}
+	user, err := s.features.User(ctx, userID)
+	if err != nil {
+		s.logger.Warn("Failed to load user features", zap.Error(err))
+		return
+	}
+	ids := make([]string, 0, len(results))
+	for _, result := range results {
+		if result.Service != nil {
+			ids = append(ids, result.Service.ID)
+		}
+	}
+	services, err := s.features.Services(ctx, ids)
+	if err != nil {
+		s.logger.Warn("Failed to load service features", zap.Error(err))
+		return
+	}
+
+	weights := s.features.Weights()
+	for i := range results {
+		svc := results[i].Service
+		if svc == nil {
+			continue
+		}
+		if factor := weights.Factor(user, svc.Category, services[svc.ID]); factor != 1 {
+			results[i].Score *= factor
+			results[i].MatchDetails.FeatureFactor = factor
+		}
+	}
+	sortByScore(results)
+}
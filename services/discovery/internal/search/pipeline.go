@@ -0,0 +1,217 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/org/llm-marketplace/services/discovery/internal/config"
+)
+
+// QueryStage is a step of the query pipeline. Rewrite returns the query to
+// pass on; on error the query is passed on unchanged.
+type QueryStage interface {
+	Name() string
+	Rewrite(ctx context.Context, query string) (string, error)
+}
+
+// QueryRewrite is what the query pipeline did to a query: the query searched
+// and each stage that changed it
+type QueryRewrite struct {
+	Query string        `json:"query"`
+	Steps []RewriteStep `json:"steps"`
+}
+
+// RewriteStep is the query a stage passed on
+type RewriteStep struct {
+	Stage string `json:"stage"`
+	Query string `json:"query"`
+}
+
+// Names of the built-in query stages
+const (
+	StageLowercase  = "lowercase"
+	StageStopwords  = "stopwords"
+	StageAcronyms   = "acronyms"
+	StageSynonyms   = "synonyms"
+	StageLLMRewrite = "llm_rewrite"
+)
+
+// queryStages returns the stages enabled in cfg, in pipeline order
+func queryStages(cfg config.QueryPipelineConfig, assistant *assistantClient) []QueryStage {
+	var stages []QueryStage
+	if cfg.Lowercase {
+		stages = append(stages, lowercaseStage{})
+	}
+	if cfg.RemoveStopwords && len(cfg.Stopwords) > 0 {
+		words := make(map[string]bool, len(cfg.Stopwords))
+		for _, w := range cfg.Stopwords {
+			words[strings.ToLower(w)] = true
+		}
+		stages = append(stages, stopwordStage{words: words})
+	}
+	if cfg.ExpandAcronyms && len(cfg.Acronyms) > 0 {
+		stages = append(stages, acronymStage{expansions: lowerKeys(cfg.Acronyms)})
+	}
+	if cfg.InjectSynonyms && len(cfg.Synonyms) > 0 {
+		stages = append(stages, synonymStage{synonyms: lowerKeys(cfg.Synonyms)})
+	}
+	if cfg.LLMRewrite {
+		stages = append(stages, llmRewriteStage{assistant: assistant, timeout: cfg.LLMRewriteTimeout})
+	}
+	return stages
+}
+
+func lowerKeys[V any](m map[string]V) map[string]V {
+	lowered := make(map[string]V, len(m))
+	for k, v := range m {
+		lowered[strings.ToLower(k)] = v
+	}
+	return lowered
+}
+
+// AddQueryStage appends a stage to the query pipeline, after the built-in
+// ones. Call it before the service starts serving.
+func (s *Service) AddQueryStage(stage QueryStage) {
+	s.queryStages = append(s.queryStages, stage)
+}
+
+// rewriteQuery runs req's query through the pipeline and searches the
+// result. It returns nil when no stage changed the query.
+func (s *Service) rewriteQuery(ctx context.Context, req *SearchRequest) *QueryRewrite {
+	if req.Literal || strings.TrimSpace(req.Query) == "" || len(s.queryStages) == 0 {
+		return nil
+	}
+
+	rewrite := &QueryRewrite{Query: req.Query, Steps: []RewriteStep{}}
+	for _, stage := range s.queryStages {
+		start := time.Now()
+		query, err := stage.Rewrite(ctx, rewrite.Query)
+		query = strings.Join(strings.Fields(query), " ")
+		switch {
+		case err != nil:
+			s.logger.Debug("Query stage failed", zap.String("stage", stage.Name()), zap.Error(err))
+			s.metrics.QueryRewrite(stage.Name(), "error", time.Since(start))
+		case query == "" || query == rewrite.Query:
+			s.metrics.QueryRewrite(stage.Name(), "unchanged", time.Since(start))
+		default:
+			s.metrics.QueryRewrite(stage.Name(), "changed", time.Since(start))
+			rewrite.Query = query
+			rewrite.Steps = append(rewrite.Steps, RewriteStep{Stage: stage.Name(), Query: query})
+		}
+	}
+	if len(rewrite.Steps) == 0 {
+		return nil
+	}
+	req.Query = rewrite.Query
+	return rewrite
+}
+
+type lowercaseStage struct{}
+
+func (lowercaseStage) Name() string { return StageLowercase }
+
+func (lowercaseStage) Rewrite(_ context.Context, query string) (string, error) {
+	return strings.ToLower(query), nil
+}
+
+// stopwordStage drops stopwords, unless the query has nothing else
+type stopwordStage struct {
+	words map[string]bool
+}
+
+func (stopwordStage) Name() string { return StageStopwords }
+
+func (st stopwordStage) Rewrite(_ context.Context, query string) (string, error) {
+	var kept []string
+	for _, w := range strings.Fields(query) {
+		if !st.words[strings.ToLower(w)] {
+			kept = append(kept, w)
+		}
+	}
+	if len(kept) == 0 {
+		return query, nil
+	}
+	return strings.Join(kept, " "), nil
+}
+
+// acronymStage follows each known acronym with its expansion
+type acronymStage struct {
+	expansions map[string]string
+}
+
+func (acronymStage) Name() string { return StageAcronyms }
+
+func (st acronymStage) Rewrite(_ context.Context, query string) (string, error) {
+	words := strings.Fields(query)
+	out := make([]string, 0, len(words))
+	for _, w := range words {
+		out = append(out, w)
+		if expansion, ok := st.expansions[strings.ToLower(strings.Trim(w, ",.;:!?"))]; ok && !strings.Contains(strings.ToLower(query), strings.ToLower(expansion)) {
+			out = append(out, expansion)
+		}
+	}
+	return strings.Join(out, " "), nil
+}
+
+// synonymStage appends the synonyms of the query's words that it lacks
+type synonymStage struct {
+	synonyms map[string][]string
+}
+
+func (synonymStage) Name() string { return StageSynonyms }
+
+func (st synonymStage) Rewrite(_ context.Context, query string) (string, error) {
+	words := strings.Fields(query)
+	present := make(map[string]bool, len(words))
+	for _, w := range words {
+		present[strings.ToLower(w)] = true
+	}
+	out := words
+	for _, w := range words {
+		for _, synonym := range st.synonyms[strings.ToLower(w)] {
+			if !present[strings.ToLower(synonym)] {
+				present[strings.ToLower(synonym)] = true
+				out = append(out, synonym)
+			}
+		}
+	}
+	return strings.Join(out, " "), nil
+}
+
+// maxRewrittenQuery bounds what the model may return, so a runaway reply
+// isn't searched
+const maxRewrittenQuery = 200
+
+const rewriteInstructions = `You rewrite search queries for a catalog of AI services.
+Restate the query as short search keywords naming the task, modality and requirements. Keep product and provider names.
+Reply with the rewritten query only, on one line.`
+
+// llmRewriteStage has the assistant model restate the query as keywords,
+// within timeout
+type llmRewriteStage struct {
+	assistant *assistantClient
+	timeout   time.Duration
+}
+
+func (llmRewriteStage) Name() string { return StageLLMRewrite }
+
+func (st llmRewriteStage) Rewrite(ctx context.Context, query string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, st.timeout)
+	defer cancel()
+	reply, err := st.assistant.complete(ctx, []chatMessage{
+		{Role: "system", Content: rewriteInstructions},
+		{Role: "user", Content: query},
+	})
+	if err != nil {
+		return "", err
+	}
+	reply = strings.Trim(strings.SplitN(reply, "\n", 2)[0], " \"'`")
+	if reply == "" || len(reply) > maxRewrittenQuery {
+		return "", errors.New("unusable rewrite")
+	}
+	return reply, nil
+}
@@ -0,0 +1,40 @@
+package search
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/org/llm-marketplace/pkg/marketplace"
+	"github.com/org/llm-marketplace/services/discovery/internal/elasticsearch"
+	"github.com/org/llm-marketplace/services/discovery/internal/elasticsearch/query"
+)
+
+// ErrInvalidKind is returned for a search filtering on an unknown kind of
+// listing
+var ErrInvalidKind = errors.New("invalid kind")
+
+// filterKind restricts a search to the kind of listing and the model
+// families of prompt templates the filters ask for. Documents indexed before
+// other kinds were listed have no kind and are services.
+func filterKind(boolQuery *query.BoolQuery, f SearchFilters) error {
+	switch f.Kind {
+	case "":
+	case marketplace.KindService:
+		boolQuery.MustNot(query.Terms("kind", []string{marketplace.KindPromptTemplate, marketplace.KindDataset, marketplace.KindFineTuning}))
+	case marketplace.KindPromptTemplate, marketplace.KindDataset, marketplace.KindFineTuning:
+		boolQuery.Filter(query.Term("kind", f.Kind))
+	default:
+		return fmt.Errorf("%w: must be one of %s", ErrInvalidKind, strings.Join(marketplace.Kinds, ", "))
+	}
+
+	// Prompt templates written for any one of the model families
+	if len(f.ModelFamilies) > 0 {
+		families := make([]string, len(f.ModelFamilies))
+		for i, family := range f.ModelFamilies {
+			families[i] = strings.ToLower(strings.TrimSpace(family))
+		}
+		boolQuery.Filter(query.Terms(elasticsearch.ModelFamiliesField, families))
+	}
+	return nil
+}
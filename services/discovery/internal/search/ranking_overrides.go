@@ -0,0 +1,45 @@
+package search
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/org/llm-marketplace/services/discovery/internal/config"
+)
+
+// ErrInvalidRankingWeights is returned for ranking weight overrides that are
+// negative or don't sum to 1.0
+var ErrInvalidRankingWeights = errors.New("invalid ranking weights")
+
+// AllowsRankingOverride reports whether the operator operatorID, or a caller
+// of tenantID, may rank its searches by its own weights. Both must come from
+// the gateway's identity headers, which callers can't set.
+func (s *Service) AllowsRankingOverride(operatorID, tenantID string) bool {
+	o := s.config.Search.RankingOverrides
+	if !o.Enabled {
+		return false
+	}
+	return operatorID != "" || (tenantID != "" && slices.Contains(o.Tenants, tenantID))
+}
+
+// validateRankingWeights checks an override as the configured weights are
+// checked at startup
+func validateRankingWeights(w *config.RankingWeights) error {
+	if w.Relevance < 0 || w.Popularity < 0 || w.Performance < 0 || w.Compliance < 0 {
+		return fmt.Errorf("%w: weights can't be negative", ErrInvalidRankingWeights)
+	}
+	if sum := w.Sum(); sum < 0.99 || sum > 1.01 {
+		return fmt.Errorf("%w: weights must sum to 1.0, got %.2f", ErrInvalidRankingWeights, sum)
+	}
+	return nil
+}
+
+// rankingWeights returns the weights req is ranked by: its override, or the
+// configured weights in effect
+func (s *Service) rankingWeights(req *SearchRequest) config.RankingWeights {
+	if req.RankingWeights != nil {
+		return *req.RankingWeights
+	}
+	return s.config.RankingWeights()
+}
@@ -0,0 +1,50 @@
+package search
+
+import (
+	"math"
+	"strings"
+
+	"github.com/org/llm-marketplace/services/discovery/internal/elasticsearch"
+	"github.com/org/llm-marketplace/services/discovery/internal/elasticsearch/query"
+)
+
+// filterServingRegion restricts a search to services served from the
+// region, for workloads that can't leave it
+func filterServingRegion(boolQuery *query.BoolQuery, f SearchFilters) {
+	if region := strings.ToLower(strings.TrimSpace(f.ServingRegion)); region != "" {
+		boolQuery.Filter(query.Term(elasticsearch.ServingRegionField, region))
+	}
+}
+
+// rankByRegion adjusts ranked results by their latency from the caller's
+// region: a service served from it gains up to latency_boost, less the
+// nearer its median latency there is to reference_latency. Services not
+// served from the region are left as ranked.
+func (s *Service) rankByRegion(region string, results []SearchResult) {
+	cfg := s.config.Search.RegionRanking
+	if !cfg.Enabled || region == "" || len(results) == 0 {
+		return
+	}
+
+	reference := float64(cfg.ReferenceLatency.Milliseconds())
+	ranked := false
+	for i := range results {
+		svc := results[i].Service
+		if svc == nil {
+			continue
+		}
+		latency, ok := svc.RegionLatency(region)
+		if !ok {
+			continue
+		}
+		factor := 1 + cfg.LatencyBoost*(1-math.Min(float64(latency)/reference, 1))
+		if factor != 1 {
+			results[i].Score *= factor
+			results[i].MatchDetails.RegionFactor = factor
+			ranked = true
+		}
+	}
+	if ranked {
+		sortByScore(results)
+	}
+}
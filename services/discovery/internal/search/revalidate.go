@@ -0,0 +1,59 @@
+package search
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/org/llm-marketplace/services/discovery/internal/redis"
+)
+
+// revalidateLockPrefix marks the search cache entry a replica is refreshing
+const revalidateLockPrefix = "revalidating:"
+
+// cachedResponse is a search response as cached, with when it goes stale.
+// Entries are kept for stale_while_revalidate.stale_ttl past that.
+type cachedResponse struct {
+	Response   *SearchResponse `json:"response"`
+	FreshUntil time.Time       `json:"fresh_until"`
+}
+
+// revalidate refreshes the stale response cached under cacheKey in the
+// background, running req again past the cache. A lock in Redis keeps
+// replicas serving the same entry from refreshing it together; it expires
+// after refresh_timeout should a replica stop mid-refresh.
+func (s *Service) revalidate(ctx context.Context, req *SearchRequest, cacheKey string) {
+	cfg := s.config.Search.StaleWhileRevalidate
+	refresh := *req
+	refresh.background = true
+	// The caller's entitlements and trace, without its cancellation
+	caller := context.WithoutCancel(ctx)
+
+	s.workers.Task("search_revalidate", func(taskCtx context.Context) {
+		ctx, cancel := context.WithTimeout(caller, cfg.RefreshTimeout)
+		defer cancel()
+		defer context.AfterFunc(taskCtx, cancel)()
+
+		lock := revalidateLockPrefix + cacheKey
+		token, err := redis.LockToken()
+		if err != nil {
+			s.metrics.CacheRevalidation("skipped")
+			return
+		}
+		acquired, err := s.redisClient.SetNX(ctx, lock, token, cfg.RefreshTimeout).Result()
+		if err != nil || !acquired {
+			s.metrics.CacheRevalidation("skipped")
+			return
+		}
+		// A refresh that overran the lock mustn't release another replica's
+		defer redis.ReleaseLock(context.WithoutCancel(ctx), s.redisClient, lock, token)
+
+		if _, err := s.search(ctx, &refresh); err != nil {
+			s.logger.Warn("Failed to refresh stale search results", zap.String("key", cacheKey), zap.Error(err))
+			s.metrics.CacheRevalidation("failed")
+			return
+		}
+		s.metrics.CacheRevalidation("refreshed")
+	})
+}
@@ -0,0 +1,72 @@
+package search
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/org/llm-marketplace/services/discovery/internal/elasticsearch"
+	"github.com/org/llm-marketplace/services/discovery/internal/entitlement"
+)
+
+// scanBatchSize is the page size used when walking results with search_after
+const scanBatchSize = 500
+
+// Scan walks every document matching req in relevance order, up to limit, using
+// search_after so deep result sets are not bounded by from/size. Pagination on
+// req is ignored and results are not re-ranked.
+func (s *Service) Scan(
+	ctx context.Context,
+	req *SearchRequest,
+	limit int,
+	fn func(doc *elasticsearch.ServiceDocument) error,
+) (int, error) {
+	// Read and rewritten as Search would, so exports match search results
+	scan := *req
+	s.understand(ctx, &scan)
+	s.rewriteQuery(ctx, &scan)
+
+	esQuery, err := s.buildSearchQuery(ctx, &scan)
+	if err != nil {
+		return 0, fmt.Errorf("failed to build query: %w", err)
+	}
+
+	delete(esQuery, "from")
+	delete(esQuery, "aggs")
+	esQuery["sort"] = []interface{}{
+		map[string]interface{}{"_score": "desc"},
+		map[string]interface{}{"id": "asc"},
+	}
+
+	caller := entitlement.FromContext(ctx)
+	count := 0
+	for count < limit {
+		size := scanBatchSize
+		if remaining := limit - count; remaining < size {
+			size = remaining
+		}
+		esQuery["size"] = size
+
+		esResponse, err := s.esClient.Search(ctx, esQuery)
+		if err != nil {
+			return count, fmt.Errorf("scan failed: %w", err)
+		}
+
+		hits := esResponse.Hits.Hits
+		for i := range hits {
+			if !caller.CanView(hits[i].Source.Access) {
+				continue
+			}
+			if err := fn(&hits[i].Source); err != nil {
+				return count, err
+			}
+			count++
+		}
+
+		if len(hits) < size {
+			break
+		}
+		esQuery["search_after"] = hits[len(hits)-1].Sort
+	}
+
+	return count, nil
+}
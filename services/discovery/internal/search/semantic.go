@@ -0,0 +1,99 @@
+package search
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"go.uber.org/zap"
+)
+
+// defaultQueryBudget applies when embedding_service.query_budget is unset
+const defaultQueryBudget = 150 * time.Millisecond
+
+// queryEmbedding returns the query model's embedding for a search query from the
+// cache or the embedding service. It gives up once the query budget is spent and returns
+// nil, so the search runs without its semantic clause instead of waiting.
+func (s *Service) queryEmbedding(ctx context.Context, query string) []float32 {
+	start := time.Now()
+	budget := s.config.EmbeddingService.QueryBudget
+	if budget <= 0 {
+		budget = defaultQueryBudget
+	}
+	budgetCtx, cancel := withStageBudget(ctx, s.config.Search.LatencyBudget.EmbeddingShare, budget)
+	defer cancel()
+
+	model := s.config.QueryEmbeddingModel()
+	key := queryEmbeddingKey(embeddingModelID(model), query)
+	if data, err := s.redisClient.Get(budgetCtx, key).Bytes(); err == nil {
+		if vector, ok := decodeVector(data); ok {
+			s.metrics.QueryEmbedding("hit", time.Since(start))
+			return vector
+		}
+	} else if err != redis.Nil {
+		s.logger.Debug("Query embedding cache lookup failed", zap.Error(err))
+	}
+
+	vector, err := s.embeddingClient.GetEmbedding(budgetCtx, model.Model, query)
+	if err == nil && len(vector) != model.Dimensions {
+		err = fmt.Errorf("%w: got %d, want %d", ErrDimensionMismatch, len(vector), model.Dimensions)
+	}
+	if err != nil {
+		result := "error"
+		if errors.Is(budgetCtx.Err(), context.DeadlineExceeded) {
+			result = "timeout"
+		}
+		s.budgetExceeded(budgetCtx, stageEmbedding)
+		s.metrics.QueryEmbedding(result, time.Since(start))
+		s.logger.Warn("Skipping semantic search",
+			zap.String("result", result),
+			zap.Duration("budget", budget),
+			zap.Error(err),
+		)
+		return nil
+	}
+	s.metrics.QueryEmbedding("miss", time.Since(start))
+
+	// The budget covers the lookup only; caching uses the request context
+	ttl := s.config.CacheTTL("query_embeddings")
+	if err := s.redisClient.Set(ctx, key, encodeVector(vector), ttl).Err(); err != nil {
+		s.logger.Warn("Failed to cache query embedding", zap.Error(err))
+	}
+
+	return vector
+}
+
+// queryEmbeddingKey keys cached embeddings by model and normalized query, so
+// queries differing only in case or spacing share an entry
+func queryEmbeddingKey(model, query string) string {
+	normalized := strings.Join(strings.Fields(strings.ToLower(query)), " ")
+	sum := sha256.Sum256([]byte(normalized))
+	return "query_embedding:" + model + ":" + hex.EncodeToString(sum[:])
+}
+
+// encodeVector packs a vector as little-endian float32s, a quarter the size of JSON
+func encodeVector(vector []float32) []byte {
+	data := make([]byte, 4*len(vector))
+	for i, v := range vector {
+		binary.LittleEndian.PutUint32(data[4*i:], math.Float32bits(v))
+	}
+	return data
+}
+
+func decodeVector(data []byte) ([]float32, bool) {
+	if len(data) == 0 || len(data)%4 != 0 {
+		return nil, false
+	}
+	vector := make([]float32, len(data)/4)
+	for i := range vector {
+		vector[i] = math.Float32frombits(binary.LittleEndian.Uint32(data[4*i:]))
+	}
+	return vector, true
+}
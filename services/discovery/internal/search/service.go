@@ -1,17 +1,27 @@
 package search
 
 import (
+	"cmp"
 	"context"
 	"encoding/json"
 	"fmt"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/go-redis/redis/v8"
+	"github.com/org/llm-marketplace/pkg/marketplace"
+	"github.com/org/llm-marketplace/pkg/marketplace/flags"
+	"github.com/org/llm-marketplace/services/discovery/internal/analytics"
 	"github.com/org/llm-marketplace/services/discovery/internal/config"
 	"github.com/org/llm-marketplace/services/discovery/internal/elasticsearch"
+	"github.com/org/llm-marketplace/services/discovery/internal/elasticsearch/query"
+	"github.com/org/llm-marketplace/services/discovery/internal/entitlement"
+	"github.com/org/llm-marketplace/services/discovery/internal/features"
 	"github.com/org/llm-marketplace/services/discovery/internal/observability"
 	"github.com/org/llm-marketplace/services/discovery/internal/postgres"
+	"github.com/org/llm-marketplace/services/discovery/internal/taxonomy"
+	"github.com/org/llm-marketplace/services/discovery/internal/worker"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
@@ -25,6 +35,19 @@ type Service struct {
 	logger        *zap.Logger
 	metrics       *observability.Metrics
 	embeddingClient *EmbeddingClient
+	embeddingHealth *EmbeddingHealth
+	flags           *flags.Evaluator
+	notifier        ChangeNotifier
+	analytics       *analytics.Producer
+	subscriptions   Subscriptions
+	taxonomy        *taxonomy.Manager
+	workers         *worker.Group
+	facets          map[string]map[string]interface{} // Aggregation per configured facet
+	facetLabels     map[string]map[string]string      // Range bucket labels by facet and key
+	capabilities    capabilityVectors                 // Capability name embeddings for query understanding
+	assistant       *assistantClient
+	queryStages     []QueryStage // Applied to queries before they are searched
+	features        *features.Store
 }
 
 func NewService(
@@ -35,6 +58,14 @@ func NewService(
 	logger *zap.Logger,
 	metrics *observability.Metrics,
 ) *Service {
+	embeddingClient := NewEmbeddingClient(cfg.EmbeddingService, metrics)
+	if err := embeddingClient.LoadLocalModel(); err != nil {
+		// Semantic matching is skipped while no backend can serve embeddings
+		logger.Error("Local embedding backend unavailable", zap.Error(err))
+	}
+
+	assistant := newAssistantClient(cfg.Assistant)
+
 	return &Service{
 		esClient:    esClient,
 		redisClient: redisClient,
@@ -42,16 +73,45 @@ func NewService(
 		config:      cfg,
 		logger:      logger,
 		metrics:     metrics,
-		embeddingClient: NewEmbeddingClient(cfg.EmbeddingService),
+		embeddingClient: embeddingClient,
+		assistant:       assistant,
+		queryStages:     queryStages(cfg.Search.QueryPipeline, assistant),
+		facets:          facetAggregations(cfg.Search.FacetDefinitions()),
+		facetLabels:     facetLabels(cfg.Search.FacetDefinitions()),
 	}
 }
 
 // SearchRequest represents a search query
 type SearchRequest struct {
-	Query      string            `json:"query"`
-	Filters    SearchFilters     `json:"filters"`
-	Pagination PaginationRequest `json:"pagination"`
-	UserID     string            `json:"user_id,omitempty"`
+	Query       string            `json:"query"`
+	Filters     SearchFilters     `json:"filters"`
+	Pagination  PaginationRequest `json:"pagination"`
+	UserID      string            `json:"user_id,omitempty"`
+	Fields      []string          `json:"fields,omitempty"`       // Sparse response; embedding is omitted unless requested
+	Types       []string          `json:"types,omitempty"`        // Entity types to search; services only when empty
+	AllVersions bool              `json:"all_versions,omitempty"` // Include superseded and pre-release versions
+
+	AggregationsOnly bool     `json:"aggregations_only,omitempty"` // Facet counts and total without hits
+	CountOnly        bool     `json:"count_only,omitempty"`        // Total without hits or facets
+	Facets           []string `json:"facets,omitempty"`            // Facets to aggregate; every configured facet when empty
+
+	Pareto *ParetoRequest `json:"pareto,omitempty"` // Return the price, quality and latency frontier of a task category
+
+	Literal bool `json:"literal,omitempty"` // Search the query as written, without reading filters out of it or rewriting it
+
+	SessionID string `json:"session_id,omitempty"` // Browsing session whose clicks and dismissals re-rank the results
+
+	Region string `json:"region,omitempty"` // The caller's region; services served from it rank by their latency there
+
+	Debug bool `json:"debug,omitempty"` // Capture the query, hits and ranking scores; see search.debug_capture
+
+	RankingWeights *config.RankingWeights `json:"ranking_weights,omitempty"` // Rank by these instead of the configured weights; see search.ranking_overrides
+
+	typed   string        // The query as the caller wrote it, while a search runs with another
+	intent  *QueryIntent  // Set while a search runs with filters read out of the query
+	rewrite *QueryRewrite // Set while a search runs with a rewritten query
+
+	background bool // Set on searches the service runs itself, refreshing a stale cached response or warming the cache
 }
 
 // SearchFilters represents multi-dimensional filtering
@@ -59,6 +119,7 @@ type SearchFilters struct {
 	Categories      []string `json:"categories,omitempty"`
 	Tags            []string `json:"tags,omitempty"`
 	MinRating       float64  `json:"min_rating,omitempty"`
+	MinPrice        float64  `json:"min_price,omitempty"`
 	MaxPrice        float64  `json:"max_price,omitempty"`
 	PricingModels   []string `json:"pricing_models,omitempty"`
 	ComplianceLevel string   `json:"compliance_level,omitempty"`
@@ -67,6 +128,19 @@ type SearchFilters struct {
 	VerifiedOnly    bool     `json:"verified_only,omitempty"`
 	Status          string   `json:"status,omitempty"`
 	MinAvailability float64  `json:"min_availability,omitempty"`
+	Capabilities    []string `json:"capabilities,omitempty"`    // Services offering every one listed
+	MaxLatencyMS    int      `json:"max_latency_ms,omitempty"`  // SLA maximum latency bound
+	GDPRCompliant   bool     `json:"gdpr_compliant,omitempty"`
+	HIPAACompliant  bool     `json:"hipaa_compliant,omitempty"`
+	PolicyCompliant bool     `json:"policy_compliant,omitempty"` // Passed its last policy engine check
+	Kind            string   `json:"kind,omitempty"`             // One of marketplace.Kinds; every kind when empty
+	ModelFamilies   []string `json:"model_families,omitempty"`   // Prompt templates written for any one listed
+	Licenses        []string `json:"licenses,omitempty"`         // Datasets under any one of these SPDX licenses
+	DatasetFormats  []string `json:"dataset_formats,omitempty"`  // Datasets in any one of these formats
+	MaxDatasetBytes int64    `json:"max_dataset_bytes,omitempty"` // Datasets up to this size
+	BaseModels      []string `json:"base_models,omitempty"`       // Fine-tuning offerings for any one of these base models
+	MaxTurnaroundHours int   `json:"max_turnaround_hours,omitempty"` // Fine-tuning offerings that finish jobs within this many hours
+	ServingRegion   string   `json:"serving_region,omitempty"`   // Only services served from this region
 }
 
 // PaginationRequest represents pagination parameters
@@ -77,13 +151,20 @@ type PaginationRequest struct {
 
 // SearchResponse represents search results
 type SearchResponse struct {
-	Results        []SearchResult     `json:"results"`
-	Total          int                `json:"total"`
-	Page           int                `json:"page"`
-	PageSize       int                `json:"page_size"`
-	Took           int                `json:"took_ms"`
-	Aggregations   map[string]interface{} `json:"aggregations,omitempty"`
-	Recommendations []SearchResult    `json:"recommendations,omitempty"`
+	QueryID         string                  `json:"query_id,omitempty"` // Echo in click events to attribute them
+	Results         []SearchResult          `json:"results"`
+	Total           int                     `json:"total"`
+	Page            int                     `json:"page"`
+	PageSize        int                     `json:"page_size"`
+	Took            int                     `json:"took_ms"`
+	Aggregations    map[string]interface{}  `json:"aggregations,omitempty"`
+	Recommendations []SearchResult          `json:"recommendations,omitempty"`
+	Groups          map[string]*EntityGroup `json:"groups,omitempty"` // Results for entity types other than services
+	Pareto          *ParetoSummary          `json:"pareto,omitempty"` // The candidates of a Pareto search left off the frontier
+	Intent          *QueryIntent            `json:"intent,omitempty"`  // The filters read out of the query
+	Rewrite         *QueryRewrite           `json:"rewrite,omitempty"` // How the query pipeline changed the query
+	Partial         bool                    `json:"partial,omitempty"` // Elasticsearch timed out, and results are what it found by then
+	DebugID         string                  `json:"debug_id,omitempty"` // Request ID the search was captured under
 }
 
 // SearchResult represents a single search result
@@ -91,6 +172,37 @@ type SearchResult struct {
 	Service       *elasticsearch.ServiceDocument `json:"service"`
 	Score         float64                        `json:"score"`
 	MatchDetails  MatchDetails                   `json:"match_details"`
+	Deprecation   *DeprecationBanner             `json:"deprecation,omitempty"`
+	Subscribed    bool                           `json:"subscribed,omitempty"` // The caller's tenant holds an active subscription
+	Pareto        *ParetoPoint                   `json:"pareto,omitempty"`     // Set by Pareto searches
+
+	fields []string // Selected service fields, see MarshalJSON
+}
+
+// MarshalJSON renders only the selected service fields when field selection is in effect
+func (r SearchResult) MarshalJSON() ([]byte, error) {
+	type plain SearchResult
+	if len(r.fields) == 0 || r.Service == nil {
+		return json.Marshal(plain(r))
+	}
+
+	service, err := ProjectDocument(r.Service, r.fields)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(struct {
+		plain
+		Service map[string]interface{} `json:"service"`
+	}{plain(r), service})
+}
+
+// DeprecationBanner is shown alongside deprecated services in results
+type DeprecationBanner struct {
+	Message            string     `json:"message"`
+	SunsetAt           *time.Time `json:"sunset_at,omitempty"`
+	ReplacementService string     `json:"replacement_service_id,omitempty"`
+	ReplacementURL     string     `json:"replacement_url,omitempty"`
 }
 
 // MatchDetails explains why a result matched
@@ -100,10 +212,47 @@ type MatchDetails struct {
 	PerformanceScore float64 `json:"performance_score"`
 	ComplianceScore float64 `json:"compliance_score"`
 	SemanticMatch   bool    `json:"semantic_match"`
+	Demoted         bool    `json:"demoted,omitempty"` // Ranked down for repeated upheld reports
+	SessionFactor   float64 `json:"session_factor,omitempty"` // Score multiplier from the browsing session's signals
+	FeatureFactor   float64 `json:"feature_factor,omitempty"` // Score multiplier from the user's and service's stored features
+	RegionFactor    float64 `json:"region_factor,omitempty"`  // Score multiplier from the service's latency in the caller's region
 }
 
-// Search performs the main search operation
+// Search performs the main search operation. Unless the request is literal,
+// filters the query states in words, such as "cheap" or "under 200ms", are
+// applied first and echoed in the response's intent, then what is left of
+// the query goes through the query pipeline.
 func (s *Service) Search(ctx context.Context, req *SearchRequest) (*SearchResponse, error) {
+	if err := s.checkCost(req); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := s.withLatencyBudget(ctx)
+	defer cancel()
+
+	original := *req
+	req.intent = s.understand(ctx, req)
+	req.rewrite = s.rewriteQuery(ctx, req)
+	intent, rewrite := req.intent, req.rewrite
+	if intent != nil || rewrite != nil {
+		req.typed = original.Query
+		// The caller's request is left as it came
+		defer func() { *req = original }()
+	}
+
+	response, err := s.search(ctx, req)
+	if err != nil {
+		if s.budgetExceeded(ctx, stageSearch) {
+			return nil, budgetError(ctx, err)
+		}
+		return nil, err
+	}
+	response.Intent, response.Rewrite = intent, rewrite
+	s.metrics.RewrittenSearch(rewrite != nil, response.Total)
+	return response, nil
+}
+
+func (s *Service) search(ctx context.Context, req *SearchRequest) (*SearchResponse, error) {
 	startTime := time.Now()
 	span := trace.SpanFromContext(ctx)
 	span.SetAttributes(
@@ -111,14 +260,61 @@ func (s *Service) Search(ctx context.Context, req *SearchRequest) (*SearchRespon
 		attribute.Int("search.page", req.Pagination.Page),
 	)
 
-	// Check cache first
-	cacheKey := s.buildCacheKey(req)
-	if cached, err := s.getCachedResults(ctx, cacheKey); err == nil && cached != nil {
-		s.logger.Debug("Cache hit", zap.String("key", cacheKey))
-		s.metrics.CacheHit()
-		return cached, nil
+	if err := ValidateTypes(req.Types); err != nil {
+		return nil, err
+	}
+	if err := s.ValidateFacets(req.Facets); err != nil {
+		return nil, err
+	}
+	if req.Pareto != nil {
+		if err := validatePareto(req.Pareto); err != nil {
+			return nil, err
+		}
+	}
+	if req.RankingWeights != nil {
+		if err := validateRankingWeights(req.RankingWeights); err != nil {
+			return nil, err
+		}
+	}
+
+	capture := s.startCapture(ctx, req)
+
+	// Check cache first; background and captured searches skip it to run the query
+	cacheKey := s.buildCacheKey(ctx, req)
+	if !req.background && capture == nil {
+		if cached, stale, err := s.getCachedResults(ctx, cacheKey); err == nil && cached != nil {
+			s.logger.Debug("Cache hit", zap.String("key", cacheKey), zap.Bool("stale", stale))
+			s.metrics.CacheHit(ctx)
+			if stale {
+				s.metrics.CacheStaleServe()
+				s.revalidate(ctx, req, cacheKey)
+			}
+			if req.AggregationsOnly || req.CountOnly {
+				return cached, nil
+			}
+			applyFields(cached.Results, req.Fields)
+			s.markSubscribed(ctx, cached.Results)
+			if req.Pareto == nil {
+				s.rerank(ctx, req, cached.Results)
+			}
+			cached.QueryID = analytics.NewID()
+			s.trackSearchEvent(req, cached, time.Since(startTime), true)
+			return cached, nil
+		}
+		s.metrics.CacheMiss(ctx)
+	}
+
+	if !includesServices(req.Types) {
+		return s.searchEntitiesOnly(ctx, req, cacheKey, startTime)
+	}
+
+	if req.AggregationsOnly || req.CountOnly {
+		return s.searchSummary(ctx, req, cacheKey, startTime)
+	}
+
+	if req.Pareto != nil {
+		return s.searchPareto(ctx, req, cacheKey, startTime)
 	}
-	s.metrics.CacheMiss()
 
 	// Build Elasticsearch query
 	esQuery, err := s.buildSearchQuery(ctx, req)
@@ -128,18 +324,32 @@ func (s *Service) Search(ctx context.Context, req *SearchRequest) (*SearchRespon
 	}
 
 	// Execute search
+	s.boundQuery(ctx, esQuery)
 	esResponse, err := s.esClient.Search(ctx, esQuery)
 	if err != nil {
 		s.logger.Error("Search failed", zap.Error(err))
 		s.metrics.SearchError()
 		return nil, fmt.Errorf("search failed: %w", err)
 	}
+	capture.recordElasticsearch(s.esClient.ScopedQuery(ctx, esQuery), esResponse)
+	partial, err := s.partialResults(esResponse)
+	if err != nil {
+		s.logger.Warn("Search timed out", zap.String("query", req.Query))
+		return nil, err
+	}
+
+	aggregations, err := s.facetResults(ctx, esResponse)
+	if err != nil {
+		return nil, fmt.Errorf("search failed: %w", err)
+	}
 
 	// Process results
-	results := s.processSearchResults(esResponse, req)
+	results := s.processSearchResults(ctx, esResponse, req)
+	capture.recordStage(DebugStageElasticsearch, results)
 
 	// Rank results
-	rankedResults := s.rankResults(results)
+	rankedResults := s.rankResults(results, esResponse.Hits.MaxScore, s.rankingWeights(req))
+	capture.recordStage(DebugStageRank, rankedResults)
 
 	// Build response
 	response := &SearchResponse{
@@ -148,17 +358,35 @@ func (s *Service) Search(ctx context.Context, req *SearchRequest) (*SearchRespon
 		Page:     req.Pagination.Page,
 		PageSize: req.Pagination.PageSize,
 		Took:     esResponse.Took,
-		Aggregations: esResponse.Aggregations,
+		Aggregations: aggregations,
+		Partial:  partial,
 	}
 
-	// Cache results
-	if err := s.cacheResults(ctx, cacheKey, response); err != nil {
-		s.logger.Warn("Failed to cache results", zap.Error(err))
+	if others := otherEntityTypes(req.Types); len(others) > 0 {
+		groups, err := s.searchEntities(ctx, req, others)
+		if err != nil {
+			s.logger.Error("Entity search failed", zap.Error(err))
+			s.metrics.SearchError()
+			return nil, fmt.Errorf("search failed: %w", err)
+		}
+		response.Groups = groups
 	}
 
+	// Cache results; partial ones would outlive the slowness that cut them short
+	if !partial {
+		if err := s.cacheResults(ctx, cacheKey, response, "search_results"); err != nil {
+			s.logger.Warn("Failed to cache results", zap.Error(err))
+		}
+	}
+
+	// Flagged and re-ranked after caching, since both are the caller's own
+	s.markSubscribed(ctx, response.Results)
+	s.rerank(ctx, req, response.Results)
+	capture.recordStage(DebugStageRerank, response.Results)
+
 	// Record metrics
 	duration := time.Since(startTime)
-	s.metrics.SearchDuration(duration)
+	s.metrics.SearchDuration(ctx, duration)
 	s.metrics.SearchResults(len(results))
 
 	s.logger.Info("Search completed",
@@ -168,7 +396,13 @@ func (s *Service) Search(ctx context.Context, req *SearchRequest) (*SearchRespon
 	)
 
 	// Track analytics
-	go s.trackSearchEvent(ctx, req, response)
+	response.QueryID = analytics.NewID()
+	s.trackSearchEvent(req, response, duration, false)
+
+	if capture != nil {
+		s.saveCapture(ctx, capture)
+		response.DebugID = capture.RequestID
+	}
 
 	return response, nil
 }
@@ -185,288 +419,266 @@ func (s *Service) buildSearchQuery(ctx context.Context, req *SearchRequest) (map
 		size = s.config.Search.MaxResults
 	}
 
-	query := map[string]interface{}{
-		"from": from,
-		"size": size,
-		"query": map[string]interface{}{
-			"bool": map[string]interface{}{
-				"must":   []interface{}{},
-				"filter": []interface{}{},
-				"should": []interface{}{},
-			},
-		},
-		"aggs": s.buildAggregations(),
-	}
-
-	boolQuery := query["query"].(map[string]interface{})["bool"].(map[string]interface{})
+	boolQuery := query.Bool()
 
 	// Text search
 	if req.Query != "" {
-		// Multi-match query
-		multiMatch := map[string]interface{}{
-			"multi_match": map[string]interface{}{
-				"query": req.Query,
-				"fields": []string{
-					"name^3",
-					"name.autocomplete^2",
-					"description^2",
-					"tags^1.5",
-					"capabilities",
-				},
-				"type":       "best_fields",
-				"fuzziness":  "AUTO",
-				"operator":   "or",
-			},
-		}
-		boolQuery["should"] = append(boolQuery["should"].([]interface{}), multiMatch)
+		boolQuery.Should(
+			query.MultiMatch(req.Query,
+				"name^3",
+				"name.autocomplete^2",
+				"description^2",
+				"tags^1.5",
+				"capabilities",
+			).Type("best_fields").Fuzziness("AUTO").Operator("or"),
+		)
 
 		// Semantic search with embeddings
-		if s.config.Search.SemanticEnabled {
-			embedding, err := s.embeddingClient.GetEmbedding(ctx, req.Query)
-			if err == nil && len(embedding) > 0 {
-				knnQuery := map[string]interface{}{
-					"script_score": map[string]interface{}{
-						"query": map[string]interface{}{"match_all": map[string]interface{}{}},
-						"script": map[string]interface{}{
-							"source": "cosineSimilarity(params.query_vector, 'embedding') + 1.0",
-							"params": map[string]interface{}{
-								"query_vector": embedding,
-							},
-						},
+		// Rolled out by tenant alone, since results are cached per tenant
+		if s.config.Search.SemanticEnabled && s.semanticAvailable() && s.flagOn(ctx, flags.SemanticSearch, "") {
+			if embedding := s.queryEmbedding(ctx, req.Query); len(embedding) > 0 {
+				// Documents not yet embedded by the query model are skipped rather than failing the script
+				field := vectorField(s.config.QueryEmbeddingModel())
+				boolQuery.Should(query.ScriptScore(query.Exists(field), query.Script{
+					Source: "cosineSimilarity(params.query_vector, params.field) + 1.0",
+					Params: map[string]interface{}{
+						"query_vector": embedding,
+						"field":        field,
 					},
-				}
-				boolQuery["should"] = append(boolQuery["should"].([]interface{}), knnQuery)
+				}))
 			}
 		}
 
-		boolQuery["minimum_should_match"] = 1
+		boolQuery.MinimumShouldMatch(1)
 	}
 
-	// Filters
-	filters := boolQuery["filter"].([]interface{})
-
-	// Status filter (always active services by default)
+	// Status filter (active and deprecated services by default)
 	if req.Filters.Status != "" {
-		filters = append(filters, map[string]interface{}{
-			"term": map[string]interface{}{
-				"status": req.Filters.Status,
-			},
-		})
+		boolQuery.Filter(query.Term("status", req.Filters.Status))
 	} else {
-		filters = append(filters, map[string]interface{}{
-			"term": map[string]interface{}{
-				"status": "active",
-			},
-		})
+		boolQuery.Filter(query.Terms("status", elasticsearch.SearchableStatuses))
+	}
+
+	// Retired services are never returned by search
+	boolQuery.MustNot(query.Term("status", elasticsearch.StatusRetired))
+
+	// Only services the caller is entitled to see
+	boolQuery.Filter(entitlement.FromContext(ctx).Filter())
+
+	// Collapse to the latest stable version; documents without version info are unaffected
+	if !req.AllVersions {
+		boolQuery.MustNot(query.Term("version.latest", false))
 	}
 
-	// Category filter
+	// Category filter; a parent category also matches its descendants
 	if len(req.Filters.Categories) > 0 {
-		filters = append(filters, map[string]interface{}{
-			"terms": map[string]interface{}{
-				"category": req.Filters.Categories,
-			},
-		})
+		tax := s.loadTaxonomy(ctx)
+		if err := tax.Validate(req.Filters.Categories...); err != nil {
+			return nil, err
+		}
+		boolQuery.Filter(query.Terms("category", tax.Expand(req.Filters.Categories)))
 	}
 
 	// Tags filter
 	if len(req.Filters.Tags) > 0 {
-		filters = append(filters, map[string]interface{}{
-			"terms": map[string]interface{}{
-				"tags": req.Filters.Tags,
-			},
-		})
+		boolQuery.Filter(query.Terms("tags", req.Filters.Tags))
 	}
 
 	// Rating filter
 	if req.Filters.MinRating > 0 {
-		filters = append(filters, map[string]interface{}{
-			"range": map[string]interface{}{
-				"metrics.rating": map[string]interface{}{
-					"gte": req.Filters.MinRating,
-				},
-			},
-		})
+		boolQuery.Filter(query.Range("metrics.rating").Gte(req.Filters.MinRating))
 	}
 
-	// Price filter
+	// Price filters
+	if req.Filters.MinPrice > 0 {
+		boolQuery.Filter(query.Range("pricing.rate").Gte(req.Filters.MinPrice))
+	}
 	if req.Filters.MaxPrice > 0 {
-		filters = append(filters, map[string]interface{}{
-			"range": map[string]interface{}{
-				"pricing.rate": map[string]interface{}{
-					"lte": req.Filters.MaxPrice,
-				},
-			},
-		})
+		boolQuery.Filter(query.Range("pricing.rate").Lte(req.Filters.MaxPrice))
 	}
 
 	// Pricing model filter
 	if len(req.Filters.PricingModels) > 0 {
-		filters = append(filters, map[string]interface{}{
-			"terms": map[string]interface{}{
-				"pricing.model": req.Filters.PricingModels,
-			},
-		})
+		boolQuery.Filter(query.Terms("pricing.model", req.Filters.PricingModels))
 	}
 
 	// Compliance level filter
 	if req.Filters.ComplianceLevel != "" {
-		filters = append(filters, map[string]interface{}{
-			"term": map[string]interface{}{
-				"compliance.level": req.Filters.ComplianceLevel,
-			},
-		})
+		boolQuery.Filter(query.Term("compliance.level", req.Filters.ComplianceLevel))
 	}
 
 	// Certifications filter
 	if len(req.Filters.Certifications) > 0 {
-		filters = append(filters, map[string]interface{}{
-			"terms": map[string]interface{}{
-				"compliance.certifications": req.Filters.Certifications,
-			},
-		})
+		boolQuery.Filter(query.Terms("compliance.certifications", req.Filters.Certifications))
 	}
 
 	// Data residency filter
 	if len(req.Filters.DataResidency) > 0 {
-		filters = append(filters, map[string]interface{}{
-			"terms": map[string]interface{}{
-				"compliance.data_residency": req.Filters.DataResidency,
-			},
-		})
+		boolQuery.Filter(query.Terms("compliance.data_residency", req.Filters.DataResidency))
 	}
 
 	// Verified providers only
 	if req.Filters.VerifiedOnly {
-		filters = append(filters, map[string]interface{}{
-			"term": map[string]interface{}{
-				"provider.verified": true,
-			},
-		})
+		boolQuery.Filter(query.Term("provider.verified", true))
 	}
 
 	// Availability filter
 	if req.Filters.MinAvailability > 0 {
-		filters = append(filters, map[string]interface{}{
-			"range": map[string]interface{}{
-				"sla.availability": map[string]interface{}{
-					"gte": req.Filters.MinAvailability,
-				},
-			},
-		})
+		boolQuery.Filter(query.Range("sla.availability").Gte(req.Filters.MinAvailability))
 	}
 
-	boolQuery["filter"] = filters
+	// Capability filter, every one required
+	for _, capability := range req.Filters.Capabilities {
+		boolQuery.Filter(query.Term("capabilities", capability))
+	}
 
-	return query, nil
-}
+	// Latency filter; services without a latency bound don't qualify
+	if req.Filters.MaxLatencyMS > 0 {
+		boolQuery.Filter(query.Range("sla.max_latency_ms").Gt(0).Lte(req.Filters.MaxLatencyMS))
+	}
 
-// buildAggregations builds faceted search aggregations
-func (s *Service) buildAggregations() map[string]interface{} {
-	return map[string]interface{}{
-		"categories": map[string]interface{}{
-			"terms": map[string]interface{}{
-				"field": "category",
-				"size":  50,
-			},
-		},
-		"tags": map[string]interface{}{
-			"terms": map[string]interface{}{
-				"field": "tags",
-				"size":  100,
-			},
-		},
-		"pricing_models": map[string]interface{}{
-			"terms": map[string]interface{}{
-				"field": "pricing.model",
-				"size":  10,
-			},
-		},
-		"compliance_levels": map[string]interface{}{
-			"terms": map[string]interface{}{
-				"field": "compliance.level",
-				"size":  10,
-			},
-		},
-		"avg_rating": map[string]interface{}{
-			"avg": map[string]interface{}{
-				"field": "metrics.rating",
-			},
-		},
-		"price_ranges": map[string]interface{}{
-			"histogram": map[string]interface{}{
-				"field":    "pricing.rate",
-				"interval": 0.01,
-			},
-		},
+	// Regulatory compliance filters
+	if req.Filters.GDPRCompliant {
+		boolQuery.Filter(query.Term("compliance.gdpr_compliant", true))
+	}
+	if req.Filters.HIPAACompliant {
+		boolQuery.Filter(query.Term("compliance.hipaa_compliant", true))
+	}
+	if req.Filters.PolicyCompliant {
+		boolQuery.Filter(query.Term("policy_compliance.compliant", true))
+	}
+
+	// Kind and model family filters
+	if err := filterKind(boolQuery, req.Filters); err != nil {
+		return nil, err
 	}
+	filterDatasets(boolQuery, req.Filters)
+	filterFineTuning(boolQuery, req.Filters)
+	filterServingRegion(boolQuery, req.Filters)
+
+	aggs, err := s.buildAggregations(req.Facets)
+	if err != nil {
+		return nil, err
+	}
+
+	search := &query.Search{
+		Query:        boolQuery,
+		From:         from,
+		Size:         size,
+		Aggregations: aggs,
+		SourceFilter: sourceFilter(req.Fields),
+	}
+	if req.AggregationsOnly || req.CountOnly {
+		search.From, search.Size, search.SourceFilter = 0, 0, false
+	}
+	if req.CountOnly {
+		search.Aggregations = nil
+	}
+	return search.Map(), nil
 }
 
 // processSearchResults processes Elasticsearch hits into search results
-func (s *Service) processSearchResults(esResp *elasticsearch.SearchResponse, req *SearchRequest) []SearchResult {
+func (s *Service) processSearchResults(ctx context.Context, esResp *elasticsearch.SearchResponse, req *SearchRequest) []SearchResult {
 	results := make([]SearchResult, 0, len(esResp.Hits.Hits))
-
-	for _, hit := range esResp.Hits.Hits {
+	caller := entitlement.FromContext(ctx)
+
+	// Results point into the hits rather than copying each document
+	for i := range esResp.Hits.Hits {
+		hit := &esResp.Hits.Hits[i]
+		// The query already filters by entitlement; this guards against a query built without it
+		if !caller.CanView(hit.Source.Access) {
+			continue
+		}
 		result := SearchResult{
 			Service: &hit.Source,
 			Score:   hit.Score,
 			MatchDetails: MatchDetails{
 				RelevanceScore: hit.Score,
 			},
+			Deprecation: buildDeprecationBanner(&hit.Source),
 		}
 		results = append(results, result)
 	}
 
+	applyFields(results, req.Fields)
 	return results
 }
 
-// rankResults applies the ranking algorithm
-func (s *Service) rankResults(results []SearchResult) []SearchResult {
-	weights := s.config.Search.RankingWeights
-
+func applyFields(results []SearchResult, fields []string) {
 	for i := range results {
-		svc := results[i].Service
+		results[i].fields = fields
+	}
+}
 
-		// Relevance (already from Elasticsearch score)
-		relevanceScore := results[i].Score / 10.0 // Normalize
-		if relevanceScore > 1.0 {
-			relevanceScore = 1.0
+// rankResults applies the ranking algorithm. Relevance is each result's
+// Elasticsearch score relative to maxScore, the best score of any match for
+// the query, so it means the same on every page and for every query.
+func (s *Service) rankResults(results []SearchResult, maxScore float64, weights config.RankingWeights) []SearchResult {
+	// Elasticsearch has no max_score for searches not sorted by score
+	if maxScore <= 0 {
+		for _, r := range results {
+			maxScore = max(maxScore, r.Score)
 		}
+	}
 
-		// Popularity (based on metrics)
-		popularityScore := s.calculatePopularityScore(svc)
+	for i := range results {
+		// Relevance (already from Elasticsearch score)
+		relevanceScore := normalizeRelevance(results[i].Score, maxScore)
 
-		// Performance (based on SLA metrics)
-		performanceScore := s.calculatePerformanceScore(svc)
+		results[i].Score, results[i].MatchDetails = s.rankingScore(results[i].Service, relevanceScore, weights)
+	}
 
-		// Compliance (based on compliance level and certifications)
-		complianceScore := s.calculateComplianceScore(svc)
+	sortByScore(results)
+	return results
+}
 
-		// Calculate weighted score
-		finalScore := (relevanceScore * weights.Relevance) +
-			(popularityScore * weights.Popularity) +
-			(performanceScore * weights.Performance) +
-			(complianceScore * weights.Compliance)
+// normalizeRelevance scales an Elasticsearch score to between 0 and 1 by
+// the best score for the query
+func normalizeRelevance(score, maxScore float64) float64 {
+	if maxScore <= 0 {
+		return 0
+	}
+	return min(max(score/maxScore, 0), 1.0)
+}
 
-		results[i].Score = finalScore
-		results[i].MatchDetails = MatchDetails{
-			RelevanceScore:   relevanceScore,
-			PopularityScore:  popularityScore,
-			PerformanceScore: performanceScore,
-			ComplianceScore:  complianceScore,
-		}
+// rankingScore weighs a service's normalized relevance with its popularity,
+// performance and compliance into its ranking score
+func (s *Service) rankingScore(svc *elasticsearch.ServiceDocument, relevanceScore float64, weights config.RankingWeights) (float64, MatchDetails) {
+	demotion := s.config.Search.ReportDemotion
+
+	// Popularity (based on metrics)
+	popularityScore := s.calculatePopularityScore(svc)
+
+	// Performance (based on SLA metrics)
+	performanceScore := s.calculatePerformanceScore(svc)
+
+	// Compliance (based on compliance level and certifications)
+	complianceScore := s.calculateComplianceScore(svc)
+
+	// Calculate weighted score
+	finalScore := (relevanceScore * weights.Relevance) +
+		(popularityScore * weights.Popularity) +
+		(performanceScore * weights.Performance) +
+		(complianceScore * weights.Compliance)
+
+	// Repeat offenders sink below comparable services
+	demoted := demotion.Threshold > 0 && svc.UpheldReports >= demotion.Threshold
+	if demoted {
+		finalScore *= demotion.Factor
 	}
 
-	// Sort by final score
-	for i := 0; i < len(results)-1; i++ {
-		for j := i + 1; j < len(results); j++ {
-			if results[j].Score > results[i].Score {
-				results[i], results[j] = results[j], results[i]
-			}
-		}
+	return finalScore, MatchDetails{
+		RelevanceScore:   relevanceScore,
+		PopularityScore:  popularityScore,
+		PerformanceScore: performanceScore,
+		ComplianceScore:  complianceScore,
+		Demoted:          demoted,
 	}
+}
 
-	return results
+// sortByScore orders results by descending score, keeping the order of ties
+func sortByScore(results []SearchResult) {
+	slices.SortStableFunc(results, func(a, b SearchResult) int { return cmp.Compare(b.Score, a.Score) })
 }
 
 // Score calculation helpers
@@ -497,28 +709,60 @@ func (s *Service) calculateComplianceScore(svc *elasticsearch.ServiceDocument) f
 
 	// Compliance level
 	switch svc.Compliance.Level {
-	case "public":
+	case marketplace.CompliancePublic:
 		score += 0.1
-	case "internal":
+	case marketplace.ComplianceInternal:
 		score += 0.2
-	case "confidential":
+	case marketplace.ComplianceConfidential:
 		score += 0.3
 	}
 
-	// Certifications bonus
+	// Lapsing documents, as flagged by the registry, cost the service:
+	// expired certifications earn no bonus
 	certCount := len(svc.Compliance.Certifications)
-	score += min(float64(certCount)*0.1, 0.2)
+	penalty := 0.0
+	for _, e := range svc.Expiries {
+		switch {
+		case e.Status == marketplace.ExpiryExpiring:
+			penalty += 0.05
+		case e.Kind == marketplace.ExpiryCertification:
+			certCount--
+			penalty += 0.1
+		default: // An expired data-processing agreement
+			penalty += 0.2
+		}
+	}
+
+	// Certifications bonus
+	score += min(float64(max(certCount, 0))*0.1, 0.2)
 
-	return min(score, 1.0)
+	return max(min(score, 1.0)-penalty, 0)
 }
 
 // Cache helpers
-func (s *Service) buildCacheKey(req *SearchRequest) string {
+func (s *Service) buildCacheKey(ctx context.Context, req *SearchRequest) string {
+	prefix := "search"
+	switch {
+	case req.CountOnly:
+		prefix = "search_count"
+	case req.AggregationsOnly:
+		prefix = "search_aggs"
+	case req.Pareto != nil:
+		prefix = "search_pareto"
+	}
+
 	parts := []string{
-		"search",
+		prefix,
+		"g" + s.searchGeneration(ctx),
+		entitlement.FromContext(ctx).CacheScope(),
 		req.Query,
-		fmt.Sprintf("p%d", req.Pagination.Page),
-		fmt.Sprintf("s%d", req.Pagination.PageSize),
+	}
+	// Summaries are the same for every page
+	if prefix == "search" {
+		parts = append(parts,
+			fmt.Sprintf("p%d", req.Pagination.Page),
+			fmt.Sprintf("s%d", req.Pagination.PageSize),
+		)
 	}
 
 	if len(req.Filters.Categories) > 0 {
@@ -527,41 +771,225 @@ func (s *Service) buildCacheKey(req *SearchRequest) string {
 	if len(req.Filters.Tags) > 0 {
 		parts = append(parts, "tag:"+strings.Join(req.Filters.Tags, ","))
 	}
+	if req.Filters.MinPrice > 0 || req.Filters.MaxPrice > 0 {
+		parts = append(parts, fmt.Sprintf("price:%g-%g", req.Filters.MinPrice, req.Filters.MaxPrice))
+	}
+	if req.Filters.MinRating > 0 {
+		parts = append(parts, fmt.Sprintf("rating:%g", req.Filters.MinRating))
+	}
+	if len(req.Filters.PricingModels) > 0 {
+		parts = append(parts, "pricing:"+strings.Join(req.Filters.PricingModels, ","))
+	}
+	if req.Filters.ComplianceLevel != "" {
+		parts = append(parts, "compliance:"+req.Filters.ComplianceLevel)
+	}
+	if len(req.Filters.Certifications) > 0 {
+		parts = append(parts, "certs:"+strings.Join(req.Filters.Certifications, ","))
+	}
+	if len(req.Filters.DataResidency) > 0 {
+		parts = append(parts, "residency:"+strings.Join(req.Filters.DataResidency, ","))
+	}
+	if req.Filters.VerifiedOnly {
+		parts = append(parts, "verified")
+	}
+	if req.Filters.Status != "" {
+		parts = append(parts, "status:"+req.Filters.Status)
+	}
+	if req.Filters.MinAvailability > 0 {
+		parts = append(parts, fmt.Sprintf("availability:%g", req.Filters.MinAvailability))
+	}
+	if len(req.Filters.Capabilities) > 0 {
+		parts = append(parts, "caps:"+strings.Join(req.Filters.Capabilities, ","))
+	}
+	if req.Filters.MaxLatencyMS > 0 {
+		parts = append(parts, fmt.Sprintf("latency:%d", req.Filters.MaxLatencyMS))
+	}
+	if req.Filters.GDPRCompliant {
+		parts = append(parts, "gdpr")
+	}
+	if req.Filters.HIPAACompliant {
+		parts = append(parts, "hipaa")
+	}
+	if req.Filters.PolicyCompliant {
+		parts = append(parts, "policy_compliant")
+	}
+	if req.Filters.Kind != "" {
+		parts = append(parts, "kind:"+req.Filters.Kind)
+	}
+	if len(req.Filters.ModelFamilies) > 0 {
+		parts = append(parts, "models:"+strings.Join(req.Filters.ModelFamilies, ","))
+	}
+	if len(req.Filters.Licenses) > 0 {
+		parts = append(parts, "licenses:"+strings.Join(req.Filters.Licenses, ","))
+	}
+	if len(req.Filters.DatasetFormats) > 0 {
+		parts = append(parts, "formats:"+strings.Join(req.Filters.DatasetFormats, ","))
+	}
+	if req.Filters.MaxDatasetBytes > 0 {
+		parts = append(parts, fmt.Sprintf("dataset_bytes:%d", req.Filters.MaxDatasetBytes))
+	}
+	if len(req.Filters.BaseModels) > 0 {
+		parts = append(parts, "base_models:"+strings.Join(req.Filters.BaseModels, ","))
+	}
+	if req.Filters.MaxTurnaroundHours > 0 {
+		parts = append(parts, fmt.Sprintf("turnaround:%d", req.Filters.MaxTurnaroundHours))
+	}
+	if req.Filters.ServingRegion != "" {
+		parts = append(parts, "serving_region:"+req.Filters.ServingRegion)
+	}
+	if len(req.Fields) > 0 {
+		parts = append(parts, "fields:"+strings.Join(req.Fields, ","))
+	}
+	if len(req.Types) > 0 {
+		parts = append(parts, "types:"+strings.Join(req.Types, ","))
+	}
+	if req.AllVersions {
+		parts = append(parts, "all_versions")
+	}
+	if len(req.Facets) > 0 {
+		parts = append(parts, "facets:"+strings.Join(req.Facets, ","))
+	}
+	if req.Pareto != nil {
+		parts = append(parts, fmt.Sprintf("pareto:%s:%g", req.Pareto.Category, req.Pareto.MinQuality))
+	}
+	if w := req.RankingWeights; w != nil {
+		parts = append(parts, fmt.Sprintf("weights:%g,%g,%g,%g", w.Relevance, w.Popularity, w.Performance, w.Compliance))
+	}
 
 	return strings.Join(parts, ":")
 }
 
-func (s *Service) getCachedResults(ctx context.Context, key string) (*SearchResponse, error) {
-	data, err := s.redisClient.Get(ctx, key).Bytes()
+// searchGenerationKey counts the changes that invalidate every cached search.
+// It is part of each search's cache key, so bumping it retires them all.
+const searchGenerationKey = "search_generation"
+
+// searchGeneration returns the current search cache generation
+func (s *Service) searchGeneration(ctx context.Context) string {
+	generation, err := s.redisClient.Get(ctx, searchGenerationKey).Result()
 	if err != nil {
-		return nil, err
+		return "0"
 	}
+	return generation
+}
 
-	var response SearchResponse
-	if err := json.Unmarshal(data, &response); err != nil {
-		return nil, err
+// invalidateSearches stops every cached search from being served again
+func (s *Service) invalidateSearches(ctx context.Context) {
+	if err := s.redisClient.Incr(ctx, searchGenerationKey).Err(); err != nil {
+		s.logger.Warn("Failed to invalidate search cache", zap.Error(err))
+	}
+}
+
+// getCachedResults returns the cached response under key, and whether it is
+// past its soft TTL
+func (s *Service) getCachedResults(ctx context.Context, key string) (*SearchResponse, bool, error) {
+	start := time.Now()
+	cacheCtx, cancel := withStageBudget(ctx, s.config.Search.LatencyBudget.CacheShare, 0)
+	defer cancel()
+	data, err := s.redisClient.Get(cacheCtx, key).Bytes()
+	callErr := err
+	if err == redis.Nil {
+		callErr = nil // A miss is a successful call
+	} else if err != nil {
+		s.budgetExceeded(cacheCtx, stageCache)
+	}
+	s.metrics.DependencyCall(ctx, "redis", "get", time.Since(start), callErr)
+	if err != nil {
+		return nil, false, err
 	}
 
-	return &response, nil
+	var entry cachedResponse
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, false, err
+	}
+
+	stale := s.config.Search.StaleWhileRevalidate.Enabled && time.Now().After(entry.FreshUntil)
+	return entry.Response, stale, nil
 }
 
-func (s *Service) cacheResults(ctx context.Context, key string, response *SearchResponse) error {
-	data, err := json.Marshal(response)
+// cacheResults caches a response for the cache_ttl entry named ttlName
+func (s *Service) cacheResults(ctx context.Context, key string, response *SearchResponse, ttlName string) error {
+	ttl := s.config.CacheTTL(ttlName)
+	entry := cachedResponse{Response: response, FreshUntil: time.Now().Add(ttl)}
+	data, err := json.Marshal(entry)
 	if err != nil {
 		return err
 	}
-
-	ttl := s.config.Redis.GetCacheTTL("search_results")
+	// Stale responses are kept to be served while they are refreshed
+	if swr := s.config.Search.StaleWhileRevalidate; swr.Enabled {
+		ttl += swr.StaleTTL
+	}
 	return s.redisClient.Set(ctx, key, data, ttl).Err()
 }
 
-// trackSearchEvent sends search analytics
-func (s *Service) trackSearchEvent(ctx context.Context, req *SearchRequest, resp *SearchResponse) {
-	// This would integrate with Analytics Hub via Kafka
-	s.logger.Debug("Tracking search event",
-		zap.String("query", req.Query),
-		zap.Int("results", resp.Total),
-	)
+// SetAnalytics registers the producer that receives search events
+func (s *Service) SetAnalytics(p *analytics.Producer) {
+	s.analytics = p
+}
+
+// Subscriptions reports the services a consumer organisation holds active
+// subscriptions to
+type Subscriptions interface {
+	Subscribed(ctx context.Context, consumerID string) (map[string]bool, error)
+}
+
+// SetSubscriptions registers where search looks up the caller's
+// subscriptions to flag subscribed services
+func (s *Service) SetSubscriptions(subs Subscriptions) {
+	s.subscriptions = subs
+}
+
+// markSubscribed flags the results the caller's tenant is subscribed to.
+// Results stay unflagged when the subscriptions can't be looked up.
+func (s *Service) markSubscribed(ctx context.Context, results []SearchResult) {
+	tenant := entitlement.FromContext(ctx).TenantID
+	if s.subscriptions == nil || tenant == "" || len(results) == 0 {
+		return
+	}
+	subscribed, err := s.subscriptions.Subscribed(ctx, tenant)
+	if err != nil {
+		s.logger.Warn("Failed to look up subscriptions", zap.String("tenant", tenant), zap.Error(err))
+		return
+	}
+	for i := range results {
+		results[i].Subscribed = results[i].Service != nil && subscribed[results[i].Service.ID]
+	}
+}
+
+// trackSearchEvent publishes the search to the analytics hub
+func (s *Service) trackSearchEvent(req *SearchRequest, resp *SearchResponse, latency time.Duration, cacheHit bool) {
+	// Background searches aren't searches anyone made
+	if req.background {
+		return
+	}
+	resultIDs := make([]string, 0, len(resp.Results))
+	for _, result := range resp.Results {
+		if result.Service != nil {
+			resultIDs = append(resultIDs, result.Service.ID)
+		}
+	}
+
+	filters, err := json.Marshal(req.Filters)
+	if err != nil {
+		s.logger.Warn("Failed to encode search filters for analytics", zap.Error(err))
+	}
+
+	// Searches are tracked by what the user typed
+	typed := req.Query
+	if req.typed != "" {
+		typed = req.typed
+	}
+
+	s.analytics.Track(analytics.NewSearchEvent(req.UserID, &analytics.SearchEvent{
+		QueryID:   resp.QueryID,
+		Query:     typed,
+		Filters:   filters,
+		Total:     resp.Total,
+		ResultIDs: resultIDs,
+		Page:      resp.Page,
+		PageSize:  resp.PageSize,
+		LatencyMS: float64(latency.Microseconds()) / 1000,
+		CacheHit:  cacheHit,
+	}))
 }
 
 func min(a, b float64) float64 {
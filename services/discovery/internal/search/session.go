@@ -0,0 +1,136 @@
+package search
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/go-redis/redis/v8"
+	"go.uber.org/zap"
+
+	"github.com/org/llm-marketplace/services/discovery/internal/entitlement"
+)
+
+// Field prefixes of a session's Redis hash
+const (
+	sessionCategoryField  = "category:"
+	sessionDismissedField = "dismissed:"
+)
+
+// SessionSignals are what a browsing session did so far
+type SessionSignals struct {
+	Clicks    map[string]int // Clicks by category
+	Dismissed map[string]int // Dismissals by provider ID
+}
+
+// sessionKey is scoped to the caller, so a session ID can't carry signals
+// between tenants or users
+func sessionKey(ctx context.Context, sessionID string) string {
+	return "session:" + entitlement.FromContext(ctx).CacheScope() + ":" + sessionID
+}
+
+// RecordSessionClick counts a click on a service towards its category in the
+// session. It does nothing when session re-ranking is off.
+func (s *Service) RecordSessionClick(ctx context.Context, sessionID, serviceID string) error {
+	if !s.config.Search.Session.Enabled || sessionID == "" {
+		return nil
+	}
+	service, err := s.GetServiceByID(ctx, serviceID)
+	if err != nil {
+		return err
+	}
+	return s.recordSessionSignal(ctx, sessionID, sessionCategoryField+service.Category)
+}
+
+// RecordSessionDismissal counts a dismissal of a service against its
+// provider in the session. It does nothing when session re-ranking is off.
+func (s *Service) RecordSessionDismissal(ctx context.Context, sessionID, serviceID string) error {
+	if !s.config.Search.Session.Enabled || sessionID == "" {
+		return nil
+	}
+	service, err := s.GetServiceByID(ctx, serviceID)
+	if err != nil {
+		return err
+	}
+	if service.Provider.ID == "" {
+		return nil
+	}
+	return s.recordSessionSignal(ctx, sessionID, sessionDismissedField+service.Provider.ID)
+}
+
+// recordSessionSignal increments field and extends the session's TTL
+func (s *Service) recordSessionSignal(ctx context.Context, sessionID, field string) error {
+	key := sessionKey(ctx, sessionID)
+	_, err := s.redisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HIncrBy(ctx, key, field, 1)
+		pipe.Expire(ctx, key, s.config.Search.Session.TTL)
+		return nil
+	})
+	return err
+}
+
+// sessionSignals loads a session's signals; both maps are empty for an
+// unknown or expired session
+func (s *Service) sessionSignals(ctx context.Context, sessionID string) (*SessionSignals, error) {
+	fields, err := s.redisClient.HGetAll(ctx, sessionKey(ctx, sessionID)).Result()
+	if err != nil {
+		return nil, err
+	}
+	signals := &SessionSignals{Clicks: map[string]int{}, Dismissed: map[string]int{}}
+	for field, value := range fields {
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			continue
+		}
+		switch {
+		case strings.HasPrefix(field, sessionCategoryField):
+			signals.Clicks[strings.TrimPrefix(field, sessionCategoryField)] = n
+		case strings.HasPrefix(field, sessionDismissedField):
+			signals.Dismissed[strings.TrimPrefix(field, sessionDismissedField)] = n
+		}
+	}
+	return signals, nil
+}
+
+// rerankForSession adjusts ranked results by the session's signals: a
+// category's services gain category_boost times its share of the session's
+// clicks, and a dismissed provider's services are multiplied by
+// dismiss_factor. Results are left as ranked when the signals can't be read.
+func (s *Service) rerankForSession(ctx context.Context, sessionID string, results []SearchResult) {
+	cfg := s.config.Search.Session
+	if !cfg.Enabled || sessionID == "" || len(results) == 0 {
+		return
+	}
+	signals, err := s.sessionSignals(ctx, sessionID)
+	if err != nil {
+		s.logger.Warn("Failed to load session signals", zap.Error(err))
+		return
+	}
+
+	clicks := 0
+	for _, n := range signals.Clicks {
+		clicks += n
+	}
+	if clicks == 0 && len(signals.Dismissed) == 0 {
+		return
+	}
+
+	for i := range results {
+		svc := results[i].Service
+		if svc == nil {
+			continue
+		}
+		factor := 1.0
+		if n := signals.Clicks[svc.Category]; n > 0 {
+			factor += cfg.CategoryBoost * float64(n) / float64(clicks)
+		}
+		if signals.Dismissed[svc.Provider.ID] > 0 {
+			factor *= cfg.DismissFactor
+		}
+		if factor != 1 {
+			results[i].Score *= factor
+			results[i].MatchDetails.SessionFactor = factor
+		}
+	}
+	sortByScore(results)
+}
@@ -0,0 +1,216 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/org/llm-marketplace/services/discovery/internal/analytics"
+	"github.com/org/llm-marketplace/services/discovery/internal/entitlement"
+	"go.uber.org/zap"
+)
+
+// suggestion is a candidate from one autocomplete source
+type suggestion struct {
+	text  string
+	score float64
+}
+
+// Autocomplete provides search suggestions from service names and popular past
+// queries. A category limits names to services in it or its descendants;
+// popular queries aren't categorized, so they're only suggested without one.
+func (s *Service) Autocomplete(ctx context.Context, query, category string, limit int) ([]string, error) {
+	if limit <= 0 || limit > 50 {
+		limit = 10
+	}
+
+	var categories []string
+	if category != "" {
+		tax := s.loadTaxonomy(ctx)
+		if err := tax.Validate(category); err != nil {
+			return nil, err
+		}
+		categories = tax.Expand([]string{category})
+	}
+
+	cfg := s.config.Search.Autocomplete
+	nameSuggestions := s.nameSuggestions
+	if cfg.Suggester {
+		nameSuggestions = s.completionSuggestions
+	}
+	names, err := nameSuggestions(ctx, query, categories, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	var queries []suggestion
+	if cfg.QueryWeight > 0 && category == "" {
+		queries, err = s.querySuggestions(ctx, query, limit)
+		if err != nil {
+			// Names alone are still useful
+			s.logger.Warn("Failed to load query suggestions", zap.Error(err))
+		}
+	}
+
+	return mergeSuggestions(limit,
+		weightedSource{suggestions: names, weight: cfg.NameWeight},
+		weightedSource{suggestions: queries, weight: cfg.QueryWeight},
+	), nil
+}
+
+// completionSuggestions reads service names from the completion suggester,
+// ranked by popularity
+func (s *Service) completionSuggestions(ctx context.Context, query string, categories []string, limit int) ([]suggestion, error) {
+	caller := entitlement.FromContext(ctx)
+	hits, err := s.esClient.Suggest(ctx, query, caller.Audiences(), categories, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	suggestions := make([]suggestion, 0, len(hits))
+	for _, hit := range hits {
+		if !caller.CanView(hit.Source.Access) {
+			continue
+		}
+		suggestions = append(suggestions, suggestion{text: hit.Source.Name, score: hit.Score})
+	}
+	return suggestions, nil
+}
+
+// nameSuggestions matches service names as the user types
+func (s *Service) nameSuggestions(ctx context.Context, query string, categories []string, limit int) ([]suggestion, error) {
+	filters := []interface{}{
+		entitlement.FromContext(ctx).Filter().Source(),
+	}
+	if len(categories) > 0 {
+		filters = append(filters, map[string]interface{}{
+			"terms": map[string]interface{}{"category": categories},
+		})
+	}
+
+	// Use the autocomplete analyzer
+	esQuery := map[string]interface{}{
+		"size": limit,
+		"query": map[string]interface{}{
+			"bool": map[string]interface{}{
+				"must": map[string]interface{}{
+					"multi_match": map[string]interface{}{
+						"query": query,
+						"fields": []string{
+							"name.autocomplete^2",
+							"name^1",
+						},
+						"type": "bool_prefix",
+					},
+				},
+				"filter": filters,
+			},
+		},
+		"_source": []string{"name", "access"},
+	}
+
+	resp, err := s.esClient.Search(ctx, esQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	suggestions := make([]suggestion, 0, len(resp.Hits.Hits))
+	caller := entitlement.FromContext(ctx)
+	for _, hit := range resp.Hits.Hits {
+		if !caller.CanView(hit.Source.Access) {
+			continue
+		}
+		suggestions = append(suggestions, suggestion{text: hit.Source.Name, score: hit.Score})
+	}
+
+	return suggestions, nil
+}
+
+// querySuggestions returns popular past queries starting with the prefix, ranked by decayed frequency
+func (s *Service) querySuggestions(ctx context.Context, prefix string, limit int) ([]suggestion, error) {
+	prefix = analytics.NormalizeQuery(prefix)
+	if prefix == "" {
+		return nil, nil
+	}
+
+	cfg := s.config.Search.Autocomplete
+
+	query := `
+		SELECT query, score * power(0.5, EXTRACT(EPOCH FROM (NOW() - last_seen_at)) / $3) AS decayed
+		FROM query_suggestions
+		WHERE query LIKE $1 ESCAPE '\' AND searches >= $4
+		ORDER BY decayed DESC
+		LIMIT $2
+	`
+
+	rows, err := s.pgPool.Query(ctx, query, escapeLike(prefix)+"%", limit, cfg.QueryHalfLife.Seconds(), cfg.MinQueryCount)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query suggestions: %w", err)
+	}
+	defer rows.Close()
+
+	var suggestions []suggestion
+	for rows.Next() {
+		var sg suggestion
+		if err := rows.Scan(&sg.text, &sg.score); err != nil {
+			return nil, fmt.Errorf("failed to scan suggestion: %w", err)
+		}
+		suggestions = append(suggestions, sg)
+	}
+	return suggestions, rows.Err()
+}
+
+type weightedSource struct {
+	suggestions []suggestion
+	weight      float64
+}
+
+// mergeSuggestions scales each source to its best score, applies the weights and
+// sums scores for text suggested by more than one source
+func mergeSuggestions(limit int, sources ...weightedSource) []string {
+	merged := make(map[string]*suggestion)
+	var order []string
+
+	for _, source := range sources {
+		best := 0.0
+		for _, sg := range source.suggestions {
+			if sg.score > best {
+				best = sg.score
+			}
+		}
+
+		for _, sg := range source.suggestions {
+			score := source.weight
+			if best > 0 {
+				score *= sg.score / best
+			}
+
+			key := strings.ToLower(sg.text)
+			if existing, ok := merged[key]; ok {
+				existing.score += score
+				continue
+			}
+			merged[key] = &suggestion{text: sg.text, score: score}
+			order = append(order, key)
+		}
+	}
+
+	// Stable sort keeps earlier sources first on ties
+	sort.SliceStable(order, func(i, j int) bool {
+		return merged[order[i]].score > merged[order[j]].score
+	})
+
+	suggestions := []string{}
+	for _, key := range order {
+		if len(suggestions) == limit {
+			break
+		}
+		suggestions = append(suggestions, merged[key].text)
+	}
+	return suggestions
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
@@ -0,0 +1,57 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// searchSummary serves aggregations_only and count_only searches. Elasticsearch
+// returns no hits, so ranking, projection and entity groups are skipped, and
+// the response is cached under its own TTL since counts go stale slowly.
+func (s *Service) searchSummary(ctx context.Context, req *SearchRequest, cacheKey string, startTime time.Time) (*SearchResponse, error) {
+	esQuery, err := s.buildSearchQuery(ctx, req)
+	if err != nil {
+		s.logger.Error("Failed to build search query", zap.Error(err))
+		return nil, fmt.Errorf("failed to build query: %w", err)
+	}
+
+	s.boundQuery(ctx, esQuery)
+	esResponse, err := s.esClient.Search(ctx, esQuery)
+	if err != nil {
+		s.logger.Error("Search failed", zap.Error(err))
+		s.metrics.SearchError()
+		return nil, fmt.Errorf("search failed: %w", err)
+	}
+	partial, err := s.partialResults(esResponse)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &SearchResponse{
+		Results: []SearchResult{},
+		Total:   esResponse.Hits.Total.Value,
+		Took:    esResponse.Took,
+		Partial: partial,
+	}
+
+	ttlName := "search_counts"
+	if !req.CountOnly {
+		ttlName = "search_aggregations"
+		if response.Aggregations, err = s.facetResults(ctx, esResponse); err != nil {
+			return nil, fmt.Errorf("search failed: %w", err)
+		}
+	}
+
+	if !partial {
+		if err := s.cacheResults(ctx, cacheKey, response, ttlName); err != nil {
+			s.logger.Warn("Failed to cache results", zap.Error(err))
+		}
+	}
+
+	s.metrics.SearchDuration(ctx, time.Since(startTime))
+
+	return response, nil
+}
@@ -0,0 +1,145 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/org/llm-marketplace/services/discovery/internal/elasticsearch"
+	"github.com/org/llm-marketplace/services/discovery/internal/entitlement"
+	"go.uber.org/zap"
+)
+
+// ErrInvalidVersion is returned when a published version is rejected
+var ErrInvalidVersion = errors.New("invalid service version")
+
+// VersionHistory lists the published versions of a service, newest first
+type VersionHistory struct {
+	ServiceKey string           `json:"service_key"`
+	LatestID   string           `json:"latest_id,omitempty"` // Latest stable version; empty if none is stable
+	Versions   []VersionSummary `json:"versions"`
+}
+
+// VersionSummary is one entry in a service's version history
+type VersionSummary struct {
+	ID         string     `json:"id"`
+	Number     string     `json:"number,omitempty"`
+	Status     string     `json:"status"`
+	Stable     bool       `json:"stable"`
+	Latest     bool       `json:"latest"`
+	Changelog  string     `json:"changelog,omitempty"`
+	ReleasedAt *time.Time `json:"released_at,omitempty"`
+	URL        string     `json:"url"`
+}
+
+// Versions returns the version history of the service that id belongs to
+func (s *Service) Versions(ctx context.Context, id string) (*VersionHistory, error) {
+	service, err := s.GetServiceByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	key := service.Key()
+	all, err := s.esClient.Versions(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load versions: %w", err)
+	}
+	versions := entitlement.FromContext(ctx).Visible(all)
+
+	history := &VersionHistory{
+		ServiceKey: key,
+		Versions:   make([]VersionSummary, 0, len(versions)),
+	}
+	if latest := elasticsearch.LatestStable(versions); latest != nil {
+		history.LatestID = latest.ID
+	}
+
+	for _, v := range versions {
+		summary := VersionSummary{
+			ID:     v.ID,
+			Status: v.Status,
+			Latest: v.ID == history.LatestID,
+			URL:    "/api/v1/services/" + v.ID,
+		}
+		if v.Version != nil {
+			summary.Number = v.Version.Number
+			summary.Stable = v.Version.Stable
+			summary.Changelog = v.Version.Changelog
+			releasedAt := v.Version.ReleasedAt
+			summary.ReleasedAt = &releasedAt
+		}
+		history.Versions = append(history.Versions, summary)
+	}
+
+	return history, nil
+}
+
+// PublishVersion indexes a new or updated version and moves the latest flag if needed
+func (s *Service) PublishVersion(ctx context.Context, doc *elasticsearch.ServiceDocument) error {
+	if doc.ID == "" || doc.Version == nil || doc.Version.Number == "" {
+		return fmt.Errorf("%w: id and version number are required", ErrInvalidVersion)
+	}
+	if doc.ServiceKey == "" {
+		doc.ServiceKey = doc.ID
+	}
+	if doc.Version.ReleasedAt.IsZero() {
+		doc.Version.ReleasedAt = time.Now().UTC()
+	}
+
+	previous, err := s.esClient.Get(ctx, doc.ID)
+	if errors.Is(err, elasticsearch.ErrNotFound) {
+		previous = nil
+	} else if err != nil {
+		return err
+	}
+
+	if s.NeedsEmbedding(doc) {
+		// A missing embedding only costs semantic recall; the backfill job picks it up later
+		if err := s.EmbedDocuments(ctx, []*elasticsearch.ServiceDocument{doc}); err != nil {
+			s.logger.Warn("Indexing version without embedding", zap.String("id", doc.ID), zap.Error(err))
+		}
+	}
+
+	if err := s.esClient.Index(ctx, doc); err != nil {
+		return fmt.Errorf("failed to index version: %w", err)
+	}
+
+	if err := s.refreshLatestVersion(ctx, doc.ServiceKey); err != nil {
+		return err
+	}
+
+	s.notifyChange(ctx, previous, doc)
+	return nil
+}
+
+// refreshLatestVersion sets the latest flag on the newest stable version and clears it elsewhere
+func (s *Service) refreshLatestVersion(ctx context.Context, serviceKey string) error {
+	versions, err := s.esClient.Versions(ctx, serviceKey)
+	if err != nil {
+		return fmt.Errorf("failed to load versions: %w", err)
+	}
+
+	latest := elasticsearch.LatestStable(versions)
+	for _, v := range versions {
+		if v.Version == nil {
+			continue
+		}
+		isLatest := latest != nil && v.ID == latest.ID
+		if v.Version.Latest == isLatest {
+			continue
+		}
+
+		update := map[string]interface{}{
+			"version": map[string]interface{}{"latest": isLatest},
+		}
+		if err := s.esClient.UpdateFields(ctx, v.ID, update); err != nil {
+			return fmt.Errorf("failed to update latest version: %w", err)
+		}
+		if err := s.redisClient.Del(ctx, fmt.Sprintf("service:%s", v.ID)).Err(); err != nil {
+			s.logger.Warn("Failed to invalidate service cache", zap.String("id", v.ID), zap.Error(err))
+		}
+	}
+
+	return nil
+}
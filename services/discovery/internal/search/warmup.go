@@ -0,0 +1,92 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
+)
+
+// warmupPageSize is the page searches get when the caller doesn't set one
+const warmupPageSize = 20
+
+// PopularQueries returns the most popular past queries, ranked by decayed
+// frequency as autocomplete ranks them
+func (s *Service) PopularQueries(ctx context.Context, limit int) ([]string, error) {
+	if limit <= 0 {
+		return nil, nil
+	}
+	cfg := s.config.Search.Autocomplete
+
+	query := `
+		SELECT query
+		FROM query_suggestions
+		WHERE searches >= $2
+		ORDER BY score * power(0.5, EXTRACT(EPOCH FROM (NOW() - last_seen_at)) / $3) DESC
+		LIMIT $1
+	`
+
+	rows, err := s.pgPool.Query(ctx, query, limit, cfg.MinQueryCount, cfg.QueryHalfLife.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("failed to query popular queries: %w", err)
+	}
+	defer rows.Close()
+
+	var queries []string
+	for rows.Next() {
+		var q string
+		if err := rows.Scan(&q); err != nil {
+			return nil, fmt.Errorf("failed to scan popular query: %w", err)
+		}
+		queries = append(queries, q)
+	}
+	return queries, rows.Err()
+}
+
+// Warm primes the caches a new instance would otherwise fill from its first
+// requests: categories, tags and the first page of each query, searched
+// search.warmup.concurrency at a time. Failures are logged rather than
+// returned; a query that couldn't be primed is searched when it's asked for.
+// Warm-up searches aren't tracked.
+func (s *Service) Warm(ctx context.Context, queries []string) {
+	start := time.Now()
+
+	if _, err := s.GetCategories(ctx); err != nil {
+		s.logger.Warn("Failed to prime categories", zap.Error(err))
+	}
+	if _, err := s.GetTags(ctx); err != nil {
+		s.logger.Warn("Failed to prime tags", zap.Error(err))
+	}
+
+	var group errgroup.Group
+	group.SetLimit(max(s.config.Search.Warmup.Concurrency, 1))
+	var primed atomic.Int64
+	for _, query := range queries {
+		group.Go(func() error {
+			req := &SearchRequest{
+				Query:      query,
+				Pagination: PaginationRequest{PageSize: warmupPageSize},
+				background: true,
+			}
+			if _, err := s.Search(ctx, req); err != nil {
+				s.logger.Debug("Failed to prime search", zap.String("query", query), zap.Error(err))
+				s.metrics.CacheWarmupQuery("failed")
+				return nil
+			}
+			primed.Add(1)
+			s.metrics.CacheWarmupQuery("primed")
+			return nil
+		})
+	}
+	group.Wait()
+
+	s.metrics.CacheWarmupFinished(time.Since(start))
+	s.logger.Info("Caches warmed",
+		zap.Int("queries", len(queries)),
+		zap.Int64("primed", primed.Load()),
+		zap.Duration("duration", time.Since(start)),
+	)
+}
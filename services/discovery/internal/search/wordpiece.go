@@ -0,0 +1,144 @@
+package search
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+)
+
+// maxWordChars is the longest word WordPiece splits; longer words become the unknown token
+const maxWordChars = 100
+
+// wordPieceTokenizer is the uncased WordPiece tokenizer used by BERT-style
+// sentence-transformers. Special tokens are looked up under both the BERT
+// ([CLS]) and RoBERTa/MPNet (<s>) spellings.
+type wordPieceTokenizer struct {
+	vocab     map[string]int64
+	cls       int64
+	sep       int64
+	unk       int64
+	pad       int64
+	maxTokens int
+}
+
+func loadWordPieceTokenizer(path string, maxTokens int) (*wordPieceTokenizer, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open vocabulary: %w", err)
+	}
+	defer f.Close()
+
+	vocab := make(map[string]int64)
+	scanner := bufio.NewScanner(f)
+	for id := int64(0); scanner.Scan(); id++ {
+		vocab[strings.TrimRight(scanner.Text(), "\r")] = id
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read vocabulary: %w", err)
+	}
+
+	t := &wordPieceTokenizer{vocab: vocab, maxTokens: maxTokens}
+	for _, special := range []struct {
+		dst   *int64
+		names []string
+	}{
+		{&t.cls, []string{"[CLS]", "<s>"}},
+		{&t.sep, []string{"[SEP]", "</s>"}},
+		{&t.unk, []string{"[UNK]", "<unk>"}},
+	} {
+		found := false
+		for _, name := range special.names {
+			if id, ok := vocab[name]; ok {
+				*special.dst = id
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("vocabulary has no %s token", special.names[0])
+		}
+	}
+	// Padding is masked out, so any id works when the vocabulary has none
+	if id, ok := vocab["[PAD]"]; ok {
+		t.pad = id
+	} else if id, ok := vocab["<pad>"]; ok {
+		t.pad = id
+	}
+	if t.maxTokens <= 2 {
+		t.maxTokens = 512
+	}
+	return t, nil
+}
+
+// Encode returns the token ids for text wrapped in the start and end tokens,
+// truncated to maxTokens
+func (t *wordPieceTokenizer) Encode(text string) []int64 {
+	ids := []int64{t.cls}
+	for _, word := range splitWords(strings.ToLower(text)) {
+		ids = append(ids, t.wordPieces(word)...)
+		if len(ids) >= t.maxTokens-1 {
+			ids = ids[:t.maxTokens-1]
+			break
+		}
+	}
+	return append(ids, t.sep)
+}
+
+// wordPieces splits word greedily into the longest vocabulary entries, marking
+// continuations with ##
+func (t *wordPieceTokenizer) wordPieces(word string) []int64 {
+	runes := []rune(word)
+	if len(runes) > maxWordChars {
+		return []int64{t.unk}
+	}
+
+	var pieces []int64
+	for start := 0; start < len(runes); {
+		end := len(runes)
+		var id int64 = -1
+		for ; end > start; end-- {
+			piece := string(runes[start:end])
+			if start > 0 {
+				piece = "##" + piece
+			}
+			if v, ok := t.vocab[piece]; ok {
+				id = v
+				break
+			}
+		}
+		if id < 0 {
+			return []int64{t.unk}
+		}
+		pieces = append(pieces, id)
+		start = end
+	}
+	return pieces
+}
+
+// splitWords splits on whitespace and makes each punctuation character its own word
+func splitWords(text string) []string {
+	var words []string
+	var current strings.Builder
+	flush := func() {
+		if current.Len() > 0 {
+			words = append(words, current.String())
+			current.Reset()
+		}
+	}
+
+	for _, r := range text {
+		switch {
+		case unicode.IsSpace(r) || unicode.IsControl(r):
+			flush()
+		case unicode.IsPunct(r) || unicode.IsSymbol(r):
+			flush()
+			words = append(words, string(r))
+		default:
+			current.WriteRune(r)
+		}
+	}
+	flush()
+	return words
+}
@@ -0,0 +1,90 @@
+package sla
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/org/llm-marketplace/pkg/marketplace"
+	"github.com/segmentio/kafka-go"
+	"go.uber.org/zap"
+)
+
+// Breaches receives changes in a service's health
+type Breaches interface {
+	Publish(ctx context.Context, breach *marketplace.SLABreach) error
+}
+
+// SetBreaches registers the receiver of health changes. Without one, changes
+// are only reflected in the health summary.
+func (m *Monitor) SetBreaches(breaches Breaches) {
+	m.breaches = breaches
+}
+
+// publishBreach reports a change from previous to current. A service's
+// first healthy summary is not a change worth reporting.
+func (m *Monitor) publishBreach(ctx context.Context, previous, current *HealthSummary) {
+	if current.Status == previous.Status || current.Status == StatusUnknown ||
+		(previous.Status == StatusUnknown && current.Status == StatusHealthy) {
+		return
+	}
+
+	breach := &marketplace.SLABreach{
+		ID:             fmt.Sprintf("%s/%d", current.ServiceID, current.LastCheckedAt.UnixNano()),
+		OccurredAt:     current.LastCheckedAt.UTC(),
+		ServiceID:      current.ServiceID,
+		Status:         current.Status,
+		PreviousStatus: previous.Status,
+		Availability:   current.Availability,
+		AvgLatencyMS:   current.AvgLatencyMS,
+		ErrorRate:      current.ErrorRate,
+	}
+	if doc, err := m.esClient.Get(ctx, current.ServiceID); err == nil {
+		breach.ServiceName = doc.Name
+		breach.ProviderID = doc.Provider.ID
+	}
+
+	if err := m.breaches.Publish(ctx, breach); err != nil {
+		m.logger.Warn("Failed to publish SLA breach",
+			zap.String("service_id", current.ServiceID),
+			zap.String("status", current.Status),
+			zap.Error(err),
+		)
+		return
+	}
+	m.logger.Info("Service health changed",
+		zap.String("service_id", current.ServiceID),
+		zap.String("previous_status", previous.Status),
+		zap.String("status", current.Status),
+	)
+}
+
+// BreachPublisher publishes health changes to Kafka, keyed by service ID
+type BreachPublisher struct {
+	writer *kafka.Writer
+}
+
+// NewBreachPublisher creates a publisher writing to topic
+func NewBreachPublisher(brokers []string, topic string) *BreachPublisher {
+	return &BreachPublisher{writer: &kafka.Writer{
+		Addr:         kafka.TCP(brokers...),
+		Topic:        topic,
+		Balancer:     &kafka.Hash{},
+		BatchTimeout: 10 * time.Millisecond,
+		RequiredAcks: kafka.RequireAll,
+	}}
+}
+
+func (p *BreachPublisher) Publish(ctx context.Context, breach *marketplace.SLABreach) error {
+	payload, err := json.Marshal(breach)
+	if err != nil {
+		return err
+	}
+	return p.writer.WriteMessages(ctx, kafka.Message{Key: []byte(breach.ServiceID), Value: payload})
+}
+
+// Close flushes pending writes
+func (p *BreachPublisher) Close() error {
+	return p.writer.Close()
+}
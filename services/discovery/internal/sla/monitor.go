@@ -0,0 +1,287 @@
+package sla
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/org/llm-marketplace/pkg/marketplace/egress"
+	"github.com/org/llm-marketplace/services/discovery/internal/config"
+	"github.com/org/llm-marketplace/services/discovery/internal/elasticsearch"
+	"github.com/org/llm-marketplace/services/discovery/internal/observability"
+	"go.uber.org/zap"
+)
+
+// Health badge states
+const (
+	StatusHealthy  = "healthy"
+	StatusDegraded = "degraded"
+	StatusDown     = "down"
+	StatusUnknown  = "unknown"
+)
+
+// Sample sources
+const (
+	SourceProbe    = "probe"
+	SourceProvider = "provider"
+)
+
+// reportClockSkew is how far ahead of this server's clock a provider's
+// reported_at may be
+const reportClockSkew = time.Minute
+
+var (
+	// ErrNotProvider is returned when a health report doesn't come from the
+	// service's own provider
+	ErrNotProvider = errors.New("only the service's provider can report its health")
+
+	// ErrInvalidReport is returned for reports with inconsistent counts or a
+	// reported_at outside the accepted range
+	ErrInvalidReport = errors.New("invalid health report")
+)
+
+// Monitor tracks rolling availability and latency for listed services
+type Monitor struct {
+	esClient    *elasticsearch.Client
+	redisClient *redis.Client
+	config      config.SLAMonitoringConfig
+	logger      *zap.Logger
+	metrics     *observability.Metrics
+	httpClient  *http.Client
+	breaches    Breaches
+}
+
+func NewMonitor(
+	esClient *elasticsearch.Client,
+	redisClient *redis.Client,
+	cfg config.SLAMonitoringConfig,
+	logger *zap.Logger,
+	metrics *observability.Metrics,
+) *Monitor {
+	return &Monitor{
+		esClient:    esClient,
+		redisClient: redisClient,
+		config:      cfg,
+		logger:      logger,
+		metrics:     metrics,
+		// Endpoints are given by providers, so probes must not reach the
+		// marketplace's own network
+		httpClient: egress.Client(cfg.ProbeTimeout),
+	}
+}
+
+// Sample is one observation of a service, either a single probe or an aggregated provider report
+type Sample struct {
+	Timestamp time.Time `json:"timestamp"`
+	Source    string    `json:"source"`
+	Checks    int       `json:"checks"`
+	Failures  int       `json:"failures"`
+	LatencyMS float64   `json:"latency_ms"`
+}
+
+// ProviderReport is uptime data reported by a provider for a period
+type ProviderReport struct {
+	Checks       int       `json:"checks" binding:"required,min=1"`
+	Failures     int       `json:"failures" binding:"min=0"`
+	AvgLatencyMS float64   `json:"avg_latency_ms" binding:"min=0"`
+	ReportedAt   time.Time `json:"reported_at"`
+}
+
+// HealthSummary is the rolling health state of a service
+type HealthSummary struct {
+	ServiceID     string     `json:"service_id"`
+	Status        string     `json:"status"`
+	Availability  float64    `json:"availability"`
+	AvgLatencyMS  float64    `json:"avg_latency_ms"`
+	ErrorRate     float64    `json:"error_rate"`
+	Samples       int        `json:"samples"`
+	LastCheckedAt *time.Time `json:"last_checked_at,omitempty"`
+}
+
+// GetHealth returns the rolling health summary for a service
+func (m *Monitor) GetHealth(ctx context.Context, serviceID string) (*HealthSummary, error) {
+	samples, err := m.getSamples(ctx, serviceID)
+	if err != nil {
+		return nil, err
+	}
+
+	return summarize(serviceID, samples, m.config.DegradedLatencyMS), nil
+}
+
+// RecordProviderReport ingests provider-reported uptime for a service.
+// providerID is the caller's provider organisation, set by the gateway, and
+// must be the one the service is listed under.
+func (m *Monitor) RecordProviderReport(ctx context.Context, serviceID, providerID string, report *ProviderReport) (*HealthSummary, error) {
+	if report.Failures > report.Checks {
+		return nil, fmt.Errorf("%w: failures (%d) cannot exceed checks (%d)", ErrInvalidReport, report.Failures, report.Checks)
+	}
+
+	service, err := m.esClient.Get(ctx, serviceID)
+	if err != nil {
+		return nil, err
+	}
+	if providerID == "" || service.Provider.ID != providerID {
+		return nil, ErrNotProvider
+	}
+
+	now := time.Now().UTC()
+	timestamp := report.ReportedAt
+	switch {
+	case timestamp.IsZero():
+		timestamp = now
+	case timestamp.After(now.Add(reportClockSkew)):
+		return nil, fmt.Errorf("%w: reported_at is in the future", ErrInvalidReport)
+	case m.config.ReportMaxAge > 0 && timestamp.Before(now.Add(-m.config.ReportMaxAge)):
+		return nil, fmt.Errorf("%w: reported_at is more than %s ago", ErrInvalidReport, m.config.ReportMaxAge)
+	}
+
+	sample := Sample{
+		Timestamp: timestamp,
+		Source:    SourceProvider,
+		Checks:    report.Checks,
+		Failures:  report.Failures,
+		LatencyMS: report.AvgLatencyMS,
+	}
+
+	return m.record(ctx, serviceID, sample)
+}
+
+// record stores a sample, recomputes the summary, and pushes it to the index
+func (m *Monitor) record(ctx context.Context, serviceID string, sample Sample) (*HealthSummary, error) {
+	// The status before the sample, to report changes
+	var previous *HealthSummary
+	if m.breaches != nil {
+		previous, _ = m.GetHealth(ctx, serviceID)
+	}
+
+	if err := m.appendSample(ctx, serviceID, sample); err != nil {
+		return nil, fmt.Errorf("failed to store sample: %w", err)
+	}
+
+	summary, err := m.GetHealth(ctx, serviceID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = m.esClient.UpdateFields(ctx, serviceID, map[string]interface{}{
+		"metrics": map[string]interface{}{
+			"avg_latency_ms": summary.AvgLatencyMS,
+			"error_rate":     summary.ErrorRate,
+		},
+	})
+	if err != nil {
+		m.logger.Warn("Failed to update service metrics in index",
+			zap.String("service_id", serviceID),
+			zap.Error(err),
+		)
+	}
+
+	if previous != nil {
+		m.publishBreach(ctx, previous, summary)
+	}
+
+	return summary, nil
+}
+
+func sampleKey(serviceID string) string {
+	return fmt.Sprintf("sla:samples:%s", serviceID)
+}
+
+func (m *Monitor) appendSample(ctx context.Context, serviceID string, sample Sample) error {
+	data, err := json.Marshal(sample)
+	if err != nil {
+		return err
+	}
+
+	key := sampleKey(serviceID)
+	pipe := m.redisClient.TxPipeline()
+	pipe.LPush(ctx, key, data)
+	pipe.LTrim(ctx, key, 0, int64(m.windowSize()-1))
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+func (m *Monitor) getSamples(ctx context.Context, serviceID string) ([]Sample, error) {
+	values, err := m.redisClient.LRange(ctx, sampleKey(serviceID), 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	samples := make([]Sample, 0, len(values))
+	for _, value := range values {
+		var sample Sample
+		if err := json.Unmarshal([]byte(value), &sample); err != nil {
+			continue
+		}
+		samples = append(samples, sample)
+	}
+
+	return samples, nil
+}
+
+func (m *Monitor) windowSize() int {
+	if m.config.WindowSize <= 0 {
+		return 1440
+	}
+	return m.config.WindowSize
+}
+
+// summarize computes rolling health from samples ordered newest first
+func summarize(serviceID string, samples []Sample, degradedLatencyMS float64) *HealthSummary {
+	summary := &HealthSummary{
+		ServiceID: serviceID,
+		Status:    StatusUnknown,
+		Samples:   len(samples),
+	}
+
+	if len(samples) == 0 {
+		return summary
+	}
+
+	var checks, failures int
+	var latencyTotal float64
+	var latencyWeight int
+
+	for _, sample := range samples {
+		checks += sample.Checks
+		failures += sample.Failures
+
+		// Latency is only meaningful for successful checks
+		if succeeded := sample.Checks - sample.Failures; succeeded > 0 {
+			latencyTotal += sample.LatencyMS * float64(succeeded)
+			latencyWeight += succeeded
+		}
+	}
+
+	if checks == 0 {
+		return summary
+	}
+
+	lastChecked := samples[0].Timestamp
+	summary.LastCheckedAt = &lastChecked
+	summary.ErrorRate = float64(failures) / float64(checks)
+	summary.Availability = (1.0 - summary.ErrorRate) * 100.0
+	if latencyWeight > 0 {
+		summary.AvgLatencyMS = latencyTotal / float64(latencyWeight)
+	}
+
+	latest := samples[0]
+	switch {
+	case latest.Checks > 0 && latest.Failures == latest.Checks:
+		summary.Status = StatusDown
+	case summary.Availability < 95.0:
+		summary.Status = StatusDown
+	case summary.Availability < 99.0:
+		summary.Status = StatusDegraded
+	case degradedLatencyMS > 0 && summary.AvgLatencyMS > degradedLatencyMS:
+		summary.Status = StatusDegraded
+	default:
+		summary.Status = StatusHealthy
+	}
+
+	return summary
+}
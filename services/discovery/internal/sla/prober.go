@@ -0,0 +1,178 @@
+package sla
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/org/llm-marketplace/services/discovery/internal/elasticsearch"
+	"go.uber.org/zap"
+)
+
+// Start runs the prober until ctx is cancelled
+func (m *Monitor) Start(ctx context.Context) {
+	if !m.config.Enabled {
+		m.logger.Info("SLA monitoring is disabled")
+		return
+	}
+
+	interval := m.config.ProbeInterval
+	if interval <= 0 {
+		interval = time.Minute
+	}
+
+	m.logger.Info("Starting SLA prober", zap.Duration("interval", interval))
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	m.probeAll(ctx)
+
+	for {
+		select {
+		case <-ticker.C:
+			m.probeAll(ctx)
+		case <-ctx.Done():
+			m.logger.Info("SLA prober stopped")
+			return
+		}
+	}
+}
+
+// probeAll probes every searchable service that declares an endpoint
+func (m *Monitor) probeAll(ctx context.Context) {
+	// Probing covers every tenant's catalog
+	services, err := m.listProbeTargets(elasticsearch.WithAllTenants(ctx))
+	if err != nil {
+		m.logger.Error("Failed to list services to probe", zap.Error(err))
+		return
+	}
+
+	concurrency := m.config.Concurrency
+	if concurrency <= 0 {
+		concurrency = 10
+	}
+
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+
+	for _, svc := range services {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			wg.Wait()
+			return
+		}
+
+		wg.Add(1)
+		go func(id, endpoint, tenantID string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			sample := m.probe(ctx, endpoint)
+			if _, err := m.record(elasticsearch.WithTenant(ctx, tenantID), id, sample); err != nil {
+				m.logger.Warn("Failed to record probe result",
+					zap.String("service_id", id),
+					zap.Error(err),
+				)
+			}
+		}(svc.ID, svc.Endpoint, svc.TenantID)
+	}
+
+	wg.Wait()
+}
+
+// probe performs a single health check against a service endpoint
+func (m *Monitor) probe(ctx context.Context, endpoint string) Sample {
+	sample := Sample{
+		Timestamp: time.Now().UTC(),
+		Source:    SourceProbe,
+		Checks:    1,
+	}
+
+	start := time.Now()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err == nil && req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+		err = fmt.Errorf("unsupported scheme %q", req.URL.Scheme)
+	}
+	if err != nil {
+		sample.Failures = 1
+		m.metrics.SLAProbe("error", time.Since(start))
+		return sample
+	}
+
+	resp, err := m.httpClient.Do(req)
+	duration := time.Since(start)
+	sample.LatencyMS = float64(duration.Microseconds()) / 1000.0
+
+	if err != nil {
+		sample.Failures = 1
+		m.metrics.SLAProbe("error", duration)
+		return sample
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= 500 {
+		sample.Failures = 1
+		m.metrics.SLAProbe("failure", duration)
+		return sample
+	}
+
+	m.metrics.SLAProbe("success", duration)
+	return sample
+}
+
+// listProbeTargets pages through every searchable service with an endpoint,
+// max_services at a time
+func (m *Monitor) listProbeTargets(ctx context.Context) ([]elasticsearch.ServiceDocument, error) {
+	size := m.config.MaxServices
+	if size <= 0 {
+		size = 1000
+	}
+
+	query := map[string]interface{}{
+		"size":    size,
+		"_source": []string{"id", "endpoint", "tenant_id"},
+		"sort": []interface{}{
+			map[string]interface{}{"id": "asc"},
+		},
+		"query": map[string]interface{}{
+			"bool": map[string]interface{}{
+				"filter": []interface{}{
+					map[string]interface{}{
+						"terms": map[string]interface{}{
+							"status": elasticsearch.SearchableStatuses,
+						},
+					},
+					map[string]interface{}{
+						"exists": map[string]interface{}{
+							"field": "endpoint",
+						},
+					},
+				},
+			},
+		},
+	}
+
+	var services []elasticsearch.ServiceDocument
+	for {
+		resp, err := m.esClient.Search(ctx, query)
+		if err != nil {
+			return nil, err
+		}
+
+		hits := resp.Hits.Hits
+		for _, hit := range hits {
+			if hit.Source.Endpoint != "" {
+				services = append(services, hit.Source)
+			}
+		}
+
+		if len(hits) < size {
+			return services, nil
+		}
+		query["search_after"] = hits[len(hits)-1].Sort
+	}
+}
@@ -0,0 +1,389 @@
+// Package snapshot writes consistent snapshots of the whole catalog to object
+// storage, for partner syndication and disaster recovery
+package snapshot
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/org/llm-marketplace/services/discovery/internal/config"
+	"github.com/org/llm-marketplace/services/discovery/internal/elasticsearch"
+	"github.com/org/llm-marketplace/services/discovery/internal/worker"
+	"go.uber.org/zap"
+)
+
+// Job states
+const (
+	JobRunning   = "running"
+	JobCompleted = "completed"
+	JobFailed    = "failed"
+)
+
+// What started a snapshot
+const (
+	TriggerAPI      = "api"
+	TriggerSchedule = "schedule"
+)
+
+const (
+	lockKey = "catalog_snapshot:lock"
+	lockTTL = 10 * time.Minute
+	jobTTL  = 7 * 24 * time.Hour
+
+	// ManifestVersion is bumped when the manifest or file layout changes
+	ManifestVersion = 1
+)
+
+var (
+	// ErrJobNotFound is returned for unknown or expired snapshot jobs
+	ErrJobNotFound = errors.New("snapshot job not found")
+	// ErrRunning is returned when a snapshot is already being taken
+	ErrRunning = errors.New("catalog snapshot already running")
+)
+
+// Store is where snapshot objects are written
+type Store interface {
+	Put(ctx context.Context, key, contentType string, body io.ReadSeeker, size int64) error
+}
+
+// File is one object of a snapshot
+type File struct {
+	Key       string `json:"key"`
+	Documents int    `json:"documents"`
+	Bytes     int64  `json:"bytes"`
+	SHA256    string `json:"sha256"`
+}
+
+// Manifest describes a complete snapshot. It is written after the files it
+// lists, so a snapshot without one is incomplete and should be ignored.
+type Manifest struct {
+	Version           int       `json:"version"`
+	ID                string    `json:"id"`
+	Format            string    `json:"format"`
+	Index             string    `json:"index"`
+	TakenAt           time.Time `json:"taken_at"` // When the point in time was opened; the snapshot is the catalog as of then
+	CompletedAt       time.Time `json:"completed_at"`
+	Documents         int       `json:"documents"`
+	IncludeEmbeddings bool      `json:"include_embeddings"`
+	Files             []File    `json:"files"`
+}
+
+// Job tracks a snapshot being taken
+type Job struct {
+	ID          string     `json:"id"`
+	Status      string     `json:"status"`
+	Trigger     string     `json:"trigger"`
+	Documents   int        `json:"documents"`
+	ManifestKey string     `json:"manifest_key,omitempty"`
+	Error       string     `json:"error,omitempty"`
+	StartedAt   time.Time  `json:"started_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
+
+// Snapshotter takes catalog snapshots. Every service in every tenant catalog
+// is read from one Elasticsearch point in time with search_after, so the
+// snapshot is consistent however the index changes while it's taken.
+type Snapshotter struct {
+	esClient    *elasticsearch.Client
+	redisClient *redis.Client
+	store       Store
+	config      config.SnapshotConfig
+	indexName   string
+	logger      *zap.Logger
+	workers     *worker.Group
+}
+
+func NewSnapshotter(
+	esClient *elasticsearch.Client,
+	redisClient *redis.Client,
+	store Store,
+	cfg config.SnapshotConfig,
+	indexName string,
+	logger *zap.Logger,
+) *Snapshotter {
+	return &Snapshotter{
+		esClient:    esClient,
+		redisClient: redisClient,
+		store:       store,
+		config:      cfg,
+		indexName:   indexName,
+		logger:      logger,
+	}
+}
+
+// SetWorkers registers the group snapshots run in, so shutdown waits for them
+func (s *Snapshotter) SetWorkers(g *worker.Group) {
+	s.workers = g
+}
+
+// Start takes a snapshot every interval until ctx is cancelled. Replicas
+// claim each interval in Redis, so only one of them takes its snapshot.
+func (s *Snapshotter) Start(ctx context.Context) {
+	if s.config.Interval <= 0 {
+		s.logger.Info("Scheduled catalog snapshots are disabled")
+		return
+	}
+
+	s.logger.Info("Starting scheduled catalog snapshots", zap.Duration("interval", s.config.Interval))
+
+	ticker := time.NewTicker(s.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.scheduled(ctx)
+		case <-ctx.Done():
+			s.logger.Info("Scheduled catalog snapshots stopped")
+			return
+		}
+	}
+}
+
+func (s *Snapshotter) scheduled(ctx context.Context) {
+	slot := time.Now().Truncate(s.config.Interval).Unix()
+	claimed, err := s.redisClient.SetNX(ctx, fmt.Sprintf("catalog_snapshot:scheduled:%d", slot), 1, s.config.Interval).Result()
+	if err != nil {
+		s.logger.Error("Failed to claim scheduled catalog snapshot", zap.Error(err))
+		return
+	}
+	if !claimed {
+		return
+	}
+
+	if _, err := s.StartJob(ctx, TriggerSchedule); err != nil && !errors.Is(err, ErrRunning) {
+		s.logger.Error("Failed to start scheduled catalog snapshot", zap.Error(err))
+	}
+}
+
+// StartJob starts a snapshot in the background. Only one snapshot is taken
+// at a time across replicas.
+func (s *Snapshotter) StartJob(ctx context.Context, trigger string) (*Job, error) {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return nil, fmt.Errorf("failed to generate job id: %w", err)
+	}
+
+	now := time.Now().UTC()
+	job := &Job{
+		// Sortable, so listing the prefix lists snapshots oldest first
+		ID:        now.Format("20060102T150405Z") + "-" + hex.EncodeToString(b),
+		Status:    JobRunning,
+		Trigger:   trigger,
+		StartedAt: now,
+	}
+
+	acquired, err := s.redisClient.SetNX(ctx, lockKey, job.ID, lockTTL).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire snapshot lock: %w", err)
+	}
+	if !acquired {
+		return nil, ErrRunning
+	}
+
+	if err := s.saveJob(ctx, job); err != nil {
+		s.redisClient.Del(ctx, lockKey)
+		return nil, err
+	}
+
+	s.workers.Task("catalog_snapshot", func(ctx context.Context) {
+		s.run(ctx, job)
+	})
+
+	return job, nil
+}
+
+// GetJob returns the current state of a snapshot job
+func (s *Snapshotter) GetJob(ctx context.Context, id string) (*Job, error) {
+	data, err := s.redisClient.Get(ctx, jobKey(id)).Bytes()
+	if err == redis.Nil {
+		return nil, ErrJobNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load snapshot job: %w", err)
+	}
+
+	var job Job
+	if err := json.Unmarshal(data, &job); err != nil {
+		return nil, fmt.Errorf("failed to decode snapshot job: %w", err)
+	}
+	return &job, nil
+}
+
+func (s *Snapshotter) run(ctx context.Context, job *Job) {
+	// The snapshot covers every tenant's catalog and is not tied to the request
+	ctx, cancel := context.WithTimeout(elasticsearch.WithAllTenants(ctx), s.config.Timeout)
+	defer cancel()
+	defer s.redisClient.Del(context.WithoutCancel(ctx), lockKey)
+
+	manifest, err := s.take(ctx, job)
+
+	now := time.Now().UTC()
+	job.CompletedAt = &now
+	job.Status = JobCompleted
+	if err != nil {
+		job.Status = JobFailed
+		job.Error = err.Error()
+		s.logger.Error("Catalog snapshot failed", zap.String("job_id", job.ID), zap.Error(err))
+	} else {
+		job.Documents = manifest.Documents
+		s.logger.Info("Catalog snapshot completed",
+			zap.String("job_id", job.ID),
+			zap.String("manifest", job.ManifestKey),
+			zap.Int("documents", manifest.Documents),
+			zap.Duration("duration", now.Sub(job.StartedAt)),
+		)
+	}
+
+	// Record the outcome even when the run was cut off by shutdown
+	if err := s.saveJob(context.WithoutCancel(ctx), job); err != nil {
+		s.logger.Error("Failed to save snapshot job", zap.String("job_id", job.ID), zap.Error(err))
+	}
+}
+
+// take writes every service to a staged NDJSON file, uploads it, then
+// uploads the manifest
+func (s *Snapshotter) take(ctx context.Context, job *Job) (*Manifest, error) {
+	if err := os.MkdirAll(s.config.Directory, 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create snapshot directory: %w", err)
+	}
+	f, err := os.CreateTemp(s.config.Directory, job.ID+"-*.ndjson")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create snapshot file: %w", err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	manifest := &Manifest{
+		Version:           ManifestVersion,
+		ID:                job.ID,
+		Format:            "ndjson",
+		Index:             s.indexName,
+		IncludeEmbeddings: s.config.IncludeEmbeddings,
+	}
+
+	hash := sha256.New()
+	buf := bufio.NewWriter(io.MultiWriter(f, hash))
+	manifest.TakenAt = time.Now().UTC()
+	documents, err := s.write(ctx, job, buf)
+	if err != nil {
+		return nil, err
+	}
+	if err := buf.Flush(); err != nil {
+		return nil, fmt.Errorf("failed to write snapshot file: %w", err)
+	}
+	size, err := f.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write snapshot file: %w", err)
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("failed to rewind snapshot file: %w", err)
+	}
+
+	services := File{
+		Key:       s.key(job.ID, "services.ndjson"),
+		Documents: documents,
+		Bytes:     size,
+		SHA256:    hex.EncodeToString(hash.Sum(nil)),
+	}
+	if err := s.store.Put(ctx, services.Key, "application/x-ndjson", f, size); err != nil {
+		return nil, err
+	}
+
+	manifest.Documents = documents
+	manifest.Files = []File{services}
+	manifest.CompletedAt = time.Now().UTC()
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode manifest: %w", err)
+	}
+	job.ManifestKey = s.key(job.ID, "manifest.json")
+	if err := s.store.Put(ctx, job.ManifestKey, "application/json", bytes.NewReader(data), int64(len(data))); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// write pages through a point in time, writing each service as a line of w
+func (s *Snapshotter) write(ctx context.Context, job *Job, w io.Writer) (int, error) {
+	pitID, err := s.esClient.OpenPointInTime(ctx, s.config.KeepAlive)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err := s.esClient.ClosePointInTime(context.WithoutCancel(ctx), pitID); err != nil {
+			s.logger.Warn("Failed to close point in time", zap.String("job_id", job.ID), zap.Error(err))
+		}
+	}()
+
+	var excludes []string
+	if !s.config.IncludeEmbeddings {
+		excludes = elasticsearch.DefaultSourceExcludes
+	}
+
+	encoder := json.NewEncoder(w)
+	documents := 0
+	var after []interface{}
+	for {
+		resp, err := s.esClient.SearchPointInTime(ctx, pitID, s.config.KeepAlive, s.config.BatchSize, after, excludes)
+		if err != nil {
+			return documents, fmt.Errorf("failed to read services: %w", err)
+		}
+		pitID = resp.PitID
+
+		hits := resp.Hits.Hits
+		for i := range hits {
+			doc := &hits[i].Source
+			if doc.ID == "" {
+				doc.ID = hits[i].ID
+			}
+			if err := encoder.Encode(doc); err != nil {
+				return documents, fmt.Errorf("failed to write service %s: %w", doc.ID, err)
+			}
+			documents++
+		}
+
+		// Progress is best effort; the lock is extended so long runs keep it
+		job.Documents = documents
+		s.redisClient.Expire(ctx, lockKey, lockTTL)
+		if err := s.saveJob(ctx, job); err != nil {
+			s.logger.Warn("Failed to save snapshot progress", zap.String("job_id", job.ID), zap.Error(err))
+		}
+
+		if len(hits) < s.config.BatchSize {
+			return documents, nil
+		}
+		after = hits[len(hits)-1].Sort
+	}
+}
+
+func (s *Snapshotter) key(id, name string) string {
+	return path.Join(s.config.Prefix, id, name)
+}
+
+func (s *Snapshotter) saveJob(ctx context.Context, job *Job) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("failed to encode snapshot job: %w", err)
+	}
+	if err := s.redisClient.Set(ctx, jobKey(job.ID), data, jobTTL).Err(); err != nil {
+		return fmt.Errorf("failed to save snapshot job: %w", err)
+	}
+	return nil
+}
+
+func jobKey(id string) string {
+	return "catalog_snapshot:job:" + id
+}
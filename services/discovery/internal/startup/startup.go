@@ -0,0 +1,98 @@
+// Package startup waits for dependencies while the service starts. In a fresh
+// environment Postgres, Redis and Elasticsearch may come up after discovery;
+// retrying with backoff avoids a crash loop while they do.
+package startup
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"github.com/org/llm-marketplace/services/discovery/internal/config"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultMaxWait        = 2 * time.Minute
+	defaultInitialBackoff = 500 * time.Millisecond
+	defaultMaxBackoff     = 10 * time.Second
+)
+
+// Waiter retries startup steps that fail because a dependency is not up yet
+type Waiter struct {
+	config config.StartupConfig
+	logger *zap.Logger
+}
+
+// NewWaiter creates a waiter; unset settings take their defaults
+func NewWaiter(cfg config.StartupConfig, logger *zap.Logger) *Waiter {
+	if cfg.MaxWait <= 0 {
+		cfg.MaxWait = defaultMaxWait
+	}
+	if cfg.InitialBackoff <= 0 {
+		cfg.InitialBackoff = defaultInitialBackoff
+	}
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = defaultMaxBackoff
+	}
+	if cfg.MaxBackoff < cfg.InitialBackoff {
+		cfg.MaxBackoff = cfg.InitialBackoff
+	}
+	return &Waiter{config: cfg, logger: logger}
+}
+
+// Wait calls fn until it succeeds, backing off between attempts. It gives up
+// after MaxWait, returning the last error.
+func (w *Waiter) Wait(ctx context.Context, name string, fn func(ctx context.Context) error) error {
+	ctx, cancel := context.WithTimeout(ctx, w.config.MaxWait)
+	defer cancel()
+	return w.retry(ctx, name, fn)
+}
+
+// Retry calls fn until it succeeds or ctx is cancelled. It is for steps that
+// can complete in the background once the service is serving.
+func (w *Waiter) Retry(ctx context.Context, name string, fn func(ctx context.Context) error) error {
+	return w.retry(ctx, name, fn)
+}
+
+func (w *Waiter) retry(ctx context.Context, name string, fn func(ctx context.Context) error) error {
+	start := time.Now()
+	delay := w.config.InitialBackoff
+	for attempt := 1; ; attempt++ {
+		err := fn(ctx)
+		if err == nil {
+			if attempt > 1 {
+				w.logger.Info("Dependency available",
+					zap.String("dependency", name),
+					zap.Int("attempts", attempt),
+					zap.Duration("waited", time.Since(start)),
+				)
+			}
+			return nil
+		}
+
+		// Half the delay is fixed, half random, so replicas spread out
+		sleep := delay/2 + rand.N(delay/2+1)
+		w.logger.Warn("Dependency not available, retrying",
+			zap.String("dependency", name),
+			zap.Int("attempt", attempt),
+			zap.Duration("retry_in", sleep),
+			zap.Error(err),
+		)
+
+		timer := time.NewTimer(sleep)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("%s not available after %s and %d attempts: %w",
+				name, time.Since(start).Round(time.Millisecond), attempt, err)
+		}
+
+		delay *= 2
+		if delay > w.config.MaxBackoff {
+			delay = w.config.MaxBackoff
+		}
+	}
+}
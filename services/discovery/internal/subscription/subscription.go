@@ -0,0 +1,122 @@
+// Package subscription looks up the services a consumer organisation holds
+// active subscriptions to in the registry, so search can flag them.
+package subscription
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/org/llm-marketplace/services/discovery/internal/config"
+)
+
+// pageSize is the most subscriptions the registry returns at once
+const pageSize = 100
+
+// Client lists subscriptions in the registry, caching each consumer's
+// subscribed services for the configured TTL
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	ttl        time.Duration
+
+	mu    sync.Mutex
+	cache map[string]entry
+}
+
+type entry struct {
+	services map[string]bool
+	expires  time.Time
+}
+
+// NewClient creates a client of the registry at cfg.RegistryURL
+func NewClient(cfg config.SubscriptionsConfig) *Client {
+	return &Client{
+		baseURL:    strings.TrimSuffix(cfg.RegistryURL, "/"),
+		httpClient: &http.Client{Timeout: cfg.Timeout},
+		ttl:        cfg.CacheTTL,
+		cache:      make(map[string]entry),
+	}
+}
+
+// Subscribed returns the IDs of the services consumerID holds an active
+// subscription to
+func (c *Client) Subscribed(ctx context.Context, consumerID string) (map[string]bool, error) {
+	now := time.Now()
+	c.mu.Lock()
+	e, ok := c.cache[consumerID]
+	c.mu.Unlock()
+	if ok && now.Before(e.expires) {
+		return e.services, nil
+	}
+
+	services := make(map[string]bool)
+	for offset := 0; ; offset += pageSize {
+		ids, total, err := c.page(ctx, consumerID, offset)
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range ids {
+			services[id] = true
+		}
+		if len(ids) == 0 || offset+len(ids) >= total {
+			break
+		}
+	}
+
+	c.mu.Lock()
+	for consumer, old := range c.cache {
+		if !now.Before(old.expires) {
+			delete(c.cache, consumer)
+		}
+	}
+	c.cache[consumerID] = entry{services: services, expires: now.Add(c.ttl)}
+	c.mu.Unlock()
+	return services, nil
+}
+
+// page fetches the service IDs of one page of a consumer's active
+// subscriptions and the total
+func (c *Client) page(ctx context.Context, consumerID string, offset int) ([]string, int, error) {
+	query := url.Values{
+		"consumer_id": {consumerID},
+		"status":      {"active"},
+		"limit":       {strconv.Itoa(pageSize)},
+		"offset":      {strconv.Itoa(offset)},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/api/v1/subscriptions?"+query.Encode(), nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list subscriptions: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, 0, fmt.Errorf("registry returned %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	var page struct {
+		Subscriptions []struct {
+			ServiceID string `json:"service_id"`
+		} `json:"subscriptions"`
+		Total int `json:"total"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&page); err != nil {
+		return nil, 0, fmt.Errorf("invalid subscriptions response: %w", err)
+	}
+	ids := make([]string, len(page.Subscriptions))
+	for i, s := range page.Subscriptions {
+		ids[i] = s.ServiceID
+	}
+	return ids, page.Total, nil
+}
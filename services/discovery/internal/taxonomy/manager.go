@@ -0,0 +1,252 @@
+package taxonomy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/org/llm-marketplace/services/discovery/internal/config"
+	"github.com/org/llm-marketplace/services/discovery/internal/postgres"
+	"go.uber.org/zap"
+)
+
+// cacheKey holds the serialized category list shared by all replicas
+const cacheKey = "taxonomy:all"
+
+// Manager stores the category hierarchy in PostgreSQL and caches it in Redis
+type Manager struct {
+	pgPool      *postgres.Pool
+	redisClient *redis.Client
+	config      *config.Config
+	logger      *zap.Logger
+}
+
+func NewManager(
+	pgPool *postgres.Pool,
+	redisClient *redis.Client,
+	cfg *config.Config,
+	logger *zap.Logger,
+) *Manager {
+	return &Manager{
+		pgPool:      pgPool,
+		redisClient: redisClient,
+		config:      cfg,
+		logger:      logger,
+	}
+}
+
+// CreateCategoryRequest adds a category under an optional parent
+type CreateCategoryRequest struct {
+	Name        string `json:"name" binding:"required"`
+	Parent      string `json:"parent"`
+	Description string `json:"description"`
+	Icon        string `json:"icon"`
+}
+
+// UpdateCategoryRequest changes the fields that are set; an empty Parent moves the category to the root
+type UpdateCategoryRequest struct {
+	Parent      *string `json:"parent"`
+	Description *string `json:"description"`
+	Icon        *string `json:"icon"`
+}
+
+// Load returns the current taxonomy
+func (m *Manager) Load(ctx context.Context) (*Taxonomy, error) {
+	if data, err := m.redisClient.Get(ctx, cacheKey).Bytes(); err == nil {
+		var categories []*Category
+		if err := json.Unmarshal(data, &categories); err == nil {
+			return New(categories), nil
+		}
+	}
+
+	categories, err := m.list(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if data, err := json.Marshal(categories); err == nil {
+		if err := m.redisClient.Set(ctx, cacheKey, data, m.config.CacheTTL("categories")).Err(); err != nil {
+			m.logger.Warn("Failed to cache taxonomy", zap.Error(err))
+		}
+	}
+
+	return New(categories), nil
+}
+
+// Get returns a single category
+func (m *Manager) Get(ctx context.Context, name string) (*Category, error) {
+	t, err := m.Load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c := t.Get(name)
+	if c == nil {
+		return nil, ErrCategoryNotFound
+	}
+	return c, nil
+}
+
+// Create adds a category to the hierarchy
+func (m *Manager) Create(ctx context.Context, req *CreateCategoryRequest) (*Category, error) {
+	if !namePattern.MatchString(req.Name) {
+		return nil, fmt.Errorf("%w: name must be lowercase words separated by hyphens", ErrInvalidCategory)
+	}
+
+	t, err := m.Load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if req.Parent != "" && t.Get(req.Parent) == nil {
+		return nil, fmt.Errorf("%w: unknown parent %s", ErrInvalidCategory, req.Parent)
+	}
+
+	query := `
+		INSERT INTO categories (name, description, parent_id, icon)
+		VALUES ($1, NULLIF($2, ''), (SELECT id FROM categories WHERE name = NULLIF($3, '')), NULLIF($4, ''))
+		RETURNING id, created_at
+	`
+
+	c := &Category{
+		Name:        req.Name,
+		Parent:      req.Parent,
+		Description: req.Description,
+		Icon:        req.Icon,
+	}
+	err = m.pgPool.QueryRow(ctx, query, req.Name, req.Description, req.Parent, req.Icon).Scan(&c.ID, &c.CreatedAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return nil, ErrCategoryExists
+		}
+		return nil, fmt.Errorf("failed to create category: %w", err)
+	}
+
+	m.invalidate(ctx)
+	m.logger.Info("Category created", zap.String("name", c.Name), zap.String("parent", c.Parent))
+
+	return c, nil
+}
+
+// Update edits a category, rejecting moves that would create a cycle
+func (m *Manager) Update(ctx context.Context, name string, req *UpdateCategoryRequest) (*Category, error) {
+	t, err := m.Load(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	existing := t.Get(name)
+	if existing == nil {
+		return nil, ErrCategoryNotFound
+	}
+	c := *existing
+
+	if req.Parent != nil {
+		parent := *req.Parent
+		if parent != "" {
+			if t.Get(parent) == nil {
+				return nil, fmt.Errorf("%w: unknown parent %s", ErrInvalidCategory, parent)
+			}
+			if t.isDescendant(parent, name) {
+				return nil, fmt.Errorf("%w: %s cannot be moved under itself or its descendant %s", ErrInvalidCategory, name, parent)
+			}
+		}
+		c.Parent = parent
+	}
+	if req.Description != nil {
+		c.Description = *req.Description
+	}
+	if req.Icon != nil {
+		c.Icon = *req.Icon
+	}
+
+	query := `
+		UPDATE categories
+		SET parent_id = (SELECT id FROM categories WHERE name = NULLIF($2, '')),
+		    description = NULLIF($3, ''),
+		    icon = NULLIF($4, '')
+		WHERE name = $1
+	`
+
+	res, err := m.pgPool.Exec(ctx, query, name, c.Parent, c.Description, c.Icon)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update category: %w", err)
+	}
+	if res.RowsAffected() == 0 {
+		return nil, ErrCategoryNotFound
+	}
+
+	m.invalidate(ctx)
+
+	return &c, nil
+}
+
+// Delete removes a leaf category
+func (m *Manager) Delete(ctx context.Context, name string) error {
+	t, err := m.Load(ctx)
+	if err != nil {
+		return err
+	}
+	if t.Get(name) == nil {
+		return ErrCategoryNotFound
+	}
+	if len(t.children[name]) > 0 {
+		return ErrCategoryInUse
+	}
+
+	res, err := m.pgPool.Exec(ctx, `DELETE FROM categories WHERE name = $1`, name)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+			return ErrCategoryInUse
+		}
+		return fmt.Errorf("failed to delete category: %w", err)
+	}
+	if res.RowsAffected() == 0 {
+		return ErrCategoryNotFound
+	}
+
+	m.invalidate(ctx)
+	m.logger.Info("Category deleted", zap.String("name", name))
+
+	return nil
+}
+
+func (m *Manager) list(ctx context.Context) ([]*Category, error) {
+	query := `
+		SELECT c.id, c.name, COALESCE(p.name, ''), COALESCE(c.description, ''), COALESCE(c.icon, ''), c.created_at
+		FROM categories c
+		LEFT JOIN categories p ON p.id = c.parent_id
+		ORDER BY c.name
+	`
+
+	rows, err := m.pgPool.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list categories: %w", err)
+	}
+	defer rows.Close()
+
+	categories := []*Category{}
+	for rows.Next() {
+		var c Category
+		var createdAt *time.Time
+		if err := rows.Scan(&c.ID, &c.Name, &c.Parent, &c.Description, &c.Icon, &createdAt); err != nil {
+			return nil, fmt.Errorf("failed to scan category: %w", err)
+		}
+		if createdAt != nil {
+			c.CreatedAt = *createdAt
+		}
+		categories = append(categories, &c)
+	}
+	return categories, rows.Err()
+}
+
+// invalidate drops the cached taxonomy and the category facet counts built from it
+func (m *Manager) invalidate(ctx context.Context) {
+	if err := m.redisClient.Del(ctx, cacheKey, "categories:all").Err(); err != nil {
+		m.logger.Warn("Failed to invalidate taxonomy cache", zap.Error(err))
+	}
+}
@@ -0,0 +1,165 @@
+package taxonomy
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"time"
+)
+
+var (
+	// ErrCategoryNotFound is returned for unknown category names
+	ErrCategoryNotFound = errors.New("category not found")
+	// ErrCategoryExists is returned when creating a category whose name is taken
+	ErrCategoryExists = errors.New("category already exists")
+	// ErrCategoryInUse is returned when deleting a category that still has children
+	ErrCategoryInUse = errors.New("category has child categories")
+	// ErrInvalidCategory is returned for malformed requests and categories outside the taxonomy
+	ErrInvalidCategory = errors.New("invalid category")
+)
+
+// namePattern matches the keywords stored in the service document's category field
+var namePattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// Category is a node in the managed category hierarchy
+type Category struct {
+	ID          string    `json:"id"`
+	Name        string    `json:"name"`
+	Parent      string    `json:"parent,omitempty"`
+	Description string    `json:"description,omitempty"`
+	Icon        string    `json:"icon,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// Node is a category with its children, for tree responses
+type Node struct {
+	*Category
+	Children []*Node `json:"children"`
+}
+
+// Taxonomy is an immutable snapshot of the hierarchy
+type Taxonomy struct {
+	byName   map[string]*Category
+	children map[string][]string
+}
+
+// New indexes categories into a hierarchy
+func New(categories []*Category) *Taxonomy {
+	t := &Taxonomy{
+		byName:   make(map[string]*Category, len(categories)),
+		children: make(map[string][]string),
+	}
+	for _, c := range categories {
+		t.byName[c.Name] = c
+		t.children[c.Parent] = append(t.children[c.Parent], c.Name)
+	}
+	for _, names := range t.children {
+		sort.Strings(names)
+	}
+	return t
+}
+
+// Empty reports whether no categories are managed yet
+func (t *Taxonomy) Empty() bool {
+	return len(t.byName) == 0
+}
+
+// Get returns the named category, or nil
+func (t *Taxonomy) Get(name string) *Category {
+	return t.byName[name]
+}
+
+// Parent returns the parent of name, or "" for roots and unknown names
+func (t *Taxonomy) Parent(name string) string {
+	if c := t.byName[name]; c != nil {
+		return c.Parent
+	}
+	return ""
+}
+
+// Ancestors returns the parents of name, nearest first
+func (t *Taxonomy) Ancestors(name string) []string {
+	var ancestors []string
+	seen := map[string]bool{name: true}
+	for parent := t.Parent(name); parent != "" && !seen[parent]; parent = t.Parent(parent) {
+		seen[parent] = true
+		ancestors = append(ancestors, parent)
+	}
+	return ancestors
+}
+
+// Expand returns names plus all of their descendants, so filtering on a parent matches its children
+func (t *Taxonomy) Expand(names []string) []string {
+	seen := make(map[string]bool)
+	var expanded []string
+
+	var walk func(name string)
+	walk = func(name string) {
+		if seen[name] {
+			return
+		}
+		seen[name] = true
+		expanded = append(expanded, name)
+		for _, child := range t.children[name] {
+			walk(child)
+		}
+	}
+
+	for _, name := range names {
+		walk(name)
+	}
+	return expanded
+}
+
+// Validate returns ErrInvalidCategory for names outside the taxonomy.
+// Every name is accepted until the taxonomy has been populated.
+func (t *Taxonomy) Validate(names ...string) error {
+	if t.Empty() {
+		return nil
+	}
+	for _, name := range names {
+		if t.byName[name] == nil {
+			return fmt.Errorf("%w: unknown category %s", ErrInvalidCategory, name)
+		}
+	}
+	return nil
+}
+
+// RollUp adds each category's count to all of its ancestors
+func (t *Taxonomy) RollUp(direct map[string]int) map[string]int {
+	total := make(map[string]int, len(direct))
+	for name, count := range direct {
+		total[name] += count
+		for _, ancestor := range t.Ancestors(name) {
+			total[ancestor] += count
+		}
+	}
+	return total
+}
+
+// Tree returns the hierarchy from its roots
+func (t *Taxonomy) Tree() []*Node {
+	var build func(parent string) []*Node
+	build = func(parent string) []*Node {
+		nodes := []*Node{}
+		for _, name := range t.children[parent] {
+			nodes = append(nodes, &Node{Category: t.byName[name], Children: build(name)})
+		}
+		return nodes
+	}
+	return build("")
+}
+
+// isDescendant reports whether candidate is name or below it
+func (t *Taxonomy) isDescendant(candidate, name string) bool {
+	if candidate == name {
+		return true
+	}
+	for _, ancestor := range t.Ancestors(candidate) {
+		if ancestor == name {
+			return true
+		}
+	}
+	return false
+}
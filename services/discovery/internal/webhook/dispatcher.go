@@ -0,0 +1,325 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/org/llm-marketplace/pkg/marketplace/egress"
+	"github.com/org/llm-marketplace/services/discovery/internal/config"
+	"github.com/org/llm-marketplace/services/discovery/internal/elasticsearch"
+	"github.com/org/llm-marketplace/services/discovery/internal/entitlement"
+	"github.com/org/llm-marketplace/services/discovery/internal/observability"
+	"github.com/org/llm-marketplace/services/discovery/internal/postgres"
+	"go.uber.org/zap"
+)
+
+// Dispatcher fans catalog changes out to webhook subscriptions and delivers them with retries
+type Dispatcher struct {
+	store      *store
+	config     config.WebhookConfig
+	logger     *zap.Logger
+	metrics    *observability.Metrics
+	httpClient *http.Client
+}
+
+func NewDispatcher(
+	pgPool *postgres.Pool,
+	cfg config.WebhookConfig,
+	logger *zap.Logger,
+	metrics *observability.Metrics,
+) *Dispatcher {
+	return &Dispatcher{
+		store:   &store{pgPool: pgPool},
+		config:  cfg,
+		logger:  logger,
+		metrics: metrics,
+		// Subscribers must answer directly, from a public address; redirects
+		// or a host resolving inside our network could point anywhere
+		httpClient: egress.Client(cfg.Timeout),
+	}
+}
+
+// CreateSubscriptionRequest registers a webhook endpoint
+type CreateSubscriptionRequest struct {
+	URL    string   `json:"url" binding:"required"`
+	Events []string `json:"events" binding:"required,min=1"`
+	Filter Filter   `json:"filter"`
+}
+
+// CreateSubscription validates and stores a subscription; the returned secret is shown only once
+func (d *Dispatcher) CreateSubscription(ctx context.Context, ownerID string, req *CreateSubscriptionRequest) (*Subscription, error) {
+	if err := d.validateURL(req.URL); err != nil {
+		return nil, err
+	}
+	for _, event := range req.Events {
+		if !IsEventType(event) {
+			return nil, fmt.Errorf("%w: unknown event type %s", ErrInvalidSubscription, event)
+		}
+	}
+
+	secret, err := randomHex(32)
+	if err != nil {
+		return nil, err
+	}
+
+	sub := &Subscription{
+		OwnerID:  ownerID,
+		TenantID: entitlement.FromContext(ctx).TenantID,
+		URL:      req.URL,
+		Events:   req.Events,
+		Filter:   req.Filter,
+		Secret:   secret,
+	}
+
+	if err := d.store.createSubscription(ctx, sub); err != nil {
+		return nil, err
+	}
+
+	d.logger.Info("Webhook subscription created",
+		zap.String("id", sub.ID),
+		zap.Strings("events", sub.Events),
+	)
+
+	return sub, nil
+}
+
+// GetSubscription returns a subscription without its secret
+func (d *Dispatcher) GetSubscription(ctx context.Context, id string) (*Subscription, error) {
+	return d.store.getSubscription(ctx, id)
+}
+
+// ListSubscriptions returns the owner's subscriptions, or all when ownerID is empty
+func (d *Dispatcher) ListSubscriptions(ctx context.Context, ownerID string) ([]*Subscription, error) {
+	return d.store.listSubscriptions(ctx, ownerID)
+}
+
+// DeleteSubscription removes a subscription and its delivery log
+func (d *Dispatcher) DeleteSubscription(ctx context.Context, id string) error {
+	return d.store.deleteSubscription(ctx, id)
+}
+
+// ListDeliveries returns the most recent deliveries for a subscription
+func (d *Dispatcher) ListDeliveries(ctx context.Context, subscriptionID string, limit int) ([]*Delivery, error) {
+	if _, err := d.store.getSubscription(ctx, subscriptionID); err != nil {
+		return nil, err
+	}
+	return d.store.listDeliveries(ctx, subscriptionID, limit)
+}
+
+// ServiceChanged enqueues deliveries for every subscription matching the change
+func (d *Dispatcher) ServiceChanged(ctx context.Context, previous, current *elasticsearch.ServiceDocument) {
+	// The write has already happened; don't lose events to a cancelled request
+	ctx = context.WithoutCancel(ctx)
+
+	service := *current
+	service.Embedding = nil
+	service.Embeddings = nil
+
+	for _, eventType := range DiffEvents(previous, current) {
+		subs, err := d.store.subscriptionsFor(ctx, eventType, current.TenantID)
+		if err != nil {
+			d.logger.Error("Failed to load webhook subscriptions", zap.String("event", eventType), zap.Error(err))
+			continue
+		}
+
+		for _, sub := range subs {
+			if !sub.Filter.Matches(current) {
+				continue
+			}
+			// Subscriptions only know their owner, so tenant-wide visibility doesn't apply
+			if !(entitlement.Caller{UserID: sub.OwnerID}).CanView(current.Access) {
+				continue
+			}
+
+			payload, err := d.buildPayload(eventType, &service, previous)
+			if err != nil {
+				d.logger.Error("Failed to build webhook payload", zap.Error(err))
+				continue
+			}
+
+			if err := d.store.enqueueDelivery(ctx, sub.ID, eventType, payload); err != nil {
+				d.logger.Error("Failed to enqueue webhook delivery",
+					zap.String("subscription_id", sub.ID),
+					zap.String("event", eventType),
+					zap.Error(err),
+				)
+			}
+		}
+	}
+}
+
+// Start delivers due webhooks until ctx is cancelled
+func (d *Dispatcher) Start(ctx context.Context) {
+	if !d.config.Enabled {
+		d.logger.Info("Webhook delivery is disabled")
+		return
+	}
+
+	ticker := time.NewTicker(d.config.PollInterval)
+	defer ticker.Stop()
+
+	d.logger.Info("Webhook dispatcher started", zap.Duration("poll_interval", d.config.PollInterval))
+
+	for {
+		select {
+		case <-ctx.Done():
+			d.logger.Info("Webhook dispatcher stopped")
+			return
+		case <-ticker.C:
+			d.deliverDue(ctx)
+		}
+	}
+}
+
+func (d *Dispatcher) deliverDue(ctx context.Context) {
+	// Lease long enough to cover a full attempt so others skip these rows
+	lease := d.config.Timeout + d.config.PollInterval
+
+	deliveries, err := d.store.claimDue(ctx, d.config.BatchSize, lease)
+	if err != nil {
+		d.logger.Error("Failed to claim webhook deliveries", zap.Error(err))
+		return
+	}
+
+	var wg sync.WaitGroup
+	for _, delivery := range deliveries {
+		wg.Add(1)
+		go func(delivery *Delivery) {
+			defer wg.Done()
+			d.attempt(ctx, delivery)
+		}(delivery)
+	}
+	wg.Wait()
+}
+
+func (d *Dispatcher) attempt(ctx context.Context, delivery *Delivery) {
+	start := time.Now()
+	statusCode, err := d.send(ctx, delivery)
+
+	delivery.Attempts++
+	delivery.LastStatusCode = statusCode
+	delivery.LastError = ""
+
+	var nextAttempt *time.Time
+	result := DeliverySucceeded
+
+	if err == nil {
+		delivery.Status = DeliverySucceeded
+		now := time.Now().UTC()
+		delivery.DeliveredAt = &now
+	} else {
+		delivery.LastError = err.Error()
+		if delivery.Attempts >= d.config.MaxAttempts {
+			delivery.Status = DeliveryFailed
+			result = DeliveryFailed
+		} else {
+			next := time.Now().Add(d.backoff(delivery.Attempts))
+			nextAttempt = &next
+			result = "retry"
+		}
+		d.logger.Warn("Webhook delivery attempt failed",
+			zap.String("delivery_id", delivery.ID),
+			zap.Int("attempt", delivery.Attempts),
+			zap.Error(err),
+		)
+	}
+
+	d.metrics.WebhookDelivery(result, time.Since(start))
+
+	if err := d.store.recordAttempt(ctx, delivery, nextAttempt); err != nil {
+		d.logger.Error("Failed to record webhook delivery", zap.String("delivery_id", delivery.ID), zap.Error(err))
+	}
+}
+
+func (d *Dispatcher) send(ctx context.Context, delivery *Delivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.url, bytes.NewReader(delivery.payload))
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "llm-marketplace-webhooks/1.0")
+	req.Header.Set(HeaderEvent, delivery.EventType)
+	req.Header.Set(HeaderDelivery, delivery.ID)
+	req.Header.Set(HeaderSignature, Sign(delivery.secret, time.Now(), delivery.payload))
+
+	resp, err := d.httpClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("endpoint returned %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// backoff doubles from InitialBackoff per attempt, capped at MaxBackoff
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	delay := d.config.InitialBackoff
+	for i := 1; i < attempts && delay < d.config.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > d.config.MaxBackoff {
+		delay = d.config.MaxBackoff
+	}
+	return delay
+}
+
+func (d *Dispatcher) buildPayload(
+	eventType string,
+	service *elasticsearch.ServiceDocument,
+	previous *elasticsearch.ServiceDocument,
+) ([]byte, error) {
+	id, err := randomHex(16)
+	if err != nil {
+		return nil, err
+	}
+
+	event := Event{
+		ID:        id,
+		Type:      eventType,
+		CreatedAt: time.Now().UTC(),
+		Service:   service,
+	}
+	if eventType == EventPriceChanged && previous != nil {
+		event.Previous = &previous.Pricing
+	}
+
+	return json.Marshal(event)
+}
+
+func (d *Dispatcher) validateURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return fmt.Errorf("%w: url must be absolute", ErrInvalidSubscription)
+	}
+	if u.Scheme != "https" && !(u.Scheme == "http" && d.config.AllowInsecure) {
+		return fmt.Errorf("%w: url must use https", ErrInvalidSubscription)
+	}
+	// Host names are checked when delivering, once they resolve
+	if ip := net.ParseIP(u.Hostname()); ip != nil && !egress.Public(ip) {
+		return fmt.Errorf("%w: url must be a public address", ErrInvalidSubscription)
+	}
+	return nil
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate random id: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,257 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/org/llm-marketplace/services/discovery/internal/postgres"
+)
+
+var (
+	// ErrSubscriptionNotFound is returned for unknown subscription IDs
+	ErrSubscriptionNotFound = errors.New("webhook subscription not found")
+	// ErrInvalidSubscription is returned when a subscription request is rejected
+	ErrInvalidSubscription = errors.New("invalid webhook subscription")
+)
+
+// store persists subscriptions and the delivery log in PostgreSQL
+type store struct {
+	pgPool *postgres.Pool
+}
+
+func (s *store) createSubscription(ctx context.Context, sub *Subscription) error {
+	filter, err := json.Marshal(sub.Filter)
+	if err != nil {
+		return fmt.Errorf("failed to encode filter: %w", err)
+	}
+
+	query := `
+		INSERT INTO webhook_subscriptions (owner_id, tenant_id, url, events, filter, secret)
+		VALUES ($1, NULLIF($2, ''), $3, $4, $5, $6)
+		RETURNING id, active, created_at
+	`
+
+	err = s.pgPool.QueryRow(ctx, query, sub.OwnerID, sub.TenantID, sub.URL, sub.Events, filter, sub.Secret).
+		Scan(&sub.ID, &sub.Active, &sub.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create subscription: %w", err)
+	}
+	return nil
+}
+
+func (s *store) getSubscription(ctx context.Context, id string) (*Subscription, error) {
+	query := `
+		SELECT id, COALESCE(owner_id, ''), COALESCE(tenant_id, ''), url, events, filter, active, created_at
+		FROM webhook_subscriptions
+		WHERE id = $1
+	`
+
+	sub, err := scanSubscription(s.pgPool.QueryRow(ctx, query, id))
+	if errors.Is(err, pgx.ErrNoRows) || isInvalidID(err) {
+		return nil, ErrSubscriptionNotFound
+	}
+	return sub, err
+}
+
+func (s *store) listSubscriptions(ctx context.Context, ownerID string) ([]*Subscription, error) {
+	query := `
+		SELECT id, COALESCE(owner_id, ''), COALESCE(tenant_id, ''), url, events, filter, active, created_at
+		FROM webhook_subscriptions
+		WHERE $1 = '' OR owner_id = $1
+		ORDER BY created_at DESC
+	`
+
+	rows, err := s.pgPool.Query(ctx, query, ownerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list subscriptions: %w", err)
+	}
+	defer rows.Close()
+
+	subs := []*Subscription{}
+	for rows.Next() {
+		sub, err := scanSubscription(rows)
+		if err != nil {
+			return nil, err
+		}
+		subs = append(subs, sub)
+	}
+	return subs, rows.Err()
+}
+
+func (s *store) deleteSubscription(ctx context.Context, id string) error {
+	res, err := s.pgPool.Exec(ctx, `DELETE FROM webhook_subscriptions WHERE id = $1`, id)
+	if isInvalidID(err) {
+		return ErrSubscriptionNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete subscription: %w", err)
+	}
+	if res.RowsAffected() == 0 {
+		return ErrSubscriptionNotFound
+	}
+	return nil
+}
+
+// subscriptionsFor returns active subscriptions registered for eventType that may see
+// the tenant's services, including secrets; shared catalog changes go to every tenant
+func (s *store) subscriptionsFor(ctx context.Context, eventType, tenantID string) ([]*Subscription, error) {
+	query := `
+		SELECT id, COALESCE(owner_id, ''), COALESCE(tenant_id, ''), url, events, filter, active, created_at, secret
+		FROM webhook_subscriptions
+		WHERE active AND $1 = ANY(events) AND ($2 = '' OR tenant_id = $2)
+	`
+
+	rows, err := s.pgPool.Query(ctx, query, eventType, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load subscriptions: %w", err)
+	}
+	defer rows.Close()
+
+	var subs []*Subscription
+	for rows.Next() {
+		var sub Subscription
+		var filter []byte
+		err := rows.Scan(&sub.ID, &sub.OwnerID, &sub.TenantID, &sub.URL, &sub.Events, &filter, &sub.Active, &sub.CreatedAt, &sub.Secret)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan subscription: %w", err)
+		}
+		if err := json.Unmarshal(filter, &sub.Filter); err != nil {
+			return nil, fmt.Errorf("failed to decode filter: %w", err)
+		}
+		subs = append(subs, &sub)
+	}
+	return subs, rows.Err()
+}
+
+func (s *store) enqueueDelivery(ctx context.Context, subscriptionID, eventType string, payload []byte) error {
+	query := `
+		INSERT INTO webhook_deliveries (subscription_id, event_type, payload)
+		VALUES ($1, $2, $3)
+	`
+
+	if _, err := s.pgPool.Exec(ctx, query, subscriptionID, eventType, payload); err != nil {
+		return fmt.Errorf("failed to enqueue delivery: %w", err)
+	}
+	return nil
+}
+
+// claimDue leases up to limit due deliveries so concurrent replicas don't send them twice
+func (s *store) claimDue(ctx context.Context, limit int, lease time.Duration) ([]*Delivery, error) {
+	query := `
+		WITH due AS (
+			SELECT id FROM webhook_deliveries
+			WHERE status = 'pending' AND next_attempt_at <= NOW()
+			ORDER BY next_attempt_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		UPDATE webhook_deliveries d
+		SET next_attempt_at = NOW() + make_interval(secs => $2)
+		FROM due, webhook_subscriptions s
+		WHERE d.id = due.id AND s.id = d.subscription_id
+		RETURNING d.id, d.subscription_id, d.event_type, d.payload, d.attempts, d.created_at, s.url, s.secret
+	`
+
+	rows, err := s.pgPool.Query(ctx, query, limit, lease.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	var deliveries []*Delivery
+	for rows.Next() {
+		d := &Delivery{Status: DeliveryPending}
+		err := rows.Scan(&d.ID, &d.SubscriptionID, &d.EventType, &d.payload, &d.Attempts, &d.CreatedAt, &d.url, &d.secret)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan delivery: %w", err)
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+// recordAttempt stores the outcome of a delivery attempt; nextAttempt is nil once final
+func (s *store) recordAttempt(ctx context.Context, d *Delivery, nextAttempt *time.Time) error {
+	query := `
+		UPDATE webhook_deliveries
+		SET status = $2,
+		    attempts = $3,
+		    next_attempt_at = COALESCE($4, next_attempt_at),
+		    last_status_code = NULLIF($5, 0),
+		    last_error = NULLIF($6, ''),
+		    delivered_at = $7
+		WHERE id = $1
+	`
+
+	_, err := s.pgPool.Exec(ctx, query,
+		d.ID, d.Status, d.Attempts, nextAttempt, d.LastStatusCode, d.LastError, d.DeliveredAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record delivery attempt: %w", err)
+	}
+	return nil
+}
+
+func (s *store) listDeliveries(ctx context.Context, subscriptionID string, limit int) ([]*Delivery, error) {
+	query := `
+		SELECT id, subscription_id, event_type, status, attempts, next_attempt_at,
+		       COALESCE(last_status_code, 0), COALESCE(last_error, ''), created_at, delivered_at
+		FROM webhook_deliveries
+		WHERE subscription_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2
+	`
+
+	rows, err := s.pgPool.Query(ctx, query, subscriptionID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries := []*Delivery{}
+	for rows.Next() {
+		var d Delivery
+		var next, delivered *time.Time
+		err := rows.Scan(&d.ID, &d.SubscriptionID, &d.EventType, &d.Status, &d.Attempts, &next,
+			&d.LastStatusCode, &d.LastError, &d.CreatedAt, &delivered)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan delivery: %w", err)
+		}
+		if d.Status == DeliveryPending {
+			d.NextAttemptAt = next
+		}
+		d.DeliveredAt = delivered
+		deliveries = append(deliveries, &d)
+	}
+	return deliveries, rows.Err()
+}
+
+// isInvalidID reports whether err is Postgres rejecting a malformed UUID
+func isInvalidID(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "22P02"
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanSubscription(row rowScanner) (*Subscription, error) {
+	var sub Subscription
+	var filter []byte
+	if err := row.Scan(&sub.ID, &sub.OwnerID, &sub.TenantID, &sub.URL, &sub.Events, &filter, &sub.Active, &sub.CreatedAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to scan subscription: %w", err)
+	}
+	if err := json.Unmarshal(filter, &sub.Filter); err != nil {
+		return nil, fmt.Errorf("failed to decode filter: %w", err)
+	}
+	return &sub, nil
+}
@@ -0,0 +1,156 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/org/llm-marketplace/services/discovery/internal/elasticsearch"
+)
+
+// Catalog event types
+const (
+	EventServiceCreated    = "service.created"
+	EventServiceUpdated    = "service.updated"
+	EventServiceDeprecated = "service.deprecated"
+	EventPriceChanged      = "price.changed"
+)
+
+// EventTypes lists every event a subscription can register for
+var EventTypes = []string{
+	EventServiceCreated,
+	EventServiceUpdated,
+	EventServiceDeprecated,
+	EventPriceChanged,
+}
+
+// Delivery states
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryFailed    = "failed"
+)
+
+// Delivery request headers
+const (
+	HeaderEvent     = "X-Marketplace-Event"
+	HeaderDelivery  = "X-Marketplace-Delivery"
+	HeaderSignature = "X-Marketplace-Signature"
+)
+
+// Filter restricts a subscription to matching services; empty fields match everything
+type Filter struct {
+	ServiceIDs  []string `json:"service_ids,omitempty"`
+	ProviderIDs []string `json:"provider_ids,omitempty"`
+	Categories  []string `json:"categories,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+}
+
+// Matches reports whether doc satisfies every non-empty predicate
+func (f Filter) Matches(doc *elasticsearch.ServiceDocument) bool {
+	if len(f.ServiceIDs) > 0 && !contains(f.ServiceIDs, doc.ID) {
+		return false
+	}
+	if len(f.ProviderIDs) > 0 && !contains(f.ProviderIDs, doc.Provider.ID) {
+		return false
+	}
+	if len(f.Categories) > 0 && !contains(f.Categories, doc.Category) {
+		return false
+	}
+	if len(f.Tags) > 0 {
+		for _, tag := range doc.Tags {
+			if contains(f.Tags, tag) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// Subscription is a registered webhook endpoint
+type Subscription struct {
+	ID        string    `json:"id"`
+	OwnerID   string    `json:"owner_id,omitempty"`
+	TenantID  string    `json:"tenant_id,omitempty"` // Only receives the shared catalog and this tenant's services
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	Filter    Filter    `json:"filter"`
+	Secret    string    `json:"secret,omitempty"` // Only returned when the subscription is created
+	Active    bool      `json:"active"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Delivery is one event sent, or to be sent, to a subscription
+type Delivery struct {
+	ID             string     `json:"id"`
+	SubscriptionID string     `json:"subscription_id"`
+	EventType      string     `json:"event_type"`
+	Status         string     `json:"status"`
+	Attempts       int        `json:"attempts"`
+	NextAttemptAt  *time.Time `json:"next_attempt_at,omitempty"`
+	LastStatusCode int        `json:"last_status_code,omitempty"`
+	LastError      string     `json:"last_error,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty"`
+
+	payload []byte
+	url     string
+	secret  string
+}
+
+// Event is the JSON body posted to subscribers
+type Event struct {
+	ID        string                         `json:"id"`
+	Type      string                         `json:"type"`
+	CreatedAt time.Time                      `json:"created_at"`
+	Service   *elasticsearch.ServiceDocument `json:"service"`
+	Previous  *elasticsearch.PricingInfo     `json:"previous_pricing,omitempty"`
+}
+
+// DiffEvents returns the events implied by a catalog write; previous is nil for new services
+func DiffEvents(previous, current *elasticsearch.ServiceDocument) []string {
+	if previous == nil {
+		return []string{EventServiceCreated}
+	}
+
+	events := []string{EventServiceUpdated}
+	if current.Status == elasticsearch.StatusDeprecated && previous.Status != elasticsearch.StatusDeprecated {
+		events = append(events, EventServiceDeprecated)
+	}
+	if !current.Pricing.Equal(previous.Pricing) {
+		events = append(events, EventPriceChanged)
+	}
+	return events
+}
+
+// Sign computes the signature header value for body sent at timestamp.
+// Receivers recompute HMAC-SHA256 over "<t>.<body>" with their secret and
+// should reject stale timestamps to prevent replay.
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	t := strconv.FormatInt(timestamp.Unix(), 10)
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(t))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	return fmt.Sprintf("t=%s,v1=%s", t, hex.EncodeToString(mac.Sum(nil)))
+}
+
+// IsEventType reports whether name is a known event type
+func IsEventType(name string) bool {
+	return contains(EventTypes, name)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
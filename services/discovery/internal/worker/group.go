@@ -0,0 +1,126 @@
+// Package worker tracks the service's background goroutines so shutdown can
+// wait for them instead of cutting them off.
+package worker
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+// Group runs background goroutines that share the process lifetime. Workers
+// are long-running loops stopped as soon as Shutdown begins; tasks are
+// one-off jobs, such as exports, given until the drain deadline to finish.
+type Group struct {
+	logger *zap.Logger
+
+	workerCtx    context.Context
+	stopWorkers  context.CancelFunc
+	taskCtx      context.Context
+	abandonTasks context.CancelFunc
+
+	mu      sync.Mutex
+	wg      sync.WaitGroup
+	running map[string]int
+	closed  bool
+}
+
+// NewGroup creates an empty group
+func NewGroup(logger *zap.Logger) *Group {
+	g := &Group{logger: logger, running: make(map[string]int)}
+	g.workerCtx, g.stopWorkers = context.WithCancel(context.Background())
+	g.taskCtx, g.abandonTasks = context.WithCancel(context.Background())
+	return g
+}
+
+// Go runs a long-running worker such as a poller. Its context is cancelled
+// when Shutdown begins.
+func (g *Group) Go(name string, fn func(ctx context.Context)) {
+	if g == nil {
+		go fn(context.Background())
+		return
+	}
+	g.start(name, g.workerCtx, fn)
+}
+
+// Task runs a one-off job. Its context is cancelled only if the job is still
+// running when the drain deadline passes. A nil group runs the job detached.
+func (g *Group) Task(name string, fn func(ctx context.Context)) {
+	if g == nil {
+		go fn(context.Background())
+		return
+	}
+	g.start(name, g.taskCtx, fn)
+}
+
+func (g *Group) start(name string, ctx context.Context, fn func(ctx context.Context)) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.closed {
+		g.logger.Warn("Not starting background work during shutdown", zap.String("name", name))
+		return
+	}
+
+	g.running[name]++
+	g.wg.Add(1)
+	go func() {
+		defer g.done(name)
+		defer func() {
+			if r := recover(); r != nil {
+				g.logger.Error("Background work panicked", zap.String("name", name), zap.Any("panic", r))
+			}
+		}()
+		fn(ctx)
+	}()
+}
+
+func (g *Group) done(name string) {
+	g.mu.Lock()
+	g.running[name]--
+	if g.running[name] == 0 {
+		delete(g.running, name)
+	}
+	g.mu.Unlock()
+	g.wg.Done()
+}
+
+// Running returns the names of goroutines that have not returned yet
+func (g *Group) Running() []string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	names := make([]string, 0, len(g.running))
+	for name := range g.running {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Shutdown stops the workers and waits for every goroutine to return. Tasks
+// still running when ctx ends are cancelled and reported in the error; they
+// are not waited for further. Nothing new is started once Shutdown is called.
+func (g *Group) Shutdown(ctx context.Context) error {
+	g.mu.Lock()
+	g.closed = true
+	g.mu.Unlock()
+
+	g.stopWorkers()
+
+	drained := make(chan struct{})
+	go func() {
+		g.wg.Wait()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+		g.abandonTasks()
+		return nil
+	case <-ctx.Done():
+		g.abandonTasks()
+		return fmt.Errorf("background work still running after drain timeout: %v", g.Running())
+	}
+}
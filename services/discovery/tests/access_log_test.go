@@ -0,0 +1,126 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/org/llm-marketplace/services/discovery/internal/config"
+	"github.com/org/llm-marketplace/services/discovery/internal/observability"
+	"github.com/org/llm-marketplace/services/discovery/internal/problem"
+	"github.com/org/llm-marketplace/services/discovery/internal/requestid"
+)
+
+func newAccessLogRouter(cfg config.AccessLogConfig) (*gin.Engine, *observer.ObservedLogs) {
+	testSpans()
+	gin.SetMode(gin.TestMode)
+	core, logs := observer.New(zapcore.DebugLevel)
+
+	router := gin.New()
+	router.Use(
+		observability.GinRequestID(),
+		observability.GinLogger(zap.New(core), cfg),
+		observability.GinTracing(),
+	)
+	router.GET("/ok", func(c *gin.Context) { c.Status(http.StatusOK) })
+	router.GET("/slow", func(c *gin.Context) {
+		time.Sleep(20 * time.Millisecond)
+		c.Status(http.StatusOK)
+	})
+	router.GET("/fail", func(c *gin.Context) { problem.Abort(c, problem.Internal, "") })
+	return router, logs
+}
+
+func TestRequestIDIsGeneratedOrReused(t *testing.T) {
+	router, _ := newAccessLogRouter(config.AccessLogConfig{})
+
+	tests := []struct {
+		name     string
+		incoming string
+		reused   bool
+	}{
+		{"generated", "", false},
+		{"reused", "req-123", true},
+		{"unsafe replaced", "bad id\r\nX-Injected: 1", false},
+		{"oversized replaced", strings.Repeat("a", 200), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/ok", nil)
+			if tt.incoming != "" {
+				req.Header.Set(requestid.Header, tt.incoming)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			got := w.Header().Get(requestid.Header)
+			if !requestid.Valid(got) {
+				t.Fatalf("response request ID %q is not valid", got)
+			}
+			if (got == tt.incoming) != tt.reused {
+				t.Errorf("request ID = %q, incoming %q, want reused=%v", got, tt.incoming, tt.reused)
+			}
+		})
+	}
+}
+
+func TestAccessLogIncludesRequestAndTraceIDs(t *testing.T) {
+	router, logs := newAccessLogRouter(config.AccessLogConfig{})
+
+	req := httptest.NewRequest(http.MethodGet, "/fail", nil)
+	req.Header.Set(requestid.Header, "req-456")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var details problem.Details
+	if err := json.Unmarshal(w.Body.Bytes(), &details); err != nil {
+		t.Fatalf("failed to decode problem details: %v", err)
+	}
+	if details.RequestID != "req-456" {
+		t.Errorf("problem request_id = %q, want req-456", details.RequestID)
+	}
+
+	entries := logs.All()
+	if len(entries) != 1 {
+		t.Fatalf("logged %d entries, want 1", len(entries))
+	}
+	entry := entries[0]
+	fields := entry.ContextMap()
+	if entry.Level != zapcore.ErrorLevel {
+		t.Errorf("level = %v, want error for a 500", entry.Level)
+	}
+	if fields["request_id"] != "req-456" {
+		t.Errorf("request_id = %v, want req-456", fields["request_id"])
+	}
+	if fields["trace_id"] != details.TraceID || details.TraceID == "" {
+		t.Errorf("trace_id = %v, problem trace_id = %q; want the same, non-empty", fields["trace_id"], details.TraceID)
+	}
+}
+
+func TestAccessLogSamplesHealthyRequests(t *testing.T) {
+	router, logs := newAccessLogRouter(config.AccessLogConfig{
+		SuccessSampleRate: 1e-12,
+		SlowThreshold:     10 * time.Millisecond,
+	})
+
+	for _, path := range []string{"/ok", "/ok", "/missing", "/slow"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	var got []string
+	for _, entry := range logs.All() {
+		got = append(got, entry.Level.String()+" "+entry.ContextMap()["path"].(string))
+	}
+	want := []string{"info /missing", "warn /slow"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("logged %v, want %v", got, want)
+	}
+}
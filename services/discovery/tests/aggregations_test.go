@@ -0,0 +1,84 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/org/llm-marketplace/services/discovery/internal/config"
+)
+
+// aggregationsOnly answers every search with no hits and body's aggregations
+func aggregationsOnly(body string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Elastic-Product", "Elasticsearch")
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"took":1,"hits":{"total":{"value":0,"relation":"eq"},"hits":[]}` + body + `}`))
+	})
+}
+
+func TestCategoriesAndTagsDecodeTypedAggregations(t *testing.T) {
+	svc := newSearchServiceOn(t, aggregationsOnly(`,"aggregations":{
+		"categories":{"buckets":[
+			{"key":"chat","doc_count":3,"avg_rating":{"value":4.5}},
+			{"key":"vision","doc_count":2,"avg_rating":{"value":null}}
+		]},
+		"tags":{"buckets":[{"key":"nlp","doc_count":4},{"key":2024,"doc_count":1},{"key":true,"doc_count":1}]}
+	}`), "127.0.0.1:1", func(*config.Config) {})
+	ctx := context.Background()
+
+	categories, err := svc.GetCategories(ctx)
+	if err != nil {
+		t.Fatalf("GetCategories: %v", err)
+	}
+	got := map[string][2]float64{}
+	for _, c := range categories {
+		got[c.Name] = [2]float64{float64(c.Count), c.AvgRating}
+	}
+	if got["chat"] != [2]float64{3, 4.5} || got["vision"] != [2]float64{2, 0} {
+		t.Errorf("categories = %+v, want chat 3 rated 4.5 and vision 2 unrated", categories)
+	}
+
+	tags, err := svc.GetTags(ctx)
+	if err != nil {
+		t.Fatalf("GetTags: %v", err)
+	}
+	if len(tags) != 3 || tags[0].Name != "nlp" || tags[1].Name != "2024" || tags[2].Name != "true" {
+		t.Errorf("tags = %+v, want nlp, 2024 and true", tags)
+	}
+}
+
+func TestMissingAggregationsAreEmpty(t *testing.T) {
+	for name, body := range map[string]string{
+		"no aggregations":  ``,
+		"null aggregation": `,"aggregations":{"categories":null,"tags":null}`,
+		"no buckets":       `,"aggregations":{"categories":{},"tags":{"buckets":null}}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			svc := newSearchServiceOn(t, aggregationsOnly(body), "127.0.0.1:1", func(*config.Config) {})
+			ctx := context.Background()
+
+			if categories, err := svc.GetCategories(ctx); err != nil || len(categories) != 0 {
+				t.Errorf("GetCategories = %+v, %v; want none", categories, err)
+			}
+			if tags, err := svc.GetTags(ctx); err != nil || len(tags) != 0 {
+				t.Errorf("GetTags = %+v, %v; want none", tags, err)
+			}
+		})
+	}
+}
+
+func TestMalformedAggregationsFailWithoutPanicking(t *testing.T) {
+	svc := newSearchServiceOn(t, aggregationsOnly(`,"aggregations":{
+		"categories":{"buckets":{"chat":{"doc_count":3}}},
+		"tags":{"buckets":[{"key":{"nested":1},"doc_count":"many"}]}
+	}`), "127.0.0.1:1", func(*config.Config) {})
+	ctx := context.Background()
+
+	if _, err := svc.GetCategories(ctx); err == nil {
+		t.Error("GetCategories succeeded on keyed buckets, want an error")
+	}
+	if _, err := svc.GetTags(ctx); err == nil {
+		t.Error("GetTags succeeded on an object key, want an error")
+	}
+}
@@ -0,0 +1,256 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"go.uber.org/zap"
+
+	"github.com/org/llm-marketplace/services/discovery/internal/analytics"
+	"github.com/org/llm-marketplace/services/discovery/internal/config"
+)
+
+// fakeWriter records each batch written to it
+type fakeWriter struct {
+	mu      sync.Mutex
+	batches [][]kafka.Message
+	closed  bool
+}
+
+func (w *fakeWriter) WriteMessages(ctx context.Context, msgs ...kafka.Message) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.batches = append(w.batches, append([]kafka.Message(nil), msgs...))
+	return nil
+}
+
+func (w *fakeWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.closed = true
+	return nil
+}
+
+// sizes returns the size of each batch written so far
+func (w *fakeWriter) sizes() []int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	sizes := make([]int, len(w.batches))
+	for i, batch := range w.batches {
+		sizes[i] = len(batch)
+	}
+	return sizes
+}
+
+func waitForBatches(t *testing.T, w *fakeWriter, n int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for len(w.sizes()) < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("batches %v, want %d", w.sizes(), n)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func newTestProducer(writer analytics.Writer, batchSize int, flushInterval time.Duration) *analytics.Producer {
+	return analytics.NewProducerWithWriter(config.AnalyticsHubConfig{
+		Enabled:       true,
+		BatchSize:     batchSize,
+		FlushInterval: flushInterval,
+		BufferSize:    100,
+	}, writer, zap.NewNop(), testMetrics())
+}
+
+func TestAnalyticsProducerBatchesAndFlushesOnClose(t *testing.T) {
+	writer := &fakeWriter{}
+	producer := newTestProducer(writer, 3, time.Hour)
+
+	for i := 0; i < 7; i++ {
+		producer.Track(analytics.NewClickEvent("user-1", &analytics.ClickEvent{ServiceID: "svc", Position: i}))
+	}
+	// Full batches are written without waiting for the flush interval
+	waitForBatches(t, writer, 2)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := producer.Close(ctx); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	// Close flushes the partial batch, then closes the writer
+	if sizes := writer.sizes(); len(sizes) != 3 || sizes[0] != 3 || sizes[1] != 3 || sizes[2] != 1 {
+		t.Errorf("batches %v, want [3 3 1]", sizes)
+	}
+	if !writer.closed {
+		t.Error("writer not closed")
+	}
+
+	// Events tracked after Close are discarded
+	producer.Track(analytics.NewClickEvent("user-1", &analytics.ClickEvent{ServiceID: "svc"}))
+	if sizes := writer.sizes(); len(sizes) != 3 {
+		t.Errorf("batches %v after close", sizes)
+	}
+}
+
+func TestAnalyticsProducerFlushesOnInterval(t *testing.T) {
+	writer := &fakeWriter{}
+	producer := newTestProducer(writer, 100, 20*time.Millisecond)
+	defer producer.Close(context.Background())
+
+	producer.Track(analytics.NewSearchEvent("", &analytics.SearchEvent{QueryID: "q-1", Query: "translation"}))
+	waitForBatches(t, writer, 1)
+}
+
+func TestAnalyticsEventsCarryTheirSchema(t *testing.T) {
+	writer := &fakeWriter{}
+	producer := newTestProducer(writer, 2, time.Hour)
+
+	search := analytics.NewSearchEvent("", &analytics.SearchEvent{QueryID: "q-1", Query: "translation", ResultIDs: []string{"svc"}})
+	impression := analytics.NewImpressionEvent("user-1", &analytics.ImpressionEvent{Source: "trending", ServiceIDs: []string{"svc"}})
+	producer.Track(search)
+	producer.Track(impression)
+	waitForBatches(t, writer, 1)
+	producer.Close(context.Background())
+
+	writer.mu.Lock()
+	defer writer.mu.Unlock()
+	for i, want := range []struct {
+		event   *analytics.Event
+		key     string
+		payload string
+	}{
+		// Anonymous events are keyed by their own ID, others by user
+		{search, search.ID, "search"},
+		{impression, "user-1", "impression"},
+	} {
+		msg := writer.batches[0][i]
+		if string(msg.Key) != want.key {
+			t.Errorf("%s: key %q, want %q", want.event.Type, msg.Key, want.key)
+		}
+		headers := map[string]string{}
+		for _, h := range msg.Headers {
+			headers[h.Key] = string(h.Value)
+		}
+		if headers["event-type"] != want.event.Type || headers["schema-version"] != "1" || headers["content-type"] != "application/json" {
+			t.Errorf("%s: headers %v", want.event.Type, headers)
+		}
+
+		var envelope map[string]json.RawMessage
+		if err := json.Unmarshal(msg.Value, &envelope); err != nil {
+			t.Fatalf("%s: invalid value %s: %v", want.event.Type, msg.Value, err)
+		}
+		if string(envelope["schema_version"]) != "1" || string(envelope["event_type"]) != `"`+want.event.Type+`"` {
+			t.Errorf("%s: envelope %s", want.event.Type, msg.Value)
+		}
+		for _, payload := range []string{"search", "click", "impression"} {
+			if _, ok := envelope[payload]; ok != (payload == want.payload) {
+				t.Errorf("%s: want only the %s payload in %s", want.event.Type, want.payload, msg.Value)
+			}
+		}
+	}
+}
+
+func TestDisabledAnalyticsProducerDiscardsEvents(t *testing.T) {
+	producer := analytics.NewProducer(config.AnalyticsHubConfig{Enabled: true}, zap.NewNop(), testMetrics())
+	producer.Track(analytics.NewClickEvent("user-1", &analytics.ClickEvent{ServiceID: "svc"}))
+	if err := producer.Close(context.Background()); err != nil {
+		t.Errorf("close: %v", err)
+	}
+
+	var nilProducer *analytics.Producer
+	nilProducer.Track(analytics.NewClickEvent("user-1", &analytics.ClickEvent{ServiceID: "svc"}))
+	if err := nilProducer.Close(context.Background()); err != nil {
+		t.Errorf("nil close: %v", err)
+	}
+}
+
+func TestAnalyticsAggregatorCommitsOnlyFlushedEvents(t *testing.T) {
+	cfg := startSandbox(t)
+	cfg.AnalyticsHub.Aggregation = config.AggregationConfig{Enabled: true, FlushInterval: time.Hour, MaxPending: 100}
+	cfg.AnalyticsHub.Related.Enabled = false
+	clients := connectSandbox(t, cfg)
+
+	event := func(e *analytics.Event) []byte {
+		data, _ := json.Marshal(e)
+		return data
+	}
+	future := analytics.NewClickEvent("user-1", &analytics.ClickEvent{QueryID: "q-1", ServiceID: "svc"})
+	future.SchemaVersion = analytics.SchemaVersion + 1
+	reader := &fakeReader{
+		messages: []kafka.Message{
+			{Offset: 1, Value: event(analytics.NewSearchEvent("user-1", &analytics.SearchEvent{QueryID: "q-1", Query: "  Machine  Translation ", Total: 3, LatencyMS: 42}))},
+			{Offset: 2, Value: event(analytics.NewClickEvent("user-1", &analytics.ClickEvent{QueryID: "q-1", ServiceID: "svc"}))},
+			{Offset: 3, Value: []byte(`not json`)},
+			{Offset: 4, Value: event(future)},
+		},
+		done: make(chan struct{}),
+	}
+	aggregator := analytics.NewAggregator(clients.postgres, clients.redis, cfg, zap.NewNop())
+	aggregator.SetReader(reader)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		aggregator.Start(ctx)
+		close(stopped)
+	}()
+
+	select {
+	case <-reader.done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("aggregator did not reach the end of the topic")
+	}
+	// Nothing is committed before the rollup is written
+	reader.mu.Lock()
+	if len(reader.committed) != 0 {
+		t.Errorf("committed %v before the flush", reader.committed)
+	}
+	reader.mu.Unlock()
+
+	// Stopping flushes, committing the skipped events with the applied ones
+	cancel()
+	<-stopped
+	if fmt.Sprint(reader.committed) != "[1 2 3 4]" {
+		t.Errorf("committed = %v, want every offset", reader.committed)
+	}
+
+	// Query IDs outlive the batch, so clicks in later batches are attributed
+	if query, err := clients.redis.Get(context.Background(), "analytics:query:q-1").Result(); err != nil || query != "machine translation" {
+		t.Errorf("query for q-1 = %q, %v; want the normalized query", query, err)
+	}
+}
+
+func TestAnalyticsReportWindows(t *testing.T) {
+	for raw, want := range map[string]time.Duration{
+		"":    24 * time.Hour,
+		"1h":  time.Hour,
+		"90m": 90 * time.Minute,
+		"7d":  7 * 24 * time.Hour,
+		"90d": analytics.MaxWindow,
+	} {
+		if got, err := analytics.ParseWindow(raw); err != nil || got != want {
+			t.Errorf("ParseWindow(%q) = %v, %v; want %v", raw, got, err, want)
+		}
+	}
+	for _, raw := range []string{"30m", "91d", "d", "1w", "-1h"} {
+		if _, err := analytics.ParseWindow(raw); err == nil {
+			t.Errorf("ParseWindow(%q) accepted", raw)
+		}
+	}
+
+	router := newAPIRouter(t, &fakeElasticsearch{}, "127.0.0.1:1", func(*config.Config) {})
+	for _, report := range []string{"top-queries", "zero-results", "ctr", "latency"} {
+		for _, query := range []string{"window=30m", "window=1y", "limit=0", "limit=501"} {
+			path := "/api/v1/analytics/" + report + "?" + query
+			if w := apiRequest(router, http.MethodGet, path, "", nil); w.Code != http.StatusBadRequest {
+				t.Errorf("GET %s: status %d, want 400", path, w.Code)
+			}
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/org/llm-marketplace/services/discovery/internal/config"
+	"github.com/org/llm-marketplace/services/discovery/internal/elasticsearch"
+	"github.com/org/llm-marketplace/services/discovery/internal/search"
+)
+
+var askFixtures = map[string]*elasticsearch.ServiceDocument{
+	"summarize-eu": {
+		ID: "summarize-eu", Name: "EU Summarizer", Description: "Summaries of long documents, hosted in Frankfurt",
+		Category: "summarization", Status: "active", Provider: elasticsearch.ProviderInfo{Name: "Acme"},
+		Compliance: elasticsearch.ComplianceInfo{GDPRCompliant: true},
+	},
+	"summarize-us": {
+		ID: "summarize-us", Name: "Fast Summarizer", Description: "Low-latency summarization",
+		Category: "summarization", Status: "active", Provider: elasticsearch.ProviderInfo{Name: "Globex"},
+	},
+}
+
+// fakeModel is an OpenAI-compatible chat completions API that replies with
+// answer, or fails with status when it is set
+type fakeModel struct {
+	mu       sync.Mutex
+	answer   string
+	status   int
+	requests []map[string]interface{}
+}
+
+func (m *fakeModel) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req map[string]interface{}
+	json.NewDecoder(r.Body).Decode(&req)
+	m.mu.Lock()
+	m.requests = append(m.requests, req)
+	m.mu.Unlock()
+
+	if r.URL.Path != "/v1/chat/completions" || r.Header.Get("Authorization") != "Bearer test-key" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if m.status != 0 {
+		w.WriteHeader(m.status)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"choices": []map[string]interface{}{{"message": map[string]string{"role": "assistant", "content": m.answer}}},
+	})
+}
+
+func newAskSearch(t *testing.T, es *fakeElasticsearch, model *fakeModel, enabled bool) *search.Service {
+	t.Helper()
+	llm := httptest.NewServer(model)
+	t.Cleanup(llm.Close)
+
+	return newSearchService(t, es, func(cfg *config.Config) {
+		cfg.Assistant = config.AssistantConfig{
+			Enabled:     enabled,
+			Backend:     config.AssistantBackendOpenAI,
+			URL:         llm.URL + "/v1",
+			Model:       "test-model",
+			APIKey:      "test-key",
+			Timeout:     time.Second,
+			MaxServices: 5,
+		}
+	})
+}
+
+func TestAskCitesRetrievedServices(t *testing.T) {
+	model := &fakeModel{answer: "For GDPR, use [2]; it is the only compliant one. Avoid [7]."}
+	svc := newAskSearch(t, &fakeElasticsearch{docs: askFixtures}, model, true)
+
+	resp, err := svc.Ask(context.Background(), &search.AskRequest{Question: "Which summarizer is GDPR compliant?"})
+	if err != nil {
+		t.Fatalf("Ask: %v", err)
+	}
+	if resp.Backend != config.AssistantBackendOpenAI || resp.Model != "test-model" || resp.Answer != model.answer {
+		t.Errorf("response = %+v", resp)
+	}
+	if len(resp.Services) != 2 {
+		t.Fatalf("got %d services, want 2", len(resp.Services))
+	}
+	// [7] is beyond the retrieved services
+	if len(resp.Citations) != 1 || resp.Citations[0].Marker != 2 || resp.Citations[0].ServiceID != resp.Services[1].Service.ID {
+		t.Errorf("citations = %+v, want [2] only", resp.Citations)
+	}
+
+	// The model is told about every retrieved service by number
+	messages := model.requests[0]["messages"].([]interface{})
+	prompt := messages[len(messages)-1].(map[string]interface{})["content"].(string)
+	for _, want := range []string{"[1] ", "[2] ", "GDPR compliant", "Question: Which summarizer is GDPR compliant?"} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("prompt lacks %q:\n%s", want, prompt)
+		}
+	}
+}
+
+func TestAskFallsBackToExtractiveAnswer(t *testing.T) {
+	es := &fakeElasticsearch{docs: askFixtures}
+	svc := newAskSearch(t, es, &fakeModel{status: http.StatusBadGateway}, true)
+
+	resp, err := svc.Ask(context.Background(), &search.AskRequest{Question: "summarization", MaxServices: 2})
+	if err != nil {
+		t.Fatalf("Ask: %v", err)
+	}
+	if body := es.searches[len(es.searches)-1]; !strings.Contains(body, `"size":2`) {
+		t.Errorf("retrieval search body = %s, want size 2", body)
+	}
+	if resp.Backend != config.AssistantBackendExtractive || resp.Model != "" {
+		t.Errorf("backend = %q, model = %q, want extractive without a model", resp.Backend, resp.Model)
+	}
+	if len(resp.Citations) != len(resp.Services) {
+		t.Fatalf("services = %d, citations = %+v", len(resp.Services), resp.Citations)
+	}
+	for i, c := range resp.Citations {
+		svc := resp.Services[i].Service
+		if c.Marker != i+1 || c.ServiceID != svc.ID || !strings.Contains(resp.Answer, svc.Name+" by "+svc.Provider.Name) {
+			t.Errorf("citation %d = %+v, answer = %q", i, c, resp.Answer)
+		}
+	}
+}
+
+func TestAskRejectsInvalidRequests(t *testing.T) {
+	svc := newAskSearch(t, &fakeElasticsearch{docs: askFixtures}, &fakeModel{}, true)
+	if _, err := svc.Ask(context.Background(), &search.AskRequest{Question: "  "}); !errors.Is(err, search.ErrInvalidQuestion) {
+		t.Errorf("empty question: err = %v, want ErrInvalidQuestion", err)
+	}
+
+	disabled := newAskSearch(t, &fakeElasticsearch{docs: askFixtures}, &fakeModel{}, false)
+	if _, err := disabled.Ask(context.Background(), &search.AskRequest{Question: "chat"}); !errors.Is(err, search.ErrAssistantDisabled) {
+		t.Errorf("disabled: err = %v, want ErrAssistantDisabled", err)
+	}
+}
@@ -0,0 +1,133 @@
+package tests
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/org/llm-marketplace/services/discovery/internal/backtest"
+	"github.com/org/llm-marketplace/services/discovery/internal/config"
+)
+
+// backtestDataset has three users with a history before the cutoff. After
+// it, u1 uses b, which u2 rated highly, and u2 uses d, which trended; u3
+// does nothing new and u4 has no history, so neither is evaluated.
+func backtestDataset(cutoff time.Time) *backtest.Dataset {
+	at := func(hours float64) time.Time { return cutoff.Add(time.Duration(hours * float64(time.Hour))) }
+	rated := func(user, service string, rating, hours float64) backtest.Interaction {
+		return backtest.Interaction{UserID: user, ServiceID: service, Type: "rate", Rating: rating, Rated: true, Timestamp: at(hours)}
+	}
+	used := func(user, service string, hours float64) backtest.Interaction {
+		return backtest.Interaction{UserID: user, ServiceID: service, Type: "consume", Timestamp: at(hours)}
+	}
+
+	return &backtest.Dataset{
+		Services: []backtest.Service{
+			{ID: "a", Category: "text", Tags: []string{"nlp"}, PricingModel: "per-token", Status: "active"},
+			{ID: "b", Category: "text", Tags: []string{"nlp"}, PricingModel: "per-token", Status: "active"},
+			{ID: "c", Category: "vision", Tags: []string{"images"}, PricingModel: "free", Status: "active"},
+			{ID: "d", Category: "code", Tags: []string{"code"}, PricingModel: "subscription", Status: "active"},
+			{ID: "e", Category: "text", Tags: []string{"nlp"}, PricingModel: "per-token", Status: "retired"},
+		},
+		Interactions: []backtest.Interaction{
+			used("u1", "d", -6), used("u1", "c", -5), rated("u1", "a", 5, -3),
+			used("u2", "c", -10), rated("u2", "a", 5, -10), rated("u2", "b", 5, -9),
+			used("u3", "d", -2), used("u3", "d", -1),
+			used("u1", "b", 2), used("u2", "d", 3), used("u3", "d", 4), used("u4", "a", 5),
+		},
+	}
+}
+
+func TestBacktestScoresEachVariant(t *testing.T) {
+	cutoff := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	report := backtest.Run(backtestDataset(cutoff), backtest.Options{
+		Cutoff: cutoff,
+		K:      []int{1, 2},
+		Config: config.RecommendationsConfig{
+			CollaborativeWeight:     0.4,
+			ContentWeight:           0.3,
+			PopularityWeight:        0.3,
+			MinCommonUsers:          2,
+			TrendingWindow:          24 * time.Hour,
+			TrendingMinInteractions: 2,
+		},
+		Weights: []backtest.Weights{{Collaborative: 0.1, Content: 0.1, Popularity: 0.8}},
+	})
+
+	if report.Users != 2 || report.CatalogSize != 4 || report.TrainSize != 8 || report.TestSize != 4 {
+		t.Errorf("users %d, catalog %d, train %d, test %d; want 2, 4, 8, 4",
+			report.Users, report.CatalogSize, report.TrainSize, report.TestSize)
+	}
+
+	variants := make(map[string]backtest.VariantReport)
+	var names []string
+	for _, v := range report.Variants {
+		variants[v.Name] = v
+		names = append(names, v.Name)
+	}
+	if got := strings.Join(names, ","); got != "collaborative,content,trending,hybrid,hybrid:0.1/0.1/0.8" {
+		t.Fatalf("variants = %s", got)
+	}
+
+	near := func(got, want float64) bool { return math.Abs(got-want) < 1e-9 }
+	for _, tt := range []struct {
+		variant                     string
+		k                           int
+		precision, recall, coverage float64
+	}{
+		// u2 shares a and c with u1 and rated b, so u1 gets b; u1 rated
+		// nothing u2 lacks
+		{"collaborative", 1, 0.5, 0.5, 0.25},
+		{"collaborative", 2, 0.25, 0.5, 0.25},
+		// b is most like a, which u1 used last; u2 used b last and gets a
+		{"content", 1, 0.5, 0.5, 0.5},
+		// d trended with three uses, then a and c with two; u2 hadn't used d
+		{"trending", 1, 0.5, 0.5, 0.25},
+		{"trending", 2, 0.25, 0.5, 0.5},
+		{"hybrid", 1, 0.5, 0.5, 0.5},
+	} {
+		v := variants[tt.variant]
+		var m *backtest.Metrics
+		for i := range v.Metrics {
+			if v.Metrics[i].K == tt.k {
+				m = &v.Metrics[i]
+			}
+		}
+		if m == nil || !near(m.Precision, tt.precision) || !near(m.Recall, tt.recall) || !near(m.Coverage, tt.coverage) {
+			t.Errorf("%s@%d = %+v, want precision %g, recall %g, coverage %g", tt.variant, tt.k, m, tt.precision, tt.recall, tt.coverage)
+		}
+	}
+	if v := variants["collaborative"]; v.UsersWithRecommendations != 1 {
+		t.Errorf("collaborative recommended to %d users, want 1", v.UsersWithRecommendations)
+	}
+	if w := variants["hybrid"].Weights; w == nil || w.Collaborative != 0.4 {
+		t.Errorf("hybrid weights = %+v, want the configured ones", w)
+	}
+
+	var table bytes.Buffer
+	if err := report.WriteTable(&table); err != nil || !strings.Contains(table.String(), "hybrid:0.1/0.1/0.8") {
+		t.Errorf("table = %q, %v", table.String(), err)
+	}
+}
+
+func TestBacktestParsesFlags(t *testing.T) {
+	weights, err := backtest.ParseWeights("0.6,0.2,0.2; 0.2, 0.5, 0.3")
+	if err != nil || len(weights) != 2 || weights[1].Content != 0.5 {
+		t.Errorf("ParseWeights = %+v, %v", weights, err)
+	}
+	for _, bad := range []string{"0.5,0.5", "0.5,0.5,0.5", "a,b,c", "-0.2,0.6,0.6"} {
+		if _, err := backtest.ParseWeights(bad); err == nil {
+			t.Errorf("ParseWeights(%q) succeeded", bad)
+		}
+	}
+
+	ks, err := backtest.ParseK("10,5")
+	if err != nil || len(ks) != 2 || ks[0] != 5 {
+		t.Errorf("ParseK = %v, %v; want [5 10]", ks, err)
+	}
+	if _, err := backtest.ParseK("5,0"); err == nil {
+		t.Error("ParseK accepted k = 0")
+	}
+}
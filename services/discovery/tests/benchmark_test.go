@@ -2,159 +2,180 @@ package tests
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/org/llm-marketplace/services/discovery/internal/config"
+	"github.com/org/llm-marketplace/services/discovery/internal/fixtures"
+	"github.com/org/llm-marketplace/services/discovery/internal/loadtest"
 	"github.com/org/llm-marketplace/services/discovery/internal/search"
 )
 
-// BenchmarkSearch benchmarks the search operation
-func BenchmarkSearch(b *testing.B) {
-	// Setup (would need actual service initialization)
-	// This is a template showing the benchmark structure
-
-	ctx := context.Background()
-	req := &search.SearchRequest{
-		Query: "language model",
-		Pagination: search.PaginationRequest{
-			Page:     0,
-			PageSize: 20,
+// benchmarkServices is the page of services every benchmark search returns
+const benchmarkServices = 100
+
+// newBenchmarkSearch returns a search service over an Elasticsearch that
+// answers every search with the same pre-encoded page of services and
+// category buckets, so benchmarks measure the search path rather than the
+// fake. Redis is unreachable, so nothing is served from the cache.
+func newBenchmarkSearch(b *testing.B) *search.Service {
+	b.Helper()
+	hits := make([]map[string]interface{}, benchmarkServices)
+	buckets := make([]map[string]interface{}, benchmarkServices)
+	docs := fixtures.Generate(fixtures.Options{Seed: 1, Services: benchmarkServices}).Services
+	for i, doc := range docs {
+		hits[i] = map[string]interface{}{"_index": "services", "_id": doc.ID, "_score": float64((i*37)%benchmarkServices) / 10, "_source": doc}
+		buckets[i] = map[string]interface{}{"key": fmt.Sprintf("category-%d", i), "doc_count": i + 1, "avg_rating": map[string]interface{}{"value": doc.Metrics.Rating}}
+	}
+	body, err := json.Marshal(map[string]interface{}{
+		"took":      1,
+		"timed_out": false,
+		"hits": map[string]interface{}{
+			"total": map[string]interface{}{"value": len(hits), "relation": "eq"},
+			"hits":  hits,
+		},
+		"aggregations": map[string]interface{}{
+			"categories": map[string]interface{}{"buckets": buckets},
+			"tags":       map[string]interface{}{"buckets": buckets},
 		},
+	})
+	if err != nil {
+		b.Fatalf("failed to encode response: %v", err)
 	}
+	es := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		w.Header().Set("X-Elastic-Product", "Elasticsearch")
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(body)
+	})
+	return newSearchServiceOn(b, es, "127.0.0.1:1", func(*config.Config) {})
+}
+
+// BenchmarkSearch benchmarks a search returning a full page, from the
+// Elasticsearch response to ranked results
+func BenchmarkSearch(b *testing.B) {
+	svc := newBenchmarkSearch(b)
+	ctx := context.Background()
 
 	b.ResetTimer()
 	b.ReportAllocs()
 
 	for i := 0; i < b.N; i++ {
-		// svc.Search(ctx, req)
-		_ = ctx
-		_ = req
+		req := &search.SearchRequest{
+			Query:      "language model",
+			Pagination: search.PaginationRequest{PageSize: benchmarkServices},
+		}
+		if _, err := svc.Search(ctx, req); err != nil {
+			b.Fatalf("Search: %v", err)
+		}
 	}
 }
 
 // BenchmarkSearchParallel benchmarks concurrent search operations
 func BenchmarkSearchParallel(b *testing.B) {
+	svc := newBenchmarkSearch(b)
 	ctx := context.Background()
-	req := &search.SearchRequest{
-		Query: "language model",
-		Pagination: search.PaginationRequest{
-			Page:     0,
-			PageSize: 20,
-		},
-	}
 
 	b.ResetTimer()
+	b.ReportAllocs()
 	b.RunParallel(func(pb *testing.PB) {
 		for pb.Next() {
-			// svc.Search(ctx, req)
-			_ = ctx
-			_ = req
+			req := &search.SearchRequest{
+				Query:      "language model",
+				Pagination: search.PaginationRequest{PageSize: benchmarkServices},
+			}
+			if _, err := svc.Search(ctx, req); err != nil {
+				b.Errorf("Search: %v", err)
+				return
+			}
 		}
 	})
 }
 
-// LoadTest simulates concurrent load on the search service
+// BenchmarkCategoriesAndTags benchmarks parsing category and tag aggregations
+func BenchmarkCategoriesAndTags(b *testing.B) {
+	svc := newBenchmarkSearch(b)
+	ctx := context.Background()
+
+	b.ResetTimer()
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := svc.GetCategories(ctx); err != nil {
+			b.Fatalf("GetCategories: %v", err)
+		}
+		if _, err := svc.GetTags(ctx); err != nil {
+			b.Fatalf("GetTags: %v", err)
+		}
+	}
+}
+
+// TestLoadTest drives the HTTP API and checks latency against the targets in
+// config.yaml. It targets DISCOVERY_LOADTEST_URL when set, and otherwise the
+// router in-process over a fake Elasticsearch. Set DISCOVERY_LOADTEST_REPORT
+// to keep the JSON report. Under the race detector only errors fail it.
 func TestLoadTest(t *testing.T) {
 	if testing.Short() {
 		t.Skip("Skipping load test in short mode")
 	}
 
-	const (
-		concurrentUsers = 100
-		requestsPerUser = 100
-		targetP95MS     = 200
-	)
-
-	var wg sync.WaitGroup
-	latencies := make(chan time.Duration, concurrentUsers*requestsPerUser)
-
-	startTime := time.Now()
-
-	// Simulate concurrent users
-	for i := 0; i < concurrentUsers; i++ {
-		wg.Add(1)
-		go func(userID int) {
-			defer wg.Done()
-
-			for j := 0; j < requestsPerUser; j++ {
-				reqStart := time.Now()
-
-				// Simulate search request
-				// In real test, call actual service
-				time.Sleep(10 * time.Millisecond) // Simulated latency
-
-				latency := time.Since(reqStart)
-				latencies <- latency
-			}
-		}(i)
+	cfg, err := config.Load("../config.yaml")
+	if err != nil {
+		t.Fatalf("failed to load configuration: %v", err)
 	}
 
-	wg.Wait()
-	close(latencies)
-
-	totalTime := time.Since(startTime)
-
-	// Collect and analyze results
-	var (
-		totalLatency time.Duration
-		count        int
-		allLatencies []time.Duration
-	)
-
-	for lat := range latencies {
-		totalLatency += lat
-		allLatencies = append(allLatencies, lat)
-		count++
+	baseURL := os.Getenv("DISCOVERY_LOADTEST_URL")
+	if baseURL == "" {
+		server := httptest.NewServer(newEntitlementRouter(t, &fakeElasticsearch{}))
+		t.Cleanup(server.Close)
+		baseURL = server.URL
 	}
 
-	// Calculate statistics
-	avgLatency := totalLatency / time.Duration(count)
-	p95Latency := calculatePercentile(allLatencies, 0.95)
-	p99Latency := calculatePercentile(allLatencies, 0.99)
-	throughput := float64(count) / totalTime.Seconds()
-
-	// Report results
-	t.Logf("Load Test Results:")
-	t.Logf("  Total Requests: %d", count)
-	t.Logf("  Total Time: %v", totalTime)
-	t.Logf("  Throughput: %.2f req/s", throughput)
-	t.Logf("  Avg Latency: %v", avgLatency)
-	t.Logf("  P95 Latency: %v", p95Latency)
-	t.Logf("  P99 Latency: %v", p99Latency)
-
-	// Validate against SLA
-	if p95Latency > time.Duration(targetP95MS)*time.Millisecond {
-		t.Errorf("P95 latency %v exceeds target %dms", p95Latency, targetP95MS)
+	report, err := loadtest.Run(context.Background(), loadtest.Options{
+		BaseURL:     baseURL,
+		Requests:    loadtest.DefaultRequests(),
+		Concurrency: 100,
+		Total:       10000,
+		Duration:    5 * time.Minute,
+	})
+	if err != nil {
+		t.Fatalf("load test failed: %v", err)
 	}
-}
+	report.Check(cfg.Performance)
 
-// calculatePercentile calculates the percentile value
-func calculatePercentile(latencies []time.Duration, percentile float64) time.Duration {
-	if len(latencies) == 0 {
-		return 0
+	if path := os.Getenv("DISCOVERY_LOADTEST_REPORT"); path != "" {
+		if err := report.WriteFile(path); err != nil {
+			t.Errorf("failed to write report: %v", err)
+		}
 	}
 
-	// Sort latencies
-	sorted := make([]time.Duration, len(latencies))
-	copy(sorted, latencies)
-
-	// Simple bubble sort (for small datasets)
-	for i := 0; i < len(sorted)-1; i++ {
-		for j := i + 1; j < len(sorted); j++ {
-			if sorted[j] < sorted[i] {
-				sorted[i], sorted[j] = sorted[j], sorted[i]
-			}
+	t.Logf("Load Test Results:")
+	t.Logf("  Total Requests: %d", report.Requests)
+	t.Logf("  Errors: %d", report.Errors)
+	t.Logf("  Throughput: %.2f req/s", report.Throughput)
+	t.Logf("  P50 Latency: %.1fms", report.Latency.P50MS)
+	t.Logf("  P95 Latency: %.1fms", report.Latency.P95MS)
+	t.Logf("  P99 Latency: %.1fms", report.Latency.P99MS)
+
+	// The race detector slows every request several times over
+	for _, v := range report.Violations {
+		if raceEnabled {
+			t.Logf("SLA violated under the race detector: %s", v)
+			continue
 		}
+		t.Errorf("SLA violated: %s", v)
 	}
-
-	index := int(float64(len(sorted)) * percentile)
-	if index >= len(sorted) {
-		index = len(sorted) - 1
+	if report.Errors > 0 {
+		t.Errorf("%d of %d requests failed: %v", report.Errors, report.Requests, report.ErrorSample)
 	}
-
-	return sorted[index]
 }
 
 // TestConcurrentSearches tests handling of concurrent requests
@@ -251,55 +272,58 @@ func TestSearchLatency(t *testing.T) {
 	}
 }
 
-// TestThroughput measures maximum sustainable throughput
+// TestThroughput calls the search handlers from concurrent callers for a fixed
+// time and checks they sustain targetRPS. The router is in-process over a fake
+// Elasticsearch, so this measures the handlers rather than the network. Under
+// the race detector only failures are checked.
 func TestThroughput(t *testing.T) {
 	if testing.Short() {
 		t.Skip("Skipping throughput test in short mode")
 	}
 
 	const (
-		duration       = 10 * time.Second
-		targetRPS      = 1000
-		maxConcurrency = 200
+		duration    = 3 * time.Second
+		targetRPS   = 500
+		concurrency = 50
 	)
 
-	var (
-		requestCount int
-		mu           sync.Mutex
-	)
+	router := newEntitlementRouter(t, &fakeElasticsearch{docs: sessionFixtures})
+	requests := loadtest.DefaultRequests()
 
+	var served, failed atomic.Int64
 	ctx, cancel := context.WithTimeout(context.Background(), duration)
 	defer cancel()
 
-	// Spawn workers
-	for i := 0; i < maxConcurrency; i++ {
-		go func() {
-			for {
-				select {
-				case <-ctx.Done():
-					return
-				default:
-					// Execute request (simulated)
-					time.Sleep(10 * time.Millisecond)
-
-					mu.Lock()
-					requestCount++
-					mu.Unlock()
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			for n := worker; ctx.Err() == nil; n++ {
+				r := requests[n%len(requests)]
+				if w := apiRequest(router, r.Method, r.Path, string(r.Body), nil); w.Code != http.StatusOK {
+					failed.Add(1)
+					continue
 				}
+				served.Add(1)
 			}
-		}()
+		}(i)
 	}
-
-	<-ctx.Done()
-
-	actualRPS := float64(requestCount) / duration.Seconds()
+	// Every call has returned before the counts are read
+	wg.Wait()
+	elapsed := time.Since(start)
+	actualRPS := float64(served.Load()) / elapsed.Seconds()
 
 	t.Logf("Throughput Test Results:")
-	t.Logf("  Total Requests: %d", requestCount)
-	t.Logf("  Duration: %v", duration)
+	t.Logf("  Total Requests: %d", served.Load()+failed.Load())
+	t.Logf("  Duration: %v", elapsed)
 	t.Logf("  Throughput: %.2f req/s", actualRPS)
 
-	if actualRPS < float64(targetRPS)*0.9 {
+	if n := failed.Load(); n > 0 {
+		t.Errorf("%d of %d requests failed", n, served.Load()+n)
+	}
+	if !raceEnabled && actualRPS < targetRPS {
 		t.Errorf("Throughput %.2f req/s is below target %d req/s", actualRPS, targetRPS)
 	}
 }
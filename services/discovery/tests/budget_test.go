@@ -0,0 +1,75 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/org/llm-marketplace/services/discovery/internal/config"
+	"github.com/org/llm-marketplace/services/discovery/internal/search"
+)
+
+var searchTimeoutPattern = regexp.MustCompile(`"timeout":"(\d+)ms"`)
+
+func TestLatencyBudgetBoundsElasticsearchTimeout(t *testing.T) {
+	es := &fakeElasticsearch{}
+	_, addr := newFakeRedis(t)
+	router := newAPIRouter(t, es, addr, func(c *config.Config) {
+		c.Search.Guardrails = guardrailsConfig
+		c.Search.LatencyBudget = config.LatencyBudgetConfig{Total: 200 * time.Millisecond, ElasticsearchShare: 0.5}
+	})
+
+	if w := apiRequest(router, http.MethodPost, "/api/v1/search", `{"query": "model"}`, nil); w.Code != http.StatusOK {
+		t.Fatalf("search: status %d, body %s", w.Code, w.Body.String())
+	}
+
+	es.mu.Lock()
+	defer es.mu.Unlock()
+	if len(es.searches) == 0 {
+		t.Fatal("no search was sent")
+	}
+	for _, body := range es.searches {
+		match := searchTimeoutPattern.FindStringSubmatch(body)
+		if match == nil {
+			t.Errorf("search sent without a timeout: %s", body)
+			continue
+		}
+		// Half of what is left of 200ms, rather than the 500ms guardrail
+		if ms, _ := strconv.Atoi(match[1]); ms < 1 || ms > 100 {
+			t.Errorf("search timeout = %dms, want at most 100ms", ms)
+		}
+	}
+}
+
+func TestSearchOutOfBudget(t *testing.T) {
+	_, addr := newFakeRedis(t)
+	router := newAPIRouter(t, &fakeElasticsearch{}, addr, func(c *config.Config) {
+		c.Search.LatencyBudget = config.LatencyBudgetConfig{Total: time.Nanosecond}
+	})
+	if w := apiRequest(router, http.MethodPost, "/api/v1/search", `{"query": "model"}`, nil); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("search out of budget: status %d, want 503", w.Code)
+	}
+
+	// A search the client abandoned is canceled, not timed out
+	svc := newSearchServiceOn(t, &fakeElasticsearch{}, addr, func(*config.Config) {})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := svc.Search(ctx, &search.SearchRequest{Query: "model", Pagination: search.PaginationRequest{PageSize: 10}})
+	if !errors.Is(err, context.Canceled) || errors.Is(err, search.ErrSearchTimedOut) {
+		t.Errorf("abandoned search: err = %v, want context.Canceled", err)
+	}
+}
+
+func TestLatencyBudgetConfigValidation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeConfig(t, path, "embedding_share: 0.25", "embedding_share: 1.5")
+	if _, err := config.Load(path); err == nil || !strings.Contains(err.Error(), "latency_budget") {
+		t.Errorf("Load error = %v, want the embedding_share rejected", err)
+	}
+}
@@ -0,0 +1,179 @@
+package tests
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/org/llm-marketplace/services/discovery/internal/config"
+	"github.com/org/llm-marketplace/services/discovery/internal/elasticsearch"
+)
+
+// fakeBulk answers bulk requests item by item: ids in reject fail with 400,
+// and ids in busy get 429 until they have been sent busy[id] times. The last
+// document sent for each id is kept in docs.
+type fakeBulk struct {
+	mu       sync.Mutex
+	reject   map[string]bool
+	busy     map[string]int
+	seen     map[string]int
+	docs     map[string]string
+	requests int
+}
+
+func (f *fakeBulk) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("X-Elastic-Product", "Elasticsearch")
+	w.Header().Set("Content-Type", "application/json")
+	if !strings.HasSuffix(r.URL.Path, "/_bulk") {
+		w.Write([]byte(`{}`))
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests++
+
+	var items []map[string]interface{}
+	scanner := bufio.NewScanner(r.Body)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		var action struct {
+			Index struct {
+				ID string `json:"_id"`
+			} `json:"index"`
+		}
+		json.Unmarshal(scanner.Bytes(), &action)
+		scanner.Scan() // Document line
+
+		id := action.Index.ID
+		f.seen[id]++
+		f.docs[id] = scanner.Text()
+		outcome := map[string]interface{}{"_id": id, "status": http.StatusCreated}
+		switch {
+		case f.reject[id]:
+			outcome["status"] = http.StatusBadRequest
+			outcome["error"] = map[string]interface{}{"type": "mapper_parsing_exception", "reason": "bad field"}
+		case f.seen[id] <= f.busy[id]:
+			outcome["status"] = http.StatusTooManyRequests
+			outcome["error"] = map[string]interface{}{"type": "es_rejected_execution_exception", "reason": "queue full"}
+		}
+		items = append(items, map[string]interface{}{"index": outcome})
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{"took": 1, "errors": true, "items": items})
+}
+
+func newBulkClient(t *testing.T, es *fakeBulk, bulk config.BulkConfig) *elasticsearch.Client {
+	t.Helper()
+	es.seen = map[string]int{}
+	es.docs = map[string]string{}
+	server := httptest.NewServer(es)
+	t.Cleanup(server.Close)
+
+	client, err := elasticsearch.NewClient(config.ElasticsearchConfig{
+		Addresses: []string{server.URL},
+		IndexName: "services",
+		Bulk:      bulk,
+	}, nil)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	client.SetMetrics(testMetrics())
+	return client
+}
+
+func bulkDocs(ids ...string) []*elasticsearch.ServiceDocument {
+	docs := make([]*elasticsearch.ServiceDocument, len(ids))
+	for i, id := range ids {
+		docs[i] = &elasticsearch.ServiceDocument{ID: id, Name: "service " + id}
+	}
+	return docs
+}
+
+func TestBulkIndexRetriesRejectedDocuments(t *testing.T) {
+	es := &fakeBulk{busy: map[string]int{"b": 2}}
+	client := newBulkClient(t, es, config.BulkConfig{MaxAttempts: 3, InitialBackoff: time.Millisecond})
+
+	result, err := client.BulkIndex(context.Background(), bulkDocs("a", "b", "c"))
+	if err != nil {
+		t.Fatalf("BulkIndex: %v", err)
+	}
+	if result.Indexed != 3 || len(result.Failed) != 0 {
+		t.Errorf("result = %+v, want 3 indexed", result)
+	}
+	if es.seen["a"] != 1 || es.seen["b"] != 3 {
+		t.Errorf("sends = %v, want a once and b three times", es.seen)
+	}
+}
+
+func TestBulkIndexReportsFailedDocuments(t *testing.T) {
+	es := &fakeBulk{reject: map[string]bool{"a": true}, busy: map[string]int{"b": 10}}
+	client := newBulkClient(t, es, config.BulkConfig{MaxAttempts: 2, InitialBackoff: time.Millisecond})
+
+	result, err := client.BulkIndex(context.Background(), bulkDocs("a", "b", "c"))
+	if !errors.Is(err, elasticsearch.ErrBulkItemsFailed) {
+		t.Fatalf("err = %v, want ErrBulkItemsFailed", err)
+	}
+	if result.Indexed != 1 {
+		t.Errorf("indexed = %d, want 1", result.Indexed)
+	}
+
+	failed := map[string]elasticsearch.BulkFailure{}
+	for _, f := range result.Failed {
+		failed[f.ID] = f
+	}
+	if f := failed["a"]; f.Status != http.StatusBadRequest || f.Type != "mapper_parsing_exception" {
+		t.Errorf("failure for a = %+v, want 400 mapper_parsing_exception", f)
+	}
+	if f := failed["b"]; f.Status != http.StatusTooManyRequests {
+		t.Errorf("failure for b = %+v, want 429", f)
+	}
+	if es.seen["a"] != 1 || es.seen["b"] != 2 {
+		t.Errorf("sends = %v, want a once and b twice", es.seen)
+	}
+}
+
+func TestBulkIndexSkipsInvalidDocuments(t *testing.T) {
+	es := &fakeBulk{}
+	client := newBulkClient(t, es, config.BulkConfig{MaxAttempts: 1})
+
+	docs := bulkDocs("a", "b")
+	docs[1].SLA.Availability = 150
+
+	result, err := client.BulkIndex(context.Background(), docs)
+	if !errors.Is(err, elasticsearch.ErrBulkItemsFailed) {
+		t.Fatalf("err = %v, want ErrBulkItemsFailed", err)
+	}
+	if result.Indexed != 1 || len(result.Failed) != 1 {
+		t.Fatalf("result = %+v, want a indexed and b failed", result)
+	}
+	if f := result.Failed[0]; f.ID != "b" || f.Status != http.StatusBadRequest || !strings.Contains(f.Reason, "sla.availability") {
+		t.Errorf("failure = %+v, want a 400 naming sla.availability", f)
+	}
+	if es.seen["b"] != 0 {
+		t.Error("the invalid document was sent")
+	}
+}
+
+func TestBulkIndexSplitsLargeBatches(t *testing.T) {
+	es := &fakeBulk{}
+	client := newBulkClient(t, es, config.BulkConfig{MaxBytes: 300, MaxAttempts: 1})
+
+	result, err := client.BulkIndex(context.Background(), bulkDocs("a", "b", "c", "d", "e"))
+	if err != nil {
+		t.Fatalf("BulkIndex: %v", err)
+	}
+	if result.Indexed != 5 {
+		t.Errorf("indexed = %d, want 5", result.Indexed)
+	}
+	if es.requests < 2 {
+		t.Errorf("requests = %d, want the batch split across several", es.requests)
+	}
+}
@@ -0,0 +1,68 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/org/llm-marketplace/services/discovery/internal/elasticsearch"
+	"github.com/org/llm-marketplace/services/discovery/internal/problem"
+)
+
+func TestGetServiceByName(t *testing.T) {
+	docs := map[string]*elasticsearch.ServiceDocument{
+		"fast-translator": {ID: "fast-translator", Name: "Fast Translator", Status: elasticsearch.StatusActive},
+		"vision-api":      {ID: "vision-api", Name: "Vision API", Status: elasticsearch.StatusActive},
+		"vision-apx":      {ID: "vision-apx", Name: "Vision APX", Status: elasticsearch.StatusActive},
+	}
+	for id, doc := range entitlementFixtures {
+		docs[id] = doc
+	}
+	es := &fakeElasticsearch{docs: docs}
+	router := newEntitlementRouter(t, es)
+
+	for _, tt := range []struct {
+		name, caller string
+		status       int
+		id, match    string
+		distance     int
+	}{
+		{"fast   TRANSLATOR", "", http.StatusOK, "fast-translator", "exact", 0},
+		{"fast translatr", "", http.StatusOK, "fast-translator", "fuzzy", 1},
+		{"vision api", "", http.StatusOK, "vision-api", "exact", 0},
+		{"slow translater", "", http.StatusNotFound, "", "", 0},
+		{"vision apz", "", http.StatusConflict, "", "", 0},
+		{"acme restricted tenant model", "", http.StatusNotFound, "", "", 0},
+		{"acme restricted tenant model", "acme", http.StatusOK, "restricted-tenant-svc", "exact", 0},
+	} {
+		header := http.Header{}
+		if tt.caller != "" {
+			header.Set("X-Tenant-ID", tt.caller)
+		}
+		w := apiRequest(router, http.MethodGet, "/api/v1/services/by-name/"+url.PathEscape(tt.name), "", header)
+		if w.Code != tt.status {
+			t.Errorf("%q as %q: status %d, want %d: %s", tt.name, tt.caller, w.Code, tt.status, w.Body.String())
+			continue
+		}
+		if tt.status == http.StatusConflict && !strings.Contains(w.Body.String(), problem.Conflict.Code) {
+			t.Errorf("%q: body %s, want a %s problem", tt.name, w.Body.String(), problem.Conflict.Code)
+		}
+		if tt.status != http.StatusOK {
+			continue
+		}
+
+		var got struct {
+			Service  struct{ ID string } `json:"service"`
+			Match    string              `json:"match"`
+			Distance int                 `json:"distance"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+			t.Fatalf("%q: %v", tt.name, err)
+		}
+		if got.Service.ID != tt.id || got.Match != tt.match || got.Distance != tt.distance {
+			t.Errorf("%q resolved to %s (%s, %d edits), want %s (%s, %d edits)", tt.name, got.Service.ID, got.Match, got.Distance, tt.id, tt.match, tt.distance)
+		}
+	}
+}
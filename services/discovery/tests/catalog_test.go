@@ -0,0 +1,313 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"go.uber.org/zap"
+
+	"github.com/org/llm-marketplace/pkg/marketplace"
+	"github.com/org/llm-marketplace/services/discovery/internal/catalog"
+	"github.com/org/llm-marketplace/services/discovery/internal/config"
+	"github.com/org/llm-marketplace/services/discovery/internal/elasticsearch"
+)
+
+// fakeCatalog stands in for the revision store, the index and the publisher
+type fakeCatalog struct {
+	mu        sync.Mutex
+	revisions map[string]int64
+	docs      map[string]*elasticsearch.ServiceDocument
+	failures  int // PublishVersion calls to fail before succeeding
+	published int
+}
+
+func newFakeCatalog() *fakeCatalog {
+	return &fakeCatalog{revisions: map[string]int64{}, docs: map[string]*elasticsearch.ServiceDocument{}}
+}
+
+func (f *fakeCatalog) Revision(ctx context.Context, serviceID string) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.revisions[serviceID], nil
+}
+
+func (f *fakeCatalog) Save(ctx context.Context, event *marketplace.CatalogEvent) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.revisions[event.Service.ServiceID] = event.Revision
+	return nil
+}
+
+func (f *fakeCatalog) Get(ctx context.Context, id string) (*elasticsearch.ServiceDocument, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if doc, ok := f.docs[id]; ok {
+		return doc, nil
+	}
+	return nil, elasticsearch.ErrNotFound
+}
+
+func (f *fakeCatalog) PublishVersion(ctx context.Context, doc *elasticsearch.ServiceDocument) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.failures > 0 {
+		f.failures--
+		return errors.New("cluster unavailable")
+	}
+	f.published++
+	f.docs[doc.ID] = doc
+	return nil
+}
+
+// fakeReader delivers a fixed list of messages, then blocks until cancelled
+type fakeReader struct {
+	mu        sync.Mutex
+	messages  []kafka.Message
+	committed []int64
+	done      chan struct{}
+}
+
+func (r *fakeReader) FetchMessage(ctx context.Context) (kafka.Message, error) {
+	r.mu.Lock()
+	if len(r.messages) > 0 {
+		msg := r.messages[0]
+		r.messages = r.messages[1:]
+		r.mu.Unlock()
+		return msg, nil
+	}
+	r.mu.Unlock()
+	close(r.done)
+	<-ctx.Done()
+	return kafka.Message{}, ctx.Err()
+}
+
+func (r *fakeReader) CommitMessages(ctx context.Context, msgs ...kafka.Message) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, m := range msgs {
+		r.committed = append(r.committed, m.Offset)
+	}
+	return nil
+}
+
+func (r *fakeReader) Close() error { return nil }
+
+func catalogEvent(revision int64, status string, mutate func(*marketplace.CatalogEvent)) []byte {
+	event := marketplace.CatalogEvent{
+		ID:         "evt-1",
+		Type:       marketplace.ServiceUpdated,
+		OccurredAt: time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC),
+		Revision:   revision,
+		Status:     status,
+		Provider:   marketplace.ProviderInfo{ID: "prov-1", Name: "Acme AI", Verified: true},
+		Service: marketplace.ServiceDescriptor{
+			ServiceID:    "3f1c6a52-8a7e-4b55-9d0e-1f2a3b4c5d6e",
+			Name:         "Summarizer",
+			Version:      "1.2.0",
+			Description:  "Summarizes long documents",
+			ProviderID:   "prov-1",
+			Category:     "text-generation",
+			Tags:         []string{"nlp"},
+			Capabilities: []marketplace.Capability{{Name: "summarization"}},
+			Endpoint:     &marketplace.EndpointInfo{URL: "https://api.acme.ai/summarize"},
+			Pricing:      &marketplace.PricingInfo{Model: "per-token", Rate: 0.002, Unit: "1k tokens"},
+		},
+	}
+	if mutate != nil {
+		mutate(&event)
+	}
+	payload, _ := json.Marshal(event)
+	return payload
+}
+
+func newCatalogConsumer(fake *fakeCatalog) *catalog.Consumer {
+	cfg := config.CatalogConfig{RetryBackoff: time.Millisecond, MaxRetryBackoff: 5 * time.Millisecond}
+	return catalog.NewConsumer(cfg, fake, fake, fake, zap.NewNop())
+}
+
+func TestCatalogIndexesRegisteredService(t *testing.T) {
+	fake := newFakeCatalog()
+	consumer := newCatalogConsumer(fake)
+
+	if err := consumer.Apply(context.Background(), catalogEvent(1, marketplace.StatusActive, nil)); err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	doc := fake.docs["3f1c6a52-8a7e-4b55-9d0e-1f2a3b4c5d6e"]
+	if doc == nil {
+		t.Fatal("service was not indexed")
+	}
+	if doc.ServiceKey != "prov-1:summarizer" || doc.Version == nil || doc.Version.Number != "1.2.0" || !doc.Version.Stable {
+		t.Errorf("key = %q, version = %+v; want prov-1:summarizer at stable 1.2.0", doc.ServiceKey, doc.Version)
+	}
+	if doc.Endpoint != "https://api.acme.ai/summarize" || doc.Pricing.Rate != 0.002 || len(doc.Capabilities) != 1 {
+		t.Errorf("document = %+v, want the descriptor's endpoint, pricing and capabilities", doc)
+	}
+
+	// A redelivered or older event changes nothing
+	fake.published = 0
+	stale := catalogEvent(1, marketplace.StatusRetired, nil)
+	if err := consumer.Apply(context.Background(), stale); err != nil || fake.published != 0 {
+		t.Errorf("Apply of a stale event = %v after %d publishes, want it skipped", err, fake.published)
+	}
+}
+
+func TestCatalogKeepsFieldsTheRegistryDoesNotOwn(t *testing.T) {
+	fake := newFakeCatalog()
+	consumer := newCatalogConsumer(fake)
+	ctx := context.Background()
+
+	if err := consumer.Apply(ctx, catalogEvent(1, marketplace.StatusActive, nil)); err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	doc := fake.docs["3f1c6a52-8a7e-4b55-9d0e-1f2a3b4c5d6e"]
+	doc.Embedding = []float32{0.1, 0.2}
+	doc.Metrics.Rating = 4.5
+	created := doc.CreatedAt
+
+	// A pricing change keeps the embedding
+	err := consumer.Apply(ctx, catalogEvent(2, marketplace.StatusDeprecated, func(e *marketplace.CatalogEvent) {
+		e.OccurredAt = e.OccurredAt.Add(time.Hour)
+		e.Service.Pricing.Rate = 0.003
+		e.UpheldReports = 2
+		e.ComplianceExpiries = []marketplace.ComplianceExpiry{{Kind: marketplace.ExpiryCertification, Name: "SOC2", Status: marketplace.ExpiryExpiring, ExpiresAt: e.OccurredAt.AddDate(0, 0, 20)}}
+	}))
+	if err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	doc = fake.docs["3f1c6a52-8a7e-4b55-9d0e-1f2a3b4c5d6e"]
+	if len(doc.Embedding) != 2 || doc.Metrics.Rating != 4.5 || !doc.CreatedAt.Equal(created) {
+		t.Errorf("embedding = %v, rating = %v, created = %v; want them kept", doc.Embedding, doc.Metrics.Rating, doc.CreatedAt)
+	}
+	if doc.UpheldReports != 2 {
+		t.Errorf("upheld reports = %d, want the registry's count", doc.UpheldReports)
+	}
+	if len(doc.Expiries) != 1 || doc.Expiries[0].Name != "SOC2" {
+		t.Errorf("compliance expiries = %+v, want the registry's", doc.Expiries)
+	}
+	if doc.Status != elasticsearch.StatusDeprecated || doc.Deprecation == nil {
+		t.Errorf("status = %s, deprecation = %v; want deprecated with a deprecation time", doc.Status, doc.Deprecation)
+	}
+
+	// A new description needs a new embedding
+	err = consumer.Apply(ctx, catalogEvent(3, marketplace.StatusActive, func(e *marketplace.CatalogEvent) {
+		e.Service.Description = "Summarizes and translates long documents"
+	}))
+	if err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	doc = fake.docs["3f1c6a52-8a7e-4b55-9d0e-1f2a3b4c5d6e"]
+	if doc.Embedding != nil || doc.Deprecation != nil {
+		t.Errorf("embedding = %v, deprecation = %v; want both cleared", doc.Embedding, doc.Deprecation)
+	}
+}
+
+func TestCatalogIndexesPrivateServiceInItsTenant(t *testing.T) {
+	fake := newFakeCatalog()
+	consumer := newCatalogConsumer(fake)
+	ctx := context.Background()
+
+	private := func(e *marketplace.CatalogEvent) {
+		e.Service.Class = marketplace.ClassPrivate
+		e.Service.TenantID = "acme"
+	}
+	if err := consumer.Apply(ctx, catalogEvent(1, marketplace.StatusActive, private)); err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	doc := fake.docs["3f1c6a52-8a7e-4b55-9d0e-1f2a3b4c5d6e"]
+	if doc.TenantID != "acme" || doc.Access == nil || doc.Access.Visibility != elasticsearch.VisibilityTenant || doc.Access.OwnerTenant != "acme" {
+		t.Fatalf("tenant = %q, access = %+v; want visible only inside acme", doc.TenantID, doc.Access)
+	}
+
+	// Access rules set on the index can't publish a private service
+	doc.Access = &elasticsearch.AccessInfo{Visibility: elasticsearch.VisibilityPublic}
+	if err := consumer.Apply(ctx, catalogEvent(2, marketplace.StatusActive, private)); err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	doc = fake.docs["3f1c6a52-8a7e-4b55-9d0e-1f2a3b4c5d6e"]
+	if doc.Access.Visibility != elasticsearch.VisibilityTenant || doc.Access.OwnerTenant != "acme" {
+		t.Errorf("access = %+v, want it restored to acme", doc.Access)
+	}
+}
+
+func TestCatalogAccumulatesChangelog(t *testing.T) {
+	fake := newFakeCatalog()
+	consumer := newCatalogConsumer(fake)
+	ctx := context.Background()
+
+	if err := consumer.Apply(ctx, catalogEvent(1, marketplace.StatusActive, nil)); err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	for revision := int64(2); revision <= 25; revision++ {
+		err := consumer.Apply(ctx, catalogEvent(revision, marketplace.StatusActive, func(e *marketplace.CatalogEvent) {
+			e.Changes = []marketplace.ServiceChange{
+				{Kind: marketplace.ChangePricing, Revision: revision, Summary: "Pricing changed"},
+				{Kind: marketplace.ChangeSLA, Revision: revision, Summary: "SLA changed"},
+			}
+		}))
+		if err != nil {
+			t.Fatalf("Apply: %v", err)
+		}
+	}
+	changelog := fake.docs["3f1c6a52-8a7e-4b55-9d0e-1f2a3b4c5d6e"].Changelog
+	if len(changelog) != 20 {
+		t.Fatalf("changelog has %d entries, want the newest 20", len(changelog))
+	}
+	if changelog[0].Revision != 25 || changelog[0].Kind != marketplace.ChangeSLA || changelog[19].Revision != 16 {
+		t.Errorf("changelog runs from %+v to %+v, want revision 25 down to 16", changelog[0], changelog[19])
+	}
+}
+
+func TestCatalogRejectsInvalidEvents(t *testing.T) {
+	consumer := newCatalogConsumer(newFakeCatalog())
+
+	for name, payload := range map[string][]byte{
+		"malformed":      []byte(`{"revision": "one"}`),
+		"invalid":        catalogEvent(1, marketplace.StatusActive, func(e *marketplace.CatalogEvent) { e.Service.Name = "" }),
+		"unknown status": catalogEvent(1, "paused", nil),
+	} {
+		if err := consumer.Apply(context.Background(), payload); !errors.Is(err, catalog.ErrPermanent) {
+			t.Errorf("%s: Apply = %v, want ErrPermanent", name, err)
+		}
+	}
+}
+
+func TestCatalogConsumerRetriesBeforeCommitting(t *testing.T) {
+	fake := newFakeCatalog()
+	fake.failures = 2
+	reader := &fakeReader{
+		messages: []kafka.Message{
+			{Offset: 1, Value: []byte(`not json`)},
+			{Offset: 2, Value: catalogEvent(1, marketplace.StatusActive, nil)},
+		},
+		done: make(chan struct{}),
+	}
+	consumer := newCatalogConsumer(fake)
+	consumer.SetReader(reader)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		consumer.Start(ctx)
+		close(stopped)
+	}()
+
+	select {
+	case <-reader.done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("consumer did not reach the end of the topic")
+	}
+	cancel()
+	<-stopped
+
+	if fake.published != 1 {
+		t.Errorf("published = %d, want 1 after the retries", fake.published)
+	}
+	if len(reader.committed) != 2 || reader.committed[0] != 1 || reader.committed[1] != 2 {
+		t.Errorf("committed = %v, want the skipped and the applied offsets in order", reader.committed)
+	}
+}
@@ -0,0 +1,103 @@
+package tests
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/gin-gonic/gin"
+
+	"github.com/org/llm-marketplace/services/discovery/internal/api"
+)
+
+const compressionMinSize = 64
+
+var largeBody = strings.Repeat("translation model ", 20)
+
+// newCompressionRouter serves a large body at /large and a small one at /small
+func newCompressionRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(api.Compression(compressionMinSize))
+	router.GET("/large", func(c *gin.Context) { c.String(http.StatusOK, largeBody) })
+	router.GET("/small", func(c *gin.Context) { c.String(http.StatusOK, "ok") })
+	router.GET("/empty", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+	return router
+}
+
+func getEncoded(router *gin.Engine, path, acceptEncoding string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	if acceptEncoding != "" {
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+// decodeBody undoes the response's Content-Encoding
+func decodeBody(t *testing.T, w *httptest.ResponseRecorder) string {
+	t.Helper()
+	var r io.Reader = w.Body
+	switch w.Header().Get("Content-Encoding") {
+	case "gzip":
+		gz, err := gzip.NewReader(w.Body)
+		if err != nil {
+			t.Fatalf("invalid gzip body: %v", err)
+		}
+		r = gz
+	case "br":
+		r = brotli.NewReader(w.Body)
+	}
+	body, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("failed to decode body: %v", err)
+	}
+	return string(body)
+}
+
+func TestCompressionNegotiatesEncoding(t *testing.T) {
+	router := newCompressionRouter()
+
+	for _, tc := range []struct {
+		acceptEncoding string
+		want           string
+	}{
+		{"gzip", "gzip"},
+		{"gzip, deflate", "gzip"},
+		{"gzip, br", "br"},
+		{"br;q=0, gzip", "gzip"},
+		{"gzip;q=0", ""},
+		{"identity", ""},
+		{"", ""},
+	} {
+		w := getEncoded(router, "/large", tc.acceptEncoding)
+		if got := w.Header().Get("Content-Encoding"); got != tc.want {
+			t.Errorf("Accept-Encoding %q: Content-Encoding %q, want %q", tc.acceptEncoding, got, tc.want)
+		}
+		if body := decodeBody(t, w); body != largeBody {
+			t.Errorf("Accept-Encoding %q: body %q, want the handler's", tc.acceptEncoding, body)
+		}
+		if tc.want != "" && w.Header().Get("Vary") != "Accept-Encoding" {
+			t.Errorf("Accept-Encoding %q: Vary %q, want Accept-Encoding", tc.acceptEncoding, w.Header().Get("Vary"))
+		}
+	}
+}
+
+func TestCompressionSkipsSmallAndEmptyBodies(t *testing.T) {
+	router := newCompressionRouter()
+
+	w := getEncoded(router, "/small", "gzip")
+	if enc := w.Header().Get("Content-Encoding"); enc != "" || w.Body.String() != "ok" {
+		t.Errorf("small body: Content-Encoding %q, body %q; want it sent as is", enc, w.Body.String())
+	}
+
+	w = getEncoded(router, "/empty", "gzip")
+	if enc := w.Header().Get("Content-Encoding"); w.Code != http.StatusNoContent || enc != "" || w.Body.Len() != 0 {
+		t.Errorf("204: status %d, Content-Encoding %q, %d bytes", w.Code, enc, w.Body.Len())
+	}
+}
@@ -0,0 +1,178 @@
+package tests
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/org/llm-marketplace/pkg/marketplace/envconfig"
+	"github.com/org/llm-marketplace/services/discovery/internal/config"
+)
+
+func TestLoadWithoutConfigFile(t *testing.T) {
+	cfg, err := config.Load("")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.Server.Port != 8080 || cfg.Postgres.Host != "localhost" || len(cfg.Elasticsearch.Addresses) != 1 {
+		t.Errorf("defaults not applied: port %d, postgres %q, elasticsearch %v",
+			cfg.Server.Port, cfg.Postgres.Host, cfg.Elasticsearch.Addresses)
+	}
+	if got := cfg.CacheTTL("search_results"); got != 30*time.Second {
+		t.Errorf("search_results TTL = %s, want 30s", got)
+	}
+}
+
+func TestEnvironmentOverrides(t *testing.T) {
+	t.Setenv("DISCOVERY_SERVER_PORT", "9000")
+	t.Setenv("DISCOVERY_SERVER_READ_TIMEOUT", "45s")
+	t.Setenv("DISCOVERY_SEARCH_SEMANTIC_ENABLED", "false")
+	t.Setenv("DISCOVERY_SEARCH_RANKING_WEIGHTS_RELEVANCE", "0.7")
+	t.Setenv("DISCOVERY_SEARCH_RANKING_WEIGHTS_POPULARITY", "0.1")
+	t.Setenv("DISCOVERY_SEARCH_RANKING_WEIGHTS_PERFORMANCE", "0.1")
+	t.Setenv("DISCOVERY_SEARCH_RANKING_WEIGHTS_COMPLIANCE", "0.1")
+	t.Setenv("DISCOVERY_ELASTICSEARCH_ADDRESSES", "http://es-1:9200, http://es-2:9200")
+	t.Setenv("DISCOVERY_REDIS_CACHE_TTL", "{search_results: 1m}")
+	t.Setenv("DISCOVERY_POSTGRES_PASSWORD", "from-env")
+
+	cfg, err := config.Load("../config.yaml")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.Server.Port != 9000 || cfg.Server.ReadTimeout != 45*time.Second {
+		t.Errorf("server = %d %s, want 9000 45s", cfg.Server.Port, cfg.Server.ReadTimeout)
+	}
+	if cfg.Search.SemanticEnabled {
+		t.Error("search.semantic_enabled not overridden")
+	}
+	if w := cfg.RankingWeights(); w.Relevance != 0.7 || w.Compliance != 0.1 {
+		t.Errorf("ranking weights = %+v, want relevance 0.7", w)
+	}
+	if got := strings.Join(cfg.Elasticsearch.Addresses, " "); got != "http://es-1:9200 http://es-2:9200" {
+		t.Errorf("elasticsearch addresses = %q", got)
+	}
+	if got := cfg.CacheTTL("search_results"); got != time.Minute {
+		t.Errorf("search_results TTL = %s, want 1m", got)
+	}
+	if cfg.Postgres.Password != "from-env" {
+		t.Errorf("postgres password = %q, want the environment's", cfg.Postgres.Password)
+	}
+
+	// Settings the environment leaves alone come from the file
+	if cfg.Postgres.Host != "postgres" {
+		t.Errorf("postgres host = %q, want the file's", cfg.Postgres.Host)
+	}
+}
+
+func TestEnvironmentOverrideErrors(t *testing.T) {
+	t.Setenv("DISCOVERY_SERVER_PORT", "eighty")
+
+	_, err := config.Load("")
+	if err == nil || !strings.Contains(err.Error(), "DISCOVERY_SERVER_PORT") {
+		t.Fatalf("Load error = %v, want it to name the variable", err)
+	}
+}
+
+func TestBreachTopicRequiresBrokers(t *testing.T) {
+	t.Setenv("DISCOVERY_SLA_MONITORING_BREACH_TOPIC", "marketplace.sla.breaches")
+
+	_, err := config.Load("")
+	if err == nil || !strings.Contains(err.Error(), "breach_topic") {
+		t.Fatalf("Load error = %v, want breach_topic to require kafka_brokers", err)
+	}
+
+	t.Setenv("DISCOVERY_SLA_MONITORING_KAFKA_BROKERS", "kafka:9092")
+	if _, err := config.Load(""); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+}
+
+func TestEnvVarsAreUnique(t *testing.T) {
+	vars := envconfig.Vars(&config.Config{}, "DISCOVERY_")
+	seen := make(map[string]bool, len(vars))
+	for _, name := range vars {
+		if seen[name] {
+			t.Errorf("%s overrides more than one setting", name)
+		}
+		seen[name] = true
+	}
+	for _, want := range []string{"DISCOVERY_POSTGRES_HOST", "DISCOVERY_SERVER_HOT_RELOAD", "DISCOVERY_OBSERVABILITY_LOGGING_ACCESS_SLOW_THRESHOLD"} {
+		if !seen[want] {
+			t.Errorf("no environment variable %s", want)
+		}
+	}
+}
+
+// writeConfig copies config.yaml to a temporary file with old replaced by new
+func writeConfig(t *testing.T, path, old, new string) {
+	t.Helper()
+	base, err := os.ReadFile("../config.yaml")
+	if err != nil {
+		t.Fatalf("failed to read config.yaml: %v", err)
+	}
+	if !strings.Contains(string(base), old) {
+		t.Fatalf("config.yaml has no %q", old)
+	}
+	content := strings.Replace(string(base), old, new, 1)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+}
+
+func TestWatcherReloadsRankingWeightsAndCacheTTLs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeConfig(t, path, "", "")
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	watcher := config.NewWatcher(path, cfg, zap.NewNop())
+
+	writeConfig(t, path, "relevance: 0.4\n    popularity: 0.2", "relevance: 0.5\n    popularity: 0.1")
+	if err := watcher.Reload(); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	if w := cfg.RankingWeights(); w.Relevance != 0.5 || w.Popularity != 0.1 {
+		t.Errorf("ranking weights = %+v, want relevance 0.5 and popularity 0.1", w)
+	}
+	if cfg.Search.RankingWeights.Relevance != 0.4 {
+		t.Errorf("search.ranking_weights changed in place; readers must go through RankingWeights()")
+	}
+
+	// Weights that don't sum to 1 are rejected and the last good ones kept
+	writeConfig(t, path, "relevance: 0.4", "relevance: 0.9")
+	if err := watcher.Reload(); err == nil {
+		t.Error("Reload accepted ranking weights summing to 1.5")
+	}
+	if w := cfg.RankingWeights(); w.Relevance != 0.5 {
+		t.Errorf("ranking weights = %+v after an invalid reload, want the previous ones", w)
+	}
+}
+
+func TestWatcherFollowsFileChanges(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeConfig(t, path, "", "")
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go config.NewWatcher(path, cfg, zap.NewNop()).Start(ctx)
+	time.Sleep(100 * time.Millisecond) // let the watch start
+
+	writeConfig(t, path, "search_results: 30s", "search_results: 90s")
+	deadline := time.Now().Add(5 * time.Second)
+	for cfg.CacheTTL("search_results") != 90*time.Second {
+		if time.Now().After(deadline) {
+			t.Fatalf("search_results TTL = %s, want the reloaded 90s", cfg.CacheTTL("search_results"))
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
@@ -0,0 +1,60 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/org/llm-marketplace/pkg/marketplace"
+)
+
+func TestCatalogIndexesDataset(t *testing.T) {
+	fake := newFakeCatalog()
+	consumer := newCatalogConsumer(fake)
+
+	err := consumer.Apply(context.Background(), catalogEvent(1, marketplace.StatusActive, func(e *marketplace.CatalogEvent) {
+		e.Service.Kind = marketplace.KindDataset
+		e.Service.Endpoint = nil
+		e.Service.Dataset = &marketplace.DatasetInfo{
+			License:    "CC-BY-4.0",
+			Format:     "parquet",
+			SizeBytes:  5 << 30,
+			Records:    1200000,
+			Provenance: &marketplace.DatasetProvenance{Source: "Support tickets", Method: marketplace.ProvenanceAnnotated},
+		}
+	}))
+	if err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	doc := fake.docs["3f1c6a52-8a7e-4b55-9d0e-1f2a3b4c5d6e"]
+	if doc == nil {
+		t.Fatal("dataset was not indexed")
+	}
+	if doc.Kind != marketplace.KindDataset || doc.Dataset == nil || doc.Dataset.License != "CC-BY-4.0" || doc.Dataset.Provenance == nil {
+		t.Errorf("document = %+v, want the dataset with its license and provenance", doc)
+	}
+	if desc := doc.Descriptor(); !desc.IsDataset() || desc.Dataset.SizeBytes != 5<<30 {
+		t.Errorf("descriptor = %+v, want the dataset for policy checks", desc)
+	}
+}
+
+func TestSearchFiltersOnDatasetLicenseAndFormat(t *testing.T) {
+	es := &fakeElasticsearch{}
+	router := newEntitlementRouter(t, es)
+
+	if w := apiRequest(router, http.MethodGet, "/api/v1/search?q=tickets&kind=dataset&licenses=CC-BY-4.0,MIT&dataset_formats=Parquet&max_dataset_bytes=1000000", "", nil); w.Code != http.StatusOK {
+		t.Fatalf("search: status %d, body %s", w.Code, w.Body.String())
+	}
+	body := es.searches[len(es.searches)-1]
+	for _, want := range []string{
+		`{"term":{"kind":"dataset"}}`,
+		`{"terms":{"dataset.license":["CC-BY-4.0","MIT"]}}`,
+		`{"terms":{"dataset.format":["parquet"]}}`,
+		`"dataset.size_bytes":{`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("search body %s, want %s", body, want)
+		}
+	}
+}
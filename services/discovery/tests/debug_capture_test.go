@@ -0,0 +1,73 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/org/llm-marketplace/services/discovery/internal/requestid"
+	"github.com/org/llm-marketplace/services/discovery/internal/search"
+)
+
+func TestSearchDebugCaptureRecordsEachStage(t *testing.T) {
+	cfg := startSandbox(t)
+	cfg.Search.DebugCapture.Enabled = true
+	cfg.Search.DebugCapture.TTL = time.Minute
+
+	svc, _ := newSandboxSearch(t, cfg)
+
+	// A plain search is cached and not captured
+	ctx := requestid.WithID(context.Background(), "req-plain")
+	plain, err := svc.Search(ctx, &search.SearchRequest{Query: "code review", Literal: true})
+	if err != nil {
+		t.Fatalf("search failed: %v", err)
+	}
+	if plain.DebugID != "" {
+		t.Errorf("search without debug captured as %q", plain.DebugID)
+	}
+	if _, err := svc.GetDebugCapture(ctx, "req-plain"); !errors.Is(err, search.ErrCaptureNotFound) {
+		t.Errorf("capture of a plain search: %v, want not found", err)
+	}
+
+	// The same search with debug skips the cache and is captured
+	ctx = requestid.WithID(context.Background(), "req-debug")
+	resp, err := svc.Search(ctx, &search.SearchRequest{Query: "code review", Literal: true, Debug: true})
+	if err != nil {
+		t.Fatalf("search failed: %v", err)
+	}
+	if resp.DebugID != "req-debug" {
+		t.Fatalf("debug_id %q, want the request ID", resp.DebugID)
+	}
+
+	capture, err := svc.GetDebugCapture(context.Background(), "req-debug")
+	if err != nil {
+		t.Fatalf("GetDebugCapture: %v", err)
+	}
+	if capture.Query != "code review" || capture.ElasticsearchQuery["query"] == nil {
+		t.Errorf("captured query %q with Elasticsearch query %v", capture.Query, capture.ElasticsearchQuery)
+	}
+	if len(capture.Hits) == 0 || capture.Total != resp.Total {
+		t.Fatalf("captured %d hits of %d, response has %d", len(capture.Hits), capture.Total, resp.Total)
+	}
+
+	stages := []string{search.DebugStageElasticsearch, search.DebugStageRank, search.DebugStageRerank}
+	if len(capture.Stages) != len(stages) {
+		t.Fatalf("captured %d stages, want %d", len(capture.Stages), len(stages))
+	}
+	for i, name := range stages {
+		if capture.Stages[i].Name != name {
+			t.Errorf("stage %d is %q, want %q", i, capture.Stages[i].Name, name)
+		}
+	}
+	if got := capture.Stages[0].Results[0]; got.Score != capture.Hits[0].Score || got.ServiceID != capture.Hits[0].ID {
+		t.Errorf("first Elasticsearch stage result %+v doesn't match the first hit", got)
+	}
+	final := capture.Stages[len(capture.Stages)-1].Results
+	for i, r := range resp.Results {
+		if final[i].ServiceID != r.Service.ID || final[i].Score != r.Score {
+			t.Errorf("final stage result %d is %s at %f, response has %s at %f",
+				i, final[i].ServiceID, final[i].Score, r.Service.ID, r.Score)
+		}
+	}
+}
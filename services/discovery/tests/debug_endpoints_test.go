@@ -0,0 +1,93 @@
+package tests
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/org/llm-marketplace/services/discovery/internal/config"
+	"github.com/org/llm-marketplace/services/discovery/internal/observability"
+)
+
+func startDebugMetricsServer(t *testing.T, debug config.DebugConfig) string {
+	t.Helper()
+	srv := observability.NewMetricsServer(config.MetricsConfig{Debug: debug})
+	addr, _ := serveMetrics(t, srv)
+	t.Cleanup(func() { srv.Shutdown(context.Background()) })
+	return "http://" + addr
+}
+
+func debugGet(t *testing.T, url, user, pass string) (int, string) {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatalf("failed to build request: %v", err)
+	}
+	if user != "" {
+		req.SetBasicAuth(user, pass)
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer res.Body.Close()
+	body, _ := io.ReadAll(res.Body)
+	return res.StatusCode, string(body)
+}
+
+func TestDebugEndpointsDisabledByDefault(t *testing.T) {
+	base := startDebugMetricsServer(t, config.DebugConfig{})
+	for _, path := range []string{"/debug/pprof/", "/debug/vars", "/debug/goroutines"} {
+		if status, _ := debugGet(t, base+path, "", ""); status != http.StatusNotFound {
+			t.Errorf("%s status = %d, want 404", path, status)
+		}
+	}
+}
+
+func TestDebugEndpointsRequireCredentials(t *testing.T) {
+	base := startDebugMetricsServer(t, config.DebugConfig{
+		Enabled:      true,
+		Username:     "ops",
+		Password:     "secret",
+		AllowedCIDRs: []string{"127.0.0.0/8"},
+	})
+
+	if status, _ := debugGet(t, base+"/debug/pprof/", "", ""); status != http.StatusUnauthorized {
+		t.Errorf("without credentials status = %d, want 401", status)
+	}
+	if status, _ := debugGet(t, base+"/debug/pprof/", "ops", "wrong"); status != http.StatusUnauthorized {
+		t.Errorf("wrong password status = %d, want 401", status)
+	}
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/debug/pprof/", "goroutine"},
+		{"/debug/vars", "memstats"},
+		{"/debug/goroutines", "goroutine "},
+	}
+	for _, tt := range tests {
+		status, body := debugGet(t, base+tt.path, "ops", "secret")
+		if status != http.StatusOK || !strings.Contains(body, tt.want) {
+			t.Errorf("%s = %d, want 200 containing %q", tt.path, status, tt.want)
+		}
+	}
+
+	// Scrapes are not behind the debug credentials
+	if status, _ := debugGet(t, base+"/metrics", "", ""); status != http.StatusOK {
+		t.Errorf("/metrics status = %d, want 200", status)
+	}
+}
+
+func TestDebugEndpointsEnforceAllowlist(t *testing.T) {
+	base := startDebugMetricsServer(t, config.DebugConfig{
+		Enabled:      true,
+		AllowedCIDRs: []string{"10.0.0.0/8"},
+	})
+	if status, _ := debugGet(t, base+"/debug/vars", "", ""); status != http.StatusForbidden {
+		t.Errorf("status from outside the allowlist = %d, want 403", status)
+	}
+}
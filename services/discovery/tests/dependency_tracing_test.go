@@ -0,0 +1,147 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	goredis "github.com/go-redis/redis/v8"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/org/llm-marketplace/services/discovery/internal/config"
+	"github.com/org/llm-marketplace/services/discovery/internal/elasticsearch"
+	"github.com/org/llm-marketplace/services/discovery/internal/redis"
+	"github.com/org/llm-marketplace/services/discovery/internal/search"
+)
+
+var (
+	spansOnce sync.Once
+	spans     *tracetest.SpanRecorder
+	tracer    trace.Tracer
+)
+
+// testSpans installs a global tracer provider that records every span. The
+// global provider can only be delegated to once, so tests share it and look
+// for spans under their own parent.
+func testSpans() (*tracetest.SpanRecorder, trace.Tracer) {
+	spansOnce.Do(func() {
+		spans = tracetest.NewSpanRecorder()
+		tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans))
+		otel.SetTracerProvider(tp)
+		otel.SetTextMapPropagator(propagation.TraceContext{})
+		tracer = tp.Tracer("test")
+	})
+	return spans, tracer
+}
+
+// childSpans returns the ended spans whose parent is parent
+func childSpans(recorder *tracetest.SpanRecorder, parent trace.Span) []sdktrace.ReadOnlySpan {
+	var children []sdktrace.ReadOnlySpan
+	for _, span := range recorder.Ended() {
+		if span.Parent().SpanID() == parent.SpanContext().SpanID() {
+			children = append(children, span)
+		}
+	}
+	return children
+}
+
+func TestEmbeddingCallsAreTracedAndPropagated(t *testing.T) {
+	recorder, tracer := testSpans()
+
+	var traceparent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+		w.Write([]byte(`{"embeddings": [[1]], "model": "test-model"}`))
+	}))
+	t.Cleanup(server.Close)
+
+	ctx, parent := tracer.Start(context.Background(), "search")
+	client := search.NewEmbeddingClient(embeddingConfig(server.URL), testMetrics())
+	if _, err := client.GetEmbedding(ctx, "test-model", "abc"); err != nil {
+		t.Fatalf("GetEmbedding: %v", err)
+	}
+	parent.End()
+
+	if !strings.Contains(traceparent, parent.SpanContext().TraceID().String()) {
+		t.Errorf("traceparent = %q, want trace %s", traceparent, parent.SpanContext().TraceID())
+	}
+	children := childSpans(recorder, parent)
+	if len(children) != 1 || children[0].Name() != "embedding_service.embed" {
+		t.Fatalf("child spans = %v, want one embedding_service.embed", spanNames(children))
+	}
+	if children[0].SpanKind() != trace.SpanKindClient {
+		t.Errorf("span kind = %v, want client", children[0].SpanKind())
+	}
+}
+
+func TestElasticsearchCallsAreTraced(t *testing.T) {
+	recorder, tracer := testSpans()
+
+	server := httptest.NewServer(&fakeElasticsearch{})
+	t.Cleanup(server.Close)
+	client, err := elasticsearch.NewClient(config.ElasticsearchConfig{Addresses: []string{server.URL}, IndexName: "services"}, nil)
+	if err != nil {
+		t.Fatalf("failed to create elasticsearch client: %v", err)
+	}
+
+	ctx, parent := tracer.Start(context.Background(), "search")
+	if _, err := client.Search(ctx, map[string]interface{}{"query": map[string]interface{}{"match_all": map[string]interface{}{}}}); err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	parent.End()
+
+	children := childSpans(recorder, parent)
+	if len(children) != 1 || children[0].Name() != "search" {
+		t.Fatalf("child spans = %v, want one search", spanNames(children))
+	}
+	if system := spanAttribute(children[0], "db.system"); system != "elasticsearch" {
+		t.Errorf("db.system = %q, want elasticsearch", system)
+	}
+}
+
+func TestRedisCommandsAreTraced(t *testing.T) {
+	recorder, tracer := testSpans()
+
+	client := goredis.NewClient(&goredis.Options{Addr: "127.0.0.1:1", MaxRetries: -1})
+	client.AddHook(redis.NewTracingHook(0))
+	t.Cleanup(func() { client.Close() })
+
+	ctx, parent := tracer.Start(context.Background(), "search")
+	if err := client.Get(ctx, "search:key").Err(); err == nil {
+		t.Fatal("Get against an unreachable server succeeded")
+	}
+	parent.End()
+
+	children := childSpans(recorder, parent)
+	if len(children) != 1 || children[0].Name() != "redis get" {
+		t.Fatalf("child spans = %v, want one redis get", spanNames(children))
+	}
+	if children[0].Status().Code != codes.Error {
+		t.Errorf("status = %v, want error for a failed command", children[0].Status())
+	}
+}
+
+func spanNames(spans []sdktrace.ReadOnlySpan) []string {
+	names := make([]string, len(spans))
+	for i, span := range spans {
+		names[i] = span.Name()
+	}
+	return names
+}
+
+func spanAttribute(span sdktrace.ReadOnlySpan, key string) string {
+	for _, kv := range span.Attributes() {
+		if string(kv.Key) == key {
+			return kv.Value.Emit()
+		}
+	}
+	return ""
+}
@@ -0,0 +1,498 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/org/llm-marketplace/services/discovery/internal/config"
+	"github.com/org/llm-marketplace/services/discovery/internal/elasticsearch"
+	"github.com/org/llm-marketplace/services/discovery/internal/search"
+	"go.uber.org/zap"
+)
+
+// embeddingServer fails the first failures calls with status, then embeds each
+// text as a one-element vector holding its length
+func embeddingServer(t *testing.T, failures int32, status int) (*httptest.Server, *int32) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) <= failures {
+			w.WriteHeader(status)
+			return
+		}
+		var req search.EmbeddingRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+
+		resp := search.EmbeddingResponse{Model: req.Model}
+		for _, text := range req.Texts {
+			resp.Embeddings = append(resp.Embeddings, []float32{float32(len(text))})
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	t.Cleanup(server.Close)
+	return server, &calls
+}
+
+func embeddingConfig(url string) config.EmbeddingServiceConfig {
+	return config.EmbeddingServiceConfig{
+		URL:            url,
+		Model:          "test-model",
+		Timeout:        time.Second,
+		BatchSize:      2,
+		MaxAttempts:    3,
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     5 * time.Millisecond,
+		Concurrency:    3,
+	}
+}
+
+func TestEmbeddingClientRetriesTransientFailures(t *testing.T) {
+	server, calls := embeddingServer(t, 2, http.StatusServiceUnavailable)
+	client := search.NewEmbeddingClient(embeddingConfig(server.URL), testMetrics())
+
+	vector, err := client.GetEmbedding(context.Background(), "test-model", "abc")
+	if err != nil {
+		t.Fatalf("GetEmbedding: %v", err)
+	}
+	if len(vector) != 1 || vector[0] != 3 {
+		t.Errorf("vector = %v, want [3]", vector)
+	}
+	if n := atomic.LoadInt32(calls); n != 3 {
+		t.Errorf("calls = %d, want 3", n)
+	}
+}
+
+func TestEmbeddingClientDoesNotRetryClientErrors(t *testing.T) {
+	server, calls := embeddingServer(t, 1, http.StatusBadRequest)
+	client := search.NewEmbeddingClient(embeddingConfig(server.URL), testMetrics())
+
+	if _, err := client.GetEmbedding(context.Background(), "test-model", "abc"); err == nil {
+		t.Fatal("GetEmbedding succeeded, want error")
+	}
+	if n := atomic.LoadInt32(calls); n != 1 {
+		t.Errorf("calls = %d, want 1", n)
+	}
+}
+
+func TestEmbeddingClientGivesUpAfterMaxAttempts(t *testing.T) {
+	server, calls := embeddingServer(t, 10, http.StatusBadGateway)
+	client := search.NewEmbeddingClient(embeddingConfig(server.URL), testMetrics())
+
+	if _, err := client.GetEmbedding(context.Background(), "test-model", "abc"); err == nil {
+		t.Fatal("GetEmbedding succeeded, want error")
+	}
+	if n := atomic.LoadInt32(calls); n != 3 {
+		t.Errorf("calls = %d, want 3", n)
+	}
+}
+
+func TestEmbeddingClientBatchesKeepInputOrder(t *testing.T) {
+	server, calls := embeddingServer(t, 0, 0)
+	client := search.NewEmbeddingClient(embeddingConfig(server.URL), testMetrics())
+
+	texts := []string{"a", "bb", "ccc", "dddd", "eeeee", "ffffff", "ggggggg"}
+	vectors, err := client.GetEmbeddingsBatch(context.Background(), "test-model", texts)
+	if err != nil {
+		t.Fatalf("GetEmbeddingsBatch: %v", err)
+	}
+	if len(vectors) != len(texts) {
+		t.Fatalf("got %d vectors, want %d", len(vectors), len(texts))
+	}
+	for i, text := range texts {
+		if vectors[i][0] != float32(len(text)) {
+			t.Errorf("vector %d = %v, want [%d]", i, vectors[i], len(text))
+		}
+	}
+	if n := atomic.LoadInt32(calls); n != 4 {
+		t.Errorf("calls = %d, want 4", n)
+	}
+}
+
+func TestSemanticClauseSkippedWhileEmbeddingServiceIsDown(t *testing.T) {
+	var down atomic.Bool
+	var embeds int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/health" {
+			if down.Load() {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}
+			return
+		}
+		atomic.AddInt32(&embeds, 1)
+		json.NewEncoder(w).Encode(search.EmbeddingResponse{Embeddings: [][]float32{{1}}})
+	}))
+	t.Cleanup(server.Close)
+
+	cfg := embeddingConfig(server.URL)
+	cfg.Health = config.EmbeddingHealthConfig{Interval: 5 * time.Millisecond, FailureThreshold: 1}
+	svc := newSearchService(t, &fakeElasticsearch{}, func(c *config.Config) {
+		c.Search.SemanticEnabled = true
+		c.EmbeddingService = cfg
+	})
+	prober := search.NewEmbeddingHealth(cfg, zap.NewNop())
+	svc.SetEmbeddingHealth(prober)
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go prober.Start(ctx)
+
+	waitFor := func(healthy bool) {
+		t.Helper()
+		deadline := time.Now().Add(2 * time.Second)
+		for prober.Healthy() != healthy {
+			if time.Now().After(deadline) {
+				t.Fatalf("prober never reported healthy = %v", healthy)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+	searchOnce := func() {
+		t.Helper()
+		if _, err := svc.Search(context.Background(), &search.SearchRequest{Query: "chat", Pagination: search.PaginationRequest{PageSize: 10}}); err != nil {
+			t.Fatalf("Search: %v", err)
+		}
+	}
+
+	down.Store(true)
+	waitFor(false)
+	if _, err := prober.Check(context.Background()); err == nil {
+		t.Error("Check passed while the embedding service is down")
+	}
+	searchOnce()
+	if n := atomic.LoadInt32(&embeds); n != 0 {
+		t.Errorf("embedding calls while down = %d, want 0", n)
+	}
+
+	down.Store(false)
+	waitFor(true)
+	searchOnce()
+	if n := atomic.LoadInt32(&embeds); n == 0 {
+		t.Error("search skipped the semantic clause after the embedding service recovered")
+	}
+}
+
+func TestQueryEmbeddingsAreCachedByNormalizedQuery(t *testing.T) {
+	server, calls := embeddingServer(t, 0, 0)
+	redis, addr := newFakeRedis(t)
+	es := &fakeElasticsearch{}
+	svc := newSearchServiceOn(t, es, addr, func(c *config.Config) {
+		c.Search.SemanticEnabled = true
+		c.Elasticsearch.VectorDimensions = 1
+		c.EmbeddingService = embeddingConfig(server.URL)
+	})
+
+	// Queries differing in case and spacing share one embedding. The page
+	// sizes differ so the second search isn't answered from the results cache.
+	for i, q := range []string{"Chat  Models", "chat models"} {
+		req := &search.SearchRequest{Query: q, Pagination: search.PaginationRequest{PageSize: 10 + i}}
+		if _, err := svc.Search(context.Background(), req); err != nil {
+			t.Fatalf("Search(%q): %v", q, err)
+		}
+	}
+	if n := atomic.LoadInt32(calls); n != 1 {
+		t.Errorf("embedding calls = %d, want 1", n)
+	}
+
+	es.mu.Lock()
+	for i, body := range es.searches {
+		if !strings.Contains(body, "cosineSimilarity") {
+			t.Errorf("search %d has no semantic clause", i)
+		}
+	}
+	es.mu.Unlock()
+
+	redis.mu.Lock()
+	defer redis.mu.Unlock()
+	var cached []string
+	for key := range redis.strings {
+		if strings.HasPrefix(key, "query_embedding:") {
+			cached = append(cached, key)
+		}
+	}
+	// Keyed by model as well as query
+	if len(cached) != 1 || !strings.Contains(cached[0], ":test-model@1:") {
+		t.Errorf("cached embeddings %v, want one for the model", cached)
+	}
+}
+
+func TestSemanticClauseSkippedPastTheQueryBudget(t *testing.T) {
+	var embeds int32
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&embeds, 1)
+		<-release
+		json.NewEncoder(w).Encode(search.EmbeddingResponse{Embeddings: [][]float32{{1}}})
+	}))
+	t.Cleanup(server.Close)
+	// Cleanups run last first, so the handler is released before Close waits on it
+	t.Cleanup(func() { close(release) })
+
+	redis, addr := newFakeRedis(t)
+	es := &fakeElasticsearch{}
+	svc := newSearchServiceOn(t, es, addr, func(c *config.Config) {
+		c.Search.SemanticEnabled = true
+		c.Elasticsearch.VectorDimensions = 1
+		c.EmbeddingService = embeddingConfig(server.URL)
+		c.EmbeddingService.QueryBudget = 20 * time.Millisecond
+	})
+
+	start := time.Now()
+	if _, err := svc.Search(context.Background(), &search.SearchRequest{Query: "chat", Pagination: search.PaginationRequest{PageSize: 10}}); err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	// The search goes ahead without waiting for the embedding
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("search took %v with a 20ms embedding budget", elapsed)
+	}
+	if atomic.LoadInt32(&embeds) == 0 {
+		t.Fatal("the embedding service was never asked")
+	}
+
+	es.mu.Lock()
+	if len(es.searches) != 1 || strings.Contains(es.searches[0], "cosineSimilarity") {
+		t.Errorf("searches %d, want one without a semantic clause", len(es.searches))
+	}
+	es.mu.Unlock()
+
+	redis.mu.Lock()
+	defer redis.mu.Unlock()
+	for key := range redis.strings {
+		if strings.HasPrefix(key, "query_embedding:") {
+			t.Errorf("cached %s after a timeout", key)
+		}
+	}
+}
+
+// fakeLocalModel embeds each text as a two-element vector holding its length
+type fakeLocalModel struct {
+	calls atomic.Int32
+}
+
+func (m *fakeLocalModel) Embed(texts []string) ([][]float32, error) {
+	m.calls.Add(1)
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vectors[i] = []float32{float32(len(text)), 0}
+	}
+	return vectors, nil
+}
+
+func TestLocalEmbeddingBackend(t *testing.T) {
+	server, calls := embeddingServer(t, 0, 0)
+	cfg := embeddingConfig(server.URL)
+	cfg.Backend = search.EmbeddingBackendLocal
+	client := search.NewEmbeddingClient(cfg, testMetrics())
+
+	// Without an ONNX build there is nothing to load
+	if err := client.LoadLocalModel(); err == nil {
+		t.Fatal("loaded a local model without the onnx build tag")
+	}
+	if _, err := client.GetEmbedding(context.Background(), "test-model", "abc"); err == nil {
+		t.Error("embedded without a local model loaded")
+	}
+
+	local := &fakeLocalModel{}
+	client.SetLocalModel(local)
+	vector, err := client.GetEmbedding(context.Background(), "test-model", "abc")
+	if err != nil || len(vector) != 2 || vector[0] != 3 {
+		t.Errorf("GetEmbedding = %v, %v; want the local vector", vector, err)
+	}
+	// The local model only serves the model it was exported from
+	if _, err := client.GetEmbedding(context.Background(), "other-model", "abc"); err == nil {
+		t.Error("local backend served another model")
+	}
+	if n := atomic.LoadInt32(calls); n != 0 {
+		t.Errorf("remote calls = %d with the local backend primary, want 0", n)
+	}
+}
+
+func TestLocalEmbeddingFallback(t *testing.T) {
+	down, downCalls := embeddingServer(t, 100, http.StatusServiceUnavailable)
+	cfg := embeddingConfig(down.URL)
+	cfg.Fallback = search.EmbeddingBackendLocal
+	local := &fakeLocalModel{}
+	client := search.NewEmbeddingClient(cfg, testMetrics())
+	client.SetLocalModel(local)
+
+	// The remote service is tried first, then the local model stands in
+	vector, err := client.GetEmbedding(context.Background(), "test-model", "abcd")
+	if err != nil || len(vector) != 2 || vector[0] != 4 {
+		t.Errorf("GetEmbedding = %v, %v; want the local vector", vector, err)
+	}
+	if n := atomic.LoadInt32(downCalls); n != int32(cfg.MaxAttempts) {
+		t.Errorf("remote calls = %d, want %d before falling back", n, cfg.MaxAttempts)
+	}
+
+	// A caller that stopped waiting gets no fallback
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	before := local.calls.Load()
+	if _, err := client.GetEmbedding(ctx, "test-model", "abcd"); err == nil {
+		t.Error("GetEmbedding succeeded with a cancelled context")
+	}
+	if local.calls.Load() != before {
+		t.Error("fell back to the local model after the caller gave up")
+	}
+
+	// While the remote service is up the local model isn't used
+	up, _ := embeddingServer(t, 0, 0)
+	cfg.URL = up.URL
+	client = search.NewEmbeddingClient(cfg, testMetrics())
+	client.SetLocalModel(local)
+	before = local.calls.Load()
+	if vector, err := client.GetEmbedding(context.Background(), "test-model", "abcd"); err != nil || len(vector) != 1 {
+		t.Errorf("GetEmbedding = %v, %v; want the remote vector", vector, err)
+	}
+	if local.calls.Load() != before {
+		t.Error("used the local model while the remote service is up")
+	}
+}
+
+// modelServer embeds each text with as many dimensions as the model's
+// version, so model-v2 vectors have two
+func modelServer(t *testing.T) (*httptest.Server, *sync.Map) {
+	var requests sync.Map // Calls by model
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req search.EmbeddingRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		n, _ := requests.LoadOrStore(req.Model, new(atomic.Int32))
+		n.(*atomic.Int32).Add(1)
+
+		dims := map[string]int{"model-v1": 1, "model-v2": 2, "model-bad": 3}[req.Model]
+		resp := search.EmbeddingResponse{Model: req.Model}
+		for range req.Texts {
+			resp.Embeddings = append(resp.Embeddings, make([]float32, dims))
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+var (
+	modelV1 = config.EmbeddingModelConfig{Name: "v1", Model: "model-v1", Dimensions: 1}
+	modelV2 = config.EmbeddingModelConfig{Name: "v2", Model: "model-v2", Dimensions: 2}
+)
+
+func TestEmbeddingsAreDualWrittenDuringMigration(t *testing.T) {
+	server, _ := modelServer(t)
+	newService := func(models ...config.EmbeddingModelConfig) *search.Service {
+		return newSearchService(t, &fakeElasticsearch{}, func(c *config.Config) {
+			c.EmbeddingService = embeddingConfig(server.URL)
+			c.EmbeddingService.Models = models
+		})
+	}
+	docs := []*elasticsearch.ServiceDocument{{ID: "a", Name: "A"}, {ID: "b", Name: "B"}}
+
+	// Before the migration documents carry the old model only
+	before := newService(modelV1)
+	if err := before.EmbedDocuments(context.Background(), docs); err != nil {
+		t.Fatalf("EmbedDocuments: %v", err)
+	}
+	during := newService(modelV1, modelV2)
+	for _, doc := range docs {
+		if len(doc.Embeddings["v1"]) != 1 || len(doc.Embeddings["v2"]) != 0 {
+			t.Errorf("%s: embeddings %v, want v1 only", doc.ID, doc.Embeddings)
+		}
+		if before.NeedsEmbedding(doc) || !during.NeedsEmbedding(doc) {
+			t.Errorf("%s: a document with only v1 needs embedding for v1+v2 alone", doc.ID)
+		}
+	}
+
+	// While both are listed each document gets both, each recorded by model
+	if err := during.EmbedDocuments(context.Background(), docs); err != nil {
+		t.Fatalf("EmbedDocuments: %v", err)
+	}
+	for _, doc := range docs {
+		if len(doc.Embeddings["v1"]) != 1 || len(doc.Embeddings["v2"]) != 2 || during.NeedsEmbedding(doc) {
+			t.Errorf("%s: embeddings %v, want both models", doc.ID, doc.Embeddings)
+		}
+		want := []string{"embeddings.v1:model-v1@1", "embeddings.v2:model-v2@2"}
+		if !slices.Equal(doc.EmbeddingModels, want) {
+			t.Errorf("%s: models %v, want %v", doc.ID, doc.EmbeddingModels, want)
+		}
+	}
+
+	// A model whose vectors don't fit its field leaves them missing, without
+	// holding up the others
+	bad := config.EmbeddingModelConfig{Name: "bad", Model: "model-bad", Dimensions: 4}
+	doc := &elasticsearch.ServiceDocument{ID: "c", Name: "C"}
+	if err := newService(bad, modelV2).EmbedDocuments(context.Background(), []*elasticsearch.ServiceDocument{doc}); !errors.Is(err, search.ErrDimensionMismatch) {
+		t.Errorf("EmbedDocuments = %v, want a dimension mismatch", err)
+	}
+	if len(doc.Embeddings["bad"]) != 0 || len(doc.Embeddings["v2"]) != 2 {
+		t.Errorf("embeddings %v, want v2 only", doc.Embeddings)
+	}
+}
+
+func TestQueryModelSwitchesTheSemanticField(t *testing.T) {
+	server, requests := modelServer(t)
+
+	for i, queryModel := range []string{"", "v1", "v2"} {
+		es := &fakeElasticsearch{}
+		svc := newSearchService(t, es, func(c *config.Config) {
+			c.Search.SemanticEnabled = true
+			c.EmbeddingService = embeddingConfig(server.URL)
+			c.EmbeddingService.Models = []config.EmbeddingModelConfig{modelV1, modelV2}
+			c.EmbeddingService.QueryModel = queryModel
+		})
+		if _, err := svc.Search(context.Background(), &search.SearchRequest{Query: "chat", Pagination: search.PaginationRequest{PageSize: 10 + i}}); err != nil {
+			t.Fatalf("query model %q: Search: %v", queryModel, err)
+		}
+
+		// The first model is queried unless another is named
+		want := "v1"
+		if queryModel != "" {
+			want = queryModel
+		}
+		es.mu.Lock()
+		if len(es.searches) != 1 || !strings.Contains(es.searches[0], `"field":"embeddings.`+want+`"`) {
+			t.Errorf("query model %q: search doesn't score against embeddings.%s", queryModel, want)
+		}
+		es.mu.Unlock()
+	}
+
+	for model, want := range map[string]int32{"model-v1": 2, "model-v2": 1} {
+		n, ok := requests.Load(model)
+		if !ok || n.(*atomic.Int32).Load() != want {
+			t.Errorf("%s embedded queries %v times, want %d", model, n, want)
+		}
+	}
+}
+
+func TestEmbeddingModelsAreValidated(t *testing.T) {
+	for name, models := range map[string]string{
+		"duplicate name":      `[{name: v1, model: a, dimensions: 1}, {name: v1, model: b, dimensions: 2}]`,
+		"unusable field name": `[{name: "V-1", model: a, dimensions: 1}]`,
+		"no dimensions":       `[{name: v1, model: a}]`,
+		"query model unknown": `[{name: v2, model: a, dimensions: 1}]`,
+	} {
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		writeConfig(t, path, `  # query_model: "mpnet"`, "  models: "+models+"\n  query_model: v1")
+		if _, err := config.Load(path); err == nil || !strings.Contains(err.Error(), "embedding_service") {
+			t.Errorf("%s: Load = %v, want an embedding_service error", name, err)
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeConfig(t, path, `  # query_model: "mpnet"`, "  models: [{name: v1, model: a, dimensions: 1}, {name: v2, model: b, dimensions: 2}]\n  query_model: v2")
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if m := cfg.QueryEmbeddingModel(); m.Name != "v2" || len(cfg.EmbeddingModels()) != 2 {
+		t.Errorf("query model %+v of %d", m, len(cfg.EmbeddingModels()))
+	}
+}
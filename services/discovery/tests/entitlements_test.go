@@ -0,0 +1,559 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	goredis "github.com/go-redis/redis/v8"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+
+	"github.com/org/llm-marketplace/services/discovery/internal/analytics"
+	"github.com/org/llm-marketplace/services/discovery/internal/api"
+	"github.com/org/llm-marketplace/services/discovery/internal/config"
+	"github.com/org/llm-marketplace/services/discovery/internal/elasticsearch"
+	"github.com/org/llm-marketplace/services/discovery/internal/entitlement"
+	"github.com/org/llm-marketplace/services/discovery/internal/export"
+	"github.com/org/llm-marketplace/services/discovery/internal/feed"
+	"github.com/org/llm-marketplace/services/discovery/internal/graphql"
+	"github.com/org/llm-marketplace/services/discovery/internal/observability"
+	"github.com/org/llm-marketplace/services/discovery/internal/postgres"
+	"github.com/org/llm-marketplace/services/discovery/internal/quota"
+	"github.com/org/llm-marketplace/services/discovery/internal/recommendation"
+	"github.com/org/llm-marketplace/services/discovery/internal/search"
+	"github.com/org/llm-marketplace/services/discovery/internal/sla"
+	"github.com/org/llm-marketplace/services/discovery/internal/taxonomy"
+	"github.com/org/llm-marketplace/services/discovery/internal/webhook"
+)
+
+// Restricted fixtures; every ID and name carries restrictedMarker so leaks are easy to spot
+const restrictedMarker = "restricted"
+
+var entitlementFixtures = map[string]*elasticsearch.ServiceDocument{
+	"public-svc": {
+		ID:     "public-svc",
+		Name:   "Public translation model",
+		Status: elasticsearch.StatusActive,
+	},
+	"restricted-tenant-svc": {
+		ID:     "restricted-tenant-svc",
+		Name:   "Acme restricted tenant model",
+		Status: elasticsearch.StatusActive,
+		Access: &elasticsearch.AccessInfo{
+			Visibility:  elasticsearch.VisibilityTenant,
+			OwnerTenant: "acme",
+		},
+	},
+	"restricted-private-svc": {
+		ID:     "restricted-private-svc",
+		Name:   "Private restricted model",
+		Status: elasticsearch.StatusActive,
+		Access: &elasticsearch.AccessInfo{
+			Visibility:   elasticsearch.VisibilityPrivate,
+			OwnerTenant:  "acme",
+			AllowedUsers: []string{"user-allowed"},
+		},
+	},
+}
+
+func TestCallerCanView(t *testing.T) {
+	tenant := &elasticsearch.AccessInfo{
+		Visibility:     elasticsearch.VisibilityTenant,
+		OwnerTenant:    "acme",
+		AllowedTenants: []string{"partner"},
+	}
+	private := &elasticsearch.AccessInfo{
+		Visibility:   elasticsearch.VisibilityPrivate,
+		OwnerTenant:  "acme",
+		AllowedUsers: []string{"alice"},
+	}
+
+	cases := []struct {
+		name   string
+		caller entitlement.Caller
+		access *elasticsearch.AccessInfo
+		want   bool
+	}{
+		{"no access info is public", entitlement.Caller{}, nil, true},
+		{"public", entitlement.Caller{}, &elasticsearch.AccessInfo{Visibility: elasticsearch.VisibilityPublic}, true},
+		{"tenant hidden from anonymous", entitlement.Caller{}, tenant, false},
+		{"tenant visible to owner", entitlement.Caller{TenantID: "acme"}, tenant, true},
+		{"tenant visible to allowed tenant", entitlement.Caller{TenantID: "partner"}, tenant, true},
+		{"tenant hidden from other tenant", entitlement.Caller{TenantID: "globex"}, tenant, false},
+		{"private hidden from owner tenant", entitlement.Caller{TenantID: "acme"}, private, false},
+		{"private visible to allowed user", entitlement.Caller{TenantID: "globex", UserID: "alice"}, private, true},
+		{"unknown visibility fails closed", entitlement.Caller{TenantID: "acme"}, &elasticsearch.AccessInfo{Visibility: "internal", OwnerTenant: "acme"}, false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.caller.CanView(tc.access); got != tc.want {
+				t.Errorf("CanView() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+// fakeElasticsearch answers every search and suggestion with every fixture,
+// ignoring the query, so a route that relies on Elasticsearch alone to filter
+// would leak. The fixtures are entitlementFixtures unless docs is set.
+type fakeElasticsearch struct {
+	mu       sync.Mutex
+	searches []string
+	mgets    int // Multi-get requests served
+	docs     map[string]*elasticsearch.ServiceDocument
+	timedOut bool // Searches report that shards timed out
+}
+
+func (f *fakeElasticsearch) fixtures() map[string]*elasticsearch.ServiceDocument {
+	if f.docs != nil {
+		return f.docs
+	}
+	return entitlementFixtures
+}
+
+func (f *fakeElasticsearch) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("X-Elastic-Product", "Elasticsearch")
+	w.Header().Set("Content-Type", "application/json")
+	body, _ := io.ReadAll(r.Body)
+
+	switch {
+	case strings.HasSuffix(r.URL.Path, "/_search") && strings.Contains(string(body), `"suggest":{`):
+		f.mu.Lock()
+		f.searches = append(f.searches, string(body))
+		f.mu.Unlock()
+
+		options := []map[string]interface{}{}
+		for id, doc := range f.fixtures() {
+			options = append(options, map[string]interface{}{"text": doc.Name, "_id": id, "_score": 1.0, "_source": doc})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"suggest": map[string]interface{}{
+				"services": []interface{}{map[string]interface{}{"options": options}},
+			},
+		})
+	case strings.HasSuffix(r.URL.Path, "/_search"):
+		f.mu.Lock()
+		f.searches = append(f.searches, string(body))
+		f.mu.Unlock()
+
+		hits := []map[string]interface{}{}
+		for id, doc := range f.fixtures() {
+			hits = append(hits, map[string]interface{}{"_index": "services", "_id": id, "_score": 1.0, "_source": doc})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"took":      1,
+			"timed_out": f.timedOut,
+			"hits": map[string]interface{}{
+				"total": map[string]interface{}{"value": len(hits), "relation": "eq"},
+				"hits":  hits,
+			},
+			"aggregations": map[string]interface{}{
+				"price_ranges": map[string]interface{}{"buckets": []interface{}{
+					map[string]interface{}{"key": "free", "to": 0.000001, "doc_count": len(hits)},
+				}},
+			},
+		})
+	case strings.HasSuffix(r.URL.Path, "/_mget"):
+		f.mu.Lock()
+		f.mgets++
+		f.mu.Unlock()
+
+		var req struct {
+			IDs []string `json:"ids"`
+		}
+		json.Unmarshal(body, &req)
+		docs := []map[string]interface{}{}
+		for _, id := range req.IDs {
+			doc, ok := f.fixtures()[id]
+			docs = append(docs, map[string]interface{}{"_id": id, "found": ok, "_source": doc})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"docs": docs})
+	case strings.Contains(r.URL.Path, "/_doc/") && r.Method == http.MethodGet:
+		id := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+		doc, ok := f.fixtures()[id]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"found":false}`))
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"_id": id, "found": true, "_source": doc})
+	default:
+		w.Write([]byte(`{}`))
+	}
+}
+
+func newEntitlementRouter(t *testing.T, es *fakeElasticsearch) *gin.Engine {
+	t.Helper()
+	// Redis and Postgres are unreachable; routes that need them fail rather than leak
+	return newAPIRouter(t, es, "127.0.0.1:1", func(*config.Config) {})
+}
+
+// newAPIRouter serves the API over es and the Redis at redisAddr, with the
+// test config as configure leaves it. Postgres is unreachable; an enabled
+// feed keeps its changes in memory.
+func newAPIRouter(t *testing.T, es *fakeElasticsearch, redisAddr string, configure func(*config.Config)) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	server := httptest.NewServer(es)
+	t.Cleanup(server.Close)
+
+	cfg := &config.Config{
+		Elasticsearch: config.ElasticsearchConfig{Addresses: []string{server.URL}, IndexName: "services"},
+		Search: config.SearchConfig{
+			MaxResults:     100,
+			DefaultResults: 20,
+			RankingWeights: config.RankingWeights{Relevance: 1},
+			Autocomplete:   config.AutocompleteConfig{NameWeight: 1},
+			ReportDemotion: config.ReportDemotionConfig{Threshold: 2, Factor: 0.5},
+		},
+		Recommendations: config.RecommendationsConfig{Enabled: true, MaxRecommendations: 10},
+		Export:          config.ExportConfig{MaxRows: 100, AsyncMaxRows: 100, Directory: t.TempDir()},
+		Entitlements:    config.EntitlementsConfig{TenantHeader: "X-Tenant-ID", UserHeader: "X-User-ID"},
+	}
+	configure(cfg)
+
+	esClient, err := elasticsearch.NewClient(cfg.Elasticsearch, nil)
+	if err != nil {
+		t.Fatalf("failed to create elasticsearch client: %v", err)
+	}
+
+	redisClient := goredis.NewClient(&goredis.Options{Addr: redisAddr, MaxRetries: -1})
+	t.Cleanup(func() { redisClient.Close() })
+	db, err := pgxpool.New(context.Background(), "host=127.0.0.1 port=1 sslmode=disable connect_timeout=1")
+	if err != nil {
+		t.Fatalf("failed to create pool: %v", err)
+	}
+	t.Cleanup(db.Close)
+	pgPool := &postgres.Pool{Pool: db}
+
+	logger := zap.NewNop()
+	metrics := testMetrics()
+
+	searchService := search.NewService(esClient, redisClient, pgPool, cfg, logger, metrics)
+	taxonomyManager := taxonomy.NewManager(pgPool, redisClient, cfg, logger)
+	searchService.SetTaxonomy(taxonomyManager)
+	recService := recommendation.NewService(pgPool, redisClient, cfg, logger, metrics)
+	recService.SetVisibility(searchService)
+	var changes *feed.Feed
+	if cfg.Feed.Enabled {
+		changes = feed.NewFeed(newMemoryFeedStore(), esClient, cfg.Feed, logger)
+		searchService.SetChangeNotifier(changes)
+	}
+
+	schema, err := graphql.ParseSchema(searchService, recService)
+	if err != nil {
+		t.Fatalf("failed to parse schema: %v", err)
+	}
+
+	quotas := quota.NewService(redisClient, cfg.Quotas, logger)
+	router := gin.New()
+	router.Use(api.Entitlements(cfg.Entitlements))
+	api.RegisterRoutes(router,
+		searchService,
+		recService,
+		sla.NewMonitor(esClient, redisClient, cfg.SLAMonitoring, logger, metrics),
+		export.NewExporter(searchService, redisClient, cfg.Export, logger),
+		nil,
+		changes,
+		webhook.NewDispatcher(pgPool, cfg.Webhooks, logger, metrics),
+		analytics.NewProducer(cfg.AnalyticsHub, logger, metrics),
+		analytics.NewReporter(pgPool),
+		analytics.NewRelated(pgPool, redisClient, cfg.AnalyticsHub.Related, logger),
+		taxonomyManager,
+		quotas,
+		logger,
+		metrics,
+	)
+	gqlHandler := graphql.NewHandler(schema, esClient, redisClient, cfg, logger)
+	api.RegisterGraphQL(router, gqlHandler.Handle, quotas, logger)
+
+	return router
+}
+
+var (
+	metricsOnce   sync.Once
+	sharedMetrics *observability.Metrics
+)
+
+// testMetrics registers the Prometheus collectors once per test binary
+func testMetrics() *observability.Metrics {
+	metricsOnce.Do(func() { sharedMetrics = observability.InitMetrics() })
+	return sharedMetrics
+}
+
+// entitlementRequestBodies are sent to routes that expect a body; others get {}
+var entitlementRequestBodies = map[string]string{
+	"POST /api/v1/search":             `{"query": "model"}`,
+	"POST /api/v1/search/export":      `{"format": "ndjson"}`,
+	"PUT /api/v1/services/:id/status": `{"status": "deprecated"}`,
+	"POST /graphql": `{"query": "{ service(id: \"restricted-tenant-svc\") { id name } ` +
+		`services(ids: [\"restricted-tenant-svc\", \"restricted-private-svc\"]) { id name } ` +
+		`search(input: {query: \"model\"}) { results { service { id name } } } }"}`,
+}
+
+// restrictedIn returns the restricted fixture IDs and names appearing as
+// values anywhere in a JSON or NDJSON body
+func restrictedIn(body []byte) []string {
+	restricted := map[string]bool{}
+	for id, doc := range entitlementFixtures {
+		if doc.Access != nil {
+			restricted[id], restricted[doc.Name] = true, true
+		}
+	}
+	var found []string
+	for _, value := range jsonStrings(body) {
+		if restricted[value] {
+			found = append(found, value)
+		}
+	}
+	return found
+}
+
+// serviceIDsIn returns the IDs of the fixtures whose ID or name appears as a
+// value in a JSON or NDJSON body
+func serviceIDsIn(body []byte) map[string]bool {
+	ids := map[string]bool{}
+	for _, value := range jsonStrings(body) {
+		for id, doc := range entitlementFixtures {
+			if value == id || value == doc.Name {
+				ids[id] = true
+			}
+		}
+	}
+	return ids
+}
+
+// jsonStrings returns every string value in a JSON or NDJSON body
+func jsonStrings(body []byte) []string {
+	var values []string
+	var walk func(v interface{})
+	walk = func(v interface{}) {
+		switch v := v.(type) {
+		case string:
+			values = append(values, v)
+		case []interface{}:
+			for _, e := range v {
+				walk(e)
+			}
+		case map[string]interface{}:
+			for _, e := range v {
+				walk(e)
+			}
+		}
+	}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	for {
+		var v interface{}
+		if decoder.Decode(&v) != nil {
+			return values
+		}
+		walk(v)
+	}
+}
+
+// seedRecommendationCaches caches every fixture as the recommendations of
+// each of userIDs, so the routes serving them have restricted services to filter
+func seedRecommendationCaches(t *testing.T, redis *fakeRedis, maxResults int, userIDs ...string) {
+	t.Helper()
+	var recs []recommendation.Recommendation
+	for id, doc := range entitlementFixtures {
+		recs = append(recs, recommendation.Recommendation{ServiceID: id, Service: doc, Score: 1})
+	}
+	encode := func(v interface{}) string {
+		data, err := json.Marshal(v)
+		if err != nil {
+			t.Fatalf("failed to encode cached recommendations: %v", err)
+		}
+		return string(data)
+	}
+	response := encode(recommendation.RecommendationResponse{Recommendations: recs, Algorithm: config.AlgorithmHybrid})
+	slate := encode(recommendation.SlateResponse{Placements: []recommendation.Placement{
+		{Name: config.PlacementHomepageHero, Algorithm: config.AlgorithmHybrid, Recommendations: recs},
+	}})
+
+	redis.mu.Lock()
+	defer redis.mu.Unlock()
+	redis.strings[fmt.Sprintf("recommendations:trending:%d", maxResults)] = response
+	redis.strings[fmt.Sprintf("recommendations:anonymous:%d:", maxResults)] = response
+	for _, userID := range userIDs {
+		// Personalized and similar-service recommendations share the user's key
+		redis.strings["recommendations:"+userID] = response
+		redis.strings["recommendations:slate:"+userID+"::"+config.PlacementHomepageHero] = slate
+	}
+}
+
+// newLeakRouter serves the API with recommendation caches holding every
+// fixture, for the callers in leakCallers
+func newLeakRouter(t *testing.T, es *fakeElasticsearch) *gin.Engine {
+	t.Helper()
+	redis, addr := newFakeRedis(t)
+	router := newAPIRouter(t, es, addr, func(c *config.Config) {
+		c.Recommendations.Slate = map[string]config.PlacementConfig{
+			config.PlacementHomepageHero: {Algorithm: config.AlgorithmHybrid, Budget: 10},
+		}
+	})
+	seedRecommendationCaches(t, redis, 10, "", "user-other")
+	return router
+}
+
+// leakCallers are entitled to public-svc alone
+var leakCallers = map[string]http.Header{
+	"anonymous":    {},
+	"other tenant": {"X-Tenant-Id": {"globex"}, "X-User-Id": {"user-other"}},
+}
+
+func TestNoRouteLeaksRestrictedServices(t *testing.T) {
+	es := &fakeElasticsearch{}
+	router := newLeakRouter(t, es)
+
+	for _, route := range router.Routes() {
+		paths := []string{route.Path}
+		if strings.Contains(route.Path, ":id") {
+			paths = nil
+			for id, doc := range entitlementFixtures {
+				if doc.Access != nil {
+					paths = append(paths, strings.ReplaceAll(route.Path, ":id", id))
+				}
+			}
+		}
+
+		body, ok := entitlementRequestBodies[route.Method+" "+route.Path]
+		if !ok {
+			body = `{}`
+		}
+
+		for _, path := range paths {
+			path = strings.ReplaceAll(path, ":name", "nlp")
+			if route.Path == "/api/v1/autocomplete" {
+				path += "?q=model"
+			}
+
+			for name, header := range leakCallers {
+				req := httptest.NewRequest(route.Method, path, bytes.NewBufferString(body))
+				req.Header = header.Clone()
+				req.Header.Set("Content-Type", "application/json")
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, req)
+
+				if leaked := restrictedIn(w.Body.Bytes()); len(leaked) > 0 {
+					t.Errorf("%s %s as %s leaked %v (status %d): %s",
+						route.Method, path, name, leaked, w.Code, w.Body.String())
+				}
+				// Problem responses echo the request path; only the payload matters
+				payload := strings.ReplaceAll(w.Body.String(), req.URL.Path, "")
+				if strings.Contains(strings.ToLower(payload), restrictedMarker) {
+					t.Errorf("%s %s as %s leaked a restricted service (status %d): %s",
+						route.Method, path, name, w.Code, w.Body.String())
+				}
+			}
+		}
+	}
+
+	// The mandatory filter is sent on every search that returns documents
+	es.mu.Lock()
+	defer es.mu.Unlock()
+	for _, body := range es.searches {
+		if strings.Contains(body, `"size":0`) || strings.Contains(body, `"service_key"`) {
+			continue // aggregation-only queries and version lookups, filtered in memory
+		}
+		if strings.Contains(body, `"embedding_models"`) {
+			continue // the embedding backfill's scan, which returns nothing to the caller
+		}
+		if !strings.Contains(body, `"access.visibility"`) {
+			t.Errorf("search sent without the entitlement filter: %s", body)
+		}
+	}
+}
+
+func TestServiceDataRoutesReturnOnlyVisibleServices(t *testing.T) {
+	router := newLeakRouter(t, &fakeElasticsearch{})
+
+	// Each of these answers with a result set that holds public-svc, and
+	// would hold the restricted fixtures too if they weren't filtered out
+	routes := []struct{ method, path, body string }{
+		{http.MethodPost, "/api/v1/search", entitlementRequestBodies["POST /api/v1/search"]},
+		{http.MethodGet, "/api/v1/search?q=model", ""},
+		{http.MethodPost, "/api/v1/search/export", entitlementRequestBodies["POST /api/v1/search/export"]},
+		{http.MethodGet, "/api/v1/autocomplete?q=model", ""},
+		{http.MethodGet, "/api/v1/services/public-svc", ""},
+		{http.MethodGet, "/api/v1/services/by-name/Public%20translation%20model", ""},
+		{http.MethodGet, "/api/v1/services/public-svc/versions", ""},
+		{http.MethodGet, "/api/v1/services/public-svc/dependencies", ""},
+		{http.MethodGet, "/api/v1/services/public-svc/similar", ""},
+		{http.MethodGet, "/api/v1/services/public-svc/health", ""},
+		{http.MethodGet, "/api/v1/recommendations", ""},
+		{http.MethodGet, "/api/v1/recommendations/trending", ""},
+		{http.MethodGet, "/api/v1/recommendations/slate", ""},
+		{http.MethodPost, "/graphql", entitlementRequestBodies["POST /graphql"]},
+	}
+
+	for name, header := range leakCallers {
+		for _, route := range routes {
+			w := apiRequest(router, route.method, route.path, route.body, header)
+			if w.Code != http.StatusOK {
+				t.Errorf("%s %s as %s: status %d: %s", route.method, route.path, name, w.Code, w.Body.String())
+				continue
+			}
+			ids := serviceIDsIn(w.Body.Bytes())
+			if !ids["public-svc"] || len(ids) != 1 {
+				t.Errorf("%s %s as %s returned %v, want public-svc alone", route.method, route.path, name, ids)
+			}
+		}
+
+		// Restricted services are not found, rather than forbidden
+		for id, doc := range entitlementFixtures {
+			if doc.Access == nil {
+				continue
+			}
+			for _, path := range []string{
+				"/api/v1/services/" + id,
+				"/api/v1/services/by-name/" + url.PathEscape(doc.Name),
+				"/api/v1/services/" + id + "/versions",
+				"/api/v1/services/" + id + "/dependencies",
+				"/api/v1/services/" + id + "/health",
+			} {
+				if w := apiRequest(router, http.MethodGet, path, "", header); w.Code != http.StatusNotFound {
+					t.Errorf("GET %s as %s: status %d, want 404", path, name, w.Code)
+				}
+			}
+		}
+	}
+}
+
+func TestEntitledCallersSeeRestrictedServices(t *testing.T) {
+	router := newEntitlementRouter(t, &fakeElasticsearch{})
+
+	cases := []struct {
+		id     string
+		header http.Header
+	}{
+		{"restricted-tenant-svc", http.Header{"X-Tenant-Id": {"acme"}}},
+		{"restricted-private-svc", http.Header{"X-User-Id": {"user-allowed"}}},
+	}
+
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/services/"+tc.id, nil)
+		req.Header = tc.header
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), tc.id) {
+			t.Errorf("GET %s as entitled caller: status %d, body %s", tc.id, w.Code, w.Body.String())
+		}
+		if cc := w.Header().Get("Cache-Control"); strings.Contains(cc, "public") {
+			t.Errorf("GET %s as entitled caller is publicly cacheable: %s", tc.id, cc)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/org/llm-marketplace/services/discovery/internal/api"
+	"github.com/org/llm-marketplace/services/discovery/internal/config"
+)
+
+// newETagRouter serves a JSON payload at /payload, and a 404 at /missing,
+// behind the ETag and compression middleware
+func newETagRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(api.Entitlements(config.EntitlementsConfig{TenantHeader: "X-Tenant-ID", UserHeader: "X-User-ID"}))
+	router.Use(api.Compression(compressionMinSize))
+	router.GET("/payload", api.ETag(), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"description": largeBody})
+	})
+	router.GET("/missing", api.ETag(), func(c *gin.Context) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+	})
+	return router
+}
+
+func getIfNoneMatch(router *gin.Engine, path, ifNoneMatch string, header http.Header) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	if header != nil {
+		req.Header = header.Clone()
+	}
+	if ifNoneMatch != "" {
+		req.Header.Set("If-None-Match", ifNoneMatch)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestETagAnswersMatchingRequestsWithNotModified(t *testing.T) {
+	router := newETagRouter()
+
+	w := getIfNoneMatch(router, "/payload", "", nil)
+	etag := w.Header().Get("ETag")
+	if w.Code != http.StatusOK || !strings.HasPrefix(etag, `W/"`) {
+		t.Fatalf("first request: status %d, ETag %q; want 200 with a weak tag", w.Code, etag)
+	}
+	if cc := w.Header().Get("Cache-Control"); cc != "public, no-cache" {
+		t.Errorf("anonymous Cache-Control = %q", cc)
+	}
+	strong := strings.TrimPrefix(etag, "W/")
+
+	for _, tc := range []struct {
+		name        string
+		ifNoneMatch string
+		want        int
+	}{
+		{"same weak tag", etag, http.StatusNotModified},
+		// If-None-Match uses the weak comparison, so a strong tag with the
+		// same opaque value matches too
+		{"same value, strong", strong, http.StatusNotModified},
+		{"in a list", `"other", ` + etag, http.StatusNotModified},
+		{"any", "*", http.StatusNotModified},
+		{"other weak tag", `W/"0123"`, http.StatusOK},
+		{"other strong tag", `"0123"`, http.StatusOK},
+	} {
+		w := getIfNoneMatch(router, "/payload", tc.ifNoneMatch, nil)
+		if w.Code != tc.want {
+			t.Errorf("%s: status %d, want %d", tc.name, w.Code, tc.want)
+			continue
+		}
+		if tc.want == http.StatusNotModified && w.Body.Len() != 0 {
+			t.Errorf("%s: 304 with a %d byte body", tc.name, w.Body.Len())
+		}
+		if tc.want == http.StatusOK && decodeBody(t, w) == "" {
+			t.Errorf("%s: 200 with no body", tc.name)
+		}
+	}
+}
+
+func TestETagIsTheSameForEveryEncoding(t *testing.T) {
+	router := newETagRouter()
+
+	identity := getIfNoneMatch(router, "/payload", "", nil)
+	gzipped := getIfNoneMatch(router, "/payload", "", http.Header{"Accept-Encoding": {"gzip"}})
+	if gzipped.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("Content-Encoding = %q, want gzip", gzipped.Header().Get("Content-Encoding"))
+	}
+	if identity.Header().Get("ETag") != gzipped.Header().Get("ETag") {
+		t.Errorf("ETag identity %q, gzip %q; want the same", identity.Header().Get("ETag"), gzipped.Header().Get("ETag"))
+	}
+	if decodeBody(t, gzipped) != identity.Body.String() {
+		t.Error("gzip body differs from the identity body")
+	}
+
+	// A tag from an uncompressed response revalidates a compressed one
+	w := getIfNoneMatch(router, "/payload", identity.Header().Get("ETag"), http.Header{"Accept-Encoding": {"gzip"}})
+	if w.Code != http.StatusNotModified || w.Header().Get("Content-Encoding") != "" {
+		t.Errorf("status %d, Content-Encoding %q; want an unencoded 304", w.Code, w.Header().Get("Content-Encoding"))
+	}
+}
+
+func TestETagSkipsErrorsAndMarksIdentifiedResponsesPrivate(t *testing.T) {
+	router := newETagRouter()
+
+	w := getIfNoneMatch(router, "/missing", "*", nil)
+	if w.Code != http.StatusNotFound || w.Header().Get("ETag") != "" {
+		t.Errorf("404: status %d, ETag %q; want an untagged 404", w.Code, w.Header().Get("ETag"))
+	}
+
+	w = getIfNoneMatch(router, "/payload", "", http.Header{"X-Tenant-Id": {"acme"}})
+	if cc := w.Header().Get("Cache-Control"); cc != "private, no-cache" {
+		t.Errorf("tenant Cache-Control = %q, want private", cc)
+	}
+}
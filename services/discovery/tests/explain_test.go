@@ -0,0 +1,88 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/org/llm-marketplace/services/discovery/internal/elasticsearch"
+	"github.com/org/llm-marketplace/services/discovery/internal/search"
+)
+
+func TestExplainMatchesTheSearchRanking(t *testing.T) {
+	cfg := startSandbox(t)
+	ctx := context.Background()
+
+	svc, _ := newSandboxSearch(t, cfg)
+
+	resp, err := svc.Search(ctx, &search.SearchRequest{Query: "code review"})
+	if err != nil {
+		t.Fatalf("search failed: %v", err)
+	}
+	if len(resp.Results) == 0 {
+		t.Fatal("search of the seeded catalog found nothing")
+	}
+	top := resp.Results[0]
+
+	got, err := svc.Explain(ctx, "code review", top.Service.ID)
+	if err != nil {
+		t.Fatalf("Explain: %v", err)
+	}
+	if !got.Matched || got.Elasticsearch == nil {
+		t.Fatalf("top result explained as unmatched: %+v", got)
+	}
+	if math.Abs(got.Score-top.Score) > 1e-9 {
+		t.Errorf("explained score %f, search ranked it %f", got.Score, top.Score)
+	}
+	c := got.Components
+	sum := c.Relevance.Contribution + c.Popularity.Contribution + c.Performance.Contribution + c.Compliance.Contribution
+	if !got.Demoted && math.Abs(sum-got.Score) > 1e-9 {
+		t.Errorf("contributions sum to %f, score is %f", sum, got.Score)
+	}
+	if weights := cfg.RankingWeights(); c.Relevance.Weight != weights.Relevance || c.Compliance.Weight != weights.Compliance {
+		t.Errorf("weights %+v, configured %+v", c, weights)
+	}
+	if c.Popularity.Score != top.MatchDetails.PopularityScore {
+		t.Errorf("popularity %f, search had %f", c.Popularity.Score, top.MatchDetails.PopularityScore)
+	}
+
+	if _, err := svc.Explain(ctx, "code review", "missing"); !errors.Is(err, elasticsearch.ErrNotFound) {
+		t.Errorf("explaining a missing service: %v, want not found", err)
+	}
+	if _, err := svc.Explain(ctx, " ", top.Service.ID); !errors.Is(err, search.ErrInvalidExplain) {
+		t.Errorf("explaining without a query: %v, want invalid", err)
+	}
+}
+
+func TestRelevanceIsScaledByTheBestMatch(t *testing.T) {
+	cfg := startSandbox(t)
+	ctx := context.Background()
+
+	svc, _ := newSandboxSearch(t, cfg)
+
+	relevance := func(page int) (lowest, highest float64) {
+		resp, err := svc.Search(ctx, &search.SearchRequest{Query: "code review", Pagination: search.PaginationRequest{Page: page, PageSize: 5}})
+		if err != nil {
+			t.Fatalf("search of page %d failed: %v", page, err)
+		}
+		if len(resp.Results) == 0 {
+			t.Fatalf("page %d of the seeded catalog is empty", page)
+		}
+		lowest = math.Inf(1)
+		for _, r := range resp.Results {
+			lowest = math.Min(lowest, r.MatchDetails.RelevanceScore)
+			highest = math.Max(highest, r.MatchDetails.RelevanceScore)
+		}
+		return lowest, highest
+	}
+
+	firstLowest, firstHighest := relevance(0)
+	if firstHighest != 1 {
+		t.Errorf("best match has relevance %f, want 1", firstHighest)
+	}
+	// Scaled by the same best score, later pages stay below earlier ones
+	if _, secondHighest := relevance(1); secondHighest > firstLowest {
+		t.Errorf("page 2 relevance reaches %f, above page 1's lowest %f", secondHighest, firstLowest)
+	}
+}
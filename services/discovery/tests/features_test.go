@@ -0,0 +1,202 @@
+package tests
+
+import (
+	"context"
+	"math"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	goredis "github.com/go-redis/redis/v8"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+
+	"github.com/org/llm-marketplace/services/discovery/internal/config"
+	"github.com/org/llm-marketplace/services/discovery/internal/features"
+	"github.com/org/llm-marketplace/services/discovery/internal/postgres"
+	"github.com/org/llm-marketplace/services/discovery/internal/search"
+)
+
+var featuresConfig = config.FeaturesConfig{
+	Enabled:         true,
+	RefreshInterval: time.Hour,
+	Window:          90 * 24 * time.Hour,
+	CacheTTL:        time.Hour,
+	AffinityBoost:   0.2,
+	PriceWeight:     0.2,
+	TrustWeight:     0.1,
+}
+
+func TestComputeUserFeatures(t *testing.T) {
+	f := features.ComputeUser([]features.CategoryUsage{
+		{Category: "text", Interactions: 3, PriceRank: 0.5, Priced: true},
+		{Category: "vision", Interactions: 1},
+	})
+	if f.CategoryAffinity["text"] != 0.75 || f.CategoryAffinity["vision"] != 0.25 {
+		t.Errorf("affinity = %v, want text 0.75 and vision 0.25", f.CategoryAffinity)
+	}
+	// Only the priced category counts towards sensitivity
+	if f.PriceSensitivity != 0.5 {
+		t.Errorf("price sensitivity = %v, want 0.5", f.PriceSensitivity)
+	}
+	if top := f.TopCategories(1); len(top) != 1 || top[0] != "text" {
+		t.Errorf("top categories = %v, want [text]", top)
+	}
+
+	var none *features.UserFeatures
+	if top := none.TopCategories(3); len(top) != 0 {
+		t.Errorf("a user without features has top categories %v", top)
+	}
+}
+
+func TestProviderTrust(t *testing.T) {
+	near := func(got, want float64) bool { return math.Abs(got-want) < 1e-9 }
+	for _, tt := range []struct {
+		provider features.Provider
+		want     float64
+	}{
+		{features.Provider{Verified: true, Rating: 5, Rated: true}, 1},
+		// An unrated provider counts as rated 2.5
+		{features.Provider{ErrorRate: 0.5}, 0.3},
+		{features.Provider{Verified: true, Rating: 4, Rated: true, ErrorRate: 0.1}, 0.4 + 0.32 + 0.18},
+	} {
+		if got := tt.provider.Trust(); !near(got, tt.want) {
+			t.Errorf("trust of %+v = %v, want %v", tt.provider, got, tt.want)
+		}
+	}
+}
+
+func TestFeatureFactor(t *testing.T) {
+	w := features.Weights{AffinityBoost: 0.2, PriceWeight: 0.2, TrustWeight: 0.1}
+	user := &features.UserFeatures{CategoryAffinity: map[string]float64{"text": 0.5}, PriceSensitivity: 1}
+	dear := &features.ServiceFeatures{ProviderTrust: 1, PriceRank: 1}
+
+	near := func(got, want float64) bool { return math.Abs(got-want) < 1e-9 }
+	if got := w.Factor(user, "text", dear); !near(got, 1.1*0.8*1.1) {
+		t.Errorf("factor = %v, want affinity 1.1 x price 0.8 x trust 1.1", got)
+	}
+	// Without the user, only trust counts
+	if got := w.Factor(nil, "text", &features.ServiceFeatures{ProviderTrust: 0}); !near(got, 0.9) {
+		t.Errorf("factor of an untrusted provider = %v, want 0.9", got)
+	}
+	if got := w.Factor(nil, "text", nil); got != 1 {
+		t.Errorf("factor without features = %v, want 1", got)
+	}
+}
+
+// newFeatureStore returns a store over redisAddr. Postgres is unreachable,
+// so only entities cached in Redis have features.
+func newFeatureStore(t *testing.T, redisAddr string, cfg config.FeaturesConfig) *features.Store {
+	t.Helper()
+	db, err := pgxpool.New(context.Background(), "host=127.0.0.1 port=1 sslmode=disable connect_timeout=1")
+	if err != nil {
+		t.Fatalf("failed to create pool: %v", err)
+	}
+	t.Cleanup(db.Close)
+	redisClient := goredis.NewClient(&goredis.Options{Addr: redisAddr, MaxRetries: -1})
+	t.Cleanup(func() { redisClient.Close() })
+	return features.NewStore(&postgres.Pool{Pool: db}, redisClient, cfg, nil, zap.NewNop())
+}
+
+// cacheFeatures caches the hash of an entity's features
+func cacheFeatures(redis *fakeRedis, entity, id string, fields map[string]string) {
+	redis.mu.Lock()
+	defer redis.mu.Unlock()
+	redis.hashes["features:"+entity+":"+id] = fields
+}
+
+func TestFeatureStoreReadsCurrentVersions(t *testing.T) {
+	redis, addr := newFakeRedis(t)
+	store := newFeatureStore(t, addr, featuresConfig)
+	cacheFeatures(redis, features.EntityUser, "u1", map[string]string{
+		"category_affinity:1": `{"translation": 1}`,
+		"price_sensitivity:0": `0.9`, // An older version, ignored
+	})
+	cacheFeatures(redis, features.EntityService, "chat-acme", map[string]string{
+		"provider_trust:1": `0.8`,
+		"price_rank:1":     `0.25`,
+	})
+
+	user, err := store.User(context.Background(), "u1")
+	if err != nil {
+		t.Fatalf("User: %v", err)
+	}
+	if user == nil || user.CategoryAffinity["translation"] != 1 || user.PriceSensitivity != 0 {
+		t.Errorf("user features = %+v, want translation affinity and no price sensitivity", user)
+	}
+
+	services, err := store.Services(context.Background(), []string{"chat-acme"})
+	if err != nil {
+		t.Fatalf("Services: %v", err)
+	}
+	if f := services["chat-acme"]; f == nil || f.ProviderTrust != 0.8 || f.PriceRank != 0.25 {
+		t.Errorf("service features = %+v", f)
+	}
+
+	// Entities not cached are read from Postgres, which is down
+	if _, err := store.User(context.Background(), "u2"); err == nil {
+		t.Error("User succeeded without the cache or Postgres")
+	}
+
+	disabled := newFeatureStore(t, addr, config.FeaturesConfig{})
+	if user, err := disabled.User(context.Background(), "u1"); user != nil || err != nil {
+		t.Errorf("disabled store returned %+v, %v", user, err)
+	}
+}
+
+func TestSearchRanksByStoredFeatures(t *testing.T) {
+	redis, addr := newFakeRedis(t)
+	svc := newSearchServiceOn(t, &fakeElasticsearch{docs: sessionFixtures}, addr, func(*config.Config) {})
+	svc.SetFeatures(newFeatureStore(t, addr, featuresConfig))
+
+	cacheFeatures(redis, features.EntityUser, "u1", map[string]string{"category_affinity:1": `{"translation": 1}`})
+	for _, id := range []string{"chat-acme", "chat-globex", "translate-acme"} {
+		cacheFeatures(redis, features.EntityService, id, map[string]string{"provider_trust:1": `0.5`, "price_rank:1": `0`})
+	}
+	// Globex is trusted more than Acme
+	cacheFeatures(redis, features.EntityService, "chat-globex", map[string]string{"provider_trust:1": `1`, "price_rank:1": `0`})
+
+	ranked := func(userID string) []search.SearchResult {
+		t.Helper()
+		resp, err := svc.Search(context.Background(), &search.SearchRequest{Query: "assistant", UserID: userID, Pagination: search.PaginationRequest{PageSize: 10}})
+		if err != nil {
+			t.Fatalf("Search: %v", err)
+		}
+		return resp.Results
+	}
+	ids := func(results []search.SearchResult) string {
+		var out []string
+		for _, r := range results {
+			out = append(out, r.Service.ID)
+		}
+		return strings.Join(out, ",")
+	}
+
+	// Affinity 1.2 beats trust 1.1
+	results := ranked("u1")
+	if got := ids(results); got != "translate-acme,chat-globex,chat-acme" {
+		t.Errorf("ranked %s for a translation user", got)
+	}
+	if results[0].MatchDetails.FeatureFactor != 1.2 {
+		t.Errorf("feature factor = %v, want 1.2", results[0].MatchDetails.FeatureFactor)
+	}
+
+	// An anonymous search ranks by trust alone
+	if got := ids(ranked("")); !strings.HasPrefix(got, "chat-globex,") {
+		t.Errorf("ranked %s anonymously", got)
+	}
+}
+
+func TestFeaturesConfigValidation(t *testing.T) {
+	for _, tt := range []struct{ old, new, want string }{
+		{"enabled: false\n  refresh_interval: 1h", "enabled: true\n  refresh_interval: 0s", "positive refresh_interval"},
+		{"trust_weight: 0.1", "trust_weight: 1.5", "trust_weight between 0 and 1"},
+	} {
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		writeConfig(t, path, tt.old, tt.new)
+		if _, err := config.Load(path); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: Load error = %v, want %q", tt.new, err, tt.want)
+		}
+	}
+}
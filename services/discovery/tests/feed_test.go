@@ -0,0 +1,293 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	goredis "github.com/go-redis/redis/v8"
+	"go.uber.org/zap"
+
+	"github.com/org/llm-marketplace/services/discovery/internal/config"
+	"github.com/org/llm-marketplace/services/discovery/internal/elasticsearch"
+	"github.com/org/llm-marketplace/services/discovery/internal/entitlement"
+	"github.com/org/llm-marketplace/services/discovery/internal/feed"
+	"github.com/org/llm-marketplace/services/discovery/internal/webhook"
+)
+
+// memoryFeedStore keeps catalog changes in memory. They are stamped a
+// minute old, so they have settled by the time they are read.
+type memoryFeedStore struct {
+	mu            sync.Mutex
+	records       []*feed.Record
+	prunedThrough int64
+	seq           int64
+}
+
+func newMemoryFeedStore() *memoryFeedStore {
+	return &memoryFeedStore{}
+}
+
+func (s *memoryFeedStore) Append(ctx context.Context, rec *feed.Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.seq++
+	rec.Seq, rec.ChangedAt = s.seq, time.Now().Add(-time.Minute)
+	s.records = append(s.records, rec)
+	return nil
+}
+
+func (s *memoryFeedStore) After(ctx context.Context, seq int64, limit int) ([]*feed.Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var records []*feed.Record
+	for _, rec := range s.records {
+		if rec.Seq > seq && len(records) < limit {
+			records = append(records, rec)
+		}
+	}
+	return records, nil
+}
+
+func (s *memoryFeedStore) Bounds(ctx context.Context) (int64, int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.prunedThrough, s.seq, nil
+}
+
+func (s *memoryFeedStore) Prune(ctx context.Context, before time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	kept := s.records[:0]
+	for _, rec := range s.records {
+		if rec.ChangedAt.Before(before) {
+			s.prunedThrough = rec.Seq
+			continue
+		}
+		kept = append(kept, rec)
+	}
+	s.records = kept
+	return nil
+}
+
+// tenantCatalog puts the shared catalog and the caller's own services in
+// every caller's catalog
+type tenantCatalog struct{}
+
+func (tenantCatalog) InTenant(ctx context.Context, doc *elasticsearch.ServiceDocument) bool {
+	return doc.TenantID == "" || doc.TenantID == elasticsearch.TenantFromContext(ctx)
+}
+
+var feedConfig = config.FeedConfig{Enabled: true, Retention: time.Hour, PageSize: 10, MaxPageSize: 100, PruneInterval: time.Hour, PushTimeout: time.Second}
+
+func newTestFeed() (*feed.Feed, *memoryFeedStore) {
+	store := newMemoryFeedStore()
+	return feed.NewFeed(store, tenantCatalog{}, feedConfig, zap.NewNop()), store
+}
+
+func feedChanges(t *testing.T, f *feed.Feed, ctx context.Context, cursor string, fields []string) *feed.Page {
+	t.Helper()
+	page, err := f.Changes(ctx, cursor, 0, fields)
+	if err != nil {
+		t.Fatalf("Changes(%q): %v", cursor, err)
+	}
+	return page
+}
+
+func TestFeedServesChangesAsTheCallerSeesThem(t *testing.T) {
+	f, _ := newTestFeed()
+	ctx := context.Background()
+	partner := entitlement.WithCaller(ctx, entitlement.Caller{TenantID: "partner-co"})
+
+	start := feedChanges(t, f, partner, "", nil)
+	if len(start.Changes) != 0 || start.Cursor != "0" {
+		t.Fatalf("without a cursor got %+v, want no changes at cursor 0", start)
+	}
+
+	shared := &elasticsearch.AccessInfo{Visibility: elasticsearch.VisibilityTenant, OwnerTenant: "acme", AllowedTenants: []string{"partner-co"}}
+	chat := &elasticsearch.ServiceDocument{ID: "chat", Name: "Chat", Category: "chat", Metadata: map[string]interface{}{"cost_center": "42"}}
+	translate := &elasticsearch.ServiceDocument{ID: "translate", Name: "Translate", Access: shared}
+	f.ServiceChanged(ctx, nil, chat)
+	f.ServiceChanged(ctx, nil, translate)
+
+	page := feedChanges(t, f, partner, start.Cursor, nil)
+	if len(page.Changes) != 2 || page.HasMore || page.Cursor != "2" {
+		t.Fatalf("first page = %+v, want 2 changes up to cursor 2", page)
+	}
+	upserted := page.Changes[1].Service.(*elasticsearch.ServiceDocument)
+	if page.Changes[1].Op != feed.OpUpsert || upserted.ID != "translate" || upserted.Access != nil {
+		t.Errorf("second change = %+v with access %+v, want translate upserted without its access rules", page.Changes[1], upserted.Access)
+	}
+	if doc := page.Changes[0].Service.(*elasticsearch.ServiceDocument); doc.Metadata != nil {
+		t.Errorf("chat was sent with metadata %v", doc.Metadata)
+	}
+
+	// Two writes to chat collapse into one; translate is withdrawn from the
+	// partner and an acme service it never saw stays hidden
+	renamed := *chat
+	renamed.Name = "Chat 2"
+	f.ServiceChanged(ctx, chat, &renamed)
+	withdrawn := *translate
+	withdrawn.Access = &elasticsearch.AccessInfo{Visibility: elasticsearch.VisibilityTenant, OwnerTenant: "acme"}
+	f.ServiceChanged(ctx, translate, &withdrawn)
+	f.ServiceChanged(ctx, nil, &elasticsearch.ServiceDocument{ID: "internal", TenantID: "acme"})
+	final := renamed
+	final.Description = "Now with memory"
+	f.ServiceChanged(ctx, &renamed, &final)
+
+	page = feedChanges(t, f, partner, page.Cursor, []string{"name"})
+	if page.Cursor != "6" || len(page.Changes) != 2 {
+		t.Fatalf("second page = %+v, want 2 changes up to cursor 6", page)
+	}
+	if c := page.Changes[0]; c.Op != feed.OpDelete || c.ServiceID != "translate" || c.Service != nil {
+		t.Errorf("first change = %+v, want translate deleted", c)
+	}
+	sparse, _ := page.Changes[1].Service.(map[string]interface{})
+	if c := page.Changes[1]; c.Op != feed.OpUpsert || c.ServiceID != "chat" || sparse["name"] != "Chat 2" || len(sparse) != 2 {
+		t.Errorf("second change = %+v, want chat upserted with only its id and name", c)
+	}
+
+	// Acme sees its own service
+	acme := entitlement.WithCaller(ctx, entitlement.Caller{TenantID: "acme"})
+	page = feedChanges(t, f, acme, "2", nil)
+	if len(page.Changes) != 3 {
+		t.Errorf("acme got %d changes, want 3", len(page.Changes))
+	}
+}
+
+func TestFeedPagesAndExpiredCursors(t *testing.T) {
+	f, store := newTestFeed()
+	ctx := context.Background()
+	for _, id := range []string{"a", "b", "c"} {
+		f.ServiceChanged(ctx, nil, &elasticsearch.ServiceDocument{ID: id})
+	}
+
+	page, err := f.Changes(ctx, "0", 2, nil)
+	if err != nil || len(page.Changes) != 2 || !page.HasMore || page.Cursor != "2" {
+		t.Fatalf("Changes(limit 2) = %+v, %v; want 2 changes and more", page, err)
+	}
+	for _, cursor := range []string{"abc", "-1", "9"} {
+		if _, err := f.Changes(ctx, cursor, 0, nil); !errors.Is(err, feed.ErrInvalidRequest) {
+			t.Errorf("Changes(%q) error = %v, want ErrInvalidRequest", cursor, err)
+		}
+	}
+	if _, err := f.Changes(ctx, "0", 101, nil); !errors.Is(err, feed.ErrInvalidRequest) {
+		t.Errorf("Changes(limit 101) error = %v, want ErrInvalidRequest", err)
+	}
+
+	store.Prune(ctx, time.Now())
+	if _, err := f.Changes(ctx, "2", 0, nil); !errors.Is(err, feed.ErrCursorExpired) {
+		t.Errorf("Changes after pruning error = %v, want ErrCursorExpired", err)
+	}
+	if page := feedChanges(t, f, ctx, "3", nil); len(page.Changes) != 0 || page.Cursor != "3" {
+		t.Errorf("Changes at the latest cursor = %+v, want none", page)
+	}
+}
+
+func TestFeedRoute(t *testing.T) {
+	_, redisAddr := newFakeRedis(t)
+	es := &fakeElasticsearch{docs: map[string]*elasticsearch.ServiceDocument{
+		"chat": {ID: "chat", Name: "Chat", Status: elasticsearch.StatusActive},
+	}}
+	router := newAPIRouter(t, es, redisAddr, func(c *config.Config) { c.Feed = feedConfig })
+
+	w := apiRequest(router, http.MethodGet, "/api/v1/feed/changes", "", nil)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"cursor":"0"`) {
+		t.Fatalf("GET without a cursor = %d %s", w.Code, w.Body)
+	}
+
+	if w := apiRequest(router, http.MethodPut, "/api/v1/services/chat/status", `{"status": "deprecated"}`, nil); w.Code != http.StatusOK {
+		t.Fatalf("PUT status = %d %s", w.Code, w.Body)
+	}
+	w = apiRequest(router, http.MethodGet, "/api/v1/feed/changes?cursor=0&fields=status", "", nil)
+	var page struct {
+		Changes []struct {
+			Op      string                 `json:"op"`
+			Service map[string]interface{} `json:"service"`
+		} `json:"changes"`
+		Cursor string `json:"cursor"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &page)
+	if w.Code != http.StatusOK || len(page.Changes) != 1 || page.Changes[0].Service["status"] != elasticsearch.StatusDeprecated || page.Cursor != "1" {
+		t.Errorf("GET from cursor 0 = %d %s", w.Code, w.Body)
+	}
+
+	for _, query := range []string{"cursor=x", "limit=0&cursor=0", "fields=nope"} {
+		if w := apiRequest(router, http.MethodGet, "/api/v1/feed/changes?"+query, "", nil); w.Code != http.StatusBadRequest {
+			t.Errorf("GET ?%s = %d, want 400", query, w.Code)
+		}
+	}
+}
+
+func TestFeedPushSignsPagesAndAdvancesTheCursor(t *testing.T) {
+	redis, redisAddr := newFakeRedis(t)
+	redisClient := goredis.NewClient(&goredis.Options{Addr: redisAddr})
+	t.Cleanup(func() { redisClient.Close() })
+
+	var mu sync.Mutex
+	var bodies []string
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		var timestamp int64
+		signature := r.Header.Get(webhook.HeaderSignature)
+		if _, err := fmt.Sscanf(signature, "t=%d,", &timestamp); err != nil || signature != webhook.Sign("s3cret", time.Unix(timestamp, 0), body) {
+			t.Errorf("signature %q doesn't match the body", signature)
+		}
+		bodies = append(bodies, string(body))
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+
+	f, _ := newTestFeed()
+	partner := config.FeedPartner{Name: "p1", URL: server.URL, Secret: "s3cret", TenantID: "partner-co", Fields: []string{"name"}, Interval: time.Minute}
+	cfg := feedConfig
+	cfg.Partners = []config.FeedPartner{partner}
+	pusher, err := feed.NewPusher(f, redisClient, cfg, zap.NewNop(), testMetrics())
+	if err != nil {
+		t.Fatalf("NewPusher: %v", err)
+	}
+	cursor := func() string {
+		redis.mu.Lock()
+		defer redis.mu.Unlock()
+		return redis.strings["feed:push:p1:cursor"]
+	}
+	ctx := context.Background()
+
+	// The first push starts from the latest write
+	f.ServiceChanged(ctx, nil, &elasticsearch.ServiceDocument{ID: "before"})
+	pusher.Push(ctx, partner)
+	if len(bodies) != 0 || cursor() != "1" {
+		t.Fatalf("first push sent %v and saved cursor %q, want nothing sent and cursor 1", bodies, cursor())
+	}
+
+	f.ServiceChanged(ctx, nil, &elasticsearch.ServiceDocument{ID: "chat", Name: "Chat"})
+	pusher.Push(ctx, partner)
+	if len(bodies) != 1 || !strings.Contains(bodies[0], `"service":{"id":"chat","name":"Chat"}`) || cursor() != "2" {
+		t.Fatalf("push sent %v and saved cursor %q, want chat and cursor 2", bodies, cursor())
+	}
+
+	// A rejected page is sent again
+	status = http.StatusServiceUnavailable
+	f.ServiceChanged(ctx, nil, &elasticsearch.ServiceDocument{ID: "translate"})
+	pusher.Push(ctx, partner)
+	status = http.StatusOK
+	pusher.Push(ctx, partner)
+	if len(bodies) != 3 || bodies[1] != bodies[2] || cursor() != "3" {
+		t.Errorf("after a rejected push sent %d pages and saved cursor %q, want the page resent and cursor 3", len(bodies), cursor())
+	}
+
+	if _, err := feed.NewPusher(f, redisClient, config.FeedConfig{Partners: []config.FeedPartner{{Name: "p2", Fields: []string{"nope"}}}}, zap.NewNop(), testMetrics()); err == nil {
+		t.Error("NewPusher accepted an unknown field")
+	}
+}
@@ -0,0 +1,81 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/org/llm-marketplace/pkg/marketplace"
+	"github.com/org/llm-marketplace/services/discovery/internal/config"
+	"github.com/org/llm-marketplace/services/discovery/internal/recommendation"
+)
+
+func TestCatalogIndexesFineTuning(t *testing.T) {
+	fake := newFakeCatalog()
+	consumer := newCatalogConsumer(fake)
+
+	err := consumer.Apply(context.Background(), catalogEvent(1, marketplace.StatusActive, func(e *marketplace.CatalogEvent) {
+		e.Service.Kind = marketplace.KindFineTuning
+		e.Service.FineTuning = &marketplace.FineTuningOffering{
+			BaseModels:       []string{"llama-3.1-8b", "mistral-7b"},
+			DataRequirements: &marketplace.FineTuningDataRequirements{Formats: []string{"jsonl"}, MinExamples: 100},
+			TurnaroundHours:  24,
+		}
+	}))
+	if err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	doc := fake.docs["3f1c6a52-8a7e-4b55-9d0e-1f2a3b4c5d6e"]
+	if doc == nil {
+		t.Fatal("fine-tuning offering was not indexed")
+	}
+	if doc.Kind != marketplace.KindFineTuning || doc.FineTuning == nil || doc.FineTuning.TurnaroundHours != 24 || doc.FineTuning.DataRequirements == nil {
+		t.Errorf("document = %+v, want the fine-tuning offering", doc)
+	}
+	if desc := doc.Descriptor(); !desc.IsFineTuning() || !desc.FineTuning.Tunes("mistral-7b") {
+		t.Errorf("descriptor = %+v, want the fine-tuning offering for policy checks", desc)
+	}
+}
+
+func TestSearchFiltersOnBaseModelAndTurnaround(t *testing.T) {
+	es := &fakeElasticsearch{}
+	router := newEntitlementRouter(t, es)
+
+	if w := apiRequest(router, http.MethodGet, "/api/v1/search?q=tuning&kind=fine_tuning&base_models=Llama-3.1-8B,mistral-7b&max_turnaround_hours=48", "", nil); w.Code != http.StatusOK {
+		t.Fatalf("search: status %d, body %s", w.Code, w.Body.String())
+	}
+	body := es.searches[len(es.searches)-1]
+	for _, want := range []string{
+		`{"term":{"kind":"fine_tuning"}}`,
+		`{"terms":{"fine_tuning.base_models":["llama-3.1-8b","mistral-7b"]}}`,
+		`"fine_tuning.turnaround_hours":{`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("search body %s, want %s", body, want)
+		}
+	}
+}
+
+func TestSlateFillsFineTuningPlacement(t *testing.T) {
+	svc := newSlateService(t, true, map[string]config.PlacementConfig{
+		config.PlacementFineTuning: {Algorithm: config.AlgorithmFineTuning, Budget: 4},
+	})
+
+	slate, err := svc.GetSlate(context.Background(), &recommendation.SlateRequest{Placements: []string{config.PlacementFineTuning}})
+	if err != nil {
+		t.Fatalf("GetSlate: %v", err)
+	}
+	// The database is unreachable, so the placement is empty but present
+	if got := placementNames(slate); got != "fine_tuning" {
+		t.Errorf("placements = %s, want fine_tuning", got)
+	}
+
+	cfg, err := config.Load("../config.yaml")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if p := cfg.Recommendations.Slate[config.PlacementFineTuning]; p.Algorithm != config.AlgorithmFineTuning || p.Budget != 4 {
+		t.Errorf("fine_tuning placement = %+v, want the fine_tuning algorithm with a budget of 4", p)
+	}
+}
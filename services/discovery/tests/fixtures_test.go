@@ -0,0 +1,90 @@
+package tests
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/org/llm-marketplace/pkg/marketplace"
+
+	"github.com/org/llm-marketplace/services/discovery/internal/fixtures"
+)
+
+func TestFixturesAreDeterministic(t *testing.T) {
+	opts := fixtures.Options{Seed: 42, Services: 50, Users: 10, Dimensions: 8, EmbeddingModel: "test-model"}
+	a, b := fixtures.Generate(opts), fixtures.Generate(opts)
+	if !reflect.DeepEqual(a, b) {
+		t.Fatal("same seed generated different catalogs")
+	}
+
+	opts.Seed = 43
+	if reflect.DeepEqual(a, fixtures.Generate(opts)) {
+		t.Error("different seeds generated the same catalog")
+	}
+
+	if !reflect.DeepEqual(fixtures.Queries(7, 20), fixtures.Queries(7, 20)) {
+		t.Error("same seed generated different queries")
+	}
+}
+
+func TestFixturesAreValid(t *testing.T) {
+	catalog := fixtures.Generate(fixtures.Options{Seed: 1, Services: 200, Users: 20, Dimensions: 16})
+	if len(catalog.Services) != 200 {
+		t.Fatalf("generated %d services, want 200", len(catalog.Services))
+	}
+
+	ids := map[string]bool{}
+	kinds := map[string]int{}
+	for _, s := range catalog.Services {
+		if err := s.Validate(); err != nil {
+			t.Errorf("%s (%s): %v", s.ID, s.Name, err)
+		}
+		if ids[s.ID] {
+			t.Errorf("duplicate ID %s", s.ID)
+		}
+		ids[s.ID] = true
+		kinds[s.Descriptor().ItemKind()]++
+		if len(s.Embedding) != 16 {
+			t.Errorf("%s has a %d-dimensional embedding", s.ID, len(s.Embedding))
+		}
+	}
+	for _, kind := range []string{marketplace.KindService, marketplace.KindPromptTemplate, marketplace.KindDataset, marketplace.KindFineTuning} {
+		if kinds[kind] == 0 {
+			t.Errorf("no items of kind %s", kind)
+		}
+	}
+
+	if len(catalog.Interactions) == 0 {
+		t.Fatal("no interactions generated")
+	}
+	for _, in := range catalog.Interactions {
+		if !ids[in.ServiceID] {
+			t.Errorf("interaction with unknown service %s", in.ServiceID)
+		}
+		if in.Type == "rate" && (in.Rating < 1 || in.Rating > 5) {
+			t.Errorf("rating %v outside 1 to 5", in.Rating)
+		}
+	}
+}
+
+func TestFixtureEmbeddingsClusterByCategory(t *testing.T) {
+	catalog := fixtures.Generate(fixtures.Options{Seed: 3, Services: 120, Dimensions: 32})
+
+	var within, across float64
+	var nWithin, nAcross int
+	for i, a := range catalog.Services {
+		for _, b := range catalog.Services[i+1:] {
+			var dot float64
+			for d := range a.Embedding {
+				dot += float64(a.Embedding[d]) * float64(b.Embedding[d])
+			}
+			if a.Category == b.Category {
+				within, nWithin = within+dot, nWithin+1
+			} else {
+				across, nAcross = across+dot, nAcross+1
+			}
+		}
+	}
+	if within/float64(nWithin) <= across/float64(nAcross)+0.2 {
+		t.Errorf("mean similarity %.2f within categories, %.2f across", within/float64(nWithin), across/float64(nAcross))
+	}
+}
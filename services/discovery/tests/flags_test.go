@@ -0,0 +1,90 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"go.uber.org/zap"
+
+	"github.com/org/llm-marketplace/pkg/marketplace/flags"
+	"github.com/org/llm-marketplace/services/discovery/internal/config"
+	"github.com/org/llm-marketplace/services/discovery/internal/entitlement"
+	"github.com/org/llm-marketplace/services/discovery/internal/search"
+)
+
+func TestSemanticSearchFlagRollsOutByTenant(t *testing.T) {
+	var embeds int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&embeds, 1)
+		json.NewEncoder(w).Encode(search.EmbeddingResponse{Embeddings: [][]float32{{1}}})
+	}))
+	t.Cleanup(server.Close)
+
+	svc := newSearchService(t, &fakeElasticsearch{}, func(c *config.Config) {
+		c.Search.SemanticEnabled = true
+		c.EmbeddingService = embeddingConfig(server.URL)
+	})
+	e := flags.NewEvaluator(flags.Static{flags.SemanticSearch: {Tenants: map[string]bool{"beta": true}}})
+	if err := e.Refresh(context.Background()); err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+	svc.SetFlags(e)
+
+	searchAs := func(tenant string) int32 {
+		t.Helper()
+		before := atomic.LoadInt32(&embeds)
+		ctx := entitlement.WithCaller(context.Background(), entitlement.Caller{TenantID: tenant})
+		if _, err := svc.Search(ctx, &search.SearchRequest{Query: "chat", Pagination: search.PaginationRequest{PageSize: 10}}); err != nil {
+			t.Fatalf("Search: %v", err)
+		}
+		return atomic.LoadInt32(&embeds) - before
+	}
+	if n := searchAs("acme"); n != 0 {
+		t.Errorf("acme embedded its query %d times with semantic search off", n)
+	}
+	if n := searchAs("beta"); n == 0 {
+		t.Error("beta searched without its semantic clause")
+	}
+}
+
+func TestFeatureFlagsReloadWithConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeConfig(t, path, "", "")
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	watcher := config.NewWatcher(path, cfg, zap.NewNop())
+	e := flags.NewEvaluator(cfg)
+
+	writeConfig(t, path, "  flags: {}", "  flags:\n    discovery.personalization: {percentage: 10}")
+	if err := watcher.Reload(); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	if err := e.Refresh(context.Background()); err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+	if got := e.Flags()[flags.Personalization]; got.Percentage != 10 {
+		t.Errorf("personalization flag = %+v after reload, want a 10%% rollout", got)
+	}
+}
+
+func TestFeatureFlagsConfigValidation(t *testing.T) {
+	for _, tt := range []struct{ old, new, want string }{
+		{`source: "config"`, `source: "consul"`, `unknown feature_flags source "consul"`},
+		{`source: "config"`, `source: "file"`, "requires file"},
+		{"  flags: {}", "  flags:\n    discovery.personalization: {percentage: 150}", "not between 0 and 100"},
+	} {
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		writeConfig(t, path, tt.old, tt.new)
+		if _, err := config.Load(path); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: Load error = %v, want %q", tt.new, err, tt.want)
+		}
+	}
+}
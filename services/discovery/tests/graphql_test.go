@@ -0,0 +1,130 @@
+package tests
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/org/llm-marketplace/services/discovery/internal/config"
+)
+
+// graphqlResponse is a GraphQL response with its data left encoded
+type graphqlResponse struct {
+	Data   json.RawMessage `json:"data"`
+	Errors []struct {
+		Message    string `json:"message"`
+		Extensions struct {
+			Code string `json:"code"`
+		} `json:"extensions"`
+	} `json:"errors"`
+}
+
+func postGraphQL(t *testing.T, router *gin.Engine, body string, header http.Header) (int, *graphqlResponse) {
+	t.Helper()
+	w := apiRequest(router, http.MethodPost, "/graphql", body, header)
+	var resp graphqlResponse
+	if w.Code == http.StatusOK {
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("invalid GraphQL response %s: %v", w.Body.String(), err)
+		}
+	}
+	return w.Code, &resp
+}
+
+func TestGraphQLBatchesServiceLookups(t *testing.T) {
+	es := &fakeElasticsearch{}
+	router := newAPIRouter(t, es, "127.0.0.1:1", func(*config.Config) {})
+
+	query := `{"query": "{ a: service(id: \"public-svc\") { id name } ` +
+		`b: service(id: \"restricted-tenant-svc\") { id } ` +
+		`c: services(ids: [\"public-svc\", \"restricted-private-svc\", \"missing\"]) { id } }"}`
+	code, resp := postGraphQL(t, router, query, http.Header{"X-Tenant-Id": {"acme"}})
+	if code != http.StatusOK || len(resp.Errors) > 0 {
+		t.Fatalf("status %d, errors %+v", code, resp.Errors)
+	}
+
+	var data struct {
+		A *struct{ ID, Name string }
+		B *struct{ ID string }
+		C []*struct{ ID string }
+	}
+	if err := json.Unmarshal(resp.Data, &data); err != nil {
+		t.Fatalf("invalid data %s: %v", resp.Data, err)
+	}
+	if data.A == nil || data.A.Name != entitlementFixtures["public-svc"].Name {
+		t.Errorf("a = %+v", data.A)
+	}
+	// acme sees its tenant's service, but not the private one; unknown IDs are null
+	if data.B == nil || data.B.ID != "restricted-tenant-svc" {
+		t.Errorf("b = %+v", data.B)
+	}
+	if len(data.C) != 3 || data.C[0] == nil || data.C[1] != nil || data.C[2] != nil {
+		t.Errorf("c = %s", resp.Data)
+	}
+
+	es.mu.Lock()
+	defer es.mu.Unlock()
+	if es.mgets != 1 {
+		t.Errorf("%d multi-gets for one query, want 1", es.mgets)
+	}
+}
+
+func TestGraphQLPersistedQueries(t *testing.T) {
+	_, addr := newFakeRedis(t)
+	router := newAPIRouter(t, &fakeElasticsearch{}, addr, func(*config.Config) {})
+
+	query := `{ service(id: "public-svc") { name } }`
+	sum := sha256.Sum256([]byte(query))
+	hash := hex.EncodeToString(sum[:])
+	persisted := func(hash string, withQuery bool) string {
+		body := map[string]interface{}{
+			"extensions": map[string]interface{}{"persistedQuery": map[string]interface{}{"version": 1, "sha256Hash": hash}},
+		}
+		if withQuery {
+			body["query"] = query
+		}
+		data, _ := json.Marshal(body)
+		return string(data)
+	}
+	wantName := fmt.Sprintf(`{"service":{"name":%q}}`, entitlementFixtures["public-svc"].Name)
+
+	// An unknown hash asks the client to send the query
+	code, resp := postGraphQL(t, router, persisted(hash, false), nil)
+	if code != http.StatusOK || len(resp.Errors) != 1 || resp.Errors[0].Extensions.Code != "PERSISTED_QUERY_NOT_FOUND" {
+		t.Fatalf("unknown hash: status %d, errors %+v", code, resp.Errors)
+	}
+
+	// Sending it registers it, and runs it
+	code, resp = postGraphQL(t, router, persisted(hash, true), nil)
+	if code != http.StatusOK || string(resp.Data) != wantName {
+		t.Fatalf("registering: status %d, data %s, errors %+v", code, resp.Data, resp.Errors)
+	}
+
+	// After which the hash alone runs it
+	code, resp = postGraphQL(t, router, persisted(hash, false), nil)
+	if code != http.StatusOK || string(resp.Data) != wantName {
+		t.Errorf("by hash: status %d, data %s, errors %+v", code, resp.Data, resp.Errors)
+	}
+
+	// A query can't be registered under another query's hash
+	other := sha256.Sum256([]byte("{ tags { name } }"))
+	if code, _ := postGraphQL(t, router, persisted(hex.EncodeToString(other[:]), true), nil); code != http.StatusBadRequest {
+		t.Errorf("mismatched hash: status %d, want 400", code)
+	}
+}
+
+func TestGraphQLRecommendationsAreForTheCaller(t *testing.T) {
+	router := newAPIRouter(t, &fakeElasticsearch{}, "127.0.0.1:1", func(*config.Config) {})
+
+	// Another user's recommendations can't be asked for
+	query := `{"query": "{ recommendations(input: {userId: \"someone-else\"}) { algorithm } }"}`
+	code, resp := postGraphQL(t, router, query, nil)
+	if code != http.StatusOK || len(resp.Errors) == 0 || resp.Data != nil && string(resp.Data) != "null" {
+		t.Errorf("status %d, data %s, errors %+v, want a validation error", code, resp.Data, resp.Errors)
+	}
+}
@@ -0,0 +1,63 @@
+package tests
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/org/llm-marketplace/services/discovery/internal/observability"
+)
+
+func TestGRPCCallsPropagateTraceContext(t *testing.T) {
+	recorder, tracer := testSpans()
+
+	// The server records the trace context it receives, as the policy engine
+	// does before starting its own span
+	received := make(chan string, 1)
+	server := grpc.NewServer(grpc.UnaryInterceptor(func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		received <- strings.Join(md.Get("traceparent"), ",")
+		return handler(ctx, req)
+	}))
+	grpc_health_v1.RegisterHealthServer(server, health.NewServer())
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	go server.Serve(ln)
+	t.Cleanup(server.Stop)
+
+	opts := append([]grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}, observability.GRPCDialOptions()...)
+	conn, err := grpc.NewClient(ln.Addr().String(), opts...)
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	ctx, parent := tracer.Start(context.Background(), "search")
+	if _, err := grpc_health_v1.NewHealthClient(conn).Check(ctx, &grpc_health_v1.HealthCheckRequest{}); err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	parent.End()
+
+	if traceparent := <-received; !strings.Contains(traceparent, parent.SpanContext().TraceID().String()) {
+		t.Errorf("traceparent = %q, want trace %s", traceparent, parent.SpanContext().TraceID())
+	}
+	children := childSpans(recorder, parent)
+	if len(children) != 1 || children[0].Name() != "grpc.health.v1.Health/Check" {
+		t.Fatalf("child spans = %v, want one grpc.health.v1.Health/Check", spanNames(children))
+	}
+	if code := spanAttribute(children[0], "rpc.grpc.status_code"); code != "0" {
+		t.Errorf("rpc.grpc.status_code = %q, want 0", code)
+	}
+	if service := spanAttribute(children[0], "rpc.service"); service != "grpc.health.v1.Health" {
+		t.Errorf("rpc.service = %q, want grpc.health.v1.Health", service)
+	}
+}
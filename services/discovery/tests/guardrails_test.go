@@ -0,0 +1,105 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/org/llm-marketplace/services/discovery/internal/config"
+	"github.com/org/llm-marketplace/services/discovery/internal/problem"
+)
+
+var guardrailsConfig = config.GuardrailsConfig{
+	Enabled:        true,
+	MaxFilters:     3,
+	MaxPageSize:    50,
+	MaxOffset:      100,
+	MaxQueryLength: 40,
+	MaxFuzzyTerms:  4,
+	Timeout:        500 * time.Millisecond,
+	PartialResults: true,
+}
+
+// newGuardedRouter serves the API over es with guardrails
+func newGuardedRouter(t *testing.T, es *fakeElasticsearch, guardrails config.GuardrailsConfig) *gin.Engine {
+	t.Helper()
+	_, addr := newFakeRedis(t)
+	return newAPIRouter(t, es, addr, func(c *config.Config) { c.Search.Guardrails = guardrails })
+}
+
+func TestGuardrailsRejectExpensiveSearches(t *testing.T) {
+	es := &fakeElasticsearch{}
+	router := newGuardedRouter(t, es, guardrailsConfig)
+
+	for _, tt := range []struct {
+		name, method, path, body string
+		want                     int
+	}{
+		{"within every limit", http.MethodPost, "/api/v1/search", `{"query": "translation model", "filters": {"tags": ["nlp", "text"]}, "pagination": {"page": 2, "page_size": 50}}`, http.StatusOK},
+		{"short words aren't fuzzy", http.MethodPost, "/api/v1/search", `{"query": "a b c d e f g h chat"}`, http.StatusOK},
+		{"too many filter values", http.MethodPost, "/api/v1/search", `{"query": "model", "filters": {"tags": ["a", "b"], "verified_only": true, "min_rating": 4}}`, http.StatusUnprocessableEntity},
+		{"page too large", http.MethodGet, "/api/v1/search?q=model&page_size=51", "", http.StatusUnprocessableEntity},
+		{"page too deep", http.MethodGet, "/api/v1/search?q=model&page=3&page_size=50", "", http.StatusUnprocessableEntity},
+		{"query too long", http.MethodPost, "/api/v1/search", `{"query": "` + strings.Repeat("x", 41) + `"}`, http.StatusUnprocessableEntity},
+		{"too many fuzzy words", http.MethodPost, "/api/v1/search", `{"query": "fast cheap vision model api"}`, http.StatusUnprocessableEntity},
+		{"pareto searches set their own page", http.MethodPost, "/api/v1/search", `{"pareto": {"category": "text-generation"}, "pagination": {"page_size": 500}}`, http.StatusOK},
+	} {
+		w := apiRequest(router, tt.method, tt.path, tt.body, nil)
+		if w.Code != tt.want {
+			t.Errorf("%s: status %d, want %d: %s", tt.name, w.Code, tt.want, w.Body.String())
+			continue
+		}
+		if tt.want == http.StatusUnprocessableEntity && !strings.Contains(w.Body.String(), problem.QueryTooExpensive.Code) {
+			t.Errorf("%s: body %s, want a %s problem", tt.name, w.Body.String(), problem.QueryTooExpensive.Code)
+		}
+	}
+
+	// Rejected searches never reach Elasticsearch, and the rest carry the timeout
+	es.mu.Lock()
+	defer es.mu.Unlock()
+	for _, body := range es.searches {
+		if strings.Contains(body, "xxxx") || strings.Contains(body, "fast cheap") {
+			t.Errorf("rejected search was sent: %s", body)
+		}
+		if !strings.Contains(body, `"timeout":"500ms"`) {
+			t.Errorf("search sent without the timeout: %s", body)
+		}
+	}
+}
+
+func TestTimedOutSearches(t *testing.T) {
+	es := &fakeElasticsearch{timedOut: true}
+
+	router := newGuardedRouter(t, es, guardrailsConfig)
+	w := apiRequest(router, http.MethodPost, "/api/v1/search", `{"query": "model"}`, nil)
+	var resp struct {
+		Partial bool              `json:"partial"`
+		Results []json.RawMessage `json:"results"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("timed out search: status %d, body %s", w.Code, w.Body.String())
+	}
+	if !resp.Partial || len(resp.Results) == 0 {
+		t.Errorf("timed out search returned partial=%v with %d results, want the partial hits", resp.Partial, len(resp.Results))
+	}
+
+	strict := guardrailsConfig
+	strict.PartialResults = false
+	router = newGuardedRouter(t, es, strict)
+	if w := apiRequest(router, http.MethodPost, "/api/v1/search", `{"query": "model"}`, nil); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("timed out search without partial results: status %d, want 503", w.Code)
+	}
+}
+
+func TestGuardrailsConfigValidation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeConfig(t, path, "max_page_size: 100", "max_page_size: -1")
+	if _, err := config.Load(path); err == nil || !strings.Contains(err.Error(), "guardrails limits and timeout must not be negative") {
+		t.Errorf("Load error = %v", err)
+	}
+}
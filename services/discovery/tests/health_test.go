@@ -0,0 +1,81 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/org/llm-marketplace/services/discovery/internal/config"
+	"github.com/org/llm-marketplace/services/discovery/internal/elasticsearch"
+	"go.uber.org/zap"
+)
+
+// clusterHealthServer reports the cluster status held in status
+func clusterHealthServer(t *testing.T, status *atomic.Value) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Elastic-Product", "Elasticsearch")
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/_cluster/health" {
+			w.Write([]byte(`{"status": "` + status.Load().(string) + `", "number_of_nodes": 3}`))
+			return
+		}
+		w.Write([]byte(`{}`))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// waitForHealth polls the prober until want reports true
+func waitForHealth(t *testing.T, prober *elasticsearch.HealthProber, want func(elasticsearch.Health) bool) elasticsearch.Health {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if h := prober.Health(); want(h) {
+			return h
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("health never reached the expected state, last: %+v", prober.Health())
+	return elasticsearch.Health{}
+}
+
+func TestHealthProberTracksClusterStatus(t *testing.T) {
+	var status atomic.Value
+	status.Store("green")
+	server := clusterHealthServer(t, &status)
+
+	client, err := elasticsearch.NewClient(config.ElasticsearchConfig{Addresses: []string{server.URL}, IndexName: "services"}, nil)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	client.SetMetrics(testMetrics())
+
+	prober := elasticsearch.NewHealthProber(client, config.ESHealthConfig{
+		Interval:         5 * time.Millisecond,
+		FailureThreshold: 2,
+	}, zap.NewNop())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go prober.Start(ctx)
+
+	h := waitForHealth(t, prober, func(h elasticsearch.Health) bool { return h.Status == "green" })
+	if !h.Healthy || h.Nodes != 3 {
+		t.Errorf("health = %+v, want healthy with 3 nodes", h)
+	}
+
+	status.Store("red")
+	h = waitForHealth(t, prober, func(h elasticsearch.Health) bool { return !h.Healthy })
+	if h.ConsecutiveFailures < 2 {
+		t.Errorf("unhealthy after %d failures, want at least 2", h.ConsecutiveFailures)
+	}
+
+	status.Store("yellow")
+	h = waitForHealth(t, prober, func(h elasticsearch.Health) bool { return h.Healthy })
+	if h.Status != "yellow" || h.ConsecutiveFailures != 0 {
+		t.Errorf("health = %+v, want recovered yellow", h)
+	}
+}
@@ -0,0 +1,617 @@
+//go:build integration
+
+package tests
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	goredis "github.com/go-redis/redis/v8"
+	"github.com/testcontainers/testcontainers-go"
+	tcelasticsearch "github.com/testcontainers/testcontainers-go/modules/elasticsearch"
+	tcpostgres "github.com/testcontainers/testcontainers-go/modules/postgres"
+	tcredis "github.com/testcontainers/testcontainers-go/modules/redis"
+	"go.uber.org/zap"
+
+	"github.com/org/llm-marketplace/services/discovery/internal/config"
+	"github.com/org/llm-marketplace/services/discovery/internal/elasticsearch"
+	"github.com/org/llm-marketplace/services/discovery/internal/migrations"
+	"github.com/org/llm-marketplace/services/discovery/internal/postgres"
+	"github.com/org/llm-marketplace/services/discovery/internal/privacy"
+	"github.com/org/llm-marketplace/services/discovery/internal/recommendation"
+	"github.com/org/llm-marketplace/services/discovery/internal/redis"
+	"github.com/org/llm-marketplace/services/discovery/internal/search"
+)
+
+// Images match docker-compose.yml so the suite runs against what the service is deployed with
+const (
+	integrationPostgresImage      = "postgres:16-alpine"
+	integrationRedisImage         = "redis:7-alpine"
+	integrationElasticsearchImage = "docker.elastic.co/elasticsearch/elasticsearch:8.11.1"
+)
+
+// integrationEnv is the discovery service wired to real Postgres, Redis and Elasticsearch
+type integrationEnv struct {
+	cfg           *config.Config
+	pgPool        *postgres.Pool
+	redisClient   *goredis.Client
+	esClient      *elasticsearch.Client
+	searchService *search.Service
+	recService    *recommendation.Service
+}
+
+// integrationService is a fixture written to both the services table and the index
+type integrationService struct {
+	id          string
+	name        string
+	description string
+	category    string
+	tags        []string
+	pricing     string
+	rate        float64
+	rating      float64
+}
+
+var integrationServices = []integrationService{
+	{"11111111-1111-1111-1111-111111111111", "GPT Summarizer", "Summarizes long documents into short abstracts", "text-generation", []string{"summarization", "nlp"}, "per-token", 0.002, 4.8},
+	{"22222222-2222-2222-2222-222222222222", "Doc Condenser", "Condenses reports and articles into bullet summaries", "text-generation", []string{"summarization", "reports"}, "per-token", 0.0005, 4.2},
+	{"33333333-3333-3333-3333-333333333333", "Free Translator", "Translates text between forty languages", "translation", []string{"translation", "nlp"}, "free", 0, 4.5},
+	{"44444444-4444-4444-4444-444444444444", "Image Captioner", "Describes images in natural language", "vision", []string{"captioning", "vision"}, "per-request", 0.01, 3.9},
+	{"55555555-5555-5555-5555-555555555555", "Code Reviewer", "Reviews pull requests and suggests fixes", "code", []string{"code", "review"}, "subscription", 0.05, 4.6},
+}
+
+// Users for collaborative filtering: the target and a neighbour who shares
+// two services and rated a third highly
+const (
+	integrationUser     = "aaaaaaaa-aaaa-aaaa-aaaa-aaaaaaaaaaaa"
+	integrationNeighbor = "bbbbbbbb-bbbb-bbbb-bbbb-bbbbbbbbbbbb"
+)
+
+// TestIntegration runs the search, recommendation and caching paths against
+// containers. Run it with `make integration-test`; it needs Docker.
+func TestIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration tests in short mode")
+	}
+	testcontainers.SkipIfProviderIsNotHealthy(t)
+
+	env := newIntegrationEnv(t)
+	seedIntegrationData(t, env)
+
+	t.Run("MigrationsAreCurrent", env.testMigrationsAreCurrent)
+	t.Run("SearchRanksRelevantServices", env.testSearchRelevance)
+	t.Run("SearchFilters", env.testSearchFilters)
+	t.Run("ServiceByID", env.testServiceByID)
+	t.Run("ContentBasedRecommendations", env.testContentRecommendations)
+	t.Run("CategoryRecommendations", env.testCategoryRecommendations)
+	t.Run("CollaborativeRecommendations", env.testCollaborativeRecommendations)
+	t.Run("RecommendationSlate", env.testRecommendationSlate)
+	t.Run("SearchResultsAreCached", env.testSearchCache)
+	t.Run("PrivacyJob", env.testPrivacyJob)
+}
+
+func newIntegrationEnv(t *testing.T) *integrationEnv {
+	t.Helper()
+	ctx := context.Background()
+
+	pgContainer, err := tcpostgres.Run(ctx, integrationPostgresImage,
+		tcpostgres.WithDatabase("marketplace"),
+		tcpostgres.WithUsername("discovery"),
+		tcpostgres.WithPassword("discovery"),
+		tcpostgres.BasicWaitStrategies(),
+	)
+	testcontainers.CleanupContainer(t, pgContainer)
+	if err != nil {
+		t.Fatalf("failed to start postgres: %v", err)
+	}
+
+	redisContainer, err := tcredis.Run(ctx, integrationRedisImage)
+	testcontainers.CleanupContainer(t, redisContainer)
+	if err != nil {
+		t.Fatalf("failed to start redis: %v", err)
+	}
+
+	esContainer, err := tcelasticsearch.Run(ctx, integrationElasticsearchImage,
+		testcontainers.WithEnv(map[string]string{"xpack.security.enabled": "false"}),
+	)
+	testcontainers.CleanupContainer(t, esContainer)
+	if err != nil {
+		t.Fatalf("failed to start elasticsearch: %v", err)
+	}
+
+	pgHost, err := pgContainer.Host(ctx)
+	if err != nil {
+		t.Fatalf("failed to get postgres host: %v", err)
+	}
+	pgPort, err := pgContainer.MappedPort(ctx, "5432/tcp")
+	if err != nil {
+		t.Fatalf("failed to get postgres port: %v", err)
+	}
+	redisHost, err := redisContainer.Host(ctx)
+	if err != nil {
+		t.Fatalf("failed to get redis host: %v", err)
+	}
+	redisPort, err := redisContainer.MappedPort(ctx, "6379/tcp")
+	if err != nil {
+		t.Fatalf("failed to get redis port: %v", err)
+	}
+
+	cfg := &config.Config{
+		Elasticsearch: config.ElasticsearchConfig{
+			Addresses:        []string{esContainer.Settings.Address},
+			IndexName:        "services",
+			Shards:           1,
+			Replicas:         0,
+			RefreshInterval:  "1s",
+			VectorDimensions: 384,
+			Similarity:       "cosine",
+		},
+		Redis: config.RedisConfig{
+			Address:  fmt.Sprintf("%s:%s", redisHost, redisPort.Port()),
+			PoolSize: 10,
+			CacheTTL: map[string]string{"search_results": "1m", "service_details": "1m"},
+		},
+		Postgres: config.PostgresConfig{
+			Host:            pgHost,
+			Port:            pgPort.Int(),
+			Database:        "marketplace",
+			User:            "discovery",
+			Password:        "discovery",
+			SSLMode:         "disable",
+			MaxConns:        10,
+			MinConns:        2,
+			ConnMaxLifetime: time.Minute,
+		},
+		Search: config.SearchConfig{
+			MaxResults:     100,
+			DefaultResults: 20,
+			RankingWeights: config.RankingWeights{Relevance: 1},
+			Autocomplete:   config.AutocompleteConfig{NameWeight: 1},
+		},
+		Recommendations: config.RecommendationsConfig{
+			Enabled:             true,
+			MaxRecommendations:  10,
+			CollaborativeWeight: 1,
+			ContentWeight:       1,
+			PopularityWeight:    1,
+			MinCommonUsers:      2,
+			TrendingWindow:      24 * time.Hour,
+			Slate: map[string]config.PlacementConfig{
+				config.PlacementHomepageHero:   {Algorithm: config.AlgorithmHybrid, Budget: 2},
+				config.PlacementBecauseYouUsed: {Algorithm: config.AlgorithmContent, Budget: 2},
+				config.PlacementNewArrivals:    {Algorithm: config.AlgorithmNewest, Budget: 5},
+			},
+		},
+	}
+
+	pgPool, err := postgres.NewPool(cfg.Postgres, nil)
+	if err != nil {
+		t.Fatalf("failed to connect to postgres: %v", err)
+	}
+	t.Cleanup(func() { pgPool.Close() })
+
+	migrator, err := migrations.New(pgPool, zap.NewNop())
+	if err != nil {
+		t.Fatalf("failed to load migrations: %v", err)
+	}
+	if _, err := migrator.Up(ctx); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+
+	redisClient, err := redis.NewClient(cfg.Redis, nil)
+	if err != nil {
+		t.Fatalf("failed to connect to redis: %v", err)
+	}
+	t.Cleanup(func() { redisClient.Close() })
+
+	esClient, err := elasticsearch.NewClient(cfg.Elasticsearch, nil)
+	if err != nil {
+		t.Fatalf("failed to connect to elasticsearch: %v", err)
+	}
+
+	logger := zap.NewNop()
+	if err := elasticsearch.NewIndexManager(esClient, cfg.Elasticsearch, logger).CreateIndex(ctx); err != nil {
+		t.Fatalf("failed to create index: %v", err)
+	}
+
+	metrics := testMetrics()
+	searchService := search.NewService(esClient, redisClient, pgPool, cfg, logger, metrics)
+	recService := recommendation.NewService(pgPool, redisClient, cfg, logger, metrics)
+	recService.SetVisibility(searchService)
+
+	return &integrationEnv{
+		cfg:           cfg,
+		pgPool:        pgPool,
+		redisClient:   redisClient,
+		esClient:      esClient,
+		searchService: searchService,
+		recService:    recService,
+	}
+}
+
+func seedIntegrationData(t *testing.T, env *integrationEnv) {
+	t.Helper()
+	ctx := context.Background()
+
+	for i, svc := range integrationServices {
+		_, err := env.pgPool.Exec(ctx, `
+			INSERT INTO services (id, registry_id, name, version, description, provider_id, provider_name,
+				category, tags, capabilities, pricing_model, pricing_rate, avg_rating, status)
+			VALUES ($1, $1, $2, '1.0.0', $3, $1, 'Acme', $4, $5, '[]', $6, $7, $8, 'active')
+		`, svc.id, svc.name, svc.description, svc.category, svc.tags, svc.pricing, svc.rate, svc.rating)
+		if err != nil {
+			t.Fatalf("failed to insert service %s: %v", svc.name, err)
+		}
+
+		now := time.Now().UTC()
+		doc := &elasticsearch.ServiceDocument{
+			ID:          svc.id,
+			Name:        svc.name,
+			Description: svc.description,
+			Category:    svc.category,
+			Tags:        svc.tags,
+			Provider:    elasticsearch.ProviderInfo{ID: svc.id, Name: "Acme", Verified: i%2 == 0},
+			Pricing:     elasticsearch.PricingInfo{Model: svc.pricing, Rate: svc.rate},
+			Status:      "active",
+			Metrics:     elasticsearch.MetricsInfo{Rating: svc.rating},
+			CreatedAt:   now,
+			UpdatedAt:   now,
+		}
+		if err := env.esClient.Index(ctx, doc); err != nil {
+			t.Fatalf("failed to index service %s: %v", svc.name, err)
+		}
+	}
+
+	// The user used the first three services; the neighbour shares two of
+	// them and rated the code reviewer highly
+	interactions := []struct {
+		user, service string
+		rating        float64
+	}{
+		{integrationUser, integrationServices[0].id, 5},
+		{integrationUser, integrationServices[1].id, 4},
+		{integrationUser, integrationServices[2].id, 4},
+		{integrationNeighbor, integrationServices[0].id, 5},
+		{integrationNeighbor, integrationServices[1].id, 5},
+		{integrationNeighbor, integrationServices[4].id, 5},
+	}
+	for _, in := range interactions {
+		_, err := env.pgPool.Exec(ctx, `
+			INSERT INTO user_interactions (user_id, service_id, interaction_type, rating)
+			VALUES ($1, $2, 'rate', $3)
+		`, in.user, in.service, in.rating)
+		if err != nil {
+			t.Fatalf("failed to insert interaction: %v", err)
+		}
+	}
+}
+
+func (env *integrationEnv) testMigrationsAreCurrent(t *testing.T) {
+	ctx := context.Background()
+	migrator, err := migrations.New(env.pgPool, zap.NewNop())
+	if err != nil {
+		t.Fatalf("failed to load migrations: %v", err)
+	}
+
+	if applied, err := migrator.Up(ctx); err != nil || applied != 0 {
+		t.Fatalf("second Up applied %d migrations, err %v; want 0, nil", applied, err)
+	}
+	status, err := migrator.Status(ctx)
+	if err != nil {
+		t.Fatalf("Status: %v", err)
+	}
+	if err := status.Err(); err != nil {
+		t.Fatalf("schema is not current: %v", err)
+	}
+
+	// A migration edited after it was applied is reported as drift
+	if _, err := env.pgPool.Exec(ctx, `UPDATE schema_migrations SET checksum = 'edited' WHERE version = 1`); err != nil {
+		t.Fatalf("failed to edit checksum: %v", err)
+	}
+	t.Cleanup(func() {
+		env.pgPool.Exec(ctx, `UPDATE schema_migrations SET checksum = $1 WHERE version = 1`, status.Applied[0].Checksum)
+	})
+	status, err = migrator.Status(ctx)
+	if err != nil {
+		t.Fatalf("Status: %v", err)
+	}
+	if !errors.Is(status.Err(), migrations.ErrDrift) || len(status.Drift) != 1 {
+		t.Errorf("drift = %v, want the edited migration", status.Drift)
+	}
+	if _, err := migrator.Up(ctx); !errors.Is(err, migrations.ErrDrift) {
+		t.Errorf("Up over drift = %v, want ErrDrift", err)
+	}
+}
+
+func (env *integrationEnv) testSearchRelevance(t *testing.T) {
+	resp, err := env.searchService.Search(context.Background(), &search.SearchRequest{Query: "summarize documents"})
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if len(resp.Results) == 0 {
+		t.Fatal("expected results for a summarization query")
+	}
+	if got := resp.Results[0].Service.ID; got != integrationServices[0].id {
+		t.Errorf("top result = %s, want %s", got, integrationServices[0].name)
+	}
+	for _, r := range resp.Results {
+		if r.Service.Category == "vision" || r.Service.Category == "code" {
+			t.Errorf("unrelated service %s matched a summarization query", r.Service.Name)
+		}
+	}
+}
+
+func (env *integrationEnv) testSearchFilters(t *testing.T) {
+	tests := []struct {
+		name    string
+		filters search.SearchFilters
+		want    []string
+	}{
+		{"category", search.SearchFilters{Categories: []string{"translation"}}, []string{integrationServices[2].id}},
+		{"tag", search.SearchFilters{Tags: []string{"summarization"}}, []string{integrationServices[0].id, integrationServices[1].id}},
+		{"max price", search.SearchFilters{MaxPrice: 0.001}, []string{integrationServices[1].id, integrationServices[2].id}},
+		{"min rating", search.SearchFilters{MinRating: 4.6}, []string{integrationServices[0].id, integrationServices[4].id}},
+		{"pricing model", search.SearchFilters{PricingModels: []string{"subscription"}}, []string{integrationServices[4].id}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := env.searchService.Search(context.Background(), &search.SearchRequest{Filters: tt.filters})
+			if err != nil {
+				t.Fatalf("Search: %v", err)
+			}
+
+			got := map[string]bool{}
+			for _, r := range resp.Results {
+				got[r.Service.ID] = true
+			}
+			if len(got) != len(tt.want) {
+				t.Errorf("got %d results, want %d", len(got), len(tt.want))
+			}
+			for _, id := range tt.want {
+				if !got[id] {
+					t.Errorf("missing %s", id)
+				}
+			}
+			if resp.Total != len(tt.want) {
+				t.Errorf("total = %d, want %d", resp.Total, len(tt.want))
+			}
+		})
+	}
+}
+
+func (env *integrationEnv) testServiceByID(t *testing.T) {
+	ctx := context.Background()
+
+	svc, err := env.searchService.GetServiceByID(ctx, integrationServices[3].id)
+	if err != nil {
+		t.Fatalf("GetServiceByID: %v", err)
+	}
+	if svc.Name != integrationServices[3].name {
+		t.Errorf("name = %q, want %q", svc.Name, integrationServices[3].name)
+	}
+
+	_, err = env.searchService.GetServiceByID(ctx, "99999999-9999-9999-9999-999999999999")
+	if !errors.Is(err, elasticsearch.ErrNotFound) {
+		t.Errorf("err = %v, want ErrNotFound", err)
+	}
+}
+
+func (env *integrationEnv) testContentRecommendations(t *testing.T) {
+	resp, err := env.recService.GetRecommendations(context.Background(), &recommendation.RecommendationRequest{
+		UserID:    "cccccccc-cccc-cccc-cccc-cccccccccccc",
+		ServiceID: integrationServices[0].id,
+	})
+	if err != nil {
+		t.Fatalf("GetRecommendations: %v", err)
+	}
+
+	ids := recommendedIDs(resp)
+	if ids[integrationServices[0].id] {
+		t.Error("the reference service was recommended")
+	}
+	if !ids[integrationServices[1].id] {
+		t.Errorf("recommendations %v do not include the other summarizer", ids)
+	}
+	for _, rec := range resp.Recommendations {
+		if rec.Service == nil || rec.Service.ID != rec.ServiceID || rec.Service.Name == "" {
+			t.Errorf("recommendation %s was not hydrated: %+v", rec.ServiceID, rec.Service)
+		}
+	}
+}
+
+func (env *integrationEnv) testCategoryRecommendations(t *testing.T) {
+	resp, err := env.recService.GetRecommendations(context.Background(), &recommendation.RecommendationRequest{
+		UserID:     "dddddddd-dddd-dddd-dddd-dddddddddddd",
+		Categories: []string{"translation", "vision"},
+	})
+	if err != nil {
+		t.Fatalf("GetRecommendations: %v", err)
+	}
+
+	// The captioner is rated below the 4.0 cut-off
+	ids := recommendedIDs(resp)
+	if !ids[integrationServices[2].id] || ids[integrationServices[3].id] {
+		t.Errorf("recommendations = %v, want only the translator", ids)
+	}
+}
+
+func (env *integrationEnv) testCollaborativeRecommendations(t *testing.T) {
+	resp, err := env.recService.GetRecommendations(context.Background(), &recommendation.RecommendationRequest{
+		UserID: integrationUser,
+	})
+	if err != nil {
+		t.Fatalf("GetRecommendations: %v", err)
+	}
+
+	ids := recommendedIDs(resp)
+	if !ids[integrationServices[4].id] {
+		t.Errorf("recommendations = %v, want the service the neighbour rated", ids)
+	}
+	for _, svc := range integrationServices[:3] {
+		if ids[svc.id] {
+			t.Errorf("recommended %s, which the user already used", svc.name)
+		}
+	}
+}
+
+func (env *integrationEnv) testRecommendationSlate(t *testing.T) {
+	slate, err := env.recService.GetSlate(context.Background(), &recommendation.SlateRequest{UserID: integrationUser})
+	if err != nil {
+		t.Fatalf("GetSlate: %v", err)
+	}
+	if len(slate.Placements) != 3 {
+		t.Fatalf("got %d placements, want 3", len(slate.Placements))
+	}
+
+	hero, because, arrivals := slate.Placements[0], slate.Placements[1], slate.Placements[2]
+	// The user rated the translator last, so it anchors the slate
+	if !recommendedIn(hero, integrationServices[4].id) {
+		t.Errorf("homepage_hero = %v, want the service the neighbour rated", hero.Recommendations)
+	}
+	if because.Anchor != integrationServices[2].id || len(because.Recommendations) == 0 {
+		t.Errorf("because_you_used = %+v, want services like the translator", because)
+	}
+
+	placed := make(map[string]string)
+	for _, p := range slate.Placements {
+		if len(p.Recommendations) > p.Budget {
+			t.Errorf("%s holds %d services over its budget of %d", p.Name, len(p.Recommendations), p.Budget)
+		}
+		for _, rec := range p.Recommendations {
+			if other, ok := placed[rec.ServiceID]; ok {
+				t.Errorf("%s is in both %s and %s", rec.ServiceID, other, p.Name)
+			}
+			placed[rec.ServiceID] = p.Name
+			if rec.Service == nil || rec.Service.ID != rec.ServiceID {
+				t.Errorf("recommendation %s in %s was not hydrated", rec.ServiceID, p.Name)
+			}
+		}
+	}
+	// New arrivals come from the anchor's category
+	if arrivals.Category != "translation" {
+		t.Errorf("new_arrivals category = %q, want the translator's", arrivals.Category)
+	}
+	for _, rec := range arrivals.Recommendations {
+		if rec.Service != nil && rec.Service.Category != "translation" {
+			t.Errorf("new_arrivals has %s from %s", rec.Service.Name, rec.Service.Category)
+		}
+	}
+}
+
+func recommendedIn(p recommendation.Placement, serviceID string) bool {
+	for _, rec := range p.Recommendations {
+		if rec.ServiceID == serviceID {
+			return true
+		}
+	}
+	return false
+}
+
+func (env *integrationEnv) testSearchCache(t *testing.T) {
+	ctx := context.Background()
+	req := &search.SearchRequest{Query: "images", Filters: search.SearchFilters{Categories: []string{"vision"}}}
+
+	first, err := env.searchService.Search(ctx, req)
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if len(first.Results) != 1 {
+		t.Fatalf("got %d results, want the captioner", len(first.Results))
+	}
+
+	keys, err := env.redisClient.Keys(ctx, "search:*").Result()
+	if err != nil {
+		t.Fatalf("failed to list cache keys: %v", err)
+	}
+	if len(keys) == 0 {
+		t.Fatal("search results were not cached")
+	}
+	ttl, err := env.redisClient.TTL(ctx, keys[0]).Result()
+	if err != nil {
+		t.Fatalf("failed to read TTL: %v", err)
+	}
+	if ttl <= 0 || ttl > env.cfg.Redis.GetCacheTTL("search_results") {
+		t.Errorf("ttl = %v, want within the search_results TTL", ttl)
+	}
+
+	// Served from the cache, the result outlives the document
+	if err := env.esClient.Delete(ctx, integrationServices[3].id); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	second, err := env.searchService.Search(ctx, req)
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if len(second.Results) != 1 || second.Results[0].Service.ID != integrationServices[3].id {
+		t.Errorf("results = %d, want the cached captioner", len(second.Results))
+	}
+
+	// A new query misses the cache and sees the deletion
+	fresh, err := env.searchService.Search(ctx, &search.SearchRequest{Filters: search.SearchFilters{Categories: []string{"vision"}}})
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if len(fresh.Results) != 0 {
+		t.Errorf("got %d results after deletion, want none", len(fresh.Results))
+	}
+}
+
+func recommendedIDs(resp *recommendation.RecommendationResponse) map[string]bool {
+	ids := make(map[string]bool, len(resp.Recommendations))
+	for _, rec := range resp.Recommendations {
+		ids[rec.ServiceID] = true
+	}
+	return ids
+}
+
+// testPrivacyJob runs last, since it pseudonymizes the seeded interactions
+func (env *integrationEnv) testPrivacyJob(t *testing.T) {
+	ctx := context.Background()
+	_, err := env.pgPool.Exec(ctx, `
+		INSERT INTO user_interactions (user_id, service_id, interaction_type, timestamp)
+		VALUES ($1, $2, 'view', NOW() - INTERVAL '100 days')
+	`, integrationUser, integrationServices[3].id)
+	if err != nil {
+		t.Fatalf("failed to insert interaction: %v", err)
+	}
+
+	cfg := *env.cfg
+	cfg.Privacy = config.PrivacyConfig{
+		Enabled:      true,
+		BatchSize:    2,
+		Retention:    config.RetentionConfig{UserInteractions: 90 * 24 * time.Hour},
+		Pseudonymize: true,
+		PseudonymKey: "integration-pseudonym-key-0123456789",
+	}
+	result, err := privacy.NewJob(env.pgPool, cfg.Privacy, zap.NewNop()).Run(ctx)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if result.Purged[privacy.TableUserInteractions] != 1 || result.Pseudonymized[privacy.TableUserInteractions] != 6 {
+		t.Errorf("result = %+v, want 1 interaction purged and 6 pseudonymized", result)
+	}
+
+	var raw int
+	if err := env.pgPool.QueryRow(ctx, `SELECT COUNT(*) FROM user_interactions WHERE user_id = $1`, integrationUser).Scan(&raw); err != nil || raw != 0 {
+		t.Errorf("%d interactions still stored under the user's ID, err %v", raw, err)
+	}
+	again, err := privacy.NewJob(env.pgPool, cfg.Privacy, zap.NewNop()).Run(ctx)
+	if err != nil || again.Pseudonymized[privacy.TableUserInteractions] != 0 {
+		t.Errorf("second run = %+v, %v; want nothing left to pseudonymize", again, err)
+	}
+
+	// Recommendations find the user's history under their pseudonym
+	env.redisClient.Del(ctx, "recommendations:"+integrationUser)
+	recService := recommendation.NewService(env.pgPool, env.redisClient, &cfg, zap.NewNop(), testMetrics())
+	resp, err := recService.GetRecommendations(ctx, &recommendation.RecommendationRequest{UserID: integrationUser})
+	if err != nil {
+		t.Fatalf("GetRecommendations: %v", err)
+	}
+	if ids := recommendedIDs(resp); !ids[integrationServices[4].id] {
+		t.Errorf("recommendations = %v, want the service the neighbour rated", ids)
+	}
+}
@@ -0,0 +1,228 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	goredis "github.com/go-redis/redis/v8"
+	"go.uber.org/zap"
+
+	"github.com/org/llm-marketplace/services/discovery/internal/config"
+	"github.com/org/llm-marketplace/services/discovery/internal/elasticsearch"
+	"github.com/org/llm-marketplace/services/discovery/internal/search"
+)
+
+var intentConfig = config.QueryUnderstandingConfig{
+	Enabled:    true,
+	CheapPrice: 0.001,
+	Capabilities: map[string][]string{
+		"summarization":      {"summarize", "summary"},
+		"sentiment-analysis": {"sentiment", "sentiment analysis"},
+	},
+}
+
+// intentFilters maps each match of intent to its filter and value
+func intentFilters(intent *search.QueryIntent) map[string]interface{} {
+	filters := map[string]interface{}{}
+	if intent == nil {
+		return filters
+	}
+	for _, m := range intent.Matches {
+		filters[m.Filter] = m.Value
+	}
+	return filters
+}
+
+func TestParseQuery(t *testing.T) {
+	tests := []struct {
+		query   string
+		filters map[string]interface{}
+		rest    string
+	}{
+		{
+			query: "cheap GDPR compliant summarization under 200ms",
+			filters: map[string]interface{}{
+				"max_price":      0.001,
+				"gdpr_compliant": true,
+				"capabilities":   "summarization",
+				"max_latency_ms": 200,
+			},
+		},
+		{
+			query:   "legal summary service under $0.002 with SOC 2",
+			filters: map[string]interface{}{"capabilities": "summarization", "max_price": 0.002, "certifications": "SOC2"},
+			rest:    "legal",
+		},
+		{
+			query:   "verified HIPAA sentiment analysis within 1.5s for clinical notes",
+			filters: map[string]interface{}{"verified_only": true, "hipaa_compliant": true, "capabilities": "sentiment-analysis", "max_latency_ms": 1500},
+			rest:    "clinical notes",
+		},
+		{query: "llama fine-tuning", filters: map[string]interface{}{}},
+	}
+	for _, tt := range tests {
+		intent := search.ParseQuery(tt.query, intentConfig)
+		if got := intentFilters(intent); !reflect.DeepEqual(got, tt.filters) {
+			t.Errorf("ParseQuery(%q) filters = %v, want %v", tt.query, got, tt.filters)
+		}
+		if intent != nil && intent.Query != tt.rest {
+			t.Errorf("ParseQuery(%q) query = %q, want %q", tt.query, intent.Query, tt.rest)
+		}
+	}
+}
+
+// newSearchService returns a search service backed by es, with configure
+// applied to a minimal config. Redis is unreachable, so every search misses
+// the cache.
+func newSearchService(t *testing.T, es *fakeElasticsearch, configure func(*config.Config)) *search.Service {
+	t.Helper()
+	return newSearchServiceOn(t, es, "127.0.0.1:1", configure)
+}
+
+// newSearchServiceOn is newSearchService with Redis at redisAddr
+func newSearchServiceOn(t testing.TB, es http.Handler, redisAddr string, configure func(*config.Config)) *search.Service {
+	t.Helper()
+	server := httptest.NewServer(es)
+	t.Cleanup(server.Close)
+
+	cfg := &config.Config{
+		Elasticsearch: config.ElasticsearchConfig{Addresses: []string{server.URL}, IndexName: "services"},
+		Search: config.SearchConfig{
+			MaxResults:     100,
+			DefaultResults: 20,
+			RankingWeights: config.RankingWeights{Relevance: 1},
+		},
+	}
+	configure(cfg)
+	esClient, err := elasticsearch.NewClient(cfg.Elasticsearch, nil)
+	if err != nil {
+		t.Fatalf("failed to create elasticsearch client: %v", err)
+	}
+	redisClient := goredis.NewClient(&goredis.Options{Addr: redisAddr, MaxRetries: -1})
+	t.Cleanup(func() { redisClient.Close() })
+
+	return search.NewService(esClient, redisClient, nil, cfg, zap.NewNop(), testMetrics())
+}
+
+// newIntentSearch returns a search service with query understanding on
+func newIntentSearch(t *testing.T, es *fakeElasticsearch, embeddings config.EmbeddingServiceConfig, threshold float64) *search.Service {
+	t.Helper()
+	return newSearchService(t, es, func(cfg *config.Config) {
+		cfg.Search.QueryUnderstanding = intentConfig
+		cfg.Search.QueryUnderstanding.EmbeddingThreshold = threshold
+		cfg.EmbeddingService = embeddings
+	})
+}
+
+func TestSearchAppliesQueryIntent(t *testing.T) {
+	es := &fakeElasticsearch{docs: map[string]*elasticsearch.ServiceDocument{}}
+	svc := newIntentSearch(t, es, config.EmbeddingServiceConfig{}, 0)
+
+	req := &search.SearchRequest{
+		Query:      "cheap GDPR compliant summarization under 200ms",
+		Filters:    search.SearchFilters{MaxPrice: 0.01},
+		Pagination: search.PaginationRequest{PageSize: 10},
+	}
+	resp, err := svc.Search(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+
+	if resp.Intent == nil || resp.Intent.Original != "cheap GDPR compliant summarization under 200ms" {
+		t.Fatalf("intent = %+v, want the original query echoed", resp.Intent)
+	}
+	for _, m := range resp.Intent.Matches {
+		// The request's own price cap wins over "cheap"
+		if want := m.Filter != "max_price"; m.Applied != want {
+			t.Errorf("%s applied = %v, want %v", m.Filter, m.Applied, want)
+		}
+	}
+	if req.Query != "cheap GDPR compliant summarization under 200ms" || req.Filters.GDPRCompliant {
+		t.Errorf("request was changed: %+v", req)
+	}
+
+	body := es.searches[len(es.searches)-1]
+	for _, want := range []string{
+		`{"term":{"capabilities":"summarization"}}`,
+		`{"term":{"compliance.gdpr_compliant":true}}`,
+		`"sla.max_latency_ms":{"gt":0,"lte":200}`,
+		`"pricing.rate":{"lte":0.01}`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("search body lacks %s: %s", want, body)
+		}
+	}
+	if strings.Contains(body, "cheap") {
+		t.Errorf("search body still matches the recognized words: %s", body)
+	}
+}
+
+func TestLiteralSearchSkipsQueryIntent(t *testing.T) {
+	es := &fakeElasticsearch{docs: map[string]*elasticsearch.ServiceDocument{}}
+	svc := newIntentSearch(t, es, config.EmbeddingServiceConfig{}, 0)
+
+	resp, err := svc.Search(context.Background(), &search.SearchRequest{
+		Query:      "cheap summarization",
+		Literal:    true,
+		Pagination: search.PaginationRequest{PageSize: 10},
+	})
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if resp.Intent != nil {
+		t.Errorf("intent = %+v, want none", resp.Intent)
+	}
+	if body := es.searches[len(es.searches)-1]; !strings.Contains(body, "cheap summarization") || strings.Contains(body, `{"term":{"capabilities"`) {
+		t.Errorf("literal search body = %s", body)
+	}
+}
+
+func TestQueryIntentMatchesCapabilitiesByEmbedding(t *testing.T) {
+	vectors := map[string][]float32{
+		"summarization":      {1, 0},
+		"sentiment-analysis": {0, 1},
+		"digest":             {0.9, 0.1},
+	}
+	embeddings := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req search.EmbeddingRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		resp := search.EmbeddingResponse{Model: req.Model}
+		for _, text := range req.Texts {
+			v, ok := vectors[text]
+			if !ok {
+				v = []float32{0, 0}
+			}
+			resp.Embeddings = append(resp.Embeddings, v)
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	t.Cleanup(embeddings.Close)
+
+	es := &fakeElasticsearch{docs: map[string]*elasticsearch.ServiceDocument{}}
+	svc := newIntentSearch(t, es, embeddingConfig(embeddings.URL), 0.8)
+
+	resp, err := svc.Search(context.Background(), &search.SearchRequest{
+		Query:      "news digest under 300ms",
+		Pagination: search.PaginationRequest{PageSize: 10},
+	})
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	var match *search.IntentMatch
+	for i, m := range resp.Intent.Matches {
+		if m.Source == search.IntentEmbedding {
+			match = &resp.Intent.Matches[i]
+		}
+	}
+	if match == nil || match.Text != "digest" || match.Value != "summarization" || match.Similarity < 0.8 {
+		t.Fatalf("embedding match = %+v, want digest read as summarization", match)
+	}
+	if resp.Intent.Query != "news" {
+		t.Errorf("query = %q, want news", resp.Intent.Query)
+	}
+}
@@ -0,0 +1,97 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/org/llm-marketplace/services/discovery/internal/elasticsearch"
+	"github.com/org/llm-marketplace/services/discovery/internal/search"
+)
+
+func TestServiceLifecycle(t *testing.T) {
+	cfg := startSandbox(t)
+	svc, clients := newSandboxSearch(t, cfg)
+	ctx := context.Background()
+
+	seeded, err := clients.es.Search(ctx, map[string]interface{}{
+		"size":  2,
+		"query": map[string]interface{}{"term": map[string]interface{}{"status": elasticsearch.StatusActive}},
+	})
+	if err != nil || len(seeded.Hits.Hits) < 2 {
+		t.Fatalf("failed to read active seeded services: %v", err)
+	}
+	old, replacement := seeded.Hits.Hits[0].Source, seeded.Hits.Hits[1].Source
+
+	// found returns the result for id in a search for its name, if any
+	found := func(id, name string, filters search.SearchFilters) *search.SearchResult {
+		t.Helper()
+		resp, err := svc.Search(ctx, &search.SearchRequest{Query: name, Literal: true, Filters: filters})
+		if err != nil {
+			t.Fatalf("search failed: %v", err)
+		}
+		for i := range resp.Results {
+			if resp.Results[i].Service.ID == id {
+				return &resp.Results[i]
+			}
+		}
+		return nil
+	}
+
+	for _, req := range []search.StatusTransitionRequest{
+		{Status: "archived"},
+		{Status: elasticsearch.StatusDraft},
+		{Status: elasticsearch.StatusDeprecated, ReplacementServiceID: old.ID},
+		{Status: elasticsearch.StatusDeprecated, ReplacementServiceID: "missing"},
+	} {
+		if _, err := svc.TransitionStatus(ctx, old.ID, &req); !errors.Is(err, search.ErrInvalidTransition) {
+			t.Errorf("transition %+v: %v, want invalid", req, err)
+		}
+	}
+
+	deprecated, err := svc.TransitionStatus(ctx, old.ID, &search.StatusTransitionRequest{
+		Status:               elasticsearch.StatusDeprecated,
+		Message:              "Superseded",
+		ReplacementServiceID: replacement.ID,
+	})
+	if err != nil {
+		t.Fatalf("deprecate: %v", err)
+	}
+	if d := deprecated.Deprecation; d == nil || d.ReplacementServiceID != replacement.ID || d.DeprecatedAt.IsZero() {
+		t.Errorf("deprecation = %+v, want the replacement recorded", d)
+	}
+	doc, err := clients.es.Get(ctx, old.ID)
+	if err != nil || doc.Status != elasticsearch.StatusDeprecated || doc.Deprecation == nil || doc.Deprecation.ReplacementServiceID != replacement.ID {
+		t.Fatalf("indexed service = %+v, %v; want it deprecated with its replacement", doc, err)
+	}
+
+	// Deprecated services are still found, with a banner linking the replacement
+	result := found(old.ID, old.Name, search.SearchFilters{})
+	if result == nil {
+		t.Fatal("deprecated service not found by its name")
+	}
+	if b := result.Deprecation; b == nil || b.Message != "Superseded" || b.ReplacementService != replacement.ID || b.ReplacementURL != "/api/v1/services/"+replacement.ID {
+		t.Errorf("banner = %+v", b)
+	}
+
+	if _, err := svc.TransitionStatus(ctx, old.ID, &search.StatusTransitionRequest{Status: elasticsearch.StatusRetired}); err != nil {
+		t.Fatalf("retire: %v", err)
+	}
+	// Retired services are never searchable, even when asked for by status.
+	// The search above was cached, and retiring drops it.
+	if found(old.ID, old.Name, search.SearchFilters{}) != nil {
+		t.Error("retired service returned by search")
+	}
+	if found(old.ID, old.Name, search.SearchFilters{Status: elasticsearch.StatusRetired}) != nil {
+		t.Error("retired service returned by a status filter")
+	}
+	// Retirement is final
+	if _, err := svc.TransitionStatus(ctx, old.ID, &search.StatusTransitionRequest{Status: elasticsearch.StatusActive}); !errors.Is(err, search.ErrInvalidTransition) {
+		t.Errorf("reactivating a retired service: %v, want invalid", err)
+	}
+	// A retired service can't replace another
+	_, err = svc.TransitionStatus(ctx, replacement.ID, &search.StatusTransitionRequest{Status: elasticsearch.StatusDeprecated, ReplacementServiceID: old.ID})
+	if !errors.Is(err, search.ErrInvalidTransition) {
+		t.Errorf("replacing with a retired service: %v, want invalid", err)
+	}
+}
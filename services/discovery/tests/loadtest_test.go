@@ -0,0 +1,130 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/org/llm-marketplace/services/discovery/internal/config"
+	"github.com/org/llm-marketplace/services/discovery/internal/loadtest"
+)
+
+// newLoadTarget serves /fast immediately, /slow after 30ms and /fail with a 500
+func newLoadTarget(t *testing.T) string {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/fast", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{}`))
+	})
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(30 * time.Millisecond)
+		w.Write([]byte(`{}`))
+	})
+	mux.HandleFunc("/fail", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server.URL
+}
+
+func TestLoadRunReportsEndpointsAndErrors(t *testing.T) {
+	report, err := loadtest.Run(context.Background(), loadtest.Options{
+		BaseURL: newLoadTarget(t),
+		Requests: []loadtest.Request{
+			{Name: "fast", Path: "/fast", Weight: 3},
+			{Name: "fail", Path: "/fail"},
+		},
+		Concurrency: 4,
+		Total:       200,
+	})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	if report.Requests != 200 {
+		t.Errorf("requests = %d, want 200", report.Requests)
+	}
+	if fast := report.Endpoints["fast"]; fast.Requests != 150 || fast.Errors != 0 {
+		t.Errorf("fast = %d requests, %d errors; want 150 and 0", fast.Requests, fast.Errors)
+	}
+	if fail := report.Endpoints["fail"]; fail.Requests != 50 || fail.Errors != 50 {
+		t.Errorf("fail = %d requests, %d errors; want 50 and 50", fail.Requests, fail.Errors)
+	}
+	if report.ErrorRate != 0.25 {
+		t.Errorf("error rate = %v, want 0.25", report.ErrorRate)
+	}
+	if report.StatusCodes[http.StatusInternalServerError] != 50 {
+		t.Errorf("status codes = %v, want 50 500s", report.StatusCodes)
+	}
+
+	counted := 0
+	for _, b := range report.Histogram {
+		counted += b.Count
+	}
+	if counted != report.Requests {
+		t.Errorf("histogram holds %d calls, want %d", counted, report.Requests)
+	}
+}
+
+func TestLoadReportCheckUsesPerformanceTargets(t *testing.T) {
+	report, err := loadtest.Run(context.Background(), loadtest.Options{
+		BaseURL:     newLoadTarget(t),
+		Requests:    []loadtest.Request{{Name: "slow", Path: "/slow"}},
+		Concurrency: 5,
+		Total:       20,
+	})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if report.Latency.P95MS < 30 || report.Latency.P99MS < report.Latency.P95MS {
+		t.Errorf("latency = %+v, want p95 of at least 30ms and p99 >= p95", report.Latency)
+	}
+
+	if !report.Check(config.PerformanceConfig{TargetP95LatencyMS: 5000, TargetP99LatencyMS: 5000}) {
+		t.Errorf("violations = %v, want the run to pass loose targets", report.Violations)
+	}
+	if report.Check(config.PerformanceConfig{TargetP95LatencyMS: 10, TargetP99LatencyMS: 10}) {
+		t.Fatal("run passed targets below its latency")
+	}
+	if len(report.Violations) != 2 {
+		t.Errorf("violations = %v, want p95 and p99", report.Violations)
+	}
+
+	var buf bytes.Buffer
+	if err := report.WriteJSON(&buf); err != nil {
+		t.Fatalf("WriteJSON: %v", err)
+	}
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("report is not JSON: %v", err)
+	}
+	for _, key := range []string{"latency", "histogram", "endpoints", "targets", "passed", "violations"} {
+		if _, ok := decoded[key]; !ok {
+			t.Errorf("report is missing %q", key)
+		}
+	}
+}
+
+func TestLoadRunStopsAtDuration(t *testing.T) {
+	start := time.Now()
+	report, err := loadtest.Run(context.Background(), loadtest.Options{
+		BaseURL:     newLoadTarget(t),
+		Requests:    []loadtest.Request{{Name: "slow", Path: "/slow"}},
+		Concurrency: 2,
+		Duration:    200 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("run took %v, want it to stop near 200ms", elapsed)
+	}
+	if report.Requests == 0 || report.Errors != 0 {
+		t.Errorf("report = %d requests, %d errors; want some and none", report.Requests, report.Errors)
+	}
+}
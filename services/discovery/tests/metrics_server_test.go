@@ -0,0 +1,158 @@
+package tests
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/org/llm-marketplace/services/discovery/internal/config"
+	"github.com/org/llm-marketplace/services/discovery/internal/observability"
+)
+
+// metricsShutdownTimeout bounds how long a test waits for a metrics server to
+// stop, generously so a loaded machine doesn't fail it
+const metricsShutdownTimeout = 10 * time.Second
+
+// defaultMuxPaths numbers the handlers tests register on http.DefaultServeMux,
+// which panics when a path is registered twice, as under -count=2
+var defaultMuxPaths atomic.Int64
+
+// serveMetrics starts srv on a free port and returns its address and a channel
+// that receives Serve's result. The server is shut down when the test ends.
+func serveMetrics(t *testing.T, srv *observability.MetricsServer) (string, <-chan error) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	done := make(chan error, 1)
+	go func() { done <- srv.ServeListener(ln) }()
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), metricsShutdownTimeout)
+		defer cancel()
+		srv.Shutdown(ctx)
+	})
+	return ln.Addr().String(), done
+}
+
+func TestMetricsServerUsesOwnMuxAndShutsDown(t *testing.T) {
+	testMetrics()
+	defaultMuxPath := fmt.Sprintf("/metrics-test-default-mux-%d", defaultMuxPaths.Add(1))
+	http.HandleFunc(defaultMuxPath, func(w http.ResponseWriter, r *http.Request) {})
+
+	srv := observability.NewMetricsServer(config.MetricsConfig{Path: "/prom"})
+	addr, done := serveMetrics(t, srv)
+
+	res, err := http.Get("http://" + addr + "/prom")
+	if err != nil {
+		t.Fatalf("scrape failed: %v", err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		t.Errorf("scrape status = %d, want 200", res.StatusCode)
+	}
+
+	res, err = http.Get("http://" + addr + defaultMuxPath)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusNotFound {
+		t.Errorf("default mux handler status = %d, want 404", res.StatusCode)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), metricsShutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Serve returned %v after shutdown, want nil", err)
+		}
+	case <-time.After(metricsShutdownTimeout):
+		t.Fatal("Serve did not return after shutdown")
+	}
+}
+
+func TestMetricsServerServesTLS(t *testing.T) {
+	certFile, keyFile := writeTestCertificate(t)
+	srv := observability.NewMetricsServer(config.MetricsConfig{
+		TLS: config.TLSConfig{CertFile: certFile, KeyFile: keyFile},
+	})
+	addr, _ := serveMetrics(t, srv)
+
+	client := &http.Client{Transport: &http.Transport{
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+	}}
+	res, err := client.Get("https://" + addr + "/metrics")
+	if err != nil {
+		t.Fatalf("scrape failed: %v", err)
+	}
+	defer res.Body.Close()
+	io.Copy(io.Discard, res.Body)
+	if res.StatusCode != http.StatusOK || res.TLS == nil {
+		t.Errorf("status = %d, tls = %v; want 200 over TLS", res.StatusCode, res.TLS != nil)
+	}
+
+	// The TLS listener answers plain HTTP with a 400, not a scrape
+	if plain, err := http.Get("http://" + addr + "/metrics"); err == nil {
+		plain.Body.Close()
+		if plain.StatusCode == http.StatusOK {
+			t.Error("plain HTTP scrape succeeded against a TLS server")
+		}
+	}
+}
+
+// writeTestCertificate writes a self-signed certificate for 127.0.0.1
+func writeTestCertificate(t *testing.T) (string, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "metrics"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("failed to marshal key: %v", err)
+	}
+
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatalf("failed to write certificate: %v", err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatalf("failed to write key: %v", err)
+	}
+	return certFile, keyFile
+}
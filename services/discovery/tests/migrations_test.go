@@ -0,0 +1,66 @@
+package tests
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/org/llm-marketplace/services/discovery/internal/migrations"
+)
+
+func TestMigrationsLoad(t *testing.T) {
+	loaded, err := migrations.Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(loaded) == 0 {
+		t.Fatal("no migrations embedded")
+	}
+
+	var schema strings.Builder
+	for i, m := range loaded {
+		if m.Version != i+1 {
+			t.Errorf("migration %d (%s) has version %d; versions must run 1, 2, 3...", i, m.Name, m.Version)
+		}
+		if len(m.Checksum) != 64 {
+			t.Errorf("migration %d checksum = %q, want a sha256", m.Version, m.Checksum)
+		}
+		schema.WriteString(m.SQL)
+	}
+
+	// The tables and indexes discovery queries must all be created
+	for _, want := range []string{
+		"CREATE TABLE IF NOT EXISTS services",
+		"CREATE TABLE IF NOT EXISTS user_interactions",
+		"CREATE TABLE saved_searches",
+		"ADD COLUMN IF NOT EXISTS registry_revision",
+		"ON user_interactions(user_id)",
+		"ON user_interactions(service_id)",
+		"ON user_interactions(timestamp DESC)",
+	} {
+		if !strings.Contains(schema.String(), want) {
+			t.Errorf("migrations do not contain %q", want)
+		}
+	}
+}
+
+func TestMigrationStatusErr(t *testing.T) {
+	current := &migrations.Status{}
+	if err := current.Err(); err != nil {
+		t.Errorf("Err() = %v for a current schema", err)
+	}
+
+	behind := &migrations.Status{
+		Pending: []migrations.Migration{{Version: 2, Name: "saved_searches"}},
+		Drift:   []string{"migration 1 (initial_schema) was changed after it was applied"},
+	}
+	err := behind.Err()
+	if !errors.Is(err, migrations.ErrDrift) {
+		t.Fatalf("Err() = %v, want ErrDrift", err)
+	}
+	for _, want := range []string{"migration 1 (initial_schema) was changed", "migration 2 (saved_searches) is not applied"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Err() = %q, want it to mention %q", err, want)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package tests
+
+import (
+	"context"
+	"math"
+	"net"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+
+	pb "github.com/org/llm-marketplace/services/discovery/api/proto/policyengine/v1"
+	"github.com/org/llm-marketplace/services/discovery/internal/config"
+	"github.com/org/llm-marketplace/services/discovery/internal/elasticsearch"
+	"github.com/org/llm-marketplace/services/discovery/internal/mockserver"
+	"github.com/org/llm-marketplace/services/discovery/internal/policy"
+	"github.com/org/llm-marketplace/services/discovery/internal/search"
+)
+
+func TestMockEmbeddingsServesTheEmbeddingContract(t *testing.T) {
+	server := httptest.NewServer(mockserver.NewEmbeddings(mockserver.EmbeddingOptions{
+		Dimensions:      8,
+		ModelDimensions: map[string]int{"large": 16},
+	}))
+	t.Cleanup(server.Close)
+	client := search.NewEmbeddingClient(embeddingConfig(server.URL), testMetrics())
+	ctx := context.Background()
+
+	vectors, err := client.GetEmbeddings(ctx, "test-model", []string{"code review", "Code-Review", "image generation"})
+	if err != nil {
+		t.Fatalf("GetEmbeddings: %v", err)
+	}
+	if len(vectors) != 3 || len(vectors[0]) != 8 {
+		t.Fatalf("got %d vectors of %d dimensions, want 3 of 8", len(vectors), len(vectors[0]))
+	}
+	if !reflect.DeepEqual(vectors[0], vectors[1]) {
+		t.Error("texts with the same words got different vectors")
+	}
+	var norm float64
+	for _, v := range vectors[2] {
+		norm += float64(v) * float64(v)
+	}
+	if math.Abs(norm-1) > 1e-5 {
+		t.Errorf("vector norm² %f, want 1", norm)
+	}
+
+	large, err := client.GetEmbedding(ctx, "large", "code review")
+	if err != nil {
+		t.Fatalf("GetEmbedding: %v", err)
+	}
+	if len(large) != 16 {
+		t.Errorf("large model vector has %d dimensions, want 16", len(large))
+	}
+}
+
+func TestMockEmbeddingsInjectsFailuresAndLatency(t *testing.T) {
+	failing := httptest.NewServer(mockserver.NewEmbeddings(mockserver.EmbeddingOptions{
+		Behavior:   mockserver.Behavior{FailureRate: 1},
+		Dimensions: 8,
+	}))
+	t.Cleanup(failing.Close)
+	if _, err := search.NewEmbeddingClient(embeddingConfig(failing.URL), testMetrics()).
+		GetEmbedding(context.Background(), "test-model", "code review"); err == nil {
+		t.Error("call succeeded against a mock failing every request")
+	}
+
+	slow := httptest.NewServer(mockserver.NewEmbeddings(mockserver.EmbeddingOptions{
+		Behavior:   mockserver.Behavior{Latency: 50 * time.Millisecond},
+		Dimensions: 8,
+	}))
+	t.Cleanup(slow.Close)
+	cfg := embeddingConfig(slow.URL)
+	cfg.Timeout, cfg.MaxAttempts = 10*time.Millisecond, 1
+	if _, err := search.NewEmbeddingClient(cfg, testMetrics()).
+		GetEmbedding(context.Background(), "test-model", "code review"); err == nil {
+		t.Error("call outlasting its timeout succeeded")
+	}
+}
+
+func TestMockPolicyServesCannedValidations(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "responses.json")
+	responses := `{
+		"*": {"compliant": true, "policy_version": "v3"},
+		"Translator": {"compliant": false, "violations": [{"policy_id": "min-sla", "severity": "high"}]}
+	}`
+	if err := os.WriteFile(path, []byte(responses), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	canned, err := mockserver.LoadPolicyResponses(path)
+	if err != nil {
+		t.Fatalf("LoadPolicyResponses: %v", err)
+	}
+
+	server := grpc.NewServer()
+	pb.RegisterPolicyEngineServiceServer(server, mockserver.NewPolicy(mockserver.PolicyOptions{Responses: canned}))
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	go server.Serve(ln)
+	t.Cleanup(server.Stop)
+
+	conn, err := policy.Dial(config.PolicyEngineConfig{GRPCEndpoint: ln.Addr().String()})
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	client := policy.NewClient(conn, time.Second)
+	ctx := context.Background()
+
+	failing := &elasticsearch.ServiceDocument{ID: "svc-1", Name: "Translator"}
+	got, err := client.ValidateService(ctx, failing.Descriptor())
+	if err != nil {
+		t.Fatalf("ValidateService: %v", err)
+	}
+	if got.Compliant || !reflect.DeepEqual(got.FailingPolicies, []string{"min-sla"}) || got.PolicyVersion != "mock" {
+		t.Errorf("got %+v, want min-sla failing at the mock version", got)
+	}
+
+	other := &elasticsearch.ServiceDocument{ID: "svc-2", Name: "Summarizer"}
+	got, err = client.ValidateService(ctx, other.Descriptor())
+	if err != nil {
+		t.Fatalf("ValidateService: %v", err)
+	}
+	if !got.Compliant || got.PolicyVersion != "v3" {
+		t.Errorf("got %+v, want the default compliant at v3", got)
+	}
+}
@@ -0,0 +1,7 @@
+//go:build !race
+
+package tests
+
+// raceEnabled reports whether the tests run under the race detector, which
+// slows them several times over
+const raceEnabled = false
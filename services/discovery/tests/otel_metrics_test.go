@@ -0,0 +1,122 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	dto "github.com/prometheus/client_model/go"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.uber.org/zap"
+
+	"github.com/org/llm-marketplace/services/discovery/internal/config"
+	"github.com/org/llm-marketplace/services/discovery/internal/observability"
+)
+
+func TestOTelMetricsCarryTraceExemplars(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	shutdown, err := observability.InitOTelMetrics(config.OTelMetricsConfig{
+		Enabled:    true,
+		Prometheus: true,
+	}, resource.Default(), reg, zap.NewNop())
+	if err != nil {
+		t.Fatalf("InitOTelMetrics: %v", err)
+	}
+	defer shutdown()
+
+	tp := sdktrace.NewTracerProvider()
+	defer tp.Shutdown(context.Background())
+	ctx, span := tp.Tracer("test").Start(context.Background(), "search")
+	traceID := span.SpanContext().TraceID().String()
+
+	m := testMetrics()
+	m.SearchDuration(ctx, 20*time.Millisecond)
+	m.CacheHit(ctx)
+	m.CacheMiss(ctx)
+	m.DependencyCall(ctx, "elasticsearch", "search", 5*time.Millisecond, nil)
+	m.DependencyCall(ctx, "embedding_service", "embed", 50*time.Millisecond, errors.New("unavailable"))
+	span.End()
+
+	// Names keep their dots in the registry and are escaped for scrapers
+	// that don't negotiate UTF-8; exemplars need OpenMetrics
+	scrape := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	req.Header.Set("Accept", "application/openmetrics-text; version=1.0.0")
+	promhttp.HandlerFor(reg, promhttp.HandlerOpts{EnableOpenMetrics: true}).ServeHTTP(scrape, req)
+	body := scrape.Body.String()
+	if !strings.Contains(body, "discovery_search_latency_seconds_bucket{") {
+		t.Errorf("scrape has no discovery_search_latency_seconds histogram:\n%s", body)
+	}
+	if !strings.Contains(body, `trace_id="`+traceID+`"`) {
+		t.Errorf("scrape has no exemplar for trace %s", traceID)
+	}
+
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("Gather: %v", err)
+	}
+	byName := make(map[string]*dto.MetricFamily)
+	for _, f := range families {
+		byName[f.GetName()] = f
+	}
+
+	latency := byName["discovery.search.latency_seconds"]
+	if latency == nil {
+		t.Fatal("discovery.search.latency_seconds not exported")
+	}
+	if !hasExemplar(latency.GetMetric()[0].GetHistogram(), traceID) {
+		t.Errorf("search latency has no exemplar for trace %s", traceID)
+	}
+
+	deps := byName["discovery.dependency.latency_seconds"]
+	if deps == nil {
+		t.Fatal("discovery.dependency.latency_seconds not exported")
+	}
+	outcomes := make(map[string]string)
+	for _, metric := range deps.GetMetric() {
+		labels := labelMap(metric)
+		outcomes[labels["dependency"]] = labels["outcome"]
+	}
+	if outcomes["elasticsearch"] != "success" || outcomes["embedding_service"] != "error" {
+		t.Errorf("dependency outcomes = %v", outcomes)
+	}
+
+	lookups := byName["discovery.cache.lookups_total"]
+	if lookups == nil || len(lookups.GetMetric()) != 2 {
+		t.Fatalf("cache lookups = %v, want hit and miss series", lookups)
+	}
+
+	ratio := byName["discovery.cache.hit_ratio"]
+	if ratio == nil {
+		t.Fatal("discovery.cache.hit_ratio not exported")
+	}
+	if v := ratio.GetMetric()[0].GetGauge().GetValue(); v <= 0 || v >= 1 {
+		t.Errorf("cache hit ratio = %v, want between 0 and 1", v)
+	}
+}
+
+func hasExemplar(h *dto.Histogram, traceID string) bool {
+	for _, bucket := range h.GetBucket() {
+		for _, label := range bucket.GetExemplar().GetLabel() {
+			if label.GetName() == "trace_id" && label.GetValue() == traceID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func labelMap(m *dto.Metric) map[string]string {
+	labels := make(map[string]string)
+	for _, l := range m.GetLabel() {
+		labels[l.GetName()] = l.GetValue()
+	}
+	return labels
+}
@@ -0,0 +1,154 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/org/llm-marketplace/services/discovery/internal/config"
+	"github.com/org/llm-marketplace/services/discovery/internal/elasticsearch"
+	"github.com/org/llm-marketplace/services/discovery/internal/search"
+)
+
+var pipelineConfig = config.QueryPipelineConfig{
+	Lowercase:       true,
+	RemoveStopwords: true,
+	Stopwords:       []string{"find", "me", "an", "for"},
+	ExpandAcronyms:  true,
+	Acronyms:        map[string]string{"llm": "large language model", "asr": "automatic speech recognition"},
+	InjectSynonyms:  true,
+	Synonyms:        map[string][]string{"chatbot": {"chat", "assistant"}},
+}
+
+// searchedQuery is the query text of the last search es received
+func searchedQuery(t *testing.T, es *fakeElasticsearch) string {
+	t.Helper()
+	body := es.searches[len(es.searches)-1]
+	const key = `"query":"`
+	i := strings.Index(body, `"multi_match":`)
+	if i < 0 {
+		t.Fatalf("search body has no multi_match: %s", body)
+	}
+	rest := body[i:]
+	start := strings.Index(rest, key) + len(key)
+	return rest[start : start+strings.Index(rest[start:], `"`)]
+}
+
+func TestQueryPipelineRewritesQuery(t *testing.T) {
+	es := &fakeElasticsearch{docs: map[string]*elasticsearch.ServiceDocument{}}
+	svc := newSearchService(t, es, func(cfg *config.Config) { cfg.Search.QueryPipeline = pipelineConfig })
+
+	req := &search.SearchRequest{Query: "Find me an LLM chatbot for ASR", Pagination: search.PaginationRequest{PageSize: 10}}
+	resp, err := svc.Search(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+
+	want := "llm large language model chatbot asr automatic speech recognition chat assistant"
+	if got := searchedQuery(t, es); got != want {
+		t.Errorf("searched %q, want %q", got, want)
+	}
+	if resp.Rewrite == nil || resp.Rewrite.Query != want {
+		t.Fatalf("rewrite = %+v", resp.Rewrite)
+	}
+	var stages []string
+	for _, step := range resp.Rewrite.Steps {
+		stages = append(stages, step.Stage)
+	}
+	if got := strings.Join(stages, ","); got != "lowercase,stopwords,acronyms,synonyms" {
+		t.Errorf("stages = %s", got)
+	}
+	if req.Query != "Find me an LLM chatbot for ASR" {
+		t.Errorf("request query changed to %q", req.Query)
+	}
+}
+
+func TestQueryPipelineKeepsAllStopwordQueries(t *testing.T) {
+	es := &fakeElasticsearch{docs: map[string]*elasticsearch.ServiceDocument{}}
+	svc := newSearchService(t, es, func(cfg *config.Config) { cfg.Search.QueryPipeline = pipelineConfig })
+
+	resp, err := svc.Search(context.Background(), &search.SearchRequest{Query: "find me", Pagination: search.PaginationRequest{PageSize: 10}})
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if got := searchedQuery(t, es); got != "find me" || resp.Rewrite != nil {
+		t.Errorf("searched %q with rewrite %+v, want the query as is", got, resp.Rewrite)
+	}
+}
+
+func TestLiteralSearchSkipsQueryPipeline(t *testing.T) {
+	es := &fakeElasticsearch{docs: map[string]*elasticsearch.ServiceDocument{}}
+	svc := newSearchService(t, es, func(cfg *config.Config) { cfg.Search.QueryPipeline = pipelineConfig })
+
+	if _, err := svc.Search(context.Background(), &search.SearchRequest{Query: "LLM for ASR", Literal: true, Pagination: search.PaginationRequest{PageSize: 10}}); err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if got := searchedQuery(t, es); got != "LLM for ASR" {
+		t.Errorf("searched %q, want the query as written", got)
+	}
+}
+
+// suffixStage is a custom stage appending a word
+type suffixStage struct{ word string }
+
+func (suffixStage) Name() string { return "suffix" }
+
+func (s suffixStage) Rewrite(_ context.Context, query string) (string, error) {
+	return query + " " + s.word, nil
+}
+
+func TestCustomQueryStageRunsAfterBuiltIns(t *testing.T) {
+	es := &fakeElasticsearch{docs: map[string]*elasticsearch.ServiceDocument{}}
+	svc := newSearchService(t, es, func(cfg *config.Config) {
+		cfg.Search.QueryPipeline = config.QueryPipelineConfig{Lowercase: true}
+	})
+	svc.AddQueryStage(suffixStage{word: "API"})
+
+	if _, err := svc.Search(context.Background(), &search.SearchRequest{Query: "Whisper", Pagination: search.PaginationRequest{PageSize: 10}}); err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if got := searchedQuery(t, es); got != "whisper API" {
+		t.Errorf("searched %q, want %q", got, "whisper API")
+	}
+}
+
+func TestLLMRewriteStage(t *testing.T) {
+	for _, tt := range []struct {
+		name  string
+		model http.Handler
+		want  string
+	}{
+		{name: "rewritten", model: &fakeModel{answer: "\"speech recognition transcription\"\nExplanation: ..."}, want: "speech recognition transcription"},
+		{name: "model fails", model: &fakeModel{status: http.StatusInternalServerError}, want: "turn calls into text"},
+		{
+			name: "too slow",
+			model: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				select {
+				case <-r.Context().Done():
+				case <-time.After(200 * time.Millisecond):
+				}
+			}),
+			want: "turn calls into text",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			llm := httptest.NewServer(tt.model)
+			t.Cleanup(llm.Close)
+			es := &fakeElasticsearch{docs: map[string]*elasticsearch.ServiceDocument{}}
+			svc := newSearchService(t, es, func(cfg *config.Config) {
+				cfg.Search.QueryPipeline = config.QueryPipelineConfig{LLMRewrite: true, LLMRewriteTimeout: 50 * time.Millisecond}
+				cfg.Assistant = config.AssistantConfig{Backend: config.AssistantBackendOpenAI, URL: llm.URL + "/v1", Model: "test-model", APIKey: "test-key"}
+			})
+
+			if _, err := svc.Search(context.Background(), &search.SearchRequest{Query: "turn calls into text", Pagination: search.PaginationRequest{PageSize: 10}}); err != nil {
+				t.Fatalf("Search: %v", err)
+			}
+			if got := searchedQuery(t, es); got != tt.want {
+				t.Errorf("searched %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
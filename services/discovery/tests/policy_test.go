@@ -0,0 +1,147 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/org/llm-marketplace/pkg/marketplace"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "github.com/org/llm-marketplace/services/discovery/api/proto/policyengine/v1"
+	"github.com/org/llm-marketplace/services/discovery/internal/config"
+	"github.com/org/llm-marketplace/services/discovery/internal/elasticsearch"
+	"github.com/org/llm-marketplace/services/discovery/internal/policy"
+)
+
+// fakePolicyEngine fails every service with violations, recording the request
+type fakePolicyEngine struct {
+	pb.UnimplementedPolicyEngineServiceServer
+	violations []*pb.PolicyViolation
+	received   chan *pb.ValidateServiceRequest
+}
+
+func (f *fakePolicyEngine) ValidateService(ctx context.Context, req *pb.ValidateServiceRequest) (*pb.ValidateServiceResponse, error) {
+	f.received <- req
+	return &pb.ValidateServiceResponse{
+		Compliant:     len(f.violations) == 0,
+		Violations:    f.violations,
+		PolicyVersion: "v7",
+		ValidatedAt:   timestamppb.New(time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)),
+	}, nil
+}
+
+func TestPolicyClientSummarisesValidation(t *testing.T) {
+	engine := &fakePolicyEngine{
+		violations: []*pb.PolicyViolation{
+			{PolicyId: "gdpr-residency", Field: "compliance.data_residency"},
+			{PolicyId: "gdpr-residency", Field: "compliance.gdpr_compliant"},
+			{PolicyId: "min-sla", Field: "sla.availability"},
+		},
+		received: make(chan *pb.ValidateServiceRequest, 1),
+	}
+	server := grpc.NewServer()
+	pb.RegisterPolicyEngineServiceServer(server, engine)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	go server.Serve(ln)
+	t.Cleanup(server.Stop)
+
+	conn, err := policy.Dial(config.PolicyEngineConfig{GRPCEndpoint: ln.Addr().String()})
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	doc := &elasticsearch.ServiceDocument{
+		ID:           "svc-1",
+		Name:         "Translator",
+		Capabilities: []string{"translation"},
+		Pricing:      elasticsearch.PricingInfo{Model: "per-token", Rate: 0.002, Unit: "1k tokens"},
+		Version:      &elasticsearch.VersionInfo{Number: "1.2.0"},
+	}
+	got, err := policy.NewClient(conn, time.Second).ValidateService(context.Background(), doc.Descriptor())
+	if err != nil {
+		t.Fatalf("ValidateService: %v", err)
+	}
+
+	want := &elasticsearch.PolicyCompliance{
+		FailingPolicies: []string{"gdpr-residency", "min-sla"},
+		PolicyVersion:   "v7",
+		ValidatedAt:     time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC),
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("compliance = %+v, want %+v", got, want)
+	}
+
+	req := <-engine.received
+	if req.GetServiceId() != "svc-1" || req.GetVersion() != "1.2.0" || len(req.GetCapabilities()) != 1 {
+		t.Errorf("request = %v, want svc-1 1.2.0 with one capability", req)
+	}
+	if rates := req.GetPricing().GetRates(); len(rates) != 1 || rates[0].GetRate() != 0.002 {
+		t.Errorf("pricing rates = %v, want the headline rate as one tier", rates)
+	}
+}
+
+// fakePolicyValidator answers from results, and fails for services not in it
+type fakePolicyValidator map[string]*elasticsearch.PolicyCompliance
+
+func (f fakePolicyValidator) ValidateService(ctx context.Context, desc *marketplace.ServiceDescriptor) (*elasticsearch.PolicyCompliance, error) {
+	if compliance, ok := f[desc.ServiceID]; ok {
+		return compliance, nil
+	}
+	return nil, errors.New("policy engine unavailable")
+}
+
+func TestIndexingStoresPolicyCompliance(t *testing.T) {
+	es := &fakeBulk{}
+	client := newBulkClient(t, es, config.BulkConfig{MaxAttempts: 1})
+	validatedAt := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	client.SetPolicyValidator(fakePolicyValidator{
+		"a": {Compliant: true, PolicyVersion: "v7", ValidatedAt: validatedAt},
+		"b": {FailingPolicies: []string{"min-sla"}, PolicyVersion: "v7", ValidatedAt: validatedAt},
+	})
+
+	// c can't be checked, so keeps the summary it was indexed with before
+	docs := bulkDocs("a", "b", "c")
+	previous := &elasticsearch.PolicyCompliance{Compliant: true, PolicyVersion: "v6", ValidatedAt: validatedAt.Add(-time.Hour)}
+	docs[2].PolicyCompliance = previous
+	if _, err := client.BulkIndex(context.Background(), docs); err != nil {
+		t.Fatalf("BulkIndex: %v", err)
+	}
+
+	for id, want := range map[string]*elasticsearch.PolicyCompliance{
+		"a": {Compliant: true, PolicyVersion: "v7", ValidatedAt: validatedAt},
+		"b": {FailingPolicies: []string{"min-sla"}, PolicyVersion: "v7", ValidatedAt: validatedAt},
+		"c": previous,
+	} {
+		var indexed elasticsearch.ServiceDocument
+		if err := json.Unmarshal([]byte(es.docs[id]), &indexed); err != nil {
+			t.Fatalf("document %s: %v", id, err)
+		}
+		if !reflect.DeepEqual(indexed.PolicyCompliance, want) {
+			t.Errorf("document %s policy_compliance = %+v, want %+v", id, indexed.PolicyCompliance, want)
+		}
+	}
+}
+
+func TestSearchFiltersOnPolicyCompliance(t *testing.T) {
+	es := &fakeElasticsearch{}
+	router := newEntitlementRouter(t, es)
+
+	if w := apiRequest(router, http.MethodGet, "/api/v1/search?q=translation&policy_compliant=true", "", nil); w.Code != http.StatusOK {
+		t.Fatalf("search: status %d, body %s", w.Code, w.Body.String())
+	}
+	if body := es.searches[len(es.searches)-1]; !strings.Contains(body, `{"term":{"policy_compliance.compliant":true}}`) {
+		t.Errorf("search body %s, want a policy_compliance.compliant filter", body)
+	}
+}
@@ -0,0 +1,88 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/org/llm-marketplace/services/discovery/internal/config"
+	"github.com/org/llm-marketplace/services/discovery/internal/postgres"
+)
+
+func TestPostgresPoolConfig(t *testing.T) {
+	poolConfig, err := postgres.ParseConfig(config.PostgresConfig{
+		Host:             "127.0.0.1",
+		Port:             1,
+		Database:         "marketplace",
+		User:             "discovery",
+		SSLMode:          "disable",
+		MaxConns:         7,
+		MinConns:         2,
+		ConnMaxIdleTime:  time.Minute,
+		StatementTimeout: 1500 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("ParseConfig: %v", err)
+	}
+
+	if poolConfig.MaxConns != 7 || poolConfig.MinConns != 2 {
+		t.Errorf("max/min conns = %d/%d, want 7/2", poolConfig.MaxConns, poolConfig.MinConns)
+	}
+	if poolConfig.MaxConnIdleTime != time.Minute {
+		t.Errorf("max idle time = %v, want 1m", poolConfig.MaxConnIdleTime)
+	}
+	if got := poolConfig.ConnConfig.RuntimeParams["statement_timeout"]; got != "1500" {
+		t.Errorf("statement_timeout = %q, want 1500", got)
+	}
+	if poolConfig.ConnConfig.Tracer == nil {
+		t.Error("queries are not traced")
+	}
+}
+
+func TestPostgresPoolCollector(t *testing.T) {
+	poolConfig, err := postgres.ParseConfig(config.PostgresConfig{Host: "127.0.0.1", Port: 1, SSLMode: "disable", MaxConns: 7})
+	if err != nil {
+		t.Fatalf("ParseConfig: %v", err)
+	}
+	// The pool connects lazily, so it can be created without a server
+	db, err := pgxpool.NewWithConfig(context.Background(), poolConfig)
+	if err != nil {
+		t.Fatalf("failed to create pool: %v", err)
+	}
+	t.Cleanup(db.Close)
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister((&postgres.Pool{Pool: db}).Collector())
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatalf("Gather: %v", err)
+	}
+
+	got := map[string]float64{}
+	for _, family := range families {
+		for _, m := range family.GetMetric() {
+			name := family.GetName()
+			if labels := labelMap(m); labels["state"] != "" {
+				name += "{" + labels["state"] + "}"
+			}
+			got[name] = m.GetGauge().GetValue() + m.GetCounter().GetValue()
+		}
+	}
+
+	if got["discovery_postgres_pool_max_connections"] != 7 {
+		t.Errorf("max connections = %v, want 7", got["discovery_postgres_pool_max_connections"])
+	}
+	for _, name := range []string{
+		"discovery_postgres_pool_connections{acquired}",
+		"discovery_postgres_pool_connections{idle}",
+		"discovery_postgres_pool_acquires_total",
+		"discovery_postgres_pool_acquire_wait_seconds_total",
+	} {
+		if _, ok := got[name]; !ok {
+			t.Errorf("missing %s in %v", name, got)
+		}
+	}
+}
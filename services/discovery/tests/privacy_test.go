@@ -0,0 +1,68 @@
+package tests
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/org/llm-marketplace/services/discovery/internal/config"
+	"github.com/org/llm-marketplace/services/discovery/internal/privacy"
+)
+
+const testPseudonymKey = "test-pseudonym-key-0123456789abcdef"
+
+func TestPseudonymsAreStableUUIDs(t *testing.T) {
+	p := privacy.NewPseudonymizer(config.PrivacyConfig{Pseudonymize: true, PseudonymKey: testPseudonymKey})
+	user := "6f1c2a9e-3b4d-4e5f-8a7b-9c0d1e2f3a4b"
+
+	pseudonym := p.Pseudonym(user)
+	if !regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-8[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`).MatchString(pseudonym) {
+		t.Errorf("pseudonym %q is not a version 8 UUID", pseudonym)
+	}
+	if pseudonym == user || p.Pseudonym(user) != pseudonym {
+		t.Errorf("pseudonym %q of %q is not a stable replacement", pseudonym, user)
+	}
+
+	other := privacy.NewPseudonymizer(config.PrivacyConfig{Pseudonymize: true, PseudonymKey: strings.Repeat("k", 32)})
+	if other.Pseudonym(user) == pseudonym {
+		t.Error("two keys gave the same pseudonym")
+	}
+
+	if ids := p.StoredIDs(user); len(ids) != 2 || ids[0] != user || ids[1] != pseudonym {
+		t.Errorf("stored IDs = %v, want the ID and its pseudonym", ids)
+	}
+	if got := p.Canonical(user, false); got != pseudonym {
+		t.Errorf("canonical ID of a raw row = %q, want its pseudonym", got)
+	}
+	if got := p.Canonical(pseudonym, true); got != pseudonym {
+		t.Errorf("canonical ID of a pseudonymized row = %q, want it unchanged", got)
+	}
+}
+
+func TestPseudonymizationOff(t *testing.T) {
+	p := privacy.NewPseudonymizer(config.PrivacyConfig{PseudonymKey: testPseudonymKey})
+	if p != nil {
+		t.Fatal("got a pseudonymizer with pseudonymize off")
+	}
+	if got := p.Pseudonym("u1"); got != "u1" {
+		t.Errorf("Pseudonym = %q, want the ID unchanged", got)
+	}
+	if ids := p.StoredIDs("u1"); len(ids) != 1 || ids[0] != "u1" {
+		t.Errorf("StoredIDs = %v, want just the ID", ids)
+	}
+}
+
+func TestPrivacyConfigValidation(t *testing.T) {
+	for _, tt := range []struct{ old, new, want string }{
+		{"pseudonymize: false", "pseudonymize: true", "pseudonym_key of at least 32 characters"},
+		{"user_interactions: 2160h", "user_interactions: -1h", "retention periods must not be negative"},
+		{"batch_size: 5000", "batch_size: 0", "positive purge_interval and batch_size"},
+	} {
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		writeConfig(t, path, tt.old, tt.new)
+		if _, err := config.Load(path); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: Load error = %v, want %q", tt.new, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/org/llm-marketplace/pkg/marketplace"
+)
+
+func TestCatalogIndexesPromptTemplate(t *testing.T) {
+	fake := newFakeCatalog()
+	consumer := newCatalogConsumer(fake)
+
+	err := consumer.Apply(context.Background(), catalogEvent(1, marketplace.StatusActive, func(e *marketplace.CatalogEvent) {
+		e.Service.Kind = marketplace.KindPromptTemplate
+		e.Service.Endpoint = nil
+		e.Service.PromptTemplate = &marketplace.PromptTemplate{
+			Template:      "Summarise {{document}} in {{length}} sentences.",
+			Variables:     []marketplace.TemplateVariable{{Name: "document", Required: true}, {Name: "length", Default: "3"}},
+			ModelFamilies: []string{"gpt-4", "claude-3"},
+		}
+	}))
+	if err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	doc := fake.docs["3f1c6a52-8a7e-4b55-9d0e-1f2a3b4c5d6e"]
+	if doc == nil {
+		t.Fatal("prompt template was not indexed")
+	}
+	if doc.Kind != marketplace.KindPromptTemplate || doc.PromptTemplate == nil || len(doc.PromptTemplate.Variables) != 2 || doc.Endpoint != "" {
+		t.Errorf("document = %+v, want the prompt template without an endpoint", doc)
+	}
+	if desc := doc.Descriptor(); !desc.IsPromptTemplate() || !desc.PromptTemplate.Supports("claude-3") {
+		t.Errorf("descriptor = %+v, want the prompt template for policy checks", desc)
+	}
+}
+
+func TestSearchFiltersOnKindAndModelFamily(t *testing.T) {
+	es := &fakeElasticsearch{}
+	router := newEntitlementRouter(t, es)
+
+	if w := apiRequest(router, http.MethodGet, "/api/v1/search?q=summarise&kind=prompt_template&model_families=GPT-4,claude-3", "", nil); w.Code != http.StatusOK {
+		t.Fatalf("search: status %d, body %s", w.Code, w.Body.String())
+	}
+	body := es.searches[len(es.searches)-1]
+	if !strings.Contains(body, `{"term":{"kind":"prompt_template"}}`) || !strings.Contains(body, `{"terms":{"prompt_template.model_families":["gpt-4","claude-3"]}}`) {
+		t.Errorf("search body %s, want kind and model family filters", body)
+	}
+
+	// Services include documents indexed without a kind
+	if w := apiRequest(router, http.MethodGet, "/api/v1/search?q=summarise&kind=service", "", nil); w.Code != http.StatusOK {
+		t.Fatalf("search: status %d, body %s", w.Code, w.Body.String())
+	}
+	if body := es.searches[len(es.searches)-1]; !strings.Contains(body, `"must_not":[`) || !strings.Contains(body, `{"terms":{"kind":["prompt_template","dataset","fine_tuning"]}}`) {
+		t.Errorf("search body %s, want the other kinds excluded", body)
+	}
+
+	if w := apiRequest(router, http.MethodGet, "/api/v1/search?q=summarise&kind=notebook", "", nil); w.Code != http.StatusBadRequest {
+		t.Errorf("search of an unknown kind: status %d, want 400", w.Code)
+	}
+}
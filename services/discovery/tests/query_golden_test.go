@@ -0,0 +1,144 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/org/llm-marketplace/pkg/marketplace"
+
+	"github.com/org/llm-marketplace/services/discovery/internal/config"
+	"github.com/org/llm-marketplace/services/discovery/internal/entitlement"
+	"github.com/org/llm-marketplace/services/discovery/internal/search"
+)
+
+// Run with -update to rewrite the golden files from the current query builder
+var updateGolden = flag.Bool("update", false, "rewrite golden files")
+
+// goldenQueries are searches whose Elasticsearch request bodies are kept in
+// testdata/queries, one file per case
+var goldenQueries = []struct {
+	name     string
+	req      search.SearchRequest
+	caller   entitlement.Caller
+	semantic bool // Semantic search on, with a working embedding service
+}{
+	{name: "match_all"},
+	{name: "text", req: search.SearchRequest{Query: "chat assistant"}},
+	{name: "hybrid", req: search.SearchRequest{Query: "chat assistant"}, semantic: true},
+	{name: "semantic_without_query", semantic: true},
+	{name: "tenant_caller", req: search.SearchRequest{Query: "chat"}, caller: entitlement.Caller{TenantID: "acme", UserID: "u1"}},
+	{name: "pagination", req: search.SearchRequest{Query: "chat", Pagination: search.PaginationRequest{Page: 3, PageSize: 500}}},
+	{name: "fields", req: search.SearchRequest{Query: "chat", Fields: []string{"name", "pricing"}}},
+	{name: "facets", req: search.SearchRequest{Query: "chat", Facets: []string{"categories", "price_ranges"}}},
+	{name: "aggregations_only", req: search.SearchRequest{Query: "chat", AggregationsOnly: true}},
+	{name: "count_only", req: search.SearchRequest{Query: "chat", CountOnly: true}},
+	{name: "all_versions", req: search.SearchRequest{AllVersions: true}},
+	{name: "status", req: search.SearchRequest{Filters: search.SearchFilters{Status: "deprecated"}}},
+	{name: "categories_and_tags", req: search.SearchRequest{Filters: search.SearchFilters{Categories: []string{"chat", "vision"}, Tags: []string{"nlp"}}}},
+	{name: "rating_and_price", req: search.SearchRequest{Filters: search.SearchFilters{MinRating: 4, MinPrice: 0.5, MaxPrice: 2, PricingModels: []string{"per-token"}}}},
+	{name: "compliance", req: search.SearchRequest{Filters: search.SearchFilters{
+		ComplianceLevel: "enterprise",
+		Certifications:  []string{"SOC2", "ISO27001"},
+		DataResidency:   []string{"EU"},
+		GDPRCompliant:   true,
+		HIPAACompliant:  true,
+		PolicyCompliant: true,
+	}}},
+	{name: "provider_and_sla", req: search.SearchRequest{Filters: search.SearchFilters{
+		VerifiedOnly:    true,
+		MinAvailability: 99.9,
+		MaxLatencyMS:    250,
+		Capabilities:    []string{"streaming", "function-calling"},
+	}}},
+	{name: "kind_service", req: search.SearchRequest{Filters: search.SearchFilters{Kind: marketplace.KindService}}},
+	{name: "prompt_templates", req: search.SearchRequest{Filters: search.SearchFilters{Kind: marketplace.KindPromptTemplate, ModelFamilies: []string{" GPT-4 ", "claude"}}}},
+	{name: "datasets", req: search.SearchRequest{Filters: search.SearchFilters{Kind: marketplace.KindDataset, Licenses: []string{"MIT"}, DatasetFormats: []string{"parquet"}, MaxDatasetBytes: 1 << 30}}},
+	{name: "fine_tuning", req: search.SearchRequest{Filters: search.SearchFilters{Kind: marketplace.KindFineTuning, BaseModels: []string{"Llama-3"}, MaxTurnaroundHours: 24}}},
+	{name: "serving_region", req: search.SearchRequest{Filters: search.SearchFilters{ServingRegion: " EU-West-1 "}}},
+	{name: "all_filters", req: search.SearchRequest{Query: "chat", Filters: search.SearchFilters{
+		Categories:      []string{"chat"},
+		Tags:            []string{"nlp"},
+		MinRating:       4,
+		MinPrice:        0.5,
+		MaxPrice:        2,
+		PricingModels:   []string{"per-token"},
+		ComplianceLevel: "enterprise",
+		Certifications:  []string{"SOC2"},
+		DataResidency:   []string{"EU"},
+		VerifiedOnly:    true,
+		Status:          "active",
+		MinAvailability: 99.9,
+		Capabilities:    []string{"streaming"},
+		MaxLatencyMS:    250,
+		GDPRCompliant:   true,
+		HIPAACompliant:  true,
+		PolicyCompliant: true,
+		Kind:            marketplace.KindService,
+		ServingRegion:   "eu-west-1",
+	}}, caller: entitlement.Caller{TenantID: "acme"}, semantic: true},
+}
+
+// TestSearchQueryGolden pins the query DSL each search sends to
+// Elasticsearch, so changes to the query builder show up as golden diffs
+func TestSearchQueryGolden(t *testing.T) {
+	embeddings, _ := embeddingServer(t, 0, http.StatusOK)
+
+	for _, tc := range goldenQueries {
+		t.Run(tc.name, func(t *testing.T) {
+			es := &fakeElasticsearch{}
+			svc := newSearchService(t, es, func(c *config.Config) {
+				c.Search.SemanticEnabled = tc.semantic
+				if tc.semantic {
+					c.EmbeddingService = embeddingConfig(embeddings.URL)
+					c.Elasticsearch.VectorDimensions = 1 // The length of the query, from embeddingServer
+				}
+			})
+
+			ctx := entitlement.WithCaller(context.Background(), tc.caller)
+			req := tc.req
+			if _, err := svc.Search(ctx, &req); err != nil {
+				t.Fatalf("Search: %v", err)
+			}
+
+			es.mu.Lock()
+			defer es.mu.Unlock()
+			if len(es.searches) != 1 {
+				t.Fatalf("sent %d searches, want 1", len(es.searches))
+			}
+			var got bytes.Buffer
+			if err := json.Indent(&got, []byte(es.searches[0]), "", "  "); err != nil {
+				t.Fatalf("search body is not JSON: %v", err)
+			}
+			got.WriteByte('\n')
+			compareGolden(t, filepath.Join("testdata", "queries", tc.name+".json"), got.Bytes())
+		})
+	}
+}
+
+// compareGolden fails the test when got differs from the golden file at
+// path, or rewrites the file with -update
+func compareGolden(t *testing.T, path string, got []byte) {
+	t.Helper()
+	if *updateGolden {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read golden file (run with -update to create it): %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("query differs from %s (run with -update if the change is intended)\ngot:\n%s\nwant:\n%s", path, got, want)
+	}
+}
@@ -0,0 +1,133 @@
+package tests
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/org/llm-marketplace/services/discovery/internal/elasticsearch/query"
+	"github.com/org/llm-marketplace/services/discovery/internal/entitlement"
+)
+
+// assertJSON compares the JSON rendering of got with want, ignoring key order
+func assertJSON(t *testing.T, got interface{}, want string) {
+	t.Helper()
+	gotJSON, err := json.Marshal(got)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+
+	var gotValue, wantValue interface{}
+	if err := json.Unmarshal(gotJSON, &gotValue); err != nil {
+		t.Fatalf("unmarshal rendered query: %v", err)
+	}
+	if err := json.Unmarshal([]byte(want), &wantValue); err != nil {
+		t.Fatalf("unmarshal expected query: %v", err)
+	}
+	if !reflect.DeepEqual(gotValue, wantValue) {
+		t.Errorf("query mismatch\n got: %s\nwant: %s", gotJSON, want)
+	}
+}
+
+func TestQueryClauses(t *testing.T) {
+	tests := []struct {
+		name   string
+		clause query.Clause
+		want   string
+	}{
+		{"term", query.Term("status", "active"), `{"term": {"status": "active"}}`},
+		{"terms", query.Terms("tags", []string{"nlp", "vision"}), `{"terms": {"tags": ["nlp", "vision"]}}`},
+		{"exists", query.Exists("embedding"), `{"exists": {"field": "embedding"}}`},
+		{"match all", query.MatchAll(), `{"match_all": {}}`},
+		{
+			"range",
+			query.Range("pricing.rate").Gte(1).Lt(5.5),
+			`{"range": {"pricing.rate": {"gte": 1, "lt": 5.5}}}`,
+		},
+		{
+			"multi match",
+			query.MultiMatch("translate", "name^3", "description").Type("best_fields").Fuzziness("AUTO").Operator("or"),
+			`{"multi_match": {"query": "translate", "fields": ["name^3", "description"], "type": "best_fields", "fuzziness": "AUTO", "operator": "or"}}`,
+		},
+		{
+			"multi match defaults omitted",
+			query.MultiMatch("translate", "name"),
+			`{"multi_match": {"query": "translate", "fields": ["name"]}}`,
+		},
+		{
+			"script score",
+			query.ScriptScore(query.Exists("embedding"), query.Script{
+				Source: "cosineSimilarity(params.v, 'embedding') + 1.0",
+				Params: map[string]interface{}{"v": []float32{0.5}},
+			}),
+			`{"script_score": {"query": {"exists": {"field": "embedding"}}, "script": {"source": "cosineSimilarity(params.v, 'embedding') + 1.0", "params": {"v": [0.5]}}}}`,
+		},
+		{
+			"empty bool",
+			query.Bool(),
+			`{"bool": {}}`,
+		},
+		{
+			"bool",
+			query.Bool().
+				Must(query.MatchAll()).
+				Filter(query.Term("a", 1), query.Term("b", 2)).
+				Should(query.Term("c", 3)).
+				MustNot(query.Term("d", false)).
+				MinimumShouldMatch(1),
+			`{"bool": {
+				"must": [{"match_all": {}}],
+				"filter": [{"term": {"a": 1}}, {"term": {"b": 2}}],
+				"should": [{"term": {"c": 3}}],
+				"must_not": [{"term": {"d": false}}],
+				"minimum_should_match": 1
+			}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertJSON(t, tt.clause.Source(), tt.want)
+		})
+	}
+}
+
+func TestSearchBody(t *testing.T) {
+	search := &query.Search{
+		Query:          query.Bool().Filter(query.Term("status", "active")),
+		From:           20,
+		Size:           10,
+		Aggregations:   map[string]interface{}{"tags": map[string]interface{}{"terms": map[string]interface{}{"field": "tags"}}},
+		SourceFilter:   map[string]interface{}{"excludes": []string{"embedding"}},
+		Sort:           []interface{}{map[string]interface{}{"id": "asc"}},
+		TrackTotalHits: true,
+	}
+
+	assertJSON(t, search, `{
+		"from": 20,
+		"size": 10,
+		"query": {"bool": {"filter": [{"term": {"status": "active"}}]}},
+		"aggs": {"tags": {"terms": {"field": "tags"}}},
+		"_source": {"excludes": ["embedding"]},
+		"sort": [{"id": "asc"}],
+		"track_total_hits": true
+	}`)
+
+	assertJSON(t, &query.Search{Size: 0}, `{"from": 0, "size": 0}`)
+}
+
+func TestCallerFilterQuery(t *testing.T) {
+	anonymous := `{"bool": {"should": [
+		{"bool": {"must_not": [{"exists": {"field": "access.visibility"}}]}},
+		{"term": {"access.visibility": "public"}}
+	], "minimum_should_match": 1}}`
+	assertJSON(t, entitlement.Caller{}.Filter().Source(), anonymous)
+
+	assertJSON(t, entitlement.Caller{TenantID: "acme", UserID: "u1"}.Filter().Source(), `{"bool": {"should": [
+		{"bool": {"must_not": [{"exists": {"field": "access.visibility"}}]}},
+		{"term": {"access.visibility": "public"}},
+		{"bool": {"filter": [{"term": {"access.visibility": "tenant"}}, {"term": {"access.owner_tenant": "acme"}}]}},
+		{"bool": {"filter": [{"terms": {"access.visibility": ["tenant", "private"]}}, {"term": {"access.allowed_tenants": "acme"}}]}},
+		{"bool": {"filter": [{"terms": {"access.visibility": ["tenant", "private"]}}, {"term": {"access.allowed_users": "u1"}}]}}
+	], "minimum_should_match": 1}}`)
+}
@@ -0,0 +1,220 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/org/llm-marketplace/services/discovery/internal/config"
+	"github.com/org/llm-marketplace/services/discovery/internal/problem"
+)
+
+var quotasConfig = config.QuotasConfig{
+	Enabled:       true,
+	APIKeyHeader:  "X-API-Key-ID",
+	DefaultTier:   "standard",
+	AnonymousTier: "anonymous",
+	Tiers: map[string]config.QuotaLimits{
+		"anonymous":  {RequestsPerSecond: 1},
+		"standard":   {RequestsPerDay: 3},
+		"enterprise": {},
+	},
+	Tenants: map[string]string{"globex": "enterprise"},
+	APIKeys: map[string]string{"key-1": "anonymous"},
+}
+
+// newQuotaRouter serves the API with quotasConfig over a fake Redis
+func newQuotaRouter(t *testing.T) *gin.Engine {
+	t.Helper()
+	_, addr := newFakeRedis(t)
+	return newAPIRouter(t, &fakeElasticsearch{}, addr, func(c *config.Config) { c.Quotas = quotasConfig })
+}
+
+// apiRequest sends a request to router as the caller header names
+func apiRequest(router *gin.Engine, method, path, body string, header http.Header) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+	if header != nil {
+		req.Header = header.Clone()
+	}
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestDailyQuotaByTenant(t *testing.T) {
+	router := newQuotaRouter(t)
+	acme := http.Header{"X-Tenant-Id": {"acme"}}
+
+	for i, remaining := range []string{"2", "1", "0"} {
+		w := apiRequest(router, http.MethodGet, "/api/v1/categories", "", acme)
+		if w.Code == http.StatusTooManyRequests {
+			t.Fatalf("request %d rejected within the quota", i+1)
+		}
+		if got := w.Header().Get("X-Quota-Remaining"); got != remaining {
+			t.Errorf("request %d: X-Quota-Remaining = %q, want %s", i+1, got, remaining)
+		}
+		if w.Header().Get("X-RateLimit-Limit") != "" {
+			t.Errorf("request %d: rate limit headers sent for a tier without one", i+1)
+		}
+	}
+
+	w := apiRequest(router, http.MethodGet, "/api/v1/categories", "", acme)
+	if w.Code != http.StatusTooManyRequests || !strings.Contains(w.Body.String(), problem.RateLimited.Code) {
+		t.Fatalf("request over the quota: status %d, body %s", w.Code, w.Body.String())
+	}
+	if w.Header().Get("Retry-After") == "" || w.Header().Get("X-Quota-Limit") != "3" {
+		t.Errorf("rejection headers = %v", w.Header())
+	}
+
+	// Rejected requests don't use the quota, and the usage endpoint isn't limited
+	w = apiRequest(router, http.MethodGet, "/api/v1/quota", "", acme)
+	var got struct {
+		Enforced bool `json:"enforced"`
+		Usage    struct {
+			Caller struct{ Kind, ID string } `json:"caller"`
+			Tier   string                    `json:"tier"`
+			Day    struct {
+				Limit     int   `json:"limit"`
+				Used      int64 `json:"used"`
+				Remaining int64 `json:"remaining"`
+			} `json:"day"`
+		} `json:"usage"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("GET /api/v1/quota: status %d, body %s", w.Code, w.Body.String())
+	}
+	if !got.Enforced || got.Usage.Caller.ID != "acme" || got.Usage.Tier != "standard" || got.Usage.Day.Used != 3 || got.Usage.Day.Remaining != 0 {
+		t.Errorf("usage = %+v, want acme on standard with 3 of 3 used", got)
+	}
+
+	// Tenants are counted apart, and an enterprise tenant is unlimited
+	for i := 0; i < 5; i++ {
+		if w := apiRequest(router, http.MethodGet, "/api/v1/categories", "", http.Header{"X-Tenant-Id": {"globex"}}); w.Code == http.StatusTooManyRequests {
+			t.Fatalf("enterprise request %d rejected", i+1)
+		}
+	}
+}
+
+func TestGraphQLCountsAgainstTheQuota(t *testing.T) {
+	router := newQuotaRouter(t)
+	acme := http.Header{"X-Tenant-Id": {"acme"}}
+	query := `{"query": "{ categories { name } }"}`
+
+	for i := 0; i < 2; i++ {
+		if w := apiRequest(router, http.MethodPost, "/graphql", query, acme); w.Code == http.StatusTooManyRequests {
+			t.Fatalf("query %d rejected within the quota", i+1)
+		}
+	}
+	// Queries and REST requests share the daily quota of 3
+	if w := apiRequest(router, http.MethodGet, "/api/v1/categories", "", acme); w.Header().Get("X-Quota-Remaining") != "0" {
+		t.Errorf("X-Quota-Remaining after two queries and a request = %q, want 0", w.Header().Get("X-Quota-Remaining"))
+	}
+	if w := apiRequest(router, http.MethodPost, "/graphql", query, acme); w.Code != http.StatusTooManyRequests {
+		t.Errorf("query over the quota: status %d, want 429", w.Code)
+	}
+}
+
+func TestRateLimitByAPIKey(t *testing.T) {
+	router := newQuotaRouter(t)
+	// The API key is counted rather than its tenant, and its tier allows one request a second
+	caller := http.Header{"X-Tenant-Id": {"globex"}, "X-Api-Key-Id": {"key-1"}}
+
+	// However the requests fall across seconds, two land in the same one
+	rejected := 0
+	for i := 0; i < 3; i++ {
+		w := apiRequest(router, http.MethodGet, "/api/v1/categories", "", caller)
+		if w.Code == http.StatusTooManyRequests {
+			rejected++
+			if w.Header().Get("X-RateLimit-Remaining") != "0" || w.Header().Get("Retry-After") != "1" {
+				t.Errorf("rejection headers = %v", w.Header())
+			}
+		}
+	}
+	if rejected == 0 {
+		t.Error("no request rejected over one a second")
+	}
+}
+
+func TestQuotaOverrides(t *testing.T) {
+	router := newQuotaRouter(t)
+	acme := http.Header{"X-Tenant-Id": {"acme"}}
+
+	for _, tt := range []struct {
+		body string
+		want int
+	}{
+		{`{"tier": "platinum"}`, http.StatusBadRequest},
+		{`{"tier": "enterprise", "requests_per_day": 5}`, http.StatusBadRequest},
+		{`{}`, http.StatusBadRequest},
+		{`{"tier": "enterprise"}`, http.StatusOK},
+	} {
+		if w := apiRequest(router, http.MethodPut, "/api/v1/admin/quotas/tenants/acme", tt.body, nil); w.Code != tt.want {
+			t.Errorf("PUT %s: status %d, want %d: %s", tt.body, w.Code, tt.want, w.Body.String())
+		}
+	}
+	if w := apiRequest(router, http.MethodPut, "/api/v1/admin/quotas/users/acme", `{"tier": "enterprise"}`, nil); w.Code != http.StatusNotFound {
+		t.Errorf("override of an unknown kind of caller: status %d", w.Code)
+	}
+
+	// The enterprise override lifts acme's daily quota
+	for i := 0; i < 5; i++ {
+		if w := apiRequest(router, http.MethodGet, "/api/v1/categories", "", acme); w.Code == http.StatusTooManyRequests {
+			t.Fatalf("overridden request %d rejected", i+1)
+		}
+	}
+
+	w := apiRequest(router, http.MethodGet, "/api/v1/admin/quotas", "", nil)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"tenant:acme":{"tier":"enterprise"`) {
+		t.Errorf("GET /api/v1/admin/quotas: status %d, body %s", w.Code, w.Body.String())
+	}
+
+	if w := apiRequest(router, http.MethodDelete, "/api/v1/admin/quotas/tenants/acme", "", nil); w.Code != http.StatusNoContent {
+		t.Errorf("DELETE: status %d", w.Code)
+	}
+	if w := apiRequest(router, http.MethodDelete, "/api/v1/admin/quotas/tenants/acme", "", nil); w.Code != http.StatusNotFound {
+		t.Errorf("second DELETE: status %d, want 404", w.Code)
+	}
+
+	// Back on standard, acme has used its quota
+	if w := apiRequest(router, http.MethodGet, "/api/v1/categories", "", acme); w.Code != http.StatusTooManyRequests {
+		t.Errorf("request after the override was removed: status %d, want 429", w.Code)
+	}
+}
+
+func TestQuotasAllowRequestsWithoutRedis(t *testing.T) {
+	router := newAPIRouter(t, &fakeElasticsearch{}, "127.0.0.1:1", func(c *config.Config) { c.Quotas = quotasConfig })
+	for i := 0; i < 3; i++ {
+		w := apiRequest(router, http.MethodGet, "/api/v1/autocomplete?q=model", "", nil)
+		if w.Code == http.StatusTooManyRequests || w.Header().Get("X-RateLimit-Limit") != "" {
+			t.Fatalf("request %d limited without Redis: status %d", i+1, w.Code)
+		}
+	}
+}
+
+func TestQuotasConfigValidation(t *testing.T) {
+	t.Setenv("DISCOVERY_QUOTAS_ENABLED", "true")
+	for _, tt := range []struct{ old, new, want string }{
+		{"api_key_header: \"X-API-Key-ID\"", "api_key_header: \"X-API-Key-ID\"", ""},
+		{"api_key_header: \"X-API-Key-ID\"", "api_key_header: \"\"", "needs an api_key_header"},
+		{"tenants: {}", "tenants: {acme: platinum}", `unknown tier "platinum"`},
+		{"default_tier: standard", "default_tier: gold", "must name a tier"},
+		{"requests_per_second: 20", "requests_per_second: -1", "limits must not be negative"},
+	} {
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		writeConfig(t, path, tt.old, tt.new)
+		_, err := config.Load(path)
+		switch {
+		case tt.want == "" && err != nil:
+			t.Errorf("%s: Load error = %v", tt.new, err)
+		case tt.want != "" && (err == nil || !strings.Contains(err.Error(), tt.want)):
+			t.Errorf("%s: Load error = %v, want %q", tt.new, err, tt.want)
+		}
+	}
+}
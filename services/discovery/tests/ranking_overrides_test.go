@@ -0,0 +1,87 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/org/llm-marketplace/services/discovery/internal/config"
+	"github.com/org/llm-marketplace/services/discovery/internal/search"
+)
+
+func TestRankingWeightOverrides(t *testing.T) {
+	cfg := startSandbox(t)
+	cfg.Search.RankingOverrides = config.RankingOverridesConfig{Enabled: true, Tenants: []string{"acme"}}
+	ctx := context.Background()
+
+	svc, _ := newSandboxSearch(t, cfg)
+
+	if _, err := svc.Search(ctx, &search.SearchRequest{Query: "code review"}); err != nil {
+		t.Fatalf("search failed: %v", err)
+	}
+	// Ranked by popularity alone, bypassing the response cached above
+	popularity := &config.RankingWeights{Popularity: 1}
+	resp, err := svc.Search(ctx, &search.SearchRequest{Query: "code review", RankingWeights: popularity})
+	if err != nil {
+		t.Fatalf("search with overridden weights failed: %v", err)
+	}
+	if len(resp.Results) == 0 {
+		t.Fatal("search of the seeded catalog found nothing")
+	}
+	for i, r := range resp.Results {
+		if !r.MatchDetails.Demoted && math.Abs(r.MatchDetails.PopularityScore-r.Score) > 1e-9 {
+			t.Errorf("result %d scored %f, popularity %f", i, r.Score, r.MatchDetails.PopularityScore)
+		}
+	}
+
+	for _, weights := range []config.RankingWeights{
+		{Relevance: 0.5, Popularity: 0.2},
+		{Relevance: 1.2, Popularity: -0.2},
+	} {
+		_, err := svc.Search(ctx, &search.SearchRequest{Query: "code review", RankingWeights: &weights})
+		if !errors.Is(err, search.ErrInvalidRankingWeights) {
+			t.Errorf("weights %+v: %v, want invalid", weights, err)
+		}
+	}
+}
+
+func TestRankingWeightOverridesNeedGatewayIdentity(t *testing.T) {
+	router := newAPIRouter(t, &fakeElasticsearch{}, "127.0.0.1:1", func(cfg *config.Config) {
+		cfg.Entitlements.OperatorHeader = "X-Operator-ID"
+		cfg.Quotas.APIKeyHeader = "X-API-Key-ID"
+		cfg.Search.RankingOverrides = config.RankingOverridesConfig{Enabled: true, Tenants: []string{"acme"}}
+	})
+	search := func(body string, header http.Header) int {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/search", bytes.NewBufferString(body))
+		req.Header = header
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	weighted := `{"query": "model", "ranking_weights": {"relevance": 0.7, "popularity": 0.1, "performance": 0.1, "compliance": 0.1}}`
+	for _, tc := range []struct {
+		name   string
+		body   string
+		header http.Header
+		want   int
+	}{
+		{"operator", weighted, http.Header{"X-Operator-Id": {"root"}}, http.StatusOK},
+		{"listed tenant", weighted, http.Header{"X-Tenant-Id": {"acme"}}, http.StatusOK},
+		{"listed tenant, bad weights", `{"query": "model", "ranking_weights": {"relevance": 0.7}}`, http.Header{"X-Tenant-Id": {"acme"}}, http.StatusBadRequest},
+		{"other tenant", weighted, http.Header{"X-Tenant-Id": {"globex"}}, http.StatusForbidden},
+		// The API key header is one clients can send, so it grants nothing
+		{"forged API key", weighted, http.Header{"X-Api-Key-Id": {"key-tools"}}, http.StatusForbidden},
+		{"anonymous", weighted, http.Header{}, http.StatusForbidden},
+		{"without weights", `{"query": "model"}`, http.Header{}, http.StatusOK},
+	} {
+		if got := search(tc.body, tc.header); got != tc.want {
+			t.Errorf("%s: status %d, want %d", tc.name, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+
+	"github.com/org/llm-marketplace/services/discovery/internal/config"
+	"github.com/org/llm-marketplace/services/discovery/internal/health"
+)
+
+// fakeDependency is a check whose latency and result tests control
+type fakeDependency struct {
+	latency time.Duration
+	err     error
+}
+
+func (f *fakeDependency) check(context.Context) (time.Duration, error) {
+	return f.latency, f.err
+}
+
+func serveReadiness(t *testing.T, monitor *health.Monitor) (int, health.Report) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/ready", monitor.Handler())
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ready", nil))
+
+	var report health.Report
+	if err := json.Unmarshal(w.Body.Bytes(), &report); err != nil {
+		t.Fatalf("failed to decode readiness report: %v", err)
+	}
+	return w.Code, report
+}
+
+func TestReadinessStatus(t *testing.T) {
+	postgres := &fakeDependency{latency: time.Millisecond}
+	redis := &fakeDependency{latency: time.Millisecond}
+	es := &fakeDependency{latency: time.Millisecond}
+	monitor := health.NewMonitor([]health.Dependency{
+		{Name: "postgres", Check: postgres.check, Critical: true},
+		{Name: "redis", Check: redis.check},
+		{Name: "elasticsearch", Check: es.check, Critical: true, SlowThreshold: 100 * time.Millisecond},
+	}, config.ReadinessConfig{}, zap.NewNop(), testMetrics())
+
+	if code, report := serveReadiness(t, monitor); code != http.StatusServiceUnavailable || report.Status != health.Unhealthy {
+		t.Errorf("before the first check: %d %s, want 503 unhealthy", code, report.Status)
+	}
+
+	tests := []struct {
+		name     string
+		setup    func()
+		wantCode int
+		want     health.Status
+	}{
+		{"all up", func() {}, http.StatusOK, health.Healthy},
+		{"elasticsearch slow", func() { es.latency = 300 * time.Millisecond }, http.StatusOK, health.Degraded},
+		{"redis down", func() { es.latency = time.Millisecond; redis.err = errors.New("connection refused") }, http.StatusOK, health.Degraded},
+		{"postgres down", func() { postgres.err = errors.New("connection refused") }, http.StatusServiceUnavailable, health.Unhealthy},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setup()
+			monitor.Check(context.Background())
+
+			code, report := serveReadiness(t, monitor)
+			if code != tt.wantCode || report.Status != tt.want {
+				t.Errorf("got %d %s, want %d %s", code, report.Status, tt.wantCode, tt.want)
+			}
+			if gauge := readinessGauge(t, string(tt.want)); gauge != 1 {
+				t.Errorf("discovery_readiness_status{status=%q} = %v, want 1", tt.want, gauge)
+			}
+		})
+	}
+
+	_, report := serveReadiness(t, monitor)
+	pg := report.Dependencies["postgres"]
+	if pg.Error == "" || pg.LastSuccessAt == nil {
+		t.Errorf("postgres = %+v, want the error and the time it last succeeded", pg)
+	}
+	if es := report.Dependencies["elasticsearch"]; es.Status != health.Healthy || es.LatencyMS != 1 {
+		t.Errorf("elasticsearch = %+v, want healthy at 1ms", es)
+	}
+}
+
+func TestReadinessConfigOverridesDependencies(t *testing.T) {
+	redis := &fakeDependency{err: errors.New("connection refused")}
+	monitor := health.NewMonitor([]health.Dependency{
+		{Name: "redis", Check: redis.check},
+	}, config.ReadinessConfig{
+		Dependencies: map[string]config.DependencyCheckConfig{"redis": {Critical: true}},
+	}, zap.NewNop(), nil)
+	monitor.Check(context.Background())
+
+	if code, report := serveReadiness(t, monitor); code != http.StatusServiceUnavailable || !report.Dependencies["redis"].Critical {
+		t.Errorf("got %d %+v, want redis critical and the service unavailable", code, report)
+	}
+}
+
+// readinessGauge reads discovery_readiness_status for status
+func readinessGauge(t *testing.T, status string) float64 {
+	t.Helper()
+	families, err := prometheus.DefaultGatherer.Gather()
+	if err != nil {
+		t.Fatalf("Gather: %v", err)
+	}
+	for _, family := range families {
+		if family.GetName() != "discovery_readiness_status" {
+			continue
+		}
+		for _, m := range family.GetMetric() {
+			if labelMap(m)["status"] == status {
+				return m.GetGauge().GetValue()
+			}
+		}
+	}
+	t.Fatalf("no discovery_readiness_status{status=%q}", status)
+	return 0
+}
@@ -0,0 +1,96 @@
+package tests
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/org/llm-marketplace/pkg/marketplace"
+	"github.com/org/llm-marketplace/services/discovery/internal/config"
+	"github.com/org/llm-marketplace/services/discovery/internal/elasticsearch"
+	"github.com/org/llm-marketplace/services/discovery/internal/search"
+)
+
+var regionFixtures = map[string]*elasticsearch.ServiceDocument{
+	"chat-us": {
+		ID: "chat-us", Name: "US Chat", Category: "chat", Status: "active",
+		ServingRegions: []elasticsearch.ServingRegion{{Region: "us-east-1", MedianLatencyMS: 20}},
+	},
+	"chat-eu-far": {
+		ID: "chat-eu-far", Name: "Far Chat", Category: "chat", Status: "active",
+		ServingRegions: []elasticsearch.ServingRegion{{Region: "us-east-1", MedianLatencyMS: 30}, {Region: "eu-west-1", MedianLatencyMS: 240}},
+	},
+	"chat-eu-near": {
+		ID: "chat-eu-near", Name: "Near Chat", Category: "chat", Status: "active",
+		ServingRegions: []elasticsearch.ServingRegion{{Region: "eu-west-1", MedianLatencyMS: 15}},
+	},
+}
+
+func TestSearchRanksByLatencyFromCallerRegion(t *testing.T) {
+	svc := newSearchService(t, &fakeElasticsearch{docs: regionFixtures}, func(c *config.Config) {
+		c.Search.RegionRanking = config.RegionRankingConfig{Enabled: true, LatencyBoost: 0.3, ReferenceLatency: 300 * time.Millisecond}
+	})
+
+	resp, err := svc.Search(context.Background(), &search.SearchRequest{Query: "chat", Region: "eu-west-1", Pagination: search.PaginationRequest{PageSize: 10}})
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	var ids []string
+	factors := map[string]float64{}
+	for _, r := range resp.Results {
+		ids = append(ids, r.Service.ID)
+		factors[r.Service.ID] = r.MatchDetails.RegionFactor
+	}
+	// The nearest service gains 0.3 x (1 - 15/300), the far one 0.3 x (1 - 240/300)
+	if strings.Join(ids, ",") != "chat-eu-near,chat-eu-far,chat-us" {
+		t.Errorf("ranked %v, want the nearest in eu-west-1 first", ids)
+	}
+	if math.Abs(factors["chat-eu-near"]-1.285) > 1e-9 || math.Abs(factors["chat-eu-far"]-1.06) > 1e-9 || factors["chat-us"] != 0 {
+		t.Errorf("region factors = %v", factors)
+	}
+
+	// Without the caller's region, nothing is re-ranked
+	resp, err = svc.Search(context.Background(), &search.SearchRequest{Query: "chat", Pagination: search.PaginationRequest{PageSize: 10}})
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	for _, r := range resp.Results {
+		if r.MatchDetails.RegionFactor != 0 {
+			t.Errorf("%s has region factor %v without a region", r.Service.ID, r.MatchDetails.RegionFactor)
+		}
+	}
+}
+
+func TestSearchFiltersOnServingRegion(t *testing.T) {
+	es := &fakeElasticsearch{}
+	router := newEntitlementRouter(t, es)
+
+	if w := apiRequest(router, http.MethodGet, "/api/v1/search?q=chat&serving_region=EU-West-1&region=eu-west-1", "", nil); w.Code != http.StatusOK {
+		t.Fatalf("search: status %d, body %s", w.Code, w.Body.String())
+	}
+	if body := es.searches[len(es.searches)-1]; !strings.Contains(body, `{"term":{"serving_regions.region":"eu-west-1"}}`) {
+		t.Errorf("search body %s, want the serving region filter", body)
+	}
+}
+
+func TestCatalogIndexesServingRegions(t *testing.T) {
+	fake := newFakeCatalog()
+	consumer := newCatalogConsumer(fake)
+
+	err := consumer.Apply(context.Background(), catalogEvent(1, marketplace.StatusActive, func(e *marketplace.CatalogEvent) {
+		e.Service.ServingRegions = []marketplace.ServingRegion{{Region: "us-east-1", MedianLatencyMS: 40}}
+	}))
+	if err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	doc := fake.docs["3f1c6a52-8a7e-4b55-9d0e-1f2a3b4c5d6e"]
+	if ms, ok := doc.RegionLatency("us-east-1"); !ok || ms != 40 {
+		t.Errorf("indexed us-east-1 at %d ms, %v; want 40", ms, ok)
+	}
+	if desc := doc.Descriptor(); len(desc.ServingRegions) != 1 {
+		t.Errorf("descriptor = %+v, want the serving regions for policy checks", desc)
+	}
+}
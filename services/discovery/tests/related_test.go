@@ -0,0 +1,89 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/org/llm-marketplace/services/discovery/internal/analytics"
+	"github.com/org/llm-marketplace/services/discovery/internal/config"
+)
+
+func TestSessionRelatesSearches(t *testing.T) {
+	start := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	window := 30 * time.Minute
+	var session analytics.Session
+
+	steps := []struct {
+		query      string
+		categories []string
+		at         time.Duration
+		queries    []analytics.Pair
+		cats       []analytics.Pair
+	}{
+		{"translation", []string{"nlp", "translation"}, 0, nil, []analytics.Pair{{A: "nlp", B: "translation"}}},
+		{"speech to text", []string{"audio"}, 10 * time.Minute, []analytics.Pair{{A: "speech to text", B: "translation"}}, []analytics.Pair{{A: "audio", B: "nlp"}, {A: "audio", B: "translation"}}},
+		// Repeats don't pair again, and searches that found nothing only bring their categories
+		{"translation", []string{"nlp"}, 20 * time.Minute, nil, nil},
+		{"", []string{"vision"}, 25 * time.Minute, nil, []analytics.Pair{{A: "nlp", B: "vision"}, {A: "translation", B: "vision"}, {A: "audio", B: "vision"}}},
+		// A search after the window starts a new session
+		{"chat", []string{"nlp"}, 56 * time.Minute, nil, nil},
+		{"summarization", nil, 60 * time.Minute, []analytics.Pair{{A: "chat", B: "summarization"}}, nil},
+	}
+	for i, step := range steps {
+		queries, cats := session.Observe(step.query, step.categories, start.Add(step.at), window)
+		if !reflect.DeepEqual(queries, step.queries) || !reflect.DeepEqual(cats, step.cats) {
+			t.Errorf("search %d (%q): pairs %v %v, want %v %v", i+1, step.query, queries, cats, step.queries, step.cats)
+		}
+	}
+}
+
+func TestRelatedEndpoints(t *testing.T) {
+	redis, addr := newFakeRedis(t)
+	redis.hashes["related:categories"] = map[string]string{
+		"nlp": `[{"name":"translation","count":12},{"name":"audio","count":4}]`,
+	}
+	redis.hashes["related:queries"] = map[string]string{
+		"speech to text": `[{"name":"transcription","count":7}]`,
+	}
+	router := newAPIRouter(t, &fakeElasticsearch{}, addr, func(*config.Config) {})
+
+	type response struct {
+		Category string                  `json:"category"`
+		Query    string                  `json:"query"`
+		Related  []analytics.RelatedItem `json:"related"`
+	}
+	for _, tt := range []struct {
+		path string
+		want response
+	}{
+		{"/api/v1/categories/nlp/related?limit=1", response{Category: "nlp", Related: []analytics.RelatedItem{{Name: "translation", Count: 12}}}},
+		{"/api/v1/categories/vision/related", response{Category: "vision", Related: []analytics.RelatedItem{}}},
+		{"/api/v1/queries/related?q=Speech%20%20To%20Text", response{Query: "speech to text", Related: []analytics.RelatedItem{{Name: "transcription", Count: 7}}}},
+	} {
+		w := apiRequest(router, http.MethodGet, tt.path, "", nil)
+		var got response
+		if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil || w.Code != http.StatusOK {
+			t.Fatalf("GET %s: status %d, body %s", tt.path, w.Code, w.Body.String())
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("GET %s = %+v, want %+v", tt.path, got, tt.want)
+		}
+	}
+
+	if w := apiRequest(router, http.MethodGet, "/api/v1/queries/related", "", nil); w.Code != http.StatusBadRequest {
+		t.Errorf("related queries without q: status %d, want 400", w.Code)
+	}
+}
+
+func TestRelatedConfigValidation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeConfig(t, path, "session_window: 30m", "session_window: 0s")
+	if _, err := config.Load(path); err == nil || !strings.Contains(err.Error(), "related session_window") {
+		t.Errorf("Load error = %v", err)
+	}
+}
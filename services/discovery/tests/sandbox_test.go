@@ -0,0 +1,186 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	goredis "github.com/go-redis/redis/v8"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+
+	"github.com/org/llm-marketplace/services/discovery/internal/config"
+	"github.com/org/llm-marketplace/services/discovery/internal/elasticsearch"
+	"github.com/org/llm-marketplace/services/discovery/internal/postgres"
+	"github.com/org/llm-marketplace/services/discovery/internal/redis"
+	"github.com/org/llm-marketplace/services/discovery/internal/sandbox"
+	"github.com/org/llm-marketplace/services/discovery/internal/search"
+)
+
+func startSandbox(t *testing.T) *config.Config {
+	t.Helper()
+	cfg, err := config.Load("")
+	if err != nil {
+		t.Fatalf("failed to load default config: %v", err)
+	}
+	sb, err := sandbox.Start(context.Background(), cfg, sandbox.Options{Seed: 1, Services: 100}, zap.NewNop())
+	if err != nil {
+		t.Fatalf("failed to start sandbox: %v", err)
+	}
+	t.Cleanup(func() { sb.Close() })
+	return cfg
+}
+
+// sandboxClients are connections to a sandbox, closed when the test ends
+type sandboxClients struct {
+	redis    *goredis.Client
+	postgres *postgres.Pool
+	es       *elasticsearch.Client
+}
+
+// connectSandbox connects to the sandbox cfg was pointed at by startSandbox
+func connectSandbox(t *testing.T, cfg *config.Config) *sandboxClients {
+	t.Helper()
+	redisClient, err := redis.NewClient(cfg.Redis, nil)
+	if err != nil {
+		t.Fatalf("failed to connect to sandbox redis: %v", err)
+	}
+	t.Cleanup(func() { redisClient.Close() })
+	pgPool, err := postgres.NewPool(cfg.Postgres, nil)
+	if err != nil {
+		t.Fatalf("failed to connect to sandbox postgres: %v", err)
+	}
+	t.Cleanup(pgPool.Close)
+	esClient, err := elasticsearch.NewClient(cfg.Elasticsearch, nil)
+	if err != nil {
+		t.Fatalf("failed to connect to sandbox elasticsearch: %v", err)
+	}
+	return &sandboxClients{redis: redisClient, postgres: pgPool, es: esClient}
+}
+
+// newSandboxSearch returns a search service over the sandbox, configured by
+// cfg as it stands
+func newSandboxSearch(t *testing.T, cfg *config.Config) (*search.Service, *sandboxClients) {
+	t.Helper()
+	clients := connectSandbox(t, cfg)
+	return search.NewService(clients.es, clients.redis, clients.postgres, cfg, zap.NewNop(), testMetrics()), clients
+}
+
+func TestSandboxServesSeededSearch(t *testing.T) {
+	cfg := startSandbox(t)
+	ctx := context.Background()
+
+	svc, clients := newSandboxSearch(t, cfg)
+	resp, err := svc.Search(ctx, &search.SearchRequest{Query: "code review"})
+	if err != nil {
+		t.Fatalf("search failed: %v", err)
+	}
+	if resp.Total == 0 || len(resp.Results) == 0 {
+		t.Fatal("search of the seeded catalog found nothing")
+	}
+	if name := resp.Results[0].Service.Name; !strings.Contains(strings.ToLower(name), "code") {
+		t.Errorf("top result %q doesn't match the query", name)
+	}
+	if len(resp.Results[0].Service.Embedding) != 0 {
+		t.Error("embedding returned without being requested")
+	}
+
+	doc, err := clients.es.Get(ctx, resp.Results[0].Service.ID)
+	if err != nil {
+		t.Fatalf("get failed: %v", err)
+	}
+	if doc.Name != resp.Results[0].Service.Name {
+		t.Errorf("got %q, searched %q", doc.Name, resp.Results[0].Service.Name)
+	}
+
+	categories, err := svc.GetCategories(ctx)
+	if err != nil {
+		t.Fatalf("categories failed: %v", err)
+	}
+	total := 0
+	for _, c := range categories {
+		total += c.Count
+	}
+	if total == 0 || total > 100 {
+		t.Errorf("categories count %d of 100 items", total)
+	}
+}
+
+func TestSandboxRedisAndPostgres(t *testing.T) {
+	cfg := startSandbox(t)
+	ctx := context.Background()
+
+	clients := connectSandbox(t, cfg)
+	redisClient, pgPool := clients.redis, clients.postgres
+
+	if err := redisClient.Set(ctx, "k", "v", time.Minute).Err(); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := redisClient.Get(ctx, "k").Result(); err != nil || v != "v" {
+		t.Errorf("GET k = %q, %v", v, err)
+	}
+	if ok, _ := redisClient.SetNX(ctx, "k", "w", 0).Result(); ok {
+		t.Error("SETNX replaced an existing key")
+	}
+	if err := redisClient.Set(ctx, "short", "v", time.Millisecond).Err(); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(5 * time.Millisecond)
+	if _, err := redisClient.Get(ctx, "short").Result(); err == nil {
+		t.Error("key outlived its TTL")
+	}
+	if _, err := redisClient.TxPipelined(ctx, func(p goredis.Pipeliner) error {
+		p.HSet(ctx, "h", "a", 1)
+		p.HIncrBy(ctx, "h", "a", 2)
+		return nil
+	}); err != nil {
+		t.Fatalf("transaction failed: %v", err)
+	}
+	if v, _ := redisClient.HGet(ctx, "h", "a").Result(); v != "3" {
+		t.Errorf("HGET h a = %q, want 3", v)
+	}
+
+	if _, err := pgPool.Exec(ctx, `INSERT INTO services (id, name) VALUES ($1, $2)`, "a", "b"); err != nil {
+		t.Errorf("insert failed: %v", err)
+	}
+	var name string
+	err := pgPool.QueryRow(ctx, `SELECT name FROM services WHERE id = $1`, "a").Scan(&name)
+	if !errors.Is(err, pgx.ErrNoRows) {
+		t.Errorf("query returned %v, want no rows", err)
+	}
+}
+
+func TestReleaseLockKeepsAnotherHoldersLock(t *testing.T) {
+	cfg := startSandbox(t)
+	client := connectSandbox(t, cfg).redis
+	ctx := context.Background()
+
+	mine, err := redis.LockToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	theirs, _ := redis.LockToken()
+	if mine == theirs || len(mine) != 32 {
+		t.Fatalf("tokens %q and %q, want distinct random tokens", mine, theirs)
+	}
+
+	// Our lock expired and another holder took it: releasing ours leaves theirs
+	if err := client.Set(ctx, "lock", theirs, time.Minute).Err(); err != nil {
+		t.Fatal(err)
+	}
+	if err := redis.ReleaseLock(ctx, client, "lock", mine); err != nil {
+		t.Fatalf("release: %v", err)
+	}
+	if v, err := client.Get(ctx, "lock").Result(); err != nil || v != theirs {
+		t.Errorf("lock = %q, %v after releasing another token; want it kept", v, err)
+	}
+
+	if err := redis.ReleaseLock(ctx, client, "lock", theirs); err != nil {
+		t.Fatalf("release: %v", err)
+	}
+	if n, _ := client.Exists(ctx, "lock").Result(); n != 0 {
+		t.Error("lock kept after its holder released it")
+	}
+}
@@ -0,0 +1,349 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/org/llm-marketplace/pkg/marketplace"
+
+	"github.com/org/llm-marketplace/services/discovery/internal/config"
+	"github.com/org/llm-marketplace/services/discovery/internal/elasticsearch"
+)
+
+func TestSummarySearchModesSkipHits(t *testing.T) {
+	cases := []struct {
+		name     string
+		path     string
+		wantAggs bool
+	}{
+		{"size zero", "/api/v1/search?q=model&size=0", true},
+		{"aggregations only", "/api/v1/search?q=model&aggregations_only=true", true},
+		{"count only", "/api/v1/search?q=model&count_only=true", false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			es := &fakeElasticsearch{}
+			router := newEntitlementRouter(t, es)
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tc.path, nil))
+			if w.Code != http.StatusOK {
+				t.Fatalf("status %d: %s", w.Code, w.Body.String())
+			}
+
+			var resp struct {
+				Results []json.RawMessage `json:"results"`
+				Total   int               `json:"total"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+			if len(resp.Results) != 0 || resp.Total != len(entitlementFixtures) {
+				t.Errorf("got %d results and total %d, want none and %d", len(resp.Results), resp.Total, len(entitlementFixtures))
+			}
+
+			es.mu.Lock()
+			defer es.mu.Unlock()
+			if len(es.searches) != 1 {
+				t.Fatalf("sent %d searches, want 1", len(es.searches))
+			}
+			body := es.searches[0]
+			if !strings.Contains(body, `"size":0`) || !strings.Contains(body, `"_source":false`) {
+				t.Errorf("search requested hits: %s", body)
+			}
+			if got := strings.Contains(body, `"aggs"`); got != tc.wantAggs {
+				t.Errorf("aggregations requested = %v, want %v: %s", got, tc.wantAggs, body)
+			}
+			if !strings.Contains(body, `"access.visibility"`) {
+				t.Errorf("search sent without the entitlement filter: %s", body)
+			}
+		})
+	}
+}
+
+func TestSearchFacetSubset(t *testing.T) {
+	es := &fakeElasticsearch{}
+	router := newEntitlementRouter(t, es)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/search?q=model&facets=pricing_models,avg_rating", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body.String())
+	}
+
+	es.mu.Lock()
+	defer es.mu.Unlock()
+	var body struct {
+		Aggs map[string]json.RawMessage `json:"aggs"`
+	}
+	if err := json.Unmarshal([]byte(es.searches[0]), &body); err != nil {
+		t.Fatalf("decode search: %v", err)
+	}
+	if len(body.Aggs) != 2 || body.Aggs["pricing_models"] == nil || body.Aggs["avg_rating"] == nil {
+		t.Errorf("aggs = %v, want pricing_models and avg_rating", body.Aggs)
+	}
+}
+
+func TestSearchRejectsUnknownFacet(t *testing.T) {
+	router := newEntitlementRouter(t, &fakeElasticsearch{})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/search?q=model&facets=colour", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("status %d, want 400: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestPriceRangeFacetLabels(t *testing.T) {
+	es := &fakeElasticsearch{}
+	router := newEntitlementRouter(t, es)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/search?q=model&size=0&facets=price_ranges", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body.String())
+	}
+
+	var resp struct {
+		Aggregations struct {
+			PriceRanges struct {
+				Buckets []struct {
+					Key   string `json:"key"`
+					Label string `json:"label"`
+				} `json:"buckets"`
+			} `json:"price_ranges"`
+		} `json:"aggregations"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	buckets := resp.Aggregations.PriceRanges.Buckets
+	if len(buckets) != 1 || buckets[0].Label != "Free" {
+		t.Errorf("buckets = %+v, want the free bucket labelled", buckets)
+	}
+
+	// The default price facet is a handful of ranges, not a fine histogram
+	es.mu.Lock()
+	defer es.mu.Unlock()
+	var body struct {
+		Aggs struct {
+			PriceRanges struct {
+				Range struct {
+					Ranges []json.RawMessage `json:"ranges"`
+				} `json:"range"`
+			} `json:"price_ranges"`
+		} `json:"aggs"`
+	}
+	if err := json.Unmarshal([]byte(es.searches[0]), &body); err != nil {
+		t.Fatalf("decode search: %v", err)
+	}
+	if n := len(body.Aggs.PriceRanges.Range.Ranges); n != 5 {
+		t.Errorf("price_ranges has %d ranges, want 5", n)
+	}
+}
+
+func TestRepeatedlyReportedServicesAreDemoted(t *testing.T) {
+	es := &fakeElasticsearch{docs: map[string]*elasticsearch.ServiceDocument{
+		"reported-svc": {ID: "reported-svc", Name: "Reported model", Status: elasticsearch.StatusActive, UpheldReports: 2},
+		"once-svc":     {ID: "once-svc", Name: "Once reported model", Status: elasticsearch.StatusActive, UpheldReports: 1},
+		"clean-svc":    {ID: "clean-svc", Name: "Clean model", Status: elasticsearch.StatusActive},
+	}}
+	router := newEntitlementRouter(t, es)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/search?q=model", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body.String())
+	}
+
+	var resp struct {
+		Results []struct {
+			Score        float64             `json:"score"`
+			Service      struct{ ID string } `json:"service"`
+			MatchDetails struct {
+				Demoted bool `json:"demoted"`
+			} `json:"match_details"`
+		} `json:"results"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if len(resp.Results) != 3 {
+		t.Fatalf("got %d results, want 3", len(resp.Results))
+	}
+	// Every fixture scores the same until demotion halves the reported one
+	last := resp.Results[2]
+	if last.Service.ID != "reported-svc" || !last.MatchDetails.Demoted || last.Score != resp.Results[0].Score*0.5 {
+		t.Errorf("results = %+v, want reported-svc last at half the score", resp.Results)
+	}
+	for _, r := range resp.Results[:2] {
+		if r.MatchDetails.Demoted {
+			t.Errorf("%s demoted below the threshold", r.Service.ID)
+		}
+	}
+}
+
+func TestLapsingComplianceLowersRanking(t *testing.T) {
+	certified := func(id string, expiries ...elasticsearch.ComplianceExpiry) *elasticsearch.ServiceDocument {
+		doc := &elasticsearch.ServiceDocument{ID: id, Name: id + " model", Status: elasticsearch.StatusActive, Expiries: expiries}
+		doc.Compliance.Certifications = []string{"SOC2"}
+		return doc
+	}
+	lapsing := time.Now().AddDate(0, 0, 10)
+	es := &fakeElasticsearch{docs: map[string]*elasticsearch.ServiceDocument{
+		"current":  certified("current"),
+		"expiring": certified("expiring", elasticsearch.ComplianceExpiry{Kind: marketplace.ExpiryCertification, Name: "SOC2", Status: marketplace.ExpiryExpiring, ExpiresAt: lapsing}),
+		"expired":  certified("expired", elasticsearch.ComplianceExpiry{Kind: marketplace.ExpiryCertification, Name: "SOC2", Status: marketplace.ExpiryExpired, ExpiresAt: lapsing}),
+	}}
+	router := newAPIRouter(t, es, "127.0.0.1:1", func(cfg *config.Config) {
+		cfg.Search.RankingWeights.Compliance = 1
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/search?q=model", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Results []struct {
+			Service      struct{ ID string } `json:"service"`
+			MatchDetails struct {
+				ComplianceScore float64 `json:"compliance_score"`
+			} `json:"match_details"`
+		} `json:"results"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if len(resp.Results) != 3 {
+		t.Fatalf("got %d results, want 3", len(resp.Results))
+	}
+	var order []string
+	for _, r := range resp.Results {
+		order = append(order, r.Service.ID)
+	}
+	if !slices.Equal(order, []string{"current", "expiring", "expired"}) {
+		t.Errorf("order = %v, want lapsing certifications ranked lower", order)
+	}
+	if current, expired := resp.Results[0].MatchDetails.ComplianceScore, resp.Results[2].MatchDetails.ComplianceScore; expired >= current-0.1 {
+		t.Errorf("compliance score %v with an expired certification, %v without", expired, current)
+	}
+}
+
+func benchmarked(id string, price, quality, latency float64) *elasticsearch.ServiceDocument {
+	doc := &elasticsearch.ServiceDocument{ID: id, Name: id, Category: "text-generation", Status: elasticsearch.StatusActive}
+	doc.Pricing.Rate = price
+	doc.Metrics.AvgLatencyMS = latency
+	if quality > 0 {
+		doc.Metrics.Quality = map[string]float64{"text-generation": quality}
+	}
+	return doc
+}
+
+func TestParetoSearchReturnsFrontier(t *testing.T) {
+	es := &fakeElasticsearch{docs: map[string]*elasticsearch.ServiceDocument{
+		"cheap":      benchmarked("cheap", 0.001, 0.7, 900),
+		"balanced":   benchmarked("balanced", 0.002, 0.85, 400),
+		"premium":    benchmarked("premium", 0.01, 0.95, 300),
+		"outclassed": benchmarked("outclassed", 0.003, 0.8, 500),
+		"weak":       benchmarked("weak", 0.0005, 0.5, 200),
+		"unbenched":  benchmarked("unbenched", 0.0001, 0, 100),
+	}}
+	router := newEntitlementRouter(t, es)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/search?mode=pareto&category=text-generation&min_quality=0.6", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body.String())
+	}
+
+	var resp struct {
+		Results []struct {
+			Service struct{ ID string } `json:"service"`
+			Pareto  struct {
+				Price   float64 `json:"price"`
+				Quality float64 `json:"quality"`
+			} `json:"pareto"`
+		} `json:"results"`
+		Total  int `json:"total"`
+		Pareto struct {
+			Candidates int `json:"candidates"`
+			Excluded   int `json:"excluded"`
+			Dominated  []struct {
+				ServiceID   string   `json:"service_id"`
+				DominatedBy []string `json:"dominated_by"`
+				Reason      string   `json:"reason"`
+			} `json:"dominated"`
+		} `json:"pareto"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	var ids []string
+	for _, r := range resp.Results {
+		ids = append(ids, r.Service.ID)
+	}
+	if strings.Join(ids, ",") != "cheap,balanced,premium" || resp.Total != 3 || resp.Results[1].Pareto.Quality != 0.85 {
+		t.Errorf("frontier = %v (total %d), want cheap, balanced and premium, cheapest first", ids, resp.Total)
+	}
+	if resp.Pareto.Candidates != 6 || resp.Pareto.Excluded != 2 || len(resp.Pareto.Dominated) != 1 {
+		t.Fatalf("summary = %+v, want 6 candidates, 2 excluded and 1 dominated", resp.Pareto)
+	}
+	dominated := resp.Pareto.Dominated[0]
+	want := "Dominated by balanced: cheaper (0.002 vs 0.003), higher quality (0.85 vs 0.8), faster (400 vs 500 ms)"
+	if dominated.ServiceID != "outclassed" || len(dominated.DominatedBy) != 1 || dominated.Reason != want {
+		t.Errorf("dominated = %+v, want outclassed beaten by balanced", dominated)
+	}
+
+	// Every candidate in the category is fetched, whatever the page size
+	es.mu.Lock()
+	body := es.searches[0]
+	es.mu.Unlock()
+	if !strings.Contains(body, `"size":100`) || !strings.Contains(body, `"category"`) {
+		t.Errorf("candidates not fetched by category: %s", body)
+	}
+}
+
+func TestParetoSearchNeedsCategory(t *testing.T) {
+	router := newEntitlementRouter(t, &fakeElasticsearch{})
+	for _, path := range []string{
+		"/api/v1/search?mode=pareto",
+		"/api/v1/search?mode=pareto&category=text-generation&min_quality=1.5",
+	} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: status %d, want 400", path, w.Code)
+		}
+	}
+}
+
+func TestSetBenchmark(t *testing.T) {
+	router := newEntitlementRouter(t, &fakeElasticsearch{})
+	cases := []struct {
+		path string
+		body string
+		want int
+	}{
+		{"/api/v1/services/public-svc/benchmarks", `{"category": "translation", "quality": 0.9}`, http.StatusOK},
+		{"/api/v1/services/public-svc/benchmarks", `{"category": "translation", "quality": 1.2}`, http.StatusBadRequest},
+		{"/api/v1/services/public-svc/benchmarks", `{"category": "translation"}`, http.StatusBadRequest},
+		{"/api/v1/services/missing-svc/benchmarks", `{"category": "translation", "quality": 0.9}`, http.StatusNotFound},
+	}
+	for _, tc := range cases {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPut, tc.path, strings.NewReader(tc.body)))
+		if w.Code != tc.want {
+			t.Errorf("%s %s: status %d, want %d: %s", tc.path, tc.body, w.Code, tc.want, w.Body.String())
+		}
+		if tc.want == http.StatusOK && !strings.Contains(w.Body.String(), `"quality":{"translation":0.9}`) {
+			t.Errorf("response = %s, want the recorded quality", w.Body.String())
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/org/llm-marketplace/services/discovery/internal/config"
+	"github.com/org/llm-marketplace/services/discovery/internal/elasticsearch"
+)
+
+func TestElasticsearchUsesCurrentPassword(t *testing.T) {
+	var lastPassword atomic.Value
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, password, _ := r.BasicAuth()
+		lastPassword.Store(password)
+		w.Header().Set("X-Elastic-Product", "Elasticsearch")
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	var password atomic.Value
+	password.Store("first")
+	client, err := elasticsearch.NewClient(config.ElasticsearchConfig{
+		Addresses: []string{server.URL},
+		Username:  "elastic",
+		Password:  "vault:secret/data/discovery#es_password",
+	}, func() string { return password.Load().(string) })
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	if got := lastPassword.Load(); got != "first" {
+		t.Errorf("password = %v, want first", got)
+	}
+
+	password.Store("rotated")
+	if err := client.Ping(); err != nil {
+		t.Fatalf("Ping: %v", err)
+	}
+	if got := lastPassword.Load(); got != "rotated" {
+		t.Errorf("password = %v after rotation, want rotated", got)
+	}
+}
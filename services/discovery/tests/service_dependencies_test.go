@@ -0,0 +1,97 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/org/llm-marketplace/services/discovery/internal/elasticsearch"
+	"github.com/org/llm-marketplace/services/discovery/internal/search"
+)
+
+var dependencyFixtures = map[string]*elasticsearch.ServiceDocument{
+	"rag": {
+		ID: "rag", Name: "RAG", Status: elasticsearch.StatusActive,
+		Version: &elasticsearch.VersionInfo{Number: "2.0.0"},
+		Dependencies: []elasticsearch.Dependency{
+			{ServiceID: "embeddings", Role: "embedding"},
+			{ServiceID: "vectors", Role: "vector-store"},
+			{ServiceID: "gone"},
+		},
+	},
+	"embeddings": {ID: "embeddings", Name: "Embeddings", Status: elasticsearch.StatusActive},
+	"vectors": {
+		ID: "vectors", Name: "Acme vector DB", Status: elasticsearch.StatusActive,
+		Access: &elasticsearch.AccessInfo{Visibility: elasticsearch.VisibilityTenant, OwnerTenant: "acme"},
+	},
+	"chatbot": {
+		ID: "chatbot", Name: "Chatbot", Status: elasticsearch.StatusActive,
+		Dependencies: []elasticsearch.Dependency{{ServiceID: "rag", Role: "retrieval"}},
+	},
+	"acme-assistant": {
+		ID: "acme-assistant", Name: "Acme assistant", Status: elasticsearch.StatusActive,
+		Access:       &elasticsearch.AccessInfo{Visibility: elasticsearch.VisibilityTenant, OwnerTenant: "acme"},
+		Dependencies: []elasticsearch.Dependency{{ServiceID: "rag"}},
+	},
+}
+
+func getDependencies(t *testing.T, router http.Handler, id, tenant string) (int, *search.DependencyGraph) {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/services/"+id+"/dependencies", nil)
+	if tenant != "" {
+		req.Header.Set("X-Tenant-ID", tenant)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var graph search.DependencyGraph
+	if w.Code == http.StatusOK {
+		if err := json.Unmarshal(w.Body.Bytes(), &graph); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+	}
+	return w.Code, &graph
+}
+
+func TestServiceDependencies(t *testing.T) {
+	router := newEntitlementRouter(t, &fakeElasticsearch{docs: dependencyFixtures})
+
+	code, graph := getDependencies(t, router, "rag", "")
+	if code != http.StatusOK {
+		t.Fatalf("status = %d", code)
+	}
+	want := []search.DependencySummary{
+		{ID: "embeddings", Name: "Embeddings", Status: elasticsearch.StatusActive, Role: "embedding", URL: "/api/v1/services/embeddings"},
+		{ID: "vectors", Role: "vector-store", Missing: true}, // Another tenant's
+		{ID: "gone", Missing: true},
+	}
+	if len(graph.Dependencies) != len(want) {
+		t.Fatalf("dependencies = %+v", graph.Dependencies)
+	}
+	for i := range want {
+		if graph.Dependencies[i] != want[i] {
+			t.Errorf("dependency %d = %+v, want %+v", i, graph.Dependencies[i], want[i])
+		}
+	}
+	if len(graph.Dependents) != 1 || graph.Dependents[0].ID != "chatbot" || graph.Dependents[0].Role != "retrieval" {
+		t.Errorf("dependents = %+v, want only the chatbot", graph.Dependents)
+	}
+
+	// The tenant sees its own services on both sides
+	_, graph = getDependencies(t, router, "rag", "acme")
+	if d := graph.Dependencies[1]; d.Missing || d.Name != "Acme vector DB" {
+		t.Errorf("tenant's dependency = %+v", d)
+	}
+	if len(graph.Dependents) != 2 {
+		t.Errorf("tenant's dependents = %+v", graph.Dependents)
+	}
+
+	_, graph = getDependencies(t, router, "embeddings", "")
+	if len(graph.Dependencies) != 0 || len(graph.Dependents) != 1 || graph.Dependents[0].ID != "rag" || graph.Dependents[0].Version != "2.0.0" {
+		t.Errorf("embeddings graph = %+v", graph)
+	}
+
+	if code, _ := getDependencies(t, router, "vectors", "globex"); code != http.StatusNotFound {
+		t.Errorf("another tenant's service = %d, want 404", code)
+	}
+}
@@ -0,0 +1,314 @@
+package tests
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/org/llm-marketplace/services/discovery/internal/config"
+	"github.com/org/llm-marketplace/services/discovery/internal/elasticsearch"
+	"github.com/org/llm-marketplace/services/discovery/internal/redis"
+	"github.com/org/llm-marketplace/services/discovery/internal/search"
+)
+
+var sessionFixtures = map[string]*elasticsearch.ServiceDocument{
+	"chat-acme": {
+		ID: "chat-acme", Name: "Acme Chat", Category: "chat", Status: "active",
+		Provider: elasticsearch.ProviderInfo{ID: "acme", Name: "Acme"},
+	},
+	"chat-globex": {
+		ID: "chat-globex", Name: "Globex Chat", Category: "chat", Status: "active",
+		Provider: elasticsearch.ProviderInfo{ID: "globex", Name: "Globex"},
+	},
+	"translate-acme": {
+		ID: "translate-acme", Name: "Acme Translate", Category: "translation", Status: "active",
+		Provider: elasticsearch.ProviderInfo{ID: "acme", Name: "Acme"},
+	},
+}
+
+// fakeRedis serves the string, hash, list, counter and TTL commands session
+// signals, cached features, quotas, job records and SLA samples use, and the
+// lock release script. Keys other commands would write are absent, and the
+// rest are acknowledged.
+type fakeRedis struct {
+	mu       sync.Mutex
+	strings  map[string]string
+	hashes   map[string]map[string]string
+	lists    map[string][]string
+	counters map[string]int64
+	ttls     map[string]time.Duration
+}
+
+func newFakeRedis(t *testing.T) (*fakeRedis, string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	r := &fakeRedis{strings: map[string]string{}, hashes: map[string]map[string]string{}, lists: map[string][]string{}, counters: map[string]int64{}, ttls: map[string]time.Duration{}}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go r.serve(conn)
+		}
+	}()
+	return r, ln.Addr().String()
+}
+
+func (r *fakeRedis) serve(conn net.Conn) {
+	defer conn.Close()
+	in := bufio.NewReader(conn)
+	var queued [][]string
+	inTx := false
+	for {
+		cmd, err := readCommand(in)
+		if err != nil {
+			return
+		}
+		switch name := strings.ToUpper(cmd[0]); {
+		case name == "MULTI":
+			inTx, queued = true, nil
+			io.WriteString(conn, "+OK\r\n")
+		case name == "EXEC":
+			fmt.Fprintf(conn, "*%d\r\n", len(queued))
+			for _, c := range queued {
+				io.WriteString(conn, r.exec(c))
+			}
+			inTx = false
+		case inTx:
+			queued = append(queued, cmd)
+			io.WriteString(conn, "+QUEUED\r\n")
+		default:
+			io.WriteString(conn, r.exec(cmd))
+		}
+	}
+}
+
+func (r *fakeRedis) exec(cmd []string) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	switch strings.ToUpper(cmd[0]) {
+	case "GET":
+		if value, ok := r.strings[cmd[1]]; ok {
+			return fmt.Sprintf("$%d\r\n%s\r\n", len(value), value)
+		}
+		n, ok := r.counters[cmd[1]]
+		if !ok {
+			return "$-1\r\n"
+		}
+		value := strconv.FormatInt(n, 10)
+		return fmt.Sprintf("$%d\r\n%s\r\n", len(value), value)
+	case "INCR", "DECR":
+		if strings.ToUpper(cmd[0]) == "INCR" {
+			r.counters[cmd[1]]++
+		} else {
+			r.counters[cmd[1]]--
+		}
+		return fmt.Sprintf(":%d\r\n", r.counters[cmd[1]])
+	case "HINCRBY":
+		n, _ := strconv.ParseInt(cmd[3], 10, 64)
+		if r.hashes[cmd[1]] == nil {
+			r.hashes[cmd[1]] = map[string]string{}
+		}
+		current, _ := strconv.ParseInt(r.hashes[cmd[1]][cmd[2]], 10, 64)
+		r.hashes[cmd[1]][cmd[2]] = strconv.FormatInt(current+n, 10)
+		return fmt.Sprintf(":%d\r\n", current+n)
+	case "HSET":
+		if r.hashes[cmd[1]] == nil {
+			r.hashes[cmd[1]] = map[string]string{}
+		}
+		for i := 2; i+1 < len(cmd); i += 2 {
+			r.hashes[cmd[1]][cmd[i]] = cmd[i+1]
+		}
+		return fmt.Sprintf(":%d\r\n", (len(cmd)-2)/2)
+	case "HGET":
+		value, ok := r.hashes[cmd[1]][cmd[2]]
+		if !ok {
+			return "$-1\r\n"
+		}
+		return fmt.Sprintf("$%d\r\n%s\r\n", len(value), value)
+	case "HDEL":
+		deleted := 0
+		for _, field := range cmd[2:] {
+			if _, ok := r.hashes[cmd[1]][field]; ok {
+				delete(r.hashes[cmd[1]], field)
+				deleted++
+			}
+		}
+		return fmt.Sprintf(":%d\r\n", deleted)
+	case "HGETALL":
+		fields := r.hashes[cmd[1]]
+		reply := fmt.Sprintf("*%d\r\n", 2*len(fields))
+		for field, value := range fields {
+			reply += fmt.Sprintf("$%d\r\n%s\r\n$%d\r\n%s\r\n", len(field), field, len(value), value)
+		}
+		return reply
+	case "LPUSH":
+		for _, value := range cmd[2:] {
+			r.lists[cmd[1]] = append([]string{value}, r.lists[cmd[1]]...)
+		}
+		return fmt.Sprintf(":%d\r\n", len(r.lists[cmd[1]]))
+	case "LRANGE":
+		// The range is ignored; SLA samples are always read whole
+		values := r.lists[cmd[1]]
+		reply := fmt.Sprintf("*%d\r\n", len(values))
+		for _, value := range values {
+			reply += fmt.Sprintf("$%d\r\n%s\r\n", len(value), value)
+		}
+		return reply
+	case "SET":
+		for _, option := range cmd[3:] {
+			if _, exists := r.strings[cmd[1]]; exists && strings.ToUpper(option) == "NX" {
+				return "$-1\r\n"
+			}
+		}
+		r.strings[cmd[1]] = cmd[2]
+		return "+OK\r\n"
+	case "DEL":
+		delete(r.strings, cmd[1])
+		delete(r.hashes, cmd[1])
+		delete(r.lists, cmd[1])
+		delete(r.ttls, cmd[1])
+		return ":1\r\n"
+	case "EVALSHA":
+		return "-NOSCRIPT No matching script. Please use EVAL.\r\n"
+	case "EVAL":
+		// The lock release is the only script run
+		if cmd[1] != redis.ReleaseLockScript {
+			return "-ERR unknown script\r\n"
+		}
+		if value, ok := r.strings[cmd[3]]; !ok || value != cmd[4] {
+			return ":0\r\n"
+		}
+		delete(r.strings, cmd[3])
+		delete(r.ttls, cmd[3])
+		return ":1\r\n"
+	case "EXPIRE":
+		seconds, _ := strconv.Atoi(cmd[2])
+		r.ttls[cmd[1]] = time.Duration(seconds) * time.Second
+		return ":1\r\n"
+	default:
+		return "+OK\r\n"
+	}
+}
+
+// readCommand reads a command sent as an array of bulk strings
+func readCommand(in *bufio.Reader) ([]string, error) {
+	line, err := in.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+	if err != nil {
+		return nil, err
+	}
+	cmd := make([]string, n)
+	for i := range cmd {
+		header, err := in.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		size, err := strconv.Atoi(strings.TrimSpace(header[1:]))
+		if err != nil {
+			return nil, err
+		}
+		// Arguments such as scripts may span lines, so they're read by length
+		arg := make([]byte, size+2)
+		if _, err := io.ReadFull(in, arg); err != nil {
+			return nil, err
+		}
+		cmd[i] = string(arg[:size])
+	}
+	return cmd, nil
+}
+
+var sessionConfig = config.SessionRerankConfig{Enabled: true, TTL: 30 * time.Minute, CategoryBoost: 0.3, DismissFactor: 0.5}
+
+func newSessionSearch(t *testing.T, cfg config.SessionRerankConfig) (*search.Service, *fakeRedis) {
+	t.Helper()
+	redis, addr := newFakeRedis(t)
+	svc := newSearchServiceOn(t, &fakeElasticsearch{docs: sessionFixtures}, addr, func(c *config.Config) {
+		c.Search.Session = cfg
+	})
+	return svc, redis
+}
+
+// rankedIDs is the order of a session's search results
+func rankedIDs(t *testing.T, svc *search.Service, sessionID string) []string {
+	t.Helper()
+	resp, err := svc.Search(context.Background(), &search.SearchRequest{Query: "assistant", SessionID: sessionID, Pagination: search.PaginationRequest{PageSize: 10}})
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	var ids []string
+	for _, r := range resp.Results {
+		ids = append(ids, r.Service.ID)
+	}
+	return ids
+}
+
+func TestSessionRerankingFollowsClicksAndDismissals(t *testing.T) {
+	svc, redis := newSessionSearch(t, sessionConfig)
+	ctx := context.Background()
+
+	if err := svc.RecordSessionClick(ctx, "s1", "translate-acme"); err != nil {
+		t.Fatalf("RecordSessionClick: %v", err)
+	}
+	if ids := rankedIDs(t, svc, "s1"); ids[0] != "translate-acme" {
+		t.Errorf("after a translation click, ranked %v", ids)
+	}
+	redis.mu.Lock()
+	ttl := redis.ttls["session:public:s1"]
+	redis.mu.Unlock()
+	if ttl != 30*time.Minute {
+		t.Errorf("session ttl = %v, want 30m", ttl)
+	}
+
+	if err := svc.RecordSessionDismissal(ctx, "s1", "chat-acme"); err != nil {
+		t.Fatalf("RecordSessionDismissal: %v", err)
+	}
+	ids := rankedIDs(t, svc, "s1")
+	// Acme's translation service keeps a net boost of 1.3 * 0.5 = 0.65,
+	// still below Globex, and its chat service falls to the bottom
+	if strings.Join(ids, ",") != "chat-globex,translate-acme,chat-acme" {
+		t.Errorf("after dismissing Acme, ranked %v", ids)
+	}
+
+	// Another session is unaffected
+	resp, err := svc.Search(ctx, &search.SearchRequest{Query: "assistant", SessionID: "s2", Pagination: search.PaginationRequest{PageSize: 10}})
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	for _, r := range resp.Results {
+		if r.MatchDetails.SessionFactor != 0 {
+			t.Errorf("%s has session factor %v in a fresh session", r.Service.ID, r.MatchDetails.SessionFactor)
+		}
+	}
+}
+
+func TestSessionSignalsIgnoredWhenDisabled(t *testing.T) {
+	svc, redis := newSessionSearch(t, config.SessionRerankConfig{})
+
+	if err := svc.RecordSessionClick(context.Background(), "s1", "translate-acme"); err != nil {
+		t.Fatalf("RecordSessionClick: %v", err)
+	}
+	if err := svc.RecordSessionDismissal(context.Background(), "s1", "chat-acme"); err != nil {
+		t.Fatalf("RecordSessionDismissal: %v", err)
+	}
+	redis.mu.Lock()
+	defer redis.mu.Unlock()
+	if len(redis.hashes) != 0 {
+		t.Errorf("recorded %v with session re-ranking off", redis.hashes)
+	}
+}
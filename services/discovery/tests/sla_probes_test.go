@@ -0,0 +1,74 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/org/llm-marketplace/services/discovery/internal/elasticsearch"
+	"github.com/org/llm-marketplace/services/discovery/internal/sla"
+)
+
+func TestSLAProbesPageThroughServicesAndStayOffInternalAddresses(t *testing.T) {
+	cfg := startSandbox(t)
+	clients := connectSandbox(t, cfg)
+	ctx := context.Background()
+
+	var reached atomic.Int64
+	internal := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) { reached.Add(1) }))
+	defer internal.Close()
+
+	resp, err := clients.es.Search(ctx, map[string]interface{}{
+		"size":  5,
+		"query": map[string]interface{}{"term": map[string]interface{}{"status": elasticsearch.StatusActive}},
+	})
+	if err != nil || len(resp.Hits.Hits) < 5 {
+		t.Fatalf("listing services: %v", err)
+	}
+	var ids []string
+	for _, hit := range resp.Hits.Hits {
+		doc := hit.Source
+		if err := clients.es.UpdateFields(elasticsearch.WithTenant(ctx, doc.TenantID), doc.ID, map[string]interface{}{"endpoint": internal.URL}); err != nil {
+			t.Fatalf("setting endpoint: %v", err)
+		}
+		ids = append(ids, doc.ID)
+	}
+
+	// Two services a page, so the five take three
+	cfg.SLAMonitoring.Enabled = true
+	cfg.SLAMonitoring.ProbeInterval = time.Hour
+	cfg.SLAMonitoring.MaxServices = 2
+	monitor := sla.NewMonitor(clients.es, clients.redis, cfg.SLAMonitoring, zap.NewNop(), testMetrics())
+	probeCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go monitor.Start(probeCtx)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for _, id := range ids {
+		for {
+			health, err := monitor.GetHealth(ctx, id)
+			if err != nil {
+				t.Fatalf("health of %s: %v", id, err)
+			}
+			if health.Samples > 0 {
+				// A loopback endpoint is a failed check, not a request
+				if health.ErrorRate != 1 {
+					t.Errorf("%s: error rate %v, want 1", id, health.ErrorRate)
+				}
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("%s was never probed", id)
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+	}
+	if n := reached.Load(); n > 0 {
+		t.Errorf("probes reached a loopback server %d times", n)
+	}
+}
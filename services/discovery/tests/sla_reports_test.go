@@ -0,0 +1,97 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/org/llm-marketplace/services/discovery/internal/config"
+	"github.com/org/llm-marketplace/services/discovery/internal/elasticsearch"
+)
+
+var slaFixtures = map[string]*elasticsearch.ServiceDocument{
+	"acme-chat": {
+		ID: "acme-chat", Name: "Acme Chat", Status: elasticsearch.StatusActive,
+		Provider: elasticsearch.ProviderInfo{ID: "acme", Name: "Acme"},
+	},
+	"acme-internal": {
+		ID: "acme-internal", Name: "Acme Internal", Status: elasticsearch.StatusActive,
+		Provider: elasticsearch.ProviderInfo{ID: "acme", Name: "Acme"},
+		Access:   &elasticsearch.AccessInfo{Visibility: elasticsearch.VisibilityTenant, OwnerTenant: "acme"},
+	},
+}
+
+// newSLARouter serves the API over slaFixtures and a fake Redis
+func newSLARouter(t *testing.T) *gin.Engine {
+	t.Helper()
+	_, addr := newFakeRedis(t)
+	return newAPIRouter(t, &fakeElasticsearch{docs: slaFixtures}, addr, func(c *config.Config) {
+		c.Entitlements.ProviderHeader = "X-Provider-ID"
+		c.SLAMonitoring = config.SLAMonitoringConfig{WindowSize: 10, ReportMaxAge: time.Hour}
+	})
+}
+
+func TestProviderHealthReportsNeedTheServicesProvider(t *testing.T) {
+	router := newSLARouter(t)
+	report := func(id, body string, header http.Header) int {
+		return apiRequest(router, http.MethodPost, "/api/v1/services/"+id+"/health/reports", body, header).Code
+	}
+	at := func(d time.Duration) string {
+		return fmt.Sprintf(`{"checks": 60, "failures": 1, "reported_at": %q}`, time.Now().Add(d).UTC().Format(time.RFC3339))
+	}
+	acme := http.Header{"X-Provider-Id": {"acme"}}
+
+	for _, tc := range []struct {
+		name   string
+		id     string
+		body   string
+		header http.Header
+		want   int
+	}{
+		{"own provider", "acme-chat", `{"checks": 60, "failures": 1}`, acme, http.StatusAccepted},
+		{"own provider, recent", "acme-chat", at(-10 * time.Minute), acme, http.StatusAccepted},
+		{"other provider", "acme-chat", `{"checks": 60}`, http.Header{"X-Provider-Id": {"globex"}}, http.StatusForbidden},
+		{"consumer", "acme-chat", `{"checks": 60}`, http.Header{"X-Tenant-Id": {"acme"}}, http.StatusForbidden},
+		{"anonymous", "acme-chat", `{"checks": 60}`, nil, http.StatusForbidden},
+		{"unknown service", "missing", `{"checks": 60}`, acme, http.StatusNotFound},
+		{"in the future", "acme-chat", at(time.Hour), acme, http.StatusBadRequest},
+		{"too old", "acme-chat", at(-2 * time.Hour), acme, http.StatusBadRequest},
+		{"more failures than checks", "acme-chat", `{"checks": 1, "failures": 2}`, acme, http.StatusBadRequest},
+	} {
+		if got := report(tc.id, tc.body, tc.header); got != tc.want {
+			t.Errorf("%s: status %d, want %d", tc.name, got, tc.want)
+		}
+	}
+
+	w := apiRequest(router, http.MethodGet, "/api/v1/services/acme-chat/health", "", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("health: status %d, body %s", w.Code, w.Body.String())
+	}
+}
+
+func TestServiceHealthIsOnlyShownForVisibleServices(t *testing.T) {
+	router := newSLARouter(t)
+	health := func(id string, header http.Header) int {
+		return apiRequest(router, http.MethodGet, "/api/v1/services/"+id+"/health", "", header).Code
+	}
+
+	for _, tc := range []struct {
+		name   string
+		id     string
+		header http.Header
+		want   int
+	}{
+		{"public", "acme-chat", nil, http.StatusOK},
+		{"unknown", "missing", nil, http.StatusNotFound},
+		{"restricted, owner tenant", "acme-internal", http.Header{"X-Tenant-Id": {"acme"}}, http.StatusOK},
+		{"restricted, other tenant", "acme-internal", http.Header{"X-Tenant-Id": {"globex"}}, http.StatusNotFound},
+		{"restricted, anonymous", "acme-internal", nil, http.StatusNotFound},
+	} {
+		if got := health(tc.id, tc.header); got != tc.want {
+			t.Errorf("%s: status %d, want %d", tc.name, got, tc.want)
+		}
+	}
+}
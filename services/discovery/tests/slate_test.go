@@ -0,0 +1,194 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	goredis "github.com/go-redis/redis/v8"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+
+	"github.com/org/llm-marketplace/services/discovery/internal/config"
+	"github.com/org/llm-marketplace/services/discovery/internal/postgres"
+	"github.com/org/llm-marketplace/services/discovery/internal/recommendation"
+)
+
+// newSlateService returns a recommendation service whose stores are
+// unreachable, so every placement comes back empty
+func newSlateService(t *testing.T, enabled bool, slate map[string]config.PlacementConfig) *recommendation.Service {
+	t.Helper()
+	redisClient := goredis.NewClient(&goredis.Options{Addr: "127.0.0.1:1", MaxRetries: -1})
+	t.Cleanup(func() { redisClient.Close() })
+	db, err := pgxpool.New(context.Background(), "host=127.0.0.1 port=1 sslmode=disable connect_timeout=1")
+	if err != nil {
+		t.Fatalf("failed to create pool: %v", err)
+	}
+	t.Cleanup(db.Close)
+
+	cfg := &config.Config{Recommendations: config.RecommendationsConfig{Enabled: enabled, Slate: slate}}
+	return recommendation.NewService(&postgres.Pool{Pool: db}, redisClient, cfg, zap.NewNop(), testMetrics())
+}
+
+var slateConfig = map[string]config.PlacementConfig{
+	config.PlacementNewArrivals:        {Algorithm: config.AlgorithmNewest, Budget: 4},
+	config.PlacementHomepageHero:       {Algorithm: config.AlgorithmHybrid, Budget: 2},
+	config.PlacementTrendingInCategory: {Algorithm: config.AlgorithmTrending, Budget: 0},
+}
+
+func placementNames(slate *recommendation.SlateResponse) string {
+	var names []string
+	for _, p := range slate.Placements {
+		names = append(names, p.Name)
+		if p.Recommendations == nil {
+			names = append(names, "(nil)")
+		}
+	}
+	return strings.Join(names, ",")
+}
+
+func TestSlateFillsPlacementsInSlateOrder(t *testing.T) {
+	svc := newSlateService(t, true, slateConfig)
+
+	slate, err := svc.GetSlate(context.Background(), &recommendation.SlateRequest{Category: "translation"})
+	if err != nil {
+		t.Fatalf("GetSlate: %v", err)
+	}
+	// A placement with no budget is left out
+	if got := placementNames(slate); got != "homepage_hero,new_arrivals" {
+		t.Errorf("placements = %s", got)
+	}
+	if p := slate.Placements[1]; p.Algorithm != config.AlgorithmNewest || p.Budget != 4 || p.Category != "translation" {
+		t.Errorf("new_arrivals = %+v", p)
+	}
+
+	// Requested placements keep slate order
+	slate, err = svc.GetSlate(context.Background(), &recommendation.SlateRequest{Placements: []string{"new_arrivals", "homepage_hero"}})
+	if err != nil {
+		t.Fatalf("GetSlate: %v", err)
+	}
+	if got := placementNames(slate); got != "homepage_hero,new_arrivals" {
+		t.Errorf("requested placements = %s", got)
+	}
+}
+
+func TestSlateRejectsUnknownPlacements(t *testing.T) {
+	for _, enabled := range []bool{true, false} {
+		svc := newSlateService(t, enabled, slateConfig)
+		for _, name := range []string{"sidebar", config.PlacementTrendingInCategory, config.PlacementBecauseYouUsed} {
+			_, err := svc.GetSlate(context.Background(), &recommendation.SlateRequest{Placements: []string{name}})
+			if !errors.Is(err, recommendation.ErrUnknownPlacement) {
+				t.Errorf("enabled %v, placement %s: err = %v, want ErrUnknownPlacement", enabled, name, err)
+			}
+		}
+	}
+
+	disabled := newSlateService(t, false, slateConfig)
+	slate, err := disabled.GetSlate(context.Background(), &recommendation.SlateRequest{})
+	if err != nil || len(slate.Placements) != 0 {
+		t.Errorf("disabled slate = %+v, %v; want no placements", slate, err)
+	}
+}
+
+func TestSlateConfigValidation(t *testing.T) {
+	for _, tt := range []struct{ old, new, want string }{
+		{"algorithm: newest", "algorithm: random", `unknown algorithm "random"`},
+		{"    new_arrivals:", "    sidebar:", `unknown recommendations slate placement "sidebar"`},
+		{"budget: 3", "budget: -1", "homepage_hero needs a non-negative budget"},
+	} {
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		writeConfig(t, path, tt.old, tt.new)
+		if _, err := config.Load(path); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: Load error = %v, want %q", tt.new, err, tt.want)
+		}
+	}
+}
+
+func TestHydrationConfigValidation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeConfig(t, path, "    batch_size: 10\n", "    batch_size: -1\n")
+	if _, err := config.Load(path); err == nil || !strings.Contains(err.Error(), "hydration") {
+		t.Errorf("Load error = %v, want the batch_size rejected", err)
+	}
+}
+
+func TestAnonymousCallersGetFallbackRecommendations(t *testing.T) {
+	svc := newSlateService(t, true, map[string]config.PlacementConfig{
+		config.PlacementHomepageHero:   {Algorithm: config.AlgorithmHybrid, Budget: 2},
+		config.PlacementBecauseYouUsed: {Algorithm: config.AlgorithmContent, Budget: 2},
+	})
+	ctx := context.Background()
+
+	for _, req := range []*recommendation.RecommendationRequest{
+		{},
+		{Categories: []string{"translation"}, IncludeTrending: true},
+	} {
+		resp, err := svc.GetRecommendations(ctx, req)
+		if err != nil {
+			t.Fatalf("GetRecommendations(%+v): %v", req, err)
+		}
+		if resp.Algorithm != recommendation.AlgorithmAnonymousFallback || resp.Recommendations == nil {
+			t.Errorf("GetRecommendations(%+v) = %+v, want an anonymous fallback", req, resp)
+		}
+	}
+	if resp, err := svc.GetRecommendations(ctx, &recommendation.RecommendationRequest{UserID: "u1"}); err != nil || resp.Algorithm != "hybrid" {
+		t.Errorf("user recommendations = %+v, %v; want hybrid", resp, err)
+	}
+
+	slate, err := svc.GetSlate(ctx, &recommendation.SlateRequest{})
+	if err != nil {
+		t.Fatalf("GetSlate: %v", err)
+	}
+	if got := slate.Placements[1]; got.Name != config.PlacementBecauseYouUsed || got.Algorithm != recommendation.AlgorithmAnonymousFallback {
+		t.Errorf("anonymous because_you_used = %+v, want an anonymous fallback", got)
+	}
+	if got := slate.Placements[0]; got.Algorithm != config.AlgorithmHybrid {
+		t.Errorf("anonymous homepage_hero algorithm = %s, want hybrid", got.Algorithm)
+	}
+}
+
+func TestTrendingIsNotAnAnonymousFallback(t *testing.T) {
+	svc := newSlateService(t, true, nil)
+
+	resp, err := svc.GetTrending(context.Background(), 5)
+	if err != nil {
+		t.Fatalf("GetTrending: %v", err)
+	}
+	if resp.Algorithm != config.AlgorithmTrending {
+		t.Errorf("trending algorithm = %s, want %s", resp.Algorithm, config.AlgorithmTrending)
+	}
+}
+
+func TestAnonymousFallbackIsCachedPerPageSize(t *testing.T) {
+	fake, addr := newFakeRedis(t)
+	redisClient := goredis.NewClient(&goredis.Options{Addr: addr})
+	t.Cleanup(func() { redisClient.Close() })
+	db, err := pgxpool.New(context.Background(), "host=127.0.0.1 port=1 sslmode=disable connect_timeout=1")
+	if err != nil {
+		t.Fatalf("failed to create pool: %v", err)
+	}
+	t.Cleanup(db.Close)
+	cfg := &config.Config{Recommendations: config.RecommendationsConfig{Enabled: true, MaxRecommendations: 20}}
+	svc := recommendation.NewService(&postgres.Pool{Pool: db}, redisClient, cfg, zap.NewNop(), testMetrics())
+
+	for _, n := range []int{3, 10, 3} {
+		req := &recommendation.RecommendationRequest{MaxResults: n, Categories: []string{"translation"}}
+		if _, err := svc.GetRecommendations(context.Background(), req); err != nil {
+			t.Fatalf("GetRecommendations(%d): %v", n, err)
+		}
+	}
+
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	var keys []string
+	for key := range fake.strings {
+		if strings.HasPrefix(key, "recommendations:anonymous:") {
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) != 2 {
+		t.Errorf("anonymous cache keys = %v, want one per max_results", keys)
+	}
+}
@@ -0,0 +1,216 @@
+package tests
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	goredis "github.com/go-redis/redis/v8"
+	"go.uber.org/zap"
+
+	"github.com/org/llm-marketplace/services/discovery/internal/config"
+	"github.com/org/llm-marketplace/services/discovery/internal/elasticsearch"
+	"github.com/org/llm-marketplace/services/discovery/internal/snapshot"
+	"github.com/org/llm-marketplace/services/discovery/internal/worker"
+)
+
+// fakePointInTime serves a point in time over docs, in order. Each page
+// hands out a new point in time id, as Elasticsearch may.
+type fakePointInTime struct {
+	mu     sync.Mutex
+	docs   []*elasticsearch.ServiceDocument
+	pits   []string // Point in time ids each search was made with
+	closed []string
+}
+
+func (f *fakePointInTime) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("X-Elastic-Product", "Elasticsearch")
+	w.Header().Set("Content-Type", "application/json")
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == "/services/_pit":
+		w.Write([]byte(`{"id":"pit-0"}`))
+	case r.Method == http.MethodDelete && r.URL.Path == "/_pit":
+		var body struct{ ID string }
+		json.NewDecoder(r.Body).Decode(&body)
+		f.closed = append(f.closed, body.ID)
+		w.Write([]byte(`{"succeeded":true}`))
+	case r.URL.Path == "/_search":
+		var body struct {
+			Pit         struct{ ID string }
+			Size        int
+			SearchAfter []int `json:"search_after"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		f.pits = append(f.pits, body.Pit.ID)
+
+		from := 0
+		if len(body.SearchAfter) > 0 {
+			from = body.SearchAfter[0] + 1
+		}
+		hits := []map[string]interface{}{}
+		for i := from; i < len(f.docs) && i < from+body.Size; i++ {
+			hits = append(hits, map[string]interface{}{"_id": f.docs[i].ID, "_source": f.docs[i], "sort": []int{i}})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"pit_id": fmt.Sprintf("pit-%d", len(f.pits)),
+			"hits":   map[string]interface{}{"hits": hits},
+		})
+	default:
+		w.Write([]byte(`{}`))
+	}
+}
+
+// memoryStore keeps uploaded objects
+type memoryStore struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+	fail    bool
+}
+
+func (m *memoryStore) Put(ctx context.Context, key, contentType string, body io.ReadSeeker, size int64) error {
+	if m.fail {
+		return errors.New("bucket unavailable")
+	}
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return err
+	}
+	if int64(len(data)) != size {
+		return fmt.Errorf("read %d bytes, want %d", len(data), size)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.objects[key] = data
+	return nil
+}
+
+func newSnapshotter(t *testing.T, es *fakePointInTime, store *memoryStore) (*snapshot.Snapshotter, *fakeRedis, *worker.Group) {
+	t.Helper()
+	server := httptest.NewServer(es)
+	t.Cleanup(server.Close)
+	esClient, err := elasticsearch.NewClient(config.ElasticsearchConfig{
+		Addresses: []string{server.URL},
+		IndexName: "services",
+	}, nil)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+
+	redis, addr := newFakeRedis(t)
+	redisClient := goredis.NewClient(&goredis.Options{Addr: addr, MaxRetries: -1})
+	t.Cleanup(func() { redisClient.Close() })
+
+	cfg := config.SnapshotConfig{
+		Enabled:   true,
+		Bucket:    "snapshots",
+		Prefix:    "catalog-snapshots",
+		BatchSize: 2,
+		KeepAlive: time.Minute,
+		Directory: t.TempDir(),
+		Timeout:   time.Minute,
+	}
+	snapshotter := snapshot.NewSnapshotter(esClient, redisClient, store, cfg, "services", zap.NewNop())
+	workers := worker.NewGroup(zap.NewNop())
+	snapshotter.SetWorkers(workers)
+	return snapshotter, redis, workers
+}
+
+func TestCatalogSnapshot(t *testing.T) {
+	es := &fakePointInTime{docs: []*elasticsearch.ServiceDocument{
+		{ID: "a", Name: "Service A", Status: elasticsearch.StatusActive},
+		{ID: "b", Name: "Service B", Status: elasticsearch.StatusActive, TenantID: "acme"},
+		{ID: "c", Name: "Service C", Status: elasticsearch.StatusDeprecated},
+	}}
+	store := &memoryStore{objects: map[string][]byte{}}
+	snapshotter, _, workers := newSnapshotter(t, es, store)
+
+	ctx := context.Background()
+	job, err := snapshotter.StartJob(ctx, snapshot.TriggerAPI)
+	if err != nil {
+		t.Fatalf("StartJob: %v", err)
+	}
+	if err := workers.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+
+	job, err = snapshotter.GetJob(ctx, job.ID)
+	if err != nil {
+		t.Fatalf("GetJob: %v", err)
+	}
+	if job.Status != snapshot.JobCompleted || job.Documents != 3 {
+		t.Fatalf("job = %+v, want completed with 3 documents", job)
+	}
+
+	var manifest snapshot.Manifest
+	if err := json.Unmarshal(store.objects[job.ManifestKey], &manifest); err != nil {
+		t.Fatalf("manifest %s: %v", job.ManifestKey, err)
+	}
+	if manifest.ID != job.ID || manifest.Documents != 3 || manifest.Index != "services" || len(manifest.Files) != 1 {
+		t.Fatalf("manifest = %+v", manifest)
+	}
+	file := manifest.Files[0]
+	if !strings.HasPrefix(file.Key, "catalog-snapshots/"+job.ID+"/") || file.Bytes != int64(len(store.objects[file.Key])) {
+		t.Errorf("file = %+v, stored %d bytes", file, len(store.objects[file.Key]))
+	}
+
+	var ids []string
+	scanner := bufio.NewScanner(bytes.NewReader(store.objects[file.Key]))
+	for scanner.Scan() {
+		var doc elasticsearch.ServiceDocument
+		if err := json.Unmarshal(scanner.Bytes(), &doc); err != nil {
+			t.Fatalf("line %q: %v", scanner.Text(), err)
+		}
+		ids = append(ids, doc.ID)
+	}
+	if strings.Join(ids, ",") != "a,b,c" {
+		t.Errorf("snapshot holds %v, want a, b and c", ids)
+	}
+
+	// Each page continues from the point in time the last one returned
+	if strings.Join(es.pits, ",") != "pit-0,pit-1" || strings.Join(es.closed, ",") != "pit-2" {
+		t.Errorf("searched %v and closed %v", es.pits, es.closed)
+	}
+}
+
+func TestCatalogSnapshotFailures(t *testing.T) {
+	es := &fakePointInTime{docs: []*elasticsearch.ServiceDocument{{ID: "a", Name: "Service A"}}}
+	store := &memoryStore{objects: map[string][]byte{}, fail: true}
+	snapshotter, redis, workers := newSnapshotter(t, es, store)
+	ctx := context.Background()
+
+	redis.strings["catalog_snapshot:lock"] = "other"
+	if _, err := snapshotter.StartJob(ctx, snapshot.TriggerAPI); !errors.Is(err, snapshot.ErrRunning) {
+		t.Fatalf("StartJob while another runs = %v, want ErrRunning", err)
+	}
+	delete(redis.strings, "catalog_snapshot:lock")
+
+	job, err := snapshotter.StartJob(ctx, snapshot.TriggerAPI)
+	if err != nil {
+		t.Fatalf("StartJob: %v", err)
+	}
+	if err := workers.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+	if job, err = snapshotter.GetJob(ctx, job.ID); err != nil || job.Status != snapshot.JobFailed || !strings.Contains(job.Error, "bucket unavailable") {
+		t.Errorf("job = %+v (%v), want failed on the upload", job, err)
+	}
+	if len(store.objects) != 0 || len(es.closed) != 1 {
+		t.Errorf("stored %d objects and closed %v, want none and the point in time closed", len(store.objects), es.closed)
+	}
+	if _, ok := redis.strings["catalog_snapshot:lock"]; ok {
+		t.Error("lock was not released")
+	}
+}
@@ -0,0 +1,87 @@
+package tests
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/org/llm-marketplace/services/discovery/internal/config"
+	"github.com/org/llm-marketplace/services/discovery/internal/elasticsearch"
+	"github.com/org/llm-marketplace/services/discovery/internal/search"
+)
+
+func TestStaleSearchResultsServedWhileRevalidated(t *testing.T) {
+	redis, addr := newFakeRedis(t)
+	es := &fakeElasticsearch{docs: map[string]*elasticsearch.ServiceDocument{
+		"chat-acme": sessionFixtures["chat-acme"],
+	}}
+	svc := newSearchServiceOn(t, es, addr, func(c *config.Config) {
+		c.Redis.CacheTTL = map[string]string{"search_results": "20ms"}
+		c.Search.StaleWhileRevalidate = config.StaleWhileRevalidateConfig{Enabled: true, StaleTTL: time.Minute, RefreshTimeout: time.Second}
+	})
+	ctx := context.Background()
+	req := func() *search.SearchRequest {
+		return &search.SearchRequest{Query: "chat", Pagination: search.PaginationRequest{PageSize: 10}}
+	}
+	searches := func() int {
+		es.mu.Lock()
+		defer es.mu.Unlock()
+		return len(es.searches)
+	}
+
+	if _, err := svc.Search(ctx, req()); err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	// Fresh: served from the cache without a refresh
+	if _, err := svc.Search(ctx, req()); err != nil || searches() != 1 {
+		t.Fatalf("fresh search: %v, %d Elasticsearch searches; want 1", err, searches())
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	es.mu.Lock()
+	es.docs = sessionFixtures
+	es.mu.Unlock()
+
+	// Stale: served as cached at once, and refreshed in the background
+	resp, err := svc.Search(ctx, req())
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if len(resp.Results) != 1 {
+		t.Errorf("stale search returned %d results, want the 1 cached", len(resp.Results))
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		redis.mu.Lock()
+		locked := false
+		for key := range redis.strings {
+			locked = locked || strings.HasPrefix(key, "revalidating:")
+		}
+		redis.mu.Unlock()
+		if searches() == 2 && !locked {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("refresh did not finish: %d Elasticsearch searches, lock held %v", searches(), locked)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	resp, err = svc.Search(ctx, req())
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if len(resp.Results) != len(sessionFixtures) || searches() != 2 {
+		t.Errorf("after the refresh: %d results, %d Elasticsearch searches; want %d from the cache", len(resp.Results), searches(), len(sessionFixtures))
+	}
+}
+
+func TestStaleWhileRevalidateConfigValidation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeConfig(t, path, "stale_ttl: 5m", "stale_ttl: 0s")
+	if _, err := config.Load(path); err == nil || !strings.Contains(err.Error(), "stale_while_revalidate") {
+		t.Errorf("Load error = %v, want the stale_ttl rejected", err)
+	}
+}
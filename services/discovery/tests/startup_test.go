@@ -0,0 +1,85 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/org/llm-marketplace/services/discovery/internal/config"
+	"github.com/org/llm-marketplace/services/discovery/internal/startup"
+)
+
+var errConnectionRefused = errors.New("connection refused")
+
+func TestWaiterRetriesUntilAvailable(t *testing.T) {
+	waiter := startup.NewWaiter(config.StartupConfig{
+		MaxWait:        time.Second,
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     2 * time.Millisecond,
+	}, zap.NewNop())
+
+	attempts := 0
+	err := waiter.Wait(context.Background(), "postgres", func(context.Context) error {
+		attempts++
+		if attempts < 4 {
+			return errConnectionRefused
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Wait: %v", err)
+	}
+	if attempts != 4 {
+		t.Errorf("attempts = %d, want 4", attempts)
+	}
+}
+
+func TestWaiterGivesUpAfterMaxWait(t *testing.T) {
+	waiter := startup.NewWaiter(config.StartupConfig{
+		MaxWait:        50 * time.Millisecond,
+		InitialBackoff: 5 * time.Millisecond,
+		MaxBackoff:     10 * time.Millisecond,
+	}, zap.NewNop())
+
+	start := time.Now()
+	err := waiter.Wait(context.Background(), "redis", func(context.Context) error {
+		return errConnectionRefused
+	})
+	if !errors.Is(err, errConnectionRefused) {
+		t.Fatalf("Wait() = %v, want the last attempt's error", err)
+	}
+	if !strings.Contains(err.Error(), "redis not available") {
+		t.Errorf("Wait() = %q, want it to name the dependency", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Wait took %s, want it to stop after max_wait", elapsed)
+	}
+}
+
+func TestWaiterRetryStopsWithContext(t *testing.T) {
+	waiter := startup.NewWaiter(config.StartupConfig{
+		MaxWait:        time.Millisecond, // Retry is not bounded by max_wait
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     time.Millisecond,
+	}, zap.NewNop())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	attempts := 0
+	err := waiter.Retry(ctx, "elasticsearch indices", func(context.Context) error {
+		attempts++
+		if attempts == 20 {
+			cancel()
+		}
+		return errConnectionRefused
+	})
+	if !errors.Is(err, errConnectionRefused) {
+		t.Fatalf("Retry() = %v, want the last attempt's error", err)
+	}
+	if attempts != 20 {
+		t.Errorf("attempts = %d, want Retry to keep going until cancelled", attempts)
+	}
+}
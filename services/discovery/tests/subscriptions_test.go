@@ -0,0 +1,125 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	goredis "github.com/go-redis/redis/v8"
+	"go.uber.org/zap"
+
+	"github.com/org/llm-marketplace/services/discovery/internal/config"
+	"github.com/org/llm-marketplace/services/discovery/internal/elasticsearch"
+	"github.com/org/llm-marketplace/services/discovery/internal/entitlement"
+	"github.com/org/llm-marketplace/services/discovery/internal/search"
+	"github.com/org/llm-marketplace/services/discovery/internal/subscription"
+)
+
+// fakeSubscriptionRegistry serves the active subscriptions of acme, and
+// fails every request while failing is set
+type fakeSubscriptionRegistry struct {
+	calls   atomic.Int32
+	failing atomic.Bool
+}
+
+func (f *fakeSubscriptionRegistry) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.calls.Add(1)
+	if f.failing.Load() {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	subs := []map[string]string{}
+	if q := r.URL.Query(); q.Get("consumer_id") == "acme" && q.Get("status") == "active" {
+		subs = append(subs, map[string]string{"service_id": "public-svc"})
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{"subscriptions": subs, "total": len(subs)})
+}
+
+func newSubscriptionSearch(t *testing.T, registry *fakeSubscriptionRegistry) *search.Service {
+	t.Helper()
+	es := httptest.NewServer(&fakeElasticsearch{})
+	t.Cleanup(es.Close)
+	reg := httptest.NewServer(registry)
+	t.Cleanup(reg.Close)
+
+	cfg := &config.Config{
+		Elasticsearch: config.ElasticsearchConfig{Addresses: []string{es.URL}, IndexName: "services"},
+		Search: config.SearchConfig{
+			MaxResults:     100,
+			DefaultResults: 20,
+			RankingWeights: config.RankingWeights{Relevance: 1},
+		},
+		Subscriptions: config.SubscriptionsConfig{Enabled: true, RegistryURL: reg.URL, Timeout: time.Second, CacheTTL: time.Minute},
+	}
+	esClient, err := elasticsearch.NewClient(cfg.Elasticsearch, nil)
+	if err != nil {
+		t.Fatalf("failed to create elasticsearch client: %v", err)
+	}
+	// Redis is unreachable, so every search misses the cache
+	redisClient := goredis.NewClient(&goredis.Options{Addr: "127.0.0.1:1", MaxRetries: -1})
+	t.Cleanup(func() { redisClient.Close() })
+
+	svc := search.NewService(esClient, redisClient, nil, cfg, zap.NewNop(), testMetrics())
+	svc.SetSubscriptions(subscription.NewClient(cfg.Subscriptions))
+	return svc
+}
+
+// subscribedIDs searches as tenant and returns whether each result is flagged
+func subscribedIDs(t *testing.T, svc *search.Service, tenant string) map[string]bool {
+	t.Helper()
+	ctx := entitlement.WithCaller(context.Background(), entitlement.Caller{TenantID: tenant})
+	resp, err := svc.Search(ctx, &search.SearchRequest{Query: "model", Pagination: search.PaginationRequest{Page: 1, PageSize: 20}})
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	flags := make(map[string]bool)
+	for _, r := range resp.Results {
+		flags[r.Service.ID] = r.Subscribed
+	}
+	return flags
+}
+
+func TestSearchFlagsSubscribedServices(t *testing.T) {
+	registry := &fakeSubscriptionRegistry{}
+	svc := newSubscriptionSearch(t, registry)
+
+	flags := subscribedIDs(t, svc, "acme")
+	if !flags["public-svc"] {
+		t.Errorf("public-svc is not flagged subscribed for acme: %v", flags)
+	}
+	if flags["restricted-tenant-svc"] {
+		t.Errorf("restricted-tenant-svc is flagged subscribed for acme: %v", flags)
+	}
+
+	// Another tenant's results are not flagged, and acme's subscriptions
+	// are reused from the cache
+	if flags := subscribedIDs(t, svc, "globex"); flags["public-svc"] {
+		t.Errorf("public-svc is flagged subscribed for globex")
+	}
+	subscribedIDs(t, svc, "acme")
+	if got := registry.calls.Load(); got != 2 {
+		t.Errorf("registry called %d times, want once per tenant", got)
+	}
+
+	// Anonymous callers have no subscriptions to look up
+	subscribedIDs(t, svc, "")
+	if got := registry.calls.Load(); got != 2 {
+		t.Errorf("registry called for an anonymous caller")
+	}
+}
+
+func TestSearchWithoutRegistryLeavesResultsUnflagged(t *testing.T) {
+	registry := &fakeSubscriptionRegistry{}
+	registry.failing.Store(true)
+	svc := newSubscriptionSearch(t, registry)
+
+	for id, subscribed := range subscribedIDs(t, svc, "acme") {
+		if subscribed {
+			t.Errorf("%s is flagged subscribed while the registry is down", id)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/org/llm-marketplace/services/discovery/internal/config"
+	"github.com/org/llm-marketplace/services/discovery/internal/elasticsearch"
+)
+
+func TestServiceCompletion(t *testing.T) {
+	public := &elasticsearch.ServiceDocument{
+		Name:     "Fast Translation Model",
+		Category: "translation",
+		Status:   elasticsearch.StatusActive,
+		Metrics:  elasticsearch.MetricsInfo{PopularityScore: 0.8},
+	}
+	c := public.Completion()
+	if c == nil {
+		t.Fatal("no completion for an active public service")
+	}
+	if want := []string{"Fast Translation Model", "Translation Model", "Model"}; strings.Join(c.Input, ",") != strings.Join(want, ",") {
+		t.Errorf("inputs = %q, want %q", c.Input, want)
+	}
+	for _, want := range []string{"*|public|*", "*|public|translation", "shared|public|*"} {
+		if !containsString(c.Contexts["scope"], want) {
+			t.Errorf("contexts %q missing %s", c.Contexts["scope"], want)
+		}
+	}
+
+	unpopular := *public
+	unpopular.Metrics.PopularityScore = 0.1
+	if unpopular.Completion().Weight >= c.Weight {
+		t.Errorf("weight %d for a less popular service, want below %d", unpopular.Completion().Weight, c.Weight)
+	}
+
+	private := &elasticsearch.ServiceDocument{
+		Name:     "Private model",
+		Status:   elasticsearch.StatusActive,
+		TenantID: "acme",
+		Access: &elasticsearch.AccessInfo{
+			Visibility:   elasticsearch.VisibilityPrivate,
+			OwnerTenant:  "acme",
+			AllowedUsers: []string{"alice"},
+		},
+	}
+	scopes := private.Completion().Contexts["scope"]
+	if !containsString(scopes, "tenant:acme|user:alice|*") || !containsString(scopes, "*|user:alice|*") {
+		t.Errorf("private contexts = %q, want alice in acme's catalog", scopes)
+	}
+	for _, scope := range scopes {
+		if strings.Contains(scope, "|public|") || strings.Contains(scope, "|tenant:acme|") {
+			t.Errorf("private service suggested to %s", scope)
+		}
+	}
+
+	for name, doc := range map[string]*elasticsearch.ServiceDocument{
+		"retired":            {Name: "Old model", Status: elasticsearch.StatusRetired},
+		"draft":              {Name: "New model", Status: elasticsearch.StatusDraft},
+		"older version":      {Name: "Model", Status: elasticsearch.StatusActive, Version: &elasticsearch.VersionInfo{Number: "1.0.0", Stable: true}},
+		"unknown visibility": {Name: "Model", Status: elasticsearch.StatusActive, Access: &elasticsearch.AccessInfo{Visibility: "internal"}},
+	} {
+		if c := doc.Completion(); c != nil {
+			t.Errorf("%s service has a completion: %+v", name, c)
+		}
+	}
+}
+
+func TestAutocompleteSuggester(t *testing.T) {
+	es := &fakeElasticsearch{}
+	router := newAPIRouter(t, es, "127.0.0.1:1", func(c *config.Config) { c.Search.Autocomplete.Suggester = true })
+
+	suggest := func(path string, header http.Header) []string {
+		t.Helper()
+		w := apiRequest(router, http.MethodGet, path, "", header)
+		var resp struct {
+			Suggestions []string `json:"suggestions"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || w.Code != http.StatusOK {
+			t.Fatalf("GET %s: status %d, body %s", path, w.Code, w.Body.String())
+		}
+		return resp.Suggestions
+	}
+
+	// Suggestions the caller may not see are dropped even if Elasticsearch returns them
+	if got := suggest("/api/v1/autocomplete?q=mod", nil); len(got) != 1 || got[0] != "Public translation model" {
+		t.Errorf("anonymous suggestions = %q, want the public service only", got)
+	}
+	got := suggest("/api/v1/autocomplete?q=mod&category=nlp", http.Header{"X-Tenant-Id": {"acme"}})
+	if len(got) != 2 || containsString(got, "Private restricted model") {
+		t.Errorf("acme suggestions = %q, want the public and acme services", got)
+	}
+
+	es.mu.Lock()
+	defer es.mu.Unlock()
+	if len(es.searches) != 2 {
+		t.Fatalf("%d requests sent, want 2 suggesters", len(es.searches))
+	}
+	for _, want := range []string{`"prefix":"mod"`, `"field":"suggest"`, `"*|public|*"`} {
+		if !strings.Contains(es.searches[0], want) {
+			t.Errorf("anonymous suggester %s missing %s", es.searches[0], want)
+		}
+	}
+	if strings.Contains(es.searches[0], "tenant:") {
+		t.Errorf("anonymous suggester reads tenant suggestions: %s", es.searches[0])
+	}
+	for _, want := range []string{`"*|public|nlp"`, `"*|tenant:acme|nlp"`} {
+		if !strings.Contains(es.searches[1], want) {
+			t.Errorf("acme suggester %s missing %s", es.searches[1], want)
+		}
+	}
+	if strings.Contains(es.searches[1], `|*"`) {
+		t.Errorf("category suggester reads every category: %s", es.searches[1])
+	}
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,93 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/org/llm-marketplace/services/discovery/internal/config"
+	"github.com/org/llm-marketplace/services/discovery/internal/elasticsearch"
+	"github.com/org/llm-marketplace/services/discovery/internal/entitlement"
+)
+
+// tenantServices are the private services indexed into the sandbox catalog,
+// by ID, with their tenant
+var tenantServices = map[string]string{
+	"acme-private":   "acme",
+	"globex-private": "globex",
+}
+
+// indexTenantServices clones a seeded service into each tenant's catalog
+func indexTenantServices(t *testing.T, cfg config.ElasticsearchConfig) {
+	t.Helper()
+	ctx := context.Background()
+	esClient, err := elasticsearch.NewClient(cfg, nil)
+	if err != nil {
+		t.Fatalf("failed to connect to sandbox elasticsearch: %v", err)
+	}
+	resp, err := esClient.Search(ctx, map[string]interface{}{"size": 1})
+	if err != nil || len(resp.Hits.Hits) == 0 {
+		t.Fatalf("failed to read a seeded service: %v", err)
+	}
+	for id, tenant := range tenantServices {
+		doc := resp.Hits.Hits[0].Source
+		doc.ID, doc.Name, doc.TenantID = id, "Tenancy probe "+id, tenant
+		if err := esClient.Index(elasticsearch.WithTenant(ctx, tenant), &doc); err != nil {
+			t.Fatalf("failed to index %s: %v", id, err)
+		}
+	}
+}
+
+func TestTenantSearchesNeverMatchOtherTenantsServices(t *testing.T) {
+	cfg := startSandbox(t)
+	indexTenantServices(t, cfg.Elasticsearch)
+
+	for _, shared := range []bool{true, false} {
+		esCfg := cfg.Elasticsearch
+		esCfg.Tenancy = config.TenancyConfig{Enabled: true, SharedCatalog: shared}
+		esClient, err := elasticsearch.NewClient(esCfg, nil)
+		if err != nil {
+			t.Fatalf("failed to connect to sandbox elasticsearch: %v", err)
+		}
+
+		for _, tenant := range []string{"acme", "globex", "initech", ""} {
+			ctx := entitlement.WithCaller(context.Background(), entitlement.Caller{TenantID: tenant})
+			for _, query := range []map[string]interface{}{
+				{"size": 1000},
+				{"size": 1000, "query": map[string]interface{}{"match": map[string]interface{}{"name": "tenancy probe"}}},
+			} {
+				resp, err := esClient.Search(ctx, query)
+				if err != nil {
+					t.Fatalf("shared %v, tenant %q: search failed: %v", shared, tenant, err)
+				}
+				ownSeen := false
+				for _, hit := range resp.Hits.Hits {
+					owner, private := tenantServices[hit.ID]
+					switch {
+					case private && owner != tenant:
+						t.Errorf("shared %v, tenant %q: matched %s of %s", shared, tenant, hit.ID, owner)
+					case private:
+						ownSeen = true
+					case hit.Source.TenantID != "":
+						t.Errorf("shared %v, tenant %q: matched %s of tenant %s", shared, tenant, hit.ID, hit.Source.TenantID)
+					case !shared && tenant != "":
+						t.Errorf("shared %v, tenant %q: matched shared service %s", shared, tenant, hit.ID)
+					}
+				}
+				if tenant == "acme" && !ownSeen {
+					t.Errorf("shared %v: acme didn't match its own service with %v", shared, query)
+				}
+			}
+
+			for id, owner := range tenantServices {
+				_, err := esClient.Get(ctx, id)
+				if owner != tenant && !errors.Is(err, elasticsearch.ErrNotFound) {
+					t.Errorf("shared %v, tenant %q: Get(%s) = %v, want not found", shared, tenant, id, err)
+				}
+				if owner == tenant && err != nil {
+					t.Errorf("shared %v, tenant %q: Get(%s) = %v", shared, tenant, id, err)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/org/llm-marketplace/services/discovery/internal/config"
+	"github.com/org/llm-marketplace/services/discovery/internal/observability"
+)
+
+func TestNewResourceAppliesConfiguredAttributes(t *testing.T) {
+	t.Setenv("OTEL_RESOURCE_ATTRIBUTES", "deployment.environment=from-env,team=search")
+
+	res, err := observability.NewResource(map[string]string{
+		"deployment.environment": "staging",
+		"service.version":        "2.3.0",
+		"cloud.region":           "",
+	})
+	if err != nil {
+		t.Fatalf("NewResource: %v", err)
+	}
+
+	attrs := make(map[string]string)
+	for _, kv := range res.Attributes() {
+		attrs[string(kv.Key)] = kv.Value.Emit()
+	}
+	want := map[string]string{
+		"service.name":           "llm-marketplace-discovery",
+		"service.version":        "2.3.0",
+		"deployment.environment": "staging",
+		"team":                   "search",
+	}
+	for key, value := range want {
+		if attrs[key] != value {
+			t.Errorf("%s = %q, want %q", key, attrs[key], value)
+		}
+	}
+	if _, ok := attrs["cloud.region"]; ok {
+		t.Error("empty configured attribute was added")
+	}
+}
+
+func TestTracingConfigValidation(t *testing.T) {
+	base, err := os.ReadFile("../config.yaml")
+	if err != nil {
+		t.Fatalf("failed to read config: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		old     string
+		new     string
+		wantErr string
+	}{
+		{"otlp default", "", "", ""},
+		{"legacy jaeger", `exporter: "otlp"`, `exporter: "jaeger"`, ""},
+		{"unknown exporter", `exporter: "otlp"`, `exporter: "zipkin"`, "unknown tracing exporter"},
+		{"unknown sampler", `sampler: "parent_based"`, `sampler: "sometimes"`, "unknown tracing sampler"},
+		{"otlp without endpoint", `endpoint: "jaeger:4317"`, `endpoint: ""`, "otlp.endpoint is required"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content := string(base)
+			if tt.old != "" {
+				if !strings.Contains(content, tt.old) {
+					t.Fatalf("config.yaml has no %q", tt.old)
+				}
+				content = strings.Replace(content, tt.old, tt.new, 1)
+			}
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+				t.Fatalf("failed to write config: %v", err)
+			}
+
+			_, err := config.Load(path)
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("Load: %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("Load error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/org/llm-marketplace/services/discovery/internal/elasticsearch"
+	"github.com/org/llm-marketplace/services/discovery/internal/search"
+)
+
+func TestServiceVersions(t *testing.T) {
+	cfg := startSandbox(t)
+	svc, clients := newSandboxSearch(t, cfg)
+	ctx := context.Background()
+
+	seeded, err := clients.es.Search(ctx, map[string]interface{}{"size": 1})
+	if err != nil || len(seeded.Hits.Hits) == 0 {
+		t.Fatalf("failed to read a seeded service: %v", err)
+	}
+	released := time.Now().UTC().Add(-72 * time.Hour).Truncate(time.Second)
+	publish := func(id, number string, stable bool, age int) {
+		t.Helper()
+		doc := seeded.Hits.Hits[0].Source
+		doc.ID, doc.Name, doc.ServiceKey = id, "Versioned probe", "versioned"
+		doc.Status = elasticsearch.StatusActive
+		doc.Version = &elasticsearch.VersionInfo{
+			Number: number, Stable: stable, Changelog: "Release " + number,
+			ReleasedAt: released.Add(time.Duration(age) * 24 * time.Hour),
+		}
+		if err := svc.PublishVersion(ctx, &doc); err != nil {
+			t.Fatalf("publish %s: %v", id, err)
+		}
+	}
+	// searched returns the IDs found searching for the probes' name
+	searched := func(allVersions bool, filters search.SearchFilters) []string {
+		t.Helper()
+		resp, err := svc.Search(ctx, &search.SearchRequest{Query: "Versioned probe", Literal: true, AllVersions: allVersions, Filters: filters})
+		if err != nil {
+			t.Fatalf("search failed: %v", err)
+		}
+		var ids []string
+		for _, r := range resp.Results {
+			if r.Service.ServiceKey == "versioned" {
+				ids = append(ids, r.Service.ID)
+			}
+		}
+		return ids
+	}
+
+	if err := svc.PublishVersion(ctx, &elasticsearch.ServiceDocument{ID: "versioned-x"}); !errors.Is(err, search.ErrInvalidVersion) {
+		t.Errorf("publishing without a version number: %v, want invalid", err)
+	}
+
+	publish("versioned-1", "1.0.0", true, 0)
+	publish("versioned-2", "2.0.0", true, 1)
+	publish("versioned-3", "3.0.0-beta", false, 2)
+
+	history, err := svc.Versions(ctx, "versioned-1")
+	if err != nil {
+		t.Fatalf("versions: %v", err)
+	}
+	// Newest first; the pre-release is listed but isn't the latest
+	if history.ServiceKey != "versioned" || history.LatestID != "versioned-2" || len(history.Versions) != 3 {
+		t.Fatalf("history = %+v", history)
+	}
+	for i, want := range []struct {
+		id     string
+		latest bool
+	}{{"versioned-3", false}, {"versioned-2", true}, {"versioned-1", false}} {
+		v := history.Versions[i]
+		if v.ID != want.id || v.Latest != want.latest || v.Changelog == "" || v.URL != "/api/v1/services/"+want.id {
+			t.Errorf("version %d = %+v, want %s", i, v, want.id)
+		}
+	}
+	if _, err := svc.Versions(ctx, "missing"); !errors.Is(err, elasticsearch.ErrNotFound) {
+		t.Errorf("versions of an unknown service: %v, want not found", err)
+	}
+
+	// Search collapses to the latest stable version unless asked for all
+	if ids := searched(false, search.SearchFilters{}); len(ids) != 1 || ids[0] != "versioned-2" {
+		t.Errorf("search found %v, want only the latest stable version", ids)
+	}
+	if ids := searched(true, search.SearchFilters{}); len(ids) != 3 {
+		t.Errorf("all-versions search found %v, want every version", ids)
+	}
+
+	// A newer stable release takes the latest flag. The search above is
+	// cached, so this one is filtered to differ from it.
+	publish("versioned-4", "4.0.0", true, 3)
+	for id, want := range map[string]bool{"versioned-2": false, "versioned-4": true} {
+		doc, err := clients.es.Get(ctx, id)
+		if err != nil || doc.Version == nil || doc.Version.Latest != want {
+			t.Errorf("%s: latest = %+v, %v; want %v", id, doc.Version, err, want)
+		}
+	}
+	if ids := searched(false, search.SearchFilters{Categories: []string{seeded.Hits.Hits[0].Source.Category}}); len(ids) != 1 || ids[0] != "versioned-4" {
+		t.Errorf("search found %v after a new release, want only it", ids)
+	}
+}
@@ -0,0 +1,49 @@
+package tests
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/org/llm-marketplace/services/discovery/internal/config"
+	"github.com/org/llm-marketplace/services/discovery/internal/search"
+)
+
+func TestWarmPrimesPopularQueries(t *testing.T) {
+	_, addr := newFakeRedis(t)
+	es := &fakeElasticsearch{docs: sessionFixtures}
+	svc := newSearchServiceOn(t, es, addr, func(c *config.Config) {
+		c.Search.Warmup = config.WarmupConfig{Enabled: true, TopQueries: 2, Concurrency: 2}
+	})
+	ctx := context.Background()
+
+	svc.Warm(ctx, []string{"chat", "embeddings"})
+	es.mu.Lock()
+	warmed := len(es.searches)
+	es.mu.Unlock()
+
+	for _, query := range []string{"chat", "embeddings"} {
+		resp, err := svc.Search(ctx, &search.SearchRequest{Query: query, Pagination: search.PaginationRequest{PageSize: 20}})
+		if err != nil {
+			t.Fatalf("Search %q: %v", query, err)
+		}
+		if len(resp.Results) != len(sessionFixtures) {
+			t.Errorf("Search %q returned %d results, want %d", query, len(resp.Results), len(sessionFixtures))
+		}
+	}
+
+	es.mu.Lock()
+	defer es.mu.Unlock()
+	if len(es.searches) != warmed {
+		t.Errorf("%d Elasticsearch searches after warm-up, want the primed queries served from the cache", len(es.searches)-warmed)
+	}
+}
+
+func TestWarmupConfigValidation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeConfig(t, path, "top_queries: 50\n    concurrency: 4", "top_queries: 50\n    concurrency: 0")
+	if _, err := config.Load(path); err == nil || !strings.Contains(err.Error(), "warmup") {
+		t.Errorf("Load error = %v, want the concurrency rejected", err)
+	}
+}
@@ -0,0 +1,118 @@
+package tests
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/org/llm-marketplace/services/discovery/internal/config"
+	"github.com/org/llm-marketplace/services/discovery/internal/elasticsearch"
+	"github.com/org/llm-marketplace/services/discovery/internal/webhook"
+)
+
+func TestWebhookFilterMatchesEveryPredicate(t *testing.T) {
+	doc := &elasticsearch.ServiceDocument{
+		ID: "chat-acme", Category: "chat", Tags: []string{"llm", "streaming"},
+		Provider: elasticsearch.ProviderInfo{ID: "acme"},
+	}
+
+	for _, tc := range []struct {
+		name   string
+		filter webhook.Filter
+		want   bool
+	}{
+		{"empty", webhook.Filter{}, true},
+		{"service", webhook.Filter{ServiceIDs: []string{"other", "chat-acme"}}, true},
+		{"other service", webhook.Filter{ServiceIDs: []string{"other"}}, false},
+		{"provider", webhook.Filter{ProviderIDs: []string{"acme"}}, true},
+		{"other provider", webhook.Filter{ProviderIDs: []string{"globex"}}, false},
+		{"category", webhook.Filter{Categories: []string{"chat"}}, true},
+		{"any tag", webhook.Filter{Tags: []string{"vision", "streaming"}}, true},
+		{"no tag", webhook.Filter{Tags: []string{"vision"}}, false},
+		{"all match", webhook.Filter{ProviderIDs: []string{"acme"}, Categories: []string{"chat"}, Tags: []string{"llm"}}, true},
+		{"one misses", webhook.Filter{ProviderIDs: []string{"acme"}, Categories: []string{"translation"}}, false},
+	} {
+		if got := tc.filter.Matches(doc); got != tc.want {
+			t.Errorf("%s: Matches = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestWebhookEventsForCatalogChanges(t *testing.T) {
+	active := &elasticsearch.ServiceDocument{
+		ID: "chat", Status: elasticsearch.StatusActive,
+		Pricing: elasticsearch.PricingInfo{Model: "per_token", Rate: 0.002, Unit: "1k_tokens"},
+	}
+	deprecated := *active
+	deprecated.Status = elasticsearch.StatusDeprecated
+	repriced := *active
+	repriced.Pricing.Rate = 0.001
+
+	for _, tc := range []struct {
+		name              string
+		previous, current *elasticsearch.ServiceDocument
+		want              []string
+	}{
+		{"created", nil, active, []string{webhook.EventServiceCreated}},
+		{"updated", active, active, []string{webhook.EventServiceUpdated}},
+		{"deprecated", active, &deprecated, []string{webhook.EventServiceUpdated, webhook.EventServiceDeprecated}},
+		{"still deprecated", &deprecated, &deprecated, []string{webhook.EventServiceUpdated}},
+		{"repriced", active, &repriced, []string{webhook.EventServiceUpdated, webhook.EventPriceChanged}},
+	} {
+		if got := webhook.DiffEvents(tc.previous, tc.current); !slices.Equal(got, tc.want) {
+			t.Errorf("%s: events %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestWebhookSignatureCoversTimestampAndBody(t *testing.T) {
+	body := []byte(`{"type":"service.created"}`)
+	at := time.Unix(1700000000, 0)
+
+	signature := webhook.Sign("s3cret", at, body)
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write([]byte("1700000000." + string(body)))
+	if want := "t=1700000000,v1=" + hex.EncodeToString(mac.Sum(nil)); signature != want {
+		t.Errorf("signature %q, want %q", signature, want)
+	}
+
+	mac1 := func(signature string) string {
+		_, v1, _ := strings.Cut(signature, ",v1=")
+		return v1
+	}
+	for name, other := range map[string]string{
+		"secret":    webhook.Sign("other", at, body),
+		"timestamp": webhook.Sign("s3cret", at.Add(time.Second), body),
+		"body":      webhook.Sign("s3cret", at, []byte(`{"type":"price.changed"}`)),
+	} {
+		if mac1(other) == mac1(signature) {
+			t.Errorf("changing the %s left the signature unchanged", name)
+		}
+	}
+}
+
+func TestWebhookSubscriptionsAreValidated(t *testing.T) {
+	router := newAPIRouter(t, &fakeElasticsearch{}, "127.0.0.1:1", func(c *config.Config) {
+		c.Webhooks = config.WebhookConfig{Timeout: time.Second}
+	})
+
+	for _, body := range []string{
+		`{"url": "https://hooks.example.com/catalog"}`,
+		`{"url": "https://hooks.example.com/catalog", "events": []}`,
+		`{"url": "https://hooks.example.com/catalog", "events": ["service.deleted"]}`,
+		`{"url": "http://hooks.example.com/catalog", "events": ["service.created"]}`,
+		`{"url": "/catalog", "events": ["service.created"]}`,
+		`{"url": "https://127.0.0.1/catalog", "events": ["service.created"]}`,
+		`{"url": "https://169.254.169.254/latest/meta-data", "events": ["service.created"]}`,
+		`{"url": "https://[::1]:8443/catalog", "events": ["service.created"]}`,
+	} {
+		if w := apiRequest(router, http.MethodPost, "/api/v1/webhooks", body, nil); w.Code != http.StatusBadRequest {
+			t.Errorf("POST %s: status %d, want 400", body, w.Code)
+		}
+	}
+}
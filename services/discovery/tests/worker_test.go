@@ -0,0 +1,94 @@
+package tests
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/org/llm-marketplace/services/discovery/internal/worker"
+)
+
+func TestWorkerGroupDrainsTasksAndStopsWorkers(t *testing.T) {
+	g := worker.NewGroup(zap.NewNop())
+
+	var workerStopped, taskFinished atomic.Bool
+	g.Go("poller", func(ctx context.Context) {
+		<-ctx.Done()
+		workerStopped.Store(true)
+	})
+	g.Task("export", func(ctx context.Context) {
+		// Not cancelled at shutdown; given time to finish
+		select {
+		case <-time.After(50 * time.Millisecond):
+			taskFinished.Store(true)
+		case <-ctx.Done():
+		}
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := g.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+	if !workerStopped.Load() {
+		t.Error("worker was not stopped")
+	}
+	if !taskFinished.Load() {
+		t.Error("task was cut off instead of drained")
+	}
+	if running := g.Running(); len(running) != 0 {
+		t.Errorf("running = %v, want none", running)
+	}
+}
+
+func TestWorkerGroupCancelsTasksAfterDrainTimeout(t *testing.T) {
+	g := worker.NewGroup(zap.NewNop())
+
+	cancelled := make(chan struct{})
+	g.Task("backfill", func(ctx context.Context) {
+		<-ctx.Done()
+		close(cancelled)
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err := g.Shutdown(ctx)
+	if err == nil || !strings.Contains(err.Error(), "backfill") {
+		t.Fatalf("err = %v, want the unfinished task named", err)
+	}
+
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Fatal("task context was not cancelled after the drain timeout")
+	}
+}
+
+func TestWorkerGroupRefusesWorkDuringShutdown(t *testing.T) {
+	g := worker.NewGroup(zap.NewNop())
+	if err := g.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+
+	var ran atomic.Bool
+	g.Task("late", func(ctx context.Context) { ran.Store(true) })
+	time.Sleep(10 * time.Millisecond)
+	if ran.Load() {
+		t.Error("task started after shutdown")
+	}
+}
+
+func TestWorkerGroupRecoversPanics(t *testing.T) {
+	g := worker.NewGroup(zap.NewNop())
+	g.Task("broken", func(ctx context.Context) { panic("boom") })
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := g.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/org/llm-marketplace/services/metering/internal/alerts"
+	"github.com/org/llm-marketplace/services/metering/internal/api"
+	"github.com/org/llm-marketplace/services/metering/internal/config"
+	"github.com/org/llm-marketplace/services/metering/internal/consumer"
+	"github.com/org/llm-marketplace/services/metering/internal/metering"
+	"github.com/org/llm-marketplace/services/metering/internal/migrations"
+	"github.com/org/llm-marketplace/services/metering/internal/store"
+)
+
+func main() {
+	// Load configuration; without a file, defaults and METERING_* variables apply
+	configPath := config.Path()
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		panic(fmt.Sprintf("Failed to load configuration: %v", err))
+	}
+
+	logger, err := newLogger(cfg.Logging)
+	if err != nil {
+		panic(fmt.Sprintf("Failed to initialize logger: %v", err))
+	}
+	defer logger.Sync()
+
+	logger.Info("Starting LLM-Marketplace Metering Service",
+		zap.String("version", "1.0.0"),
+		zap.String("environment", os.Getenv("ENVIRONMENT")),
+		zap.String("config", configPath),
+	)
+
+	ctx := context.Background()
+
+	pool, err := store.NewPool(ctx, cfg.Postgres)
+	if err != nil {
+		logger.Fatal("Failed to connect to PostgreSQL", zap.Error(err))
+	}
+	defer pool.Close()
+
+	if cfg.Postgres.AutoMigrate {
+		if _, err := migrations.Up(ctx, pool, logger); err != nil {
+			logger.Fatal("Failed to apply migrations", zap.Error(err))
+		}
+	}
+
+	meteringService := metering.NewService(store.New(pool), logger)
+
+	alertPublisher := alerts.NewPublisher(alerts.NewWriter(cfg.Kafka.Brokers, cfg.Budgets.AlertTopic))
+	defer alertPublisher.Close()
+	meteringService.SetAlerter(alertPublisher)
+
+	// Usage events are recorded, expired event IDs pruned and budgets checked
+	// until shutdown
+	workCtx, stopWork := context.WithCancel(ctx)
+	consumerDone := make(chan struct{})
+	go func() {
+		defer close(consumerDone)
+		consumer.NewConsumer(cfg.Kafka, meteringService, logger).Start(workCtx)
+	}()
+	go meteringService.StartPruning(workCtx, cfg.Metering.PruneInterval, cfg.Metering.DedupeWindow)
+	go meteringService.StartBudgetChecks(workCtx, cfg.Budgets.CheckInterval)
+
+	// REST API
+	if cfg.Server.Mode == "production" {
+		gin.SetMode(gin.ReleaseMode)
+	}
+	router := gin.New()
+	router.HandleMethodNotAllowed = true
+	router.Use(gin.Recovery())
+
+	router.GET("/health", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{
+			"status":    "healthy",
+			"timestamp": time.Now().UTC(),
+		})
+	})
+	router.GET("/ready", api.Readiness(map[string]api.Check{
+		"postgres": pool.Ping,
+	}, 2*time.Second))
+	router.GET("/metrics", gin.WrapH(promhttp.Handler()))
+	api.RegisterRoutes(router, meteringService, cfg.Server.ConsumerHeader, cfg.Server.ProviderHeader, logger)
+
+	addr := fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Server.Port)
+	server := &http.Server{
+		Addr:         addr,
+		Handler:      router,
+		ReadTimeout:  cfg.Server.ReadTimeout,
+		WriteTimeout: cfg.Server.WriteTimeout,
+		IdleTimeout:  cfg.Server.IdleTimeout,
+	}
+	go func() {
+		logger.Info("Starting HTTP server", zap.String("address", addr))
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			logger.Fatal("Failed to start HTTP server", zap.Error(err))
+		}
+	}()
+
+	// Graceful shutdown
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+
+	logger.Info("Shutting down server...")
+
+	shutdownCtx, cancel := context.WithTimeout(ctx, cfg.Server.ShutdownTimeout)
+	defer cancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		logger.Error("Server forced to shutdown", zap.Error(err))
+	}
+
+	// An event being recorded is rolled back and redelivered on the next start
+	stopWork()
+	<-consumerDone
+
+	logger.Info("Server exited")
+}
+
+func newLogger(cfg config.LoggingConfig) (*zap.Logger, error) {
+	level, err := zapcore.ParseLevel(cfg.Level)
+	if err != nil {
+		return nil, err
+	}
+	zapConfig := zap.NewProductionConfig()
+	if cfg.Format == "console" {
+		zapConfig = zap.NewDevelopmentConfig()
+	}
+	zapConfig.Level = zap.NewAtomicLevelAt(level)
+	return zapConfig.Build()
+}
@@ -0,0 +1,59 @@
+// Package alerts publishes budget alerts to Kafka for the notification
+// service to deliver
+package alerts
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/org/llm-marketplace/pkg/marketplace"
+	"github.com/segmentio/kafka-go"
+)
+
+// writeTimeout bounds writing one alert to the brokers
+const writeTimeout = 10 * time.Second
+
+// Writer writes messages to Kafka; *kafka.Writer satisfies it
+type Writer interface {
+	WriteMessages(ctx context.Context, msgs ...kafka.Message) error
+	Close() error
+}
+
+// NewWriter creates a Kafka writer for topic
+func NewWriter(brokers []string, topic string) *kafka.Writer {
+	return &kafka.Writer{
+		Addr:         kafka.TCP(brokers...),
+		Topic:        topic,
+		Balancer:     &kafka.Hash{},
+		RequiredAcks: kafka.RequireAll,
+	}
+}
+
+// Publisher writes each alert as it is raised, keyed by consumer ID. Alerts
+// are rare, so they are not batched; a failed write fails the alert, which
+// is retried at the next budget check.
+type Publisher struct {
+	writer Writer
+}
+
+// NewPublisher creates a publisher writing with writer
+func NewPublisher(writer Writer) *Publisher {
+	return &Publisher{writer: writer}
+}
+
+// Alert publishes alert
+func (p *Publisher) Alert(ctx context.Context, alert *marketplace.BudgetAlert) error {
+	value, err := json.Marshal(alert)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, writeTimeout)
+	defer cancel()
+	return p.writer.WriteMessages(ctx, kafka.Message{Key: []byte(alert.ConsumerID), Value: value})
+}
+
+// Close closes the writer
+func (p *Publisher) Close() error {
+	return p.writer.Close()
+}
@@ -0,0 +1,227 @@
+// Package api serves the metering REST API
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/org/llm-marketplace/pkg/marketplace/problem"
+	"go.uber.org/zap"
+
+	"github.com/org/llm-marketplace/services/metering/internal/metering"
+)
+
+// dateLayout is accepted for from and to besides RFC 3339 timestamps
+const dateLayout = "2006-01-02"
+
+// RegisterRoutes registers the API routes. consumerHeader and providerHeader
+// name the headers carrying the caller the gateway authenticated: consumers
+// only see their own usage and providers only the usage of their services.
+// Requests with neither are trusted as operators.
+func RegisterRoutes(router *gin.Engine, svc *metering.Service, consumerHeader, providerHeader string, logger *zap.Logger) {
+	h := &handlers{svc: svc, consumerHeader: consumerHeader, providerHeader: providerHeader, logger: logger}
+
+	api := router.Group("/api/v1")
+	{
+		api.GET("/usage", h.usage)
+		api.GET("/consumers/:id/invoice-preview", h.invoicePreview)
+		api.GET("/consumers/:id/budget", h.getBudget)
+		api.PUT("/consumers/:id/budget", h.putBudget)
+		api.DELETE("/consumers/:id/budget", h.deleteBudget)
+	}
+
+	router.NoRoute(func(c *gin.Context) {
+		abort(c, problem.NotFound, "No route matches "+c.Request.URL.Path)
+	})
+	router.NoMethod(func(c *gin.Context) {
+		abort(c, problem.MethodNotAllowed, c.Request.Method+" is not supported on "+c.Request.URL.Path)
+	})
+}
+
+type handlers struct {
+	svc            *metering.Service
+	consumerHeader string
+	providerHeader string
+	logger         *zap.Logger
+}
+
+// usage handles GET /api/v1/usage
+func (h *handlers) usage(c *gin.Context) {
+	filter := metering.UsageFilter{
+		ConsumerID:  c.Query("consumer_id"),
+		ServiceID:   c.Query("service_id"),
+		ProviderID:  c.Query("provider_id"),
+		Granularity: c.Query("granularity"),
+	}
+	var err error
+	if filter.From, err = parseTime(c.Query("from")); err != nil {
+		abort(c, problem.InvalidRequest, "from: "+err.Error())
+		return
+	}
+	if filter.To, err = parseTime(c.Query("to")); err != nil {
+		abort(c, problem.InvalidRequest, "to: "+err.Error())
+		return
+	}
+
+	// Callers are held to their own usage
+	if consumer := c.GetHeader(h.consumerHeader); consumer != "" {
+		if filter.ConsumerID != "" && filter.ConsumerID != consumer {
+			abortWithError(c, fmt.Errorf("%w: consumers can only read their own usage", metering.ErrForbidden), h.logger)
+			return
+		}
+		filter.ConsumerID = consumer
+	} else if provider := c.GetHeader(h.providerHeader); provider != "" {
+		if filter.ProviderID != "" && filter.ProviderID != provider {
+			abortWithError(c, fmt.Errorf("%w: providers can only read the usage of their own services", metering.ErrForbidden), h.logger)
+			return
+		}
+		filter.ProviderID = provider
+	}
+
+	rollups, err := h.svc.Usage(c.Request.Context(), filter)
+	if err != nil {
+		abortWithError(c, err, h.logger)
+		return
+	}
+	if rollups == nil {
+		rollups = []metering.Rollup{}
+	}
+	c.JSON(http.StatusOK, gin.H{"usage": rollups, "total": len(rollups)})
+}
+
+// invoicePreview handles GET /api/v1/consumers/:id/invoice-preview
+func (h *handlers) invoicePreview(c *gin.Context) {
+	id := c.Param("id")
+	if c.GetHeader(h.consumerHeader) == "" && c.GetHeader(h.providerHeader) != "" {
+		abortWithError(c, fmt.Errorf("%w: providers can't read consumer invoices", metering.ErrForbidden), h.logger)
+		return
+	}
+	if consumer := c.GetHeader(h.consumerHeader); consumer != "" && consumer != id {
+		abortWithError(c, fmt.Errorf("%w: consumers can only read their own invoices", metering.ErrForbidden), h.logger)
+		return
+	}
+
+	invoice, err := h.svc.InvoicePreview(c.Request.Context(), id, c.Query("period"))
+	if err != nil {
+		abortWithError(c, err, h.logger)
+		return
+	}
+	c.JSON(http.StatusOK, invoice)
+}
+
+// getBudget handles GET /api/v1/consumers/:id/budget
+func (h *handlers) getBudget(c *gin.Context) {
+	id := c.Param("id")
+	if c.GetHeader(h.consumerHeader) == "" && c.GetHeader(h.providerHeader) != "" {
+		abortWithError(c, fmt.Errorf("%w: providers can't read consumer budgets", metering.ErrForbidden), h.logger)
+		return
+	}
+	if consumer := c.GetHeader(h.consumerHeader); consumer != "" && consumer != id {
+		abortWithError(c, fmt.Errorf("%w: consumers can only read their own budget", metering.ErrForbidden), h.logger)
+		return
+	}
+
+	status, err := h.svc.Budget(c.Request.Context(), id)
+	if err != nil {
+		abortWithError(c, err, h.logger)
+		return
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+// budgetRequest is the body of PUT /api/v1/consumers/:id/budget
+type budgetRequest struct {
+	MonthlyCap float64 `json:"monthly_cap"`
+	Currency   string  `json:"currency"`
+	HardStop   bool    `json:"hard_stop"`
+}
+
+// putBudget handles PUT /api/v1/consumers/:id/budget
+func (h *handlers) putBudget(c *gin.Context) {
+	if !h.operator(c, "set budgets") {
+		return
+	}
+	var req budgetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		abort(c, problem.InvalidRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	status, err := h.svc.SetBudget(c.Request.Context(), metering.Budget{
+		ConsumerID: c.Param("id"),
+		MonthlyCap: req.MonthlyCap,
+		Currency:   req.Currency,
+		HardStop:   req.HardStop,
+	})
+	if err != nil {
+		abortWithError(c, err, h.logger)
+		return
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+// deleteBudget handles DELETE /api/v1/consumers/:id/budget
+func (h *handlers) deleteBudget(c *gin.Context) {
+	if !h.operator(c, "remove budgets") {
+		return
+	}
+	if err := h.svc.DeleteBudget(c.Request.Context(), c.Param("id")); err != nil {
+		abortWithError(c, err, h.logger)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// operator aborts with 403 unless the request carries neither caller header
+func (h *handlers) operator(c *gin.Context, action string) bool {
+	if c.GetHeader(h.consumerHeader) != "" || c.GetHeader(h.providerHeader) != "" {
+		abortWithError(c, fmt.Errorf("%w: only operators can %s", metering.ErrForbidden, action), h.logger)
+		return false
+	}
+	return true
+}
+
+// parseTime reads an RFC 3339 timestamp or a date, which is midnight UTC
+func parseTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t.UTC(), nil
+	}
+	if t, err := time.Parse(dateLayout, s); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("%q is neither an RFC 3339 timestamp nor a YYYY-MM-DD date", s)
+}
+
+// Check is a readiness check of one dependency
+type Check func(ctx context.Context) error
+
+// Readiness reports 200 when every check passes and 503 naming the failing
+// ones otherwise
+func Readiness(checks map[string]Check, timeout time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+
+		status := http.StatusOK
+		results := make(map[string]string, len(checks))
+		for name, check := range checks {
+			if err := check(ctx); err != nil {
+				status = http.StatusServiceUnavailable
+				results[name] = err.Error()
+				continue
+			}
+			results[name] = "ok"
+		}
+		ready := "ready"
+		if status != http.StatusOK {
+			ready = "not_ready"
+		}
+		c.JSON(status, gin.H{"status": ready, "checks": results, "timestamp": time.Now().UTC()})
+	}
+}
@@ -0,0 +1,155 @@
+// Package config loads the metering configuration from built-in defaults, an
+// optional YAML file and METERING_* environment variables, in that order.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/org/llm-marketplace/pkg/marketplace/envconfig"
+	"gopkg.in/yaml.v3"
+)
+
+type Config struct {
+	Server   ServerConfig   `yaml:"server"`
+	Postgres PostgresConfig `yaml:"postgres"`
+	Kafka    KafkaConfig    `yaml:"kafka"`
+	Metering MeteringConfig `yaml:"metering"`
+	Budgets  BudgetsConfig  `yaml:"budgets"`
+	Logging  LoggingConfig  `yaml:"logging"`
+}
+
+type ServerConfig struct {
+	Host         string        `yaml:"host"`
+	Port         int           `yaml:"port"`
+	Mode         string        `yaml:"mode"` // development, production
+	ReadTimeout  time.Duration `yaml:"read_timeout"`
+	WriteTimeout time.Duration `yaml:"write_timeout"`
+	IdleTimeout  time.Duration `yaml:"idle_timeout"`
+
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+
+	// ConsumerHeader and ProviderHeader carry the authenticated caller, set
+	// by the gateway. A consumer only sees its own usage and a provider only
+	// the usage of its services; requests with neither act as an operator.
+	ConsumerHeader string `yaml:"consumer_header"`
+	ProviderHeader string `yaml:"provider_header"`
+}
+
+type PostgresConfig struct {
+	Host        string        `yaml:"host"`
+	Port        int           `yaml:"port"`
+	Database    string        `yaml:"database"`
+	User        string        `yaml:"user"`
+	Password    string        `yaml:"password"`
+	SSLMode     string        `yaml:"ssl_mode"`
+	MaxConns    int           `yaml:"max_conns"`
+	MinConns    int           `yaml:"min_conns"`
+	ConnTimeout time.Duration `yaml:"conn_timeout"`
+	AutoMigrate bool          `yaml:"auto_migrate"` // Apply pending migrations at startup
+}
+
+// DSN returns the connection string for the pool
+func (c PostgresConfig) DSN() string {
+	return fmt.Sprintf("host=%s port=%d dbname=%s user=%s password='%s' sslmode=%s",
+		c.Host, c.Port, c.Database, c.User, dsnEscaper.Replace(c.Password), c.SSLMode)
+}
+
+var dsnEscaper = strings.NewReplacer(`\`, `\\`, `'`, `\'`)
+
+// KafkaConfig is where the gateway's usage events are read from
+type KafkaConfig struct {
+	Brokers         []string      `yaml:"brokers"`
+	Topic           string        `yaml:"topic"`
+	ConsumerGroup   string        `yaml:"consumer_group"`
+	RetryBackoff    time.Duration `yaml:"retry_backoff"`     // Delay after the first failure to record an event, doubled after each
+	MaxRetryBackoff time.Duration `yaml:"max_retry_backoff"` // Failed events are retried until they succeed; later events wait
+}
+
+// MeteringConfig controls how usage is recorded
+type MeteringConfig struct {
+	// DedupeWindow is how long recorded event IDs are kept to drop
+	// redelivered events. It should exceed the usage topic's retention.
+	DedupeWindow  time.Duration `yaml:"dedupe_window"`
+	PruneInterval time.Duration `yaml:"prune_interval"` // How often expired event IDs are removed
+}
+
+// BudgetsConfig controls budget alerts, which are published to AlertTopic
+// on the Kafka brokers usage is read from
+type BudgetsConfig struct {
+	CheckInterval time.Duration `yaml:"check_interval"` // How often spend is compared to budget thresholds
+	AlertTopic    string        `yaml:"alert_topic"`
+}
+
+type LoggingConfig struct {
+	Level  string `yaml:"level"`  // debug, info, warn, error
+	Format string `yaml:"format"` // json, console
+}
+
+// DefaultPath is the config file used when CONFIG_PATH is not set
+const DefaultPath = "config.yaml"
+
+// Path returns the config file to load: CONFIG_PATH if set, otherwise
+// config.yaml when it exists in the working directory. It is empty when the
+// service is configured by defaults and environment variables alone.
+func Path() string {
+	if path := os.Getenv("CONFIG_PATH"); path != "" {
+		return path
+	}
+	if _, err := os.Stat(DefaultPath); err == nil {
+		return DefaultPath
+	}
+	return ""
+}
+
+// Load builds the configuration from the built-in defaults, overridden by the
+// file at path, if any, then by METERING_* environment variables
+func Load(path string) (*Config, error) {
+	var cfg Config
+	cfg.setDefaults()
+
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read config file: %w", err)
+		}
+		if err := yaml.Unmarshal([]byte(os.ExpandEnv(string(data))), &cfg); err != nil {
+			return nil, fmt.Errorf("failed to parse config: %w", err)
+		}
+	}
+
+	if err := envconfig.Apply(&cfg, "METERING_", os.Getenv); err != nil {
+		return nil, fmt.Errorf("invalid environment override: %w", err)
+	}
+
+	if err := validate(&cfg); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+	return &cfg, nil
+}
+
+func validate(cfg *Config) error {
+	var errs []error
+	if cfg.Server.Port <= 0 || cfg.Server.Port > 65535 {
+		errs = append(errs, fmt.Errorf("server.port %d is not a valid port", cfg.Server.Port))
+	}
+	if cfg.Postgres.Host == "" || cfg.Postgres.Database == "" {
+		errs = append(errs, errors.New("postgres.host and postgres.database are required"))
+	}
+	if len(cfg.Kafka.Brokers) == 0 || cfg.Kafka.Topic == "" || cfg.Kafka.ConsumerGroup == "" {
+		errs = append(errs, errors.New("kafka.brokers, kafka.topic and kafka.consumer_group are required"))
+	}
+	if cfg.Kafka.RetryBackoff <= 0 || cfg.Kafka.MaxRetryBackoff < cfg.Kafka.RetryBackoff {
+		errs = append(errs, errors.New("kafka.retry_backoff must be positive and no longer than kafka.max_retry_backoff"))
+	}
+	if cfg.Metering.DedupeWindow <= 0 || cfg.Metering.PruneInterval <= 0 {
+		errs = append(errs, errors.New("metering.dedupe_window and metering.prune_interval must be positive"))
+	}
+	if cfg.Budgets.CheckInterval <= 0 || cfg.Budgets.AlertTopic == "" {
+		errs = append(errs, errors.New("budgets.check_interval must be positive and budgets.alert_topic is required"))
+	}
+	return errors.Join(errs...)
+}
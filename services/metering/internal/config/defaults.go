@@ -0,0 +1,47 @@
+package config
+
+import (
+	"time"
+
+	"github.com/org/llm-marketplace/pkg/marketplace"
+)
+
+// setDefaults fills in the settings used when neither the config file nor the
+// environment sets them. They match config.yaml, with dependencies expected
+// on localhost.
+func (c *Config) setDefaults() {
+	c.Server.Host = "0.0.0.0"
+	c.Server.Port = 3030
+	c.Server.Mode = "development"
+	c.Server.ReadTimeout = 30 * time.Second
+	c.Server.WriteTimeout = 30 * time.Second
+	c.Server.IdleTimeout = 120 * time.Second
+	c.Server.ShutdownTimeout = 30 * time.Second
+	c.Server.ConsumerHeader = "X-Consumer-ID"
+	c.Server.ProviderHeader = "X-Provider-ID"
+
+	c.Postgres.Host = "localhost"
+	c.Postgres.Port = 5432
+	c.Postgres.Database = "metering"
+	c.Postgres.User = "metering"
+	c.Postgres.SSLMode = "prefer"
+	c.Postgres.MaxConns = 20
+	c.Postgres.MinConns = 2
+	c.Postgres.ConnTimeout = 5 * time.Second
+	c.Postgres.AutoMigrate = true
+
+	c.Kafka.Brokers = []string{"localhost:9092"}
+	c.Kafka.Topic = marketplace.UsageTopic
+	c.Kafka.ConsumerGroup = "metering"
+	c.Kafka.RetryBackoff = time.Second
+	c.Kafka.MaxRetryBackoff = time.Minute
+
+	c.Metering.DedupeWindow = 7 * 24 * time.Hour
+	c.Metering.PruneInterval = time.Hour
+
+	c.Budgets.CheckInterval = time.Minute
+	c.Budgets.AlertTopic = marketplace.BudgetAlertTopic
+
+	c.Logging.Level = "info"
+	c.Logging.Format = "json"
+}
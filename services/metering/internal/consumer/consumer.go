@@ -0,0 +1,131 @@
+// Package consumer reads the usage events the consumption gateway publishes
+// and records them
+package consumer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/org/llm-marketplace/pkg/marketplace"
+	"github.com/segmentio/kafka-go"
+	"go.uber.org/zap"
+
+	"github.com/org/llm-marketplace/services/metering/internal/config"
+	"github.com/org/llm-marketplace/services/metering/internal/metering"
+)
+
+// Reader fetches usage events; *kafka.Reader satisfies it
+type Reader interface {
+	FetchMessage(ctx context.Context) (kafka.Message, error)
+	CommitMessages(ctx context.Context, msgs ...kafka.Message) error
+	Close() error
+}
+
+// Recorder records usage events; *metering.Service satisfies it
+type Recorder interface {
+	Record(ctx context.Context, event *marketplace.UsageEvent) error
+}
+
+// Consumer records usage events in order. Offsets are committed once an
+// event is recorded or skipped, so each event is recorded at least once;
+// the recorder drops redelivered events.
+type Consumer struct {
+	reader   Reader
+	recorder Recorder
+	config   config.KafkaConfig
+	logger   *zap.Logger
+}
+
+// NewConsumer creates a consumer reading from Kafka
+func NewConsumer(cfg config.KafkaConfig, recorder Recorder, logger *zap.Logger) *Consumer {
+	return &Consumer{
+		reader: kafka.NewReader(kafka.ReaderConfig{
+			Brokers:  cfg.Brokers,
+			Topic:    cfg.Topic,
+			GroupID:  cfg.ConsumerGroup,
+			MinBytes: 1,
+			MaxBytes: 10 << 20,
+		}),
+		recorder: recorder,
+		config:   cfg,
+		logger:   logger,
+	}
+}
+
+// SetReader replaces the Kafka reader
+func (c *Consumer) SetReader(reader Reader) {
+	c.reader = reader
+}
+
+// Start records events until ctx is cancelled. An event that fails for a
+// transient reason is retried with backoff; the events after it wait, so no
+// usage is lost while the database is unavailable.
+func (c *Consumer) Start(ctx context.Context) {
+	defer c.reader.Close()
+
+	c.logger.Info("Usage consumer started", zap.String("topic", c.config.Topic))
+
+	for {
+		msg, err := c.reader.FetchMessage(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				c.logger.Info("Usage consumer stopped")
+				return
+			}
+			c.logger.Warn("Failed to fetch usage event", zap.Error(err))
+			if !sleep(ctx, time.Second) {
+				return
+			}
+			continue
+		}
+
+		backoff := c.config.RetryBackoff
+		for {
+			err := c.Apply(ctx, msg.Value)
+			if err == nil {
+				break
+			}
+			if errors.Is(err, metering.ErrInvalidEvent) {
+				c.logger.Error("Skipping usage event", zap.Int64("offset", msg.Offset), zap.String("key", string(msg.Key)), zap.Error(err))
+				break
+			}
+			c.logger.Warn("Failed to record usage event, retrying",
+				zap.Int64("offset", msg.Offset),
+				zap.String("key", string(msg.Key)),
+				zap.Duration("retry_in", backoff),
+				zap.Error(err),
+			)
+			if !sleep(ctx, backoff) {
+				return
+			}
+			backoff = min(backoff*2, c.config.MaxRetryBackoff)
+		}
+
+		if err := c.reader.CommitMessages(ctx, msg); err != nil && ctx.Err() == nil {
+			// The event is recorded; on redelivery its ID is already known
+			c.logger.Warn("Failed to commit usage offset", zap.Error(err))
+		}
+	}
+}
+
+// Apply records one usage event. It returns an error wrapping
+// metering.ErrInvalidEvent for an event that can never be recorded.
+func (c *Consumer) Apply(ctx context.Context, payload []byte) error {
+	var event marketplace.UsageEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return fmt.Errorf("%w: malformed event: %v", metering.ErrInvalidEvent, err)
+	}
+	return c.recorder.Record(ctx, &event)
+}
+
+func sleep(ctx context.Context, d time.Duration) bool {
+	select {
+	case <-time.After(d):
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,200 @@
+package metering
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"time"
+
+	"github.com/org/llm-marketplace/pkg/marketplace"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+)
+
+var budgetAlerts = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "metering_budget_alerts_total",
+	Help: "Budget alerts by threshold and result: sent or failed",
+}, []string{"threshold", "result"})
+
+var currencyCode = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// Budget caps what a consumer, typically a team, spends in a calendar month
+// in one currency. Usage charged in other currencies doesn't count.
+type Budget struct {
+	ConsumerID string    `json:"consumer_id"`
+	MonthlyCap float64   `json:"monthly_cap"`
+	Currency   string    `json:"currency"`
+	HardStop   bool      `json:"hard_stop"` // Calls are refused once the cap is reached
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// BudgetStatus is a budget with the consumer's spend in the current month
+type BudgetStatus struct {
+	Budget
+	Period   string  `json:"period"`
+	Spend    float64 `json:"spend"`
+	Used     float64 `json:"used"`     // Percent of the cap spent
+	Exceeded bool    `json:"exceeded"` // The cap is reached
+	Blocked  bool    `json:"blocked"`  // The cap is reached and enforced
+}
+
+// BudgetStore persists budgets and the alerts sent for them
+type BudgetStore interface {
+	PutBudget(ctx context.Context, budget *Budget) error
+	// GetBudget returns ErrNotFound for a consumer without a budget
+	GetBudget(ctx context.Context, consumerID string) (*Budget, error)
+	DeleteBudget(ctx context.Context, consumerID string) error
+	ListBudgets(ctx context.Context) ([]Budget, error)
+	// RecordAlert records that consumerID was alerted at threshold in period
+	// and calls send, unless the alert was already recorded. The record is
+	// kept only if send succeeds. It reports whether the alert was sent.
+	RecordAlert(ctx context.Context, consumerID, period string, threshold int, send func() error) (bool, error)
+}
+
+// Alerter delivers budget alerts
+type Alerter interface {
+	Alert(ctx context.Context, alert *marketplace.BudgetAlert) error
+}
+
+// SetAlerter sets where budget alerts are sent. Without one, thresholds are
+// not alerted.
+func (s *Service) SetAlerter(alerter Alerter) {
+	s.alerter = alerter
+}
+
+// SetBudget creates or replaces a consumer's budget
+func (s *Service) SetBudget(ctx context.Context, budget Budget) (*BudgetStatus, error) {
+	if budget.Currency == "" {
+		budget.Currency = defaultCurrency
+	}
+	switch {
+	case budget.ConsumerID == "":
+		return nil, fmt.Errorf("%w: consumer_id is required", ErrInvalidQuery)
+	case !(budget.MonthlyCap > 0) || math.IsInf(budget.MonthlyCap, 0):
+		return nil, fmt.Errorf("%w: monthly_cap must be positive", ErrInvalidQuery)
+	case !currencyCode.MatchString(budget.Currency):
+		return nil, fmt.Errorf("%w: currency must be an ISO 4217 code such as USD", ErrInvalidQuery)
+	}
+	budget.UpdatedAt = s.now().UTC()
+	if err := s.store.PutBudget(ctx, &budget); err != nil {
+		return nil, err
+	}
+	s.logger.Info("Budget set",
+		zap.String("consumer_id", budget.ConsumerID),
+		zap.Float64("monthly_cap", budget.MonthlyCap),
+		zap.String("currency", budget.Currency),
+		zap.Bool("hard_stop", budget.HardStop),
+	)
+	return s.budgetStatus(ctx, &budget)
+}
+
+// Budget returns a consumer's budget and its spend this month
+func (s *Service) Budget(ctx context.Context, consumerID string) (*BudgetStatus, error) {
+	budget, err := s.store.GetBudget(ctx, consumerID)
+	if err != nil {
+		return nil, err
+	}
+	return s.budgetStatus(ctx, budget)
+}
+
+// DeleteBudget removes a consumer's budget
+func (s *Service) DeleteBudget(ctx context.Context, consumerID string) error {
+	return s.store.DeleteBudget(ctx, consumerID)
+}
+
+func (s *Service) budgetStatus(ctx context.Context, budget *Budget) (*BudgetStatus, error) {
+	invoice, err := s.InvoicePreview(ctx, budget.ConsumerID, "")
+	if err != nil {
+		return nil, err
+	}
+	spend := invoice.Totals[budget.Currency]
+	status := &BudgetStatus{
+		Budget:   *budget,
+		Period:   invoice.Period,
+		Spend:    spend,
+		Used:     math.Round(spend/budget.MonthlyCap*10000) / 100,
+		Exceeded: spend >= budget.MonthlyCap,
+	}
+	status.Blocked = status.Exceeded && budget.HardStop
+	return status, nil
+}
+
+// CheckBudgets alerts every consumer whose spend this month has reached a
+// threshold of its budget it wasn't yet alerted at
+func (s *Service) CheckBudgets(ctx context.Context) error {
+	if s.alerter == nil {
+		return nil
+	}
+	budgets, err := s.store.ListBudgets(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list budgets: %w", err)
+	}
+
+	var errs []error
+	for i := range budgets {
+		status, err := s.budgetStatus(ctx, &budgets[i])
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		for _, threshold := range marketplace.BudgetAlertThresholds {
+			if status.Used < float64(threshold) {
+				break
+			}
+			if err := s.alert(ctx, status, threshold); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (s *Service) alert(ctx context.Context, status *BudgetStatus, threshold int) error {
+	alert := &marketplace.BudgetAlert{
+		ID:         fmt.Sprintf("%s/%s/%d", status.ConsumerID, status.Period, threshold),
+		OccurredAt: s.now().UTC(),
+		ConsumerID: status.ConsumerID,
+		Period:     status.Period,
+		Threshold:  threshold,
+		Spend:      status.Spend,
+		Cap:        status.MonthlyCap,
+		Currency:   status.Currency,
+		HardStop:   status.HardStop,
+	}
+	sent, err := s.store.RecordAlert(ctx, status.ConsumerID, status.Period, threshold, func() error {
+		return s.alerter.Alert(ctx, alert)
+	})
+	label := fmt.Sprint(threshold)
+	if err != nil {
+		budgetAlerts.WithLabelValues(label, "failed").Inc()
+		return fmt.Errorf("failed to alert %s at %d%%: %w", status.ConsumerID, threshold, err)
+	}
+	if sent {
+		budgetAlerts.WithLabelValues(label, "sent").Inc()
+		s.logger.Info("Budget alert sent",
+			zap.String("consumer_id", status.ConsumerID),
+			zap.Int("threshold", threshold),
+			zap.Float64("spend", status.Spend),
+		)
+	}
+	return nil
+}
+
+// StartBudgetChecks checks budgets every interval until ctx is cancelled
+func (s *Service) StartBudgetChecks(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if err := s.CheckBudgets(ctx); err != nil && ctx.Err() == nil {
+			s.logger.Warn("Failed to check budgets", zap.Error(err))
+		}
+	}
+}
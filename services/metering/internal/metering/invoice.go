@@ -0,0 +1,255 @@
+package metering
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/org/llm-marketplace/pkg/marketplace"
+)
+
+// PeriodLayout is how billing periods are named: calendar months in UTC
+const PeriodLayout = "2006-01"
+
+// defaultCurrency is the currency of pricing that doesn't name one
+const defaultCurrency = "USD"
+
+// freeModel is the pricing model of services that don't charge
+const freeModel = "free"
+
+// Invoice is what a consumer owes for its usage in a billing period. Until
+// the period ends it is a preview of the usage so far.
+type Invoice struct {
+	ConsumerID  string             `json:"consumer_id"`
+	Period      string             `json:"period"`
+	From        time.Time          `json:"from"`
+	To          time.Time          `json:"to"`
+	Final       bool               `json:"final"` // The period has ended
+	Lines       []InvoiceLine      `json:"lines"`
+	Totals      map[string]float64 `json:"totals"` // By currency
+	GeneratedAt time.Time          `json:"generated_at"`
+}
+
+// InvoiceLine is the usage of one service charged in one currency
+type InvoiceLine struct {
+	ServiceID  string   `json:"service_id"`
+	ProviderID string   `json:"provider_id"`
+	Currency   string   `json:"currency"`
+	Requests   int64    `json:"requests"` // Billable calls
+	Tokens     int64    `json:"tokens"`   // Tokens of billable calls
+	Charges    []Charge `json:"charges"`
+	Amount     float64  `json:"amount"`
+
+	// Unpriced is set when some of the usage couldn't be priced: the calls
+	// carried no pricing, or its unit is counted in neither tokens nor
+	// requests. That usage is not charged.
+	Unpriced bool `json:"unpriced,omitempty"`
+}
+
+// Charge is the usage charged at one rate
+type Charge struct {
+	Tier     string  `json:"tier,omitempty"`
+	Rate     float64 `json:"rate"`
+	Unit     string  `json:"unit"`
+	Quantity float64 `json:"quantity"` // In units
+	Amount   float64 `json:"amount"`
+}
+
+// rate is a rate usage is charged at, with the band of the period's usage it
+// covers, in tokens or requests
+type rate struct {
+	tier   string
+	rate   float64
+	unit   string
+	size   float64 // Tokens or requests per unit
+	lo, hi float64
+}
+
+// basis is what pricing counts: tokens or requests
+type basis int
+
+const (
+	byTokens basis = iota
+	byRequests
+)
+
+func unitBasis(unit string) (basis, float64, bool) {
+	if size, ok := marketplace.TokensPerUnit(unit); ok {
+		return byTokens, size, true
+	}
+	if size, ok := marketplace.RequestsPerUnit(unit); ok {
+		return byRequests, size, true
+	}
+	return 0, 0, false
+}
+
+// rates returns the rates of p and what they count. Graduated tiers are
+// charged by band; otherwise usage is charged at the headline rate, or the
+// first tier's when there is no headline unit. It reports false when the
+// pricing's units can't be counted.
+func rates(p *marketplace.PricingInfo) ([]rate, basis, bool) {
+	graduated := false
+	for _, t := range p.Tiers {
+		graduated = graduated || t.UpTo > 0
+	}
+
+	if !graduated {
+		r := rate{rate: p.Rate, unit: p.Unit, hi: math.Inf(1)}
+		if p.Unit == "" && len(p.Tiers) > 0 {
+			t := p.Tiers[0]
+			r.tier, r.rate, r.unit = t.Tier, t.Rate, t.Unit
+		}
+		b, size, ok := unitBasis(r.unit)
+		r.size = size
+		return []rate{r}, b, ok
+	}
+
+	var (
+		first basis
+		lo    float64
+		out   = make([]rate, 0, len(p.Tiers))
+	)
+	for i, t := range p.Tiers {
+		b, size, ok := unitBasis(t.Unit)
+		if !ok || (i > 0 && b != first) {
+			return nil, 0, false
+		}
+		first = b
+		hi := math.Inf(1)
+		if t.UpTo > 0 {
+			hi = t.UpTo * size
+		}
+		out = append(out, rate{tier: t.Tier, rate: t.Rate, unit: t.Unit, size: size, lo: lo, hi: hi})
+		lo = hi
+	}
+	return out, first, true
+}
+
+// InvoicePreview prices the usage of consumerID in period, a month named
+// YYYY-MM in UTC, or the current month when period is empty. Each call is
+// charged under the pricing the service had when it was made. Graduated
+// tiers count a consumer's usage of a service from the start of the period.
+func (s *Service) InvoicePreview(ctx context.Context, consumerID, period string) (*Invoice, error) {
+	now := s.now().UTC()
+	from := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	if period != "" {
+		var err error
+		if from, err = time.Parse(PeriodLayout, period); err != nil {
+			return nil, fmt.Errorf("%w: period must be a month as YYYY-MM", ErrInvalidQuery)
+		}
+	}
+	if from.After(now) {
+		return nil, fmt.Errorf("%w: period %s hasn't started", ErrInvalidQuery, from.Format(PeriodLayout))
+	}
+	to := from.AddDate(0, 1, 0)
+
+	usage, err := s.store.PricedUsage(ctx, consumerID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	invoice := price(usage)
+	invoice.ConsumerID = consumerID
+	invoice.Period = from.Format(PeriodLayout)
+	invoice.From, invoice.To = from, to
+	invoice.Final = !now.Before(to)
+	invoice.GeneratedAt = now
+	return invoice, nil
+}
+
+// price charges usage, which is in time order
+func price(usage []PricedUsage) *Invoice {
+	type lineKey struct{ service, currency string }
+	type positionKey struct {
+		service string
+		basis   basis
+	}
+
+	var (
+		lines    = map[lineKey]*InvoiceLine{}
+		charges  = map[lineKey][]*Charge{}   // In the order they were first charged
+		position = map[positionKey]float64{} // Usage of a service so far in the period
+	)
+	charge := func(key lineKey, r rate) *Charge {
+		for _, c := range charges[key] {
+			if c.Tier == r.tier && c.Unit == r.unit && c.Rate == r.rate {
+				return c
+			}
+		}
+		c := &Charge{Tier: r.tier, Rate: r.rate, Unit: r.unit}
+		charges[key] = append(charges[key], c)
+		return c
+	}
+	for _, u := range usage {
+		currency := defaultCurrency
+		if u.Pricing != nil && u.Pricing.Currency != "" {
+			currency = u.Pricing.Currency
+		}
+		key := lineKey{u.ServiceID, currency}
+		line, ok := lines[key]
+		if !ok {
+			line = &InvoiceLine{ServiceID: u.ServiceID, ProviderID: u.ProviderID, Currency: currency}
+			lines[key] = line
+		}
+		line.Requests += u.Requests
+		line.Tokens += u.Tokens
+
+		if u.Pricing == nil {
+			line.Unpriced = line.Unpriced || u.Requests > 0
+			continue
+		}
+		if u.Pricing.Model == freeModel {
+			continue
+		}
+		rs, b, ok := rates(u.Pricing)
+		if !ok {
+			line.Unpriced = true
+			continue
+		}
+
+		quantity := float64(u.Tokens)
+		if b == byRequests {
+			quantity = float64(u.Requests)
+		}
+		pos := positionKey{u.ServiceID, b}
+		start, end := position[pos], position[pos]+quantity
+		position[pos] = end
+
+		for _, r := range rs {
+			covered := math.Min(end, r.hi) - math.Max(start, r.lo)
+			if covered <= 0 {
+				continue
+			}
+			charge(key, r).Quantity += covered / r.size
+		}
+	}
+
+	invoice := &Invoice{Lines: make([]InvoiceLine, 0, len(lines)), Totals: map[string]float64{}}
+	for key, line := range lines {
+		line.Charges = make([]Charge, 0, len(charges[key]))
+		for _, c := range charges[key] {
+			c.Quantity = round(c.Quantity)
+			c.Amount = round(c.Quantity * c.Rate)
+			line.Amount += c.Amount
+			line.Charges = append(line.Charges, *c)
+		}
+		line.Amount = round(line.Amount)
+		invoice.Totals[line.Currency] = round(invoice.Totals[line.Currency] + line.Amount)
+		invoice.Lines = append(invoice.Lines, *line)
+	}
+	sort.Slice(invoice.Lines, func(i, j int) bool {
+		a, b := invoice.Lines[i], invoice.Lines[j]
+		if a.ServiceID != b.ServiceID {
+			return a.ServiceID < b.ServiceID
+		}
+		return a.Currency < b.Currency
+	})
+	return invoice
+}
+
+// round rounds an amount to a millionth of a currency unit
+func round(v float64) float64 {
+	return math.Round(v*1e6) / 1e6
+}
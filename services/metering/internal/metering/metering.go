@@ -0,0 +1,247 @@
+// Package metering records the usage events the consumption gateway
+// publishes into hourly and daily rollups per consumer and service, and
+// prices a consumer's usage for a billing period with the pricing each call
+// was made under.
+package metering
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/org/llm-marketplace/pkg/marketplace"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+)
+
+// Rollup granularities
+const (
+	Hourly = "hour"
+	Daily  = "day"
+)
+
+// maxRange bounds the period a usage query may cover at each granularity
+var maxRange = map[string]time.Duration{
+	Hourly: 31 * 24 * time.Hour,
+	Daily:  366 * 24 * time.Hour,
+}
+
+var (
+	// ErrInvalidEvent marks a usage event that can never be recorded
+	ErrInvalidEvent = errors.New("invalid usage event")
+	// ErrInvalidQuery is returned for a usage query or period that can't be
+	// answered
+	ErrInvalidQuery = errors.New("invalid query")
+	// ErrForbidden is returned when a caller asks for usage that isn't theirs
+	ErrForbidden = errors.New("forbidden")
+	// ErrNotFound is returned for a consumer without a budget
+	ErrNotFound = errors.New("not found")
+)
+
+var eventsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "metering_events_total",
+	Help: "Usage events by result: recorded, duplicate or invalid",
+}, []string{"result"})
+
+// Rollup is the usage of a consumer of one service in an hour or a day.
+// Errors counts the calls the service failed with a 5xx status; they are not
+// billed and their tokens are not counted.
+type Rollup struct {
+	Start             time.Time `json:"start"`
+	ConsumerID        string    `json:"consumer_id"`
+	ServiceID         string    `json:"service_id"`
+	ProviderID        string    `json:"provider_id"`
+	Requests          int64     `json:"requests"`
+	Errors            int64     `json:"errors"`
+	EstimatedRequests int64     `json:"estimated_requests"` // Calls whose tokens were estimated by the gateway
+	PromptTokens      int64     `json:"prompt_tokens"`
+	CompletionTokens  int64     `json:"completion_tokens"`
+	TotalTokens       int64     `json:"total_tokens"`
+	AvgLatencyMS      float64   `json:"avg_latency_ms"`
+	MaxLatencyMS      float64   `json:"max_latency_ms"`
+	AvgUpstreamMS     float64   `json:"avg_upstream_latency_ms"`
+
+	// Latency sums, from which the averages are computed
+	LatencyMSSum  float64 `json:"-"`
+	UpstreamMSSum float64 `json:"-"`
+}
+
+// UsageFilter selects rollups. Empty IDs match everything.
+type UsageFilter struct {
+	ConsumerID  string
+	ServiceID   string
+	ProviderID  string
+	Granularity string
+	From        time.Time // Inclusive
+	To          time.Time // Exclusive
+}
+
+// PricedUsage is the billable usage of a consumer of one service in an hour,
+// under one pricing
+type PricedUsage struct {
+	Hour       time.Time
+	ServiceID  string
+	ProviderID string
+	Pricing    *marketplace.PricingInfo // nil when the calls carried no pricing
+	Requests   int64                    // Billable calls
+	Tokens     int64                    // Tokens of billable calls
+}
+
+// Store persists rollups
+type Store interface {
+	// Record adds event to the hourly and daily rollups, unless an event with
+	// its ID was already recorded. It reports whether the event was added.
+	Record(ctx context.Context, event *marketplace.UsageEvent, pricingKey string) (bool, error)
+	// Rollups returns the rollups matching filter, oldest first
+	Rollups(ctx context.Context, filter UsageFilter) ([]Rollup, error)
+	// PricedUsage returns the hourly usage of consumerID in [from, to),
+	// oldest first
+	PricedUsage(ctx context.Context, consumerID string, from, to time.Time) ([]PricedUsage, error)
+	// PruneRecorded forgets the IDs of events recorded before t
+	PruneRecorded(ctx context.Context, before time.Time) (int64, error)
+
+	BudgetStore
+}
+
+// Service records and reports usage
+type Service struct {
+	store   Store
+	logger  *zap.Logger
+	now     func() time.Time
+	alerter Alerter
+}
+
+// NewService creates a service over store
+func NewService(store Store, logger *zap.Logger) *Service {
+	return &Service{store: store, logger: logger, now: time.Now}
+}
+
+// SetClock replaces the clock that decides the current billing period and
+// default usage ranges
+func (s *Service) SetClock(now func() time.Time) {
+	s.now = now
+}
+
+// Record adds a usage event to the rollups. Redelivered events are dropped.
+// It returns an error wrapping ErrInvalidEvent for an event that can never
+// be recorded.
+func (s *Service) Record(ctx context.Context, event *marketplace.UsageEvent) error {
+	if err := validateEvent(event); err != nil {
+		eventsTotal.WithLabelValues("invalid").Inc()
+		return err
+	}
+
+	recorded, err := s.store.Record(ctx, event, PricingKey(event.Pricing))
+	if err != nil {
+		return fmt.Errorf("failed to record usage: %w", err)
+	}
+	if !recorded {
+		eventsTotal.WithLabelValues("duplicate").Inc()
+		s.logger.Debug("Skipping recorded usage event", zap.String("id", event.ID))
+		return nil
+	}
+	eventsTotal.WithLabelValues("recorded").Inc()
+	return nil
+}
+
+func validateEvent(event *marketplace.UsageEvent) error {
+	switch {
+	case event.ID == "":
+		return fmt.Errorf("%w: no id", ErrInvalidEvent)
+	case event.ConsumerID == "" || event.ServiceID == "":
+		return fmt.Errorf("%w: consumer_id and service_id are required", ErrInvalidEvent)
+	case event.OccurredAt.IsZero():
+		return fmt.Errorf("%w: no occurred_at", ErrInvalidEvent)
+	case event.PromptTokens < 0 || event.CompletionTokens < 0 || event.TotalTokens < 0:
+		return fmt.Errorf("%w: negative token count", ErrInvalidEvent)
+	}
+	return nil
+}
+
+// Billable reports whether the call an event records is billed: calls the
+// service failed with a 5xx status are not
+func Billable(event *marketplace.UsageEvent) bool {
+	return event.StatusCode < 500
+}
+
+// PricingKey identifies a pricing in rollups: a hash of its JSON, or "" for
+// no pricing
+func PricingKey(p *marketplace.PricingInfo) string {
+	if p == nil {
+		return ""
+	}
+	data, _ := json.Marshal(p)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8])
+}
+
+// Usage returns the rollups filter selects. The granularity defaults to
+// daily, and the period to the last day of hourly rollups or the last 30
+// days of daily ones.
+func (s *Service) Usage(ctx context.Context, filter UsageFilter) ([]Rollup, error) {
+	if filter.Granularity == "" {
+		filter.Granularity = Daily
+	}
+	limit, ok := maxRange[filter.Granularity]
+	if !ok {
+		return nil, fmt.Errorf("%w: granularity must be %s or %s", ErrInvalidQuery, Hourly, Daily)
+	}
+	if filter.To.IsZero() {
+		filter.To = s.now().UTC()
+	}
+	if filter.From.IsZero() {
+		filter.From = filter.To.Add(-24 * time.Hour)
+		if filter.Granularity == Daily {
+			filter.From = filter.To.Add(-30 * 24 * time.Hour)
+		}
+	}
+	if !filter.From.Before(filter.To) {
+		return nil, fmt.Errorf("%w: from must be before to", ErrInvalidQuery)
+	}
+	if filter.To.Sub(filter.From) > limit {
+		return nil, fmt.Errorf("%w: %s rollups can be queried for at most %d days at a time", ErrInvalidQuery, filter.Granularity, int(limit.Hours()/24))
+	}
+
+	rollups, err := s.store.Rollups(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	for i := range rollups {
+		r := &rollups[i]
+		if r.Requests > 0 {
+			r.AvgLatencyMS = r.LatencyMSSum / float64(r.Requests)
+			r.AvgUpstreamMS = r.UpstreamMSSum / float64(r.Requests)
+		}
+	}
+	return rollups, nil
+}
+
+// StartPruning forgets the IDs of events recorded more than window ago,
+// every interval until ctx is cancelled. Events redelivered after that are
+// counted again, so window should exceed the usage topic's retention.
+func (s *Service) StartPruning(ctx context.Context, interval, window time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		n, err := s.store.PruneRecorded(ctx, s.now().Add(-window))
+		if err != nil {
+			if ctx.Err() == nil {
+				s.logger.Warn("Failed to prune recorded events", zap.Error(err))
+			}
+			continue
+		}
+		if n > 0 {
+			s.logger.Info("Pruned recorded events", zap.Int64("count", n))
+		}
+	}
+}
@@ -0,0 +1,60 @@
+-- Initial metering schema: hourly and daily usage rollups per consumer and
+-- service, and the IDs of recorded usage events, used to drop redelivered
+-- events.
+
+CREATE TABLE IF NOT EXISTS recorded_events (
+    event_id TEXT PRIMARY KEY,
+    recorded_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_recorded_events_recorded_at ON recorded_events(recorded_at);
+
+-- Hourly rollups keep the pricing the calls were made under, one row per
+-- pricing, so charges use the rates in effect at the time of each call.
+-- pricing_key is a hash of the pricing, or '' for calls without pricing.
+-- Tokens are counted for billable calls only: those the service didn't fail
+-- with a 5xx status, which are counted in errors.
+CREATE TABLE IF NOT EXISTS usage_hourly (
+    bucket TIMESTAMP WITH TIME ZONE NOT NULL,
+    consumer_id TEXT NOT NULL,
+    service_id TEXT NOT NULL,
+    provider_id TEXT NOT NULL,
+    pricing_key TEXT NOT NULL,
+    pricing JSONB,
+    requests BIGINT NOT NULL DEFAULT 0,
+    errors BIGINT NOT NULL DEFAULT 0,
+    estimated_requests BIGINT NOT NULL DEFAULT 0,
+    prompt_tokens BIGINT NOT NULL DEFAULT 0,
+    completion_tokens BIGINT NOT NULL DEFAULT 0,
+    total_tokens BIGINT NOT NULL DEFAULT 0,
+    latency_ms_sum DOUBLE PRECISION NOT NULL DEFAULT 0,
+    latency_ms_max DOUBLE PRECISION NOT NULL DEFAULT 0,
+    upstream_ms_sum DOUBLE PRECISION NOT NULL DEFAULT 0,
+
+    PRIMARY KEY (consumer_id, bucket, service_id, pricing_key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_usage_hourly_service ON usage_hourly(service_id, bucket);
+CREATE INDEX IF NOT EXISTS idx_usage_hourly_provider ON usage_hourly(provider_id, bucket);
+
+-- Daily rollups, in UTC days, across pricing
+CREATE TABLE IF NOT EXISTS usage_daily (
+    bucket TIMESTAMP WITH TIME ZONE NOT NULL,
+    consumer_id TEXT NOT NULL,
+    service_id TEXT NOT NULL,
+    provider_id TEXT NOT NULL,
+    requests BIGINT NOT NULL DEFAULT 0,
+    errors BIGINT NOT NULL DEFAULT 0,
+    estimated_requests BIGINT NOT NULL DEFAULT 0,
+    prompt_tokens BIGINT NOT NULL DEFAULT 0,
+    completion_tokens BIGINT NOT NULL DEFAULT 0,
+    total_tokens BIGINT NOT NULL DEFAULT 0,
+    latency_ms_sum DOUBLE PRECISION NOT NULL DEFAULT 0,
+    latency_ms_max DOUBLE PRECISION NOT NULL DEFAULT 0,
+    upstream_ms_sum DOUBLE PRECISION NOT NULL DEFAULT 0,
+
+    PRIMARY KEY (consumer_id, bucket, service_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_usage_daily_service ON usage_daily(service_id, bucket);
+CREATE INDEX IF NOT EXISTS idx_usage_daily_provider ON usage_daily(provider_id, bucket);
@@ -0,0 +1,22 @@
+-- Monthly budget caps per consumer, and the threshold alerts sent for them.
+-- A consumer is usually a team; spend is what its usage is charged in the
+-- budget's currency in the calendar month, as in an invoice preview.
+
+CREATE TABLE IF NOT EXISTS budgets (
+    consumer_id TEXT PRIMARY KEY,
+    monthly_cap NUMERIC(18, 6) NOT NULL CHECK (monthly_cap > 0),
+    currency CHAR(3) NOT NULL,
+    hard_stop BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- One row per threshold a consumer was alerted at in a month (YYYY-MM), so
+-- each is alerted once however often budgets are checked
+CREATE TABLE IF NOT EXISTS budget_alerts (
+    consumer_id TEXT NOT NULL,
+    period CHAR(7) NOT NULL,
+    threshold SMALLINT NOT NULL,
+    sent_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (consumer_id, period, threshold)
+);
@@ -0,0 +1,102 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/org/llm-marketplace/services/metering/internal/metering"
+)
+
+func (s *Store) PutBudget(ctx context.Context, budget *metering.Budget) error {
+	_, err := s.pool.Exec(ctx, `
+		INSERT INTO budgets (consumer_id, monthly_cap, currency, hard_stop, updated_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (consumer_id) DO UPDATE SET
+			monthly_cap = EXCLUDED.monthly_cap,
+			currency = EXCLUDED.currency,
+			hard_stop = EXCLUDED.hard_stop,
+			updated_at = EXCLUDED.updated_at
+	`, budget.ConsumerID, budget.MonthlyCap, budget.Currency, budget.HardStop, budget.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to store budget: %w", err)
+	}
+	return nil
+}
+
+const budgetColumns = `consumer_id, monthly_cap::float8, currency, hard_stop, updated_at`
+
+func scanBudget(row pgx.Row) (*metering.Budget, error) {
+	var b metering.Budget
+	if err := row.Scan(&b.ConsumerID, &b.MonthlyCap, &b.Currency, &b.HardStop, &b.UpdatedAt); err != nil {
+		return nil, err
+	}
+	b.UpdatedAt = b.UpdatedAt.UTC()
+	return &b, nil
+}
+
+func (s *Store) GetBudget(ctx context.Context, consumerID string) (*metering.Budget, error) {
+	budget, err := scanBudget(s.pool.QueryRow(ctx, `SELECT `+budgetColumns+` FROM budgets WHERE consumer_id = $1`, consumerID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %s has no budget", metering.ErrNotFound, consumerID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get budget: %w", err)
+	}
+	return budget, nil
+}
+
+func (s *Store) DeleteBudget(ctx context.Context, consumerID string) error {
+	tag, err := s.pool.Exec(ctx, `DELETE FROM budgets WHERE consumer_id = $1`, consumerID)
+	if err != nil {
+		return fmt.Errorf("failed to delete budget: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%w: %s has no budget", metering.ErrNotFound, consumerID)
+	}
+	return nil
+}
+
+func (s *Store) ListBudgets(ctx context.Context) ([]metering.Budget, error) {
+	rows, err := s.pool.Query(ctx, `SELECT `+budgetColumns+` FROM budgets ORDER BY consumer_id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list budgets: %w", err)
+	}
+	defer rows.Close()
+
+	var budgets []metering.Budget
+	for rows.Next() {
+		b, err := scanBudget(rows)
+		if err != nil {
+			return nil, err
+		}
+		budgets = append(budgets, *b)
+	}
+	return budgets, rows.Err()
+}
+
+// RecordAlert holds the alert's row lock while sending, so replicas checking
+// budgets at the same time send it once
+func (s *Store) RecordAlert(ctx context.Context, consumerID, period string, threshold int, send func() error) (bool, error) {
+	sent := false
+	err := pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx, `
+			INSERT INTO budget_alerts (consumer_id, period, threshold) VALUES ($1, $2, $3)
+			ON CONFLICT (consumer_id, period, threshold) DO NOTHING
+		`, consumerID, period, threshold)
+		if err != nil || tag.RowsAffected() == 0 {
+			return err
+		}
+		if err := send(); err != nil {
+			return err
+		}
+		sent = true
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+	return sent, nil
+}
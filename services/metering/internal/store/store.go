@@ -0,0 +1,207 @@
+// Package store keeps usage rollups and the IDs of recorded usage events in
+// PostgreSQL
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/org/llm-marketplace/pkg/marketplace"
+
+	"github.com/org/llm-marketplace/services/metering/internal/config"
+	"github.com/org/llm-marketplace/services/metering/internal/metering"
+)
+
+// Store implements metering.Store
+type Store struct {
+	pool *pgxpool.Pool
+}
+
+// NewPool connects to PostgreSQL
+func NewPool(ctx context.Context, cfg config.PostgresConfig) (*pgxpool.Pool, error) {
+	poolConfig, err := pgxpool.ParseConfig(cfg.DSN())
+	if err != nil {
+		return nil, fmt.Errorf("invalid postgres config: %w", err)
+	}
+	poolConfig.MaxConns = int32(cfg.MaxConns)
+	poolConfig.MinConns = int32(cfg.MinConns)
+	poolConfig.ConnConfig.ConnectTimeout = cfg.ConnTimeout
+
+	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create pool: %w", err)
+	}
+	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("failed to ping postgres: %w", err)
+	}
+	return pool, nil
+}
+
+// New creates a store over pool
+func New(pool *pgxpool.Pool) *Store {
+	return &Store{pool: pool}
+}
+
+// rollupColumns are the counters of both rollup tables, in the order
+// Record passes them
+const rollupColumns = `requests, errors, estimated_requests, prompt_tokens, completion_tokens, total_tokens, latency_ms_sum, latency_ms_max, upstream_ms_sum`
+
+// rollupUpdate adds an event's counters to an existing rollup row
+const rollupUpdate = `
+	requests = t.requests + EXCLUDED.requests,
+	errors = t.errors + EXCLUDED.errors,
+	estimated_requests = t.estimated_requests + EXCLUDED.estimated_requests,
+	prompt_tokens = t.prompt_tokens + EXCLUDED.prompt_tokens,
+	completion_tokens = t.completion_tokens + EXCLUDED.completion_tokens,
+	total_tokens = t.total_tokens + EXCLUDED.total_tokens,
+	latency_ms_sum = t.latency_ms_sum + EXCLUDED.latency_ms_sum,
+	latency_ms_max = GREATEST(t.latency_ms_max, EXCLUDED.latency_ms_max),
+	upstream_ms_sum = t.upstream_ms_sum + EXCLUDED.upstream_ms_sum`
+
+func (s *Store) Record(ctx context.Context, event *marketplace.UsageEvent, pricingKey string) (bool, error) {
+	var pricing []byte
+	if event.Pricing != nil {
+		var err error
+		if pricing, err = json.Marshal(event.Pricing); err != nil {
+			return false, err
+		}
+	}
+
+	var errs, estimated, prompt, completion, total int64
+	if metering.Billable(event) {
+		prompt, completion, total = event.PromptTokens, event.CompletionTokens, event.TotalTokens
+	} else {
+		errs = 1
+	}
+	if event.TokensEstimated {
+		estimated = 1
+	}
+	counters := []interface{}{int64(1), errs, estimated, prompt, completion, total, event.LatencyMS, event.LatencyMS, event.UpstreamMS}
+
+	at := event.OccurredAt.UTC()
+	hour, day := at.Truncate(time.Hour), time.Date(at.Year(), at.Month(), at.Day(), 0, 0, 0, 0, time.UTC)
+
+	recorded := false
+	err := pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx, `
+			INSERT INTO recorded_events (event_id) VALUES ($1) ON CONFLICT (event_id) DO NOTHING
+		`, event.ID)
+		if err != nil || tag.RowsAffected() == 0 {
+			return err
+		}
+		recorded = true
+
+		_, err = tx.Exec(ctx, `
+			INSERT INTO usage_hourly AS t (bucket, consumer_id, service_id, provider_id, pricing_key, pricing, `+rollupColumns+`)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+			ON CONFLICT (consumer_id, bucket, service_id, pricing_key) DO UPDATE SET`+rollupUpdate,
+			append([]interface{}{hour, event.ConsumerID, event.ServiceID, event.ProviderID, pricingKey, pricing}, counters...)...)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(ctx, `
+			INSERT INTO usage_daily AS t (bucket, consumer_id, service_id, provider_id, `+rollupColumns+`)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+			ON CONFLICT (consumer_id, bucket, service_id) DO UPDATE SET`+rollupUpdate,
+			append([]interface{}{day, event.ConsumerID, event.ServiceID, event.ProviderID}, counters...)...)
+		return err
+	})
+	if err != nil {
+		return false, err
+	}
+	return recorded, nil
+}
+
+func (s *Store) Rollups(ctx context.Context, filter metering.UsageFilter) ([]metering.Rollup, error) {
+	table := "usage_daily"
+	if filter.Granularity == metering.Hourly {
+		table = "usage_hourly"
+	}
+
+	args := []interface{}{filter.From, filter.To}
+	where := []string{"bucket >= $1", "bucket < $2"}
+	for column, value := range map[string]string{
+		"consumer_id": filter.ConsumerID,
+		"service_id":  filter.ServiceID,
+		"provider_id": filter.ProviderID,
+	} {
+		if value != "" {
+			args = append(args, value)
+			where = append(where, fmt.Sprintf("%s = $%d", column, len(args)))
+		}
+	}
+
+	// Hourly rows are kept per pricing; they are reported together
+	rows, err := s.pool.Query(ctx, fmt.Sprintf(`
+		SELECT bucket, consumer_id, service_id, MAX(provider_id),
+			SUM(requests), SUM(errors), SUM(estimated_requests),
+			SUM(prompt_tokens), SUM(completion_tokens), SUM(total_tokens),
+			SUM(latency_ms_sum), MAX(latency_ms_max), SUM(upstream_ms_sum)
+		FROM %s WHERE %s
+		GROUP BY bucket, consumer_id, service_id
+		ORDER BY bucket, consumer_id, service_id
+	`, table, strings.Join(where, " AND ")), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query rollups: %w", err)
+	}
+	defer rows.Close()
+
+	var rollups []metering.Rollup
+	for rows.Next() {
+		var r metering.Rollup
+		if err := rows.Scan(&r.Start, &r.ConsumerID, &r.ServiceID, &r.ProviderID,
+			&r.Requests, &r.Errors, &r.EstimatedRequests,
+			&r.PromptTokens, &r.CompletionTokens, &r.TotalTokens,
+			&r.LatencyMSSum, &r.MaxLatencyMS, &r.UpstreamMSSum); err != nil {
+			return nil, err
+		}
+		r.Start = r.Start.UTC()
+		rollups = append(rollups, r)
+	}
+	return rollups, rows.Err()
+}
+
+func (s *Store) PricedUsage(ctx context.Context, consumerID string, from, to time.Time) ([]metering.PricedUsage, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT bucket, service_id, provider_id, pricing, requests - errors, total_tokens
+		FROM usage_hourly
+		WHERE consumer_id = $1 AND bucket >= $2 AND bucket < $3
+		ORDER BY bucket, service_id, pricing_key
+	`, consumerID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query usage: %w", err)
+	}
+	defer rows.Close()
+
+	var usage []metering.PricedUsage
+	for rows.Next() {
+		var u metering.PricedUsage
+		var pricing []byte
+		if err := rows.Scan(&u.Hour, &u.ServiceID, &u.ProviderID, &pricing, &u.Requests, &u.Tokens); err != nil {
+			return nil, err
+		}
+		if pricing != nil {
+			u.Pricing = &marketplace.PricingInfo{}
+			if err := json.Unmarshal(pricing, u.Pricing); err != nil {
+				return nil, fmt.Errorf("invalid pricing of %s: %w", u.ServiceID, err)
+			}
+		}
+		u.Hour = u.Hour.UTC()
+		usage = append(usage, u)
+	}
+	return usage, rows.Err()
+}
+
+func (s *Store) PruneRecorded(ctx context.Context, before time.Time) (int64, error) {
+	tag, err := s.pool.Exec(ctx, `DELETE FROM recorded_events WHERE recorded_at < $1`, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune recorded events: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
@@ -0,0 +1,113 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/org/llm-marketplace/services/metering/internal/api"
+)
+
+func newTestRouter(t *testing.T) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	svc, _ := newTestService()
+	day := time.Date(2026, 3, 10, 9, 0, 0, 0, time.UTC)
+	record(t, svc,
+		usageEvent("alice", "gpt", day, 1000, perToken(0.01)),
+		usageEvent("bob", "gpt", day, 2000, perToken(0.01)),
+		usageEvent("bob", "embed", day, 3000, perToken(0.01)),
+	)
+
+	router := gin.New()
+	router.HandleMethodNotAllowed = true
+	api.RegisterRoutes(router, svc, "X-Consumer-ID", "X-Provider-ID", zap.NewNop())
+	return router
+}
+
+func get(router *gin.Engine, path string, headers map[string]string) (*httptest.ResponseRecorder, map[string]interface{}) {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var decoded map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &decoded)
+	return w, decoded
+}
+
+func TestUsageAPIScopesCallers(t *testing.T) {
+	router := newTestRouter(t)
+	const query = "/api/v1/usage?from=2026-03-10&to=2026-03-11"
+
+	tests := []struct {
+		name    string
+		query   string
+		headers map[string]string
+		status  int
+		total   float64
+	}{
+		{"operator", query, nil, http.StatusOK, 3},
+		{"consumer", query, map[string]string{"X-Consumer-ID": "bob"}, http.StatusOK, 2},
+		{"consumer filter", query + "&service_id=embed", map[string]string{"X-Consumer-ID": "bob"}, http.StatusOK, 1},
+		{"other consumer", query + "&consumer_id=alice", map[string]string{"X-Consumer-ID": "bob"}, http.StatusForbidden, 0},
+		{"provider", query, map[string]string{"X-Provider-ID": "prov-gpt"}, http.StatusOK, 2},
+		{"other provider", query + "&provider_id=prov-embed", map[string]string{"X-Provider-ID": "prov-gpt"}, http.StatusForbidden, 0},
+		{"hourly", "/api/v1/usage?granularity=hour&from=2026-03-10T09:00:00Z&to=2026-03-10T10:00:00Z", nil, http.StatusOK, 3},
+		{"bad time", "/api/v1/usage?from=yesterday", nil, http.StatusBadRequest, 0},
+		{"bad granularity", query + "&granularity=week", nil, http.StatusBadRequest, 0},
+	}
+	for _, tt := range tests {
+		w, body := get(router, tt.query, tt.headers)
+		if w.Code != tt.status {
+			t.Errorf("%s: status %d, want %d: %s", tt.name, w.Code, tt.status, w.Body)
+			continue
+		}
+		if tt.status != http.StatusOK {
+			if ct := w.Header().Get("Content-Type"); ct != "application/problem+json" {
+				t.Errorf("%s: content type %q", tt.name, ct)
+			}
+			continue
+		}
+		if body["total"] != tt.total {
+			t.Errorf("%s: total %v, want %v", tt.name, body["total"], tt.total)
+		}
+	}
+}
+
+func TestInvoicePreviewAPI(t *testing.T) {
+	router := newTestRouter(t)
+
+	w, body := get(router, "/api/v1/consumers/bob/invoice-preview?period=2026-03", map[string]string{"X-Consumer-ID": "bob"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	if totals := body["totals"].(map[string]interface{}); totals["USD"] != 0.05 {
+		t.Errorf("totals = %v, want 0.05 USD", totals)
+	}
+	if lines := body["lines"].([]interface{}); len(lines) != 2 {
+		t.Errorf("got %d lines, want 2", len(lines))
+	}
+
+	for name, tt := range map[string]struct {
+		path    string
+		headers map[string]string
+		status  int
+	}{
+		"other consumer": {"/api/v1/consumers/alice/invoice-preview", map[string]string{"X-Consumer-ID": "bob"}, http.StatusForbidden},
+		"provider":       {"/api/v1/consumers/alice/invoice-preview", map[string]string{"X-Provider-ID": "prov-gpt"}, http.StatusForbidden},
+		"bad period":     {"/api/v1/consumers/alice/invoice-preview?period=2026-3", nil, http.StatusBadRequest},
+		"operator":       {"/api/v1/consumers/alice/invoice-preview", nil, http.StatusOK},
+	} {
+		if w, _ := get(router, tt.path, tt.headers); w.Code != tt.status {
+			t.Errorf("%s: status %d, want %d", name, w.Code, tt.status)
+		}
+	}
+}
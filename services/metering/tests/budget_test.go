@@ -0,0 +1,211 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/org/llm-marketplace/pkg/marketplace"
+
+	"github.com/org/llm-marketplace/services/metering/internal/metering"
+)
+
+// fakeAlerter keeps the alerts it is sent, and fails while failing is set
+type fakeAlerter struct {
+	alerts  []*marketplace.BudgetAlert
+	failing bool
+}
+
+func (a *fakeAlerter) Alert(_ context.Context, alert *marketplace.BudgetAlert) error {
+	if a.failing {
+		return errors.New("broker unavailable")
+	}
+	a.alerts = append(a.alerts, alert)
+	return nil
+}
+
+func (a *fakeAlerter) thresholds() []int {
+	var thresholds []int
+	for _, alert := range a.alerts {
+		thresholds = append(thresholds, alert.Threshold)
+	}
+	return thresholds
+}
+
+func TestBudgetStatus(t *testing.T) {
+	svc, _ := newTestService()
+	ctx := context.Background()
+	march := time.Date(2026, 3, 2, 8, 0, 0, 0, time.UTC)
+	record(t, svc,
+		usageEvent("alice", "gpt", march, 30000, perToken(0.01)),                   // 0.30
+		usageEvent("alice", "gpt", march.AddDate(0, -1, 0), 90000, perToken(0.01)), // February
+	)
+
+	if _, err := svc.Budget(ctx, "alice"); !errors.Is(err, metering.ErrNotFound) {
+		t.Fatalf("got %v, want ErrNotFound before a budget is set", err)
+	}
+
+	status, err := svc.SetBudget(ctx, metering.Budget{ConsumerID: "alice", MonthlyCap: 0.5, HardStop: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Currency != "USD" || status.Period != "2026-03" || status.Spend != 0.3 || status.Used != 60 {
+		t.Errorf("status = %+v, want 0.30 USD spent in 2026-03, 60%% of the cap", status)
+	}
+	if status.Exceeded || status.Blocked {
+		t.Errorf("status = %+v, want neither exceeded nor blocked", status)
+	}
+
+	record(t, svc, usageEvent("alice", "gpt", march, 20000, perToken(0.01)))
+	if status, err = svc.Budget(ctx, "alice"); err != nil {
+		t.Fatal(err)
+	}
+	if !status.Exceeded || !status.Blocked {
+		t.Errorf("status = %+v, want blocked at the cap", status)
+	}
+
+	// Without a hard stop, reaching the cap is reported but not enforced
+	if status, err = svc.SetBudget(ctx, metering.Budget{ConsumerID: "alice", MonthlyCap: 0.5}); err != nil {
+		t.Fatal(err)
+	}
+	if !status.Exceeded || status.Blocked {
+		t.Errorf("status = %+v, want exceeded but not blocked", status)
+	}
+
+	for name, budget := range map[string]metering.Budget{
+		"no consumer":  {MonthlyCap: 1},
+		"zero cap":     {ConsumerID: "alice"},
+		"negative cap": {ConsumerID: "alice", MonthlyCap: -5},
+		"bad currency": {ConsumerID: "alice", MonthlyCap: 1, Currency: "dollars"},
+	} {
+		if _, err := svc.SetBudget(ctx, budget); !errors.Is(err, metering.ErrInvalidQuery) {
+			t.Errorf("%s: got %v, want ErrInvalidQuery", name, err)
+		}
+	}
+
+	if err := svc.DeleteBudget(ctx, "alice"); err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.DeleteBudget(ctx, "alice"); !errors.Is(err, metering.ErrNotFound) {
+		t.Errorf("got %v, want ErrNotFound deleting twice", err)
+	}
+}
+
+func TestCheckBudgetsAlertsEachThresholdOnce(t *testing.T) {
+	svc, _ := newTestService()
+	ctx := context.Background()
+	alerter := &fakeAlerter{}
+	svc.SetAlerter(alerter)
+	march := time.Date(2026, 3, 2, 8, 0, 0, 0, time.UTC)
+
+	for _, consumer := range []string{"alice", "bob"} {
+		if _, err := svc.SetBudget(ctx, metering.Budget{ConsumerID: consumer, MonthlyCap: 1}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	record(t, svc, usageEvent("alice", "gpt", march, 85000, perToken(0.01))) // 85%
+
+	if err := svc.CheckBudgets(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got := alerter.thresholds(); len(got) != 2 || got[0] != 50 || got[1] != 80 {
+		t.Fatalf("alerted at %v, want 50 and 80", got)
+	}
+	alert := alerter.alerts[1]
+	if alert.ConsumerID != "alice" || alert.Period != "2026-03" || alert.Spend != 0.85 || alert.Cap != 1 || alert.Currency != "USD" {
+		t.Errorf("alert = %+v", alert)
+	}
+
+	// Checking again doesn't repeat alerts; a failed alert is retried
+	alerter.failing = true
+	record(t, svc, usageEvent("alice", "gpt", march, 15000, perToken(0.01)))
+	if err := svc.CheckBudgets(ctx); err == nil {
+		t.Error("got no error while the alerter fails")
+	}
+	alerter.failing = false
+	if err := svc.CheckBudgets(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.CheckBudgets(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got := alerter.thresholds(); len(got) != 3 || got[2] != 100 {
+		t.Errorf("alerted at %v, want 50, 80 and then 100 once", got)
+	}
+
+	// Thresholds are alerted again in a new month
+	svc.SetClock(func() time.Time { return time.Date(2026, 4, 3, 0, 0, 0, 0, time.UTC) })
+	record(t, svc, usageEvent("alice", "gpt", time.Date(2026, 4, 2, 0, 0, 0, 0, time.UTC), 50000, perToken(0.01)))
+	if err := svc.CheckBudgets(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got := alerter.alerts[len(alerter.alerts)-1]; got.Period != "2026-04" || got.Threshold != 50 {
+		t.Errorf("last alert = %+v, want 50%% in 2026-04", got)
+	}
+}
+
+func do(router *gin.Engine, method, path, body string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestBudgetAPI(t *testing.T) {
+	router := newTestRouter(t)
+	const path = "/api/v1/consumers/bob/budget"
+	bob := map[string]string{"X-Consumer-ID": "bob"}
+
+	if w, _ := get(router, path, bob); w.Code != http.StatusNotFound {
+		t.Errorf("status %d before a budget is set, want 404", w.Code)
+	}
+	if w := do(router, http.MethodPut, path, `{"monthly_cap": 10, "hard_stop": true}`, bob); w.Code != http.StatusForbidden {
+		t.Errorf("consumer set its own budget: status %d, want 403", w.Code)
+	}
+	if w := do(router, http.MethodPut, path, `{"monthly_cap": 0}`, nil); w.Code != http.StatusBadRequest {
+		t.Errorf("zero cap: status %d, want 400", w.Code)
+	}
+	if w := do(router, http.MethodPut, path, `{"monthly_cap": 0.1, "hard_stop": true}`, nil); w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+
+	w, body := get(router, path, bob)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	if body["spend"] != 0.05 || body["used"] != 50.0 || body["blocked"] != false {
+		t.Errorf("budget = %v, want 0.05 spent, 50%% used, not blocked", body)
+	}
+
+	for name, tt := range map[string]struct {
+		headers map[string]string
+		status  int
+	}{
+		"other consumer": {map[string]string{"X-Consumer-ID": "alice"}, http.StatusForbidden},
+		"provider":       {map[string]string{"X-Provider-ID": "prov-gpt"}, http.StatusForbidden},
+		"operator":       {nil, http.StatusOK},
+	} {
+		if w, _ := get(router, path, tt.headers); w.Code != tt.status {
+			t.Errorf("%s: status %d, want %d", name, w.Code, tt.status)
+		}
+	}
+
+	if w := do(router, http.MethodDelete, path, "", bob); w.Code != http.StatusForbidden {
+		t.Errorf("consumer removed its own budget: status %d, want 403", w.Code)
+	}
+	if w := do(router, http.MethodDelete, path, "", nil); w.Code != http.StatusNoContent {
+		t.Errorf("delete: status %d, want 204", w.Code)
+	}
+	if w := do(router, http.MethodDelete, path, "", nil); w.Code != http.StatusNotFound {
+		t.Errorf("delete again: status %d, want 404", w.Code)
+	}
+}
@@ -0,0 +1,102 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/org/llm-marketplace/pkg/marketplace"
+	"github.com/segmentio/kafka-go"
+	"go.uber.org/zap"
+
+	"github.com/org/llm-marketplace/services/metering/internal/config"
+	"github.com/org/llm-marketplace/services/metering/internal/consumer"
+	"github.com/org/llm-marketplace/services/metering/internal/metering"
+)
+
+// fakeReader serves queued messages, then blocks until cancelled
+type fakeReader struct {
+	mu        sync.Mutex
+	messages  []kafka.Message
+	committed []int64
+	done      chan struct{}
+}
+
+func (r *fakeReader) FetchMessage(ctx context.Context) (kafka.Message, error) {
+	r.mu.Lock()
+	if len(r.messages) > 0 {
+		msg := r.messages[0]
+		r.messages = r.messages[1:]
+		r.mu.Unlock()
+		return msg, nil
+	}
+	r.mu.Unlock()
+	close(r.done)
+	<-ctx.Done()
+	return kafka.Message{}, ctx.Err()
+}
+
+func (r *fakeReader) CommitMessages(_ context.Context, msgs ...kafka.Message) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, m := range msgs {
+		r.committed = append(r.committed, m.Offset)
+	}
+	return nil
+}
+
+func (r *fakeReader) Close() error { return nil }
+
+// flakyStore fails the first failures records
+type flakyStore struct {
+	*memStore
+	failures int
+}
+
+func (s *flakyStore) Record(ctx context.Context, event *marketplace.UsageEvent, key string) (bool, error) {
+	if s.failures > 0 {
+		s.failures--
+		return false, errors.New("connection reset")
+	}
+	return s.memStore.Record(ctx, event, key)
+}
+
+func TestConsumerRetriesAndSkipsInvalidEvents(t *testing.T) {
+	store := &flakyStore{memStore: newMemStore(), failures: 2}
+	svc := metering.NewService(store, zap.NewNop())
+
+	valid, _ := json.Marshal(usageEvent("alice", "gpt", now, 100, nil))
+	invalid, _ := json.Marshal(usageEvent("", "gpt", now, 100, nil))
+	reader := &fakeReader{done: make(chan struct{}), messages: []kafka.Message{
+		{Offset: 1, Value: []byte("not json")},
+		{Offset: 2, Value: invalid},
+		{Offset: 3, Value: valid},
+		{Offset: 4, Value: valid}, // Redelivered
+	}}
+
+	c := consumer.NewConsumer(config.KafkaConfig{Brokers: []string{"localhost:9092"}, Topic: "usage", RetryBackoff: time.Millisecond, MaxRetryBackoff: time.Millisecond}, svc, zap.NewNop())
+	c.SetReader(reader)
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		c.Start(ctx)
+	}()
+	<-reader.done
+	cancel()
+	<-stopped
+
+	if len(reader.committed) != 4 {
+		t.Errorf("committed offsets %v, want all 4", reader.committed)
+	}
+	rollups, err := svc.Usage(context.Background(), metering.UsageFilter{Granularity: metering.Hourly, From: now.Add(-time.Hour), To: now.Add(time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rollups) != 1 || rollups[0].Requests != 1 {
+		t.Errorf("rollups = %+v, want the valid event recorded once after retries", rollups)
+	}
+}
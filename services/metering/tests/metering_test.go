@@ -0,0 +1,277 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/org/llm-marketplace/pkg/marketplace"
+	"go.uber.org/zap"
+
+	"github.com/org/llm-marketplace/services/metering/internal/metering"
+)
+
+// now is the clock of the tests: mid-March 2026
+var now = time.Date(2026, 3, 15, 12, 0, 0, 0, time.UTC)
+
+func newTestService() (*metering.Service, *memStore) {
+	store := newMemStore()
+	svc := metering.NewService(store, zap.NewNop())
+	svc.SetClock(func() time.Time { return now })
+	return svc, store
+}
+
+var seq int
+
+func usageEvent(consumer, service string, at time.Time, tokens int64, pricing *marketplace.PricingInfo) *marketplace.UsageEvent {
+	seq++
+	return &marketplace.UsageEvent{
+		ID:               fmt.Sprintf("evt-%d", seq),
+		OccurredAt:       at,
+		ConsumerID:       consumer,
+		ServiceID:        service,
+		ProviderID:       "prov-" + service,
+		Method:           "POST",
+		Path:             "/v1/chat/completions",
+		StatusCode:       200,
+		PromptTokens:     tokens / 2,
+		CompletionTokens: tokens - tokens/2,
+		TotalTokens:      tokens,
+		LatencyMS:        100,
+		UpstreamMS:       80,
+		Pricing:          pricing,
+	}
+}
+
+func record(t *testing.T, svc *metering.Service, events ...*marketplace.UsageEvent) {
+	t.Helper()
+	for _, e := range events {
+		if err := svc.Record(context.Background(), e); err != nil {
+			t.Fatalf("record %s: %v", e.ID, err)
+		}
+	}
+}
+
+func perToken(rate float64) *marketplace.PricingInfo {
+	return &marketplace.PricingInfo{Model: "per-token", Rate: rate, Unit: "1k tokens", Currency: "USD"}
+}
+
+func TestRecordDropsRedeliveredAndInvalidEvents(t *testing.T) {
+	svc, _ := newTestService()
+	ctx := context.Background()
+
+	event := usageEvent("alice", "gpt", now.Add(-time.Hour), 100, perToken(0.01))
+	record(t, svc, event, event)
+
+	for name, e := range map[string]*marketplace.UsageEvent{
+		"no id":       {ConsumerID: "alice", ServiceID: "gpt", OccurredAt: now},
+		"no consumer": {ID: "x", ServiceID: "gpt", OccurredAt: now},
+		"no time":     {ID: "y", ConsumerID: "alice", ServiceID: "gpt"},
+	} {
+		if err := svc.Record(ctx, e); !errors.Is(err, metering.ErrInvalidEvent) {
+			t.Errorf("%s: got %v, want ErrInvalidEvent", name, err)
+		}
+	}
+
+	rollups, err := svc.Usage(ctx, metering.UsageFilter{ConsumerID: "alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rollups) != 1 || rollups[0].Requests != 1 || rollups[0].TotalTokens != 100 {
+		t.Fatalf("redelivered event counted twice: %+v", rollups)
+	}
+}
+
+func TestUsageRollsUpByHourAndDay(t *testing.T) {
+	svc, _ := newTestService()
+	ctx := context.Background()
+
+	day := time.Date(2026, 3, 14, 0, 0, 0, 0, time.UTC)
+	failed := usageEvent("alice", "gpt", day.Add(9*time.Hour+50*time.Minute), 40, nil)
+	failed.StatusCode = 502
+	failed.LatencyMS = 400
+	estimated := usageEvent("alice", "gpt", day.Add(10*time.Hour+5*time.Minute), 30, nil)
+	estimated.TokensEstimated = true
+	record(t, svc,
+		usageEvent("alice", "gpt", day.Add(9*time.Hour+10*time.Minute), 100, nil),
+		failed,
+		estimated,
+		usageEvent("bob", "gpt", day.Add(9*time.Hour), 500, nil),
+	)
+
+	hourly, err := svc.Usage(ctx, metering.UsageFilter{ConsumerID: "alice", Granularity: metering.Hourly, From: day, To: day.Add(24 * time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hourly) != 2 {
+		t.Fatalf("got %d hourly rollups, want 2", len(hourly))
+	}
+	nine := hourly[0]
+	if !nine.Start.Equal(day.Add(9*time.Hour)) || nine.Requests != 2 || nine.Errors != 1 || nine.TotalTokens != 100 {
+		t.Errorf("9:00 rollup = %+v; failed calls count as errors without tokens", nine)
+	}
+	if nine.AvgLatencyMS != 250 || nine.MaxLatencyMS != 400 || nine.AvgUpstreamMS != 80 {
+		t.Errorf("9:00 latencies = avg %v max %v upstream %v", nine.AvgLatencyMS, nine.MaxLatencyMS, nine.AvgUpstreamMS)
+	}
+	if hourly[1].EstimatedRequests != 1 {
+		t.Errorf("10:00 estimated requests = %d, want 1", hourly[1].EstimatedRequests)
+	}
+
+	daily, err := svc.Usage(ctx, metering.UsageFilter{Granularity: metering.Daily, From: day, To: day.Add(24 * time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(daily) != 2 || daily[0].ConsumerID != "alice" || daily[0].Requests != 3 || daily[0].TotalTokens != 130 || daily[1].TotalTokens != 500 {
+		t.Errorf("daily rollups = %+v", daily)
+	}
+}
+
+func TestUsageValidatesQuery(t *testing.T) {
+	svc, _ := newTestService()
+	ctx := context.Background()
+
+	for name, filter := range map[string]metering.UsageFilter{
+		"granularity":  {Granularity: "minute"},
+		"reversed":     {From: now, To: now.Add(-time.Hour)},
+		"hourly range": {Granularity: metering.Hourly, From: now.Add(-40 * 24 * time.Hour), To: now},
+		"daily range":  {From: now.Add(-400 * 24 * time.Hour), To: now},
+	} {
+		if _, err := svc.Usage(ctx, filter); !errors.Is(err, metering.ErrInvalidQuery) {
+			t.Errorf("%s: got %v, want ErrInvalidQuery", name, err)
+		}
+	}
+}
+
+func TestInvoicePreviewChargesHeadlineRate(t *testing.T) {
+	svc, _ := newTestService()
+	march := time.Date(2026, 3, 2, 8, 0, 0, 0, time.UTC)
+
+	failed := usageEvent("alice", "gpt", march, 9000, perToken(0.02))
+	failed.StatusCode = 503
+	record(t, svc,
+		usageEvent("alice", "gpt", march, 1500, perToken(0.02)),
+		usageEvent("alice", "gpt", march.Add(48*time.Hour), 2500, perToken(0.02)),
+		failed,
+		usageEvent("alice", "gpt", march.AddDate(0, -1, 0), 1000, perToken(0.02)), // February
+		usageEvent("bob", "gpt", march, 1000, perToken(0.02)),
+	)
+
+	invoice, err := svc.InvoicePreview(context.Background(), "alice", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if invoice.Period != "2026-03" || invoice.Final {
+		t.Errorf("period %s final %v, want an open 2026-03", invoice.Period, invoice.Final)
+	}
+	if len(invoice.Lines) != 1 {
+		t.Fatalf("got %d lines, want 1", len(invoice.Lines))
+	}
+	line := invoice.Lines[0]
+	if line.Requests != 2 || line.Tokens != 4000 {
+		t.Errorf("line counts %d requests and %d tokens, want 2 and 4000", line.Requests, line.Tokens)
+	}
+	if len(line.Charges) != 1 || line.Charges[0].Quantity != 4 || line.Charges[0].Amount != 0.08 {
+		t.Errorf("charges = %+v, want 4 units at 0.02", line.Charges)
+	}
+	if invoice.Totals["USD"] != 0.08 {
+		t.Errorf("USD total = %v, want 0.08", invoice.Totals["USD"])
+	}
+
+	february, err := svc.InvoicePreview(context.Background(), "alice", "2026-02")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !february.Final || february.Totals["USD"] != 0.02 {
+		t.Errorf("February invoice final %v total %v, want final 0.02", february.Final, february.Totals["USD"])
+	}
+}
+
+func TestInvoicePreviewGraduatedTiers(t *testing.T) {
+	svc, _ := newTestService()
+	pricing := &marketplace.PricingInfo{Model: "tiered", Currency: "EUR", Tiers: []marketplace.PricingTier{
+		{Tier: "first", Rate: 0.03, Unit: "1k tokens", UpTo: 10},
+		{Tier: "volume", Rate: 0.02, Unit: "1k tokens", UpTo: 20},
+		{Tier: "bulk", Rate: 0.01, Unit: "1k tokens"},
+	}}
+	march := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	// 8k, then 7k crossing into the second tier, then 20k crossing into the third
+	record(t, svc,
+		usageEvent("alice", "gpt", march, 8000, pricing),
+		usageEvent("alice", "gpt", march.Add(time.Hour), 7000, pricing),
+		usageEvent("alice", "gpt", march.Add(2*time.Hour), 20000, pricing),
+	)
+
+	invoice, err := svc.InvoicePreview(context.Background(), "alice", "2026-03")
+	if err != nil {
+		t.Fatal(err)
+	}
+	charges := invoice.Lines[0].Charges
+	want := []metering.Charge{
+		{Tier: "first", Rate: 0.03, Unit: "1k tokens", Quantity: 10, Amount: 0.3},
+		{Tier: "volume", Rate: 0.02, Unit: "1k tokens", Quantity: 10, Amount: 0.2},
+		{Tier: "bulk", Rate: 0.01, Unit: "1k tokens", Quantity: 15, Amount: 0.15},
+	}
+	if len(charges) != len(want) {
+		t.Fatalf("charges = %+v", charges)
+	}
+	for i := range want {
+		if charges[i] != want[i] {
+			t.Errorf("charge %d = %+v, want %+v", i, charges[i], want[i])
+		}
+	}
+	if invoice.Lines[0].Currency != "EUR" || invoice.Totals["EUR"] != 0.65 {
+		t.Errorf("totals = %v, want 0.65 EUR", invoice.Totals)
+	}
+}
+
+func TestInvoicePreviewPricingChangesAndUnpricedUsage(t *testing.T) {
+	svc, _ := newTestService()
+	march := time.Date(2026, 3, 3, 0, 0, 0, 0, time.UTC)
+
+	perCall := &marketplace.PricingInfo{Model: "per-request", Rate: 0.5, Unit: "1k requests"}
+	record(t, svc,
+		usageEvent("alice", "gpt", march, 1000, perToken(0.01)),
+		usageEvent("alice", "gpt", march.Add(time.Hour), 1000, perToken(0.03)), // Price raised
+		usageEvent("alice", "embed", march, 50, perCall),
+		usageEvent("alice", "embed", march, 50, perCall),
+		usageEvent("alice", "free", march, 1000, &marketplace.PricingInfo{Model: "free"}),
+		usageEvent("alice", "legacy", march, 1000, nil),
+		usageEvent("alice", "odd", march, 1000, &marketplace.PricingInfo{Model: "flat", Rate: 10, Unit: "month"}),
+	)
+
+	invoice, err := svc.InvoicePreview(context.Background(), "alice", "2026-03")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := map[string]metering.InvoiceLine{}
+	for _, line := range invoice.Lines {
+		lines[line.ServiceID] = line
+	}
+	if got := lines["gpt"]; len(got.Charges) != 2 || got.Amount != 0.04 {
+		t.Errorf("gpt line = %+v, want each call at the rate it was made under", got)
+	}
+	if got := lines["embed"]; got.Amount != 0.001 || got.Charges[0].Quantity != 0.002 {
+		t.Errorf("embed line = %+v, want 2 requests at 0.5 per 1k", got)
+	}
+	if got := lines["free"]; got.Amount != 0 || got.Unpriced {
+		t.Errorf("free line = %+v", got)
+	}
+	if !lines["legacy"].Unpriced || !lines["odd"].Unpriced || lines["odd"].Amount != 0 {
+		t.Errorf("usage without a countable pricing must be flagged unpriced: %+v %+v", lines["legacy"], lines["odd"])
+	}
+	if invoice.Totals["USD"] != 0.041 {
+		t.Errorf("USD total = %v, want 0.041", invoice.Totals["USD"])
+	}
+}
+
+func TestInvoicePreviewValidatesPeriod(t *testing.T) {
+	svc, _ := newTestService()
+	for _, period := range []string{"March", "2026-13", "2026-04"} {
+		if _, err := svc.InvoicePreview(context.Background(), "alice", period); !errors.Is(err, metering.ErrInvalidQuery) {
+			t.Errorf("period %q: got %v, want ErrInvalidQuery", period, err)
+		}
+	}
+}
@@ -0,0 +1,239 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/org/llm-marketplace/pkg/marketplace"
+
+	"github.com/org/llm-marketplace/services/metering/internal/metering"
+)
+
+// memStore is an in-memory metering.Store with the semantics of the
+// PostgreSQL store
+type memStore struct {
+	mu       sync.Mutex
+	recorded map[string]time.Time
+	hourly   map[hourKey]*hourRow
+	budgets  map[string]metering.Budget
+	alerted  map[alertKey]bool
+	err      error
+}
+
+type alertKey struct {
+	consumer, period string
+	threshold        int
+}
+
+type hourKey struct {
+	consumer, service, pricing string
+	hour                       time.Time
+}
+
+type hourRow struct {
+	rollup  metering.Rollup
+	pricing *marketplace.PricingInfo
+}
+
+func newMemStore() *memStore {
+	return &memStore{
+		recorded: map[string]time.Time{},
+		hourly:   map[hourKey]*hourRow{},
+		budgets:  map[string]metering.Budget{},
+		alerted:  map[alertKey]bool{},
+	}
+}
+
+func (s *memStore) Record(_ context.Context, event *marketplace.UsageEvent, pricingKey string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return false, s.err
+	}
+	if _, ok := s.recorded[event.ID]; ok {
+		return false, nil
+	}
+	s.recorded[event.ID] = time.Now()
+
+	hour := event.OccurredAt.UTC().Truncate(time.Hour)
+	key := hourKey{event.ConsumerID, event.ServiceID, pricingKey, hour}
+	row, ok := s.hourly[key]
+	if !ok {
+		row = &hourRow{rollup: metering.Rollup{Start: hour, ConsumerID: event.ConsumerID, ServiceID: event.ServiceID, ProviderID: event.ProviderID}, pricing: event.Pricing}
+		s.hourly[key] = row
+	}
+	add(&row.rollup, event)
+	return true, nil
+}
+
+func add(r *metering.Rollup, event *marketplace.UsageEvent) {
+	r.Requests++
+	if metering.Billable(event) {
+		r.PromptTokens += event.PromptTokens
+		r.CompletionTokens += event.CompletionTokens
+		r.TotalTokens += event.TotalTokens
+	} else {
+		r.Errors++
+	}
+	if event.TokensEstimated {
+		r.EstimatedRequests++
+	}
+	r.LatencyMSSum += event.LatencyMS
+	r.MaxLatencyMS = max(r.MaxLatencyMS, event.LatencyMS)
+	r.UpstreamMSSum += event.UpstreamMS
+}
+
+func (s *memStore) Rollups(_ context.Context, filter metering.UsageFilter) ([]metering.Rollup, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return nil, s.err
+	}
+
+	type key struct {
+		consumer, service string
+		start             time.Time
+	}
+	merged := map[key]*metering.Rollup{}
+	for _, row := range s.hourly {
+		r := row.rollup
+		if filter.Granularity == metering.Daily {
+			r.Start = time.Date(r.Start.Year(), r.Start.Month(), r.Start.Day(), 0, 0, 0, 0, time.UTC)
+		}
+		if r.Start.Before(filter.From) || !r.Start.Before(filter.To) ||
+			(filter.ConsumerID != "" && r.ConsumerID != filter.ConsumerID) ||
+			(filter.ServiceID != "" && r.ServiceID != filter.ServiceID) ||
+			(filter.ProviderID != "" && r.ProviderID != filter.ProviderID) {
+			continue
+		}
+		k := key{r.ConsumerID, r.ServiceID, r.Start}
+		m, ok := merged[k]
+		if !ok {
+			merged[k] = &r
+			continue
+		}
+		m.Requests += r.Requests
+		m.Errors += r.Errors
+		m.EstimatedRequests += r.EstimatedRequests
+		m.PromptTokens += r.PromptTokens
+		m.CompletionTokens += r.CompletionTokens
+		m.TotalTokens += r.TotalTokens
+		m.LatencyMSSum += r.LatencyMSSum
+		m.MaxLatencyMS = max(m.MaxLatencyMS, r.MaxLatencyMS)
+		m.UpstreamMSSum += r.UpstreamMSSum
+	}
+
+	rollups := make([]metering.Rollup, 0, len(merged))
+	for _, r := range merged {
+		rollups = append(rollups, *r)
+	}
+	sort.Slice(rollups, func(i, j int) bool {
+		a, b := rollups[i], rollups[j]
+		if !a.Start.Equal(b.Start) {
+			return a.Start.Before(b.Start)
+		}
+		if a.ConsumerID != b.ConsumerID {
+			return a.ConsumerID < b.ConsumerID
+		}
+		return a.ServiceID < b.ServiceID
+	})
+	return rollups, nil
+}
+
+func (s *memStore) PricedUsage(_ context.Context, consumerID string, from, to time.Time) ([]metering.PricedUsage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return nil, s.err
+	}
+
+	var usage []metering.PricedUsage
+	for key, row := range s.hourly {
+		r := row.rollup
+		if key.consumer != consumerID || r.Start.Before(from) || !r.Start.Before(to) {
+			continue
+		}
+		usage = append(usage, metering.PricedUsage{
+			Hour: r.Start, ServiceID: r.ServiceID, ProviderID: r.ProviderID, Pricing: row.pricing,
+			Requests: r.Requests - r.Errors, Tokens: r.TotalTokens,
+		})
+	}
+	sort.Slice(usage, func(i, j int) bool {
+		if !usage[i].Hour.Equal(usage[j].Hour) {
+			return usage[i].Hour.Before(usage[j].Hour)
+		}
+		return usage[i].ServiceID < usage[j].ServiceID
+	})
+	return usage, nil
+}
+
+func (s *memStore) PruneRecorded(_ context.Context, before time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var n int64
+	for id, at := range s.recorded {
+		if at.Before(before) {
+			delete(s.recorded, id)
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (s *memStore) PutBudget(_ context.Context, budget *metering.Budget) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.budgets[budget.ConsumerID] = *budget
+	return nil
+}
+
+func (s *memStore) GetBudget(_ context.Context, consumerID string) (*metering.Budget, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	budget, ok := s.budgets[consumerID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s has no budget", metering.ErrNotFound, consumerID)
+	}
+	return &budget, nil
+}
+
+func (s *memStore) DeleteBudget(_ context.Context, consumerID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.budgets[consumerID]; !ok {
+		return fmt.Errorf("%w: %s has no budget", metering.ErrNotFound, consumerID)
+	}
+	delete(s.budgets, consumerID)
+	return nil
+}
+
+func (s *memStore) ListBudgets(_ context.Context) ([]metering.Budget, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	budgets := make([]metering.Budget, 0, len(s.budgets))
+	for _, b := range s.budgets {
+		budgets = append(budgets, b)
+	}
+	sort.Slice(budgets, func(i, j int) bool { return budgets[i].ConsumerID < budgets[j].ConsumerID })
+	return budgets, nil
+}
+
+func (s *memStore) RecordAlert(_ context.Context, consumerID, period string, threshold int, send func() error) (bool, error) {
+	key := alertKey{consumerID, period, threshold}
+	s.mu.Lock()
+	alerted := s.alerted[key]
+	s.mu.Unlock()
+	if alerted {
+		return false, nil
+	}
+	if err := send(); err != nil {
+		return false, err
+	}
+	s.mu.Lock()
+	s.alerted[key] = true
+	s.mu.Unlock()
+	return true, nil
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/org/llm-marketplace/pkg/marketplace/egress"
+	"github.com/org/llm-marketplace/services/notification/internal/api"
+	"github.com/org/llm-marketplace/services/notification/internal/channels"
+	"github.com/org/llm-marketplace/services/notification/internal/config"
+	"github.com/org/llm-marketplace/services/notification/internal/consumer"
+	"github.com/org/llm-marketplace/services/notification/internal/migrations"
+	"github.com/org/llm-marketplace/services/notification/internal/notify"
+	"github.com/org/llm-marketplace/services/notification/internal/store"
+)
+
+func main() {
+	// Load configuration; without a file, defaults and NOTIFICATION_* variables apply
+	configPath := config.Path()
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		panic(fmt.Sprintf("Failed to load configuration: %v", err))
+	}
+
+	logger, err := newLogger(cfg.Logging)
+	if err != nil {
+		panic(fmt.Sprintf("Failed to initialize logger: %v", err))
+	}
+	defer logger.Sync()
+
+	logger.Info("Starting LLM-Marketplace Notification Service",
+		zap.String("version", "1.0.0"),
+		zap.String("environment", os.Getenv("ENVIRONMENT")),
+		zap.String("config", configPath),
+	)
+
+	ctx := context.Background()
+
+	pool, err := store.NewPool(ctx, cfg.Postgres)
+	if err != nil {
+		logger.Fatal("Failed to connect to PostgreSQL", zap.Error(err))
+	}
+	defer pool.Close()
+
+	if cfg.Postgres.AutoMigrate {
+		if _, err := migrations.Up(ctx, pool, logger); err != nil {
+			logger.Fatal("Failed to apply migrations", zap.Error(err))
+		}
+	}
+
+	templates, err := notify.ParseTemplates(cfg.Templates)
+	if err != nil {
+		logger.Fatal("Failed to parse templates", zap.Error(err))
+	}
+	notifyService := notify.NewService(store.New(pool), templates, cfg.Delivery.Policy(), logger)
+
+	// Email needs an SMTP relay; Slack and webhooks only the network. Their
+	// URLs are the recipients', so they must not reach ours.
+	httpClient := egress.Client(cfg.Delivery.Timeout)
+	if cfg.Email.SMTPHost != "" {
+		notifyService.SetSender(notify.ChannelEmail, channels.NewEmail(
+			cfg.Email.SMTPHost, cfg.Email.SMTPPort, cfg.Email.Username, cfg.Email.Password, cfg.Email.From))
+	} else {
+		logger.Warn("Email is not delivered: email.smtp_host is not set")
+	}
+	notifyService.SetSender(notify.ChannelSlack, channels.NewSlack(httpClient))
+	notifyService.SetSender(notify.ChannelWebhook, channels.NewWebhook(httpClient))
+
+	// Events are notified, deliveries sent and old notifications pruned until
+	// shutdown
+	workCtx, stopWork := context.WithCancel(ctx)
+	var workers sync.WaitGroup
+	topics := cfg.Kafka.Topics()
+	for _, kind := range notify.Kinds {
+		topic, ok := topics[kind]
+		if !ok {
+			continue
+		}
+		c := consumer.NewConsumer(kind, topic, cfg.Kafka, notifyService, logger)
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			c.Start(workCtx)
+		}()
+	}
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		notifyService.StartDispatcher(workCtx, cfg.Delivery.PollInterval)
+	}()
+	go notifyService.StartPruning(workCtx, cfg.Delivery.PruneInterval, cfg.Delivery.Retention)
+
+	// REST API
+	if cfg.Server.Mode == "production" {
+		gin.SetMode(gin.ReleaseMode)
+	}
+	router := gin.New()
+	router.HandleMethodNotAllowed = true
+	router.Use(gin.Recovery())
+
+	router.GET("/health", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{
+			"status":    "healthy",
+			"timestamp": time.Now().UTC(),
+		})
+	})
+	router.GET("/ready", api.Readiness(map[string]api.Check{
+		"postgres": pool.Ping,
+	}, 2*time.Second))
+	router.GET("/metrics", gin.WrapH(promhttp.Handler()))
+	api.RegisterRoutes(router, notifyService, cfg.Server.RecipientHeaders, logger)
+
+	addr := fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Server.Port)
+	server := &http.Server{
+		Addr:         addr,
+		Handler:      router,
+		ReadTimeout:  cfg.Server.ReadTimeout,
+		WriteTimeout: cfg.Server.WriteTimeout,
+		IdleTimeout:  cfg.Server.IdleTimeout,
+	}
+	go func() {
+		logger.Info("Starting HTTP server", zap.String("address", addr))
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			logger.Fatal("Failed to start HTTP server", zap.Error(err))
+		}
+	}()
+
+	// Graceful shutdown
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+
+	logger.Info("Shutting down server...")
+
+	shutdownCtx, cancel := context.WithTimeout(ctx, cfg.Server.ShutdownTimeout)
+	defer cancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		logger.Error("Server forced to shutdown", zap.Error(err))
+	}
+
+	// An event being notified is redelivered on the next start; a delivery
+	// being sent is retried once its lease expires
+	stopWork()
+	workers.Wait()
+
+	logger.Info("Server exited")
+}
+
+func newLogger(cfg config.LoggingConfig) (*zap.Logger, error) {
+	level, err := zapcore.ParseLevel(cfg.Level)
+	if err != nil {
+		return nil, err
+	}
+	zapConfig := zap.NewProductionConfig()
+	if cfg.Format == "console" {
+		zapConfig = zap.NewDevelopmentConfig()
+	}
+	zapConfig.Level = zap.NewAtomicLevelAt(level)
+	return zapConfig.Build()
+}
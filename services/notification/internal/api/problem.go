@@ -0,0 +1,32 @@
+package api
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/org/llm-marketplace/pkg/marketplace/problem"
+	"go.uber.org/zap"
+
+	"github.com/org/llm-marketplace/services/notification/internal/notify"
+)
+
+// abort writes problem details and stops the handler chain
+func abort(c *gin.Context, t problem.Type, detail string) {
+	problem.Error(c.Writer, c.Request, t, detail)
+	c.Abort()
+}
+
+// abortWithError maps a notify error to its problem type
+func abortWithError(c *gin.Context, err error, logger *zap.Logger) {
+	switch {
+	case errors.Is(err, notify.ErrInvalidRequest):
+		abort(c, problem.InvalidRequest, err.Error())
+	case errors.Is(err, notify.ErrForbidden):
+		abort(c, problem.Forbidden, err.Error())
+	case errors.Is(err, notify.ErrNotFound):
+		abort(c, problem.NotFound, err.Error())
+	default:
+		logger.Error("Request failed", zap.String("path", c.Request.URL.Path), zap.Error(err))
+		abort(c, problem.InternalError, "")
+	}
+}
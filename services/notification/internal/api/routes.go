@@ -0,0 +1,214 @@
+// Package api serves the notification preferences and history API
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/org/llm-marketplace/pkg/marketplace/problem"
+	"go.uber.org/zap"
+
+	"github.com/org/llm-marketplace/services/notification/internal/notify"
+)
+
+// RegisterRoutes registers the API routes. recipientHeaders name the
+// headers carrying the caller the gateway authenticated: a caller only reads
+// and changes the preferences and notifications of the IDs they carry.
+// Requests with none are trusted as operators.
+func RegisterRoutes(router *gin.Engine, svc *notify.Service, recipientHeaders []string, logger *zap.Logger) {
+	h := &handlers{svc: svc, recipientHeaders: recipientHeaders, logger: logger}
+
+	api := router.Group("/api/v1")
+	{
+		recipients := api.Group("/recipients/:id")
+		recipients.GET("/preferences", h.getPreferences)
+		recipients.PUT("/preferences", h.putPreferences)
+		recipients.DELETE("/preferences", h.deletePreferences)
+		recipients.GET("/notifications", h.listNotifications)
+
+		api.GET("/notifications/:id", h.getNotification)
+	}
+
+	router.NoRoute(func(c *gin.Context) {
+		abort(c, problem.NotFound, "No route matches "+c.Request.URL.Path)
+	})
+	router.NoMethod(func(c *gin.Context) {
+		abort(c, problem.MethodNotAllowed, c.Request.Method+" is not supported on "+c.Request.URL.Path)
+	})
+}
+
+type handlers struct {
+	svc              *notify.Service
+	recipientHeaders []string
+	logger           *zap.Logger
+}
+
+// preferencesRequest is the body of PUT /preferences. Unlike the response,
+// it carries the webhook secret.
+type preferencesRequest struct {
+	Email           string                           `json:"email"`
+	SlackWebhookURL string                           `json:"slack_webhook_url"`
+	WebhookURL      string                           `json:"webhook_url"`
+	WebhookSecret   string                           `json:"webhook_secret"`
+	Kinds           map[string]notify.KindPreference `json:"kinds"`
+}
+
+// getPreferences handles GET /api/v1/recipients/:id/preferences
+func (h *handlers) getPreferences(c *gin.Context) {
+	id, ok := h.recipient(c)
+	if !ok {
+		return
+	}
+	prefs, err := h.svc.Preferences(c.Request.Context(), id)
+	if err != nil {
+		abortWithError(c, err, h.logger)
+		return
+	}
+	c.JSON(http.StatusOK, prefs)
+}
+
+// putPreferences handles PUT /api/v1/recipients/:id/preferences. The body
+// replaces the recipient's preferences whole.
+func (h *handlers) putPreferences(c *gin.Context) {
+	id, ok := h.recipient(c)
+	if !ok {
+		return
+	}
+	var req preferencesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		abort(c, problem.InvalidRequest, err.Error())
+		return
+	}
+	prefs, err := h.svc.SetPreferences(c.Request.Context(), notify.Preferences{
+		RecipientID:     id,
+		Email:           req.Email,
+		SlackWebhookURL: req.SlackWebhookURL,
+		WebhookURL:      req.WebhookURL,
+		WebhookSecret:   req.WebhookSecret,
+		Kinds:           req.Kinds,
+	})
+	if err != nil {
+		abortWithError(c, err, h.logger)
+		return
+	}
+	c.JSON(http.StatusOK, prefs)
+}
+
+// deletePreferences handles DELETE /api/v1/recipients/:id/preferences
+func (h *handlers) deletePreferences(c *gin.Context) {
+	id, ok := h.recipient(c)
+	if !ok {
+		return
+	}
+	if err := h.svc.DeletePreferences(c.Request.Context(), id); err != nil {
+		abortWithError(c, err, h.logger)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// listNotifications handles GET /api/v1/recipients/:id/notifications
+func (h *handlers) listNotifications(c *gin.Context) {
+	id, ok := h.recipient(c)
+	if !ok {
+		return
+	}
+	filter := notify.NotificationFilter{RecipientID: id, Kind: c.Query("kind")}
+	for _, p := range []struct {
+		name  string
+		value *int
+	}{{"limit", &filter.Limit}, {"offset", &filter.Offset}} {
+		if v := c.Query(p.name); v != "" {
+			var err error
+			if *p.value, err = strconv.Atoi(v); err != nil {
+				abort(c, problem.InvalidRequest, fmt.Sprintf("%s: %q is not a number", p.name, v))
+				return
+			}
+		}
+	}
+
+	notifications, err := h.svc.Notifications(c.Request.Context(), filter)
+	if err != nil {
+		abortWithError(c, err, h.logger)
+		return
+	}
+	if notifications == nil {
+		notifications = []*notify.Notification{}
+	}
+	c.JSON(http.StatusOK, gin.H{"notifications": notifications, "total": len(notifications)})
+}
+
+// getNotification handles GET /api/v1/notifications/:id
+func (h *handlers) getNotification(c *gin.Context) {
+	n, err := h.svc.Notification(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		abortWithError(c, err, h.logger)
+		return
+	}
+	if !h.allowed(c, n.RecipientID) {
+		// Other recipients' notifications are reported as missing
+		abortWithError(c, fmt.Errorf("%w: notification %s", notify.ErrNotFound, c.Param("id")), h.logger)
+		return
+	}
+	c.JSON(http.StatusOK, n)
+}
+
+// recipient returns the :id of the request, aborting with 403 when the
+// caller is someone else
+func (h *handlers) recipient(c *gin.Context) (string, bool) {
+	id := c.Param("id")
+	if !h.allowed(c, id) {
+		abortWithError(c, fmt.Errorf("%w: callers can only manage their own notifications", notify.ErrForbidden), h.logger)
+		return "", false
+	}
+	return id, true
+}
+
+// allowed reports whether the caller may act for recipientID: operators
+// always, others when one of their headers names it
+func (h *handlers) allowed(c *gin.Context, recipientID string) bool {
+	operator := true
+	for _, header := range h.recipientHeaders {
+		caller := c.GetHeader(header)
+		if caller == "" {
+			continue
+		}
+		if caller == recipientID {
+			return true
+		}
+		operator = false
+	}
+	return operator
+}
+
+// Check is a readiness check of one dependency
+type Check func(ctx context.Context) error
+
+// Readiness reports 200 when every check passes and 503 naming the failing
+// ones otherwise
+func Readiness(checks map[string]Check, timeout time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+
+		status := http.StatusOK
+		results := make(map[string]string, len(checks))
+		for name, check := range checks {
+			if err := check(ctx); err != nil {
+				status = http.StatusServiceUnavailable
+				results[name] = err.Error()
+				continue
+			}
+			results[name] = "ok"
+		}
+		ready := "ready"
+		if status != http.StatusOK {
+			ready = "not_ready"
+		}
+		c.JSON(status, gin.H{"status": ready, "checks": results, "timestamp": time.Now().UTC()})
+	}
+}
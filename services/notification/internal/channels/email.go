@@ -0,0 +1,71 @@
+package channels
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/org/llm-marketplace/services/notification/internal/notify"
+)
+
+// Email sends messages as plain text through an SMTP relay
+type Email struct {
+	addr string
+	host string
+	auth smtp.Auth
+	from string
+	now  func() time.Time
+}
+
+// NewEmail creates an email sender relaying through host:port. Without a
+// username the relay is used unauthenticated.
+func NewEmail(host string, port int, username, password, from string) *Email {
+	e := &Email{addr: net.JoinHostPort(host, strconv.Itoa(port)), host: host, from: from, now: time.Now}
+	if username != "" {
+		e.auth = smtp.PlainAuth("", username, password, host)
+	}
+	return e
+}
+
+// Send mails msg to the delivery's address. net/smtp has no context, so the
+// send is bounded by the dispatcher's lease rather than ctx.
+func (e *Email) Send(ctx context.Context, d *notify.Delivery, msg *notify.Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	from, err := mail.ParseAddress(e.from)
+	if err != nil {
+		return fmt.Errorf("invalid sender address: %w", err)
+	}
+	return smtp.SendMail(e.addr, e.auth, from.Address, []string{d.Address}, e.compose(d, msg))
+}
+
+// compose renders msg as an RFC 5322 message
+func (e *Email) compose(d *notify.Delivery, msg *notify.Message) []byte {
+	var b strings.Builder
+	header := func(name, value string) {
+		fmt.Fprintf(&b, "%s: %s\r\n", name, value)
+	}
+	header("From", e.from)
+	header("To", d.Address)
+	header("Subject", mime.QEncoding.Encode("utf-8", msg.Subject))
+	header("Date", e.now().Format(time.RFC1123Z))
+	// Delivery IDs aren't valid message IDs, so the ID is hashed; retries
+	// of a delivery keep the same Message-ID
+	id := sha256.Sum256([]byte(msg.ID))
+	header("Message-ID", fmt.Sprintf("<%x@%s>", id[:16], e.host))
+	header("MIME-Version", "1.0")
+	header("Content-Type", `text/plain; charset="utf-8"`)
+	header("Content-Transfer-Encoding", "8bit")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(strings.ReplaceAll(msg.Body, "\r\n", "\n"), "\n", "\r\n"))
+	b.WriteString("\r\n")
+	return []byte(b.String())
+}
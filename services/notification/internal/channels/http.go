@@ -0,0 +1,135 @@
+// Package channels sends notification messages by email, to Slack incoming
+// webhooks and to signed recipient webhooks
+package channels
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/org/llm-marketplace/services/notification/internal/notify"
+)
+
+// Webhook request headers, the same discovery sends catalog events with
+const (
+	HeaderEvent     = "X-Marketplace-Event"
+	HeaderDelivery  = "X-Marketplace-Delivery"
+	HeaderSignature = "X-Marketplace-Signature"
+)
+
+// EventDigest is the event header of a webhook carrying several notifications
+const EventDigest = "digest"
+
+const userAgent = "llm-marketplace-notifications/1.0"
+
+// Slack posts messages to a Slack incoming webhook
+type Slack struct {
+	client *http.Client
+}
+
+// NewSlack creates a Slack sender
+func NewSlack(client *http.Client) *Slack {
+	return &Slack{client: client}
+}
+
+// Send posts msg to the delivery's webhook URL
+func (s *Slack) Send(ctx context.Context, d *notify.Delivery, msg *notify.Message) error {
+	body, err := json.Marshal(map[string]string{"text": fmt.Sprintf("*%s*\n%s", msg.Subject, msg.Body)})
+	if err != nil {
+		return err
+	}
+	req, err := newRequest(ctx, d.Address, body)
+	if err != nil {
+		return err
+	}
+	return do(s.client, req)
+}
+
+// Webhook posts messages as signed JSON to a recipient's endpoint
+type Webhook struct {
+	client *http.Client
+	now    func() time.Time
+}
+
+// NewWebhook creates a webhook sender
+func NewWebhook(client *http.Client) *Webhook {
+	return &Webhook{client: client, now: time.Now}
+}
+
+// WebhookBody is the JSON body posted to recipient webhooks
+type WebhookBody struct {
+	ID            string                 `json:"id"`
+	Subject       string                 `json:"subject"`
+	Body          string                 `json:"body"`
+	Notifications []*notify.Notification `json:"notifications"`
+}
+
+// Send posts msg to the delivery's webhook URL, signed with its secret when
+// the recipient set one
+func (w *Webhook) Send(ctx context.Context, d *notify.Delivery, msg *notify.Message) error {
+	body, err := json.Marshal(WebhookBody{ID: msg.ID, Subject: msg.Subject, Body: msg.Body, Notifications: msg.Notifications})
+	if err != nil {
+		return err
+	}
+	req, err := newRequest(ctx, d.Address, body)
+	if err != nil {
+		return err
+	}
+	event := EventDigest
+	if len(msg.Notifications) == 1 {
+		event = msg.Notifications[0].Kind
+	}
+	req.Header.Set(HeaderEvent, event)
+	req.Header.Set(HeaderDelivery, msg.ID)
+	if d.Secret != "" {
+		req.Header.Set(HeaderSignature, Sign(d.Secret, w.now(), body))
+	}
+	return do(w.client, req)
+}
+
+// Sign computes the signature header value for body sent at timestamp.
+// Receivers recompute HMAC-SHA256 over "<t>.<body>" with their secret and
+// should reject stale timestamps to prevent replay.
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	t := strconv.FormatInt(timestamp.Unix(), 10)
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(t))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	return fmt.Sprintf("t=%s,v1=%s", t, hex.EncodeToString(mac.Sum(nil)))
+}
+
+func newRequest(ctx context.Context, url string, body []byte) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", userAgent)
+	return req, nil
+}
+
+// do sends req and fails on any status other than 2xx
+func do(client *http.Client, req *http.Request) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("endpoint returned %s", resp.Status)
+	}
+	return nil
+}
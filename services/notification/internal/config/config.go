@@ -0,0 +1,221 @@
+// Package config loads the notification service configuration from built-in
+// defaults, an optional YAML file and NOTIFICATION_* environment variables,
+// in that order.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/org/llm-marketplace/pkg/marketplace/envconfig"
+	"gopkg.in/yaml.v3"
+
+	"github.com/org/llm-marketplace/services/notification/internal/notify"
+)
+
+type Config struct {
+	Server    ServerConfig                   `yaml:"server"`
+	Postgres  PostgresConfig                 `yaml:"postgres"`
+	Kafka     KafkaConfig                    `yaml:"kafka"`
+	Delivery  DeliveryConfig                 `yaml:"delivery"`
+	Email     EmailConfig                    `yaml:"email"`
+	Templates map[string]notify.TemplateText `yaml:"templates"` // Override the default templates of some kinds
+	Logging   LoggingConfig                  `yaml:"logging"`
+}
+
+type ServerConfig struct {
+	Host         string        `yaml:"host"`
+	Port         int           `yaml:"port"`
+	Mode         string        `yaml:"mode"` // development, production
+	ReadTimeout  time.Duration `yaml:"read_timeout"`
+	WriteTimeout time.Duration `yaml:"write_timeout"`
+	IdleTimeout  time.Duration `yaml:"idle_timeout"`
+
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+
+	// RecipientHeaders carry the authenticated caller, set by the gateway.
+	// A caller only reads and changes the notifications and preferences of
+	// the IDs in these headers; requests with none act as an operator.
+	RecipientHeaders []string `yaml:"recipient_headers"`
+}
+
+type PostgresConfig struct {
+	Host        string        `yaml:"host"`
+	Port        int           `yaml:"port"`
+	Database    string        `yaml:"database"`
+	User        string        `yaml:"user"`
+	Password    string        `yaml:"password"`
+	SSLMode     string        `yaml:"ssl_mode"`
+	MaxConns    int           `yaml:"max_conns"`
+	MinConns    int           `yaml:"min_conns"`
+	ConnTimeout time.Duration `yaml:"conn_timeout"`
+	AutoMigrate bool          `yaml:"auto_migrate"` // Apply pending migrations at startup
+}
+
+// DSN returns the connection string for the pool
+func (c PostgresConfig) DSN() string {
+	return fmt.Sprintf("host=%s port=%d dbname=%s user=%s password='%s' sslmode=%s",
+		c.Host, c.Port, c.Database, c.User, dsnEscaper.Replace(c.Password), c.SSLMode)
+}
+
+var dsnEscaper = strings.NewReplacer(`\`, `\\`, `'`, `\'`)
+
+// KafkaConfig is where events are read from. An empty topic disables its
+// kind of notification.
+type KafkaConfig struct {
+	Brokers          []string      `yaml:"brokers"`
+	ConsumerGroup    string        `yaml:"consumer_group"`
+	ValidationTopic  string        `yaml:"validation_topic"`   // Descriptor checks published by the registry; failures notify the provider
+	SLATopic         string        `yaml:"sla_topic"`          // Health changes published by discovery
+	SavedSearchTopic string        `yaml:"saved_search_topic"` // Saved-search matches
+	BudgetTopic      string        `yaml:"budget_topic"`       // Budget alerts published by metering
+	PriceChangeTopic string        `yaml:"price_change_topic"` // Price-change notices published by the registry
+	ExpiryTopic      string        `yaml:"expiry_topic"`       // Compliance expiry notices published by the registry
+	RetryBackoff     time.Duration `yaml:"retry_backoff"`      // Delay after the first failure to store a notification, doubled after each
+	MaxRetryBackoff  time.Duration `yaml:"max_retry_backoff"`  // Failed events are retried until they succeed; later events wait
+}
+
+// Topics returns the topic of each enabled kind
+func (c KafkaConfig) Topics() map[string]string {
+	topics := make(map[string]string)
+	for kind, topic := range map[string]string{
+		notify.KindPolicyViolation:  c.ValidationTopic,
+		notify.KindSLABreach:        c.SLATopic,
+		notify.KindSavedSearchMatch: c.SavedSearchTopic,
+		notify.KindBudgetAlert:      c.BudgetTopic,
+		notify.KindPriceChange:      c.PriceChangeTopic,
+		notify.KindComplianceExpiry: c.ExpiryTopic,
+	} {
+		if topic != "" {
+			topics[kind] = topic
+		}
+	}
+	return topics
+}
+
+// DeliveryConfig controls sending notifications and how long they are kept
+type DeliveryConfig struct {
+	PollInterval    time.Duration `yaml:"poll_interval"`     // How often due deliveries are sent
+	BatchSize       int           `yaml:"batch_size"`        // Deliveries claimed per round
+	Timeout         time.Duration `yaml:"timeout"`           // Bound on one send
+	MaxAttempts     int           `yaml:"max_attempts"`      // A delivery fails for good after this many attempts
+	RetryBackoff    time.Duration `yaml:"retry_backoff"`     // Delay after the first failed attempt, doubled after each
+	MaxRetryBackoff time.Duration `yaml:"max_retry_backoff"` // Longest delay between attempts
+	DigestInterval  time.Duration `yaml:"digest_interval"`   // Digests go out at multiples of this in UTC; 24h is daily at midnight
+	Retention       time.Duration `yaml:"retention"`         // How long notifications and their deliveries are kept
+	PruneInterval   time.Duration `yaml:"prune_interval"`
+}
+
+// Policy returns the delivery policy of the notify service
+func (c DeliveryConfig) Policy() notify.Policy {
+	return notify.Policy{
+		BatchSize:       c.BatchSize,
+		Timeout:         c.Timeout,
+		MaxAttempts:     c.MaxAttempts,
+		RetryBackoff:    c.RetryBackoff,
+		MaxRetryBackoff: c.MaxRetryBackoff,
+		DigestInterval:  c.DigestInterval,
+	}
+}
+
+// EmailConfig is the SMTP relay email is sent through. Without a host,
+// email isn't delivered.
+type EmailConfig struct {
+	SMTPHost string `yaml:"smtp_host"`
+	SMTPPort int    `yaml:"smtp_port"`
+	Username string `yaml:"username"` // PLAIN authentication when set
+	Password string `yaml:"password"`
+	From     string `yaml:"from"`
+}
+
+type LoggingConfig struct {
+	Level  string `yaml:"level"`  // debug, info, warn, error
+	Format string `yaml:"format"` // json, console
+}
+
+// DefaultPath is the config file used when CONFIG_PATH is not set
+const DefaultPath = "config.yaml"
+
+// Path returns the config file to load: CONFIG_PATH if set, otherwise
+// config.yaml when it exists in the working directory. It is empty when the
+// service is configured by defaults and environment variables alone.
+func Path() string {
+	if path := os.Getenv("CONFIG_PATH"); path != "" {
+		return path
+	}
+	if _, err := os.Stat(DefaultPath); err == nil {
+		return DefaultPath
+	}
+	return ""
+}
+
+// Load builds the configuration from the built-in defaults, overridden by the
+// file at path, if any, then by NOTIFICATION_* environment variables
+func Load(path string) (*Config, error) {
+	var cfg Config
+	cfg.setDefaults()
+
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read config file: %w", err)
+		}
+		if err := yaml.Unmarshal([]byte(os.ExpandEnv(string(data))), &cfg); err != nil {
+			return nil, fmt.Errorf("failed to parse config: %w", err)
+		}
+	}
+
+	if err := envconfig.Apply(&cfg, "NOTIFICATION_", os.Getenv); err != nil {
+		return nil, fmt.Errorf("invalid environment override: %w", err)
+	}
+
+	if err := validate(&cfg); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+	return &cfg, nil
+}
+
+func validate(cfg *Config) error {
+	var errs []error
+	if cfg.Server.Port <= 0 || cfg.Server.Port > 65535 {
+		errs = append(errs, fmt.Errorf("server.port %d is not a valid port", cfg.Server.Port))
+	}
+	if len(cfg.Server.RecipientHeaders) == 0 {
+		errs = append(errs, errors.New("server.recipient_headers is required"))
+	}
+	if cfg.Postgres.Host == "" || cfg.Postgres.Database == "" {
+		errs = append(errs, errors.New("postgres.host and postgres.database are required"))
+	}
+	if len(cfg.Kafka.Brokers) == 0 || cfg.Kafka.ConsumerGroup == "" {
+		errs = append(errs, errors.New("kafka.brokers and kafka.consumer_group are required"))
+	}
+	if len(cfg.Kafka.Topics()) == 0 {
+		errs = append(errs, errors.New("at least one of the kafka topics is required"))
+	}
+	if cfg.Kafka.RetryBackoff <= 0 || cfg.Kafka.MaxRetryBackoff < cfg.Kafka.RetryBackoff {
+		errs = append(errs, errors.New("kafka.retry_backoff must be positive and no longer than kafka.max_retry_backoff"))
+	}
+	d := cfg.Delivery
+	if d.PollInterval <= 0 || d.BatchSize <= 0 || d.Timeout <= 0 || d.MaxAttempts <= 0 {
+		errs = append(errs, errors.New("delivery.poll_interval, batch_size, timeout and max_attempts must be positive"))
+	}
+	if d.RetryBackoff <= 0 || d.MaxRetryBackoff < d.RetryBackoff {
+		errs = append(errs, errors.New("delivery.retry_backoff must be positive and no longer than delivery.max_retry_backoff"))
+	}
+	if d.DigestInterval < time.Minute {
+		errs = append(errs, errors.New("delivery.digest_interval must be at least a minute"))
+	}
+	if d.Retention <= 0 || d.PruneInterval <= 0 {
+		errs = append(errs, errors.New("delivery.retention and delivery.prune_interval must be positive"))
+	}
+	if cfg.Email.SMTPHost != "" && (cfg.Email.SMTPPort <= 0 || cfg.Email.From == "") {
+		errs = append(errs, errors.New("email.smtp_port and email.from are required with email.smtp_host"))
+	}
+	if _, err := notify.ParseTemplates(cfg.Templates); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,58 @@
+package config
+
+import (
+	"time"
+
+	"github.com/org/llm-marketplace/pkg/marketplace"
+)
+
+// setDefaults fills in the settings used when neither the config file nor the
+// environment sets them. They match config.yaml, with dependencies expected
+// on localhost.
+func (c *Config) setDefaults() {
+	c.Server.Host = "0.0.0.0"
+	c.Server.Port = 3050
+	c.Server.Mode = "development"
+	c.Server.ReadTimeout = 30 * time.Second
+	c.Server.WriteTimeout = 30 * time.Second
+	c.Server.IdleTimeout = 120 * time.Second
+	c.Server.ShutdownTimeout = 30 * time.Second
+	c.Server.RecipientHeaders = []string{"X-Consumer-ID", "X-Provider-ID", "X-User-ID"}
+
+	c.Postgres.Host = "localhost"
+	c.Postgres.Port = 5432
+	c.Postgres.Database = "notification"
+	c.Postgres.User = "notification"
+	c.Postgres.SSLMode = "prefer"
+	c.Postgres.MaxConns = 20
+	c.Postgres.MinConns = 2
+	c.Postgres.ConnTimeout = 5 * time.Second
+	c.Postgres.AutoMigrate = true
+
+	c.Kafka.Brokers = []string{"localhost:9092"}
+	c.Kafka.ConsumerGroup = "notification"
+	c.Kafka.ValidationTopic = marketplace.ValidationTopic
+	c.Kafka.SLATopic = marketplace.SLABreachTopic
+	c.Kafka.SavedSearchTopic = marketplace.SavedSearchTopic
+	c.Kafka.BudgetTopic = marketplace.BudgetAlertTopic
+	c.Kafka.PriceChangeTopic = marketplace.PriceChangeTopic
+	c.Kafka.ExpiryTopic = marketplace.ComplianceExpiryTopic
+	c.Kafka.RetryBackoff = time.Second
+	c.Kafka.MaxRetryBackoff = time.Minute
+
+	c.Delivery.PollInterval = 5 * time.Second
+	c.Delivery.BatchSize = 100
+	c.Delivery.Timeout = 10 * time.Second
+	c.Delivery.MaxAttempts = 6
+	c.Delivery.RetryBackoff = 30 * time.Second
+	c.Delivery.MaxRetryBackoff = time.Hour
+	c.Delivery.DigestInterval = 24 * time.Hour
+	c.Delivery.Retention = 90 * 24 * time.Hour
+	c.Delivery.PruneInterval = time.Hour
+
+	c.Email.SMTPPort = 587
+	c.Email.From = "LLM Marketplace <notifications@llm-marketplace.com>"
+
+	c.Logging.Level = "info"
+	c.Logging.Format = "json"
+}
@@ -0,0 +1,139 @@
+// Package consumer reads the events the marketplace services publish and
+// turns them into notifications
+package consumer
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"go.uber.org/zap"
+
+	"github.com/org/llm-marketplace/services/notification/internal/config"
+	"github.com/org/llm-marketplace/services/notification/internal/notify"
+)
+
+// Reader fetches events; *kafka.Reader satisfies it
+type Reader interface {
+	FetchMessage(ctx context.Context) (kafka.Message, error)
+	CommitMessages(ctx context.Context, msgs ...kafka.Message) error
+	Close() error
+}
+
+// Notifier makes notifications; *notify.Service satisfies it
+type Notifier interface {
+	Notify(ctx context.Context, n *notify.Notification) error
+	Ignored(kind string)
+	Invalid(kind string)
+}
+
+// Consumer notifies the events of one kind in order. Offsets are committed
+// once an event is notified or skipped, so each event is notified at least
+// once; the store drops redelivered events.
+type Consumer struct {
+	kind     string
+	topic    string
+	reader   Reader
+	notifier Notifier
+	config   config.KafkaConfig
+	logger   *zap.Logger
+}
+
+// NewConsumer creates a consumer reading kind's events from topic
+func NewConsumer(kind, topic string, cfg config.KafkaConfig, notifier Notifier, logger *zap.Logger) *Consumer {
+	return &Consumer{
+		kind:  kind,
+		topic: topic,
+		reader: kafka.NewReader(kafka.ReaderConfig{
+			Brokers:  cfg.Brokers,
+			Topic:    topic,
+			GroupID:  cfg.ConsumerGroup,
+			MinBytes: 1,
+			MaxBytes: 10 << 20,
+		}),
+		notifier: notifier,
+		config:   cfg,
+		logger:   logger.With(zap.String("kind", kind)),
+	}
+}
+
+// SetReader replaces the Kafka reader
+func (c *Consumer) SetReader(reader Reader) {
+	c.reader = reader
+}
+
+// Start notifies events until ctx is cancelled. An event that fails for a
+// transient reason is retried with backoff; the events after it wait, so
+// none are lost while the database is unavailable.
+func (c *Consumer) Start(ctx context.Context) {
+	defer c.reader.Close()
+
+	c.logger.Info("Event consumer started", zap.String("topic", c.topic))
+
+	for {
+		msg, err := c.reader.FetchMessage(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				c.logger.Info("Event consumer stopped")
+				return
+			}
+			c.logger.Warn("Failed to fetch event", zap.Error(err))
+			if !sleep(ctx, time.Second) {
+				return
+			}
+			continue
+		}
+
+		backoff := c.config.RetryBackoff
+		for {
+			err := c.Apply(ctx, msg.Value)
+			if err == nil {
+				break
+			}
+			if errors.Is(err, notify.ErrInvalidEvent) {
+				c.logger.Error("Skipping event", zap.Int64("offset", msg.Offset), zap.String("key", string(msg.Key)), zap.Error(err))
+				c.notifier.Invalid(c.kind)
+				break
+			}
+			c.logger.Warn("Failed to notify event, retrying",
+				zap.Int64("offset", msg.Offset),
+				zap.String("key", string(msg.Key)),
+				zap.Duration("retry_in", backoff),
+				zap.Error(err),
+			)
+			if !sleep(ctx, backoff) {
+				return
+			}
+			backoff = min(backoff*2, c.config.MaxRetryBackoff)
+		}
+
+		if err := c.reader.CommitMessages(ctx, msg); err != nil && ctx.Err() == nil {
+			// The event is notified; on redelivery its notification exists
+			c.logger.Warn("Failed to commit event offset", zap.Error(err))
+		}
+	}
+}
+
+// Apply notifies one event. It returns an error wrapping
+// notify.ErrInvalidEvent for an event that can never be notified.
+func (c *Consumer) Apply(ctx context.Context, payload []byte) error {
+	n, err := notify.Decode(c.kind, payload)
+	if err != nil {
+		return err
+	}
+	if n == nil {
+		c.notifier.Ignored(c.kind)
+		return nil
+	}
+	return c.notifier.Notify(ctx, n)
+}
+
+func sleep(ctx context.Context, d time.Duration) bool {
+	select {
+	case <-time.After(d):
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
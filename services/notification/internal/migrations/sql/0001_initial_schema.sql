@@ -0,0 +1,49 @@
+-- Initial notification service schema: recipients' preferences, the
+-- notifications made from marketplace events, and their deliveries.
+
+-- Where and how each recipient is notified. kinds overrides the channels and
+-- digesting of some notification kinds.
+CREATE TABLE IF NOT EXISTS preferences (
+    recipient_id TEXT PRIMARY KEY,
+    email TEXT NOT NULL DEFAULT '',
+    slack_webhook_url TEXT NOT NULL DEFAULT '',
+    webhook_url TEXT NOT NULL DEFAULT '',
+    webhook_secret TEXT NOT NULL DEFAULT '',
+    kinds JSONB NOT NULL DEFAULT '{}',
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- The ID is derived from the event, so redelivered events are dropped
+CREATE TABLE IF NOT EXISTS notifications (
+    id TEXT PRIMARY KEY,
+    kind VARCHAR(50) NOT NULL,
+    recipient_id TEXT NOT NULL,
+    subject TEXT NOT NULL,
+    body TEXT NOT NULL,
+    data JSONB NOT NULL,
+    occurred_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_notifications_recipient ON notifications(recipient_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_notifications_created_at ON notifications(created_at);
+
+-- One row per notification and channel. Pending deliveries are claimed once
+-- next_attempt_at passes; claiming pushes it forward by a lease.
+CREATE TABLE IF NOT EXISTS deliveries (
+    id TEXT PRIMARY KEY,
+    notification_id TEXT NOT NULL REFERENCES notifications(id) ON DELETE CASCADE,
+    recipient_id TEXT NOT NULL,
+    channel VARCHAR(20) NOT NULL,
+    address TEXT NOT NULL,
+    secret TEXT NOT NULL DEFAULT '',
+    digest BOOLEAN NOT NULL DEFAULT FALSE,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL DEFAULT '',
+    next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    sent_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_deliveries_notification ON deliveries(notification_id);
+CREATE INDEX IF NOT EXISTS idx_deliveries_due ON deliveries(next_attempt_at) WHERE status = 'pending';
@@ -0,0 +1,249 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+)
+
+// Delivery states
+const (
+	DeliveryPending = "pending"
+	DeliverySent    = "sent"
+	DeliveryFailed  = "failed"
+)
+
+var deliveriesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "notification_deliveries_total",
+	Help: "Delivery attempts by channel and result: sent, retried or failed",
+}, []string{"channel", "result"})
+
+// Delivery tracks sending a notification on one channel. The address is the
+// one the recipient's preferences had when the notification was made.
+type Delivery struct {
+	ID             string     `json:"id"`
+	NotificationID string     `json:"notification_id"`
+	RecipientID    string     `json:"recipient_id"`
+	Channel        string     `json:"channel"`
+	Address        string     `json:"address"`
+	Digest         bool       `json:"digest"`
+	Status         string     `json:"status"`
+	Attempts       int        `json:"attempts"`
+	LastError      string     `json:"last_error,omitempty"`
+	NextAttemptAt  time.Time  `json:"next_attempt_at"`
+	SentAt         *time.Time `json:"sent_at,omitempty"`
+
+	// Secret signs webhook deliveries
+	Secret string `json:"-"`
+	// Notification is set on claimed deliveries
+	Notification *Notification `json:"-"`
+}
+
+// Message is what a sender delivers: one notification, or a digest of
+// several for the same recipient and channel
+type Message struct {
+	ID            string // The delivery, or the first delivery of a digest
+	Subject       string
+	Body          string
+	Notifications []*Notification
+}
+
+// Sender delivers messages on one channel
+type Sender interface {
+	Send(ctx context.Context, d *Delivery, msg *Message) error
+}
+
+// SetSender sets how channel is delivered
+func (s *Service) SetSender(channel string, sender Sender) {
+	s.senders[channel] = sender
+}
+
+// Policy controls when deliveries are attempted
+type Policy struct {
+	BatchSize       int           // Deliveries claimed per round
+	Timeout         time.Duration // Bound on one send; claims are leased for twice as long
+	MaxAttempts     int           // A delivery fails for good after this many attempts
+	RetryBackoff    time.Duration // Delay after the first failed attempt, doubled after each
+	MaxRetryBackoff time.Duration
+	DigestInterval  time.Duration // Digests are sent at multiples of this since the Unix epoch, in UTC
+}
+
+// schedule returns n's deliveries under prefs. Digests are due at the next
+// digest time.
+func (s *Service) schedule(n *Notification, prefs *Preferences) []*Delivery {
+	pref := prefs.delivery(n.Kind)
+	due := n.CreatedAt
+	if pref.Digest {
+		due = due.Truncate(s.policy.DigestInterval).Add(s.policy.DigestInterval)
+	}
+	var deliveries []*Delivery
+	for _, channel := range pref.Channels {
+		address := prefs.address(channel)
+		if address == "" {
+			continue
+		}
+		if _, ok := s.senders[channel]; !ok {
+			s.logger.Debug("Channel is not configured", zap.String("channel", channel))
+			continue
+		}
+		d := &Delivery{
+			ID:             n.ID + "/" + channel,
+			NotificationID: n.ID,
+			RecipientID:    n.RecipientID,
+			Channel:        channel,
+			Address:        address,
+			Digest:         pref.Digest,
+			Status:         DeliveryPending,
+			NextAttemptAt:  due,
+		}
+		if channel == ChannelWebhook {
+			d.Secret = prefs.WebhookSecret
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries
+}
+
+// Dispatch sends the deliveries that are due, grouping digest deliveries by
+// recipient, channel and address. It returns how many deliveries it claimed.
+func (s *Service) Dispatch(ctx context.Context) (int, error) {
+	deliveries, err := s.store.ClaimDeliveries(ctx, s.now().UTC(), 2*s.policy.Timeout, s.policy.BatchSize)
+	if err != nil {
+		return 0, fmt.Errorf("failed to claim deliveries: %w", err)
+	}
+
+	var groups [][]*Delivery
+	digests := map[string]int{}
+	for _, d := range deliveries {
+		if !d.Digest {
+			groups = append(groups, []*Delivery{d})
+			continue
+		}
+		key := d.RecipientID + "\x00" + d.Channel + "\x00" + d.Address
+		if i, ok := digests[key]; ok {
+			groups[i] = append(groups[i], d)
+			continue
+		}
+		digests[key] = len(groups)
+		groups = append(groups, []*Delivery{d})
+	}
+
+	var errs []string
+	for _, group := range groups {
+		if err := s.send(ctx, group); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return len(deliveries), fmt.Errorf("failed to update deliveries: %s", strings.Join(errs, "; "))
+	}
+	return len(deliveries), nil
+}
+
+// send delivers a group and records the outcome on each of its deliveries
+func (s *Service) send(ctx context.Context, group []*Delivery) error {
+	first := group[0]
+	msg := &Message{ID: first.ID}
+	for _, d := range group {
+		msg.Notifications = append(msg.Notifications, d.Notification)
+	}
+	if len(group) == 1 {
+		msg.Subject, msg.Body = first.Notification.Subject, first.Notification.Body
+	} else {
+		msg.Subject, msg.Body = digest(msg.Notifications)
+	}
+
+	var err error
+	if sender, ok := s.senders[first.Channel]; ok {
+		sendCtx, cancel := context.WithTimeout(ctx, s.policy.Timeout)
+		err = sender.Send(sendCtx, first, msg)
+		cancel()
+	} else {
+		err = fmt.Errorf("%s is not configured", first.Channel)
+	}
+
+	now := s.now().UTC()
+	result := "sent"
+	for _, d := range group {
+		d.Attempts++
+		if err == nil {
+			d.Status, d.LastError, d.SentAt = DeliverySent, "", &now
+			continue
+		}
+		d.LastError = err.Error()
+		if d.Attempts >= s.policy.MaxAttempts {
+			d.Status, result = DeliveryFailed, "failed"
+			continue
+		}
+		backoff := s.policy.RetryBackoff << (d.Attempts - 1)
+		if backoff <= 0 || backoff > s.policy.MaxRetryBackoff {
+			backoff = s.policy.MaxRetryBackoff
+		}
+		d.NextAttemptAt, result = now.Add(backoff), "retried"
+	}
+	deliveriesTotal.WithLabelValues(first.Channel, result).Add(float64(len(group)))
+	if err != nil {
+		s.logger.Warn("Failed to deliver notification",
+			zap.String("delivery_id", first.ID),
+			zap.String("channel", first.Channel),
+			zap.Int("notifications", len(group)),
+			zap.Int("attempts", first.Attempts),
+			zap.String("result", result),
+			zap.Error(err),
+		)
+	}
+
+	for _, d := range group {
+		// Unrecorded outcomes are retried once the lease expires
+		if err := s.store.UpdateDelivery(ctx, d); err != nil {
+			return fmt.Errorf("%s: %w", d.ID, err)
+		}
+	}
+	return nil
+}
+
+// digest summarizes several notifications in one message
+func digest(notifications []*Notification) (string, string) {
+	subject := fmt.Sprintf("%d marketplace notifications", len(notifications))
+	var body strings.Builder
+	for i, n := range notifications {
+		if i > 0 {
+			body.WriteString("\n\n---\n\n")
+		}
+		body.WriteString(n.Subject)
+		body.WriteString("\n\n")
+		body.WriteString(n.Body)
+	}
+	return subject, body.String()
+}
+
+// StartDispatcher sends due deliveries every interval until ctx is
+// cancelled. Full rounds are followed by another at once.
+func (s *Service) StartDispatcher(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		for ctx.Err() == nil {
+			n, err := s.Dispatch(ctx)
+			if err != nil {
+				if ctx.Err() == nil {
+					s.logger.Warn("Failed to dispatch notifications", zap.Error(err))
+				}
+				break
+			}
+			if n < s.policy.BatchSize {
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,295 @@
+// Package notify turns marketplace events into notifications for the
+// providers, consumers and users they concern, and delivers them by email,
+// Slack or webhook according to each recipient's preferences, at once or in
+// a digest.
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/org/llm-marketplace/pkg/marketplace"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+)
+
+// Notification kinds, one per event they are made from
+const (
+	KindPolicyViolation  = "policy_violation"
+	KindSLABreach        = "sla_breach"
+	KindSavedSearchMatch = "saved_search_match"
+	KindBudgetAlert      = "budget_alert"
+	KindPriceChange      = "price_change"
+	KindComplianceExpiry = "compliance_expiry"
+)
+
+// Kinds lists every notification kind
+var Kinds = []string{KindPolicyViolation, KindSLABreach, KindSavedSearchMatch, KindBudgetAlert, KindPriceChange, KindComplianceExpiry}
+
+var (
+	// ErrInvalidEvent marks an event that can never be turned into a
+	// notification
+	ErrInvalidEvent = errors.New("invalid event")
+	// ErrInvalidRequest is returned for preferences or queries that can't be
+	// accepted
+	ErrInvalidRequest = errors.New("invalid request")
+	// ErrNotFound is returned for unknown notifications and recipients
+	// without preferences
+	ErrNotFound = errors.New("not found")
+	// ErrForbidden is returned when a caller asks for another recipient's
+	// notifications or preferences
+	ErrForbidden = errors.New("forbidden")
+)
+
+var notificationsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "notification_events_total",
+	Help: "Events by kind and result: notified, duplicate, ignored or invalid",
+}, []string{"kind", "result"})
+
+// Notification is a message for one recipient. Its ID is derived from the
+// event it was made from, so a redelivered event makes no new notification.
+type Notification struct {
+	ID          string          `json:"id"`
+	Kind        string          `json:"kind"`
+	RecipientID string          `json:"recipient_id"`
+	Subject     string          `json:"subject"`
+	Body        string          `json:"body"`
+	Data        json.RawMessage `json:"data"` // The event as it was published
+	OccurredAt  time.Time       `json:"occurred_at"`
+	CreatedAt   time.Time       `json:"created_at"`
+	Deliveries  []*Delivery     `json:"deliveries"`
+
+	// Event is the decoded event the templates render
+	Event interface{} `json:"-"`
+}
+
+// Decode reads an event of kind's topic. It returns nil for an event that
+// notifies nobody, such as a descriptor that passed validation, and an error
+// wrapping ErrInvalidEvent for a payload that can never be notified.
+func Decode(kind string, payload []byte) (*Notification, error) {
+	n := &Notification{Kind: kind, Data: payload}
+	var id string
+	switch kind {
+	case KindPolicyViolation:
+		var e marketplace.ValidationEvent
+		if err := json.Unmarshal(payload, &e); err != nil {
+			return nil, fmt.Errorf("%w: malformed validation event: %v", ErrInvalidEvent, err)
+		}
+		if e.Valid {
+			return nil, nil
+		}
+		id, n.RecipientID, n.OccurredAt, n.Event = e.ID, e.ProviderID, e.OccurredAt, &e
+	case KindSLABreach:
+		var e marketplace.SLABreach
+		if err := json.Unmarshal(payload, &e); err != nil {
+			return nil, fmt.Errorf("%w: malformed SLA breach: %v", ErrInvalidEvent, err)
+		}
+		id, n.RecipientID, n.OccurredAt, n.Event = e.ID, e.ProviderID, e.OccurredAt, &e
+	case KindSavedSearchMatch:
+		var e marketplace.SavedSearchMatch
+		if err := json.Unmarshal(payload, &e); err != nil {
+			return nil, fmt.Errorf("%w: malformed saved-search match: %v", ErrInvalidEvent, err)
+		}
+		if len(e.Services) == 0 {
+			return nil, nil
+		}
+		id, n.RecipientID, n.OccurredAt, n.Event = e.ID, e.UserID, e.OccurredAt, &e
+	case KindBudgetAlert:
+		var e marketplace.BudgetAlert
+		if err := json.Unmarshal(payload, &e); err != nil {
+			return nil, fmt.Errorf("%w: malformed budget alert: %v", ErrInvalidEvent, err)
+		}
+		id, n.RecipientID, n.OccurredAt, n.Event = e.ID, e.ConsumerID, e.OccurredAt, &e
+	case KindPriceChange:
+		var e marketplace.PriceChangeNotice
+		if err := json.Unmarshal(payload, &e); err != nil {
+			return nil, fmt.Errorf("%w: malformed price-change notice: %v", ErrInvalidEvent, err)
+		}
+		id, n.RecipientID, n.OccurredAt, n.Event = e.ID, e.ConsumerID, e.OccurredAt, &e
+	case KindComplianceExpiry:
+		var e marketplace.ComplianceExpiryNotice
+		if err := json.Unmarshal(payload, &e); err != nil {
+			return nil, fmt.Errorf("%w: malformed compliance expiry notice: %v", ErrInvalidEvent, err)
+		}
+		id, n.RecipientID, n.OccurredAt, n.Event = e.ID, e.RecipientID, e.OccurredAt, &e
+	default:
+		return nil, fmt.Errorf("%w: unknown kind %q", ErrInvalidEvent, kind)
+	}
+	if id == "" || n.RecipientID == "" {
+		return nil, fmt.Errorf("%w: %s event without an id or recipient", ErrInvalidEvent, kind)
+	}
+	n.ID = kind + ":" + id
+	n.OccurredAt = n.OccurredAt.UTC()
+	return n, nil
+}
+
+// NotificationFilter selects a recipient's notifications, newest first
+type NotificationFilter struct {
+	RecipientID string
+	Kind        string
+	Limit       int
+	Offset      int
+}
+
+// Query limits
+const (
+	DefaultLimit = 50
+	MaxLimit     = 500
+)
+
+// Store persists preferences, notifications and their deliveries
+type Store interface {
+	PutPreferences(ctx context.Context, prefs *Preferences) error
+	// GetPreferences returns ErrNotFound for a recipient without preferences
+	GetPreferences(ctx context.Context, recipientID string) (*Preferences, error)
+	DeletePreferences(ctx context.Context, recipientID string) error
+
+	// SaveNotification stores n with its deliveries, unless a notification
+	// with its ID exists. It reports whether n was new.
+	SaveNotification(ctx context.Context, n *Notification) (bool, error)
+	// GetNotification returns ErrNotFound for an unknown ID
+	GetNotification(ctx context.Context, id string) (*Notification, error)
+	ListNotifications(ctx context.Context, filter NotificationFilter) ([]*Notification, error)
+	// PruneNotifications removes notifications, and their deliveries, created
+	// before t
+	PruneNotifications(ctx context.Context, before time.Time) (int64, error)
+
+	// ClaimDeliveries returns up to limit pending deliveries due at now, each
+	// with its notification, oldest first, and defers them by lease so that
+	// other replicas skip them while they are sent
+	ClaimDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*Delivery, error)
+	// UpdateDelivery records the outcome of an attempt
+	UpdateDelivery(ctx context.Context, d *Delivery) error
+}
+
+// Service makes and delivers notifications
+type Service struct {
+	store     Store
+	templates Templates
+	senders   map[string]Sender
+	policy    Policy
+	logger    *zap.Logger
+	now       func() time.Time
+}
+
+// NewService creates a service over store. Channels without a sender are
+// not delivered to.
+func NewService(store Store, templates Templates, policy Policy, logger *zap.Logger) *Service {
+	return &Service{
+		store:     store,
+		templates: templates,
+		senders:   map[string]Sender{},
+		policy:    policy,
+		logger:    logger,
+		now:       time.Now,
+	}
+}
+
+// SetClock replaces the clock that schedules deliveries
+func (s *Service) SetClock(now func() time.Time) {
+	s.now = now
+}
+
+// Notify renders n and schedules its deliveries on the channels its
+// recipient chose for its kind. A recipient without preferences only finds
+// it in their notifications. Redelivered events are dropped.
+func (s *Service) Notify(ctx context.Context, n *Notification) error {
+	if err := s.templates.Render(n); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidEvent, err)
+	}
+	n.CreatedAt = s.now().UTC()
+
+	prefs, err := s.store.GetPreferences(ctx, n.RecipientID)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return fmt.Errorf("failed to read preferences: %w", err)
+	}
+	if prefs != nil {
+		n.Deliveries = s.schedule(n, prefs)
+	}
+
+	created, err := s.store.SaveNotification(ctx, n)
+	if err != nil {
+		return fmt.Errorf("failed to store notification: %w", err)
+	}
+	if !created {
+		notificationsTotal.WithLabelValues(n.Kind, "duplicate").Inc()
+		return nil
+	}
+	notificationsTotal.WithLabelValues(n.Kind, "notified").Inc()
+	s.logger.Debug("Notification created",
+		zap.String("id", n.ID),
+		zap.String("recipient_id", n.RecipientID),
+		zap.Int("deliveries", len(n.Deliveries)),
+	)
+	return nil
+}
+
+// Ignored counts an event of kind that notified nobody
+func (s *Service) Ignored(kind string) {
+	notificationsTotal.WithLabelValues(kind, "ignored").Inc()
+}
+
+// Invalid counts an event of kind that was skipped as invalid
+func (s *Service) Invalid(kind string) {
+	notificationsTotal.WithLabelValues(kind, "invalid").Inc()
+}
+
+// Notification returns a notification with its deliveries
+func (s *Service) Notification(ctx context.Context, id string) (*Notification, error) {
+	return s.store.GetNotification(ctx, id)
+}
+
+// Notifications returns a recipient's notifications, newest first
+func (s *Service) Notifications(ctx context.Context, filter NotificationFilter) ([]*Notification, error) {
+	switch {
+	case filter.Kind != "" && !contains(Kinds, filter.Kind):
+		return nil, fmt.Errorf("%w: unknown kind %q", ErrInvalidRequest, filter.Kind)
+	case filter.Limit < 0 || filter.Limit > MaxLimit || filter.Offset < 0:
+		return nil, fmt.Errorf("%w: limit must be between 1 and %d and offset not negative", ErrInvalidRequest, MaxLimit)
+	}
+	if filter.Limit == 0 {
+		filter.Limit = DefaultLimit
+	}
+	notifications, err := s.store.ListNotifications(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list notifications: %w", err)
+	}
+	return notifications, nil
+}
+
+// StartPruning removes notifications older than retention every interval
+// until ctx is cancelled
+func (s *Service) StartPruning(ctx context.Context, interval, retention time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		n, err := s.store.PruneNotifications(ctx, s.now().Add(-retention))
+		if err != nil {
+			if ctx.Err() == nil {
+				s.logger.Warn("Failed to prune notifications", zap.Error(err))
+			}
+			continue
+		}
+		if n > 0 {
+			s.logger.Info("Pruned notifications", zap.Int64("count", n))
+		}
+	}
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,135 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/url"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/org/llm-marketplace/pkg/marketplace/egress"
+)
+
+// Delivery channels
+const (
+	ChannelEmail   = "email"
+	ChannelSlack   = "slack"
+	ChannelWebhook = "webhook"
+)
+
+// Channels lists every delivery channel
+var Channels = []string{ChannelEmail, ChannelSlack, ChannelWebhook}
+
+// Preferences are where and how a recipient is notified. Each channel is
+// used once its address is set.
+type Preferences struct {
+	RecipientID     string `json:"recipient_id"`
+	Email           string `json:"email,omitempty"`
+	SlackWebhookURL string `json:"slack_webhook_url,omitempty"`
+	WebhookURL      string `json:"webhook_url,omitempty"`
+	// WebhookSecret signs webhook deliveries. It is never returned.
+	WebhookSecret string `json:"-"`
+	// Kinds overrides the delivery of some kinds; the others are sent at
+	// once on every channel with an address
+	Kinds     map[string]KindPreference `json:"kinds,omitempty"`
+	UpdatedAt time.Time                 `json:"updated_at"`
+}
+
+// KindPreference is how one kind of notification is delivered
+type KindPreference struct {
+	Channels []string `json:"channels"` // Empty mutes the kind
+	Digest   bool     `json:"digest"`   // Batched into a digest instead of sent at once
+}
+
+// address returns where channel delivers to, or "" when it has no address
+func (p *Preferences) address(channel string) string {
+	switch channel {
+	case ChannelEmail:
+		return p.Email
+	case ChannelSlack:
+		return p.SlackWebhookURL
+	case ChannelWebhook:
+		return p.WebhookURL
+	}
+	return ""
+}
+
+// delivery returns how notifications of kind are delivered
+func (p *Preferences) delivery(kind string) KindPreference {
+	if pref, ok := p.Kinds[kind]; ok {
+		return pref
+	}
+	var pref KindPreference
+	for _, channel := range Channels {
+		if p.address(channel) != "" {
+			pref.Channels = append(pref.Channels, channel)
+		}
+	}
+	return pref
+}
+
+func (p *Preferences) validate() error {
+	if p.RecipientID == "" {
+		return fmt.Errorf("%w: recipient_id is required", ErrInvalidRequest)
+	}
+	if p.Email != "" {
+		if addr, err := mail.ParseAddress(p.Email); err != nil || addr.Address != p.Email {
+			return fmt.Errorf("%w: email %q is not an address", ErrInvalidRequest, p.Email)
+		}
+	}
+	for name, raw := range map[string]string{"slack_webhook_url": p.SlackWebhookURL, "webhook_url": p.WebhookURL} {
+		if raw == "" {
+			continue
+		}
+		u, err := url.Parse(raw)
+		if err != nil || u.Scheme != "https" || u.Host == "" {
+			return fmt.Errorf("%w: %s must be an https URL", ErrInvalidRequest, name)
+		}
+		// Host names are checked when delivering, once they resolve
+		if ip := net.ParseIP(u.Hostname()); ip != nil && !egress.Public(ip) {
+			return fmt.Errorf("%w: %s must be a public address", ErrInvalidRequest, name)
+		}
+	}
+	for kind, pref := range p.Kinds {
+		if !contains(Kinds, kind) {
+			return fmt.Errorf("%w: unknown kind %q", ErrInvalidRequest, kind)
+		}
+		for _, channel := range pref.Channels {
+			if !contains(Channels, channel) {
+				return fmt.Errorf("%w: unknown channel %q for %s", ErrInvalidRequest, channel, kind)
+			}
+			if p.address(channel) == "" {
+				return fmt.Errorf("%w: %s is chosen for %s but has no address", ErrInvalidRequest, channel, kind)
+			}
+		}
+	}
+	return nil
+}
+
+// SetPreferences creates or replaces a recipient's preferences. They apply
+// to notifications made from then on.
+func (s *Service) SetPreferences(ctx context.Context, prefs Preferences) (*Preferences, error) {
+	if err := prefs.validate(); err != nil {
+		return nil, err
+	}
+	prefs.UpdatedAt = s.now().UTC()
+	if err := s.store.PutPreferences(ctx, &prefs); err != nil {
+		return nil, err
+	}
+	s.logger.Info("Preferences set", zap.String("recipient_id", prefs.RecipientID))
+	return &prefs, nil
+}
+
+// Preferences returns a recipient's preferences
+func (s *Service) Preferences(ctx context.Context, recipientID string) (*Preferences, error) {
+	return s.store.GetPreferences(ctx, recipientID)
+}
+
+// DeletePreferences removes a recipient's preferences; they are no longer
+// delivered to
+func (s *Service) DeletePreferences(ctx context.Context, recipientID string) error {
+	return s.store.DeletePreferences(ctx, recipientID)
+}
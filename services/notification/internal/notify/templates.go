@@ -0,0 +1,167 @@
+package notify
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+
+	"github.com/org/llm-marketplace/pkg/marketplace"
+)
+
+// TemplateText is the source of a kind's subject and body templates. Both
+// are Go text templates executed with the event the notification is made
+// from: a marketplace.ValidationEvent, SLABreach, SavedSearchMatch,
+// BudgetAlert, PriceChangeNotice or ComplianceExpiryNotice.
+type TemplateText struct {
+	Subject string `yaml:"subject" json:"subject"`
+	Body    string `yaml:"body" json:"body"`
+}
+
+// DefaultTemplates are the templates of each kind unless overridden
+var DefaultTemplates = map[string]TemplateText{
+	KindPolicyViolation: {
+		Subject: `{{.Name}} {{.Version}} failed validation`,
+		Body: `The descriptor of {{.Name}} {{.Version}} was rejected.
+{{range .Errors}}
+- {{.Field}}: {{.Message}}{{end}}{{range .Violations}}
+- Breaks {{.PolicyName}} ({{.Severity}}){{end}}
+{{if .PolicyVersion}}
+Policies as of version {{.PolicyVersion}}.{{end}}`,
+	},
+	KindSLABreach: {
+		Subject: `{{or .ServiceName .ServiceID}} {{if .Recovered}}has recovered{{else}}is {{.Status}}{{end}}`,
+		Body: `{{or .ServiceName .ServiceID}} went from {{.PreviousStatus}} to {{.Status}} at {{.OccurredAt.Format "2006-01-02 15:04 MST"}}.
+
+Availability: {{printf "%.2f" .Availability}}%
+Average latency: {{printf "%.0f" .AvgLatencyMS}} ms
+Error rate: {{printf "%.2f" (percent .ErrorRate)}}%`,
+	},
+	KindSavedSearchMatch: {
+		Subject: `{{len .Services}} new {{if eq (len .Services) 1}}service matches{{else}}services match{{end}} "{{.Name}}"`,
+		Body: `New services match your saved search "{{.Name}}"{{if .Query}} ({{.Query}}){{end}}:
+{{range .Services}}
+- {{.Name}} {{.Version}}{{end}}`,
+	},
+	KindBudgetAlert: {
+		Subject: `{{.ConsumerID}} has used {{.Threshold}}% of its {{.Period}} budget`,
+		Body: `Spend in {{.Period}} is {{printf "%.2f" .Spend}} {{.Currency}} of a {{printf "%.2f" .Cap}} {{.Currency}} cap.
+{{if .HardStop}}{{if ge .Threshold 100}}
+Calls are refused until the next month or until the cap is raised.{{else}}
+Calls will be refused once the cap is reached.{{end}}{{end}}`,
+	},
+	KindPriceChange: {
+		Subject: `{{if .Cancelled}}The price change of {{or .ServiceName .ServiceID}} is cancelled{{else}}{{or .ServiceName .ServiceID}} changes price on {{.EffectiveAt.Format "2006-01-02"}}{{end}}`,
+		Body: `{{if .Cancelled}}The price change of {{or .ServiceName .ServiceID}} planned for {{.EffectiveAt.Format "2006-01-02 15:04 MST"}} won't take effect. It stays at {{price .OldPricing}}.{{else}}From {{.EffectiveAt.Format "2006-01-02 15:04 MST"}}, {{or .ServiceName .ServiceID}} costs {{price .NewPricing}} instead of {{price .OldPricing}}.
+{{range .NewPricing.Tiers}}
+- {{.Tier}}: {{.Rate}} per {{.Unit}}{{end}}{{end}}`,
+	},
+	KindComplianceExpiry: {
+		Subject: `{{document .Expiry}} of {{or .ServiceName .ServiceID}} {{if eq .Expiry.Status "expired"}}has expired{{else}}expires on {{.Expiry.ExpiresAt.Format "2006-01-02"}}{{end}}`,
+		Body: `{{document .Expiry}} declared by {{or .ServiceName .ServiceID}} {{if eq .Expiry.Status "expired"}}expired on{{else}}expires on{{end}} {{.Expiry.ExpiresAt.Format "2006-01-02 15:04 MST"}}.
+{{if eq .Expiry.Kind "certification"}}
+Upload a current artifact for it in the provider portal.{{else}}
+Renew the agreement and update the service's descriptor with its new expiry.{{end}}{{if eq .Expiry.Status "expired"}} Until then the service ranks lower in search.{{end}}`,
+	},
+}
+
+// Templates are the parsed templates of each kind
+type Templates map[string]*kindTemplates
+
+type kindTemplates struct {
+	subject *template.Template
+	body    *template.Template
+}
+
+var templateFuncs = template.FuncMap{
+	"percent": func(rate float64) float64 { return rate * 100 },
+	"price": func(p marketplace.PricingInfo) string {
+		currency := p.Currency
+		if currency == "" {
+			currency = "USD"
+		}
+		return fmt.Sprintf("%g %s per %s (%s)", p.Rate, currency, p.Unit, p.Model)
+	},
+	"document": func(e marketplace.ComplianceExpiry) string {
+		if e.Kind == marketplace.ExpiryCertification {
+			return "The " + e.Name + " certification"
+		}
+		if e.Name == "" {
+			return "The data-processing agreement"
+		}
+		return "The data-processing agreement " + e.Name
+	},
+}
+
+// samples are empty events of each kind, executed once at parse time so
+// that templates naming unknown fields are rejected with the config
+var samples = map[string]interface{}{
+	KindPolicyViolation:  &marketplace.ValidationEvent{},
+	KindSLABreach:        &marketplace.SLABreach{},
+	KindSavedSearchMatch: &marketplace.SavedSearchMatch{},
+	KindBudgetAlert:      &marketplace.BudgetAlert{},
+	KindPriceChange:      &marketplace.PriceChangeNotice{},
+	KindComplianceExpiry: &marketplace.ComplianceExpiryNotice{},
+}
+
+// ParseTemplates parses the default templates with overrides replacing those
+// of some kinds. An override's empty subject or body keeps the default.
+func ParseTemplates(overrides map[string]TemplateText) (Templates, error) {
+	templates := Templates{}
+	for _, kind := range Kinds {
+		text := DefaultTemplates[kind]
+		if override, ok := overrides[kind]; ok {
+			if override.Subject != "" {
+				text.Subject = override.Subject
+			}
+			if override.Body != "" {
+				text.Body = override.Body
+			}
+		}
+		subject, err := template.New(kind + ".subject").Funcs(templateFuncs).Option("missingkey=error").Parse(text.Subject)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s subject template: %w", kind, err)
+		}
+		body, err := template.New(kind + ".body").Funcs(templateFuncs).Option("missingkey=error").Parse(text.Body)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s body template: %w", kind, err)
+		}
+		tmpl := &kindTemplates{subject: subject, body: body}
+		if err := tmpl.execute(samples[kind], io.Discard, io.Discard); err != nil {
+			return nil, fmt.Errorf("invalid %s template: %w", kind, err)
+		}
+		templates[kind] = tmpl
+	}
+	for kind := range overrides {
+		if !contains(Kinds, kind) {
+			return nil, fmt.Errorf("template for unknown kind %q", kind)
+		}
+	}
+	return templates, nil
+}
+
+// Render sets n's subject and body from its event
+func (t Templates) Render(n *Notification) error {
+	tmpl, ok := t[n.Kind]
+	if !ok {
+		return fmt.Errorf("no template for %s", n.Kind)
+	}
+	var subject, body strings.Builder
+	if err := tmpl.execute(n.Event, &subject, &body); err != nil {
+		return fmt.Errorf("failed to render %s: %w", n.Kind, err)
+	}
+	// Subjects are a single line
+	n.Subject = strings.Join(strings.Fields(subject.String()), " ")
+	n.Body = strings.TrimSpace(body.String())
+	return nil
+}
+
+func (t *kindTemplates) execute(event interface{}, subject, body io.Writer) error {
+	if err := t.subject.Execute(subject, event); err != nil {
+		return fmt.Errorf("subject: %w", err)
+	}
+	if err := t.body.Execute(body, event); err != nil {
+		return fmt.Errorf("body: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,308 @@
+// Package store keeps preferences, notifications and their deliveries in
+// PostgreSQL
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/org/llm-marketplace/services/notification/internal/config"
+	"github.com/org/llm-marketplace/services/notification/internal/notify"
+)
+
+// Store implements notify.Store
+type Store struct {
+	pool *pgxpool.Pool
+}
+
+// NewPool connects to PostgreSQL
+func NewPool(ctx context.Context, cfg config.PostgresConfig) (*pgxpool.Pool, error) {
+	poolConfig, err := pgxpool.ParseConfig(cfg.DSN())
+	if err != nil {
+		return nil, fmt.Errorf("invalid postgres config: %w", err)
+	}
+	poolConfig.MaxConns = int32(cfg.MaxConns)
+	poolConfig.MinConns = int32(cfg.MinConns)
+	poolConfig.ConnConfig.ConnectTimeout = cfg.ConnTimeout
+
+	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create pool: %w", err)
+	}
+	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("failed to ping postgres: %w", err)
+	}
+	return pool, nil
+}
+
+// New creates a store over pool
+func New(pool *pgxpool.Pool) *Store {
+	return &Store{pool: pool}
+}
+
+func (s *Store) PutPreferences(ctx context.Context, prefs *notify.Preferences) error {
+	kinds, err := json.Marshal(prefs.Kinds)
+	if err != nil {
+		return err
+	}
+	_, err = s.pool.Exec(ctx, `
+		INSERT INTO preferences (recipient_id, email, slack_webhook_url, webhook_url, webhook_secret, kinds, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (recipient_id) DO UPDATE SET
+			email = EXCLUDED.email,
+			slack_webhook_url = EXCLUDED.slack_webhook_url,
+			webhook_url = EXCLUDED.webhook_url,
+			webhook_secret = EXCLUDED.webhook_secret,
+			kinds = EXCLUDED.kinds,
+			updated_at = EXCLUDED.updated_at
+	`, prefs.RecipientID, prefs.Email, prefs.SlackWebhookURL, prefs.WebhookURL, prefs.WebhookSecret, string(kinds), prefs.UpdatedAt)
+	return err
+}
+
+func (s *Store) GetPreferences(ctx context.Context, recipientID string) (*notify.Preferences, error) {
+	var (
+		prefs notify.Preferences
+		kinds []byte
+	)
+	err := s.pool.QueryRow(ctx, `
+		SELECT recipient_id, email, slack_webhook_url, webhook_url, webhook_secret, kinds, updated_at
+		FROM preferences WHERE recipient_id = $1
+	`, recipientID).Scan(&prefs.RecipientID, &prefs.Email, &prefs.SlackWebhookURL, &prefs.WebhookURL,
+		&prefs.WebhookSecret, &kinds, &prefs.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, notify.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(kinds, &prefs.Kinds); err != nil {
+		return nil, fmt.Errorf("invalid kinds of %s: %w", recipientID, err)
+	}
+	if len(prefs.Kinds) == 0 {
+		prefs.Kinds = nil
+	}
+	prefs.UpdatedAt = prefs.UpdatedAt.UTC()
+	return &prefs, nil
+}
+
+func (s *Store) DeletePreferences(ctx context.Context, recipientID string) error {
+	tag, err := s.pool.Exec(ctx, `DELETE FROM preferences WHERE recipient_id = $1`, recipientID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return notify.ErrNotFound
+	}
+	return nil
+}
+
+func (s *Store) SaveNotification(ctx context.Context, n *notify.Notification) (bool, error) {
+	created := false
+	err := pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx, `
+			INSERT INTO notifications (id, kind, recipient_id, subject, body, data, occurred_at, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+			ON CONFLICT (id) DO NOTHING
+		`, n.ID, n.Kind, n.RecipientID, n.Subject, n.Body, string(n.Data), n.OccurredAt, n.CreatedAt)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return nil
+		}
+		created = true
+
+		batch := &pgx.Batch{}
+		for _, d := range n.Deliveries {
+			batch.Queue(`
+				INSERT INTO deliveries (id, notification_id, recipient_id, channel, address, secret, digest,
+					status, attempts, next_attempt_at)
+				VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+			`, d.ID, d.NotificationID, d.RecipientID, d.Channel, d.Address, d.Secret, d.Digest,
+				d.Status, d.Attempts, d.NextAttemptAt)
+		}
+		return tx.SendBatch(ctx, batch).Close()
+	})
+	if err != nil {
+		return false, err
+	}
+	return created, nil
+}
+
+const notificationColumns = `n.id, n.kind, n.recipient_id, n.subject, n.body, n.data, n.occurred_at, n.created_at`
+
+func scanNotification(row pgx.Row, extra ...interface{}) (*notify.Notification, error) {
+	var (
+		n    notify.Notification
+		data []byte
+	)
+	dest := append([]interface{}{&n.ID, &n.Kind, &n.RecipientID, &n.Subject, &n.Body, &data, &n.OccurredAt, &n.CreatedAt}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return nil, err
+	}
+	n.Data = data
+	n.OccurredAt = n.OccurredAt.UTC()
+	n.CreatedAt = n.CreatedAt.UTC()
+	return &n, nil
+}
+
+func (s *Store) GetNotification(ctx context.Context, id string) (*notify.Notification, error) {
+	n, err := scanNotification(s.pool.QueryRow(ctx, `
+		SELECT `+notificationColumns+` FROM notifications n WHERE n.id = $1
+	`, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, notify.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := s.attachDeliveries(ctx, []*notify.Notification{n}); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+func (s *Store) ListNotifications(ctx context.Context, filter notify.NotificationFilter) ([]*notify.Notification, error) {
+	args := []interface{}{filter.RecipientID}
+	conditions := []string{"n.recipient_id = $1"}
+	if filter.Kind != "" {
+		args = append(args, filter.Kind)
+		conditions = append(conditions, fmt.Sprintf("n.kind = $%d", len(args)))
+	}
+	rows, err := s.pool.Query(ctx, fmt.Sprintf(`
+		SELECT `+notificationColumns+` FROM notifications n
+		WHERE %s
+		ORDER BY n.created_at DESC, n.id
+		LIMIT %d OFFSET %d
+	`, strings.Join(conditions, " AND "), filter.Limit, filter.Offset), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var notifications []*notify.Notification
+	for rows.Next() {
+		n, err := scanNotification(rows)
+		if err != nil {
+			return nil, err
+		}
+		notifications = append(notifications, n)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if err := s.attachDeliveries(ctx, notifications); err != nil {
+		return nil, err
+	}
+	return notifications, nil
+}
+
+const deliveryColumns = `d.id, d.notification_id, d.recipient_id, d.channel, d.address, d.secret, d.digest,
+	d.status, d.attempts, d.last_error, d.next_attempt_at, d.sent_at`
+
+func scanDelivery(d *notify.Delivery) []interface{} {
+	return []interface{}{&d.ID, &d.NotificationID, &d.RecipientID, &d.Channel, &d.Address, &d.Secret, &d.Digest,
+		&d.Status, &d.Attempts, &d.LastError, &d.NextAttemptAt, &d.SentAt}
+}
+
+func normalizeDelivery(d *notify.Delivery) {
+	d.NextAttemptAt = d.NextAttemptAt.UTC()
+	if d.SentAt != nil {
+		sent := d.SentAt.UTC()
+		d.SentAt = &sent
+	}
+}
+
+// attachDeliveries loads the deliveries of notifications
+func (s *Store) attachDeliveries(ctx context.Context, notifications []*notify.Notification) error {
+	if len(notifications) == 0 {
+		return nil
+	}
+	byID := make(map[string]*notify.Notification, len(notifications))
+	ids := make([]string, 0, len(notifications))
+	for _, n := range notifications {
+		byID[n.ID] = n
+		ids = append(ids, n.ID)
+	}
+	rows, err := s.pool.Query(ctx, `
+		SELECT `+deliveryColumns+` FROM deliveries d
+		WHERE d.notification_id = ANY($1)
+		ORDER BY d.notification_id, d.channel
+	`, ids)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var d notify.Delivery
+		if err := rows.Scan(scanDelivery(&d)...); err != nil {
+			return err
+		}
+		normalizeDelivery(&d)
+		n := byID[d.NotificationID]
+		n.Deliveries = append(n.Deliveries, &d)
+	}
+	return rows.Err()
+}
+
+func (s *Store) PruneNotifications(ctx context.Context, before time.Time) (int64, error) {
+	tag, err := s.pool.Exec(ctx, `DELETE FROM notifications WHERE created_at < $1`, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune notifications: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+func (s *Store) ClaimDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*notify.Delivery, error) {
+	rows, err := s.pool.Query(ctx, `
+		WITH claimed AS (
+			UPDATE deliveries SET next_attempt_at = $2
+			WHERE id IN (
+				SELECT id FROM deliveries
+				WHERE status = 'pending' AND next_attempt_at <= $1
+				ORDER BY next_attempt_at, recipient_id
+				LIMIT $3
+				FOR UPDATE SKIP LOCKED
+			)
+			RETURNING *
+		)
+		SELECT `+notificationColumns+`, `+deliveryColumns+`
+		FROM claimed d JOIN notifications n ON n.id = d.notification_id
+		ORDER BY n.created_at, d.id
+	`, now, now.Add(lease), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []*notify.Delivery
+	for rows.Next() {
+		var d notify.Delivery
+		n, err := scanNotification(rows, scanDelivery(&d)...)
+		if err != nil {
+			return nil, err
+		}
+		normalizeDelivery(&d)
+		d.Notification = n
+		deliveries = append(deliveries, &d)
+	}
+	return deliveries, rows.Err()
+}
+
+func (s *Store) UpdateDelivery(ctx context.Context, d *notify.Delivery) error {
+	_, err := s.pool.Exec(ctx, `
+		UPDATE deliveries
+		SET status = $2, attempts = $3, last_error = $4, next_attempt_at = $5, sent_at = $6
+		WHERE id = $1
+	`, d.ID, d.Status, d.Attempts, d.LastError, d.NextAttemptAt, d.SentAt)
+	return err
+}
@@ -0,0 +1,143 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/org/llm-marketplace/services/notification/internal/api"
+	"github.com/org/llm-marketplace/services/notification/internal/notify"
+)
+
+var recipientHeaders = []string{"X-Consumer-ID", "X-Provider-ID", "X-User-ID"}
+
+func newTestRouter(t *testing.T) (*gin.Engine, *testService) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	ts := newTestService(t)
+	ts.notify(t, notify.KindPolicyViolation, violationPayload("v-1", "prov-1"))
+	ts.notify(t, notify.KindSLABreach, breachPayload("b-1", "prov-1", "healthy", "down"))
+	ts.notify(t, notify.KindBudgetAlert, budgetPayload("a-1", "acme", 80))
+
+	router := gin.New()
+	router.HandleMethodNotAllowed = true
+	api.RegisterRoutes(router, ts.Service, recipientHeaders, nopLogger)
+	return router, ts
+}
+
+func request(router *gin.Engine, method, path, body string, headers map[string]string) (*httptest.ResponseRecorder, map[string]interface{}) {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var decoded map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &decoded)
+	return w, decoded
+}
+
+func TestNotificationsAPIScopesCallers(t *testing.T) {
+	router, _ := newTestRouter(t)
+	provider := map[string]string{"X-Provider-ID": "prov-1"}
+	consumer := map[string]string{"X-Consumer-ID": "acme"}
+	// A caller acting as both a consumer and a provider
+	both := map[string]string{"X-Consumer-ID": "acme", "X-Provider-ID": "prov-1"}
+
+	tests := []struct {
+		name    string
+		path    string
+		headers map[string]string
+		status  int
+		total   float64
+	}{
+		{"operator", "/api/v1/recipients/prov-1/notifications", nil, http.StatusOK, 2},
+		{"own", "/api/v1/recipients/prov-1/notifications", provider, http.StatusOK, 2},
+		{"by kind", "/api/v1/recipients/prov-1/notifications?kind=sla_breach", provider, http.StatusOK, 1},
+		{"page", "/api/v1/recipients/prov-1/notifications?limit=1&offset=1", provider, http.StatusOK, 1},
+		{"either identity", "/api/v1/recipients/acme/notifications", both, http.StatusOK, 1},
+		{"other recipient", "/api/v1/recipients/prov-1/notifications", consumer, http.StatusForbidden, 0},
+		{"unknown kind", "/api/v1/recipients/prov-1/notifications?kind=digest", nil, http.StatusBadRequest, 0},
+		{"bad limit", "/api/v1/recipients/prov-1/notifications?limit=all", nil, http.StatusBadRequest, 0},
+		{"one", "/api/v1/notifications/budget_alert:a-1", consumer, http.StatusOK, 0},
+		{"someone else's", "/api/v1/notifications/budget_alert:a-1", provider, http.StatusNotFound, 0},
+		{"unknown", "/api/v1/notifications/budget_alert:a-9", nil, http.StatusNotFound, 0},
+	}
+	for _, tt := range tests {
+		w, body := request(router, http.MethodGet, tt.path, "", tt.headers)
+		if w.Code != tt.status {
+			t.Errorf("%s: status %d, want %d: %s", tt.name, w.Code, tt.status, w.Body)
+			continue
+		}
+		if tt.status != http.StatusOK {
+			if ct := w.Header().Get("Content-Type"); ct != "application/problem+json" {
+				t.Errorf("%s: content type %q", tt.name, ct)
+			}
+			continue
+		}
+		if tt.total > 0 && body["total"] != tt.total {
+			t.Errorf("%s: total %v, want %v", tt.name, body["total"], tt.total)
+		}
+	}
+}
+
+func TestPreferencesAPI(t *testing.T) {
+	router, ts := newTestRouter(t)
+	const path = "/api/v1/recipients/acme/preferences"
+	consumer := map[string]string{"X-Consumer-ID": "acme"}
+
+	w, _ := request(router, http.MethodGet, path, "", consumer)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("GET before PUT: status %d, want 404", w.Code)
+	}
+
+	w, body := request(router, http.MethodPut, path, `{
+		"email": "billing@acme.com",
+		"webhook_url": "https://acme.com/hooks",
+		"webhook_secret": "s3cret",
+		"kinds": {"budget_alert": {"channels": ["email"], "digest": true}}
+	}`, consumer)
+	if w.Code != http.StatusOK {
+		t.Fatalf("PUT: status %d: %s", w.Code, w.Body)
+	}
+	if _, ok := body["webhook_secret"]; ok || body["recipient_id"] != "acme" {
+		t.Errorf("PUT returned %v, want acme's preferences without the secret", body)
+	}
+	if prefs := ts.store.preferences["acme"]; prefs.WebhookSecret != "s3cret" {
+		t.Errorf("stored secret = %q", prefs.WebhookSecret)
+	}
+
+	for name, tt := range map[string]struct {
+		body    string
+		headers map[string]string
+		status  int
+	}{
+		"other caller":   {`{"email": "x@y.com"}`, map[string]string{"X-Provider-ID": "prov-1"}, http.StatusForbidden},
+		"bad email":      {`{"email": "nope"}`, consumer, http.StatusBadRequest},
+		"malformed":      {`{"email": `, consumer, http.StatusBadRequest},
+		"unknown kind":   {`{"email": "x@y.com", "kinds": {"digest": {}}}`, consumer, http.StatusBadRequest},
+		"no address for": {`{"kinds": {"sla_breach": {"channels": ["slack"]}}}`, consumer, http.StatusBadRequest},
+	} {
+		if w, _ := request(router, http.MethodPut, path, tt.body, tt.headers); w.Code != tt.status {
+			t.Errorf("PUT %s: status %d, want %d: %s", name, w.Code, tt.status, w.Body)
+		}
+	}
+
+	w, body = request(router, http.MethodGet, path, "", consumer)
+	if w.Code != http.StatusOK || body["email"] != "billing@acme.com" {
+		t.Errorf("GET: status %d, body %v", w.Code, body)
+	}
+
+	if w, _ := request(router, http.MethodDelete, path, "", consumer); w.Code != http.StatusNoContent {
+		t.Errorf("DELETE: status %d", w.Code)
+	}
+	if w, _ := request(router, http.MethodDelete, path, "", consumer); w.Code != http.StatusNotFound {
+		t.Errorf("second DELETE: status %d, want 404", w.Code)
+	}
+}
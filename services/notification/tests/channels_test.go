@@ -0,0 +1,104 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/org/llm-marketplace/services/notification/internal/channels"
+	"github.com/org/llm-marketplace/services/notification/internal/notify"
+)
+
+// receiver records the requests posted to it and answers with status
+type receiver struct {
+	status  int
+	headers http.Header
+	body    []byte
+}
+
+func (r *receiver) serve() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		r.headers = req.Header.Clone()
+		r.body, _ = io.ReadAll(req.Body)
+		w.WriteHeader(r.status)
+	}))
+}
+
+func testMessage(kinds ...string) *notify.Message {
+	msg := &notify.Message{ID: "budget_alert:a-1/webhook", Subject: "Budget alert", Body: "80% used"}
+	for _, kind := range kinds {
+		msg.Notifications = append(msg.Notifications, &notify.Notification{ID: kind + ":1", Kind: kind, Data: json.RawMessage(`{}`)})
+	}
+	return msg
+}
+
+func TestWebhookSendsSignedJSON(t *testing.T) {
+	r := &receiver{status: http.StatusAccepted}
+	server := r.serve()
+	defer server.Close()
+
+	w := channels.NewWebhook(server.Client())
+	d := &notify.Delivery{Address: server.URL, Secret: "s3cret"}
+	start := time.Now()
+	if err := w.Send(context.Background(), d, testMessage(notify.KindBudgetAlert)); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	if got := r.headers.Get(channels.HeaderEvent); got != notify.KindBudgetAlert {
+		t.Errorf("event header = %q", got)
+	}
+	if got := r.headers.Get(channels.HeaderDelivery); got != "budget_alert:a-1/webhook" {
+		t.Errorf("delivery header = %q", got)
+	}
+	signature := r.headers.Get(channels.HeaderSignature)
+	if signature != channels.Sign("s3cret", start, r.body) && signature != channels.Sign("s3cret", time.Now(), r.body) {
+		t.Errorf("signature %q doesn't match the body", signature)
+	}
+	var body channels.WebhookBody
+	if err := json.Unmarshal(r.body, &body); err != nil || body.Subject != "Budget alert" || len(body.Notifications) != 1 {
+		t.Errorf("body = %s, %v", r.body, err)
+	}
+
+	// Digests carry every notification; unsigned without a secret
+	d.Secret = ""
+	if err := w.Send(context.Background(), d, testMessage(notify.KindBudgetAlert, notify.KindSLABreach)); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if r.headers.Get(channels.HeaderEvent) != channels.EventDigest || r.headers.Get(channels.HeaderSignature) != "" {
+		t.Errorf("digest headers = %v", r.headers)
+	}
+
+	r.status = http.StatusGone
+	if err := w.Send(context.Background(), d, testMessage(notify.KindBudgetAlert)); err == nil || !strings.Contains(err.Error(), "410") {
+		t.Errorf("Send() to a failing endpoint error = %v", err)
+	}
+}
+
+func TestSignMatchesDiscoveryWebhooks(t *testing.T) {
+	// The scheme discovery signs catalog events with, so receivers verify both alike
+	got := channels.Sign("secret", time.Unix(1700000000, 0), []byte(`{"id":"1"}`))
+	want := "t=1700000000,v1=086f6aff7bd084c98679825129c5a64dbad88c760016d6d2c0fb123f27951d54"
+	if got != want {
+		t.Errorf("Sign() = %q, want %q", got, want)
+	}
+}
+
+func TestSlackPostsText(t *testing.T) {
+	r := &receiver{status: http.StatusOK}
+	server := r.serve()
+	defer server.Close()
+
+	s := channels.NewSlack(server.Client())
+	if err := s.Send(context.Background(), &notify.Delivery{Address: server.URL}, testMessage(notify.KindBudgetAlert)); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	var body map[string]string
+	if err := json.Unmarshal(r.body, &body); err != nil || body["text"] != "*Budget alert*\n80% used" {
+		t.Errorf("body = %s, %v", r.body, err)
+	}
+}
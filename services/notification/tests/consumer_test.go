@@ -0,0 +1,112 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+
+	"github.com/org/llm-marketplace/services/notification/internal/config"
+	"github.com/org/llm-marketplace/services/notification/internal/consumer"
+	"github.com/org/llm-marketplace/services/notification/internal/notify"
+)
+
+// fakeReader serves queued messages, then blocks until cancelled
+type fakeReader struct {
+	mu        sync.Mutex
+	messages  []kafka.Message
+	committed []int64
+}
+
+func (r *fakeReader) FetchMessage(ctx context.Context) (kafka.Message, error) {
+	r.mu.Lock()
+	if len(r.messages) > 0 {
+		msg := r.messages[0]
+		r.messages = r.messages[1:]
+		r.mu.Unlock()
+		return msg, nil
+	}
+	r.mu.Unlock()
+	<-ctx.Done()
+	return kafka.Message{}, ctx.Err()
+}
+
+func (r *fakeReader) CommitMessages(_ context.Context, msgs ...kafka.Message) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, m := range msgs {
+		r.committed = append(r.committed, m.Offset)
+	}
+	return nil
+}
+
+func (r *fakeReader) Close() error { return nil }
+
+// flakyStore fails to save the first failures notifications
+type flakyStore struct {
+	*memStore
+	failures int
+}
+
+func (s *flakyStore) SaveNotification(ctx context.Context, n *notify.Notification) (bool, error) {
+	if s.failures > 0 {
+		s.failures--
+		return false, errors.New("connection reset")
+	}
+	return s.memStore.SaveNotification(ctx, n)
+}
+
+func TestConsumerNotifiesRetriesAndSkipsInvalidEvents(t *testing.T) {
+	store := &flakyStore{memStore: newMemStore(), failures: 2}
+	templates, _ := notify.ParseTemplates(nil)
+	svc := notify.NewService(store, templates, policy, nopLogger)
+
+	violation := violationPayload("v-1", "prov-1")
+	reader := &fakeReader{messages: []kafka.Message{
+		{Offset: 1, Value: violation},
+		{Offset: 2, Value: []byte("not json")},
+		{Offset: 3, Value: violation}, // Redelivered
+		{Offset: 4, Value: mustJSON(map[string]interface{}{"id": "v-2", "provider_id": "prov-1", "valid": true})},
+		{Offset: 5, Value: violationPayload("v-3", "prov-2")},
+	}}
+
+	cfg := config.KafkaConfig{
+		Brokers:         []string{"localhost:9092"},
+		RetryBackoff:    time.Millisecond,
+		MaxRetryBackoff: time.Millisecond,
+	}
+	c := consumer.NewConsumer(notify.KindPolicyViolation, "validation", cfg, svc, nopLogger)
+	c.SetReader(reader)
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		c.Start(ctx)
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		reader.mu.Lock()
+		committed := len(reader.committed)
+		reader.mu.Unlock()
+		if committed == 5 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("committed %d offsets, want all 5", committed)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	<-stopped
+
+	if len(store.notifications) != 2 {
+		t.Errorf("stored %d notifications, want the two violations", len(store.notifications))
+	}
+	if _, ok := store.notifications["policy_violation:v-3"]; !ok {
+		t.Error("the violation after the invalid event was not notified")
+	}
+}
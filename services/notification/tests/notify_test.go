@@ -0,0 +1,438 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/org/llm-marketplace/pkg/marketplace"
+	"go.uber.org/zap"
+
+	"github.com/org/llm-marketplace/services/notification/internal/notify"
+)
+
+// now is the time the tests start at
+var now = time.Date(2026, 3, 10, 9, 30, 0, 0, time.UTC)
+
+var nopLogger = zap.NewNop()
+
+var policy = notify.Policy{
+	BatchSize:       100,
+	Timeout:         time.Second,
+	MaxAttempts:     3,
+	RetryBackoff:    time.Minute,
+	MaxRetryBackoff: 5 * time.Minute,
+	DigestInterval:  24 * time.Hour,
+}
+
+// fakeSender records the messages it is asked to send, failing while err
+// is set
+type fakeSender struct {
+	mu       sync.Mutex
+	messages []*notify.Message
+	err      error
+}
+
+func (s *fakeSender) Send(_ context.Context, _ *notify.Delivery, msg *notify.Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return s.err
+	}
+	s.messages = append(s.messages, msg)
+	return nil
+}
+
+type testService struct {
+	*notify.Service
+	store   *memStore
+	clock   time.Time
+	email   *fakeSender
+	webhook *fakeSender
+}
+
+// newTestService creates a service delivering email and webhooks to fake
+// senders; Slack is not configured
+func newTestService(t *testing.T) *testService {
+	t.Helper()
+	templates, err := notify.ParseTemplates(nil)
+	if err != nil {
+		t.Fatalf("ParseTemplates() error = %v", err)
+	}
+	ts := &testService{store: newMemStore(), clock: now, email: &fakeSender{}, webhook: &fakeSender{}}
+	ts.Service = notify.NewService(ts.store, templates, policy, nopLogger)
+	ts.SetClock(func() time.Time { return ts.clock })
+	ts.SetSender(notify.ChannelEmail, ts.email)
+	ts.SetSender(notify.ChannelWebhook, ts.webhook)
+	return ts
+}
+
+func (ts *testService) notify(t *testing.T, kind string, payload []byte) *notify.Notification {
+	t.Helper()
+	n, err := notify.Decode(kind, payload)
+	if err != nil || n == nil {
+		t.Fatalf("Decode(%s) = %v, %v", kind, n, err)
+	}
+	if err := ts.Notify(context.Background(), n); err != nil {
+		t.Fatalf("Notify() error = %v", err)
+	}
+	return n
+}
+
+func (ts *testService) dispatch(t *testing.T) int {
+	t.Helper()
+	n, err := ts.Dispatch(context.Background())
+	if err != nil {
+		t.Fatalf("Dispatch() error = %v", err)
+	}
+	return n
+}
+
+func mustJSON(v interface{}) []byte {
+	payload, _ := json.Marshal(v)
+	return payload
+}
+
+func violationPayload(id, provider string) []byte {
+	return mustJSON(marketplace.ValidationEvent{
+		ID: id, OccurredAt: now, ProviderID: provider, ServiceID: "svc-1", Name: "chat-gpt", Version: "1.2.0",
+		PolicyVersion: "7",
+		Errors:        []marketplace.FieldError{{Field: "pricing", Message: "is required"}},
+		Violations:    []marketplace.PolicyViolation{{PolicyID: "pol-1", PolicyName: "No PII", Severity: "high"}},
+	})
+}
+
+func breachPayload(id, provider, previous, status string) []byte {
+	return mustJSON(marketplace.SLABreach{
+		ID: id, OccurredAt: now, ServiceID: "svc-1", ServiceName: "chat-gpt", ProviderID: provider,
+		Status: status, PreviousStatus: previous, Availability: 93.5, AvgLatencyMS: 820, ErrorRate: 0.065,
+	})
+}
+
+func budgetPayload(id, consumer string, threshold int) []byte {
+	return mustJSON(marketplace.BudgetAlert{
+		ID: id, OccurredAt: now, ConsumerID: consumer, Period: "2026-03", Threshold: threshold,
+		Spend: 80, Cap: 100, Currency: "USD", HardStop: true,
+	})
+}
+
+func pricePayload(id, consumer string, cancelled bool) []byte {
+	return mustJSON(marketplace.PriceChangeNotice{
+		ID: id, OccurredAt: now, PriceChangeID: "pc-1", ConsumerID: consumer, SubscriptionID: "sub-1",
+		ServiceID: "svc-1", ServiceName: "chat-gpt", ProviderID: "prov-1",
+		OldPricing:  marketplace.PricingInfo{Model: "per-token", Rate: 0.002, Unit: "1k tokens"},
+		NewPricing:  marketplace.PricingInfo{Model: "per-token", Rate: 0.003, Unit: "1k tokens", Currency: "EUR"},
+		EffectiveAt: now.AddDate(0, 1, 0), Cancelled: cancelled,
+	})
+}
+
+func expiryPayload(id, recipient, kind, name, status string) []byte {
+	return mustJSON(marketplace.ComplianceExpiryNotice{
+		ID: id, OccurredAt: now, RecipientID: recipient, ProviderID: "prov-1", ServiceID: "svc-1", ServiceName: "chat-gpt",
+		Expiry: marketplace.ComplianceExpiry{Kind: kind, Name: name, Status: status, ExpiresAt: now.AddDate(0, 0, 20)},
+	})
+}
+
+func matchPayload(id, user string, services ...string) []byte {
+	match := marketplace.SavedSearchMatch{ID: id, OccurredAt: now, UserID: user, SavedSearchID: "ss-1", Name: "Cheap chat", Query: "chat"}
+	for _, s := range services {
+		match.Services = append(match.Services, marketplace.SavedSearchResult{ID: s, Name: s, Version: "1.0.0", ProviderID: "prov-1"})
+	}
+	return mustJSON(match)
+}
+
+func TestDecodeRoutesEventsToRecipients(t *testing.T) {
+	tests := []struct {
+		name      string
+		kind      string
+		payload   []byte
+		recipient string // Empty when the event notifies nobody
+		err       error
+	}{
+		{"violation", notify.KindPolicyViolation, violationPayload("v-1", "prov-1"), "prov-1", nil},
+		{"passed validation", notify.KindPolicyViolation, mustJSON(marketplace.ValidationEvent{ID: "v-2", ProviderID: "prov-1", Valid: true}), "", nil},
+		{"breach", notify.KindSLABreach, breachPayload("b-1", "prov-1", "healthy", "down"), "prov-1", nil},
+		{"match", notify.KindSavedSearchMatch, matchPayload("m-1", "alice", "gpt"), "alice", nil},
+		{"empty match", notify.KindSavedSearchMatch, matchPayload("m-2", "alice"), "", nil},
+		{"budget", notify.KindBudgetAlert, budgetPayload("a-1", "acme", 80), "acme", nil},
+		{"price change", notify.KindPriceChange, pricePayload("p-1", "acme", false), "acme", nil},
+		{"compliance expiry", notify.KindComplianceExpiry, expiryPayload("e-1", "marketplace-admins", marketplace.ExpiryCertification, "SOC2", marketplace.ExpiryExpiring), "marketplace-admins", nil},
+		{"no recipient", notify.KindBudgetAlert, budgetPayload("a-2", "", 80), "", notify.ErrInvalidEvent},
+		{"no id", notify.KindSLABreach, breachPayload("", "prov-1", "healthy", "down"), "", notify.ErrInvalidEvent},
+		{"malformed", notify.KindBudgetAlert, []byte("not json"), "", notify.ErrInvalidEvent},
+		{"unknown kind", "digest", budgetPayload("a-3", "acme", 80), "", notify.ErrInvalidEvent},
+	}
+	for _, tt := range tests {
+		n, err := notify.Decode(tt.kind, tt.payload)
+		if !errors.Is(err, tt.err) {
+			t.Errorf("%s: error = %v, want %v", tt.name, err, tt.err)
+			continue
+		}
+		switch {
+		case tt.recipient == "" && n != nil:
+			t.Errorf("%s: notification for %s, want none", tt.name, n.RecipientID)
+		case tt.recipient != "" && (n == nil || n.RecipientID != tt.recipient):
+			t.Errorf("%s: notification = %+v, want one for %s", tt.name, n, tt.recipient)
+		case n != nil && !strings.HasPrefix(n.ID, tt.kind+":"):
+			t.Errorf("%s: id %q isn't derived from the kind", tt.name, n.ID)
+		}
+	}
+}
+
+func TestDefaultTemplatesRenderEveryKind(t *testing.T) {
+	ts := newTestService(t)
+	tests := []struct {
+		kind    string
+		payload []byte
+		subject string
+		body    []string
+	}{
+		{notify.KindPolicyViolation, violationPayload("v-1", "prov-1"), "chat-gpt 1.2.0 failed validation",
+			[]string{"- pricing: is required", "- Breaks No PII (high)", "version 7"}},
+		{notify.KindSLABreach, breachPayload("b-1", "prov-1", "healthy", "down"), "chat-gpt is down",
+			[]string{"from healthy to down", "Availability: 93.50%", "Error rate: 6.50%"}},
+		{notify.KindSLABreach, breachPayload("b-2", "prov-1", "down", "healthy"), "chat-gpt has recovered", nil},
+		{notify.KindSavedSearchMatch, matchPayload("m-1", "alice", "gpt", "claude"), `2 new services match "Cheap chat"`,
+			[]string{"- gpt 1.0.0", "- claude 1.0.0"}},
+		{notify.KindBudgetAlert, budgetPayload("a-1", "acme", 80), "acme has used 80% of its 2026-03 budget",
+			[]string{"80.00 USD of a 100.00 USD cap", "refused once the cap is reached"}},
+		{notify.KindPriceChange, pricePayload("p-1", "acme", false), "chat-gpt changes price on " + now.AddDate(0, 1, 0).Format("2006-01-02"),
+			[]string{"costs 0.003 EUR per 1k tokens (per-token) instead of 0.002 USD per 1k tokens (per-token)"}},
+		{notify.KindPriceChange, pricePayload("p-2", "acme", true), "The price change of chat-gpt is cancelled",
+			[]string{"won't take effect", "stays at 0.002 USD"}},
+		{notify.KindComplianceExpiry, expiryPayload("e-1", "prov-1", marketplace.ExpiryCertification, "SOC2", marketplace.ExpiryExpiring),
+			"The SOC2 certification of chat-gpt expires on " + now.AddDate(0, 0, 20).Format("2006-01-02"),
+			[]string{"Upload a current artifact"}},
+		{notify.KindComplianceExpiry, expiryPayload("e-2", "prov-1", marketplace.ExpiryDataProcessingAgreement, "DPA-2026-01", marketplace.ExpiryExpired),
+			"The data-processing agreement DPA-2026-01 of chat-gpt has expired",
+			[]string{"Renew the agreement", "ranks lower in search"}},
+	}
+	for _, tt := range tests {
+		n := ts.notify(t, tt.kind, tt.payload)
+		if n.Subject != tt.subject {
+			t.Errorf("%s subject = %q, want %q", tt.kind, n.Subject, tt.subject)
+		}
+		for _, want := range tt.body {
+			if !strings.Contains(n.Body, want) {
+				t.Errorf("%s body = %q, want it to contain %q", tt.kind, n.Body, want)
+			}
+		}
+	}
+}
+
+func TestParseTemplatesValidatesOverrides(t *testing.T) {
+	templates, err := notify.ParseTemplates(map[string]notify.TemplateText{
+		notify.KindBudgetAlert: {Subject: "Budget {{.Threshold}}%", Body: "{{.Spend}} spent"},
+	})
+	if err != nil {
+		t.Fatalf("ParseTemplates() error = %v", err)
+	}
+	n, _ := notify.Decode(notify.KindBudgetAlert, budgetPayload("a-1", "acme", 50))
+	if err := templates.Render(n); err != nil || n.Subject != "Budget 50%" || n.Body != "80 spent" {
+		t.Errorf("Render() = %q, %q, %v", n.Subject, n.Body, err)
+	}
+
+	// An empty subject keeps the default
+	templates, _ = notify.ParseTemplates(map[string]notify.TemplateText{notify.KindSLABreach: {Body: "{{.Status}}"}})
+	n, _ = notify.Decode(notify.KindSLABreach, breachPayload("b-1", "prov-1", "healthy", "down"))
+	if err := templates.Render(n); err != nil || n.Subject != "chat-gpt is down" || n.Body != "down" {
+		t.Errorf("Render() = %q, %q, %v", n.Subject, n.Body, err)
+	}
+
+	for name, overrides := range map[string]map[string]notify.TemplateText{
+		"unknown kind":  {"digest": {Subject: "x", Body: "y"}},
+		"bad syntax":    {notify.KindSLABreach: {Subject: "{{.Status", Body: "y"}},
+		"unknown field": {notify.KindSLABreach: {Subject: "{{.Nope}}", Body: "y"}},
+		"unknown func":  {notify.KindSLABreach: {Subject: "{{upper .Status}}", Body: "y"}},
+	} {
+		if _, err := notify.ParseTemplates(overrides); err == nil {
+			t.Errorf("%s: ParseTemplates() succeeded", name)
+		}
+	}
+}
+
+func TestSetPreferencesValidates(t *testing.T) {
+	ts := newTestService(t)
+	valid := notify.Preferences{RecipientID: "acme", Email: "ops@acme.com", WebhookURL: "https://acme.com/hooks"}
+	tests := []struct {
+		name   string
+		modify func(p *notify.Preferences)
+		valid  bool
+	}{
+		{"valid", func(p *notify.Preferences) {}, true},
+		{"kind override", func(p *notify.Preferences) {
+			p.Kinds = map[string]notify.KindPreference{notify.KindBudgetAlert: {Channels: []string{notify.ChannelEmail}, Digest: true}}
+		}, true},
+		{"muted kind", func(p *notify.Preferences) {
+			p.Kinds = map[string]notify.KindPreference{notify.KindSLABreach: {}}
+		}, true},
+		{"bad email", func(p *notify.Preferences) { p.Email = "ops at acme" }, false},
+		{"named email", func(p *notify.Preferences) { p.Email = "Ops <ops@acme.com>" }, false},
+		{"bad url", func(p *notify.Preferences) { p.SlackWebhookURL = "ftp://hooks.slack.com/x" }, false},
+		{"plain http", func(p *notify.Preferences) { p.WebhookURL = "http://acme.com/hooks" }, false},
+		{"loopback", func(p *notify.Preferences) { p.WebhookURL = "https://127.0.0.1/hooks" }, false},
+		{"metadata service", func(p *notify.Preferences) { p.SlackWebhookURL = "https://169.254.169.254/latest" }, false},
+		{"private", func(p *notify.Preferences) { p.WebhookURL = "https://10.0.0.5:8443/hooks" }, false},
+		{"unknown kind", func(p *notify.Preferences) {
+			p.Kinds = map[string]notify.KindPreference{"digest": {}}
+		}, false},
+		{"unknown channel", func(p *notify.Preferences) {
+			p.Kinds = map[string]notify.KindPreference{notify.KindSLABreach: {Channels: []string{"sms"}}}
+		}, false},
+		{"channel without address", func(p *notify.Preferences) {
+			p.Kinds = map[string]notify.KindPreference{notify.KindSLABreach: {Channels: []string{notify.ChannelSlack}}}
+		}, false},
+	}
+	for _, tt := range tests {
+		prefs := valid
+		tt.modify(&prefs)
+		_, err := ts.SetPreferences(context.Background(), prefs)
+		if tt.valid && err != nil {
+			t.Errorf("%s: SetPreferences() error = %v", tt.name, err)
+		}
+		if !tt.valid && !errors.Is(err, notify.ErrInvalidRequest) {
+			t.Errorf("%s: SetPreferences() error = %v, want ErrInvalidRequest", tt.name, err)
+		}
+	}
+}
+
+func TestNotifySchedulesDeliveriesByPreference(t *testing.T) {
+	ts := newTestService(t)
+	ctx := context.Background()
+	if _, err := ts.SetPreferences(ctx, notify.Preferences{
+		RecipientID:     "prov-1",
+		Email:           "ops@prov.com",
+		SlackWebhookURL: "https://hooks.slack.com/services/x",
+		WebhookURL:      "https://prov.com/hooks",
+		WebhookSecret:   "s3cret",
+		Kinds: map[string]notify.KindPreference{
+			notify.KindPolicyViolation: {Channels: []string{notify.ChannelEmail}, Digest: true},
+			notify.KindSLABreach:       {},
+		},
+	}); err != nil {
+		t.Fatalf("SetPreferences() error = %v", err)
+	}
+
+	// Every configured channel with an address; Slack has no sender
+	ts.notify(t, notify.KindBudgetAlert, budgetPayload("a-1", "prov-1", 50))
+	n, err := ts.Notification(ctx, "budget_alert:a-1")
+	if err != nil {
+		t.Fatalf("Notification() error = %v", err)
+	}
+	if len(n.Deliveries) != 2 || n.Deliveries[0].Channel != notify.ChannelEmail || n.Deliveries[1].Channel != notify.ChannelWebhook {
+		t.Fatalf("deliveries = %+v, want email and webhook", n.Deliveries)
+	}
+	if d := n.Deliveries[1]; d.Secret != "s3cret" || !d.NextAttemptAt.Equal(now) || d.Status != notify.DeliveryPending {
+		t.Errorf("webhook delivery = %+v, want it pending now with the secret", d)
+	}
+
+	// Digested on email at the next midnight
+	ts.notify(t, notify.KindPolicyViolation, violationPayload("v-1", "prov-1"))
+	n, _ = ts.Notification(ctx, "policy_violation:v-1")
+	if len(n.Deliveries) != 1 || !n.Deliveries[0].Digest || !n.Deliveries[0].NextAttemptAt.Equal(time.Date(2026, 3, 11, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("deliveries = %+v, want one email digest due at midnight", n.Deliveries)
+	}
+
+	// Muted kinds and recipients without preferences are only listed
+	ts.notify(t, notify.KindSLABreach, breachPayload("b-1", "prov-1", "healthy", "down"))
+	ts.notify(t, notify.KindBudgetAlert, budgetPayload("a-2", "prov-2", 50))
+	for _, id := range []string{"sla_breach:b-1", "budget_alert:a-2"} {
+		if n, _ := ts.Notification(ctx, id); n == nil || len(n.Deliveries) != 0 {
+			t.Errorf("%s = %+v, want it stored without deliveries", id, n)
+		}
+	}
+
+	// Redelivered events make no new notification
+	ts.notify(t, notify.KindBudgetAlert, budgetPayload("a-1", "prov-1", 50))
+	list, err := ts.Notifications(ctx, notify.NotificationFilter{RecipientID: "prov-1"})
+	if err != nil || len(list) != 3 {
+		t.Errorf("Notifications() = %d, %v, want 3", len(list), err)
+	}
+	if _, err := ts.Notifications(ctx, notify.NotificationFilter{RecipientID: "prov-1", Limit: notify.MaxLimit + 1}); !errors.Is(err, notify.ErrInvalidRequest) {
+		t.Errorf("Notifications() over the limit error = %v", err)
+	}
+}
+
+func TestDispatchSendsAtOnceAndDigestsOnSchedule(t *testing.T) {
+	ts := newTestService(t)
+	ctx := context.Background()
+	if _, err := ts.SetPreferences(ctx, notify.Preferences{
+		RecipientID: "prov-1",
+		Email:       "ops@prov.com",
+		Kinds: map[string]notify.KindPreference{
+			notify.KindPolicyViolation: {Channels: []string{notify.ChannelEmail}, Digest: true},
+		},
+	}); err != nil {
+		t.Fatalf("SetPreferences() error = %v", err)
+	}
+
+	ts.notify(t, notify.KindSLABreach, breachPayload("b-1", "prov-1", "healthy", "down"))
+	for i := 1; i <= 3; i++ {
+		ts.clock = ts.clock.Add(time.Hour)
+		ts.notify(t, notify.KindPolicyViolation, violationPayload(fmt.Sprintf("v-%d", i), "prov-1"))
+	}
+
+	if n := ts.dispatch(t); n != 1 {
+		t.Fatalf("dispatched %d deliveries, want the breach", n)
+	}
+	if len(ts.email.messages) != 1 || ts.email.messages[0].Subject != "chat-gpt is down" {
+		t.Fatalf("messages = %+v, want the breach", ts.email.messages)
+	}
+	if d := ts.store.delivery("sla_breach:b-1/email"); d.Status != notify.DeliverySent || d.Attempts != 1 || d.SentAt == nil {
+		t.Errorf("breach delivery = %+v, want it sent", d)
+	}
+
+	ts.clock = time.Date(2026, 3, 11, 0, 0, 0, 0, time.UTC)
+	if n := ts.dispatch(t); n != 3 {
+		t.Fatalf("dispatched %d deliveries at midnight, want the 3 violations", n)
+	}
+	if len(ts.email.messages) != 2 {
+		t.Fatalf("sent %d messages, want the digest after the breach", len(ts.email.messages))
+	}
+	digest := ts.email.messages[1]
+	if digest.Subject != "3 marketplace notifications" || len(digest.Notifications) != 3 || strings.Count(digest.Body, "failed validation") != 3 {
+		t.Errorf("digest = %q: %q", digest.Subject, digest.Body)
+	}
+	if n := ts.dispatch(t); n != 0 {
+		t.Errorf("dispatched %d deliveries again", n)
+	}
+}
+
+func TestDispatchRetriesWithBackoffThenFails(t *testing.T) {
+	ts := newTestService(t)
+	ctx := context.Background()
+	if _, err := ts.SetPreferences(ctx, notify.Preferences{RecipientID: "acme", WebhookURL: "https://acme.com/hooks"}); err != nil {
+		t.Fatalf("SetPreferences() error = %v", err)
+	}
+	ts.notify(t, notify.KindBudgetAlert, budgetPayload("a-1", "acme", 100))
+	ts.webhook.err = errors.New("endpoint returned 503 Service Unavailable")
+
+	const id = "budget_alert:a-1/webhook"
+	for attempt, backoff := range []time.Duration{time.Minute, 2 * time.Minute} {
+		if n := ts.dispatch(t); n != 1 {
+			t.Fatalf("attempt %d: dispatched %d deliveries", attempt+1, n)
+		}
+		d := ts.store.delivery(id)
+		if d.Status != notify.DeliveryPending || d.Attempts != attempt+1 || !d.NextAttemptAt.Equal(ts.clock.Add(backoff)) || d.LastError == "" {
+			t.Fatalf("attempt %d: delivery = %+v, want a retry in %s", attempt+1, d, backoff)
+		}
+		if n := ts.dispatch(t); n != 0 {
+			t.Errorf("attempt %d: retried %d deliveries before the backoff", attempt+1, n)
+		}
+		ts.clock = d.NextAttemptAt
+	}
+
+	ts.dispatch(t)
+	if d := ts.store.delivery(id); d.Status != notify.DeliveryFailed || d.Attempts != policy.MaxAttempts {
+		t.Errorf("delivery = %+v, want it failed after %d attempts", d, policy.MaxAttempts)
+	}
+	ts.clock = ts.clock.Add(time.Hour)
+	if n := ts.dispatch(t); n != 0 {
+		t.Errorf("dispatched %d failed deliveries", n)
+	}
+}
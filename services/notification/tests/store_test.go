@@ -0,0 +1,190 @@
+package tests
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/org/llm-marketplace/services/notification/internal/notify"
+)
+
+// memStore is an in-memory notify.Store with the semantics of the
+// PostgreSQL store
+type memStore struct {
+	mu            sync.Mutex
+	preferences   map[string]notify.Preferences
+	notifications map[string]*notify.Notification
+	deliveries    map[string]*notify.Delivery
+	err           error
+}
+
+func newMemStore() *memStore {
+	return &memStore{
+		preferences:   map[string]notify.Preferences{},
+		notifications: map[string]*notify.Notification{},
+		deliveries:    map[string]*notify.Delivery{},
+	}
+}
+
+func (s *memStore) PutPreferences(_ context.Context, prefs *notify.Preferences) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.preferences[prefs.RecipientID] = *prefs
+	return nil
+}
+
+func (s *memStore) GetPreferences(_ context.Context, recipientID string) (*notify.Preferences, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return nil, s.err
+	}
+	prefs, ok := s.preferences[recipientID]
+	if !ok {
+		return nil, notify.ErrNotFound
+	}
+	return &prefs, nil
+}
+
+func (s *memStore) DeletePreferences(_ context.Context, recipientID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.preferences[recipientID]; !ok {
+		return notify.ErrNotFound
+	}
+	delete(s.preferences, recipientID)
+	return nil
+}
+
+func (s *memStore) SaveNotification(_ context.Context, n *notify.Notification) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return false, s.err
+	}
+	if _, ok := s.notifications[n.ID]; ok {
+		return false, nil
+	}
+	stored := *n
+	stored.Deliveries = nil
+	s.notifications[n.ID] = &stored
+	for _, d := range n.Deliveries {
+		copied := *d
+		s.deliveries[d.ID] = &copied
+	}
+	return true, nil
+}
+
+// withDeliveries returns a copy of n with its deliveries
+func (s *memStore) withDeliveries(n *notify.Notification) *notify.Notification {
+	copied := *n
+	for _, d := range s.deliveries {
+		if d.NotificationID == n.ID {
+			delivery := *d
+			copied.Deliveries = append(copied.Deliveries, &delivery)
+		}
+	}
+	sort.Slice(copied.Deliveries, func(i, j int) bool { return copied.Deliveries[i].Channel < copied.Deliveries[j].Channel })
+	return &copied
+}
+
+func (s *memStore) GetNotification(_ context.Context, id string) (*notify.Notification, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n, ok := s.notifications[id]
+	if !ok {
+		return nil, notify.ErrNotFound
+	}
+	return s.withDeliveries(n), nil
+}
+
+func (s *memStore) ListNotifications(_ context.Context, filter notify.NotificationFilter) ([]*notify.Notification, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var notifications []*notify.Notification
+	for _, n := range s.notifications {
+		if n.RecipientID == filter.RecipientID && (filter.Kind == "" || n.Kind == filter.Kind) {
+			notifications = append(notifications, s.withDeliveries(n))
+		}
+	}
+	sort.Slice(notifications, func(i, j int) bool {
+		a, b := notifications[i], notifications[j]
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.After(b.CreatedAt)
+		}
+		return a.ID < b.ID
+	})
+	if filter.Offset >= len(notifications) {
+		return nil, nil
+	}
+	notifications = notifications[filter.Offset:]
+	if len(notifications) > filter.Limit {
+		notifications = notifications[:filter.Limit]
+	}
+	return notifications, nil
+}
+
+func (s *memStore) PruneNotifications(_ context.Context, before time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var pruned int64
+	for id, n := range s.notifications {
+		if n.CreatedAt.Before(before) {
+			delete(s.notifications, id)
+			pruned++
+		}
+	}
+	for id, d := range s.deliveries {
+		if _, ok := s.notifications[d.NotificationID]; !ok {
+			delete(s.deliveries, id)
+		}
+	}
+	return pruned, nil
+}
+
+func (s *memStore) ClaimDeliveries(_ context.Context, now time.Time, lease time.Duration, limit int) ([]*notify.Delivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var due []*notify.Delivery
+	for _, d := range s.deliveries {
+		if d.Status == notify.DeliveryPending && !d.NextAttemptAt.After(now) {
+			due = append(due, d)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool {
+		a, b := s.notifications[due[i].NotificationID], s.notifications[due[j].NotificationID]
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.Before(b.CreatedAt)
+		}
+		return due[i].ID < due[j].ID
+	})
+	if len(due) > limit {
+		due = due[:limit]
+	}
+	claimed := make([]*notify.Delivery, len(due))
+	for i, d := range due {
+		d.NextAttemptAt = now.Add(lease)
+		copied := *d
+		n := *s.notifications[d.NotificationID]
+		copied.Notification = &n
+		claimed[i] = &copied
+	}
+	return claimed, nil
+}
+
+func (s *memStore) UpdateDelivery(_ context.Context, d *notify.Delivery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	copied := *d
+	copied.Notification = nil
+	s.deliveries[d.ID] = &copied
+	return nil
+}
+
+// delivery returns the stored delivery with id
+func (s *memStore) delivery(id string) *notify.Delivery {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.deliveries[id]
+}
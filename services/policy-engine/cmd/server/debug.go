@@ -0,0 +1,80 @@
+package main
+
+import (
+	"crypto/subtle"
+	"expvar"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	runtimepprof "runtime/pprof"
+
+	"github.com/llm-marketplace/policy-engine/internal/config"
+)
+
+// registerDebug adds pprof, expvar and a goroutine dump to the metrics mux
+// behind the allowlist and basic auth in cfg. Handlers are registered
+// explicitly so nothing lands on http.DefaultServeMux.
+func registerDebug(mux *http.ServeMux, cfg config.DebugConfig) {
+	guard := debugGuard(cfg)
+
+	mux.Handle("/debug/pprof/", guard(http.HandlerFunc(pprof.Index)))
+	mux.Handle("/debug/pprof/cmdline", guard(http.HandlerFunc(pprof.Cmdline)))
+	mux.Handle("/debug/pprof/profile", guard(http.HandlerFunc(pprof.Profile)))
+	mux.Handle("/debug/pprof/symbol", guard(http.HandlerFunc(pprof.Symbol)))
+	mux.Handle("/debug/pprof/trace", guard(http.HandlerFunc(pprof.Trace)))
+	mux.Handle("/debug/vars", guard(expvar.Handler()))
+	mux.Handle("/debug/goroutines", guard(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		runtimepprof.Lookup("goroutine").WriteTo(w, 2)
+	})))
+}
+
+// debugGuard answers 403 outside the allowed networks and 401 without the
+// configured credentials
+func debugGuard(cfg config.DebugConfig) func(http.Handler) http.Handler {
+	var networks []*net.IPNet
+	for _, cidr := range cfg.AllowedCIDRs {
+		if _, network, err := net.ParseCIDR(cidr); err == nil {
+			networks = append(networks, network)
+		}
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if len(networks) > 0 && !allowedAddr(r.RemoteAddr, networks) {
+				http.Error(w, "forbidden", http.StatusForbidden)
+				return
+			}
+
+			if cfg.Username != "" {
+				user, pass, ok := r.BasicAuth()
+				userOK := subtle.ConstantTimeCompare([]byte(user), []byte(cfg.Username)) == 1
+				passOK := subtle.ConstantTimeCompare([]byte(pass), []byte(cfg.Password)) == 1
+				if !ok || !userOK || !passOK {
+					w.Header().Set("WWW-Authenticate", `Basic realm="debug"`)
+					http.Error(w, "unauthorized", http.StatusUnauthorized)
+					return
+				}
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func allowedAddr(remoteAddr string, networks []*net.IPNet) bool {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	for _, network := range networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
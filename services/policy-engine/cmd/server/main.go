@@ -2,8 +2,13 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
 	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"flag"
 	"fmt"
+	"math/rand"
 	"net"
 	"net/http"
 	"os"
@@ -11,6 +16,9 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/lib/pq"
+	"github.com/org/llm-marketplace/pkg/marketplace/flags"
+	"github.com/org/llm-marketplace/pkg/marketplace/secrets"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/rs/zerolog"
@@ -20,10 +28,13 @@ import (
 	"google.golang.org/grpc/reflection"
 
 	pb "github.com/llm-marketplace/policy-engine/api/proto/v1"
+	"github.com/llm-marketplace/policy-engine/internal/budgets"
 	"github.com/llm-marketplace/policy-engine/internal/config"
 	"github.com/llm-marketplace/policy-engine/internal/policy"
+	"github.com/llm-marketplace/policy-engine/internal/sanctions"
 	"github.com/llm-marketplace/policy-engine/internal/server"
 	"github.com/llm-marketplace/policy-engine/internal/storage"
+	"github.com/llm-marketplace/policy-engine/internal/subscriptions"
 )
 
 var (
@@ -61,6 +72,9 @@ func init() {
 }
 
 func main() {
+	sandbox := flag.Bool("sandbox", false, "Keep policies and sanctions lists in memory instead of PostgreSQL, seeded with the default policies")
+	flag.Parse()
+
 	// Load configuration
 	configPath := os.Getenv("CONFIG_PATH")
 	cfg, err := config.Load(configPath)
@@ -78,23 +92,59 @@ func main() {
 		Int("port", cfg.Server.Port).
 		Msg("Configuration loaded")
 
-	// Connect to database
-	db, err := connectDatabase(cfg)
+	// Initialize tracing
+	shutdownTracing, err := initTracing(cfg.Observability.Tracing)
 	if err != nil {
-		log.Fatal().Err(err).Msg("Failed to connect to database")
+		log.Fatal().Err(err).Msg("Failed to initialize tracing")
 	}
-	defer db.Close()
-
-	// Initialize policy store
-	policyStore := storage.NewPolicyStore(
-		db,
-		cfg.Cache.Enabled,
-		cfg.Cache.TTL,
-		cfg.Cache.MaxSize,
-	)
 
-	// Initialize database schema
 	ctx := context.Background()
+	secretsManager := secrets.NewManager(cfg.Secrets, func(name string, err error) {
+		if err != nil {
+			log.Warn().Err(err).Str("secret", name).Msg("Failed to refresh secret, keeping the last value")
+			return
+		}
+		log.Info().Str("secret", name).Msg("Secret rotated")
+	})
+	secretsCtx, stopSecrets := context.WithCancel(ctx)
+	defer stopSecrets()
+
+	var policyStore *storage.PolicyStore
+	var sanctionsStore *storage.SanctionsStore
+	if *sandbox {
+		// Nothing outside the process: no database, feeds, registry or metering
+		log.Warn().Msg("Sandbox mode: policies and sanctions lists are kept in memory and lost on exit")
+		cfg.Sanctions.Feeds = nil
+		cfg.Subscriptions.RegistryURL = ""
+		cfg.Budgets.MeteringURL = ""
+		policyStore = storage.NewMemoryPolicyStore()
+		sanctionsStore = storage.NewMemorySanctionsStore()
+	} else {
+		// Resolve the database password when it names a secret
+		dbPassword, err := secretsManager.Resolve(ctx, cfg.Database.Password)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to resolve database password")
+		}
+		go secretsManager.Start(secretsCtx)
+
+		// Connect to database
+		db, err := connectDatabase(cfg, dbPassword)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to connect to database")
+		}
+		defer db.Close()
+
+		// Initialize policy store
+		policyStore = storage.NewPolicyStore(
+			db,
+			cfg.Cache.Enabled,
+			cfg.Cache.TTL,
+			cfg.Cache.MaxSize,
+		)
+		sanctionsStore = storage.NewSanctionsStore(db)
+	}
+
+	// Initialize database schema
 	if err := policyStore.Initialize(ctx); err != nil {
 		log.Fatal().Err(err).Msg("Failed to initialize policy store")
 	}
@@ -104,14 +154,55 @@ func main() {
 		log.Fatal().Err(err).Msg("Failed to seed default policies")
 	}
 
+	// Load the synced sanctions lists before any validation depends on them
+	if err := sanctionsStore.Initialize(ctx); err != nil {
+		log.Fatal().Err(err).Msg("Failed to initialize sanctions store")
+	}
+	sanctionsSyncer := sanctions.NewSyncer(cfg.Sanctions, sanctionsStore)
+	if err := sanctionsSyncer.Load(ctx); err != nil {
+		log.Fatal().Err(err).Msg("Failed to load sanctions lists")
+	}
+	sanctionsCtx, stopSanctions := context.WithCancel(ctx)
+	defer stopSanctions()
+	if len(cfg.Sanctions.Feeds) > 0 {
+		go sanctionsSyncer.Start(sanctionsCtx)
+		log.Info().Int("feeds", len(cfg.Sanctions.Feeds)).Dur("interval", cfg.Sanctions.SyncInterval).Msg("Syncing sanctions lists")
+	}
+
 	// Create policy validator
 	validator := policy.NewValidator(policyStore)
+	validator.SetSanctions(sanctionsSyncer)
+	if cfg.Subscriptions.RegistryURL != "" {
+		validator.SetSubscriptions(subscriptions.NewClient(cfg.Subscriptions))
+		log.Info().Str("registry_url", cfg.Subscriptions.RegistryURL).Msg("Checking subscriptions in the registry")
+	}
+	if cfg.Budgets.MeteringURL != "" {
+		validator.SetBudgets(budgets.NewClient(cfg.Budgets))
+		log.Info().Str("metering_url", cfg.Budgets.MeteringURL).Msg("Enforcing budgets from the metering service")
+	}
+
+	// Gate policy engines behind feature flags, refreshed without a restart
+	var flagSource flags.Source = flags.Static(cfg.FeatureFlags.Flags)
+	if cfg.FeatureFlags.File != "" {
+		flagSource = flags.File(cfg.FeatureFlags.File)
+	}
+	featureFlags := flags.NewEvaluator(flagSource)
+	if err := featureFlags.Refresh(ctx); err != nil {
+		log.Fatal().Err(err).Msg("Failed to load feature flags")
+	}
+	flagsCtx, stopFlags := context.WithCancel(ctx)
+	defer stopFlags()
+	go featureFlags.Run(flagsCtx, cfg.FeatureFlags.RefreshInterval, func(err error) {
+		log.Warn().Err(err).Msg("Failed to refresh feature flags, keeping the last ones")
+	})
+	validator.SetFlags(featureFlags)
 
 	// Create gRPC server
-	grpcServer := grpc.NewServer(
-		grpc.MaxRecvMsgSize(10*1024*1024), // 10MB
-		grpc.MaxSendMsgSize(10*1024*1024), // 10MB
-	)
+	serverOpts := append([]grpc.ServerOption{
+		grpc.MaxRecvMsgSize(10 * 1024 * 1024), // 10MB
+		grpc.MaxSendMsgSize(10 * 1024 * 1024), // 10MB
+	}, tracingServerOptions()...)
+	grpcServer := grpc.NewServer(serverOpts...)
 
 	// Register services
 	policyEngineServer := server.NewPolicyEngineServer(validator, policyStore)
@@ -130,8 +221,10 @@ func main() {
 	}
 
 	// Start metrics server
+	var metricsServer *http.Server
 	if cfg.Observability.Metrics.Enabled {
-		go startMetricsServer(cfg)
+		metricsServer = newMetricsServer(cfg.Observability.Metrics)
+		go serveMetrics(metricsServer, cfg.Observability.Metrics)
 	}
 
 	// Start gRPC server
@@ -151,8 +244,10 @@ func main() {
 		}
 	}()
 
-	// Update metrics
-	go updateMetrics(ctx, policyStore)
+	// Update metrics until shutdown
+	metricsCtx, stopMetrics := context.WithCancel(ctx)
+	defer stopMetrics()
+	go updateMetrics(metricsCtx, policyStore)
 
 	// Wait for interrupt signal
 	quit := make(chan os.Signal, 1)
@@ -161,10 +256,38 @@ func main() {
 
 	log.Info().Msg("Shutting down server...")
 
-	// Graceful shutdown
-	grpcServer.GracefulStop()
+	// Graceful shutdown, bounded so a stuck stream can't hold the process
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+
+	stopMetrics()
+	stopSecrets()
+	stopSanctions()
+
+	stopped := make(chan struct{})
+	go func() {
+		grpcServer.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-shutdownCtx.Done():
+		log.Warn().Msg("gRPC graceful stop timed out, forcing")
+		grpcServer.Stop()
+	}
+
+	// Metrics stay scrapeable until the gRPC server has drained
+	if metricsServer != nil {
+		if err := metricsServer.Shutdown(shutdownCtx); err != nil {
+			log.Error().Err(err).Msg("Metrics server forced to shutdown")
+		}
+	}
+
 	policyStore.Close()
 
+	// Flush spans recorded while draining
+	shutdownTracing(shutdownCtx)
+
 	log.Info().Msg("Server stopped")
 }
 
@@ -185,46 +308,116 @@ func setupLogging(cfg config.LoggingConfig) {
 	zerolog.TimeFieldFormat = cfg.TimeFormat
 }
 
-func connectDatabase(cfg *config.Config) (*sql.DB, error) {
+func connectDatabase(cfg *config.Config, password *secrets.Secret) (*sql.DB, error) {
 	log.Info().
 		Str("host", cfg.Database.Host).
 		Int("port", cfg.Database.Port).
 		Str("database", cfg.Database.Database).
 		Msg("Connecting to database")
 
-	db, err := sql.Open("postgres", cfg.GetDatabaseDSN())
-	if err != nil {
-		return nil, fmt.Errorf("failed to open database: %w", err)
-	}
+	db := sql.OpenDB(&passwordConnector{dsn: cfg.DatabaseDSN, password: password.Value})
 
 	// Set connection pool settings
 	db.SetMaxOpenConns(cfg.Database.MaxConnections)
 	db.SetMaxIdleConns(cfg.Database.MaxIdleConns)
 	db.SetConnMaxLifetime(cfg.Database.ConnMaxLifetime)
 
-	// Verify connection
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
-	if err := db.PingContext(ctx); err != nil {
-		return nil, fmt.Errorf("failed to ping database: %w", err)
+	// Verify connection, waiting for a database that is still starting
+	if err := waitForDatabase(db, cfg.Server.Startup); err != nil {
+		db.Close()
+		return nil, err
 	}
 
 	log.Info().Msg("Database connection established")
 	return db, nil
 }
 
-func startMetricsServer(cfg *config.Config) {
-	metricsAddr := fmt.Sprintf(":%d", cfg.Observability.Metrics.Port)
+// passwordConnector opens each connection with the current database password,
+// so a rotated secret applies to new connections without a restart
+type passwordConnector struct {
+	dsn      func(password string) string
+	password func() string
+}
 
-	http.Handle(cfg.Observability.Metrics.Path, promhttp.Handler())
+func (c *passwordConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	connector, err := pq.NewConnector(c.dsn(c.password()))
+	if err != nil {
+		return nil, err
+	}
+	return connector.Connect(ctx)
+}
 
+func (c *passwordConnector) Driver() driver.Driver {
+	return &pq.Driver{}
+}
+
+// waitForDatabase pings db until it answers, backing off between attempts,
+// and gives up after cfg.MaxWait
+func waitForDatabase(db *sql.DB, cfg config.StartupConfig) error {
+	start := time.Now()
+	deadline := start.Add(cfg.MaxWait)
+	delay := cfg.InitialBackoff
+	for attempt := 1; ; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		err := db.PingContext(ctx)
+		cancel()
+		if err == nil {
+			return nil
+		}
+
+		// Half the delay is fixed, half random, so replicas spread out
+		sleep := delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+		if time.Now().Add(sleep).After(deadline) {
+			return fmt.Errorf("failed to ping database after %s and %d attempts: %w",
+				time.Since(start).Round(time.Millisecond), attempt, err)
+		}
+
+		log.Warn().
+			Err(err).
+			Int("attempt", attempt).
+			Dur("retry_in", sleep).
+			Msg("Database not available, retrying")
+		time.Sleep(sleep)
+
+		delay = min(delay*2, cfg.MaxBackoff)
+	}
+}
+
+// newMetricsServer serves the Prometheus registry on its own mux so handlers
+// registered on http.DefaultServeMux by dependencies are not exposed
+func newMetricsServer(cfg config.MetricsConfig) *http.Server {
+	mux := http.NewServeMux()
+	mux.Handle(cfg.Path, promhttp.Handler())
+	if cfg.Debug.Enabled {
+		registerDebug(mux, cfg.Debug)
+	}
+
+	server := &http.Server{
+		Addr:              fmt.Sprintf(":%d", cfg.Port),
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	if cfg.TLSCertFile != "" {
+		server.TLSConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+	return server
+}
+
+func serveMetrics(server *http.Server, cfg config.MetricsConfig) {
+	tlsEnabled := cfg.TLSCertFile != ""
 	log.Info().
-		Str("address", metricsAddr).
-		Str("path", cfg.Observability.Metrics.Path).
+		Str("address", server.Addr).
+		Str("path", cfg.Path).
+		Bool("tls", tlsEnabled).
 		Msg("Starting metrics server")
 
-	if err := http.ListenAndServe(metricsAddr, nil); err != nil {
+	var err error
+	if tlsEnabled {
+		err = server.ListenAndServeTLS(cfg.TLSCertFile, cfg.TLSKeyFile)
+	} else {
+		err = server.ListenAndServe()
+	}
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
 		log.Error().Err(err).Msg("Metrics server failed")
 	}
 }
@@ -0,0 +1,103 @@
+// Package budgets looks up consumers' monthly budgets in the metering
+// service
+package budgets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/llm-marketplace/policy-engine/internal/config"
+)
+
+// Status is a consumer's budget and its spend this month
+type Status struct {
+	MonthlyCap float64 `json:"monthly_cap"`
+	Currency   string  `json:"currency"`
+	Spend      float64 `json:"spend"`
+	Blocked    bool    `json:"blocked"` // The cap is reached and enforced
+}
+
+// Client queries the metering service's budgets API as an operator. Answers
+// are cached for the configured TTL, so a consumer reaching its cap, or an
+// operator raising it, takes up to that long to be enforced.
+type Client struct {
+	baseURL string
+	client  *http.Client
+	ttl     time.Duration
+
+	mu    sync.Mutex
+	cache map[string]entry
+}
+
+type entry struct {
+	status  *Status
+	expires time.Time
+}
+
+// NewClient creates a client of the metering service at cfg.MeteringURL
+func NewClient(cfg config.BudgetsConfig) *Client {
+	return &Client{
+		baseURL: strings.TrimSuffix(cfg.MeteringURL, "/"),
+		client:  &http.Client{Timeout: cfg.Timeout},
+		ttl:     cfg.CacheTTL,
+		cache:   make(map[string]entry),
+	}
+}
+
+// Budget returns consumerID's budget status, or nil when it has no budget
+func (c *Client) Budget(ctx context.Context, consumerID string) (*Status, error) {
+	now := time.Now()
+
+	c.mu.Lock()
+	e, ok := c.cache[consumerID]
+	c.mu.Unlock()
+	if ok && now.Before(e.expires) {
+		return e.status, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/api/v1/consumers/"+url.PathEscape(consumerID)+"/budget", nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query budget: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var status *Status
+	switch resp.StatusCode {
+	case http.StatusOK:
+		status = &Status{}
+		if err := json.NewDecoder(resp.Body).Decode(status); err != nil {
+			return nil, fmt.Errorf("invalid budget response: %w", err)
+		}
+	case http.StatusNotFound:
+	default:
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("metering returned %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	if c.ttl > 0 {
+		c.mu.Lock()
+		// Drop expired answers now and then, so the cache doesn't keep every
+		// consumer ever checked
+		if len(c.cache) >= 10000 {
+			for k, old := range c.cache {
+				if !now.Before(old.expires) {
+					delete(c.cache, k)
+				}
+			}
+		}
+		c.cache[consumerID] = entry{status: status, expires: now.Add(c.ttl)}
+		c.mu.Unlock()
+	}
+	return status, nil
+}
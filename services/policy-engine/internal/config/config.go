@@ -2,10 +2,14 @@ package config
 
 import (
 	"fmt"
+	"net"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/org/llm-marketplace/pkg/marketplace/flags"
+	"github.com/org/llm-marketplace/pkg/marketplace/secrets"
 	"gopkg.in/yaml.v3"
 )
 
@@ -16,6 +20,11 @@ type Config struct {
 	Cache       CacheConfig       `yaml:"cache"`
 	Observability ObservabilityConfig `yaml:"observability"`
 	Policies    PoliciesConfig    `yaml:"policies"`
+	Secrets     secrets.Config    `yaml:"secrets"`
+	Subscriptions SubscriptionsConfig `yaml:"subscriptions"`
+	Budgets     BudgetsConfig     `yaml:"budgets"`
+	Sanctions   SanctionsConfig   `yaml:"sanctions"`
+	FeatureFlags FeatureFlagsConfig `yaml:"feature_flags"`
 }
 
 // ServerConfig holds server-specific configuration
@@ -27,6 +36,15 @@ type ServerConfig struct {
 	MaxConnections    int           `yaml:"max_connections"`
 	EnableReflection  bool          `yaml:"enable_reflection"`
 	EnableHealthCheck bool          `yaml:"enable_health_check"`
+	ShutdownTimeout   time.Duration `yaml:"shutdown_timeout"`
+	Startup           StartupConfig `yaml:"startup"`
+}
+
+// StartupConfig holds how long startup waits for the database to come up
+type StartupConfig struct {
+	MaxWait        time.Duration `yaml:"max_wait"`
+	InitialBackoff time.Duration `yaml:"initial_backoff"` // Doubled after each failed attempt
+	MaxBackoff     time.Duration `yaml:"max_backoff"`
 }
 
 // DatabaseConfig holds database configuration
@@ -42,6 +60,47 @@ type DatabaseConfig struct {
 	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime"`
 }
 
+// SubscriptionsConfig holds where the registry's subscriptions are looked up
+// for access-control rules that require one. Those rules deny every request
+// when RegistryURL is empty.
+type SubscriptionsConfig struct {
+	RegistryURL string        `yaml:"registry_url"`
+	Timeout     time.Duration `yaml:"timeout"`
+	CacheTTL    time.Duration `yaml:"cache_ttl"` // How long a lookup is reused; 0 disables caching
+}
+
+// BudgetsConfig holds where consumers' monthly budgets are looked up in the
+// metering service. Consumption of a consumer whose budget is blocked at its
+// cap is denied. Budgets are not enforced when MeteringURL is empty.
+type BudgetsConfig struct {
+	MeteringURL string        `yaml:"metering_url"`
+	Timeout     time.Duration `yaml:"timeout"`
+	CacheTTL    time.Duration `yaml:"cache_ttl"` // How long a lookup is reused; 0 disables caching
+}
+
+// SanctionsConfig holds the external feeds of sanctioned and embargoed
+// countries that DATA_RESIDENCY rules refer to by name with blocked_lists
+type SanctionsConfig struct {
+	SyncInterval time.Duration         `yaml:"sync_interval"`
+	Timeout      time.Duration         `yaml:"timeout"`
+	Feeds        []SanctionsFeedConfig `yaml:"feeds"`
+}
+
+// SanctionsFeedConfig holds where one named list is synced from
+type SanctionsFeedConfig struct {
+	Name string `yaml:"name"`
+	URL  string `yaml:"url"`
+}
+
+// FeatureFlagsConfig holds the feature flags gating policy evaluation
+// engines, named policy.engine.<policy type>. Flags are read from File when
+// set, otherwise taken from Flags, and refreshed every RefreshInterval.
+type FeatureFlagsConfig struct {
+	File            string        `yaml:"file"`
+	RefreshInterval time.Duration `yaml:"refresh_interval"`
+	Flags           flags.Set     `yaml:"flags"`
+}
+
 // CacheConfig holds cache configuration
 type CacheConfig struct {
 	Enabled        bool          `yaml:"enabled"`
@@ -59,9 +118,21 @@ type ObservabilityConfig struct {
 
 // MetricsConfig holds metrics configuration
 type MetricsConfig struct {
-	Enabled bool   `yaml:"enabled"`
-	Port    int    `yaml:"port"`
-	Path    string `yaml:"path"`
+	Enabled     bool        `yaml:"enabled"`
+	Port        int         `yaml:"port"`
+	Path        string      `yaml:"path"`
+	TLSCertFile string      `yaml:"tls_cert_file"` // Metrics are served over HTTPS when both are set
+	TLSKeyFile  string      `yaml:"tls_key_file"`
+	Debug       DebugConfig `yaml:"debug"`
+}
+
+// DebugConfig holds settings for the pprof, expvar and goroutine dump
+// endpoints on the metrics port
+type DebugConfig struct {
+	Enabled      bool     `yaml:"enabled"`
+	Username     string   `yaml:"username"`
+	Password     string   `yaml:"password"`
+	AllowedCIDRs []string `yaml:"allowed_cidrs"` // Any address when empty
 }
 
 // TracingConfig holds tracing configuration
@@ -122,6 +193,10 @@ func (c *Config) setDefaults() {
 	c.Server.MaxConnections = 1000
 	c.Server.EnableReflection = true
 	c.Server.EnableHealthCheck = true
+	c.Server.ShutdownTimeout = 30 * time.Second
+	c.Server.Startup.MaxWait = 2 * time.Minute
+	c.Server.Startup.InitialBackoff = 500 * time.Millisecond
+	c.Server.Startup.MaxBackoff = 10 * time.Second
 
 	// Database defaults
 	c.Database.Host = "localhost"
@@ -158,6 +233,25 @@ func (c *Config) setDefaults() {
 	c.Policies.ReloadInterval = 5 * time.Minute
 	c.Policies.EnableAutoReload = true
 	c.Policies.ValidationTimeout = 5 * time.Second
+
+	// Secrets defaults
+	c.Secrets.RefreshInterval = 5 * time.Minute
+	c.Secrets.Timeout = 10 * time.Second
+
+	// Subscriptions defaults
+	c.Subscriptions.Timeout = 2 * time.Second
+	c.Subscriptions.CacheTTL = 30 * time.Second
+
+	// Budgets defaults
+	c.Budgets.Timeout = 2 * time.Second
+	c.Budgets.CacheTTL = 30 * time.Second
+
+	// Sanctions defaults
+	c.Sanctions.SyncInterval = 6 * time.Hour
+	c.Sanctions.Timeout = 30 * time.Second
+
+	// Feature flag defaults
+	c.FeatureFlags.RefreshInterval = 30 * time.Second
 }
 
 func (c *Config) loadFromFile(path string) error {
@@ -179,6 +273,11 @@ func (c *Config) loadFromEnv() {
 	if host := os.Getenv("POLICY_ENGINE_HOST"); host != "" {
 		c.Server.Host = host
 	}
+	if maxWait := os.Getenv("STARTUP_MAX_WAIT"); maxWait != "" {
+		if d, err := time.ParseDuration(maxWait); err == nil {
+			c.Server.Startup.MaxWait = d
+		}
+	}
 
 	// Database config
 	if host := os.Getenv("DB_HOST"); host != "" {
@@ -202,6 +301,29 @@ func (c *Config) loadFromEnv() {
 		c.Database.SSLMode = sslMode
 	}
 
+	// Secrets config
+	if address := os.Getenv("VAULT_ADDR"); address != "" {
+		c.Secrets.Vault.Address = address
+	}
+	if token := os.Getenv("VAULT_TOKEN"); token != "" {
+		c.Secrets.Vault.Token = token
+	}
+
+	// Subscriptions config
+	if registryURL := os.Getenv("REGISTRY_URL"); registryURL != "" {
+		c.Subscriptions.RegistryURL = registryURL
+	}
+
+	// Budgets config
+	if meteringURL := os.Getenv("METERING_URL"); meteringURL != "" {
+		c.Budgets.MeteringURL = meteringURL
+	}
+
+	// Sanctions config
+	if feedURL := os.Getenv("SANCTIONS_FEED_URL"); feedURL != "" {
+		c.Sanctions.Feeds = []SanctionsFeedConfig{{Name: "sanctions", URL: feedURL}}
+	}
+
 	// Observability config
 	if jaegerURL := os.Getenv("JAEGER_URL"); jaegerURL != "" {
 		c.Observability.Tracing.JaegerURL = jaegerURL
@@ -210,6 +332,15 @@ func (c *Config) loadFromEnv() {
 	if logLevel := os.Getenv("LOG_LEVEL"); logLevel != "" {
 		c.Observability.Logging.Level = logLevel
 	}
+	if certFile := os.Getenv("METRICS_TLS_CERT_FILE"); certFile != "" {
+		c.Observability.Metrics.TLSCertFile = certFile
+	}
+	if keyFile := os.Getenv("METRICS_TLS_KEY_FILE"); keyFile != "" {
+		c.Observability.Metrics.TLSKeyFile = keyFile
+	}
+	if password := os.Getenv("DEBUG_ENDPOINTS_PASSWORD"); password != "" {
+		c.Observability.Metrics.Debug.Password = password
+	}
 }
 
 func (c *Config) validate() error {
@@ -217,6 +348,10 @@ func (c *Config) validate() error {
 		return fmt.Errorf("invalid server port: %d", c.Server.Port)
 	}
 
+	if c.Server.Startup.InitialBackoff <= 0 || c.Server.Startup.MaxBackoff < c.Server.Startup.InitialBackoff {
+		return fmt.Errorf("startup backoff must be positive with max_backoff at least initial_backoff")
+	}
+
 	if c.Database.Host == "" {
 		return fmt.Errorf("database host is required")
 	}
@@ -229,17 +364,74 @@ func (c *Config) validate() error {
 		return fmt.Errorf("database name is required")
 	}
 
+	if c.Secrets.RefreshInterval <= 0 {
+		return fmt.Errorf("secrets refresh interval must be positive")
+	}
+
+	if c.Subscriptions.RegistryURL != "" && c.Subscriptions.Timeout <= 0 {
+		return fmt.Errorf("subscriptions timeout must be positive")
+	}
+
+	if c.Budgets.MeteringURL != "" && c.Budgets.Timeout <= 0 {
+		return fmt.Errorf("budgets timeout must be positive")
+	}
+
+	if len(c.Sanctions.Feeds) > 0 && (c.Sanctions.SyncInterval <= 0 || c.Sanctions.Timeout <= 0) {
+		return fmt.Errorf("sanctions sync interval and timeout must be positive")
+	}
+	names := make(map[string]bool)
+	for _, feed := range c.Sanctions.Feeds {
+		if feed.Name == "" || feed.URL == "" {
+			return fmt.Errorf("sanctions feeds need a name and a url")
+		}
+		if names[feed.Name] {
+			return fmt.Errorf("duplicate sanctions feed %q", feed.Name)
+		}
+		names[feed.Name] = true
+	}
+
+	if c.FeatureFlags.RefreshInterval <= 0 {
+		return fmt.Errorf("feature flags refresh interval must be positive")
+	}
+	if err := c.FeatureFlags.Flags.Validate(); err != nil {
+		return fmt.Errorf("invalid feature flags: %w", err)
+	}
+
+	if (c.Observability.Metrics.TLSCertFile == "") != (c.Observability.Metrics.TLSKeyFile == "") {
+		return fmt.Errorf("metrics TLS needs both a certificate and a key file")
+	}
+
+	if debug := c.Observability.Metrics.Debug; debug.Enabled {
+		if debug.Username == "" && len(debug.AllowedCIDRs) == 0 {
+			return fmt.Errorf("debug endpoints need credentials or allowed CIDRs")
+		}
+		if debug.Username != "" && debug.Password == "" {
+			return fmt.Errorf("debug endpoints password is required with a username")
+		}
+		for _, cidr := range debug.AllowedCIDRs {
+			if _, _, err := net.ParseCIDR(cidr); err != nil {
+				return fmt.Errorf("invalid debug allowed CIDR %q: %w", cidr, err)
+			}
+		}
+	}
+
 	return nil
 }
 
 // GetDatabaseDSN returns the database connection string
 func (c *Config) GetDatabaseDSN() string {
+	return c.DatabaseDSN(c.Database.Password)
+}
+
+// DatabaseDSN returns the database connection string with password in place
+// of the configured one, which may be a secret reference
+func (c *Config) DatabaseDSN(password string) string {
 	return fmt.Sprintf(
-		"host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
+		"host=%s port=%d user=%s password='%s' dbname=%s sslmode=%s",
 		c.Database.Host,
 		c.Database.Port,
 		c.Database.User,
-		c.Database.Password,
+		strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(password),
 		c.Database.Database,
 		c.Database.SSLMode,
 	)
@@ -2,71 +2,36 @@ package policy
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/url"
+	"regexp"
+	"slices"
 	"strings"
 	"time"
 
+	"github.com/org/llm-marketplace/pkg/marketplace"
+	"github.com/org/llm-marketplace/pkg/marketplace/flags"
+	"github.com/rs/zerolog/log"
+
+	"github.com/llm-marketplace/policy-engine/internal/budgets"
+	"github.com/llm-marketplace/policy-engine/internal/sanctions"
 	"github.com/llm-marketplace/policy-engine/internal/storage"
 )
 
-// ServiceRequest represents a service validation request
-type ServiceRequest struct {
-	ServiceID   string
-	Name        string
-	Version     string
-	Description string
-	ProviderID  string
-	Category    string
-	Endpoint    *EndpointInfo
-	Compliance  *ComplianceInfo
-	SLA         *SLAInfo
-	Pricing     *PricingInfo
-	Capabilities []Capability
-}
-
-// EndpointInfo represents service endpoint information
-type EndpointInfo struct {
-	URL            string
-	Protocol       string
-	Authentication string
-}
-
-// ComplianceInfo represents compliance information
-type ComplianceInfo struct {
-	Level           string
-	Certifications  []string
-	DataResidency   []string
-	GDPRCompliant   bool
-	HIPAACompliant  bool
-}
-
-// SLAInfo represents SLA information
-type SLAInfo struct {
-	Availability   float64
-	MaxLatency     int32
-	SupportLevel   string
-}
-
-// PricingInfo represents pricing information
-type PricingInfo struct {
-	Model    string
-	Currency string
-	Rates    []PricingTier
-}
-
-// PricingTier represents a pricing tier
-type PricingTier struct {
-	Tier        string
-	Rate        float64
-	Unit        string
-	Description string
-}
-
-// Capability represents a service capability
-type Capability struct {
-	Name        string
-	Description string
-}
+// ServiceRequest is the service being validated, described with the shared
+// marketplace types
+type ServiceRequest = marketplace.ServiceDescriptor
+
+// The request's sections under their names in this package
+type (
+	EndpointInfo   = marketplace.EndpointInfo
+	ComplianceInfo = marketplace.ComplianceInfo
+	SLAInfo        = marketplace.SLAInfo
+	PricingInfo    = marketplace.PricingInfo
+	PricingTier    = marketplace.PricingTier
+	Capability     = marketplace.Capability
+)
 
 // ValidationResult represents the result of policy validation
 type ValidationResult struct {
@@ -94,18 +59,83 @@ type Violation struct {
 
 // Validator performs policy validation
 type Validator struct {
-	store *storage.PolicyStore
+	store         PolicySource
+	subscriptions SubscriptionChecker
+	budgets       BudgetChecker
+	sanctions     SanctionsLists
+	flags         *flags.Evaluator
+}
+
+// PolicySource returns the policies to validate against, as
+// *storage.PolicyStore does
+type PolicySource interface {
+	GetEnabledPolicies(ctx context.Context) ([]*storage.Policy, error)
+	GetPoliciesByType(ctx context.Context, policyType string) ([]*storage.Policy, error)
+}
+
+// SubscriptionChecker reports whether a consumer holds an active
+// subscription to a service
+type SubscriptionChecker interface {
+	Active(ctx context.Context, consumerID, serviceID string) (bool, error)
+}
+
+// BudgetChecker returns a consumer's monthly budget status, or nil when it
+// has no budget
+type BudgetChecker interface {
+	Budget(ctx context.Context, consumerID string) (*budgets.Status, error)
 }
 
+// SanctionsLists returns the current version of a named sanctions list
+type SanctionsLists interface {
+	Lookup(name string) (*storage.SanctionsList, error)
+}
+
+// consumeAction is the CheckAccess action of calling a service
+const consumeAction = "consume"
+
 // NewValidator creates a new policy validator
-func NewValidator(store *storage.PolicyStore) *Validator {
+func NewValidator(store PolicySource) *Validator {
 	return &Validator{
 		store: store,
 	}
 }
 
-// ValidateService validates a service against all enabled policies
-func (v *Validator) ValidateService(ctx context.Context, req *ServiceRequest) (*ValidationResult, error) {
+// SetSubscriptions sets where access-control rules with require_subscription
+// look up subscriptions. Without it, those rules deny consumption.
+func (v *Validator) SetSubscriptions(checker SubscriptionChecker) {
+	v.subscriptions = checker
+}
+
+// SetBudgets sets where ValidateConsumption looks up consumers' budgets.
+// Without it, budgets are not enforced.
+func (v *Validator) SetBudgets(checker BudgetChecker) {
+	v.budgets = checker
+}
+
+// SetSanctions sets where the lists named by blocked_lists in DATA_RESIDENCY
+// rules are looked up. Without it, those rules only block the countries
+// they name in blocked_countries.
+func (v *Validator) SetSanctions(lists SanctionsLists) {
+	v.sanctions = lists
+}
+
+// SetFlags sets the feature flags gating each policy type's evaluation, named
+// by flags.PolicyEngine. Without them, every policy type is evaluated.
+func (v *Validator) SetFlags(evaluator *flags.Evaluator) {
+	v.flags = evaluator
+}
+
+// engineEnabled reports whether policies of policyType are evaluated for req.
+// Rollouts are by the service's tenant, then its provider, so all of a
+// provider's services are validated alike.
+func (v *Validator) engineEnabled(policyType string, req *ServiceRequest) bool {
+	return v.flags.Enabled(flags.PolicyEngine(policyType), flags.Subject{Tenant: req.TenantID, Key: req.ProviderID})
+}
+
+// ValidateService validates a service against all enabled policies. The
+// descriptors of the services it depends on are validated against them too,
+// and their violations reported on the service's dependencies field.
+func (v *Validator) ValidateService(ctx context.Context, req *ServiceRequest, dependencies ...*ServiceRequest) (*ValidationResult, error) {
 	startTime := time.Now()
 
 	result := &ValidationResult{
@@ -121,11 +151,16 @@ func (v *Validator) ValidateService(ctx context.Context, req *ServiceRequest) (*
 		return nil, fmt.Errorf("failed to get enabled policies: %w", err)
 	}
 
-	result.PoliciesEvaluated = len(policies)
-
-	// Validate against each policy
+	// Validate against each policy whose engine is on for the service
 	for _, policy := range policies {
+		if !v.engineEnabled(policy.Type, req) {
+			continue
+		}
+		result.PoliciesEvaluated++
 		violations := v.validateAgainstPolicy(policy, req)
+		for _, dep := range dependencies {
+			violations = append(violations, dependencyViolations(req, dep, v.validateAgainstPolicy(policy, dep))...)
+		}
 		if len(violations) > 0 {
 			result.Violations = append(result.Violations, violations...)
 			result.PoliciesFailed++
@@ -140,6 +175,18 @@ func (v *Validator) ValidateService(ctx context.Context, req *ServiceRequest) (*
 	return result, nil
 }
 
+// dependencyViolations reports the violations of a dependency of req as
+// violations of req
+func dependencyViolations(req, dep *ServiceRequest, violations []Violation) []Violation {
+	index := slices.IndexFunc(req.Dependencies, func(d marketplace.Dependency) bool { return d.ServiceID == dep.ServiceID })
+	for i := range violations {
+		violations[i].Message = fmt.Sprintf("Dependency %s is not compliant: %s", dep.Name, violations[i].Message)
+		violations[i].Remediation = fmt.Sprintf("Bring %s into compliance or depend on a compliant service instead", dep.Name)
+		violations[i].Field = fmt.Sprintf("dependencies[%d].%s", index, violations[i].Field)
+	}
+	return violations
+}
+
 func (v *Validator) validateAgainstPolicy(policy *storage.Policy, req *ServiceRequest) []Violation {
 	violations := []Violation{}
 
@@ -152,6 +199,12 @@ func (v *Validator) validateAgainstPolicy(policy *storage.Policy, req *ServiceRe
 		violations = v.validateSecurity(policy, req)
 	case "PRICING":
 		violations = v.validatePricing(policy, req)
+	case "PRIVATE_SERVICE":
+		violations = v.validatePrivateService(policy, req)
+	case "CONTENT_FILTERING":
+		violations = v.validateContentFiltering(policy, req)
+	case "LICENSE":
+		violations = v.validateLicense(policy, req)
 	}
 
 	return violations
@@ -224,6 +277,15 @@ func (v *Validator) validateDataResidency(policy *storage.Policy, req *ServiceRe
 		}
 	}
 
+	// Check countries on the sanctions lists the rule refers to
+	if blockedLists, ok := rule["blocked_lists"].([]interface{}); ok && req.Compliance != nil && len(req.Compliance.DataResidency) > 0 {
+		for _, name := range blockedLists {
+			if nameStr, ok := name.(string); ok {
+				violations = append(violations, v.sanctionsViolations(policy, nameStr, req.Compliance.DataResidency)...)
+			}
+		}
+	}
+
 	// Check allowed countries
 	if allowedCountries, ok := rule["allowed_countries"].([]interface{}); ok && req.Compliance != nil {
 		allowedMap := make(map[string]bool)
@@ -252,6 +314,52 @@ func (v *Validator) validateDataResidency(policy *storage.Policy, req *ServiceRe
 	return violations
 }
 
+// sanctionsViolations returns a violation for each of countries on the
+// current version of the named sanctions list. A list no feed is configured
+// for is skipped; one that hasn't been synced yet fails the rule, since the
+// countries can't be cleared.
+func (v *Validator) sanctionsViolations(policy *storage.Policy, name string, countries []string) []Violation {
+	if v.sanctions == nil {
+		log.Warn().Str("policy", policy.Name).Str("list", name).Msg("Sanctions lists aren't configured, skipping blocked list")
+		return nil
+	}
+	list, err := v.sanctions.Lookup(name)
+	if errors.Is(err, sanctions.ErrUnknownList) {
+		log.Warn().Str("policy", policy.Name).Str("list", name).Msg("No feed is configured for sanctions list, skipping blocked list")
+		return nil
+	}
+	if err != nil {
+		return []Violation{{
+			PolicyID:      policy.ID,
+			PolicyName:    policy.Name,
+			Severity:      policy.Severity,
+			Message:       fmt.Sprintf("Data residency cannot be checked against sanctions list %s: %v", name, err),
+			Remediation:   "Retry once the sanctions list has been synced",
+			Field:         "compliance.dataResidency",
+			ActualValue:   strings.Join(countries, ","),
+			ExpectedValue: fmt.Sprintf("not in sanctions list %s", name),
+		}}
+	}
+
+	var violations []Violation
+	for _, country := range countries {
+		if !slices.Contains(list.Countries, strings.ToUpper(country)) {
+			continue
+		}
+		violations = append(violations, Violation{
+			PolicyID:      policy.ID,
+			PolicyName:    policy.Name,
+			Severity:      policy.Severity,
+			Message:       fmt.Sprintf("Service cannot have data residency in sanctioned country: %s", country),
+			Remediation:   "Remove sanctioned countries from data residency list",
+			Field:         "compliance.dataResidency",
+			ActualValue:   country,
+			ExpectedValue: fmt.Sprintf("not in sanctions list %s version %d", list.Name, list.Version),
+		})
+	}
+	return violations
+}
+
 func (v *Validator) validateCompliance(policy *storage.Policy, req *ServiceRequest) []Violation {
 	violations := []Violation{}
 
@@ -260,10 +368,22 @@ func (v *Validator) validateCompliance(policy *storage.Policy, req *ServiceReque
 		return violations
 	}
 
+	// With require_artifacts, only certifications backed by an unexpired
+	// artifact count
+	var certifications []string
+	if req.Compliance != nil {
+		certifications = req.Compliance.Certifications
+		if requireArtifacts, ok := rule["require_artifacts"].(bool); ok && requireArtifacts {
+			var unverified []Violation
+			certifications, unverified = verifiedCertifications(policy, req.Compliance, time.Now())
+			violations = append(violations, unverified...)
+		}
+	}
+
 	// Check required certifications
 	if requiredCerts, ok := rule["required_certifications"].([]interface{}); ok && req.Compliance != nil {
 		certMap := make(map[string]bool)
-		for _, cert := range req.Compliance.Certifications {
+		for _, cert := range certifications {
 			certMap[strings.ToUpper(cert)] = true
 		}
 
@@ -277,7 +397,7 @@ func (v *Validator) validateCompliance(policy *storage.Policy, req *ServiceReque
 						Message:       fmt.Sprintf("Service is missing required certification: %s", certStr),
 						Remediation:   fmt.Sprintf("Add %s certification", certStr),
 						Field:         "compliance.certifications",
-						ActualValue:   strings.Join(req.Compliance.Certifications, ", "),
+						ActualValue:   strings.Join(certifications, ", "),
 						ExpectedValue: certStr,
 					})
 				}
@@ -287,15 +407,8 @@ func (v *Validator) validateCompliance(policy *storage.Policy, req *ServiceReque
 
 	// Check minimum compliance level
 	if minLevel, ok := rule["minimum_compliance_level"].(string); ok && req.Compliance != nil {
-		complianceLevels := map[string]int{
-			"public":       0,
-			"internal":     1,
-			"confidential": 2,
-			"restricted":   3,
-		}
-
-		minLevelNum := complianceLevels[minLevel]
-		actualLevelNum := complianceLevels[req.Compliance.Level]
+		minLevelNum := marketplace.ComplianceRank(minLevel)
+		actualLevelNum := marketplace.ComplianceRank(req.Compliance.Level)
 
 		if actualLevelNum < minLevelNum {
 			violations = append(violations, Violation{
@@ -346,6 +459,46 @@ func (v *Validator) validateCompliance(policy *storage.Policy, req *ServiceReque
 	return violations
 }
 
+// verifiedCertifications returns the declared certifications that have an
+// artifact unexpired at now, and a violation for each of the others
+func verifiedCertifications(policy *storage.Policy, compliance *ComplianceInfo, now time.Time) ([]string, []Violation) {
+	current := make(map[string]bool)
+	expired := make(map[string]bool)
+	for _, artifact := range compliance.Artifacts {
+		cert := strings.ToUpper(artifact.Certification)
+		if artifact.Current(now) {
+			current[cert] = true
+		} else {
+			expired[cert] = true
+		}
+	}
+
+	verified := []string{}
+	violations := []Violation{}
+	for _, cert := range compliance.Certifications {
+		key := strings.ToUpper(cert)
+		if current[key] {
+			verified = append(verified, cert)
+			continue
+		}
+		message, actual := fmt.Sprintf("Certification %s has no artifact on file", cert), "none"
+		if expired[key] {
+			message, actual = fmt.Sprintf("The artifact for certification %s has expired", cert), "expired"
+		}
+		violations = append(violations, Violation{
+			PolicyID:      policy.ID,
+			PolicyName:    policy.Name,
+			Severity:      policy.Severity,
+			Message:       message,
+			Remediation:   fmt.Sprintf("Upload a current %s artifact to the registry", cert),
+			Field:         "compliance.certifications",
+			ActualValue:   actual,
+			ExpectedValue: "an unexpired artifact",
+		})
+	}
+	return verified, violations
+}
+
 func (v *Validator) validateSecurity(policy *storage.Policy, req *ServiceRequest) []Violation {
 	violations := []Violation{}
 
@@ -442,7 +595,7 @@ func (v *Validator) validatePricing(policy *storage.Policy, req *ServiceRequest)
 	if requireFreeTier, ok := rule["require_free_tier"].(bool); ok && requireFreeTier {
 		if req.Pricing != nil {
 			hasFree := false
-			for _, tier := range req.Pricing.Rates {
+			for _, tier := range req.Pricing.Tiers {
 				if tier.Rate == 0 || strings.ToLower(tier.Tier) == "free" {
 					hasFree = true
 					break
@@ -455,7 +608,7 @@ func (v *Validator) validatePricing(policy *storage.Policy, req *ServiceRequest)
 					Severity:      policy.Severity,
 					Message:       "Service must offer a free tier",
 					Remediation:   "Add a free pricing tier",
-					Field:         "pricing.rates",
+					Field:         "pricing.tiers",
 					ActualValue:   "no free tier",
 					ExpectedValue: "at least one free tier",
 				})
@@ -466,8 +619,237 @@ func (v *Validator) validatePricing(policy *storage.Policy, req *ServiceRequest)
 	return violations
 }
 
+// validatePrivateService checks the rules for an enterprise's own model
+// endpoints. Public services are not checked.
+func (v *Validator) validatePrivateService(policy *storage.Policy, req *ServiceRequest) []Violation {
+	violations := []Violation{}
+
+	rule, ok := policy.Rule["private_service"].(map[string]interface{})
+	if !ok || !req.Private() {
+		return violations
+	}
+
+	violation := func(message, remediation, field, actual, expected string) {
+		violations = append(violations, Violation{
+			PolicyID:      policy.ID,
+			PolicyName:    policy.Name,
+			Severity:      policy.Severity,
+			Message:       message,
+			Remediation:   remediation,
+			Field:         field,
+			ActualValue:   actual,
+			ExpectedValue: expected,
+		})
+	}
+
+	// The endpoint is reachable by everyone in the tenant, so it must check
+	// who calls it
+	if requireAuth, ok := rule["require_authentication"].(bool); ok && requireAuth {
+		if req.Endpoint == nil || req.Endpoint.Authentication == "" {
+			violation("Private services must authenticate their callers",
+				"Add authentication configuration to endpoint",
+				"endpoint.authentication", "none", "api-key, oauth2, or jwt")
+		}
+	}
+
+	// Private endpoints are kept on the tenant's own network
+	if hosts, ok := rule["allowed_endpoint_hosts"].([]interface{}); ok && len(hosts) > 0 && req.Endpoint != nil {
+		host := ""
+		if u, err := url.Parse(req.Endpoint.URL); err == nil {
+			host = strings.ToLower(u.Hostname())
+		}
+		allowed := false
+		var names []string
+		for _, h := range hosts {
+			suffix, ok := h.(string)
+			if !ok {
+				continue
+			}
+			suffix = strings.ToLower(strings.TrimPrefix(suffix, "."))
+			names = append(names, suffix)
+			if host == suffix || strings.HasSuffix(host, "."+suffix) {
+				allowed = true
+			}
+		}
+		if !allowed {
+			violation(fmt.Sprintf("Private service endpoint host %s is not on an allowed network", host),
+				"Serve the model from a host in one of the allowed domains",
+				"endpoint.url", req.Endpoint.URL, "a host in "+strings.Join(names, ", "))
+		}
+	}
+
+	if minLevel, ok := rule["minimum_compliance_level"].(string); ok && minLevel != "" {
+		level := ""
+		if req.Compliance != nil {
+			level = req.Compliance.Level
+		}
+		if marketplace.ComplianceRank(level) < marketplace.ComplianceRank(minLevel) {
+			violation(fmt.Sprintf("Private services must be classified %s or above", minLevel),
+				"Set the compliance level the tenant's data requires",
+				"compliance.level", level, fmt.Sprintf("at least %s", minLevel))
+		}
+	}
+
+	return violations
+}
+
+// validateContentFiltering checks the text of prompt templates, which
+// consumers send to models as it is, for blocked terms and patterns such as
+// prompt injections. Services are not checked.
+func (v *Validator) validateContentFiltering(policy *storage.Policy, req *ServiceRequest) []Violation {
+	violations := []Violation{}
+
+	rule, ok := policy.Rule["content_filtering"].(map[string]interface{})
+	if !ok || !req.IsPromptTemplate() || req.PromptTemplate == nil {
+		return violations
+	}
+
+	violation := func(message, remediation, field, actual, expected string) {
+		violations = append(violations, Violation{
+			PolicyID:      policy.ID,
+			PolicyName:    policy.Name,
+			Severity:      policy.Severity,
+			Message:       message,
+			Remediation:   remediation,
+			Field:         field,
+			ActualValue:   actual,
+			ExpectedValue: expected,
+		})
+	}
+
+	template := req.PromptTemplate
+	if maxLength, ok := rule["max_template_length"].(float64); ok && maxLength > 0 && len(template.Template) > int(maxLength) {
+		violation(fmt.Sprintf("Prompt template is longer than %d bytes", int(maxLength)),
+			"Shorten the template",
+			"prompt_template.template", fmt.Sprintf("%d bytes", len(template.Template)), fmt.Sprintf("at most %d bytes", int(maxLength)))
+	}
+
+	// Defaults are filled into the template, so they are checked with it
+	text := []string{"prompt_template.template", template.Template}
+	for i, variable := range template.Variables {
+		text = append(text, fmt.Sprintf("prompt_template.variables[%d].default", i), variable.Default)
+	}
+
+	if terms, ok := rule["blocked_terms"].([]interface{}); ok {
+		for _, t := range terms {
+			term, ok := t.(string)
+			if !ok || term == "" {
+				continue
+			}
+			for i := 0; i < len(text); i += 2 {
+				if strings.Contains(strings.ToLower(text[i+1]), strings.ToLower(term)) {
+					violation(fmt.Sprintf("Prompt template contains blocked term %q", term),
+						"Remove the term from the template",
+						text[i], term, "no blocked terms")
+				}
+			}
+		}
+	}
+
+	if patterns, ok := rule["blocked_patterns"].([]interface{}); ok {
+		for _, p := range patterns {
+			pattern, ok := p.(string)
+			if !ok || pattern == "" {
+				continue
+			}
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				log.Warn().Err(err).Str("policy", policy.Name).Str("pattern", pattern).Msg("Invalid blocked pattern; skipping it")
+				continue
+			}
+			for i := 0; i < len(text); i += 2 {
+				if match := re.FindString(text[i+1]); match != "" {
+					violation("Prompt template matches a blocked pattern",
+						"Remove instructions that override the model's or the consumer's instructions",
+						text[i], match, "no match for "+pattern)
+				}
+			}
+		}
+	}
+
+	return violations
+}
+
+// validateLicense checks that a dataset is offered under an allowed license,
+// credits its source where the license requires attribution, and says where
+// its data came from. Services and prompt templates are not checked.
+func (v *Validator) validateLicense(policy *storage.Policy, req *ServiceRequest) []Violation {
+	violations := []Violation{}
+
+	rule, ok := policy.Rule["license"].(map[string]interface{})
+	if !ok || !req.IsDataset() || req.Dataset == nil {
+		return violations
+	}
+
+	violation := func(message, remediation, field, actual, expected string) {
+		violations = append(violations, Violation{
+			PolicyID:      policy.ID,
+			PolicyName:    policy.Name,
+			Severity:      policy.Severity,
+			Message:       message,
+			Remediation:   remediation,
+			Field:         field,
+			ActualValue:   actual,
+			ExpectedValue: expected,
+		})
+	}
+
+	// License identifiers are compared regardless of case, as SPDX does
+	listed := func(key string) ([]string, bool) {
+		values, ok := rule[key].([]interface{})
+		if !ok {
+			return nil, false
+		}
+		var licenses []string
+		found := false
+		for _, value := range values {
+			if license, ok := value.(string); ok {
+				licenses = append(licenses, license)
+				found = found || strings.EqualFold(license, req.Dataset.License)
+			}
+		}
+		return licenses, found
+	}
+
+	dataset := req.Dataset
+	if allowed, found := listed("allowed_licenses"); len(allowed) > 0 && !found {
+		violation(fmt.Sprintf("Dataset license %s is not allowed", dataset.License),
+			"Offer the dataset under one of the allowed licenses",
+			"dataset.license", dataset.License, strings.Join(allowed, ", "))
+	}
+
+	if _, found := listed("attribution_required"); found && strings.TrimSpace(dataset.Attribution) == "" {
+		violation(fmt.Sprintf("Datasets licensed under %s must state the attribution consumers give", dataset.License),
+			"Add the credit the license requires to dataset.attribution",
+			"dataset.attribution", "", "the required attribution")
+	}
+
+	if requireProvenance, ok := rule["require_provenance"].(bool); ok && requireProvenance {
+		if dataset.Provenance == nil || dataset.Provenance.Source == "" {
+			violation("Datasets must state where their data came from",
+				"Add the source and collection method to dataset.provenance",
+				"dataset.provenance.source", "", "the data's source")
+		}
+	}
+
+	return violations
+}
+
 // ValidateConsumption validates a consumption request
 func (v *Validator) ValidateConsumption(ctx context.Context, consumerID, serviceID string) (bool, string, error) {
+	// A spent hard-stop budget denies every service
+	if v.budgets != nil {
+		budget, err := v.budgets.Budget(ctx, consumerID)
+		switch {
+		case err != nil:
+			// Fail open: an unreachable metering service shouldn't stop all
+			// consumption, and calls are still metered
+			log.Warn().Err(err).Str("consumer_id", consumerID).Msg("Failed to look up budget; not enforcing it")
+		case budget != nil && budget.Blocked:
+			return false, fmt.Sprintf("Consumer %s has spent its monthly budget of %.2f %s", consumerID, budget.MonthlyCap, budget.Currency), nil
+		}
+	}
+
 	// Get access control policies
 	policies, err := v.store.GetPoliciesByType(ctx, "ACCESS_CONTROL")
 	if err != nil {
@@ -486,6 +868,11 @@ func (v *Validator) ValidateConsumption(ctx context.Context, consumerID, service
 			continue
 		}
 
+		// Check blocked services
+		if listed(rule, "blocked_service_ids", serviceID) {
+			return false, fmt.Sprintf("Service %s is blocked by policy %s", serviceID, policy.Name), nil
+		}
+
 		// Check blocked users
 		if blockedUsers, ok := rule["blocked_user_ids"].([]interface{}); ok {
 			for _, blocked := range blockedUsers {
@@ -520,6 +907,7 @@ func (v *Validator) CheckAccess(ctx context.Context, userID, serviceID, action s
 
 	requiredPermissions := []string{}
 	missingPermissions := []string{}
+	var subscriptionPolicy string
 
 	// Validate against access control policies
 	for _, policy := range policies {
@@ -528,6 +916,11 @@ func (v *Validator) CheckAccess(ctx context.Context, userID, serviceID, action s
 			continue
 		}
 
+		// Check blocked services
+		if listed(rule, "blocked_service_ids", serviceID) {
+			return false, fmt.Sprintf("Service %s is blocked by policy %s", serviceID, policy.Name), requiredPermissions, missingPermissions, nil
+		}
+
 		// Check blocked users
 		if blockedUsers, ok := rule["blocked_user_ids"].([]interface{}); ok {
 			for _, blocked := range blockedUsers {
@@ -546,7 +939,39 @@ func (v *Validator) CheckAccess(ctx context.Context, userID, serviceID, action s
 				}
 			}
 		}
+
+		// Check required subscription
+		if requireSub, ok := rule["require_subscription"].(bool); ok && requireSub && action == consumeAction && subscriptionPolicy == "" {
+			subscriptionPolicy = policy.Name
+		}
+	}
+
+	if subscriptionPolicy != "" {
+		requiredPermissions = append(requiredPermissions, "subscription")
+		subscribed := false
+		if v.subscriptions != nil {
+			// A failed lookup fails the check rather than allowing the call
+			subscribed, err = v.subscriptions.Active(ctx, userID, serviceID)
+			if err != nil {
+				return false, fmt.Sprintf("failed to check subscription: %v", err), nil, nil, err
+			}
+		}
+		if !subscribed {
+			missingPermissions = append(missingPermissions, "subscription")
+			return false, fmt.Sprintf("Policy %s requires an active subscription to service %s", subscriptionPolicy, serviceID), requiredPermissions, missingPermissions, nil
+		}
 	}
 
 	return true, "", requiredPermissions, missingPermissions, nil
 }
+
+// listed reports whether the list under key in a rule contains id
+func listed(rule map[string]interface{}, key, id string) bool {
+	values, _ := rule[key].([]interface{})
+	for _, value := range values {
+		if s, ok := value.(string); ok && s == id {
+			return true
+		}
+	}
+	return false
+}
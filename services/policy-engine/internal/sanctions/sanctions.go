@@ -0,0 +1,246 @@
+// Package sanctions keeps lists of sanctioned and embargoed countries in sync
+// with external feeds. DATA_RESIDENCY rules refer to a list by name with
+// blocked_lists instead of naming the countries themselves, so a change to a
+// feed applies to every rule without editing policies.
+//
+// A feed answers with either a JSON array of country codes, a JSON object
+// with a countries array, or plain text with one code per line and # comments.
+// Each change to a feed is saved as a new version of its list.
+package sanctions
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/llm-marketplace/policy-engine/internal/config"
+	"github.com/llm-marketplace/policy-engine/internal/storage"
+)
+
+// ErrUnknownList is returned for a list no feed is configured for
+var ErrUnknownList = errors.New("unknown sanctions list")
+
+// ErrNotSynced is returned for a configured list that hasn't been synced yet
+var ErrNotSynced = errors.New("sanctions list not synced")
+
+// maxFeedSize bounds how much of a feed response is read
+const maxFeedSize = 1 << 20
+
+// Store keeps the versions of sanctions lists
+type Store interface {
+	Latest(ctx context.Context, name string) (*storage.SanctionsList, error)
+	Save(ctx context.Context, list *storage.SanctionsList) error
+}
+
+// Syncer fetches the configured feeds and holds the current version of each
+// list
+type Syncer struct {
+	feeds    []config.SanctionsFeedConfig
+	interval time.Duration
+	store    Store
+	client   *http.Client
+
+	mu    sync.RWMutex
+	lists map[string]*storage.SanctionsList
+}
+
+// NewSyncer creates a syncer of the feeds in cfg
+func NewSyncer(cfg config.SanctionsConfig, store Store) *Syncer {
+	return &Syncer{
+		feeds:    cfg.Feeds,
+		interval: cfg.SyncInterval,
+		store:    store,
+		client:   &http.Client{Timeout: cfg.Timeout},
+		lists:    make(map[string]*storage.SanctionsList),
+	}
+}
+
+// Lookup returns the current version of the named list
+func (s *Syncer) Lookup(name string) (*storage.SanctionsList, error) {
+	s.mu.RLock()
+	list, ok := s.lists[name]
+	s.mu.RUnlock()
+	if ok {
+		return list, nil
+	}
+	for _, feed := range s.feeds {
+		if feed.Name == name {
+			return nil, fmt.Errorf("%w: %s", ErrNotSynced, name)
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnknownList, name)
+}
+
+// Load reads the newest saved version of each list, so they are enforced
+// before a feed has been reached
+func (s *Syncer) Load(ctx context.Context) error {
+	for _, feed := range s.feeds {
+		list, err := s.store.Latest(ctx, feed.Name)
+		if err != nil {
+			return err
+		}
+		if list != nil {
+			s.set(list)
+		}
+	}
+	return nil
+}
+
+// Sync fetches every feed and saves a new version of each list whose feed
+// changed. A feed that can't be fetched or parsed keeps its list's current
+// version.
+func (s *Syncer) Sync(ctx context.Context) {
+	for _, feed := range s.feeds {
+		if err := s.syncFeed(ctx, feed); err != nil {
+			log.Warn().Err(err).Str("list", feed.Name).Msg("Failed to sync sanctions list, keeping the current version")
+		}
+	}
+}
+
+func (s *Syncer) syncFeed(ctx context.Context, feed config.SanctionsFeedConfig) error {
+	body, err := s.fetch(ctx, feed.URL)
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(body)
+	digest := hex.EncodeToString(sum[:])
+
+	current, _ := s.Lookup(feed.Name)
+	if current != nil && current.SHA256 == digest {
+		return nil
+	}
+	countries, err := parseFeed(body)
+	if err != nil {
+		return fmt.Errorf("invalid sanctions feed %s: %w", feed.URL, err)
+	}
+
+	list := &storage.SanctionsList{
+		Name:      feed.Name,
+		Countries: countries,
+		Source:    feed.URL,
+		SHA256:    digest,
+		FetchedAt: time.Now().UTC(),
+	}
+	if err := s.store.Save(ctx, list); err != nil {
+		return err
+	}
+	s.set(list)
+
+	event := log.Info().
+		Str("list", list.Name).
+		Int64("version", list.Version).
+		Strs("countries", list.Countries).
+		Str("sha256", list.SHA256)
+	if current != nil {
+		event = event.Int64("previous_version", current.Version).Strs("previous_countries", current.Countries)
+	}
+	event.Msg("Sanctions list updated")
+	return nil
+}
+
+func (s *Syncer) fetch(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch sanctions feed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("sanctions feed returned %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxFeedSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sanctions feed: %w", err)
+	}
+	if len(body) > maxFeedSize {
+		return nil, fmt.Errorf("sanctions feed is larger than %d bytes", maxFeedSize)
+	}
+	return body, nil
+}
+
+func (s *Syncer) set(list *storage.SanctionsList) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lists[list.Name] = list
+}
+
+// Start syncs the feeds now and then every sync interval until ctx is
+// cancelled
+func (s *Syncer) Start(ctx context.Context) {
+	s.Sync(ctx)
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.Sync(ctx)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// parseFeed returns the sorted, upper-cased country codes in body. An empty
+// feed is rejected rather than lifting every sanction.
+func parseFeed(body []byte) ([]string, error) {
+	var codes []string
+	switch trimmed := bytes.TrimSpace(body); {
+	case bytes.HasPrefix(trimmed, []byte("[")):
+		if err := json.Unmarshal(trimmed, &codes); err != nil {
+			return nil, err
+		}
+	case bytes.HasPrefix(trimmed, []byte("{")):
+		var doc struct {
+			Countries []string `json:"countries"`
+		}
+		if err := json.Unmarshal(trimmed, &doc); err != nil {
+			return nil, err
+		}
+		codes = doc.Countries
+	default:
+		scanner := bufio.NewScanner(bytes.NewReader(trimmed))
+		for scanner.Scan() {
+			line, _, _ := strings.Cut(scanner.Text(), "#")
+			if line = strings.TrimSpace(line); line != "" {
+				codes = append(codes, line)
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	countries := make([]string, 0, len(codes))
+	for _, code := range codes {
+		code = strings.ToUpper(strings.TrimSpace(code))
+		if len(code) != 2 || code[0] < 'A' || code[0] > 'Z' || code[1] < 'A' || code[1] > 'Z' {
+			return nil, fmt.Errorf("%q is not a two-letter country code", code)
+		}
+		countries = append(countries, code)
+	}
+	if len(countries) == 0 {
+		return nil, errors.New("no countries listed")
+	}
+	slices.Sort(countries)
+	return slices.Compact(countries), nil
+}
@@ -2,10 +2,12 @@ package server
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/org/llm-marketplace/pkg/marketplace"
 	"github.com/rs/zerolog/log"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
@@ -40,67 +42,17 @@ func (s *PolicyEngineServer) ValidateService(ctx context.Context, req *pb.Valida
 		Msg("Validating service")
 
 	// Convert protobuf request to internal ServiceRequest
-	serviceReq := &policy.ServiceRequest{
-		ServiceID:   req.ServiceId,
-		Name:        req.Name,
-		Version:     req.Version,
-		Description: req.Description,
-		ProviderID:  req.ProviderId,
-		Category:    req.Category,
-	}
-
-	if req.Endpoint != nil {
-		serviceReq.Endpoint = &policy.EndpointInfo{
-			URL:            req.Endpoint.Url,
-			Protocol:       req.Endpoint.Protocol,
-			Authentication: req.Endpoint.Authentication,
-		}
-	}
-
-	if req.Compliance != nil {
-		serviceReq.Compliance = &policy.ComplianceInfo{
-			Level:           req.Compliance.Level,
-			Certifications:  req.Compliance.Certifications,
-			DataResidency:   req.Compliance.DataResidency,
-			GDPRCompliant:   req.Compliance.GdprCompliant,
-			HIPAACompliant:  req.Compliance.HipaaCompliant,
-		}
-	}
-
-	if req.Sla != nil {
-		serviceReq.SLA = &policy.SLAInfo{
-			Availability:   req.Sla.Availability,
-			MaxLatency:     req.Sla.MaxLatency,
-			SupportLevel:   req.Sla.SupportLevel,
-		}
-	}
-
-	if req.Pricing != nil {
-		rates := make([]policy.PricingTier, len(req.Pricing.Rates))
-		for i, rate := range req.Pricing.Rates {
-			rates[i] = policy.PricingTier{
-				Tier:        rate.Tier,
-				Rate:        rate.Rate,
-				Unit:        rate.Unit,
-				Description: rate.Description,
-			}
-		}
-		serviceReq.Pricing = &policy.PricingInfo{
-			Model:    req.Pricing.Model,
-			Currency: req.Pricing.Currency,
-			Rates:    rates,
-		}
+	serviceReq := serviceFromProto(req)
+	if err := serviceReq.Validate(); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
 	}
-
-	for _, cap := range req.Capabilities {
-		serviceReq.Capabilities = append(serviceReq.Capabilities, policy.Capability{
-			Name:        cap.Name,
-			Description: cap.Description,
-		})
+	dependencies := make([]*policy.ServiceRequest, 0, len(req.DependencyServices))
+	for _, dep := range req.DependencyServices {
+		dependencies = append(dependencies, serviceFromProto(dep))
 	}
 
 	// Validate the service
-	result, err := s.validator.ValidateService(ctx, serviceReq)
+	result, err := s.validator.ValidateService(ctx, serviceReq, dependencies...)
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to validate service")
 		return nil, status.Errorf(codes.Internal, "validation failed: %v", err)
@@ -314,6 +266,9 @@ func (s *PolicyEngineServer) DeletePolicy(ctx context.Context, req *pb.DeletePol
 	log.Info().Str("policy_id", req.PolicyId).Msg("Deleting policy")
 
 	if err := s.store.Delete(ctx, req.PolicyId); err != nil {
+		if errors.Is(err, storage.ErrPolicyNotFound) {
+			return nil, status.Errorf(codes.NotFound, "%v", err)
+		}
 		log.Error().Err(err).Msg("Failed to delete policy")
 		return nil, status.Errorf(codes.Internal, "failed to delete policy: %v", err)
 	}
@@ -383,6 +338,60 @@ func convertProtoToPolicy(proto *pb.Policy) *storage.Policy {
 	}
 }
 
+// serviceFromProto converts a validation request to the service it describes
+func serviceFromProto(req *pb.ValidateServiceRequest) *policy.ServiceRequest {
+	serviceReq := &policy.ServiceRequest{
+		ServiceID:   req.ServiceId,
+		Name:        req.Name,
+		Version:     req.Version,
+		Description: req.Description,
+		ProviderID:  req.ProviderId,
+		Category:    req.Category,
+		Class:       req.ServiceClass,
+		TenantID:    req.TenantId,
+		Kind:        req.Kind,
+	}
+
+	if req.Endpoint != nil {
+		serviceReq.Endpoint = marketplace.EndpointFromProto(req.Endpoint)
+	}
+	if req.Compliance != nil {
+		serviceReq.Compliance = marketplace.ComplianceFromProto(req.Compliance)
+		for _, a := range req.Compliance.Artifacts {
+			serviceReq.Compliance.Artifacts = append(serviceReq.Compliance.Artifacts, marketplace.ComplianceArtifact{
+				Certification: a.Certification,
+				SHA256:        a.Sha256,
+				ExpiresAt:     a.ExpiresAt.AsTime(),
+			})
+		}
+	}
+	if req.Sla != nil {
+		serviceReq.SLA = marketplace.SLAFromProto(req.Sla)
+	}
+	if req.Pricing != nil {
+		serviceReq.Pricing = marketplace.PricingFromProto[*pb.PricingTier](req.Pricing)
+	}
+	for _, cap := range req.Capabilities {
+		serviceReq.Capabilities = append(serviceReq.Capabilities, marketplace.CapabilityFromProto(cap))
+	}
+	for _, dep := range req.Dependencies {
+		serviceReq.Dependencies = append(serviceReq.Dependencies, marketplace.DependencyFromProto(dep))
+	}
+	for _, region := range req.ServingRegions {
+		serviceReq.ServingRegions = append(serviceReq.ServingRegions, marketplace.ServingRegionFromProto(region))
+	}
+	if req.PromptTemplate != nil {
+		serviceReq.PromptTemplate = marketplace.PromptTemplateFromProto[*pb.TemplateVariable](req.PromptTemplate)
+	}
+	if req.Dataset != nil {
+		serviceReq.Dataset = marketplace.DatasetFromProto[*pb.DatasetProvenance](req.Dataset)
+	}
+	if req.FineTuning != nil {
+		serviceReq.FineTuning = marketplace.FineTuningFromProto[*pb.FineTuningDataRequirements](req.FineTuning)
+	}
+	return serviceReq
+}
+
 func convertPolicyTypeToProto(t string) pb.PolicyType {
 	switch t {
 	case "DATA_RESIDENCY":
@@ -401,6 +410,10 @@ func convertPolicyTypeToProto(t string) pb.PolicyType {
 		return pb.PolicyType_CONTENT_FILTERING
 	case "DATA_CLASSIFICATION":
 		return pb.PolicyType_DATA_CLASSIFICATION
+	case "PRIVATE_SERVICE":
+		return pb.PolicyType_PRIVATE_SERVICE
+	case "LICENSE":
+		return pb.PolicyType_LICENSE
 	default:
 		return pb.PolicyType_POLICY_TYPE_UNSPECIFIED
 	}
@@ -424,17 +437,65 @@ func convertProtoToPolicyType(t pb.PolicyType) string {
 		return "CONTENT_FILTERING"
 	case pb.PolicyType_DATA_CLASSIFICATION:
 		return "DATA_CLASSIFICATION"
+	case pb.PolicyType_PRIVATE_SERVICE:
+		return "PRIVATE_SERVICE"
+	case pb.PolicyType_LICENSE:
+		return "LICENSE"
 	default:
 		return "POLICY_TYPE_UNSPECIFIED"
 	}
 }
 
+// Access-control rules are converted in full, since the registry creates
+// them to take services down; other rules are not converted yet.
+
 func convertRuleToProto(rule map[string]interface{}) *pb.PolicyRule {
+	if ac, ok := rule["access_control"].(map[string]interface{}); ok {
+		requireApproval, _ := ac["require_approval"].(bool)
+		return &pb.PolicyRule{Rule: &pb.PolicyRule_AccessControl{AccessControl: &pb.AccessControlRule{
+			AllowedUserRoles:  ruleStrings(ac, "allowed_user_roles"),
+			BlockedUserIds:    ruleStrings(ac, "blocked_user_ids"),
+			RequireApproval:   requireApproval,
+			AllowedIpRanges:   ruleStrings(ac, "allowed_ip_ranges"),
+			BlockedServiceIds: ruleStrings(ac, "blocked_service_ids"),
+		}}}
+	}
 	// Simplified conversion - in production, properly marshal/unmarshal
 	return &pb.PolicyRule{}
 }
 
 func convertProtoToRule(rule *pb.PolicyRule) map[string]interface{} {
+	if ac := rule.GetAccessControl(); ac != nil {
+		// Lists are stored as the []interface{} JSON decodes them to, which
+		// the validator expects
+		return map[string]interface{}{"access_control": map[string]interface{}{
+			"allowed_user_roles":  ruleList(ac.AllowedUserRoles),
+			"blocked_user_ids":    ruleList(ac.BlockedUserIds),
+			"require_approval":    ac.RequireApproval,
+			"allowed_ip_ranges":   ruleList(ac.AllowedIpRanges),
+			"blocked_service_ids": ruleList(ac.BlockedServiceIds),
+		}}
+	}
 	// Simplified conversion - in production, properly marshal/unmarshal
 	return make(map[string]interface{})
 }
+
+// ruleStrings returns the strings listed under key in a rule
+func ruleStrings(rule map[string]interface{}, key string) []string {
+	values, _ := rule[key].([]interface{})
+	strs := make([]string, 0, len(values))
+	for _, v := range values {
+		if s, ok := v.(string); ok {
+			strs = append(strs, s)
+		}
+	}
+	return strs
+}
+
+func ruleList(strs []string) []interface{} {
+	values := make([]interface{}, len(strs))
+	for i, s := range strs {
+		values[i] = s
+	}
+	return values
+}
@@ -0,0 +1,147 @@
+package storage
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// memoryPolicies holds policies for a store without a database, as in
+// sandbox mode. Contents are lost when the process exits.
+type memoryPolicies struct {
+	mu       sync.RWMutex
+	policies map[string]*Policy
+}
+
+// NewMemoryPolicyStore creates a policy store that keeps policies in memory
+// instead of PostgreSQL
+func NewMemoryPolicyStore() *PolicyStore {
+	return &PolicyStore{
+		mem:    &memoryPolicies{policies: make(map[string]*Policy)},
+		stopCh: make(chan struct{}),
+	}
+}
+
+func (m *memoryPolicies) exists(name string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, p := range m.policies {
+		if p.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *memoryPolicies) create(policy *Policy) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if policy.ID == "" {
+		policy.ID = uuid.New().String()
+	}
+	if _, ok := m.policies[policy.ID]; ok {
+		return fmt.Errorf("failed to create policy: duplicate id %s", policy.ID)
+	}
+	for _, p := range m.policies {
+		if p.Name == policy.Name {
+			return fmt.Errorf("failed to create policy: duplicate name %s", policy.Name)
+		}
+	}
+	now := time.Now()
+	policy.CreatedAt, policy.UpdatedAt = now, now
+	stored := *policy
+	m.policies[policy.ID] = &stored
+	return nil
+}
+
+func (m *memoryPolicies) get(id string) (*Policy, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	p, ok := m.policies[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrPolicyNotFound, id)
+	}
+	policy := *p
+	return &policy, nil
+}
+
+// list applies the filters List takes, newest first
+func (m *memoryPolicies) list(filter map[string]interface{}) []*Policy {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	policyType, _ := filter["type"].(string)
+	enabled, filterEnabled := filter["enabled"].(bool)
+	severity, _ := filter["severity"].(string)
+
+	policies := []*Policy{}
+	for _, p := range m.policies {
+		if policyType != "" && p.Type != policyType ||
+			filterEnabled && p.Enabled != enabled ||
+			severity != "" && p.Severity != severity {
+			continue
+		}
+		policy := *p
+		policies = append(policies, &policy)
+	}
+	sort.Slice(policies, func(i, j int) bool {
+		return policies[i].CreatedAt.After(policies[j].CreatedAt)
+	})
+	return policies
+}
+
+func (m *memoryPolicies) update(policy *Policy) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	existing, ok := m.policies[policy.ID]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrPolicyNotFound, policy.ID)
+	}
+	policy.CreatedAt = existing.CreatedAt
+	policy.UpdatedAt = time.Now()
+	stored := *policy
+	m.policies[policy.ID] = &stored
+	return nil
+}
+
+func (m *memoryPolicies) delete(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.policies[id]; !ok {
+		return fmt.Errorf("%w: %s", ErrPolicyNotFound, id)
+	}
+	delete(m.policies, id)
+	return nil
+}
+
+// memorySanctions holds every version of each sanctions list, oldest first
+type memorySanctions struct {
+	mu    sync.Mutex
+	lists map[string][]SanctionsList
+}
+
+// NewMemorySanctionsStore creates a sanctions store that keeps lists in
+// memory instead of PostgreSQL
+func NewMemorySanctionsStore() *SanctionsStore {
+	return &SanctionsStore{mem: &memorySanctions{lists: make(map[string][]SanctionsList)}}
+}
+
+func (m *memorySanctions) latest(name string) *SanctionsList {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	versions := m.lists[name]
+	if len(versions) == 0 {
+		return nil
+	}
+	list := versions[len(versions)-1]
+	return &list
+}
+
+func (m *memorySanctions) save(list *SanctionsList) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	list.Version = int64(len(m.lists[list.Name]) + 1)
+	m.lists[list.Name] = append(m.lists[list.Name], *list)
+}
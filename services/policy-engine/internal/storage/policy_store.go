@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"sync"
 	"time"
@@ -12,6 +13,9 @@ import (
 	_ "github.com/lib/pq"
 )
 
+// ErrPolicyNotFound is returned for a policy that doesn't exist
+var ErrPolicyNotFound = errors.New("policy not found")
+
 // Policy represents a policy in the system
 type Policy struct {
 	ID          string                 `json:"id"`
@@ -30,6 +34,7 @@ type Policy struct {
 // PolicyStore manages policy storage and retrieval
 type PolicyStore struct {
 	db           *sql.DB
+	mem          *memoryPolicies // Set instead of db by NewMemoryPolicyStore
 	cache        *PolicyCache
 	enableCache  bool
 	mu           sync.RWMutex
@@ -67,6 +72,10 @@ func NewPolicyStore(db *sql.DB, enableCache bool, cacheTTL time.Duration, cacheM
 
 // Initialize creates the policies table if it doesn't exist
 func (s *PolicyStore) Initialize(ctx context.Context) error {
+	if s.mem != nil {
+		return nil
+	}
+
 	query := `
 		CREATE TABLE IF NOT EXISTS policies (
 			id UUID PRIMARY KEY,
@@ -135,7 +144,7 @@ func (s *PolicyStore) SeedDefaultPolicies(ctx context.Context) error {
 			Severity:    "critical",
 			Rule: map[string]interface{}{
 				"data_residency": map[string]interface{}{
-					"blocked_countries": []string{"KP", "IR", "SY", "CU"},
+					"blocked_lists": []string{"sanctions"}, // Synced from the sanctions feed
 				},
 			},
 			Metadata: map[string]string{
@@ -198,13 +207,78 @@ func (s *PolicyStore) SeedDefaultPolicies(ctx context.Context) error {
 			},
 			Version: "1.0.0",
 		},
+		{
+			ID:          uuid.New().String(),
+			Name:        "private-service-isolation",
+			Description: "Private services must authenticate callers and be classified internal or above",
+			Type:        "PRIVATE_SERVICE",
+			Enabled:     true,
+			Severity:    "high",
+			Rule: map[string]interface{}{
+				"private_service": map[string]interface{}{
+					"require_authentication":   true,
+					"minimum_compliance_level": "internal",
+				},
+			},
+			Metadata: map[string]string{
+				"category": "security",
+			},
+			Version: "1.0.0",
+		},
+		{
+			ID:          uuid.New().String(),
+			Name:        "prompt-template-content-filter",
+			Description: "Prompt templates must not try to override the model's instructions",
+			Type:        "CONTENT_FILTERING",
+			Enabled:     true,
+			Severity:    "high",
+			Rule: map[string]interface{}{
+				"content_filtering": map[string]interface{}{
+					"max_template_length": 16384,
+					"blocked_patterns": []string{
+						`(?i)\bignore\s+(all\s+)?(the\s+)?(previous|prior|above)\s+instructions\b`,
+						`(?i)\bdisregard\s+(your|the)\s+system\s+prompt\b`,
+						`(?i)\breveal\s+(your|the)\s+system\s+prompt\b`,
+					},
+				},
+			},
+			Metadata: map[string]string{
+				"category": "content",
+			},
+			Version: "1.0.0",
+		},
+		{
+			ID:          uuid.New().String(),
+			Name:        "dataset-license-compliance",
+			Description: "Datasets must be openly licensed or commercially licensed by reference, credit their sources where the license requires it, and state their provenance",
+			Type:        "LICENSE",
+			Enabled:     true,
+			Severity:    "high",
+			Rule: map[string]interface{}{
+				"license": map[string]interface{}{
+					"allowed_licenses": []string{
+						"CC0-1.0", "CC-BY-4.0", "CC-BY-SA-4.0", "ODC-By-1.0", "ODbL-1.0", "PDDL-1.0",
+						"CDLA-Permissive-2.0", "CDLA-Sharing-1.0", "Apache-2.0", "MIT", "LicenseRef-Commercial",
+					},
+					"attribution_required": []string{
+						"CC-BY-4.0", "CC-BY-SA-4.0", "ODC-By-1.0", "ODbL-1.0", "CDLA-Sharing-1.0",
+					},
+					"require_provenance": true,
+				},
+			},
+			Metadata: map[string]string{
+				"category": "licensing",
+			},
+			Version: "1.0.0",
+		},
 	}
 
 	for _, policy := range defaultPolicies {
 		// Check if policy already exists
 		var exists bool
-		err := s.db.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM policies WHERE name = $1)", policy.Name).Scan(&exists)
-		if err != nil {
+		if s.mem != nil {
+			exists = s.mem.exists(policy.Name)
+		} else if err := s.db.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM policies WHERE name = $1)", policy.Name).Scan(&exists); err != nil {
 			return fmt.Errorf("failed to check policy existence: %w", err)
 		}
 
@@ -220,6 +294,9 @@ func (s *PolicyStore) SeedDefaultPolicies(ctx context.Context) error {
 
 // Create creates a new policy
 func (s *PolicyStore) Create(ctx context.Context, policy *Policy) error {
+	if s.mem != nil {
+		return s.mem.create(policy)
+	}
 	if policy.ID == "" {
 		policy.ID = uuid.New().String()
 	}
@@ -270,6 +347,10 @@ func (s *PolicyStore) Create(ctx context.Context, policy *Policy) error {
 
 // Get retrieves a policy by ID
 func (s *PolicyStore) Get(ctx context.Context, id string) (*Policy, error) {
+	if s.mem != nil {
+		return s.mem.get(id)
+	}
+
 	// Check cache first
 	if s.enableCache {
 		s.cache.mu.RLock()
@@ -305,7 +386,7 @@ func (s *PolicyStore) Get(ctx context.Context, id string) (*Policy, error) {
 	)
 
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("policy not found: %s", id)
+		return nil, fmt.Errorf("%w: %s", ErrPolicyNotFound, id)
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get policy: %w", err)
@@ -331,6 +412,10 @@ func (s *PolicyStore) Get(ctx context.Context, id string) (*Policy, error) {
 
 // List retrieves all policies with optional filtering
 func (s *PolicyStore) List(ctx context.Context, filter map[string]interface{}) ([]*Policy, error) {
+	if s.mem != nil {
+		return s.mem.list(filter), nil
+	}
+
 	query := `
 		SELECT id, name, description, type, enabled, severity, rule, metadata, created_at, updated_at, version
 		FROM policies
@@ -404,6 +489,10 @@ func (s *PolicyStore) List(ctx context.Context, filter map[string]interface{}) (
 
 // Update updates an existing policy
 func (s *PolicyStore) Update(ctx context.Context, policy *Policy) error {
+	if s.mem != nil {
+		return s.mem.update(policy)
+	}
+
 	ruleJSON, err := json.Marshal(policy.Rule)
 	if err != nil {
 		return fmt.Errorf("failed to marshal rule: %w", err)
@@ -436,7 +525,7 @@ func (s *PolicyStore) Update(ctx context.Context, policy *Policy) error {
 	).Scan(&policy.UpdatedAt)
 
 	if err == sql.ErrNoRows {
-		return fmt.Errorf("policy not found: %s", policy.ID)
+		return fmt.Errorf("%w: %s", ErrPolicyNotFound, policy.ID)
 	}
 	if err != nil {
 		return fmt.Errorf("failed to update policy: %w", err)
@@ -454,6 +543,10 @@ func (s *PolicyStore) Update(ctx context.Context, policy *Policy) error {
 
 // Delete deletes a policy
 func (s *PolicyStore) Delete(ctx context.Context, id string) error {
+	if s.mem != nil {
+		return s.mem.delete(id)
+	}
+
 	query := "DELETE FROM policies WHERE id = $1"
 
 	result, err := s.db.ExecContext(ctx, query, id)
@@ -467,7 +560,7 @@ func (s *PolicyStore) Delete(ctx context.Context, id string) error {
 	}
 
 	if rows == 0 {
-		return fmt.Errorf("policy not found: %s", id)
+		return fmt.Errorf("%w: %s", ErrPolicyNotFound, id)
 	}
 
 	// Invalidate cache
@@ -496,6 +589,9 @@ func (s *PolicyStore) Close() error {
 		s.reloadTicker.Stop()
 		close(s.stopCh)
 	}
+	if s.mem != nil {
+		return nil
+	}
 	return s.db.Close()
 }
 
@@ -0,0 +1,116 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// SanctionsList is one synced version of a named list of sanctioned or
+// embargoed countries. Every version is kept, so which countries a service
+// was checked against can be traced back to the feed it came from.
+type SanctionsList struct {
+	Name      string    `json:"name"`
+	Version   int64     `json:"version"`
+	Countries []string  `json:"countries"` // ISO 3166-1 alpha-2 codes
+	Source    string    `json:"source"`
+	SHA256    string    `json:"sha256"` // Of the feed response the countries were read from
+	FetchedAt time.Time `json:"fetched_at"`
+}
+
+// SanctionsStore keeps the versions of sanctions lists
+type SanctionsStore struct {
+	db  *sql.DB
+	mem *memorySanctions // Set instead of db by NewMemorySanctionsStore
+}
+
+// NewSanctionsStore creates a sanctions store
+func NewSanctionsStore(db *sql.DB) *SanctionsStore {
+	return &SanctionsStore{db: db}
+}
+
+// Initialize creates the sanctions_lists table if it doesn't exist
+func (s *SanctionsStore) Initialize(ctx context.Context) error {
+	if s.mem != nil {
+		return nil
+	}
+
+	query := `
+		CREATE TABLE IF NOT EXISTS sanctions_lists (
+			name VARCHAR(255) NOT NULL,
+			version BIGINT NOT NULL,
+			countries JSONB NOT NULL,
+			source TEXT NOT NULL,
+			sha256 CHAR(64) NOT NULL,
+			fetched_at TIMESTAMP WITH TIME ZONE NOT NULL,
+			PRIMARY KEY (name, version)
+		);
+	`
+
+	_, err := s.db.ExecContext(ctx, query)
+	return err
+}
+
+// Latest returns the newest version of the named list, or nil when it has
+// never been synced
+func (s *SanctionsStore) Latest(ctx context.Context, name string) (*SanctionsList, error) {
+	if s.mem != nil {
+		return s.mem.latest(name), nil
+	}
+
+	query := `
+		SELECT name, version, countries, source, sha256, fetched_at
+		FROM sanctions_lists
+		WHERE name = $1
+		ORDER BY version DESC
+		LIMIT 1
+	`
+
+	list := &SanctionsList{}
+	var countries []byte
+	err := s.db.QueryRowContext(ctx, query, name).Scan(
+		&list.Name, &list.Version, &countries, &list.Source, &list.SHA256, &list.FetchedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sanctions list: %w", err)
+	}
+	if err := json.Unmarshal(countries, &list.Countries); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal sanctions list countries: %w", err)
+	}
+	return list, nil
+}
+
+// Save stores list as the version after the newest one and sets its Version
+func (s *SanctionsStore) Save(ctx context.Context, list *SanctionsList) error {
+	if s.mem != nil {
+		s.mem.save(list)
+		return nil
+	}
+
+	countries, err := json.Marshal(list.Countries)
+	if err != nil {
+		return fmt.Errorf("failed to marshal sanctions list countries: %w", err)
+	}
+
+	query := `
+		INSERT INTO sanctions_lists (name, version, countries, source, sha256, fetched_at)
+		SELECT $1, COALESCE(MAX(version), 0) + 1, $2, $3, $4, $5
+		FROM sanctions_lists
+		WHERE name = $1
+		RETURNING version
+	`
+
+	err = s.db.QueryRowContext(ctx, query,
+		list.Name, countries, list.Source, list.SHA256, list.FetchedAt,
+	).Scan(&list.Version)
+	if err != nil {
+		return fmt.Errorf("failed to save sanctions list: %w", err)
+	}
+	return nil
+}
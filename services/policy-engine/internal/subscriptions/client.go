@@ -0,0 +1,102 @@
+// Package subscriptions looks up whether a consumer holds an active
+// subscription to a service in the registry
+package subscriptions
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/llm-marketplace/policy-engine/internal/config"
+)
+
+// Client queries the registry's subscriptions API as an operator. Answers
+// are cached for the configured TTL, so a new or cancelled subscription
+// takes up to that long to be enforced.
+type Client struct {
+	baseURL string
+	client  *http.Client
+	ttl     time.Duration
+
+	mu    sync.Mutex
+	cache map[string]entry
+}
+
+type entry struct {
+	active  bool
+	expires time.Time
+}
+
+// NewClient creates a client of the registry at cfg.RegistryURL
+func NewClient(cfg config.SubscriptionsConfig) *Client {
+	return &Client{
+		baseURL: strings.TrimSuffix(cfg.RegistryURL, "/"),
+		client:  &http.Client{Timeout: cfg.Timeout},
+		ttl:     cfg.CacheTTL,
+		cache:   make(map[string]entry),
+	}
+}
+
+// Active reports whether consumerID holds an active subscription to
+// serviceID
+func (c *Client) Active(ctx context.Context, consumerID, serviceID string) (bool, error) {
+	key := consumerID + "\x00" + serviceID
+	now := time.Now()
+
+	c.mu.Lock()
+	e, ok := c.cache[key]
+	c.mu.Unlock()
+	if ok && now.Before(e.expires) {
+		return e.active, nil
+	}
+
+	query := url.Values{
+		"consumer_id": {consumerID},
+		"service_id":  {serviceID},
+		"status":      {"active"},
+		"limit":       {"1"},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/api/v1/subscriptions?"+query.Encode(), nil)
+	if err != nil {
+		return false, err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("failed to query subscriptions: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return false, fmt.Errorf("registry returned %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	var page struct {
+		Total int `json:"total"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&page); err != nil {
+		return false, fmt.Errorf("invalid subscriptions response: %w", err)
+	}
+
+	active := page.Total > 0
+	if c.ttl > 0 {
+		c.mu.Lock()
+		// Drop expired answers now and then, so the cache doesn't keep every
+		// pair ever checked
+		if len(c.cache) >= 10000 {
+			for k, old := range c.cache {
+				if !now.Before(old.expires) {
+					delete(c.cache, k)
+				}
+			}
+		}
+		c.cache[key] = entry{active: active, expires: now.Add(c.ttl)}
+		c.mu.Unlock()
+	}
+	return active, nil
+}
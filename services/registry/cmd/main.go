@@ -0,0 +1,207 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"google.golang.org/grpc"
+
+	pb "github.com/org/llm-marketplace/services/registry/api/proto/v1"
+	"github.com/org/llm-marketplace/services/registry/internal/api"
+	"github.com/org/llm-marketplace/services/registry/internal/artifacts"
+	"github.com/org/llm-marketplace/services/registry/internal/config"
+	"github.com/org/llm-marketplace/services/registry/internal/contentscan"
+	"github.com/org/llm-marketplace/services/registry/internal/grpcapi"
+	"github.com/org/llm-marketplace/services/registry/internal/migrations"
+	"github.com/org/llm-marketplace/services/registry/internal/policy"
+	"github.com/org/llm-marketplace/services/registry/internal/publisher"
+	"github.com/org/llm-marketplace/services/registry/internal/registry"
+	"github.com/org/llm-marketplace/services/registry/internal/store"
+)
+
+func main() {
+	// Load configuration; without a file, defaults and REGISTRY_* variables apply
+	configPath := config.Path()
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		panic(fmt.Sprintf("Failed to load configuration: %v", err))
+	}
+
+	logger, err := newLogger(cfg.Logging)
+	if err != nil {
+		panic(fmt.Sprintf("Failed to initialize logger: %v", err))
+	}
+	defer logger.Sync()
+
+	logger.Info("Starting LLM-Marketplace Registry Service",
+		zap.String("version", "1.0.0"),
+		zap.String("environment", os.Getenv("ENVIRONMENT")),
+		zap.String("config", configPath),
+	)
+
+	ctx := context.Background()
+
+	pool, err := store.NewPool(ctx, cfg.Postgres)
+	if err != nil {
+		logger.Fatal("Failed to connect to PostgreSQL", zap.Error(err))
+	}
+	defer pool.Close()
+
+	if cfg.Postgres.AutoMigrate {
+		if _, err := migrations.Up(ctx, pool, logger); err != nil {
+			logger.Fatal("Failed to apply migrations", zap.Error(err))
+		}
+	}
+
+	policyConn, err := policy.Dial(cfg.PolicyEngine)
+	if err != nil {
+		logger.Fatal("Failed to create policy engine client", zap.Error(err))
+	}
+	defer policyConn.Close()
+	policyClient := policy.NewClient(policyConn, cfg.PolicyEngine.Timeout)
+
+	serviceStore := store.New(pool)
+	registryService := registry.NewService(serviceStore, policyClient, logger)
+	registryService.SetKeyGracePeriod(cfg.Portal.KeyGracePeriod)
+	registryService.SetPriceChangeNotice(cfg.PriceChanges.Notice)
+	registryService.SetBlocker(policyClient)
+	registryService.SetComplianceExpiry(cfg.ComplianceExpiry.Warning, cfg.ComplianceExpiry.AdminRecipient)
+	if cfg.Artifacts.Bucket != "" {
+		objects, err := artifacts.NewS3Store(ctx, cfg.Artifacts)
+		if err != nil {
+			logger.Fatal("Failed to create the artifact store", zap.Error(err))
+		}
+		registryService.SetArtifactStore(objects, cfg.Artifacts.Prefix, cfg.Artifacts.MaxSize)
+	}
+
+	// Catalog events are relayed from the outbox until shutdown
+	writer := publisher.NewWriter(cfg.Kafka)
+	relay := publisher.NewRelay(serviceStore, writer, cfg.Kafka, cfg.Outbox, logger)
+	relayCtx, stopRelay := context.WithCancel(ctx)
+	relayDone := make(chan struct{})
+	go func() {
+		defer close(relayDone)
+		relay.Start(relayCtx)
+	}()
+
+	// Scheduled price changes are applied as they fall due
+	priceCtx, stopPrices := context.WithCancel(ctx)
+	go registryService.StartPriceChanges(priceCtx, cfg.PriceChanges.PollInterval)
+
+	// Certifications and data-processing agreements are flagged as they near
+	// expiry
+	expiryCtx, stopExpiry := context.WithCancel(ctx)
+	go registryService.StartComplianceExpiry(expiryCtx, cfg.ComplianceExpiry.CheckInterval)
+
+	// New and changed listings' text is scanned in the background
+	scanCtx, stopScan := context.WithCancel(ctx)
+	if cfg.ContentScan.Enabled {
+		registryService.SetContentDetectors(
+			contentscan.PII{Action: cfg.ContentScan.PIIAction},
+			contentscan.Phrases{Detector: "prohibited-claims", Category: "prohibited-claim", Action: cfg.ContentScan.ProhibitedClaims.Action, Phrases: cfg.ContentScan.ProhibitedClaims.Phrases},
+			contentscan.Phrases{Detector: "unsafe-content", Category: "unsafe-content", Action: cfg.ContentScan.UnsafeContent.Action, Phrases: cfg.ContentScan.UnsafeContent.Phrases},
+		)
+		go registryService.StartContentScan(scanCtx, cfg.ContentScan.Interval)
+	}
+
+	// REST API
+	if cfg.Server.Mode == "production" {
+		gin.SetMode(gin.ReleaseMode)
+	}
+	router := gin.New()
+	router.HandleMethodNotAllowed = true
+	router.Use(gin.Recovery())
+
+	router.GET("/health", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{
+			"status":    "healthy",
+			"timestamp": time.Now().UTC(),
+		})
+	})
+	router.GET("/ready", api.Readiness(map[string]api.Check{
+		"postgres":      pool.Ping,
+		"policy_engine": policyClient.Check,
+	}, 2*time.Second))
+	router.GET("/metrics", gin.WrapH(promhttp.Handler()))
+	api.RegisterRoutes(router, registryService, cfg.Server.ProviderHeader, cfg.Server.ConsumerHeader, cfg.Server.OperatorHeader, logger)
+
+	addr := fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Server.Port)
+	server := &http.Server{
+		Addr:         addr,
+		Handler:      router,
+		ReadTimeout:  cfg.Server.ReadTimeout,
+		WriteTimeout: cfg.Server.WriteTimeout,
+		IdleTimeout:  cfg.Server.IdleTimeout,
+	}
+	go func() {
+		logger.Info("Starting HTTP server", zap.String("address", addr))
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			logger.Fatal("Failed to start HTTP server", zap.Error(err))
+		}
+	}()
+
+	// gRPC API
+	grpcAddr := fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Server.GRPCPort)
+	listener, err := net.Listen("tcp", grpcAddr)
+	if err != nil {
+		logger.Fatal("Failed to listen for gRPC", zap.String("address", grpcAddr), zap.Error(err))
+	}
+	grpcServer := grpc.NewServer()
+	pb.RegisterRegistryServiceServer(grpcServer, grpcapi.NewServer(registryService, logger))
+	go func() {
+		logger.Info("Starting gRPC server", zap.String("address", grpcAddr))
+		if err := grpcServer.Serve(listener); err != nil {
+			logger.Fatal("Failed to start gRPC server", zap.Error(err))
+		}
+	}()
+
+	// Graceful shutdown
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+
+	logger.Info("Shutting down server...")
+
+	shutdownCtx, cancel := context.WithTimeout(ctx, cfg.Server.ShutdownTimeout)
+	defer cancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		logger.Error("Server forced to shutdown", zap.Error(err))
+	}
+	grpcServer.GracefulStop()
+
+	// Events stored by the last requests stay in the outbox for the next start
+	stopPrices()
+	stopExpiry()
+	stopScan()
+	stopRelay()
+	<-relayDone
+	if err := writer.Close(); err != nil {
+		logger.Error("Failed to close Kafka writer", zap.Error(err))
+	}
+
+	logger.Info("Server exited")
+}
+
+func newLogger(cfg config.LoggingConfig) (*zap.Logger, error) {
+	level, err := zapcore.ParseLevel(cfg.Level)
+	if err != nil {
+		return nil, err
+	}
+	zapConfig := zap.NewProductionConfig()
+	if cfg.Format == "console" {
+		zapConfig = zap.NewDevelopmentConfig()
+	}
+	zapConfig.Level = zap.NewAtomicLevelAt(level)
+	return zapConfig.Build()
+}
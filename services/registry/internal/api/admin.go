@@ -0,0 +1,181 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/org/llm-marketplace/pkg/marketplace/problem"
+
+	"github.com/org/llm-marketplace/services/registry/internal/registry"
+)
+
+// registerAdminRoutes registers the moderation endpoints. Only operators
+// call them: the gateway routes /api/v1/admin to operators alone, and
+// requests made for a provider or consumer are refused here too.
+func registerAdminRoutes(api *gin.RouterGroup, h *handlers) {
+	admin := api.Group("/admin", h.operatorOnly)
+	admin.POST("/services/:id/suspend", h.suspend(registry.TargetService))
+	admin.POST("/services/:id/reinstate", h.reinstate(registry.TargetService))
+	admin.POST("/services/:id/release", h.releaseContentScan)
+	admin.POST("/providers/:id/suspend", h.suspend(registry.TargetProvider))
+	admin.POST("/providers/:id/reinstate", h.reinstate(registry.TargetProvider))
+	admin.GET("/reports", h.listReports)
+	admin.GET("/reports/:id", h.getReport)
+	admin.POST("/reports/:id/resolve", h.resolveReport)
+	admin.GET("/actions", h.listModerationActions)
+}
+
+// operatorOnly refuses requests the gateway made for a provider or consumer
+func (h *handlers) operatorOnly(c *gin.Context) {
+	if !h.byOperator(c) {
+		abort(c, problem.Forbidden, "Moderation is for marketplace operators")
+	}
+}
+
+// byOperator reports whether the gateway made the request for an operator
+// rather than a provider or consumer
+func (h *handlers) byOperator(c *gin.Context) bool {
+	return h.caller(c) == "" && c.GetHeader(h.consumerHeader) == ""
+}
+
+// operator returns the operator the gateway named, if any, for the audit log
+func (h *handlers) operator(c *gin.Context) string {
+	return c.GetHeader(h.operatorHeader)
+}
+
+type suspendRequest struct {
+	Reason   string `json:"reason"`
+	ReportID string `json:"report_id"`
+}
+
+// suspend handles POST /api/v1/admin/{services,providers}/:id/suspend
+func (h *handlers) suspend(target string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req suspendRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			abort(c, problem.InvalidRequest, err.Error())
+			return
+		}
+		suspend := h.svc.SuspendService
+		if target == registry.TargetProvider {
+			suspend = h.svc.SuspendProvider
+		}
+		takedown, err := suspend(c.Request.Context(), h.operator(c), c.Param("id"), req.Reason, req.ReportID)
+		if err != nil {
+			abortWithError(c, err, h.logger)
+			return
+		}
+		c.JSON(http.StatusCreated, takedown)
+	}
+}
+
+type reinstateRequest struct {
+	Reason string `json:"reason"`
+}
+
+// reinstate handles POST /api/v1/admin/{services,providers}/:id/reinstate
+func (h *handlers) reinstate(target string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req reinstateRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			abort(c, problem.InvalidRequest, err.Error())
+			return
+		}
+		takedown, err := h.svc.Reinstate(c.Request.Context(), h.operator(c), target, c.Param("id"), req.Reason)
+		if err != nil {
+			abortWithError(c, err, h.logger)
+			return
+		}
+		c.JSON(http.StatusOK, takedown)
+	}
+}
+
+// releaseContentScan handles POST /api/v1/admin/services/:id/release,
+// clearing a listing's content scan findings after review
+func (h *handlers) releaseContentScan(c *gin.Context) {
+	var req reinstateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		abort(c, problem.InvalidRequest, err.Error())
+		return
+	}
+	reg, err := h.svc.ReleaseContentScan(c.Request.Context(), h.operator(c), c.Param("id"), req.Reason)
+	if err != nil {
+		abortWithError(c, err, h.logger)
+		return
+	}
+	c.JSON(http.StatusOK, reg)
+}
+
+// listReports handles GET /api/v1/admin/reports, the moderation queue
+func (h *handlers) listReports(c *gin.Context) {
+	filter := registry.ReportFilter{
+		ServiceID:  c.Query("service_id"),
+		ProviderID: c.Query("provider_id"),
+		Status:     c.Query("status"),
+	}
+	if !bindPage(c, &filter.Limit, &filter.Offset) {
+		return
+	}
+
+	reports, total, err := h.svc.ListReports(c.Request.Context(), filter)
+	if err != nil {
+		abortWithError(c, err, h.logger)
+		return
+	}
+	if reports == nil {
+		reports = []*registry.Report{}
+	}
+	c.JSON(http.StatusOK, gin.H{"reports": reports, "total": total})
+}
+
+// getReport handles GET /api/v1/admin/reports/:id
+func (h *handlers) getReport(c *gin.Context) {
+	report, err := h.svc.GetReport(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		abortWithError(c, err, h.logger)
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+type resolveReportRequest struct {
+	Resolution string `json:"resolution"`
+	Note       string `json:"note"`
+}
+
+// resolveReport handles POST /api/v1/admin/reports/:id/resolve
+func (h *handlers) resolveReport(c *gin.Context) {
+	var req resolveReportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		abort(c, problem.InvalidRequest, err.Error())
+		return
+	}
+	report, err := h.svc.ResolveReport(c.Request.Context(), h.operator(c), c.Param("id"), req.Resolution, req.Note)
+	if err != nil {
+		abortWithError(c, err, h.logger)
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// listModerationActions handles GET /api/v1/admin/actions, the audit log
+func (h *handlers) listModerationActions(c *gin.Context) {
+	filter := registry.ActionFilter{
+		TargetType: c.Query("target_type"),
+		TargetID:   c.Query("target_id"),
+		Actor:      c.Query("actor"),
+	}
+	if !bindPage(c, &filter.Limit, &filter.Offset) {
+		return
+	}
+
+	actions, total, err := h.svc.ListModerationActions(c.Request.Context(), filter)
+	if err != nil {
+		abortWithError(c, err, h.logger)
+		return
+	}
+	if actions == nil {
+		actions = []*registry.ModerationAction{}
+	}
+	c.JSON(http.StatusOK, gin.H{"actions": actions, "total": total})
+}
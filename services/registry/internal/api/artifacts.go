@@ -0,0 +1,127 @@
+package api
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/org/llm-marketplace/pkg/marketplace/problem"
+
+	"github.com/org/llm-marketplace/services/registry/internal/registry"
+)
+
+// maxFormOverhead allows for the form fields sent with an artifact file
+const maxFormOverhead = 64 << 10
+
+// multipartMemory is how much of a form is held in memory; the rest of the
+// file is buffered on disk
+const multipartMemory = 8 << 20
+
+// portalUploadArtifact handles POST /api/v1/portal/artifacts, a multipart
+// form with the artifact in file and its certification, issuer, sha256,
+// issued_at and expires_at. Times are RFC 3339.
+func (h *handlers) portalUploadArtifact(c *gin.Context) {
+	if limit := h.svc.MaxArtifactSize(); limit > 0 {
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limit+maxFormOverhead)
+	}
+	if err := c.Request.ParseMultipartForm(multipartMemory); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			abort(c, problem.PayloadTooLarge, "The artifact is too large")
+			return
+		}
+		abort(c, problem.InvalidRequest, "The body must be a multipart form: "+err.Error())
+		return
+	}
+	upload := registry.ArtifactUpload{
+		Certification: c.PostForm("certification"),
+		Issuer:        c.PostForm("issuer"),
+		SHA256:        c.PostForm("sha256"),
+	}
+	if !parseFormTime(c, "expires_at", &upload.ExpiresAt) {
+		return
+	}
+	if raw := c.PostForm("issued_at"); raw != "" {
+		var issuedAt time.Time
+		if !parseFormTime(c, "issued_at", &issuedAt) {
+			return
+		}
+		upload.IssuedAt = &issuedAt
+	}
+
+	header, err := c.FormFile("file")
+	switch {
+	case err == nil:
+		file, err := header.Open()
+		if err != nil {
+			abortWithError(c, err, h.logger)
+			return
+		}
+		defer file.Close()
+		if upload.Content, err = io.ReadAll(file); err != nil {
+			abortWithError(c, err, h.logger)
+			return
+		}
+		upload.FileName = header.Filename
+		upload.ContentType = header.Header.Get("Content-Type")
+	case !errors.Is(err, http.ErrMissingFile):
+		abort(c, problem.InvalidRequest, err.Error())
+		return
+	}
+
+	artifact, err := h.svc.UploadArtifact(c.Request.Context(), h.provider(c).ID, upload)
+	if err != nil {
+		abortWithError(c, err, h.logger)
+		return
+	}
+	c.JSON(http.StatusCreated, artifact)
+}
+
+// portalArtifacts handles GET /api/v1/portal/artifacts
+func (h *handlers) portalArtifacts(c *gin.Context) {
+	h.listArtifacts(c, h.provider(c).ID)
+}
+
+// providerArtifacts handles GET /api/v1/providers/:id/artifacts, an
+// operator reviewing a provider's artifacts
+func (h *handlers) providerArtifacts(c *gin.Context) {
+	h.listArtifacts(c, c.Param("id"))
+}
+
+func (h *handlers) listArtifacts(c *gin.Context, providerID string) {
+	artifacts, err := h.svc.Artifacts(c.Request.Context(), providerID)
+	if err != nil {
+		abortWithError(c, err, h.logger)
+		return
+	}
+	if artifacts == nil {
+		artifacts = []*registry.ComplianceArtifact{}
+	}
+	c.JSON(http.StatusOK, gin.H{"artifacts": artifacts})
+}
+
+// portalDeleteArtifact handles DELETE /api/v1/portal/artifacts/:id
+func (h *handlers) portalDeleteArtifact(c *gin.Context) {
+	if err := h.svc.DeleteArtifact(c.Request.Context(), h.provider(c).ID, c.Param("id")); err != nil {
+		abortWithError(c, err, h.logger)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// parseFormTime parses the RFC 3339 form field name into t, if it is set
+func parseFormTime(c *gin.Context, name string, t *time.Time) bool {
+	raw := c.PostForm(name)
+	if raw == "" {
+		return true
+	}
+	parsed, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		abort(c, problem.InvalidRequest, name+" must be an RFC 3339 time")
+		return false
+	}
+	*t = parsed
+	return true
+}
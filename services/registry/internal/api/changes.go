@@ -0,0 +1,102 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/org/llm-marketplace/pkg/marketplace"
+	"github.com/org/llm-marketplace/pkg/marketplace/problem"
+
+	"github.com/org/llm-marketplace/services/registry/internal/registry"
+)
+
+// registerChangeRoutes registers a service's changelog, which anyone may
+// read, and its scheduled price changes, which its provider manages
+func registerChangeRoutes(api *gin.RouterGroup, h *handlers) {
+	api.GET("/services/:id/changelog", h.changelog)
+	api.POST("/services/:id/price-changes", h.schedulePriceChange)
+	api.GET("/services/:id/price-changes", h.listPriceChanges)
+	api.DELETE("/services/:id/price-changes/:change_id", h.cancelPriceChange)
+}
+
+// changelog handles GET /api/v1/services/:id/changelog
+func (h *handlers) changelog(c *gin.Context) {
+	filter := registry.ChangeFilter{ServiceID: c.Param("id"), Kind: c.Query("kind")}
+	if !bindPage(c, &filter.Limit, &filter.Offset) {
+		return
+	}
+	changes, total, err := h.svc.Changelog(c.Request.Context(), filter)
+	if err != nil {
+		abortWithError(c, err, h.logger)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"changes": changes, "total": total})
+}
+
+type priceChangeRequest struct {
+	Pricing     *marketplace.PricingInfo `json:"pricing" binding:"required"`
+	EffectiveAt time.Time                `json:"effective_at"`
+}
+
+// schedulePriceChange handles POST /api/v1/services/:id/price-changes
+func (h *handlers) schedulePriceChange(c *gin.Context) {
+	h.schedulePriceChangeFor(c, h.caller(c))
+}
+
+func (h *handlers) schedulePriceChangeFor(c *gin.Context, caller string) {
+	var req priceChangeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		abort(c, problem.InvalidRequest, err.Error())
+		return
+	}
+	pc, err := h.svc.SchedulePriceChange(c.Request.Context(), caller, c.Param("id"), *req.Pricing, req.EffectiveAt)
+	if err != nil {
+		abortWithError(c, err, h.logger)
+		return
+	}
+	c.JSON(http.StatusCreated, pc)
+}
+
+// listPriceChanges handles GET /api/v1/services/:id/price-changes
+func (h *handlers) listPriceChanges(c *gin.Context) {
+	h.listPriceChangesFor(c, h.caller(c))
+}
+
+func (h *handlers) listPriceChangesFor(c *gin.Context, caller string) {
+	changes, err := h.svc.PriceChanges(c.Request.Context(), caller, c.Param("id"))
+	if err != nil {
+		abortWithError(c, err, h.logger)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"price_changes": changes})
+}
+
+// cancelPriceChange handles DELETE /api/v1/services/:id/price-changes/:change_id
+func (h *handlers) cancelPriceChange(c *gin.Context) {
+	h.cancelPriceChangeFor(c, h.caller(c))
+}
+
+func (h *handlers) cancelPriceChangeFor(c *gin.Context, caller string) {
+	pc, err := h.svc.CancelPriceChange(c.Request.Context(), caller, c.Param("id"), c.Param("change_id"))
+	if err != nil {
+		abortWithError(c, err, h.logger)
+		return
+	}
+	c.JSON(http.StatusOK, pc)
+}
+
+// portalSchedulePriceChange handles POST /api/v1/portal/services/:id/price-changes
+func (h *handlers) portalSchedulePriceChange(c *gin.Context) {
+	h.schedulePriceChangeFor(c, h.provider(c).ID)
+}
+
+// portalListPriceChanges handles GET /api/v1/portal/services/:id/price-changes
+func (h *handlers) portalListPriceChanges(c *gin.Context) {
+	h.listPriceChangesFor(c, h.provider(c).ID)
+}
+
+// portalCancelPriceChange handles DELETE /api/v1/portal/services/:id/price-changes/:change_id
+func (h *handlers) portalCancelPriceChange(c *gin.Context) {
+	h.cancelPriceChangeFor(c, h.provider(c).ID)
+}
@@ -0,0 +1,257 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/org/llm-marketplace/pkg/marketplace"
+	"github.com/org/llm-marketplace/pkg/marketplace/problem"
+
+	"github.com/org/llm-marketplace/services/registry/internal/registry"
+)
+
+// providerKey is the context key of the provider an API key authenticated
+const providerKey = "portal_provider"
+
+// registerPortalRoutes registers the provider portal: the endpoints a
+// provider calls with its own API key to manage its services and keys
+func registerPortalRoutes(api *gin.RouterGroup, h *handlers) {
+	portal := api.Group("/portal", h.authenticate)
+	{
+		portal.GET("/provider", h.portalProvider)
+
+		portal.GET("/services", h.portalListServices)
+		portal.POST("/services", h.portalRegisterService)
+		portal.GET("/services/:id", h.portalGetService)
+		portal.PUT("/services/:id", h.portalUpdateService)
+		portal.PUT("/services/:id/pricing", h.portalSetPricing)
+		portal.PATCH("/services/:id/status", h.portalSetStatus)
+		portal.DELETE("/services/:id", h.portalUnpublish)
+		portal.GET("/services/:id/validations", h.portalServiceValidations)
+		portal.POST("/services/:id/price-changes", h.portalSchedulePriceChange)
+		portal.GET("/services/:id/price-changes", h.portalListPriceChanges)
+		portal.DELETE("/services/:id/price-changes/:change_id", h.portalCancelPriceChange)
+
+		portal.POST("/validate", h.portalValidate)
+		portal.GET("/validations", h.portalValidations)
+
+		portal.POST("/artifacts", h.portalUploadArtifact)
+		portal.GET("/artifacts", h.portalArtifacts)
+		portal.DELETE("/artifacts/:id", h.portalDeleteArtifact)
+
+		portal.GET("/credentials", h.portalCredentials)
+		portal.POST("/credentials/rotate", h.portalRotateCredential)
+		portal.DELETE("/credentials/:id", h.portalRevokeCredential)
+	}
+}
+
+// authenticate resolves the bearer API key to its provider
+func (h *handlers) authenticate(c *gin.Context) {
+	scheme, key, _ := strings.Cut(c.GetHeader("Authorization"), " ")
+	if !strings.EqualFold(scheme, "Bearer") || key == "" {
+		c.Header("WWW-Authenticate", `Bearer realm="provider-portal"`)
+		abort(c, problem.Unauthorized, "A provider API key is required")
+		return
+	}
+	provider, err := h.svc.Authenticate(c.Request.Context(), strings.TrimSpace(key))
+	if err != nil {
+		c.Header("WWW-Authenticate", `Bearer realm="provider-portal", error="invalid_token"`)
+		abortWithError(c, err, h.logger)
+		return
+	}
+	c.Set(providerKey, provider)
+	c.Next()
+}
+
+// provider returns the authenticated provider
+func (h *handlers) provider(c *gin.Context) *registry.Provider {
+	return c.MustGet(providerKey).(*registry.Provider)
+}
+
+// portalProvider handles GET /api/v1/portal/provider
+func (h *handlers) portalProvider(c *gin.Context) {
+	c.JSON(http.StatusOK, h.provider(c))
+}
+
+// portalListServices handles GET /api/v1/portal/services
+func (h *handlers) portalListServices(c *gin.Context) {
+	filter := registry.ServiceFilter{ProviderID: h.provider(c).ID, Status: c.Query("status")}
+	if !bindPage(c, &filter.Limit, &filter.Offset) {
+		return
+	}
+	regs, total, err := h.svc.List(c.Request.Context(), filter)
+	if err != nil {
+		abortWithError(c, err, h.logger)
+		return
+	}
+	if regs == nil {
+		regs = []*registry.Registration{}
+	}
+	c.JSON(http.StatusOK, gin.H{"services": regs, "total": total})
+}
+
+// portalRegisterService handles POST /api/v1/portal/services
+func (h *handlers) portalRegisterService(c *gin.Context) {
+	var desc marketplace.ServiceDescriptor
+	if err := c.ShouldBindJSON(&desc); err != nil {
+		abort(c, problem.InvalidRequest, err.Error())
+		return
+	}
+	reg, err := h.svc.Register(c.Request.Context(), h.provider(c).ID, desc)
+	if err != nil {
+		abortWithError(c, err, h.logger)
+		return
+	}
+	c.Header("Location", "/api/v1/portal/services/"+reg.ID)
+	c.JSON(http.StatusCreated, reg)
+}
+
+// portalGetService handles GET /api/v1/portal/services/:id
+func (h *handlers) portalGetService(c *gin.Context) {
+	reg, err := h.svc.GetOwned(c.Request.Context(), h.provider(c).ID, c.Param("id"))
+	if err != nil {
+		abortWithError(c, err, h.logger)
+		return
+	}
+	c.JSON(http.StatusOK, reg)
+}
+
+// portalUpdateService handles PUT /api/v1/portal/services/:id
+func (h *handlers) portalUpdateService(c *gin.Context) {
+	var desc marketplace.ServiceDescriptor
+	if err := c.ShouldBindJSON(&desc); err != nil {
+		abort(c, problem.InvalidRequest, err.Error())
+		return
+	}
+	reg, err := h.svc.Update(c.Request.Context(), h.provider(c).ID, c.Param("id"), desc)
+	if err != nil {
+		abortWithError(c, err, h.logger)
+		return
+	}
+	c.JSON(http.StatusOK, reg)
+}
+
+// portalSetPricing handles PUT /api/v1/portal/services/:id/pricing
+func (h *handlers) portalSetPricing(c *gin.Context) {
+	var pricing marketplace.PricingInfo
+	if err := c.ShouldBindJSON(&pricing); err != nil {
+		abort(c, problem.InvalidRequest, err.Error())
+		return
+	}
+	reg, err := h.svc.SetPricing(c.Request.Context(), h.provider(c).ID, c.Param("id"), pricing)
+	if err != nil {
+		abortWithError(c, err, h.logger)
+		return
+	}
+	c.JSON(http.StatusOK, reg)
+}
+
+// portalSetStatus handles PATCH /api/v1/portal/services/:id/status
+func (h *handlers) portalSetStatus(c *gin.Context) {
+	var req setStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		abort(c, problem.InvalidRequest, err.Error())
+		return
+	}
+	reg, err := h.svc.SetStatus(c.Request.Context(), h.provider(c).ID, c.Param("id"), req.Status)
+	if err != nil {
+		abortWithError(c, err, h.logger)
+		return
+	}
+	c.JSON(http.StatusOK, reg)
+}
+
+// portalUnpublish handles DELETE /api/v1/portal/services/:id
+func (h *handlers) portalUnpublish(c *gin.Context) {
+	reg, err := h.svc.Deregister(c.Request.Context(), h.provider(c).ID, c.Param("id"))
+	if err != nil {
+		abortWithError(c, err, h.logger)
+		return
+	}
+	c.JSON(http.StatusOK, reg)
+}
+
+// portalServiceValidations handles GET /api/v1/portal/services/:id/validations
+func (h *handlers) portalServiceValidations(c *gin.Context) {
+	if _, err := h.svc.GetOwned(c.Request.Context(), h.provider(c).ID, c.Param("id")); err != nil {
+		abortWithError(c, err, h.logger)
+		return
+	}
+	h.listValidations(c, c.Param("id"))
+}
+
+// portalValidations handles GET /api/v1/portal/validations
+func (h *handlers) portalValidations(c *gin.Context) {
+	h.listValidations(c, "")
+}
+
+func (h *handlers) listValidations(c *gin.Context, serviceID string) {
+	filter := registry.ValidationFilter{ProviderID: h.provider(c).ID, ServiceID: serviceID}
+	if raw := c.Query("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 0 {
+			abort(c, problem.InvalidRequest, "limit must be a non-negative integer")
+			return
+		}
+		filter.Limit = n
+	}
+	validations, err := h.svc.Validations(c.Request.Context(), filter)
+	if err != nil {
+		abortWithError(c, err, h.logger)
+		return
+	}
+	if validations == nil {
+		validations = []*registry.Validation{}
+	}
+	c.JSON(http.StatusOK, gin.H{"validations": validations})
+}
+
+// portalValidate handles POST /api/v1/portal/validate. The result is
+// returned whether or not the descriptor passes.
+func (h *handlers) portalValidate(c *gin.Context) {
+	var desc marketplace.ServiceDescriptor
+	if err := c.ShouldBindJSON(&desc); err != nil {
+		abort(c, problem.InvalidRequest, err.Error())
+		return
+	}
+	v, err := h.svc.Validate(c.Request.Context(), h.provider(c).ID, desc)
+	if err != nil {
+		abortWithError(c, err, h.logger)
+		return
+	}
+	c.JSON(http.StatusOK, v)
+}
+
+// portalCredentials handles GET /api/v1/portal/credentials
+func (h *handlers) portalCredentials(c *gin.Context) {
+	creds, err := h.svc.Credentials(c.Request.Context(), h.provider(c).ID)
+	if err != nil {
+		abortWithError(c, err, h.logger)
+		return
+	}
+	if creds == nil {
+		creds = []*registry.Credential{}
+	}
+	c.JSON(http.StatusOK, gin.H{"credentials": creds})
+}
+
+// portalRotateCredential handles POST /api/v1/portal/credentials/rotate
+func (h *handlers) portalRotateCredential(c *gin.Context) {
+	issued, err := h.svc.RotateCredential(c.Request.Context(), h.provider(c).ID)
+	if err != nil {
+		abortWithError(c, err, h.logger)
+		return
+	}
+	c.JSON(http.StatusCreated, issued)
+}
+
+// portalRevokeCredential handles DELETE /api/v1/portal/credentials/:id
+func (h *handlers) portalRevokeCredential(c *gin.Context) {
+	if err := h.svc.RevokeCredential(c.Request.Context(), h.provider(c).ID, c.Param("id")); err != nil {
+		abortWithError(c, err, h.logger)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,74 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/org/llm-marketplace/pkg/marketplace"
+	"github.com/org/llm-marketplace/pkg/marketplace/problem"
+	"go.uber.org/zap"
+
+	"github.com/org/llm-marketplace/services/registry/internal/registry"
+)
+
+// policyViolation is the registry's own error type; the rest are shared. See
+// README "Error Responses".
+var policyViolation = problem.Type{Code: "policy-violation", Title: "Service violates marketplace policies", Status: http.StatusUnprocessableEntity}
+
+// problemDetails adds the registry's extensions to problem details. Errors
+// lists invalid fields; Violations lists the policies a rejected descriptor
+// breaks.
+type problemDetails struct {
+	problem.Details
+	Errors        []marketplace.FieldError `json:"errors,omitempty"`
+	Violations    []registry.Violation     `json:"violations,omitempty"`
+	PolicyVersion string                   `json:"policy_version,omitempty"`
+}
+
+func newProblem(c *gin.Context, t problem.Type, detail string) *problemDetails {
+	return &problemDetails{Details: problem.New(t, c.Request.URL.Path, detail)}
+}
+
+func writeProblem(c *gin.Context, p *problemDetails) {
+	problem.Write(c.Writer, p.Status, p)
+	c.Abort()
+}
+
+// abort writes problem details and stops the handler chain
+func abort(c *gin.Context, t problem.Type, detail string) {
+	writeProblem(c, newProblem(c, t, detail))
+}
+
+// abortWithError maps a registry error to its problem type
+func abortWithError(c *gin.Context, err error, logger *zap.Logger) {
+	var verr marketplace.ValidationError
+	var rejected *registry.RejectedError
+	switch {
+	case errors.As(err, &verr):
+		p := newProblem(c, problem.InvalidRequest, "The service descriptor is invalid")
+		p.Errors = verr
+		writeProblem(c, p)
+	case errors.As(err, &rejected):
+		p := newProblem(c, policyViolation, rejected.Error())
+		p.Violations = rejected.Violations
+		p.PolicyVersion = rejected.PolicyVersion
+		writeProblem(c, p)
+	case errors.Is(err, registry.ErrUnauthenticated):
+		abort(c, problem.Unauthorized, err.Error())
+	case errors.Is(err, registry.ErrNotFound):
+		abort(c, problem.NotFound, err.Error())
+	case errors.Is(err, registry.ErrForbidden):
+		abort(c, problem.Forbidden, err.Error())
+	case errors.Is(err, registry.ErrConflict):
+		abort(c, problem.Conflict, err.Error())
+	case errors.Is(err, registry.ErrArtifactsUnavailable):
+		abort(c, problem.ServiceUnavailable, err.Error())
+	case errors.Is(err, registry.ErrPolicyUnavailable):
+		logger.Warn("Policy engine unavailable", zap.Error(err))
+		abort(c, problem.ServiceUnavailable, "The policy engine is unavailable; try again later")
+	default:
+		logger.Error("Request failed", zap.String("path", c.Request.URL.Path), zap.Error(err))
+		abort(c, problem.InternalError, "")
+	}
+}
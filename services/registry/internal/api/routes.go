@@ -0,0 +1,277 @@
+// Package api serves the registry REST API
+package api
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/org/llm-marketplace/pkg/marketplace"
+	"github.com/org/llm-marketplace/pkg/marketplace/problem"
+	"go.uber.org/zap"
+
+	"github.com/org/llm-marketplace/services/registry/internal/registry"
+)
+
+// RegisterRoutes registers the API routes. providerHeader and
+// consumerHeader name the headers carrying the provider or consumer
+// organisation the gateway authenticated, and operatorHeader the operator;
+// the portal routes authenticate providers by API key instead.
+func RegisterRoutes(router *gin.Engine, svc *registry.Service, providerHeader, consumerHeader, operatorHeader string, logger *zap.Logger) {
+	h := &handlers{svc: svc, providerHeader: providerHeader, consumerHeader: consumerHeader, operatorHeader: operatorHeader, logger: logger}
+
+	api := router.Group("/api/v1")
+	{
+		api.POST("/providers", h.registerProvider)
+		api.GET("/providers/:id", h.getProvider)
+		api.POST("/providers/:id/credentials", h.issueCredential)
+		api.GET("/providers/:id/artifacts", h.providerArtifacts)
+
+		api.POST("/services", h.registerService)
+		api.GET("/services", h.listServices)
+		api.GET("/services/:id", h.getService)
+		api.PUT("/services/:id", h.updateService)
+		api.PATCH("/services/:id/status", h.setStatus)
+		api.DELETE("/services/:id", h.deregisterService)
+		api.POST("/services/:id/report", h.reportService)
+	}
+	registerChangeRoutes(api, h)
+	registerPortalRoutes(api, h)
+	registerSubscriptionRoutes(api, h)
+	registerAdminRoutes(api, h)
+
+	router.NoRoute(func(c *gin.Context) {
+		abort(c, problem.NotFound, "No route matches "+c.Request.URL.Path)
+	})
+	router.NoMethod(func(c *gin.Context) {
+		abort(c, problem.MethodNotAllowed, c.Request.Method+" is not supported on "+c.Request.URL.Path)
+	})
+}
+
+type handlers struct {
+	svc            *registry.Service
+	providerHeader string
+	consumerHeader string
+	operatorHeader string
+	logger         *zap.Logger
+}
+
+// caller returns the authenticated provider, if any
+func (h *handlers) caller(c *gin.Context) string {
+	return c.GetHeader(h.providerHeader)
+}
+
+type registerProviderRequest struct {
+	Name         string `json:"name"`
+	ContactEmail string `json:"contact_email"`
+	// TenantID registers an enterprise's provider for its own models
+	TenantID string `json:"tenant_id"`
+}
+
+// registerProvider handles POST /api/v1/providers
+func (h *handlers) registerProvider(c *gin.Context) {
+	var req registerProviderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		abort(c, problem.InvalidRequest, err.Error())
+		return
+	}
+	var provider *registry.Provider
+	var err error
+	if req.TenantID != "" {
+		provider, err = h.svc.RegisterTenantProvider(c.Request.Context(), c.GetHeader(h.consumerHeader), req.TenantID, req.Name, req.ContactEmail)
+	} else {
+		provider, err = h.svc.RegisterProvider(c.Request.Context(), req.Name, req.ContactEmail)
+	}
+	if err != nil {
+		abortWithError(c, err, h.logger)
+		return
+	}
+	c.Header("Location", "/api/v1/providers/"+provider.ID)
+	c.JSON(http.StatusCreated, provider)
+}
+
+// getProvider handles GET /api/v1/providers/:id
+func (h *handlers) getProvider(c *gin.Context) {
+	provider, err := h.svc.GetProvider(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		abortWithError(c, err, h.logger)
+		return
+	}
+	c.JSON(http.StatusOK, provider)
+}
+
+// issueCredential handles POST /api/v1/providers/:id/credentials, an
+// operator issuing a provider an additional API key. A provider may not: the
+// key would let it act as the provider it names.
+func (h *handlers) issueCredential(c *gin.Context) {
+	if !h.byOperator(c) {
+		abort(c, problem.Forbidden, "Only marketplace operators issue provider credentials")
+		return
+	}
+	issued, err := h.svc.IssueCredential(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		abortWithError(c, err, h.logger)
+		return
+	}
+	c.JSON(http.StatusCreated, issued)
+}
+
+// registerService handles POST /api/v1/services
+func (h *handlers) registerService(c *gin.Context) {
+	var desc marketplace.ServiceDescriptor
+	if err := c.ShouldBindJSON(&desc); err != nil {
+		abort(c, problem.InvalidRequest, err.Error())
+		return
+	}
+	reg, err := h.svc.Register(c.Request.Context(), h.caller(c), desc)
+	if err != nil {
+		abortWithError(c, err, h.logger)
+		return
+	}
+	c.Header("Location", "/api/v1/services/"+reg.ID)
+	c.JSON(http.StatusCreated, reg)
+}
+
+// listServices handles GET /api/v1/services
+func (h *handlers) listServices(c *gin.Context) {
+	filter := registry.ServiceFilter{
+		ProviderID:  c.Query("provider_id"),
+		Status:      c.Query("status"),
+		ContentScan: c.Query("content_scan"),
+		Kind:        c.Query("kind"),
+	}
+	if !bindPage(c, &filter.Limit, &filter.Offset) {
+		return
+	}
+
+	regs, total, err := h.svc.List(c.Request.Context(), filter)
+	if err != nil {
+		abortWithError(c, err, h.logger)
+		return
+	}
+	if regs == nil {
+		regs = []*registry.Registration{}
+	}
+	c.JSON(http.StatusOK, gin.H{"services": regs, "total": total})
+}
+
+// bindPage reads the limit and offset query parameters, writing a problem
+// and returning false if either is invalid
+func bindPage(c *gin.Context, limit, offset *int) bool {
+	for name, dst := range map[string]*int{"limit": limit, "offset": offset} {
+		if raw := c.Query(name); raw != "" {
+			n, err := strconv.Atoi(raw)
+			if err != nil || n < 0 {
+				abort(c, problem.InvalidRequest, name+" must be a non-negative integer")
+				return false
+			}
+			*dst = n
+		}
+	}
+	return true
+}
+
+// getService handles GET /api/v1/services/:id
+func (h *handlers) getService(c *gin.Context) {
+	reg, err := h.svc.Get(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		abortWithError(c, err, h.logger)
+		return
+	}
+	c.JSON(http.StatusOK, reg)
+}
+
+// updateService handles PUT /api/v1/services/:id
+func (h *handlers) updateService(c *gin.Context) {
+	var desc marketplace.ServiceDescriptor
+	if err := c.ShouldBindJSON(&desc); err != nil {
+		abort(c, problem.InvalidRequest, err.Error())
+		return
+	}
+	reg, err := h.svc.Update(c.Request.Context(), h.caller(c), c.Param("id"), desc)
+	if err != nil {
+		abortWithError(c, err, h.logger)
+		return
+	}
+	c.JSON(http.StatusOK, reg)
+}
+
+type setStatusRequest struct {
+	Status string `json:"status" binding:"required"`
+}
+
+// setStatus handles PATCH /api/v1/services/:id/status
+func (h *handlers) setStatus(c *gin.Context) {
+	var req setStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		abort(c, problem.InvalidRequest, err.Error())
+		return
+	}
+	reg, err := h.svc.SetStatus(c.Request.Context(), h.caller(c), c.Param("id"), req.Status)
+	if err != nil {
+		abortWithError(c, err, h.logger)
+		return
+	}
+	c.JSON(http.StatusOK, reg)
+}
+
+// deregisterService handles DELETE /api/v1/services/:id
+func (h *handlers) deregisterService(c *gin.Context) {
+	reg, err := h.svc.Deregister(c.Request.Context(), h.caller(c), c.Param("id"))
+	if err != nil {
+		abortWithError(c, err, h.logger)
+		return
+	}
+	c.JSON(http.StatusOK, reg)
+}
+
+type reportRequest struct {
+	Category string `json:"category"`
+	Details  string `json:"details"`
+}
+
+// reportService handles POST /api/v1/services/:id/report, a consumer
+// reporting a listing to the moderation queue
+func (h *handlers) reportService(c *gin.Context) {
+	var req reportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		abort(c, problem.InvalidRequest, err.Error())
+		return
+	}
+	report, err := h.svc.ReportService(c.Request.Context(), c.GetHeader(h.consumerHeader), c.Param("id"), req.Category, req.Details)
+	if err != nil {
+		abortWithError(c, err, h.logger)
+		return
+	}
+	c.JSON(http.StatusCreated, report)
+}
+
+// Check is a readiness check of one dependency
+type Check func(ctx context.Context) error
+
+// Readiness reports 200 when every check passes and 503 naming the failing
+// ones otherwise
+func Readiness(checks map[string]Check, timeout time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+
+		status := http.StatusOK
+		results := make(map[string]string, len(checks))
+		for name, check := range checks {
+			if err := check(ctx); err != nil {
+				status = http.StatusServiceUnavailable
+				results[name] = err.Error()
+				continue
+			}
+			results[name] = "ok"
+		}
+		ready := "ready"
+		if status != http.StatusOK {
+			ready = "not_ready"
+		}
+		c.JSON(status, gin.H{"status": ready, "checks": results, "timestamp": time.Now().UTC()})
+	}
+}
@@ -0,0 +1,107 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/org/llm-marketplace/pkg/marketplace"
+	"github.com/org/llm-marketplace/pkg/marketplace/problem"
+
+	"github.com/org/llm-marketplace/services/registry/internal/registry"
+)
+
+// registerSubscriptionRoutes registers the subscription endpoints, which
+// consumers call for their own contracts, providers for contracts to their
+// services and operators for any
+func registerSubscriptionRoutes(api *gin.RouterGroup, h *handlers) {
+	api.POST("/subscriptions", h.subscribe)
+	api.GET("/subscriptions", h.listSubscriptions)
+	api.GET("/subscriptions/:id", h.getSubscription)
+	api.DELETE("/subscriptions/:id", h.cancelSubscription)
+}
+
+// subscriber returns who a subscription request acts for
+func (h *handlers) subscriber(c *gin.Context) registry.Caller {
+	return registry.Caller{ConsumerID: c.GetHeader(h.consumerHeader), ProviderID: h.caller(c)}
+}
+
+type subscribeRequest struct {
+	ConsumerID     string               `json:"consumer_id"`
+	ServiceID      string               `json:"service_id"`
+	Tier           string               `json:"tier"`
+	CommittedSpend float64              `json:"committed_spend"`
+	Currency       string               `json:"currency"`
+	StartsAt       time.Time            `json:"starts_at"`
+	EndsAt         *time.Time           `json:"ends_at"`
+	SLA            *marketplace.SLAInfo `json:"sla"`
+}
+
+// subscribe handles POST /api/v1/subscriptions
+func (h *handlers) subscribe(c *gin.Context) {
+	var req subscribeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		abort(c, problem.InvalidRequest, err.Error())
+		return
+	}
+	sub, err := h.svc.Subscribe(c.Request.Context(), h.subscriber(c), registry.Subscription{
+		ConsumerID:     req.ConsumerID,
+		ServiceID:      req.ServiceID,
+		Tier:           req.Tier,
+		CommittedSpend: req.CommittedSpend,
+		Currency:       req.Currency,
+		StartsAt:       req.StartsAt,
+		EndsAt:         req.EndsAt,
+		SLA:            req.SLA,
+	})
+	if err != nil {
+		abortWithError(c, err, h.logger)
+		return
+	}
+	c.Header("Location", "/api/v1/subscriptions/"+sub.ID)
+	c.JSON(http.StatusCreated, sub)
+}
+
+// listSubscriptions handles GET /api/v1/subscriptions
+func (h *handlers) listSubscriptions(c *gin.Context) {
+	filter := registry.SubscriptionFilter{
+		ConsumerID: c.Query("consumer_id"),
+		ServiceID:  c.Query("service_id"),
+		ProviderID: c.Query("provider_id"),
+		Status:     c.Query("status"),
+	}
+	if !bindPage(c, &filter.Limit, &filter.Offset) {
+		return
+	}
+
+	subs, total, err := h.svc.ListSubscriptions(c.Request.Context(), h.subscriber(c), filter)
+	if err != nil {
+		abortWithError(c, err, h.logger)
+		return
+	}
+	if subs == nil {
+		subs = []*registry.Subscription{}
+	}
+	c.JSON(http.StatusOK, gin.H{"subscriptions": subs, "total": total})
+}
+
+// getSubscription handles GET /api/v1/subscriptions/:id
+func (h *handlers) getSubscription(c *gin.Context) {
+	sub, err := h.svc.GetSubscription(c.Request.Context(), h.subscriber(c), c.Param("id"))
+	if err != nil {
+		abortWithError(c, err, h.logger)
+		return
+	}
+	c.JSON(http.StatusOK, sub)
+}
+
+// cancelSubscription handles DELETE /api/v1/subscriptions/:id. The
+// subscription is kept, cancelled.
+func (h *handlers) cancelSubscription(c *gin.Context) {
+	sub, err := h.svc.CancelSubscription(c.Request.Context(), h.subscriber(c), c.Param("id"))
+	if err != nil {
+		abortWithError(c, err, h.logger)
+		return
+	}
+	c.JSON(http.StatusOK, sub)
+}
@@ -0,0 +1,249 @@
+// Package config loads the registry configuration from built-in defaults, an
+// optional YAML file and REGISTRY_* environment variables, in that order.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/org/llm-marketplace/pkg/marketplace/envconfig"
+	"gopkg.in/yaml.v3"
+)
+
+type Config struct {
+	Server           ServerConfig           `yaml:"server"`
+	Postgres         PostgresConfig         `yaml:"postgres"`
+	PolicyEngine     PolicyEngineConfig     `yaml:"policy_engine"`
+	Kafka            KafkaConfig            `yaml:"kafka"`
+	Outbox           OutboxConfig           `yaml:"outbox"`
+	Portal           PortalConfig           `yaml:"portal"`
+	PriceChanges     PriceChangesConfig     `yaml:"price_changes"`
+	Artifacts        ArtifactsConfig        `yaml:"artifacts"`
+	ComplianceExpiry ComplianceExpiryConfig `yaml:"compliance_expiry"`
+	ContentScan      ContentScanConfig      `yaml:"content_scan"`
+	Logging          LoggingConfig          `yaml:"logging"`
+}
+
+type ServerConfig struct {
+	Host         string        `yaml:"host"`
+	Port         int           `yaml:"port"`      // REST API
+	GRPCPort     int           `yaml:"grpc_port"` // gRPC API
+	Mode         string        `yaml:"mode"`      // development, production
+	ReadTimeout  time.Duration `yaml:"read_timeout"`
+	WriteTimeout time.Duration `yaml:"write_timeout"`
+	IdleTimeout  time.Duration `yaml:"idle_timeout"`
+
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+
+	// ProviderHeader carries the authenticated provider, set by the gateway.
+	// When present, a request may only change that provider's services.
+	ProviderHeader string `yaml:"provider_header"`
+	// ConsumerHeader carries the authenticated consumer organisation. When
+	// present, a request may only manage that consumer's subscriptions.
+	ConsumerHeader string `yaml:"consumer_header"`
+	// OperatorHeader carries the operator the gateway authenticated. It is
+	// recorded in the moderation audit log.
+	OperatorHeader string `yaml:"operator_header"`
+}
+
+type PostgresConfig struct {
+	Host        string        `yaml:"host"`
+	Port        int           `yaml:"port"`
+	Database    string        `yaml:"database"`
+	User        string        `yaml:"user"`
+	Password    string        `yaml:"password"`
+	SSLMode     string        `yaml:"ssl_mode"`
+	MaxConns    int           `yaml:"max_conns"`
+	MinConns    int           `yaml:"min_conns"`
+	ConnTimeout time.Duration `yaml:"conn_timeout"`
+	AutoMigrate bool          `yaml:"auto_migrate"` // Apply pending migrations at startup
+}
+
+// DSN returns the connection string for the pool
+func (c PostgresConfig) DSN() string {
+	return fmt.Sprintf("host=%s port=%d dbname=%s user=%s password='%s' sslmode=%s",
+		c.Host, c.Port, c.Database, c.User, dsnEscaper.Replace(c.Password), c.SSLMode)
+}
+
+var dsnEscaper = strings.NewReplacer(`\`, `\\`, `'`, `\'`)
+
+// PolicyEngineConfig is the policy engine every descriptor is validated by
+// before it is stored
+type PolicyEngineConfig struct {
+	GRPCEndpoint string        `yaml:"grpc_endpoint"`
+	Timeout      time.Duration `yaml:"timeout"`
+}
+
+// KafkaConfig is where catalog and validation events, price-change notices
+// and compliance expiry notices are published
+type KafkaConfig struct {
+	Brokers               []string      `yaml:"brokers"`
+	Topic                 string        `yaml:"topic"`                   // Catalog events
+	ValidationTopic       string        `yaml:"validation_topic"`        // The outcome of every descriptor check
+	PriceChangeTopic      string        `yaml:"price_change_topic"`      // Notices to subscribers of scheduled price changes
+	ComplianceExpiryTopic string        `yaml:"compliance_expiry_topic"` // Notices of expiring certifications and data-processing agreements
+	WriteTimeout          time.Duration `yaml:"write_timeout"`
+}
+
+// OutboxConfig controls the relay publishing stored events to Kafka
+type OutboxConfig struct {
+	PollInterval time.Duration `yaml:"poll_interval"` // How often the outbox is checked when it is idle
+	BatchSize    int           `yaml:"batch_size"`    // Events published per Kafka write
+	MaxBackoff   time.Duration `yaml:"max_backoff"`   // Longest wait between attempts while Kafka fails
+}
+
+// PortalConfig controls the provider portal's API keys
+type PortalConfig struct {
+	KeyGracePeriod time.Duration `yaml:"key_grace_period"` // How long rotated-out keys keep working
+}
+
+// PriceChangesConfig controls scheduled price changes
+type PriceChangesConfig struct {
+	Notice       time.Duration `yaml:"notice"`        // How far ahead price changes are scheduled
+	PollInterval time.Duration `yaml:"poll_interval"` // How often due price changes are applied
+}
+
+// ArtifactsConfig is the S3 bucket providers' compliance artifacts are kept
+// in. Uploads are refused while no bucket is set. Credentials come from the
+// AWS SDK's default chain.
+type ArtifactsConfig struct {
+	Bucket       string `yaml:"bucket"`
+	Prefix       string `yaml:"prefix"`   // Artifacts are kept under <prefix>/<provider>/<id>
+	Region       string `yaml:"region"`   // The SDK's default when empty
+	Endpoint     string `yaml:"endpoint"` // Override, e.g. MinIO or LocalStack
+	UsePathStyle bool   `yaml:"use_path_style"`
+	MaxSize      int64  `yaml:"max_size"` // Largest artifact accepted, in bytes
+}
+
+// ComplianceExpiryConfig controls the tracking of certification and
+// data-processing agreement expiries
+type ComplianceExpiryConfig struct {
+	Warning        time.Duration `yaml:"warning"`         // How long before a document lapses it is flagged as expiring
+	CheckInterval  time.Duration `yaml:"check_interval"`  // How often services are checked
+	AdminRecipient string        `yaml:"admin_recipient"` // The notification recipient of the marketplace operators; none when empty
+}
+
+// ContentScanConfig controls the scanning of listings' names, descriptions,
+// tags and capabilities. Each detector's findings flag a listing for review
+// or block it from the catalog, as its action says.
+type ContentScanConfig struct {
+	Enabled          bool          `yaml:"enabled"`
+	Interval         time.Duration `yaml:"interval"` // How often waiting listings are scanned
+	PIIAction        string        `yaml:"pii_action"`
+	ProhibitedClaims PhrasesConfig `yaml:"prohibited_claims"`
+	UnsafeContent    PhrasesConfig `yaml:"unsafe_content"`
+}
+
+// PhrasesConfig is a list of phrases a content scan looks for
+type PhrasesConfig struct {
+	Action  string   `yaml:"action"` // flag or block
+	Phrases []string `yaml:"phrases"`
+}
+
+type LoggingConfig struct {
+	Level  string `yaml:"level"`  // debug, info, warn, error
+	Format string `yaml:"format"` // json, console
+}
+
+// DefaultPath is the config file used when CONFIG_PATH is not set
+const DefaultPath = "config.yaml"
+
+// Path returns the config file to load: CONFIG_PATH if set, otherwise
+// config.yaml when it exists in the working directory. It is empty when the
+// service is configured by defaults and environment variables alone.
+func Path() string {
+	if path := os.Getenv("CONFIG_PATH"); path != "" {
+		return path
+	}
+	if _, err := os.Stat(DefaultPath); err == nil {
+		return DefaultPath
+	}
+	return ""
+}
+
+// Load builds the configuration from the built-in defaults, overridden by the
+// file at path, if any, then by REGISTRY_* environment variables
+func Load(path string) (*Config, error) {
+	var cfg Config
+	cfg.setDefaults()
+
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read config file: %w", err)
+		}
+		if err := yaml.Unmarshal([]byte(os.ExpandEnv(string(data))), &cfg); err != nil {
+			return nil, fmt.Errorf("failed to parse config: %w", err)
+		}
+	}
+
+	if err := envconfig.Apply(&cfg, "REGISTRY_", os.Getenv); err != nil {
+		return nil, fmt.Errorf("invalid environment override: %w", err)
+	}
+
+	if err := validate(&cfg); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+	return &cfg, nil
+}
+
+func validate(cfg *Config) error {
+	var errs []error
+	if cfg.Server.Port <= 0 || cfg.Server.Port > 65535 {
+		errs = append(errs, fmt.Errorf("server.port %d is not a valid port", cfg.Server.Port))
+	}
+	if cfg.Server.GRPCPort <= 0 || cfg.Server.GRPCPort > 65535 || cfg.Server.GRPCPort == cfg.Server.Port {
+		errs = append(errs, fmt.Errorf("server.grpc_port %d must be a valid port other than server.port", cfg.Server.GRPCPort))
+	}
+	if cfg.Postgres.Host == "" || cfg.Postgres.Database == "" {
+		errs = append(errs, errors.New("postgres.host and postgres.database are required"))
+	}
+	if cfg.PolicyEngine.GRPCEndpoint == "" {
+		errs = append(errs, errors.New("policy_engine.grpc_endpoint is required; every descriptor is validated by the policy engine"))
+	}
+	if len(cfg.Kafka.Brokers) == 0 || cfg.Kafka.Topic == "" || cfg.Kafka.ValidationTopic == "" || cfg.Kafka.PriceChangeTopic == "" || cfg.Kafka.ComplianceExpiryTopic == "" {
+		errs = append(errs, errors.New("kafka.brokers, kafka.topic, kafka.validation_topic, kafka.price_change_topic and kafka.compliance_expiry_topic are required"))
+	}
+	if cfg.Outbox.BatchSize <= 0 {
+		errs = append(errs, fmt.Errorf("outbox.batch_size must be positive, got %d", cfg.Outbox.BatchSize))
+	}
+	if cfg.Outbox.PollInterval <= 0 {
+		errs = append(errs, errors.New("outbox.poll_interval must be positive"))
+	}
+	if cfg.Portal.KeyGracePeriod < 0 {
+		errs = append(errs, errors.New("portal.key_grace_period can't be negative"))
+	}
+	if cfg.PriceChanges.Notice < 0 {
+		errs = append(errs, errors.New("price_changes.notice can't be negative"))
+	}
+	if cfg.PriceChanges.PollInterval <= 0 {
+		errs = append(errs, errors.New("price_changes.poll_interval must be positive"))
+	}
+	if cfg.Artifacts.MaxSize <= 0 {
+		errs = append(errs, fmt.Errorf("artifacts.max_size must be positive, got %d", cfg.Artifacts.MaxSize))
+	}
+	if cfg.ComplianceExpiry.Warning < 0 {
+		errs = append(errs, errors.New("compliance_expiry.warning can't be negative"))
+	}
+	if cfg.ComplianceExpiry.CheckInterval <= 0 {
+		errs = append(errs, errors.New("compliance_expiry.check_interval must be positive"))
+	}
+	if scan := cfg.ContentScan; scan.Enabled {
+		if scan.Interval <= 0 {
+			errs = append(errs, errors.New("content_scan.interval must be positive"))
+		}
+		for name, action := range map[string]string{
+			"pii_action":               scan.PIIAction,
+			"prohibited_claims.action": scan.ProhibitedClaims.Action,
+			"unsafe_content.action":    scan.UnsafeContent.Action,
+		} {
+			if action != "flag" && action != "block" {
+				errs = append(errs, fmt.Errorf("content_scan.%s must be flag or block, got %q", name, action))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,68 @@
+package config
+
+import (
+	"time"
+
+	"github.com/org/llm-marketplace/pkg/marketplace"
+)
+
+// setDefaults fills in the settings used when neither the config file nor the
+// environment sets them. They match config.yaml, with dependencies expected
+// on localhost.
+func (c *Config) setDefaults() {
+	c.Server.Host = "0.0.0.0"
+	c.Server.Port = 3010
+	c.Server.GRPCPort = 50052
+	c.Server.Mode = "development"
+	c.Server.ReadTimeout = 30 * time.Second
+	c.Server.WriteTimeout = 30 * time.Second
+	c.Server.IdleTimeout = 120 * time.Second
+	c.Server.ShutdownTimeout = 30 * time.Second
+	c.Server.ProviderHeader = "X-Provider-ID"
+	c.Server.ConsumerHeader = "X-Consumer-ID"
+	c.Server.OperatorHeader = "X-Operator-ID"
+
+	c.Postgres.Host = "localhost"
+	c.Postgres.Port = 5432
+	c.Postgres.Database = "registry"
+	c.Postgres.User = "registry"
+	c.Postgres.SSLMode = "prefer"
+	c.Postgres.MaxConns = 20
+	c.Postgres.MinConns = 2
+	c.Postgres.ConnTimeout = 5 * time.Second
+	c.Postgres.AutoMigrate = true
+
+	c.PolicyEngine.GRPCEndpoint = "localhost:50051"
+	c.PolicyEngine.Timeout = 5 * time.Second
+
+	c.Kafka.Brokers = []string{"localhost:9092"}
+	c.Kafka.Topic = marketplace.CatalogTopic
+	c.Kafka.ValidationTopic = marketplace.ValidationTopic
+	c.Kafka.PriceChangeTopic = marketplace.PriceChangeTopic
+	c.Kafka.ComplianceExpiryTopic = marketplace.ComplianceExpiryTopic
+	c.Kafka.WriteTimeout = 10 * time.Second
+
+	c.Outbox.PollInterval = time.Second
+	c.Outbox.BatchSize = 100
+	c.Outbox.MaxBackoff = 30 * time.Second
+
+	c.Portal.KeyGracePeriod = 24 * time.Hour
+
+	c.PriceChanges.Notice = 30 * 24 * time.Hour
+	c.PriceChanges.PollInterval = time.Minute
+
+	c.Artifacts.Prefix = "compliance-artifacts"
+	c.Artifacts.MaxSize = 20 << 20
+
+	c.ComplianceExpiry.Warning = 30 * 24 * time.Hour
+	c.ComplianceExpiry.CheckInterval = time.Hour
+	c.ComplianceExpiry.AdminRecipient = "marketplace-admins"
+
+	c.ContentScan.Interval = 10 * time.Second
+	c.ContentScan.PIIAction = "block"
+	c.ContentScan.ProhibitedClaims.Action = "flag"
+	c.ContentScan.UnsafeContent.Action = "block"
+
+	c.Logging.Level = "info"
+	c.Logging.Format = "json"
+}
@@ -0,0 +1,149 @@
+// Package contentscan provides the built-in detectors listings' text is
+// scanned with: personal data, and configured lists of prohibited claims
+// and unsafe terms. Other detectors, such as a hosted moderation model,
+// implement registry.ContentDetector the same way.
+package contentscan
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/org/llm-marketplace/services/registry/internal/registry"
+)
+
+// Categories of personal data the PII detector finds
+const (
+	CategoryEmail      = "email"
+	CategoryPhone      = "phone"
+	CategoryCardNumber = "card-number"
+	CategorySSN        = "ssn"
+)
+
+var (
+	emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+	phonePattern = regexp.MustCompile(`\+?\(?\d[\d\s().\-]{8,}\d`)
+	cardPattern  = regexp.MustCompile(`\b(?:\d[ \-]?){12,18}\d\b`)
+	ssnPattern   = regexp.MustCompile(`\b\d{3}-\d{2}-\d{4}\b`)
+)
+
+// PII finds email addresses, phone numbers, payment card numbers and US
+// social security numbers. What it matched isn't kept in its findings.
+type PII struct {
+	Action string // registry.FindingFlag or registry.FindingBlock
+}
+
+func (d PII) Name() string { return "pii" }
+
+func (d PII) Detect(_ context.Context, field, text string) ([]registry.ContentFinding, error) {
+	var findings []registry.ContentFinding
+	found := func(category string) {
+		findings = append(findings, registry.ContentFinding{Detector: d.Name(), Field: field, Category: category, Action: d.Action})
+	}
+
+	if emailPattern.MatchString(text) {
+		found(CategoryEmail)
+	}
+	// Card numbers and SSNs are also digit runs a phone number could match
+	cards := false
+	for _, match := range cardPattern.FindAllString(text, -1) {
+		if luhn(match) {
+			cards = true
+			break
+		}
+	}
+	if cards {
+		found(CategoryCardNumber)
+	}
+	ssn := ssnPattern.MatchString(text)
+	if ssn {
+		found(CategorySSN)
+	}
+	if !cards && !ssn && phonePattern.MatchString(text) {
+		for _, match := range phonePattern.FindAllString(text, -1) {
+			if digits := countDigits(match); digits >= 10 && digits <= 15 {
+				found(CategoryPhone)
+				break
+			}
+		}
+	}
+	return findings, nil
+}
+
+// Phrases finds any of a list of phrases, matched as whole words regardless
+// of case, such as prohibited claims ("guaranteed accuracy", "HIPAA
+// certified") or unsafe content
+type Phrases struct {
+	Detector string // The detector's name
+	Category string // The category of its findings
+	Action   string // registry.FindingFlag or registry.FindingBlock
+	Phrases  []string
+}
+
+func (d Phrases) Name() string { return d.Detector }
+
+func (d Phrases) Detect(_ context.Context, field, text string) ([]registry.ContentFinding, error) {
+	var findings []registry.ContentFinding
+	lower := strings.ToLower(text)
+	for _, phrase := range d.Phrases {
+		if containsWords(lower, strings.ToLower(phrase)) {
+			findings = append(findings, registry.ContentFinding{Detector: d.Detector, Field: field, Category: d.Category, Match: phrase, Action: d.Action})
+		}
+	}
+	return findings, nil
+}
+
+// containsWords reports whether phrase occurs in text between word
+// boundaries
+func containsWords(text, phrase string) bool {
+	if phrase == "" {
+		return false
+	}
+	for offset := 0; ; {
+		i := strings.Index(text[offset:], phrase)
+		if i < 0 {
+			return false
+		}
+		start, end := offset+i, offset+i+len(phrase)
+		if (start == 0 || !isWordByte(text[start-1])) && (end == len(text) || !isWordByte(text[end])) {
+			return true
+		}
+		offset = start + 1
+	}
+}
+
+func isWordByte(b byte) bool {
+	return b == '_' || unicode.IsLetter(rune(b)) || unicode.IsDigit(rune(b))
+}
+
+// luhn reports whether the digits in s pass the Luhn checksum of payment
+// card numbers
+func luhn(s string) bool {
+	sum, n := 0, 0
+	for i := len(s) - 1; i >= 0; i-- {
+		c := s[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		digit := int(c - '0')
+		if n%2 == 1 {
+			if digit *= 2; digit > 9 {
+				digit -= 9
+			}
+		}
+		sum += digit
+		n++
+	}
+	return n >= 13 && sum%10 == 0
+}
+
+func countDigits(s string) int {
+	n := 0
+	for _, c := range s {
+		if c >= '0' && c <= '9' {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,228 @@
+// Package grpcapi serves the registry gRPC API
+package grpcapi
+
+import (
+	"context"
+	"errors"
+
+	"github.com/org/llm-marketplace/pkg/marketplace"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "github.com/org/llm-marketplace/services/registry/api/proto/v1"
+	"github.com/org/llm-marketplace/services/registry/internal/policy"
+	"github.com/org/llm-marketplace/services/registry/internal/registry"
+)
+
+// ProviderMetadataKey carries the authenticated provider on calls made on a
+// provider's behalf
+const ProviderMetadataKey = "x-provider-id"
+
+// Server implements pb.RegistryServiceServer
+type Server struct {
+	pb.UnimplementedRegistryServiceServer
+
+	svc    *registry.Service
+	logger *zap.Logger
+}
+
+// NewServer creates a gRPC server for svc
+func NewServer(svc *registry.Service, logger *zap.Logger) *Server {
+	return &Server{svc: svc, logger: logger}
+}
+
+func (s *Server) RegisterProvider(ctx context.Context, req *pb.RegisterProviderRequest) (*pb.Provider, error) {
+	provider, err := s.svc.RegisterProvider(ctx, req.GetName(), req.GetContactEmail())
+	if err != nil {
+		return nil, s.status(err)
+	}
+	return providerToProto(provider), nil
+}
+
+func (s *Server) GetProvider(ctx context.Context, req *pb.GetProviderRequest) (*pb.Provider, error) {
+	provider, err := s.svc.GetProvider(ctx, req.GetId())
+	if err != nil {
+		return nil, s.status(err)
+	}
+	return providerToProto(provider), nil
+}
+
+func (s *Server) RegisterService(ctx context.Context, req *pb.RegisterServiceRequest) (*pb.Registration, error) {
+	return s.registration(s.svc.Register(ctx, caller(ctx), descriptorFromProto(req.GetService())))
+}
+
+func (s *Server) UpdateService(ctx context.Context, req *pb.UpdateServiceRequest) (*pb.Registration, error) {
+	return s.registration(s.svc.Update(ctx, caller(ctx), req.GetId(), descriptorFromProto(req.GetService())))
+}
+
+func (s *Server) SetServiceStatus(ctx context.Context, req *pb.SetServiceStatusRequest) (*pb.Registration, error) {
+	return s.registration(s.svc.SetStatus(ctx, caller(ctx), req.GetId(), req.GetStatus()))
+}
+
+func (s *Server) DeregisterService(ctx context.Context, req *pb.DeregisterServiceRequest) (*pb.Registration, error) {
+	return s.registration(s.svc.Deregister(ctx, caller(ctx), req.GetId()))
+}
+
+func (s *Server) GetService(ctx context.Context, req *pb.GetServiceRequest) (*pb.Registration, error) {
+	return s.registration(s.svc.Get(ctx, req.GetId()))
+}
+
+func (s *Server) ListServices(ctx context.Context, req *pb.ListServicesRequest) (*pb.ListServicesResponse, error) {
+	regs, total, err := s.svc.List(ctx, registry.ServiceFilter{
+		ProviderID: req.GetProviderId(),
+		Status:     req.GetStatus(),
+		Limit:      int(req.GetLimit()),
+		Offset:     int(req.GetOffset()),
+	})
+	if err != nil {
+		return nil, s.status(err)
+	}
+	resp := &pb.ListServicesResponse{Total: int32(total)}
+	for _, reg := range regs {
+		resp.Services = append(resp.Services, registrationToProto(reg))
+	}
+	return resp, nil
+}
+
+func (s *Server) registration(reg *registry.Registration, err error) (*pb.Registration, error) {
+	if err != nil {
+		return nil, s.status(err)
+	}
+	return registrationToProto(reg), nil
+}
+
+// status maps a registry error to a gRPC status
+func (s *Server) status(err error) error {
+	var verr marketplace.ValidationError
+	var rejected *registry.RejectedError
+	switch {
+	case errors.As(err, &verr), errors.As(err, &rejected):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, registry.ErrNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, registry.ErrForbidden):
+		return status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, registry.ErrConflict):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, registry.ErrPolicyUnavailable):
+		s.logger.Warn("Policy engine unavailable", zap.Error(err))
+		return status.Error(codes.Unavailable, "policy engine unavailable")
+	default:
+		s.logger.Error("Request failed", zap.Error(err))
+		return status.Error(codes.Internal, "internal error")
+	}
+}
+
+// caller returns the authenticated provider from the call metadata, if any
+func caller(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if values := md.Get(ProviderMetadataKey); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+func providerToProto(p *registry.Provider) *pb.Provider {
+	return &pb.Provider{
+		Id:           p.ID,
+		Name:         p.Name,
+		ContactEmail: p.ContactEmail,
+		Verified:     p.Verified,
+		CreatedAt:    timestamppb.New(p.CreatedAt),
+		ApiKey:       p.APIKey,
+		TenantId:     p.TenantID,
+	}
+}
+
+func registrationToProto(reg *registry.Registration) *pb.Registration {
+	return &pb.Registration{
+		Id:            reg.ID,
+		ProviderId:    reg.ProviderID,
+		Status:        reg.Status,
+		Revision:      reg.Revision,
+		PolicyVersion: reg.PolicyVersion,
+		Service:       descriptorToProto(&reg.Service),
+		CreatedAt:     timestamppb.New(reg.CreatedAt),
+		UpdatedAt:     timestamppb.New(reg.UpdatedAt),
+	}
+}
+
+func descriptorToProto(d *marketplace.ServiceDescriptor) *pb.ServiceDescriptor {
+	req := policy.ToProto(d)
+	return &pb.ServiceDescriptor{
+		ServiceId:      req.ServiceId,
+		Name:           req.Name,
+		Version:        req.Version,
+		Description:    req.Description,
+		ProviderId:     req.ProviderId,
+		Category:       req.Category,
+		Tags:           d.Tags,
+		Endpoint:       req.Endpoint,
+		Compliance:     req.Compliance,
+		Sla:            req.Sla,
+		Pricing:        req.Pricing,
+		Capabilities:   req.Capabilities,
+		ServiceClass:   req.ServiceClass,
+		TenantId:       req.TenantId,
+		Dependencies:   req.Dependencies,
+		ServingRegions: req.ServingRegions,
+		Kind:           req.Kind,
+		PromptTemplate: req.PromptTemplate,
+		Dataset:        req.Dataset,
+		FineTuning:     req.FineTuning,
+	}
+}
+
+// descriptorFromProto converts a descriptor message; absent sections stay nil
+func descriptorFromProto(m *pb.ServiceDescriptor) marketplace.ServiceDescriptor {
+	desc := marketplace.ServiceDescriptor{
+		ServiceID:   m.GetServiceId(),
+		Name:        m.GetName(),
+		Version:     m.GetVersion(),
+		Description: m.GetDescription(),
+		ProviderID:  m.GetProviderId(),
+		Category:    m.GetCategory(),
+		Tags:        m.GetTags(),
+		Class:       m.GetServiceClass(),
+		TenantID:    m.GetTenantId(),
+		Kind:        m.GetKind(),
+	}
+	if m.GetEndpoint() != nil {
+		desc.Endpoint = marketplace.EndpointFromProto(m.GetEndpoint())
+	}
+	if m.GetCompliance() != nil {
+		desc.Compliance = marketplace.ComplianceFromProto(m.GetCompliance())
+	}
+	if m.GetSla() != nil {
+		desc.SLA = marketplace.SLAFromProto(m.GetSla())
+	}
+	if m.GetPricing() != nil {
+		desc.Pricing = marketplace.PricingFromProto(m.GetPricing())
+		// A single unnamed tier is a flat rate, as PricingToProto sends it
+		if len(desc.Pricing.Tiers) == 1 && desc.Pricing.Tiers[0].Tier == "" {
+			desc.Pricing.Tiers = nil
+		}
+	}
+	for _, c := range m.GetCapabilities() {
+		desc.Capabilities = append(desc.Capabilities, marketplace.CapabilityFromProto(c))
+	}
+	for _, d := range m.GetDependencies() {
+		desc.Dependencies = append(desc.Dependencies, marketplace.DependencyFromProto(d))
+	}
+	for _, r := range m.GetServingRegions() {
+		desc.ServingRegions = append(desc.ServingRegions, marketplace.ServingRegionFromProto(r))
+	}
+	if m.GetPromptTemplate() != nil {
+		desc.PromptTemplate = marketplace.PromptTemplateFromProto(m.GetPromptTemplate())
+	}
+	if m.GetDataset() != nil {
+		desc.Dataset = marketplace.DatasetFromProto(m.GetDataset())
+	}
+	if m.GetFineTuning() != nil {
+		desc.FineTuning = marketplace.FineTuningFromProto(m.GetFineTuning())
+	}
+	return desc
+}
@@ -0,0 +1,28 @@
+// Package migrations owns the registry database schema: the SQL files in
+// sql/, embedded in the binary and applied by the shared migrate package.
+package migrations
+
+import (
+	"context"
+	"embed"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/org/llm-marketplace/pkg/marketplace/migrate"
+	"go.uber.org/zap"
+)
+
+//go:embed sql/*.sql
+var files embed.FS
+
+// lockID serializes migrations across replicas starting at the same time
+const lockID = 7263542
+
+// Up applies every pending migration and returns how many were applied. It
+// refuses to run over drift.
+func Up(ctx context.Context, pool *pgxpool.Pool, logger *zap.Logger) (int, error) {
+	migrator, err := migrate.New(pool, files, "sql", lockID, logger)
+	if err != nil {
+		return 0, err
+	}
+	return migrator.Up(ctx)
+}
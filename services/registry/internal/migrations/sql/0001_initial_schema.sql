@@ -0,0 +1,47 @@
+-- Initial registry schema: providers, the services they register and the
+-- outbox of catalog events waiting to be published to Kafka.
+
+CREATE TABLE IF NOT EXISTS providers (
+    id UUID PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    contact_email VARCHAR(255) NOT NULL,
+    verified BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT unique_provider_name UNIQUE(name)
+);
+
+-- The descriptor is stored as submitted; name and version are copied out to
+-- keep them unique, as the discovery catalog requires
+CREATE TABLE IF NOT EXISTS services (
+    id UUID PRIMARY KEY,
+    provider_id UUID NOT NULL REFERENCES providers(id),
+    name VARCHAR(255) NOT NULL,
+    version VARCHAR(50) NOT NULL,
+    descriptor JSONB NOT NULL,
+    status VARCHAR(50) NOT NULL DEFAULT 'active',
+    revision BIGINT NOT NULL DEFAULT 1,
+    policy_version VARCHAR(100),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT unique_service_version UNIQUE(name, version),
+    CONSTRAINT valid_status CHECK (status IN ('active', 'deprecated', 'suspended', 'retired'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_services_provider ON services(provider_id);
+CREATE INDEX IF NOT EXISTS idx_services_status ON services(status);
+
+-- Events are written in the same transaction as the change they describe
+-- and deleted once Kafka has accepted them
+CREATE TABLE IF NOT EXISTS outbox (
+    id BIGSERIAL PRIMARY KEY,
+    event_id UUID NOT NULL UNIQUE,
+    service_id UUID NOT NULL,
+    event_type VARCHAR(50) NOT NULL,
+    payload JSONB NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
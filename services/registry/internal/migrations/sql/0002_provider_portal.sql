@@ -0,0 +1,34 @@
+-- Provider portal: API keys providers authenticate with, and the outcome of
+-- every descriptor check made on their behalf.
+
+-- Only a SHA-256 hash of each key is kept
+CREATE TABLE IF NOT EXISTS provider_credentials (
+    id UUID PRIMARY KEY,
+    provider_id UUID NOT NULL REFERENCES providers(id),
+    prefix VARCHAR(20) NOT NULL,
+    key_hash CHAR(64) NOT NULL UNIQUE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP WITH TIME ZONE,
+    revoked_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_provider_credentials_provider ON provider_credentials(provider_id);
+
+-- service_id is not a foreign key: rejected registrations and dry runs have
+-- no service. Name and version are TEXT because rejected descriptors may
+-- break the limits of the services table.
+CREATE TABLE IF NOT EXISTS validations (
+    id UUID PRIMARY KEY,
+    provider_id UUID NOT NULL REFERENCES providers(id),
+    service_id UUID,
+    name TEXT NOT NULL,
+    version TEXT NOT NULL,
+    valid BOOLEAN NOT NULL,
+    policy_version VARCHAR(100),
+    errors JSONB,
+    violations JSONB,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_validations_provider ON validations(provider_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_validations_service ON validations(service_id, created_at DESC);
@@ -0,0 +1,23 @@
+-- Subscriptions: contracts between consumer organisations and services. A
+-- subscription's status follows from its dates and cancelled_at, so it is
+-- not stored.
+CREATE TABLE IF NOT EXISTS subscriptions (
+    id UUID PRIMARY KEY,
+    consumer_id TEXT NOT NULL,
+    service_id UUID NOT NULL REFERENCES services(id),
+    provider_id UUID NOT NULL REFERENCES providers(id),
+    tier VARCHAR(100) NOT NULL DEFAULT '',
+    committed_spend NUMERIC(18, 6) NOT NULL DEFAULT 0,
+    currency CHAR(3) NOT NULL,
+    starts_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    ends_at TIMESTAMP WITH TIME ZONE,
+    sla JSONB,
+    cancelled_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+
+    CHECK (ends_at IS NULL OR ends_at > starts_at)
+);
+
+CREATE INDEX IF NOT EXISTS idx_subscriptions_consumer ON subscriptions(consumer_id, service_id);
+CREATE INDEX IF NOT EXISTS idx_subscriptions_service ON subscriptions(service_id);
+CREATE INDEX IF NOT EXISTS idx_subscriptions_provider ON subscriptions(provider_id);
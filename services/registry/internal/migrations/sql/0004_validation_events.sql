@@ -0,0 +1,8 @@
+-- The outbox also carries validation events, published to their own topic
+-- and keyed by provider. stream names the topic an event goes to, and
+-- message_key its Kafka key.
+ALTER TABLE outbox ADD COLUMN IF NOT EXISTS stream VARCHAR(20) NOT NULL DEFAULT 'catalog';
+ALTER TABLE outbox ADD COLUMN IF NOT EXISTS message_key TEXT;
+UPDATE outbox SET message_key = service_id::text WHERE message_key IS NULL;
+ALTER TABLE outbox ALTER COLUMN message_key SET NOT NULL;
+ALTER TABLE outbox ALTER COLUMN service_id DROP NOT NULL;
@@ -0,0 +1,55 @@
+-- Moderation: operators take services and providers down, review abuse
+-- reports and every action they take is kept in an append-only audit log.
+ALTER TABLE providers ADD COLUMN IF NOT EXISTS suspended BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- A takedown is in force until lifted_at is set. services lists the services
+-- it suspended with the status each had before, which reinstating restores.
+CREATE TABLE IF NOT EXISTS takedowns (
+    id UUID PRIMARY KEY,
+    target_type VARCHAR(20) NOT NULL CHECK (target_type IN ('service', 'provider')),
+    target_id UUID NOT NULL,
+    reason TEXT NOT NULL,
+    report_id UUID,
+    policy_id TEXT NOT NULL DEFAULT '',
+    services JSONB NOT NULL DEFAULT '[]',
+    created_by TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    lifted_by TEXT,
+    lifted_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_takedowns_active ON takedowns(target_type, target_id) WHERE lifted_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS reports (
+    id UUID PRIMARY KEY,
+    service_id UUID NOT NULL REFERENCES services(id),
+    provider_id UUID NOT NULL REFERENCES providers(id),
+    reporter_id TEXT NOT NULL,
+    category VARCHAR(50) NOT NULL,
+    details TEXT NOT NULL DEFAULT '',
+    status VARCHAR(20) NOT NULL DEFAULT 'open' CHECK (status IN ('open', 'dismissed', 'actioned')),
+    resolution TEXT NOT NULL DEFAULT '',
+    resolved_by TEXT,
+    resolved_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_reports_status ON reports(status, created_at);
+CREATE INDEX IF NOT EXISTS idx_reports_service ON reports(service_id);
+CREATE INDEX IF NOT EXISTS idx_reports_provider ON reports(provider_id);
+
+-- The audit log is only ever inserted into
+CREATE TABLE IF NOT EXISTS moderation_actions (
+    id UUID PRIMARY KEY,
+    action VARCHAR(20) NOT NULL,
+    target_type VARCHAR(20) NOT NULL,
+    target_id UUID NOT NULL,
+    actor TEXT NOT NULL DEFAULT '',
+    reason TEXT NOT NULL DEFAULT '',
+    takedown_id UUID REFERENCES takedowns(id),
+    report_id UUID REFERENCES reports(id),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_moderation_actions_target ON moderation_actions(target_type, target_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_moderation_actions_created ON moderation_actions(created_at DESC);
@@ -0,0 +1,11 @@
+-- Consumers report listings. Each report is re-validated by the policy
+-- engine; upheld reports, flagged by re-validation or actioned by an
+-- operator, are counted on the service and published with it, so discovery
+-- can demote repeat offenders.
+ALTER TABLE services ADD COLUMN IF NOT EXISTS upheld_reports INTEGER NOT NULL DEFAULT 0;
+
+ALTER TABLE reports ADD COLUMN IF NOT EXISTS upheld BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE reports ADD COLUMN IF NOT EXISTS revalidation JSONB;
+
+-- A consumer has one open report about a service at a time
+CREATE UNIQUE INDEX IF NOT EXISTS idx_reports_open_reporter ON reports(service_id, reporter_id) WHERE status = 'open';
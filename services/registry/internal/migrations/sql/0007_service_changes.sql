@@ -0,0 +1,33 @@
+-- The changelog of each service: changes to its pricing, SLA and
+-- capabilities, stored with the catalog event that publishes them.
+CREATE TABLE IF NOT EXISTS service_changes (
+    id BIGSERIAL PRIMARY KEY,
+    service_id UUID NOT NULL REFERENCES services(id),
+    revision BIGINT NOT NULL,
+    kind VARCHAR(20) NOT NULL,
+    change JSONB NOT NULL,
+    effective_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_service_changes_service ON service_changes(service_id, revision DESC);
+
+-- Price changes are scheduled ahead, and subscribed consumers are sent a
+-- notice through the outbox when they are scheduled or cancelled
+CREATE TABLE IF NOT EXISTS price_changes (
+    id UUID PRIMARY KEY,
+    service_id UUID NOT NULL REFERENCES services(id),
+    provider_id UUID NOT NULL REFERENCES providers(id),
+    old_pricing JSONB,
+    new_pricing JSONB NOT NULL,
+    effective_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'scheduled' CHECK (status IN ('scheduled', 'applied', 'cancelled')),
+    notified INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    applied_at TIMESTAMP WITH TIME ZONE,
+    cancelled_at TIMESTAMP WITH TIME ZONE
+);
+
+-- A service has one scheduled price change at a time
+CREATE UNIQUE INDEX IF NOT EXISTS idx_price_changes_scheduled ON price_changes(service_id) WHERE status = 'scheduled';
+CREATE INDEX IF NOT EXISTS idx_price_changes_due ON price_changes(effective_at) WHERE status = 'scheduled';
+CREATE INDEX IF NOT EXISTS idx_price_changes_service ON price_changes(service_id, created_at DESC);
@@ -0,0 +1,4 @@
+-- Private services: an enterprise registers its own model endpoints through
+-- a provider bound to its tenant. The services' class and tenant are kept
+-- in their descriptors.
+ALTER TABLE providers ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(255);
@@ -0,0 +1,20 @@
+-- Compliance artifacts: certification evidence providers upload, e.g. SOC 2
+-- reports and ISO 27001 certificates. The files are kept in object storage
+-- under object_key; the policy engine verifies certifications against the
+-- digest and expiry kept here.
+CREATE TABLE IF NOT EXISTS compliance_artifacts (
+    id UUID PRIMARY KEY,
+    provider_id UUID NOT NULL REFERENCES providers(id),
+    certification VARCHAR(100) NOT NULL,
+    issuer TEXT,
+    file_name TEXT,
+    content_type VARCHAR(255) NOT NULL,
+    size BIGINT NOT NULL,
+    sha256 CHAR(64) NOT NULL,
+    object_key TEXT NOT NULL,
+    issued_at TIMESTAMP WITH TIME ZONE,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_compliance_artifacts_provider ON compliance_artifacts(provider_id, created_at DESC);
@@ -0,0 +1,4 @@
+-- Certifications and data-processing agreements of a service found expiring
+-- or expired, as flagged by the registry's expiry check. They are published
+-- with the service, so discovery can lower its compliance score.
+ALTER TABLE services ADD COLUMN IF NOT EXISTS compliance_expiries JSONB NOT NULL DEFAULT '[]';
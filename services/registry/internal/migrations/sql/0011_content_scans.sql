@@ -0,0 +1,7 @@
+-- The outcome of scanning a service's text for personal data, prohibited
+-- claims and unsafe content. NULL for services never scanned. Pending scans
+-- are picked up by the background scanner, and flagged or blocked ones
+-- listed for operators to review.
+ALTER TABLE services ADD COLUMN IF NOT EXISTS content_scan JSONB;
+
+CREATE INDEX IF NOT EXISTS idx_services_content_scan ON services ((content_scan->>'status')) WHERE content_scan IS NOT NULL;
@@ -0,0 +1,3 @@
+-- Prompt templates are listed alongside services. An item's kind is kept in
+-- its descriptor; services registered before templates existed have none.
+CREATE INDEX IF NOT EXISTS idx_services_kind ON services ((COALESCE(descriptor->>'kind', 'service')));
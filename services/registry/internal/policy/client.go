@@ -0,0 +1,282 @@
+// Package policy validates descriptors with the policy engine over gRPC, and
+// blocks services operators take down
+package policy
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/org/llm-marketplace/pkg/marketplace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "github.com/org/llm-marketplace/services/registry/api/proto/policyengine/v1"
+	"github.com/org/llm-marketplace/services/registry/internal/config"
+	"github.com/org/llm-marketplace/services/registry/internal/registry"
+)
+
+// Dial connects to the policy engine. The connection is made lazily, so this
+// succeeds while the policy engine is down.
+func Dial(cfg config.PolicyEngineConfig) (*grpc.ClientConn, error) {
+	conn, err := grpc.NewClient(cfg.GRPCEndpoint, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to policy engine: %w", err)
+	}
+	return conn, nil
+}
+
+// Client implements registry.PolicyValidator and registry.ConsumptionBlocker
+type Client struct {
+	client  pb.PolicyEngineServiceClient
+	timeout time.Duration
+}
+
+// NewClient creates a client on conn; each call is limited to timeout
+func NewClient(conn grpc.ClientConnInterface, timeout time.Duration) *Client {
+	return &Client{client: pb.NewPolicyEngineServiceClient(conn), timeout: timeout}
+}
+
+// ValidateService asks the policy engine whether desc and the services it
+// depends on comply with the marketplace policies. Any failure to get an answer is reported as
+// registry.ErrPolicyUnavailable, so nothing is registered unchecked.
+func (c *Client) ValidateService(ctx context.Context, desc *marketplace.ServiceDescriptor, dependencies []*marketplace.ServiceDescriptor) (*registry.PolicyResult, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	req := ToProto(desc)
+	for _, dep := range dependencies {
+		req.DependencyServices = append(req.DependencyServices, ToProto(dep))
+	}
+	resp, err := c.client.ValidateService(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", registry.ErrPolicyUnavailable, err)
+	}
+
+	result := &registry.PolicyResult{Compliant: resp.GetCompliant(), PolicyVersion: resp.GetPolicyVersion()}
+	for _, v := range resp.GetViolations() {
+		result.Violations = append(result.Violations, registry.Violation{
+			PolicyID:    v.GetPolicyId(),
+			PolicyName:  v.GetPolicyName(),
+			Severity:    v.GetSeverity(),
+			Message:     v.GetMessage(),
+			Remediation: v.GetRemediation(),
+			Field:       v.GetField(),
+		})
+	}
+	return result, nil
+}
+
+// BlockServices creates an access-control policy blocking serviceIDs, named
+// after the takedown. Failures are reported as registry.ErrPolicyUnavailable,
+// so nothing is taken down that can still be consumed.
+func (c *Client) BlockServices(ctx context.Context, takedown *registry.Takedown, serviceIDs []string) (string, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	resp, err := c.client.CreatePolicy(ctx, &pb.CreatePolicyRequest{Policy: &pb.Policy{
+		Name:        "takedown-" + takedown.ID,
+		Description: takedown.Reason,
+		Type:        pb.PolicyType_ACCESS_CONTROL,
+		Enabled:     true,
+		Severity:    "critical",
+		Rule: &pb.PolicyRule{Rule: &pb.PolicyRule_AccessControl{AccessControl: &pb.AccessControlRule{
+			BlockedServiceIds: serviceIDs,
+		}}},
+		Metadata: map[string]string{
+			"source":      "registry",
+			"takedown_id": takedown.ID,
+			"target_type": takedown.TargetType,
+			"target_id":   takedown.TargetID,
+		},
+	}})
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", registry.ErrPolicyUnavailable, err)
+	}
+	return resp.GetPolicy().GetId(), nil
+}
+
+// UnblockServices deletes a policy BlockServices created
+func (c *Client) UnblockServices(ctx context.Context, policyID string) error {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	_, err := c.client.DeletePolicy(ctx, &pb.DeletePolicyRequest{PolicyId: policyID})
+	if err != nil && status.Code(err) != codes.NotFound {
+		return fmt.Errorf("%w: %v", registry.ErrPolicyUnavailable, err)
+	}
+	return nil
+}
+
+// Check reports whether the policy engine is serving, for readiness
+func (c *Client) Check(ctx context.Context) error {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	resp, err := c.client.HealthCheck(ctx, &pb.HealthCheckRequest{Service: "registry"})
+	if err != nil {
+		return err
+	}
+	if resp.GetStatus() != pb.HealthCheckResponse_SERVING {
+		return fmt.Errorf("policy engine is %s", resp.GetStatus())
+	}
+	return nil
+}
+
+func (c *Client) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if c.timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, c.timeout)
+}
+
+// ToProto converts a descriptor to a validation request
+func ToProto(desc *marketplace.ServiceDescriptor) *pb.ValidateServiceRequest {
+	req := &pb.ValidateServiceRequest{
+		ServiceId:      desc.ServiceID,
+		Name:           desc.Name,
+		Version:        desc.Version,
+		Description:    desc.Description,
+		ProviderId:     desc.ProviderID,
+		Category:       desc.Category,
+		Endpoint:       EndpointToProto(desc.Endpoint),
+		Compliance:     ComplianceToProto(desc.Compliance),
+		Sla:            SLAToProto(desc.SLA),
+		Pricing:        PricingToProto(desc.Pricing),
+		ServiceClass:   desc.Class,
+		TenantId:       desc.TenantID,
+		Kind:           desc.Kind,
+		PromptTemplate: PromptTemplateToProto(desc.PromptTemplate),
+		Dataset:        DatasetToProto(desc.Dataset),
+		FineTuning:     FineTuningToProto(desc.FineTuning),
+	}
+	for _, c := range desc.Capabilities {
+		req.Capabilities = append(req.Capabilities, CapabilityToProto(c))
+	}
+	for _, d := range desc.Dependencies {
+		req.Dependencies = append(req.Dependencies, &pb.ServiceDependency{ServiceId: d.ServiceID, Role: d.Role})
+	}
+	for _, r := range desc.ServingRegions {
+		req.ServingRegions = append(req.ServingRegions, &pb.ServingRegion{Region: r.Region, MedianLatencyMs: int32(r.MedianLatencyMS)})
+	}
+	return req
+}
+
+// The section converters are the inverse of the marketplace *FromProto
+// converters. A nil section converts to a nil message.
+
+// EndpointToProto converts an endpoint section
+func EndpointToProto(e *marketplace.EndpointInfo) *pb.ServiceEndpoint {
+	if e == nil {
+		return nil
+	}
+	return &pb.ServiceEndpoint{Url: e.URL, Protocol: e.Protocol, Authentication: e.Authentication}
+}
+
+// ComplianceToProto converts a compliance section with the artifacts
+// attached to it
+func ComplianceToProto(c *marketplace.ComplianceInfo) *pb.ServiceCompliance {
+	if c == nil {
+		return nil
+	}
+	compliance := &pb.ServiceCompliance{
+		Level:          c.Level,
+		Certifications: c.Certifications,
+		DataResidency:  c.DataResidency,
+		GdprCompliant:  c.GDPRCompliant,
+		HipaaCompliant: c.HIPAACompliant,
+	}
+	for _, a := range c.Artifacts {
+		compliance.Artifacts = append(compliance.Artifacts, &pb.ComplianceArtifact{
+			Certification: a.Certification,
+			Sha256:        a.SHA256,
+			ExpiresAt:     timestamppb.New(a.ExpiresAt),
+		})
+	}
+	return compliance
+}
+
+// SLAToProto converts an SLA section
+func SLAToProto(s *marketplace.SLAInfo) *pb.ServiceSLA {
+	if s == nil {
+		return nil
+	}
+	return &pb.ServiceSLA{Availability: s.Availability, MaxLatency: int32(s.MaxLatencyMS), SupportLevel: s.SupportLevel}
+}
+
+// PricingToProto converts a pricing section. The message has no headline
+// rate, so pricing without tiers is sent as a single tier.
+func PricingToProto(p *marketplace.PricingInfo) *pb.ServicePricing {
+	if p == nil {
+		return nil
+	}
+	pricing := &pb.ServicePricing{Model: p.Model, Currency: p.Currency}
+	tiers := p.Tiers
+	if len(tiers) == 0 && (p.Rate != 0 || p.Unit != "") {
+		tiers = []marketplace.PricingTier{{Rate: p.Rate, Unit: p.Unit}}
+	}
+	for _, t := range tiers {
+		pricing.Rates = append(pricing.Rates, &pb.PricingTier{Tier: t.Tier, Rate: t.Rate, Unit: t.Unit, Description: t.Description})
+	}
+	return pricing
+}
+
+// PromptTemplateToProto converts a prompt template section
+func PromptTemplateToProto(t *marketplace.PromptTemplate) *pb.PromptTemplate {
+	if t == nil {
+		return nil
+	}
+	template := &pb.PromptTemplate{Template: t.Template, ModelFamilies: t.ModelFamilies}
+	for _, v := range t.Variables {
+		template.Variables = append(template.Variables, &pb.TemplateVariable{
+			Name:        v.Name,
+			Description: v.Description,
+			Required:    v.Required,
+			Default:     v.Default,
+		})
+	}
+	return template
+}
+
+// DatasetToProto converts a dataset section
+func DatasetToProto(d *marketplace.DatasetInfo) *pb.Dataset {
+	if d == nil {
+		return nil
+	}
+	dataset := &pb.Dataset{
+		License:     d.License,
+		Attribution: d.Attribution,
+		Format:      d.Format,
+		SizeBytes:   d.SizeBytes,
+		Records:     d.Records,
+	}
+	if p := d.Provenance; p != nil {
+		dataset.Provenance = &pb.DatasetProvenance{Source: p.Source, Method: p.Method, Url: p.URL}
+	}
+	return dataset
+}
+
+// FineTuningToProto converts a fine-tuning section
+func FineTuningToProto(o *marketplace.FineTuningOffering) *pb.FineTuning {
+	if o == nil {
+		return nil
+	}
+	fineTuning := &pb.FineTuning{BaseModels: o.BaseModels, TurnaroundHours: o.TurnaroundHours}
+	if r := o.DataRequirements; r != nil {
+		fineTuning.DataRequirements = &pb.FineTuningDataRequirements{
+			Formats:      r.Formats,
+			MinExamples:  r.MinExamples,
+			MaxExamples:  r.MaxExamples,
+			MaxSizeBytes: r.MaxSizeBytes,
+		}
+	}
+	return fineTuning
+}
+
+// CapabilityToProto converts a capability
+func CapabilityToProto(c marketplace.Capability) *pb.ServiceCapability {
+	return &pb.ServiceCapability{Name: c.Name, Description: c.Description}
+}
@@ -0,0 +1,138 @@
+// Package publisher relays catalog and validation events, price-change
+// notices and compliance expiry notices from the outbox to Kafka. Events are
+// published in the order they were stored; catalog events are keyed by
+// service ID, so consumers see each service's changes in order.
+package publisher
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/segmentio/kafka-go"
+	"go.uber.org/zap"
+
+	"github.com/org/llm-marketplace/services/registry/internal/config"
+)
+
+var (
+	eventsPublished = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "registry_outbox_events_published_total",
+		Help: "Outbox events published to Kafka",
+	})
+	publishFailures = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "registry_outbox_publish_failures_total",
+		Help: "Outbox batches that could not be published",
+	})
+)
+
+// Outbox streams, each published to its own topic
+const (
+	CatalogStream     = "catalog"
+	ValidationStream  = "validation"
+	PriceChangeStream = "price_change"
+	ExpiryStream      = "compliance_expiry"
+)
+
+// Event is a stored event
+type Event struct {
+	ID      int64
+	Stream  string
+	Key     string // The service ID of catalog events, the provider ID of validation events, the consumer ID of price-change notices, the recipient ID of expiry notices
+	Type    string
+	Payload []byte
+}
+
+// Outbox hands out stored events to publish
+type Outbox interface {
+	// PublishPending passes up to limit of the oldest events to publish and
+	// removes them if it succeeds. It returns how many were published.
+	PublishPending(ctx context.Context, limit int, publish func([]Event) error) (int, error)
+}
+
+// Writer writes messages to Kafka; *kafka.Writer satisfies it
+type Writer interface {
+	WriteMessages(ctx context.Context, msgs ...kafka.Message) error
+}
+
+// NewWriter creates a Kafka writer; each message names its topic. Writes
+// wait for every in-sync replica, so an event removed from the outbox is
+// durable.
+func NewWriter(cfg config.KafkaConfig) *kafka.Writer {
+	return &kafka.Writer{
+		Addr:         kafka.TCP(cfg.Brokers...),
+		Balancer:     &kafka.Hash{},
+		RequiredAcks: kafka.RequireAll,
+		WriteTimeout: cfg.WriteTimeout,
+	}
+}
+
+// Relay moves events from the outbox to Kafka
+type Relay struct {
+	outbox Outbox
+	writer Writer
+	topics map[string]string // By stream
+	config config.OutboxConfig
+	logger *zap.Logger
+}
+
+// NewRelay creates a relay from outbox to writer, publishing each stream to
+// its topic in kafka
+func NewRelay(outbox Outbox, writer Writer, kafka config.KafkaConfig, cfg config.OutboxConfig, logger *zap.Logger) *Relay {
+	topics := map[string]string{CatalogStream: kafka.Topic, ValidationStream: kafka.ValidationTopic, PriceChangeStream: kafka.PriceChangeTopic, ExpiryStream: kafka.ComplianceExpiryTopic}
+	return &Relay{outbox: outbox, writer: writer, topics: topics, config: cfg, logger: logger}
+}
+
+// Start publishes events until ctx is cancelled. A full batch is followed
+// straight away by the next; while Kafka fails, attempts back off up to
+// the configured maximum.
+func (r *Relay) Start(ctx context.Context) {
+	r.logger.Info("Starting outbox relay", zap.Duration("poll_interval", r.config.PollInterval))
+
+	backoff := r.config.PollInterval
+	for {
+		wait := r.config.PollInterval
+		n, err := r.PublishOnce(ctx)
+		switch {
+		case err != nil && ctx.Err() == nil:
+			publishFailures.Inc()
+			r.logger.Warn("Failed to publish outbox events", zap.Error(err), zap.Duration("retry_in", backoff))
+			wait = backoff
+			backoff = min(backoff*2, max(r.config.MaxBackoff, r.config.PollInterval))
+		case n == r.config.BatchSize:
+			backoff = r.config.PollInterval
+			wait = 0
+		default:
+			backoff = r.config.PollInterval
+		}
+
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			r.logger.Info("Outbox relay stopped")
+			return
+		}
+	}
+}
+
+// PublishOnce publishes one batch of events and returns how many were sent
+func (r *Relay) PublishOnce(ctx context.Context) (int, error) {
+	n, err := r.outbox.PublishPending(ctx, r.config.BatchSize, func(events []Event) error {
+		msgs := make([]kafka.Message, len(events))
+		for i, e := range events {
+			msgs[i] = kafka.Message{
+				Topic:   r.topics[e.Stream],
+				Key:     []byte(e.Key),
+				Value:   e.Payload,
+				Headers: []kafka.Header{{Key: "event_type", Value: []byte(e.Type)}},
+			}
+		}
+		return r.writer.WriteMessages(ctx, msgs...)
+	})
+	if n > 0 {
+		eventsPublished.Add(float64(n))
+		r.logger.Debug("Published outbox events", zap.Int("count", n))
+	}
+	return n, err
+}
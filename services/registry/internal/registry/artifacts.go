@@ -0,0 +1,209 @@
+package registry
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/org/llm-marketplace/pkg/marketplace"
+	"go.uber.org/zap"
+)
+
+// ErrArtifactsUnavailable is returned for artifact uploads while no object
+// store is set
+var ErrArtifactsUnavailable = errors.New("compliance artifact storage is not configured")
+
+// ComplianceArtifact is evidence of a certification a provider uploaded,
+// such as a SOC 2 report or an ISO 27001 certificate. The file is kept in
+// object storage; the registry keeps its digest and metadata, which the
+// policy engine verifies certifications against.
+type ComplianceArtifact struct {
+	ID            string     `json:"id"`
+	ProviderID    string     `json:"provider_id"`
+	Certification string     `json:"certification"`
+	Issuer        string     `json:"issuer,omitempty"` // The auditor or certification body
+	FileName      string     `json:"file_name,omitempty"`
+	ContentType   string     `json:"content_type"`
+	Size          int64      `json:"size"`
+	SHA256        string     `json:"sha256"` // Hex digest of the file
+	ObjectKey     string     `json:"-"`
+	IssuedAt      *time.Time `json:"issued_at,omitempty"`
+	ExpiresAt     time.Time  `json:"expires_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+// ArtifactUpload is an artifact file with its metadata
+type ArtifactUpload struct {
+	Certification string
+	Issuer        string
+	FileName      string
+	ContentType   string
+	// SHA256 is the digest the provider expects, e.g. one published by the
+	// auditor. When set the file must match it.
+	SHA256    string
+	IssuedAt  *time.Time
+	ExpiresAt time.Time
+	Content   []byte
+}
+
+// ObjectStore keeps artifact files
+type ObjectStore interface {
+	Put(ctx context.Context, key, contentType string, body io.ReadSeeker, size int64) error
+	Delete(ctx context.Context, key string) error
+}
+
+// ArtifactStore persists artifact metadata
+type ArtifactStore interface {
+	CreateArtifact(ctx context.Context, a *ComplianceArtifact) error
+	// ListArtifacts returns a provider's artifacts, newest first
+	ListArtifacts(ctx context.Context, providerID string) ([]*ComplianceArtifact, error)
+	// DeleteArtifact deletes one of the provider's artifacts and returns it,
+	// or ErrNotFound
+	DeleteArtifact(ctx context.Context, providerID, id string) (*ComplianceArtifact, error)
+}
+
+// SetArtifactStore sets where artifact files are kept, and the largest file
+// accepted. Uploads fail with ErrArtifactsUnavailable until it is set.
+func (s *Service) SetArtifactStore(objects ObjectStore, prefix string, maxSize int64) {
+	s.objects = objects
+	s.objectPrefix = prefix
+	s.maxArtifactSize = maxSize
+}
+
+// MaxArtifactSize returns the largest artifact file accepted, in bytes
+func (s *Service) MaxArtifactSize() int64 {
+	return s.maxArtifactSize
+}
+
+// UploadArtifact stores an artifact for the caller. Its certification and
+// expiry are required, and an artifact already expired is refused.
+func (s *Service) UploadArtifact(ctx context.Context, caller string, upload ArtifactUpload) (*ComplianceArtifact, error) {
+	if s.objects == nil {
+		return nil, ErrArtifactsUnavailable
+	}
+	sum := sha256.Sum256(upload.Content)
+	digest := hex.EncodeToString(sum[:])
+
+	var verr marketplace.ValidationError
+	if strings.TrimSpace(upload.Certification) == "" {
+		verr = append(verr, marketplace.FieldError{Field: "certification", Message: "is required"})
+	}
+	now := time.Now().UTC()
+	switch {
+	case upload.ExpiresAt.IsZero():
+		verr = append(verr, marketplace.FieldError{Field: "expires_at", Message: "is required"})
+	case !upload.ExpiresAt.After(now):
+		verr = append(verr, marketplace.FieldError{Field: "expires_at", Message: "has passed"})
+	case upload.IssuedAt != nil && !upload.IssuedAt.Before(upload.ExpiresAt):
+		verr = append(verr, marketplace.FieldError{Field: "issued_at", Message: "must be before expires_at"})
+	}
+	switch {
+	case len(upload.Content) == 0:
+		verr = append(verr, marketplace.FieldError{Field: "file", Message: "is required"})
+	case s.maxArtifactSize > 0 && int64(len(upload.Content)) > s.maxArtifactSize:
+		verr = append(verr, marketplace.FieldError{Field: "file", Message: "is too large"})
+	case upload.SHA256 != "" && !strings.EqualFold(upload.SHA256, digest):
+		verr = append(verr, marketplace.FieldError{Field: "sha256", Message: "doesn't match the file"})
+	}
+	if len(verr) > 0 {
+		return nil, verr
+	}
+
+	contentType := upload.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	id := uuid.NewString()
+	artifact := &ComplianceArtifact{
+		ID:            id,
+		ProviderID:    caller,
+		Certification: strings.TrimSpace(upload.Certification),
+		Issuer:        upload.Issuer,
+		FileName:      upload.FileName,
+		ContentType:   contentType,
+		Size:          int64(len(upload.Content)),
+		SHA256:        digest,
+		ObjectKey:     strings.TrimSuffix(s.objectPrefix, "/") + "/" + caller + "/" + id,
+		IssuedAt:      upload.IssuedAt,
+		ExpiresAt:     upload.ExpiresAt.UTC(),
+		CreatedAt:     now,
+	}
+	if err := s.objects.Put(ctx, artifact.ObjectKey, contentType, bytes.NewReader(upload.Content), artifact.Size); err != nil {
+		return nil, err
+	}
+	if err := s.store.CreateArtifact(ctx, artifact); err != nil {
+		s.deleteObject(ctx, artifact)
+		return nil, err
+	}
+	s.logger.Info("Compliance artifact uploaded",
+		zap.String("provider_id", caller),
+		zap.String("artifact_id", id),
+		zap.String("certification", artifact.Certification),
+		zap.Time("expires_at", artifact.ExpiresAt),
+	)
+	return artifact, nil
+}
+
+// Artifacts lists a provider's artifacts, newest first
+func (s *Service) Artifacts(ctx context.Context, providerID string) ([]*ComplianceArtifact, error) {
+	if _, err := s.GetProvider(ctx, providerID); err != nil {
+		return nil, err
+	}
+	return s.store.ListArtifacts(ctx, providerID)
+}
+
+// DeleteArtifact deletes one of the caller's artifacts. Certifications it
+// backed are no longer verified when the caller's services are next checked.
+func (s *Service) DeleteArtifact(ctx context.Context, caller, id string) error {
+	if uuid.Validate(id) != nil {
+		return ErrNotFound
+	}
+	artifact, err := s.store.DeleteArtifact(ctx, caller, id)
+	if err != nil {
+		return err
+	}
+	s.deleteObject(ctx, artifact)
+	s.logger.Info("Compliance artifact deleted", zap.String("provider_id", caller), zap.String("artifact_id", id))
+	return nil
+}
+
+// deleteObject deletes an artifact's file. Failing to doesn't fail the
+// request; the file is unreferenced either way.
+func (s *Service) deleteObject(ctx context.Context, artifact *ComplianceArtifact) {
+	if s.objects == nil {
+		return
+	}
+	if err := s.objects.Delete(ctx, artifact.ObjectKey); err != nil {
+		s.logger.Warn("Failed to delete artifact file", zap.String("artifact_id", artifact.ID), zap.String("key", artifact.ObjectKey), zap.Error(err))
+	}
+}
+
+// withArtifacts returns a copy of desc carrying its provider's artifacts,
+// for the policy engine to verify its certifications against
+func (s *Service) withArtifacts(ctx context.Context, desc *marketplace.ServiceDescriptor, providerID string) (*marketplace.ServiceDescriptor, error) {
+	if desc.Compliance == nil {
+		return desc, nil
+	}
+	artifacts, err := s.store.ListArtifacts(ctx, providerID)
+	if err != nil {
+		return nil, err
+	}
+	checked := *desc
+	compliance := *desc.Compliance
+	compliance.Artifacts = nil
+	for _, a := range artifacts {
+		compliance.Artifacts = append(compliance.Artifacts, marketplace.ComplianceArtifact{
+			Certification: a.Certification,
+			SHA256:        a.SHA256,
+			ExpiresAt:     a.ExpiresAt,
+		})
+	}
+	checked.Compliance = &compliance
+	return &checked, nil
+}
@@ -0,0 +1,364 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/org/llm-marketplace/pkg/marketplace"
+	"go.uber.org/zap"
+)
+
+// Price change statuses
+const (
+	PriceChangeScheduled = "scheduled"
+	PriceChangeApplied   = "applied"
+	PriceChangeCancelled = "cancelled"
+)
+
+// PriceChange is new pricing for a service, scheduled ahead so that its
+// subscribed consumers are told before it takes effect
+type PriceChange struct {
+	ID          string                   `json:"id"`
+	ServiceID   string                   `json:"service_id"`
+	ProviderID  string                   `json:"provider_id"`
+	OldPricing  *marketplace.PricingInfo `json:"old_pricing,omitempty"`
+	NewPricing  marketplace.PricingInfo  `json:"new_pricing"`
+	EffectiveAt time.Time                `json:"effective_at"`
+	Status      string                   `json:"status"`
+	Notified    int                      `json:"notified"` // Subscribed consumers sent a notice when it was scheduled
+	CreatedAt   time.Time                `json:"created_at"`
+	AppliedAt   *time.Time               `json:"applied_at,omitempty"`
+	CancelledAt *time.Time               `json:"cancelled_at,omitempty"`
+}
+
+// ChangeFilter selects changelog entries of a service, newest first. An
+// empty Kind matches every kind.
+type ChangeFilter struct {
+	ServiceID string
+	Kind      string
+	Limit     int
+	Offset    int
+}
+
+// ChangelogStore persists the changelog and scheduled price changes. The
+// changelog entries of a catalog event are stored with it, by every method
+// that stores one.
+type ChangelogStore interface {
+	ListChanges(ctx context.Context, filter ChangeFilter) ([]marketplace.ServiceChange, int, error)
+
+	// SchedulePriceChange saves pc with its notices, unless the service has
+	// another scheduled price change; it returns ErrConflict then
+	SchedulePriceChange(ctx context.Context, pc *PriceChange, notices []*marketplace.PriceChangeNotice) error
+	GetPriceChange(ctx context.Context, id string) (*PriceChange, error)
+	// ListPriceChanges returns a service's price changes, newest first
+	ListPriceChanges(ctx context.Context, serviceID string) ([]*PriceChange, error)
+	// DuePriceChanges returns up to limit scheduled price changes effective
+	// at now, oldest first
+	DuePriceChanges(ctx context.Context, now time.Time, limit int) ([]*PriceChange, error)
+	// CancelPriceChange saves pc as cancelled with its notices, and
+	// ApplyPriceChange saves it as applied with reg and its event, unless reg
+	// is nil. Both return ErrConflict if pc is no longer scheduled.
+	CancelPriceChange(ctx context.Context, pc *PriceChange, notices []*marketplace.PriceChangeNotice) error
+	ApplyPriceChange(ctx context.Context, pc *PriceChange, reg *Registration, event *marketplace.CatalogEvent) error
+}
+
+// SetPriceChangeNotice sets how far ahead price changes are scheduled
+func (s *Service) SetPriceChangeNotice(d time.Duration) {
+	s.priceNotice = d
+}
+
+// Changelog returns a page of a service's changelog, newest first, and the
+// total matching filter
+func (s *Service) Changelog(ctx context.Context, filter ChangeFilter) ([]marketplace.ServiceChange, int, error) {
+	kinds := []string{marketplace.ChangePricing, marketplace.ChangeSLA, marketplace.ChangeCapabilities}
+	if filter.Kind != "" && !slices.Contains(kinds, filter.Kind) {
+		return nil, 0, marketplace.ValidationError{{Field: "kind", Message: "must be pricing, sla or capabilities"}}
+	}
+	if _, err := s.Get(ctx, filter.ServiceID); err != nil {
+		return nil, 0, err
+	}
+	if filter.Limit <= 0 || filter.Limit > 100 {
+		filter.Limit = 20
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+	return s.store.ListChanges(ctx, filter)
+}
+
+// SchedulePriceChange schedules new pricing for one of the caller's
+// services. It takes effect at effectiveAt, which must leave the notice
+// period, or as soon as the notice period allows when effectiveAt is zero.
+// The descriptor with the new pricing is checked by the policy engine now,
+// and every consumer subscribed until then is sent a notice.
+func (s *Service) SchedulePriceChange(ctx context.Context, caller, id string, pricing marketplace.PricingInfo, effectiveAt time.Time) (*PriceChange, error) {
+	reg, provider, err := s.owned(ctx, caller, id)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	earliest := now.Add(s.priceNotice)
+	if effectiveAt.IsZero() {
+		effectiveAt = earliest
+	}
+	var verr marketplace.ValidationError
+	if reg.Service.Pricing != nil && reg.Service.Pricing.Equal(pricing) {
+		verr = append(verr, marketplace.FieldError{Field: "pricing", Message: "is the current pricing"})
+	}
+	if effectiveAt.Before(earliest) {
+		verr = append(verr, marketplace.FieldError{Field: "effective_at", Message: fmt.Sprintf("must be at least %s ahead", s.priceNotice)})
+	}
+	if len(verr) > 0 {
+		return nil, verr
+	}
+
+	desc := reg.Service
+	desc.Pricing = &pricing
+	_, err = s.check(ctx, &desc, provider)
+	s.record(ctx, &desc, reg.ID, err)
+	if err != nil {
+		return nil, err
+	}
+
+	pc := &PriceChange{
+		ID:          uuid.NewString(),
+		ServiceID:   reg.ID,
+		ProviderID:  reg.ProviderID,
+		OldPricing:  reg.Service.Pricing,
+		NewPricing:  pricing,
+		EffectiveAt: effectiveAt.UTC(),
+		Status:      PriceChangeScheduled,
+		CreatedAt:   now,
+	}
+	notices, err := s.notices(ctx, reg, pc, now)
+	if err != nil {
+		return nil, err
+	}
+	pc.Notified = len(notices)
+	if err := s.store.SchedulePriceChange(ctx, pc, notices); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Price change scheduled",
+		zap.String("price_change_id", pc.ID),
+		zap.String("service_id", reg.ID),
+		zap.Time("effective_at", pc.EffectiveAt),
+		zap.Int("notified", pc.Notified),
+	)
+	return pc, nil
+}
+
+// PriceChanges returns the price changes of one of the caller's services,
+// newest first
+func (s *Service) PriceChanges(ctx context.Context, caller, id string) ([]*PriceChange, error) {
+	if _, err := s.visible(ctx, caller, id); err != nil {
+		return nil, err
+	}
+	return s.store.ListPriceChanges(ctx, id)
+}
+
+// CancelPriceChange cancels a scheduled price change of one of the
+// caller's services. The consumers subscribed now are told it is withdrawn.
+func (s *Service) CancelPriceChange(ctx context.Context, caller, id, changeID string) (*PriceChange, error) {
+	reg, _, err := s.owned(ctx, caller, id)
+	if err != nil {
+		return nil, err
+	}
+	pc, err := s.priceChange(ctx, reg.ID, changeID)
+	if err != nil {
+		return nil, err
+	}
+	if pc.Status != PriceChangeScheduled {
+		return nil, fmt.Errorf("%w: price change %s is %s", ErrConflict, pc.ID, pc.Status)
+	}
+
+	now := time.Now().UTC()
+	pc.Status = PriceChangeCancelled
+	pc.CancelledAt = &now
+	notices, err := s.notices(ctx, reg, pc, now)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.store.CancelPriceChange(ctx, pc, notices); err != nil {
+		return nil, err
+	}
+	s.logger.Info("Price change cancelled", zap.String("price_change_id", pc.ID), zap.String("service_id", reg.ID))
+	return pc, nil
+}
+
+// ApplyDuePriceChanges applies the scheduled price changes due at now and
+// returns how many were applied. The new pricing is published with a
+// changelog entry. A change to a service retired in the meantime is
+// cancelled; one that clashes with a concurrent update is left for the
+// next run.
+func (s *Service) ApplyDuePriceChanges(ctx context.Context, now time.Time) (int, error) {
+	due, err := s.store.DuePriceChanges(ctx, now, 100)
+	if err != nil {
+		return 0, err
+	}
+
+	applied := 0
+	for _, pc := range due {
+		err := s.applyPriceChange(ctx, pc, now.UTC())
+		if errors.Is(err, ErrConflict) {
+			s.logger.Warn("Price change deferred", zap.String("price_change_id", pc.ID), zap.Error(err))
+			continue
+		}
+		if err != nil {
+			return applied, err
+		}
+		if pc.Status == PriceChangeApplied {
+			applied++
+		}
+	}
+	return applied, nil
+}
+
+func (s *Service) applyPriceChange(ctx context.Context, pc *PriceChange, now time.Time) error {
+	reg, err := s.store.GetService(ctx, pc.ServiceID)
+	if err != nil {
+		return err
+	}
+	if reg.Status == marketplace.StatusRetired {
+		pc.Status = PriceChangeCancelled
+		pc.CancelledAt = &now
+		return s.store.ApplyPriceChange(ctx, pc, nil, nil)
+	}
+	provider, err := s.store.GetProvider(ctx, reg.ProviderID)
+	if err != nil {
+		return err
+	}
+
+	desc := reg.Service
+	desc.Pricing = &pc.NewPricing
+	changes := marketplace.Changes(&reg.Service, &desc)
+	reg.Service = desc
+	reg.Revision++
+	reg.UpdatedAt = now
+	for i := range changes {
+		changes[i].Revision = reg.Revision
+		changes[i].EffectiveAt = pc.EffectiveAt
+	}
+	event := newEvent(marketplace.ServiceUpdated, reg, provider)
+	event.Changes = changes
+
+	pc.Status = PriceChangeApplied
+	pc.AppliedAt = &now
+	if err := s.store.ApplyPriceChange(ctx, pc, reg, event); err != nil {
+		return err
+	}
+	s.logger.Info("Price change applied",
+		zap.String("price_change_id", pc.ID),
+		zap.String("service_id", reg.ID),
+		zap.Int64("revision", reg.Revision),
+	)
+	return nil
+}
+
+// StartPriceChanges applies due price changes every interval until ctx is
+// cancelled
+func (s *Service) StartPriceChanges(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if _, err := s.ApplyDuePriceChanges(ctx, time.Now()); err != nil && ctx.Err() == nil {
+			s.logger.Warn("Failed to apply price changes", zap.Error(err))
+		}
+	}
+}
+
+// visible returns a service the caller may see the price changes of: any
+// service for operators, their own for providers
+func (s *Service) visible(ctx context.Context, caller, id string) (*Registration, error) {
+	if caller == "" {
+		return s.Get(ctx, id)
+	}
+	reg, err := s.GetOwned(ctx, caller, id)
+	if errors.Is(err, ErrNotFound) {
+		if _, getErr := s.Get(ctx, id); getErr == nil {
+			return nil, errOtherProvider
+		}
+	}
+	return reg, err
+}
+
+// priceChange returns a price change of the service serviceID
+func (s *Service) priceChange(ctx context.Context, serviceID, id string) (*PriceChange, error) {
+	if uuid.Validate(id) != nil {
+		return nil, ErrNotFound
+	}
+	pc, err := s.store.GetPriceChange(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if pc.ServiceID != serviceID {
+		return nil, ErrNotFound
+	}
+	return pc, nil
+}
+
+// notices makes a notice of pc for each consumer with a subscription to
+// reg in force, or yet to start, that doesn't end before pc takes effect
+func (s *Service) notices(ctx context.Context, reg *Registration, pc *PriceChange, now time.Time) ([]*marketplace.PriceChangeNotice, error) {
+	subs, err := s.subscribers(ctx, reg.ID, now)
+	if err != nil {
+		return nil, err
+	}
+	var notices []*marketplace.PriceChangeNotice
+	seen := map[string]bool{}
+	for _, sub := range subs {
+		if seen[sub.ConsumerID] || (sub.EndsAt != nil && !sub.EndsAt.After(pc.EffectiveAt)) {
+			continue
+		}
+		seen[sub.ConsumerID] = true
+		notice := &marketplace.PriceChangeNotice{
+			ID:             uuid.NewString(),
+			OccurredAt:     now,
+			PriceChangeID:  pc.ID,
+			ConsumerID:     sub.ConsumerID,
+			SubscriptionID: sub.ID,
+			ServiceID:      reg.ID,
+			ServiceName:    reg.Service.Name,
+			ProviderID:     reg.ProviderID,
+			NewPricing:     pc.NewPricing,
+			EffectiveAt:    pc.EffectiveAt,
+			Cancelled:      pc.Status == PriceChangeCancelled,
+		}
+		if pc.OldPricing != nil {
+			notice.OldPricing = *pc.OldPricing
+		}
+		notices = append(notices, notice)
+	}
+	return notices, nil
+}
+
+// subscribers returns the subscriptions to a service that are in force at
+// now or yet to start
+func (s *Service) subscribers(ctx context.Context, serviceID string, now time.Time) ([]*Subscription, error) {
+	var subs []*Subscription
+	for _, status := range []string{marketplace.SubscriptionActive, marketplace.SubscriptionPending} {
+		filter := SubscriptionFilter{ServiceID: serviceID, Status: status, At: now, Limit: 100}
+		for {
+			page, total, err := s.store.ListSubscriptions(ctx, filter)
+			if err != nil {
+				return nil, err
+			}
+			subs = append(subs, page...)
+			filter.Offset += len(page)
+			if len(page) == 0 || filter.Offset >= total {
+				break
+			}
+		}
+	}
+	return subs, nil
+}
@@ -0,0 +1,302 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/org/llm-marketplace/pkg/marketplace"
+	"go.uber.org/zap"
+)
+
+// Content scan statuses
+const (
+	ScanPending  = "pending"  // Waiting for the scanner
+	ScanPassed   = "passed"   // Nothing found
+	ScanFlagged  = "flagged"  // Listed, with findings for operators to review
+	ScanBlocked  = "blocked"  // Held back from the catalog until fixed or released
+	ScanReleased = "released" // An operator cleared the findings
+)
+
+// ScanStatuses lists the statuses a content scan can have
+var ScanStatuses = []string{ScanPending, ScanPassed, ScanFlagged, ScanBlocked, ScanReleased}
+
+// What a content finding does to a listing
+const (
+	FindingFlag  = "flag"
+	FindingBlock = "block"
+)
+
+// Moderation actions of content scans, as recorded in the audit log
+const (
+	ActionFlag    = "flag"
+	ActionBlock   = "block"
+	ActionRelease = "release"
+)
+
+// ContentScan is the outcome of checking a listing's text for personal
+// data, prohibited claims and unsafe content. A new service is held
+// suspended until its first scan passes; a change to the text of a listed
+// service is scanned after it is published, and a blocked listing is
+// suspended then.
+type ContentScan struct {
+	Status   string           `json:"status"`
+	Findings []ContentFinding `json:"findings,omitempty"`
+	// HeldStatus is the status a service held suspended by its scan gets
+	// back once the scan passes or is released
+	HeldStatus string     `json:"held_status,omitempty"`
+	ScannedAt  *time.Time `json:"scanned_at,omitempty"`
+	ReleasedBy string     `json:"released_by,omitempty"`
+	ReleasedAt *time.Time `json:"released_at,omitempty"`
+}
+
+// Held reports whether the scan keeps its service suspended
+func (c *ContentScan) Held() bool {
+	return c != nil && c.HeldStatus != ""
+}
+
+// ContentFinding is something a detector found in a listing's text
+type ContentFinding struct {
+	Detector string `json:"detector"`
+	Field    string `json:"field"`    // e.g. description or capabilities[0].description
+	Category string `json:"category"` // e.g. email or prohibited-claim
+	// Match is the text found, left empty by detectors of personal data so
+	// it isn't copied into the registry
+	Match  string `json:"match,omitempty"`
+	Action string `json:"action"` // FindingFlag or FindingBlock
+}
+
+// ContentDetector finds problems in a field of a listing's text
+type ContentDetector interface {
+	Name() string
+	Detect(ctx context.Context, field, text string) ([]ContentFinding, error)
+}
+
+// SetContentDetectors sets the detectors listings' text is scanned with.
+// Listings aren't scanned while there are none.
+func (s *Service) SetContentDetectors(detectors ...ContentDetector) {
+	s.detectors = detectors
+}
+
+// requestScan marks reg's text for scanning if it is new or changed from
+// previous. A new service is held suspended until its scan passes.
+func (s *Service) requestScan(reg *Registration, previous *marketplace.ServiceDescriptor) {
+	if len(s.detectors) == 0 {
+		return
+	}
+	if previous != nil && reg.ContentScan != nil && slices.Equal(scannedText(previous), scannedText(&reg.Service)) {
+		return
+	}
+	scan := &ContentScan{Status: ScanPending}
+	switch {
+	case previous == nil:
+		scan.HeldStatus = reg.Status
+		reg.Status = marketplace.StatusSuspended
+	case reg.ContentScan.Held():
+		scan.HeldStatus = reg.ContentScan.HeldStatus
+	}
+	reg.ContentScan = scan
+}
+
+// ScanContent scans the text of the services waiting for a scan and returns
+// how many were scanned. A service whose scan fails, or that clashes with a
+// concurrent update, is left for the next run.
+func (s *Service) ScanContent(ctx context.Context) (int, error) {
+	if len(s.detectors) == 0 {
+		return 0, nil
+	}
+	scanned := 0
+	filter := ServiceFilter{ContentScan: ScanPending, Limit: 100}
+	for {
+		page, _, err := s.store.ListServices(ctx, filter)
+		if err != nil {
+			return scanned, err
+		}
+		for _, reg := range page {
+			err := s.scan(ctx, reg)
+			if err != nil && ctx.Err() != nil {
+				return scanned, ctx.Err()
+			}
+			if err != nil {
+				// Left pending, so skipped by the next page's offset
+				filter.Offset++
+				s.logger.Warn("Content scan deferred", zap.String("service_id", reg.ID), zap.Error(err))
+				continue
+			}
+			scanned++
+		}
+		if len(page) < filter.Limit {
+			break
+		}
+	}
+	return scanned, nil
+}
+
+// scan runs the detectors over reg's text and saves the outcome
+func (s *Service) scan(ctx context.Context, reg *Registration) error {
+	var findings []ContentFinding
+	text := scannedText(&reg.Service)
+	for i := 0; i < len(text); i += 2 {
+		if text[i+1] == "" {
+			continue
+		}
+		for _, detector := range s.detectors {
+			found, err := detector.Detect(ctx, text[i], text[i+1])
+			if err != nil {
+				return fmt.Errorf("detector %s: %w", detector.Name(), err)
+			}
+			findings = append(findings, found...)
+		}
+	}
+
+	now := time.Now().UTC()
+	scan := &ContentScan{Status: ScanPassed, Findings: findings, HeldStatus: reg.ContentScan.HeldStatus, ScannedAt: &now}
+	for _, f := range findings {
+		if f.Action == FindingBlock {
+			scan.Status = ScanBlocked
+			break
+		}
+		scan.Status = ScanFlagged
+	}
+	switch {
+	case reg.Status == marketplace.StatusRetired:
+		scan.HeldStatus = ""
+	case scan.Status == ScanBlocked && !scan.Held() && reg.Status != marketplace.StatusSuspended:
+		scan.HeldStatus = reg.Status
+		reg.Status = marketplace.StatusSuspended
+	case scan.Status != ScanBlocked && scan.Held():
+		reg.Status = scan.HeldStatus
+		scan.HeldStatus = ""
+	}
+	reg.ContentScan = scan
+	reg.Revision++
+	reg.UpdatedAt = now
+
+	provider, err := s.store.GetProvider(ctx, reg.ProviderID)
+	if err != nil {
+		return err
+	}
+	event := newEvent(marketplace.ServiceUpdated, reg, provider)
+	if scan.Status == ScanPassed {
+		err = s.store.UpdateService(ctx, reg, event)
+	} else {
+		// Findings go in the moderation audit log for operators to review
+		action := ActionFlag
+		if scan.Status == ScanBlocked {
+			action = ActionBlock
+		}
+		err = s.store.SaveModeration(ctx, &ModerationChange{
+			Action:   newAction(action, TargetService, reg.ID, "", findingsSummary(findings), now),
+			Services: []*Registration{reg},
+			Events:   []*marketplace.CatalogEvent{event},
+		})
+	}
+	if err != nil {
+		return err
+	}
+	s.logger.Info("Content scanned",
+		zap.String("service_id", reg.ID),
+		zap.String("scan", scan.Status),
+		zap.Int("findings", len(findings)),
+		zap.String("status", reg.Status),
+	)
+	return nil
+}
+
+// ReleaseContentScan clears the findings of a flagged or blocked scan after
+// an operator's review. A service held by the scan gets its status back.
+func (s *Service) ReleaseContentScan(ctx context.Context, operator, id, reason string) (*Registration, error) {
+	if reason == "" {
+		return nil, marketplace.ValidationError{{Field: "reason", Message: "is required"}}
+	}
+	reg, provider, err := s.owned(ctx, "", id)
+	if err != nil {
+		return nil, err
+	}
+	if reg.ContentScan == nil || (reg.ContentScan.Status != ScanFlagged && reg.ContentScan.Status != ScanBlocked) {
+		return nil, fmt.Errorf("%w: service %s has no content findings to release", ErrConflict, reg.ID)
+	}
+
+	now := time.Now().UTC()
+	scan := *reg.ContentScan
+	scan.Status = ScanReleased
+	scan.ReleasedBy = operator
+	scan.ReleasedAt = &now
+	if scan.Held() {
+		reg.Status = scan.HeldStatus
+		scan.HeldStatus = ""
+	}
+	reg.ContentScan = &scan
+	reg.Revision++
+	reg.UpdatedAt = now
+	err = s.store.SaveModeration(ctx, &ModerationChange{
+		Action:   newAction(ActionRelease, TargetService, reg.ID, operator, reason, now),
+		Services: []*Registration{reg},
+		Events:   []*marketplace.CatalogEvent{newEvent(marketplace.ServiceUpdated, reg, provider)},
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.logger.Info("Content scan released",
+		zap.String("service_id", reg.ID),
+		zap.String("operator", operator),
+		zap.String("status", reg.Status),
+	)
+	return reg, nil
+}
+
+// StartContentScan scans waiting services every interval until ctx is
+// cancelled
+func (s *Service) StartContentScan(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if _, err := s.ScanContent(ctx); err != nil && ctx.Err() == nil {
+			s.logger.Warn("Failed to scan content", zap.Error(err))
+		}
+	}
+}
+
+// scannedText returns the fields of desc that are scanned, as pairs of
+// field name and text
+func scannedText(desc *marketplace.ServiceDescriptor) []string {
+	text := []string{"name", desc.Name, "description", desc.Description}
+	for i, tag := range desc.Tags {
+		text = append(text, fmt.Sprintf("tags[%d]", i), tag)
+	}
+	for i, c := range desc.Capabilities {
+		text = append(text,
+			fmt.Sprintf("capabilities[%d].name", i), c.Name,
+			fmt.Sprintf("capabilities[%d].description", i), c.Description,
+		)
+	}
+	if t := desc.PromptTemplate; t != nil {
+		text = append(text, "prompt_template.template", t.Template)
+		for i, v := range t.Variables {
+			text = append(text,
+				fmt.Sprintf("prompt_template.variables[%d].description", i), v.Description,
+				fmt.Sprintf("prompt_template.variables[%d].default", i), v.Default,
+			)
+		}
+	}
+	if d := desc.Dataset; d != nil {
+		text = append(text, "dataset.attribution", d.Attribution)
+	}
+	return text
+}
+
+// findingsSummary describes findings for the audit log
+func findingsSummary(findings []ContentFinding) string {
+	parts := make([]string, len(findings))
+	for i, f := range findings {
+		parts[i] = fmt.Sprintf("%s on %s (%s)", f.Category, f.Field, f.Action)
+	}
+	return "Content scan found " + strings.Join(parts, ", ")
+}
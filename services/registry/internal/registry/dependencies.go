@@ -0,0 +1,92 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/org/llm-marketplace/pkg/marketplace"
+)
+
+// maxDependencyDepth bounds how far the dependencies of a service are
+// followed when looking for cycles
+const maxDependencyDepth = 16
+
+// dependencies loads the services desc depends on, which the policy engine
+// checks along with it. Each must be a registered service that isn't retired
+// or suspended, private dependencies must be in desc's tenant, and none may
+// depend on desc, directly or not.
+func (s *Service) dependencies(ctx context.Context, desc *marketplace.ServiceDescriptor) ([]*marketplace.ServiceDescriptor, error) {
+	var verr marketplace.ValidationError
+	var deps []*marketplace.ServiceDescriptor
+	for i, dep := range desc.Dependencies {
+		field := fmt.Sprintf("dependencies[%d].service_id", i)
+		reg, err := s.Get(ctx, dep.ServiceID)
+		if err == nil && reg.Service.Private() && reg.Service.TenantID != desc.TenantID {
+			err = ErrNotFound
+		}
+		if errors.Is(err, ErrNotFound) {
+			verr = append(verr, marketplace.FieldError{Field: field, Message: "is not a registered service"})
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch reg.Status {
+		case marketplace.StatusRetired, marketplace.StatusSuspended:
+			verr = append(verr, marketplace.FieldError{Field: field, Message: "is " + reg.Status})
+			continue
+		}
+		cycle, err := s.dependsOn(ctx, reg, desc.ServiceID)
+		if err != nil {
+			return nil, err
+		}
+		if cycle {
+			verr = append(verr, marketplace.FieldError{Field: field, Message: "depends on this service"})
+			continue
+		}
+		service := reg.Service
+		service.ServiceID = reg.ID
+		checked, err := s.withArtifacts(ctx, &service, reg.ProviderID)
+		if err != nil {
+			return nil, err
+		}
+		deps = append(deps, checked)
+	}
+	if len(verr) > 0 {
+		return nil, verr
+	}
+	return deps, nil
+}
+
+// dependsOn reports whether reg depends on the service id, directly or
+// through its dependencies. Services that are no longer registered are
+// skipped.
+func (s *Service) dependsOn(ctx context.Context, reg *Registration, id string) (bool, error) {
+	visited := map[string]bool{reg.ID: true}
+	next := []*Registration{reg}
+	for depth := 0; depth < maxDependencyDepth && len(next) > 0; depth++ {
+		var level []*Registration
+		for _, r := range next {
+			for _, dep := range r.Service.Dependencies {
+				if dep.ServiceID == id {
+					return true, nil
+				}
+				if visited[dep.ServiceID] {
+					continue
+				}
+				visited[dep.ServiceID] = true
+				found, err := s.Get(ctx, dep.ServiceID)
+				if errors.Is(err, ErrNotFound) {
+					continue
+				}
+				if err != nil {
+					return false, err
+				}
+				level = append(level, found)
+			}
+		}
+		next = level
+	}
+	return false, nil
+}
@@ -0,0 +1,188 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/org/llm-marketplace/pkg/marketplace"
+	"go.uber.org/zap"
+)
+
+// ExpiryStore persists the compliance expiries of services
+type ExpiryStore interface {
+	// UpdateExpiries saves reg as UpdateService does, with notices of its
+	// changed expiries
+	UpdateExpiries(ctx context.Context, reg *Registration, event *marketplace.CatalogEvent, notices []*marketplace.ComplianceExpiryNotice) error
+}
+
+// SetComplianceExpiry sets how long before a certification or
+// data-processing agreement lapses it is flagged as expiring, and the
+// recipient notified for the marketplace operators. Operators aren't
+// notified while recipient is empty.
+func (s *Service) SetComplianceExpiry(warning time.Duration, recipient string) {
+	s.expiryWarning = warning
+	s.expiryRecipient = recipient
+}
+
+// CheckComplianceExpiries flags the certifications and data-processing
+// agreements of active and deprecated services that are expiring or expired
+// at now, and returns how many services changed. A service whose expiries
+// changed is published again with them, and its provider and the operators
+// are notified of each one that started expiring or expired. A service that
+// clashes with a concurrent update is left for the next run.
+func (s *Service) CheckComplianceExpiries(ctx context.Context, now time.Time) (int, error) {
+	now = now.UTC()
+	artifacts := map[string][]*ComplianceArtifact{} // By provider
+	changed := 0
+	for _, status := range []string{marketplace.StatusActive, marketplace.StatusDeprecated} {
+		filter := ServiceFilter{Status: status, Limit: 100}
+		for {
+			page, total, err := s.store.ListServices(ctx, filter)
+			if err != nil {
+				return changed, err
+			}
+			for _, reg := range page {
+				if _, ok := artifacts[reg.ProviderID]; !ok {
+					if artifacts[reg.ProviderID], err = s.store.ListArtifacts(ctx, reg.ProviderID); err != nil {
+						return changed, err
+					}
+				}
+				updated, err := s.updateExpiries(ctx, reg, artifacts[reg.ProviderID], now)
+				if errors.Is(err, ErrConflict) {
+					s.logger.Warn("Compliance expiry check deferred", zap.String("service_id", reg.ID), zap.Error(err))
+					continue
+				}
+				if err != nil {
+					return changed, err
+				}
+				if updated {
+					changed++
+				}
+			}
+			filter.Offset += len(page)
+			if len(page) == 0 || filter.Offset >= total {
+				break
+			}
+		}
+	}
+	return changed, nil
+}
+
+// updateExpiries saves reg's expiries as of now if they changed, and
+// reports whether they did
+func (s *Service) updateExpiries(ctx context.Context, reg *Registration, artifacts []*ComplianceArtifact, now time.Time) (bool, error) {
+	expiries := complianceExpiries(&reg.Service, artifacts, now, s.expiryWarning)
+	if expiriesEqual(reg.ComplianceExpiries, expiries) {
+		return false, nil
+	}
+	provider, err := s.store.GetProvider(ctx, reg.ProviderID)
+	if err != nil {
+		return false, err
+	}
+
+	var notices []*marketplace.ComplianceExpiryNotice
+	for _, expiry := range expiries {
+		if flagged(reg.ComplianceExpiries, expiry) {
+			continue
+		}
+		for _, recipient := range []string{reg.ProviderID, s.expiryRecipient} {
+			if recipient == "" {
+				continue
+			}
+			notices = append(notices, &marketplace.ComplianceExpiryNotice{
+				ID:          uuid.NewString(),
+				OccurredAt:  now,
+				RecipientID: recipient,
+				ProviderID:  reg.ProviderID,
+				ServiceID:   reg.ID,
+				ServiceName: reg.Service.Name,
+				Expiry:      expiry,
+			})
+		}
+	}
+
+	reg.ComplianceExpiries = expiries
+	reg.Revision++
+	reg.UpdatedAt = now
+	if err := s.store.UpdateExpiries(ctx, reg, newEvent(marketplace.ServiceUpdated, reg, provider), notices); err != nil {
+		return false, err
+	}
+	s.logger.Info("Compliance expiries changed",
+		zap.String("service_id", reg.ID),
+		zap.Int("expiries", len(expiries)),
+		zap.Int64("revision", reg.Revision),
+	)
+	return true, nil
+}
+
+// StartComplianceExpiry checks compliance expiries every interval until ctx
+// is cancelled
+func (s *Service) StartComplianceExpiry(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if _, err := s.CheckComplianceExpiries(ctx, time.Now()); err != nil && ctx.Err() == nil {
+			s.logger.Warn("Failed to check compliance expiries", zap.Error(err))
+		}
+	}
+}
+
+// complianceExpiries returns the certifications and data-processing
+// agreement of desc that are expiring or expired at now. A certification
+// lapses with the last of the provider's artifacts for it.
+func complianceExpiries(desc *marketplace.ServiceDescriptor, artifacts []*ComplianceArtifact, now time.Time, warning time.Duration) []marketplace.ComplianceExpiry {
+	if desc.Compliance == nil {
+		return nil
+	}
+	var expiries []marketplace.ComplianceExpiry
+	for _, cert := range desc.Compliance.Certifications {
+		var last time.Time
+		for _, a := range artifacts {
+			if strings.EqualFold(a.Certification, cert) && a.ExpiresAt.After(last) {
+				last = a.ExpiresAt
+			}
+		}
+		if last.IsZero() {
+			continue
+		}
+		if status := marketplace.ExpiryStatus(last, now, warning); status != "" {
+			expiries = append(expiries, marketplace.ComplianceExpiry{Kind: marketplace.ExpiryCertification, Name: cert, Status: status, ExpiresAt: last.UTC()})
+		}
+	}
+	if dpa := desc.Compliance.DataProcessingAgreement; dpa != nil {
+		if status := marketplace.ExpiryStatus(dpa.ExpiresAt, now, warning); status != "" {
+			expiries = append(expiries, marketplace.ComplianceExpiry{Kind: marketplace.ExpiryDataProcessingAgreement, Name: dpa.Reference, Status: status, ExpiresAt: dpa.ExpiresAt.UTC()})
+		}
+	}
+	return expiries
+}
+
+// flagged reports whether expiry was already flagged with its status
+func flagged(expiries []marketplace.ComplianceExpiry, expiry marketplace.ComplianceExpiry) bool {
+	for _, e := range expiries {
+		if e.Kind == expiry.Kind && e.Name == expiry.Name && e.Status == expiry.Status {
+			return true
+		}
+	}
+	return false
+}
+
+func expiriesEqual(a, b []marketplace.ComplianceExpiry) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Kind != b[i].Kind || a[i].Name != b[i].Name || a[i].Status != b[i].Status || !a[i].ExpiresAt.Equal(b[i].ExpiresAt) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,519 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/org/llm-marketplace/pkg/marketplace"
+	"go.uber.org/zap"
+)
+
+// What moderation acts on
+const (
+	TargetService  = "service"
+	TargetProvider = "provider"
+	TargetReport   = "report"
+)
+
+// Moderation actions, as recorded in the audit log
+const (
+	ActionSuspend   = "suspend"
+	ActionReinstate = "reinstate"
+	ActionResolve   = "resolve"
+)
+
+// Report statuses
+const (
+	ReportOpen      = "open"
+	ReportDismissed = "dismissed" // Reviewed, and nothing needed doing
+	ReportActioned  = "actioned"  // Reviewed, and the listing was taken down or fixed
+)
+
+// Takedown is a suspension of a service, or of a provider with all its
+// services, by a marketplace operator. While it is in force the services are
+// suspended, so discovery drops them from search, and an access-control
+// policy in the policy engine blocks consuming them.
+type Takedown struct {
+	ID         string `json:"id"`
+	TargetType string `json:"target_type"`
+	TargetID   string `json:"target_id"`
+	Reason     string `json:"reason"`
+	ReportID   string `json:"report_id,omitempty"`
+	// PolicyID is the access-control policy blocking the services; empty
+	// for a provider without services
+	PolicyID  string             `json:"policy_id,omitempty"`
+	Services  []TakenDownService `json:"services"`
+	CreatedBy string             `json:"created_by,omitempty"`
+	CreatedAt time.Time          `json:"created_at"`
+	LiftedBy  string             `json:"lifted_by,omitempty"`
+	LiftedAt  *time.Time         `json:"lifted_at,omitempty"`
+}
+
+// TakenDownService is a service a takedown suspended, with the status it
+// had before
+type TakenDownService struct {
+	ServiceID      string `json:"service_id"`
+	PreviousStatus string `json:"previous_status"`
+}
+
+// ModerationAction is an entry in the moderation audit log. Actor is the
+// operator the gateway named, if any.
+type ModerationAction struct {
+	ID         string    `json:"id"`
+	Action     string    `json:"action"`
+	TargetType string    `json:"target_type"`
+	TargetID   string    `json:"target_id"`
+	Actor      string    `json:"actor,omitempty"`
+	Reason     string    `json:"reason"`
+	TakedownID string    `json:"takedown_id,omitempty"`
+	ReportID   string    `json:"report_id,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// Report is a consumer's abuse report against a listed service
+type Report struct {
+	ID         string `json:"id"`
+	ServiceID  string `json:"service_id"`
+	ProviderID string `json:"provider_id"`
+	ReporterID string `json:"reporter_id"` // The reporting consumer organisation
+	Category   string `json:"category"`
+	Details    string `json:"details,omitempty"`
+	Status     string `json:"status"`
+	// Upheld is set when the listing was found at fault: re-validation
+	// flagged it, or an operator actioned the report. Dismissing the report
+	// clears it.
+	Upheld bool `json:"upheld"`
+	// Revalidation is the policy engine's verdict on the listing when it was
+	// reported; nil if the policy engine couldn't be reached
+	Revalidation *Revalidation `json:"revalidation,omitempty"`
+	Resolution   string        `json:"resolution,omitempty"` // The reviewing operator's note
+	ResolvedBy   string        `json:"resolved_by,omitempty"`
+	ResolvedAt   *time.Time    `json:"resolved_at,omitempty"`
+	CreatedAt    time.Time     `json:"created_at"`
+}
+
+// ReportFilter selects reports to list, oldest first. Empty fields match
+// everything.
+type ReportFilter struct {
+	ServiceID  string
+	ProviderID string
+	Status     string
+	Limit      int
+	Offset     int
+}
+
+// ActionFilter selects audit log entries to list, newest first. Empty
+// fields match everything.
+type ActionFilter struct {
+	TargetType string
+	TargetID   string
+	Actor      string
+	Limit      int
+	Offset     int
+}
+
+// ModerationChange is everything one moderation action changes. It is saved
+// in one transaction with the action's audit log entry.
+type ModerationChange struct {
+	Action *ModerationAction
+	// Takedown is created, or lifted if LiftedAt is set
+	Takedown *Takedown
+	// Services are saved as by UpdateService, each with its event
+	Services []*Registration
+	Events   []*marketplace.CatalogEvent
+	// Provider has its suspension saved
+	Provider *Provider
+	// Report has its resolution saved
+	Report *Report
+}
+
+// ModerationStore persists takedowns, reports and the moderation audit log
+type ModerationStore interface {
+	// SaveModeration saves change. It returns ErrConflict if a service was
+	// changed concurrently, the target of a new takedown already has one in
+	// force, or the takedown or report was already lifted or resolved.
+	SaveModeration(ctx context.Context, change *ModerationChange) error
+	// ActiveTakedown returns the takedown in force on a service or provider,
+	// or ErrNotFound
+	ActiveTakedown(ctx context.Context, targetType, targetID string) (*Takedown, error)
+	ListModerationActions(ctx context.Context, filter ActionFilter) ([]*ModerationAction, int, error)
+	// CreateReport saves a new report, with reg and its event if filing it
+	// changed the service. It returns ErrConflict if the reporter already has
+	// an open report about the service.
+	CreateReport(ctx context.Context, report *Report, reg *Registration, event *marketplace.CatalogEvent) error
+	GetReport(ctx context.Context, id string) (*Report, error)
+	ListReports(ctx context.Context, filter ReportFilter) ([]*Report, int, error)
+}
+
+// ConsumptionBlocker blocks consuming services with access-control policies
+// in the policy engine
+type ConsumptionBlocker interface {
+	// BlockServices creates a policy blocking serviceIDs for takedown and
+	// returns its ID
+	BlockServices(ctx context.Context, takedown *Takedown, serviceIDs []string) (string, error)
+	// UnblockServices deletes a policy BlockServices created. A policy that
+	// no longer exists is not an error.
+	UnblockServices(ctx context.Context, policyID string) error
+}
+
+// SetBlocker sets how takedowns block consumption. Takedowns fail with
+// ErrPolicyUnavailable until it is set.
+func (s *Service) SetBlocker(blocker ConsumptionBlocker) {
+	s.blocker = blocker
+}
+
+// SuspendService takes an active or deprecated service down. reportID, if
+// set, names an open report about the service, which is resolved as
+// actioned.
+func (s *Service) SuspendService(ctx context.Context, operator, id, reason, reportID string) (*Takedown, error) {
+	reg, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if reg.Status != marketplace.StatusActive && reg.Status != marketplace.StatusDeprecated {
+		return nil, fmt.Errorf("%w: service %s is %s", ErrConflict, reg.ID, reg.Status)
+	}
+	provider, err := s.store.GetProvider(ctx, reg.ProviderID)
+	if err != nil {
+		return nil, err
+	}
+	if provider.Suspended {
+		return nil, fmt.Errorf("%w: provider %s is already taken down", ErrConflict, provider.ID)
+	}
+	return s.takeDown(ctx, operator, TargetService, id, reason, reportID, []*Registration{reg}, provider)
+}
+
+// SuspendProvider takes a provider down with all its services. Its active
+// and deprecated services are suspended, and every service it has that
+// isn't retired is blocked. reportID, if set, names an open report about one
+// of its services, which is resolved as actioned.
+func (s *Service) SuspendProvider(ctx context.Context, operator, id, reason, reportID string) (*Takedown, error) {
+	provider, err := s.GetProvider(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if provider.Suspended {
+		return nil, fmt.Errorf("%w: provider %s is already taken down", ErrConflict, provider.ID)
+	}
+
+	var regs []*Registration
+	filter := ServiceFilter{ProviderID: provider.ID, Limit: 100}
+	for {
+		page, total, err := s.store.ListServices(ctx, filter)
+		if err != nil {
+			return nil, err
+		}
+		for _, reg := range page {
+			if reg.Status != marketplace.StatusRetired {
+				regs = append(regs, reg)
+			}
+		}
+		filter.Offset += len(page)
+		if len(page) == 0 || filter.Offset >= total {
+			break
+		}
+	}
+	provider.Suspended = true
+	return s.takeDown(ctx, operator, TargetProvider, id, reason, reportID, regs, provider)
+}
+
+// takeDown blocks regs, services of provider, in the policy engine, then
+// suspends those that are active or deprecated and saves the takedown. A
+// provider takedown saves the provider's suspension too.
+func (s *Service) takeDown(ctx context.Context, operator, targetType, targetID, reason, reportID string, regs []*Registration, provider *Provider) (*Takedown, error) {
+	if reason == "" {
+		return nil, marketplace.ValidationError{{Field: "reason", Message: "is required"}}
+	}
+	now := time.Now().UTC()
+	takedown := &Takedown{
+		ID:         uuid.NewString(),
+		TargetType: targetType,
+		TargetID:   targetID,
+		Reason:     reason,
+		ReportID:   reportID,
+		Services:   []TakenDownService{},
+		CreatedBy:  operator,
+		CreatedAt:  now,
+	}
+	change := &ModerationChange{
+		Action:   newAction(ActionSuspend, targetType, targetID, operator, reason, now),
+		Takedown: takedown,
+	}
+	change.Action.TakedownID = takedown.ID
+	if targetType == TargetProvider {
+		change.Provider = provider
+	}
+
+	// counted is the service a newly upheld report counts against
+	var counted string
+	if reportID != "" {
+		report, err := s.openReport(ctx, reportID)
+		if err != nil {
+			return nil, err
+		}
+		if (targetType == TargetService && report.ServiceID != targetID) || (targetType == TargetProvider && report.ProviderID != targetID) {
+			return nil, marketplace.ValidationError{{Field: "report_id", Message: "is not a report about this " + targetType}}
+		}
+		if !report.Upheld {
+			counted = report.ServiceID
+		}
+		resolve(report, ReportActioned, reason, operator, now)
+		change.Report = report
+		change.Action.ReportID = report.ID
+	}
+
+	ids := make([]string, len(regs))
+	for i, reg := range regs {
+		ids[i] = reg.ID
+		if reg.ID == counted {
+			reg.UpheldReports++
+		} else if reg.Status == marketplace.StatusSuspended {
+			continue
+		}
+		if reg.Status != marketplace.StatusSuspended {
+			takedown.Services = append(takedown.Services, TakenDownService{ServiceID: reg.ID, PreviousStatus: reg.Status})
+			reg.Status = marketplace.StatusSuspended
+		}
+		reg.Revision++
+		reg.UpdatedAt = now
+		change.Services = append(change.Services, reg)
+		change.Events = append(change.Events, newEvent(marketplace.ServiceUpdated, reg, provider))
+	}
+
+	if len(ids) > 0 {
+		if s.blocker == nil {
+			return nil, fmt.Errorf("%w: takedowns can't block consumption", ErrPolicyUnavailable)
+		}
+		policyID, err := s.blocker.BlockServices(ctx, takedown, ids)
+		if err != nil {
+			return nil, err
+		}
+		takedown.PolicyID = policyID
+	}
+	if err := s.store.SaveModeration(ctx, change); err != nil {
+		if takedown.PolicyID != "" {
+			if unblockErr := s.blocker.UnblockServices(ctx, takedown.PolicyID); unblockErr != nil {
+				s.logger.Error("Failed to remove the policy of a failed takedown",
+					zap.String("policy_id", takedown.PolicyID),
+					zap.Error(unblockErr),
+				)
+			}
+		}
+		return nil, err
+	}
+
+	s.logger.Info("Taken down",
+		zap.String("takedown_id", takedown.ID),
+		zap.String("target_type", targetType),
+		zap.String("target_id", targetID),
+		zap.String("operator", operator),
+		zap.String("policy_id", takedown.PolicyID),
+		zap.Int("services", len(takedown.Services)),
+	)
+	return takedown, nil
+}
+
+// Reinstate lifts the takedown in force on a service or provider. The policy
+// blocking its services is deleted and the services it suspended get back
+// their previous status, unless they have since been retired or changed. A
+// service taken down with its provider is reinstated with the provider.
+func (s *Service) Reinstate(ctx context.Context, operator, targetType, id, reason string) (*Takedown, error) {
+	if reason == "" {
+		return nil, marketplace.ValidationError{{Field: "reason", Message: "is required"}}
+	}
+	var provider *Provider
+	switch targetType {
+	case TargetService:
+		reg, err := s.Get(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if provider, err = s.store.GetProvider(ctx, reg.ProviderID); err != nil {
+			return nil, err
+		}
+		if provider.Suspended {
+			return nil, fmt.Errorf("%w: provider %s is taken down; reinstate the provider", ErrConflict, provider.ID)
+		}
+	case TargetProvider:
+		var err error
+		if provider, err = s.GetProvider(ctx, id); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unknown takedown target %q", targetType)
+	}
+
+	takedown, err := s.store.ActiveTakedown(ctx, targetType, id)
+	if errors.Is(err, ErrNotFound) {
+		return nil, fmt.Errorf("%w: %s %s is not taken down", ErrConflict, targetType, id)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	takedown.LiftedBy = operator
+	takedown.LiftedAt = &now
+	change := &ModerationChange{
+		Action:   newAction(ActionReinstate, targetType, id, operator, reason, now),
+		Takedown: takedown,
+	}
+	change.Action.TakedownID = takedown.ID
+	if targetType == TargetProvider {
+		provider.Suspended = false
+		change.Provider = provider
+	}
+	for _, taken := range takedown.Services {
+		reg, err := s.store.GetService(ctx, taken.ServiceID)
+		if err != nil {
+			return nil, err
+		}
+		if reg.Status != marketplace.StatusSuspended {
+			continue
+		}
+		reg.Status = taken.PreviousStatus
+		reg.Revision++
+		reg.UpdatedAt = now
+		change.Services = append(change.Services, reg)
+		change.Events = append(change.Events, newEvent(marketplace.ServiceUpdated, reg, provider))
+	}
+
+	// Unblock first: if saving then fails, the services stay suspended and
+	// the reinstatement can be retried
+	if takedown.PolicyID != "" {
+		if s.blocker == nil {
+			return nil, fmt.Errorf("%w: takedowns can't be lifted", ErrPolicyUnavailable)
+		}
+		if err := s.blocker.UnblockServices(ctx, takedown.PolicyID); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.store.SaveModeration(ctx, change); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Reinstated",
+		zap.String("takedown_id", takedown.ID),
+		zap.String("target_type", targetType),
+		zap.String("target_id", id),
+		zap.String("operator", operator),
+		zap.Int("services", len(change.Services)),
+	)
+	return takedown, nil
+}
+
+// ResolveReport closes an open report as dismissed or actioned, with the
+// operator's note
+func (s *Service) ResolveReport(ctx context.Context, operator, id, resolution, note string) (*Report, error) {
+	if resolution != ReportDismissed && resolution != ReportActioned {
+		return nil, marketplace.ValidationError{{Field: "resolution", Message: "must be dismissed or actioned"}}
+	}
+	report, err := s.openReport(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	upheld := report.Upheld
+	resolve(report, resolution, note, operator, now)
+	change := &ModerationChange{
+		Action: newAction(ActionResolve, TargetReport, report.ID, operator, note, now),
+		Report: report,
+	}
+	change.Action.ReportID = report.ID
+	// The operator's verdict stands over the policy engine's
+	if report.Upheld != upheld {
+		if err := s.countReport(ctx, change, report, now); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.store.SaveModeration(ctx, change); err != nil {
+		return nil, err
+	}
+	s.logger.Info("Report resolved",
+		zap.String("report_id", report.ID),
+		zap.String("service_id", report.ServiceID),
+		zap.String("resolution", resolution),
+		zap.String("operator", operator),
+	)
+	return report, nil
+}
+
+// GetReport returns a report by ID
+func (s *Service) GetReport(ctx context.Context, id string) (*Report, error) {
+	if uuid.Validate(id) != nil {
+		return nil, ErrNotFound
+	}
+	return s.store.GetReport(ctx, id)
+}
+
+// ListReports returns a page of reports and the total matching filter
+func (s *Service) ListReports(ctx context.Context, filter ReportFilter) ([]*Report, int, error) {
+	if filter.Status != "" && !slices.Contains([]string{ReportOpen, ReportDismissed, ReportActioned}, filter.Status) {
+		return nil, 0, marketplace.ValidationError{{Field: "status", Message: "must be open, dismissed or actioned"}}
+	}
+	if (filter.ServiceID != "" && uuid.Validate(filter.ServiceID) != nil) || (filter.ProviderID != "" && uuid.Validate(filter.ProviderID) != nil) {
+		return []*Report{}, 0, nil
+	}
+	if filter.Limit <= 0 || filter.Limit > 100 {
+		filter.Limit = 20
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+	return s.store.ListReports(ctx, filter)
+}
+
+// ListModerationActions returns a page of the audit log and the total
+// matching filter
+func (s *Service) ListModerationActions(ctx context.Context, filter ActionFilter) ([]*ModerationAction, int, error) {
+	if filter.TargetID != "" && uuid.Validate(filter.TargetID) != nil {
+		return []*ModerationAction{}, 0, nil
+	}
+	if filter.Limit <= 0 || filter.Limit > 100 {
+		filter.Limit = 20
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+	return s.store.ListModerationActions(ctx, filter)
+}
+
+// openReport returns a report that is still open
+func (s *Service) openReport(ctx context.Context, id string) (*Report, error) {
+	report, err := s.GetReport(ctx, id)
+	if errors.Is(err, ErrNotFound) {
+		return nil, fmt.Errorf("%w: report %s", ErrNotFound, id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if report.Status != ReportOpen {
+		return nil, fmt.Errorf("%w: report %s is already %s", ErrConflict, report.ID, report.Status)
+	}
+	return report, nil
+}
+
+func resolve(report *Report, resolution, note, operator string, at time.Time) {
+	report.Status = resolution
+	report.Upheld = resolution == ReportActioned
+	report.Resolution = note
+	report.ResolvedBy = operator
+	report.ResolvedAt = &at
+}
+
+func newAction(action, targetType, targetID, actor, reason string, at time.Time) *ModerationAction {
+	return &ModerationAction{
+		ID:         uuid.NewString(),
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetID,
+		Actor:      actor,
+		Reason:     reason,
+		CreatedAt:  at,
+	}
+}
@@ -0,0 +1,278 @@
+package registry
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/org/llm-marketplace/pkg/marketplace"
+	"go.uber.org/zap"
+)
+
+// keyPrefix starts every provider API key, so leaked keys are easy to spot
+const keyPrefix = "mk_"
+
+// ErrUnauthenticated is returned for a missing, unknown, expired or revoked
+// API key
+var ErrUnauthenticated = errors.New("invalid API key")
+
+// Credential is a provider API key. Only its hash is stored; the key itself
+// is shown once, when it is issued.
+type Credential struct {
+	ID         string     `json:"id"`
+	ProviderID string     `json:"provider_id"`
+	Prefix     string     `json:"prefix"` // The first characters of the key, to tell keys apart
+	Hash       string     `json:"-"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"` // Set when the key is rotated out
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+// Active reports whether the key is accepted at now
+func (c *Credential) Active(now time.Time) bool {
+	return c.RevokedAt == nil && (c.ExpiresAt == nil || c.ExpiresAt.After(now))
+}
+
+// IssuedCredential is a newly issued credential with its key
+type IssuedCredential struct {
+	Credential
+	Key string `json:"key"`
+}
+
+// Validation is the outcome of checking a descriptor on behalf of a
+// provider, kept so providers can see why a submission was rejected
+type Validation struct {
+	ID            string                   `json:"id"`
+	ProviderID    string                   `json:"provider_id"`
+	ServiceID     string                   `json:"service_id,omitempty"` // Empty for dry runs and rejected registrations
+	Name          string                   `json:"name"`
+	Version       string                   `json:"version"`
+	Valid         bool                     `json:"valid"`
+	PolicyVersion string                   `json:"policy_version,omitempty"`
+	Errors        []marketplace.FieldError `json:"errors,omitempty"`
+	Violations    []Violation              `json:"violations,omitempty"`
+	CreatedAt     time.Time                `json:"created_at"`
+}
+
+// Event describes v as the validation event stored with it
+func (v *Validation) Event() *marketplace.ValidationEvent {
+	event := &marketplace.ValidationEvent{
+		ID:            v.ID,
+		OccurredAt:    v.CreatedAt,
+		ProviderID:    v.ProviderID,
+		ServiceID:     v.ServiceID,
+		Name:          v.Name,
+		Version:       v.Version,
+		Valid:         v.Valid,
+		PolicyVersion: v.PolicyVersion,
+		Errors:        v.Errors,
+	}
+	for _, violation := range v.Violations {
+		event.Violations = append(event.Violations, marketplace.PolicyViolation{
+			PolicyID:   violation.PolicyID,
+			PolicyName: violation.PolicyName,
+			Severity:   violation.Severity,
+		})
+	}
+	return event
+}
+
+// ValidationFilter selects a provider's validations, newest first
+type ValidationFilter struct {
+	ProviderID string
+	ServiceID  string
+	Limit      int
+}
+
+// SetKeyGracePeriod sets how long rotated-out keys keep working
+func (s *Service) SetKeyGracePeriod(d time.Duration) {
+	s.keyGrace = d
+}
+
+// Authenticate returns the provider an API key belongs to
+func (s *Service) Authenticate(ctx context.Context, key string) (*Provider, error) {
+	if !strings.HasPrefix(key, keyPrefix) {
+		return nil, ErrUnauthenticated
+	}
+	cred, err := s.store.CredentialByHash(ctx, hashKey(key))
+	if errors.Is(err, ErrNotFound) {
+		return nil, ErrUnauthenticated
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !cred.Active(time.Now()) {
+		return nil, ErrUnauthenticated
+	}
+	return s.store.GetProvider(ctx, cred.ProviderID)
+}
+
+// IssueCredential issues an additional API key for a provider, e.g. for an
+// operator restoring access. Existing keys are unaffected.
+func (s *Service) IssueCredential(ctx context.Context, providerID string) (*IssuedCredential, error) {
+	if _, err := s.GetProvider(ctx, providerID); err != nil {
+		return nil, err
+	}
+	issued, err := newCredential(providerID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.store.CreateCredential(ctx, &issued.Credential, time.Time{}); err != nil {
+		return nil, err
+	}
+	s.logger.Info("API key issued", zap.String("provider_id", providerID), zap.String("credential_id", issued.ID))
+	return issued, nil
+}
+
+// RotateCredential issues a new API key for the caller. The caller's other
+// keys expire after the grace period, so clients can switch over without
+// downtime.
+func (s *Service) RotateCredential(ctx context.Context, caller string) (*IssuedCredential, error) {
+	issued, err := newCredential(caller)
+	if err != nil {
+		return nil, err
+	}
+	expireOthers := issued.CreatedAt.Add(s.keyGrace)
+	if err := s.store.CreateCredential(ctx, &issued.Credential, expireOthers); err != nil {
+		return nil, err
+	}
+	s.logger.Info("API key rotated",
+		zap.String("provider_id", caller),
+		zap.String("credential_id", issued.ID),
+		zap.Time("previous_keys_expire_at", expireOthers),
+	)
+	return issued, nil
+}
+
+// Credentials lists the caller's API keys, newest first
+func (s *Service) Credentials(ctx context.Context, caller string) ([]*Credential, error) {
+	return s.store.ListCredentials(ctx, caller)
+}
+
+// RevokeCredential revokes one of the caller's API keys immediately
+func (s *Service) RevokeCredential(ctx context.Context, caller, id string) error {
+	if uuid.Validate(id) != nil {
+		return ErrNotFound
+	}
+	if err := s.store.RevokeCredential(ctx, caller, id, time.Now().UTC()); err != nil {
+		return err
+	}
+	s.logger.Info("API key revoked", zap.String("provider_id", caller), zap.String("credential_id", id))
+	return nil
+}
+
+// SetPricing replaces the pricing of one of the caller's services. The
+// descriptor is checked again like any other update, and a service with
+// subscribers has its price changes scheduled instead.
+func (s *Service) SetPricing(ctx context.Context, caller, id string, pricing marketplace.PricingInfo) (*Registration, error) {
+	reg, _, err := s.owned(ctx, caller, id)
+	if err != nil {
+		return nil, err
+	}
+	desc := reg.Service
+	desc.Pricing = &pricing
+	return s.Update(ctx, caller, id, desc)
+}
+
+// Validate checks a descriptor for the caller without storing it. The
+// result is recorded like that of a submission.
+func (s *Service) Validate(ctx context.Context, caller string, desc marketplace.ServiceDescriptor) (*Validation, error) {
+	desc.ServiceID = uuid.NewString() // Checked as a new registration would be
+	desc.ProviderID = caller
+	provider, err := s.store.GetProvider(ctx, caller)
+	if err != nil {
+		return nil, err
+	}
+	_, err = s.check(ctx, &desc, provider)
+	v := newValidation(&desc, "", err)
+	if v == nil {
+		return nil, err
+	}
+	s.saveValidation(ctx, v)
+	return v, nil
+}
+
+// Validations lists a provider's recorded validations, newest first
+func (s *Service) Validations(ctx context.Context, filter ValidationFilter) ([]*Validation, error) {
+	if filter.ServiceID != "" && uuid.Validate(filter.ServiceID) != nil {
+		return nil, ErrNotFound
+	}
+	if filter.Limit <= 0 || filter.Limit > 100 {
+		filter.Limit = 20
+	}
+	return s.store.ListValidations(ctx, filter)
+}
+
+// record stores the outcome of checking desc for the service serviceID, or
+// for a registration that wasn't stored when serviceID is empty. Checks that
+// didn't reach a verdict aren't recorded.
+func (s *Service) record(ctx context.Context, desc *marketplace.ServiceDescriptor, serviceID string, checkErr error) {
+	if v := newValidation(desc, serviceID, checkErr); v != nil {
+		s.saveValidation(ctx, v)
+	}
+}
+
+// saveValidation stores v. Failing to record doesn't fail the request.
+func (s *Service) saveValidation(ctx context.Context, v *Validation) {
+	if err := s.store.SaveValidation(ctx, v); err != nil {
+		s.logger.Warn("Failed to record validation", zap.String("provider_id", v.ProviderID), zap.Error(err))
+	}
+}
+
+// newValidation describes the outcome of a check, or returns nil when err
+// is not a verdict on the descriptor
+func newValidation(desc *marketplace.ServiceDescriptor, serviceID string, err error) *Validation {
+	v := &Validation{
+		ID:         uuid.NewString(),
+		ProviderID: desc.ProviderID,
+		ServiceID:  serviceID,
+		Name:       desc.Name,
+		Version:    desc.Version,
+		CreatedAt:  time.Now().UTC(),
+	}
+	var verr marketplace.ValidationError
+	var rejected *RejectedError
+	switch {
+	case err == nil:
+		v.Valid = true
+	case errors.As(err, &verr):
+		v.Errors = verr
+	case errors.As(err, &rejected):
+		v.PolicyVersion = rejected.PolicyVersion
+		v.Violations = rejected.Violations
+	default:
+		return nil
+	}
+	return v
+}
+
+func newCredential(providerID string) (*IssuedCredential, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	key := keyPrefix + base64.RawURLEncoding.EncodeToString(secret)
+	return &IssuedCredential{
+		Credential: Credential{
+			ID:         uuid.NewString(),
+			ProviderID: providerID,
+			Prefix:     key[:len(keyPrefix)+6],
+			Hash:       hashKey(key),
+			CreatedAt:  time.Now().UTC(),
+		},
+		Key: key,
+	}, nil
+}
+
+// hashKey returns the stored form of an API key. Keys are random, so an
+// unsalted hash is enough.
+func hashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
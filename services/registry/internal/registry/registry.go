@@ -0,0 +1,586 @@
+// Package registry registers providers and the services they offer. Every
+// descriptor is checked by the policy engine before it is stored, and every
+// change is stored with a catalog event that the publisher relays to Kafka,
+// where discovery indexes it.
+package registry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/mail"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/org/llm-marketplace/pkg/marketplace"
+	"go.uber.org/zap"
+)
+
+// Provider is an organisation offering services in the marketplace
+type Provider struct {
+	ID           string `json:"id"`
+	Name         string `json:"name"`
+	ContactEmail string `json:"contact_email"`
+	Verified     bool   `json:"verified"`
+	// Suspended is set while operators have the provider taken down
+	Suspended bool `json:"suspended"`
+	// TenantID is set on an enterprise's provider for its own model
+	// endpoints, whose services are private to the tenant
+	TenantID  string    `json:"tenant_id,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+
+	// APIKey is the provider's first API key, set only when it registers
+	APIKey string `json:"api_key,omitempty"`
+}
+
+// Info returns the provider as it appears in service listings
+func (p *Provider) Info() marketplace.ProviderInfo {
+	return marketplace.ProviderInfo{ID: p.ID, Name: p.Name, Verified: p.Verified}
+}
+
+// Registration is a registered service: its current descriptor and status
+type Registration struct {
+	ID         string `json:"id"`
+	ProviderID string `json:"provider_id"`
+	Status     string `json:"status"`
+	Revision   int64  `json:"revision"` // Incremented by every change
+	// UpheldReports counts the reports about the service that were upheld
+	UpheldReports int                           `json:"upheld_reports"`
+	PolicyVersion string                        `json:"policy_version,omitempty"`
+	Service       marketplace.ServiceDescriptor `json:"service"`
+	// ComplianceExpiries are the service's certifications and
+	// data-processing agreement found expiring or expired at the last check
+	ComplianceExpiries []marketplace.ComplianceExpiry `json:"compliance_expiries,omitempty"`
+	// ContentScan is the check of the service's text; nil if it was never
+	// scanned
+	ContentScan *ContentScan `json:"content_scan,omitempty"`
+	CreatedAt   time.Time    `json:"created_at"`
+	UpdatedAt   time.Time    `json:"updated_at"`
+}
+
+// ServiceFilter selects registrations to list
+type ServiceFilter struct {
+	ProviderID  string
+	Status      string
+	ContentScan string // The status of the services' content scan
+	Kind        string // One of marketplace.Kinds
+	Limit       int
+	Offset      int
+}
+
+// Store persists providers and registrations. Each registration change is
+// saved together with its catalog event, so an event is published if and
+// only if the change is committed. Methods taking a provider ID only see
+// and change that provider's rows.
+type Store interface {
+	// CreateProvider saves p with its first API key
+	CreateProvider(ctx context.Context, p *Provider, cred *Credential) error
+	GetProvider(ctx context.Context, id string) (*Provider, error)
+	CreateService(ctx context.Context, reg *Registration, event *marketplace.CatalogEvent) error
+	// UpdateService saves reg if it still belongs to reg.ProviderID and the
+	// stored revision is reg.Revision-1, and returns ErrConflict otherwise
+	UpdateService(ctx context.Context, reg *Registration, event *marketplace.CatalogEvent) error
+	GetService(ctx context.Context, id string) (*Registration, error)
+	// GetProviderService returns ErrNotFound unless the service belongs to
+	// providerID
+	GetProviderService(ctx context.Context, providerID, id string) (*Registration, error)
+	ListServices(ctx context.Context, filter ServiceFilter) ([]*Registration, int, error)
+
+	// CreateCredential saves cred. Unless expireOthers is zero, the
+	// provider's other keys expire then, or earlier if they already would.
+	CreateCredential(ctx context.Context, cred *Credential, expireOthers time.Time) error
+	CredentialByHash(ctx context.Context, hash string) (*Credential, error)
+	ListCredentials(ctx context.Context, providerID string) ([]*Credential, error)
+	RevokeCredential(ctx context.Context, providerID, id string, at time.Time) error
+
+	SaveValidation(ctx context.Context, v *Validation) error
+	ListValidations(ctx context.Context, filter ValidationFilter) ([]*Validation, error)
+
+	SubscriptionStore
+	ModerationStore
+	ChangelogStore
+	ArtifactStore
+	ExpiryStore
+}
+
+// PolicyValidator checks a descriptor, and the descriptors of the services
+// it depends on, against the marketplace policies
+type PolicyValidator interface {
+	ValidateService(ctx context.Context, desc *marketplace.ServiceDescriptor, dependencies []*marketplace.ServiceDescriptor) (*PolicyResult, error)
+}
+
+// PolicyResult is the policy engine's verdict on a descriptor
+type PolicyResult struct {
+	Compliant     bool
+	PolicyVersion string
+	Violations    []Violation
+}
+
+// Violation is a policy a descriptor breaks
+type Violation struct {
+	PolicyID    string `json:"policy_id"`
+	PolicyName  string `json:"policy_name"`
+	Severity    string `json:"severity"`
+	Message     string `json:"message"`
+	Remediation string `json:"remediation,omitempty"`
+	Field       string `json:"field,omitempty"`
+}
+
+var (
+	// ErrNotFound is returned for an unknown provider or service
+	ErrNotFound = errors.New("not found")
+	// ErrConflict is returned when a change clashes with the stored state: a
+	// duplicate name, a concurrent update or a retired service
+	ErrConflict = errors.New("conflict")
+	// ErrForbidden is returned when a provider makes a change it may not,
+	// such as changing another provider's service
+	ErrForbidden = errors.New("forbidden")
+	// ErrPolicyUnavailable is returned when the policy engine can't be
+	// reached. Descriptors are never stored unchecked, and takedowns never
+	// leave services consumable.
+	ErrPolicyUnavailable = errors.New("policy engine unavailable")
+)
+
+var errOtherProvider = fmt.Errorf("%w: service belongs to another provider", ErrForbidden)
+
+// RejectedError is returned when a descriptor violates marketplace policies
+type RejectedError struct {
+	PolicyVersion string
+	Violations    []Violation
+}
+
+func (e *RejectedError) Error() string {
+	messages := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		messages[i] = v.Message
+	}
+	return "service violates marketplace policies: " + strings.Join(messages, "; ")
+}
+
+// Service registers providers and services
+type Service struct {
+	store       Store
+	policy      PolicyValidator
+	keyGrace    time.Duration
+	priceNotice time.Duration
+	blocker     ConsumptionBlocker
+	logger      *zap.Logger
+
+	objects         ObjectStore
+	objectPrefix    string
+	maxArtifactSize int64
+
+	expiryWarning   time.Duration
+	expiryRecipient string
+
+	detectors []ContentDetector
+}
+
+// NewService creates a registry over store, checking descriptors with policy.
+// Rotated-out API keys keep working for a day, price changes are scheduled
+// 30 days ahead and compliance documents are flagged 30 days before they
+// lapse unless SetKeyGracePeriod, SetPriceChangeNotice and
+// SetComplianceExpiry say otherwise.
+func NewService(store Store, policy PolicyValidator, logger *zap.Logger) *Service {
+	return &Service{store: store, policy: policy, keyGrace: 24 * time.Hour, priceNotice: 30 * 24 * time.Hour, expiryWarning: 30 * 24 * time.Hour, logger: logger}
+}
+
+// RegisterProvider creates a provider with its first API key. Providers
+// start unverified.
+func (s *Service) RegisterProvider(ctx context.Context, name, contactEmail string) (*Provider, error) {
+	return s.registerProvider(ctx, "", name, contactEmail)
+}
+
+// RegisterTenantProvider creates a provider for an enterprise's own model
+// endpoints, which registers services private to tenantID. consumer is the
+// authenticated consumer organisation, which must be the tenant.
+func (s *Service) RegisterTenantProvider(ctx context.Context, consumer, tenantID, name, contactEmail string) (*Provider, error) {
+	if tenantID == "" || consumer != tenantID {
+		return nil, fmt.Errorf("%w: a tenant's provider is registered by the tenant", ErrForbidden)
+	}
+	return s.registerProvider(ctx, tenantID, name, contactEmail)
+}
+
+func (s *Service) registerProvider(ctx context.Context, tenantID, name, contactEmail string) (*Provider, error) {
+	var verr marketplace.ValidationError
+	name = strings.TrimSpace(name)
+	if name == "" {
+		verr = append(verr, marketplace.FieldError{Field: "name", Message: "is required"})
+	}
+	if _, err := mail.ParseAddress(contactEmail); err != nil {
+		verr = append(verr, marketplace.FieldError{Field: "contact_email", Message: "must be an email address"})
+	}
+	if len(verr) > 0 {
+		return nil, verr
+	}
+
+	provider := &Provider{ID: uuid.NewString(), Name: name, ContactEmail: contactEmail, TenantID: tenantID, CreatedAt: time.Now().UTC()}
+	issued, err := newCredential(provider.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.store.CreateProvider(ctx, provider, &issued.Credential); err != nil {
+		return nil, err
+	}
+	provider.APIKey = issued.Key
+	s.logger.Info("Provider registered",
+		zap.String("provider_id", provider.ID),
+		zap.String("name", provider.Name),
+		zap.String("tenant_id", provider.TenantID),
+	)
+	return provider, nil
+}
+
+// GetProvider returns a provider by ID
+func (s *Service) GetProvider(ctx context.Context, id string) (*Provider, error) {
+	if uuid.Validate(id) != nil {
+		return nil, ErrNotFound
+	}
+	return s.store.GetProvider(ctx, id)
+}
+
+// Register validates and stores a new service for the provider the
+// descriptor names. The registry assigns the service ID. caller is the
+// authenticated provider, or empty when the request is not made on behalf of
+// one.
+func (s *Service) Register(ctx context.Context, caller string, desc marketplace.ServiceDescriptor) (*Registration, error) {
+	if desc.ProviderID == "" {
+		desc.ProviderID = caller
+	}
+	if caller != "" && desc.ProviderID != caller {
+		return nil, errOtherProvider
+	}
+	desc.ServiceID = uuid.NewString()
+
+	provider, err := s.provider(ctx, desc.ProviderID)
+	if err != nil {
+		return nil, err
+	}
+	if provider.Suspended {
+		return nil, fmt.Errorf("%w: provider %s is suspended", ErrForbidden, provider.ID)
+	}
+	result, err := s.check(ctx, &desc, provider)
+	if err != nil {
+		s.record(ctx, &desc, "", err)
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	reg := &Registration{
+		ID:            desc.ServiceID,
+		ProviderID:    provider.ID,
+		Status:        marketplace.StatusActive,
+		Revision:      1,
+		PolicyVersion: result.PolicyVersion,
+		Service:       desc,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	s.requestScan(reg, nil)
+	if err := s.store.CreateService(ctx, reg, newEvent(marketplace.ServiceRegistered, reg, provider)); err != nil {
+		return nil, err
+	}
+	s.record(ctx, &desc, reg.ID, nil)
+	s.logger.Info("Service registered",
+		zap.String("service_id", reg.ID),
+		zap.String("provider_id", reg.ProviderID),
+		zap.String("name", desc.Name),
+		zap.String("version", desc.Version),
+	)
+	return reg, nil
+}
+
+// Update replaces the descriptor of a registered service. The descriptor is
+// checked again; the service keeps its ID, provider and status. Changes to
+// pricing, SLA and capabilities are recorded in the changelog. The pricing
+// of a service with subscribers can't be changed at once; it is changed
+// with SchedulePriceChange.
+func (s *Service) Update(ctx context.Context, caller, id string, desc marketplace.ServiceDescriptor) (*Registration, error) {
+	reg, provider, err := s.owned(ctx, caller, id)
+	if err != nil {
+		return nil, err
+	}
+	if desc.ProviderID != "" && desc.ProviderID != reg.ProviderID {
+		return nil, marketplace.ValidationError{{Field: "provider_id", Message: "can't be changed"}}
+	}
+	if desc.ItemKind() != reg.Service.ItemKind() {
+		return nil, marketplace.ValidationError{{Field: "kind", Message: "can't be changed"}}
+	}
+	desc.ServiceID = reg.ID
+	desc.ProviderID = reg.ProviderID
+
+	result, err := s.check(ctx, &desc, provider)
+	s.record(ctx, &desc, reg.ID, err)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := marketplace.Changes(&reg.Service, &desc)
+	if slices.ContainsFunc(changes, func(c marketplace.ServiceChange) bool { return c.Kind == marketplace.ChangePricing }) {
+		subs, err := s.subscribers(ctx, reg.ID, time.Now().UTC())
+		if err != nil {
+			return nil, err
+		}
+		if len(subs) > 0 {
+			return nil, fmt.Errorf("%w: service %s has subscribers; schedule its price change with notice", ErrConflict, reg.ID)
+		}
+	}
+
+	previous := reg.Service
+	reg.Service = desc
+	reg.PolicyVersion = result.PolicyVersion
+	s.requestScan(reg, &previous)
+	if err := s.save(ctx, reg, provider, marketplace.ServiceUpdated, changes...); err != nil {
+		return nil, err
+	}
+	return reg, nil
+}
+
+// SetStatus changes the status of a registered service. Setting it to
+// retired deregisters the service. Only operators, calling without a
+// provider, suspend services or lift a suspension; a provider may still
+// retire a suspended service.
+func (s *Service) SetStatus(ctx context.Context, caller, id, status string) (*Registration, error) {
+	if !slices.Contains(marketplace.Statuses, status) {
+		return nil, marketplace.ValidationError{{Field: "status", Message: "must be one of " + strings.Join(marketplace.Statuses, ", ")}}
+	}
+	reg, provider, err := s.owned(ctx, caller, id)
+	if err != nil {
+		return nil, err
+	}
+	if reg.Status == status {
+		return reg, nil
+	}
+	if caller != "" && (status == marketplace.StatusSuspended || (reg.Status == marketplace.StatusSuspended && status != marketplace.StatusRetired)) {
+		return nil, fmt.Errorf("%w: suspensions are managed by marketplace operators", ErrForbidden)
+	}
+	if reg.ContentScan.Held() && status != marketplace.StatusRetired {
+		return nil, fmt.Errorf("%w: service %s is held by its content scan; release it through the admin API", ErrConflict, reg.ID)
+	}
+	if reg.Status == marketplace.StatusSuspended && status != marketplace.StatusRetired {
+		// A takedown's suspension is lifted with the takedown, so its policy
+		// goes too
+		for _, target := range [][2]string{{TargetService, reg.ID}, {TargetProvider, reg.ProviderID}} {
+			_, err := s.store.ActiveTakedown(ctx, target[0], target[1])
+			if err == nil {
+				return nil, fmt.Errorf("%w: %s %s is taken down; reinstate it through the admin API", ErrConflict, target[0], target[1])
+			}
+			if !errors.Is(err, ErrNotFound) {
+				return nil, err
+			}
+		}
+	}
+
+	eventType := marketplace.ServiceUpdated
+	if status == marketplace.StatusRetired {
+		eventType = marketplace.ServiceDeregistered
+	}
+	reg.Status = status
+	if err := s.save(ctx, reg, provider, eventType); err != nil {
+		return nil, err
+	}
+	return reg, nil
+}
+
+// Deregister retires a service. It stays in the registry, so its name and
+// version can't be reused, but is no longer listed by discovery.
+func (s *Service) Deregister(ctx context.Context, caller, id string) (*Registration, error) {
+	return s.SetStatus(ctx, caller, id, marketplace.StatusRetired)
+}
+
+// Get returns a registered service by ID
+func (s *Service) Get(ctx context.Context, id string) (*Registration, error) {
+	if uuid.Validate(id) != nil {
+		return nil, ErrNotFound
+	}
+	return s.store.GetService(ctx, id)
+}
+
+// GetOwned returns one of the caller's services by ID. Services of other
+// providers are not found.
+func (s *Service) GetOwned(ctx context.Context, caller, id string) (*Registration, error) {
+	if uuid.Validate(id) != nil {
+		return nil, ErrNotFound
+	}
+	return s.store.GetProviderService(ctx, caller, id)
+}
+
+// List returns a page of registered services and the total matching filter
+func (s *Service) List(ctx context.Context, filter ServiceFilter) ([]*Registration, int, error) {
+	if filter.ContentScan != "" && !slices.Contains(ScanStatuses, filter.ContentScan) {
+		return nil, 0, marketplace.ValidationError{{Field: "content_scan", Message: "must be one of " + strings.Join(ScanStatuses, ", ")}}
+	}
+	if filter.Kind != "" && !slices.Contains(marketplace.Kinds, filter.Kind) {
+		return nil, 0, marketplace.ValidationError{{Field: "kind", Message: "must be one of " + strings.Join(marketplace.Kinds, ", ")}}
+	}
+	if filter.Limit <= 0 || filter.Limit > 100 {
+		filter.Limit = 20
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+	return s.store.ListServices(ctx, filter)
+}
+
+// provider looks up the provider a descriptor names, reporting an unknown
+// one as an invalid field
+func (s *Service) provider(ctx context.Context, id string) (*Provider, error) {
+	if id == "" {
+		return nil, marketplace.ValidationError{{Field: "provider_id", Message: "is required"}}
+	}
+	var provider *Provider
+	err := ErrNotFound
+	if uuid.Validate(id) == nil {
+		provider, err = s.store.GetProvider(ctx, id)
+	}
+	if errors.Is(err, ErrNotFound) {
+		return nil, marketplace.ValidationError{{Field: "provider_id", Message: "is not a registered provider"}}
+	}
+	return provider, err
+}
+
+// owned loads a service the caller may change, with its provider. Retired
+// services can't be changed.
+func (s *Service) owned(ctx context.Context, caller, id string) (*Registration, *Provider, error) {
+	if caller == "" {
+		reg, err := s.Get(ctx, id)
+		if err != nil {
+			return nil, nil, err
+		}
+		return s.changeable(ctx, reg)
+	}
+
+	reg, err := s.GetOwned(ctx, caller, id)
+	if errors.Is(err, ErrNotFound) {
+		// Tell a service of another provider apart from an unknown one
+		if _, getErr := s.Get(ctx, id); getErr == nil {
+			return nil, nil, errOtherProvider
+		}
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return s.changeable(ctx, reg)
+}
+
+// changeable returns reg with its provider unless reg is retired or its
+// provider suspended
+func (s *Service) changeable(ctx context.Context, reg *Registration) (*Registration, *Provider, error) {
+	if reg.Status == marketplace.StatusRetired {
+		return nil, nil, fmt.Errorf("%w: service %s is retired", ErrConflict, reg.ID)
+	}
+	provider, err := s.store.GetProvider(ctx, reg.ProviderID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if provider.Suspended {
+		return nil, nil, fmt.Errorf("%w: provider %s is suspended", ErrForbidden, provider.ID)
+	}
+	return reg, provider, nil
+}
+
+// check validates desc as a service of provider and has the policy engine
+// check it with the services it depends on, each with its provider's
+// compliance artifacts
+func (s *Service) check(ctx context.Context, desc *marketplace.ServiceDescriptor, provider *Provider) (*PolicyResult, error) {
+	classify(desc, provider)
+	var verr marketplace.ValidationError
+	if err := desc.Validate(); err != nil && !errors.As(err, &verr) {
+		return nil, err
+	}
+	// Only a tenant's providers register private services, and only in
+	// their tenant
+	switch {
+	case provider.TenantID == "" && desc.Private():
+		verr = append(verr, marketplace.FieldError{Field: "class", Message: "private services are registered by a tenant's provider"})
+	case provider.TenantID != "" && !desc.Private():
+		verr = append(verr, marketplace.FieldError{Field: "class", Message: "must be private for a tenant's provider"})
+	case provider.TenantID != "" && desc.TenantID != "" && desc.TenantID != provider.TenantID:
+		verr = append(verr, marketplace.FieldError{Field: "tenant_id", Message: "must be the provider's tenant"})
+	}
+	// The discovery catalog keys services by name and version
+	if desc.Version == "" {
+		verr = append(verr, marketplace.FieldError{Field: "version", Message: "is required"})
+	}
+	if desc.Category == "" {
+		verr = append(verr, marketplace.FieldError{Field: "category", Message: "is required"})
+	}
+	if len(verr) > 0 {
+		return nil, verr
+	}
+	dependencies, err := s.dependencies(ctx, desc)
+	if err != nil {
+		return nil, err
+	}
+	checked, err := s.withArtifacts(ctx, desc, provider.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := s.policy.ValidateService(ctx, checked, dependencies)
+	if err != nil {
+		return nil, err
+	}
+	if !result.Compliant {
+		s.logger.Info("Service rejected by policy",
+			zap.String("service_id", desc.ServiceID),
+			zap.String("policy_version", result.PolicyVersion),
+			zap.Int("violations", len(result.Violations)),
+		)
+		return nil, &RejectedError{PolicyVersion: result.PolicyVersion, Violations: result.Violations}
+	}
+	return result, nil
+}
+
+// save stores a changed registration as its next revision with an event
+// carrying the changelog entries made, which take effect at once
+func (s *Service) save(ctx context.Context, reg *Registration, provider *Provider, eventType string, changes ...marketplace.ServiceChange) error {
+	reg.Revision++
+	reg.UpdatedAt = time.Now().UTC()
+	for i := range changes {
+		changes[i].Revision = reg.Revision
+		changes[i].EffectiveAt = reg.UpdatedAt
+	}
+	event := newEvent(eventType, reg, provider)
+	event.Changes = changes
+	if err := s.store.UpdateService(ctx, reg, event); err != nil {
+		return err
+	}
+	s.logger.Info("Service changed",
+		zap.String("service_id", reg.ID),
+		zap.String("event", eventType),
+		zap.String("status", reg.Status),
+		zap.Int64("revision", reg.Revision),
+	)
+	return nil
+}
+
+// classify makes the services of a tenant's provider private to its tenant
+// unless the descriptor says otherwise
+func classify(desc *marketplace.ServiceDescriptor, provider *Provider) {
+	if provider.TenantID == "" {
+		return
+	}
+	if desc.Class == "" {
+		desc.Class = marketplace.ClassPrivate
+	}
+	if desc.Class == marketplace.ClassPrivate && desc.TenantID == "" {
+		desc.TenantID = provider.TenantID
+	}
+}
+
+func newEvent(eventType string, reg *Registration, provider *Provider) *marketplace.CatalogEvent {
+	return &marketplace.CatalogEvent{
+		ID:                 uuid.NewString(),
+		Type:               eventType,
+		OccurredAt:         reg.UpdatedAt,
+		Revision:           reg.Revision,
+		Status:             reg.Status,
+		UpheldReports:      reg.UpheldReports,
+		ComplianceExpiries: reg.ComplianceExpiries,
+		Provider:           provider.Info(),
+		Service:            reg.Service,
+	}
+}
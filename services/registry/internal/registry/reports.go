@@ -0,0 +1,152 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/org/llm-marketplace/pkg/marketplace"
+	"go.uber.org/zap"
+)
+
+// What consumers report listings for
+const (
+	ReportMiscategorized  = "miscategorized"
+	ReportMalicious       = "malicious"
+	ReportPolicyViolating = "policy-violating"
+)
+
+// ReportCategories lists the reasons a listing can be reported for
+var ReportCategories = []string{ReportMiscategorized, ReportMalicious, ReportPolicyViolating}
+
+// maxReportDetails bounds the free text of a report
+const maxReportDetails = 4000
+
+// Revalidation is the policy engine's verdict on a reported listing
+type Revalidation struct {
+	Compliant     bool        `json:"compliant"`
+	PolicyVersion string      `json:"policy_version,omitempty"`
+	Violations    []Violation `json:"violations,omitempty"`
+	CheckedAt     time.Time   `json:"checked_at"`
+}
+
+// ReportService files a consumer's report about a listed service. The
+// listing is validated again by the policy engine; if it now breaks a
+// policy, the report is upheld at once and counted against the service. A
+// policy engine that can't be reached doesn't stop the report being filed.
+func (s *Service) ReportService(ctx context.Context, consumer, id, category, details string) (*Report, error) {
+	if consumer == "" {
+		return nil, fmt.Errorf("%w: listings are reported by consumers", ErrForbidden)
+	}
+	var verr marketplace.ValidationError
+	if !slices.Contains(ReportCategories, category) {
+		verr = append(verr, marketplace.FieldError{Field: "category", Message: "must be one of " + strings.Join(ReportCategories, ", ")})
+	}
+	if len(details) > maxReportDetails {
+		verr = append(verr, marketplace.FieldError{Field: "details", Message: fmt.Sprintf("must be at most %d bytes", maxReportDetails)})
+	}
+	if len(verr) > 0 {
+		return nil, verr
+	}
+
+	reg, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	// Other tenants don't know a private service exists
+	if reg.Service.Private() && reg.Service.TenantID != consumer {
+		return nil, ErrNotFound
+	}
+	if reg.Status != marketplace.StatusActive && reg.Status != marketplace.StatusDeprecated {
+		return nil, fmt.Errorf("%w: service %s is %s", ErrConflict, reg.ID, reg.Status)
+	}
+	provider, err := s.store.GetProvider(ctx, reg.ProviderID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	report := &Report{
+		ID:         uuid.NewString(),
+		ServiceID:  reg.ID,
+		ProviderID: reg.ProviderID,
+		ReporterID: consumer,
+		Category:   category,
+		Details:    details,
+		Status:     ReportOpen,
+		CreatedAt:  now,
+	}
+	report.Revalidation = s.revalidate(ctx, reg, provider, now)
+
+	var changed *Registration
+	var event *marketplace.CatalogEvent
+	if report.Revalidation != nil && !report.Revalidation.Compliant {
+		report.Upheld = true
+		reg.UpheldReports++
+		reg.Revision++
+		reg.UpdatedAt = now
+		changed, event = reg, newEvent(marketplace.ServiceUpdated, reg, provider)
+	}
+	if err := s.store.CreateReport(ctx, report, changed, event); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Service reported",
+		zap.String("report_id", report.ID),
+		zap.String("service_id", reg.ID),
+		zap.String("category", category),
+		zap.Bool("upheld", report.Upheld),
+	)
+	return report, nil
+}
+
+// revalidate checks a reported listing against the policies in force and
+// records the check as a validation. It returns nil if the policy engine
+// gave no verdict.
+func (s *Service) revalidate(ctx context.Context, reg *Registration, provider *Provider, at time.Time) *Revalidation {
+	desc := reg.Service
+	result, err := s.check(ctx, &desc, provider)
+	s.record(ctx, &desc, reg.ID, err)
+
+	var rejected *RejectedError
+	switch {
+	case err == nil:
+		return &Revalidation{Compliant: true, PolicyVersion: result.PolicyVersion, CheckedAt: at}
+	case errors.As(err, &rejected):
+		return &Revalidation{PolicyVersion: rejected.PolicyVersion, Violations: rejected.Violations, CheckedAt: at}
+	default:
+		s.logger.Warn("Failed to re-validate a reported service", zap.String("service_id", reg.ID), zap.Error(err))
+		return nil
+	}
+}
+
+// countReport adds a report whose upheld flag an operator changed to change,
+// with the service counting one more or one fewer upheld report. Retired
+// services are left alone.
+func (s *Service) countReport(ctx context.Context, change *ModerationChange, report *Report, now time.Time) error {
+	reg, err := s.store.GetService(ctx, report.ServiceID)
+	if err != nil {
+		return err
+	}
+	if reg.Status == marketplace.StatusRetired {
+		return nil
+	}
+	provider, err := s.store.GetProvider(ctx, reg.ProviderID)
+	if err != nil {
+		return err
+	}
+	if report.Upheld {
+		reg.UpheldReports++
+	} else {
+		reg.UpheldReports = max(reg.UpheldReports-1, 0)
+	}
+	reg.Revision++
+	reg.UpdatedAt = now
+	change.Services = append(change.Services, reg)
+	change.Events = append(change.Events, newEvent(marketplace.ServiceUpdated, reg, provider))
+	return nil
+}
@@ -0,0 +1,237 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/org/llm-marketplace/pkg/marketplace"
+	"go.uber.org/zap"
+)
+
+// Subscription is a contract between a consumer organisation and a service
+type Subscription = marketplace.Subscription
+
+// Caller is who a subscription request acts for: a consumer organisation, a
+// provider, or neither for a marketplace operator
+type Caller struct {
+	ConsumerID string
+	ProviderID string
+}
+
+// SubscriptionFilter selects subscriptions to list. Status is matched as of
+// At. Empty fields match everything.
+type SubscriptionFilter struct {
+	ConsumerID string
+	ServiceID  string
+	ProviderID string
+	Status     string
+	At         time.Time
+	Limit      int
+	Offset     int
+}
+
+// SubscriptionStore persists subscriptions
+type SubscriptionStore interface {
+	// CreateSubscription saves sub unless the consumer has another
+	// subscription to the service, not cancelled, whose dates overlap; it
+	// returns ErrConflict then
+	CreateSubscription(ctx context.Context, sub *Subscription) error
+	GetSubscription(ctx context.Context, id string) (*Subscription, error)
+	ListSubscriptions(ctx context.Context, filter SubscriptionFilter) ([]*Subscription, int, error)
+	// CancelSubscription sets the subscription's cancellation time unless it
+	// is already cancelled, and returns ErrConflict then
+	CancelSubscription(ctx context.Context, id string, at time.Time) error
+}
+
+// Subscribe creates a subscription of a consumer to a service. A consumer
+// caller subscribes itself; operators subscribe any consumer. The service
+// must be active, and the tier, when set, one of its pricing tiers. The
+// subscription starts now unless it names a start.
+func (s *Service) Subscribe(ctx context.Context, caller Caller, sub Subscription) (*Subscription, error) {
+	if caller.ProviderID != "" && caller.ConsumerID == "" {
+		return nil, fmt.Errorf("%w: providers can't subscribe to services", ErrForbidden)
+	}
+	if sub.ConsumerID == "" {
+		sub.ConsumerID = caller.ConsumerID
+	}
+	if caller.ConsumerID != "" && sub.ConsumerID != caller.ConsumerID {
+		return nil, fmt.Errorf("%w: consumers can only subscribe themselves", ErrForbidden)
+	}
+
+	now := time.Now().UTC()
+	if sub.StartsAt.IsZero() {
+		sub.StartsAt = now
+	}
+	var verr marketplace.ValidationError
+	if err := sub.Validate(); err != nil && !errors.As(err, &verr) {
+		return nil, err
+	}
+	if sub.EndsAt != nil && !sub.EndsAt.After(now) {
+		verr = append(verr, marketplace.FieldError{Field: "ends_at", Message: "must be in the future"})
+	}
+
+	var reg *Registration
+	if sub.ServiceID != "" {
+		var err error
+		reg, err = s.Get(ctx, sub.ServiceID)
+		switch {
+		case errors.Is(err, ErrNotFound):
+			verr = append(verr, marketplace.FieldError{Field: "service_id", Message: "is not a registered service"})
+		case err != nil:
+			return nil, err
+		case reg.Service.Private() && reg.Service.TenantID != sub.ConsumerID:
+			// Other tenants don't know a private service exists
+			reg = nil
+			verr = append(verr, marketplace.FieldError{Field: "service_id", Message: "is not a registered service"})
+		case reg.Status != marketplace.StatusActive:
+			return nil, fmt.Errorf("%w: service %s is %s and takes no new subscriptions", ErrConflict, reg.ID, reg.Status)
+		}
+	}
+	if reg != nil {
+		verr = append(verr, checkTier(reg.Service.Pricing, sub.Tier)...)
+	}
+	if len(verr) > 0 {
+		return nil, verr
+	}
+
+	sub.ID = uuid.NewString()
+	sub.ProviderID = reg.ProviderID
+	if sub.Currency == "" {
+		sub.Currency = "USD"
+		if p := reg.Service.Pricing; p != nil && p.Currency != "" {
+			sub.Currency = p.Currency
+		}
+	}
+	sub.StartsAt = sub.StartsAt.UTC()
+	sub.CancelledAt = nil
+	sub.CreatedAt = now
+	if err := s.store.CreateSubscription(ctx, &sub); err != nil {
+		return nil, err
+	}
+	sub.Status = sub.StatusAt(now)
+
+	s.logger.Info("Subscription created",
+		zap.String("subscription_id", sub.ID),
+		zap.String("consumer_id", sub.ConsumerID),
+		zap.String("service_id", sub.ServiceID),
+		zap.String("tier", sub.Tier),
+	)
+	return &sub, nil
+}
+
+// checkTier reports a tier that isn't one of the pricing's tiers
+func checkTier(pricing *marketplace.PricingInfo, tier string) marketplace.ValidationError {
+	if tier == "" {
+		return nil
+	}
+	var tiers []string
+	if pricing != nil {
+		for _, t := range pricing.Tiers {
+			tiers = append(tiers, t.Tier)
+		}
+	}
+	if slices.Contains(tiers, tier) {
+		return nil
+	}
+	if len(tiers) == 0 {
+		return marketplace.ValidationError{{Field: "tier", Message: "must be empty: the service has no pricing tiers"}}
+	}
+	return marketplace.ValidationError{{Field: "tier", Message: "must be one of the service's pricing tiers: " + strings.Join(tiers, ", ")}}
+}
+
+// GetSubscription returns a subscription the caller may see: consumers see
+// their own and providers those to their services
+func (s *Service) GetSubscription(ctx context.Context, caller Caller, id string) (*Subscription, error) {
+	if uuid.Validate(id) != nil {
+		return nil, ErrNotFound
+	}
+	sub, err := s.store.GetSubscription(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if (caller.ConsumerID != "" && sub.ConsumerID != caller.ConsumerID) ||
+		(caller.ConsumerID == "" && caller.ProviderID != "" && sub.ProviderID != caller.ProviderID) {
+		return nil, ErrNotFound
+	}
+	sub.Status = sub.StatusAt(time.Now())
+	return sub, nil
+}
+
+// ListSubscriptions returns a page of the subscriptions the caller may see
+// that match filter, and the total
+func (s *Service) ListSubscriptions(ctx context.Context, caller Caller, filter SubscriptionFilter) ([]*Subscription, int, error) {
+	if caller.ConsumerID != "" {
+		if filter.ConsumerID != "" && filter.ConsumerID != caller.ConsumerID {
+			return nil, 0, fmt.Errorf("%w: consumers can only list their own subscriptions", ErrForbidden)
+		}
+		filter.ConsumerID = caller.ConsumerID
+	} else if caller.ProviderID != "" {
+		if filter.ProviderID != "" && filter.ProviderID != caller.ProviderID {
+			return nil, 0, fmt.Errorf("%w: providers can only list subscriptions to their own services", ErrForbidden)
+		}
+		filter.ProviderID = caller.ProviderID
+	}
+	if filter.Status != "" && !slices.Contains(marketplace.SubscriptionStatuses, filter.Status) {
+		return nil, 0, marketplace.ValidationError{{Field: "status", Message: "must be one of " + strings.Join(marketplace.SubscriptionStatuses, ", ")}}
+	}
+	if filter.Limit <= 0 || filter.Limit > 100 {
+		filter.Limit = 20
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+	now := time.Now()
+	filter.At = now
+
+	subs, total, err := s.store.ListSubscriptions(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+	for _, sub := range subs {
+		sub.Status = sub.StatusAt(now)
+	}
+	return subs, total, nil
+}
+
+// CancelSubscription ends a subscription now. Consumers cancel their own
+// subscriptions; providers can't cancel them.
+func (s *Service) CancelSubscription(ctx context.Context, caller Caller, id string) (*Subscription, error) {
+	if caller.ProviderID != "" && caller.ConsumerID == "" {
+		return nil, fmt.Errorf("%w: providers can't cancel subscriptions", ErrForbidden)
+	}
+	if uuid.Validate(id) != nil {
+		return nil, ErrNotFound
+	}
+	sub, err := s.store.GetSubscription(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if caller.ConsumerID != "" && sub.ConsumerID != caller.ConsumerID {
+		return nil, fmt.Errorf("%w: subscription belongs to another consumer", ErrForbidden)
+	}
+
+	now := time.Now().UTC()
+	switch sub.StatusAt(now) {
+	case marketplace.SubscriptionCancelled:
+		return nil, fmt.Errorf("%w: subscription %s is already cancelled", ErrConflict, id)
+	case marketplace.SubscriptionExpired:
+		return nil, fmt.Errorf("%w: subscription %s has expired", ErrConflict, id)
+	}
+	if err := s.store.CancelSubscription(ctx, id, now); err != nil {
+		return nil, err
+	}
+	sub.CancelledAt = &now
+	sub.Status = marketplace.SubscriptionCancelled
+
+	s.logger.Info("Subscription cancelled",
+		zap.String("subscription_id", sub.ID),
+		zap.String("consumer_id", sub.ConsumerID),
+		zap.String("service_id", sub.ServiceID),
+	)
+	return sub, nil
+}
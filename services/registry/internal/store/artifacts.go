@@ -0,0 +1,66 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/org/llm-marketplace/services/registry/internal/registry"
+)
+
+const artifactColumns = `id, provider_id, certification, COALESCE(issuer, ''), COALESCE(file_name, ''), content_type, size, sha256, object_key, issued_at, expires_at, created_at`
+
+func scanArtifact(row pgx.Row) (*registry.ComplianceArtifact, error) {
+	var a registry.ComplianceArtifact
+	err := row.Scan(&a.ID, &a.ProviderID, &a.Certification, &a.Issuer, &a.FileName, &a.ContentType, &a.Size, &a.SHA256, &a.ObjectKey, &a.IssuedAt, &a.ExpiresAt, &a.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+func (s *Store) CreateArtifact(ctx context.Context, a *registry.ComplianceArtifact) error {
+	_, err := s.pool.Exec(ctx, `
+		INSERT INTO compliance_artifacts (id, provider_id, certification, issuer, file_name, content_type, size, sha256, object_key, issued_at, expires_at, created_at)
+		VALUES ($1, $2, $3, NULLIF($4, ''), NULLIF($5, ''), $6, $7, $8, $9, $10, $11, $12)
+	`, a.ID, a.ProviderID, a.Certification, a.Issuer, a.FileName, a.ContentType, a.Size, a.SHA256, a.ObjectKey, a.IssuedAt, a.ExpiresAt, a.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to store artifact: %w", err)
+	}
+	return nil
+}
+
+func (s *Store) ListArtifacts(ctx context.Context, providerID string) ([]*registry.ComplianceArtifact, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT `+artifactColumns+` FROM compliance_artifacts WHERE provider_id = $1 ORDER BY created_at DESC, id
+	`, providerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list artifacts: %w", err)
+	}
+	defer rows.Close()
+
+	var artifacts []*registry.ComplianceArtifact
+	for rows.Next() {
+		a, err := scanArtifact(rows)
+		if err != nil {
+			return nil, err
+		}
+		artifacts = append(artifacts, a)
+	}
+	return artifacts, rows.Err()
+}
+
+func (s *Store) DeleteArtifact(ctx context.Context, providerID, id string) (*registry.ComplianceArtifact, error) {
+	a, err := scanArtifact(s.pool.QueryRow(ctx, `
+		DELETE FROM compliance_artifacts WHERE id = $1 AND provider_id = $2
+		RETURNING `+artifactColumns, id, providerID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, registry.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete artifact: %w", err)
+	}
+	return a, nil
+}
@@ -0,0 +1,191 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/org/llm-marketplace/pkg/marketplace"
+
+	"github.com/org/llm-marketplace/services/registry/internal/publisher"
+	"github.com/org/llm-marketplace/services/registry/internal/registry"
+)
+
+func (s *Store) ListChanges(ctx context.Context, filter registry.ChangeFilter) ([]marketplace.ServiceChange, int, error) {
+	clause, args := where(map[string]string{
+		"service_id": filter.ServiceID,
+		"kind":       filter.Kind,
+	})
+
+	var total int
+	if err := s.pool.QueryRow(ctx, `SELECT COUNT(*) FROM service_changes`+clause, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count changes: %w", err)
+	}
+
+	args = append(args, filter.Limit, filter.Offset)
+	rows, err := s.pool.Query(ctx, fmt.Sprintf(`SELECT change FROM service_changes%s ORDER BY revision DESC, id DESC LIMIT $%d OFFSET $%d`,
+		clause, len(args)-1, len(args)), args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list changes: %w", err)
+	}
+	defer rows.Close()
+
+	changes := []marketplace.ServiceChange{}
+	for rows.Next() {
+		var encoded []byte
+		if err := rows.Scan(&encoded); err != nil {
+			return nil, 0, err
+		}
+		var change marketplace.ServiceChange
+		if err := json.Unmarshal(encoded, &change); err != nil {
+			return nil, 0, fmt.Errorf("failed to decode change: %w", err)
+		}
+		changes = append(changes, change)
+	}
+	return changes, total, rows.Err()
+}
+
+func (s *Store) SchedulePriceChange(ctx context.Context, pc *registry.PriceChange, notices []*marketplace.PriceChangeNotice) error {
+	oldPricing, err := json.Marshal(pc.OldPricing)
+	if err != nil {
+		return err
+	}
+	newPricing, err := json.Marshal(pc.NewPricing)
+	if err != nil {
+		return err
+	}
+	return pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, `
+			INSERT INTO price_changes (id, service_id, provider_id, old_pricing, new_pricing, effective_at, status, notified, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		`, pc.ID, pc.ServiceID, pc.ProviderID, oldPricing, newPricing, pc.EffectiveAt, pc.Status, pc.Notified, pc.CreatedAt)
+		if isUniqueViolation(err) {
+			return fmt.Errorf("%w: service %s already has a scheduled price change", registry.ErrConflict, pc.ServiceID)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to store price change: %w", err)
+		}
+		return insertNotices(ctx, tx, notices)
+	})
+}
+
+func (s *Store) CancelPriceChange(ctx context.Context, pc *registry.PriceChange, notices []*marketplace.PriceChangeNotice) error {
+	return pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		if err := updatePriceChange(ctx, tx, pc); err != nil {
+			return err
+		}
+		return insertNotices(ctx, tx, notices)
+	})
+}
+
+func (s *Store) ApplyPriceChange(ctx context.Context, pc *registry.PriceChange, reg *registry.Registration, event *marketplace.CatalogEvent) error {
+	return pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		if err := updatePriceChange(ctx, tx, pc); err != nil {
+			return err
+		}
+		if reg == nil {
+			return nil
+		}
+		if err := updateService(ctx, tx, reg); err != nil {
+			return err
+		}
+		return insertEvent(ctx, tx, event)
+	})
+}
+
+// updatePriceChange saves the status of a price change that is still
+// scheduled
+func updatePriceChange(ctx context.Context, tx pgx.Tx, pc *registry.PriceChange) error {
+	tag, err := tx.Exec(ctx, `
+		UPDATE price_changes SET status = $2, applied_at = $3, cancelled_at = $4 WHERE id = $1 AND status = 'scheduled'
+	`, pc.ID, pc.Status, pc.AppliedAt, pc.CancelledAt)
+	if err != nil {
+		return fmt.Errorf("failed to update price change: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%w: price change %s is no longer scheduled", registry.ErrConflict, pc.ID)
+	}
+	return nil
+}
+
+// insertNotices adds price-change notices to the outbox in tx
+func insertNotices(ctx context.Context, tx pgx.Tx, notices []*marketplace.PriceChangeNotice) error {
+	for _, n := range notices {
+		payload, err := json.Marshal(n)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(ctx, `
+			INSERT INTO outbox (event_id, stream, message_key, service_id, event_type, payload, created_at)
+			VALUES ($1, $2, $3, $4, $2, $5, $6)
+		`, n.ID, publisher.PriceChangeStream, n.ConsumerID, n.ServiceID, payload, n.OccurredAt)
+		if err != nil {
+			return fmt.Errorf("failed to store price-change notice: %w", err)
+		}
+	}
+	return nil
+}
+
+const priceChangeColumns = `id, service_id, provider_id, old_pricing, new_pricing, effective_at, status, notified, created_at, applied_at, cancelled_at`
+
+func scanPriceChange(row pgx.Row) (*registry.PriceChange, error) {
+	var pc registry.PriceChange
+	var oldPricing, newPricing []byte
+	if err := row.Scan(&pc.ID, &pc.ServiceID, &pc.ProviderID, &oldPricing, &newPricing, &pc.EffectiveAt, &pc.Status, &pc.Notified,
+		&pc.CreatedAt, &pc.AppliedAt, &pc.CancelledAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(newPricing, &pc.NewPricing); err != nil {
+		return nil, fmt.Errorf("failed to decode price change %s: %w", pc.ID, err)
+	}
+	if oldPricing != nil {
+		if err := json.Unmarshal(oldPricing, &pc.OldPricing); err != nil {
+			return nil, fmt.Errorf("failed to decode price change %s: %w", pc.ID, err)
+		}
+	}
+	return &pc, nil
+}
+
+func (s *Store) GetPriceChange(ctx context.Context, id string) (*registry.PriceChange, error) {
+	pc, err := scanPriceChange(s.pool.QueryRow(ctx, `SELECT `+priceChangeColumns+` FROM price_changes WHERE id = $1`, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, registry.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get price change: %w", err)
+	}
+	return pc, nil
+}
+
+func (s *Store) ListPriceChanges(ctx context.Context, serviceID string) ([]*registry.PriceChange, error) {
+	return s.queryPriceChanges(ctx, `SELECT `+priceChangeColumns+` FROM price_changes WHERE service_id = $1 ORDER BY created_at DESC, id`, serviceID)
+}
+
+func (s *Store) DuePriceChanges(ctx context.Context, now time.Time, limit int) ([]*registry.PriceChange, error) {
+	return s.queryPriceChanges(ctx, `
+		SELECT `+priceChangeColumns+` FROM price_changes
+		WHERE status = 'scheduled' AND effective_at <= $1
+		ORDER BY effective_at, id LIMIT $2
+	`, now, limit)
+}
+
+func (s *Store) queryPriceChanges(ctx context.Context, query string, args ...interface{}) ([]*registry.PriceChange, error) {
+	rows, err := s.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list price changes: %w", err)
+	}
+	defer rows.Close()
+
+	changes := []*registry.PriceChange{}
+	for rows.Next() {
+		pc, err := scanPriceChange(rows)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, pc)
+	}
+	return changes, rows.Err()
+}
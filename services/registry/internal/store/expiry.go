@@ -0,0 +1,35 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/org/llm-marketplace/pkg/marketplace"
+
+	"github.com/org/llm-marketplace/services/registry/internal/publisher"
+	"github.com/org/llm-marketplace/services/registry/internal/registry"
+)
+
+func (s *Store) UpdateExpiries(ctx context.Context, reg *registry.Registration, event *marketplace.CatalogEvent, notices []*marketplace.ComplianceExpiryNotice) error {
+	return s.withEvent(ctx, event, func(tx pgx.Tx) error {
+		if err := updateService(ctx, tx, reg); err != nil {
+			return err
+		}
+		for _, n := range notices {
+			payload, err := json.Marshal(n)
+			if err != nil {
+				return err
+			}
+			_, err = tx.Exec(ctx, `
+				INSERT INTO outbox (event_id, stream, message_key, service_id, event_type, payload, created_at)
+				VALUES ($1, $2, $3, $4, $2, $5, $6)
+			`, n.ID, publisher.ExpiryStream, n.RecipientID, n.ServiceID, payload, n.OccurredAt)
+			if err != nil {
+				return fmt.Errorf("failed to store expiry notice: %w", err)
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,249 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/org/llm-marketplace/pkg/marketplace"
+
+	"github.com/org/llm-marketplace/services/registry/internal/registry"
+)
+
+func (s *Store) SaveModeration(ctx context.Context, change *registry.ModerationChange) error {
+	return pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		if t := change.Takedown; t != nil {
+			if err := saveTakedown(ctx, tx, t); err != nil {
+				return err
+			}
+		}
+		if r := change.Report; r != nil {
+			tag, err := tx.Exec(ctx, `
+				UPDATE reports SET status = $2, upheld = $3, resolution = $4, resolved_by = $5, resolved_at = $6
+				WHERE id = $1 AND status = 'open'
+			`, r.ID, r.Status, r.Upheld, r.Resolution, r.ResolvedBy, r.ResolvedAt)
+			if err != nil {
+				return fmt.Errorf("failed to resolve report: %w", err)
+			}
+			if tag.RowsAffected() == 0 {
+				return fmt.Errorf("%w: report %s was resolved by another request", registry.ErrConflict, r.ID)
+			}
+		}
+		if p := change.Provider; p != nil {
+			if _, err := tx.Exec(ctx, `UPDATE providers SET suspended = $2, updated_at = NOW() WHERE id = $1`, p.ID, p.Suspended); err != nil {
+				return fmt.Errorf("failed to update provider: %w", err)
+			}
+		}
+		for i, reg := range change.Services {
+			if err := updateService(ctx, tx, reg); err != nil {
+				return err
+			}
+			if err := insertEvent(ctx, tx, change.Events[i]); err != nil {
+				return err
+			}
+		}
+
+		a := change.Action
+		_, err := tx.Exec(ctx, `
+			INSERT INTO moderation_actions (id, action, target_type, target_id, actor, reason, takedown_id, report_id, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, '')::uuid, NULLIF($8, '')::uuid, $9)
+		`, a.ID, a.Action, a.TargetType, a.TargetID, a.Actor, a.Reason, a.TakedownID, a.ReportID, a.CreatedAt)
+		if err != nil {
+			return fmt.Errorf("failed to record moderation action: %w", err)
+		}
+		return nil
+	})
+}
+
+// saveTakedown inserts a new takedown or lifts one in force
+func saveTakedown(ctx context.Context, tx pgx.Tx, t *registry.Takedown) error {
+	if t.LiftedAt != nil {
+		tag, err := tx.Exec(ctx, `
+			UPDATE takedowns SET lifted_by = $2, lifted_at = $3 WHERE id = $1 AND lifted_at IS NULL
+		`, t.ID, t.LiftedBy, t.LiftedAt)
+		if err != nil {
+			return fmt.Errorf("failed to lift takedown: %w", err)
+		}
+		if tag.RowsAffected() == 0 {
+			return fmt.Errorf("%w: takedown %s was lifted by another request", registry.ErrConflict, t.ID)
+		}
+		return nil
+	}
+
+	services, err := json.Marshal(t.Services)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(ctx, `
+		INSERT INTO takedowns (id, target_type, target_id, reason, report_id, policy_id, services, created_by, created_at)
+		VALUES ($1, $2, $3, $4, NULLIF($5, '')::uuid, $6, $7, $8, $9)
+	`, t.ID, t.TargetType, t.TargetID, t.Reason, t.ReportID, t.PolicyID, services, t.CreatedBy, t.CreatedAt)
+	if isUniqueViolation(err) {
+		return fmt.Errorf("%w: %s %s is already taken down", registry.ErrConflict, t.TargetType, t.TargetID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to store takedown: %w", err)
+	}
+	return nil
+}
+
+func (s *Store) ActiveTakedown(ctx context.Context, targetType, targetID string) (*registry.Takedown, error) {
+	var t registry.Takedown
+	var services []byte
+	err := s.pool.QueryRow(ctx, `
+		SELECT id, target_type, target_id, reason, COALESCE(report_id::text, ''), policy_id, services, created_by, created_at
+		FROM takedowns
+		WHERE target_type = $1 AND target_id = $2 AND lifted_at IS NULL
+	`, targetType, targetID).Scan(&t.ID, &t.TargetType, &t.TargetID, &t.Reason, &t.ReportID, &t.PolicyID, &services, &t.CreatedBy, &t.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, registry.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get takedown: %w", err)
+	}
+	if err := json.Unmarshal(services, &t.Services); err != nil {
+		return nil, fmt.Errorf("failed to decode takedown %s: %w", t.ID, err)
+	}
+	return &t, nil
+}
+
+func (s *Store) ListModerationActions(ctx context.Context, filter registry.ActionFilter) ([]*registry.ModerationAction, int, error) {
+	clause, args := where(map[string]string{
+		"target_type": filter.TargetType,
+		"target_id":   filter.TargetID,
+		"actor":       filter.Actor,
+	})
+
+	var total int
+	if err := s.pool.QueryRow(ctx, `SELECT COUNT(*) FROM moderation_actions`+clause, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count moderation actions: %w", err)
+	}
+
+	args = append(args, filter.Limit, filter.Offset)
+	rows, err := s.pool.Query(ctx, fmt.Sprintf(`
+		SELECT id, action, target_type, target_id, actor, reason, COALESCE(takedown_id::text, ''), COALESCE(report_id::text, ''), created_at
+		FROM moderation_actions%s ORDER BY created_at DESC, id LIMIT $%d OFFSET $%d`,
+		clause, len(args)-1, len(args)), args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list moderation actions: %w", err)
+	}
+	defer rows.Close()
+
+	var actions []*registry.ModerationAction
+	for rows.Next() {
+		var a registry.ModerationAction
+		if err := rows.Scan(&a.ID, &a.Action, &a.TargetType, &a.TargetID, &a.Actor, &a.Reason, &a.TakedownID, &a.ReportID, &a.CreatedAt); err != nil {
+			return nil, 0, err
+		}
+		actions = append(actions, &a)
+	}
+	return actions, total, rows.Err()
+}
+
+func (s *Store) CreateReport(ctx context.Context, r *registry.Report, reg *registry.Registration, event *marketplace.CatalogEvent) error {
+	var revalidation []byte
+	if r.Revalidation != nil {
+		var err error
+		if revalidation, err = json.Marshal(r.Revalidation); err != nil {
+			return err
+		}
+	}
+	return pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, `
+			INSERT INTO reports (id, service_id, provider_id, reporter_id, category, details, status, upheld, revalidation, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		`, r.ID, r.ServiceID, r.ProviderID, r.ReporterID, r.Category, r.Details, r.Status, r.Upheld, revalidation, r.CreatedAt)
+		if isUniqueViolation(err) {
+			return fmt.Errorf("%w: %s already has an open report about service %s", registry.ErrConflict, r.ReporterID, r.ServiceID)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to store report: %w", err)
+		}
+		if reg == nil {
+			return nil
+		}
+		if err := updateService(ctx, tx, reg); err != nil {
+			return err
+		}
+		return insertEvent(ctx, tx, event)
+	})
+}
+
+const reportColumns = `id, service_id, provider_id, reporter_id, category, details, status, upheld, revalidation, resolution, COALESCE(resolved_by, ''), resolved_at, created_at`
+
+func scanReport(row pgx.Row) (*registry.Report, error) {
+	var r registry.Report
+	var revalidation []byte
+	if err := row.Scan(&r.ID, &r.ServiceID, &r.ProviderID, &r.ReporterID, &r.Category, &r.Details, &r.Status, &r.Upheld, &revalidation,
+		&r.Resolution, &r.ResolvedBy, &r.ResolvedAt, &r.CreatedAt); err != nil {
+		return nil, err
+	}
+	if revalidation != nil {
+		r.Revalidation = &registry.Revalidation{}
+		if err := json.Unmarshal(revalidation, r.Revalidation); err != nil {
+			return nil, fmt.Errorf("failed to decode report %s: %w", r.ID, err)
+		}
+	}
+	return &r, nil
+}
+
+func (s *Store) GetReport(ctx context.Context, id string) (*registry.Report, error) {
+	r, err := scanReport(s.pool.QueryRow(ctx, `SELECT `+reportColumns+` FROM reports WHERE id = $1`, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, registry.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get report: %w", err)
+	}
+	return r, nil
+}
+
+func (s *Store) ListReports(ctx context.Context, filter registry.ReportFilter) ([]*registry.Report, int, error) {
+	clause, args := where(map[string]string{
+		"service_id":  filter.ServiceID,
+		"provider_id": filter.ProviderID,
+		"status":      filter.Status,
+	})
+
+	var total int
+	if err := s.pool.QueryRow(ctx, `SELECT COUNT(*) FROM reports`+clause, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count reports: %w", err)
+	}
+
+	args = append(args, filter.Limit, filter.Offset)
+	rows, err := s.pool.Query(ctx, fmt.Sprintf(`SELECT %s FROM reports%s ORDER BY created_at, id LIMIT $%d OFFSET $%d`,
+		reportColumns, clause, len(args)-1, len(args)), args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list reports: %w", err)
+	}
+	defer rows.Close()
+
+	var reports []*registry.Report
+	for rows.Next() {
+		r, err := scanReport(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		reports = append(reports, r)
+	}
+	return reports, total, rows.Err()
+}
+
+// where builds a WHERE clause matching each non-empty value to its column
+func where(columns map[string]string) (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+	for column, value := range columns {
+		if value != "" {
+			args = append(args, value)
+			conditions = append(conditions, fmt.Sprintf("%s = $%d", column, len(args)))
+		}
+	}
+	if len(conditions) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
@@ -0,0 +1,159 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/org/llm-marketplace/services/registry/internal/publisher"
+	"github.com/org/llm-marketplace/services/registry/internal/registry"
+)
+
+func (s *Store) CreateCredential(ctx context.Context, cred *registry.Credential, expireOthers time.Time) error {
+	return pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		if !expireOthers.IsZero() {
+			_, err := tx.Exec(ctx, `
+				UPDATE provider_credentials
+				SET expires_at = LEAST(COALESCE(expires_at, $2), $2)
+				WHERE provider_id = $1 AND revoked_at IS NULL
+			`, cred.ProviderID, expireOthers)
+			if err != nil {
+				return fmt.Errorf("failed to expire credentials: %w", err)
+			}
+		}
+		return insertCredential(ctx, tx, cred)
+	})
+}
+
+func insertCredential(ctx context.Context, tx pgx.Tx, cred *registry.Credential) error {
+	_, err := tx.Exec(ctx, `
+		INSERT INTO provider_credentials (id, provider_id, prefix, key_hash, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`, cred.ID, cred.ProviderID, cred.Prefix, cred.Hash, cred.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to store credential: %w", err)
+	}
+	return nil
+}
+
+const credentialColumns = `id, provider_id, prefix, key_hash, created_at, expires_at, revoked_at`
+
+func scanCredential(row pgx.Row) (*registry.Credential, error) {
+	var c registry.Credential
+	if err := row.Scan(&c.ID, &c.ProviderID, &c.Prefix, &c.Hash, &c.CreatedAt, &c.ExpiresAt, &c.RevokedAt); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (s *Store) CredentialByHash(ctx context.Context, hash string) (*registry.Credential, error) {
+	cred, err := scanCredential(s.pool.QueryRow(ctx, `SELECT `+credentialColumns+` FROM provider_credentials WHERE key_hash = $1`, hash))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, registry.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get credential: %w", err)
+	}
+	return cred, nil
+}
+
+func (s *Store) ListCredentials(ctx context.Context, providerID string) ([]*registry.Credential, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT `+credentialColumns+` FROM provider_credentials WHERE provider_id = $1 ORDER BY created_at DESC, id
+	`, providerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list credentials: %w", err)
+	}
+	defer rows.Close()
+
+	var creds []*registry.Credential
+	for rows.Next() {
+		cred, err := scanCredential(rows)
+		if err != nil {
+			return nil, err
+		}
+		creds = append(creds, cred)
+	}
+	return creds, rows.Err()
+}
+
+func (s *Store) RevokeCredential(ctx context.Context, providerID, id string, at time.Time) error {
+	tag, err := s.pool.Exec(ctx, `
+		UPDATE provider_credentials SET revoked_at = COALESCE(revoked_at, $3)
+		WHERE id = $1 AND provider_id = $2
+	`, id, providerID, at)
+	if err != nil {
+		return fmt.Errorf("failed to revoke credential: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return registry.ErrNotFound
+	}
+	return nil
+}
+
+func (s *Store) SaveValidation(ctx context.Context, v *registry.Validation) error {
+	fieldErrors, err := json.Marshal(v.Errors)
+	if err != nil {
+		return err
+	}
+	violations, err := json.Marshal(v.Violations)
+	if err != nil {
+		return err
+	}
+	payload, err := json.Marshal(v.Event())
+	if err != nil {
+		return err
+	}
+	return pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, `
+			INSERT INTO validations (id, provider_id, service_id, name, version, valid, policy_version, errors, violations, created_at)
+			VALUES ($1, $2, NULLIF($3, '')::uuid, $4, $5, $6, NULLIF($7, ''), $8, $9, $10)
+		`, v.ID, v.ProviderID, v.ServiceID, v.Name, v.Version, v.Valid, v.PolicyVersion, fieldErrors, violations, v.CreatedAt)
+		if err != nil {
+			return fmt.Errorf("failed to store validation: %w", err)
+		}
+		_, err = tx.Exec(ctx, `
+			INSERT INTO outbox (event_id, stream, message_key, service_id, event_type, payload, created_at)
+			VALUES ($1, $2, $3, NULLIF($4, '')::uuid, $2, $5, $6)
+		`, v.ID, publisher.ValidationStream, v.ProviderID, v.ServiceID, payload, v.CreatedAt)
+		if err != nil {
+			return fmt.Errorf("failed to store validation event: %w", err)
+		}
+		return nil
+	})
+}
+
+func (s *Store) ListValidations(ctx context.Context, filter registry.ValidationFilter) ([]*registry.Validation, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT id, provider_id, COALESCE(service_id::text, ''), name, version, valid, COALESCE(policy_version, ''), errors, violations, created_at
+		FROM validations
+		WHERE provider_id = $1 AND ($2 = '' OR service_id = NULLIF($2, '')::uuid)
+		ORDER BY created_at DESC, id
+		LIMIT $3
+	`, filter.ProviderID, filter.ServiceID, filter.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list validations: %w", err)
+	}
+	defer rows.Close()
+
+	var validations []*registry.Validation
+	for rows.Next() {
+		var v registry.Validation
+		var fieldErrors, violations []byte
+		if err := rows.Scan(&v.ID, &v.ProviderID, &v.ServiceID, &v.Name, &v.Version, &v.Valid, &v.PolicyVersion, &fieldErrors, &violations, &v.CreatedAt); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(fieldErrors, &v.Errors); err != nil {
+			return nil, fmt.Errorf("failed to decode validation %s: %w", v.ID, err)
+		}
+		if err := json.Unmarshal(violations, &v.Violations); err != nil {
+			return nil, fmt.Errorf("failed to decode validation %s: %w", v.ID, err)
+		}
+		validations = append(validations, &v)
+	}
+	return validations, rows.Err()
+}
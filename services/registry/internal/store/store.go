@@ -0,0 +1,351 @@
+// Package store keeps providers, registrations and the catalog event outbox
+// in PostgreSQL
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/org/llm-marketplace/pkg/marketplace"
+
+	"github.com/org/llm-marketplace/services/registry/internal/config"
+	"github.com/org/llm-marketplace/services/registry/internal/publisher"
+	"github.com/org/llm-marketplace/services/registry/internal/registry"
+)
+
+// relayLockID makes one replica at a time relay the outbox, which keeps
+// events in order
+const relayLockID = 7263543
+
+// uniqueViolation is the PostgreSQL error code for a duplicate key
+const uniqueViolation = "23505"
+
+// Store implements registry.Store and publisher.Outbox
+type Store struct {
+	pool *pgxpool.Pool
+}
+
+// NewPool connects to PostgreSQL
+func NewPool(ctx context.Context, cfg config.PostgresConfig) (*pgxpool.Pool, error) {
+	poolConfig, err := pgxpool.ParseConfig(cfg.DSN())
+	if err != nil {
+		return nil, fmt.Errorf("invalid postgres config: %w", err)
+	}
+	poolConfig.MaxConns = int32(cfg.MaxConns)
+	poolConfig.MinConns = int32(cfg.MinConns)
+	poolConfig.ConnConfig.ConnectTimeout = cfg.ConnTimeout
+
+	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create pool: %w", err)
+	}
+	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("failed to ping postgres: %w", err)
+	}
+	return pool, nil
+}
+
+// New creates a store over pool
+func New(pool *pgxpool.Pool) *Store {
+	return &Store{pool: pool}
+}
+
+func (s *Store) CreateProvider(ctx context.Context, p *registry.Provider, cred *registry.Credential) error {
+	return pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, `
+			INSERT INTO providers (id, name, contact_email, verified, tenant_id, created_at, updated_at)
+			VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6, $6)
+		`, p.ID, p.Name, p.ContactEmail, p.Verified, p.TenantID, p.CreatedAt)
+		if isUniqueViolation(err) {
+			return fmt.Errorf("%w: a provider named %q is already registered", registry.ErrConflict, p.Name)
+		}
+		if err != nil {
+			return err
+		}
+		return insertCredential(ctx, tx, cred)
+	})
+}
+
+func (s *Store) GetProvider(ctx context.Context, id string) (*registry.Provider, error) {
+	var p registry.Provider
+	err := s.pool.QueryRow(ctx, `
+		SELECT id, name, contact_email, verified, suspended, COALESCE(tenant_id, ''), created_at FROM providers WHERE id = $1
+	`, id).Scan(&p.ID, &p.Name, &p.ContactEmail, &p.Verified, &p.Suspended, &p.TenantID, &p.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, registry.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get provider: %w", err)
+	}
+	return &p, nil
+}
+
+func (s *Store) CreateService(ctx context.Context, reg *registry.Registration, event *marketplace.CatalogEvent) error {
+	descriptor, err := json.Marshal(reg.Service)
+	if err != nil {
+		return err
+	}
+	scan, err := marshalScan(reg.ContentScan)
+	if err != nil {
+		return err
+	}
+	return s.withEvent(ctx, event, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, `
+			INSERT INTO services (id, provider_id, name, version, descriptor, status, revision, policy_version, content_scan, created_at, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		`, reg.ID, reg.ProviderID, reg.Service.Name, reg.Service.Version, descriptor, reg.Status, reg.Revision, reg.PolicyVersion, scan, reg.CreatedAt, reg.UpdatedAt)
+		if isUniqueViolation(err) {
+			return fmt.Errorf("%w: %s %s is already registered", registry.ErrConflict, reg.Service.Name, reg.Service.Version)
+		}
+		return err
+	})
+}
+
+func (s *Store) UpdateService(ctx context.Context, reg *registry.Registration, event *marketplace.CatalogEvent) error {
+	return s.withEvent(ctx, event, func(tx pgx.Tx) error {
+		return updateService(ctx, tx, reg)
+	})
+}
+
+// updateService saves reg in tx as UpdateService does
+func updateService(ctx context.Context, tx pgx.Tx, reg *registry.Registration) error {
+	descriptor, err := json.Marshal(reg.Service)
+	if err != nil {
+		return err
+	}
+	expiries, err := json.Marshal(reg.ComplianceExpiries)
+	if err != nil {
+		return err
+	}
+	scan, err := marshalScan(reg.ContentScan)
+	if err != nil {
+		return err
+	}
+	tag, err := tx.Exec(ctx, `
+		UPDATE services
+		SET name = $2, version = $3, descriptor = $4, status = $5, revision = $6, policy_version = $7, updated_at = $8, upheld_reports = $10, compliance_expiries = $11, content_scan = $12
+		WHERE id = $1 AND provider_id = $9 AND revision = $6 - 1
+	`, reg.ID, reg.Service.Name, reg.Service.Version, descriptor, reg.Status, reg.Revision, reg.PolicyVersion, reg.UpdatedAt, reg.ProviderID, reg.UpheldReports, expiries, scan)
+	if isUniqueViolation(err) {
+		return fmt.Errorf("%w: %s %s is already registered", registry.ErrConflict, reg.Service.Name, reg.Service.Version)
+	}
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%w: service %s was changed by another request", registry.ErrConflict, reg.ID)
+	}
+	return nil
+}
+
+// withEvent runs fn and stores event in one transaction
+func (s *Store) withEvent(ctx context.Context, event *marketplace.CatalogEvent, fn func(tx pgx.Tx) error) error {
+	return pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		if err := fn(tx); err != nil {
+			return err
+		}
+		return insertEvent(ctx, tx, event)
+	})
+}
+
+// insertEvent adds event to the outbox in tx, and its changes to the
+// service's changelog
+func insertEvent(ctx context.Context, tx pgx.Tx, event *marketplace.CatalogEvent) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(ctx, `
+		INSERT INTO outbox (event_id, stream, message_key, service_id, event_type, payload, created_at)
+		VALUES ($1, $2, $3, $3::uuid, $4, $5, $6)
+	`, event.ID, publisher.CatalogStream, event.Service.ServiceID, event.Type, payload, event.OccurredAt)
+	if err != nil {
+		return fmt.Errorf("failed to store event: %w", err)
+	}
+	for _, change := range event.Changes {
+		encoded, err := json.Marshal(change)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(ctx, `
+			INSERT INTO service_changes (service_id, revision, kind, change, effective_at) VALUES ($1, $2, $3, $4, $5)
+		`, event.Service.ServiceID, change.Revision, change.Kind, encoded, change.EffectiveAt)
+		if err != nil {
+			return fmt.Errorf("failed to store change: %w", err)
+		}
+	}
+	return nil
+}
+
+const serviceColumns = `id, provider_id, status, revision, COALESCE(policy_version, ''), upheld_reports, descriptor, compliance_expiries, content_scan, created_at, updated_at`
+
+func scanService(row pgx.Row) (*registry.Registration, error) {
+	var reg registry.Registration
+	var descriptor, expiries, scan []byte
+	if err := row.Scan(&reg.ID, &reg.ProviderID, &reg.Status, &reg.Revision, &reg.PolicyVersion, &reg.UpheldReports, &descriptor, &expiries, &scan, &reg.CreatedAt, &reg.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(descriptor, &reg.Service); err != nil {
+		return nil, fmt.Errorf("failed to decode descriptor of service %s: %w", reg.ID, err)
+	}
+	if err := json.Unmarshal(expiries, &reg.ComplianceExpiries); err != nil {
+		return nil, fmt.Errorf("failed to decode compliance expiries of service %s: %w", reg.ID, err)
+	}
+	if scan != nil {
+		reg.ContentScan = &registry.ContentScan{}
+		if err := json.Unmarshal(scan, reg.ContentScan); err != nil {
+			return nil, fmt.Errorf("failed to decode content scan of service %s: %w", reg.ID, err)
+		}
+	}
+	return &reg, nil
+}
+
+// marshalScan encodes a content scan, or NULL for a service never scanned
+func marshalScan(scan *registry.ContentScan) ([]byte, error) {
+	if scan == nil {
+		return nil, nil
+	}
+	return json.Marshal(scan)
+}
+
+func (s *Store) GetService(ctx context.Context, id string) (*registry.Registration, error) {
+	reg, err := scanService(s.pool.QueryRow(ctx, `SELECT `+serviceColumns+` FROM services WHERE id = $1`, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, registry.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get service: %w", err)
+	}
+	return reg, nil
+}
+
+func (s *Store) GetProviderService(ctx context.Context, providerID, id string) (*registry.Registration, error) {
+	reg, err := scanService(s.pool.QueryRow(ctx, `SELECT `+serviceColumns+` FROM services WHERE id = $1 AND provider_id = $2`, id, providerID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, registry.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get service: %w", err)
+	}
+	return reg, nil
+}
+
+func (s *Store) ListServices(ctx context.Context, filter registry.ServiceFilter) ([]*registry.Registration, int, error) {
+	var where []string
+	var args []interface{}
+	if filter.ProviderID != "" {
+		args = append(args, filter.ProviderID)
+		where = append(where, fmt.Sprintf("provider_id = $%d", len(args)))
+	}
+	if filter.Status != "" {
+		args = append(args, filter.Status)
+		where = append(where, fmt.Sprintf("status = $%d", len(args)))
+	}
+	if filter.ContentScan != "" {
+		args = append(args, filter.ContentScan)
+		where = append(where, fmt.Sprintf("content_scan->>'status' = $%d", len(args)))
+	}
+	if filter.Kind != "" {
+		args = append(args, filter.Kind)
+		where = append(where, fmt.Sprintf("COALESCE(descriptor->>'kind', 'service') = $%d", len(args)))
+	}
+	clause := ""
+	if len(where) > 0 {
+		clause = " WHERE " + strings.Join(where, " AND ")
+	}
+
+	var total int
+	if err := s.pool.QueryRow(ctx, `SELECT COUNT(*) FROM services`+clause, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count services: %w", err)
+	}
+
+	args = append(args, filter.Limit, filter.Offset)
+	rows, err := s.pool.Query(ctx, fmt.Sprintf(`SELECT %s FROM services%s ORDER BY created_at DESC, id LIMIT $%d OFFSET $%d`,
+		serviceColumns, clause, len(args)-1, len(args)), args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list services: %w", err)
+	}
+	defer rows.Close()
+
+	var regs []*registry.Registration
+	for rows.Next() {
+		reg, err := scanService(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		regs = append(regs, reg)
+	}
+	return regs, total, rows.Err()
+}
+
+// PublishPending implements publisher.Outbox. The batch is locked for the
+// duration of publish; if another replica is relaying, it returns nothing.
+// A failed batch stays in the outbox with the error recorded.
+func (s *Store) PublishPending(ctx context.Context, limit int, publish func([]publisher.Event) error) (int, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	var locked bool
+	if err := tx.QueryRow(ctx, `SELECT pg_try_advisory_xact_lock($1)`, relayLockID).Scan(&locked); err != nil {
+		return 0, err
+	}
+	if !locked {
+		return 0, nil
+	}
+
+	rows, err := tx.Query(ctx, `
+		SELECT id, stream, message_key, event_type, payload FROM outbox ORDER BY id LIMIT $1
+	`, limit)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read outbox: %w", err)
+	}
+	var events []publisher.Event
+	var ids []int64
+	for rows.Next() {
+		var e publisher.Event
+		if err := rows.Scan(&e.ID, &e.Stream, &e.Key, &e.Type, &e.Payload); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		events = append(events, e)
+		ids = append(ids, e.ID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	if len(events) == 0 {
+		return 0, nil
+	}
+
+	if publishErr := publish(events); publishErr != nil {
+		_, err := tx.Exec(ctx, `UPDATE outbox SET attempts = attempts + 1, last_error = $2 WHERE id = ANY($1)`, ids, publishErr.Error())
+		if err == nil {
+			err = tx.Commit(ctx)
+		}
+		return 0, errors.Join(publishErr, err)
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM outbox WHERE id = ANY($1)`, ids); err != nil {
+		return 0, fmt.Errorf("failed to clear published events: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+	return len(events), nil
+}
+
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == uniqueViolation
+}
@@ -0,0 +1,156 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/org/llm-marketplace/pkg/marketplace"
+
+	"github.com/org/llm-marketplace/services/registry/internal/registry"
+)
+
+func (s *Store) CreateSubscription(ctx context.Context, sub *registry.Subscription) error {
+	var sla []byte
+	if sub.SLA != nil {
+		var err error
+		if sla, err = json.Marshal(sub.SLA); err != nil {
+			return err
+		}
+	}
+
+	return pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		// Serialise subscribing one consumer to one service, so two requests
+		// can't both find no overlapping contract
+		if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext($1 || '/' || $2))`, sub.ConsumerID, sub.ServiceID); err != nil {
+			return fmt.Errorf("failed to lock subscriptions: %w", err)
+		}
+
+		var overlapping string
+		err := tx.QueryRow(ctx, `
+			SELECT id FROM subscriptions
+			WHERE consumer_id = $1 AND service_id = $2 AND cancelled_at IS NULL
+				AND (ends_at IS NULL OR ends_at > $3)
+				AND ($4::timestamptz IS NULL OR starts_at < $4)
+			LIMIT 1
+		`, sub.ConsumerID, sub.ServiceID, sub.StartsAt, sub.EndsAt).Scan(&overlapping)
+		if err == nil {
+			return fmt.Errorf("%w: consumer %s already has subscription %s to the service for that period", registry.ErrConflict, sub.ConsumerID, overlapping)
+		}
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("failed to check subscriptions: %w", err)
+		}
+
+		_, err = tx.Exec(ctx, `
+			INSERT INTO subscriptions (id, consumer_id, service_id, provider_id, tier, committed_spend, currency, starts_at, ends_at, sla, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		`, sub.ID, sub.ConsumerID, sub.ServiceID, sub.ProviderID, sub.Tier, sub.CommittedSpend, sub.Currency,
+			sub.StartsAt, sub.EndsAt, sla, sub.CreatedAt)
+		if err != nil {
+			return fmt.Errorf("failed to store subscription: %w", err)
+		}
+		return nil
+	})
+}
+
+const subscriptionColumns = `id, consumer_id, service_id, provider_id, tier, committed_spend::float8, currency, starts_at, ends_at, sla, cancelled_at, created_at`
+
+func scanSubscription(row pgx.Row) (*registry.Subscription, error) {
+	var sub registry.Subscription
+	var sla []byte
+	if err := row.Scan(&sub.ID, &sub.ConsumerID, &sub.ServiceID, &sub.ProviderID, &sub.Tier, &sub.CommittedSpend, &sub.Currency,
+		&sub.StartsAt, &sub.EndsAt, &sla, &sub.CancelledAt, &sub.CreatedAt); err != nil {
+		return nil, err
+	}
+	if sla != nil {
+		sub.SLA = &marketplace.SLAInfo{}
+		if err := json.Unmarshal(sla, sub.SLA); err != nil {
+			return nil, fmt.Errorf("invalid SLA of subscription %s: %w", sub.ID, err)
+		}
+	}
+	return &sub, nil
+}
+
+func (s *Store) GetSubscription(ctx context.Context, id string) (*registry.Subscription, error) {
+	sub, err := scanSubscription(s.pool.QueryRow(ctx, `SELECT `+subscriptionColumns+` FROM subscriptions WHERE id = $1`, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, registry.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get subscription: %w", err)
+	}
+	return sub, nil
+}
+
+func (s *Store) ListSubscriptions(ctx context.Context, filter registry.SubscriptionFilter) ([]*registry.Subscription, int, error) {
+	var where []string
+	var args []interface{}
+	for column, value := range map[string]string{
+		"consumer_id": filter.ConsumerID,
+		"service_id":  filter.ServiceID,
+		"provider_id": filter.ProviderID,
+	} {
+		if value != "" {
+			args = append(args, value)
+			where = append(where, fmt.Sprintf("%s = $%d", column, len(args)))
+		}
+	}
+	if filter.Status != "" {
+		// The status a subscription has at filter.At, as StatusAt decides it
+		args = append(args, filter.At)
+		at := fmt.Sprintf("$%d", len(args))
+		notCancelled := "(cancelled_at IS NULL OR cancelled_at > " + at + ")"
+		switch filter.Status {
+		case marketplace.SubscriptionCancelled:
+			where = append(where, "cancelled_at <= "+at)
+		case marketplace.SubscriptionPending:
+			where = append(where, notCancelled, "starts_at > "+at)
+		case marketplace.SubscriptionExpired:
+			where = append(where, notCancelled, "ends_at <= "+at)
+		default:
+			where = append(where, notCancelled, "starts_at <= "+at, "(ends_at IS NULL OR ends_at > "+at+")")
+		}
+	}
+	clause := ""
+	if len(where) > 0 {
+		clause = " WHERE " + strings.Join(where, " AND ")
+	}
+
+	var total int
+	if err := s.pool.QueryRow(ctx, `SELECT COUNT(*) FROM subscriptions`+clause, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count subscriptions: %w", err)
+	}
+
+	args = append(args, filter.Limit, filter.Offset)
+	rows, err := s.pool.Query(ctx, fmt.Sprintf(`SELECT %s FROM subscriptions%s ORDER BY created_at DESC, id LIMIT $%d OFFSET $%d`,
+		subscriptionColumns, clause, len(args)-1, len(args)), args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list subscriptions: %w", err)
+	}
+	defer rows.Close()
+
+	var subs []*registry.Subscription
+	for rows.Next() {
+		sub, err := scanSubscription(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		subs = append(subs, sub)
+	}
+	return subs, total, rows.Err()
+}
+
+func (s *Store) CancelSubscription(ctx context.Context, id string, at time.Time) error {
+	tag, err := s.pool.Exec(ctx, `UPDATE subscriptions SET cancelled_at = $2 WHERE id = $1 AND cancelled_at IS NULL`, id, at)
+	if err != nil {
+		return fmt.Errorf("failed to cancel subscription: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%w: subscription %s is already cancelled", registry.ErrConflict, id)
+	}
+	return nil
+}
@@ -0,0 +1,276 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/org/llm-marketplace/pkg/marketplace"
+	"go.uber.org/zap"
+
+	"github.com/org/llm-marketplace/services/registry/internal/api"
+	"github.com/org/llm-marketplace/services/registry/internal/registry"
+)
+
+// fakePolicy rejects descriptors whose compliance level, or that of one of
+// their dependencies, is in reject, and fails every call while err is set
+type fakePolicy struct {
+	reject       map[string]bool
+	err          error
+	checked      []*marketplace.ServiceDescriptor
+	dependencies [][]*marketplace.ServiceDescriptor // Those of each checked descriptor
+}
+
+func (p *fakePolicy) ValidateService(_ context.Context, desc *marketplace.ServiceDescriptor, dependencies []*marketplace.ServiceDescriptor) (*registry.PolicyResult, error) {
+	if p.err != nil {
+		return nil, p.err
+	}
+	p.checked = append(p.checked, desc)
+	p.dependencies = append(p.dependencies, dependencies)
+	for i, dep := range dependencies {
+		if dep.Compliance != nil && p.reject[dep.Compliance.Level] {
+			return &registry.PolicyResult{PolicyVersion: "v7", Violations: []registry.Violation{{
+				PolicyID: "data-classification", Severity: "high", Message: "Dependency " + dep.Name + " is not compliant", Field: fmt.Sprintf("dependencies[%d].compliance.level", i),
+			}}}, nil
+		}
+	}
+	if desc.Compliance != nil && p.reject[desc.Compliance.Level] {
+		return &registry.PolicyResult{PolicyVersion: "v7", Violations: []registry.Violation{{
+			PolicyID: "data-classification", Severity: "high", Message: "restricted services need an approved exception", Field: "compliance.level",
+		}}}, nil
+	}
+	return &registry.PolicyResult{Compliant: true, PolicyVersion: "v7"}, nil
+}
+
+type testRegistry struct {
+	store   *memStore
+	policy  *fakePolicy
+	blocker *fakeBlocker
+	svc     *registry.Service
+	router  *gin.Engine
+}
+
+func newTestRegistry(t *testing.T) *testRegistry {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	r := &testRegistry{store: newMemStore(), policy: &fakePolicy{reject: map[string]bool{"restricted": true}}}
+	r.router = gin.New()
+	r.router.HandleMethodNotAllowed = true
+	r.svc = registry.NewService(r.store, r.policy, zap.NewNop())
+	r.blocker = &fakeBlocker{policies: map[string][]string{}}
+	r.svc.SetBlocker(r.blocker)
+	api.RegisterRoutes(r.router, r.svc, "X-Provider-ID", "X-Consumer-ID", "X-Operator-ID", zap.NewNop())
+	return r
+}
+
+func (r *testRegistry) do(t *testing.T, method, path, provider string, body interface{}) (*httptest.ResponseRecorder, map[string]interface{}) {
+	t.Helper()
+	var reader bytes.Buffer
+	if body != nil {
+		json.NewEncoder(&reader).Encode(body)
+	}
+	req := httptest.NewRequest(method, path, &reader)
+	req.Header.Set("Content-Type", "application/json")
+	if provider != "" {
+		req.Header.Set("X-Provider-ID", provider)
+	}
+	w := httptest.NewRecorder()
+	r.router.ServeHTTP(w, req)
+
+	var decoded map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &decoded)
+	return w, decoded
+}
+
+func (r *testRegistry) provider(t *testing.T, name string) string {
+	t.Helper()
+	w, body := r.do(t, http.MethodPost, "/api/v1/providers", "", map[string]string{"name": name, "contact_email": "ops@" + name + ".example"})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("register provider = %d %s", w.Code, w.Body)
+	}
+	return body["id"].(string)
+}
+
+func descriptor(name string) marketplace.ServiceDescriptor {
+	return marketplace.ServiceDescriptor{
+		Name:         name,
+		Version:      "1.0.0",
+		Description:  "Summarizes documents",
+		Category:     "text-generation",
+		Tags:         []string{"nlp"},
+		Endpoint:     &marketplace.EndpointInfo{URL: "https://api.example.com/summarize", Protocol: "rest", Authentication: "api_key"},
+		Compliance:   &marketplace.ComplianceInfo{Level: "internal"},
+		SLA:          &marketplace.SLAInfo{Availability: 99.9, MaxLatencyMS: 800},
+		Pricing:      &marketplace.PricingInfo{Model: "per-token", Rate: 0.002, Unit: "1k tokens"},
+		Capabilities: []marketplace.Capability{{Name: "summarization"}},
+	}
+}
+
+func TestRegisterServicePublishesEvent(t *testing.T) {
+	r := newTestRegistry(t)
+	provider := r.provider(t, "acme")
+
+	w, body := r.do(t, http.MethodPost, "/api/v1/services", provider, descriptor("Summarizer"))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("register = %d %s", w.Code, w.Body)
+	}
+	id := body["id"].(string)
+	if body["status"] != "active" || body["revision"] != 1.0 || body["policy_version"] != "v7" {
+		t.Errorf("registration = %v", body)
+	}
+	if got := w.Header().Get("Location"); got != "/api/v1/services/"+id {
+		t.Errorf("Location = %q", got)
+	}
+	if len(r.policy.checked) != 1 || r.policy.checked[0].ServiceID != id || r.policy.checked[0].ProviderID != provider {
+		t.Errorf("policy engine checked %+v, want the descriptor with its assigned IDs", r.policy.checked)
+	}
+
+	events := r.store.events()
+	if len(events) != 1 {
+		t.Fatalf("outbox has %d events, want 1", len(events))
+	}
+	e := events[0]
+	if e.Type != marketplace.ServiceRegistered || e.Service.ServiceID != id || e.Provider.Name != "acme" || e.Status != "active" || e.Revision != 1 {
+		t.Errorf("event = %+v", e)
+	}
+}
+
+func TestRegisterServiceRejectsInvalidDescriptor(t *testing.T) {
+	r := newTestRegistry(t)
+	provider := r.provider(t, "acme")
+
+	desc := descriptor("Summarizer")
+	desc.Version = ""
+	desc.SLA.Availability = 120
+	w, body := r.do(t, http.MethodPost, "/api/v1/services", provider, desc)
+	if w.Code != http.StatusBadRequest || w.Header().Get("Content-Type") != "application/problem+json" {
+		t.Fatalf("register = %d %s, want a 400 problem", w.Code, w.Body)
+	}
+	var fields []string
+	for _, e := range body["errors"].([]interface{}) {
+		fields = append(fields, e.(map[string]interface{})["field"].(string))
+	}
+	if fmt.Sprint(fields) != "[sla.availability version]" {
+		t.Errorf("invalid fields = %v", fields)
+	}
+	if len(r.policy.checked) != 0 || len(r.store.events()) != 0 {
+		t.Error("an invalid descriptor reached the policy engine or the outbox")
+	}
+
+	// An unknown provider is an invalid field too
+	w, _ = r.do(t, http.MethodPost, "/api/v1/services", "", marketplace.ServiceDescriptor{Name: "x", ProviderID: "f47ac10b-58cc-4372-a567-0e02b2c3d479"})
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("unknown provider = %d, want 400", w.Code)
+	}
+}
+
+func TestRegisterServiceRejectedByPolicy(t *testing.T) {
+	r := newTestRegistry(t)
+	provider := r.provider(t, "acme")
+
+	desc := descriptor("Summarizer")
+	desc.Compliance.Level = "restricted"
+	w, body := r.do(t, http.MethodPost, "/api/v1/services", provider, desc)
+	if w.Code != http.StatusUnprocessableEntity || body["code"] != "policy-violation" {
+		t.Fatalf("register = %d %s, want 422 policy-violation", w.Code, w.Body)
+	}
+	violations := body["violations"].([]interface{})
+	if len(violations) != 1 || violations[0].(map[string]interface{})["policy_id"] != "data-classification" {
+		t.Errorf("violations = %v", violations)
+	}
+	if len(r.store.events()) != 0 {
+		t.Error("a rejected service was stored")
+	}
+}
+
+func TestRegisterServiceFailsClosedWithoutPolicyEngine(t *testing.T) {
+	r := newTestRegistry(t)
+	provider := r.provider(t, "acme")
+	r.policy.err = fmt.Errorf("%w: connection refused", registry.ErrPolicyUnavailable)
+
+	w, _ := r.do(t, http.MethodPost, "/api/v1/services", provider, descriptor("Summarizer"))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("register = %d, want 503", w.Code)
+	}
+	if len(r.store.events()) != 0 {
+		t.Error("a service was stored without a policy check")
+	}
+}
+
+func TestServiceLifecycle(t *testing.T) {
+	r := newTestRegistry(t)
+	provider := r.provider(t, "acme")
+	other := r.provider(t, "globex")
+
+	_, body := r.do(t, http.MethodPost, "/api/v1/services", provider, descriptor("Summarizer"))
+	id := body["id"].(string)
+	path := "/api/v1/services/" + id
+
+	// Duplicate name and version
+	if w, _ := r.do(t, http.MethodPost, "/api/v1/services", provider, descriptor("Summarizer")); w.Code != http.StatusConflict {
+		t.Errorf("duplicate register = %d, want 409", w.Code)
+	}
+
+	// Only the owner may change the service
+	updated := descriptor("Summarizer")
+	updated.Version = "1.1.0"
+	if w, _ := r.do(t, http.MethodPut, path, other, updated); w.Code != http.StatusForbidden {
+		t.Errorf("update by another provider = %d, want 403", w.Code)
+	}
+	w, body := r.do(t, http.MethodPut, path, provider, updated)
+	if w.Code != http.StatusOK || body["revision"] != 2.0 {
+		t.Fatalf("update = %d %s", w.Code, w.Body)
+	}
+
+	w, body = r.do(t, http.MethodPatch, path+"/status", provider, map[string]string{"status": "deprecated"})
+	if w.Code != http.StatusOK || body["status"] != "deprecated" {
+		t.Fatalf("set status = %d %s", w.Code, w.Body)
+	}
+	if w, _ := r.do(t, http.MethodPatch, path+"/status", provider, map[string]string{"status": "gone"}); w.Code != http.StatusBadRequest {
+		t.Errorf("unknown status = %d, want 400", w.Code)
+	}
+
+	w, body = r.do(t, http.MethodDelete, path, provider, nil)
+	if w.Code != http.StatusOK || body["status"] != "retired" {
+		t.Fatalf("deregister = %d %s", w.Code, w.Body)
+	}
+	if w, _ := r.do(t, http.MethodPut, path, provider, updated); w.Code != http.StatusConflict {
+		t.Errorf("update of a retired service = %d, want 409", w.Code)
+	}
+
+	var types []string
+	for _, e := range r.store.events() {
+		if e.Service.ServiceID == id {
+			types = append(types, fmt.Sprintf("%s@%d:%s", e.Type, e.Revision, e.Status))
+		}
+	}
+	want := "[service.registered@1:active service.updated@2:active service.updated@3:deprecated service.deregistered@4:retired]"
+	if fmt.Sprint(types) != want {
+		t.Errorf("events = %v, want %s", types, want)
+	}
+
+	w, body = r.do(t, http.MethodGet, "/api/v1/services?provider_id="+provider, "", nil)
+	if w.Code != http.StatusOK || body["total"] != 1.0 {
+		t.Errorf("list = %d %s", w.Code, w.Body)
+	}
+	if w, _ := r.do(t, http.MethodGet, "/api/v1/services/not-an-id", "", nil); w.Code != http.StatusNotFound {
+		t.Errorf("get unknown = %d, want 404", w.Code)
+	}
+}
+
+func TestRegisterProviderValidation(t *testing.T) {
+	r := newTestRegistry(t)
+	r.provider(t, "acme")
+
+	if w, _ := r.do(t, http.MethodPost, "/api/v1/providers", "", map[string]string{"name": "acme", "contact_email": "a@b.example"}); w.Code != http.StatusConflict {
+		t.Errorf("duplicate provider = %d, want 409", w.Code)
+	}
+	if w, _ := r.do(t, http.MethodPost, "/api/v1/providers", "", map[string]string{"name": "initech", "contact_email": "nope"}); w.Code != http.StatusBadRequest {
+		t.Errorf("bad email = %d, want 400", w.Code)
+	}
+}
@@ -0,0 +1,191 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeObjects is an in-memory object store
+type fakeObjects struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func (f *fakeObjects) Put(_ context.Context, key, _ string, body io.ReadSeeker, _ int64) error {
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.objects[key] = data
+	return nil
+}
+
+func (f *fakeObjects) Delete(_ context.Context, key string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.objects, key)
+	return nil
+}
+
+// withArtifacts gives the registry an in-memory artifact store taking files
+// up to maxSize
+func (r *testRegistry) withArtifacts(maxSize int64) *fakeObjects {
+	objects := &fakeObjects{objects: map[string][]byte{}}
+	r.svc.SetArtifactStore(objects, "artifacts", maxSize)
+	return objects
+}
+
+// uploadArtifact posts an artifact form to the portal
+func (r *testRegistry) uploadArtifact(t *testing.T, key string, fields map[string]string, content []byte) (*httptest.ResponseRecorder, map[string]interface{}) {
+	t.Helper()
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	for name, value := range fields {
+		form.WriteField(name, value)
+	}
+	if content != nil {
+		file, _ := form.CreateFormFile("file", "soc2-report.pdf")
+		file.Write(content)
+	}
+	form.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/portal/artifacts", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	req.Header.Set("Authorization", "Bearer "+key)
+	w := httptest.NewRecorder()
+	r.router.ServeHTTP(w, req)
+
+	var decoded map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &decoded)
+	return w, decoded
+}
+
+func soc2Fields() map[string]string {
+	return map[string]string{
+		"certification": "SOC2",
+		"issuer":        "Example Audit LLP",
+		"expires_at":    time.Now().Add(365 * 24 * time.Hour).UTC().Format(time.RFC3339),
+	}
+}
+
+func TestUploadArtifact(t *testing.T) {
+	r := newTestRegistry(t)
+	provider, key := r.providerKey(t, "acme")
+	report := []byte("%PDF-1.7 SOC 2 Type II report")
+
+	if w, _ := r.uploadArtifact(t, key, soc2Fields(), report); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("upload without storage = %d, want 503", w.Code)
+	}
+
+	objects := r.withArtifacts(1 << 20)
+	sum := sha256.Sum256(report)
+	fields := soc2Fields()
+	fields["sha256"] = hex.EncodeToString(sum[:])
+	w, body := r.uploadArtifact(t, key, fields, report)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("upload = %d %s", w.Code, w.Body)
+	}
+	if body["sha256"] != fields["sha256"] || body["certification"] != "SOC2" || body["size"] != float64(len(report)) || body["file_name"] != "soc2-report.pdf" {
+		t.Errorf("artifact = %v", body)
+	}
+	stored := objects.objects["artifacts/"+provider+"/"+body["id"].(string)]
+	if !bytes.Equal(stored, report) {
+		t.Errorf("stored object = %q", stored)
+	}
+
+	w, list := r.portal(t, http.MethodGet, "/artifacts", key, nil)
+	if w.Code != http.StatusOK || len(list["artifacts"].([]interface{})) != 1 {
+		t.Errorf("list = %d %s", w.Code, w.Body)
+	}
+	w, list = r.do(t, http.MethodGet, "/api/v1/providers/"+provider+"/artifacts", "", nil)
+	if w.Code != http.StatusOK || len(list["artifacts"].([]interface{})) != 1 {
+		t.Errorf("operator list = %d %s", w.Code, w.Body)
+	}
+}
+
+func TestUploadArtifactRejected(t *testing.T) {
+	r := newTestRegistry(t)
+	_, key := r.providerKey(t, "acme")
+	objects := r.withArtifacts(64)
+	report := []byte("SOC 2 report")
+
+	for name, tt := range map[string]struct {
+		change  func(map[string]string)
+		content []byte
+		field   string
+	}{
+		"no certification": {func(f map[string]string) { delete(f, "certification") }, report, "certification"},
+		"no expiry":        {func(f map[string]string) { delete(f, "expires_at") }, report, "expires_at"},
+		"expired":          {func(f map[string]string) { f["expires_at"] = "2020-01-01T00:00:00Z" }, report, "expires_at"},
+		"wrong digest":     {func(f map[string]string) { f["sha256"] = "00ff" }, report, "sha256"},
+		"no file":          {func(map[string]string) {}, nil, "file"},
+	} {
+		fields := soc2Fields()
+		tt.change(fields)
+		w, body := r.uploadArtifact(t, key, fields, tt.content)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: upload = %d, want 400", name, w.Code)
+			continue
+		}
+		if e := body["errors"].([]interface{})[0].(map[string]interface{}); e["field"] != tt.field {
+			t.Errorf("%s: error = %v, want one on %s", name, e, tt.field)
+		}
+	}
+
+	if w, _ := r.uploadArtifact(t, key, soc2Fields(), bytes.Repeat([]byte("x"), 128<<10)); w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("oversized upload = %d, want 413", w.Code)
+	}
+	if len(objects.objects) != 0 {
+		t.Errorf("rejected uploads stored %d objects", len(objects.objects))
+	}
+}
+
+func TestArtifactsSentToPolicyEngine(t *testing.T) {
+	r := newTestRegistry(t)
+	provider, key := r.providerKey(t, "acme")
+	objects := r.withArtifacts(1 << 20)
+	_, artifact := r.uploadArtifact(t, key, soc2Fields(), []byte("SOC 2 report"))
+
+	desc := descriptor("Summarizer")
+	desc.Compliance.Certifications = []string{"SOC2"}
+	w, body := r.do(t, http.MethodPost, "/api/v1/services", provider, desc)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("register = %d %s", w.Code, w.Body)
+	}
+	checked := r.policy.checked[len(r.policy.checked)-1].Compliance.Artifacts
+	if len(checked) != 1 || checked[0].SHA256 != artifact["sha256"] || checked[0].Certification != "SOC2" {
+		t.Errorf("policy engine checked artifacts %+v", checked)
+	}
+	if compliance := body["service"].(map[string]interface{})["compliance"].(map[string]interface{}); compliance["artifacts"] != nil {
+		t.Errorf("published compliance = %v, want no artifacts", compliance)
+	}
+
+	// Another provider can't delete the artifact
+	_, otherKey := r.providerKey(t, "globex")
+	if w, _ := r.portal(t, http.MethodDelete, "/artifacts/"+artifact["id"].(string), otherKey, nil); w.Code != http.StatusNotFound {
+		t.Errorf("delete another provider's artifact = %d, want 404", w.Code)
+	}
+
+	if w, _ := r.portal(t, http.MethodDelete, "/artifacts/"+artifact["id"].(string), key, nil); w.Code != http.StatusNoContent {
+		t.Fatalf("delete = %d %s", w.Code, w.Body)
+	}
+	if len(objects.objects) != 0 {
+		t.Errorf("deleted artifact's file kept: %v", objects.objects)
+	}
+	r.do(t, http.MethodPut, "/api/v1/services/"+body["id"].(string), provider, desc)
+	if checked := r.policy.checked[len(r.policy.checked)-1].Compliance.Artifacts; len(checked) != 0 {
+		t.Errorf("policy engine checked deleted artifacts %+v", checked)
+	}
+}
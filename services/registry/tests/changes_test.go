@@ -0,0 +1,155 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/org/llm-marketplace/pkg/marketplace"
+)
+
+func TestUpdateRecordsChangelog(t *testing.T) {
+	r := newTestRegistry(t)
+	provider := r.provider(t, "acme")
+	id := r.service(t, provider, "Summarizer")
+	path := "/api/v1/services/" + id
+
+	desc := descriptor("Summarizer")
+	desc.Pricing.Rate = 0.003
+	desc.SLA = &marketplace.SLAInfo{Availability: 99.95, MaxLatencyMS: 500}
+	desc.Capabilities = append(desc.Capabilities, marketplace.Capability{Name: "translation"})
+	if w, _ := r.do(t, http.MethodPut, path, provider, desc); w.Code != http.StatusOK {
+		t.Fatalf("update = %d %s", w.Code, w.Body)
+	}
+	events := r.store.events()
+	changes := events[len(events)-1].Changes
+	if len(changes) != 3 || changes[0].Kind != marketplace.ChangePricing || changes[0].Revision != 2 || changes[0].EffectiveAt.IsZero() {
+		t.Fatalf("event changes = %+v, want pricing, SLA and capabilities at revision 2", changes)
+	}
+
+	// Changes that touch none of them aren't recorded
+	desc.Description = "Summarizes and translates documents"
+	r.do(t, http.MethodPut, path, provider, desc)
+	if events := r.store.events(); len(events[len(events)-1].Changes) != 0 {
+		t.Errorf("a description change was recorded: %+v", events[len(events)-1].Changes)
+	}
+
+	w, body := r.consumerDo(t, http.MethodGet, path+"/changelog", "org-1", nil)
+	if w.Code != http.StatusOK || body["total"] != 3.0 {
+		t.Fatalf("changelog = %d %s", w.Code, w.Body)
+	}
+	_, body = r.consumerDo(t, http.MethodGet, path+"/changelog?kind=capabilities", "org-1", nil)
+	entries, _ := body["changes"].([]interface{})
+	if len(entries) != 1 || entries[0].(map[string]interface{})["summary"] != "Capabilities added: [translation]" {
+		t.Errorf("capability changes = %v", body)
+	}
+	if w, _ := r.consumerDo(t, http.MethodGet, path+"/changelog?kind=colour", "org-1", nil); w.Code != http.StatusBadRequest {
+		t.Errorf("unknown kind = %d, want 400", w.Code)
+	}
+}
+
+// subscribe subscribes consumer to a service, ending at end unless it is zero
+func (r *testRegistry) subscribe(t *testing.T, consumer, service string, end time.Time) {
+	t.Helper()
+	req := map[string]interface{}{"service_id": service}
+	if !end.IsZero() {
+		req["ends_at"] = end
+	}
+	if w, _ := r.consumerDo(t, http.MethodPost, "/api/v1/subscriptions", consumer, req); w.Code != http.StatusCreated {
+		t.Fatalf("subscribe = %d %s", w.Code, w.Body)
+	}
+}
+
+func TestPriceChangeNoticeAndApply(t *testing.T) {
+	r := newTestRegistry(t)
+	provider := r.provider(t, "acme")
+	id := r.service(t, provider, "Summarizer")
+	path := "/api/v1/services/" + id
+	r.subscribe(t, "org-1", id, time.Time{})
+	r.subscribe(t, "org-2", id, time.Now().AddDate(0, 0, 10)) // Ends before the change
+
+	// Subscribers are told before the price changes
+	desc := descriptor("Summarizer")
+	desc.Pricing.Rate = 0.003
+	if w, _ := r.do(t, http.MethodPut, path, provider, desc); w.Code != http.StatusConflict {
+		t.Errorf("repricing a subscribed service = %d, want 409", w.Code)
+	}
+	newPricing := map[string]interface{}{"model": "per-token", "rate": 0.003, "unit": "1k tokens"}
+	early := time.Now().AddDate(0, 0, 1)
+	if w, _ := r.do(t, http.MethodPost, path+"/price-changes", provider, map[string]interface{}{"pricing": newPricing, "effective_at": early}); w.Code != http.StatusBadRequest {
+		t.Errorf("price change without notice = %d, want 400", w.Code)
+	}
+
+	w, body := r.do(t, http.MethodPost, path+"/price-changes", provider, map[string]interface{}{"pricing": newPricing})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("schedule = %d %s", w.Code, w.Body)
+	}
+	effective, _ := time.Parse(time.RFC3339, body["effective_at"].(string))
+	if body["status"] != "scheduled" || body["notified"] != 1.0 || effective.Before(time.Now().AddDate(0, 0, 29)) {
+		t.Errorf("price change = %v, want scheduled a month ahead with one notice", body)
+	}
+	notices := r.store.notices()
+	if len(notices) != 1 || notices[0].ConsumerID != "org-1" || notices[0].OldPricing.Rate != 0.002 || notices[0].NewPricing.Rate != 0.003 || notices[0].Cancelled {
+		t.Errorf("notices = %+v, want one to org-1", notices)
+	}
+	if w, _ := r.do(t, http.MethodPost, path+"/price-changes", provider, map[string]interface{}{"pricing": newPricing}); w.Code != http.StatusConflict {
+		t.Errorf("second price change = %d, want 409", w.Code)
+	}
+	other := r.provider(t, "globex")
+	if w, _ := r.do(t, http.MethodPost, path+"/price-changes", other, map[string]interface{}{"pricing": newPricing}); w.Code != http.StatusForbidden {
+		t.Errorf("another provider's price change = %d, want 403", w.Code)
+	}
+
+	ctx := context.Background()
+	if n, err := r.svc.ApplyDuePriceChanges(ctx, time.Now()); err != nil || n != 0 {
+		t.Fatalf("ApplyDuePriceChanges before it is due = %d, %v", n, err)
+	}
+	before := len(r.store.events())
+	if n, err := r.svc.ApplyDuePriceChanges(ctx, effective); err != nil || n != 1 {
+		t.Fatalf("ApplyDuePriceChanges = %d, %v; want 1", n, err)
+	}
+	reg, _ := r.store.GetService(ctx, id)
+	if reg.Service.Pricing.Rate != 0.003 || reg.Revision != 2 {
+		t.Errorf("service = rate %v revision %d, want the new price at revision 2", reg.Service.Pricing.Rate, reg.Revision)
+	}
+	events := r.store.events()
+	if len(events) != before+1 || len(events[before].Changes) != 1 || !events[before].Changes[0].EffectiveAt.Equal(effective) {
+		t.Errorf("events = %+v, want the price change published", events[before:])
+	}
+	_, body = r.do(t, http.MethodGet, path+"/price-changes", provider, nil)
+	if changes, _ := body["price_changes"].([]interface{}); len(changes) != 1 || changes[0].(map[string]interface{})["status"] != "applied" {
+		t.Errorf("price changes = %v, want it applied", body)
+	}
+}
+
+func TestCancelPriceChange(t *testing.T) {
+	r := newTestRegistry(t)
+	provider, key := r.providerKey(t, "acme")
+	id := r.service(t, provider, "Summarizer")
+	r.subscribe(t, "org-1", id, time.Time{})
+	path := "/services/" + id + "/price-changes"
+
+	w, body := r.portal(t, http.MethodPost, path, key, map[string]interface{}{"pricing": map[string]interface{}{"model": "per-token", "rate": 0.003, "unit": "1k tokens"}})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("schedule = %d %s", w.Code, w.Body)
+	}
+	changeID := body["id"].(string)
+	if w, body := r.portal(t, http.MethodDelete, path+"/"+changeID, key, nil); w.Code != http.StatusOK || body["status"] != "cancelled" {
+		t.Fatalf("cancel = %d %s", w.Code, w.Body)
+	}
+	notices := r.store.notices()
+	if len(notices) != 2 || !notices[1].Cancelled || notices[1].PriceChangeID != changeID {
+		t.Errorf("notices = %+v, want the change withdrawn", notices)
+	}
+	if w, _ := r.portal(t, http.MethodDelete, path+"/"+changeID, key, nil); w.Code != http.StatusConflict {
+		t.Errorf("second cancel = %d, want 409", w.Code)
+	}
+
+	if n, _ := r.svc.ApplyDuePriceChanges(context.Background(), time.Now().AddDate(1, 0, 0)); n != 0 {
+		t.Errorf("applied %d cancelled price changes", n)
+	}
+	if reg, _ := r.store.GetService(context.Background(), id); reg.Service.Pricing.Rate != 0.002 {
+		t.Errorf("rate = %v, want the price unchanged", reg.Service.Pricing.Rate)
+	}
+}
@@ -0,0 +1,166 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/org/llm-marketplace/pkg/marketplace"
+
+	"github.com/org/llm-marketplace/services/registry/internal/contentscan"
+	"github.com/org/llm-marketplace/services/registry/internal/registry"
+)
+
+func (r *testRegistry) withContentScan() {
+	r.svc.SetContentDetectors(
+		contentscan.PII{Action: registry.FindingBlock},
+		contentscan.Phrases{Detector: "prohibited-claims", Category: "prohibited-claim", Action: registry.FindingFlag, Phrases: []string{"guaranteed accuracy"}},
+	)
+}
+
+func (r *testRegistry) scan(t *testing.T, id string) *registry.ContentScan {
+	t.Helper()
+	if _, err := r.svc.ScanContent(context.Background()); err != nil {
+		t.Fatalf("ScanContent: %v", err)
+	}
+	reg, err := r.store.GetService(context.Background(), id)
+	if err != nil {
+		t.Fatalf("GetService: %v", err)
+	}
+	return reg.ContentScan
+}
+
+func TestContentScanHoldsNewListings(t *testing.T) {
+	r := newTestRegistry(t)
+	r.withContentScan()
+	provider := r.provider(t, "acme")
+
+	id := r.service(t, provider, "Summarizer")
+	if r.status(t, id) != marketplace.StatusSuspended {
+		t.Fatalf("new service is %s, want held suspended until scanned", r.status(t, id))
+	}
+	if events := r.store.events(); events[len(events)-1].Status != marketplace.StatusSuspended {
+		t.Errorf("published %s, want suspended", events[len(events)-1].Status)
+	}
+	// Operators can't list it around the scan
+	if w, _ := r.do(t, http.MethodPatch, "/api/v1/services/"+id+"/status", "", map[string]string{"status": "active"}); w.Code != http.StatusConflict {
+		t.Errorf("operator PATCH active = %d, want 409", w.Code)
+	}
+
+	if scan := r.scan(t, id); scan.Status != registry.ScanPassed || scan.Held() || scan.ScannedAt == nil {
+		t.Errorf("scan = %+v", scan)
+	}
+	if r.status(t, id) != marketplace.StatusActive {
+		t.Errorf("scanned service is %s, want active", r.status(t, id))
+	}
+	if events := r.store.events(); events[len(events)-1].Status != marketplace.StatusActive || events[len(events)-1].Revision != 2 {
+		t.Errorf("published %+v, want active at revision 2", events[len(events)-1])
+	}
+
+	// A new listing with personal data stays held
+	desc := descriptor("Translator")
+	desc.Description = "Questions? Mail jane.doe@example.com"
+	w, body := r.do(t, http.MethodPost, "/api/v1/services", provider, desc)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("register = %d %s", w.Code, w.Body)
+	}
+	other := body["id"].(string)
+	scan := r.scan(t, other)
+	if scan.Status != registry.ScanBlocked || len(scan.Findings) != 1 || scan.Findings[0].Category != contentscan.CategoryEmail || scan.Findings[0].Field != "description" || scan.Findings[0].Match != "" {
+		t.Errorf("scan = %+v", scan)
+	}
+	if r.status(t, other) != marketplace.StatusSuspended {
+		t.Errorf("blocked service is %s, want suspended", r.status(t, other))
+	}
+	actions, _, _ := r.svc.ListModerationActions(context.Background(), registry.ActionFilter{TargetID: other})
+	if len(actions) != 1 || actions[0].Action != registry.ActionBlock || actions[0].Actor != "" {
+		t.Errorf("audit log = %+v", actions)
+	}
+
+	// Fixing the description has it scanned again and listed
+	desc.Description = "Translates between 40 languages"
+	if w, _ := r.do(t, http.MethodPut, "/api/v1/services/"+other, provider, desc); w.Code != http.StatusOK {
+		t.Fatalf("update = %d %s", w.Code, w.Body)
+	}
+	if scan := r.scan(t, other); scan.Status != registry.ScanPassed || r.status(t, other) != marketplace.StatusActive {
+		t.Errorf("after fix scan = %+v, status %s", scan, r.status(t, other))
+	}
+}
+
+func TestContentScanFlagsChangedListings(t *testing.T) {
+	r := newTestRegistry(t)
+	r.withContentScan()
+	provider := r.provider(t, "acme")
+	id := r.service(t, provider, "Summarizer")
+	r.scan(t, id)
+
+	// Only changes to the scanned text are scanned again
+	desc := descriptor("Summarizer")
+	desc.SLA.Availability = 99.95
+	if w, _ := r.do(t, http.MethodPut, "/api/v1/services/"+id, provider, desc); w.Code != http.StatusOK {
+		t.Fatalf("update = %d %s", w.Code, w.Body)
+	}
+	if reg, _ := r.store.GetService(context.Background(), id); reg.ContentScan.Status != registry.ScanPassed {
+		t.Errorf("scan after SLA change = %s, want passed", reg.ContentScan.Status)
+	}
+
+	desc.Description = "Summaries with Guaranteed Accuracy"
+	if w, _ := r.do(t, http.MethodPut, "/api/v1/services/"+id, provider, desc); w.Code != http.StatusOK {
+		t.Fatalf("update = %d %s", w.Code, w.Body)
+	}
+	if r.status(t, id) != marketplace.StatusActive {
+		t.Errorf("changed service is %s, want it listed while scanned", r.status(t, id))
+	}
+	scan := r.scan(t, id)
+	if scan.Status != registry.ScanFlagged || len(scan.Findings) != 1 || scan.Findings[0].Match != "guaranteed accuracy" || r.status(t, id) != marketplace.StatusActive {
+		t.Errorf("scan = %+v, status %s", scan, r.status(t, id))
+	}
+
+	// Flagged listings make up the review queue
+	w, body := r.do(t, http.MethodGet, "/api/v1/services?content_scan=flagged", "", nil)
+	if w.Code != http.StatusOK || body["total"] != float64(1) {
+		t.Errorf("flagged listings = %d %v", w.Code, body)
+	}
+	if w, _ := r.do(t, http.MethodGet, "/api/v1/services?content_scan=unknown", "", nil); w.Code != http.StatusBadRequest {
+		t.Errorf("unknown scan status = %d, want 400", w.Code)
+	}
+
+	if w, _ := r.adminDo(t, http.MethodPost, "/api/v1/admin/services/"+id+"/release", map[string]string{}); w.Code != http.StatusBadRequest {
+		t.Errorf("release without reason = %d, want 400", w.Code)
+	}
+	w, body = r.adminDo(t, http.MethodPost, "/api/v1/admin/services/"+id+"/release", map[string]string{"reason": "Backed by published benchmarks"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("release = %d %s", w.Code, w.Body)
+	}
+	if scan := body["content_scan"].(map[string]interface{}); scan["status"] != registry.ScanReleased || scan["released_by"] != "mod-alice" {
+		t.Errorf("released scan = %v", scan)
+	}
+	if w, _ := r.adminDo(t, http.MethodPost, "/api/v1/admin/services/"+id+"/release", map[string]string{"reason": "again"}); w.Code != http.StatusConflict {
+		t.Errorf("second release = %d, want 409", w.Code)
+	}
+}
+
+func TestPIIDetector(t *testing.T) {
+	detector := contentscan.PII{Action: registry.FindingBlock}
+	for text, want := range map[string]string{
+		"Contact sales@example.com for access":     contentscan.CategoryEmail,
+		"Call +1 (415) 555-0132 for a demo":        contentscan.CategoryPhone,
+		"Test card 4111 1111 1111 1111 works":      contentscan.CategoryCardNumber,
+		"Example record: 123-45-6789":              contentscan.CategorySSN,
+		"Version 2.1.0, released 2026-01-15":       "",
+		"Handles 1000000 tokens at 99.9% accuracy": "",
+		"Order 4111 1111 1111 1112 is not a card":  "",
+	} {
+		findings, err := detector.Detect(context.Background(), "description", text)
+		if err != nil {
+			t.Fatalf("Detect(%q): %v", text, err)
+		}
+		got := ""
+		if len(findings) > 0 {
+			got = findings[0].Category
+		}
+		if got != want || len(findings) > 1 {
+			t.Errorf("Detect(%q) = %+v, want %q", text, findings, want)
+		}
+	}
+}
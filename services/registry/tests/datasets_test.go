@@ -0,0 +1,62 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/org/llm-marketplace/pkg/marketplace"
+)
+
+// dataset is a valid dataset listing
+func dataset(name string) marketplace.ServiceDescriptor {
+	desc := descriptor(name)
+	desc.Kind = marketplace.KindDataset
+	desc.Endpoint = &marketplace.EndpointInfo{URL: "https://data.example.com/transcripts"}
+	desc.Dataset = &marketplace.DatasetInfo{
+		License:     "CC-BY-4.0",
+		Attribution: "Acme Corp. Support Transcripts (2025)",
+		Format:      "jsonl",
+		SizeBytes:   2 << 30,
+		Records:     120000,
+		Provenance:  &marketplace.DatasetProvenance{Source: "Acme support desk", Method: marketplace.ProvenanceCollected},
+	}
+	return desc
+}
+
+func TestRegisterDataset(t *testing.T) {
+	r := newTestRegistry(t)
+	provider := r.provider(t, "acme")
+
+	w, body := r.do(t, http.MethodPost, "/api/v1/services", provider, dataset("Support transcripts"))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("register = %d %s", w.Code, w.Body)
+	}
+	id := body["id"].(string)
+	if checked := r.policy.checked[0]; !checked.IsDataset() || checked.Dataset == nil || checked.Dataset.Provenance.Source != "Acme support desk" {
+		t.Errorf("policy engine checked %+v, want the dataset", checked)
+	}
+	if e := r.store.events()[0]; e.Service.Dataset == nil || e.Service.Dataset.License != "CC-BY-4.0" {
+		t.Errorf("event service = %+v, want the dataset", e.Service)
+	}
+	r.service(t, provider, "Summarizer")
+
+	if w, body := r.do(t, http.MethodGet, "/api/v1/services?kind=dataset", "", nil); w.Code != http.StatusOK || body["total"] != 1.0 {
+		t.Errorf("list datasets = %d %v, want 1", w.Code, body["total"])
+	}
+
+	invalid := dataset("Support transcripts")
+	invalid.Dataset.License = ""
+	invalid.Dataset.SizeBytes = 0
+	if w, body := r.do(t, http.MethodPut, "/api/v1/services/"+id, provider, invalid); w.Code != http.StatusBadRequest {
+		t.Errorf("invalid dataset = %d %v, want 400", w.Code, body)
+	}
+	template := promptTemplate("Support transcripts")
+	if w, _ := r.do(t, http.MethodPut, "/api/v1/services/"+id, provider, template); w.Code != http.StatusBadRequest {
+		t.Errorf("dataset changed to a prompt template = %d, want 400", w.Code)
+	}
+	reg, _ := r.store.GetService(context.Background(), id)
+	if reg.Service.Dataset == nil || reg.Revision != 1 {
+		t.Errorf("stored %+v at revision %d, want the dataset unchanged", reg.Service, reg.Revision)
+	}
+}
@@ -0,0 +1,104 @@
+package tests
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/org/llm-marketplace/pkg/marketplace"
+)
+
+func TestRegisterServiceWithDependencies(t *testing.T) {
+	r := newTestRegistry(t)
+	provider := r.provider(t, "acme")
+	embeddings := r.service(t, provider, "Embeddings")
+	vectors := r.service(t, r.provider(t, "globex"), "Vector DB")
+
+	desc := descriptor("RAG")
+	desc.Dependencies = []marketplace.Dependency{{ServiceID: embeddings, Role: "embedding"}, {ServiceID: vectors, Role: "vector-store"}}
+	w, body := r.do(t, http.MethodPost, "/api/v1/services", provider, desc)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("register = %d %s", w.Code, w.Body)
+	}
+	if deps := body["service"].(map[string]interface{})["dependencies"].([]interface{}); len(deps) != 2 {
+		t.Errorf("dependencies = %v", deps)
+	}
+	checked := r.policy.dependencies[len(r.policy.dependencies)-1]
+	if len(checked) != 2 || checked[0].ServiceID != embeddings || checked[1].Name != "Vector DB" {
+		t.Errorf("policy engine checked dependencies %+v", checked)
+	}
+}
+
+func TestDependencyMustBeCompliant(t *testing.T) {
+	r := newTestRegistry(t)
+	provider := r.provider(t, "acme")
+	embeddings := r.service(t, provider, "Embeddings")
+
+	// The policies change after the dependency was registered
+	r.policy.reject["internal"] = true
+	desc := descriptor("RAG")
+	desc.Compliance.Level = "confidential"
+	desc.Dependencies = []marketplace.Dependency{{ServiceID: embeddings}}
+	w, body := r.do(t, http.MethodPost, "/api/v1/services", provider, desc)
+	if w.Code != http.StatusUnprocessableEntity || body["code"] != "policy-violation" {
+		t.Fatalf("register = %d %s, want 422 policy-violation", w.Code, w.Body)
+	}
+}
+
+func TestDependenciesMustBeAvailable(t *testing.T) {
+	r := newTestRegistry(t)
+	provider := r.provider(t, "acme")
+	retired := r.service(t, provider, "Legacy")
+	r.do(t, http.MethodDelete, "/api/v1/services/"+retired, provider, nil)
+	suspended := r.service(t, provider, "Phishing")
+	r.adminDo(t, http.MethodPost, "/api/v1/admin/services/"+suspended+"/suspend", map[string]string{"reason": "Phishing endpoint"})
+	private := r.service(t, r.tenantProvider(t, "acme-ml", "acme"), "Internal LLM")
+
+	for name, tt := range map[string]struct {
+		id, message string
+	}{
+		"unknown":          {"2b0e6a3c-58a7-4a4b-9d59-1f0f6b1f4c11", "is not a registered service"},
+		"retired":          {retired, "is retired"},
+		"suspended":        {suspended, "is suspended"},
+		"another tenant's": {private, "is not a registered service"},
+	} {
+		desc := descriptor("RAG")
+		desc.Dependencies = []marketplace.Dependency{{ServiceID: tt.id}}
+		w, body := r.do(t, http.MethodPost, "/api/v1/services", provider, desc)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s dependency = %d, want 400", name, w.Code)
+			continue
+		}
+		if e := body["errors"].([]interface{})[0].(map[string]interface{}); e["field"] != "dependencies[0].service_id" || e["message"] != tt.message {
+			t.Errorf("%s dependency error = %v", name, e)
+		}
+	}
+}
+
+func TestDependencyCycleRejected(t *testing.T) {
+	r := newTestRegistry(t)
+	provider := r.provider(t, "acme")
+	embeddings := r.service(t, provider, "Embeddings")
+
+	desc := descriptor("Reranker")
+	desc.Dependencies = []marketplace.Dependency{{ServiceID: embeddings}}
+	w, body := r.do(t, http.MethodPost, "/api/v1/services", provider, desc)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("register = %d %s", w.Code, w.Body)
+	}
+	desc = descriptor("RAG")
+	desc.Dependencies = []marketplace.Dependency{{ServiceID: body["id"].(string)}}
+	if w, body = r.do(t, http.MethodPost, "/api/v1/services", provider, desc); w.Code != http.StatusCreated {
+		t.Fatalf("register = %d %s", w.Code, w.Body)
+	}
+
+	// Embeddings -> RAG -> Reranker -> Embeddings
+	update := descriptor("Embeddings")
+	update.Dependencies = []marketplace.Dependency{{ServiceID: body["id"].(string)}}
+	w, body = r.do(t, http.MethodPut, "/api/v1/services/"+embeddings, provider, update)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("update = %d %s, want 400", w.Code, w.Body)
+	}
+	if e := body["errors"].([]interface{})[0].(map[string]interface{}); e["message"] != "depends on this service" {
+		t.Errorf("error = %v", e)
+	}
+}
@@ -0,0 +1,102 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/org/llm-marketplace/pkg/marketplace"
+)
+
+func TestComplianceExpiries(t *testing.T) {
+	ctx := context.Background()
+	r := newTestRegistry(t)
+	r.svc.SetComplianceExpiry(30*24*time.Hour, "marketplace-admins")
+	provider, key := r.providerKey(t, "acme")
+	r.withArtifacts(1 << 20)
+	now := time.Now().UTC()
+
+	fields := soc2Fields()
+	fields["expires_at"] = now.AddDate(0, 0, 40).Format(time.RFC3339)
+	if w, _ := r.uploadArtifact(t, key, fields, []byte("SOC 2 report")); w.Code != http.StatusCreated {
+		t.Fatalf("upload = %d %s", w.Code, w.Body)
+	}
+	desc := descriptor("Summarizer")
+	desc.Compliance.Certifications = []string{"SOC2", "ISO27001"} // ISO 27001 has no artifact and isn't tracked
+	desc.Compliance.DataProcessingAgreement = &marketplace.DataProcessingAgreement{Reference: "DPA-2026-01", ExpiresAt: now.AddDate(0, 0, 100)}
+	w, body := r.do(t, http.MethodPost, "/api/v1/services", provider, desc)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("register = %d %s", w.Code, w.Body)
+	}
+	id := body["id"].(string)
+
+	if n, err := r.svc.CheckComplianceExpiries(ctx, now); err != nil || n != 0 {
+		t.Fatalf("check while current = %d, %v", n, err)
+	}
+
+	// The SOC 2 artifact is within 30 days of lapsing
+	if n, err := r.svc.CheckComplianceExpiries(ctx, now.AddDate(0, 0, 15)); err != nil || n != 1 {
+		t.Fatalf("check at 15 days = %d, %v", n, err)
+	}
+	_, body = r.do(t, http.MethodGet, "/api/v1/services/"+id, provider, nil)
+	expiries := body["compliance_expiries"].([]interface{})
+	if len(expiries) != 1 || expiries[0].(map[string]interface{})["name"] != "SOC2" || expiries[0].(map[string]interface{})["status"] != marketplace.ExpiryExpiring {
+		t.Errorf("expiries = %v", expiries)
+	}
+	events := r.store.events()
+	if last := events[len(events)-1]; len(last.ComplianceExpiries) != 1 || last.Revision != 2 {
+		t.Errorf("published %+v", last)
+	}
+	notices := r.store.expiryNotices()
+	if len(notices) != 2 || notices[0].RecipientID != provider || notices[1].RecipientID != "marketplace-admins" || notices[0].Expiry.Kind != marketplace.ExpiryCertification {
+		t.Errorf("notices = %+v", notices)
+	}
+
+	// Nothing changed, so nothing is published or sent again
+	if n, err := r.svc.CheckComplianceExpiries(ctx, now.AddDate(0, 0, 16)); err != nil || n != 0 {
+		t.Fatalf("repeated check = %d, %v", n, err)
+	}
+	if len(r.store.expiryNotices()) != 2 {
+		t.Errorf("repeated check sent notices again")
+	}
+
+	// SOC 2 lapses and the DPA nears expiry
+	if n, err := r.svc.CheckComplianceExpiries(ctx, now.AddDate(0, 0, 75)); err != nil || n != 1 {
+		t.Fatalf("check at 75 days = %d, %v", n, err)
+	}
+	notices = r.store.expiryNotices()[2:]
+	if len(notices) != 4 || notices[0].Expiry.Status != marketplace.ExpiryExpired || notices[2].Expiry.Kind != marketplace.ExpiryDataProcessingAgreement || notices[2].Expiry.Name != "DPA-2026-01" {
+		t.Errorf("notices = %+v", notices)
+	}
+
+	// A renewed DPA is no longer flagged
+	desc.Compliance.DataProcessingAgreement.ExpiresAt = now.AddDate(2, 0, 0)
+	if w, _ := r.do(t, http.MethodPut, "/api/v1/services/"+id, provider, desc); w.Code != http.StatusOK {
+		t.Fatalf("update = %d %s", w.Code, w.Body)
+	}
+	if n, err := r.svc.CheckComplianceExpiries(ctx, now.AddDate(0, 0, 75)); err != nil || n != 1 {
+		t.Fatalf("check after renewal = %d, %v", n, err)
+	}
+	_, body = r.do(t, http.MethodGet, "/api/v1/services/"+id, provider, nil)
+	if expiries := body["compliance_expiries"].([]interface{}); len(expiries) != 1 || expiries[0].(map[string]interface{})["status"] != marketplace.ExpiryExpired {
+		t.Errorf("expiries after renewal = %v", expiries)
+	}
+	if len(r.store.expiryNotices()) != 6 {
+		t.Errorf("renewal sent notices")
+	}
+}
+
+func TestDataProcessingAgreementNeedsExpiry(t *testing.T) {
+	r := newTestRegistry(t)
+	provider := r.provider(t, "acme")
+	desc := descriptor("Summarizer")
+	desc.Compliance.DataProcessingAgreement = &marketplace.DataProcessingAgreement{Reference: "DPA-2026-01"}
+	w, body := r.do(t, http.MethodPost, "/api/v1/services", provider, desc)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("register = %d, want 400", w.Code)
+	}
+	if e := body["errors"].([]interface{})[0].(map[string]interface{}); e["field"] != "compliance.data_processing_agreement.expires_at" {
+		t.Errorf("error = %v", e)
+	}
+}
@@ -0,0 +1,58 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/org/llm-marketplace/pkg/marketplace"
+)
+
+// fineTuning is a valid fine-tuning offering
+func fineTuning(name string) marketplace.ServiceDescriptor {
+	desc := descriptor(name)
+	desc.Kind = marketplace.KindFineTuning
+	desc.FineTuning = &marketplace.FineTuningOffering{
+		BaseModels:       []string{"llama-3.1-8b", "mistral-7b"},
+		DataRequirements: &marketplace.FineTuningDataRequirements{Formats: []string{"jsonl"}, MinExamples: 100, MaxSizeBytes: 1 << 30},
+		TurnaroundHours:  24,
+	}
+	return desc
+}
+
+func TestRegisterFineTuning(t *testing.T) {
+	r := newTestRegistry(t)
+	provider := r.provider(t, "acme")
+
+	w, body := r.do(t, http.MethodPost, "/api/v1/services", provider, fineTuning("Llama tuning"))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("register = %d %s", w.Code, w.Body)
+	}
+	id := body["id"].(string)
+	if checked := r.policy.checked[0]; !checked.IsFineTuning() || checked.FineTuning == nil || checked.FineTuning.TurnaroundHours != 24 {
+		t.Errorf("policy engine checked %+v, want the fine-tuning offering", checked)
+	}
+	if e := r.store.events()[0]; e.Service.FineTuning == nil || !e.Service.FineTuning.Tunes("mistral-7b") {
+		t.Errorf("event service = %+v, want the fine-tuning offering", e.Service)
+	}
+	r.service(t, provider, "Summarizer")
+
+	if w, body := r.do(t, http.MethodGet, "/api/v1/services?kind=fine_tuning", "", nil); w.Code != http.StatusOK || body["total"] != 1.0 {
+		t.Errorf("list fine-tuning offerings = %d %v, want 1", w.Code, body["total"])
+	}
+
+	invalid := fineTuning("Llama tuning")
+	invalid.FineTuning.BaseModels = nil
+	invalid.FineTuning.TurnaroundHours = 0
+	if w, body := r.do(t, http.MethodPut, "/api/v1/services/"+id, provider, invalid); w.Code != http.StatusBadRequest {
+		t.Errorf("invalid fine-tuning offering = %d %v, want 400", w.Code, body)
+	}
+	service := descriptor("Llama tuning")
+	if w, _ := r.do(t, http.MethodPut, "/api/v1/services/"+id, provider, service); w.Code != http.StatusBadRequest {
+		t.Errorf("fine-tuning offering changed to a service = %d, want 400", w.Code)
+	}
+	reg, _ := r.store.GetService(context.Background(), id)
+	if reg.Service.FineTuning == nil || reg.Revision != 1 {
+		t.Errorf("stored %+v at revision %d, want the offering unchanged", reg.Service, reg.Revision)
+	}
+}
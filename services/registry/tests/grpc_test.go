@@ -0,0 +1,200 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/org/llm-marketplace/pkg/marketplace"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	policypb "github.com/org/llm-marketplace/services/registry/api/proto/policyengine/v1"
+	pb "github.com/org/llm-marketplace/services/registry/api/proto/v1"
+	"github.com/org/llm-marketplace/services/registry/internal/grpcapi"
+	"github.com/org/llm-marketplace/services/registry/internal/policy"
+	"github.com/org/llm-marketplace/services/registry/internal/registry"
+)
+
+// fakePolicyEngine records validation requests and rejects services without
+// an endpoint
+type fakePolicyEngine struct {
+	policypb.UnimplementedPolicyEngineServiceServer
+	requests []*policypb.ValidateServiceRequest
+}
+
+func (f *fakePolicyEngine) ValidateService(_ context.Context, req *policypb.ValidateServiceRequest) (*policypb.ValidateServiceResponse, error) {
+	f.requests = append(f.requests, req)
+	resp := &policypb.ValidateServiceResponse{Compliant: true, PolicyVersion: "v7"}
+	if req.GetEndpoint().GetUrl() == "" {
+		resp.Compliant = false
+		resp.Violations = []*policypb.PolicyViolation{{PolicyId: "endpoint", Severity: "critical", Message: "an endpoint is required", Field: "endpoint.url"}}
+	}
+	return resp, nil
+}
+
+// dialBufconn serves register on an in-memory listener and returns a
+// connection to it
+func dialBufconn(t *testing.T, register func(*grpc.Server)) *grpc.ClientConn {
+	t.Helper()
+	listener := bufconn.Listen(1 << 20)
+	server := grpc.NewServer()
+	register(server)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return listener.Dial() }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestPolicyClient(t *testing.T) {
+	engine := &fakePolicyEngine{}
+	client := policy.NewClient(dialBufconn(t, func(s *grpc.Server) { policypb.RegisterPolicyEngineServiceServer(s, engine) }), time.Second)
+
+	desc := descriptor("Summarizer")
+	desc.ServiceID = "svc-1"
+	result, err := client.ValidateService(context.Background(), &desc, nil)
+	if err != nil {
+		t.Fatalf("ValidateService: %v", err)
+	}
+	if !result.Compliant || result.PolicyVersion != "v7" {
+		t.Errorf("result = %+v", result)
+	}
+	req := engine.requests[0]
+	if req.GetServiceId() != "svc-1" || req.GetSla().GetMaxLatency() != 800 || req.GetEndpoint().GetAuthentication() != "api_key" {
+		t.Errorf("request = %v", req)
+	}
+	// A flat rate is sent as a single tier
+	if rates := req.GetPricing().GetRates(); len(rates) != 1 || rates[0].GetRate() != 0.002 || rates[0].GetUnit() != "1k tokens" {
+		t.Errorf("pricing = %v", req.GetPricing())
+	}
+
+	desc.Endpoint = nil
+	result, err = client.ValidateService(context.Background(), &desc, nil)
+	if err != nil || result.Compliant || len(result.Violations) != 1 || result.Violations[0].Field != "endpoint.url" {
+		t.Errorf("result = %+v, %v; want one endpoint violation", result, err)
+	}
+
+	template := promptTemplate("Summary prompt")
+	if _, err := client.ValidateService(context.Background(), &template, nil); err != nil {
+		t.Fatalf("ValidateService: %v", err)
+	}
+	req = engine.requests[2]
+	if tpl := req.GetPromptTemplate(); req.GetKind() != marketplace.KindPromptTemplate || tpl.GetTemplate() != template.PromptTemplate.Template ||
+		len(tpl.GetVariables()) != 2 || tpl.GetVariables()[1].GetDefault() != "3" || tpl.GetModelFamilies()[0] != "gpt-4" {
+		t.Errorf("request = %v, want the prompt template", req)
+	}
+
+	data := dataset("Support transcripts")
+	if _, err := client.ValidateService(context.Background(), &data, nil); err != nil {
+		t.Fatalf("ValidateService: %v", err)
+	}
+	req = engine.requests[3]
+	if ds := req.GetDataset(); req.GetKind() != marketplace.KindDataset || ds.GetLicense() != "CC-BY-4.0" || ds.GetSizeBytes() != 2<<30 ||
+		ds.GetProvenance().GetMethod() != marketplace.ProvenanceCollected {
+		t.Errorf("request = %v, want the dataset", req)
+	}
+
+	tuning := fineTuning("Llama tuning")
+	if _, err := client.ValidateService(context.Background(), &tuning, nil); err != nil {
+		t.Fatalf("ValidateService: %v", err)
+	}
+	req = engine.requests[4]
+	if ft := req.GetFineTuning(); req.GetKind() != marketplace.KindFineTuning || ft.GetBaseModels()[0] != "llama-3.1-8b" || ft.GetTurnaroundHours() != 24 ||
+		ft.GetDataRequirements().GetMinExamples() != 100 {
+		t.Errorf("request = %v, want the fine-tuning offering", req)
+	}
+
+	regional := descriptor("Chat")
+	regional.ServingRegions = []marketplace.ServingRegion{{Region: "us-east-1", MedianLatencyMS: 40}, {Region: "eu-west-1", MedianLatencyMS: 65}}
+	if _, err := client.ValidateService(context.Background(), &regional, nil); err != nil {
+		t.Fatalf("ValidateService: %v", err)
+	}
+	req = engine.requests[5]
+	if regions := req.GetServingRegions(); len(regions) != 2 || regions[1].GetRegion() != "eu-west-1" || regions[1].GetMedianLatencyMs() != 65 {
+		t.Errorf("request = %v, want the serving regions", req)
+	}
+
+	// Unimplemented HealthCheck stands in for an unreachable engine
+	if err := client.Check(context.Background()); err == nil {
+		t.Error("Check succeeded against an engine without HealthCheck")
+	}
+}
+
+func TestPolicyClientFailsClosed(t *testing.T) {
+	conn := dialBufconn(t, func(*grpc.Server) {}) // No policy engine registered
+	client := policy.NewClient(conn, time.Second)
+
+	desc := descriptor("Summarizer")
+	if _, err := client.ValidateService(context.Background(), &desc, nil); !errors.Is(err, registry.ErrPolicyUnavailable) {
+		t.Errorf("err = %v, want ErrPolicyUnavailable", err)
+	}
+}
+
+func TestGRPCRegisterService(t *testing.T) {
+	store := newMemStore()
+	engine := &fakePolicyEngine{}
+	policyClient := policy.NewClient(dialBufconn(t, func(s *grpc.Server) { policypb.RegisterPolicyEngineServiceServer(s, engine) }), time.Second)
+	svc := registry.NewService(store, policyClient, zap.NewNop())
+	client := pb.NewRegistryServiceClient(dialBufconn(t, func(s *grpc.Server) {
+		pb.RegisterRegistryServiceServer(s, grpcapi.NewServer(svc, zap.NewNop()))
+	}))
+	ctx := context.Background()
+
+	provider, err := client.RegisterProvider(ctx, &pb.RegisterProviderRequest{Name: "acme", ContactEmail: "ops@acme.example"})
+	if err != nil {
+		t.Fatalf("RegisterProvider: %v", err)
+	}
+	asProvider := metadata.AppendToOutgoingContext(ctx, grpcapi.ProviderMetadataKey, provider.GetId())
+
+	service := &pb.ServiceDescriptor{
+		Name:     "Summarizer",
+		Version:  "1.0.0",
+		Category: "text-generation",
+		Tags:     []string{"nlp"},
+		Endpoint: &policypb.ServiceEndpoint{Url: "https://api.example.com/summarize"},
+		Pricing:  &policypb.ServicePricing{Model: "per-token", Rates: []*policypb.PricingTier{{Rate: 0.002, Unit: "1k tokens"}}},
+	}
+	reg, err := client.RegisterService(asProvider, &pb.RegisterServiceRequest{Service: service})
+	if err != nil {
+		t.Fatalf("RegisterService: %v", err)
+	}
+	if reg.GetProviderId() != provider.GetId() || reg.GetStatus() != "active" || reg.GetService().GetTags()[0] != "nlp" {
+		t.Errorf("registration = %v", reg)
+	}
+	stored, _ := store.GetService(ctx, reg.GetId())
+	if p := stored.Service.Pricing; p.Rate != 0.002 || len(p.Tiers) != 0 {
+		t.Errorf("stored pricing = %+v, want a flat rate", p)
+	}
+
+	service.Endpoint = nil
+	service.Version = "2.0.0"
+	_, err = client.RegisterService(asProvider, &pb.RegisterServiceRequest{Service: service})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("policy violation = %v, want InvalidArgument", err)
+	}
+
+	other := metadata.AppendToOutgoingContext(ctx, grpcapi.ProviderMetadataKey, "f47ac10b-58cc-4372-a567-0e02b2c3d479")
+	_, err = client.DeregisterService(other, &pb.DeregisterServiceRequest{Id: reg.GetId()})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Errorf("deregister by another provider = %v, want PermissionDenied", err)
+	}
+
+	if got := store.events(); len(got) != 1 || got[0].Type != marketplace.ServiceRegistered {
+		t.Errorf("events = %+v, want one registration", got)
+	}
+}
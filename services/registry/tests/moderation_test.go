@@ -0,0 +1,297 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/org/llm-marketplace/pkg/marketplace"
+
+	"github.com/org/llm-marketplace/services/registry/internal/registry"
+)
+
+// fakeBlocker keeps the policies blocking services by ID, and fails every
+// call while err is set
+type fakeBlocker struct {
+	policies map[string][]string
+	err      error
+	next     int
+}
+
+func (b *fakeBlocker) BlockServices(_ context.Context, _ *registry.Takedown, serviceIDs []string) (string, error) {
+	if b.err != nil {
+		return "", b.err
+	}
+	b.next++
+	id := fmt.Sprintf("policy-%d", b.next)
+	b.policies[id] = serviceIDs
+	return id, nil
+}
+
+func (b *fakeBlocker) UnblockServices(_ context.Context, policyID string) error {
+	if b.err != nil {
+		return b.err
+	}
+	delete(b.policies, policyID)
+	return nil
+}
+
+// blocked reports whether a policy blocks the service
+func (b *fakeBlocker) blocked(serviceID string) bool {
+	for _, ids := range b.policies {
+		if slices.Contains(ids, serviceID) {
+			return true
+		}
+	}
+	return false
+}
+
+// adminDo sends a request as an operator
+func (r *testRegistry) adminDo(t *testing.T, method, path string, body interface{}) (*httptest.ResponseRecorder, map[string]interface{}) {
+	t.Helper()
+	var reader bytes.Buffer
+	if body != nil {
+		json.NewEncoder(&reader).Encode(body)
+	}
+	req := httptest.NewRequest(method, path, &reader)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Operator-ID", "mod-alice")
+	w := httptest.NewRecorder()
+	r.router.ServeHTTP(w, req)
+
+	var decoded map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &decoded)
+	return w, decoded
+}
+
+// service registers an active service for provider
+func (r *testRegistry) service(t *testing.T, provider, name string) string {
+	t.Helper()
+	w, body := r.do(t, http.MethodPost, "/api/v1/services", provider, descriptor(name))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("register = %d %s", w.Code, w.Body)
+	}
+	return body["id"].(string)
+}
+
+func (r *testRegistry) status(t *testing.T, id string) string {
+	t.Helper()
+	reg, err := r.store.GetService(context.Background(), id)
+	if err != nil {
+		t.Fatalf("GetService: %v", err)
+	}
+	return reg.Status
+}
+
+func TestServiceTakedownAndReinstate(t *testing.T) {
+	r := newTestRegistry(t)
+	provider := r.provider(t, "acme")
+	id := r.service(t, provider, "Summarizer")
+	other := r.service(t, provider, "Translator")
+	r.do(t, http.MethodPatch, "/api/v1/services/"+id+"/status", provider, map[string]string{"status": "deprecated"})
+	before := len(r.store.events())
+
+	w, body := r.adminDo(t, http.MethodPost, "/api/v1/admin/services/"+id+"/suspend", map[string]string{"reason": "Phishing endpoint"})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("suspend = %d %s", w.Code, w.Body)
+	}
+	if body["target_type"] != "service" || body["created_by"] != "mod-alice" || body["policy_id"] == "" {
+		t.Errorf("takedown = %v", body)
+	}
+	if r.status(t, id) != marketplace.StatusSuspended || !r.blocker.blocked(id) {
+		t.Errorf("service is %s, blocked %v; want suspended and blocked", r.status(t, id), r.blocker.blocked(id))
+	}
+	if r.status(t, other) != marketplace.StatusActive || r.blocker.blocked(other) {
+		t.Error("the provider's other service was taken down too")
+	}
+	events := r.store.events()
+	if len(events) != before+1 || events[before].Type != marketplace.ServiceUpdated || events[before].Status != marketplace.StatusSuspended {
+		t.Errorf("events = %+v, want the suspension published", events[before:])
+	}
+
+	// Taking it down twice, or lifting the suspension around the takedown,
+	// conflicts; the provider can't change it at all
+	if w, _ := r.adminDo(t, http.MethodPost, "/api/v1/admin/services/"+id+"/suspend", map[string]string{"reason": "again"}); w.Code != http.StatusConflict {
+		t.Errorf("second suspend = %d, want 409", w.Code)
+	}
+	if w, _ := r.do(t, http.MethodPatch, "/api/v1/services/"+id+"/status", "", map[string]string{"status": "active"}); w.Code != http.StatusConflict {
+		t.Errorf("operator PATCH active = %d, want 409", w.Code)
+	}
+	if w, _ := r.do(t, http.MethodPatch, "/api/v1/services/"+id+"/status", provider, map[string]string{"status": "active"}); w.Code != http.StatusForbidden {
+		t.Errorf("provider PATCH active = %d, want 403", w.Code)
+	}
+
+	w, body = r.adminDo(t, http.MethodPost, "/api/v1/admin/services/"+id+"/reinstate", map[string]string{"reason": "Endpoint fixed"})
+	if w.Code != http.StatusOK || body["lifted_by"] != "mod-alice" {
+		t.Fatalf("reinstate = %d %s", w.Code, w.Body)
+	}
+	if r.status(t, id) != marketplace.StatusDeprecated || r.blocker.blocked(id) {
+		t.Errorf("service is %s, blocked %v; want deprecated again and unblocked", r.status(t, id), r.blocker.blocked(id))
+	}
+	if w, _ := r.adminDo(t, http.MethodPost, "/api/v1/admin/services/"+id+"/reinstate", map[string]string{"reason": "again"}); w.Code != http.StatusConflict {
+		t.Errorf("second reinstate = %d, want 409", w.Code)
+	}
+
+	w, body = r.adminDo(t, http.MethodGet, "/api/v1/admin/actions?target_id="+id, nil)
+	actions, _ := body["actions"].([]interface{})
+	if w.Code != http.StatusOK || body["total"] != 2.0 || len(actions) != 2 {
+		t.Fatalf("actions = %d %s", w.Code, w.Body)
+	}
+	latest := actions[0].(map[string]interface{})
+	if latest["action"] != "reinstate" || latest["actor"] != "mod-alice" || latest["reason"] != "Endpoint fixed" {
+		t.Errorf("latest action = %v", latest)
+	}
+}
+
+func TestProviderTakedown(t *testing.T) {
+	r := newTestRegistry(t)
+	provider := r.provider(t, "acme")
+	first := r.service(t, provider, "Summarizer")
+	second := r.service(t, provider, "Translator")
+	retired := r.service(t, provider, "Legacy")
+	r.do(t, http.MethodDelete, "/api/v1/services/"+retired, provider, nil)
+
+	w, body := r.adminDo(t, http.MethodPost, "/api/v1/admin/providers/"+provider+"/suspend", map[string]string{"reason": "Fraudulent billing"})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("suspend = %d %s", w.Code, w.Body)
+	}
+	if services, _ := body["services"].([]interface{}); len(services) != 2 {
+		t.Errorf("takedown suspended %v, want the two live services", body["services"])
+	}
+	for _, id := range []string{first, second} {
+		if r.status(t, id) != marketplace.StatusSuspended || !r.blocker.blocked(id) {
+			t.Errorf("service %s is %s, want suspended and blocked", id, r.status(t, id))
+		}
+	}
+	if r.status(t, retired) != marketplace.StatusRetired || r.blocker.blocked(retired) {
+		t.Error("the retired service was taken down")
+	}
+	if _, body := r.do(t, http.MethodGet, "/api/v1/providers/"+provider, "", nil); body["suspended"] != true {
+		t.Errorf("provider = %v, want suspended", body)
+	}
+
+	// A suspended provider can't register or change services, and its
+	// services can't be reinstated on their own
+	if w, _ := r.do(t, http.MethodPost, "/api/v1/services", provider, descriptor("Classifier")); w.Code != http.StatusForbidden {
+		t.Errorf("register = %d, want 403", w.Code)
+	}
+	if w, _ := r.do(t, http.MethodPut, "/api/v1/services/"+first, provider, descriptor("Summarizer")); w.Code != http.StatusForbidden {
+		t.Errorf("update = %d, want 403", w.Code)
+	}
+	if w, _ := r.adminDo(t, http.MethodPost, "/api/v1/admin/services/"+first+"/reinstate", map[string]string{"reason": "ok"}); w.Code != http.StatusConflict {
+		t.Errorf("service reinstate = %d, want 409", w.Code)
+	}
+
+	if w, _ := r.adminDo(t, http.MethodPost, "/api/v1/admin/providers/"+provider+"/reinstate", map[string]string{"reason": "Billing cleared"}); w.Code != http.StatusOK {
+		t.Fatalf("reinstate = %d %s", w.Code, w.Body)
+	}
+	for _, id := range []string{first, second} {
+		if r.status(t, id) != marketplace.StatusActive || r.blocker.blocked(id) {
+			t.Errorf("service %s is %s, want active and unblocked", id, r.status(t, id))
+		}
+	}
+	if w, _ := r.do(t, http.MethodPost, "/api/v1/services", provider, descriptor("Classifier")); w.Code != http.StatusCreated {
+		t.Errorf("register after reinstatement = %d %s", w.Code, w.Body)
+	}
+}
+
+func TestAdminRoutesAreOperatorOnly(t *testing.T) {
+	r := newTestRegistry(t)
+	provider := r.provider(t, "acme")
+	id := r.service(t, provider, "Summarizer")
+
+	path := "/api/v1/admin/services/" + id + "/suspend"
+	if w, _ := r.do(t, http.MethodPost, path, provider, map[string]string{"reason": "competitor"}); w.Code != http.StatusForbidden {
+		t.Errorf("provider suspend = %d, want 403", w.Code)
+	}
+	if w, _ := r.consumerDo(t, http.MethodGet, "/api/v1/admin/reports", "globex", nil); w.Code != http.StatusForbidden {
+		t.Errorf("consumer list reports = %d, want 403", w.Code)
+	}
+	if r.status(t, id) != marketplace.StatusActive {
+		t.Error("a provider took a service down")
+	}
+}
+
+func TestTakedownFailsClosed(t *testing.T) {
+	r := newTestRegistry(t)
+	provider := r.provider(t, "acme")
+	id := r.service(t, provider, "Summarizer")
+	path := "/api/v1/admin/services/" + id + "/suspend"
+
+	if w, body := r.adminDo(t, http.MethodPost, path, map[string]string{}); w.Code != http.StatusBadRequest {
+		t.Errorf("suspend without reason = %d %v, want 400", w.Code, body)
+	}
+
+	r.blocker.err = registry.ErrPolicyUnavailable
+	if w, _ := r.adminDo(t, http.MethodPost, path, map[string]string{"reason": "Phishing"}); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("suspend with the policy engine down = %d, want 503", w.Code)
+	}
+	if r.status(t, id) != marketplace.StatusActive || len(r.store.actions) != 0 {
+		t.Error("a takedown was recorded without blocking the service")
+	}
+
+	r.blocker.err = nil
+	r.adminDo(t, http.MethodPost, path, map[string]string{"reason": "Phishing"})
+	r.blocker.err = registry.ErrPolicyUnavailable
+	if w, _ := r.adminDo(t, http.MethodPost, "/api/v1/admin/services/"+id+"/reinstate", map[string]string{"reason": "ok"}); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("reinstate with the policy engine down = %d, want 503", w.Code)
+	}
+	if r.status(t, id) != marketplace.StatusSuspended {
+		t.Error("a service was reinstated while still blocked")
+	}
+}
+
+func TestResolveReports(t *testing.T) {
+	r := newTestRegistry(t)
+	provider := r.provider(t, "acme")
+	id := r.service(t, provider, "Summarizer")
+	spam := &registry.Report{ID: uuid.NewString(), ServiceID: id, ProviderID: provider, ReporterID: "globex", Category: "malicious", CreatedAt: time.Now().UTC()}
+	dup := &registry.Report{ID: uuid.NewString(), ServiceID: id, ProviderID: provider, ReporterID: "initech", Category: "miscategorized", CreatedAt: time.Now().UTC()}
+	r.store.addReport(spam)
+	r.store.addReport(dup)
+
+	w, body := r.adminDo(t, http.MethodGet, "/api/v1/admin/reports?status=open", nil)
+	if w.Code != http.StatusOK || body["total"] != 2.0 {
+		t.Fatalf("open reports = %d %s", w.Code, w.Body)
+	}
+	if w, _ := r.adminDo(t, http.MethodGet, "/api/v1/admin/reports?status=closed", nil); w.Code != http.StatusBadRequest {
+		t.Errorf("unknown status = %d, want 400", w.Code)
+	}
+
+	// Acting on a report takes the service down and closes the report
+	w, body = r.adminDo(t, http.MethodPost, "/api/v1/admin/services/"+id+"/suspend", map[string]string{"reason": "Malware in responses", "report_id": spam.ID})
+	if w.Code != http.StatusCreated || body["report_id"] != spam.ID {
+		t.Fatalf("suspend = %d %s", w.Code, w.Body)
+	}
+	_, body = r.adminDo(t, http.MethodGet, "/api/v1/admin/reports/"+spam.ID, nil)
+	if body["status"] != "actioned" || body["resolved_by"] != "mod-alice" {
+		t.Errorf("report = %v, want actioned", body)
+	}
+
+	w, body = r.adminDo(t, http.MethodPost, "/api/v1/admin/reports/"+dup.ID+"/resolve", map[string]string{"resolution": "dismissed", "note": "Category is accurate"})
+	if w.Code != http.StatusOK || body["status"] != "dismissed" || body["resolution"] != "Category is accurate" {
+		t.Errorf("resolve = %d %s", w.Code, w.Body)
+	}
+	if w, _ := r.adminDo(t, http.MethodPost, "/api/v1/admin/reports/"+dup.ID+"/resolve", map[string]string{"resolution": "actioned"}); w.Code != http.StatusConflict {
+		t.Errorf("second resolve = %d, want 409", w.Code)
+	}
+	if w, _ := r.adminDo(t, http.MethodPost, "/api/v1/admin/reports/"+uuid.NewString()+"/resolve", map[string]string{"resolution": "dismissed"}); w.Code != http.StatusNotFound {
+		t.Errorf("resolve unknown report = %d, want 404", w.Code)
+	}
+
+	_, body = r.adminDo(t, http.MethodGet, "/api/v1/admin/reports?status=open", nil)
+	if body["total"] != 0.0 {
+		t.Errorf("open reports = %v, want none", body)
+	}
+	_, body = r.adminDo(t, http.MethodGet, "/api/v1/admin/actions?target_type=report", nil)
+	if body["total"] != 1.0 {
+		t.Errorf("report actions = %v, want the dismissal", body)
+	}
+}
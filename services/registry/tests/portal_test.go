@@ -0,0 +1,239 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/org/llm-marketplace/pkg/marketplace"
+)
+
+// portal calls a provider portal endpoint with an API key
+func (r *testRegistry) portal(t *testing.T, method, path, key string, body interface{}) (*httptest.ResponseRecorder, map[string]interface{}) {
+	t.Helper()
+	var reader bytes.Buffer
+	if body != nil {
+		json.NewEncoder(&reader).Encode(body)
+	}
+	req := httptest.NewRequest(method, "/api/v1/portal"+path, &reader)
+	req.Header.Set("Content-Type", "application/json")
+	if key != "" {
+		req.Header.Set("Authorization", "Bearer "+key)
+	}
+	w := httptest.NewRecorder()
+	r.router.ServeHTTP(w, req)
+
+	var decoded map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &decoded)
+	return w, decoded
+}
+
+// providerKey registers a provider and returns its ID and first API key
+func (r *testRegistry) providerKey(t *testing.T, name string) (string, string) {
+	t.Helper()
+	w, body := r.do(t, http.MethodPost, "/api/v1/providers", "", map[string]string{"name": name, "contact_email": "ops@" + name + ".example"})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("register provider = %d %s", w.Code, w.Body)
+	}
+	key, _ := body["api_key"].(string)
+	if !strings.HasPrefix(key, "mk_") {
+		t.Fatalf("api_key = %q, want a new key", key)
+	}
+	return body["id"].(string), key
+}
+
+func TestPortalRequiresAPIKey(t *testing.T) {
+	r := newTestRegistry(t)
+	r.providerKey(t, "acme")
+
+	for _, key := range []string{"", "mk_unknown", "not-a-key"} {
+		w, body := r.portal(t, http.MethodGet, "/services", key, nil)
+		if w.Code != http.StatusUnauthorized || body["code"] != "unauthorized" {
+			t.Errorf("key %q: status = %d %s, want 401", key, w.Code, w.Body)
+		}
+		if !strings.HasPrefix(w.Header().Get("WWW-Authenticate"), "Bearer") {
+			t.Errorf("key %q: WWW-Authenticate = %q", key, w.Header().Get("WWW-Authenticate"))
+		}
+	}
+}
+
+func TestPortalManagesOwnServices(t *testing.T) {
+	r := newTestRegistry(t)
+	provider, key := r.providerKey(t, "acme")
+	_, otherKey := r.providerKey(t, "globex")
+
+	w, body := r.portal(t, http.MethodGet, "/provider", key, nil)
+	if w.Code != http.StatusOK || body["id"] != provider || body["api_key"] != nil {
+		t.Fatalf("provider = %d %s, want acme without its key", w.Code, w.Body)
+	}
+
+	desc := descriptor("Summarizer")
+	desc.ProviderID = "" // Taken from the key
+	w, body = r.portal(t, http.MethodPost, "/services", key, desc)
+	if w.Code != http.StatusCreated || body["provider_id"] != provider {
+		t.Fatalf("register = %d %s", w.Code, w.Body)
+	}
+	id := body["id"].(string)
+
+	// Another provider can neither see nor change it
+	if w, _ := r.portal(t, http.MethodGet, "/services/"+id, otherKey, nil); w.Code != http.StatusNotFound {
+		t.Errorf("get by another provider = %d, want 404", w.Code)
+	}
+	if w, _ := r.portal(t, http.MethodPut, "/services/"+id+"/pricing", otherKey, marketplace.PricingInfo{Model: "free"}); w.Code != http.StatusForbidden {
+		t.Errorf("pricing by another provider = %d, want 403", w.Code)
+	}
+	if _, body := r.portal(t, http.MethodGet, "/services", otherKey, nil); body["total"] != 0.0 {
+		t.Errorf("other provider lists %v services, want 0", body["total"])
+	}
+
+	pricing := marketplace.PricingInfo{Model: "tiered", Unit: "1k tokens", Tiers: []marketplace.PricingTier{
+		{Tier: "starter", Rate: 0.002, Unit: "1k tokens"},
+		{Tier: "scale", Rate: 0.001, Unit: "1k tokens"},
+	}}
+	w, body = r.portal(t, http.MethodPut, "/services/"+id+"/pricing", key, pricing)
+	if w.Code != http.StatusOK || body["revision"] != 2.0 {
+		t.Fatalf("set pricing = %d %s", w.Code, w.Body)
+	}
+	if tiers := body["service"].(map[string]interface{})["pricing"].(map[string]interface{})["tiers"].([]interface{}); len(tiers) != 2 {
+		t.Errorf("tiers = %v, want 2", tiers)
+	}
+	pricing.Tiers[0].Rate = -1
+	if w, _ := r.portal(t, http.MethodPut, "/services/"+id+"/pricing", key, pricing); w.Code != http.StatusBadRequest {
+		t.Errorf("negative tier rate = %d, want 400", w.Code)
+	}
+
+	// Suspension is for operators
+	if w, _ := r.portal(t, http.MethodPatch, "/services/"+id+"/status", key, map[string]string{"status": "suspended"}); w.Code != http.StatusForbidden {
+		t.Errorf("self-suspend = %d, want 403", w.Code)
+	}
+	if w, _ := r.do(t, http.MethodPatch, "/api/v1/services/"+id+"/status", "", map[string]string{"status": "suspended"}); w.Code != http.StatusOK {
+		t.Fatalf("operator suspend = %d", w.Code)
+	}
+	if w, _ := r.portal(t, http.MethodPatch, "/services/"+id+"/status", key, map[string]string{"status": "active"}); w.Code != http.StatusForbidden {
+		t.Errorf("lifting a suspension = %d, want 403", w.Code)
+	}
+
+	w, body = r.portal(t, http.MethodDelete, "/services/"+id, key, nil)
+	if w.Code != http.StatusOK || body["status"] != "retired" {
+		t.Fatalf("unpublish = %d %s", w.Code, w.Body)
+	}
+	if events := r.store.events(); events[len(events)-1].Type != marketplace.ServiceDeregistered {
+		t.Errorf("last event = %s, want %s", events[len(events)-1].Type, marketplace.ServiceDeregistered)
+	}
+}
+
+func TestPortalRecordsValidations(t *testing.T) {
+	r := newTestRegistry(t)
+	_, key := r.providerKey(t, "acme")
+
+	rejected := descriptor("Summarizer")
+	rejected.Compliance.Level = "restricted"
+	if w, _ := r.portal(t, http.MethodPost, "/services", key, rejected); w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("register = %d, want 422", w.Code)
+	}
+
+	// A dry run reports the verdict without storing the service
+	w, body := r.portal(t, http.MethodPost, "/validate", key, rejected)
+	if w.Code != http.StatusOK || body["valid"] != false || len(body["violations"].([]interface{})) != 1 {
+		t.Fatalf("validate = %d %s", w.Code, w.Body)
+	}
+	if len(r.store.events()) != 0 {
+		t.Error("a dry run stored a service")
+	}
+
+	_, body = r.portal(t, http.MethodPost, "/services", key, descriptor("Summarizer"))
+	id := body["id"].(string)
+	invalid := descriptor("Summarizer")
+	invalid.SLA.Availability = 120
+	if w, _ := r.portal(t, http.MethodPut, "/services/"+id, key, invalid); w.Code != http.StatusBadRequest {
+		t.Fatalf("update = %d, want 400", w.Code)
+	}
+
+	w, body = r.portal(t, http.MethodGet, "/validations", key, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("validations = %d %s", w.Code, w.Body)
+	}
+	var outcomes []string
+	for _, v := range body["validations"].([]interface{}) {
+		v := v.(map[string]interface{})
+		outcome := fmt.Sprint(v["valid"])
+		if v["service_id"] == id {
+			outcome += "@service"
+		}
+		outcomes = append(outcomes, outcome)
+	}
+	if want := "[false@service true@service false false]"; fmt.Sprint(outcomes) != want {
+		t.Errorf("validations = %v, want %s (newest first)", outcomes, want)
+	}
+
+	_, body = r.portal(t, http.MethodGet, "/services/"+id+"/validations", key, nil)
+	validations := body["validations"].([]interface{})
+	if len(validations) != 2 {
+		t.Fatalf("service validations = %v, want 2", validations)
+	}
+	if errs := validations[0].(map[string]interface{})["errors"].([]interface{}); errs[0].(map[string]interface{})["field"] != "sla.availability" {
+		t.Errorf("errors = %v, want sla.availability", errs)
+	}
+}
+
+func TestPortalKeyRotation(t *testing.T) {
+	r := newTestRegistry(t)
+	provider, oldKey := r.providerKey(t, "acme")
+
+	w, body := r.portal(t, http.MethodPost, "/credentials/rotate", oldKey, nil)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("rotate = %d %s", w.Code, w.Body)
+	}
+	newKey := body["key"].(string)
+	newID := body["id"].(string)
+
+	// Both keys work during the grace period
+	for _, key := range []string{oldKey, newKey} {
+		if w, _ := r.portal(t, http.MethodGet, "/services", key, nil); w.Code != http.StatusOK {
+			t.Errorf("during grace period = %d, want 200", w.Code)
+		}
+	}
+	w, body = r.portal(t, http.MethodGet, "/credentials", newKey, nil)
+	creds := body["credentials"].([]interface{})
+	if w.Code != http.StatusOK || len(creds) != 2 || creds[1].(map[string]interface{})["expires_at"] == nil {
+		t.Fatalf("credentials = %d %s, want the old key expiring", w.Code, w.Body)
+	}
+	if strings.Contains(w.Body.String(), newKey) {
+		t.Error("listing credentials returned a key")
+	}
+
+	// Without a grace period the old keys stop working at once
+	r.svc.SetKeyGracePeriod(0)
+	_, body = r.portal(t, http.MethodPost, "/credentials/rotate", newKey, nil)
+	latest := body["key"].(string)
+	if w, _ := r.portal(t, http.MethodGet, "/services", newKey, nil); w.Code != http.StatusUnauthorized {
+		t.Errorf("rotated-out key = %d, want 401", w.Code)
+	}
+
+	// Revoking is immediate; operators can issue a key to recover
+	if w, _ := r.portal(t, http.MethodDelete, "/credentials/"+newID, latest, nil); w.Code != http.StatusNoContent {
+		t.Errorf("revoke = %d, want 204", w.Code)
+	}
+	if w, _ := r.portal(t, http.MethodDelete, "/credentials/"+body["id"].(string), latest, nil); w.Code != http.StatusNoContent {
+		t.Errorf("revoke own key = %d, want 204", w.Code)
+	}
+	if w, _ := r.portal(t, http.MethodGet, "/services", latest, nil); w.Code != http.StatusUnauthorized {
+		t.Errorf("revoked key = %d, want 401", w.Code)
+	}
+	// Another provider can't mint a key for this one
+	other, _ := r.providerKey(t, "globex")
+	if w, _ := r.do(t, http.MethodPost, "/api/v1/providers/"+provider+"/credentials", other, nil); w.Code != http.StatusForbidden {
+		t.Errorf("provider issuing another's key = %d, want 403", w.Code)
+	}
+	w, body = r.do(t, http.MethodPost, "/api/v1/providers/"+provider+"/credentials", "", nil)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("issue = %d %s", w.Code, w.Body)
+	}
+	if w, _ := r.portal(t, http.MethodGet, "/services", body["key"].(string), nil); w.Code != http.StatusOK {
+		t.Errorf("issued key = %d, want 200", w.Code)
+	}
+}
@@ -0,0 +1,91 @@
+package tests
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/org/llm-marketplace/pkg/marketplace"
+)
+
+// tenantProvider registers an enterprise's provider for its own models
+func (r *testRegistry) tenantProvider(t *testing.T, name, tenant string) string {
+	t.Helper()
+	w, body := r.consumerDo(t, http.MethodPost, "/api/v1/providers", tenant, map[string]string{"name": name, "contact_email": "ml@" + name + ".example", "tenant_id": tenant})
+	if w.Code != http.StatusCreated || body["tenant_id"] != tenant {
+		t.Fatalf("register tenant provider = %d %s", w.Code, w.Body)
+	}
+	return body["id"].(string)
+}
+
+func TestTenantProviderIsRegisteredByItsTenant(t *testing.T) {
+	r := newTestRegistry(t)
+
+	body := map[string]string{"name": "acme-ml", "contact_email": "ml@acme.example", "tenant_id": "acme"}
+	if w, _ := r.do(t, http.MethodPost, "/api/v1/providers", "", body); w.Code != http.StatusForbidden {
+		t.Errorf("unauthenticated tenant provider = %d, want 403", w.Code)
+	}
+	if w, _ := r.consumerDo(t, http.MethodPost, "/api/v1/providers", "globex", body); w.Code != http.StatusForbidden {
+		t.Errorf("another tenant's provider = %d, want 403", w.Code)
+	}
+	r.tenantProvider(t, "acme-ml", "acme")
+}
+
+func TestRegisterPrivateService(t *testing.T) {
+	r := newTestRegistry(t)
+	provider := r.tenantProvider(t, "acme-ml", "acme")
+
+	w, body := r.do(t, http.MethodPost, "/api/v1/services", provider, descriptor("Internal LLM"))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("register = %d %s", w.Code, w.Body)
+	}
+	id := body["id"].(string)
+	if service := body["service"].(map[string]interface{}); service["class"] != "private" || service["tenant_id"] != "acme" {
+		t.Errorf("service = %v, want private to acme", service)
+	}
+	if checked := r.policy.checked[0]; !checked.Private() || checked.TenantID != "acme" {
+		t.Errorf("policy engine checked class %q tenant %q", checked.Class, checked.TenantID)
+	}
+	if e := r.store.events()[0]; !e.Service.Private() || e.Service.TenantID != "acme" {
+		t.Errorf("event service = %+v, want private to acme", e.Service)
+	}
+
+	// A tenant's provider can't list publicly or in another tenant
+	public := descriptor("Public LLM")
+	public.Class = marketplace.ClassPublic
+	if w, _ := r.do(t, http.MethodPost, "/api/v1/services", provider, public); w.Code != http.StatusBadRequest {
+		t.Errorf("public service of a tenant provider = %d, want 400", w.Code)
+	}
+	moved := descriptor("Internal LLM")
+	moved.Class, moved.TenantID = marketplace.ClassPrivate, "globex"
+	if w, _ := r.do(t, http.MethodPut, "/api/v1/services/"+id, provider, moved); w.Code != http.StatusBadRequest {
+		t.Errorf("moving the service to another tenant = %d, want 400", w.Code)
+	}
+
+	// Nor can other providers register private services
+	other := descriptor("Hosted LLM")
+	other.Class, other.TenantID = marketplace.ClassPrivate, "acme"
+	if w, _ := r.do(t, http.MethodPost, "/api/v1/services", r.provider(t, "globex"), other); w.Code != http.StatusBadRequest {
+		t.Errorf("private service of a public provider = %d, want 400", w.Code)
+	}
+}
+
+func TestPrivateServiceHiddenFromOtherTenants(t *testing.T) {
+	r := newTestRegistry(t)
+	provider := r.tenantProvider(t, "acme-ml", "acme")
+	id := r.service(t, provider, "Internal LLM")
+
+	if w, _ := r.consumerDo(t, http.MethodPost, "/api/v1/subscriptions", "globex", map[string]interface{}{"service_id": id}); w.Code != http.StatusBadRequest {
+		t.Errorf("another tenant's subscription = %d, want 400", w.Code)
+	}
+	if w, _ := r.consumerDo(t, http.MethodPost, "/api/v1/subscriptions", "acme", map[string]interface{}{"service_id": id}); w.Code != http.StatusCreated {
+		t.Errorf("the tenant's subscription = %d %s", w.Code, w.Body)
+	}
+
+	path := "/api/v1/services/" + id + "/report"
+	if w, _ := r.consumerDo(t, http.MethodPost, path, "globex", map[string]string{"category": "malicious"}); w.Code != http.StatusNotFound {
+		t.Errorf("another tenant's report = %d, want 404", w.Code)
+	}
+	if w, _ := r.consumerDo(t, http.MethodPost, path, "acme", map[string]string{"category": "miscategorized"}); w.Code != http.StatusCreated {
+		t.Errorf("the tenant's report = %d %s", w.Code, w.Body)
+	}
+}
@@ -0,0 +1,88 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/org/llm-marketplace/pkg/marketplace"
+	"github.com/segmentio/kafka-go"
+	"go.uber.org/zap"
+
+	"github.com/org/llm-marketplace/services/registry/internal/config"
+	"github.com/org/llm-marketplace/services/registry/internal/publisher"
+	"github.com/org/llm-marketplace/services/registry/internal/registry"
+)
+
+type fakeWriter struct {
+	err      error
+	messages []kafka.Message
+}
+
+func (w *fakeWriter) WriteMessages(_ context.Context, msgs ...kafka.Message) error {
+	if w.err != nil {
+		return w.err
+	}
+	w.messages = append(w.messages, msgs...)
+	return nil
+}
+
+func TestRelayPublishesOutboxInOrder(t *testing.T) {
+	store := newMemStore()
+	svc := registry.NewService(store, &fakePolicy{}, zap.NewNop())
+	provider, err := svc.RegisterProvider(context.Background(), "acme", "ops@acme.example")
+	if err != nil {
+		t.Fatal(err)
+	}
+	desc := descriptor("Summarizer")
+	desc.ProviderID = provider.ID
+	reg, err := svc.Register(context.Background(), "", desc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.Deregister(context.Background(), "", reg.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	writer := &fakeWriter{err: errors.New("broker down")}
+	topics := config.KafkaConfig{Topic: "catalog", ValidationTopic: "validations"}
+	relay := publisher.NewRelay(store, writer, topics, config.OutboxConfig{BatchSize: 1, PollInterval: time.Millisecond}, zap.NewNop())
+
+	if _, err := relay.PublishOnce(context.Background()); err == nil {
+		t.Fatal("PublishOnce succeeded with Kafka down")
+	}
+	if len(store.events()) != 2 {
+		t.Fatal("a failed batch left the outbox")
+	}
+
+	writer.err = nil
+	for i := 0; i < 3; i++ {
+		if _, err := relay.PublishOnce(context.Background()); err != nil {
+			t.Fatalf("PublishOnce: %v", err)
+		}
+	}
+	if len(writer.messages) != 3 || len(store.events()) != 0 {
+		t.Fatalf("published %d messages with %d left, want 3 and none", len(writer.messages), len(store.events()))
+	}
+	// The registration's validation is published between its catalog events
+	for i, want := range []struct{ topic, key, eventType string }{
+		{"catalog", reg.ID, marketplace.ServiceRegistered},
+		{"validations", provider.ID, publisher.ValidationStream},
+		{"catalog", reg.ID, marketplace.ServiceDeregistered},
+	} {
+		m := writer.messages[i]
+		if m.Topic != want.topic || string(m.Key) != want.key || len(m.Headers) != 1 || string(m.Headers[0].Value) != want.eventType {
+			t.Errorf("message %d = %s key %s headers %v, want %s key %s and %s", i, m.Topic, m.Key, m.Headers, want.topic, want.key, want.eventType)
+		}
+	}
+
+	var validation marketplace.ValidationEvent
+	if err := json.Unmarshal(writer.messages[1].Value, &validation); err != nil {
+		t.Fatal(err)
+	}
+	if !validation.Valid || validation.ServiceID != reg.ID || validation.Name != "Summarizer" {
+		t.Errorf("validation event = %+v, want a valid check of %s", validation, reg.ID)
+	}
+}
@@ -0,0 +1,37 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/org/llm-marketplace/pkg/marketplace"
+)
+
+func TestRegisterServiceWithServingRegions(t *testing.T) {
+	r := newTestRegistry(t)
+	provider := r.provider(t, "acme")
+
+	desc := descriptor("Chat")
+	desc.ServingRegions = []marketplace.ServingRegion{{Region: "us-east-1", MedianLatencyMS: 40}, {Region: "eu-west-1", MedianLatencyMS: 65}}
+	w, body := r.do(t, http.MethodPost, "/api/v1/services", provider, desc)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("register = %d %s", w.Code, w.Body)
+	}
+	id := body["id"].(string)
+	if e := r.store.events()[0]; len(e.Service.ServingRegions) != 2 {
+		t.Errorf("event service = %+v, want its serving regions", e.Service)
+	}
+	if ms, ok := r.policy.checked[0].RegionLatency("eu-west-1"); !ok || ms != 65 {
+		t.Errorf("policy engine checked eu-west-1 at %d ms, %v; want 65", ms, ok)
+	}
+
+	desc.ServingRegions = append(desc.ServingRegions, marketplace.ServingRegion{Region: "us-east-1"})
+	if w, body := r.do(t, http.MethodPut, "/api/v1/services/"+id, provider, desc); w.Code != http.StatusBadRequest {
+		t.Errorf("region listed twice = %d %v, want 400", w.Code, body)
+	}
+	reg, _ := r.store.GetService(context.Background(), id)
+	if len(reg.Service.ServingRegions) != 2 || reg.Revision != 1 {
+		t.Errorf("stored %+v at revision %d, want the regions unchanged", reg.Service.ServingRegions, reg.Revision)
+	}
+}
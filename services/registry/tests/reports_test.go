@@ -0,0 +1,133 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/org/llm-marketplace/pkg/marketplace"
+
+	"github.com/org/llm-marketplace/services/registry/internal/registry"
+)
+
+func (r *testRegistry) upheld(t *testing.T, id string) int {
+	t.Helper()
+	reg, err := r.store.GetService(context.Background(), id)
+	if err != nil {
+		t.Fatalf("GetService: %v", err)
+	}
+	return reg.UpheldReports
+}
+
+func TestReportService(t *testing.T) {
+	r := newTestRegistry(t)
+	provider := r.provider(t, "acme")
+	id := r.service(t, provider, "Summarizer")
+	path := "/api/v1/services/" + id + "/report"
+	before := len(r.store.events())
+
+	w, body := r.consumerDo(t, http.MethodPost, path, "globex", map[string]string{"category": "miscategorized", "details": "It translates, it doesn't summarize"})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("report = %d %s", w.Code, w.Body)
+	}
+	revalidation, _ := body["revalidation"].(map[string]interface{})
+	if body["status"] != "open" || body["reporter_id"] != "globex" || body["upheld"] != false || revalidation["compliant"] != true {
+		t.Errorf("report = %v, want open, re-validated and not upheld", body)
+	}
+	if len(r.policy.checked) != 2 || r.policy.checked[1].ServiceID != id {
+		t.Errorf("policy engine checked %d descriptors, want the listing re-validated", len(r.policy.checked))
+	}
+	if len(r.store.events()) != before || r.upheld(t, id) != 0 {
+		t.Error("a compliant listing was counted at fault")
+	}
+
+	// One open report per consumer and listing
+	if w, _ := r.consumerDo(t, http.MethodPost, path, "globex", map[string]string{"category": "malicious"}); w.Code != http.StatusConflict {
+		t.Errorf("second report = %d, want 409", w.Code)
+	}
+	if w, _ := r.consumerDo(t, http.MethodPost, path, "initech", map[string]string{"category": "malicious"}); w.Code != http.StatusCreated {
+		t.Errorf("another consumer's report = %d %s", w.Code, w.Body)
+	}
+	_, body = r.adminDo(t, http.MethodGet, "/api/v1/admin/reports?service_id="+id, nil)
+	if body["total"] != 2.0 {
+		t.Errorf("queue = %v, want both reports", body)
+	}
+
+	if w, _ := r.consumerDo(t, http.MethodPost, path, "hooli", map[string]string{"category": "boring"}); w.Code != http.StatusBadRequest {
+		t.Errorf("unknown category = %d, want 400", w.Code)
+	}
+	if w, _ := r.do(t, http.MethodPost, path, provider, map[string]string{"category": "malicious"}); w.Code != http.StatusForbidden {
+		t.Errorf("provider report = %d, want 403", w.Code)
+	}
+	r.do(t, http.MethodDelete, "/api/v1/services/"+id, provider, nil)
+	if w, _ := r.consumerDo(t, http.MethodPost, path, "hooli", map[string]string{"category": "malicious"}); w.Code != http.StatusConflict {
+		t.Errorf("report of a retired service = %d, want 409", w.Code)
+	}
+}
+
+func TestReportRevalidationUpholds(t *testing.T) {
+	r := newTestRegistry(t)
+	provider := r.provider(t, "acme")
+	id := r.service(t, provider, "Summarizer")
+	path := "/api/v1/services/" + id + "/report"
+
+	// The policies changed since the listing was registered
+	r.policy.reject["internal"] = true
+	before := len(r.store.events())
+	w, body := r.consumerDo(t, http.MethodPost, path, "globex", map[string]string{"category": "policy-violating"})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("report = %d %s", w.Code, w.Body)
+	}
+	revalidation, _ := body["revalidation"].(map[string]interface{})
+	if body["upheld"] != true || revalidation["compliant"] != false || revalidation["violations"] == nil {
+		t.Errorf("report = %v, want upheld with the violations", body)
+	}
+	if r.upheld(t, id) != 1 {
+		t.Errorf("upheld reports = %d, want 1", r.upheld(t, id))
+	}
+	events := r.store.events()
+	if len(events) != before+1 || events[before].UpheldReports != 1 || events[before].Status != marketplace.StatusActive {
+		t.Errorf("events = %+v, want the count published", events[before:])
+	}
+	if len(r.store.validations) != 2 || r.store.validations[1].Valid {
+		t.Error("the failed re-validation wasn't recorded")
+	}
+	reportID := body["id"].(string)
+
+	// The operator's verdict overrides the policy engine's
+	if w, _ := r.adminDo(t, http.MethodPost, "/api/v1/admin/reports/"+reportID+"/resolve", map[string]string{"resolution": "dismissed"}); w.Code != http.StatusOK {
+		t.Fatalf("dismiss = %d %s", w.Code, w.Body)
+	}
+	if r.upheld(t, id) != 0 {
+		t.Errorf("upheld reports after dismissal = %d, want 0", r.upheld(t, id))
+	}
+
+	// With the policy engine down the report is filed unchecked
+	r.policy.err = registry.ErrPolicyUnavailable
+	w, body = r.consumerDo(t, http.MethodPost, path, "initech", map[string]string{"category": "malicious"})
+	if w.Code != http.StatusCreated || body["revalidation"] != nil || body["upheld"] != false {
+		t.Fatalf("report = %d %s, want filed without a verdict", w.Code, w.Body)
+	}
+	r.policy.err = nil
+	r.adminDo(t, http.MethodPost, "/api/v1/admin/reports/"+body["id"].(string)+"/resolve", map[string]string{"resolution": "actioned", "note": "Malware confirmed"})
+	if r.upheld(t, id) != 1 {
+		t.Errorf("upheld reports after actioning = %d, want 1", r.upheld(t, id))
+	}
+}
+
+func TestTakedownCountsReport(t *testing.T) {
+	r := newTestRegistry(t)
+	provider := r.provider(t, "acme")
+	id := r.service(t, provider, "Summarizer")
+
+	_, body := r.consumerDo(t, http.MethodPost, "/api/v1/services/"+id+"/report", "globex", map[string]string{"category": "malicious"})
+	w, _ := r.adminDo(t, http.MethodPost, "/api/v1/admin/services/"+id+"/suspend", map[string]string{"reason": "Malware", "report_id": body["id"].(string)})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("suspend = %d %s", w.Code, w.Body)
+	}
+	events := r.store.events()
+	last := events[len(events)-1]
+	if r.upheld(t, id) != 1 || last.UpheldReports != 1 || last.Status != marketplace.StatusSuspended {
+		t.Errorf("upheld reports = %d, last event %+v; want the report counted with the suspension", r.upheld(t, id), last)
+	}
+}
@@ -0,0 +1,658 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/org/llm-marketplace/pkg/marketplace"
+
+	"github.com/org/llm-marketplace/services/registry/internal/publisher"
+	"github.com/org/llm-marketplace/services/registry/internal/registry"
+)
+
+// memStore is an in-memory registry.Store and publisher.Outbox
+type memStore struct {
+	mu          sync.Mutex
+	providers   map[string]*registry.Provider
+	services    map[string]*registry.Registration
+	credentials []*registry.Credential
+	validations []*registry.Validation
+	subs        []*registry.Subscription
+	takedowns   []*registry.Takedown
+	reports     []*registry.Report
+	actions     []*registry.ModerationAction
+	changes     map[string][]marketplace.ServiceChange // By service, oldest first
+	prices      []*registry.PriceChange
+	artifacts   []*registry.ComplianceArtifact
+	outbox      []publisher.Event
+	nextID      int64
+}
+
+func newMemStore() *memStore {
+	return &memStore{providers: map[string]*registry.Provider{}, services: map[string]*registry.Registration{}, changes: map[string][]marketplace.ServiceChange{}}
+}
+
+func (s *memStore) CreateProvider(_ context.Context, p *registry.Provider, cred *registry.Credential) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, existing := range s.providers {
+		if existing.Name == p.Name {
+			return registry.ErrConflict
+		}
+	}
+	c := *p
+	s.providers[p.ID] = &c
+	credCopy := *cred
+	s.credentials = append(s.credentials, &credCopy)
+	return nil
+}
+
+func (s *memStore) GetProvider(_ context.Context, id string) (*registry.Provider, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, ok := s.providers[id]
+	if !ok {
+		return nil, registry.ErrNotFound
+	}
+	c := *p
+	return &c, nil
+}
+
+func (s *memStore) CreateService(_ context.Context, reg *registry.Registration, event *marketplace.CatalogEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.duplicate(reg) {
+		return registry.ErrConflict
+	}
+	c := *reg
+	s.services[reg.ID] = &c
+	s.addEvent(event)
+	return nil
+}
+
+func (s *memStore) UpdateService(_ context.Context, reg *registry.Registration, event *marketplace.CatalogEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored, ok := s.services[reg.ID]
+	if !ok || stored.ProviderID != reg.ProviderID || stored.Revision != reg.Revision-1 || s.duplicate(reg) {
+		return registry.ErrConflict
+	}
+	c := *reg
+	s.services[reg.ID] = &c
+	s.addEvent(event)
+	return nil
+}
+
+func (s *memStore) duplicate(reg *registry.Registration) bool {
+	for _, existing := range s.services {
+		if existing.ID != reg.ID && existing.Service.Name == reg.Service.Name && existing.Service.Version == reg.Service.Version {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *memStore) addEvent(event *marketplace.CatalogEvent) {
+	payload, _ := json.Marshal(event)
+	s.nextID++
+	s.outbox = append(s.outbox, publisher.Event{ID: s.nextID, Stream: publisher.CatalogStream, Key: event.Service.ServiceID, Type: event.Type, Payload: payload})
+	s.changes[event.Service.ServiceID] = append(s.changes[event.Service.ServiceID], event.Changes...)
+}
+
+func (s *memStore) GetService(_ context.Context, id string) (*registry.Registration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	reg, ok := s.services[id]
+	if !ok {
+		return nil, registry.ErrNotFound
+	}
+	c := *reg
+	return &c, nil
+}
+
+func (s *memStore) GetProviderService(ctx context.Context, providerID, id string) (*registry.Registration, error) {
+	reg, err := s.GetService(ctx, id)
+	if err != nil || reg.ProviderID != providerID {
+		return nil, registry.ErrNotFound
+	}
+	return reg, nil
+}
+
+func (s *memStore) ListServices(_ context.Context, filter registry.ServiceFilter) ([]*registry.Registration, int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var regs []*registry.Registration
+	for _, reg := range s.services {
+		if (filter.ProviderID == "" || reg.ProviderID == filter.ProviderID) && (filter.Status == "" || reg.Status == filter.Status) &&
+			(filter.ContentScan == "" || (reg.ContentScan != nil && reg.ContentScan.Status == filter.ContentScan)) &&
+			(filter.Kind == "" || reg.Service.ItemKind() == filter.Kind) {
+			c := *reg
+			regs = append(regs, &c)
+		}
+	}
+	sort.Slice(regs, func(i, j int) bool { return regs[i].CreatedAt.Before(regs[j].CreatedAt) })
+	total := len(regs)
+	regs = regs[min(filter.Offset, total):min(filter.Offset+filter.Limit, total)]
+	return regs, total, nil
+}
+
+func (s *memStore) CreateCredential(_ context.Context, cred *registry.Credential, expireOthers time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, other := range s.credentials {
+		if other.ProviderID == cred.ProviderID && other.RevokedAt == nil && !expireOthers.IsZero() &&
+			(other.ExpiresAt == nil || other.ExpiresAt.After(expireOthers)) {
+			at := expireOthers
+			other.ExpiresAt = &at
+		}
+	}
+	c := *cred
+	s.credentials = append(s.credentials, &c)
+	return nil
+}
+
+func (s *memStore) CredentialByHash(_ context.Context, hash string) (*registry.Credential, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, cred := range s.credentials {
+		if cred.Hash == hash {
+			c := *cred
+			return &c, nil
+		}
+	}
+	return nil, registry.ErrNotFound
+}
+
+func (s *memStore) ListCredentials(_ context.Context, providerID string) ([]*registry.Credential, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var creds []*registry.Credential
+	for i := len(s.credentials) - 1; i >= 0; i-- {
+		if s.credentials[i].ProviderID == providerID {
+			c := *s.credentials[i]
+			creds = append(creds, &c)
+		}
+	}
+	return creds, nil
+}
+
+func (s *memStore) RevokeCredential(_ context.Context, providerID, id string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, cred := range s.credentials {
+		if cred.ID == id && cred.ProviderID == providerID {
+			if cred.RevokedAt == nil {
+				cred.RevokedAt = &at
+			}
+			return nil
+		}
+	}
+	return registry.ErrNotFound
+}
+
+func (s *memStore) CreateArtifact(_ context.Context, a *registry.ComplianceArtifact) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c := *a
+	s.artifacts = append(s.artifacts, &c)
+	return nil
+}
+
+func (s *memStore) ListArtifacts(_ context.Context, providerID string) ([]*registry.ComplianceArtifact, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var artifacts []*registry.ComplianceArtifact
+	for i := len(s.artifacts) - 1; i >= 0; i-- {
+		if s.artifacts[i].ProviderID == providerID {
+			c := *s.artifacts[i]
+			artifacts = append(artifacts, &c)
+		}
+	}
+	return artifacts, nil
+}
+
+func (s *memStore) DeleteArtifact(_ context.Context, providerID, id string) (*registry.ComplianceArtifact, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, a := range s.artifacts {
+		if a.ID == id && a.ProviderID == providerID {
+			s.artifacts = append(s.artifacts[:i], s.artifacts[i+1:]...)
+			return a, nil
+		}
+	}
+	return nil, registry.ErrNotFound
+}
+
+func (s *memStore) SaveValidation(_ context.Context, v *registry.Validation) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c := *v
+	s.validations = append(s.validations, &c)
+	payload, _ := json.Marshal(v.Event())
+	s.nextID++
+	s.outbox = append(s.outbox, publisher.Event{ID: s.nextID, Stream: publisher.ValidationStream, Key: v.ProviderID, Type: publisher.ValidationStream, Payload: payload})
+	return nil
+}
+
+func (s *memStore) ListValidations(_ context.Context, filter registry.ValidationFilter) ([]*registry.Validation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var validations []*registry.Validation
+	for i := len(s.validations) - 1; i >= 0 && len(validations) < filter.Limit; i-- {
+		v := s.validations[i]
+		if v.ProviderID == filter.ProviderID && (filter.ServiceID == "" || v.ServiceID == filter.ServiceID) {
+			c := *v
+			validations = append(validations, &c)
+		}
+	}
+	return validations, nil
+}
+
+func (s *memStore) CreateSubscription(_ context.Context, sub *registry.Subscription) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, other := range s.subs {
+		if other.ConsumerID == sub.ConsumerID && other.ServiceID == sub.ServiceID && other.CancelledAt == nil &&
+			(other.EndsAt == nil || other.EndsAt.After(sub.StartsAt)) && (sub.EndsAt == nil || other.StartsAt.Before(*sub.EndsAt)) {
+			return registry.ErrConflict
+		}
+	}
+	c := *sub
+	s.subs = append(s.subs, &c)
+	return nil
+}
+
+func (s *memStore) GetSubscription(_ context.Context, id string) (*registry.Subscription, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, sub := range s.subs {
+		if sub.ID == id {
+			c := *sub
+			return &c, nil
+		}
+	}
+	return nil, registry.ErrNotFound
+}
+
+func (s *memStore) ListSubscriptions(_ context.Context, filter registry.SubscriptionFilter) ([]*registry.Subscription, int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var matched []*registry.Subscription
+	for i := len(s.subs) - 1; i >= 0; i-- {
+		sub := s.subs[i]
+		if (filter.ConsumerID == "" || sub.ConsumerID == filter.ConsumerID) &&
+			(filter.ServiceID == "" || sub.ServiceID == filter.ServiceID) &&
+			(filter.ProviderID == "" || sub.ProviderID == filter.ProviderID) &&
+			(filter.Status == "" || sub.StatusAt(filter.At) == filter.Status) {
+			c := *sub
+			matched = append(matched, &c)
+		}
+	}
+	total := len(matched)
+	if filter.Offset >= total {
+		return nil, total, nil
+	}
+	return matched[filter.Offset:min(filter.Offset+filter.Limit, total)], total, nil
+}
+
+func (s *memStore) CancelSubscription(_ context.Context, id string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, sub := range s.subs {
+		if sub.ID == id {
+			if sub.CancelledAt != nil {
+				return registry.ErrConflict
+			}
+			sub.CancelledAt = &at
+			return nil
+		}
+	}
+	return registry.ErrNotFound
+}
+
+func (s *memStore) SaveModeration(_ context.Context, change *registry.ModerationChange) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// Check everything before changing anything, as the transaction would
+	var lifted *registry.Takedown
+	if t := change.Takedown; t != nil {
+		for _, other := range s.takedowns {
+			if other.LiftedAt == nil && other.TargetType == t.TargetType && other.TargetID == t.TargetID && t.LiftedAt == nil {
+				return registry.ErrConflict
+			}
+			if other.ID == t.ID {
+				if other.LiftedAt != nil {
+					return registry.ErrConflict
+				}
+				lifted = other
+			}
+		}
+	}
+	var report *registry.Report
+	if r := change.Report; r != nil {
+		for _, stored := range s.reports {
+			if stored.ID == r.ID {
+				report = stored
+			}
+		}
+		if report == nil || report.Status != registry.ReportOpen {
+			return registry.ErrConflict
+		}
+	}
+	for _, reg := range change.Services {
+		stored, ok := s.services[reg.ID]
+		if !ok || stored.Revision != reg.Revision-1 {
+			return registry.ErrConflict
+		}
+	}
+
+	if t := change.Takedown; t != nil {
+		c := *t
+		if lifted != nil {
+			*lifted = c
+		} else {
+			s.takedowns = append(s.takedowns, &c)
+		}
+	}
+	if report != nil {
+		*report = *change.Report
+	}
+	if p := change.Provider; p != nil {
+		s.providers[p.ID].Suspended = p.Suspended
+	}
+	for i, reg := range change.Services {
+		c := *reg
+		s.services[reg.ID] = &c
+		s.addEvent(change.Events[i])
+	}
+	a := *change.Action
+	s.actions = append(s.actions, &a)
+	return nil
+}
+
+func (s *memStore) ActiveTakedown(_ context.Context, targetType, targetID string) (*registry.Takedown, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, t := range s.takedowns {
+		if t.LiftedAt == nil && t.TargetType == targetType && t.TargetID == targetID {
+			c := *t
+			return &c, nil
+		}
+	}
+	return nil, registry.ErrNotFound
+}
+
+func (s *memStore) ListModerationActions(_ context.Context, filter registry.ActionFilter) ([]*registry.ModerationAction, int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var matched []*registry.ModerationAction
+	for i := len(s.actions) - 1; i >= 0; i-- {
+		a := s.actions[i]
+		if (filter.TargetType == "" || a.TargetType == filter.TargetType) &&
+			(filter.TargetID == "" || a.TargetID == filter.TargetID) &&
+			(filter.Actor == "" || a.Actor == filter.Actor) {
+			c := *a
+			matched = append(matched, &c)
+		}
+	}
+	total := len(matched)
+	if filter.Offset >= total {
+		return nil, total, nil
+	}
+	return matched[filter.Offset:min(filter.Offset+filter.Limit, total)], total, nil
+}
+
+func (s *memStore) CreateReport(_ context.Context, r *registry.Report, reg *registry.Registration, event *marketplace.CatalogEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, other := range s.reports {
+		if other.ServiceID == r.ServiceID && other.ReporterID == r.ReporterID && other.Status == registry.ReportOpen {
+			return registry.ErrConflict
+		}
+	}
+	if reg != nil {
+		if stored, ok := s.services[reg.ID]; !ok || stored.Revision != reg.Revision-1 {
+			return registry.ErrConflict
+		}
+		c := *reg
+		s.services[reg.ID] = &c
+		s.addEvent(event)
+	}
+	c := *r
+	s.reports = append(s.reports, &c)
+	return nil
+}
+
+func (s *memStore) GetReport(_ context.Context, id string) (*registry.Report, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, r := range s.reports {
+		if r.ID == id {
+			c := *r
+			return &c, nil
+		}
+	}
+	return nil, registry.ErrNotFound
+}
+
+func (s *memStore) ListReports(_ context.Context, filter registry.ReportFilter) ([]*registry.Report, int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var matched []*registry.Report
+	for _, r := range s.reports {
+		if (filter.ServiceID == "" || r.ServiceID == filter.ServiceID) &&
+			(filter.ProviderID == "" || r.ProviderID == filter.ProviderID) &&
+			(filter.Status == "" || r.Status == filter.Status) {
+			c := *r
+			matched = append(matched, &c)
+		}
+	}
+	total := len(matched)
+	if filter.Offset >= total {
+		return nil, total, nil
+	}
+	return matched[filter.Offset:min(filter.Offset+filter.Limit, total)], total, nil
+}
+
+// addReport stores an open report about a service
+func (s *memStore) addReport(r *registry.Report) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r.Status = registry.ReportOpen
+	c := *r
+	s.reports = append(s.reports, &c)
+}
+
+func (s *memStore) ListChanges(_ context.Context, filter registry.ChangeFilter) ([]marketplace.ServiceChange, int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	matched := []marketplace.ServiceChange{}
+	changes := s.changes[filter.ServiceID]
+	for i := len(changes) - 1; i >= 0; i-- {
+		if filter.Kind == "" || changes[i].Kind == filter.Kind {
+			matched = append(matched, changes[i])
+		}
+	}
+	total := len(matched)
+	return matched[min(filter.Offset, total):min(filter.Offset+filter.Limit, total)], total, nil
+}
+
+func (s *memStore) SchedulePriceChange(_ context.Context, pc *registry.PriceChange, notices []*marketplace.PriceChangeNotice) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, other := range s.prices {
+		if other.ServiceID == pc.ServiceID && other.Status == registry.PriceChangeScheduled {
+			return registry.ErrConflict
+		}
+	}
+	c := *pc
+	s.prices = append(s.prices, &c)
+	s.addNotices(notices)
+	return nil
+}
+
+func (s *memStore) addNotices(notices []*marketplace.PriceChangeNotice) {
+	for _, n := range notices {
+		payload, _ := json.Marshal(n)
+		s.nextID++
+		s.outbox = append(s.outbox, publisher.Event{ID: s.nextID, Stream: publisher.PriceChangeStream, Key: n.ConsumerID, Type: publisher.PriceChangeStream, Payload: payload})
+	}
+}
+
+func (s *memStore) GetPriceChange(_ context.Context, id string) (*registry.PriceChange, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, pc := range s.prices {
+		if pc.ID == id {
+			c := *pc
+			return &c, nil
+		}
+	}
+	return nil, registry.ErrNotFound
+}
+
+func (s *memStore) ListPriceChanges(_ context.Context, serviceID string) ([]*registry.PriceChange, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	changes := []*registry.PriceChange{}
+	for i := len(s.prices) - 1; i >= 0; i-- {
+		if s.prices[i].ServiceID == serviceID {
+			c := *s.prices[i]
+			changes = append(changes, &c)
+		}
+	}
+	return changes, nil
+}
+
+func (s *memStore) DuePriceChanges(_ context.Context, now time.Time, limit int) ([]*registry.PriceChange, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var due []*registry.PriceChange
+	for _, pc := range s.prices {
+		if pc.Status == registry.PriceChangeScheduled && !pc.EffectiveAt.After(now) && len(due) < limit {
+			c := *pc
+			due = append(due, &c)
+		}
+	}
+	return due, nil
+}
+
+func (s *memStore) CancelPriceChange(_ context.Context, pc *registry.PriceChange, notices []*marketplace.PriceChangeNotice) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored := s.scheduledPrice(pc.ID)
+	if stored == nil {
+		return registry.ErrConflict
+	}
+	*stored = *pc
+	s.addNotices(notices)
+	return nil
+}
+
+func (s *memStore) ApplyPriceChange(_ context.Context, pc *registry.PriceChange, reg *registry.Registration, event *marketplace.CatalogEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored := s.scheduledPrice(pc.ID)
+	if stored == nil {
+		return registry.ErrConflict
+	}
+	if reg != nil {
+		if current, ok := s.services[reg.ID]; !ok || current.Revision != reg.Revision-1 {
+			return registry.ErrConflict
+		}
+		c := *reg
+		s.services[reg.ID] = &c
+		s.addEvent(event)
+	}
+	*stored = *pc
+	return nil
+}
+
+func (s *memStore) UpdateExpiries(ctx context.Context, reg *registry.Registration, event *marketplace.CatalogEvent, notices []*marketplace.ComplianceExpiryNotice) error {
+	if err := s.UpdateService(ctx, reg, event); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, n := range notices {
+		payload, _ := json.Marshal(n)
+		s.nextID++
+		s.outbox = append(s.outbox, publisher.Event{ID: s.nextID, Stream: publisher.ExpiryStream, Key: n.RecipientID, Type: publisher.ExpiryStream, Payload: payload})
+	}
+	return nil
+}
+
+// scheduledPrice returns the stored price change id if it is scheduled
+func (s *memStore) scheduledPrice(id string) *registry.PriceChange {
+	for _, pc := range s.prices {
+		if pc.ID == id && pc.Status == registry.PriceChangeScheduled {
+			return pc
+		}
+	}
+	return nil
+}
+
+func (s *memStore) PublishPending(_ context.Context, limit int, publish func([]publisher.Event) error) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	batch := s.outbox[:min(limit, len(s.outbox))]
+	if len(batch) == 0 {
+		return 0, nil
+	}
+	if err := publish(batch); err != nil {
+		return 0, err
+	}
+	s.outbox = s.outbox[len(batch):]
+	return len(batch), nil
+}
+
+// events decodes the catalog events waiting in the outbox
+func (s *memStore) events() []marketplace.CatalogEvent {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var events []marketplace.CatalogEvent
+	for _, e := range s.outbox {
+		if e.Stream != publisher.CatalogStream {
+			continue
+		}
+		var event marketplace.CatalogEvent
+		json.Unmarshal(e.Payload, &event)
+		events = append(events, event)
+	}
+	return events
+}
+
+// expiryNotices decodes the compliance expiry notices waiting in the outbox
+func (s *memStore) expiryNotices() []marketplace.ComplianceExpiryNotice {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var notices []marketplace.ComplianceExpiryNotice
+	for _, e := range s.outbox {
+		if e.Stream != publisher.ExpiryStream {
+			continue
+		}
+		var notice marketplace.ComplianceExpiryNotice
+		json.Unmarshal(e.Payload, &notice)
+		notices = append(notices, notice)
+	}
+	return notices
+}
+
+// notices decodes the price-change notices waiting in the outbox
+func (s *memStore) notices() []marketplace.PriceChangeNotice {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var notices []marketplace.PriceChangeNotice
+	for _, e := range s.outbox {
+		if e.Stream != publisher.PriceChangeStream {
+			continue
+		}
+		var notice marketplace.PriceChangeNotice
+		json.Unmarshal(e.Payload, &notice)
+		notices = append(notices, notice)
+	}
+	return notices
+}
@@ -0,0 +1,164 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/org/llm-marketplace/pkg/marketplace"
+)
+
+// consumerDo sends a request as a consumer organisation
+func (r *testRegistry) consumerDo(t *testing.T, method, path, consumer string, body interface{}) (*httptest.ResponseRecorder, map[string]interface{}) {
+	t.Helper()
+	var reader bytes.Buffer
+	if body != nil {
+		json.NewEncoder(&reader).Encode(body)
+	}
+	req := httptest.NewRequest(method, path, &reader)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Consumer-ID", consumer)
+	w := httptest.NewRecorder()
+	r.router.ServeHTTP(w, req)
+
+	var decoded map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &decoded)
+	return w, decoded
+}
+
+// tieredService registers an active service with pricing tiers
+func (r *testRegistry) tieredService(t *testing.T, provider string) string {
+	t.Helper()
+	desc := descriptor("Summarizer")
+	desc.Pricing = &marketplace.PricingInfo{Model: "tiered", Currency: "EUR", Tiers: []marketplace.PricingTier{
+		{Tier: "standard", Rate: 0.002, Unit: "1k tokens"},
+		{Tier: "enterprise", Rate: 0.001, Unit: "1k tokens"},
+	}}
+	w, body := r.do(t, http.MethodPost, "/api/v1/services", provider, desc)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("register = %d %s", w.Code, w.Body)
+	}
+	return body["id"].(string)
+}
+
+func TestSubscriptionLifecycle(t *testing.T) {
+	r := newTestRegistry(t)
+	provider := r.provider(t, "acme")
+	service := r.tieredService(t, provider)
+
+	end := time.Now().AddDate(1, 0, 0).UTC().Truncate(time.Second)
+	w, body := r.consumerDo(t, http.MethodPost, "/api/v1/subscriptions", "org-1", map[string]interface{}{
+		"service_id":      service,
+		"tier":            "enterprise",
+		"committed_spend": 5000,
+		"ends_at":         end,
+		"sla":             map[string]interface{}{"availability": 99.95, "max_latency_ms": 300, "support": "enterprise"},
+	})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("subscribe = %d %s", w.Code, w.Body)
+	}
+	id := body["id"].(string)
+	if body["consumer_id"] != "org-1" || body["provider_id"] != provider || body["currency"] != "EUR" || body["status"] != "active" {
+		t.Errorf("subscription = %v", body)
+	}
+	if got := w.Header().Get("Location"); got != "/api/v1/subscriptions/"+id {
+		t.Errorf("Location = %q", got)
+	}
+
+	// An overlapping contract for the same service is refused
+	if w, _ := r.consumerDo(t, http.MethodPost, "/api/v1/subscriptions", "org-1", map[string]string{"service_id": service}); w.Code != http.StatusConflict {
+		t.Errorf("overlapping subscribe = %d, want 409", w.Code)
+	}
+
+	// The consumer and the provider see it; other consumers and providers don't
+	if w, _ := r.consumerDo(t, http.MethodGet, "/api/v1/subscriptions/"+id, "org-1", nil); w.Code != http.StatusOK {
+		t.Errorf("consumer get = %d", w.Code)
+	}
+	if w, _ := r.do(t, http.MethodGet, "/api/v1/subscriptions/"+id, provider, nil); w.Code != http.StatusOK {
+		t.Errorf("provider get = %d", w.Code)
+	}
+	if w, _ := r.consumerDo(t, http.MethodGet, "/api/v1/subscriptions/"+id, "org-2", nil); w.Code != http.StatusNotFound {
+		t.Errorf("other consumer get = %d, want 404", w.Code)
+	}
+	if w, _ := r.do(t, http.MethodGet, "/api/v1/subscriptions/"+id, r.provider(t, "other"), nil); w.Code != http.StatusNotFound {
+		t.Errorf("other provider get = %d, want 404", w.Code)
+	}
+
+	w, body = r.consumerDo(t, http.MethodGet, "/api/v1/subscriptions?status=active&service_id="+service, "org-1", nil)
+	if w.Code != http.StatusOK || body["total"] != 1.0 {
+		t.Errorf("list active = %d %s", w.Code, w.Body)
+	}
+	if w, _ := r.consumerDo(t, http.MethodGet, "/api/v1/subscriptions?consumer_id=org-2", "org-1", nil); w.Code != http.StatusForbidden {
+		t.Errorf("list another consumer's = %d, want 403", w.Code)
+	}
+	if w, _ := r.consumerDo(t, http.MethodGet, "/api/v1/subscriptions?status=paused", "org-1", nil); w.Code != http.StatusBadRequest {
+		t.Errorf("list by unknown status = %d, want 400", w.Code)
+	}
+
+	// Only the consumer or an operator cancels
+	if w, _ := r.do(t, http.MethodDelete, "/api/v1/subscriptions/"+id, provider, nil); w.Code != http.StatusForbidden {
+		t.Errorf("provider cancel = %d, want 403", w.Code)
+	}
+	if w, _ := r.consumerDo(t, http.MethodDelete, "/api/v1/subscriptions/"+id, "org-2", nil); w.Code != http.StatusForbidden {
+		t.Errorf("other consumer cancel = %d, want 403", w.Code)
+	}
+	w, body = r.consumerDo(t, http.MethodDelete, "/api/v1/subscriptions/"+id, "org-1", nil)
+	if w.Code != http.StatusOK || body["status"] != "cancelled" || body["cancelled_at"] == nil {
+		t.Fatalf("cancel = %d %s", w.Code, w.Body)
+	}
+	if w, _ := r.consumerDo(t, http.MethodDelete, "/api/v1/subscriptions/"+id, "org-1", nil); w.Code != http.StatusConflict {
+		t.Errorf("second cancel = %d, want 409", w.Code)
+	}
+	w, body = r.consumerDo(t, http.MethodGet, "/api/v1/subscriptions?status=active", "org-1", nil)
+	if w.Code != http.StatusOK || body["total"] != 0.0 {
+		t.Errorf("list active after cancel = %d %s", w.Code, w.Body)
+	}
+
+	// A cancelled contract no longer blocks a new one
+	if w, _ := r.consumerDo(t, http.MethodPost, "/api/v1/subscriptions", "org-1", map[string]string{"service_id": service}); w.Code != http.StatusCreated {
+		t.Errorf("resubscribe = %d %s", w.Code, w.Body)
+	}
+}
+
+func TestSubscribeValidation(t *testing.T) {
+	r := newTestRegistry(t)
+	provider := r.provider(t, "acme")
+	service := r.tieredService(t, provider)
+
+	w, body := r.consumerDo(t, http.MethodPost, "/api/v1/subscriptions", "org-1", map[string]interface{}{
+		"service_id":      service,
+		"tier":            "gold",
+		"committed_spend": -1,
+	})
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("subscribe = %d %s, want 400", w.Code, w.Body)
+	}
+	var fields []string
+	for _, e := range body["errors"].([]interface{}) {
+		fields = append(fields, e.(map[string]interface{})["field"].(string))
+	}
+	if len(fields) != 2 || fields[0] != "committed_spend" || fields[1] != "tier" {
+		t.Errorf("invalid fields = %v", fields)
+	}
+
+	if w, _ := r.consumerDo(t, http.MethodPost, "/api/v1/subscriptions", "org-1", map[string]string{"service_id": "7d0a3c1e-0000-4000-8000-000000000000"}); w.Code != http.StatusBadRequest {
+		t.Errorf("subscribe to unknown service = %d, want 400", w.Code)
+	}
+	if w, _ := r.consumerDo(t, http.MethodPost, "/api/v1/subscriptions", "org-1", map[string]string{"service_id": service, "consumer_id": "org-2"}); w.Code != http.StatusForbidden {
+		t.Errorf("subscribe another consumer = %d, want 403", w.Code)
+	}
+	if w, _ := r.do(t, http.MethodPost, "/api/v1/subscriptions", provider, map[string]string{"service_id": service, "consumer_id": "org-1"}); w.Code != http.StatusForbidden {
+		t.Errorf("provider subscribe = %d, want 403", w.Code)
+	}
+
+	// Services that aren't active take no new subscriptions
+	if w, _ := r.do(t, http.MethodPatch, "/api/v1/services/"+service+"/status", provider, map[string]string{"status": "deprecated"}); w.Code != http.StatusOK {
+		t.Fatalf("deprecate = %d %s", w.Code, w.Body)
+	}
+	if w, _ := r.consumerDo(t, http.MethodPost, "/api/v1/subscriptions", "org-1", map[string]string{"service_id": service}); w.Code != http.StatusConflict {
+		t.Errorf("subscribe to deprecated service = %d, want 409", w.Code)
+	}
+}
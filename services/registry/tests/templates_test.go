@@ -0,0 +1,81 @@
+package tests
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/org/llm-marketplace/pkg/marketplace"
+
+	"github.com/org/llm-marketplace/services/registry/internal/registry"
+)
+
+// promptTemplate is a valid prompt template listing
+func promptTemplate(name string) marketplace.ServiceDescriptor {
+	desc := descriptor(name)
+	desc.Kind = marketplace.KindPromptTemplate
+	desc.Endpoint = nil
+	desc.PromptTemplate = &marketplace.PromptTemplate{
+		Template:      "Summarise {{document}} in {{length}} sentences.",
+		Variables:     []marketplace.TemplateVariable{{Name: "document", Required: true}, {Name: "length", Default: "3"}},
+		ModelFamilies: []string{"gpt-4", "claude-3"},
+	}
+	return desc
+}
+
+func TestRegisterPromptTemplate(t *testing.T) {
+	r := newTestRegistry(t)
+	provider := r.provider(t, "acme")
+
+	w, body := r.do(t, http.MethodPost, "/api/v1/services", provider, promptTemplate("Summary prompt"))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("register = %d %s", w.Code, w.Body)
+	}
+	id := body["id"].(string)
+	if checked := r.policy.checked[0]; !checked.IsPromptTemplate() || checked.PromptTemplate == nil || len(checked.PromptTemplate.Variables) != 2 {
+		t.Errorf("policy engine checked %+v, want the template", checked)
+	}
+	if e := r.store.events()[0]; e.Service.PromptTemplate == nil || e.Service.PromptTemplate.ModelFamilies[1] != "claude-3" {
+		t.Errorf("event service = %+v, want the template", e.Service)
+	}
+	r.service(t, provider, "Summarizer")
+
+	// Listings can be filtered by kind
+	for kind, want := range map[string]int{"": 2, marketplace.KindPromptTemplate: 1, marketplace.KindService: 1} {
+		if w, body := r.do(t, http.MethodGet, "/api/v1/services?kind="+kind, "", nil); w.Code != http.StatusOK || body["total"] != float64(want) {
+			t.Errorf("list kind %q = %d %v, want %d", kind, w.Code, body["total"], want)
+		}
+	}
+	if w, _ := r.do(t, http.MethodGet, "/api/v1/services?kind=notebook", "", nil); w.Code != http.StatusBadRequest {
+		t.Errorf("list kind notebook = %d, want 400", w.Code)
+	}
+
+	// A template uses the variables it declares and isn't called at an
+	// endpoint
+	invalid := promptTemplate("Summary prompt")
+	invalid.PromptTemplate.Template = "Summarise {{doc}}"
+	invalid.Endpoint = &marketplace.EndpointInfo{URL: "https://api.example.com/summarize"}
+	if w, _ := r.do(t, http.MethodPut, "/api/v1/services/"+id, provider, invalid); w.Code != http.StatusBadRequest {
+		t.Errorf("invalid template = %d, want 400", w.Code)
+	}
+	// nor becomes a service
+	if w, _ := r.do(t, http.MethodPut, "/api/v1/services/"+id, provider, descriptor("Summary prompt")); w.Code != http.StatusBadRequest {
+		t.Errorf("template changed to a service = %d, want 400", w.Code)
+	}
+}
+
+func TestContentScanCoversPromptTemplates(t *testing.T) {
+	r := newTestRegistry(t)
+	r.withContentScan()
+	provider := r.provider(t, "acme")
+
+	desc := promptTemplate("Summary prompt")
+	desc.PromptTemplate.Template = "Summarise {{document}} with guaranteed accuracy in {{length}} sentences."
+	w, body := r.do(t, http.MethodPost, "/api/v1/services", provider, desc)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("register = %d %s", w.Code, w.Body)
+	}
+	scan := r.scan(t, body["id"].(string))
+	if scan.Status != registry.ScanFlagged || len(scan.Findings) != 1 || scan.Findings[0].Field != "prompt_template.template" {
+		t.Errorf("scan = %+v, want the claim in the template flagged", scan)
+	}
+}